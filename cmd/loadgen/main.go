@@ -0,0 +1,284 @@
+// Command loadgen drives concurrent deposits, withdrawals and transfers against a
+// running wallet service and reports throughput, error rates and p50/p95/p99
+// latencies per operation, to validate locking and connection pool settings.
+//
+// It registers its own users, funds their wallets, then runs workers until the
+// duration or request budget is spent:
+//
+//	go run ./cmd/loadgen -url http://localhost:8082 -concurrency 32 -wallets 10 -duration 30s
+//
+// Fewer wallets mean more requests contending for the same rows.
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// Operations loadgen can run
+const (
+	opDeposit  = "deposit"
+	opWithdraw = "withdraw"
+	opTransfer = "transfer"
+)
+
+// config is what the flags set
+type config struct {
+	url         string
+	concurrency int
+	wallets     int
+	duration    time.Duration
+	requests    int64
+	mix         map[string]int
+	maxAmount   int
+	fund        int
+	timeout     time.Duration
+}
+
+// account is a registered user's wallet and the token that moves its money
+type account struct {
+	walletID string
+	token    string
+}
+
+func main() {
+	cfg, err := parseFlags(os.Args[1:])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if err := run(ctx, cfg, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "loadgen:", err)
+		os.Exit(1)
+	}
+}
+
+func parseFlags(args []string) (*config, error) {
+	cfg := &config{}
+	var mix string
+	flags := flag.NewFlagSet("loadgen", flag.ContinueOnError)
+	flags.StringVar(&cfg.url, "url", "http://localhost:8082", "base URL of the wallet service")
+	flags.IntVar(&cfg.concurrency, "concurrency", 16, "requests in flight at once")
+	flags.IntVar(&cfg.wallets, "wallets", 10, "wallets to spread the load over; fewer means more contention")
+	flags.DurationVar(&cfg.duration, "duration", 30*time.Second, "how long to run")
+	flags.Int64Var(&cfg.requests, "requests", 0, "stop after this many requests; 0 runs for the whole duration")
+	flags.StringVar(&mix, "mix", "deposit=30,withdraw=30,transfer=40", "relative weight of each operation")
+	flags.IntVar(&cfg.maxAmount, "max-amount", 50, "largest amount moved by one request")
+	flags.IntVar(&cfg.fund, "fund", 100000, "amount each wallet is funded with before the run")
+	flags.DurationVar(&cfg.timeout, "timeout", 10*time.Second, "timeout of each request")
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if cfg.concurrency < 1 || cfg.wallets < 1 || cfg.maxAmount < 1 {
+		return nil, errors.New("-concurrency, -wallets and -max-amount must be at least 1")
+	}
+	var err error
+	if cfg.mix, err = parseMix(mix); err != nil {
+		return nil, err
+	}
+	if cfg.mix[opTransfer] > 0 && cfg.wallets < 2 {
+		return nil, errors.New("transfers need at least 2 wallets")
+	}
+	cfg.url = strings.TrimSuffix(cfg.url, "/")
+	return cfg, nil
+}
+
+// parseMix reads comma-separated OPERATION=WEIGHT entries
+func parseMix(spec string) (map[string]int, error) {
+	mix := make(map[string]int)
+	total := 0
+	for _, item := range strings.Split(spec, ",") {
+		op, value, ok := strings.Cut(strings.TrimSpace(item), "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid -mix entry %q, want OPERATION=WEIGHT", item)
+		}
+		switch op {
+		case opDeposit, opWithdraw, opTransfer:
+		default:
+			return nil, fmt.Errorf("invalid -mix entry %q: operation must be deposit, withdraw or transfer", item)
+		}
+		weight, err := strconv.Atoi(value)
+		if err != nil || weight < 0 {
+			return nil, fmt.Errorf("invalid -mix entry %q: weight must be a whole number of at least 0", item)
+		}
+		mix[op] = weight
+		total += weight
+	}
+	if total == 0 {
+		return nil, errors.New("-mix must give some operation a weight")
+	}
+	return mix, nil
+}
+
+// pick chooses an operation at random, in proportion to its weight
+func pick(mix map[string]int, rng *rand.Rand) string {
+	total := 0
+	for _, weight := range mix {
+		total += weight
+	}
+	roll := rng.IntN(total)
+	for _, op := range []string{opDeposit, opWithdraw, opTransfer} {
+		if roll < mix[op] {
+			return op
+		}
+		roll -= mix[op]
+	}
+	return opDeposit
+}
+
+func run(ctx context.Context, cfg *config, out io.Writer) error {
+	client := &http.Client{
+		Timeout:   cfg.timeout,
+		Transport: &http.Transport{MaxIdleConnsPerHost: cfg.concurrency},
+	}
+
+	fmt.Fprintf(out, "Registering and funding %d wallets at %s\n", cfg.wallets, cfg.url)
+	accounts, err := setup(ctx, client, cfg)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "Running %d workers for %s\n", cfg.concurrency, cfg.duration)
+	ctx, cancel := context.WithTimeout(ctx, cfg.duration)
+	defer cancel()
+
+	rec := newRecorder()
+	var sent atomic.Int64
+	var wg sync.WaitGroup
+	started := time.Now()
+	for worker := range cfg.concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rng := rand.New(rand.NewPCG(uint64(started.UnixNano()), uint64(worker)))
+			for ctx.Err() == nil {
+				if cfg.requests > 0 && sent.Add(1) > cfg.requests {
+					return
+				}
+				res := send(ctx, client, cfg, accounts, pick(cfg.mix, rng), rng)
+				if ctx.Err() != nil && res.status == 0 {
+					// Cut off by the end of the run rather than failed
+					return
+				}
+				rec.record(res)
+			}
+		}()
+	}
+	wg.Wait()
+
+	fmt.Fprintln(out)
+	report(out, rec.stats(), time.Since(started))
+	return nil
+}
+
+// setup registers a user per wallet and funds each wallet
+func setup(ctx context.Context, client *http.Client, cfg *config) ([]account, error) {
+	runID := strconv.FormatInt(time.Now().UnixNano(), 36)
+	password := "loadgen-" + runID
+
+	accounts := make([]account, cfg.wallets)
+	for i := range accounts {
+		username := fmt.Sprintf("loadgen-%s-%d", runID, i)
+		body := map[string]string{"name": "Load Test " + strconv.Itoa(i), "username": username, "password": password}
+		var registered struct {
+			AccessToken string `json:"access_token"`
+			User        struct {
+				Wallet struct {
+					ID string `json:"id"`
+				} `json:"wallet"`
+			} `json:"user"`
+		}
+		status, err := post(ctx, client, cfg.url+"/api/v1/auth/register", "", body, &registered)
+		if err != nil {
+			return nil, fmt.Errorf("failed to register %s: %w", username, err)
+		}
+		if status != http.StatusCreated {
+			return nil, fmt.Errorf("failed to register %s: status %d", username, status)
+		}
+		accounts[i] = account{walletID: registered.User.Wallet.ID, token: registered.AccessToken}
+
+		status, err = post(ctx, client, cfg.url+"/api/v1/wallets/"+accounts[i].walletID+"/deposit", accounts[i].token, map[string]int{"amount": cfg.fund}, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fund wallet %s: %w", accounts[i].walletID, err)
+		}
+		if status != http.StatusOK {
+			return nil, fmt.Errorf("failed to fund wallet %s: status %d", accounts[i].walletID, status)
+		}
+	}
+	return accounts, nil
+}
+
+// send makes one request of the operation from a random wallet and times it
+func send(ctx context.Context, client *http.Client, cfg *config, accounts []account, op string, rng *rand.Rand) result {
+	from := accounts[rng.IntN(len(accounts))]
+	amount := float64(1+rng.IntN(cfg.maxAmount*100)) / 100
+
+	body := map[string]any{"amount": amount}
+	if op == opTransfer {
+		to := from
+		for to.walletID == from.walletID {
+			to = accounts[rng.IntN(len(accounts))]
+		}
+		body["to_wallet_id"] = to.walletID
+	}
+
+	started := time.Now()
+	status, err := post(ctx, client, cfg.url+"/api/v1/wallets/"+from.walletID+"/"+op, from.token, body, nil)
+	res := result{op: op, status: status, latency: time.Since(started)}
+	if err != nil {
+		res.status = 0
+	}
+	return res
+}
+
+// post sends body as JSON and decodes a successful response into out when it is set
+func post(ctx context.Context, client *http.Client, url, token string, body, out any) (int, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if out != nil && resp.StatusCode < 300 {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return resp.StatusCode, fmt.Errorf("invalid response: %w", err)
+		}
+		return resp.StatusCode, nil
+	}
+	// Drain the body so the connection is reused
+	_, err = io.Copy(io.Discard, resp.Body)
+	return resp.StatusCode, err
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// result is the outcome of one request. status is 0 when no response came back.
+type result struct {
+	op      string
+	status  int
+	latency time.Duration
+}
+
+// failed reports whether the request errored or was refused
+func (r result) failed() bool {
+	return r.status == 0 || r.status >= 400
+}
+
+// recorder collects the results of every request a run makes
+type recorder struct {
+	mu      sync.Mutex
+	results map[string][]result
+}
+
+func newRecorder() *recorder {
+	return &recorder{results: make(map[string][]result)}
+}
+
+func (r *recorder) record(res result) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.results[res.op] = append(r.results[res.op], res)
+}
+
+// opStats summarizes the requests of one operation
+type opStats struct {
+	op       string
+	requests int
+	errors   int
+	statuses map[int]int
+	p50      time.Duration
+	p95      time.Duration
+	p99      time.Duration
+	max      time.Duration
+}
+
+// errorRate is the fraction of requests that failed
+func (s opStats) errorRate() float64 {
+	if s.requests == 0 {
+		return 0
+	}
+	return float64(s.errors) / float64(s.requests)
+}
+
+// summarize computes the stats of one set of results
+func summarize(op string, results []result) opStats {
+	stats := opStats{op: op, requests: len(results), statuses: make(map[int]int)}
+	if len(results) == 0 {
+		return stats
+	}
+
+	latencies := make([]time.Duration, len(results))
+	for i, res := range results {
+		latencies[i] = res.latency
+		stats.statuses[res.status]++
+		if res.failed() {
+			stats.errors++
+		}
+	}
+	slices.Sort(latencies)
+	stats.p50 = percentile(latencies, 50)
+	stats.p95 = percentile(latencies, 95)
+	stats.p99 = percentile(latencies, 99)
+	stats.max = latencies[len(latencies)-1]
+	return stats
+}
+
+// percentile returns the nearest-rank pth percentile of sorted latencies
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// stats returns the stats of each operation, by name, then of all of them together
+func (r *recorder) stats() []opStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ops := make([]string, 0, len(r.results))
+	var all []result
+	for op, results := range r.results {
+		ops = append(ops, op)
+		all = append(all, results...)
+	}
+	sort.Strings(ops)
+
+	stats := make([]opStats, 0, len(ops)+1)
+	for _, op := range ops {
+		stats = append(stats, summarize(op, r.results[op]))
+	}
+	return append(stats, summarize("total", all))
+}
+
+// report writes a table of the run's throughput, error rates and latencies
+func report(w io.Writer, stats []opStats, elapsed time.Duration) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "operation\trequests\treq/s\terrors\terror rate\tp50\tp95\tp99\tmax\tstatuses\t")
+	for _, s := range stats {
+		fmt.Fprintf(tw, "%s\t%d\t%.1f\t%d\t%.2f%%\t%s\t%s\t%s\t%s\t%s\t\n",
+			s.op, s.requests, float64(s.requests)/elapsed.Seconds(), s.errors, 100*s.errorRate(),
+			round(s.p50), round(s.p95), round(s.p99), round(s.max), statuses(s.statuses))
+	}
+	tw.Flush()
+}
+
+// statuses lists status codes with their counts, "0" standing for requests that got
+// no response
+func statuses(counts map[int]int) string {
+	codes := make([]int, 0, len(counts))
+	for code := range counts {
+		codes = append(codes, code)
+	}
+	slices.Sort(codes)
+
+	parts := make([]string, len(codes))
+	for i, code := range codes {
+		parts[i] = fmt.Sprintf("%d×%d", code, counts[code])
+	}
+	return strings.Join(parts, " ")
+}
+
+// round trims a latency to a readable precision
+func round(d time.Duration) time.Duration {
+	if d >= time.Second {
+		return d.Round(time.Millisecond)
+	}
+	return d.Round(10 * time.Microsecond)
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSummarizeReportsPercentilesAndErrors(t *testing.T) {
+	rec := newRecorder()
+	for i := 1; i <= 100; i++ {
+		status := 200
+		if i%10 == 0 {
+			status = 409
+		}
+		rec.record(result{op: opTransfer, status: status, latency: time.Duration(i) * time.Millisecond})
+	}
+	rec.record(result{op: opDeposit, status: 0, latency: time.Second})
+
+	stats := rec.stats()
+	require.Len(t, stats, 3)
+	assert.Equal(t, opDeposit, stats[0].op)
+	assert.Equal(t, 1.0, stats[0].errorRate())
+
+	transfers := stats[1]
+	assert.Equal(t, 100, transfers.requests)
+	assert.Equal(t, 10, transfers.errors)
+	assert.Equal(t, 50*time.Millisecond, transfers.p50)
+	assert.Equal(t, 95*time.Millisecond, transfers.p95)
+	assert.Equal(t, 99*time.Millisecond, transfers.p99)
+	assert.Equal(t, 100*time.Millisecond, transfers.max)
+	assert.Equal(t, map[int]int{200: 90, 409: 10}, transfers.statuses)
+
+	assert.Equal(t, "total", stats[2].op)
+	assert.Equal(t, 101, stats[2].requests)
+	assert.Equal(t, 11, stats[2].errors)
+
+	var out bytes.Buffer
+	report(&out, stats, time.Second)
+	assert.Contains(t, out.String(), "409×10")
+}
+
+func TestParseMix(t *testing.T) {
+	mix, err := parseMix("deposit=1, transfer=3")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{opDeposit: 1, opTransfer: 3}, mix)
+
+	for _, spec := range []string{"deposit", "refund=1", "deposit=-1", "deposit=0"} {
+		_, err := parseMix(spec)
+		assert.Error(t, err, spec)
+	}
+}
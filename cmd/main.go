@@ -2,28 +2,51 @@ package main
 
 import (
 	"context"
+	"expvar"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
+	"github.com/redis/go-redis/v9"
 	_ "github.com/shanwije/wallet-app/docs"
 	"github.com/shanwije/wallet-app/internal/api"
 	"github.com/shanwije/wallet-app/internal/config"
+	"github.com/shanwije/wallet-app/internal/events"
+	"github.com/shanwije/wallet-app/internal/fees"
+	"github.com/shanwije/wallet-app/internal/fx"
+	"github.com/shanwije/wallet-app/internal/grpcapi"
+	"github.com/shanwije/wallet-app/internal/notify"
+	"github.com/shanwije/wallet-app/internal/paymentprovider"
+	"github.com/shanwije/wallet-app/internal/payout"
+	"github.com/shanwije/wallet-app/internal/service"
+	"github.com/shanwije/wallet-app/internal/settlement"
+	"github.com/shanwije/wallet-app/pkg/auth"
+	"github.com/shanwije/wallet-app/pkg/awssig"
 	"github.com/shanwije/wallet-app/pkg/db"
+	"github.com/shanwije/wallet-app/pkg/featureflag"
+	"github.com/shanwije/wallet-app/pkg/lifecycle"
 	"github.com/shanwije/wallet-app/pkg/logger"
+	"github.com/shanwije/wallet-app/pkg/tlsconfig"
 	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 )
 
+// @title       Wallet API
+// @version     1.0
+// @description Wallets with deposits, withdrawals, transfers and the ledger behind them.
 func main() {
-	// Initialize logger first
-	if err := logger.Initialize(logger.GetEnvironment()); err != nil {
+	// Build the logger first. Everything started below gets it from the context, or
+	// from the router and gRPC server it is handed to.
+	log, logLevel, err := logger.New(logger.GetEnvironment())
+	if err != nil {
 		panic("Failed to initialize logger: " + err.Error())
 	}
-	defer logger.Close()
-
-	log := logger.Log
+	defer log.Sync()
+	baseCtx := logger.NewContext(context.Background(), log)
 
 	// Load and validate config
 	cfg, err := config.LoadConfig()
@@ -38,25 +61,320 @@ func main() {
 	)
 
 	// Setup DB connection
-	pgCfg := db.Config{
+	dbCfg := db.Config{
+		Driver:   cfg.DBDriver,
 		Host:     cfg.DBHost,
 		Port:     cfg.DBPort,
 		User:     cfg.DBUser,
 		Password: cfg.DBPassword,
 		Name:     cfg.DBName,
 		SSLMode:  cfg.DBSSLMode,
+
+		ReplicaDSN: cfg.DBReplicaDSN,
+		Pool: db.PoolConfig{
+			MaxOpenConns:    cfg.DBMaxOpenConns,
+			MaxIdleConns:    cfg.DBMaxIdleConns,
+			ConnMaxLifetime: cfg.DBConnMaxLifetime,
+			ConnMaxIdleTime: cfg.DBConnMaxIdleTime,
+		},
 	}
 
-	dbConn, err := db.New(pgCfg)
+	dbConn, err := db.New(dbCfg)
 	if err != nil {
 		log.Fatal("Failed to connect to DB", zap.Error(err))
 	}
-	defer dbConn.Close()
 
-	log.Info("Database connection established")
+	log.Info("Database connection established",
+		zap.String("driver", cfg.DBDriver),
+		zap.Bool("read_replica", dbConn.Replica != nil),
+		zap.Int("max_open_conns", cfg.DBMaxOpenConns))
+
+	// Pool usage is published with the other runtime metrics at /debug/vars
+	expvar.Publish("database", expvar.Func(func() any { return dbConn.PoolStats() }))
+
+	// `migrate [up|down|status]` manages the schema and exits without serving
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		command := "up"
+		if len(os.Args) > 2 {
+			command = os.Args[2]
+		}
+		if err := runMigrations(baseCtx, dbConn.DB, cfg.DBDriver, command); err != nil {
+			log.Fatal("Migration failed", zap.Error(err))
+		}
+		dbConn.Close()
+		return
+	}
+
+	if cfg.MigrateOnStartup {
+		if err := runMigrations(baseCtx, dbConn.DB, cfg.DBDriver, "up"); err != nil {
+			log.Fatal("Failed to migrate database", zap.Error(err))
+		}
+	}
+
+	// Everything below is stopped in the reverse order it is added: servers drain
+	// in-flight requests first, then workers finish, then connections are closed
+	app := lifecycle.New(cfg.ShutdownTimeout)
+	app.Add(lifecycle.Closer("database", dbConn))
+
+	// Redis is optional; it lets every instance share the same rate limit buckets
+	var redisClient *redis.Client
+	if cfg.RedisURL != "" {
+		redisClient, err = db.NewRedis(cfg.RedisURL)
+		if err != nil {
+			log.Fatal("Failed to connect to Redis", zap.Error(err))
+		}
+		app.Add(lifecycle.Closer("redis", redisClient))
+
+		log.Info("Redis connection established")
+	} else if cfg.RateLimitPerMinute > 0 {
+		log.Warn("REDIS_URL not set; rate limits are enforced per instance")
+	}
+
+	// Wallet events go through the outbox only when a publisher is configured
+	publisher, err := newEventPublisher(cfg)
+	if err != nil {
+		log.Fatal("Failed to set up event publishing", zap.Error(err))
+	}
+	if publisher != nil {
+		app.Add(lifecycle.Closer("event publisher", publisher))
+	}
+
+	// Transaction alerts are emailed only when a provider is configured
+	mailer, err := newNotificationProvider(cfg)
+	if err != nil {
+		log.Fatal("Failed to set up notifications", zap.Error(err))
+	}
+
+	// Setup services, router and inject dependencies
+	rates, err := newExchangeRateProvider(cfg)
+	if err != nil {
+		log.Fatal("Failed to set up exchange rates", zap.Error(err))
+	}
+
+	// Payouts to bank accounts are rejected unless a provider is configured
+	payouts := newPayoutProvider(cfg)
+
+	// Feature flags default to on unless FEATURE_FLAGS switches them off for this environment
+	flagDefaults, err := featureflag.ParseDefaults(service.DefaultFeatureFlags, cfg.FeatureFlags)
+	if err != nil {
+		log.Fatal("Invalid FEATURE_FLAGS", zap.Error(err))
+	}
+
+	feeSchedule, err := fees.ParseSchedule(cfg.Fees)
+	if err != nil {
+		log.Fatal("Invalid FEES", zap.Error(err))
+	}
+
+	services := api.NewServices(cfg, dbConn, redisClient, publisher, mailer, rates, payouts, feeSchedule, flagDefaults)
+	services.LogLevel = logLevel
+	// Refunded deposits are refunded to the customer when the payment provider's API is configured
+	if cfg.PaymentProviderAPIURL != "" {
+		services.DepositRefunds.Refunder = paymentprovider.NewHTTPRefunder(cfg.PaymentProviderAPIURL)
+	}
+
+	// `seed [flags]` fills the database with demo data and exits without serving
+	if len(os.Args) > 1 && os.Args[1] == "seed" {
+		ctx, stop := signal.NotifyContext(baseCtx, syscall.SIGINT, syscall.SIGTERM)
+		err := runSeed(ctx, services, os.Args[2:])
+		stop()
+		if err != nil {
+			log.Fatal("Seeding failed", zap.Error(err))
+		}
+		if redisClient != nil {
+			redisClient.Close()
+		}
+		dbConn.Close()
+		return
+	}
+
+	expvar.Publish("reconciliation", expvar.Func(func() any { return services.Reconciliation.Stats() }))
+	router := api.NewRouter(cfg, services, log)
+
+	// Run due scheduled transfers in the background until shutdown
+	if cfg.SchedulerInterval > 0 {
+		app.Add(lifecycle.Component{
+			Name: "scheduled transfer worker",
+			Run: func(ctx context.Context) error {
+				log.Info("Scheduled transfer worker started", zap.Duration("interval", cfg.SchedulerInterval))
+				services.ScheduledTransfers.Run(ctx, cfg.SchedulerInterval)
+				return nil
+			},
+		})
+	}
+	if cfg.PendingTransferExpiryInterval > 0 {
+		app.Add(lifecycle.Component{
+			Name: "pending transfer expiry worker",
+			Run: func(ctx context.Context) error {
+				log.Info("Pending transfer expiry worker started", zap.Duration("interval", cfg.PendingTransferExpiryInterval))
+				services.PendingTransfers.Run(ctx, cfg.PendingTransferExpiryInterval)
+				return nil
+			},
+		})
+	}
+	if cfg.IncomingTransferExpiryInterval > 0 {
+		app.Add(lifecycle.Component{
+			Name: "incoming transfer expiry worker",
+			Run: func(ctx context.Context) error {
+				log.Info("Incoming transfer expiry worker started", zap.Duration("interval", cfg.IncomingTransferExpiryInterval))
+				services.IncomingTransfers.Run(ctx, cfg.IncomingTransferExpiryInterval)
+				return nil
+			},
+		})
+	}
+	if cfg.BalanceSnapshotInterval > 0 {
+		app.Add(lifecycle.Component{
+			Name: "balance snapshot worker",
+			Run: func(ctx context.Context) error {
+				log.Info("Balance snapshot worker started", zap.Duration("interval", cfg.BalanceSnapshotInterval))
+				services.BalanceSnapshots.Run(ctx, cfg.BalanceSnapshotInterval)
+				return nil
+			},
+		})
+	}
+	if cfg.ReconciliationInterval > 0 {
+		app.Add(lifecycle.Component{
+			Name: "reconciliation worker",
+			Run: func(ctx context.Context) error {
+				log.Info("Reconciliation worker started", zap.Duration("interval", cfg.ReconciliationInterval))
+				services.Reconciliation.Run(ctx, cfg.ReconciliationInterval)
+				return nil
+			},
+		})
+	}
+	if payouts != nil && cfg.PayoutPollInterval > 0 {
+		app.Add(lifecycle.Component{
+			Name: "payout worker",
+			Run: func(ctx context.Context) error {
+				log.Info("Payout worker started",
+					zap.String("provider", cfg.PayoutProvider),
+					zap.Duration("interval", cfg.PayoutPollInterval))
+				services.Payouts.Run(ctx, cfg.PayoutPollInterval)
+				return nil
+			},
+		})
+	}
+	if services.DepositRefunds.Refunder != nil && cfg.RefundRetryInterval > 0 {
+		app.Add(lifecycle.Component{
+			Name: "deposit refund worker",
+			Run: func(ctx context.Context) error {
+				log.Info("Deposit refund worker started", zap.Duration("interval", cfg.RefundRetryInterval))
+				services.DepositRefunds.Run(ctx, cfg.RefundRetryInterval)
+				return nil
+			},
+		})
+	}
+	if services.Events != nil {
+		app.Add(lifecycle.Component{
+			Name: "outbox dispatcher",
+			Run: func(ctx context.Context) error {
+				log.Info("Outbox dispatcher started",
+					zap.String("publisher", cfg.EventsPublisher),
+					zap.String("notifications", cfg.NotificationsProvider),
+					zap.Duration("interval", cfg.OutboxPollInterval))
+				services.Events.Run(ctx, cfg.OutboxPollInterval)
+				return nil
+			},
+		})
+	}
+
+	if services.Export != nil && cfg.ExportInterval > 0 {
+		app.Add(lifecycle.Component{
+			Name: "export worker",
+			Run: func(ctx context.Context) error {
+				log.Info("Export worker started",
+					zap.String("bucket", cfg.ExportS3Bucket),
+					zap.String("prefix", cfg.ExportPrefix),
+					zap.Duration("interval", cfg.ExportInterval))
+				services.Export.Run(ctx, cfg.ExportInterval)
+				return nil
+			},
+		})
+	}
+	if services.ExportJobs.Store != nil && cfg.ExportJobInterval > 0 {
+		app.Add(lifecycle.Component{
+			Name: "export job worker",
+			Run: func(ctx context.Context) error {
+				log.Info("Export job worker started",
+					zap.String("bucket", cfg.ExportS3Bucket),
+					zap.Duration("interval", cfg.ExportJobInterval))
+				services.ExportJobs.Run(ctx, cfg.ExportJobInterval)
+				return nil
+			},
+		})
+	}
+
+	// Credit wallets from external settlement events when a topic is configured
+	if cfg.SettlementKafkaRESTURL != "" {
+		source := settlement.NewKafkaRESTSource(cfg.SettlementKafkaRESTURL, cfg.SettlementGroup, cfg.SettlementTopic)
+		app.Add(lifecycle.Closer("settlement source", source))
+
+		consumer := &settlement.Consumer{
+			Source:     source,
+			Handler:    &settlement.Processor{Wallets: services.Wallets},
+			RetryDelay: 5 * time.Second,
+		}
+		app.Add(lifecycle.Component{
+			Name: "settlement consumer",
+			Run: func(ctx context.Context) error {
+				log.Info("Settlement consumer started",
+					zap.String("topic", cfg.SettlementTopic),
+					zap.String("group", cfg.SettlementGroup))
+				consumer.Run(ctx, cfg.SettlementPollInterval)
+				return nil
+			},
+		})
+	}
 
-	// Setup router and inject dependencies
-	router := api.NewRouter(cfg, dbConn, log)
+	// Serve over TLS, with HTTP/2, when a certificate source is configured
+	tlsCfg, err := tlsconfig.New(tlsconfig.Config{
+		CertFile:         cfg.TLSCertFile,
+		KeyFile:          cfg.TLSKeyFile,
+		AutocertDomains:  cfg.TLSAutocertDomains,
+		AutocertCacheDir: cfg.TLSAutocertCacheDir,
+		ClientCAFile:     cfg.TLSClientCAFile,
+	})
+	if err != nil {
+		log.Fatal("Failed to set up TLS", zap.Error(err))
+	}
+
+	// Serve gRPC alongside HTTP when a port is configured
+	if cfg.GRPCPort != "" {
+		var tokens *auth.TokenManager
+		if cfg.AuthEnabled {
+			tokens = services.Tokens
+		}
+		var grpcOpts []grpc.ServerOption
+		if tlsCfg != nil {
+			grpcOpts = append(grpcOpts, grpc.Creds(credentials.NewTLS(tlsCfg)))
+		}
+		grpcServer := grpcapi.NewServer(log, services.Users, services.Wallets, services.TransactionPINs, tokens, services.Audit, cfg.RequestTimeout, grpcOpts...)
+
+		listener, err := net.Listen("tcp", ":"+cfg.GRPCPort)
+		if err != nil {
+			log.Fatal("Failed to listen for gRPC", zap.Error(err))
+		}
+
+		app.Add(lifecycle.Component{
+			Name: "grpc server",
+			Run: func(context.Context) error {
+				log.Info("gRPC server starting", zap.String("address", listener.Addr().String()))
+				return grpcServer.Serve(listener)
+			},
+			Stop: func(ctx context.Context) error { return stopGRPC(ctx, grpcServer) },
+		})
+	}
+
+	// WebSocket clients are disconnected once the HTTP server has stopped taking requests
+	app.Add(lifecycle.Closer("websocket hub", services.Realtime))
+	if services.BalanceRelay != nil {
+		app.Add(lifecycle.Component{
+			Name: "balance update relay",
+			Run: func(ctx context.Context) error {
+				services.BalanceRelay.Run(ctx)
+				return nil
+			},
+		})
+	}
 
 	// Setup HTTP server
 	server := &http.Server{
@@ -65,32 +383,114 @@ func main() {
 		ReadTimeout:  15 * time.Second,
 		WriteTimeout: 15 * time.Second,
 		IdleTimeout:  60 * time.Second,
+		TLSConfig:    tlsCfg,
 	}
+	app.Add(lifecycle.Component{
+		Name: "http server",
+		Run: func(context.Context) error {
+			log.Info("Server starting", zap.String("address", server.Addr), zap.Bool("tls", tlsCfg != nil))
+			if tlsCfg != nil {
+				// The certificate comes from TLSConfig
+				return server.ListenAndServeTLS("", "")
+			}
+			return server.ListenAndServe()
+		},
+		Stop: server.Shutdown,
+	})
+
+	// Serve until SIGINT or SIGTERM, or until a component fails
+	ctx, stop := signal.NotifyContext(baseCtx, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 
-	// Start server in a goroutine
+	if err := app.Run(ctx); err != nil {
+		log.Error("Server stopped with errors", zap.Error(err))
+		log.Sync()
+		os.Exit(1)
+	}
+
+	log.Info("Server exited")
+}
+
+// stopGRPC waits for in-flight RPCs to finish, cutting them off once ctx expires
+func stopGRPC(ctx context.Context, server *grpc.Server) error {
+	done := make(chan struct{})
 	go func() {
-		log.Info("Server starting", zap.String("address", server.Addr))
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatal("Server failed to start", zap.Error(err))
-		}
+		server.GracefulStop()
+		close(done)
 	}()
 
-	// Wait for interrupt signal for graceful shutdown
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		server.Stop()
+		return ctx.Err()
+	}
+}
 
-	log.Info("Server shutting down...")
+// newEventPublisher returns the configured event publisher, or nil for EVENTS_PUBLISHER=none
+func newEventPublisher(cfg *config.Config) (events.Publisher, error) {
+	switch cfg.EventsPublisher {
+	case "log":
+		return events.LogPublisher{}, nil
+	case "nats":
+		return events.NewNATSPublisher(cfg.EventsNATSURL)
+	case "kafka":
+		return events.NewKafkaRESTPublisher(cfg.EventsKafkaRESTURL, cfg.EventsKafkaTopic), nil
+	default:
+		return nil, nil
+	}
+}
 
-	// Create a context with timeout for graceful shutdown
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
+// newNotificationProvider returns the configured email provider, or nil for
+// NOTIFICATIONS_PROVIDER=none
+func newNotificationProvider(cfg *config.Config) (notify.Provider, error) {
+	switch cfg.NotificationsProvider {
+	case "dummy":
+		return notify.LogProvider{}, nil
+	case "smtp":
+		return &notify.SMTPProvider{
+			Addr:     cfg.SMTPAddr,
+			From:     cfg.NotificationsFrom,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+		}, nil
+	case "ses":
+		return &notify.SESProvider{
+			Endpoint: cfg.SESEndpoint,
+			Region:   cfg.SESRegion,
+			From:     cfg.NotificationsFrom,
+			Credentials: awssig.Credentials{
+				AccessKeyID:     cfg.AWSAccessKeyID,
+				SecretAccessKey: cfg.AWSSecretAccessKey,
+				SessionToken:    cfg.AWSSessionToken,
+			},
+		}, nil
+	default:
+		return nil, nil
+	}
+}
 
-	// Shutdown server gracefully
-	if err := server.Shutdown(ctx); err != nil {
-		log.Error("Server forced to shutdown", zap.Error(err))
-		return
+// newExchangeRateProvider returns the configured exchange rate provider, or nil for FX_PROVIDER=none
+func newExchangeRateProvider(cfg *config.Config) (fx.ExchangeRateProvider, error) {
+	switch cfg.FXProvider {
+	case "static":
+		return fx.ParseStaticRates(cfg.FXRates)
+	case "http":
+		return fx.NewHTTPRates(cfg.FXAPIURL, cfg.FXRateTTL), nil
+	default:
+		return nil, nil
 	}
+}
 
-	log.Info("Server exited")
+// newPayoutProvider returns the configured payout provider, or nil for PAYOUT_PROVIDER=none
+func newPayoutProvider(cfg *config.Config) payout.Provider {
+	switch cfg.PayoutProvider {
+	case "dummy":
+		return payout.LogProvider{}
+	case "http":
+		return payout.NewHTTPProvider(cfg.PayoutAPIURL)
+	default:
+		return nil
+	}
 }
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+
+	"github.com/shanwije/wallet-app/db/migrations"
+	"github.com/shanwije/wallet-app/pkg/db"
+	"github.com/shanwije/wallet-app/pkg/logger"
+)
+
+// runMigrations applies the embedded migrations for the driver. command is "up" to
+// apply everything pending, "down" to roll back the latest migration, or "status".
+func runMigrations(ctx context.Context, dbConn *sqlx.DB, driver, command string) error {
+	log := logger.FromContext(ctx)
+
+	fsys, err := migrations.ForDriver(driver)
+	if err != nil {
+		return err
+	}
+	migrator, err := db.NewMigrator(dbConn, driver, fsys)
+	if err != nil {
+		return err
+	}
+
+	switch command {
+	case "up":
+		results, err := migrator.Up(ctx)
+		for _, result := range results {
+			log.Info("Applied migration",
+				zap.String("file", result.Source.Path),
+				zap.Duration("duration", result.Duration))
+		}
+		if err != nil {
+			return fmt.Errorf("failed to apply migrations: %w", err)
+		}
+		if len(results) == 0 {
+			log.Info("Database schema is up to date")
+		}
+	case "down":
+		result, err := migrator.Down(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to roll back migration: %w", err)
+		}
+		log.Info("Rolled back migration", zap.String("file", result.Source.Path))
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get migration status: %w", err)
+		}
+		for _, status := range statuses {
+			fields := []zap.Field{zap.String("file", status.Source.Path), zap.String("state", string(status.State))}
+			if !status.AppliedAt.IsZero() {
+				fields = append(fields, zap.Time("applied_at", status.AppliedAt))
+			}
+			log.Info("Migration", fields...)
+		}
+	default:
+		return fmt.Errorf("unknown migrate command %q; use up, down or status", command)
+	}
+
+	return nil
+}
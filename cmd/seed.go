@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/shanwije/wallet-app/internal/api"
+	"github.com/shanwije/wallet-app/internal/seed"
+	"github.com/shanwije/wallet-app/pkg/logger"
+)
+
+// runSeed fills the database with demo users and transaction histories, sized by the
+// flags in args: -users, -transactions, -password, -username-prefix and -seed
+func runSeed(ctx context.Context, services *api.Services, args []string) error {
+	log := logger.FromContext(ctx)
+
+	cfg := seed.Config{}
+	flags := flag.NewFlagSet("seed", flag.ContinueOnError)
+	flags.IntVar(&cfg.Users, "users", 20, "users to create, each with a wallet")
+	flags.IntVar(&cfg.Transactions, "transactions", 25, "deposits, withdrawals and transfers per wallet")
+	flags.StringVar(&cfg.Password, "password", "", "register users with this password so they can log in; empty creates them without credentials")
+	flags.StringVar(&cfg.UsernamePrefix, "username-prefix", "demo", "username prefix of registered users, followed by their number")
+	flags.Uint64Var(&cfg.Seed, "seed", uint64(time.Now().UnixNano()), "random seed, for repeatable data")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	log.Info("Seeding demo data",
+		zap.Int("users", cfg.Users),
+		zap.Int("transactions_per_wallet", cfg.Transactions),
+		zap.Uint64("seed", cfg.Seed))
+
+	generator := &seed.Generator{Users: services.Users, Wallets: services.Wallets, Config: cfg}
+	started := time.Now()
+	summary, err := generator.Run(ctx)
+	if summary != nil {
+		log.Info("Seeded demo data",
+			zap.Int("users", summary.Users),
+			zap.Int("deposits", summary.Deposits),
+			zap.Int("withdrawals", summary.Withdrawals),
+			zap.Int("transfers", summary.Transfers),
+			zap.Int("skipped", summary.Skipped),
+			zap.Duration("duration", time.Since(started)))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to seed demo data: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- A journal is one money movement; its ledger entries are the debit and credit legs.
+-- Entries with a NULL wallet_id belong to the external settlement account.
+CREATE TABLE journals (
+    id UUID PRIMARY KEY,
+    type TEXT NOT NULL CHECK (type IN ('deposit', 'withdraw', 'transfer')),
+    description TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE ledger_entries (
+    id UUID PRIMARY KEY,
+    journal_id UUID NOT NULL REFERENCES journals(id),
+    wallet_id UUID REFERENCES wallets(id),
+    direction TEXT NOT NULL CHECK (direction IN ('debit', 'credit')),
+    amount NUMERIC(20, 2) NOT NULL CHECK (amount > 0),
+    currency CHAR(3) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_ledger_entries_journal ON ledger_entries(journal_id);
+CREATE INDEX idx_ledger_entries_wallet_created ON ledger_entries(wallet_id, created_at DESC, id DESC);
+
+-- Deposits and withdrawals become a journal each, keeping the transaction ID
+INSERT INTO journals (id, type, description, created_at)
+SELECT id, type, description, COALESCE(created_at, now())
+FROM transactions
+WHERE type IN ('deposit', 'withdraw');
+
+-- Both sides of a transfer share a reference_id, which becomes the journal ID
+INSERT INTO journals (id, type, description, created_at)
+SELECT reference_id, 'transfer', MAX(description), COALESCE(MIN(created_at), now())
+FROM transactions
+WHERE type IN ('transfer_in', 'transfer_out')
+GROUP BY reference_id;
+
+-- Wallet legs keep the original transaction IDs
+INSERT INTO ledger_entries (id, journal_id, wallet_id, direction, amount, currency, created_at)
+SELECT t.id,
+       CASE WHEN t.type IN ('deposit', 'withdraw') THEN t.id ELSE t.reference_id END,
+       t.wallet_id,
+       CASE WHEN t.type IN ('deposit', 'transfer_in') THEN 'credit' ELSE 'debit' END,
+       t.amount,
+       w.currency,
+       COALESCE(t.created_at, now())
+FROM transactions t
+JOIN wallets w ON w.id = t.wallet_id;
+
+-- Settlement legs balance deposits and withdrawals
+INSERT INTO ledger_entries (id, journal_id, wallet_id, direction, amount, currency, created_at)
+SELECT uuid_generate_v4(),
+       t.id,
+       NULL,
+       CASE WHEN t.type = 'deposit' THEN 'debit' ELSE 'credit' END,
+       t.amount,
+       w.currency,
+       COALESCE(t.created_at, now())
+FROM transactions t
+JOIN wallets w ON w.id = t.wallet_id
+WHERE t.type IN ('deposit', 'withdraw');
+
+DROP TABLE transactions;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+CREATE TABLE transactions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    wallet_id UUID NOT NULL REFERENCES wallets(id) ON DELETE CASCADE,
+    type TEXT NOT NULL CHECK (type IN ('deposit', 'withdraw', 'transfer_in', 'transfer_out')),
+    amount NUMERIC(20, 2) NOT NULL CHECK (amount > 0),
+    reference_id UUID, -- for linking to related tx (e.g., the other side of a transfer)
+    description TEXT,
+    created_at TIMESTAMPTZ DEFAULT now()
+);
+
+INSERT INTO transactions (id, wallet_id, type, amount, reference_id, description, created_at)
+SELECT e.id,
+       e.wallet_id,
+       CASE
+           WHEN j.type <> 'transfer' THEN j.type
+           WHEN e.direction = 'credit' THEN 'transfer_in'
+           ELSE 'transfer_out'
+       END,
+       e.amount,
+       CASE WHEN j.type = 'transfer' THEN j.id END,
+       j.description,
+       e.created_at
+FROM ledger_entries e
+JOIN journals j ON j.id = e.journal_id
+WHERE e.wallet_id IS NOT NULL;
+
+DROP TABLE ledger_entries;
+DROP TABLE journals;
+
+-- +goose StatementEnd
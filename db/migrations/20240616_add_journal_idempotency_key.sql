@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Scoped client keys for deposits, withdrawals and transfers; NULL for journals recorded without one
+ALTER TABLE journals ADD COLUMN idempotency_key VARCHAR(255);
+ALTER TABLE journals ADD CONSTRAINT uq_journals_idempotency_key UNIQUE (idempotency_key);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE journals DROP CONSTRAINT uq_journals_idempotency_key;
+ALTER TABLE journals DROP COLUMN idempotency_key;
+
+-- +goose StatementEnd
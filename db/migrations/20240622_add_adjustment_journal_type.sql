@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Adjustments are operator corrections posted against the settlement account
+ALTER TABLE journals DROP CONSTRAINT journals_type_check;
+ALTER TABLE journals ADD CONSTRAINT journals_type_check
+    CHECK (type IN ('deposit', 'withdraw', 'transfer', 'adjustment'));
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+-- Fails while adjustment journals exist, as they cannot be reclassified
+ALTER TABLE journals DROP CONSTRAINT journals_type_check;
+ALTER TABLE journals ADD CONSTRAINT journals_type_check
+    CHECK (type IN ('deposit', 'withdraw', 'transfer'));
+
+-- +goose StatementEnd
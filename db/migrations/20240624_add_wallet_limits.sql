@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Per-wallet caps in the wallet's currency; a NULL cap is not enforced. The daily caps
+-- are checked against the wallet's ledger entries from the last 24 hours.
+CREATE TABLE wallet_limits (
+    wallet_id UUID PRIMARY KEY REFERENCES wallets(id),
+    max_transaction_amount NUMERIC(20, 2) CHECK (max_transaction_amount > 0),
+    daily_withdrawal_limit NUMERIC(20, 2) CHECK (daily_withdrawal_limit > 0),
+    daily_transfer_limit NUMERIC(20, 2) CHECK (daily_transfer_limit > 0),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE wallet_limits;
+
+-- +goose StatementEnd
@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Withdrawals and transfers the risk checks flagged or blocked, kept for review.
+-- reasons lists the rules that fired.
+CREATE TABLE risk_decisions (
+    id UUID PRIMARY KEY,
+    wallet_id UUID NOT NULL REFERENCES wallets(id),
+    operation TEXT NOT NULL CHECK (operation IN ('withdraw', 'transfer')),
+    counterparty_wallet_id UUID REFERENCES wallets(id),
+    amount NUMERIC(20, 2) NOT NULL CHECK (amount > 0),
+    currency CHAR(3) NOT NULL,
+    action TEXT NOT NULL CHECK (action IN ('flag', 'block')),
+    reasons TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_risk_decisions_wallet ON risk_decisions(wallet_id, created_at DESC);
+CREATE INDEX idx_risk_decisions_created ON risk_decisions(created_at DESC);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE risk_decisions;
+
+-- +goose StatementEnd
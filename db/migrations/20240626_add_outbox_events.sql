@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Transactional outbox: wallet events are inserted in the same transaction as the
+-- journal they describe and published by a background dispatcher.
+CREATE TABLE outbox_events (
+    id UUID PRIMARY KEY,
+    event_type TEXT NOT NULL,
+    wallet_id UUID NOT NULL,
+    payload JSONB NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    published_at TIMESTAMPTZ
+);
+
+CREATE INDEX idx_outbox_events_unpublished ON outbox_events(created_at) WHERE published_at IS NULL;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE outbox_events;
+
+-- +goose StatementEnd
@@ -0,0 +1,43 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Append-only record of every mutating API call. status_code is the HTTP status, or
+-- the gRPC code for calls over gRPC.
+CREATE TABLE audit_log (
+    id UUID PRIMARY KEY,
+    actor_type TEXT NOT NULL CHECK (actor_type IN ('user', 'admin', 'anonymous')),
+    actor_id TEXT NOT NULL,
+    endpoint TEXT NOT NULL,
+    path TEXT NOT NULL,
+    payload_hash CHAR(64) NOT NULL,
+    status_code INT NOT NULL,
+    request_id TEXT NOT NULL DEFAULT '',
+    wallet_id UUID,
+    balance_before NUMERIC(20, 2),
+    balance_after NUMERIC(20, 2),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_audit_log_created ON audit_log(created_at DESC);
+CREATE INDEX idx_audit_log_actor ON audit_log(actor_id, created_at DESC);
+CREATE INDEX idx_audit_log_wallet ON audit_log(wallet_id, created_at DESC);
+
+CREATE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_log_append_only
+    BEFORE UPDATE OR DELETE ON audit_log
+    FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE audit_log;
+DROP FUNCTION audit_log_append_only();
+
+-- +goose StatementEnd
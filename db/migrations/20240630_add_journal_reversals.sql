@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- A reversal posts the legs of an earlier journal in the opposite direction and points
+-- back at it; the unique key lets each journal be reversed at most once
+ALTER TABLE journals
+    ADD COLUMN reverses_journal_id UUID REFERENCES journals(id),
+    ADD CONSTRAINT uq_journals_reverses_journal_id UNIQUE (reverses_journal_id);
+
+ALTER TABLE journals DROP CONSTRAINT journals_type_check;
+ALTER TABLE journals ADD CONSTRAINT journals_type_check
+    CHECK (type IN ('deposit', 'withdraw', 'transfer', 'adjustment', 'reversal'));
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+-- Fails while reversal journals exist, as they cannot be reclassified
+ALTER TABLE journals DROP CONSTRAINT journals_type_check;
+ALTER TABLE journals ADD CONSTRAINT journals_type_check
+    CHECK (type IN ('deposit', 'withdraw', 'transfer', 'adjustment'));
+
+ALTER TABLE journals
+    DROP CONSTRAINT uq_journals_reverses_journal_id,
+    DROP COLUMN reverses_journal_id;
+
+-- +goose StatementEnd
@@ -0,0 +1,40 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Runs of the reconciliation job, which compares every wallet's stored balance with the
+-- sum of its ledger entries, and the wallets each run found disagreeing. difference is
+-- balance - ledger_balance.
+CREATE TABLE reconciliation_runs (
+    id UUID PRIMARY KEY,
+    started_at TIMESTAMPTZ NOT NULL,
+    finished_at TIMESTAMPTZ NOT NULL,
+    wallets_checked BIGINT NOT NULL,
+    discrepancy_count INTEGER NOT NULL
+);
+
+CREATE INDEX idx_reconciliation_runs_started ON reconciliation_runs (started_at DESC);
+
+CREATE TABLE reconciliation_discrepancies (
+    id UUID PRIMARY KEY,
+    run_id UUID NOT NULL REFERENCES reconciliation_runs(id),
+    wallet_id UUID NOT NULL REFERENCES wallets(id),
+    balance NUMERIC(20, 2) NOT NULL,
+    ledger_balance NUMERIC(20, 2) NOT NULL,
+    difference NUMERIC(20, 2) NOT NULL,
+    currency CHAR(3) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (run_id, wallet_id)
+);
+
+CREATE INDEX idx_reconciliation_discrepancies_wallet ON reconciliation_discrepancies (wallet_id, created_at DESC);
+CREATE INDEX idx_reconciliation_discrepancies_created ON reconciliation_discrepancies (created_at DESC);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE reconciliation_discrepancies;
+DROP TABLE reconciliation_runs;
+
+-- +goose StatementEnd
@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Indexes behind the admin wallet search. Each ends with the column the search sorts
+-- by and id, its tiebreaker, so a cursor page starts with a range scan right after the
+-- last wallet of the previous one. Searches filtered by status or currency keep to the
+-- wallets with that value; the rest walk the sort order. balance changes with every
+-- money movement, and idx_wallets_balance is updated with it.
+CREATE INDEX idx_wallets_created ON wallets(created_at, id);
+CREATE INDEX idx_wallets_balance ON wallets(balance, id);
+CREATE INDEX idx_wallets_status_created ON wallets(status, created_at, id);
+CREATE INDEX idx_wallets_currency_created ON wallets(currency, created_at, id);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP INDEX idx_wallets_currency_created;
+DROP INDEX idx_wallets_status_created;
+DROP INDEX idx_wallets_balance;
+DROP INDEX idx_wallets_created;
+
+-- +goose StatementEnd
@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- User search matches any part of a name, and similar names through pg_trgm's trigram
+-- similarity. A trigram index serves both the ILIKE and the % operator, which a b-tree
+-- on name could not for a match in the middle of it.
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX idx_users_name_trgm ON users USING gin (name gin_trgm_ops);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP INDEX idx_users_name_trgm;
+
+-- pg_trgm is left installed in case anything else has come to use it
+
+-- +goose StatementEnd
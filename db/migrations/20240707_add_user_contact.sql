@@ -0,0 +1,29 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Users may give an email address and a phone number, each belonging to at most one
+-- user. Deleting a user clears them so they can be used again. Emails are stored
+-- lowercased and phone numbers in E.164, so the unique indexes compare them as the
+-- service does. version guards profile updates against lost writes.
+ALTER TABLE users
+    ADD COLUMN email VARCHAR(254),
+    ADD COLUMN phone VARCHAR(16),
+    ADD COLUMN version BIGINT NOT NULL DEFAULT 0;
+
+CREATE UNIQUE INDEX uq_users_email ON users (email);
+CREATE UNIQUE INDEX uq_users_phone ON users (phone);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP INDEX uq_users_phone;
+DROP INDEX uq_users_email;
+
+ALTER TABLE users
+    DROP COLUMN version,
+    DROP COLUMN phone,
+    DROP COLUMN email;
+
+-- +goose StatementEnd
@@ -0,0 +1,38 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- The balance each wallet's owner wants to hear about falling below, in the wallet's
+-- currency. A NULL threshold sends no low balance alerts.
+CREATE TABLE wallet_alert_settings (
+    wallet_id UUID PRIMARY KEY REFERENCES wallets(id),
+    low_balance_threshold NUMERIC(20, 2) CHECK (low_balance_threshold > 0),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Alerts raised by the movements that took a wallet's balance below its threshold
+CREATE TABLE wallet_alerts (
+    id UUID PRIMARY KEY,
+    wallet_id UUID NOT NULL REFERENCES wallets(id),
+    alert_type TEXT NOT NULL CHECK (alert_type IN ('low_balance')),
+    journal_id UUID NOT NULL REFERENCES journals(id),
+    threshold NUMERIC(20, 2) NOT NULL,
+    balance NUMERIC(20, 2) NOT NULL,
+    currency CHAR(3) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_wallet_alerts_wallet ON wallet_alerts(wallet_id, created_at DESC);
+
+-- Low balance alerts now follow each wallet's own threshold
+ALTER TABLE notification_preferences DROP COLUMN low_balance_threshold;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE notification_preferences ADD COLUMN low_balance_threshold NUMERIC(20, 2) CHECK (low_balance_threshold > 0);
+DROP TABLE wallet_alerts;
+DROP TABLE wallet_alert_settings;
+
+-- +goose StatementEnd
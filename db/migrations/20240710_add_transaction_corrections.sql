@@ -0,0 +1,48 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- A correction is an operator's reversal of an erroneous journal. It points back at the
+-- journal like a reversal, so a journal is reversed or corrected at most once, and
+-- records why the journal was wrong.
+ALTER TABLE journals ADD COLUMN correction_reason TEXT
+    CHECK (correction_reason IN ('duplicate', 'wrong_amount', 'wrong_recipient', 'fraud', 'system_error'));
+ALTER TABLE journals ADD CONSTRAINT journals_correction_reason_check
+    CHECK ((type = 'correction') = (correction_reason IS NOT NULL));
+
+ALTER TABLE journals DROP CONSTRAINT journals_type_check;
+ALTER TABLE journals ADD CONSTRAINT journals_type_check
+    CHECK (type IN ('deposit', 'withdraw', 'transfer', 'adjustment', 'reversal', 'correction'));
+
+-- Mistakes are corrected with new journals, so the ledger is append-only
+CREATE FUNCTION ledger_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION '% is append-only', TG_TABLE_NAME;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER journals_append_only
+    BEFORE UPDATE OR DELETE ON journals
+    FOR EACH ROW EXECUTE FUNCTION ledger_append_only();
+
+CREATE TRIGGER ledger_entries_append_only
+    BEFORE UPDATE OR DELETE ON ledger_entries
+    FOR EACH ROW EXECUTE FUNCTION ledger_append_only();
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TRIGGER ledger_entries_append_only ON ledger_entries;
+DROP TRIGGER journals_append_only ON journals;
+DROP FUNCTION ledger_append_only();
+
+-- Fails while correction journals exist, as they cannot be reclassified
+ALTER TABLE journals DROP CONSTRAINT journals_type_check;
+ALTER TABLE journals ADD CONSTRAINT journals_type_check
+    CHECK (type IN ('deposit', 'withdraw', 'transfer', 'adjustment', 'reversal'));
+
+ALTER TABLE journals DROP CONSTRAINT journals_correction_reason_check;
+ALTER TABLE journals DROP COLUMN correction_reason;
+
+-- +goose StatementEnd
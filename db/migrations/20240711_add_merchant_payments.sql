@@ -0,0 +1,55 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Wallets registered by an operator to take payments for orders, under the name
+-- their payers see
+CREATE TABLE merchant_accounts (
+    wallet_id UUID PRIMARY KEY REFERENCES wallets(id),
+    name TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- A payment for one of a merchant's orders, posted as journal_id. Refunds pay part or
+-- all of it back; refunded_amount is their total.
+CREATE TABLE payments (
+    id UUID PRIMARY KEY,
+    payer_wallet_id UUID NOT NULL REFERENCES wallets(id),
+    merchant_wallet_id UUID NOT NULL REFERENCES merchant_accounts(wallet_id),
+    order_reference TEXT NOT NULL,
+    amount NUMERIC(20, 2) NOT NULL CHECK (amount > 0),
+    refunded_amount NUMERIC(20, 2) NOT NULL DEFAULT 0 CHECK (refunded_amount >= 0 AND refunded_amount <= amount),
+    currency CHAR(3) NOT NULL,
+    status TEXT NOT NULL DEFAULT 'completed' CHECK (status IN ('completed', 'partially_refunded', 'refunded')),
+    journal_id UUID NOT NULL REFERENCES journals(id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    -- An order is paid once
+    UNIQUE (merchant_wallet_id, order_reference),
+    CHECK (payer_wallet_id <> merchant_wallet_id)
+);
+
+CREATE INDEX idx_payments_merchant_created ON payments(merchant_wallet_id, created_at);
+
+-- Money a merchant paid back against a payment, posted as journal_id
+CREATE TABLE payment_refunds (
+    id UUID PRIMARY KEY,
+    payment_id UUID NOT NULL REFERENCES payments(id),
+    amount NUMERIC(20, 2) NOT NULL CHECK (amount > 0),
+    currency CHAR(3) NOT NULL,
+    reason TEXT,
+    journal_id UUID NOT NULL REFERENCES journals(id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_payment_refunds_payment ON payment_refunds(payment_id, created_at);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE payment_refunds;
+DROP TABLE payments;
+DROP TABLE merchant_accounts;
+
+-- +goose StatementEnd
@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Clients can file a deposit, withdrawal or transfer under a category and free-form
+-- tags. Both are set when the journal is posted, as the ledger is append-only.
+ALTER TABLE journals ADD COLUMN category VARCHAR(50);
+
+CREATE TABLE journal_tags (
+    journal_id UUID NOT NULL REFERENCES journals(id),
+    tag VARCHAR(50) NOT NULL,
+    PRIMARY KEY (journal_id, tag)
+);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE journal_tags;
+ALTER TABLE journals DROP COLUMN category;
+
+-- +goose StatementEnd
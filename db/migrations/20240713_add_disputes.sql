@@ -0,0 +1,48 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- A wallet owner's dispute of a transfer they sent, transaction_id being their leg of
+-- it. While the dispute is open what the recipient was credited, amount, is held in
+-- their wallet. An operator resolves it by refunding the transfer, posted as
+-- reversal_journal_id, or rejecting it. A transaction is disputed at most once.
+CREATE TABLE disputes (
+    id UUID PRIMARY KEY,
+    transaction_id UUID NOT NULL UNIQUE REFERENCES ledger_entries(id),
+    journal_id UUID NOT NULL REFERENCES journals(id),
+    wallet_id UUID NOT NULL REFERENCES wallets(id),
+    counterparty_wallet_id UUID NOT NULL REFERENCES wallets(id),
+    amount NUMERIC(20, 2) NOT NULL CHECK (amount > 0),
+    currency CHAR(3) NOT NULL,
+    reason TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'refunded', 'rejected')),
+    reversal_journal_id UUID REFERENCES journals(id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_disputes_status_created ON disputes(status, created_at);
+
+-- Every status a dispute entered, when and with the note given
+CREATE TABLE dispute_events (
+    id UUID PRIMARY KEY,
+    dispute_id UUID NOT NULL REFERENCES disputes(id),
+    status TEXT NOT NULL CHECK (status IN ('open', 'refunded', 'rejected')),
+    note TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_dispute_events_dispute ON dispute_events(dispute_id, created_at);
+
+-- The hold securing an open dispute, which only resolving the dispute frees
+ALTER TABLE holds ADD COLUMN dispute_id UUID REFERENCES disputes(id);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE holds DROP COLUMN dispute_id;
+DROP TABLE dispute_events;
+DROP TABLE disputes;
+
+-- +goose StatementEnd
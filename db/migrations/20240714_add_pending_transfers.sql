@@ -0,0 +1,34 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- A transfer over the confirmation threshold, waiting for its sender to confirm it or
+-- an operator to approve it before any money moves. Once it runs it is completed, as
+-- transfer_journal_id, or failed; unconfirmed by expires_at it is cancelled. reason
+-- says why it failed or was cancelled.
+CREATE TABLE pending_transfers (
+    id UUID PRIMARY KEY,
+    from_wallet_id UUID NOT NULL REFERENCES wallets(id),
+    to_wallet_id UUID NOT NULL REFERENCES wallets(id),
+    amount NUMERIC(20, 2) NOT NULL CHECK (amount > 0),
+    currency CHAR(3) NOT NULL,
+    description TEXT,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'completed', 'failed', 'cancelled')),
+    reason TEXT,
+    transfer_journal_id UUID REFERENCES journals(id),
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CHECK (from_wallet_id <> to_wallet_id)
+);
+
+CREATE INDEX idx_pending_transfers_from_wallet ON pending_transfers(from_wallet_id, created_at);
+CREATE INDEX idx_pending_transfers_status_expires ON pending_transfers(status, expires_at);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE pending_transfers;
+
+-- +goose StatementEnd
@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- How far each export stream has shipped its rows to object storage: the ID of the
+-- last row exported and how many batches and rows went before. IDs are time-ordered,
+-- so the next batch starts after last_id.
+CREATE TABLE export_checkpoints (
+    stream VARCHAR(50) PRIMARY KEY,
+    last_id UUID NOT NULL,
+    batches BIGINT NOT NULL DEFAULT 0,
+    exported_rows BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE export_checkpoints;
+
+-- +goose StatementEnd
@@ -0,0 +1,45 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Whether each wallet takes incoming transfers only once its owner accepts them. A
+-- wallet without a row takes them straight away.
+CREATE TABLE incoming_transfer_settings (
+    wallet_id UUID PRIMARY KEY REFERENCES wallets(id),
+    require_acceptance BOOLEAN NOT NULL DEFAULT false,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- A transfer, journal_id, into a wallet that requires acceptance. While it is pending
+-- what the recipient was credited, amount, is held in their wallet. Accepting it frees
+-- the hold; rejecting it, or leaving it pending past expires_at, returns it to the
+-- sender as return_journal_id.
+CREATE TABLE incoming_transfers (
+    id UUID PRIMARY KEY,
+    journal_id UUID NOT NULL UNIQUE REFERENCES journals(id),
+    from_wallet_id UUID NOT NULL REFERENCES wallets(id),
+    to_wallet_id UUID NOT NULL REFERENCES wallets(id),
+    amount NUMERIC(20, 2) NOT NULL CHECK (amount > 0),
+    currency CHAR(3) NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'accepted', 'rejected', 'expired')),
+    return_journal_id UUID REFERENCES journals(id),
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_incoming_transfers_to_wallet ON incoming_transfers(to_wallet_id, created_at);
+CREATE INDEX idx_incoming_transfers_status_expires ON incoming_transfers(status, expires_at);
+
+-- The hold on a transfer awaiting acceptance, which only accepting or returning it frees
+ALTER TABLE holds ADD COLUMN incoming_transfer_id UUID REFERENCES incoming_transfers(id);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE holds DROP COLUMN incoming_transfer_id;
+DROP TABLE incoming_transfers;
+DROP TABLE incoming_transfer_settings;
+
+-- +goose StatementEnd
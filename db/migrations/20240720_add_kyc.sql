@@ -0,0 +1,37 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- The verification tier operators gave each user, which sets the limits on what the
+-- user's wallet may pay out. A user without a row is unverified.
+CREATE TABLE kyc_profiles (
+    user_id UUID PRIMARY KEY REFERENCES users(id),
+    tier TEXT NOT NULL DEFAULT 'unverified' CHECK (tier IN ('unverified', 'basic', 'full')),
+    note TEXT,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- The identity documents users submitted for verification. reference points at the
+-- document in the store it was uploaded to; the file itself is not kept here.
+CREATE TABLE kyc_documents (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id),
+    document_type TEXT NOT NULL CHECK (document_type IN ('passport', 'national_id', 'drivers_license', 'proof_of_address')),
+    reference TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'rejected')),
+    note TEXT,
+    submitted_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    reviewed_at TIMESTAMPTZ
+);
+
+CREATE INDEX idx_kyc_documents_user ON kyc_documents(user_id, submitted_at);
+CREATE INDEX idx_kyc_documents_status ON kyc_documents(status, submitted_at);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE kyc_documents;
+DROP TABLE kyc_profiles;
+
+-- +goose StatementEnd
@@ -0,0 +1,31 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Movements the anti-money-laundering checks found reportable: single movements over
+-- the reporting threshold, and runs of movements just below it. movement_count and
+-- total cover the run, or the one movement for a large transaction.
+CREATE TABLE aml_reports (
+    id UUID PRIMARY KEY,
+    type TEXT NOT NULL CHECK (type IN ('large_transaction', 'structuring')),
+    wallet_id UUID NOT NULL REFERENCES wallets(id),
+    journal_id UUID NOT NULL REFERENCES journals(id),
+    operation TEXT NOT NULL CHECK (operation IN ('deposit', 'withdraw', 'transfer')),
+    amount NUMERIC(20, 2) NOT NULL CHECK (amount > 0),
+    currency CHAR(3) NOT NULL,
+    movement_count INTEGER NOT NULL CHECK (movement_count > 0),
+    total NUMERIC(20, 2) NOT NULL CHECK (total > 0),
+    reason TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_aml_reports_wallet ON aml_reports(wallet_id, type, created_at DESC);
+CREATE INDEX idx_aml_reports_created ON aml_reports(created_at DESC);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE aml_reports;
+
+-- +goose StatementEnd
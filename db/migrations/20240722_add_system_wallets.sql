@@ -0,0 +1,29 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- System wallets belong to the platform rather than a customer. The treasury wallet of
+-- a currency mirrors the money held outside the platform, so deposits and withdrawals
+-- are transfers against it; the suspense wallet takes the other side of balance
+-- adjustments; fee wallets collect fees. There is at most one of each kind per
+-- currency, all owned by a reserved system user that is marked deleted so it never
+-- shows up as a customer.
+INSERT INTO users (id, name, created_at, deleted_at)
+VALUES ('00000000-0000-0000-0000-000000000001', 'System', now(), now());
+
+ALTER TABLE wallets ADD COLUMN kind VARCHAR(16) NOT NULL DEFAULT 'user'
+    CHECK (kind IN ('user', 'treasury', 'suspense', 'fees'));
+
+CREATE UNIQUE INDEX idx_wallets_system ON wallets(kind, currency) WHERE kind <> 'user';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+-- Fails once system wallets have ledger entries, which cannot be moved to other wallets
+DROP INDEX idx_wallets_system;
+DELETE FROM wallets WHERE kind <> 'user';
+ALTER TABLE wallets DROP COLUMN kind;
+DELETE FROM users WHERE id = '00000000-0000-0000-0000-000000000001';
+
+-- +goose StatementEnd
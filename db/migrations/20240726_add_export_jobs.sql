@@ -0,0 +1,30 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Exports of a wallet's transactions over [period_from, period_to), produced in the
+-- background. A ready job's file is stored under object_key.
+CREATE TABLE export_jobs (
+    id UUID PRIMARY KEY,
+    wallet_id UUID NOT NULL REFERENCES wallets(id),
+    period_from TIMESTAMPTZ NOT NULL,
+    period_to TIMESTAMPTZ NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'ready', 'failed')),
+    object_key TEXT,
+    exported_rows BIGINT NOT NULL DEFAULT 0,
+    failure_reason TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    completed_at TIMESTAMPTZ
+);
+
+-- The export worker pages through the jobs still pending
+CREATE INDEX idx_export_jobs_status ON export_jobs(status, id);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE export_jobs;
+
+-- +goose StatementEnd
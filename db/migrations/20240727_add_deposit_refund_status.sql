@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- A refund through the payment provider is recorded pending along with the wallet's
+-- debit and asked of the provider once that commits. It is completed when the provider
+-- refunds the payment, or failed when it refuses, and reversal_journal_id then credited
+-- the wallet back. Refunds without the provider are completed straight away.
+ALTER TABLE deposit_refunds
+    ADD COLUMN status TEXT NOT NULL DEFAULT 'completed' CHECK (status IN ('pending', 'completed', 'failed')),
+    ADD COLUMN failure_reason TEXT,
+    ADD COLUMN reversal_journal_id UUID REFERENCES journals(id);
+
+-- The refund worker pages through the refunds still pending
+CREATE INDEX idx_deposit_refunds_status ON deposit_refunds(status, id);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP INDEX idx_deposit_refunds_status;
+ALTER TABLE deposit_refunds
+    DROP COLUMN reversal_journal_id,
+    DROP COLUMN failure_reason,
+    DROP COLUMN status;
+
+-- +goose StatementEnd
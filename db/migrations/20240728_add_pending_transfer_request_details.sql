@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- A transfer held for confirmation keeps what its request filed it under, so the
+-- transfer it runs as is filed the same way: the category and the tags, as a JSON
+-- array. idempotency_key is the sender's scoped Idempotency-Key, so a retry of the
+-- request gets the same pending transfer; NULL for transfers held without one.
+ALTER TABLE pending_transfers
+    ADD COLUMN category VARCHAR(50),
+    ADD COLUMN tags TEXT,
+    ADD COLUMN idempotency_key VARCHAR(255);
+ALTER TABLE pending_transfers ADD CONSTRAINT uq_pending_transfers_idempotency_key UNIQUE (idempotency_key);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE pending_transfers DROP CONSTRAINT uq_pending_transfers_idempotency_key;
+ALTER TABLE pending_transfers
+    DROP COLUMN idempotency_key,
+    DROP COLUMN tags,
+    DROP COLUMN category;
+
+-- +goose StatementEnd
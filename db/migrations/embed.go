@@ -0,0 +1,29 @@
+// Package migrations embeds the goose SQL migrations so the schema is versioned
+// with the binary. PostgreSQL migrations sit at the top level and their MySQL and
+// SQLite twins under mysql/ and sqlite/, with matching version numbers.
+package migrations
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+
+	"github.com/shanwije/wallet-app/pkg/db"
+)
+
+//go:embed *.sql mysql/*.sql sqlite/*.sql
+var files embed.FS
+
+// ForDriver returns the migrations written for the database driver
+func ForDriver(driver string) (fs.FS, error) {
+	switch driver {
+	case "", db.DriverPostgres:
+		return fs.Sub(files, ".")
+	case db.DriverMySQL:
+		return fs.Sub(files, "mysql")
+	case db.DriverSQLite:
+		return fs.Sub(files, "sqlite")
+	default:
+		return nil, fmt.Errorf("unsupported database driver: %q", driver)
+	}
+}
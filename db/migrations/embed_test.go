@@ -0,0 +1,87 @@
+package migrations
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shanwije/wallet-app/pkg/db"
+)
+
+// versions loads the driver's migrations the way the server does and returns their
+// file names by version; no database connection is made
+func versions(t *testing.T, driver string) map[int64]string {
+	t.Helper()
+
+	fsys, err := ForDriver(driver)
+	require.NoError(t, err)
+
+	// Opening a pool does not connect, and loading migrations never touches it. pgx
+	// registers its database/sql driver as "pgx".
+	driverName := driver
+	if driver == db.DriverPostgres {
+		driverName = "pgx"
+	}
+	conn, err := sqlx.Open(driverName, "")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	migrator, err := db.NewMigrator(conn, driver, fsys)
+	require.NoError(t, err)
+
+	names := make(map[int64]string)
+	for _, source := range migrator.ListSources() {
+		names[source.Version] = filepath.Base(source.Path)
+	}
+	return names
+}
+
+func TestEveryMigrationHasAMySQLTwin(t *testing.T) {
+	postgres := versions(t, db.DriverPostgres)
+	mysql := versions(t, db.DriverMySQL)
+
+	assert.NotEmpty(t, postgres)
+	assert.Equal(t, postgres, mysql)
+}
+
+func TestEveryMigrationHasASQLiteTwin(t *testing.T) {
+	postgres := versions(t, db.DriverPostgres)
+	sqlite := versions(t, db.DriverSQLite)
+
+	assert.NotEmpty(t, postgres)
+	assert.Equal(t, postgres, sqlite)
+}
+
+// SQLite runs in-process, so its migrations can be applied for real: all the way up,
+// back down and up again
+func TestSQLiteMigrationsApplyAndRollBack(t *testing.T) {
+	conn, err := db.New(db.Config{Driver: db.DriverSQLite, Name: filepath.Join(t.TempDir(), "wallet.db")})
+	require.NoError(t, err)
+
+	fsys, err := ForDriver(db.DriverSQLite)
+	require.NoError(t, err)
+	migrator, err := db.NewMigrator(conn.DB, db.DriverSQLite, fsys)
+	require.NoError(t, err)
+	defer migrator.Close()
+
+	ctx := context.Background()
+	_, err = migrator.Up(ctx)
+	require.NoError(t, err)
+	_, err = migrator.DownTo(ctx, 0)
+	require.NoError(t, err)
+	_, err = migrator.Up(ctx)
+	require.NoError(t, err)
+
+	version, err := migrator.GetDBVersion(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(20240728), version)
+}
+
+func TestForDriverRejectsUnknownDriver(t *testing.T) {
+	_, err := ForDriver("oracle")
+	assert.Error(t, err)
+}
@@ -0,0 +1,39 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- UUIDs are generated by the application and stored in their canonical text form
+CREATE TABLE users (
+    id CHAR(36) PRIMARY KEY,
+    name TEXT NOT NULL,
+    created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6)
+) ENGINE=InnoDB;
+
+CREATE TABLE wallets (
+    id CHAR(36) PRIMARY KEY,
+    user_id CHAR(36) NOT NULL,
+    balance DECIMAL(20, 2) NOT NULL DEFAULT 0.00,
+    created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    CONSTRAINT fk_wallets_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+) ENGINE=InnoDB;
+
+CREATE TABLE transactions (
+    id CHAR(36) PRIMARY KEY,
+    wallet_id CHAR(36) NOT NULL,
+    type VARCHAR(32) NOT NULL CHECK (type IN ('deposit', 'withdraw', 'transfer_in', 'transfer_out')),
+    amount DECIMAL(20, 2) NOT NULL CHECK (amount > 0),
+    reference_id CHAR(36) NULL, -- for linking to related tx (e.g., the other side of a transfer)
+    description TEXT,
+    created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    CONSTRAINT fk_transactions_wallet FOREIGN KEY (wallet_id) REFERENCES wallets(id) ON DELETE CASCADE
+) ENGINE=InnoDB;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE transactions;
+DROP TABLE wallets;
+DROP TABLE users;
+
+-- +goose StatementEnd
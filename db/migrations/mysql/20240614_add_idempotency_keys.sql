@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+
+CREATE TABLE idempotency_keys (
+    request_key CHAR(64) PRIMARY KEY,
+    status_code INT NOT NULL DEFAULT 0,
+    headers JSON NOT NULL,
+    response_body MEDIUMBLOB,
+    created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    INDEX idx_idempotency_keys_created_at (created_at)
+) ENGINE=InnoDB;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE idempotency_keys;
+
+-- +goose StatementEnd
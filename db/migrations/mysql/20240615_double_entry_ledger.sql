@@ -0,0 +1,101 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- A journal is one money movement; its ledger entries are the debit and credit legs.
+-- Entries with a NULL wallet_id belong to the external settlement account.
+CREATE TABLE journals (
+    id CHAR(36) PRIMARY KEY,
+    type VARCHAR(16) NOT NULL CHECK (type IN ('deposit', 'withdraw', 'transfer')),
+    description TEXT,
+    created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6)
+) ENGINE=InnoDB;
+
+CREATE TABLE ledger_entries (
+    id CHAR(36) PRIMARY KEY,
+    journal_id CHAR(36) NOT NULL,
+    wallet_id CHAR(36) NULL,
+    direction VARCHAR(8) NOT NULL CHECK (direction IN ('debit', 'credit')),
+    amount DECIMAL(20, 2) NOT NULL CHECK (amount > 0),
+    currency CHAR(3) NOT NULL,
+    created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    INDEX idx_ledger_entries_wallet_created (wallet_id, created_at, id),
+    CONSTRAINT fk_ledger_entries_journal FOREIGN KEY (journal_id) REFERENCES journals(id),
+    CONSTRAINT fk_ledger_entries_wallet FOREIGN KEY (wallet_id) REFERENCES wallets(id)
+) ENGINE=InnoDB;
+
+-- Deposits and withdrawals become a journal each, keeping the transaction ID
+INSERT INTO journals (id, type, description, created_at)
+SELECT id, type, description, created_at
+FROM transactions
+WHERE type IN ('deposit', 'withdraw');
+
+-- Both sides of a transfer share a reference_id, which becomes the journal ID
+INSERT INTO journals (id, type, description, created_at)
+SELECT reference_id, 'transfer', MAX(description), MIN(created_at)
+FROM transactions
+WHERE type IN ('transfer_in', 'transfer_out')
+GROUP BY reference_id;
+
+-- Wallet legs keep the original transaction IDs
+INSERT INTO ledger_entries (id, journal_id, wallet_id, direction, amount, currency, created_at)
+SELECT t.id,
+       CASE WHEN t.type IN ('deposit', 'withdraw') THEN t.id ELSE t.reference_id END,
+       t.wallet_id,
+       CASE WHEN t.type IN ('deposit', 'transfer_in') THEN 'credit' ELSE 'debit' END,
+       t.amount,
+       w.currency,
+       t.created_at
+FROM transactions t
+JOIN wallets w ON w.id = t.wallet_id;
+
+-- Settlement legs balance deposits and withdrawals
+INSERT INTO ledger_entries (id, journal_id, wallet_id, direction, amount, currency, created_at)
+SELECT UUID(),
+       t.id,
+       NULL,
+       CASE WHEN t.type = 'deposit' THEN 'debit' ELSE 'credit' END,
+       t.amount,
+       w.currency,
+       t.created_at
+FROM transactions t
+JOIN wallets w ON w.id = t.wallet_id
+WHERE t.type IN ('deposit', 'withdraw');
+
+DROP TABLE transactions;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+CREATE TABLE transactions (
+    id CHAR(36) PRIMARY KEY,
+    wallet_id CHAR(36) NOT NULL,
+    type VARCHAR(32) NOT NULL CHECK (type IN ('deposit', 'withdraw', 'transfer_in', 'transfer_out')),
+    amount DECIMAL(20, 2) NOT NULL CHECK (amount > 0),
+    reference_id CHAR(36) NULL, -- for linking to related tx (e.g., the other side of a transfer)
+    description TEXT,
+    created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    CONSTRAINT fk_transactions_wallet FOREIGN KEY (wallet_id) REFERENCES wallets(id) ON DELETE CASCADE
+) ENGINE=InnoDB;
+
+INSERT INTO transactions (id, wallet_id, type, amount, reference_id, description, created_at)
+SELECT e.id,
+       e.wallet_id,
+       CASE
+           WHEN j.type <> 'transfer' THEN j.type
+           WHEN e.direction = 'credit' THEN 'transfer_in'
+           ELSE 'transfer_out'
+       END,
+       e.amount,
+       CASE WHEN j.type = 'transfer' THEN j.id END,
+       j.description,
+       e.created_at
+FROM ledger_entries e
+JOIN journals j ON j.id = e.journal_id
+WHERE e.wallet_id IS NOT NULL;
+
+DROP TABLE ledger_entries;
+DROP TABLE journals;
+
+-- +goose StatementEnd
@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Scoped client keys for deposits, withdrawals and transfers; NULL for journals recorded without one
+ALTER TABLE journals
+    ADD COLUMN idempotency_key VARCHAR(255) NULL,
+    ADD UNIQUE KEY uq_journals_idempotency_key (idempotency_key);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE journals
+    DROP INDEX uq_journals_idempotency_key,
+    DROP COLUMN idempotency_key;
+
+-- +goose StatementEnd
@@ -0,0 +1,34 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- A transfer to run at start_at and, unless frequency is 'once', repeatedly after it.
+-- occurrence counts the runs already processed; next_run_at is when the next one is due.
+CREATE TABLE scheduled_transfers (
+    id CHAR(36) PRIMARY KEY,
+    from_wallet_id CHAR(36) NOT NULL,
+    to_wallet_id CHAR(36) NOT NULL,
+    amount DECIMAL(20, 2) NOT NULL CHECK (amount > 0),
+    currency CHAR(3) NOT NULL,
+    description TEXT,
+    frequency VARCHAR(16) NOT NULL CHECK (frequency IN ('once', 'daily', 'weekly', 'monthly')),
+    start_at DATETIME(6) NOT NULL,
+    next_run_at DATETIME(6) NOT NULL,
+    occurrence INT NOT NULL DEFAULT 0,
+    status VARCHAR(16) NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'completed', 'failed', 'cancelled')),
+    last_error TEXT,
+    created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    updated_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    INDEX idx_scheduled_transfers_from_wallet (from_wallet_id),
+    INDEX idx_scheduled_transfers_due (status, next_run_at),
+    CONSTRAINT fk_scheduled_transfers_from FOREIGN KEY (from_wallet_id) REFERENCES wallets(id),
+    CONSTRAINT fk_scheduled_transfers_to FOREIGN KEY (to_wallet_id) REFERENCES wallets(id)
+) ENGINE=InnoDB;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE scheduled_transfers;
+
+-- +goose StatementEnd
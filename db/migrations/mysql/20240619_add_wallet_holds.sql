@@ -0,0 +1,35 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- held_balance is the part of balance reserved by active holds; the available
+-- balance that withdrawals and transfers may spend is balance - held_balance.
+ALTER TABLE wallets ADD COLUMN held_balance DECIMAL(20, 2) NOT NULL DEFAULT 0.00
+    CHECK (held_balance >= 0);
+
+-- A reservation of funds that is later captured (posted to the ledger) or released.
+-- capture_journal_id is the withdrawal journal a captured hold was posted as.
+CREATE TABLE holds (
+    id CHAR(36) PRIMARY KEY,
+    wallet_id CHAR(36) NOT NULL,
+    amount DECIMAL(20, 2) NOT NULL CHECK (amount > 0),
+    currency CHAR(3) NOT NULL,
+    description TEXT,
+    status VARCHAR(16) NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'captured', 'released')),
+    captured_amount DECIMAL(20, 2) CHECK (captured_amount > 0),
+    capture_journal_id CHAR(36),
+    created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    updated_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    INDEX idx_holds_wallet_id (wallet_id),
+    CONSTRAINT fk_holds_wallet FOREIGN KEY (wallet_id) REFERENCES wallets(id),
+    CONSTRAINT fk_holds_capture_journal FOREIGN KEY (capture_journal_id) REFERENCES journals(id)
+) ENGINE=InnoDB;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE holds;
+ALTER TABLE wallets DROP COLUMN held_balance;
+
+-- +goose StatementEnd
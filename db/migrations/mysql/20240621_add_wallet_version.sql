@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Bumped by every wallet update so optimistic writers can detect lost updates
+ALTER TABLE wallets ADD COLUMN version BIGINT NOT NULL DEFAULT 0;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE wallets DROP COLUMN version;
+
+-- +goose StatementEnd
@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Adjustments are operator corrections posted against the settlement account.
+-- journals_chk_1 is the name MySQL generated for the type check.
+ALTER TABLE journals DROP CHECK journals_chk_1;
+ALTER TABLE journals ADD CONSTRAINT journals_type_check
+    CHECK (type IN ('deposit', 'withdraw', 'transfer', 'adjustment'));
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+-- Fails while adjustment journals exist, as they cannot be reclassified
+ALTER TABLE journals DROP CHECK journals_type_check;
+ALTER TABLE journals ADD CONSTRAINT journals_chk_1
+    CHECK (type IN ('deposit', 'withdraw', 'transfer'));
+
+-- +goose StatementEnd
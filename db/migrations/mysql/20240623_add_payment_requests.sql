@@ -0,0 +1,31 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- A request for the payer wallet to pay the requester wallet. Accepting it runs the
+-- transfer; transfer_journal_id is the journal the payment was posted as.
+CREATE TABLE payment_requests (
+    id CHAR(36) PRIMARY KEY,
+    requester_wallet_id CHAR(36) NOT NULL,
+    payer_wallet_id CHAR(36) NOT NULL,
+    amount DECIMAL(20, 2) NOT NULL CHECK (amount > 0),
+    currency CHAR(3) NOT NULL,
+    description TEXT,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'accepted', 'declined')),
+    transfer_journal_id CHAR(36),
+    created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    updated_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    CHECK (requester_wallet_id <> payer_wallet_id),
+    INDEX idx_payment_requests_payer (payer_wallet_id, status),
+    CONSTRAINT fk_payment_requests_requester FOREIGN KEY (requester_wallet_id) REFERENCES wallets(id),
+    CONSTRAINT fk_payment_requests_payer FOREIGN KEY (payer_wallet_id) REFERENCES wallets(id),
+    CONSTRAINT fk_payment_requests_journal FOREIGN KEY (transfer_journal_id) REFERENCES journals(id)
+) ENGINE=InnoDB;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE payment_requests;
+
+-- +goose StatementEnd
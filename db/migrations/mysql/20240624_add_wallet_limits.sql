@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Per-wallet caps in the wallet's currency; a NULL cap is not enforced. The daily caps
+-- are checked against the wallet's ledger entries from the last 24 hours.
+CREATE TABLE wallet_limits (
+    wallet_id CHAR(36) PRIMARY KEY,
+    max_transaction_amount DECIMAL(20, 2) CHECK (max_transaction_amount > 0),
+    daily_withdrawal_limit DECIMAL(20, 2) CHECK (daily_withdrawal_limit > 0),
+    daily_transfer_limit DECIMAL(20, 2) CHECK (daily_transfer_limit > 0),
+    updated_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    CONSTRAINT fk_wallet_limits_wallet FOREIGN KEY (wallet_id) REFERENCES wallets(id)
+) ENGINE=InnoDB;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE wallet_limits;
+
+-- +goose StatementEnd
@@ -0,0 +1,29 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Withdrawals and transfers the risk checks flagged or blocked, kept for review.
+-- reasons lists the rules that fired.
+CREATE TABLE risk_decisions (
+    id CHAR(36) PRIMARY KEY,
+    wallet_id CHAR(36) NOT NULL,
+    operation VARCHAR(16) NOT NULL CHECK (operation IN ('withdraw', 'transfer')),
+    counterparty_wallet_id CHAR(36),
+    amount DECIMAL(20, 2) NOT NULL CHECK (amount > 0),
+    currency CHAR(3) NOT NULL,
+    action VARCHAR(16) NOT NULL CHECK (action IN ('flag', 'block')),
+    reasons TEXT NOT NULL,
+    created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    INDEX idx_risk_decisions_wallet (wallet_id, created_at DESC),
+    INDEX idx_risk_decisions_created (created_at DESC),
+    CONSTRAINT fk_risk_decisions_wallet FOREIGN KEY (wallet_id) REFERENCES wallets(id),
+    CONSTRAINT fk_risk_decisions_counterparty FOREIGN KEY (counterparty_wallet_id) REFERENCES wallets(id)
+) ENGINE=InnoDB;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE risk_decisions;
+
+-- +goose StatementEnd
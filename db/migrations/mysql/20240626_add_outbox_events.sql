@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Transactional outbox: wallet events are inserted in the same transaction as the
+-- journal they describe and published by a background dispatcher.
+CREATE TABLE outbox_events (
+    id CHAR(36) PRIMARY KEY,
+    event_type VARCHAR(64) NOT NULL,
+    wallet_id CHAR(36) NOT NULL,
+    payload JSON NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT,
+    created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    published_at DATETIME(6),
+    INDEX idx_outbox_events_unpublished (published_at, created_at)
+) ENGINE=InnoDB;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE outbox_events;
+
+-- +goose StatementEnd
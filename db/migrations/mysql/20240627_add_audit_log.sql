@@ -0,0 +1,41 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Append-only record of every mutating API call. status_code is the HTTP status, or
+-- the gRPC code for calls over gRPC.
+CREATE TABLE audit_log (
+    id CHAR(36) PRIMARY KEY,
+    actor_type VARCHAR(16) NOT NULL CHECK (actor_type IN ('user', 'admin', 'anonymous')),
+    actor_id VARCHAR(64) NOT NULL,
+    endpoint VARCHAR(255) NOT NULL,
+    path VARCHAR(255) NOT NULL,
+    payload_hash CHAR(64) NOT NULL,
+    status_code INT NOT NULL,
+    request_id VARCHAR(64) NOT NULL DEFAULT '',
+    wallet_id CHAR(36),
+    balance_before DECIMAL(20, 2),
+    balance_after DECIMAL(20, 2),
+    created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    INDEX idx_audit_log_created (created_at DESC),
+    INDEX idx_audit_log_actor (actor_id, created_at DESC),
+    INDEX idx_audit_log_wallet (wallet_id, created_at DESC)
+) ENGINE=InnoDB;
+
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER audit_log_no_update BEFORE UPDATE ON audit_log
+FOR EACH ROW SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'audit_log is append-only';
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER audit_log_no_delete BEFORE DELETE ON audit_log
+FOR EACH ROW SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'audit_log is append-only';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE audit_log;
+
+-- +goose StatementEnd
@@ -0,0 +1,30 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- A reversal posts the legs of an earlier journal in the opposite direction and points
+-- back at it; the unique key lets each journal be reversed at most once
+ALTER TABLE journals
+    ADD COLUMN reverses_journal_id CHAR(36) NULL,
+    ADD UNIQUE KEY uq_journals_reverses_journal_id (reverses_journal_id),
+    ADD CONSTRAINT fk_journals_reverses_journal FOREIGN KEY (reverses_journal_id) REFERENCES journals(id);
+
+ALTER TABLE journals DROP CHECK journals_type_check;
+ALTER TABLE journals ADD CONSTRAINT journals_type_check
+    CHECK (type IN ('deposit', 'withdraw', 'transfer', 'adjustment', 'reversal'));
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+-- Fails while reversal journals exist, as they cannot be reclassified
+ALTER TABLE journals DROP CHECK journals_type_check;
+ALTER TABLE journals ADD CONSTRAINT journals_type_check
+    CHECK (type IN ('deposit', 'withdraw', 'transfer', 'adjustment'));
+
+ALTER TABLE journals
+    DROP FOREIGN KEY fk_journals_reverses_journal,
+    DROP INDEX uq_journals_reverses_journal_id,
+    DROP COLUMN reverses_journal_id;
+
+-- +goose StatementEnd
@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- End-of-day wallet balances: balance is the sum of the wallet's ledger entries created
+-- before as_of, a midnight UTC. Historical balances start from the latest snapshot and
+-- add the entries since, instead of summing the wallet's whole ledger.
+CREATE TABLE balance_snapshots (
+    wallet_id CHAR(36) NOT NULL,
+    as_of DATETIME(6) NOT NULL,
+    balance DECIMAL(20, 2) NOT NULL,
+    currency CHAR(3) NOT NULL,
+    created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    PRIMARY KEY (wallet_id, as_of),
+    INDEX idx_balance_snapshots_as_of (as_of),
+    CONSTRAINT fk_balance_snapshots_wallet FOREIGN KEY (wallet_id) REFERENCES wallets(id)
+) ENGINE=InnoDB;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE balance_snapshots;
+
+-- +goose StatementEnd
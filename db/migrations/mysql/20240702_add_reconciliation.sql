@@ -0,0 +1,40 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Runs of the reconciliation job, which compares every wallet's stored balance with the
+-- sum of its ledger entries, and the wallets each run found disagreeing. difference is
+-- balance - ledger_balance.
+CREATE TABLE reconciliation_runs (
+    id CHAR(36) PRIMARY KEY,
+    started_at DATETIME(6) NOT NULL,
+    finished_at DATETIME(6) NOT NULL,
+    wallets_checked BIGINT NOT NULL,
+    discrepancy_count INT NOT NULL,
+    INDEX idx_reconciliation_runs_started (started_at DESC)
+) ENGINE=InnoDB;
+
+CREATE TABLE reconciliation_discrepancies (
+    id CHAR(36) PRIMARY KEY,
+    run_id CHAR(36) NOT NULL,
+    wallet_id CHAR(36) NOT NULL,
+    balance DECIMAL(20, 2) NOT NULL,
+    ledger_balance DECIMAL(20, 2) NOT NULL,
+    difference DECIMAL(20, 2) NOT NULL,
+    currency CHAR(3) NOT NULL,
+    created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    UNIQUE KEY uq_reconciliation_discrepancies_run_wallet (run_id, wallet_id),
+    INDEX idx_reconciliation_discrepancies_wallet (wallet_id, created_at DESC),
+    INDEX idx_reconciliation_discrepancies_created (created_at DESC),
+    CONSTRAINT fk_reconciliation_discrepancies_run FOREIGN KEY (run_id) REFERENCES reconciliation_runs(id),
+    CONSTRAINT fk_reconciliation_discrepancies_wallet FOREIGN KEY (wallet_id) REFERENCES wallets(id)
+) ENGINE=InnoDB;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE reconciliation_discrepancies;
+DROP TABLE reconciliation_runs;
+
+-- +goose StatementEnd
@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- The lowest a wallet's balance may be taken by withdrawals, transfers and holds: an
+-- overdraft lets it go that far below zero, a minimum balance keeps that much in it.
+-- A wallet has one or the other.
+ALTER TABLE wallet_limits
+    ADD COLUMN overdraft_limit DECIMAL(20, 2) CHECK (overdraft_limit > 0),
+    ADD COLUMN minimum_balance DECIMAL(20, 2) CHECK (minimum_balance > 0),
+    ADD CONSTRAINT wallet_limits_one_floor_check CHECK (overdraft_limit IS NULL OR minimum_balance IS NULL);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE wallet_limits
+    DROP CHECK wallet_limits_one_floor_check,
+    DROP COLUMN minimum_balance,
+    DROP COLUMN overdraft_limit;
+
+-- +goose StatementEnd
@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Indexes behind the admin wallet search. Each ends with the column the search sorts
+-- by and id, its tiebreaker, so a cursor page starts with a range scan right after the
+-- last wallet of the previous one. Searches filtered by status or currency keep to the
+-- wallets with that value; the rest walk the sort order. balance changes with every
+-- money movement, and idx_wallets_balance is updated with it.
+ALTER TABLE wallets
+    ADD INDEX idx_wallets_created (created_at, id),
+    ADD INDEX idx_wallets_balance (balance, id),
+    ADD INDEX idx_wallets_status_created (status, created_at, id),
+    ADD INDEX idx_wallets_currency_created (currency, created_at, id);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE wallets
+    DROP INDEX idx_wallets_currency_created,
+    DROP INDEX idx_wallets_status_created,
+    DROP INDEX idx_wallets_balance,
+    DROP INDEX idx_wallets_created;
+
+-- +goose StatementEnd
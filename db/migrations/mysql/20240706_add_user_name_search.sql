@@ -0,0 +1,9 @@
+-- +goose Up
+
+-- User search matches any part of a name with LIKE, which ignores case under the
+-- default collation. A b-tree cannot serve a match in the middle of a name, and an
+-- ngram FULLTEXT index drops every token containing a stopword such as "a" unless the
+-- server's stopwords are turned off, so searches scan the users. This keeps the
+-- versions in step with PostgreSQL, whose trigram index does serve them.
+
+-- +goose Down
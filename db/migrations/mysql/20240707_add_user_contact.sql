@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Users may give an email address and a phone number, each belonging to at most one
+-- user. Deleting a user clears them so they can be used again. Emails are stored
+-- lowercased and phone numbers in E.164, so the unique indexes compare them as the
+-- service does. version guards profile updates against lost writes.
+ALTER TABLE users
+    ADD COLUMN email VARCHAR(254) NULL,
+    ADD COLUMN phone VARCHAR(16) NULL,
+    ADD COLUMN version BIGINT NOT NULL DEFAULT 0,
+    ADD UNIQUE INDEX uq_users_email (email),
+    ADD UNIQUE INDEX uq_users_phone (phone);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE users
+    DROP INDEX uq_users_phone,
+    DROP INDEX uq_users_email,
+    DROP COLUMN version,
+    DROP COLUMN phone,
+    DROP COLUMN email;
+
+-- +goose StatementEnd
@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Which transaction alerts each user is emailed, in their wallet's currency. A NULL
+-- threshold turns its alert off. Users without a row get the configured defaults.
+CREATE TABLE notification_preferences (
+    user_id CHAR(36) PRIMARY KEY,
+    large_withdrawal_threshold DECIMAL(20, 2) CHECK (large_withdrawal_threshold > 0),
+    incoming_transfer BOOLEAN NOT NULL,
+    low_balance_threshold DECIMAL(20, 2) CHECK (low_balance_threshold > 0),
+    updated_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    CONSTRAINT fk_notification_preferences_user FOREIGN KEY (user_id) REFERENCES users(id)
+) ENGINE=InnoDB;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE notification_preferences;
+
+-- +goose StatementEnd
@@ -0,0 +1,40 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- The balance each wallet's owner wants to hear about falling below, in the wallet's
+-- currency. A NULL threshold sends no low balance alerts.
+CREATE TABLE wallet_alert_settings (
+    wallet_id CHAR(36) PRIMARY KEY,
+    low_balance_threshold DECIMAL(20, 2) CHECK (low_balance_threshold > 0),
+    updated_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    CONSTRAINT fk_wallet_alert_settings_wallet FOREIGN KEY (wallet_id) REFERENCES wallets(id)
+) ENGINE=InnoDB;
+
+-- Alerts raised by the movements that took a wallet's balance below its threshold
+CREATE TABLE wallet_alerts (
+    id CHAR(36) PRIMARY KEY,
+    wallet_id CHAR(36) NOT NULL,
+    alert_type VARCHAR(32) NOT NULL CHECK (alert_type IN ('low_balance')),
+    journal_id CHAR(36) NOT NULL,
+    threshold DECIMAL(20, 2) NOT NULL,
+    balance DECIMAL(20, 2) NOT NULL,
+    currency CHAR(3) NOT NULL,
+    created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    INDEX idx_wallet_alerts_wallet (wallet_id, created_at DESC),
+    CONSTRAINT fk_wallet_alerts_wallet FOREIGN KEY (wallet_id) REFERENCES wallets(id),
+    CONSTRAINT fk_wallet_alerts_journal FOREIGN KEY (journal_id) REFERENCES journals(id)
+) ENGINE=InnoDB;
+
+-- Low balance alerts now follow each wallet's own threshold
+ALTER TABLE notification_preferences DROP COLUMN low_balance_threshold;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE notification_preferences ADD COLUMN low_balance_threshold DECIMAL(20, 2) CHECK (low_balance_threshold > 0);
+DROP TABLE wallet_alerts;
+DROP TABLE wallet_alert_settings;
+
+-- +goose StatementEnd
@@ -0,0 +1,60 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- A correction is an operator's reversal of an erroneous journal. It points back at the
+-- journal like a reversal, so a journal is reversed or corrected at most once, and
+-- records why the journal was wrong.
+ALTER TABLE journals
+    ADD COLUMN correction_reason VARCHAR(32) NULL,
+    ADD CONSTRAINT journals_correction_reason_values_check
+        CHECK (correction_reason IN ('duplicate', 'wrong_amount', 'wrong_recipient', 'fraud', 'system_error')),
+    ADD CONSTRAINT journals_correction_reason_check
+        CHECK ((type = 'correction') = (correction_reason IS NOT NULL));
+
+ALTER TABLE journals DROP CHECK journals_type_check;
+ALTER TABLE journals ADD CONSTRAINT journals_type_check
+    CHECK (type IN ('deposit', 'withdraw', 'transfer', 'adjustment', 'reversal', 'correction'));
+
+-- +goose StatementEnd
+
+-- Mistakes are corrected with new journals, so the ledger is append-only
+
+-- +goose StatementBegin
+CREATE TRIGGER journals_no_update BEFORE UPDATE ON journals
+FOR EACH ROW SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'journals is append-only';
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER journals_no_delete BEFORE DELETE ON journals
+FOR EACH ROW SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'journals is append-only';
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER ledger_entries_no_update BEFORE UPDATE ON ledger_entries
+FOR EACH ROW SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'ledger_entries is append-only';
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER ledger_entries_no_delete BEFORE DELETE ON ledger_entries
+FOR EACH ROW SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'ledger_entries is append-only';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TRIGGER ledger_entries_no_delete;
+DROP TRIGGER ledger_entries_no_update;
+DROP TRIGGER journals_no_delete;
+DROP TRIGGER journals_no_update;
+
+-- Fails while correction journals exist, as they cannot be reclassified
+ALTER TABLE journals DROP CHECK journals_type_check;
+ALTER TABLE journals ADD CONSTRAINT journals_type_check
+    CHECK (type IN ('deposit', 'withdraw', 'transfer', 'adjustment', 'reversal'));
+
+ALTER TABLE journals
+    DROP CHECK journals_correction_reason_check,
+    DROP CHECK journals_correction_reason_values_check,
+    DROP COLUMN correction_reason;
+
+-- +goose StatementEnd
@@ -0,0 +1,59 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Wallets registered by an operator to take payments for orders, under the name
+-- their payers see
+CREATE TABLE merchant_accounts (
+    wallet_id CHAR(36) PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    CONSTRAINT fk_merchant_accounts_wallet FOREIGN KEY (wallet_id) REFERENCES wallets(id)
+) ENGINE=InnoDB;
+
+-- A payment for one of a merchant's orders, posted as journal_id. Refunds pay part or
+-- all of it back; refunded_amount is their total.
+CREATE TABLE payments (
+    id CHAR(36) PRIMARY KEY,
+    payer_wallet_id CHAR(36) NOT NULL,
+    merchant_wallet_id CHAR(36) NOT NULL,
+    order_reference VARCHAR(255) NOT NULL,
+    amount DECIMAL(20, 2) NOT NULL CHECK (amount > 0),
+    refunded_amount DECIMAL(20, 2) NOT NULL DEFAULT 0 CHECK (refunded_amount >= 0 AND refunded_amount <= amount),
+    currency CHAR(3) NOT NULL,
+    status VARCHAR(32) NOT NULL DEFAULT 'completed' CHECK (status IN ('completed', 'partially_refunded', 'refunded')),
+    journal_id CHAR(36) NOT NULL,
+    created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    updated_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    -- An order is paid once
+    UNIQUE KEY uq_payments_order (merchant_wallet_id, order_reference),
+    CHECK (payer_wallet_id <> merchant_wallet_id),
+    INDEX idx_payments_merchant_created (merchant_wallet_id, created_at),
+    CONSTRAINT fk_payments_payer FOREIGN KEY (payer_wallet_id) REFERENCES wallets(id),
+    CONSTRAINT fk_payments_merchant FOREIGN KEY (merchant_wallet_id) REFERENCES merchant_accounts(wallet_id),
+    CONSTRAINT fk_payments_journal FOREIGN KEY (journal_id) REFERENCES journals(id)
+) ENGINE=InnoDB;
+
+-- Money a merchant paid back against a payment, posted as journal_id
+CREATE TABLE payment_refunds (
+    id CHAR(36) PRIMARY KEY,
+    payment_id CHAR(36) NOT NULL,
+    amount DECIMAL(20, 2) NOT NULL CHECK (amount > 0),
+    currency CHAR(3) NOT NULL,
+    reason TEXT,
+    journal_id CHAR(36) NOT NULL,
+    created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    INDEX idx_payment_refunds_payment (payment_id, created_at),
+    CONSTRAINT fk_payment_refunds_payment FOREIGN KEY (payment_id) REFERENCES payments(id),
+    CONSTRAINT fk_payment_refunds_journal FOREIGN KEY (journal_id) REFERENCES journals(id)
+) ENGINE=InnoDB;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE payment_refunds;
+DROP TABLE payments;
+DROP TABLE merchant_accounts;
+
+-- +goose StatementEnd
@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Clients can file a deposit, withdrawal or transfer under a category and free-form
+-- tags. Both are set when the journal is posted, as the ledger is append-only.
+ALTER TABLE journals ADD COLUMN category VARCHAR(50) NULL;
+
+CREATE TABLE journal_tags (
+    journal_id CHAR(36) NOT NULL,
+    tag VARCHAR(50) NOT NULL,
+    PRIMARY KEY (journal_id, tag),
+    CONSTRAINT fk_journal_tags_journal FOREIGN KEY (journal_id) REFERENCES journals(id)
+) ENGINE=InnoDB;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE journal_tags;
+ALTER TABLE journals DROP COLUMN category;
+
+-- +goose StatementEnd
@@ -0,0 +1,57 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- A wallet owner's dispute of a transfer they sent, transaction_id being their leg of
+-- it. While the dispute is open what the recipient was credited, amount, is held in
+-- their wallet. An operator resolves it by refunding the transfer, posted as
+-- reversal_journal_id, or rejecting it. A transaction is disputed at most once.
+CREATE TABLE disputes (
+    id CHAR(36) PRIMARY KEY,
+    transaction_id CHAR(36) NOT NULL,
+    journal_id CHAR(36) NOT NULL,
+    wallet_id CHAR(36) NOT NULL,
+    counterparty_wallet_id CHAR(36) NOT NULL,
+    amount DECIMAL(20, 2) NOT NULL CHECK (amount > 0),
+    currency CHAR(3) NOT NULL,
+    reason TEXT NOT NULL,
+    status VARCHAR(32) NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'refunded', 'rejected')),
+    reversal_journal_id CHAR(36) NULL,
+    created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    updated_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    UNIQUE KEY uq_disputes_transaction (transaction_id),
+    INDEX idx_disputes_status_created (status, created_at),
+    CONSTRAINT fk_disputes_transaction FOREIGN KEY (transaction_id) REFERENCES ledger_entries(id),
+    CONSTRAINT fk_disputes_journal FOREIGN KEY (journal_id) REFERENCES journals(id),
+    CONSTRAINT fk_disputes_wallet FOREIGN KEY (wallet_id) REFERENCES wallets(id),
+    CONSTRAINT fk_disputes_counterparty_wallet FOREIGN KEY (counterparty_wallet_id) REFERENCES wallets(id),
+    CONSTRAINT fk_disputes_reversal_journal FOREIGN KEY (reversal_journal_id) REFERENCES journals(id)
+) ENGINE=InnoDB;
+
+-- Every status a dispute entered, when and with the note given
+CREATE TABLE dispute_events (
+    id CHAR(36) PRIMARY KEY,
+    dispute_id CHAR(36) NOT NULL,
+    status VARCHAR(32) NOT NULL CHECK (status IN ('open', 'refunded', 'rejected')),
+    note TEXT NULL,
+    created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    INDEX idx_dispute_events_dispute (dispute_id, created_at),
+    CONSTRAINT fk_dispute_events_dispute FOREIGN KEY (dispute_id) REFERENCES disputes(id)
+) ENGINE=InnoDB;
+
+-- The hold securing an open dispute, which only resolving the dispute frees
+ALTER TABLE holds
+    ADD COLUMN dispute_id CHAR(36) NULL,
+    ADD CONSTRAINT fk_holds_dispute FOREIGN KEY (dispute_id) REFERENCES disputes(id);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE holds
+    DROP FOREIGN KEY fk_holds_dispute,
+    DROP COLUMN dispute_id;
+DROP TABLE dispute_events;
+DROP TABLE disputes;
+
+-- +goose StatementEnd
@@ -0,0 +1,36 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- A transfer over the confirmation threshold, waiting for its sender to confirm it or
+-- an operator to approve it before any money moves. Once it runs it is completed, as
+-- transfer_journal_id, or failed; unconfirmed by expires_at it is cancelled. reason
+-- says why it failed or was cancelled.
+CREATE TABLE pending_transfers (
+    id CHAR(36) PRIMARY KEY,
+    from_wallet_id CHAR(36) NOT NULL,
+    to_wallet_id CHAR(36) NOT NULL,
+    amount DECIMAL(20, 2) NOT NULL CHECK (amount > 0),
+    currency CHAR(3) NOT NULL,
+    description TEXT,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'completed', 'failed', 'cancelled')),
+    reason TEXT,
+    transfer_journal_id CHAR(36) NULL,
+    expires_at DATETIME(6) NOT NULL,
+    created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    updated_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    CHECK (from_wallet_id <> to_wallet_id),
+    INDEX idx_pending_transfers_from_wallet (from_wallet_id, created_at),
+    INDEX idx_pending_transfers_status_expires (status, expires_at),
+    CONSTRAINT fk_pending_transfers_from_wallet FOREIGN KEY (from_wallet_id) REFERENCES wallets(id),
+    CONSTRAINT fk_pending_transfers_to_wallet FOREIGN KEY (to_wallet_id) REFERENCES wallets(id),
+    CONSTRAINT fk_pending_transfers_journal FOREIGN KEY (transfer_journal_id) REFERENCES journals(id)
+) ENGINE=InnoDB;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE pending_transfers;
+
+-- +goose StatementEnd
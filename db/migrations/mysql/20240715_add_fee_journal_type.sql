@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- A fee journal charges a wallet the fee on one of its movements, paying it into the
+-- fee-collection wallet. It is posted in the same transaction as the movement.
+ALTER TABLE journals DROP CHECK journals_type_check;
+ALTER TABLE journals ADD CONSTRAINT journals_type_check
+    CHECK (type IN ('deposit', 'withdraw', 'transfer', 'adjustment', 'reversal', 'correction', 'fee'));
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+-- Fails while fee journals exist, as they cannot be reclassified
+ALTER TABLE journals DROP CHECK journals_type_check;
+ALTER TABLE journals ADD CONSTRAINT journals_type_check
+    CHECK (type IN ('deposit', 'withdraw', 'transfer', 'adjustment', 'reversal', 'correction'));
+
+-- +goose StatementEnd
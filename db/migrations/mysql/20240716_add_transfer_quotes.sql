@@ -0,0 +1,36 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- The terms a transfer was quoted at: fee, debited and credited amounts and the
+-- exchange rate, held until expires_at. The transfer that uses the quote is
+-- transfer_journal_id; a quote is used at most once.
+CREATE TABLE transfer_quotes (
+    id CHAR(36) PRIMARY KEY,
+    from_wallet_id CHAR(36) NOT NULL,
+    to_wallet_id CHAR(36) NOT NULL,
+    amount DECIMAL(20, 2) NOT NULL CHECK (amount > 0),
+    currency CHAR(3) NOT NULL,
+    fee DECIMAL(20, 2) NOT NULL DEFAULT 0 CHECK (fee >= 0),
+    debited_amount DECIMAL(20, 2) NOT NULL CHECK (debited_amount > 0),
+    credited_amount DECIMAL(20, 2) NOT NULL CHECK (credited_amount > 0),
+    credited_currency CHAR(3) NOT NULL,
+    exchange_rate DECIMAL(20, 10) CHECK (exchange_rate > 0),
+    transfer_journal_id CHAR(36) NULL,
+    expires_at DATETIME(6) NOT NULL,
+    created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    CHECK (from_wallet_id <> to_wallet_id),
+    UNIQUE KEY uq_transfer_quotes_journal (transfer_journal_id),
+    INDEX idx_transfer_quotes_expires (expires_at),
+    CONSTRAINT fk_transfer_quotes_from_wallet FOREIGN KEY (from_wallet_id) REFERENCES wallets(id),
+    CONSTRAINT fk_transfer_quotes_to_wallet FOREIGN KEY (to_wallet_id) REFERENCES wallets(id),
+    CONSTRAINT fk_transfer_quotes_journal FOREIGN KEY (transfer_journal_id) REFERENCES journals(id)
+) ENGINE=InnoDB;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE transfer_quotes;
+
+-- +goose StatementEnd
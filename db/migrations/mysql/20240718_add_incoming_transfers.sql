@@ -0,0 +1,54 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Whether each wallet takes incoming transfers only once its owner accepts them. A
+-- wallet without a row takes them straight away.
+CREATE TABLE incoming_transfer_settings (
+    wallet_id CHAR(36) PRIMARY KEY,
+    require_acceptance BOOLEAN NOT NULL DEFAULT false,
+    updated_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    CONSTRAINT fk_incoming_transfer_settings_wallet FOREIGN KEY (wallet_id) REFERENCES wallets(id)
+) ENGINE=InnoDB;
+
+-- A transfer, journal_id, into a wallet that requires acceptance. While it is pending
+-- what the recipient was credited, amount, is held in their wallet. Accepting it frees
+-- the hold; rejecting it, or leaving it pending past expires_at, returns it to the
+-- sender as return_journal_id.
+CREATE TABLE incoming_transfers (
+    id CHAR(36) PRIMARY KEY,
+    journal_id CHAR(36) NOT NULL,
+    from_wallet_id CHAR(36) NOT NULL,
+    to_wallet_id CHAR(36) NOT NULL,
+    amount DECIMAL(20, 2) NOT NULL CHECK (amount > 0),
+    currency CHAR(3) NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'accepted', 'rejected', 'expired')),
+    return_journal_id CHAR(36) NULL,
+    expires_at DATETIME(6) NOT NULL,
+    created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    updated_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    UNIQUE KEY uq_incoming_transfers_journal (journal_id),
+    INDEX idx_incoming_transfers_to_wallet (to_wallet_id, created_at),
+    INDEX idx_incoming_transfers_status_expires (status, expires_at),
+    CONSTRAINT fk_incoming_transfers_journal FOREIGN KEY (journal_id) REFERENCES journals(id),
+    CONSTRAINT fk_incoming_transfers_from_wallet FOREIGN KEY (from_wallet_id) REFERENCES wallets(id),
+    CONSTRAINT fk_incoming_transfers_to_wallet FOREIGN KEY (to_wallet_id) REFERENCES wallets(id),
+    CONSTRAINT fk_incoming_transfers_return_journal FOREIGN KEY (return_journal_id) REFERENCES journals(id)
+) ENGINE=InnoDB;
+
+-- The hold on a transfer awaiting acceptance, which only accepting or returning it frees
+ALTER TABLE holds
+    ADD COLUMN incoming_transfer_id CHAR(36) NULL,
+    ADD CONSTRAINT fk_holds_incoming_transfer FOREIGN KEY (incoming_transfer_id) REFERENCES incoming_transfers(id);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE holds
+    DROP FOREIGN KEY fk_holds_incoming_transfer,
+    DROP COLUMN incoming_transfer_id;
+DROP TABLE incoming_transfers;
+DROP TABLE incoming_transfer_settings;
+
+-- +goose StatementEnd
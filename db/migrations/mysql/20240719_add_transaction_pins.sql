@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- The PIN each user who set one must give for withdrawals and transfers over the
+-- step-up threshold, bcrypt hashed. failed_attempts counts wrong PINs since the last
+-- right one; reaching the limit locks the PIN until locked_until.
+CREATE TABLE transaction_pins (
+    user_id CHAR(36) PRIMARY KEY,
+    pin_hash VARCHAR(255) NOT NULL,
+    failed_attempts INT NOT NULL DEFAULT 0,
+    locked_until DATETIME(6),
+    created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    updated_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    CONSTRAINT fk_transaction_pins_user FOREIGN KEY (user_id) REFERENCES users(id)
+) ENGINE=InnoDB;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE transaction_pins;
+
+-- +goose StatementEnd
@@ -0,0 +1,38 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- The verification tier operators gave each user, which sets the limits on what the
+-- user's wallet may pay out. A user without a row is unverified.
+CREATE TABLE kyc_profiles (
+    user_id CHAR(36) PRIMARY KEY,
+    tier VARCHAR(16) NOT NULL DEFAULT 'unverified' CHECK (tier IN ('unverified', 'basic', 'full')),
+    note TEXT NULL,
+    updated_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    CONSTRAINT fk_kyc_profiles_user FOREIGN KEY (user_id) REFERENCES users(id)
+) ENGINE=InnoDB;
+
+-- The identity documents users submitted for verification. reference points at the
+-- document in the store it was uploaded to; the file itself is not kept here.
+CREATE TABLE kyc_documents (
+    id CHAR(36) PRIMARY KEY,
+    user_id CHAR(36) NOT NULL,
+    document_type VARCHAR(32) NOT NULL CHECK (document_type IN ('passport', 'national_id', 'drivers_license', 'proof_of_address')),
+    reference VARCHAR(512) NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'rejected')),
+    note TEXT NULL,
+    submitted_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    reviewed_at DATETIME(6) NULL,
+    INDEX idx_kyc_documents_user (user_id, submitted_at),
+    INDEX idx_kyc_documents_status (status, submitted_at),
+    CONSTRAINT fk_kyc_documents_user FOREIGN KEY (user_id) REFERENCES users(id)
+) ENGINE=InnoDB;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE kyc_documents;
+DROP TABLE kyc_profiles;
+
+-- +goose StatementEnd
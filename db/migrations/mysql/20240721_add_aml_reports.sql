@@ -0,0 +1,32 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Movements the anti-money-laundering checks found reportable: single movements over
+-- the reporting threshold, and runs of movements just below it. movement_count and
+-- total cover the run, or the one movement for a large transaction.
+CREATE TABLE aml_reports (
+    id CHAR(36) PRIMARY KEY,
+    type VARCHAR(32) NOT NULL CHECK (type IN ('large_transaction', 'structuring')),
+    wallet_id CHAR(36) NOT NULL,
+    journal_id CHAR(36) NOT NULL,
+    operation VARCHAR(16) NOT NULL CHECK (operation IN ('deposit', 'withdraw', 'transfer')),
+    amount DECIMAL(20, 2) NOT NULL CHECK (amount > 0),
+    currency CHAR(3) NOT NULL,
+    movement_count INT NOT NULL CHECK (movement_count > 0),
+    total DECIMAL(20, 2) NOT NULL CHECK (total > 0),
+    reason TEXT NOT NULL,
+    created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    INDEX idx_aml_reports_wallet (wallet_id, type, created_at DESC),
+    INDEX idx_aml_reports_created (created_at DESC),
+    CONSTRAINT fk_aml_reports_wallet FOREIGN KEY (wallet_id) REFERENCES wallets(id),
+    CONSTRAINT fk_aml_reports_journal FOREIGN KEY (journal_id) REFERENCES journals(id)
+) ENGINE=InnoDB;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE aml_reports;
+
+-- +goose StatementEnd
@@ -0,0 +1,31 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- System wallets belong to the platform rather than a customer. The treasury wallet of
+-- a currency mirrors the money held outside the platform, so deposits and withdrawals
+-- are transfers against it; the suspense wallet takes the other side of balance
+-- adjustments; fee wallets collect fees. There is at most one of each kind per
+-- currency, all owned by a reserved system user that is marked deleted so it never
+-- shows up as a customer.
+INSERT INTO users (id, name, created_at, deleted_at)
+VALUES ('00000000-0000-0000-0000-000000000001', 'System', CURRENT_TIMESTAMP(6), CURRENT_TIMESTAMP(6));
+
+-- MySQL has no partial indexes: system_kind is NULL for customer wallets, and NULLs
+-- never collide in a unique index
+ALTER TABLE wallets
+    ADD COLUMN kind VARCHAR(16) NOT NULL DEFAULT 'user'
+        CHECK (kind IN ('user', 'treasury', 'suspense', 'fees')),
+    ADD COLUMN system_kind VARCHAR(16) AS (CASE WHEN kind <> 'user' THEN kind END) STORED,
+    ADD UNIQUE INDEX idx_wallets_system (system_kind, currency);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+-- Fails once system wallets have ledger entries, which cannot be moved to other wallets
+DELETE FROM wallets WHERE kind <> 'user';
+ALTER TABLE wallets DROP INDEX idx_wallets_system, DROP COLUMN system_kind, DROP COLUMN kind;
+DELETE FROM users WHERE id = '00000000-0000-0000-0000-000000000001';
+
+-- +goose StatementEnd
@@ -0,0 +1,37 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Money sent from a wallet to a bank account. journal_id debited the wallet when the
+-- payout was initiated; a payout that failed was credited back by refund_journal_id.
+CREATE TABLE payouts (
+    id CHAR(36) PRIMARY KEY,
+    wallet_id CHAR(36) NOT NULL,
+    amount DECIMAL(20, 2) NOT NULL CHECK (amount > 0),
+    currency CHAR(3) NOT NULL,
+    account_name VARCHAR(255) NOT NULL,
+    account_number VARCHAR(64) NOT NULL,
+    bank_code VARCHAR(64) NOT NULL,
+    status VARCHAR(32) NOT NULL DEFAULT 'initiated' CHECK (status IN ('initiated', 'sent', 'settled', 'failed')),
+    provider_reference VARCHAR(255),
+    failure_reason TEXT,
+    journal_id CHAR(36) NOT NULL,
+    refund_journal_id CHAR(36),
+    created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    updated_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    UNIQUE KEY uq_payouts_journal (journal_id),
+    INDEX idx_payouts_wallet_created (wallet_id, created_at),
+    -- The payout worker pages through the payouts still in flight
+    INDEX idx_payouts_status (status, id),
+    CONSTRAINT fk_payouts_wallet FOREIGN KEY (wallet_id) REFERENCES wallets(id),
+    CONSTRAINT fk_payouts_journal FOREIGN KEY (journal_id) REFERENCES journals(id),
+    CONSTRAINT fk_payouts_refund_journal FOREIGN KEY (refund_journal_id) REFERENCES journals(id)
+) ENGINE=InnoDB;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE payouts;
+
+-- +goose StatementEnd
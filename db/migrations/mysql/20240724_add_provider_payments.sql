@@ -0,0 +1,31 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Payments taken by the payment provider to fund a wallet, as its webhook reported them.
+-- payment_id is the provider's ID; journal_id deposited the payment once it succeeded.
+CREATE TABLE provider_payments (
+    id CHAR(36) PRIMARY KEY,
+    payment_id VARCHAR(255) NOT NULL,
+    wallet_id CHAR(36) NOT NULL,
+    amount DECIMAL(20, 2) NOT NULL CHECK (amount > 0),
+    currency CHAR(3) NOT NULL,
+    status VARCHAR(32) NOT NULL CHECK (status IN ('pending', 'succeeded', 'failed')),
+    failure_reason TEXT,
+    journal_id CHAR(36),
+    created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    updated_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    UNIQUE KEY uq_provider_payments_payment (payment_id),
+    UNIQUE KEY uq_provider_payments_journal (journal_id),
+    INDEX idx_provider_payments_wallet_created (wallet_id, created_at),
+    CONSTRAINT fk_provider_payments_wallet FOREIGN KEY (wallet_id) REFERENCES wallets(id),
+    CONSTRAINT fk_provider_payments_journal FOREIGN KEY (journal_id) REFERENCES journals(id)
+) ENGINE=InnoDB;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE provider_payments;
+
+-- +goose StatementEnd
@@ -0,0 +1,30 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Deposits paid back out of their wallet, in one or more parts. journal_id debited the
+-- wallet; provider_refund_id is the payment provider's refund of a deposit it funded.
+CREATE TABLE deposit_refunds (
+    id CHAR(36) PRIMARY KEY,
+    deposit_journal_id CHAR(36) NOT NULL,
+    wallet_id CHAR(36) NOT NULL,
+    amount DECIMAL(20, 2) NOT NULL CHECK (amount > 0),
+    currency CHAR(3) NOT NULL,
+    reason TEXT,
+    journal_id CHAR(36) NOT NULL,
+    provider_refund_id VARCHAR(255),
+    created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    UNIQUE KEY uq_deposit_refunds_journal (journal_id),
+    INDEX idx_deposit_refunds_deposit (deposit_journal_id, created_at),
+    CONSTRAINT fk_deposit_refunds_deposit FOREIGN KEY (deposit_journal_id) REFERENCES journals(id),
+    CONSTRAINT fk_deposit_refunds_wallet FOREIGN KEY (wallet_id) REFERENCES wallets(id),
+    CONSTRAINT fk_deposit_refunds_journal FOREIGN KEY (journal_id) REFERENCES journals(id)
+) ENGINE=InnoDB;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE deposit_refunds;
+
+-- +goose StatementEnd
@@ -0,0 +1,30 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Exports of a wallet's transactions over [period_from, period_to), produced in the
+-- background. A ready job's file is stored under object_key.
+CREATE TABLE export_jobs (
+    id CHAR(36) PRIMARY KEY,
+    wallet_id CHAR(36) NOT NULL,
+    period_from DATETIME(6) NOT NULL,
+    period_to DATETIME(6) NOT NULL,
+    status VARCHAR(32) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'ready', 'failed')),
+    object_key VARCHAR(1024),
+    exported_rows BIGINT NOT NULL DEFAULT 0,
+    failure_reason TEXT,
+    created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    updated_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    completed_at DATETIME(6),
+    -- The export worker pages through the jobs still pending
+    INDEX idx_export_jobs_status (status, id),
+    CONSTRAINT fk_export_jobs_wallet FOREIGN KEY (wallet_id) REFERENCES wallets(id)
+) ENGINE=InnoDB;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE export_jobs;
+
+-- +goose StatementEnd
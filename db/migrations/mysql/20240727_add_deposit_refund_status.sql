@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- A refund through the payment provider is recorded pending along with the wallet's
+-- debit and asked of the provider once that commits. It is completed when the provider
+-- refunds the payment, or failed when it refuses, and reversal_journal_id then credited
+-- the wallet back. Refunds without the provider are completed straight away.
+ALTER TABLE deposit_refunds
+    ADD COLUMN status VARCHAR(32) NOT NULL DEFAULT 'completed' CHECK (status IN ('pending', 'completed', 'failed')),
+    ADD COLUMN failure_reason TEXT,
+    ADD COLUMN reversal_journal_id CHAR(36) NULL,
+    -- The refund worker pages through the refunds still pending
+    ADD INDEX idx_deposit_refunds_status (status, id),
+    ADD CONSTRAINT fk_deposit_refunds_reversal_journal FOREIGN KEY (reversal_journal_id) REFERENCES journals(id);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE deposit_refunds
+    DROP FOREIGN KEY fk_deposit_refunds_reversal_journal,
+    DROP INDEX idx_deposit_refunds_status,
+    DROP COLUMN reversal_journal_id,
+    DROP COLUMN failure_reason,
+    DROP COLUMN status;
+
+-- +goose StatementEnd
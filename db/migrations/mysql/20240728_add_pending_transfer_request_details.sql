@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- A transfer held for confirmation keeps what its request filed it under, so the
+-- transfer it runs as is filed the same way: the category and the tags, as a JSON
+-- array. idempotency_key is the sender's scoped Idempotency-Key, so a retry of the
+-- request gets the same pending transfer; NULL for transfers held without one.
+ALTER TABLE pending_transfers
+    ADD COLUMN category VARCHAR(50) NULL,
+    ADD COLUMN tags TEXT NULL,
+    ADD COLUMN idempotency_key VARCHAR(255) NULL,
+    ADD UNIQUE KEY uq_pending_transfers_idempotency_key (idempotency_key);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE pending_transfers
+    DROP INDEX uq_pending_transfers_idempotency_key,
+    DROP COLUMN idempotency_key,
+    DROP COLUMN tags,
+    DROP COLUMN category;
+
+-- +goose StatementEnd
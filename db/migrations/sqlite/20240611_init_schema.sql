@@ -0,0 +1,38 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- UUIDs are generated by the application and stored in their canonical text form.
+-- Times are stored as UTC text, which sorts in time order.
+CREATE TABLE users (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE wallets (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    balance DECIMAL(20, 2) NOT NULL DEFAULT 0.00,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE transactions (
+    id TEXT PRIMARY KEY,
+    wallet_id TEXT NOT NULL REFERENCES wallets(id) ON DELETE CASCADE,
+    type TEXT NOT NULL CHECK (type IN ('deposit', 'withdraw', 'transfer_in', 'transfer_out')),
+    amount DECIMAL(20, 2) NOT NULL CHECK (amount > 0),
+    reference_id TEXT NULL, -- for linking to related tx (e.g., the other side of a transfer)
+    description TEXT,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE transactions;
+DROP TABLE wallets;
+DROP TABLE users;
+
+-- +goose StatementEnd
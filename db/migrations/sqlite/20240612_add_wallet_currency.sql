@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+
+ALTER TABLE wallets ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'USD';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE wallets DROP COLUMN currency;
+
+-- +goose StatementEnd
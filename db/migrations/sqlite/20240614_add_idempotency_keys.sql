@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+
+CREATE TABLE idempotency_keys (
+    request_key TEXT PRIMARY KEY,
+    status_code INTEGER NOT NULL DEFAULT 0,
+    headers TEXT NOT NULL,
+    response_body BLOB,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_idempotency_keys_created_at ON idempotency_keys (created_at);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE idempotency_keys;
+
+-- +goose StatementEnd
@@ -0,0 +1,119 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- A journal is one money movement; its ledger entries are the debit and credit legs.
+-- Entries with a NULL wallet_id belong to the external settlement account.
+-- SQLite cannot change a CHECK constraint later, so the journal types are checked by
+-- triggers that later migrations replace.
+CREATE TABLE journals (
+    id TEXT PRIMARY KEY,
+    type TEXT NOT NULL,
+    description TEXT,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE ledger_entries (
+    id TEXT PRIMARY KEY,
+    journal_id TEXT NOT NULL REFERENCES journals(id),
+    wallet_id TEXT NULL REFERENCES wallets(id),
+    direction TEXT NOT NULL CHECK (direction IN ('debit', 'credit')),
+    amount DECIMAL(20, 2) NOT NULL CHECK (amount > 0),
+    currency CHAR(3) NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_ledger_entries_wallet_created ON ledger_entries (wallet_id, created_at, id);
+CREATE INDEX idx_ledger_entries_journal ON ledger_entries (journal_id);
+
+-- Deposits and withdrawals become a journal each, keeping the transaction ID
+INSERT INTO journals (id, type, description, created_at)
+SELECT id, type, description, created_at
+FROM transactions
+WHERE type IN ('deposit', 'withdraw');
+
+-- Both sides of a transfer share a reference_id, which becomes the journal ID
+INSERT INTO journals (id, type, description, created_at)
+SELECT reference_id, 'transfer', MAX(description), MIN(created_at)
+FROM transactions
+WHERE type IN ('transfer_in', 'transfer_out')
+GROUP BY reference_id;
+
+-- Wallet legs keep the original transaction IDs
+INSERT INTO ledger_entries (id, journal_id, wallet_id, direction, amount, currency, created_at)
+SELECT t.id,
+       CASE WHEN t.type IN ('deposit', 'withdraw') THEN t.id ELSE t.reference_id END,
+       t.wallet_id,
+       CASE WHEN t.type IN ('deposit', 'transfer_in') THEN 'credit' ELSE 'debit' END,
+       t.amount,
+       w.currency,
+       t.created_at
+FROM transactions t
+JOIN wallets w ON w.id = t.wallet_id;
+
+-- Settlement legs balance deposits and withdrawals, with a random version 4 UUID each
+INSERT INTO ledger_entries (id, journal_id, wallet_id, direction, amount, currency, created_at)
+SELECT lower(hex(randomblob(4)) || '-' || hex(randomblob(2)) || '-4' || substr(hex(randomblob(2)), 2) || '-' ||
+             substr('89ab', 1 + abs(random()) % 4, 1) || substr(hex(randomblob(2)), 2) || '-' || hex(randomblob(6))),
+       t.id,
+       NULL,
+       CASE WHEN t.type = 'deposit' THEN 'debit' ELSE 'credit' END,
+       t.amount,
+       w.currency,
+       t.created_at
+FROM transactions t
+JOIN wallets w ON w.id = t.wallet_id
+WHERE t.type IN ('deposit', 'withdraw');
+
+DROP TABLE transactions;
+
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER journals_type_insert BEFORE INSERT ON journals
+FOR EACH ROW WHEN NEW.type NOT IN ('deposit', 'withdraw', 'transfer')
+BEGIN
+    SELECT RAISE(ABORT, 'CHECK constraint failed: journals type');
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER journals_type_update BEFORE UPDATE OF type ON journals
+FOR EACH ROW WHEN NEW.type NOT IN ('deposit', 'withdraw', 'transfer')
+BEGIN
+    SELECT RAISE(ABORT, 'CHECK constraint failed: journals type');
+END;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+CREATE TABLE transactions (
+    id TEXT PRIMARY KEY,
+    wallet_id TEXT NOT NULL REFERENCES wallets(id) ON DELETE CASCADE,
+    type TEXT NOT NULL CHECK (type IN ('deposit', 'withdraw', 'transfer_in', 'transfer_out')),
+    amount DECIMAL(20, 2) NOT NULL CHECK (amount > 0),
+    reference_id TEXT NULL, -- for linking to related tx (e.g., the other side of a transfer)
+    description TEXT,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO transactions (id, wallet_id, type, amount, reference_id, description, created_at)
+SELECT e.id,
+       e.wallet_id,
+       CASE
+           WHEN j.type <> 'transfer' THEN j.type
+           WHEN e.direction = 'credit' THEN 'transfer_in'
+           ELSE 'transfer_out'
+       END,
+       e.amount,
+       CASE WHEN j.type = 'transfer' THEN j.id END,
+       j.description,
+       e.created_at
+FROM ledger_entries e
+JOIN journals j ON j.id = e.journal_id
+WHERE e.wallet_id IS NOT NULL;
+
+DROP TABLE ledger_entries;
+DROP TABLE journals;
+
+-- +goose StatementEnd
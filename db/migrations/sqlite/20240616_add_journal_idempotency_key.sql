@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Scoped client keys for deposits, withdrawals and transfers; NULL for journals recorded without one
+ALTER TABLE journals ADD COLUMN idempotency_key TEXT NULL;
+CREATE UNIQUE INDEX uq_journals_idempotency_key ON journals (idempotency_key);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP INDEX uq_journals_idempotency_key;
+ALTER TABLE journals DROP COLUMN idempotency_key;
+
+-- +goose StatementEnd
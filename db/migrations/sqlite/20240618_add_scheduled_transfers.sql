@@ -0,0 +1,33 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- A transfer to run at start_at and, unless frequency is 'once', repeatedly after it.
+-- occurrence counts the runs already processed; next_run_at is when the next one is due.
+CREATE TABLE scheduled_transfers (
+    id TEXT PRIMARY KEY,
+    from_wallet_id TEXT NOT NULL REFERENCES wallets(id),
+    to_wallet_id TEXT NOT NULL REFERENCES wallets(id),
+    amount DECIMAL(20, 2) NOT NULL CHECK (amount > 0),
+    currency CHAR(3) NOT NULL,
+    description TEXT,
+    frequency TEXT NOT NULL CHECK (frequency IN ('once', 'daily', 'weekly', 'monthly')),
+    start_at DATETIME NOT NULL,
+    next_run_at DATETIME NOT NULL,
+    occurrence INTEGER NOT NULL DEFAULT 0,
+    status TEXT NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'completed', 'failed', 'cancelled')),
+    last_error TEXT,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_scheduled_transfers_from_wallet ON scheduled_transfers (from_wallet_id);
+CREATE INDEX idx_scheduled_transfers_due ON scheduled_transfers (status, next_run_at);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE scheduled_transfers;
+
+-- +goose StatementEnd
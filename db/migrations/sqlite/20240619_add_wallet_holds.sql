@@ -0,0 +1,34 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- held_balance is the part of balance reserved by active holds; the available
+-- balance that withdrawals and transfers may spend is balance - held_balance.
+ALTER TABLE wallets ADD COLUMN held_balance DECIMAL(20, 2) NOT NULL DEFAULT 0.00
+    CHECK (held_balance >= 0);
+
+-- A reservation of funds that is later captured (posted to the ledger) or released.
+-- capture_journal_id is the withdrawal journal a captured hold was posted as.
+CREATE TABLE holds (
+    id TEXT PRIMARY KEY,
+    wallet_id TEXT NOT NULL REFERENCES wallets(id),
+    amount DECIMAL(20, 2) NOT NULL CHECK (amount > 0),
+    currency CHAR(3) NOT NULL,
+    description TEXT,
+    status TEXT NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'captured', 'released')),
+    captured_amount DECIMAL(20, 2) CHECK (captured_amount > 0),
+    capture_journal_id TEXT REFERENCES journals(id),
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_holds_wallet_id ON holds (wallet_id);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE holds;
+ALTER TABLE wallets DROP COLUMN held_balance;
+
+-- +goose StatementEnd
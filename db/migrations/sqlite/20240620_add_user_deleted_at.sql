@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Deleted users are kept for the ledger's sake and hidden from lookups
+ALTER TABLE users ADD COLUMN deleted_at DATETIME NULL;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE users DROP COLUMN deleted_at;
+
+-- +goose StatementEnd
@@ -0,0 +1,42 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Adjustments are operator corrections posted against the settlement account
+DROP TRIGGER journals_type_insert;
+DROP TRIGGER journals_type_update;
+
+CREATE TRIGGER journals_type_insert BEFORE INSERT ON journals
+FOR EACH ROW WHEN NEW.type NOT IN ('deposit', 'withdraw', 'transfer', 'adjustment')
+BEGIN
+    SELECT RAISE(ABORT, 'CHECK constraint failed: journals type');
+END;
+
+CREATE TRIGGER journals_type_update BEFORE UPDATE OF type ON journals
+FOR EACH ROW WHEN NEW.type NOT IN ('deposit', 'withdraw', 'transfer', 'adjustment')
+BEGIN
+    SELECT RAISE(ABORT, 'CHECK constraint failed: journals type');
+END;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+-- Unlike a CHECK constraint, the triggers do not look at existing rows, so adjustment
+-- journals recorded in the meantime stay
+DROP TRIGGER journals_type_insert;
+DROP TRIGGER journals_type_update;
+
+CREATE TRIGGER journals_type_insert BEFORE INSERT ON journals
+FOR EACH ROW WHEN NEW.type NOT IN ('deposit', 'withdraw', 'transfer')
+BEGIN
+    SELECT RAISE(ABORT, 'CHECK constraint failed: journals type');
+END;
+
+CREATE TRIGGER journals_type_update BEFORE UPDATE OF type ON journals
+FOR EACH ROW WHEN NEW.type NOT IN ('deposit', 'withdraw', 'transfer')
+BEGIN
+    SELECT RAISE(ABORT, 'CHECK constraint failed: journals type');
+END;
+
+-- +goose StatementEnd
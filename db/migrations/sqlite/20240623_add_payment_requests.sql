@@ -0,0 +1,29 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- A request for the payer wallet to pay the requester wallet. Accepting it runs the
+-- transfer; transfer_journal_id is the journal the payment was posted as.
+CREATE TABLE payment_requests (
+    id TEXT PRIMARY KEY,
+    requester_wallet_id TEXT NOT NULL REFERENCES wallets(id),
+    payer_wallet_id TEXT NOT NULL REFERENCES wallets(id),
+    amount DECIMAL(20, 2) NOT NULL CHECK (amount > 0),
+    currency CHAR(3) NOT NULL,
+    description TEXT,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'accepted', 'declined')),
+    transfer_journal_id TEXT REFERENCES journals(id),
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CHECK (requester_wallet_id <> payer_wallet_id)
+);
+
+CREATE INDEX idx_payment_requests_payer ON payment_requests (payer_wallet_id, status);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE payment_requests;
+
+-- +goose StatementEnd
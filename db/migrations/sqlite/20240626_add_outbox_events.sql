@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Transactional outbox: wallet events are inserted in the same transaction as the
+-- journal they describe and published by a background dispatcher.
+CREATE TABLE outbox_events (
+    id TEXT PRIMARY KEY,
+    event_type TEXT NOT NULL,
+    wallet_id TEXT NOT NULL,
+    payload TEXT NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    published_at DATETIME
+);
+
+CREATE INDEX idx_outbox_events_unpublished ON outbox_events (published_at, created_at);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE outbox_events;
+
+-- +goose StatementEnd
@@ -0,0 +1,46 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Append-only record of every mutating API call. status_code is the HTTP status, or
+-- the gRPC code for calls over gRPC.
+CREATE TABLE audit_log (
+    id TEXT PRIMARY KEY,
+    actor_type TEXT NOT NULL CHECK (actor_type IN ('user', 'admin', 'anonymous')),
+    actor_id TEXT NOT NULL,
+    endpoint TEXT NOT NULL,
+    path TEXT NOT NULL,
+    payload_hash TEXT NOT NULL,
+    status_code INTEGER NOT NULL,
+    request_id TEXT NOT NULL DEFAULT '',
+    wallet_id TEXT,
+    balance_before DECIMAL(20, 2),
+    balance_after DECIMAL(20, 2),
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_audit_log_created ON audit_log (created_at DESC);
+CREATE INDEX idx_audit_log_actor ON audit_log (actor_id, created_at DESC);
+CREATE INDEX idx_audit_log_wallet ON audit_log (wallet_id, created_at DESC);
+
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER audit_log_no_update BEFORE UPDATE ON audit_log
+BEGIN
+    SELECT RAISE(ABORT, 'audit_log is append-only');
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER audit_log_no_delete BEFORE DELETE ON audit_log
+BEGIN
+    SELECT RAISE(ABORT, 'audit_log is append-only');
+END;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE audit_log;
+
+-- +goose StatementEnd
@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Fingerprint of the request body, so a key replayed with another body is rejected
+ALTER TABLE idempotency_keys ADD COLUMN request_fingerprint TEXT NOT NULL DEFAULT '';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE idempotency_keys DROP COLUMN request_fingerprint;
+
+-- +goose StatementEnd
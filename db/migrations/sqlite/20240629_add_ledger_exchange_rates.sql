@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Legs of a transfer between currencies record the rate it used and their amount in
+-- the other currency; both are NULL on every other entry
+ALTER TABLE ledger_entries ADD COLUMN exchange_rate DECIMAL(20, 10) CHECK (exchange_rate > 0);
+ALTER TABLE ledger_entries ADD COLUMN counter_amount DECIMAL(20, 2) CHECK (counter_amount > 0);
+ALTER TABLE ledger_entries ADD COLUMN counter_currency CHAR(3);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE ledger_entries DROP COLUMN exchange_rate;
+ALTER TABLE ledger_entries DROP COLUMN counter_amount;
+ALTER TABLE ledger_entries DROP COLUMN counter_currency;
+
+-- +goose StatementEnd
@@ -0,0 +1,71 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- A reversal posts the legs of an earlier journal in the opposite direction and points
+-- back at it; the unique index lets each journal be reversed at most once. SQLite cannot
+-- drop a column with a foreign key, so the reference is checked by triggers instead.
+ALTER TABLE journals ADD COLUMN reverses_journal_id TEXT NULL;
+CREATE UNIQUE INDEX uq_journals_reverses_journal_id ON journals (reverses_journal_id);
+
+DROP TRIGGER journals_type_insert;
+DROP TRIGGER journals_type_update;
+
+CREATE TRIGGER journals_type_insert BEFORE INSERT ON journals
+FOR EACH ROW WHEN NEW.type NOT IN ('deposit', 'withdraw', 'transfer', 'adjustment', 'reversal')
+BEGIN
+    SELECT RAISE(ABORT, 'CHECK constraint failed: journals type');
+END;
+
+CREATE TRIGGER journals_type_update BEFORE UPDATE OF type ON journals
+FOR EACH ROW WHEN NEW.type NOT IN ('deposit', 'withdraw', 'transfer', 'adjustment', 'reversal')
+BEGIN
+    SELECT RAISE(ABORT, 'CHECK constraint failed: journals type');
+END;
+
+CREATE TRIGGER journals_reverses_journal_insert BEFORE INSERT ON journals
+FOR EACH ROW WHEN NEW.reverses_journal_id IS NOT NULL AND NOT EXISTS (SELECT 1 FROM journals WHERE id = NEW.reverses_journal_id)
+BEGIN
+    SELECT RAISE(ABORT, 'FOREIGN KEY constraint failed: journals reverses_journal_id');
+END;
+
+CREATE TRIGGER journals_reverses_journal_update BEFORE UPDATE OF reverses_journal_id ON journals
+FOR EACH ROW WHEN NEW.reverses_journal_id IS NOT NULL AND NOT EXISTS (SELECT 1 FROM journals WHERE id = NEW.reverses_journal_id)
+BEGIN
+    SELECT RAISE(ABORT, 'FOREIGN KEY constraint failed: journals reverses_journal_id');
+END;
+
+CREATE TRIGGER journals_reversed_journal_delete BEFORE DELETE ON journals
+FOR EACH ROW WHEN EXISTS (SELECT 1 FROM journals WHERE reverses_journal_id = OLD.id)
+BEGIN
+    SELECT RAISE(ABORT, 'FOREIGN KEY constraint failed: journals reverses_journal_id');
+END;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+-- Unlike a CHECK constraint, the triggers do not look at existing rows, so reversal
+-- journals recorded in the meantime stay
+DROP TRIGGER journals_reversed_journal_delete;
+DROP TRIGGER journals_reverses_journal_update;
+DROP TRIGGER journals_reverses_journal_insert;
+DROP TRIGGER journals_type_insert;
+DROP TRIGGER journals_type_update;
+
+CREATE TRIGGER journals_type_insert BEFORE INSERT ON journals
+FOR EACH ROW WHEN NEW.type NOT IN ('deposit', 'withdraw', 'transfer', 'adjustment')
+BEGIN
+    SELECT RAISE(ABORT, 'CHECK constraint failed: journals type');
+END;
+
+CREATE TRIGGER journals_type_update BEFORE UPDATE OF type ON journals
+FOR EACH ROW WHEN NEW.type NOT IN ('deposit', 'withdraw', 'transfer', 'adjustment')
+BEGIN
+    SELECT RAISE(ABORT, 'CHECK constraint failed: journals type');
+END;
+
+DROP INDEX uq_journals_reverses_journal_id;
+ALTER TABLE journals DROP COLUMN reverses_journal_id;
+
+-- +goose StatementEnd
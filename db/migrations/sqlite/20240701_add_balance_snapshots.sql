@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- End-of-day wallet balances: balance is the sum of the wallet's ledger entries created
+-- before as_of, a midnight UTC. Historical balances start from the latest snapshot and
+-- add the entries since, instead of summing the wallet's whole ledger.
+CREATE TABLE balance_snapshots (
+    wallet_id TEXT NOT NULL REFERENCES wallets(id),
+    as_of DATETIME NOT NULL,
+    balance DECIMAL(20, 2) NOT NULL,
+    currency CHAR(3) NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (wallet_id, as_of)
+);
+
+CREATE INDEX idx_balance_snapshots_as_of ON balance_snapshots (as_of);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE balance_snapshots;
+
+-- +goose StatementEnd
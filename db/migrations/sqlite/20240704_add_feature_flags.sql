@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Feature flag values set by operators at runtime, overriding the defaults the service
+-- is configured with. A flag without a row has its default.
+CREATE TABLE feature_flags (
+    name TEXT PRIMARY KEY,
+    enabled BOOLEAN NOT NULL,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE feature_flags;
+
+-- +goose StatementEnd
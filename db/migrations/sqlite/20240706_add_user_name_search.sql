@@ -0,0 +1,8 @@
+-- +goose Up
+
+-- User search matches any part of a name with LIKE, which ignores case in SQLite. No
+-- index can serve a match in the middle of a name without FTS5, which the driver is
+-- not built with, so searches scan the users. This keeps the versions in step with
+-- PostgreSQL and MySQL.
+
+-- +goose Down
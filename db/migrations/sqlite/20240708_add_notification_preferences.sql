@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Which transaction alerts each user is emailed, in their wallet's currency. A NULL
+-- threshold turns its alert off. Users without a row get the configured defaults.
+CREATE TABLE notification_preferences (
+    user_id TEXT PRIMARY KEY REFERENCES users(id),
+    large_withdrawal_threshold DECIMAL(20, 2) CHECK (large_withdrawal_threshold > 0),
+    incoming_transfer BOOLEAN NOT NULL,
+    low_balance_threshold DECIMAL(20, 2) CHECK (low_balance_threshold > 0),
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE notification_preferences;
+
+-- +goose StatementEnd
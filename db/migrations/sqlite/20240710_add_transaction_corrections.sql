@@ -0,0 +1,83 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- A correction is an operator's reversal of an erroneous journal. It points back at the
+-- journal like a reversal, so a journal is reversed or corrected at most once, and
+-- records why the journal was wrong. SQLite cannot add table constraints to an existing
+-- table, so the type and reason are checked by triggers.
+ALTER TABLE journals ADD COLUMN correction_reason TEXT NULL;
+
+-- journals_type_update goes, as journals cannot be updated at all any more
+DROP TRIGGER journals_type_insert;
+DROP TRIGGER journals_type_update;
+
+CREATE TRIGGER journals_type_insert BEFORE INSERT ON journals
+FOR EACH ROW WHEN NEW.type NOT IN ('deposit', 'withdraw', 'transfer', 'adjustment', 'reversal', 'correction')
+BEGIN
+    SELECT RAISE(ABORT, 'CHECK constraint failed: journals type');
+END;
+
+CREATE TRIGGER journals_correction_reason_insert BEFORE INSERT ON journals
+FOR EACH ROW WHEN (NEW.type = 'correction') <> (NEW.correction_reason IS NOT NULL)
+    OR NEW.correction_reason NOT IN ('duplicate', 'wrong_amount', 'wrong_recipient', 'fraud', 'system_error')
+BEGIN
+    SELECT RAISE(ABORT, 'CHECK constraint failed: journals correction_reason');
+END;
+
+-- +goose StatementEnd
+
+-- Mistakes are corrected with new journals, so the ledger is append-only
+
+-- +goose StatementBegin
+CREATE TRIGGER journals_no_update BEFORE UPDATE ON journals
+BEGIN
+    SELECT RAISE(ABORT, 'journals is append-only');
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER journals_no_delete BEFORE DELETE ON journals
+BEGIN
+    SELECT RAISE(ABORT, 'journals is append-only');
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER ledger_entries_no_update BEFORE UPDATE ON ledger_entries
+BEGIN
+    SELECT RAISE(ABORT, 'ledger_entries is append-only');
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER ledger_entries_no_delete BEFORE DELETE ON ledger_entries
+BEGIN
+    SELECT RAISE(ABORT, 'ledger_entries is append-only');
+END;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TRIGGER ledger_entries_no_delete;
+DROP TRIGGER ledger_entries_no_update;
+DROP TRIGGER journals_no_delete;
+DROP TRIGGER journals_no_update;
+DROP TRIGGER journals_correction_reason_insert;
+DROP TRIGGER journals_type_insert;
+
+CREATE TRIGGER journals_type_insert BEFORE INSERT ON journals
+FOR EACH ROW WHEN NEW.type NOT IN ('deposit', 'withdraw', 'transfer', 'adjustment', 'reversal')
+BEGIN
+    SELECT RAISE(ABORT, 'CHECK constraint failed: journals type');
+END;
+
+CREATE TRIGGER journals_type_update BEFORE UPDATE OF type ON journals
+FOR EACH ROW WHEN NEW.type NOT IN ('deposit', 'withdraw', 'transfer', 'adjustment', 'reversal')
+BEGIN
+    SELECT RAISE(ABORT, 'CHECK constraint failed: journals type');
+END;
+
+ALTER TABLE journals DROP COLUMN correction_reason;
+
+-- +goose StatementEnd
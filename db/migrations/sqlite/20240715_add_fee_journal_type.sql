@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- A fee journal charges a wallet the fee on one of its movements, paying it into the
+-- fee-collection wallet. It is posted in the same transaction as the movement.
+DROP TRIGGER journals_type_insert;
+
+CREATE TRIGGER journals_type_insert BEFORE INSERT ON journals
+FOR EACH ROW WHEN NEW.type NOT IN ('deposit', 'withdraw', 'transfer', 'adjustment', 'reversal', 'correction', 'fee')
+BEGIN
+    SELECT RAISE(ABORT, 'CHECK constraint failed: journals type');
+END;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TRIGGER journals_type_insert;
+
+CREATE TRIGGER journals_type_insert BEFORE INSERT ON journals
+FOR EACH ROW WHEN NEW.type NOT IN ('deposit', 'withdraw', 'transfer', 'adjustment', 'reversal', 'correction')
+BEGIN
+    SELECT RAISE(ABORT, 'CHECK constraint failed: journals type');
+END;
+
+-- +goose StatementEnd
@@ -0,0 +1,33 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- The terms a transfer was quoted at: fee, debited and credited amounts and the
+-- exchange rate, held until expires_at. The transfer that uses the quote is
+-- transfer_journal_id; a quote is used at most once.
+CREATE TABLE transfer_quotes (
+    id TEXT PRIMARY KEY,
+    from_wallet_id TEXT NOT NULL REFERENCES wallets(id),
+    to_wallet_id TEXT NOT NULL REFERENCES wallets(id),
+    amount DECIMAL(20, 2) NOT NULL CHECK (amount > 0),
+    currency CHAR(3) NOT NULL,
+    fee DECIMAL(20, 2) NOT NULL DEFAULT 0 CHECK (fee >= 0),
+    debited_amount DECIMAL(20, 2) NOT NULL CHECK (debited_amount > 0),
+    credited_amount DECIMAL(20, 2) NOT NULL CHECK (credited_amount > 0),
+    credited_currency CHAR(3) NOT NULL,
+    exchange_rate DECIMAL(20, 10) CHECK (exchange_rate > 0),
+    transfer_journal_id TEXT UNIQUE REFERENCES journals(id),
+    expires_at DATETIME NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CHECK (from_wallet_id <> to_wallet_id)
+);
+
+CREATE INDEX idx_transfer_quotes_expires ON transfer_quotes(expires_at);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE transfer_quotes;
+
+-- +goose StatementEnd
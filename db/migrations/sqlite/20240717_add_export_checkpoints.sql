@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- How far each export stream has shipped its rows to object storage: the ID of the
+-- last row exported and how many batches and rows went before. IDs are time-ordered,
+-- so the next batch starts after last_id.
+CREATE TABLE export_checkpoints (
+    stream TEXT PRIMARY KEY,
+    last_id TEXT NOT NULL,
+    batches INTEGER NOT NULL DEFAULT 0,
+    exported_rows INTEGER NOT NULL DEFAULT 0,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE export_checkpoints;
+
+-- +goose StatementEnd
@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- The PIN each user who set one must give for withdrawals and transfers over the
+-- step-up threshold, bcrypt hashed. failed_attempts counts wrong PINs since the last
+-- right one; reaching the limit locks the PIN until locked_until.
+CREATE TABLE transaction_pins (
+    user_id TEXT PRIMARY KEY REFERENCES users(id),
+    pin_hash TEXT NOT NULL,
+    failed_attempts INTEGER NOT NULL DEFAULT 0,
+    locked_until DATETIME,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE transaction_pins;
+
+-- +goose StatementEnd
@@ -0,0 +1,34 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Money sent from a wallet to a bank account. journal_id debited the wallet when the
+-- payout was initiated; a payout that failed was credited back by refund_journal_id.
+CREATE TABLE payouts (
+    id TEXT PRIMARY KEY,
+    wallet_id TEXT NOT NULL REFERENCES wallets(id),
+    amount DECIMAL(20, 2) NOT NULL CHECK (amount > 0),
+    currency CHAR(3) NOT NULL,
+    account_name TEXT NOT NULL,
+    account_number TEXT NOT NULL,
+    bank_code TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'initiated' CHECK (status IN ('initiated', 'sent', 'settled', 'failed')),
+    provider_reference TEXT,
+    failure_reason TEXT,
+    journal_id TEXT NOT NULL UNIQUE REFERENCES journals(id),
+    refund_journal_id TEXT REFERENCES journals(id),
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_payouts_wallet_created ON payouts (wallet_id, created_at);
+-- The payout worker pages through the payouts still in flight
+CREATE INDEX idx_payouts_status ON payouts (status, id);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE payouts;
+
+-- +goose StatementEnd
@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Payments taken by the payment provider to fund a wallet, as its webhook reported them.
+-- payment_id is the provider's ID; journal_id deposited the payment once it succeeded.
+CREATE TABLE provider_payments (
+    id TEXT PRIMARY KEY,
+    payment_id TEXT NOT NULL UNIQUE,
+    wallet_id TEXT NOT NULL REFERENCES wallets(id),
+    amount DECIMAL(20, 2) NOT NULL CHECK (amount > 0),
+    currency CHAR(3) NOT NULL,
+    status TEXT NOT NULL CHECK (status IN ('pending', 'succeeded', 'failed')),
+    failure_reason TEXT,
+    journal_id TEXT UNIQUE REFERENCES journals(id),
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_provider_payments_wallet_created ON provider_payments (wallet_id, created_at);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE provider_payments;
+
+-- +goose StatementEnd
@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Deposits paid back out of their wallet, in one or more parts. journal_id debited the
+-- wallet; provider_refund_id is the payment provider's refund of a deposit it funded.
+CREATE TABLE deposit_refunds (
+    id TEXT PRIMARY KEY,
+    deposit_journal_id TEXT NOT NULL REFERENCES journals(id),
+    wallet_id TEXT NOT NULL REFERENCES wallets(id),
+    amount DECIMAL(20, 2) NOT NULL CHECK (amount > 0),
+    currency CHAR(3) NOT NULL,
+    reason TEXT,
+    journal_id TEXT NOT NULL UNIQUE REFERENCES journals(id),
+    provider_refund_id TEXT,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_deposit_refunds_deposit ON deposit_refunds (deposit_journal_id, created_at);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE deposit_refunds;
+
+-- +goose StatementEnd
@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- A transfer held for confirmation keeps what its request filed it under, so the
+-- transfer it runs as is filed the same way: the category and the tags, as a JSON
+-- array. idempotency_key is the sender's scoped Idempotency-Key, so a retry of the
+-- request gets the same pending transfer; NULL for transfers held without one.
+ALTER TABLE pending_transfers ADD COLUMN category TEXT;
+ALTER TABLE pending_transfers ADD COLUMN tags TEXT;
+ALTER TABLE pending_transfers ADD COLUMN idempotency_key TEXT;
+CREATE UNIQUE INDEX uq_pending_transfers_idempotency_key ON pending_transfers (idempotency_key);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP INDEX uq_pending_transfers_idempotency_key;
+ALTER TABLE pending_transfers DROP COLUMN idempotency_key;
+ALTER TABLE pending_transfers DROP COLUMN tags;
+ALTER TABLE pending_transfers DROP COLUMN category;
+
+-- +goose StatementEnd
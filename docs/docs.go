@@ -15,120 +15,311 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/api/v1/users": {
-            "post": {
-                "consumes": [
-                    "application/json"
-                ],
+        "/api/v1/admin/aml-reports": {
+            "get": {
+                "description": "Returns the movements the AML checks reported, newest first and at most 200 per page: large transactions over AML_REPORT_THRESHOLD and runs of movements just below it.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "admin"
                 ],
-                "summary": "Create user",
+                "summary": "List AML reports",
                 "parameters": [
                     {
-                        "description": "User details",
-                        "name": "user",
-                        "in": "body",
-                        "required": true,
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "large_transaction or structuring",
+                        "name": "type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only reports on this wallet",
+                        "name": "wallet_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Reports at or after this date (YYYY-MM-DD) or time (RFC3339)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Reports up to this date (inclusive) or before this time",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 0,
+                        "type": "integer",
+                        "default": 50,
+                        "description": "Page size; larger sizes are cut to 200",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 0,
+                        "type": "integer",
+                        "default": 0,
+                        "description": "Reports to skip",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handlers.createUserRequest"
+                            "$ref": "#/definitions/handlers.amlReportListResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid filter or pagination parameters",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
                         }
                     }
+                }
+            }
+        },
+        "/api/v1/admin/aml-reports/export": {
+            "get": {
+                "description": "Streams every AML report raised in the period as CSV, oldest first, for filing with the regulator.",
+                "produces": [
+                    "text/csv"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Export AML reports",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Period start as YYYY-MM-DD or RFC3339; defaults to the first report",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Period end as YYYY-MM-DD (inclusive) or RFC3339 (exclusive); defaults to now",
+                        "name": "to",
+                        "in": "query"
+                    }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.UserWithWallet"
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid period",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
                         }
                     }
                 }
             }
         },
-        "/api/v1/wallets/{id}/balance": {
+        "/api/v1/admin/audit-log": {
             "get": {
+                "description": "Returns who called which mutating endpoint and when, with the wallet's balance before and after, newest first and at most 200 per page.\nA full page comes with a next_cursor; passing it back as cursor, with the same filters, returns the entries after that page.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "wallets"
+                    "admin"
                 ],
-                "summary": "Get wallet balance",
+                "summary": "List audit log entries",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Wallet ID",
-                        "name": "id",
-                        "in": "path",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "User ID, admin key fingerprint or client IP",
+                        "name": "actor_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only calls on this wallet",
+                        "name": "wallet_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Entries at or after this time (RFC3339)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Entries before this time (RFC3339)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "next_cursor of the previous page; cannot be combined with offset",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 0,
+                        "type": "integer",
+                        "default": 50,
+                        "description": "Page size; larger sizes are cut to 200",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 0,
+                        "type": "integer",
+                        "default": 0,
+                        "description": "Entries to skip",
+                        "name": "offset",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.Wallet"
+                            "$ref": "#/definitions/handlers.auditListResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid filter or pagination parameters",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
                         }
                     }
                 }
             }
         },
-        "/api/v1/wallets/{id}/deposit": {
-            "post": {
-                "consumes": [
-                    "application/json"
-                ],
+        "/api/v1/admin/disputes": {
+            "get": {
+                "description": "Returns disputes, oldest first, at most 200 per page. Pass status=open for the ones awaiting a decision.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "wallets"
+                    "admin"
                 ],
-                "summary": "Deposit to wallet",
+                "summary": "List disputes",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Wallet ID",
-                        "name": "id",
-                        "in": "path",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
                         "required": true
                     },
                     {
-                        "description": "Deposit details",
-                        "name": "deposit",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/handlers.depositRequest"
-                        }
+                        "type": "string",
+                        "description": "open, refunded or rejected",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 0,
+                        "type": "integer",
+                        "default": 50,
+                        "description": "Page size; larger sizes are cut to 200",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 0,
+                        "type": "integer",
+                        "default": 0,
+                        "description": "Disputes to skip",
+                        "name": "offset",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.Wallet"
+                            "$ref": "#/definitions/handlers.disputeListResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid status or pagination parameters",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
                         }
                     }
                 }
             }
         },
-        "/api/v1/wallets/{id}/transactions": {
+        "/api/v1/admin/disputes/{id}": {
             "get": {
+                "description": "Returns the dispute with every status it has been in and when",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "wallets"
+                    "admin"
                 ],
-                "summary": "Get wallet transaction history",
+                "summary": "Get a dispute",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Wallet ID",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Dispute ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -138,17 +329,33 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/models.Transaction"
-                            }
+                            "$ref": "#/definitions/models.Dispute"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid dispute ID",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Dispute not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
                         }
                     }
                 }
             }
         },
-        "/api/v1/wallets/{id}/transfer": {
+        "/api/v1/admin/disputes/{id}/resolve": {
             "post": {
+                "description": "A refund reverses the whole transfer, paying the held funds back to the sender;\na rejection leaves the transfer as it was. Either way the hold on the recipient's\nwallet is released and the note is recorded with the new status.",
                 "consumes": [
                     "application/json"
                 ],
@@ -156,24 +363,31 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "wallets"
+                    "admin"
                 ],
-                "summary": "Transfer between wallets",
+                "summary": "Resolve a dispute",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Wallet ID",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Dispute ID",
                         "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "Transfer details",
-                        "name": "transfer",
+                        "description": "Outcome and note",
+                        "name": "resolution",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/handlers.transferRequest"
+                            "$ref": "#/definitions/handlers.resolveDisputeRequest"
                         }
                     }
                 ],
@@ -181,14 +395,74 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.Wallet"
+                            "$ref": "#/definitions/models.Dispute"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid dispute ID or outcome",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Dispute not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Dispute already resolved, or the transfer was reversed since",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
                         }
                     }
                 }
             }
         },
-        "/api/v1/wallets/{id}/withdraw": {
-            "post": {
+        "/api/v1/admin/feature-flags": {
+            "get": {
+                "description": "Returns every known flag with its value, its configured default and whether an operator has overridden it.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "List feature flags",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.featureFlagListResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/admin/feature-flags/{name}": {
+            "put": {
+                "description": "Overrides the flag's configured default. Other instances pick the change up within FEATURE_FLAGS_CACHE_TTL.",
                 "consumes": [
                     "application/json"
                 ],
@@ -196,24 +470,31 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "wallets"
+                    "admin"
                 ],
-                "summary": "Withdraw from wallet",
+                "summary": "Set a feature flag",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Wallet ID",
-                        "name": "id",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Flag name",
+                        "name": "name",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "Withdraw details",
-                        "name": "withdraw",
+                        "description": "New value",
+                        "name": "flag",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/handlers.withdrawRequest"
+                            "$ref": "#/definitions/handlers.featureFlagRequest"
                         }
                     }
                 ],
@@ -221,108 +502,8915 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.Wallet"
+                            "$ref": "#/definitions/featureflag.Flag"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Unknown flag",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
                         }
                     }
                 }
-            }
-        },
-        "/health": {
-            "get": {
+            },
+            "delete": {
+                "description": "Removes the operator's override so the flag has its configured default again.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "health"
+                    "admin"
+                ],
+                "summary": "Reset a feature flag",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Flag name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "Health check",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handlers.HealthResponse"
+                            "$ref": "#/definitions/featureflag.Flag"
+                        }
+                    },
+                    "404": {
+                        "description": "Unknown flag",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
                         }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "handlers.HealthResponse": {
-            "type": "object",
-            "properties": {
-                "service": {
-                    "type": "string"
-                },
+        },
+        "/api/v1/admin/kyc/documents": {
+            "get": {
+                "description": "Returns pending documents, oldest first, at most 200 per page",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "List KYC documents awaiting review",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "minimum": 0,
+                        "type": "integer",
+                        "default": 50,
+                        "description": "Page size; larger sizes are cut to 200",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 0,
+                        "type": "integer",
+                        "default": 0,
+                        "description": "Documents to skip",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.kycDocumentListResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid pagination parameters",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/admin/kyc/documents/{id}/review": {
+            "post": {
+                "description": "Approves or rejects a pending document. Set the user's tier separately once their documents support it.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Review a KYC document",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Document ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "approved or rejected, and why",
+                        "name": "review",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.reviewKYCDocumentRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.KYCDocument"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid document ID or status",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Document not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Document already reviewed",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/admin/log-level": {
+            "get": {
+                "description": "Returns the level this instance logs at, the configured one, and when a temporary change ends.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get the log level",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/logger.LevelState"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Changes the level this instance logs at, without a restart. With a duration the configured level comes back once it has passed. Other instances are not changed.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Set the log level",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "New level: debug, info, warn or error",
+                        "name": "level",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.logLevelRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/logger.LevelState"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid level or duration",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Returns this instance to the level it was configured with, ending any temporary change.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Reset the log level",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/logger.LevelState"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/admin/merchants": {
+            "post": {
+                "description": "Lets the wallet take payments for orders; payers see the name in their history.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Register a merchant account",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Wallet and merchant name",
+                        "name": "merchant",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.merchantAccountRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.MerchantAccount"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid wallet ID or name",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Wallet not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "The wallet already is a merchant account",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/admin/payouts": {
+            "get": {
+                "description": "Returns payouts of every wallet, newest first, for following up on the ones still in flight or failed.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "List payouts",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "initiated, sent, settled or failed",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only payouts from this wallet",
+                        "name": "wallet_id",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 0,
+                        "type": "integer",
+                        "default": 50,
+                        "description": "Page size; larger sizes are cut to 200",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 0,
+                        "type": "integer",
+                        "default": 0,
+                        "description": "Payouts to skip",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.payoutListResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid wallet ID, status or pagination parameters",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/admin/pending-transfers": {
+            "get": {
+                "description": "Returns transfers held for confirmation, oldest first, at most 200 per page. Pass status=pending for the ones still waiting.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "List pending transfers",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "pending, completed, failed or cancelled",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 0,
+                        "type": "integer",
+                        "default": 50,
+                        "description": "Page size; larger sizes are cut to 200",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 0,
+                        "type": "integer",
+                        "default": 0,
+                        "description": "Transfers to skip",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.pendingTransferListResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid status or pagination parameters",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/admin/pending-transfers/{id}/approve": {
+            "post": {
+                "description": "Moves the money of a held transfer in place of its sender's confirmation, on the same terms.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Approve a pending transfer",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Pending transfer ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.PendingTransfer"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid pending transfer ID, or insufficient funds",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Pending transfer not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Transfer is no longer pending or has expired, or a wallet is frozen or closed",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Wallet limit exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/admin/reconciliation/discrepancies": {
+            "get": {
+                "description": "Returns wallets whose stored balance differed from their ledger, with both amounts, newest first, at most 200 per page.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "List balance discrepancies",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only discrepancies found by this run",
+                        "name": "run_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only discrepancies in this wallet",
+                        "name": "wallet_id",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 0,
+                        "type": "integer",
+                        "default": 50,
+                        "description": "Page size; larger sizes are cut to 200",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 0,
+                        "type": "integer",
+                        "default": 0,
+                        "description": "Discrepancies to skip",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.discrepancyListResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid filter or pagination parameters",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/admin/reconciliation/runs": {
+            "get": {
+                "description": "Returns past runs with how many wallets each checked and found out of balance, newest first, at most 200 per page.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "List reconciliation runs",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "minimum": 0,
+                        "type": "integer",
+                        "default": 50,
+                        "description": "Page size; larger sizes are cut to 200",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 0,
+                        "type": "integer",
+                        "default": 0,
+                        "description": "Runs to skip",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.reconciliationRunListResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid pagination parameters",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Compares every wallet's stored balance with the sum of its ledger entries, records the run and returns it with the wallets that disagree.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Run a reconciliation",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes retries safe: a repeat with the same key and body gets the first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.ReconciliationRun"
+                        }
+                    },
+                    "409": {
+                        "description": "Idempotency-Key reused with a different request body",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/admin/risk-decisions": {
+            "get": {
+                "description": "Returns flagged and blocked withdrawals and transfers for review, newest first, at most 200 per page.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "List risk decisions",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only decisions about this wallet",
+                        "name": "wallet_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "flag or block",
+                        "name": "action",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 0,
+                        "type": "integer",
+                        "default": 50,
+                        "description": "Page size; larger sizes are cut to 200",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 0,
+                        "type": "integer",
+                        "default": 0,
+                        "description": "Decisions to skip",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.riskDecisionListResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid filter or pagination parameters",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/admin/system-wallets": {
+            "get": {
+                "description": "Returns the treasury, suspense and fees wallets of every currency that has them. A treasury wallet's balance is the negative of the money customers paid in and have not taken out, so with the customer wallets of its currency it sums to zero.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "List system wallets",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.systemWalletListResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Returns the system wallet of the kind in the currency, creating it when there is none yet. Treasury and suspense wallets are created when first needed; create the fees wallet of a currency to point FEE_WALLET_ID at it.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Create a system wallet",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Kind and currency, USD when omitted",
+                        "name": "wallet",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ensureSystemWalletRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Wallet"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid kind or currency",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/admin/transactions/{id}/corrections": {
+            "post": {
+                "description": "Marks the transaction as erroneous by posting every leg of its journal again in\nthe opposite direction, as a correction that references it and records the reason.\nThe original stays in the ledger unchanged and its history entries gain\ncorrected_by_reference_id. A transfer can be corrected in part by passing an amount\nin the currency it was sent in. Each transaction can be corrected or reversed once;\nfrozen wallets can be corrected, closed ones cannot.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Correct a transaction",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Transaction ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Reason code, partial amount and note",
+                        "name": "correction",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.correctionRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.Journal"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid transaction ID, reason or amount, or the correction would overdraw a wallet",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Transaction not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Transaction already corrected, reversed, refunded or paid out, transfer awaiting acceptance, or a wallet is closed",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/admin/transactions/{id}/refund": {
+            "post": {
+                "description": "Debits the wallet the deposit credited by the amount, or by what is left of\nthe deposit when it is omitted, as a withdrawal linked to the deposit. A\ndeposit can be refunded in several parts, never for more than it brought in,\nand not once it was reversed. A deposit the payment provider funded is also\nrefunded to the customer through it when a provider API is configured: the\nrefund is pending until the provider has refunded the payment, when it is\ncompleted, and failed and credited back to the wallet if the provider rejects\nit. A provider that cannot be reached is asked again by the refund worker. When\nauth is enabled only the owner of the wallet can refund it.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transactions"
+                ],
+                "summary": "Refund a deposit",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Transaction ID of the deposit",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Partial amount and reason",
+                        "name": "refund",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.depositRefundRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes retries safe: a repeat with the same key and body gets the first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.DepositRefund"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid transaction ID or amount, not a deposit, more than is left of it, or insufficient funds",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Transaction not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Deposit reversed, wallet frozen or closed, or Idempotency-Key reused with a different request body",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Wallet limit exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/admin/transactions/{id}/reverse": {
+            "post": {
+                "description": "Posts every leg of the transaction's journal again in the opposite direction,\nas a reversal that references it. A transfer can be reversed in part by\npassing an amount in the currency it was sent in. Each transaction can be\nreversed once. When auth is enabled only the owner of the wallet that\nreceived the money can reverse it.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transactions"
+                ],
+                "summary": "Reverse a transaction",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Transaction ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Partial amount and reason",
+                        "name": "reversal",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.reversalRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes retries safe: a repeat with the same key and body gets the first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.Journal"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid transaction ID or amount",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Transaction not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Transaction already reversed, refunded or paid out, transfer awaiting acceptance, or Idempotency-Key reused with a different request body",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/admin/users": {
+            "get": {
+                "description": "Returns users that have not been deleted, oldest first, at most 200 per page.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "List users",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "minimum": 0,
+                        "type": "integer",
+                        "default": 50,
+                        "description": "Page size; larger sizes are cut to 200",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 0,
+                        "type": "integer",
+                        "default": 0,
+                        "description": "Users to skip",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.userListResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid pagination parameters",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/admin/users/{id}/kyc": {
+            "get": {
+                "description": "Returns the user's verification tier, the daily limits it holds their wallet to, and the documents they submitted, newest first.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Get KYC profile",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.KYCProfile"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid user ID",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/admin/users/{id}/kyc/tier": {
+            "put": {
+                "description": "Moves the user to the unverified, basic or full tier. Their wallet is held to the new tier's daily limits from its next withdrawal or transfer.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Set a user's KYC tier",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "New tier and why",
+                        "name": "tier",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.setKYCTierRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.KYCProfile"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid user ID or tier",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/admin/wallets": {
+            "get": {
+                "description": "Returns the wallets matching every given filter, oldest first unless sorted otherwise, at most 200 per page.\nA full page comes with a next_cursor; passing it back as cursor, with the same filters and sort, returns the wallets after that page. Cursors page by the sort column rather than by skipping rows, so deep pages are as cheap as the first and wallets created meanwhile are neither skipped nor repeated.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Search wallets",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "active, frozen or closed",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ISO 4217 currency code",
+                        "name": "currency",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Smallest balance",
+                        "name": "min_balance",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Largest balance",
+                        "name": "max_balance",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Wallets created at or after this time (RFC3339)",
+                        "name": "created_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Wallets created before this time (RFC3339)",
+                        "name": "created_to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "created_at",
+                        "description": "created_at or balance, descending with a leading -",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "next_cursor of the previous page; cannot be combined with offset",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 0,
+                        "type": "integer",
+                        "default": 50,
+                        "description": "Page size; larger sizes are cut to 200",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 0,
+                        "type": "integer",
+                        "default": 0,
+                        "description": "Wallets to skip",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.walletListResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid filter, sort or pagination parameters",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/admin/wallets/{id}": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get wallet",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Wallet ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Wallet"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid wallet ID",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Wallet not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/admin/wallets/{id}/adjustments": {
+            "post": {
+                "description": "Adds a positive amount to the wallet or takes a negative one away, recording an adjustment in its history. Frozen wallets can be adjusted; closed wallets cannot, and an adjustment cannot take more than the available balance.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Adjust wallet balance",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes retries of the same adjustment safe",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Wallet ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Signed amount and reason",
+                        "name": "adjustment",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.adjustmentRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Wallet"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid wallet ID, amount or reason, or the adjustment would overdraw the wallet",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Wallet not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Wallet frozen or closed, concurrent update, or Idempotency-Key reused",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/admin/wallets/{id}/limits": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get wallet limits",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Wallet ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.WalletLimits"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid wallet ID",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Wallet not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Caps single deposits, withdrawals and transfers, and the withdrawals and transfers out over any 24 hours, in the wallet's currency.\nAn overdraft limit or a minimum balance sets how low withdrawals, transfers and holds may take the balance. Omitted limits are lifted.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Set wallet limits",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Wallet ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "New limits",
+                        "name": "limits",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.limitsRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.WalletLimits"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid wallet ID or limits",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Wallet not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/admin/wallets/{id}/status": {
+            "put": {
+                "description": "Frozen and closed wallets reject deposits, withdrawals and transfers. A closed wallet cannot be reopened.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Change wallet status",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Wallet ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "New status: active, frozen or closed",
+                        "name": "status",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.walletStatusRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Wallet"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid wallet ID or status",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Wallet not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Wallet is closed",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/auth/login": {
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Log in",
+                "parameters": [
+                    {
+                        "description": "Login details",
+                        "name": "credentials",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.loginRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.tokenResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Wrong username or password",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/auth/register": {
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Register user",
+                "parameters": [
+                    {
+                        "description": "Registration details",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.registerRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes retries safe: a repeat with the same key and body gets the first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.tokenResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid registration details",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Username already taken, or Idempotency-Key reused with a different request body",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/exports/{id}": {
+            "get": {
+                "description": "Returns the export's status: pending until the worker has produced it, then ready or failed. A ready export carries a download_url that needs no credentials and works until download_expires_at; read the export again for a fresh one. When auth is enabled only the owner of the export's wallet can see it.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "wallets"
+                ],
+                "summary": "Get an export",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Export ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.ExportJob"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid export ID",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Export not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/payments": {
+            "post": {
+                "description": "Sends the amount, in the merchant's currency, from the wallet to the merchant\naccount for the order. Each order is paid once. When auth is enabled the\nwallet paying must be the caller's.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "payments"
+                ],
+                "summary": "Pay a merchant",
+                "parameters": [
+                    {
+                        "description": "Payer, merchant, amount and order reference",
+                        "name": "payment",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.merchantPaymentRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes retries safe: a repeat with the same key and body gets the first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Owner's transaction PIN, needed over the PIN threshold once they have set one",
+                        "name": "X-Transaction-PIN",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.Payment"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid wallet IDs, amount or order reference, or insufficient funds",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "The paying wallet is not the caller's, or transaction PIN required or incorrect",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Wallet or merchant account not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Order already paid, or a wallet is frozen or closed",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "423": {
+                        "description": "Transaction PIN locked after too many incorrect attempts",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/payments/{id}": {
+            "get": {
+                "description": "When auth is enabled only the owners of the paying and the merchant wallets can see it.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "payments"
+                ],
+                "summary": "Get a payment",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Payment ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Payment"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid payment ID",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Payment not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/payments/{id}/refunds": {
+            "post": {
+                "description": "Sends the amount, or all that is left of the payment when it is omitted, from the\nmerchant back to the payer. A payment can be refunded in several parts up to its\namount. When auth is enabled only the owner of the merchant wallet can refund it.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "payments"
+                ],
+                "summary": "Refund a payment",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Payment ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Partial amount and reason",
+                        "name": "refund",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.refundRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes retries safe: a repeat with the same key and body gets the first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.PaymentRefund"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid payment ID or amount, or the merchant cannot afford the refund",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Only the merchant can refund a payment",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Payment not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "A wallet is frozen or closed",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/transactions/{id}": {
+            "get": {
+                "description": "Looks a transaction up by the id it has in its wallet's history. When auth\nis enabled only the wallet's owner can see it.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "wallets"
+                ],
+                "summary": "Get a transaction",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Transaction ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Transaction"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid transaction ID",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Transaction not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/transactions/{id}/disputes": {
+            "get": {
+                "description": "Returns the transaction's disputes with every status each has been in and\nwhen. When auth is enabled only the owner of the transaction's wallet can see them.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transactions"
+                ],
+                "summary": "List a transaction's disputes",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Transaction ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Dispute"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid transaction ID",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Transaction not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Opens a dispute on the sending side of a transfer. What the recipient was\ncredited is held in their wallet until an operator refunds the transfer or\nrejects the dispute. Each transaction can be disputed once. When auth is\nenabled only the owner of the wallet the transfer was sent from can dispute it.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transactions"
+                ],
+                "summary": "Dispute a transaction",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Transaction ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Why the transfer is disputed",
+                        "name": "dispute",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.disputeRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes retries safe: a repeat with the same key and body gets the first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.Dispute"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid transaction ID or reason, the transaction is not a transfer sent, or the recipient no longer has the funds",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Transaction not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Transaction already disputed or reversed, a merchant payment already refunded, or Idempotency-Key reused with a different request body",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/transactions/{id}/refund": {
+            "post": {
+                "description": "Debits the wallet the deposit credited by the amount, or by what is left of\nthe deposit when it is omitted, as a withdrawal linked to the deposit. A\ndeposit can be refunded in several parts, never for more than it brought in,\nand not once it was reversed. A deposit the payment provider funded is also\nrefunded to the customer through it when a provider API is configured: the\nrefund is pending until the provider has refunded the payment, when it is\ncompleted, and failed and credited back to the wallet if the provider rejects\nit. A provider that cannot be reached is asked again by the refund worker. When\nauth is enabled only the owner of the wallet can refund it.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transactions"
+                ],
+                "summary": "Refund a deposit",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Transaction ID of the deposit",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Partial amount and reason",
+                        "name": "refund",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.depositRefundRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes retries safe: a repeat with the same key and body gets the first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.DepositRefund"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid transaction ID or amount, not a deposit, more than is left of it, or insufficient funds",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Transaction not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Deposit reversed, wallet frozen or closed, or Idempotency-Key reused with a different request body",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Wallet limit exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/transactions/{id}/reverse": {
+            "post": {
+                "description": "Posts every leg of the transaction's journal again in the opposite direction,\nas a reversal that references it. A transfer can be reversed in part by\npassing an amount in the currency it was sent in. Each transaction can be\nreversed once. When auth is enabled only the owner of the wallet that\nreceived the money can reverse it.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transactions"
+                ],
+                "summary": "Reverse a transaction",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Transaction ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Partial amount and reason",
+                        "name": "reversal",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.reversalRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes retries safe: a repeat with the same key and body gets the first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.Journal"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid transaction ID or amount",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Transaction not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Transaction already reversed, refunded or paid out, transfer awaiting acceptance, or Idempotency-Key reused with a different request body",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/transfers/{reference_id}": {
+            "get": {
+                "description": "Looks a transfer up by the reference_id its transactions carry. When auth\nis enabled only the owners of the two wallets can see it.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transfers"
+                ],
+                "summary": "Get a transfer",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Transfer reference ID",
+                        "name": "reference_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Transfer"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid reference ID",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Transfer not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/users": {
+            "get": {
+                "description": "Returns the users that have not been deleted whose name contains q, ignoring case, best matches first and at most 200 per page. On PostgreSQL names similar to q, such as ones with a typo, match too.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Search users",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Part of a name",
+                        "name": "q",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "minimum": 0,
+                        "type": "integer",
+                        "default": 50,
+                        "description": "Page size; larger sizes are cut to 200",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 0,
+                        "type": "integer",
+                        "default": 0,
+                        "description": "Users to skip",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.userListResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Missing query or invalid pagination parameters",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Create user",
+                "parameters": [
+                    {
+                        "description": "User details",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.createUserRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes retries safe: a repeat with the same key and body gets the first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.UserWithWallet"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid user details",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Email or phone already in use, or Idempotency-Key reused with a different request body",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/users/{id}": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Get user",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.UserWithWallet"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the user, for If-Match on PATCH"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid user ID",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Hides the user and closes their wallet. A wallet with money in it is only closed with withdraw_balance=true, which pays the balance out first; wallets with active holds cannot be closed.",
+                "tags": [
+                    "users"
+                ],
+                "summary": "Delete user",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Withdraw any remaining balance before closing the wallet",
+                        "name": "withdraw_balance",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Invalid user ID or withdraw_balance",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Wallet frozen or still holds a balance",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Changes the name, email or phone of the user, leaving fields the body omits as they are; an empty email or phone removes it. Emails and phone numbers belong to one user at a time. With If-Match, the update is only made while the user is still at a version it names, taken from the ETag of GET /users/{id}.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Update user",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Profile fields to change",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.updateUserRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the user the update was based on",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.User"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the updated user"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid user ID, profile fields or If-Match",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Email or phone already in use",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "User changed since the If-Match version",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/users/{id}/kyc": {
+            "get": {
+                "description": "Returns the user's verification tier, the daily limits it holds their wallet to, and the documents they submitted, newest first.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Get KYC profile",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.KYCProfile"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid user ID",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/users/{id}/kyc/documents": {
+            "post": {
+                "description": "Records a document the user uploaded to the document store for an operator to review. Reviewing it does not change the user's tier by itself.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Submit a KYC document",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Document type and where it was uploaded",
+                        "name": "document",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.submitKYCDocumentRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.KYCDocument"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid user ID or document",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/users/{id}/notification-preferences": {
+            "get": {
+                "description": "Users who never chose their alerts get the server's defaults.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Get notification preferences",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.NotificationPreferences"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid user ID",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Alerts go to the user's email address: withdrawals of at least large_withdrawal_threshold, transfers in when incoming_transfer is true, and the low balance alerts set on their wallet.\nThe threshold is in the wallet's currency; omitting it turns its alert off.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Set notification preferences",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Alerts to send",
+                        "name": "preferences",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.notificationPreferencesRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.NotificationPreferences"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid user ID or thresholds",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/users/{id}/transaction-pin": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Get transaction PIN status",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.TransactionPINStatus"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid user ID",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Once set, withdrawals and transfers of more than the PIN threshold out of the user's wallet need the PIN in the X-Transaction-PIN header.\nChanging a PIN takes the current one as current_pin, which counts towards the lockout like any other attempt.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Set transaction PIN",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "New PIN of 4 to 8 digits",
+                        "name": "pin",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.setTransactionPINRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.TransactionPINStatus"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid user ID or PIN",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Current PIN incorrect",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "423": {
+                        "description": "PIN locked after too many incorrect attempts",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "tags": [
+                    "users"
+                ],
+                "summary": "Remove transaction PIN",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "The current PIN",
+                        "name": "X-Transaction-PIN",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Invalid user ID",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "PIN missing or incorrect",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found or no PIN set",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "423": {
+                        "description": "PIN locked after too many incorrect attempts",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/users/{id}/wallet": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Get user wallet",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Wallet"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid user ID",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "User or wallet not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/wallets/{id}/alerts": {
+            "get": {
+                "description": "Returns the low balance alerts raised for the wallet, newest first, at most 200 per page.\nEach names the withdrawal or transfer that took the balance below the threshold.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "wallets"
+                ],
+                "summary": "List wallet alerts",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Wallet ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "minimum": 0,
+                        "type": "integer",
+                        "default": 50,
+                        "description": "Page size; larger sizes are cut to 200",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 0,
+                        "type": "integer",
+                        "default": 0,
+                        "description": "Alerts to skip",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.alertListResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid wallet ID or pagination parameters",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Wallet not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/wallets/{id}/alerts/settings": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "wallets"
+                ],
+                "summary": "Get wallet alert settings",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Wallet ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.WalletAlertSettings"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid wallet ID",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Wallet not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "A withdrawal, transfer or hold capture that takes the balance from at or above low_balance_threshold to below it records an alert and emails it to the wallet's owner.\nThe threshold is in the wallet's currency; omitting it turns low balance alerts off.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "wallets"
+                ],
+                "summary": "Set wallet alert settings",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Wallet ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "New alert settings",
+                        "name": "settings",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.alertSettingsRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.WalletAlertSettings"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid wallet ID or threshold",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Wallet not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/wallets/{id}/analytics": {
+            "get": {
+                "description": "Counts and sums the wallet's transactions in the period by the category they\nwere filed under, in the wallet's currency. Uncategorized transactions are\ntotalled last, under a null category.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "wallets"
+                ],
+                "summary": "Get wallet analytics",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Wallet ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Period start as YYYY-MM-DD or RFC3339; defaults to when the wallet was created",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Period end as YYYY-MM-DD (inclusive) or RFC3339 (exclusive); defaults to now",
+                        "name": "to",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.WalletAnalytics"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid wallet ID or period",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Wallet not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/wallets/{id}/balance": {
+            "get": {
+                "description": "Returns the wallet. With at, returns instead its posted balance at that\ntime as a models.HistoricalBalance, counting the transactions made before it.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "wallets"
+                ],
+                "summary": "Get wallet balance",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Wallet ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "RFC3339 time to reconstruct the balance at",
+                        "name": "at",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Wallet"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "The wallet's version, for If-Match on withdrawals and transfers"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid wallet ID or time",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Wallet not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/wallets/{id}/deposit": {
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "wallets"
+                ],
+                "summary": "Deposit to wallet",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Wallet ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Deposit details",
+                        "name": "deposit",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.depositRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes retries safe: a repeat with the same key and body gets the first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Wallet"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid wallet ID, request body or amount",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Wallet not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Wallet frozen or closed, concurrent update, or Idempotency-Key reused",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Wallet limit exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/wallets/{id}/exports": {
+            "post": {
+                "description": "Queues an export of the wallet's statement for a period as CSV, in the same format as the statement endpoint, for wallets with too many transactions to stream within a request. The export worker produces the file; poll the export until it is ready, then download the file from its download_url. Needs an export bucket to be configured.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "wallets"
+                ],
+                "summary": "Export transactions in the background",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Wallet ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Period to export",
+                        "name": "export",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.exportRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/models.ExportJob"
+                        },
+                        "headers": {
+                            "Location": {
+                                "type": "string",
+                                "description": "The export to poll"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid wallet ID, request body or period",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Exports not configured",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Wallet not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/wallets/{id}/fees/quote": {
+            "post": {
+                "description": "Returns the fee the wallet would be charged on a deposit, withdrawal or transfer of the amount, and what the two together would do to its balance.\nNothing is moved. A fee of 0 means the operation is not charged one.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "wallets"
+                ],
+                "summary": "Preview a fee",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Wallet ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Operation and amount",
+                        "name": "quote",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.feeQuoteRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.FeeQuote"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid wallet ID, operation or amount, or a currency the wallet does not hold",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Wallet not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/wallets/{id}/holds": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "holds"
+                ],
+                "summary": "List holds",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Wallet ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Hold"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid wallet ID",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Wallet not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Reduces the available balance without posting a transaction until the hold is captured or released",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "holds"
+                ],
+                "summary": "Place a hold",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Wallet ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Hold details",
+                        "name": "hold",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.holdRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes retries safe: a repeat with the same key and body gets the first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.Hold"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid wallet ID or amount, or insufficient funds",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Wallet not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Wallet frozen or closed, concurrent update, or Idempotency-Key reused",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/wallets/{id}/holds/{holdID}/capture": {
+            "post": {
+                "description": "Posts the hold, or part of it, as a withdrawal; any uncaptured remainder is released",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "holds"
+                ],
+                "summary": "Capture a hold",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Wallet ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Hold ID",
+                        "name": "holdID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Partial capture amount",
+                        "name": "capture",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.captureRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes retries safe: a repeat with the same key and body gets the first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Owner's transaction PIN, needed over the PIN threshold once they have set one",
+                        "name": "X-Transaction-PIN",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Hold"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid wallet ID, hold ID or amount",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Transaction PIN required or incorrect",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Hold not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Hold is no longer active or secures a dispute, concurrent update, or Idempotency-Key reused with a different request body",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "423": {
+                        "description": "Transaction PIN locked after too many incorrect attempts",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/wallets/{id}/holds/{holdID}/release": {
+            "post": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "holds"
+                ],
+                "summary": "Release a hold",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Wallet ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Hold ID",
+                        "name": "holdID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes retries safe: a repeat with the same key and body gets the first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Hold"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid wallet ID or hold ID",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Hold not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Hold is no longer active or secures a dispute, or Idempotency-Key reused with a different request body",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/wallets/{id}/incoming-transfers": {
+            "get": {
+                "description": "Returns the transfers into the wallet that were held for acceptance, newest first, in whatever status they are now",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "incoming-transfers"
+                ],
+                "summary": "List incoming transfers",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Receiving wallet ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.IncomingTransfer"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid wallet ID",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/wallets/{id}/incoming-transfers/settings": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "incoming-transfers"
+                ],
+                "summary": "Get incoming transfer settings",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Wallet ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.IncomingTransferSettings"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid wallet ID",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Wallet not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "With require_acceptance set, what a transfer into the wallet credits is held there until the owner accepts it.\nOne rejected, or not accepted within the acceptance window, is returned to its sender; the fee the sender paid is not.\nTurning it off leaves transfers already waiting to be accepted or rejected.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "incoming-transfers"
+                ],
+                "summary": "Set incoming transfer settings",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Wallet ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "New incoming transfer settings",
+                        "name": "settings",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.incomingTransferSettingsRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.IncomingTransferSettings"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid wallet ID or request body",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Wallet not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/wallets/{id}/incoming-transfers/{transferID}/accept": {
+            "post": {
+                "description": "Releases the hold on what the transfer credited, making it available. One accepted after its\nacceptance window ended is returned to its sender instead and answered with 409.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "incoming-transfers"
+                ],
+                "summary": "Accept an incoming transfer",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Receiving wallet ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Incoming transfer ID",
+                        "name": "transferID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.IncomingTransfer"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid wallet ID or incoming transfer ID",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Incoming transfer not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Transfer is no longer pending or has expired",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/wallets/{id}/incoming-transfers/{transferID}/reject": {
+            "post": {
+                "description": "Reverses the transfer, paying what it credited back to the sender in the currency they sent it in. The fee the sender paid is kept.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "incoming-transfers"
+                ],
+                "summary": "Reject an incoming transfer",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Receiving wallet ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Incoming transfer ID",
+                        "name": "transferID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.IncomingTransfer"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid wallet ID or incoming transfer ID",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Incoming transfer not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Transfer is no longer pending, or a wallet is frozen or closed",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/wallets/{id}/payment-requests": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "payment-requests"
+                ],
+                "summary": "List pending payment requests",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Paying wallet ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.PaymentRequest"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid wallet ID",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "The payer sees the request in its pending list and can accept or decline it",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "payment-requests"
+                ],
+                "summary": "Request a payment",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Requesting wallet ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Payment request details",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.paymentRequestRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes retries safe: a repeat with the same key and body gets the first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.PaymentRequest"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid wallet ID or request body",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Wallet not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Idempotency-Key reused with a different request body",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Amount over the confirmation threshold",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/wallets/{id}/payment-requests/{requestID}/accept": {
+            "post": {
+                "description": "Transfers the requested amount to the requesting wallet",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "payment-requests"
+                ],
+                "summary": "Accept a payment request",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Paying wallet ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Payment request ID",
+                        "name": "requestID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes retries safe: a repeat with the same key and body gets the first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Owner's transaction PIN, needed over the PIN threshold once they have set one",
+                        "name": "X-Transaction-PIN",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.PaymentRequest"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid wallet ID or payment request ID, or insufficient funds",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Transaction PIN required or incorrect",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Payment request not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Payment request is not pending, a wallet is frozen or closed, or Idempotency-Key reused with a different request body",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Wallet limit exceeded, or amount over the confirmation threshold",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "423": {
+                        "description": "Transaction PIN locked after too many incorrect attempts",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/wallets/{id}/payment-requests/{requestID}/decline": {
+            "post": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "payment-requests"
+                ],
+                "summary": "Decline a payment request",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Paying wallet ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Payment request ID",
+                        "name": "requestID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes retries safe: a repeat with the same key and body gets the first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.PaymentRequest"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid wallet ID or payment request ID",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Payment request not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Payment request is not pending, or Idempotency-Key reused with a different request body",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/wallets/{id}/payouts": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "payouts"
+                ],
+                "summary": "List payouts",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Wallet ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "initiated, sent, settled or failed",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 0,
+                        "type": "integer",
+                        "default": 50,
+                        "description": "Page size; larger sizes are cut to 200",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 0,
+                        "type": "integer",
+                        "default": 0,
+                        "description": "Payouts to skip",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.payoutListResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid wallet ID, status or pagination parameters",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Debits the wallet as a withdrawal and sends the money to the bank account through the payout provider. The payout is initiated, then sent once the provider has it, and ends settled, or failed with the amount credited back to the wallet; the withdrawal fee is not refunded. A payout the provider could not be reached about stays initiated and is sent again by the payout worker.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "payouts"
+                ],
+                "summary": "Pay out to a bank account",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Wallet ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Amount and bank account",
+                        "name": "payout",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.payoutRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes retries safe: a repeat with the same key and body gets the first payout",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Owner's transaction PIN, needed over the PIN threshold once they have set one",
+                        "name": "X-Transaction-PIN",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.Payout"
+                        },
+                        "headers": {
+                            "Location": {
+                                "type": "string",
+                                "description": "The created payout"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid wallet ID, request body, amount or bank account, or insufficient funds",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Payouts or withdrawals disabled, blocked by a risk check, or transaction PIN required or incorrect",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Wallet not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Wallet frozen or closed, concurrent update, or Idempotency-Key reused",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Wallet limit exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "423": {
+                        "description": "Transaction PIN locked after too many incorrect attempts",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/wallets/{id}/payouts/{payoutID}": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "payouts"
+                ],
+                "summary": "Get payout",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Wallet ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Payout ID",
+                        "name": "payoutID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Payout"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid wallet or payout ID",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Payout not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/wallets/{id}/pending-transfers": {
+            "get": {
+                "description": "Returns the wallet's transfers that were held for confirmation, newest first, in whatever status they are now",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "pending-transfers"
+                ],
+                "summary": "List pending transfers",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Sending wallet ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.PendingTransfer"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid wallet ID",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/wallets/{id}/pending-transfers/{transferID}/cancel": {
+            "post": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "pending-transfers"
+                ],
+                "summary": "Cancel a pending transfer",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Sending wallet ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Pending transfer ID",
+                        "name": "transferID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes retries safe: a repeat with the same key and body gets the first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.PendingTransfer"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid wallet ID or pending transfer ID",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Pending transfer not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Transfer is no longer pending, or Idempotency-Key reused with a different request body",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/wallets/{id}/pending-transfers/{transferID}/confirm": {
+            "post": {
+                "description": "Moves the money of a transfer that was held for being over the confirmation threshold.\nA transfer that cannot run, such as for want of funds, is failed with the reason; one\nconfirmed after it expired is cancelled.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "pending-transfers"
+                ],
+                "summary": "Confirm a pending transfer",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Sending wallet ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Pending transfer ID",
+                        "name": "transferID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes retries safe: a repeat with the same key and body gets the first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.PendingTransfer"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid wallet ID or pending transfer ID, or insufficient funds",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Pending transfer not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Transfer is no longer pending or has expired, a wallet is frozen or closed, or Idempotency-Key reused with a different request body",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Wallet limit exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/wallets/{id}/scheduled-transfers": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "scheduled-transfers"
+                ],
+                "summary": "List scheduled transfers",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Source wallet ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.ScheduledTransfer"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid wallet ID",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Runs once at start_at, or daily, weekly or monthly from start_at until cancelled",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "scheduled-transfers"
+                ],
+                "summary": "Schedule a transfer",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Source wallet ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Scheduled transfer details",
+                        "name": "transfer",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.scheduledTransferRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes retries safe: a repeat with the same key and body gets the first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Owner's transaction PIN, needed over the PIN threshold once they have set one",
+                        "name": "X-Transaction-PIN",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.ScheduledTransfer"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid wallet ID or schedule",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Transaction PIN required or incorrect",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Idempotency-Key reused with a different request body",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Amount over the confirmation threshold",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "423": {
+                        "description": "Transaction PIN locked after too many incorrect attempts",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/wallets/{id}/scheduled-transfers/{transferID}": {
+            "delete": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "scheduled-transfers"
+                ],
+                "summary": "Cancel a scheduled transfer",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Source wallet ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Scheduled transfer ID",
+                        "name": "transferID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.ScheduledTransfer"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid wallet ID or scheduled transfer ID",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Scheduled transfer not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Scheduled transfer is no longer active",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/wallets/{id}/settlement": {
+            "get": {
+                "description": "Counts and sums the payments the merchant wallet took and the refunds it made\nin the period, and the net of the two. Refunds count in the period they were made.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "payments"
+                ],
+                "summary": "Get a merchant settlement summary",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Merchant wallet ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Period start as YYYY-MM-DD or RFC3339; defaults to when the wallet became a merchant account",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Period end as YYYY-MM-DD (inclusive) or RFC3339 (exclusive); defaults to now",
+                        "name": "to",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.MerchantSettlement"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid wallet ID or period",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Wallet or merchant account not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/wallets/{id}/statement": {
+            "get": {
+                "description": "Opening balance, each transaction with the running balance, and closing balance for a period. CSV is streamed row by row.",
+                "produces": [
+                    "text/csv",
+                    "application/pdf"
+                ],
+                "tags": [
+                    "wallets"
+                ],
+                "summary": "Export wallet statement",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Wallet ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Period start as YYYY-MM-DD or RFC3339; defaults to when the wallet was opened",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Period end as YYYY-MM-DD (inclusive) or RFC3339 (exclusive); defaults to now",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "csv",
+                            "pdf"
+                        ],
+                        "type": "string",
+                        "default": "csv",
+                        "description": "Statement format",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid wallet ID, period or format",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Wallet not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/wallets/{id}/sweep": {
+            "post": {
+                "description": "Transfers everything the wallet can spend to the recipient, for instance before closing it. The amount is worked out by the server as the transfer runs, so it cannot race a balance read by the client.\nHeld funds stay in the wallet and an overdraft is not drawn on. The transfer fee is taken out of what is swept. Sweeps cannot be held for confirmation, so one over the confirmation threshold is refused.\nA wallet with nothing to sweep is answered with 400 and code INSUFFICIENT_FUNDS. A retry with the same Idempotency-Key gets the sweep it first made.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "wallets"
+                ],
+                "summary": "Sweep a wallet",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Wallet ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Recipient: one of to_wallet_id, to_user_id or to_username",
+                        "name": "sweep",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.sweepRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes retries safe: a repeat with the same key and recipient gets the first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Owner's transaction PIN, needed over the PIN threshold once they have set one",
+                        "name": "X-Transaction-PIN",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the wallet from its balance; the request fails with 412 if the wallet has changed since",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.transferResponse"
+                        },
+                        "headers": {
+                            "Location": {
+                                "type": "string",
+                                "description": "The created transfer"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid wallet ID or request body, or nothing to sweep",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Transaction PIN required or incorrect",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Wallet or recipient not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Wallet frozen or closed, concurrent update, or Idempotency-Key reused",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "Wallet changed since the ETag in If-Match was read",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Wallet limit exceeded, or amount over the confirmation threshold",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "423": {
+                        "description": "Transaction PIN locked after too many incorrect attempts",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/wallets/{id}/transactions": {
+            "get": {
+                "description": "Newest first. Carries the latest transaction as an ETag and Last-Modified, so polling clients can send If-None-Match or If-Modified-Since and get 304 Not Modified until a transaction is added.\nWith category, only the transactions filed under it are listed.\nWith limit, sort or cursor the history is paged: a full page comes with a Link header to the next one.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "wallets"
+                ],
+                "summary": "Get wallet transaction history",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Wallet ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only list transactions in this category",
+                        "name": "category",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 0,
+                        "type": "integer",
+                        "default": 50,
+                        "description": "Page size; larger sizes are cut to 200",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "created_at",
+                            "-created_at"
+                        ],
+                        "type": "string",
+                        "default": "-created_at",
+                        "description": "created_at, or -created_at for newest first",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor from the Link header of the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of a history already held; answered with 304 while it is current",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Last-Modified of a history already held; answered with 304 while it is current",
+                        "name": "If-Modified-Since",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Transaction"
+                            }
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "The ID of the latest transaction"
+                            },
+                            "Last-Modified": {
+                                "type": "string",
+                                "description": "When the latest transaction was posted"
+                            },
+                            "Link": {
+                                "type": "string",
+                                "description": "The next page of a paged history, as rel=\\\"next\\"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "The history has not changed"
+                    },
+                    "400": {
+                        "description": "Invalid wallet ID, page, sort or cursor",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Wallet not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/wallets/{id}/transfer": {
+            "post": {
+                "description": "The recipient is a wallet ID, or a user ID or username whose wallet is credited.\nThe amount is in the sender's currency; a recipient holding another currency is credited its conversion at the current exchange rate.\nWith quote_id, the transfer runs at the fee and exchange rate of that quote, once and before it expires.\nA transfer over the confirmation threshold moves no money yet: it is answered with 202 and a pending transfer to confirm, and cannot pass quote_id.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "wallets"
+                ],
+                "summary": "Transfer between wallets",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Wallet ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Transfer details",
+                        "name": "transfer",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.transferRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes retries safe: a repeat with the same key and body gets the first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Owner's transaction PIN, needed over the PIN threshold once they have set one",
+                        "name": "X-Transaction-PIN",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the wallet from its balance; the request fails with 412 if the wallet has changed since",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Wallet"
+                        }
+                    },
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/models.PendingTransfer"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid wallet ID, request body or amount, or insufficient funds",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Transaction PIN required or incorrect",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Wallet, recipient or quote not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Wallet frozen or closed, concurrent update, quote expired or already used, or Idempotency-Key reused",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "Wallet changed since the ETag in If-Match was read",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Wallet limit exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "423": {
+                        "description": "Transaction PIN locked after too many incorrect attempts",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/wallets/{id}/transfer/quote": {
+            "post": {
+                "description": "Returns what a transfer would debit the sender, fee included, and credit the recipient, at the current exchange rate when they hold different currencies.\nNothing is moved. Passing the quote's id as quote_id to the transfer before expires_at makes it at the quoted fee and rate; each quote can be used once.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "wallets"
+                ],
+                "summary": "Quote a transfer",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Wallet ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Transfer details",
+                        "name": "transfer",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.transferRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.TransferQuote"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid wallet ID, request body or amount, or a currency the wallet does not hold",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Wallet or recipient not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Wallet frozen or closed",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "503": {
+                        "description": "No exchange rate is available for the currency pair",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/wallets/{id}/transfers": {
+            "get": {
+                "description": "Returns the wallet's transfers, newest first, at most 200 per page. Unlike\nthe transaction history, each transfer names its direction and counterparty.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transfers"
+                ],
+                "summary": "List wallet transfers",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Wallet ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "minimum": 0,
+                        "type": "integer",
+                        "default": 50,
+                        "description": "Page size; larger sizes are cut to 200",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 0,
+                        "type": "integer",
+                        "default": 0,
+                        "description": "Transfers to skip",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.transferListResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid wallet ID or pagination parameters",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Wallet not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/wallets/{id}/transfers/batch": {
+            "post": {
+                "description": "Posts up to 100 transfers in a single transaction. If any transfer fails none are posted, and the results name the one that failed.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "wallets"
+                ],
+                "summary": "Batch transfer",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Wallet ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Transfers to post",
+                        "name": "batch",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.batchTransferRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes retries safe: a repeat with the same key and body gets the first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Owner's transaction PIN, needed over the PIN threshold once they have set one",
+                        "name": "X-Transaction-PIN",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.batchTransferResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "A transfer was invalid or overdrew the wallet; the results name it",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.batchTransferResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Transaction PIN required or incorrect",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "A wallet or recipient was not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.batchTransferResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "A wallet is frozen or closed, or was updated concurrently",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.batchTransferResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "A transfer broke a wallet limit, or the batch adds up to more than the confirmation threshold",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.batchTransferResponse"
+                        }
+                    },
+                    "423": {
+                        "description": "Transaction PIN locked after too many incorrect attempts",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/wallets/{id}/withdraw": {
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "wallets"
+                ],
+                "summary": "Withdraw from wallet",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Wallet ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Withdraw details",
+                        "name": "withdraw",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.withdrawRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes retries safe: a repeat with the same key and body gets the first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Owner's transaction PIN, needed over the PIN threshold once they have set one",
+                        "name": "X-Transaction-PIN",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the wallet from its balance; the request fails with 412 if the wallet has changed since",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Wallet"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid wallet ID, request body or amount, or insufficient funds",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Transaction PIN required or incorrect",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Wallet not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Wallet frozen or closed, concurrent update, or Idempotency-Key reused",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "Wallet changed since the ETag in If-Match was read",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Wallet limit exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "423": {
+                        "description": "Transaction PIN locked after too many incorrect attempts",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/webhooks/payments": {
+            "post": {
+                "description": "Called by the payment provider, not by clients. The body must be signed in the X-Payment-Signature header as t=\u003cunix time\u003e,v1=\u003chex HMAC-SHA256 of \"\u003cunix time\u003e.\u003cbody\u003e\"\u003e with the webhook secret, within the allowed tolerance. payment.succeeded deposits the payment into its wallet once, however often and in whatever order the events arrive, without a fee or the wallet's limits; payment.pending and payment.failed only record its status. Other event types are acknowledged and ignored. A 2xx answer tells the provider to stop delivering the event; any other makes it try again later.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Receive a payment provider event",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Signature of the body",
+                        "name": "X-Payment-Signature",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Payment event",
+                        "name": "event",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.paymentEvent"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProviderPayment"
+                        }
+                    },
+                    "204": {
+                        "description": "Event type ignored"
+                    },
+                    "400": {
+                        "description": "Malformed event, or invalid payment ID, wallet or amount",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing, invalid or expired signature",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Wallet not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Wallet frozen or closed, concurrent update, or payment reported with another wallet or amount",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v2/wallets/{id}/deposit": {
+            "post": {
+                "description": "Unlike v1, responds with the transaction, the wallet's balance before and after it and the wallet, and links to the transaction in the Location header.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "wallets"
+                ],
+                "summary": "Deposit to wallet",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Wallet ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Deposit details",
+                        "name": "deposit",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.depositRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes retries safe: a repeat with the same key and body gets the first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.movementResponse"
+                        },
+                        "headers": {
+                            "Location": {
+                                "type": "string",
+                                "description": "The created transaction"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid wallet ID, request body or amount",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Wallet not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Wallet frozen or closed, concurrent update, or Idempotency-Key reused",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Wallet limit exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v2/wallets/{id}/transfer": {
+            "post": {
+                "description": "The recipient is a wallet ID, or a user ID or username whose wallet is credited.\nThe amount is in the sender's currency; a recipient holding another currency is credited its conversion at the current exchange rate.\nWith quote_id, the transfer runs at the fee and exchange rate of that quote, once and before it expires.\nUnlike v1, responds with the transfer: its legs, the IDs of the transactions in each wallet's history and both wallets' balances after it. The Location header links to the transfer.\nA transfer over the confirmation threshold moves no money yet: it is answered with 202 and a pending transfer to confirm, and cannot pass quote_id.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "wallets"
+                ],
+                "summary": "Transfer between wallets",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Wallet ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Transfer details",
+                        "name": "transfer",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.transferRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes retries safe: a repeat with the same key and body gets the first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Owner's transaction PIN, needed over the PIN threshold once they have set one",
+                        "name": "X-Transaction-PIN",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the wallet from its balance; the request fails with 412 if the wallet has changed since",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.transferResponse"
+                        },
+                        "headers": {
+                            "Location": {
+                                "type": "string",
+                                "description": "The created transfer"
+                            }
+                        }
+                    },
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/models.PendingTransfer"
+                        },
+                        "headers": {
+                            "Location": {
+                                "type": "string",
+                                "description": "The transfer awaiting confirmation"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid wallet ID, request body or amount, or insufficient funds",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Transaction PIN required or incorrect",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Wallet, recipient or quote not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Wallet frozen or closed, concurrent update, quote expired or already used, or Idempotency-Key reused",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "Wallet changed since the ETag in If-Match was read",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Wallet limit exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "423": {
+                        "description": "Transaction PIN locked after too many incorrect attempts",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v2/wallets/{id}/withdraw": {
+            "post": {
+                "description": "Unlike v1, responds with the transaction, the wallet's balance before and after it and the wallet, and links to the transaction in the Location header.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "wallets"
+                ],
+                "summary": "Withdraw from wallet",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Wallet ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Withdraw details",
+                        "name": "withdraw",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.withdrawRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes retries safe: a repeat with the same key and body gets the first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Owner's transaction PIN, needed over the PIN threshold once they have set one",
+                        "name": "X-Transaction-PIN",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the wallet from its balance; the request fails with 412 if the wallet has changed since",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.movementResponse"
+                        },
+                        "headers": {
+                            "Location": {
+                                "type": "string",
+                                "description": "The created transaction"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid wallet ID, request body or amount, or insufficient funds",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Transaction PIN required or incorrect",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Wallet not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Wallet frozen or closed, concurrent update, or Idempotency-Key reused",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "Wallet changed since the ETag in If-Match was read",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Wallet limit exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "423": {
+                        "description": "Transaction PIN locked after too many incorrect attempts",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/health": {
+            "get": {
+                "description": "Reports every dependency check with its recent latencies. Returns 503\nwhen a dependency the service cannot work without is down.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Health check",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/health.HealthReport"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/health.HealthReport"
+                        }
+                    }
+                }
+            }
+        },
+        "/live": {
+            "get": {
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Liveness probe",
+                "responses": {
+                    "200": {
+                        "description": "Alive",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/ready": {
+            "get": {
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Readiness probe",
+                "responses": {
+                    "200": {
+                        "description": "Ready",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "503": {
+                        "description": "Not Ready",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/ws": {
+            "get": {
+                "description": "Upgrades to a WebSocket. Send {\"type\":\"subscribe\",\"wallet_ids\":[...]} to watch your wallets, or \"unsubscribe\" to stop; each deposit, withdrawal and incoming transfer is then pushed as a \"balance\" message with the wallet's new balance. A client too slow to keep up is disconnected and should reconnect and read its balances again.",
+                "tags": [
+                    "wallets"
+                ],
+                "summary": "Stream balance updates",
+                "responses": {
+                    "101": {
+                        "description": "Switching Protocols",
+                        "schema": {
+                            "$ref": "#/definitions/realtime.BalanceMessage"
+                        }
+                    },
+                    "503": {
+                        "description": "Server is shutting down",
+                        "schema": {
+                            "$ref": "#/definitions/response.Problem"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "featureflag.Flag": {
+            "type": "object",
+            "properties": {
+                "default": {
+                    "type": "boolean"
+                },
+                "enabled": {
+                    "type": "boolean"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "overridden": {
+                    "description": "Overridden is true when Enabled was set at runtime rather than by the default",
+                    "type": "boolean"
+                }
+            }
+        },
+        "handlers.adjustmentRequest": {
+            "type": "object",
+            "required": [
+                "amount",
+                "reason"
+            ],
+            "properties": {
+                "amount": {
+                    "type": "string",
+                    "example": "-12.50"
+                },
+                "currency": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string",
+                    "example": "Refund of duplicate card fee"
+                }
+            }
+        },
+        "handlers.alertListResponse": {
+            "type": "object",
+            "properties": {
+                "alerts": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.WalletAlert"
+                    }
+                },
+                "limit": {
+                    "type": "integer"
+                },
+                "offset": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.alertSettingsRequest": {
+            "type": "object",
+            "properties": {
+                "low_balance_threshold": {
+                    "type": "number",
+                    "example": 50
+                }
+            }
+        },
+        "handlers.amlReportListResponse": {
+            "type": "object",
+            "properties": {
+                "limit": {
+                    "type": "integer"
+                },
+                "offset": {
+                    "type": "integer"
+                },
+                "reports": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.AMLReport"
+                    }
+                }
+            }
+        },
+        "handlers.auditListResponse": {
+            "type": "object",
+            "properties": {
+                "entries": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.AuditEntry"
+                    }
+                },
+                "limit": {
+                    "type": "integer"
+                },
+                "next_cursor": {
+                    "type": "string"
+                },
+                "offset": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.batchTransferRequest": {
+            "type": "object",
+            "required": [
+                "transfers"
+            ],
+            "properties": {
+                "transfers": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.transferRequest"
+                    }
+                }
+            }
+        },
+        "handlers.batchTransferResponse": {
+            "type": "object",
+            "properties": {
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.batchTransferResult"
+                    }
+                },
+                "status": {
+                    "type": "string",
+                    "example": "completed"
+                }
+            }
+        },
+        "handlers.batchTransferResult": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "index": {
+                    "type": "integer"
+                },
+                "journal_id": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "completed"
+                },
+                "to_wallet_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.captureRequest": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "description": "Amount to capture; the whole hold when omitted",
+                    "type": "string",
+                    "example": "25.00"
+                },
+                "currency": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.correctionRequest": {
+            "type": "object",
+            "required": [
+                "reason"
+            ],
+            "properties": {
+                "amount": {
+                    "description": "Amount to correct, in the currency the transfer was sent in; the whole transaction when omitted",
+                    "type": "string",
+                    "example": "5.00"
+                },
+                "currency": {
+                    "type": "string"
+                },
+                "note": {
+                    "type": "string",
+                    "example": "Card payment captured twice, ticket 4821"
+                },
+                "reason": {
+                    "description": "Why the transaction was wrong: duplicate, wrong_amount, wrong_recipient, fraud or system_error",
+                    "type": "string",
+                    "example": "duplicate"
+                }
+            }
+        },
+        "handlers.createUserRequest": {
+            "type": "object",
+            "required": [
+                "name"
+            ],
+            "properties": {
+                "email": {
+                    "type": "string",
+                    "example": "alice@example.com"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "phone": {
+                    "type": "string",
+                    "example": "+447700900123"
+                }
+            }
+        },
+        "handlers.depositRefundRequest": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "description": "Amount to refund, in the deposit's currency; what is left of the deposit when omitted",
+                    "type": "string",
+                    "example": "10.00"
+                },
+                "currency": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string",
+                    "example": "Duplicate top-up"
+                }
+            }
+        },
+        "handlers.depositRequest": {
+            "type": "object",
+            "required": [
+                "amount"
+            ],
+            "properties": {
+                "amount": {
+                    "type": "string",
+                    "example": "25.00"
+                },
+                "category": {
+                    "type": "string",
+                    "maxLength": 50,
+                    "example": "salary"
+                },
+                "currency": {
+                    "type": "string"
+                },
+                "tags": {
+                    "type": "array",
+                    "maxItems": 10,
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "handlers.discrepancyListResponse": {
+            "type": "object",
+            "properties": {
+                "discrepancies": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.BalanceDiscrepancy"
+                    }
+                },
+                "limit": {
+                    "type": "integer"
+                },
+                "offset": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.disputeListResponse": {
+            "type": "object",
+            "properties": {
+                "disputes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Dispute"
+                    }
+                },
+                "limit": {
+                    "type": "integer"
+                },
+                "offset": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.disputeRequest": {
+            "type": "object",
+            "required": [
+                "reason"
+            ],
+            "properties": {
+                "reason": {
+                    "type": "string",
+                    "maxLength": 500,
+                    "example": "Goods never arrived"
+                }
+            }
+        },
+        "handlers.ensureSystemWalletRequest": {
+            "type": "object",
+            "required": [
+                "kind"
+            ],
+            "properties": {
+                "currency": {
+                    "type": "string",
+                    "example": "USD"
+                },
+                "kind": {
+                    "description": "Kind is treasury, suspense or fees",
+                    "type": "string",
+                    "example": "fees"
+                }
+            }
+        },
+        "handlers.exportRequest": {
+            "type": "object",
+            "properties": {
+                "from": {
+                    "description": "Period start as YYYY-MM-DD or RFC3339; when the wallet was opened when omitted",
+                    "type": "string",
+                    "example": "2024-07-01"
+                },
+                "to": {
+                    "description": "Period end as YYYY-MM-DD (inclusive) or RFC3339 (exclusive); now when omitted",
+                    "type": "string",
+                    "example": "2024-07-31"
+                }
+            }
+        },
+        "handlers.featureFlagListResponse": {
+            "type": "object",
+            "properties": {
+                "flags": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/featureflag.Flag"
+                    }
+                }
+            }
+        },
+        "handlers.featureFlagRequest": {
+            "type": "object",
+            "properties": {
+                "enabled": {
+                    "type": "boolean",
+                    "example": false
+                }
+            }
+        },
+        "handlers.feeQuoteRequest": {
+            "type": "object",
+            "required": [
+                "amount",
+                "operation"
+            ],
+            "properties": {
+                "amount": {
+                    "type": "string",
+                    "example": "250"
+                },
+                "currency": {
+                    "type": "string"
+                },
+                "operation": {
+                    "type": "string",
+                    "enum": [
+                        "deposit",
+                        "withdraw",
+                        "transfer"
+                    ],
+                    "example": "transfer"
+                }
+            }
+        },
+        "handlers.holdRequest": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "string",
+                    "example": "25.00"
+                },
+                "currency": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.incomingTransferSettingsRequest": {
+            "type": "object",
+            "properties": {
+                "require_acceptance": {
+                    "type": "boolean",
+                    "example": true
+                }
+            }
+        },
+        "handlers.kycDocumentListResponse": {
+            "type": "object",
+            "properties": {
+                "documents": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.KYCDocument"
+                    }
+                },
+                "limit": {
+                    "type": "integer"
+                },
+                "offset": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.limitsRequest": {
+            "type": "object",
+            "properties": {
+                "daily_transfer_limit": {
+                    "type": "number",
+                    "example": 2000
+                },
+                "daily_withdrawal_limit": {
+                    "type": "number",
+                    "example": 1000
+                },
+                "max_transaction_amount": {
+                    "type": "number",
+                    "example": 500
+                },
+                "minimum_balance": {
+                    "type": "number"
+                },
+                "overdraft_limit": {
+                    "description": "OverdraftLimit lets the balance go this far below zero; MinimumBalance keeps this\nmuch in the wallet. Only one of them can be set.",
+                    "type": "number",
+                    "example": 100
+                }
+            }
+        },
+        "handlers.logLevelRequest": {
+            "type": "object",
+            "properties": {
+                "duration": {
+                    "description": "Duration is how long the level lasts, such as \"15m\". Left out, it lasts until\nit is changed or reset.",
+                    "type": "string",
+                    "example": "15m"
+                },
+                "level": {
+                    "type": "string",
+                    "example": "debug"
+                }
+            }
+        },
+        "handlers.loginRequest": {
+            "type": "object",
+            "properties": {
+                "password": {
+                    "type": "string"
+                },
+                "username": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.merchantAccountRequest": {
+            "type": "object",
+            "required": [
+                "name",
+                "wallet_id"
+            ],
+            "properties": {
+                "name": {
+                    "type": "string",
+                    "example": "Corner Books"
+                },
+                "wallet_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.merchantPaymentRequest": {
+            "type": "object",
+            "required": [
+                "amount",
+                "merchant_wallet_id",
+                "order_reference",
+                "wallet_id"
+            ],
+            "properties": {
+                "amount": {
+                    "type": "string",
+                    "example": "24.99"
+                },
+                "currency": {
+                    "type": "string"
+                },
+                "merchant_wallet_id": {
+                    "type": "string"
+                },
+                "order_reference": {
+                    "type": "string",
+                    "example": "ORD-10042"
+                },
+                "wallet_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.movementResponse": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "string"
+                },
+                "category": {
+                    "type": "string"
+                },
+                "corrected_by_reference_id": {
+                    "type": "string"
+                },
+                "correction_reason": {
+                    "type": "string"
+                },
+                "counter_amount": {
+                    "type": "string"
+                },
+                "counter_currency": {
+                    "$ref": "#/definitions/money.Currency"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "currency": {
+                    "$ref": "#/definitions/money.Currency"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "exchange_rate": {
+                    "type": "string"
+                },
+                "fee": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "new_balance": {
+                    "type": "string"
+                },
+                "previous_balance": {
+                    "type": "string"
+                },
+                "reference_id": {
+                    "type": "string"
+                },
+                "reverses_reference_id": {
+                    "type": "string"
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "type": {
+                    "description": "deposit, withdraw, transfer_in, transfer_out, adjustment_in, adjustment_out, reversal_in, reversal_out, correction_in, correction_out, fee_in, fee_out",
+                    "type": "string"
+                },
+                "wallet": {
+                    "$ref": "#/definitions/models.Wallet"
+                },
+                "wallet_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.notificationPreferencesRequest": {
+            "type": "object",
+            "properties": {
+                "incoming_transfer": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "large_withdrawal_threshold": {
+                    "type": "number",
+                    "example": 1000
+                }
+            }
+        },
+        "handlers.paymentEvent": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/handlers.paymentEventData"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "evt_1PZ3kQ"
+                },
+                "type": {
+                    "type": "string",
+                    "example": "payment.succeeded"
+                }
+            }
+        },
+        "handlers.paymentEventData": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "string",
+                    "example": "25.00"
+                },
+                "currency": {
+                    "type": "string",
+                    "example": "USD"
+                },
+                "failure_reason": {
+                    "type": "string",
+                    "example": "card_declined"
+                },
+                "payment_id": {
+                    "type": "string",
+                    "example": "pay_3MtwBw"
+                },
+                "wallet_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.paymentRequestRequest": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "string",
+                    "example": "25.00"
+                },
+                "currency": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "payer_wallet_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.payoutListResponse": {
+            "type": "object",
+            "properties": {
+                "limit": {
+                    "type": "integer"
+                },
+                "offset": {
+                    "type": "integer"
+                },
+                "payouts": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Payout"
+                    }
+                }
+            }
+        },
+        "handlers.payoutRequest": {
+            "type": "object",
+            "required": [
+                "account_name",
+                "account_number",
+                "amount",
+                "bank_code"
+            ],
+            "properties": {
+                "account_name": {
+                    "type": "string",
+                    "maxLength": 255,
+                    "example": "Alice Smith"
+                },
+                "account_number": {
+                    "type": "string",
+                    "maxLength": 64,
+                    "example": "GB29NWBK60161331926819"
+                },
+                "amount": {
+                    "type": "string",
+                    "example": "25.00"
+                },
+                "bank_code": {
+                    "type": "string",
+                    "maxLength": 64,
+                    "example": "NWBKGB2L"
+                },
+                "currency": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.pendingTransferListResponse": {
+            "type": "object",
+            "properties": {
+                "limit": {
+                    "type": "integer"
+                },
+                "offset": {
+                    "type": "integer"
+                },
+                "transfers": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.PendingTransfer"
+                    }
+                }
+            }
+        },
+        "handlers.reconciliationRunListResponse": {
+            "type": "object",
+            "properties": {
+                "limit": {
+                    "type": "integer"
+                },
+                "offset": {
+                    "type": "integer"
+                },
+                "runs": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ReconciliationRun"
+                    }
+                }
+            }
+        },
+        "handlers.refundRequest": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "description": "Amount to refund; what is left of the payment when omitted",
+                    "type": "string",
+                    "example": "5.00"
+                },
+                "currency": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string",
+                    "example": "Item out of stock"
+                }
+            }
+        },
+        "handlers.registerRequest": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string"
+                },
+                "password": {
+                    "type": "string"
+                },
+                "username": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.resolveDisputeRequest": {
+            "type": "object",
+            "required": [
+                "outcome"
+            ],
+            "properties": {
+                "note": {
+                    "type": "string",
+                    "maxLength": 500,
+                    "example": "Seller could not show delivery"
+                },
+                "outcome": {
+                    "description": "Outcome is refund, which reverses the transfer, or reject, which keeps it",
+                    "type": "string",
+                    "enum": [
+                        "refund",
+                        "reject"
+                    ],
+                    "example": "refund"
+                }
+            }
+        },
+        "handlers.reversalRequest": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "description": "Amount to reverse, in the currency the transfer was sent in; the whole transaction when omitted",
+                    "type": "string",
+                    "example": "5.00"
+                },
+                "currency": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string",
+                    "example": "Refund for cancelled order"
+                }
+            }
+        },
+        "handlers.reviewKYCDocumentRequest": {
+            "type": "object",
+            "required": [
+                "status"
+            ],
+            "properties": {
+                "note": {
+                    "type": "string",
+                    "maxLength": 500,
+                    "example": "Photo page legible, not expired"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "approved"
+                }
+            }
+        },
+        "handlers.riskDecisionListResponse": {
+            "type": "object",
+            "properties": {
+                "decisions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.RiskDecision"
+                    }
+                },
+                "limit": {
+                    "type": "integer"
+                },
+                "offset": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.scheduledTransferRequest": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "string",
+                    "example": "25.00"
+                },
+                "currency": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "frequency": {
+                    "description": "once, daily, weekly, monthly",
+                    "type": "string",
+                    "example": "monthly"
+                },
+                "start_at": {
+                    "description": "StartAt is when the first transfer runs (RFC3339); now when omitted",
+                    "type": "string"
+                },
+                "to_wallet_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.setKYCTierRequest": {
+            "type": "object",
+            "required": [
+                "tier"
+            ],
+            "properties": {
+                "note": {
+                    "type": "string",
+                    "maxLength": 500,
+                    "example": "Passport approved"
+                },
+                "tier": {
+                    "type": "string",
+                    "example": "basic"
+                }
+            }
+        },
+        "handlers.setTransactionPINRequest": {
+            "type": "object",
+            "required": [
+                "pin"
+            ],
+            "properties": {
+                "current_pin": {
+                    "type": "string",
+                    "example": "1234"
+                },
+                "pin": {
+                    "type": "string",
+                    "example": "4821"
+                }
+            }
+        },
+        "handlers.submitKYCDocumentRequest": {
+            "type": "object",
+            "required": [
+                "document_type",
+                "reference"
+            ],
+            "properties": {
+                "document_type": {
+                    "description": "DocumentType is passport, national_id, drivers_license or proof_of_address",
+                    "type": "string",
+                    "example": "passport"
+                },
+                "reference": {
+                    "description": "Reference locates the uploaded document in the document store",
+                    "type": "string",
+                    "maxLength": 500,
+                    "example": "kyc/2024/07/3f1c9a.pdf"
+                }
+            }
+        },
+        "handlers.sweepRequest": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "to_user_id": {
+                    "type": "string"
+                },
+                "to_username": {
+                    "type": "string",
+                    "example": "@alice"
+                },
+                "to_wallet_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.systemWalletListResponse": {
+            "type": "object",
+            "properties": {
+                "wallets": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Wallet"
+                    }
+                }
+            }
+        },
+        "handlers.tokenResponse": {
+            "type": "object",
+            "properties": {
+                "access_token": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "token_type": {
+                    "type": "string"
+                },
+                "user": {
+                    "$ref": "#/definitions/models.UserWithWallet"
+                }
+            }
+        },
+        "handlers.transferListResponse": {
+            "type": "object",
+            "properties": {
+                "limit": {
+                    "type": "integer"
+                },
+                "offset": {
+                    "type": "integer"
+                },
+                "transfers": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.WalletTransfer"
+                    }
+                }
+            }
+        },
+        "handlers.transferRequest": {
+            "type": "object",
+            "required": [
+                "amount"
+            ],
+            "properties": {
+                "amount": {
+                    "type": "string",
+                    "example": "25.00"
+                },
+                "category": {
+                    "type": "string",
+                    "maxLength": 50,
+                    "example": "rent"
+                },
+                "currency": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "quote_id": {
+                    "description": "QuoteID runs the transfer on the fee and exchange rate of a quote for it",
+                    "type": "string"
+                },
+                "tags": {
+                    "type": "array",
+                    "maxItems": 10,
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "to_user_id": {
+                    "type": "string"
+                },
+                "to_username": {
+                    "type": "string",
+                    "example": "@alice"
+                },
+                "to_wallet_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.transferResponse": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "credited_amount": {
+                    "type": "string"
+                },
+                "credited_currency": {
+                    "$ref": "#/definitions/money.Currency"
+                },
+                "currency": {
+                    "$ref": "#/definitions/money.Currency"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "exchange_rate": {
+                    "description": "ExchangeRate, CreditedAmount and CreditedCurrency are set for a transfer between\ncurrencies: the rate used and what the recipient received",
+                    "type": "string"
+                },
+                "fee": {
+                    "type": "string"
+                },
+                "from_balance": {
+                    "type": "string"
+                },
+                "from_transaction_id": {
+                    "type": "string"
+                },
+                "from_wallet_id": {
+                    "type": "string"
+                },
+                "legs": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.LedgerEntry"
+                    }
+                },
+                "reference_id": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "completed"
+                },
+                "to_balance": {
+                    "type": "string"
+                },
+                "to_transaction_id": {
+                    "type": "string"
+                },
+                "to_wallet_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.updateUserRequest": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "type": "string",
+                    "example": "alice@example.com"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "Alice Smith"
+                },
+                "phone": {
+                    "type": "string",
+                    "example": "+447700900123"
+                }
+            }
+        },
+        "handlers.userListResponse": {
+            "type": "object",
+            "properties": {
+                "limit": {
+                    "type": "integer"
+                },
+                "offset": {
+                    "type": "integer"
+                },
+                "users": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.User"
+                    }
+                }
+            }
+        },
+        "handlers.walletListResponse": {
+            "type": "object",
+            "properties": {
+                "limit": {
+                    "type": "integer"
+                },
+                "next_cursor": {
+                    "type": "string"
+                },
+                "offset": {
+                    "type": "integer"
+                },
+                "wallets": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Wallet"
+                    }
+                }
+            }
+        },
+        "handlers.walletStatusRequest": {
+            "type": "object",
+            "properties": {
+                "status": {
+                    "type": "string",
+                    "example": "frozen"
+                }
+            }
+        },
+        "handlers.withdrawRequest": {
+            "type": "object",
+            "required": [
+                "amount"
+            ],
+            "properties": {
+                "amount": {
+                    "type": "string",
+                    "example": "25.00"
+                },
+                "category": {
+                    "type": "string",
+                    "maxLength": 50,
+                    "example": "groceries"
+                },
+                "currency": {
+                    "type": "string"
+                },
+                "tags": {
+                    "type": "array",
+                    "maxItems": 10,
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "health.HealthCheck": {
+            "type": "object",
+            "properties": {
+                "cached": {
+                    "description": "Cached is set when the result was reused rather than checked for this request",
+                    "type": "boolean"
+                },
+                "critical": {
+                    "description": "Critical checks make the service unhealthy when they fail; the others only degrade it",
+                    "type": "boolean"
+                },
+                "details": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "duration": {
+                    "type": "integer"
+                },
+                "last_checked": {
+                    "type": "string"
+                },
+                "latency_history": {
+                    "description": "LatencyHistory holds the durations of the most recent checks, oldest first",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "message": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "status": {
+                    "$ref": "#/definitions/health.Status"
+                }
+            }
+        },
+        "health.HealthReport": {
+            "type": "object",
+            "properties": {
+                "checks": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/health.HealthCheck"
+                    }
+                },
+                "environment": {
+                    "type": "string"
+                },
+                "status": {
+                    "$ref": "#/definitions/health.Status"
+                },
+                "timestamp": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "string"
+                }
+            }
+        },
+        "health.Status": {
+            "type": "string",
+            "enum": [
+                "healthy",
+                "unhealthy",
+                "degraded"
+            ],
+            "x-enum-varnames": [
+                "StatusHealthy",
+                "StatusUnhealthy",
+                "StatusDegraded"
+            ]
+        },
+        "logger.LevelState": {
+            "type": "object",
+            "properties": {
+                "initial": {
+                    "type": "string",
+                    "example": "info"
+                },
+                "level": {
+                    "type": "string",
+                    "example": "debug"
+                },
+                "until": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.AMLReport": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "currency": {
+                    "$ref": "#/definitions/money.Currency"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "journal_id": {
+                    "type": "string"
+                },
+                "movement_count": {
+                    "type": "integer"
+                },
+                "operation": {
+                    "description": "deposit, withdraw, transfer",
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "total": {
+                    "type": "string"
+                },
+                "type": {
+                    "description": "large_transaction, structuring",
+                    "type": "string"
+                },
+                "wallet_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.AuditEntry": {
+            "type": "object",
+            "properties": {
+                "actor_id": {
+                    "type": "string"
+                },
+                "actor_type": {
+                    "type": "string"
+                },
+                "balance_after": {
+                    "type": "string"
+                },
+                "balance_before": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "endpoint": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "path": {
+                    "type": "string"
+                },
+                "payload_hash": {
+                    "type": "string"
+                },
+                "request_id": {
+                    "type": "string"
+                },
+                "status_code": {
+                    "type": "integer"
+                },
+                "wallet_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.BalanceDiscrepancy": {
+            "type": "object",
+            "properties": {
+                "balance": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "currency": {
+                    "$ref": "#/definitions/money.Currency"
+                },
+                "difference": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "ledger_balance": {
+                    "type": "string"
+                },
+                "run_id": {
+                    "type": "string"
+                },
+                "wallet_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.CategoryTotal": {
+            "type": "object",
+            "properties": {
+                "amount_in": {
+                    "type": "string"
+                },
+                "amount_out": {
+                    "type": "string"
+                },
+                "category": {
+                    "type": "string"
+                },
+                "net_amount": {
+                    "type": "string"
+                },
+                "transaction_count": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.DepositRefund": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "currency": {
+                    "$ref": "#/definitions/money.Currency"
+                },
+                "deposit_journal_id": {
+                    "type": "string"
+                },
+                "failure_reason": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "journal_id": {
+                    "type": "string"
+                },
+                "provider_refund_id": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "reversal_journal_id": {
+                    "type": "string"
+                },
+                "status": {
+                    "description": "pending, completed, failed",
+                    "type": "string"
+                },
+                "wallet_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Dispute": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "string"
+                },
+                "counterparty_wallet_id": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "currency": {
+                    "$ref": "#/definitions/money.Currency"
+                },
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.DisputeEvent"
+                    }
+                },
+                "hold_id": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "reference_id": {
+                    "type": "string"
+                },
+                "reversal_journal_id": {
+                    "type": "string"
+                },
+                "status": {
+                    "description": "open, refunded, rejected",
+                    "type": "string"
+                },
+                "transaction_id": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "wallet_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.DisputeEvent": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "dispute_id": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "note": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ExportJob": {
+            "type": "object",
+            "properties": {
+                "completed_at": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "download_expires_at": {
+                    "type": "string"
+                },
+                "download_url": {
+                    "type": "string"
+                },
+                "failure_reason": {
+                    "type": "string"
+                },
+                "from": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "rows": {
+                    "type": "integer"
+                },
+                "status": {
+                    "description": "pending, ready, failed",
+                    "type": "string"
+                },
+                "to": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "wallet_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.FeeQuote": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "string"
+                },
+                "balance_change": {
+                    "type": "string"
+                },
+                "currency": {
+                    "$ref": "#/definitions/money.Currency"
+                },
+                "fee": {
+                    "type": "string"
+                },
+                "operation": {
+                    "description": "deposit, withdraw, transfer",
+                    "type": "string"
+                }
+            }
+        },
+        "models.Hold": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "string"
+                },
+                "capture_journal_id": {
+                    "type": "string"
+                },
+                "captured_amount": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "currency": {
+                    "$ref": "#/definitions/money.Currency"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "dispute_id": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "incoming_transfer_id": {
+                    "type": "string"
+                },
+                "status": {
+                    "description": "active, captured, released",
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "wallet_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.IncomingTransfer": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "currency": {
+                    "$ref": "#/definitions/money.Currency"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "from_wallet_id": {
+                    "type": "string"
+                },
+                "hold_id": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "reference_id": {
+                    "type": "string"
+                },
+                "return_journal_id": {
+                    "type": "string"
+                },
+                "status": {
+                    "description": "pending, accepted, rejected, expired",
+                    "type": "string"
+                },
+                "to_wallet_id": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.IncomingTransferSettings": {
+            "type": "object",
+            "properties": {
+                "require_acceptance": {
+                    "type": "boolean"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "wallet_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Journal": {
+            "type": "object",
+            "properties": {
+                "category": {
+                    "type": "string"
+                },
+                "correction_reason": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "entries": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.LedgerEntry"
+                    }
+                },
+                "id": {
+                    "type": "string"
+                },
+                "reverses_journal_id": {
+                    "type": "string"
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.KYCDocument": {
+            "type": "object",
+            "properties": {
+                "document_type": {
+                    "type": "string",
+                    "example": "passport"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "note": {
+                    "type": "string"
+                },
+                "reference": {
+                    "type": "string",
+                    "example": "s3://kyc-uploads/3f2a/passport.pdf"
+                },
+                "reviewed_at": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "pending"
+                },
+                "submitted_at": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.KYCProfile": {
+            "type": "object",
+            "properties": {
+                "documents": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.KYCDocument"
+                    }
+                },
+                "limits": {
+                    "$ref": "#/definitions/models.KYCTierLimits"
+                },
+                "note": {
+                    "type": "string"
+                },
+                "tier": {
+                    "type": "string",
+                    "example": "basic"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.KYCTierLimits": {
+            "type": "object",
+            "properties": {
+                "daily_transfer_limit": {
+                    "type": "string"
+                },
+                "daily_withdrawal_limit": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.LedgerEntry": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "string"
+                },
+                "counter_amount": {
+                    "type": "string"
+                },
+                "counter_currency": {
+                    "$ref": "#/definitions/money.Currency"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "currency": {
+                    "$ref": "#/definitions/money.Currency"
+                },
+                "direction": {
+                    "type": "string"
+                },
+                "exchange_rate": {
+                    "description": "ExchangeRate, CounterAmount and CounterCurrency are set on the legs of a transfer\nbetween currencies: the rate from the sender's currency to the recipient's, and\nthe leg's amount in the other currency",
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "journal_id": {
+                    "type": "string"
+                },
+                "wallet_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.MerchantAccount": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "wallet_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.MerchantSettlement": {
+            "type": "object",
+            "properties": {
+                "currency": {
+                    "$ref": "#/definitions/money.Currency"
+                },
+                "from": {
+                    "type": "string"
+                },
+                "gross_amount": {
+                    "type": "string"
+                },
+                "net_amount": {
+                    "type": "string"
+                },
+                "payment_count": {
+                    "type": "integer"
+                },
+                "refund_count": {
+                    "type": "integer"
+                },
+                "refunded_amount": {
+                    "type": "string"
+                },
+                "to": {
+                    "type": "string"
+                },
+                "wallet_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.NotificationPreferences": {
+            "type": "object",
+            "properties": {
+                "incoming_transfer": {
+                    "type": "boolean"
+                },
+                "large_withdrawal_threshold": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Payment": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "currency": {
+                    "$ref": "#/definitions/money.Currency"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "journal_id": {
+                    "type": "string"
+                },
+                "merchant_wallet_id": {
+                    "type": "string"
+                },
+                "order_reference": {
+                    "type": "string"
+                },
+                "payer_wallet_id": {
+                    "type": "string"
+                },
+                "refunded_amount": {
+                    "type": "string"
+                },
+                "refunds": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.PaymentRefund"
+                    }
+                },
+                "status": {
+                    "description": "completed, partially_refunded, refunded",
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.PaymentRefund": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "currency": {
+                    "$ref": "#/definitions/money.Currency"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "journal_id": {
+                    "type": "string"
+                },
+                "payment_id": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.PaymentRequest": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "currency": {
+                    "$ref": "#/definitions/money.Currency"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "payer_wallet_id": {
+                    "type": "string"
+                },
+                "requester_wallet_id": {
+                    "type": "string"
+                },
+                "status": {
+                    "description": "pending, accepted, declined",
+                    "type": "string"
+                },
+                "transfer_journal_id": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Payout": {
+            "type": "object",
+            "properties": {
+                "account_name": {
+                    "type": "string"
+                },
+                "account_number": {
+                    "type": "string"
+                },
+                "amount": {
+                    "type": "string"
+                },
+                "bank_code": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "currency": {
+                    "$ref": "#/definitions/money.Currency"
+                },
+                "failure_reason": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "journal_id": {
+                    "type": "string"
+                },
+                "provider_reference": {
+                    "type": "string"
+                },
+                "refund_journal_id": {
+                    "type": "string"
+                },
+                "status": {
+                    "description": "initiated, sent, settled, failed",
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "wallet_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.PendingTransfer": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "string"
+                },
+                "category": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "currency": {
+                    "$ref": "#/definitions/money.Currency"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "from_wallet_id": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "status": {
+                    "description": "pending, completed, failed, cancelled",
+                    "type": "string"
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "to_wallet_id": {
+                    "type": "string"
+                },
+                "transfer_journal_id": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ProviderPayment": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "currency": {
+                    "$ref": "#/definitions/money.Currency"
+                },
+                "failure_reason": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "journal_id": {
+                    "type": "string"
+                },
+                "payment_id": {
+                    "type": "string"
+                },
+                "status": {
+                    "description": "pending, succeeded, failed",
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "wallet_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ReconciliationRun": {
+            "type": "object",
+            "properties": {
+                "discrepancies": {
+                    "description": "Discrepancies is only filled in for a run that has just finished",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.BalanceDiscrepancy"
+                    }
+                },
+                "discrepancy_count": {
+                    "type": "integer"
+                },
+                "finished_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "started_at": {
+                    "type": "string"
+                },
+                "wallets_checked": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.RiskDecision": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "description": "flag, block",
+                    "type": "string"
+                },
+                "amount": {
+                    "type": "string"
+                },
+                "counterparty_wallet_id": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "currency": {
+                    "$ref": "#/definitions/money.Currency"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "operation": {
+                    "description": "withdraw, transfer",
+                    "type": "string"
+                },
+                "reasons": {
+                    "type": "string"
+                },
+                "wallet_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ScheduledTransfer": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "currency": {
+                    "$ref": "#/definitions/money.Currency"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "frequency": {
+                    "description": "once, daily, weekly, monthly",
+                    "type": "string"
+                },
+                "from_wallet_id": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "last_error": {
+                    "type": "string"
+                },
+                "next_run_at": {
+                    "type": "string"
+                },
+                "occurrence": {
+                    "type": "integer"
+                },
+                "start_at": {
+                    "type": "string"
+                },
                 "status": {
+                    "description": "active, completed, failed, cancelled",
+                    "type": "string"
+                },
+                "to_wallet_id": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Transaction": {
+            "type": "object",
+            "properties": {
+                "amount": {
                     "type": "string"
                 },
-                "timestamp": {
+                "category": {
                     "type": "string"
                 },
-                "version": {
+                "corrected_by_reference_id": {
+                    "type": "string"
+                },
+                "correction_reason": {
+                    "type": "string"
+                },
+                "counter_amount": {
+                    "type": "string"
+                },
+                "counter_currency": {
+                    "$ref": "#/definitions/money.Currency"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "exchange_rate": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "reference_id": {
+                    "type": "string"
+                },
+                "reverses_reference_id": {
+                    "type": "string"
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "type": {
+                    "description": "deposit, withdraw, transfer_in, transfer_out, adjustment_in, adjustment_out, reversal_in, reversal_out, correction_in, correction_out, fee_in, fee_out",
+                    "type": "string"
+                },
+                "wallet_id": {
                     "type": "string"
                 }
             }
         },
-        "handlers.createUserRequest": {
+        "models.TransactionPINStatus": {
             "type": "object",
             "properties": {
-                "name": {
+                "enabled": {
+                    "type": "boolean"
+                },
+                "locked_until": {
+                    "type": "string"
+                },
+                "user_id": {
                     "type": "string"
                 }
             }
         },
-        "handlers.depositRequest": {
+        "models.Transfer": {
             "type": "object",
             "properties": {
                 "amount": {
-                    "type": "number"
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "credited_amount": {
+                    "type": "string"
+                },
+                "credited_currency": {
+                    "$ref": "#/definitions/money.Currency"
+                },
+                "currency": {
+                    "$ref": "#/definitions/money.Currency"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "exchange_rate": {
+                    "description": "ExchangeRate, CreditedAmount and CreditedCurrency are set for a transfer between\ncurrencies: the rate used and what the recipient received",
+                    "type": "string"
+                },
+                "from_wallet_id": {
+                    "type": "string"
+                },
+                "legs": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.LedgerEntry"
+                    }
+                },
+                "reference_id": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "completed"
+                },
+                "to_wallet_id": {
+                    "type": "string"
                 }
             }
         },
-        "handlers.transferRequest": {
+        "models.TransferQuote": {
             "type": "object",
             "properties": {
                 "amount": {
-                    "type": "number"
+                    "type": "string"
                 },
-                "description": {
+                "created_at": {
+                    "type": "string"
+                },
+                "credited_amount": {
+                    "type": "string"
+                },
+                "credited_currency": {
+                    "$ref": "#/definitions/money.Currency"
+                },
+                "currency": {
+                    "$ref": "#/definitions/money.Currency"
+                },
+                "debited_amount": {
+                    "description": "amount plus fee",
+                    "type": "string"
+                },
+                "exchange_rate": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "fee": {
+                    "type": "string"
+                },
+                "from_wallet_id": {
+                    "type": "string"
+                },
+                "id": {
                     "type": "string"
                 },
                 "to_wallet_id": {
                     "type": "string"
+                },
+                "transfer_journal_id": {
+                    "type": "string"
                 }
             }
         },
-        "handlers.withdrawRequest": {
+        "models.User": {
             "type": "object",
             "properties": {
-                "amount": {
-                    "type": "number"
+                "created_at": {
+                    "type": "string"
+                },
+                "email": {
+                    "type": "string",
+                    "example": "alice@example.com"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "phone": {
+                    "type": "string",
+                    "example": "+447700900123"
                 }
             }
         },
-        "models.Transaction": {
+        "models.UserWithWallet": {
             "type": "object",
             "properties": {
-                "amount": {
-                    "type": "number"
+                "created_at": {
+                    "type": "string"
+                },
+                "email": {
+                    "type": "string",
+                    "example": "alice@example.com"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "phone": {
+                    "type": "string",
+                    "example": "+447700900123"
+                },
+                "wallet": {
+                    "$ref": "#/definitions/models.Wallet"
+                }
+            }
+        },
+        "models.Wallet": {
+            "type": "object",
+            "properties": {
+                "balance": {
+                    "type": "string"
                 },
                 "created_at": {
                     "type": "string"
                 },
-                "description": {
+                "currency": {
+                    "$ref": "#/definitions/money.Currency"
+                },
+                "held_balance": {
                     "type": "string"
                 },
                 "id": {
                     "type": "string"
                 },
-                "reference_id": {
+                "kind": {
+                    "description": "user, treasury, suspense, fees",
+                    "type": "string"
+                },
+                "status": {
+                    "description": "active, frozen, closed",
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.WalletAlert": {
+            "type": "object",
+            "properties": {
+                "balance": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "currency": {
+                    "$ref": "#/definitions/money.Currency"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "journal_id": {
+                    "type": "string"
+                },
+                "threshold": {
                     "type": "string"
                 },
                 "type": {
-                    "description": "deposit, withdraw, transfer_in, transfer_out",
+                    "description": "low_balance",
                     "type": "string"
                 },
                 "wallet_id": {
@@ -330,37 +9418,201 @@ const docTemplate = `{
                 }
             }
         },
-        "models.UserWithWallet": {
+        "models.WalletAlertSettings": {
+            "type": "object",
+            "properties": {
+                "low_balance_threshold": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "wallet_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.WalletAnalytics": {
+            "type": "object",
+            "properties": {
+                "amount_in": {
+                    "type": "string"
+                },
+                "amount_out": {
+                    "type": "string"
+                },
+                "categories": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.CategoryTotal"
+                    }
+                },
+                "currency": {
+                    "$ref": "#/definitions/money.Currency"
+                },
+                "from": {
+                    "type": "string"
+                },
+                "to": {
+                    "type": "string"
+                },
+                "wallet_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.WalletLimits": {
+            "type": "object",
+            "properties": {
+                "daily_transfer_limit": {
+                    "type": "string"
+                },
+                "daily_withdrawal_limit": {
+                    "type": "string"
+                },
+                "max_transaction_amount": {
+                    "type": "string"
+                },
+                "minimum_balance": {
+                    "type": "string"
+                },
+                "overdraft_limit": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "wallet_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.WalletTransfer": {
             "type": "object",
             "properties": {
+                "amount": {
+                    "type": "string"
+                },
+                "counter_amount": {
+                    "type": "string"
+                },
+                "counter_currency": {
+                    "$ref": "#/definitions/money.Currency"
+                },
+                "counterparty_name": {
+                    "description": "CounterpartyName is the name of the counterparty wallet's owner",
+                    "type": "string"
+                },
+                "counterparty_wallet_id": {
+                    "type": "string"
+                },
                 "created_at": {
                     "type": "string"
                 },
-                "id": {
+                "currency": {
+                    "$ref": "#/definitions/money.Currency"
+                },
+                "description": {
                     "type": "string"
                 },
-                "name": {
+                "direction": {
+                    "description": "incoming or outgoing",
+                    "type": "string",
+                    "example": "outgoing"
+                },
+                "exchange_rate": {
                     "type": "string"
                 },
-                "wallet": {
-                    "$ref": "#/definitions/models.Wallet"
+                "reference_id": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "completed"
                 }
             }
         },
-        "models.Wallet": {
+        "money.Currency": {
+            "type": "string",
+            "enum": [
+                "USD",
+                "EUR",
+                "GBP",
+                "JPY",
+                "LKR",
+                "KWD",
+                "USD"
+            ],
+            "x-enum-varnames": [
+                "USD",
+                "EUR",
+                "GBP",
+                "JPY",
+                "LKR",
+                "KWD",
+                "DefaultCurrency"
+            ]
+        },
+        "realtime.BalanceMessage": {
             "type": "object",
             "properties": {
+                "amount": {
+                    "type": "string"
+                },
                 "balance": {
-                    "type": "number"
+                    "type": "string"
                 },
-                "created_at": {
+                "currency": {
+                    "$ref": "#/definitions/money.Currency"
+                },
+                "event": {
                     "type": "string"
                 },
-                "id": {
+                "journal_id": {
                     "type": "string"
                 },
-                "user_id": {
+                "occurred_at": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                },
+                "wallet_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "response.Problem": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "example": "WALLET_NOT_FOUND"
+                },
+                "detail": {
+                    "type": "string",
+                    "example": "Wallet not found"
+                },
+                "details": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "instance": {
                     "type": "string"
+                },
+                "status": {
+                    "type": "integer",
+                    "example": 404
+                },
+                "title": {
+                    "type": "string",
+                    "example": "Not Found"
+                },
+                "type": {
+                    "type": "string",
+                    "example": "about:blank"
                 }
             }
         }
@@ -369,12 +9621,12 @@ const docTemplate = `{
 
 // SwaggerInfo holds exported Swagger Info so clients can modify it
 var SwaggerInfo = &swag.Spec{
-	Version:          "",
+	Version:          "1.0",
 	Host:             "",
 	BasePath:         "",
 	Schemes:          []string{},
-	Title:            "",
-	Description:      "",
+	Title:            "Wallet API",
+	Description:      "Wallets with deposits, withdrawals, transfers and the ledger behind them.",
 	InfoInstanceName: "swagger",
 	SwaggerTemplate:  docTemplate,
 	LeftDelim:        "{{",
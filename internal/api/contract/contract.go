@@ -0,0 +1,140 @@
+// Package contract checks HTTP responses against the API description swag generates
+// in docs, so tests catch a handler whose status codes or bodies drift from what the
+// documentation promises clients.
+package contract
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+
+	"github.com/getkin/kin-openapi/openapi2"
+	"github.com/getkin/kin-openapi/openapi2conv"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+
+	"github.com/shanwije/wallet-app/docs"
+	"github.com/shanwije/wallet-app/pkg/response"
+)
+
+// Validator checks responses against the documented operations and keeps the
+// violations it finds
+type Validator struct {
+	router routers.Router
+
+	mu         sync.Mutex
+	violations []string
+}
+
+// Load reads the Swagger document served at /swagger/doc.json
+func Load() (*Validator, error) {
+	var swagger openapi2.T
+	if err := json.Unmarshal([]byte(docs.SwaggerInfo.ReadDoc()), &swagger); err != nil {
+		return nil, fmt.Errorf("invalid Swagger document: %w", err)
+	}
+	doc, err := openapi2conv.ToV3(&swagger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert the Swagger document: %w", err)
+	}
+	// Match requests to any host, as tests serve the API on a random port
+	doc.Servers = nil
+	if err := doc.Validate(context.Background(), openapi3.DisableExamplesValidation()); err != nil {
+		return nil, fmt.Errorf("invalid API description: %w", err)
+	}
+	router, err := gorillamux.NewRouter(doc)
+	if err != nil {
+		return nil, err
+	}
+	return &Validator{router: router}, nil
+}
+
+// Check returns why the response to req breaks the contract, or nil if it keeps it.
+// A response to a request for an undocumented endpoint breaks it too.
+func (v *Validator) Check(req *http.Request, status int, header http.Header, body []byte) error {
+	route, pathParams, err := v.router.FindRoute(req)
+	if err != nil {
+		return fmt.Errorf("undocumented endpoint: %w", err)
+	}
+
+	// Errors are problem documents, which swag can only describe as the JSON every
+	// operation produces
+	header = header.Clone()
+	if header.Get("Content-Type") == response.ContentTypeProblem {
+		header.Set("Content-Type", response.ContentTypeJSON)
+	}
+
+	return openapi3filter.ValidateResponse(req.Context(), &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: &openapi3filter.RequestValidationInput{
+			Request:    req,
+			PathParams: pathParams,
+			Route:      route,
+		},
+		Status: status,
+		Header: header,
+		Body:   io.NopCloser(bytes.NewReader(body)),
+		Options: &openapi3filter.Options{
+			IncludeResponseStatus: true,
+			MultiError:            true,
+		},
+	})
+}
+
+// Middleware checks every response to an /api/ request, recording what breaks the
+// contract. Other paths, such as /health and /swagger, are not described by the
+// document and pass through unchecked. Responses are buffered, so WebSocket upgrades
+// cannot go through it.
+func (v *Validator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/api/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		rec := httptest.NewRecorder()
+		next.ServeHTTP(rec, r)
+		body, err := decoded(rec)
+		if err == nil {
+			err = v.Check(r, rec.Code, rec.Header(), body)
+		}
+		if err != nil {
+			v.mu.Lock()
+			v.violations = append(v.violations, fmt.Sprintf("%s %s -> %d: %v", r.Method, r.URL.Path, rec.Code, err))
+			v.mu.Unlock()
+		}
+
+		for key, values := range rec.Header() {
+			w.Header()[key] = values
+		}
+		w.WriteHeader(rec.Code)
+		w.Write(rec.Body.Bytes())
+	})
+}
+
+// decoded returns the recorded body, decompressed if it was gzipped
+func decoded(rec *httptest.ResponseRecorder) ([]byte, error) {
+	if rec.Header().Get("Content-Encoding") != "gzip" {
+		return rec.Body.Bytes(), nil
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(rec.Body.Bytes()))
+	if err != nil {
+		return nil, fmt.Errorf("invalid gzip body: %w", err)
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+// Violations returns what the responses checked so far broke, in the order they came
+func (v *Validator) Violations() []string {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return append([]string(nil), v.violations...)
+}
@@ -0,0 +1,183 @@
+package contract
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/shanwije/wallet-app/db/migrations"
+	"github.com/shanwije/wallet-app/internal/api"
+	"github.com/shanwije/wallet-app/internal/config"
+	"github.com/shanwije/wallet-app/internal/fees"
+	"github.com/shanwije/wallet-app/internal/service"
+	"github.com/shanwije/wallet-app/pkg/db"
+	"github.com/shanwije/wallet-app/pkg/featureflag"
+)
+
+// newContractServer serves the real router on a fresh SQLite database, checking
+// every response against the API description
+func newContractServer(t *testing.T) (*httptest.Server, *Validator) {
+	t.Helper()
+	ctx := context.Background()
+
+	redisServer := miniredis.RunT(t)
+	t.Setenv("DB_DRIVER", db.DriverSQLite)
+	t.Setenv("DB_NAME", filepath.Join(t.TempDir(), "wallet.db"))
+	t.Setenv("JWT_SECRET", "contract-test-secret-not-for-production")
+	t.Setenv("REDIS_URL", "redis://"+redisServer.Addr())
+	cfg, err := config.Load(ctx, nil)
+	require.NoError(t, err)
+
+	conn, err := db.New(db.Config{Driver: db.DriverSQLite, Name: cfg.DBName})
+	require.NoError(t, err)
+	fsys, err := migrations.ForDriver(cfg.DBDriver)
+	require.NoError(t, err)
+	migrator, err := db.NewMigrator(conn.DB, cfg.DBDriver, fsys)
+	require.NoError(t, err)
+	t.Cleanup(func() { migrator.Close() })
+	_, err = migrator.Up(ctx)
+	require.NoError(t, err)
+
+	redisClient, err := db.NewRedis(cfg.RedisURL)
+	require.NoError(t, err)
+	t.Cleanup(func() { redisClient.Close() })
+	flagDefaults, err := featureflag.ParseDefaults(service.DefaultFeatureFlags, cfg.FeatureFlags)
+	require.NoError(t, err)
+	feeSchedule, err := fees.ParseSchedule(cfg.Fees)
+	require.NoError(t, err)
+
+	validator, err := Load()
+	require.NoError(t, err)
+	services := api.NewServices(cfg, conn, redisClient, nil, nil, nil, nil, feeSchedule, flagDefaults)
+	server := httptest.NewServer(validator.Middleware(api.NewRouter(cfg, services, zap.NewNop())))
+	t.Cleanup(server.Close)
+	return server, validator
+}
+
+// contractClient sends requests to the contract server and decodes what comes back
+type contractClient struct {
+	t     *testing.T
+	url   string
+	token string
+}
+
+func (c *contractClient) do(method, path string, body any, wantStatus int) map[string]any {
+	c.t.Helper()
+	var payload bytes.Buffer
+	if body != nil {
+		require.NoError(c.t, json.NewEncoder(&payload).Encode(body))
+	}
+	req, err := http.NewRequest(method, c.url+path, &payload)
+	require.NoError(c.t, err)
+	req.Header.Set("Content-Type", "application/json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(c.t, err)
+	defer resp.Body.Close()
+
+	require.Equal(c.t, wantStatus, resp.StatusCode, "%s %s", method, path)
+	decoded := map[string]any{}
+	json.NewDecoder(resp.Body).Decode(&decoded)
+	return decoded
+}
+
+// register signs a new user up and returns a client acting as them, with their wallet
+func register(t *testing.T, url, name string) (*contractClient, string) {
+	t.Helper()
+	anonymous := &contractClient{t: t, url: url}
+	registered := anonymous.do(http.MethodPost, "/api/v1/auth/register", map[string]string{
+		"name":     name,
+		"username": "contract-" + uuid.NewString(),
+		"password": "contract-test-password",
+	}, http.StatusCreated)
+
+	user := registered["user"].(map[string]any)
+	wallet := user["wallet"].(map[string]any)
+	return &contractClient{t: t, url: url, token: registered["access_token"].(string)}, wallet["id"].(string)
+}
+
+func TestResponsesMatchTheAPIDescription(t *testing.T) {
+	server, validator := newContractServer(t)
+	anonymous := &contractClient{t: t, url: server.URL}
+
+	username := "contract-" + uuid.NewString()
+	credentials := map[string]string{"name": "Contract User", "username": username, "password": "contract-test-password"}
+	registered := anonymous.do(http.MethodPost, "/api/v1/auth/register", credentials, http.StatusCreated)
+	anonymous.do(http.MethodPost, "/api/v1/auth/register", credentials, http.StatusConflict)
+	anonymous.do(http.MethodPost, "/api/v1/auth/login", map[string]string{"username": username, "password": "contract-test-password"}, http.StatusOK)
+	anonymous.do(http.MethodPost, "/api/v1/auth/login", map[string]string{"username": username, "password": "wrong-password"}, http.StatusUnauthorized)
+
+	user := registered["user"].(map[string]any)
+	userID := user["id"].(string)
+	walletID := user["wallet"].(map[string]any)["id"].(string)
+	sender := &contractClient{t: t, url: server.URL, token: registered["access_token"].(string)}
+	_, recipientWalletID := register(t, server.URL, "Contract Recipient")
+
+	sender.do(http.MethodGet, "/api/v1/users/"+userID, nil, http.StatusOK)
+	sender.do(http.MethodGet, "/api/v1/users/"+userID+"/wallet", nil, http.StatusOK)
+
+	wallet := "/api/v1/wallets/" + walletID
+	sender.do(http.MethodPost, wallet+"/deposit", map[string]any{"amount": 200}, http.StatusOK)
+	sender.do(http.MethodPost, wallet+"/withdraw", map[string]any{"amount": 25.5}, http.StatusOK)
+	sender.do(http.MethodPost, wallet+"/withdraw", map[string]any{"amount": 1000}, http.StatusBadRequest)
+	sender.do(http.MethodPost, wallet+"/deposit", map[string]any{"amount": -5}, http.StatusBadRequest)
+	sender.do(http.MethodPost, wallet+"/transfer/quote", map[string]any{"to_wallet_id": recipientWalletID, "amount": 10}, http.StatusCreated)
+	transfer := sender.do(http.MethodPost, wallet+"/transfer", map[string]any{"to_wallet_id": recipientWalletID, "amount": 50}, http.StatusOK)
+	sender.do(http.MethodGet, wallet+"/balance", nil, http.StatusOK)
+	sender.do(http.MethodGet, wallet+"/transactions", nil, http.StatusOK)
+	sender.do(http.MethodGet, wallet+"/transfers", nil, http.StatusOK)
+	sender.do(http.MethodGet, "/api/v1/wallets/not-a-uuid/balance", nil, http.StatusBadRequest)
+	if referenceID, ok := transfer["reference_id"].(string); ok {
+		sender.do(http.MethodGet, "/api/v1/transfers/"+referenceID, nil, http.StatusOK)
+	}
+
+	for _, violation := range validator.Violations() {
+		t.Error(violation)
+	}
+}
+
+func TestValidatorReportsDrift(t *testing.T) {
+	validator, err := Load()
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		path   string
+		status int
+		body   string
+		want   string
+	}{
+		{"undocumented status", "/api/v1/wallets/" + uuid.NewString() + "/balance", http.StatusTeapot, `{}`, "status is not supported"},
+		{"wrong field type", "/api/v1/wallets/" + uuid.NewString() + "/balance", http.StatusOK, `{"id":42}`, "id"},
+		{"undocumented endpoint", "/api/v1/nowhere", http.StatusOK, `{}`, "undocumented endpoint"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := validator.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tt.status)
+				fmt.Fprint(w, tt.body)
+			}))
+			before := len(validator.Violations())
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			violations := validator.Violations()
+			require.Len(t, violations, before+1)
+			assert.True(t, strings.Contains(violations[before], tt.want), violations[before])
+		})
+	}
+}
@@ -0,0 +1,643 @@
+package handlers
+
+import (
+	stderrors "errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository"
+	"github.com/shanwije/wallet-app/internal/service"
+	"github.com/shanwije/wallet-app/pkg/errors"
+	"github.com/shanwije/wallet-app/pkg/featureflag"
+	"github.com/shanwije/wallet-app/pkg/logger"
+	"github.com/shanwije/wallet-app/pkg/pagination"
+	"github.com/shanwije/wallet-app/pkg/response"
+)
+
+// AdminHandler serves operator endpoints guarded by the admin key
+type AdminHandler struct {
+	UserService   UserServiceInterface
+	WalletService WalletServiceInterface
+	AuditService  *service.AuditService
+	// Reconciliation runs and lists the checks of balances against the ledger
+	Reconciliation *service.ReconciliationService
+	// FeatureFlags are listed and toggled at runtime
+	FeatureFlags *featureflag.Flags
+	// LogLevel is read and changed at runtime
+	LogLevel *logger.Level
+}
+
+type walletStatusRequest struct {
+	Status string `json:"status" example:"frozen"`
+}
+
+// adjustmentRequest corrects a balance: a positive amount is added, a negative one taken away
+type adjustmentRequest struct {
+	Amount   decimal.Decimal `json:"amount" swaggertype:"string" validate:"required,ne=0" example:"-12.50"`
+	Currency string          `json:"currency,omitempty"`
+	Reason   string          `json:"reason" validate:"required" example:"Refund of duplicate card fee"`
+}
+
+// limitsRequest replaces a wallet's limits; omitted or null limits are lifted
+type limitsRequest struct {
+	MaxTransactionAmount *decimal.Decimal `json:"max_transaction_amount" swaggertype:"number" example:"500"`
+	DailyWithdrawalLimit *decimal.Decimal `json:"daily_withdrawal_limit" swaggertype:"number" example:"1000"`
+	DailyTransferLimit   *decimal.Decimal `json:"daily_transfer_limit" swaggertype:"number" example:"2000"`
+	// OverdraftLimit lets the balance go this far below zero; MinimumBalance keeps this
+	// much in the wallet. Only one of them can be set.
+	OverdraftLimit *decimal.Decimal `json:"overdraft_limit" swaggertype:"number" example:"100"`
+	MinimumBalance *decimal.Decimal `json:"minimum_balance" swaggertype:"number"`
+}
+
+// userListResponse is one page of users
+type userListResponse struct {
+	Users  []*models.User `json:"users"`
+	Limit  int            `json:"limit"`
+	Offset int            `json:"offset"`
+}
+
+// walletListResponse is one page of wallets. NextCursor continues the listing after
+// a full page.
+type walletListResponse struct {
+	Wallets    []*models.Wallet `json:"wallets"`
+	Limit      int              `json:"limit"`
+	Offset     int              `json:"offset"`
+	NextCursor string           `json:"next_cursor,omitempty"`
+}
+
+// riskDecisionListResponse is one page of risk decisions
+type riskDecisionListResponse struct {
+	Decisions []*models.RiskDecision `json:"decisions"`
+	Limit     int                    `json:"limit"`
+	Offset    int                    `json:"offset"`
+}
+
+// auditSort is the order the audit log is listed in, newest entry first
+var auditSort = pagination.Sort{Field: "created_at", Descending: true}
+
+// auditListResponse is one page of the audit log. NextCursor continues the listing
+// after a full page.
+type auditListResponse struct {
+	Entries    []*models.AuditEntry `json:"entries"`
+	Limit      int                  `json:"limit"`
+	Offset     int                  `json:"offset"`
+	NextCursor string               `json:"next_cursor,omitempty"`
+}
+
+// NewAdminHandler creates a new AdminHandler
+func NewAdminHandler(userService UserServiceInterface, walletService WalletServiceInterface, auditService *service.AuditService, reconciliation *service.ReconciliationService, flags *featureflag.Flags) *AdminHandler {
+	return &AdminHandler{
+		UserService:    userService,
+		WalletService:  walletService,
+		AuditService:   auditService,
+		Reconciliation: reconciliation,
+		FeatureFlags:   flags,
+	}
+}
+
+// SetWalletStatus freezes, unfreezes or closes a wallet
+// @Summary Change wallet status
+// @Description Frozen and closed wallets reject deposits, withdrawals and transfers. A closed wallet cannot be reopened.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param id path string true "Wallet ID"
+// @Param status body walletStatusRequest true "New status: active, frozen or closed"
+// @Success 200 {object} models.Wallet
+// @Failure 400 {object} response.Problem "Invalid wallet ID or status"
+// @Failure 404 {object} response.Problem "Wallet not found"
+// @Failure 409 {object} response.Problem "Wallet is closed"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v1/admin/wallets/{id}/status [put]
+func (h *AdminHandler) SetWalletStatus(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+	walletIDStr := chi.URLParam(r, "id")
+	walletID, err := uuid.Parse(walletIDStr)
+	if err != nil {
+		response.ErrorMessage(w, http.StatusBadRequest, "Invalid wallet ID")
+		return
+	}
+
+	var req walletStatusRequest
+	if appErr := decodeRequest(r, &req); appErr != nil {
+		response.Error(w, appErr)
+		return
+	}
+
+	wallet, err := h.WalletService.SetWalletStatus(r.Context(), walletID, req.Status)
+	if err != nil {
+		log.Error("Wallet status change failed", zap.Error(err),
+			zap.String("wallet_id", walletIDStr),
+			zap.String("status", req.Status))
+		switch {
+		case stderrors.Is(err, service.ErrInvalidWalletStatus):
+			response.Error(w, errors.InvalidInput("Status must be active, frozen or closed").
+				WithDetails("status", req.Status))
+		case stderrors.Is(err, models.ErrWalletClosed):
+			response.Error(w, errors.WalletClosed("A closed wallet cannot be reopened"))
+		default:
+			response.Error(w, walletAppError(err, walletIDStr))
+		}
+		return
+	}
+
+	log.Info("Wallet status changed",
+		zap.String("wallet_id", walletIDStr),
+		zap.String("status", wallet.Status))
+
+	response.OK(w, wallet)
+}
+
+// ListUsers pages through all users
+// @Summary List users
+// @Description Returns users that have not been deleted, oldest first, at most 200 per page.
+// @Tags admin
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param limit query int false "Page size; larger sizes are cut to 200" minimum(0) default(50)
+// @Param offset query int false "Users to skip" minimum(0) default(0)
+// @Success 200 {object} userListResponse
+// @Failure 400 {object} response.Problem "Invalid pagination parameters"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v1/admin/users [get]
+func (h *AdminHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	limit, offset, appErr := parsePage(r)
+	if appErr != nil {
+		response.Error(w, appErr)
+		return
+	}
+
+	users, err := h.UserService.ListUsers(r.Context(), limit, offset)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Failed to list users", zap.Error(err))
+		response.Error(w, errors.InternalError(err))
+		return
+	}
+
+	response.OK(w, userListResponse{Users: users, Limit: limit, Offset: offset})
+}
+
+// SearchUsers finds users by part of their name, for support staff
+// @Summary Search users
+// @Description Returns the users that have not been deleted whose name contains q, ignoring case, best matches first and at most 200 per page. On PostgreSQL names similar to q, such as ones with a typo, match too.
+// @Tags admin
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param q query string true "Part of a name"
+// @Param limit query int false "Page size; larger sizes are cut to 200" minimum(0) default(50)
+// @Param offset query int false "Users to skip" minimum(0) default(0)
+// @Success 200 {object} userListResponse
+// @Failure 400 {object} response.Problem "Missing query or invalid pagination parameters"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v1/users [get]
+func (h *AdminHandler) SearchUsers(w http.ResponseWriter, r *http.Request) {
+	limit, offset, appErr := parsePage(r)
+	if appErr != nil {
+		response.Error(w, appErr)
+		return
+	}
+
+	users, err := h.UserService.SearchUsers(r.Context(), r.URL.Query().Get("q"), limit, offset)
+	if stderrors.Is(err, service.ErrEmptySearch) {
+		response.Error(w, errors.New(errors.ErrMissingField, "q is required", http.StatusBadRequest))
+		return
+	}
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Failed to search users", zap.Error(err))
+		response.Error(w, errors.InternalError(err))
+		return
+	}
+
+	response.OK(w, userListResponse{Users: users, Limit: limit, Offset: offset})
+}
+
+// SearchWallets finds wallets of any owner
+// @Summary Search wallets
+// @Description Returns the wallets matching every given filter, oldest first unless sorted otherwise, at most 200 per page.
+// @Description A full page comes with a next_cursor; passing it back as cursor, with the same filters and sort, returns the wallets after that page. Cursors page by the sort column rather than by skipping rows, so deep pages are as cheap as the first and wallets created meanwhile are neither skipped nor repeated.
+// @Tags admin
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param status query string false "active, frozen or closed"
+// @Param currency query string false "ISO 4217 currency code"
+// @Param min_balance query number false "Smallest balance"
+// @Param max_balance query number false "Largest balance"
+// @Param created_from query string false "Wallets created at or after this time (RFC3339)"
+// @Param created_to query string false "Wallets created before this time (RFC3339)"
+// @Param sort query string false "created_at or balance, descending with a leading -" default(created_at)
+// @Param cursor query string false "next_cursor of the previous page; cannot be combined with offset"
+// @Param limit query int false "Page size; larger sizes are cut to 200" minimum(0) default(50)
+// @Param offset query int false "Wallets to skip" minimum(0) default(0)
+// @Success 200 {object} walletListResponse
+// @Failure 400 {object} response.Problem "Invalid filter, sort or pagination parameters"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v1/admin/wallets [get]
+func (h *AdminHandler) SearchWallets(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	page, err := pagination.Parse(query)
+	if err != nil {
+		response.Error(w, paginationAppError(err))
+		return
+	}
+	sort, err := pagination.ParseSort(query.Get("sort"), repository.WalletSortCreatedAt, repository.WalletSortCreatedAt, repository.WalletSortBalance)
+	if err != nil {
+		response.Error(w, paginationAppError(err))
+		return
+	}
+	filter := repository.WalletFilter{
+		Status:   query.Get("status"),
+		Currency: query.Get("currency"),
+		Sort:     sort,
+		Limit:    page.Limit,
+		Offset:   page.Offset,
+	}
+	if filter.Status != "" && !models.IsValidWalletStatus(filter.Status) {
+		response.Error(w, errors.InvalidInput("Status must be active, frozen or closed").
+			WithDetails("status", filter.Status))
+		return
+	}
+	for param, bound := range map[string]**decimal.Decimal{"min_balance": &filter.MinBalance, "max_balance": &filter.MaxBalance} {
+		value := query.Get(param)
+		if value == "" {
+			continue
+		}
+		parsed, err := decimal.NewFromString(value)
+		if err != nil {
+			response.Error(w, errors.InvalidInput("Balance bounds must be numbers").
+				WithDetails(param, value))
+			return
+		}
+		*bound = &parsed
+	}
+	for _, bound := range []struct {
+		param  string
+		target *time.Time
+	}{{"created_from", &filter.CreatedFrom}, {"created_to", &filter.CreatedTo}} {
+		param, target := bound.param, bound.target
+		value := query.Get(param)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			response.Error(w, errors.InvalidInput("Times must be RFC3339").WithDetails(param, value))
+			return
+		}
+		*target = parsed
+	}
+	if page.Cursor != "" {
+		filter.After = &repository.WalletCursor{}
+		if err := pagination.DecodeCursor(page.Cursor, sort, filter.After); err != nil {
+			response.Error(w, paginationAppError(err))
+			return
+		}
+	}
+
+	wallets, err := h.WalletService.SearchWallets(r.Context(), filter)
+	switch {
+	case stderrors.Is(err, service.ErrInvalidCreationPeriod):
+		response.Error(w, errors.InvalidInput("created_to must be after created_from"))
+		return
+	case err != nil:
+		logger.FromContext(r.Context()).Error("Failed to search wallets", zap.Error(err))
+		response.Error(w, errors.InternalError(err))
+		return
+	}
+
+	result := walletListResponse{Wallets: wallets, Limit: page.Limit, Offset: page.Offset}
+	if page.Full(len(wallets)) {
+		result.NextCursor = pagination.EncodeCursor(sort, repository.NewWalletCursor(wallets[len(wallets)-1]))
+	}
+	response.OK(w, result)
+}
+
+// ListRiskDecisions pages through the operations the risk checks flagged or blocked
+// @Summary List risk decisions
+// @Description Returns flagged and blocked withdrawals and transfers for review, newest first, at most 200 per page.
+// @Tags admin
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param wallet_id query string false "Only decisions about this wallet"
+// @Param action query string false "flag or block"
+// @Param limit query int false "Page size; larger sizes are cut to 200" minimum(0) default(50)
+// @Param offset query int false "Decisions to skip" minimum(0) default(0)
+// @Success 200 {object} riskDecisionListResponse
+// @Failure 400 {object} response.Problem "Invalid filter or pagination parameters"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v1/admin/risk-decisions [get]
+func (h *AdminHandler) ListRiskDecisions(w http.ResponseWriter, r *http.Request) {
+	limit, offset, appErr := parsePage(r)
+	if appErr != nil {
+		response.Error(w, appErr)
+		return
+	}
+
+	query := r.URL.Query()
+	filter := repository.RiskDecisionFilter{
+		Action: query.Get("action"),
+		Limit:  limit,
+		Offset: offset,
+	}
+	if filter.Action != "" && filter.Action != models.RiskActionFlag && filter.Action != models.RiskActionBlock {
+		response.Error(w, errors.InvalidInput("Action must be flag or block").
+			WithDetails("action", filter.Action))
+		return
+	}
+	if value := query.Get("wallet_id"); value != "" {
+		walletID, err := uuid.Parse(value)
+		if err != nil {
+			response.ErrorMessage(w, http.StatusBadRequest, "Invalid wallet ID")
+			return
+		}
+		filter.WalletID = walletID
+	}
+
+	decisions, err := h.WalletService.ListRiskDecisions(r.Context(), filter)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Failed to list risk decisions", zap.Error(err))
+		response.Error(w, errors.InternalError(err))
+		return
+	}
+
+	response.OK(w, riskDecisionListResponse{Decisions: decisions, Limit: limit, Offset: offset})
+}
+
+// ListAuditEntries pages through the audit log of mutating calls
+// @Summary List audit log entries
+// @Description Returns who called which mutating endpoint and when, with the wallet's balance before and after, newest first and at most 200 per page.
+// @Description A full page comes with a next_cursor; passing it back as cursor, with the same filters, returns the entries after that page.
+// @Tags admin
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param actor_id query string false "User ID, admin key fingerprint or client IP"
+// @Param wallet_id query string false "Only calls on this wallet"
+// @Param from query string false "Entries at or after this time (RFC3339)"
+// @Param to query string false "Entries before this time (RFC3339)"
+// @Param cursor query string false "next_cursor of the previous page; cannot be combined with offset"
+// @Param limit query int false "Page size; larger sizes are cut to 200" minimum(0) default(50)
+// @Param offset query int false "Entries to skip" minimum(0) default(0)
+// @Success 200 {object} auditListResponse
+// @Failure 400 {object} response.Problem "Invalid filter or pagination parameters"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v1/admin/audit-log [get]
+func (h *AdminHandler) ListAuditEntries(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	page, err := pagination.Parse(query)
+	if err != nil {
+		response.Error(w, paginationAppError(err))
+		return
+	}
+	filter := repository.AuditFilter{ActorID: query.Get("actor_id"), Limit: page.Limit, Offset: page.Offset}
+	if page.Cursor != "" {
+		filter.After = &repository.Position{}
+		if err := pagination.DecodeCursor(page.Cursor, auditSort, filter.After); err != nil {
+			response.Error(w, paginationAppError(err))
+			return
+		}
+	}
+	if value := query.Get("wallet_id"); value != "" {
+		walletID, err := uuid.Parse(value)
+		if err != nil {
+			response.ErrorMessage(w, http.StatusBadRequest, "Invalid wallet ID")
+			return
+		}
+		filter.WalletID = walletID
+	}
+	for _, bound := range []struct {
+		param  string
+		target *time.Time
+	}{{"from", &filter.From}, {"to", &filter.To}} {
+		param, target := bound.param, bound.target
+		value := query.Get(param)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			response.Error(w, errors.InvalidInput("Times must be RFC3339").WithDetails(param, value))
+			return
+		}
+		*target = parsed
+	}
+
+	entries, err := h.AuditService.ListAuditEntries(r.Context(), filter)
+	if stderrors.Is(err, service.ErrInvalidAuditPeriod) {
+		response.Error(w, errors.InvalidInput("to must be after from"))
+		return
+	}
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Failed to list audit entries", zap.Error(err))
+		response.Error(w, errors.InternalError(err))
+		return
+	}
+
+	result := auditListResponse{Entries: entries, Limit: page.Limit, Offset: page.Offset}
+	if page.Full(len(entries)) {
+		last := entries[len(entries)-1]
+		result.NextCursor = pagination.EncodeCursor(auditSort, repository.Position{ID: last.ID, CreatedAt: last.CreatedAt})
+	}
+	response.OK(w, result)
+}
+
+// GetWallet returns any wallet, whoever owns it
+// @Summary Get wallet
+// @Tags admin
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param id path string true "Wallet ID"
+// @Success 200 {object} models.Wallet
+// @Failure 400 {object} response.Problem "Invalid wallet ID"
+// @Failure 404 {object} response.Problem "Wallet not found"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v1/admin/wallets/{id} [get]
+func (h *AdminHandler) GetWallet(w http.ResponseWriter, r *http.Request) {
+	walletIDStr := chi.URLParam(r, "id")
+	walletID, err := uuid.Parse(walletIDStr)
+	if err != nil {
+		response.ErrorMessage(w, http.StatusBadRequest, "Invalid wallet ID")
+		return
+	}
+
+	wallet, err := h.WalletService.GetBalance(r.Context(), walletID)
+	if err != nil {
+		response.Error(w, walletAppError(err, walletIDStr))
+		return
+	}
+
+	response.OK(w, wallet)
+}
+
+// AdjustBalance posts an operator correction to a wallet
+// @Summary Adjust wallet balance
+// @Description Adds a positive amount to the wallet or takes a negative one away, recording an adjustment in its history. Frozen wallets can be adjusted; closed wallets cannot, and an adjustment cannot take more than the available balance.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param Idempotency-Key header string false "Makes retries of the same adjustment safe"
+// @Param id path string true "Wallet ID"
+// @Param adjustment body adjustmentRequest true "Signed amount and reason"
+// @Success 200 {object} models.Wallet
+// @Failure 400 {object} response.Problem "Invalid wallet ID, amount or reason, or the adjustment would overdraw the wallet"
+// @Failure 404 {object} response.Problem "Wallet not found"
+// @Failure 409 {object} response.Problem "Wallet frozen or closed, concurrent update, or Idempotency-Key reused"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v1/admin/wallets/{id}/adjustments [post]
+func (h *AdminHandler) AdjustBalance(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+	walletIDStr := chi.URLParam(r, "id")
+	walletID, err := uuid.Parse(walletIDStr)
+	if err != nil {
+		response.ErrorMessage(w, http.StatusBadRequest, "Invalid wallet ID")
+		return
+	}
+
+	var req adjustmentRequest
+	if appErr := decodeRequest(r, &req); appErr != nil {
+		response.Error(w, appErr)
+		return
+	}
+	if appErr := validateRequest(req); appErr != nil {
+		response.Error(w, appErr)
+		return
+	}
+	amount, appErr := parseAmount(req.Amount, req.Currency)
+	if appErr != nil {
+		response.Error(w, appErr)
+		return
+	}
+
+	wallet, err := h.WalletService.AdjustBalance(r.Context(), walletID, amount, req.Reason, r.Header.Get("Idempotency-Key"))
+	if err != nil {
+		log.Error("Balance adjustment failed", zap.Error(err),
+			zap.String("wallet_id", walletIDStr),
+			zap.String("amount", amount.String()))
+		switch {
+		case stderrors.Is(err, service.ErrInsufficientAvailableBalance):
+			response.Error(w, errors.InsufficientFunds())
+		case stderrors.Is(err, service.ErrInvalidAdjustment):
+			response.Error(w, errors.InvalidInput(err.Error()))
+		default:
+			if appErr := movementAppError(err); appErr != nil {
+				response.Error(w, appErr)
+				return
+			}
+			response.Error(w, walletAppError(err, walletIDStr))
+		}
+		return
+	}
+
+	log.Info("Wallet balance adjusted",
+		zap.String("wallet_id", walletIDStr),
+		zap.String("amount", amount.String()),
+		zap.String("reason", req.Reason))
+
+	response.OK(w, wallet)
+}
+
+// GetWalletLimits returns a wallet's transaction limits
+// @Summary Get wallet limits
+// @Tags admin
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param id path string true "Wallet ID"
+// @Success 200 {object} models.WalletLimits
+// @Failure 400 {object} response.Problem "Invalid wallet ID"
+// @Failure 404 {object} response.Problem "Wallet not found"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v1/admin/wallets/{id}/limits [get]
+func (h *AdminHandler) GetWalletLimits(w http.ResponseWriter, r *http.Request) {
+	walletIDStr := chi.URLParam(r, "id")
+	walletID, err := uuid.Parse(walletIDStr)
+	if err != nil {
+		response.ErrorMessage(w, http.StatusBadRequest, "Invalid wallet ID")
+		return
+	}
+
+	limits, err := h.WalletService.GetWalletLimits(r.Context(), walletID)
+	if err != nil {
+		response.Error(w, walletAppError(err, walletIDStr))
+		return
+	}
+
+	response.OK(w, limits)
+}
+
+// SetWalletLimits replaces a wallet's transaction limits
+// @Summary Set wallet limits
+// @Description Caps single deposits, withdrawals and transfers, and the withdrawals and transfers out over any 24 hours, in the wallet's currency.
+// @Description An overdraft limit or a minimum balance sets how low withdrawals, transfers and holds may take the balance. Omitted limits are lifted.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param id path string true "Wallet ID"
+// @Param limits body limitsRequest true "New limits"
+// @Success 200 {object} models.WalletLimits
+// @Failure 400 {object} response.Problem "Invalid wallet ID or limits"
+// @Failure 404 {object} response.Problem "Wallet not found"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v1/admin/wallets/{id}/limits [put]
+func (h *AdminHandler) SetWalletLimits(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+	walletIDStr := chi.URLParam(r, "id")
+	walletID, err := uuid.Parse(walletIDStr)
+	if err != nil {
+		response.ErrorMessage(w, http.StatusBadRequest, "Invalid wallet ID")
+		return
+	}
+
+	var req limitsRequest
+	if appErr := decodeRequest(r, &req); appErr != nil {
+		response.Error(w, appErr)
+		return
+	}
+
+	limits, err := h.WalletService.SetWalletLimits(r.Context(), &models.WalletLimits{
+		WalletID:             walletID,
+		MaxTransactionAmount: req.MaxTransactionAmount,
+		DailyWithdrawalLimit: req.DailyWithdrawalLimit,
+		DailyTransferLimit:   req.DailyTransferLimit,
+		OverdraftLimit:       req.OverdraftLimit,
+		MinimumBalance:       req.MinimumBalance,
+	})
+	if err != nil {
+		log.Error("Wallet limits change failed", zap.Error(err), zap.String("wallet_id", walletIDStr))
+		if stderrors.Is(err, service.ErrInvalidLimits) || stderrors.Is(err, service.ErrConflictingBalanceFloors) {
+			response.Error(w, errors.InvalidInput(err.Error()))
+			return
+		}
+		response.Error(w, walletAppError(err, walletIDStr))
+		return
+	}
+
+	log.Info("Wallet limits changed", zap.String("wallet_id", walletIDStr))
+
+	response.OK(w, limits)
+}
+
+// parsePage reads the limit and offset query parameters. The limit is clamped the way
+// the services clamp it, so responses report the page size actually used.
+func parsePage(r *http.Request) (limit, offset int, appErr *errors.AppError) {
+	page, err := pagination.Parse(r.URL.Query())
+	if err != nil {
+		return 0, 0, paginationAppError(err)
+	}
+	return page.Limit, page.Offset, nil
+}
+
+// paginationAppError reports an invalid paging, sort or cursor parameter
+func paginationAppError(err error) *errors.AppError {
+	var paramErr *pagination.ParamError
+	if stderrors.As(err, &paramErr) {
+		return errors.InvalidInput(paramErr.Reason).WithDetails(paramErr.Param, paramErr.Value)
+	}
+	return errors.InvalidInput(err.Error())
+}
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	stderrors "errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/service"
+	"github.com/shanwije/wallet-app/pkg/errors"
+	"github.com/shanwije/wallet-app/pkg/logger"
+	"github.com/shanwije/wallet-app/pkg/response"
+)
+
+// alertSettingsRequest replaces a wallet's alert settings; an omitted or null threshold
+// turns its alert off
+type alertSettingsRequest struct {
+	LowBalanceThreshold *decimal.Decimal `json:"low_balance_threshold" swaggertype:"number" example:"50"`
+}
+
+// alertListResponse is one page of a wallet's alerts
+type alertListResponse struct {
+	Alerts []*models.WalletAlert `json:"alerts"`
+	Limit  int                   `json:"limit"`
+	Offset int                   `json:"offset"`
+}
+
+// ListAlerts returns the alerts raised for a wallet
+// @Summary List wallet alerts
+// @Description Returns the low balance alerts raised for the wallet, newest first, at most 200 per page.
+// @Description Each names the withdrawal or transfer that took the balance below the threshold.
+// @Tags wallets
+// @Produce json
+// @Param id path string true "Wallet ID"
+// @Param limit query int false "Page size; larger sizes are cut to 200" minimum(0) default(50)
+// @Param offset query int false "Alerts to skip" minimum(0) default(0)
+// @Success 200 {object} alertListResponse
+// @Failure 400 {object} response.Problem "Invalid wallet ID or pagination parameters"
+// @Failure 404 {object} response.Problem "Wallet not found"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v1/wallets/{id}/alerts [get]
+func (h *WalletHandler) ListAlerts(w http.ResponseWriter, r *http.Request) {
+	walletIDStr := chi.URLParam(r, "id")
+	walletID, err := uuid.Parse(walletIDStr)
+	if err != nil {
+		response.ErrorMessage(w, http.StatusBadRequest, "Invalid wallet ID")
+		return
+	}
+
+	limit, offset, appErr := parsePage(r)
+	if appErr != nil {
+		response.Error(w, appErr)
+		return
+	}
+
+	alerts, err := h.WalletService.ListAlerts(r.Context(), walletID, limit, offset)
+	if err != nil {
+		response.Error(w, walletAppError(err, walletIDStr))
+		return
+	}
+
+	response.OK(w, alertListResponse{Alerts: alerts, Limit: limit, Offset: offset})
+}
+
+// GetAlertSettings returns the thresholds a wallet raises alerts at
+// @Summary Get wallet alert settings
+// @Tags wallets
+// @Produce json
+// @Param id path string true "Wallet ID"
+// @Success 200 {object} models.WalletAlertSettings
+// @Failure 400 {object} response.Problem "Invalid wallet ID"
+// @Failure 404 {object} response.Problem "Wallet not found"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v1/wallets/{id}/alerts/settings [get]
+func (h *WalletHandler) GetAlertSettings(w http.ResponseWriter, r *http.Request) {
+	walletIDStr := chi.URLParam(r, "id")
+	walletID, err := uuid.Parse(walletIDStr)
+	if err != nil {
+		response.ErrorMessage(w, http.StatusBadRequest, "Invalid wallet ID")
+		return
+	}
+
+	settings, err := h.WalletService.GetAlertSettings(r.Context(), walletID)
+	if err != nil {
+		response.Error(w, walletAppError(err, walletIDStr))
+		return
+	}
+
+	response.OK(w, settings)
+}
+
+// SetAlertSettings replaces the thresholds a wallet raises alerts at
+// @Summary Set wallet alert settings
+// @Description A withdrawal, transfer or hold capture that takes the balance from at or above low_balance_threshold to below it records an alert and emails it to the wallet's owner.
+// @Description The threshold is in the wallet's currency; omitting it turns low balance alerts off.
+// @Tags wallets
+// @Accept json
+// @Produce json
+// @Param id path string true "Wallet ID"
+// @Param settings body alertSettingsRequest true "New alert settings"
+// @Success 200 {object} models.WalletAlertSettings
+// @Failure 400 {object} response.Problem "Invalid wallet ID or threshold"
+// @Failure 404 {object} response.Problem "Wallet not found"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v1/wallets/{id}/alerts/settings [put]
+func (h *WalletHandler) SetAlertSettings(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+	walletIDStr := chi.URLParam(r, "id")
+	walletID, err := uuid.Parse(walletIDStr)
+	if err != nil {
+		response.ErrorMessage(w, http.StatusBadRequest, "Invalid wallet ID")
+		return
+	}
+
+	var req alertSettingsRequest
+	if appErr := decodeRequest(r, &req); appErr != nil {
+		response.Error(w, appErr)
+		return
+	}
+
+	settings, err := h.WalletService.SetAlertSettings(r.Context(), &models.WalletAlertSettings{
+		WalletID:            walletID,
+		LowBalanceThreshold: req.LowBalanceThreshold,
+	})
+	if err != nil {
+		if stderrors.Is(err, service.ErrInvalidNotificationThreshold) {
+			response.Error(w, errors.InvalidInput(err.Error()))
+			return
+		}
+		response.Error(w, walletAppError(err, walletIDStr))
+		return
+	}
+
+	log.Info("Wallet alert settings changed", zap.String("wallet_id", walletIDStr))
+	response.OK(w, settings)
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shanwije/wallet-app/pkg/money"
+)
+
+func TestLowBalanceAlertsAreRaisedAsTheBalanceFallsBelowTheThreshold(t *testing.T) {
+	wallets := newWalletService(t)
+	from, to := createUserWallet(t, wallets), createUserWallet(t, wallets)
+	deposit := func(amount int64) {
+		_, err := wallets.Deposit(context.Background(), from.ID, money.New(decimal.NewFromInt(amount), money.DefaultCurrency), "")
+		require.NoError(t, err)
+	}
+	deposit(100)
+
+	handler := &WalletHandler{WalletService: wallets}
+	router := chi.NewRouter()
+	router.Put("/wallets/{id}/alerts/settings", handler.SetAlertSettings)
+	router.Get("/wallets/{id}/alerts", handler.ListAlerts)
+	router.Post("/wallets/{id}/withdraw", handler.Withdraw)
+	router.Post("/wallets/{id}/transfer", handler.Transfer)
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(method, "/wallets/"+from.ID.String()+path, strings.NewReader(body)))
+		return rr
+	}
+	withdraw := func(amount string) {
+		rr := send(http.MethodPost, "/withdraw", `{"amount":`+amount+`}`)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	}
+
+	assert.Equal(t, http.StatusBadRequest, send(http.MethodPut, "/alerts/settings", `{"low_balance_threshold":-5}`).Code)
+	rr := send(http.MethodPut, "/alerts/settings", `{"low_balance_threshold":50}`)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	// 100 -> 70 stays above the threshold, the transfer takes it to 40, and 40 -> 35
+	// was already below it
+	withdraw("30")
+	rr = send(http.MethodPost, "/transfer", `{"to_wallet_id":"`+to.ID.String()+`","amount":30}`)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	withdraw("5")
+	// Once the balance has recovered it can fall below the threshold again
+	deposit(100)
+	withdraw("90")
+
+	rr = send(http.MethodGet, "/alerts", "")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var page alertListResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &page))
+	require.Len(t, page.Alerts, 2, rr.Body.String())
+	assert.Equal(t, "45", page.Alerts[0].Balance.String())
+	assert.Equal(t, "40", page.Alerts[1].Balance.String())
+	for _, alert := range page.Alerts {
+		assert.Equal(t, "low_balance", alert.Type)
+		assert.Equal(t, "50", alert.Threshold.String())
+		assert.NotEqual(t, alert.ID, alert.JournalID)
+	}
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	stderrors "errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"github.com/shanwije/wallet-app/internal/service"
+	"github.com/shanwije/wallet-app/pkg/errors"
+	"github.com/shanwije/wallet-app/pkg/response"
+)
+
+// GetAnalytics totals a wallet's money in and out by category
+// @Summary Get wallet analytics
+// @Description Counts and sums the wallet's transactions in the period by the category they
+// @Description were filed under, in the wallet's currency. Uncategorized transactions are
+// @Description totalled last, under a null category.
+// @Tags wallets
+// @Produce json
+// @Param id path string true "Wallet ID"
+// @Param from query string false "Period start as YYYY-MM-DD or RFC3339; defaults to when the wallet was created"
+// @Param to query string false "Period end as YYYY-MM-DD (inclusive) or RFC3339 (exclusive); defaults to now"
+// @Success 200 {object} models.WalletAnalytics
+// @Failure 400 {object} response.Problem "Invalid wallet ID or period"
+// @Failure 404 {object} response.Problem "Wallet not found"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v1/wallets/{id}/analytics [get]
+func (h *WalletHandler) GetAnalytics(w http.ResponseWriter, r *http.Request) {
+	walletIDStr := chi.URLParam(r, "id")
+	walletID, err := uuid.Parse(walletIDStr)
+	if err != nil {
+		response.ErrorMessage(w, http.StatusBadRequest, "Invalid wallet ID")
+		return
+	}
+
+	query := r.URL.Query()
+	from, err := parseStatementTime(query.Get("from"), false)
+	if err != nil {
+		response.Error(w, errors.InvalidInput("Invalid from date").WithDetails("from", query.Get("from")))
+		return
+	}
+	to, err := parseStatementTime(query.Get("to"), true)
+	if err != nil {
+		response.Error(w, errors.InvalidInput("Invalid to date").WithDetails("to", query.Get("to")))
+		return
+	}
+
+	analytics, err := h.WalletService.GetAnalytics(r.Context(), walletID, from, to)
+	if err != nil {
+		if stderrors.Is(err, service.ErrInvalidAnalyticsPeriod) {
+			response.Error(w, errors.InvalidInput(err.Error()))
+			return
+		}
+		response.Error(w, walletAppError(err, walletIDStr))
+		return
+	}
+
+	response.OK(w, analytics)
+}
@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	stderrors "errors"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/service"
+	"github.com/shanwije/wallet-app/pkg/auth"
+	"github.com/shanwije/wallet-app/pkg/errors"
+	"github.com/shanwije/wallet-app/pkg/logger"
+	"github.com/shanwije/wallet-app/pkg/response"
+)
+
+type AuthHandler struct {
+	UserService UserServiceInterface
+	Tokens      *auth.TokenManager
+}
+
+type registerRequest struct {
+	Name     string `json:"name"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+type loginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// tokenResponse is returned by login and registration
+type tokenResponse struct {
+	AccessToken string                 `json:"access_token"`
+	TokenType   string                 `json:"token_type"`
+	ExpiresAt   time.Time              `json:"expires_at"`
+	User        *models.UserWithWallet `json:"user,omitempty"`
+}
+
+// NewAuthHandler creates a new AuthHandler
+func NewAuthHandler(userService UserServiceInterface, tokens *auth.TokenManager) *AuthHandler {
+	return &AuthHandler{
+		UserService: userService,
+		Tokens:      tokens,
+	}
+}
+
+// Register creates a user with wallet and credentials and returns an access token
+// @Summary Register user
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param user body registerRequest true "Registration details"
+// @Param Idempotency-Key header string false "Makes retries safe: a repeat with the same key and body gets the first response"
+// @Success 201 {object} tokenResponse
+// @Failure 400 {object} response.Problem "Invalid registration details"
+// @Failure 409 {object} response.Problem "Username already taken, or Idempotency-Key reused with a different request body"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v1/auth/register [post]
+func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+
+	var req registerRequest
+	if appErr := decodeRequest(r, &req); appErr != nil {
+		log.Error("Failed to decode register request", zap.Error(appErr))
+		response.Error(w, appErr)
+		return
+	}
+
+	if req.Name == "" || req.Username == "" {
+		response.Error(w, errors.New(errors.ErrMissingField, "Name and username are required", http.StatusBadRequest))
+		return
+	}
+
+	user, err := h.UserService.Register(r.Context(), req.Name, req.Username, req.Password)
+	if err != nil {
+		switch {
+		case stderrors.Is(err, service.ErrUsernameTaken):
+			response.Error(w, errors.Conflict(err.Error()))
+		case stderrors.Is(err, auth.ErrPasswordTooShort):
+			response.Error(w, errors.InvalidInput(err.Error()))
+		default:
+			log.Error("Failed to register user", zap.Error(err), zap.String("username", req.Username))
+			response.ErrorMessage(w, http.StatusInternalServerError, "Failed to register user")
+		}
+		return
+	}
+
+	token, expiresAt, err := h.Tokens.Issue(user.ID)
+	if err != nil {
+		log.Error("Failed to issue token", zap.Error(err))
+		response.ErrorMessage(w, http.StatusInternalServerError, "Failed to issue token")
+		return
+	}
+
+	log.Info("User registered successfully", zap.String("user_id", user.ID.String()))
+	response.Created(w, "", tokenResponse{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresAt:   expiresAt,
+		User:        user,
+	})
+}
+
+// Login exchanges a username and password for an access token
+// @Summary Log in
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param credentials body loginRequest true "Login details"
+// @Success 200 {object} tokenResponse
+// @Failure 400 {object} response.Problem "Invalid request body"
+// @Failure 401 {object} response.Problem "Wrong username or password"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v1/auth/login [post]
+func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+
+	var req loginRequest
+	if appErr := decodeRequest(r, &req); appErr != nil {
+		log.Error("Failed to decode login request", zap.Error(appErr))
+		response.Error(w, appErr)
+		return
+	}
+
+	user, err := h.UserService.Login(r.Context(), req.Username, req.Password)
+	if err != nil {
+		if stderrors.Is(err, service.ErrInvalidCredentials) {
+			log.Warn("Login failed", zap.String("username", req.Username))
+			response.Error(w, errors.Unauthorized(err.Error()))
+			return
+		}
+		log.Error("Login error", zap.Error(err))
+		response.ErrorMessage(w, http.StatusInternalServerError, "Failed to log in")
+		return
+	}
+
+	token, expiresAt, err := h.Tokens.Issue(user.ID)
+	if err != nil {
+		log.Error("Failed to issue token", zap.Error(err))
+		response.ErrorMessage(w, http.StatusInternalServerError, "Failed to issue token")
+		return
+	}
+
+	response.OK(w, tokenResponse{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresAt:   expiresAt,
+	})
+}
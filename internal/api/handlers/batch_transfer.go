@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	stderrors "errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/shanwije/wallet-app/internal/service"
+	"github.com/shanwije/wallet-app/pkg/errors"
+	"github.com/shanwije/wallet-app/pkg/logger"
+	"github.com/shanwije/wallet-app/pkg/money"
+	"github.com/shanwije/wallet-app/pkg/response"
+)
+
+// Statuses of the items in a batch transfer response
+const (
+	batchItemCompleted    = "completed"
+	batchItemFailed       = "failed"
+	batchItemRolledBack   = "rolled_back"
+	batchItemNotAttempted = "not_attempted"
+)
+
+// batchTransferRequest lists the transfers of a batch; each names its recipient
+// the same way a single transfer does
+type batchTransferRequest struct {
+	Transfers []transferRequest `json:"transfers" validate:"required,dive"`
+}
+
+// batchTransferResult reports the outcome of one transfer in a batch
+type batchTransferResult struct {
+	Index      int    `json:"index"`
+	Status     string `json:"status" example:"completed"`
+	ToWalletID string `json:"to_wallet_id,omitempty"`
+	JournalID  string `json:"journal_id,omitempty"`
+	Code       string `json:"code,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// batchTransferResponse is completed when every transfer was posted and failed when none were
+type batchTransferResponse struct {
+	Status  string                `json:"status" example:"completed"`
+	Results []batchTransferResult `json:"results"`
+}
+
+// BatchTransfer pays several recipients from one wallet, all or nothing
+// @Summary Batch transfer
+// @Description Posts up to 100 transfers in a single transaction. If any transfer fails none are posted, and the results name the one that failed.
+// @Tags wallets
+// @Accept json
+// @Produce json
+// @Param id path string true "Wallet ID"
+// @Param batch body batchTransferRequest true "Transfers to post"
+// @Param Idempotency-Key header string false "Makes retries safe: a repeat with the same key and body gets the first response"
+// @Param X-Transaction-PIN header string false "Owner's transaction PIN, needed over the PIN threshold once they have set one"
+// @Success 200 {object} batchTransferResponse
+// @Failure 400 {object} batchTransferResponse "A transfer was invalid or overdrew the wallet; the results name it"
+// @Failure 403 {object} response.Problem "Transaction PIN required or incorrect"
+// @Failure 404 {object} batchTransferResponse "A wallet or recipient was not found"
+// @Failure 409 {object} batchTransferResponse "A wallet is frozen or closed, or was updated concurrently"
+// @Failure 422 {object} batchTransferResponse "A transfer broke a wallet limit, or the batch adds up to more than the confirmation threshold"
+// @Failure 423 {object} response.Problem "Transaction PIN locked after too many incorrect attempts"
+// @Failure 429 {object} response.Problem "Rate limit exceeded"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v1/wallets/{id}/transfers/batch [post]
+func (h *WalletHandler) BatchTransfer(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+	ctx := r.Context()
+	fromWalletIDStr := chi.URLParam(r, "id")
+	fromWalletID, err := uuid.Parse(fromWalletIDStr)
+	if err != nil {
+		response.ErrorMessage(w, http.StatusBadRequest, "Invalid wallet ID")
+		return
+	}
+
+	var req batchTransferRequest
+	if appErr := decodeRequest(r, &req); appErr != nil {
+		response.Error(w, appErr)
+		return
+	}
+	if appErr := validateRequest(req); appErr != nil {
+		response.Error(w, appErr)
+		return
+	}
+	if len(req.Transfers) > service.MaxBatchTransfers {
+		response.Error(w, errors.InvalidInput(service.ErrInvalidBatchSize.Error()).
+			WithDetails("max_transfers", strconv.Itoa(service.MaxBatchTransfers)))
+		return
+	}
+
+	// Amounts and recipients are checked up front so a bad item fails before any locks are taken
+	items := make([]service.BatchTransferItem, len(req.Transfers))
+	for i, transfer := range req.Transfers {
+		if transfer.QuoteID != "" {
+			respondWithBatchFailure(w, len(items), i, false, errors.InvalidInput("Quoted transfers cannot be batched"))
+			return
+		}
+		amount, appErr := parseAmount(transfer.Amount, transfer.Currency)
+		if appErr != nil {
+			respondWithBatchFailure(w, len(items), i, false, appErr)
+			return
+		}
+
+		toWalletID, err := h.WalletService.ResolveRecipient(ctx, recipientFromRequest(transfer))
+		if err != nil {
+			respondWithBatchFailure(w, len(items), i, false, recipientAppError(err, transfer))
+			return
+		}
+
+		items[i] = service.BatchTransferItem{
+			ToWalletID:  toWalletID,
+			Amount:      amount,
+			Description: transfer.Description,
+		}
+	}
+
+	// The PIN is asked for by what the batch pays out altogether
+	total := items[0].Amount
+	for _, item := range items[1:] {
+		total = money.New(total.Amount().Add(item.Amount.Amount()), total.Currency())
+	}
+	if appErr := authorizePIN(r, h.PINs, fromWalletID, total); appErr != nil {
+		response.Error(w, appErr)
+		return
+	}
+
+	journals, err := h.WalletService.BatchTransfer(ctx, fromWalletID, items, r.Header.Get("Idempotency-Key"))
+	if err != nil {
+		log.Error("Batch transfer failed", zap.Error(err),
+			zap.String("wallet_id", fromWalletIDStr),
+			zap.Int("transfers", len(items)))
+
+		var itemErr *service.BatchItemError
+		if !stderrors.As(err, &itemErr) {
+			if appErr := movementAppError(err); appErr != nil {
+				response.Error(w, appErr)
+				return
+			}
+			response.Error(w, errors.InternalError(err))
+			return
+		}
+
+		appErr := movementAppError(itemErr.Err)
+		if appErr == nil {
+			appErr = errors.New("", itemErr.Err.Error(), http.StatusBadRequest)
+		}
+		respondWithBatchFailure(w, len(items), itemErr.Index, true, appErr)
+		return
+	}
+
+	log.Info("Batch transfer successful",
+		zap.String("wallet_id", fromWalletIDStr),
+		zap.Int("transfers", len(journals)))
+
+	results := make([]batchTransferResult, len(journals))
+	for i, journal := range journals {
+		results[i] = batchTransferResult{
+			Index:      i,
+			Status:     batchItemCompleted,
+			ToWalletID: items[i].ToWalletID.String(),
+			JournalID:  journal.ID.String(),
+		}
+	}
+
+	response.OK(w, batchTransferResponse{Status: batchItemCompleted, Results: results})
+}
+
+// respondWithBatchFailure reports a batch of count transfers that was rejected, posting
+// nothing, because the transfer at index failed could not be made; appErr says why and
+// sets the status. When rolledBack, the transfers before it had run and were undone;
+// otherwise none of them ran.
+func respondWithBatchFailure(w http.ResponseWriter, count, failed int, rolledBack bool, appErr *errors.AppError) {
+	results := make([]batchTransferResult, count)
+	for i := range results {
+		results[i] = batchTransferResult{Index: i, Status: batchItemNotAttempted}
+		switch {
+		case i == failed:
+			results[i].Status = batchItemFailed
+			results[i].Code = appErr.Code
+			results[i].Error = appErr.Message
+		case i < failed && rolledBack:
+			results[i].Status = batchItemRolledBack
+		}
+	}
+
+	response.JSON(w, appErr.HTTPStatus, batchTransferResponse{Status: batchItemFailed, Results: results})
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shanwije/wallet-app/internal/models"
+)
+
+func TestTransactionsAreCategorizedFilteredAndTotalled(t *testing.T) {
+	wallets := newWalletService(t)
+	wallet, landlord := createUserWallet(t, wallets), createUserWallet(t, wallets)
+
+	handler := NewWalletHandler(wallets)
+	router := chi.NewRouter()
+	router.Post("/wallets/{id}/deposit", handler.DepositV2)
+	router.Post("/wallets/{id}/withdraw", handler.Withdraw)
+	router.Post("/wallets/{id}/transfer", handler.TransferV2)
+	router.Get("/wallets/{id}/transactions", handler.GetTransactionHistory)
+	router.Get("/wallets/{id}/analytics", handler.GetAnalytics)
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rr
+	}
+	walletPath := "/wallets/" + wallet.ID.String()
+
+	rr := send(http.MethodPost, walletPath+"/deposit", `{"amount":500,"category":" Salary ","tags":["July","july","acme"]}`)
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	var deposit movementResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &deposit))
+	require.NotNil(t, deposit.Category)
+	assert.Equal(t, "salary", *deposit.Category)
+	assert.Equal(t, []string{"july", "acme"}, deposit.Tags)
+
+	require.Equal(t, http.StatusOK, send(http.MethodPost, walletPath+"/withdraw", `{"amount":40,"category":"groceries"}`).Code)
+	require.Equal(t, http.StatusOK, send(http.MethodPost, walletPath+"/withdraw", `{"amount":25.5,"category":"groceries","tags":["market"]}`).Code)
+	require.Equal(t, http.StatusOK, send(http.MethodPost, walletPath+"/withdraw", `{"amount":10}`).Code)
+	rr = send(http.MethodPost, walletPath+"/transfer", `{"to_wallet_id":"`+landlord.ID.String()+`","amount":300,"category":"rent"}`)
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	assert.Equal(t, http.StatusBadRequest, send(http.MethodPost, walletPath+"/withdraw",
+		`{"amount":1,"category":"`+strings.Repeat("x", 51)+`"}`).Code)
+
+	// The recipient sees the transfer under the category the sender gave it
+	rr = send(http.MethodGet, "/wallets/"+landlord.ID.String()+"/transactions?category=rent", "")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var history []*models.Transaction
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &history))
+	require.Len(t, history, 1)
+	assert.Equal(t, models.TransactionTypeTransferIn, history[0].Type)
+
+	rr = send(http.MethodGet, walletPath+"/transactions?category=Groceries", "")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &history))
+	require.Len(t, history, 2)
+	assert.Equal(t, "25.5", history[0].Amount.String())
+	assert.Equal(t, []string{"market"}, history[0].Tags)
+	assert.Empty(t, history[1].Tags)
+
+	rr = send(http.MethodGet, walletPath+"/analytics", "")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var analytics models.WalletAnalytics
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &analytics))
+	assert.Equal(t, "500", analytics.AmountIn.String())
+	assert.Equal(t, "375.5", analytics.AmountOut.String())
+	require.Len(t, analytics.Categories, 4)
+	for i, want := range []struct {
+		category string
+		count    int64
+		in, out  string
+	}{
+		{"groceries", 2, "0", "65.5"},
+		{"rent", 1, "0", "300"},
+		{"salary", 1, "500", "0"},
+		{"", 1, "0", "10"},
+	} {
+		total := analytics.Categories[i]
+		if want.category == "" {
+			assert.Nil(t, total.Category)
+		} else {
+			require.NotNil(t, total.Category)
+			assert.Equal(t, want.category, *total.Category)
+		}
+		assert.Equal(t, want.count, total.TransactionCount)
+		assert.Equal(t, want.in, total.AmountIn.String())
+		assert.Equal(t, want.out, total.AmountOut.String())
+	}
+	assert.Equal(t, "-65.5", analytics.Categories[0].NetAmount.String())
+
+	assert.Equal(t, http.StatusBadRequest, send(http.MethodGet, walletPath+"/analytics?from=2024-07-02&to=2024-07-01", "").Code)
+}
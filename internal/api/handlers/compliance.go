@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"encoding/csv"
+	stderrors "errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository"
+	"github.com/shanwije/wallet-app/internal/service"
+	"github.com/shanwije/wallet-app/pkg/errors"
+	"github.com/shanwije/wallet-app/pkg/logger"
+	"github.com/shanwije/wallet-app/pkg/response"
+)
+
+// ComplianceHandler serves operators the AML reports raised by deposits, withdrawals
+// and transfers
+type ComplianceHandler struct {
+	ComplianceService *service.ComplianceService
+}
+
+// amlReportListResponse is one page of AML reports
+type amlReportListResponse struct {
+	Reports []*models.AMLReport `json:"reports"`
+	Limit   int                 `json:"limit"`
+	Offset  int                 `json:"offset"`
+}
+
+// NewComplianceHandler creates a new ComplianceHandler
+func NewComplianceHandler(complianceService *service.ComplianceService) *ComplianceHandler {
+	return &ComplianceHandler{
+		ComplianceService: complianceService,
+	}
+}
+
+// parseAMLPeriod reads the from and to query parameters, as dates or RFC3339 times
+func parseAMLPeriod(r *http.Request) (from, to time.Time, appErr *errors.AppError) {
+	query := r.URL.Query()
+	from, err := parseStatementTime(query.Get("from"), false)
+	if err != nil {
+		return from, to, errors.InvalidInput("Invalid from date").WithDetails("from", query.Get("from"))
+	}
+	to, err = parseStatementTime(query.Get("to"), true)
+	if err != nil {
+		return from, to, errors.InvalidInput("Invalid to date").WithDetails("to", query.Get("to"))
+	}
+	return from, to, nil
+}
+
+// ListReports pages through AML reports
+// @Summary List AML reports
+// @Description Returns the movements the AML checks reported, newest first and at most 200 per page: large transactions over AML_REPORT_THRESHOLD and runs of movements just below it.
+// @Tags admin
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param type query string false "large_transaction or structuring"
+// @Param wallet_id query string false "Only reports on this wallet"
+// @Param from query string false "Reports at or after this date (YYYY-MM-DD) or time (RFC3339)"
+// @Param to query string false "Reports up to this date (inclusive) or before this time"
+// @Param limit query int false "Page size; larger sizes are cut to 200" minimum(0) default(50)
+// @Param offset query int false "Reports to skip" minimum(0) default(0)
+// @Success 200 {object} amlReportListResponse
+// @Failure 400 {object} response.Problem "Invalid filter or pagination parameters"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v1/admin/aml-reports [get]
+func (h *ComplianceHandler) ListReports(w http.ResponseWriter, r *http.Request) {
+	limit, offset, appErr := parsePage(r)
+	if appErr != nil {
+		response.Error(w, appErr)
+		return
+	}
+
+	query := r.URL.Query()
+	filter := repository.AMLReportFilter{Type: query.Get("type"), Limit: limit, Offset: offset}
+	switch filter.Type {
+	case "", models.AMLReportLargeTransaction, models.AMLReportStructuring:
+	default:
+		response.Error(w, errors.InvalidInput("Type must be large_transaction or structuring").
+			WithDetails("type", filter.Type))
+		return
+	}
+	if value := query.Get("wallet_id"); value != "" {
+		walletID, err := uuid.Parse(value)
+		if err != nil {
+			response.ErrorMessage(w, http.StatusBadRequest, "Invalid wallet ID")
+			return
+		}
+		filter.WalletID = walletID
+	}
+	if filter.From, filter.To, appErr = parseAMLPeriod(r); appErr != nil {
+		response.Error(w, appErr)
+		return
+	}
+
+	reports, err := h.ComplianceService.ListReports(r.Context(), filter)
+	if stderrors.Is(err, service.ErrInvalidAMLReportPeriod) {
+		response.Error(w, errors.InvalidInput("to must be after from"))
+		return
+	}
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Failed to list AML reports", zap.Error(err))
+		response.Error(w, errors.InternalError(err))
+		return
+	}
+
+	response.OK(w, amlReportListResponse{Reports: reports, Limit: limit, Offset: offset})
+}
+
+// ExportReports downloads the AML reports of a period as CSV
+// @Summary Export AML reports
+// @Description Streams every AML report raised in the period as CSV, oldest first, for filing with the regulator.
+// @Tags admin
+// @Produce text/csv
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param from query string false "Period start as YYYY-MM-DD or RFC3339; defaults to the first report"
+// @Param to query string false "Period end as YYYY-MM-DD (inclusive) or RFC3339 (exclusive); defaults to now"
+// @Success 200 {file} file
+// @Failure 400 {object} response.Problem "Invalid period"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v1/admin/aml-reports/export [get]
+func (h *ComplianceHandler) ExportReports(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+	from, to, appErr := parseAMLPeriod(r)
+	if appErr != nil {
+		response.Error(w, appErr)
+		return
+	}
+
+	// Rows are buffered, so nothing is sent before an invalid period is caught
+	resp := &statementResponse{ResponseWriter: w}
+	writer := csv.NewWriter(resp)
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="aml-reports.csv"`)
+	writer.Write([]string{"id", "created_at", "type", "wallet_id", "journal_id", "operation",
+		"amount", "currency", "movement_count", "total", "reason"})
+
+	err := h.ComplianceService.ExportReports(r.Context(), from, to, func(report *models.AMLReport) error {
+		writer.Write([]string{
+			report.ID.String(),
+			report.CreatedAt.UTC().Format(time.RFC3339),
+			report.Type,
+			report.WalletID.String(),
+			report.JournalID.String(),
+			report.Operation,
+			report.Amount.StringFixed(report.Currency.MinorUnits()),
+			report.Currency.String(),
+			strconv.Itoa(report.MovementCount),
+			report.Total.StringFixed(report.Currency.MinorUnits()),
+			report.Reason,
+		})
+		return writer.Error()
+	})
+	if err == nil {
+		writer.Flush()
+		return
+	}
+
+	log.Error("AML report export failed", zap.Error(err))
+	if resp.written {
+		// The status line is already out; the client sees the download cut short
+		return
+	}
+	w.Header().Del("Content-Disposition")
+	if stderrors.Is(err, service.ErrInvalidAMLReportPeriod) {
+		response.Error(w, errors.InvalidInput("to must be after from"))
+		return
+	}
+	response.Error(w, errors.InternalError(err))
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository/sqlite"
+	"github.com/shanwije/wallet-app/internal/service"
+	"github.com/shanwije/wallet-app/pkg/clock"
+	"github.com/shanwije/wallet-app/pkg/money"
+)
+
+func TestAMLReportsLargeAndStructuredMovements(t *testing.T) {
+	ctx := context.Background()
+	conn := newTestDB(t)
+	now := clock.NewFake(time.Date(2024, 7, 21, 9, 0, 0, 0, time.UTC))
+	wallets := walletServiceOn(conn)
+	wallets.Clock = now
+	wallets.AMLRepo = sqlite.NewAMLRepository(conn)
+	wallets.AML = &service.AMLRules{
+		ReportThreshold:   decimal.NewFromInt(1000),
+		StructuringMargin: decimal.RequireFromString("0.1"),
+		StructuringCount:  3,
+		StructuringWindow: 24 * time.Hour,
+	}
+	handler := NewComplianceHandler(&service.ComplianceService{Repo: wallets.AMLRepo, Clock: now})
+	usd := func(amount int64) money.Money {
+		return money.New(decimal.NewFromInt(amount), money.DefaultCurrency)
+	}
+
+	wallet := createUserWallet(t, wallets)
+	_, err := wallets.Deposit(ctx, wallet.ID, usd(9000), "")
+	require.NoError(t, err)
+	// Three withdrawals just under the threshold within a day are a run; one at the
+	// threshold counts, one well under it does not, and the run is reported only once
+	for _, amount := range []int64{950, 500, 1000, 920, 990} {
+		now.Advance(time.Hour)
+		_, err := wallets.Withdraw(ctx, wallet.ID, usd(amount), "")
+		require.NoError(t, err)
+	}
+
+	list := func(query string) (*httptest.ResponseRecorder, amlReportListResponse) {
+		rr := httptest.NewRecorder()
+		handler.ListReports(rr, httptest.NewRequest(http.MethodGet, "/admin/aml-reports"+query, nil))
+		var page amlReportListResponse
+		if rr.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &page))
+		}
+		return rr, page
+	}
+
+	rr, page := list("")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	require.Len(t, page.Reports, 2)
+	structuring, large := page.Reports[0], page.Reports[1]
+	assert.Equal(t, models.AMLReportLargeTransaction, large.Type)
+	assert.Equal(t, models.JournalTypeDeposit, large.Operation)
+	assert.Equal(t, "9000", large.Amount.String())
+	assert.Equal(t, models.AMLReportStructuring, structuring.Type)
+	assert.Equal(t, models.JournalTypeWithdraw, structuring.Operation)
+	assert.Equal(t, 3, structuring.MovementCount)
+	assert.Equal(t, "2870", structuring.Total.String())
+	assert.Equal(t, wallet.ID, structuring.WalletID)
+
+	_, page = list("?type=structuring")
+	assert.Len(t, page.Reports, 1)
+	rr, _ = list("?type=suspicious")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	rr, _ = list("?from=2024-07-22&to=2024-07-21")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	// Once the window has passed the next run is reported again
+	now.Advance(24 * time.Hour)
+	for range 3 {
+		_, err := wallets.Withdraw(ctx, wallet.ID, usd(950), "")
+		require.NoError(t, err)
+	}
+	_, page = list("?type=structuring")
+	assert.Len(t, page.Reports, 2)
+
+	rr = httptest.NewRecorder()
+	handler.ExportReports(rr, httptest.NewRequest(http.MethodGet, "/admin/aml-reports/export?from=2024-07-21&to=2024-07-21", nil))
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.Equal(t, "text/csv; charset=utf-8", rr.Header().Get("Content-Type"))
+	rows, err := csv.NewReader(strings.NewReader(rr.Body.String())).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, 3)
+	assert.Equal(t, []string{"id", "created_at", "type", "wallet_id", "journal_id", "operation",
+		"amount", "currency", "movement_count", "total", "reason"}, rows[0])
+	assert.Equal(t, models.AMLReportLargeTransaction, rows[1][2], "oldest first")
+	assert.Equal(t, "9000.00", rows[1][6])
+	assert.Equal(t, models.AMLReportStructuring, rows[2][2])
+	assert.Equal(t, "3", rows[2][8])
+
+	rr = httptest.NewRecorder()
+	handler.ExportReports(rr, httptest.NewRequest(http.MethodGet, "/admin/aml-reports/export?from=2024-07-23", nil))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Empty(t, rr.Header().Get("Content-Disposition"))
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	stderrors "errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+
+	"github.com/shanwije/wallet-app/internal/service"
+	"github.com/shanwije/wallet-app/pkg/errors"
+	"github.com/shanwije/wallet-app/pkg/logger"
+	"github.com/shanwije/wallet-app/pkg/money"
+	"github.com/shanwije/wallet-app/pkg/response"
+)
+
+type correctionRequest struct {
+	// Why the transaction was wrong: duplicate, wrong_amount, wrong_recipient, fraud or system_error
+	Reason string `json:"reason" validate:"required" example:"duplicate"`
+	// Amount to correct, in the currency the transfer was sent in; the whole transaction when omitted
+	Amount   *decimal.Decimal `json:"amount,omitempty" swaggertype:"string" example:"5.00"`
+	Currency string           `json:"currency,omitempty"`
+	Note     string           `json:"note,omitempty" example:"Card payment captured twice, ticket 4821"`
+}
+
+// CorrectTransaction posts an operator correction for an erroneous transaction
+// @Summary Correct a transaction
+// @Description Marks the transaction as erroneous by posting every leg of its journal again in
+// @Description the opposite direction, as a correction that references it and records the reason.
+// @Description The original stays in the ledger unchanged and its history entries gain
+// @Description corrected_by_reference_id. A transfer can be corrected in part by passing an amount
+// @Description in the currency it was sent in. Each transaction can be corrected or reversed once;
+// @Description frozen wallets can be corrected, closed ones cannot.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param id path string true "Transaction ID"
+// @Param correction body correctionRequest true "Reason code, partial amount and note"
+// @Success 201 {object} models.Journal
+// @Failure 400 {object} response.Problem "Invalid transaction ID, reason or amount, or the correction would overdraw a wallet"
+// @Failure 404 {object} response.Problem "Transaction not found"
+// @Failure 409 {object} response.Problem "Transaction already corrected, reversed, refunded or paid out, transfer awaiting acceptance, or a wallet is closed"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v1/admin/transactions/{id}/corrections [post]
+func (h *AdminHandler) CorrectTransaction(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.FromContext(ctx)
+	transactionIDStr := chi.URLParam(r, "id")
+	transactionID, err := uuid.Parse(transactionIDStr)
+	if err != nil {
+		response.ErrorMessage(w, http.StatusBadRequest, "Invalid transaction ID")
+		return
+	}
+
+	var req correctionRequest
+	if appErr := decodeRequest(r, &req); appErr != nil {
+		response.Error(w, appErr)
+		return
+	}
+	if appErr := validateRequest(req); appErr != nil {
+		response.Error(w, appErr)
+		return
+	}
+
+	var amount *money.Money
+	if req.Amount != nil {
+		parsed, appErr := parseAmount(*req.Amount, req.Currency)
+		if appErr != nil {
+			response.Error(w, appErr)
+			return
+		}
+		amount = &parsed
+	}
+
+	correction, err := h.WalletService.CorrectTransaction(ctx, transactionID, amount, req.Reason, req.Note)
+	if err != nil {
+		log.Error("Failed to correct transaction", zap.Error(err), zap.String("transaction_id", transactionIDStr))
+		if stderrors.Is(err, service.ErrInvalidCorrectionReason) {
+			response.Error(w, errors.InvalidInput(err.Error()))
+			return
+		}
+		if appErr := reversalAppError(err, transactionIDStr); appErr != nil {
+			response.Error(w, appErr)
+			return
+		}
+		response.Error(w, errors.InternalError(err))
+		return
+	}
+
+	log.Info("Transaction corrected",
+		zap.String("transaction_id", transactionIDStr),
+		zap.String("correction_id", correction.ID.String()),
+		zap.String("reason", req.Reason))
+
+	response.Created(w, "", correction)
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/service"
+	"github.com/shanwije/wallet-app/pkg/money"
+)
+
+func TestCorrectionUndoesATransferAndIsShownInBothHistories(t *testing.T) {
+	wallets := newWalletService(t)
+	ctx := context.Background()
+	from, to := createUserWallet(t, wallets), createUserWallet(t, wallets)
+	_, err := wallets.Deposit(ctx, from.ID, money.New(decimal.NewFromInt(100), money.DefaultCurrency), "")
+	require.NoError(t, err)
+	require.NoError(t, wallets.Transfer(ctx, from.ID, to.ID, money.New(decimal.NewFromInt(30), money.DefaultCurrency), "Rent", ""))
+	history, err := wallets.GetTransactionHistory(ctx, to.ID)
+	require.NoError(t, err)
+	transferIn := history[0]
+
+	router := chi.NewRouter()
+	router.Post("/transactions/{id}/corrections", (&AdminHandler{WalletService: wallets}).CorrectTransaction)
+	correct := func(body string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/transactions/"+transferIn.ID.String()+"/corrections", strings.NewReader(body)))
+		return rr
+	}
+
+	assert.Equal(t, http.StatusBadRequest, correct(`{"reason":"changed_my_mind"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, correct(`{}`).Code)
+
+	// The recipient's wallet being frozen does not stop an operator
+	_, err = wallets.SetWalletStatus(ctx, to.ID, models.WalletStatusFrozen)
+	require.NoError(t, err)
+	rr := correct(`{"reason":"wrong_recipient","note":"Paid the wrong flat"}`)
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	var correction models.Journal
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &correction))
+	assert.Equal(t, models.JournalTypeCorrection, correction.Type)
+	assert.Equal(t, transferIn.ReferenceID, correction.ReversesJournalID)
+	require.NotNil(t, correction.CorrectionReason)
+	assert.Equal(t, models.CorrectionReasonWrongRecipient, *correction.CorrectionReason)
+
+	for wallet, balance := range map[*models.Wallet]string{from: "100", to: "0"} {
+		current, err := wallets.GetBalance(ctx, wallet.ID)
+		require.NoError(t, err)
+		assert.Equal(t, balance, current.Balance.String())
+	}
+
+	// The transfer is still in the history, marked with the correction that undid it
+	history, err = wallets.GetTransactionHistory(ctx, to.ID)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, models.TransactionTypeCorrectionOut, history[0].Type)
+	assert.Equal(t, &correction.ID, history[0].ReferenceID)
+	assert.Equal(t, transferIn.ReferenceID, history[0].ReversesReferenceID)
+	require.NotNil(t, history[0].CorrectionReason)
+	assert.Equal(t, models.CorrectionReasonWrongRecipient, *history[0].CorrectionReason)
+	assert.Equal(t, transferIn.ID, history[1].ID)
+	assert.Equal(t, "30", history[1].Amount.String())
+	assert.Equal(t, &correction.ID, history[1].CorrectedByReferenceID)
+
+	history, err = wallets.GetTransactionHistory(ctx, from.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.TransactionTypeCorrectionIn, history[0].Type)
+	assert.Equal(t, &correction.ID, history[1].CorrectedByReferenceID)
+
+	// A transaction is corrected once, and neither a correction nor a corrected
+	// transaction can be reversed
+	_, err = wallets.SetWalletStatus(ctx, to.ID, models.WalletStatusActive)
+	require.NoError(t, err)
+	_, err = wallets.Deposit(ctx, to.ID, money.New(decimal.NewFromInt(30), money.DefaultCurrency), "")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusConflict, correct(`{"reason":"duplicate"}`).Code)
+	_, err = wallets.ReverseTransaction(ctx, transferIn.ID, nil, "")
+	assert.ErrorIs(t, err, service.ErrAlreadyReversed)
+	_, err = wallets.ReverseTransaction(ctx, history[0].ID, nil, "")
+	assert.ErrorIs(t, err, service.ErrNotReversible)
+}
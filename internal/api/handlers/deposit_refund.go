@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	stderrors "errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+
+	"github.com/shanwije/wallet-app/internal/service"
+	"github.com/shanwije/wallet-app/pkg/auth"
+	"github.com/shanwije/wallet-app/pkg/errors"
+	"github.com/shanwije/wallet-app/pkg/logger"
+	"github.com/shanwije/wallet-app/pkg/money"
+	"github.com/shanwije/wallet-app/pkg/response"
+)
+
+type depositRefundRequest struct {
+	// Amount to refund, in the deposit's currency; what is left of the deposit when omitted
+	Amount   *decimal.Decimal `json:"amount,omitempty" swaggertype:"string" example:"10.00"`
+	Currency string           `json:"currency,omitempty"`
+	Reason   string           `json:"reason,omitempty" example:"Duplicate top-up"`
+}
+
+// depositRefundAppError maps the failures of a deposit refund that have their own
+// error code; it returns nil for the rest
+func depositRefundAppError(err error, transactionID string) *errors.AppError {
+	switch {
+	case stderrors.Is(err, service.ErrNotRefundable),
+		stderrors.Is(err, service.ErrInvalidDepositRefundAmount):
+		return errors.InvalidInput(err.Error())
+	default:
+		return reversalAppError(err, transactionID)
+	}
+}
+
+// RefundTransaction pays a deposit back out of its wallet
+// @Summary Refund a deposit
+// @Description Debits the wallet the deposit credited by the amount, or by what is left of
+// @Description the deposit when it is omitted, as a withdrawal linked to the deposit. A
+// @Description deposit can be refunded in several parts, never for more than it brought in,
+// @Description and not once it was reversed. A deposit the payment provider funded is also
+// @Description refunded to the customer through it when a provider API is configured: the
+// @Description refund is pending until the provider has refunded the payment, when it is
+// @Description completed, and failed and credited back to the wallet if the provider rejects
+// @Description it. A provider that cannot be reached is asked again by the refund worker. When
+// @Description auth is enabled only the owner of the wallet can refund it.
+// @Tags transactions
+// @Accept json
+// @Produce json
+// @Param id path string true "Transaction ID of the deposit"
+// @Param refund body depositRefundRequest false "Partial amount and reason"
+// @Param Idempotency-Key header string false "Makes retries safe: a repeat with the same key and body gets the first response"
+// @Success 201 {object} models.DepositRefund
+// @Failure 400 {object} response.Problem "Invalid transaction ID or amount, not a deposit, more than is left of it, or insufficient funds"
+// @Failure 404 {object} response.Problem "Transaction not found"
+// @Failure 409 {object} response.Problem "Deposit reversed, wallet frozen or closed, or Idempotency-Key reused with a different request body"
+// @Failure 422 {object} response.Problem "Wallet limit exceeded"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v1/transactions/{id}/refund [post]
+// @Router /api/v1/admin/transactions/{id}/refund [post]
+func (h *WalletHandler) RefundTransaction(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.FromContext(ctx)
+	transactionIDStr := chi.URLParam(r, "id")
+	transactionID, err := uuid.Parse(transactionIDStr)
+	if err != nil {
+		response.ErrorMessage(w, http.StatusBadRequest, "Invalid transaction ID")
+		return
+	}
+
+	var req depositRefundRequest
+	if appErr := decodeOptionalRequest(r, &req); appErr != nil {
+		response.Error(w, appErr)
+		return
+	}
+
+	var amount *money.Money
+	if req.Amount != nil {
+		parsed, appErr := parseAmount(*req.Amount, req.Currency)
+		if appErr != nil {
+			response.Error(w, appErr)
+			return
+		}
+		amount = &parsed
+	}
+
+	if userID, ok := auth.UserIDFromContext(ctx); ok {
+		// The owner of a deposit's wallet is the one it credited
+		if appErr := h.authorizeReversal(r, transactionID, transactionIDStr, userID); appErr != nil {
+			response.Error(w, appErr)
+			return
+		}
+	}
+
+	refund, err := h.DepositRefunds.Refund(ctx, transactionID, amount, req.Reason)
+	if err != nil {
+		log.Error("Failed to refund deposit", zap.Error(err), zap.String("transaction_id", transactionIDStr))
+		if appErr := depositRefundAppError(err, transactionIDStr); appErr != nil {
+			response.Error(w, appErr)
+			return
+		}
+		response.Error(w, errors.InternalError(err))
+		return
+	}
+
+	log.Info("Deposit refunded",
+		zap.String("transaction_id", transactionIDStr),
+		zap.String("refund_id", refund.ID.String()))
+
+	response.Created(w, "", refund)
+}
@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/paymentprovider"
+	"github.com/shanwije/wallet-app/internal/repository/sqlite"
+	"github.com/shanwije/wallet-app/internal/service"
+	"github.com/shanwije/wallet-app/pkg/money"
+)
+
+// fakeRefunder records the refunds it was asked for, rejecting those with reason
+// "rejected" and failing every refund while err is set
+type fakeRefunder struct {
+	mu       sync.Mutex
+	requests []paymentprovider.RefundRequest
+	err      error
+}
+
+func (f *fakeRefunder) Refund(_ context.Context, request paymentprovider.RefundRequest) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return "", f.err
+	}
+	if request.Reason == "rejected" {
+		return "", paymentprovider.ErrRejected
+	}
+	f.requests = append(f.requests, request)
+	return "re_" + request.ID.String(), nil
+}
+
+func TestRefundTransactionRefundsDepositsUpToTheirAmount(t *testing.T) {
+	ctx := context.Background()
+	conn := newTestDB(t)
+	wallets := walletServiceOn(conn)
+	wallets.SystemWallets = &service.SystemWalletService{Repo: sqlite.NewWalletRepository(conn)}
+	wallets.DepositRefunds = sqlite.NewDepositRefundRepository(conn)
+	providerPayments := sqlite.NewProviderPaymentRepository(conn)
+	refunder := &fakeRefunder{}
+	refunds := &service.DepositRefundService{
+		Repo:             wallets.DepositRefunds,
+		ProviderPayments: providerPayments,
+		Wallets:          wallets,
+		Refunder:         refunder,
+	}
+	handler := &WalletHandler{WalletService: wallets, DepositRefunds: refunds}
+	router := chi.NewRouter()
+	router.Post("/transactions/{id}/refund", handler.RefundTransaction)
+
+	wallet, other := createUserWallet(t, wallets), createUserWallet(t, wallets)
+	deposit, err := wallets.CreateDeposit(ctx, wallet.ID, money.New(decimal.NewFromInt(50), money.DefaultCurrency), "")
+	require.NoError(t, err)
+
+	refund := func(transactionID uuid.UUID, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/transactions/"+transactionID.String()+"/refund", strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	refunded := func(transactionID uuid.UUID, body string) models.DepositRefund {
+		rr := refund(transactionID, body)
+		require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+		var created models.DepositRefund
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &created))
+		return created
+	}
+	balance := func(id uuid.UUID) string {
+		current, err := wallets.GetBalance(ctx, id)
+		require.NoError(t, err)
+		return current.Balance.String()
+	}
+
+	// A deposit is refunded in parts, never for more than is left of it
+	first := refunded(deposit.Transaction.ID, `{"amount":20,"reason":"duplicate top-up"}`)
+	assert.Equal(t, wallet.ID, first.WalletID)
+	assert.Equal(t, "20", first.Amount.String())
+	assert.Equal(t, "duplicate top-up", *first.Reason)
+	assert.Equal(t, models.DepositRefundStatusCompleted, first.Status)
+	assert.Nil(t, first.ProviderRefundID, "the deposit was not funded by the provider")
+	assert.Equal(t, http.StatusBadRequest, refund(deposit.Transaction.ID, `{"amount":30.01}`).Code)
+	assert.Equal(t, http.StatusBadRequest, refund(deposit.Transaction.ID, `{"amount":5,"currency":"EUR"}`).Code)
+	rest := refunded(deposit.Transaction.ID, "")
+	assert.Equal(t, "30", rest.Amount.String())
+	assert.Equal(t, first.DepositJournalID, rest.DepositJournalID)
+	assert.Equal(t, http.StatusBadRequest, refund(deposit.Transaction.ID, "").Code)
+	assert.Equal(t, "0", balance(wallet.ID))
+
+	// Only deposits are refunded, and only with the money still in the wallet
+	_, err = wallets.CreateDeposit(ctx, wallet.ID, money.New(decimal.NewFromInt(40), money.DefaultCurrency), "")
+	require.NoError(t, err)
+	_, err = wallets.CreateTransfer(ctx, wallet.ID, other.ID, money.New(decimal.NewFromInt(15), money.DefaultCurrency), "", "")
+	require.NoError(t, err)
+	history, err := wallets.GetTransactionHistory(ctx, other.ID)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, http.StatusBadRequest, refund(history[0].ID, "").Code)
+	assert.Equal(t, http.StatusNotFound, refund(uuid.New(), "").Code)
+	otherDeposit, err := wallets.CreateDeposit(ctx, other.ID, money.New(decimal.NewFromInt(10), money.DefaultCurrency), "")
+	require.NoError(t, err)
+	_, err = wallets.CreateWithdrawal(ctx, other.ID, money.New(decimal.NewFromInt(20), money.DefaultCurrency), "")
+	require.NoError(t, err)
+	rr := refund(otherDeposit.Transaction.ID, "")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), "INSUFFICIENT_FUNDS")
+
+	// A reversed deposit cannot be refunded as well
+	reversed, err := wallets.CreateDeposit(ctx, other.ID, money.New(decimal.NewFromInt(10), money.DefaultCurrency), "")
+	require.NoError(t, err)
+	_, err = wallets.ReverseTransaction(ctx, reversed.Transaction.ID, nil, "")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusConflict, refund(reversed.Transaction.ID, `{"amount":1}`).Code)
+
+	// Nor can a refunded deposit be reversed or corrected, which would pay it back twice
+	partly, err := wallets.CreateDeposit(ctx, other.ID, money.New(decimal.NewFromInt(10), money.DefaultCurrency), "")
+	require.NoError(t, err)
+	partRefund := refunded(partly.Transaction.ID, `{"amount":1}`)
+	_, err = wallets.ReverseTransaction(ctx, partly.Transaction.ID, nil, "")
+	assert.ErrorIs(t, err, service.ErrRefunded)
+	_, err = wallets.CorrectTransaction(ctx, partly.Transaction.ID, nil, models.CorrectionReasonDuplicate, "")
+	assert.ErrorIs(t, err, service.ErrRefunded)
+	assert.Equal(t, "14", balance(other.ID))
+
+	// Nor the refund's own debit, which only the refund failing credits back
+	history, err = wallets.GetTransactionHistory(ctx, other.ID)
+	require.NoError(t, err)
+	var refundDebit uuid.UUID
+	for _, transaction := range history {
+		if transaction.ReferenceID != nil && *transaction.ReferenceID == partRefund.JournalID {
+			refundDebit = transaction.ID
+		}
+	}
+	require.NotEqual(t, uuid.Nil, refundDebit)
+	_, err = wallets.ReverseTransaction(ctx, refundDebit, nil, "")
+	assert.ErrorIs(t, err, service.ErrPaidOut)
+	_, err = wallets.CorrectTransaction(ctx, refundDebit, nil, models.CorrectionReasonSystemError, "")
+	assert.ErrorIs(t, err, service.ErrPaidOut)
+	assert.Equal(t, "14", balance(other.ID))
+
+	// A deposit from the provider is refunded through it too, once the debit has
+	// committed, and credited back when the provider refuses
+	payments := &service.ProviderPaymentService{Repo: providerPayments, Wallets: wallets}
+	payment, err := payments.Apply(ctx, service.ProviderPaymentReport{
+		PaymentID: "pay_1",
+		Status:    models.ProviderPaymentStatusSucceeded,
+		WalletID:  wallet.ID,
+		Amount:    money.New(decimal.NewFromInt(10), money.DefaultCurrency),
+	})
+	require.NoError(t, err)
+	history, err = wallets.GetTransactionHistory(ctx, wallet.ID)
+	require.NoError(t, err)
+	var paid uuid.UUID
+	for _, transaction := range history {
+		if transaction.ReferenceID != nil && *transaction.ReferenceID == *payment.JournalID {
+			paid = transaction.ID
+		}
+	}
+	require.NotEqual(t, uuid.Nil, paid)
+
+	rejected := refunded(paid, `{"amount":4,"reason":"rejected"}`)
+	assert.Equal(t, models.DepositRefundStatusFailed, rejected.Status)
+	require.NotNil(t, rejected.FailureReason)
+	require.NotNil(t, rejected.ReversalJournalID)
+	assert.Equal(t, "35", balance(wallet.ID))
+	viaProvider := refunded(paid, `{"amount":4}`)
+	assert.Equal(t, models.DepositRefundStatusCompleted, viaProvider.Status)
+	require.NotNil(t, viaProvider.ProviderRefundID)
+	require.Len(t, refunder.requests, 1)
+	assert.Equal(t, viaProvider.ID, refunder.requests[0].ID, "the refund's ID is the provider's idempotency key")
+	assert.Equal(t, "pay_1", refunder.requests[0].PaymentID)
+	assert.Equal(t, "4", refunder.requests[0].Amount.Amount().String())
+	assert.Equal(t, "re_"+viaProvider.ID.String(), *viaProvider.ProviderRefundID)
+	assert.Equal(t, "31", balance(wallet.ID))
+
+	// A provider that cannot be reached leaves the refund pending, debited, for the worker
+	// to send again under the same key
+	refunder.err = errors.New("connection refused")
+	pending := refunded(paid, `{"amount":6}`)
+	assert.Equal(t, models.DepositRefundStatusPending, pending.Status)
+	assert.Nil(t, pending.ProviderRefundID)
+	assert.Equal(t, "25", balance(wallet.ID))
+	processed, err := refunds.ProcessPending(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, processed)
+	assert.Equal(t, http.StatusBadRequest, refund(paid, `{"amount":1}`).Code, "a pending refund counts against the deposit")
+
+	refunder.err = nil
+	processed, err = refunds.ProcessPending(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, processed)
+	require.Len(t, refunder.requests, 2)
+	assert.Equal(t, pending.ID, refunder.requests[1].ID)
+	stillPending, err := wallets.DepositRefunds.ListDepositRefundsInStatus(ctx, models.DepositRefundStatusPending, uuid.Nil, 10)
+	require.NoError(t, err)
+	assert.Empty(t, stillPending)
+	assert.Equal(t, "25", balance(wallet.ID))
+
+	// Refunds go back to the treasury the deposits came from
+	treasuryID, err := wallets.SystemWallets.WalletID(ctx, models.WalletKindTreasury, money.DefaultCurrency)
+	require.NoError(t, err)
+	for _, id := range []uuid.UUID{wallet.ID, other.ID, treasuryID} {
+		assert.NoError(t, wallets.VerifyWalletBalance(ctx, id))
+	}
+}
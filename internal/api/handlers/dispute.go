@@ -0,0 +1,325 @@
+package handlers
+
+import (
+	stderrors "errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/service"
+	"github.com/shanwije/wallet-app/pkg/auth"
+	"github.com/shanwije/wallet-app/pkg/errors"
+	"github.com/shanwije/wallet-app/pkg/logger"
+	"github.com/shanwije/wallet-app/pkg/response"
+)
+
+// DisputeHandler serves disputes of transfers, opened by the sender and resolved by
+// an operator
+type DisputeHandler struct {
+	DisputeService *service.DisputeService
+}
+
+type disputeRequest struct {
+	Reason string `json:"reason" validate:"required,max=500" example:"Goods never arrived"`
+}
+
+type resolveDisputeRequest struct {
+	// Outcome is refund, which reverses the transfer, or reject, which keeps it
+	Outcome string `json:"outcome" validate:"required,oneof=refund reject" example:"refund"`
+	Note    string `json:"note,omitempty" validate:"max=500" example:"Seller could not show delivery"`
+}
+
+// disputeListResponse is one page of disputes
+type disputeListResponse struct {
+	Disputes []*models.Dispute `json:"disputes"`
+	Limit    int               `json:"limit"`
+	Offset   int               `json:"offset"`
+}
+
+// NewDisputeHandler creates a new DisputeHandler
+func NewDisputeHandler(disputeService *service.DisputeService) *DisputeHandler {
+	return &DisputeHandler{
+		DisputeService: disputeService,
+	}
+}
+
+// disputeAppError maps the failures of disputes that have their own error code; it
+// returns nil for the rest
+func disputeAppError(err error, disputeID string) *errors.AppError {
+	switch {
+	case stderrors.Is(err, service.ErrDisputeNotFound):
+		return errors.New(errors.ErrDisputeNotFound, "Dispute not found", http.StatusNotFound).
+			WithDetails("dispute_id", disputeID)
+	case stderrors.Is(err, service.ErrTransactionNotFound):
+		return errors.New(errors.ErrTransactionNotFound, "Transaction not found", http.StatusNotFound)
+	case stderrors.Is(err, service.ErrAlreadyDisputed):
+		return errors.New(errors.ErrAlreadyDisputed, err.Error(), http.StatusConflict)
+	case stderrors.Is(err, service.ErrDisputeResolved),
+		stderrors.Is(err, service.ErrAlreadyReversed):
+		return errors.Conflict(err.Error())
+	case stderrors.Is(err, service.ErrRefunded):
+		return errors.New(errors.ErrAlreadyRefunded, "Transaction has been refunded and can no longer be disputed", http.StatusConflict)
+	case stderrors.Is(err, service.ErrDisputedFundsUnavailable):
+		return errors.InsufficientFunds()
+	case stderrors.Is(err, service.ErrNotDisputable),
+		stderrors.Is(err, service.ErrInvalidDisputeReason),
+		stderrors.Is(err, service.ErrInvalidDisputeOutcome):
+		return errors.InvalidInput(err.Error())
+	default:
+		return movementAppError(err)
+	}
+}
+
+// OpenDispute disputes a transfer the caller sent
+// @Summary Dispute a transaction
+// @Description Opens a dispute on the sending side of a transfer. What the recipient was
+// @Description credited is held in their wallet until an operator refunds the transfer or
+// @Description rejects the dispute. Each transaction can be disputed once. When auth is
+// @Description enabled only the owner of the wallet the transfer was sent from can dispute it.
+// @Tags transactions
+// @Accept json
+// @Produce json
+// @Param id path string true "Transaction ID"
+// @Param dispute body disputeRequest true "Why the transfer is disputed"
+// @Param Idempotency-Key header string false "Makes retries safe: a repeat with the same key and body gets the first response"
+// @Success 201 {object} models.Dispute
+// @Failure 400 {object} response.Problem "Invalid transaction ID or reason, the transaction is not a transfer sent, or the recipient no longer has the funds"
+// @Failure 404 {object} response.Problem "Transaction not found"
+// @Failure 409 {object} response.Problem "Transaction already disputed or reversed, a merchant payment already refunded, or Idempotency-Key reused with a different request body"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v1/transactions/{id}/disputes [post]
+func (h *DisputeHandler) OpenDispute(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.FromContext(ctx)
+	transactionIDStr := chi.URLParam(r, "id")
+	transactionID, err := uuid.Parse(transactionIDStr)
+	if err != nil {
+		response.ErrorMessage(w, http.StatusBadRequest, "Invalid transaction ID")
+		return
+	}
+
+	var req disputeRequest
+	if appErr := decodeRequest(r, &req); appErr != nil {
+		response.Error(w, appErr)
+		return
+	}
+	if appErr := validateRequest(req); appErr != nil {
+		response.Error(w, appErr)
+		return
+	}
+
+	if appErr := h.authorizeTransaction(r, transactionID, transactionIDStr); appErr != nil {
+		response.Error(w, appErr)
+		return
+	}
+
+	dispute, err := h.DisputeService.Open(ctx, transactionID, req.Reason)
+	if err != nil {
+		log.Error("Failed to open dispute", zap.Error(err), zap.String("transaction_id", transactionIDStr))
+		if appErr := disputeAppError(err, ""); appErr != nil {
+			response.Error(w, appErr)
+			return
+		}
+		response.Error(w, errors.InternalError(err))
+		return
+	}
+
+	log.Info("Dispute opened",
+		zap.String("transaction_id", transactionIDStr),
+		zap.String("dispute_id", dispute.ID.String()))
+
+	response.Created(w, "", dispute)
+}
+
+// ListTransactionDisputes lists the disputes of a transaction
+// @Summary List a transaction's disputes
+// @Description Returns the transaction's disputes with every status each has been in and
+// @Description when. When auth is enabled only the owner of the transaction's wallet can see them.
+// @Tags transactions
+// @Produce json
+// @Param id path string true "Transaction ID"
+// @Success 200 {array} models.Dispute
+// @Failure 400 {object} response.Problem "Invalid transaction ID"
+// @Failure 404 {object} response.Problem "Transaction not found"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v1/transactions/{id}/disputes [get]
+func (h *DisputeHandler) ListTransactionDisputes(w http.ResponseWriter, r *http.Request) {
+	transactionIDStr := chi.URLParam(r, "id")
+	transactionID, err := uuid.Parse(transactionIDStr)
+	if err != nil {
+		response.ErrorMessage(w, http.StatusBadRequest, "Invalid transaction ID")
+		return
+	}
+
+	if appErr := h.authorizeTransaction(r, transactionID, transactionIDStr); appErr != nil {
+		response.Error(w, appErr)
+		return
+	}
+
+	disputes, err := h.DisputeService.ListTransactionDisputes(r.Context(), transactionID)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Failed to list disputes", zap.Error(err))
+		response.Error(w, errors.InternalError(err))
+		return
+	}
+
+	response.OK(w, disputes)
+}
+
+// ListDisputes pages through disputes for operators
+// @Summary List disputes
+// @Description Returns disputes, oldest first, at most 200 per page. Pass status=open for the ones awaiting a decision.
+// @Tags admin
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param status query string false "open, refunded or rejected"
+// @Param limit query int false "Page size; larger sizes are cut to 200" minimum(0) default(50)
+// @Param offset query int false "Disputes to skip" minimum(0) default(0)
+// @Success 200 {object} disputeListResponse
+// @Failure 400 {object} response.Problem "Invalid status or pagination parameters"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v1/admin/disputes [get]
+func (h *DisputeHandler) ListDisputes(w http.ResponseWriter, r *http.Request) {
+	limit, offset, appErr := parsePage(r)
+	if appErr != nil {
+		response.Error(w, appErr)
+		return
+	}
+
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", models.DisputeStatusOpen, models.DisputeStatusRefunded, models.DisputeStatusRejected:
+	default:
+		response.Error(w, errors.InvalidInput("Status must be open, refunded or rejected").
+			WithDetails("status", status))
+		return
+	}
+
+	disputes, err := h.DisputeService.ListDisputes(r.Context(), status, limit, offset)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Failed to list disputes", zap.Error(err))
+		response.Error(w, errors.InternalError(err))
+		return
+	}
+
+	response.OK(w, disputeListResponse{Disputes: disputes, Limit: limit, Offset: offset})
+}
+
+// GetDispute returns a dispute for operators
+// @Summary Get a dispute
+// @Description Returns the dispute with every status it has been in and when
+// @Tags admin
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param id path string true "Dispute ID"
+// @Success 200 {object} models.Dispute
+// @Failure 400 {object} response.Problem "Invalid dispute ID"
+// @Failure 404 {object} response.Problem "Dispute not found"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v1/admin/disputes/{id} [get]
+func (h *DisputeHandler) GetDispute(w http.ResponseWriter, r *http.Request) {
+	disputeIDStr := chi.URLParam(r, "id")
+	disputeID, err := uuid.Parse(disputeIDStr)
+	if err != nil {
+		response.ErrorMessage(w, http.StatusBadRequest, "Invalid dispute ID")
+		return
+	}
+
+	dispute, err := h.DisputeService.GetDispute(r.Context(), disputeID)
+	if err != nil {
+		if appErr := disputeAppError(err, disputeIDStr); appErr != nil {
+			response.Error(w, appErr)
+			return
+		}
+		response.Error(w, errors.InternalError(err))
+		return
+	}
+
+	response.OK(w, dispute)
+}
+
+// ResolveDispute settles an open dispute
+// @Summary Resolve a dispute
+// @Description A refund reverses the whole transfer, paying the held funds back to the sender;
+// @Description a rejection leaves the transfer as it was. Either way the hold on the recipient's
+// @Description wallet is released and the note is recorded with the new status.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param id path string true "Dispute ID"
+// @Param resolution body resolveDisputeRequest true "Outcome and note"
+// @Success 200 {object} models.Dispute
+// @Failure 400 {object} response.Problem "Invalid dispute ID or outcome"
+// @Failure 404 {object} response.Problem "Dispute not found"
+// @Failure 409 {object} response.Problem "Dispute already resolved, or the transfer was reversed since"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v1/admin/disputes/{id}/resolve [post]
+func (h *DisputeHandler) ResolveDispute(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+	disputeIDStr := chi.URLParam(r, "id")
+	disputeID, err := uuid.Parse(disputeIDStr)
+	if err != nil {
+		response.ErrorMessage(w, http.StatusBadRequest, "Invalid dispute ID")
+		return
+	}
+
+	var req resolveDisputeRequest
+	if appErr := decodeRequest(r, &req); appErr != nil {
+		response.Error(w, appErr)
+		return
+	}
+	if appErr := validateRequest(req); appErr != nil {
+		response.Error(w, appErr)
+		return
+	}
+
+	dispute, err := h.DisputeService.Resolve(r.Context(), disputeID, req.Outcome, req.Note)
+	if err != nil {
+		log.Error("Failed to resolve dispute", zap.Error(err), zap.String("dispute_id", disputeIDStr))
+		if appErr := disputeAppError(err, disputeIDStr); appErr != nil {
+			response.Error(w, appErr)
+			return
+		}
+		response.Error(w, errors.InternalError(err))
+		return
+	}
+
+	log.Info("Dispute resolved",
+		zap.String("dispute_id", disputeIDStr),
+		zap.String("status", dispute.Status))
+
+	response.OK(w, dispute)
+}
+
+// authorizeTransaction lets the user at the disputes of a transaction when they own
+// its wallet. Someone else's transaction is reported as missing rather than forbidden,
+// so transaction IDs cannot be probed.
+func (h *DisputeHandler) authorizeTransaction(r *http.Request, transactionID uuid.UUID, transactionIDStr string) *errors.AppError {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		return nil
+	}
+
+	notFound := errors.New(errors.ErrTransactionNotFound, "Transaction not found", http.StatusNotFound).
+		WithDetails("transaction_id", transactionIDStr)
+	transaction, err := h.DisputeService.Wallets.GetTransaction(r.Context(), transactionID)
+	if stderrors.Is(err, service.ErrTransactionNotFound) {
+		return notFound
+	}
+	if err != nil {
+		return errors.InternalError(err)
+	}
+
+	wallet, err := h.DisputeService.Wallets.GetBalance(r.Context(), transaction.WalletID)
+	if err != nil && !stderrors.Is(err, service.ErrWalletNotFound) {
+		return errors.InternalError(err)
+	}
+	if wallet == nil || wallet.UserID != userID {
+		return notFound
+	}
+	return nil
+}
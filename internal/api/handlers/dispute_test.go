@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository/sqlite"
+	"github.com/shanwije/wallet-app/internal/service"
+	"github.com/shanwije/wallet-app/pkg/money"
+)
+
+func TestDisputedTransfersAreHeldUntilRefundedOrRejected(t *testing.T) {
+	conn := newTestDB(t)
+	wallets := walletServiceOn(conn)
+	wallets.HoldRepo = sqlite.NewHoldRepository(conn)
+	disputes := &service.DisputeService{Repo: sqlite.NewDisputeRepository(conn), Wallets: wallets}
+	ctx := context.Background()
+	sender, recipient := createUserWallet(t, wallets), createUserWallet(t, wallets)
+	_, err := wallets.Deposit(ctx, sender.ID, money.New(decimal.NewFromInt(100), money.DefaultCurrency), "")
+	require.NoError(t, err)
+	transfer := func(amount int64) *models.Transaction {
+		require.NoError(t, wallets.Transfer(ctx, sender.ID, recipient.ID, money.New(decimal.NewFromInt(amount), money.DefaultCurrency), "", ""))
+		history, err := wallets.GetTransactionHistory(ctx, sender.ID)
+		require.NoError(t, err)
+		return history[0]
+	}
+	first, second := transfer(30), transfer(20)
+
+	handler := NewDisputeHandler(disputes)
+	walletHandler := NewWalletHandler(wallets)
+	router := chi.NewRouter()
+	router.Post("/transactions/{id}/disputes", handler.OpenDispute)
+	router.Get("/transactions/{id}/disputes", handler.ListTransactionDisputes)
+	router.Get("/admin/disputes", handler.ListDisputes)
+	router.Post("/admin/disputes/{id}/resolve", handler.ResolveDispute)
+	router.Post("/wallets/{id}/holds/{holdID}/release", walletHandler.ReleaseHold)
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rr
+	}
+	open := func(transaction *models.Transaction) *models.Dispute {
+		rr := send(http.MethodPost, "/transactions/"+transaction.ID.String()+"/disputes", `{"reason":"Goods never arrived"}`)
+		require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+		var dispute models.Dispute
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &dispute))
+		return &dispute
+	}
+	resolve := func(dispute *models.Dispute, body string) *httptest.ResponseRecorder {
+		return send(http.MethodPost, "/admin/disputes/"+dispute.ID.String()+"/resolve", body)
+	}
+	available := func(wallet *models.Wallet) string {
+		current, err := wallets.GetBalance(ctx, wallet.ID)
+		require.NoError(t, err)
+		return current.Available().Amount().String()
+	}
+
+	// Only the sending side of a transfer can be disputed, and only once
+	received, err := wallets.GetTransactionHistory(ctx, recipient.ID)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, send(http.MethodPost, "/transactions/"+received[0].ID.String()+"/disputes", `{"reason":"x"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, send(http.MethodPost, "/transactions/"+first.ID.String()+"/disputes", `{}`).Code)
+
+	dispute := open(first)
+	assert.Equal(t, models.DisputeStatusOpen, dispute.Status)
+	assert.Equal(t, recipient.ID, dispute.CounterpartyWalletID)
+	assert.Equal(t, "30", dispute.Amount.String())
+	require.NotNil(t, dispute.HoldID)
+	assert.Equal(t, "20", available(recipient))
+	rr := send(http.MethodPost, "/transactions/"+first.ID.String()+"/disputes", `{"reason":"Again"}`)
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	assert.Contains(t, rr.Body.String(), "ALREADY_DISPUTED")
+
+	// The recipient cannot release the funds held for the dispute
+	rr = send(http.MethodPost, "/wallets/"+recipient.ID.String()+"/holds/"+dispute.HoldID.String()+"/release", "")
+	assert.Equal(t, http.StatusConflict, rr.Code, rr.Body.String())
+
+	assert.Equal(t, http.StatusBadRequest, resolve(dispute, `{"outcome":"settle"}`).Code)
+	rr = resolve(dispute, `{"outcome":"refund","note":"No proof of delivery"}`)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), dispute))
+	assert.Equal(t, models.DisputeStatusRefunded, dispute.Status)
+	assert.NotNil(t, dispute.ReversalJournalID)
+	assert.Equal(t, "20", available(recipient))
+	assert.Equal(t, "80", available(sender))
+	assert.Equal(t, http.StatusConflict, resolve(dispute, `{"outcome":"reject"}`).Code)
+
+	// A rejected dispute leaves the transfer as it was
+	rejected := open(second)
+	assert.Equal(t, "0", available(recipient))
+	rr = resolve(rejected, `{"outcome":"reject"}`)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.Equal(t, "20", available(recipient))
+	assert.Equal(t, "80", available(sender))
+
+	// A transfer reversed in part was already paid back and cannot be disputed
+	third := transfer(10)
+	part := money.New(decimal.NewFromInt(4), money.DefaultCurrency)
+	_, err = wallets.ReverseTransaction(ctx, third.ID, &part, "")
+	require.NoError(t, err)
+	rr = send(http.MethodPost, "/transactions/"+third.ID.String()+"/disputes", `{"reason":"Goods never arrived"}`)
+	assert.Equal(t, http.StatusConflict, rr.Code, rr.Body.String())
+	assert.Contains(t, rr.Body.String(), "CONFLICT")
+	assert.Equal(t, "26", available(recipient))
+
+	// Every status the dispute entered is listed on the transaction
+	rr = send(http.MethodGet, "/transactions/"+first.ID.String()+"/disputes", "")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var listed []*models.Dispute
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &listed))
+	require.Len(t, listed, 1)
+	require.Len(t, listed[0].Events, 2)
+	assert.Equal(t, models.DisputeStatusOpen, listed[0].Events[0].Status)
+	assert.Equal(t, models.DisputeStatusRefunded, listed[0].Events[1].Status)
+	require.NotNil(t, listed[0].Events[1].Note)
+	assert.Equal(t, "No proof of delivery", *listed[0].Events[1].Note)
+
+	rr = send(http.MethodGet, "/admin/disputes?status=rejected", "")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var page disputeListResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &page))
+	require.Len(t, page.Disputes, 1)
+	assert.Equal(t, rejected.ID, page.Disputes[0].ID)
+	assert.Equal(t, http.StatusBadRequest, send(http.MethodGet, "/admin/disputes?status=closed", "").Code)
+}
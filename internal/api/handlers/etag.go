@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/service"
+	"github.com/shanwije/wallet-app/pkg/errors"
+)
+
+// walletETag is the entity tag of a wallet: its version, which every change to the
+// wallet advances
+func walletETag(wallet *models.Wallet) string {
+	return strconv.Quote(strconv.FormatInt(wallet.Version, 10))
+}
+
+// withIfMatch returns the request's context, carrying the wallet versions named by its
+// If-Match header as the versions the wallet must be at for money to leave it. Without
+// the header, or with If-Match: *, the wallet may be at any version.
+func withIfMatch(r *http.Request, walletID uuid.UUID) (context.Context, *errors.AppError) {
+	versions, appErr := ifMatchVersions(r, "wallet")
+	if appErr != nil || versions == nil {
+		return r.Context(), appErr
+	}
+	return service.WithExpectedVersion(r.Context(), walletID, versions...), nil
+}
+
+// ifMatchVersions returns the versions named by the request's If-Match header, whose
+// entity tags are those of a resource tagged by its version. It returns nil when any
+// version will do, without the header or with If-Match: *, and an empty slice when
+// none will.
+func ifMatchVersions(r *http.Request, resource string) ([]int64, *errors.AppError) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" || header == "*" {
+		return nil, nil
+	}
+
+	versions := []int64{}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		// If-Match compares entity tags strongly, so a weak tag never matches
+		if strings.HasPrefix(tag, "W/") {
+			continue
+		}
+		unquoted, err := strconv.Unquote(tag)
+		if err != nil {
+			return nil, errors.InvalidInput("If-Match must list entity tags from the "+resource+"'s ETag").WithDetails("If-Match", header)
+		}
+		version, err := strconv.ParseInt(unquoted, 10, 64)
+		if err != nil {
+			// Nothing has this tag
+			continue
+		}
+		versions = append(versions, version)
+	}
+	return versions, nil
+}
+
+// userETag is the entity tag of a user: their version, which every profile update
+// advances
+func userETag(version int64) string {
+	return strconv.Quote(strconv.FormatInt(version, 10))
+}
+
+// historyETag is the entity tag of a wallet's history: the ID of its latest
+// transaction, which changes whenever one is added
+func historyETag(latest *models.Transaction) string {
+	return strconv.Quote(latest.ID.String())
+}
+
+// notModified reports whether the request's If-None-Match, or without one its
+// If-Modified-Since, shows the client already has the representation with etag last
+// modified at lastModified
+func notModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if header := r.Header.Get("If-None-Match"); header != "" {
+		// If-None-Match compares entity tags weakly
+		for _, tag := range strings.Split(header, ",") {
+			tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+			if tag == "*" || tag == etag {
+				return true
+			}
+		}
+		return false
+	}
+
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	// Last-Modified has a resolution of seconds
+	return err == nil && !lastModified.Truncate(time.Second).After(since)
+}
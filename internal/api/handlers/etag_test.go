@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shanwije/wallet-app/pkg/errors"
+	"github.com/shanwije/wallet-app/pkg/money"
+	"github.com/shanwije/wallet-app/pkg/response"
+)
+
+func TestIfMatchWithdrawsOnlyFromTheBalanceRead(t *testing.T) {
+	wallets := newWalletService(t)
+	from, to := createUserWallet(t, wallets), createUserWallet(t, wallets)
+	_, err := wallets.Deposit(context.Background(), from.ID, money.New(decimal.NewFromInt(100), money.DefaultCurrency), "")
+	require.NoError(t, err)
+
+	handler := &WalletHandler{WalletService: wallets}
+	router := chi.NewRouter()
+	router.Get("/wallets/{id}/balance", handler.GetBalance)
+	router.Post("/wallets/{id}/withdraw", handler.Withdraw)
+	router.Post("/wallets/{id}/transfer", handler.Transfer)
+	balanceETag := func() string {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/wallets/"+from.ID.String()+"/balance", nil))
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		return rr.Header().Get("ETag")
+	}
+	send := func(operation, ifMatch, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/wallets/"+from.ID.String()+"/"+operation, strings.NewReader(body))
+		req.Header.Set("If-Match", ifMatch)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	seen := balanceETag()
+	require.NotEmpty(t, seen)
+	rr := send("withdraw", seen, `{"amount":30}`)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	// The withdrawal changed the wallet, so the tag read before it no longer matches
+	current := balanceETag()
+	assert.NotEqual(t, seen, current)
+	for _, operation := range []struct{ name, body string }{
+		{"withdraw", `{"amount":30}`},
+		{"transfer", `{"to_wallet_id":"` + to.ID.String() + `","amount":30}`},
+	} {
+		rr = send(operation.name, seen, operation.body)
+		require.Equal(t, http.StatusPreconditionFailed, rr.Code, operation.name)
+		var problem response.Problem
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &problem))
+		assert.Equal(t, errors.ErrVersionMismatch, problem.Code)
+	}
+	wallet, err := wallets.GetBalance(context.Background(), from.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "70", wallet.Balance.String())
+
+	// Any of several tags, or *, may match
+	rr = send("transfer", seen+", "+current, `{"to_wallet_id":"`+to.ID.String()+`","amount":30}`)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	rr = send("withdraw", "*", `{"amount":10}`)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.Equal(t, http.StatusBadRequest, send("withdraw", "7", `{"amount":10}`).Code)
+}
+
+func TestTransactionHistoryAnswersPollingWithNotModified(t *testing.T) {
+	wallets := newWalletService(t)
+	wallet := createUserWallet(t, wallets)
+	deposit := func() {
+		_, err := wallets.Deposit(context.Background(), wallet.ID, money.New(decimal.NewFromInt(10), money.DefaultCurrency), "")
+		require.NoError(t, err)
+	}
+	deposit()
+
+	router := chi.NewRouter()
+	router.Get("/wallets/{id}/transactions", (&WalletHandler{WalletService: wallets}).GetTransactionHistory)
+	get := func(header, value string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/wallets/"+wallet.ID.String()+"/transactions", nil)
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := get("", "")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	etag, lastModified := rr.Header().Get("ETag"), rr.Header().Get("Last-Modified")
+	require.NotEmpty(t, etag)
+	require.NotEmpty(t, lastModified)
+
+	rr = get("If-None-Match", etag)
+	assert.Equal(t, http.StatusNotModified, rr.Code)
+	assert.Empty(t, rr.Body.String())
+	assert.Equal(t, etag, rr.Header().Get("ETag"))
+	assert.Equal(t, http.StatusNotModified, get("If-Modified-Since", lastModified).Code)
+	assert.Equal(t, http.StatusOK, get("If-None-Match", `"something-else"`).Code)
+
+	// A new transaction changes the tag
+	deposit()
+	rr = get("If-None-Match", etag)
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.NotEqual(t, etag, rr.Header().Get("ETag"))
+	var transactions []json.RawMessage
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &transactions))
+	assert.Len(t, transactions, 2)
+}
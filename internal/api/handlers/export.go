@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	stderrors "errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/shanwije/wallet-app/internal/service"
+	"github.com/shanwije/wallet-app/pkg/auth"
+	"github.com/shanwije/wallet-app/pkg/errors"
+	"github.com/shanwije/wallet-app/pkg/logger"
+	"github.com/shanwije/wallet-app/pkg/response"
+)
+
+// ExportHandler serves exports of wallets' transactions produced in the background
+type ExportHandler struct {
+	ExportJobs *service.ExportJobService
+	// WalletService finds who owns an export's wallet
+	WalletService WalletServiceInterface
+}
+
+type exportRequest struct {
+	// Period start as YYYY-MM-DD or RFC3339; when the wallet was opened when omitted
+	From string `json:"from,omitempty" example:"2024-07-01"`
+	// Period end as YYYY-MM-DD (inclusive) or RFC3339 (exclusive); now when omitted
+	To string `json:"to,omitempty" example:"2024-07-31"`
+}
+
+// NewExportHandler creates a new ExportHandler
+func NewExportHandler(exportJobs *service.ExportJobService, walletService WalletServiceInterface) *ExportHandler {
+	return &ExportHandler{
+		ExportJobs:    exportJobs,
+		WalletService: walletService,
+	}
+}
+
+// exportAppError maps the failures of an export that have their own error code
+func exportAppError(err error, walletID string) *errors.AppError {
+	switch {
+	case stderrors.Is(err, service.ErrInvalidStatementPeriod):
+		return errors.InvalidInput(err.Error())
+	case stderrors.Is(err, service.ErrFeatureDisabled):
+		return errors.New(errors.ErrFeatureDisabled, err.Error(), http.StatusForbidden)
+	default:
+		return walletAppError(err, walletID)
+	}
+}
+
+// Create queues an export of the wallet's transactions
+// @Summary Export transactions in the background
+// @Description Queues an export of the wallet's statement for a period as CSV, in the same format as the statement endpoint, for wallets with too many transactions to stream within a request. The export worker produces the file; poll the export until it is ready, then download the file from its download_url. Needs an export bucket to be configured.
+// @Tags wallets
+// @Accept json
+// @Produce json
+// @Param id path string true "Wallet ID"
+// @Param export body exportRequest false "Period to export"
+// @Success 202 {object} models.ExportJob
+// @Header 202 {string} Location "The export to poll"
+// @Failure 400 {object} response.Problem "Invalid wallet ID, request body or period"
+// @Failure 403 {object} response.Problem "Exports not configured"
+// @Failure 404 {object} response.Problem "Wallet not found"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v1/wallets/{id}/exports [post]
+func (h *ExportHandler) Create(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+	walletIDStr := chi.URLParam(r, "id")
+	walletID, err := uuid.Parse(walletIDStr)
+	if err != nil {
+		response.ErrorMessage(w, http.StatusBadRequest, "Invalid wallet ID")
+		return
+	}
+
+	var req exportRequest
+	if appErr := decodeOptionalRequest(r, &req); appErr != nil {
+		response.Error(w, appErr)
+		return
+	}
+	from, err := parseStatementTime(req.From, false)
+	if err != nil {
+		response.Error(w, errors.InvalidInput("Invalid from date").WithDetails("from", req.From))
+		return
+	}
+	to, err := parseStatementTime(req.To, true)
+	if err != nil {
+		response.Error(w, errors.InvalidInput("Invalid to date").WithDetails("to", req.To))
+		return
+	}
+
+	job, err := h.ExportJobs.Create(r.Context(), walletID, from, to)
+	if err != nil {
+		if !stderrors.Is(err, service.ErrInvalidStatementPeriod) && !stderrors.Is(err, service.ErrWalletNotFound) {
+			log.Error("Failed to queue export", zap.Error(err), zap.String("wallet_id", walletIDStr))
+		}
+		response.Error(w, exportAppError(err, walletIDStr))
+		return
+	}
+
+	w.Header().Set("Location", "/api/v1/exports/"+job.ID.String())
+	response.JSON(w, http.StatusAccepted, job)
+}
+
+// Get returns an export and, once it is ready, a link to download it
+// @Summary Get an export
+// @Description Returns the export's status: pending until the worker has produced it, then ready or failed. A ready export carries a download_url that needs no credentials and works until download_expires_at; read the export again for a fresh one. When auth is enabled only the owner of the export's wallet can see it.
+// @Tags wallets
+// @Produce json
+// @Param id path string true "Export ID"
+// @Success 200 {object} models.ExportJob
+// @Failure 400 {object} response.Problem "Invalid export ID"
+// @Failure 404 {object} response.Problem "Export not found"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v1/exports/{id} [get]
+func (h *ExportHandler) Get(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	jobIDStr := chi.URLParam(r, "id")
+	jobID, err := uuid.Parse(jobIDStr)
+	if err != nil {
+		response.ErrorMessage(w, http.StatusBadRequest, "Invalid export ID")
+		return
+	}
+
+	notFound := errors.New(errors.ErrExportNotFound, "Export not found", http.StatusNotFound).
+		WithDetails("export_id", jobIDStr)
+
+	job, err := h.ExportJobs.Get(ctx, jobID)
+	if err != nil {
+		if stderrors.Is(err, service.ErrExportJobNotFound) {
+			response.Error(w, notFound)
+			return
+		}
+		logger.FromContext(ctx).Error("Failed to get export", zap.Error(err))
+		response.Error(w, errors.InternalError(err))
+		return
+	}
+
+	// Someone else's export is reported as missing rather than forbidden, so export
+	// IDs cannot be probed
+	if userID, ok := auth.UserIDFromContext(ctx); ok {
+		wallet, err := h.WalletService.GetBalance(ctx, job.WalletID)
+		if err != nil && !stderrors.Is(err, service.ErrWalletNotFound) {
+			logger.FromContext(ctx).Error("Failed to check export access", zap.Error(err))
+			response.Error(w, errors.InternalError(err))
+			return
+		}
+		if wallet == nil || wallet.UserID != userID {
+			response.Error(w, notFound)
+			return
+		}
+	}
+
+	response.OK(w, job)
+}
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository/sqlite"
+	"github.com/shanwije/wallet-app/internal/service"
+	"github.com/shanwije/wallet-app/pkg/money"
+)
+
+// fakeExportStore keeps uploaded objects in memory, failing uploads while err is set
+type fakeExportStore struct {
+	objects map[string][]byte
+	err     error
+}
+
+func (s *fakeExportStore) Upload(_ context.Context, key, _ string, body []byte) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.objects[key] = body
+	return nil
+}
+
+func (s *fakeExportStore) DownloadURL(key string, expires time.Duration) (string, error) {
+	return "https://exports.example.com/" + key + "?expires=" + expires.String(), nil
+}
+
+func TestExportsAreProducedInTheBackground(t *testing.T) {
+	ctx := context.Background()
+	conn := newTestDB(t)
+	wallets := walletServiceOn(conn)
+	store := &fakeExportStore{objects: map[string][]byte{}}
+	exports := &service.ExportJobService{Repo: sqlite.NewExportJobRepository(conn), Wallets: wallets, Store: store, Prefix: "exports"}
+	handler := NewExportHandler(exports, wallets)
+	router := chi.NewRouter()
+	router.Post("/wallets/{id}/exports", handler.Create)
+	router.Get("/exports/{id}", handler.Get)
+
+	wallet := createUserWallet(t, wallets)
+	for _, amount := range []int64{30, 12} {
+		_, err := wallets.Deposit(ctx, wallet.ID, money.New(decimal.NewFromInt(amount), money.DefaultCurrency), "")
+		require.NoError(t, err)
+	}
+
+	send := func(method, target, body string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return rr
+	}
+	get := func(location string) models.ExportJob {
+		rr := send(http.MethodGet, location, "")
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		var job models.ExportJob
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &job))
+		return job
+	}
+
+	rr := send(http.MethodPost, "/wallets/"+wallet.ID.String()+"/exports", "")
+	require.Equal(t, http.StatusAccepted, rr.Code, rr.Body.String())
+	location := rr.Header().Get("Location")
+	var queued models.ExportJob
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &queued))
+	assert.Equal(t, "/api/v1/exports/"+queued.ID.String(), location)
+	assert.Equal(t, models.ExportJobStatusPending, queued.Status)
+	location = strings.TrimPrefix(location, "/api/v1")
+	assert.Empty(t, get(location).DownloadURL, "a pending export has nothing to download")
+
+	// An upload that fails leaves the export pending for the next run
+	store.err = errors.New("bucket unreachable")
+	processed, err := exports.ProcessPending(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, processed)
+	assert.Equal(t, models.ExportJobStatusPending, get(location).Status)
+
+	store.err = nil
+	processed, err = exports.ProcessPending(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, processed)
+
+	ready := get(location)
+	assert.Equal(t, models.ExportJobStatusReady, ready.Status)
+	assert.EqualValues(t, 2, ready.Rows)
+	require.NotNil(t, ready.CompletedAt)
+	require.NotNil(t, ready.DownloadExpiresAt)
+	key := "exports/wallet-exports/" + wallet.ID.String() + "/" + ready.ID.String() + ".csv"
+	assert.Equal(t, "https://exports.example.com/"+key+"?expires=15m0s", ready.DownloadURL)
+
+	// The file is the wallet's statement, with the opening and closing balances
+	records, err := csv.NewReader(strings.NewReader(string(store.objects[key]))).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 5)
+	assert.Equal(t, "opening_balance", records[1][1])
+	assert.Equal(t, []string{"closing_balance", "42.00"}, []string{records[4][1], records[4][5]})
+
+	// Periods are checked when the export is queued
+	assert.Equal(t, http.StatusBadRequest, send(http.MethodPost, "/wallets/"+wallet.ID.String()+"/exports", `{"from":"2024-07-02","to":"2024-07-01"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, send(http.MethodPost, "/wallets/"+wallet.ID.String()+"/exports", `{"from":"yesterday"}`).Code)
+	assert.Equal(t, http.StatusNotFound, send(http.MethodPost, "/wallets/"+uuid.NewString()+"/exports", "").Code)
+	assert.Equal(t, http.StatusNotFound, send(http.MethodGet, "/exports/"+uuid.NewString(), "").Code)
+
+	// Without a store there is nowhere to put the file
+	exports.Store = nil
+	assert.Equal(t, http.StatusForbidden, send(http.MethodPost, "/wallets/"+wallet.ID.String()+"/exports", "").Code)
+}
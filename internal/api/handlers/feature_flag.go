@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	stderrors "errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"github.com/shanwije/wallet-app/pkg/errors"
+	"github.com/shanwije/wallet-app/pkg/featureflag"
+	"github.com/shanwije/wallet-app/pkg/logger"
+	"github.com/shanwije/wallet-app/pkg/response"
+)
+
+type featureFlagRequest struct {
+	Enabled *bool `json:"enabled" example:"false"`
+}
+
+// featureFlagListResponse lists every known feature flag
+type featureFlagListResponse struct {
+	Flags []featureflag.Flag `json:"flags"`
+}
+
+// featureFlagAppError maps a failure to change a flag to its response
+func featureFlagAppError(err error, name string) *errors.AppError {
+	if stderrors.Is(err, featureflag.ErrUnknownFlag) {
+		return errors.New(errors.ErrFeatureFlagNotFound, "Feature flag not found", http.StatusNotFound).
+			WithDetails("name", name)
+	}
+	return errors.InternalError(err)
+}
+
+// ListFeatureFlags returns every feature flag with its current value
+// @Summary List feature flags
+// @Description Returns every known flag with its value, its configured default and whether an operator has overridden it.
+// @Tags admin
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Success 200 {object} featureFlagListResponse
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v1/admin/feature-flags [get]
+func (h *AdminHandler) ListFeatureFlags(w http.ResponseWriter, r *http.Request) {
+	flags, err := h.FeatureFlags.List(r.Context())
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Failed to list feature flags", zap.Error(err))
+		response.Error(w, errors.InternalError(err))
+		return
+	}
+
+	response.OK(w, featureFlagListResponse{Flags: flags})
+}
+
+// SetFeatureFlag switches a feature on or off at runtime
+// @Summary Set a feature flag
+// @Description Overrides the flag's configured default. Other instances pick the change up within FEATURE_FLAGS_CACHE_TTL.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param name path string true "Flag name"
+// @Param flag body featureFlagRequest true "New value"
+// @Success 200 {object} featureflag.Flag
+// @Failure 400 {object} response.Problem "Invalid request body"
+// @Failure 404 {object} response.Problem "Unknown flag"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v1/admin/feature-flags/{name} [put]
+func (h *AdminHandler) SetFeatureFlag(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+
+	var req featureFlagRequest
+	if appErr := decodeRequest(r, &req); appErr != nil {
+		response.Error(w, appErr)
+		return
+	}
+	if req.Enabled == nil {
+		response.Error(w, errors.New(errors.ErrMissingField, "enabled is required", http.StatusBadRequest))
+		return
+	}
+
+	flag, err := h.FeatureFlags.Set(r.Context(), name, *req.Enabled)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Failed to set feature flag", zap.Error(err), zap.String("flag", name))
+		response.Error(w, featureFlagAppError(err, name))
+		return
+	}
+
+	logger.FromContext(r.Context()).Info("Feature flag set", zap.String("flag", name), zap.Bool("enabled", flag.Enabled))
+	response.OK(w, flag)
+}
+
+// ResetFeatureFlag returns a feature flag to its configured default
+// @Summary Reset a feature flag
+// @Description Removes the operator's override so the flag has its configured default again.
+// @Tags admin
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param name path string true "Flag name"
+// @Success 200 {object} featureflag.Flag
+// @Failure 404 {object} response.Problem "Unknown flag"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v1/admin/feature-flags/{name} [delete]
+func (h *AdminHandler) ResetFeatureFlag(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+
+	flag, err := h.FeatureFlags.Reset(r.Context(), name)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Failed to reset feature flag", zap.Error(err), zap.String("flag", name))
+		response.Error(w, featureFlagAppError(err, name))
+		return
+	}
+
+	logger.FromContext(r.Context()).Info("Feature flag reset", zap.String("flag", name), zap.Bool("enabled", flag.Enabled))
+	response.OK(w, flag)
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	stderrors "errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+
+	"github.com/shanwije/wallet-app/pkg/errors"
+	"github.com/shanwije/wallet-app/pkg/logger"
+	"github.com/shanwije/wallet-app/pkg/money"
+	"github.com/shanwije/wallet-app/pkg/response"
+)
+
+// feeQuoteRequest names the movement to preview the fee of
+type feeQuoteRequest struct {
+	Operation string          `json:"operation" validate:"required,oneof=deposit withdraw transfer" example:"transfer"`
+	Amount    decimal.Decimal `json:"amount" swaggertype:"string" validate:"required,gt=0" example:"250"`
+	Currency  string          `json:"currency,omitempty"`
+}
+
+// QuoteFee previews the fee on a deposit, withdrawal or transfer
+// @Summary Preview a fee
+// @Description Returns the fee the wallet would be charged on a deposit, withdrawal or transfer of the amount, and what the two together would do to its balance.
+// @Description Nothing is moved. A fee of 0 means the operation is not charged one.
+// @Tags wallets
+// @Accept json
+// @Produce json
+// @Param id path string true "Wallet ID"
+// @Param quote body feeQuoteRequest true "Operation and amount"
+// @Success 200 {object} models.FeeQuote
+// @Failure 400 {object} response.Problem "Invalid wallet ID, operation or amount, or a currency the wallet does not hold"
+// @Failure 404 {object} response.Problem "Wallet not found"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v1/wallets/{id}/fees/quote [post]
+func (h *WalletHandler) QuoteFee(w http.ResponseWriter, r *http.Request) {
+	walletID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.ErrorMessage(w, http.StatusBadRequest, "Invalid wallet ID")
+		return
+	}
+
+	var req feeQuoteRequest
+	if appErr := decodeRequest(r, &req); appErr != nil {
+		response.Error(w, appErr)
+		return
+	}
+	if appErr := validateRequest(req); appErr != nil {
+		response.Error(w, appErr)
+		return
+	}
+	amount, appErr := parseAmount(req.Amount, req.Currency)
+	if appErr != nil {
+		response.Error(w, appErr)
+		return
+	}
+
+	quote, err := h.WalletService.QuoteFee(r.Context(), walletID, req.Operation, amount)
+	if err != nil {
+		if appErr := movementAppError(err); appErr != nil {
+			response.Error(w, appErr)
+			return
+		}
+		if stderrors.Is(err, money.ErrCurrencyMismatch) {
+			response.Error(w, errors.InvalidInput("Amount must be in the wallet's currency").WithDetails("currency", amount.Currency().String()))
+			return
+		}
+		logger.FromContext(r.Context()).Error("Failed to quote fee", zap.Error(err))
+		response.Error(w, errors.InternalError(err))
+		return
+	}
+
+	response.OK(w, quote)
+}
+
+// chargedFee is the fee a movement was charged for its response, nil when there was none
+func chargedFee(fee money.Money) *decimal.Decimal {
+	if !fee.IsPositive() {
+		return nil
+	}
+	amount := fee.Amount()
+	return &amount
+}
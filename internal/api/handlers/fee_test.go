@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shanwije/wallet-app/internal/fees"
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/pkg/money"
+)
+
+func TestFeesArePaidIntoTheFeeWallet(t *testing.T) {
+	conn := newTestDB(t)
+	wallets := walletServiceOn(conn)
+	schedule, err := fees.ParseSchedule("transfer=1.5%,withdraw=0.50")
+	require.NoError(t, err)
+	ctx := context.Background()
+	collector, sender, recipient := createUserWallet(t, wallets), createUserWallet(t, wallets), createUserWallet(t, wallets)
+	wallets.Fees, wallets.FeeWalletID = schedule, collector.ID
+	_, err = wallets.Deposit(ctx, sender.ID, money.New(decimal.NewFromInt(100), money.DefaultCurrency), "")
+	require.NoError(t, err)
+
+	handler := NewWalletHandler(wallets)
+	router := chi.NewRouter()
+	router.Post("/wallets/{id}/fees/quote", handler.QuoteFee)
+	router.Post("/wallets/{id}/transfer", handler.TransferV2)
+	router.Post("/wallets/{id}/withdraw", handler.WithdrawV2)
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rr
+	}
+	walletPath := "/wallets/" + sender.ID.String()
+	available := func(wallet *models.Wallet) string {
+		current, err := wallets.GetBalance(ctx, wallet.ID)
+		require.NoError(t, err)
+		return current.Available().Amount().String()
+	}
+
+	// The quote is what the transfer then costs
+	rr := send(http.MethodPost, walletPath+"/fees/quote", `{"operation":"transfer","amount":40}`)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var quote models.FeeQuote
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &quote))
+	assert.Equal(t, "0.6", quote.Fee.String())
+	assert.Equal(t, "-40.6", quote.BalanceChange.String())
+	rr = send(http.MethodPost, walletPath+"/fees/quote", `{"operation":"deposit","amount":40}`)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &quote))
+	assert.True(t, quote.Fee.IsZero())
+	assert.Equal(t, http.StatusBadRequest, send(http.MethodPost, walletPath+"/fees/quote", `{"operation":"refund","amount":40}`).Code)
+	assert.Equal(t, http.StatusBadRequest, send(http.MethodPost, walletPath+"/fees/quote", `{"operation":"transfer","amount":40,"currency":"EUR"}`).Code)
+
+	rr = send(http.MethodPost, walletPath+"/transfer", `{"to_wallet_id":"`+recipient.ID.String()+`","amount":40}`)
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	var transfer transferResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &transfer))
+	require.NotNil(t, transfer.Fee)
+	assert.Equal(t, "0.6", transfer.Fee.String())
+	assert.Equal(t, "59.4", transfer.FromBalance.String())
+	assert.Equal(t, "40", available(recipient))
+	assert.Equal(t, "0.6", available(collector))
+
+	rr = send(http.MethodPost, walletPath+"/withdraw", `{"amount":10}`)
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	var withdrawal movementResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &withdrawal))
+	require.NotNil(t, withdrawal.Fee)
+	assert.Equal(t, "0.5", withdrawal.Fee.String())
+	assert.Equal(t, "48.9", withdrawal.NewBalance.String())
+	assert.Equal(t, "1.1", available(collector))
+
+	// The fee must be affordable as well as the amount, or nothing moves
+	rr = send(http.MethodPost, walletPath+"/withdraw", `{"amount":48.9}`)
+	assert.Equal(t, http.StatusBadRequest, rr.Code, rr.Body.String())
+	assert.Contains(t, rr.Body.String(), "INSUFFICIENT_FUNDS")
+	assert.Equal(t, "48.9", available(sender))
+
+	// Each fee is its own entry in both wallets' histories
+	history, err := wallets.GetTransactionHistory(ctx, sender.ID)
+	require.NoError(t, err)
+	var charged []string
+	for _, transaction := range history {
+		if transaction.Type == models.TransactionTypeFeeOut {
+			charged = append(charged, transaction.Amount.String())
+		}
+	}
+	assert.Equal(t, []string{"0.5", "0.6"}, charged)
+	collected, err := wallets.GetTransactionHistory(ctx, collector.ID)
+	require.NoError(t, err)
+	require.Len(t, collected, 2)
+	assert.Equal(t, models.TransactionTypeFeeIn, collected[0].Type)
+	for _, wallet := range []*models.Wallet{sender, recipient, collector} {
+		assert.NoError(t, wallets.VerifyWalletBalance(ctx, wallet.ID))
+	}
+}
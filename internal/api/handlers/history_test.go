@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/pkg/money"
+)
+
+func TestTransactionHistoryPagesByCursor(t *testing.T) {
+	ctx := context.Background()
+	wallets := newWalletService(t)
+	wallet := createUserWallet(t, wallets)
+	for i := 1; i <= 5; i++ {
+		_, err := wallets.Deposit(ctx, wallet.ID, money.New(decimal.NewFromInt(int64(i)), money.DefaultCurrency), "")
+		require.NoError(t, err)
+	}
+	all, err := wallets.GetTransactionHistory(ctx, wallet.ID)
+	require.NoError(t, err)
+
+	router := chi.NewRouter()
+	router.Get("/wallets/{id}/transactions", (&WalletHandler{WalletService: wallets}).GetTransactionHistory)
+	get := func(target string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, target, nil))
+		return rr
+	}
+	next := regexp.MustCompile(`^<(.+)>; rel="next"$`)
+
+	// Following the Link headers walks the whole history once, newest first
+	var paged []*models.Transaction
+	target := "/wallets/" + wallet.ID.String() + "/transactions?limit=2"
+	for pages := 0; target != ""; pages++ {
+		require.Less(t, pages, 3)
+		rr := get(target)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		var page []*models.Transaction
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &page))
+		assert.Equal(t, pages == 0, rr.Header().Get("ETag") != "", "only the first page starts with the latest transaction")
+		paged = append(paged, page...)
+
+		target = ""
+		if match := next.FindStringSubmatch(rr.Header().Get("Link")); match != nil {
+			target = match[1]
+		}
+	}
+	require.Len(t, paged, len(all))
+	for i := range all {
+		assert.Equal(t, all[i].ID, paged[i].ID)
+	}
+
+	// Oldest first, the last transaction comes last
+	rr := get("/wallets/" + wallet.ID.String() + "/transactions?sort=created_at&limit=5")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var oldestFirst []*models.Transaction
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &oldestFirst))
+	require.Len(t, oldestFirst, 5)
+	assert.Equal(t, all[0].ID, oldestFirst[4].ID)
+	assert.Empty(t, rr.Header().Get("ETag"))
+
+	// A cursor only continues the order it was issued for
+	match := next.FindStringSubmatch(get("/wallets/" + wallet.ID.String() + "/transactions?limit=2").Header().Get("Link"))
+	require.NotNil(t, match)
+	assert.Equal(t, http.StatusBadRequest, get(match[1]+"&sort=created_at").Code)
+	assert.Equal(t, http.StatusBadRequest, get("/wallets/"+wallet.ID.String()+"/transactions?sort=amount").Code)
+	assert.Equal(t, http.StatusBadRequest, get("/wallets/"+wallet.ID.String()+"/transactions?limit=2&offset=2").Code)
+}
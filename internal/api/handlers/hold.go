@@ -0,0 +1,273 @@
+package handlers
+
+import (
+	stderrors "errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+
+	"github.com/shanwije/wallet-app/internal/service"
+	"github.com/shanwije/wallet-app/pkg/errors"
+	"github.com/shanwije/wallet-app/pkg/logger"
+	"github.com/shanwije/wallet-app/pkg/money"
+	"github.com/shanwije/wallet-app/pkg/response"
+)
+
+type holdRequest struct {
+	Amount      decimal.Decimal `json:"amount" swaggertype:"string" example:"25.00"`
+	Currency    string          `json:"currency,omitempty"`
+	Description string          `json:"description,omitempty"`
+}
+
+type captureRequest struct {
+	// Amount to capture; the whole hold when omitted
+	Amount   *decimal.Decimal `json:"amount,omitempty" swaggertype:"string" example:"25.00"`
+	Currency string           `json:"currency,omitempty"`
+}
+
+// holdAppError maps the failures of a hold operation that have their own error code;
+// it returns nil for the rest
+func holdAppError(err error, holdID string) *errors.AppError {
+	switch {
+	case stderrors.Is(err, service.ErrHoldNotFound):
+		return errors.New(errors.ErrHoldNotFound, err.Error(), http.StatusNotFound).
+			WithDetails("hold_id", holdID)
+	case stderrors.Is(err, service.ErrHoldNotActive),
+		stderrors.Is(err, service.ErrHoldDisputed),
+		stderrors.Is(err, service.ErrHoldAwaitingAcceptance):
+		return errors.Conflict(err.Error())
+	case stderrors.Is(err, service.ErrInsufficientAvailableBalance):
+		return errors.InsufficientFunds()
+	case stderrors.Is(err, service.ErrInvalidCaptureAmount):
+		return errors.InvalidAmount(err.Error())
+	default:
+		return movementAppError(err)
+	}
+}
+
+// PlaceHold reserves funds in a wallet
+// @Summary Place a hold
+// @Description Reduces the available balance without posting a transaction until the hold is captured or released
+// @Tags holds
+// @Accept json
+// @Produce json
+// @Param id path string true "Wallet ID"
+// @Param hold body holdRequest true "Hold details"
+// @Param Idempotency-Key header string false "Makes retries safe: a repeat with the same key and body gets the first response"
+// @Success 201 {object} models.Hold
+// @Failure 400 {object} response.Problem "Invalid wallet ID or amount, or insufficient funds"
+// @Failure 404 {object} response.Problem "Wallet not found"
+// @Failure 409 {object} response.Problem "Wallet frozen or closed, concurrent update, or Idempotency-Key reused"
+// @Failure 429 {object} response.Problem "Rate limit exceeded"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v1/wallets/{id}/holds [post]
+func (h *WalletHandler) PlaceHold(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+	walletIDStr := chi.URLParam(r, "id")
+	walletID, err := uuid.Parse(walletIDStr)
+	if err != nil {
+		response.ErrorMessage(w, http.StatusBadRequest, "Invalid wallet ID")
+		return
+	}
+
+	var req holdRequest
+	if appErr := decodeRequest(r, &req); appErr != nil {
+		response.Error(w, appErr)
+		return
+	}
+
+	amount, appErr := parseAmount(req.Amount, req.Currency)
+	if appErr != nil {
+		response.Error(w, appErr)
+		return
+	}
+
+	hold, err := h.WalletService.PlaceHold(r.Context(), walletID, amount, req.Description)
+	if err != nil {
+		log.Error("Failed to place hold", zap.Error(err),
+			zap.String("wallet_id", walletIDStr),
+			zap.String("amount", amount.String()))
+		if appErr := holdAppError(err, ""); appErr != nil {
+			response.Error(w, appErr)
+			return
+		}
+		response.ErrorMessage(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	log.Info("Hold placed",
+		zap.String("hold_id", hold.ID.String()),
+		zap.String("wallet_id", walletIDStr),
+		zap.String("amount", amount.String()))
+
+	response.Created(w, "", hold)
+}
+
+// ListHolds returns the wallet's holds
+// @Summary List holds
+// @Tags holds
+// @Produce json
+// @Param id path string true "Wallet ID"
+// @Success 200 {array} models.Hold
+// @Failure 400 {object} response.Problem "Invalid wallet ID"
+// @Failure 404 {object} response.Problem "Wallet not found"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v1/wallets/{id}/holds [get]
+func (h *WalletHandler) ListHolds(w http.ResponseWriter, r *http.Request) {
+	walletIDStr := chi.URLParam(r, "id")
+	walletID, err := uuid.Parse(walletIDStr)
+	if err != nil {
+		response.ErrorMessage(w, http.StatusBadRequest, "Invalid wallet ID")
+		return
+	}
+
+	holds, err := h.WalletService.ListHolds(r.Context(), walletID)
+	if err != nil {
+		response.Error(w, walletAppError(err, walletIDStr))
+		return
+	}
+
+	response.OK(w, holds)
+}
+
+// CaptureHold posts a hold as a withdrawal
+// @Summary Capture a hold
+// @Description Posts the hold, or part of it, as a withdrawal; any uncaptured remainder is released
+// @Tags holds
+// @Accept json
+// @Produce json
+// @Param id path string true "Wallet ID"
+// @Param holdID path string true "Hold ID"
+// @Param capture body captureRequest false "Partial capture amount"
+// @Param Idempotency-Key header string false "Makes retries safe: a repeat with the same key and body gets the first response"
+// @Param X-Transaction-PIN header string false "Owner's transaction PIN, needed over the PIN threshold once they have set one"
+// @Success 200 {object} models.Hold
+// @Failure 400 {object} response.Problem "Invalid wallet ID, hold ID or amount"
+// @Failure 403 {object} response.Problem "Transaction PIN required or incorrect"
+// @Failure 404 {object} response.Problem "Hold not found"
+// @Failure 409 {object} response.Problem "Hold is no longer active or secures a dispute, concurrent update, or Idempotency-Key reused with a different request body"
+// @Failure 423 {object} response.Problem "Transaction PIN locked after too many incorrect attempts"
+// @Failure 429 {object} response.Problem "Rate limit exceeded"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v1/wallets/{id}/holds/{holdID}/capture [post]
+func (h *WalletHandler) CaptureHold(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+	walletID, holdID, ok := parseHoldPath(w, r)
+	if !ok {
+		return
+	}
+
+	var req captureRequest
+	if appErr := decodeOptionalRequest(r, &req); appErr != nil {
+		response.Error(w, appErr)
+		return
+	}
+
+	var amount *money.Money
+	if req.Amount != nil {
+		parsed, appErr := parseAmount(*req.Amount, req.Currency)
+		if appErr != nil {
+			response.Error(w, appErr)
+			return
+		}
+		amount = &parsed
+	}
+	if appErr := h.authorizeCapture(r, walletID, holdID, amount); appErr != nil {
+		response.Error(w, appErr)
+		return
+	}
+
+	hold, err := h.WalletService.CaptureHold(r.Context(), walletID, holdID, amount)
+	if err != nil {
+		log.Error("Failed to capture hold", zap.Error(err), zap.String("hold_id", holdID.String()))
+		if appErr := holdAppError(err, holdID.String()); appErr != nil {
+			response.Error(w, appErr)
+			return
+		}
+		response.ErrorMessage(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	log.Info("Hold captured",
+		zap.String("hold_id", holdID.String()),
+		zap.String("captured_amount", hold.CapturedAmount.String()))
+
+	response.OK(w, hold)
+}
+
+// authorizeCapture checks the transaction PIN for capturing amount of the hold, or the
+// whole hold when amount is nil. A hold the wallet does not have is left for
+// CaptureHold to report.
+func (h *WalletHandler) authorizeCapture(r *http.Request, walletID, holdID uuid.UUID, amount *money.Money) *errors.AppError {
+	if amount != nil {
+		return authorizePIN(r, h.PINs, walletID, *amount)
+	}
+	if h.PINs == nil {
+		return nil
+	}
+
+	holds, err := h.WalletService.ListHolds(r.Context(), walletID)
+	if err != nil {
+		return walletAppError(err, walletID.String())
+	}
+	for _, hold := range holds {
+		if hold.ID == holdID {
+			return authorizePIN(r, h.PINs, walletID, hold.Funds())
+		}
+	}
+	return nil
+}
+
+// ReleaseHold returns a hold's funds to the available balance
+// @Summary Release a hold
+// @Tags holds
+// @Produce json
+// @Param id path string true "Wallet ID"
+// @Param holdID path string true "Hold ID"
+// @Param Idempotency-Key header string false "Makes retries safe: a repeat with the same key and body gets the first response"
+// @Success 200 {object} models.Hold
+// @Failure 400 {object} response.Problem "Invalid wallet ID or hold ID"
+// @Failure 404 {object} response.Problem "Hold not found"
+// @Failure 409 {object} response.Problem "Hold is no longer active or secures a dispute, or Idempotency-Key reused with a different request body"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v1/wallets/{id}/holds/{holdID}/release [post]
+func (h *WalletHandler) ReleaseHold(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+	walletID, holdID, ok := parseHoldPath(w, r)
+	if !ok {
+		return
+	}
+
+	hold, err := h.WalletService.ReleaseHold(r.Context(), walletID, holdID)
+	if err != nil {
+		log.Error("Failed to release hold", zap.Error(err), zap.String("hold_id", holdID.String()))
+		if appErr := holdAppError(err, holdID.String()); appErr != nil {
+			response.Error(w, appErr)
+			return
+		}
+		response.Error(w, errors.InternalError(err))
+		return
+	}
+
+	log.Info("Hold released", zap.String("hold_id", holdID.String()))
+
+	response.OK(w, hold)
+}
+
+// parseHoldPath reads the wallet and hold IDs from the URL, responding with 400 if either is invalid
+func parseHoldPath(w http.ResponseWriter, r *http.Request) (uuid.UUID, uuid.UUID, bool) {
+	walletID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.ErrorMessage(w, http.StatusBadRequest, "Invalid wallet ID")
+		return uuid.Nil, uuid.Nil, false
+	}
+	holdID, err := uuid.Parse(chi.URLParam(r, "holdID"))
+	if err != nil {
+		response.ErrorMessage(w, http.StatusBadRequest, "Invalid hold ID")
+		return uuid.Nil, uuid.Nil, false
+	}
+	return walletID, holdID, true
+}
@@ -0,0 +1,240 @@
+package handlers
+
+import (
+	stderrors "errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/service"
+	"github.com/shanwije/wallet-app/pkg/errors"
+	"github.com/shanwije/wallet-app/pkg/logger"
+	"github.com/shanwije/wallet-app/pkg/response"
+)
+
+// IncomingTransferHandler serves the transfers into wallets that require them to be
+// accepted, and the setting that does
+type IncomingTransferHandler struct {
+	IncomingTransferService *service.IncomingTransferService
+}
+
+// incomingTransferSettingsRequest replaces a wallet's incoming transfer settings
+type incomingTransferSettingsRequest struct {
+	RequireAcceptance bool `json:"require_acceptance" example:"true"`
+}
+
+// NewIncomingTransferHandler creates a new IncomingTransferHandler
+func NewIncomingTransferHandler(incomingTransferService *service.IncomingTransferService) *IncomingTransferHandler {
+	return &IncomingTransferHandler{
+		IncomingTransferService: incomingTransferService,
+	}
+}
+
+// incomingTransferAppError maps the failures of accepting or rejecting an incoming
+// transfer that have their own error code; it returns nil for the rest
+func incomingTransferAppError(err error, transferID string) *errors.AppError {
+	switch {
+	case stderrors.Is(err, service.ErrIncomingTransferNotFound):
+		return errors.New(errors.ErrIncomingTransferNotFound, err.Error(), http.StatusNotFound).
+			WithDetails("incoming_transfer_id", transferID)
+	case stderrors.Is(err, service.ErrIncomingTransferNotPending),
+		stderrors.Is(err, service.ErrIncomingTransferExpired):
+		return errors.Conflict(err.Error())
+	default:
+		return movementAppError(err)
+	}
+}
+
+// GetSettings returns whether a wallet requires incoming transfers to be accepted
+// @Summary Get incoming transfer settings
+// @Tags incoming-transfers
+// @Produce json
+// @Param id path string true "Wallet ID"
+// @Success 200 {object} models.IncomingTransferSettings
+// @Failure 400 {object} response.Problem "Invalid wallet ID"
+// @Failure 404 {object} response.Problem "Wallet not found"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v1/wallets/{id}/incoming-transfers/settings [get]
+func (h *IncomingTransferHandler) GetSettings(w http.ResponseWriter, r *http.Request) {
+	walletIDStr := chi.URLParam(r, "id")
+	walletID, err := uuid.Parse(walletIDStr)
+	if err != nil {
+		response.ErrorMessage(w, http.StatusBadRequest, "Invalid wallet ID")
+		return
+	}
+
+	settings, err := h.IncomingTransferService.GetSettings(r.Context(), walletID)
+	if err != nil {
+		response.Error(w, walletAppError(err, walletIDStr))
+		return
+	}
+
+	response.OK(w, settings)
+}
+
+// SetSettings replaces whether a wallet requires incoming transfers to be accepted
+// @Summary Set incoming transfer settings
+// @Description With require_acceptance set, what a transfer into the wallet credits is held there until the owner accepts it.
+// @Description One rejected, or not accepted within the acceptance window, is returned to its sender; the fee the sender paid is not.
+// @Description Turning it off leaves transfers already waiting to be accepted or rejected.
+// @Tags incoming-transfers
+// @Accept json
+// @Produce json
+// @Param id path string true "Wallet ID"
+// @Param settings body incomingTransferSettingsRequest true "New incoming transfer settings"
+// @Success 200 {object} models.IncomingTransferSettings
+// @Failure 400 {object} response.Problem "Invalid wallet ID or request body"
+// @Failure 404 {object} response.Problem "Wallet not found"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v1/wallets/{id}/incoming-transfers/settings [put]
+func (h *IncomingTransferHandler) SetSettings(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+	walletIDStr := chi.URLParam(r, "id")
+	walletID, err := uuid.Parse(walletIDStr)
+	if err != nil {
+		response.ErrorMessage(w, http.StatusBadRequest, "Invalid wallet ID")
+		return
+	}
+
+	var req incomingTransferSettingsRequest
+	if appErr := decodeRequest(r, &req); appErr != nil {
+		response.Error(w, appErr)
+		return
+	}
+
+	settings, err := h.IncomingTransferService.SetSettings(r.Context(), &models.IncomingTransferSettings{
+		WalletID:          walletID,
+		RequireAcceptance: req.RequireAcceptance,
+	})
+	if err != nil {
+		response.Error(w, walletAppError(err, walletIDStr))
+		return
+	}
+
+	log.Info("Incoming transfer settings changed", zap.String("wallet_id", walletIDStr),
+		zap.Bool("require_acceptance", settings.RequireAcceptance))
+	response.OK(w, settings)
+}
+
+// List returns the transfers into a wallet that needed accepting
+// @Summary List incoming transfers
+// @Description Returns the transfers into the wallet that were held for acceptance, newest first, in whatever status they are now
+// @Tags incoming-transfers
+// @Produce json
+// @Param id path string true "Receiving wallet ID"
+// @Success 200 {array} models.IncomingTransfer
+// @Failure 400 {object} response.Problem "Invalid wallet ID"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v1/wallets/{id}/incoming-transfers [get]
+func (h *IncomingTransferHandler) List(w http.ResponseWriter, r *http.Request) {
+	walletID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.ErrorMessage(w, http.StatusBadRequest, "Invalid wallet ID")
+		return
+	}
+
+	transfers, err := h.IncomingTransferService.List(r.Context(), walletID)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Failed to list incoming transfers", zap.Error(err))
+		response.Error(w, errors.InternalError(err))
+		return
+	}
+
+	response.OK(w, transfers)
+}
+
+// Accept frees the money of a transfer held for acceptance
+// @Summary Accept an incoming transfer
+// @Description Releases the hold on what the transfer credited, making it available. One accepted after its
+// @Description acceptance window ended is returned to its sender instead and answered with 409.
+// @Tags incoming-transfers
+// @Produce json
+// @Param id path string true "Receiving wallet ID"
+// @Param transferID path string true "Incoming transfer ID"
+// @Success 200 {object} models.IncomingTransfer
+// @Failure 400 {object} response.Problem "Invalid wallet ID or incoming transfer ID"
+// @Failure 404 {object} response.Problem "Incoming transfer not found"
+// @Failure 409 {object} response.Problem "Transfer is no longer pending or has expired"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v1/wallets/{id}/incoming-transfers/{transferID}/accept [post]
+func (h *IncomingTransferHandler) Accept(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+	walletID, transferID, ok := parseIncomingTransferPath(w, r)
+	if !ok {
+		return
+	}
+
+	transfer, err := h.IncomingTransferService.Accept(r.Context(), walletID, transferID)
+	if err != nil {
+		log.Error("Failed to accept incoming transfer", zap.Error(err), zap.String("incoming_transfer_id", transferID.String()))
+		if appErr := incomingTransferAppError(err, transferID.String()); appErr != nil {
+			response.Error(w, appErr)
+			return
+		}
+		response.Error(w, errors.InternalError(err))
+		return
+	}
+
+	log.Info("Incoming transfer accepted",
+		zap.String("incoming_transfer_id", transferID.String()),
+		zap.String("amount", transfer.Funds().String()))
+
+	response.OK(w, transfer)
+}
+
+// Reject returns a transfer held for acceptance to its sender
+// @Summary Reject an incoming transfer
+// @Description Reverses the transfer, paying what it credited back to the sender in the currency they sent it in. The fee the sender paid is kept.
+// @Tags incoming-transfers
+// @Produce json
+// @Param id path string true "Receiving wallet ID"
+// @Param transferID path string true "Incoming transfer ID"
+// @Success 200 {object} models.IncomingTransfer
+// @Failure 400 {object} response.Problem "Invalid wallet ID or incoming transfer ID"
+// @Failure 404 {object} response.Problem "Incoming transfer not found"
+// @Failure 409 {object} response.Problem "Transfer is no longer pending, or a wallet is frozen or closed"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v1/wallets/{id}/incoming-transfers/{transferID}/reject [post]
+func (h *IncomingTransferHandler) Reject(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+	walletID, transferID, ok := parseIncomingTransferPath(w, r)
+	if !ok {
+		return
+	}
+
+	transfer, err := h.IncomingTransferService.Reject(r.Context(), walletID, transferID)
+	if err != nil {
+		log.Error("Failed to reject incoming transfer", zap.Error(err), zap.String("incoming_transfer_id", transferID.String()))
+		if appErr := incomingTransferAppError(err, transferID.String()); appErr != nil {
+			response.Error(w, appErr)
+			return
+		}
+		response.Error(w, errors.InternalError(err))
+		return
+	}
+
+	log.Info("Incoming transfer rejected",
+		zap.String("incoming_transfer_id", transferID.String()),
+		zap.String("amount", transfer.Funds().String()))
+
+	response.OK(w, transfer)
+}
+
+// parseIncomingTransferPath reads the wallet and incoming transfer IDs from the path,
+// answering 400 when either is not a UUID
+func parseIncomingTransferPath(w http.ResponseWriter, r *http.Request) (uuid.UUID, uuid.UUID, bool) {
+	walletID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.ErrorMessage(w, http.StatusBadRequest, "Invalid wallet ID")
+		return uuid.Nil, uuid.Nil, false
+	}
+	transferID, err := uuid.Parse(chi.URLParam(r, "transferID"))
+	if err != nil {
+		response.ErrorMessage(w, http.StatusBadRequest, "Invalid incoming transfer ID")
+		return uuid.Nil, uuid.Nil, false
+	}
+	return walletID, transferID, true
+}
@@ -0,0 +1,220 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository/sqlite"
+	"github.com/shanwije/wallet-app/internal/service"
+	"github.com/shanwije/wallet-app/pkg/clock"
+	"github.com/shanwije/wallet-app/pkg/money"
+)
+
+func TestIncomingTransfersWaitForAcceptance(t *testing.T) {
+	conn := newTestDB(t)
+	now := clock.NewFake(time.Date(2024, 7, 18, 9, 0, 0, 0, time.UTC))
+	wallets := walletServiceOn(conn)
+	wallets.HoldRepo = sqlite.NewHoldRepository(conn)
+	wallets.IncomingTransfers = sqlite.NewIncomingTransferRepository(conn)
+	wallets.AcceptanceWindow = time.Hour
+	wallets.Clock = now
+	incoming := &service.IncomingTransferService{Repo: wallets.IncomingTransfers, Wallets: wallets, Clock: now}
+	ctx := context.Background()
+
+	sender, recipient := createUserWallet(t, wallets), createUserWallet(t, wallets)
+	_, err := wallets.Deposit(ctx, sender.ID, money.New(decimal.NewFromInt(100), money.DefaultCurrency), "")
+	require.NoError(t, err)
+
+	handler := NewIncomingTransferHandler(incoming)
+	router := chi.NewRouter()
+	router.Get("/wallets/{id}/incoming-transfers", handler.List)
+	router.Put("/wallets/{id}/incoming-transfers/settings", handler.SetSettings)
+	router.Post("/wallets/{id}/incoming-transfers/{transferID}/accept", handler.Accept)
+	router.Post("/wallets/{id}/incoming-transfers/{transferID}/reject", handler.Reject)
+	router.Post("/wallets/{id}/holds/{holdID}/release", NewWalletHandler(wallets).ReleaseHold)
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rr
+	}
+	transfer := func(amount int64) *models.IncomingTransfer {
+		require.NoError(t, wallets.Transfer(ctx, sender.ID, recipient.ID, money.New(decimal.NewFromInt(amount), money.DefaultCurrency), "", ""))
+		rr := send(http.MethodGet, "/wallets/"+recipient.ID.String()+"/incoming-transfers", "")
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		var transfers []*models.IncomingTransfer
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &transfers))
+		require.NotEmpty(t, transfers)
+		return transfers[0]
+	}
+	finish := func(transfer *models.IncomingTransfer, action string) *httptest.ResponseRecorder {
+		return send(http.MethodPost, "/wallets/"+recipient.ID.String()+"/incoming-transfers/"+transfer.ID.String()+"/"+action, "")
+	}
+	balances := func(wallet *models.Wallet) (string, string) {
+		current, err := wallets.GetBalance(ctx, wallet.ID)
+		require.NoError(t, err)
+		return current.Balance.String(), current.Available().Amount().String()
+	}
+
+	// Transfers land straight away until the recipient asks to accept them
+	require.NoError(t, wallets.Transfer(ctx, sender.ID, recipient.ID, money.New(decimal.NewFromInt(10), money.DefaultCurrency), "", ""))
+	rr := send(http.MethodPut, "/wallets/"+recipient.ID.String()+"/incoming-transfers/settings", `{"require_acceptance":true}`)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	accepted := transfer(30)
+	assert.Equal(t, models.IncomingTransferStatusPending, accepted.Status)
+	assert.Equal(t, sender.ID, accepted.FromWalletID)
+	assert.Equal(t, "30", accepted.Amount.String())
+	assert.Equal(t, now.Now().Add(time.Hour), accepted.ExpiresAt.UTC())
+	require.NotNil(t, accepted.HoldID)
+	balance, available := balances(recipient)
+	assert.Equal(t, "40", balance)
+	assert.Equal(t, "10", available, "the transfer is held until accepted")
+
+	// The hold is only freed by accepting or returning the transfer
+	rr = send(http.MethodPost, "/wallets/"+recipient.ID.String()+"/holds/"+accepted.HoldID.String()+"/release", "")
+	assert.Equal(t, http.StatusConflict, rr.Code, rr.Body.String())
+	// Nor can another wallet accept it
+	rr = send(http.MethodPost, "/wallets/"+sender.ID.String()+"/incoming-transfers/"+accepted.ID.String()+"/accept", "")
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.Contains(t, rr.Body.String(), "INCOMING_TRANSFER_NOT_FOUND")
+
+	rr = finish(accepted, "accept")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	_, available = balances(recipient)
+	assert.Equal(t, "40", available)
+	assert.Equal(t, http.StatusConflict, finish(accepted, "reject").Code)
+
+	// A rejected transfer goes back to the sender
+	rejected := transfer(20)
+	rr = finish(rejected, "reject")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), rejected))
+	assert.Equal(t, models.IncomingTransferStatusRejected, rejected.Status)
+	assert.NotNil(t, rejected.ReturnJournalID)
+	balance, _ = balances(sender)
+	assert.Equal(t, "60", balance)
+	balance, available = balances(recipient)
+	assert.Equal(t, "40", balance)
+	assert.Equal(t, "40", available)
+
+	// So does one left unaccepted past its window, and accepting it then is too late
+	expired, late := transfer(5), transfer(15)
+	now.Advance(time.Hour)
+	rr = finish(late, "accept")
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	assert.Contains(t, rr.Body.String(), "not accepted in time")
+	returned, err := incoming.ExpireDue(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, returned, "the late transfer was returned when accepted")
+	transfers, err := incoming.List(ctx, recipient.ID)
+	require.NoError(t, err)
+	for _, transfer := range transfers {
+		if transfer.ID == expired.ID || transfer.ID == late.ID {
+			assert.Equal(t, models.IncomingTransferStatusExpired, transfer.Status)
+		}
+	}
+	balance, _ = balances(sender)
+	assert.Equal(t, "60", balance)
+	balance, available = balances(recipient)
+	assert.Equal(t, "40", balance)
+	assert.Equal(t, "40", available)
+
+	// A transfer awaiting acceptance cannot be reversed, which would return it twice
+	pending := transfer(7)
+	entryOf := func(transfer *models.IncomingTransfer) uuid.UUID {
+		history, err := wallets.GetTransactionHistory(ctx, recipient.ID)
+		require.NoError(t, err)
+		for _, entry := range history {
+			if entry.ReferenceID != nil && *entry.ReferenceID == transfer.JournalID {
+				return entry.ID
+			}
+		}
+		t.Fatalf("no entry for transfer %s", transfer.ID)
+		return uuid.Nil
+	}
+	_, err = wallets.ReverseTransaction(ctx, entryOf(pending), nil, "")
+	assert.ErrorIs(t, err, service.ErrTransferAwaitingAcceptance)
+	_, err = wallets.CorrectTransaction(ctx, entryOf(pending), nil, models.CorrectionReasonDuplicate, "")
+	assert.ErrorIs(t, err, service.ErrTransferAwaitingAcceptance)
+
+	// One reversed before that was refused is closed when it expires without being
+	// returned again, and does not hold up the rest of the run
+	wallets.IncomingTransfers = nil
+	_, err = wallets.ReverseTransaction(ctx, entryOf(pending), nil, "")
+	require.NoError(t, err)
+	wallets.IncomingTransfers = incoming.Repo
+	after := transfer(3)
+	now.Advance(time.Hour)
+	returned, err = incoming.ExpireDue(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, returned)
+	transfers, err = incoming.List(ctx, recipient.ID)
+	require.NoError(t, err)
+	for _, transfer := range transfers {
+		switch transfer.ID {
+		case pending.ID:
+			assert.Equal(t, models.IncomingTransferStatusExpired, transfer.Status)
+			assert.Nil(t, transfer.ReturnJournalID)
+		case after.ID:
+			assert.Equal(t, models.IncomingTransferStatusExpired, transfer.Status)
+			assert.NotNil(t, transfer.ReturnJournalID)
+		}
+	}
+	balance, _ = balances(sender)
+	assert.Equal(t, "60", balance)
+	balance, available = balances(recipient)
+	assert.Equal(t, "40", balance)
+	assert.Equal(t, "40", available)
+}
+
+func TestIncomingTransferExpiryIsNotHeldUpByTransfersItCannotReturn(t *testing.T) {
+	conn := newTestDB(t)
+	now := clock.NewFake(time.Date(2024, 7, 18, 9, 0, 0, 0, time.UTC))
+	wallets := walletServiceOn(conn)
+	wallets.HoldRepo = sqlite.NewHoldRepository(conn)
+	wallets.IncomingTransfers = sqlite.NewIncomingTransferRepository(conn)
+	wallets.AcceptanceWindow = time.Hour
+	wallets.Clock = now
+	incoming := &service.IncomingTransferService{Repo: wallets.IncomingTransfers, Wallets: wallets, Clock: now}
+	ctx := context.Background()
+
+	sender, frozen, open := createUserWallet(t, wallets), createUserWallet(t, wallets), createUserWallet(t, wallets)
+	_, err := wallets.Deposit(ctx, sender.ID, money.New(decimal.NewFromInt(200), money.DefaultCurrency), "")
+	require.NoError(t, err)
+	for _, recipient := range []*models.Wallet{frozen, open} {
+		_, err := incoming.SetSettings(ctx, &models.IncomingTransferSettings{WalletID: recipient.ID, RequireAcceptance: true})
+		require.NoError(t, err)
+	}
+
+	// More transfers than one run reads at a time expire first, into a wallet that is
+	// then frozen, so none of them can be returned
+	one := money.New(decimal.NewFromInt(1), money.DefaultCurrency)
+	for range 101 {
+		require.NoError(t, wallets.Transfer(ctx, sender.ID, frozen.ID, one, "", ""))
+	}
+	now.Advance(time.Minute)
+	require.NoError(t, wallets.Transfer(ctx, sender.ID, open.ID, one, "", ""))
+	_, err = wallets.SetWalletStatus(ctx, frozen.ID, models.WalletStatusFrozen)
+	require.NoError(t, err)
+
+	now.Advance(time.Hour)
+	returned, err := incoming.ExpireDue(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, returned)
+	transfers, err := incoming.List(ctx, open.ID)
+	require.NoError(t, err)
+	require.Len(t, transfers, 1)
+	assert.Equal(t, models.IncomingTransferStatusExpired, transfers[0].Status)
+}
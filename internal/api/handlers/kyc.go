@@ -0,0 +1,279 @@
+package handlers
+
+import (
+	stderrors "errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/service"
+	"github.com/shanwije/wallet-app/pkg/errors"
+	"github.com/shanwije/wallet-app/pkg/logger"
+	"github.com/shanwije/wallet-app/pkg/response"
+)
+
+// KYCHandler serves users' verification tiers and the documents they submit, which
+// operators review
+type KYCHandler struct {
+	KYCService *service.KYCService
+}
+
+type submitKYCDocumentRequest struct {
+	// DocumentType is passport, national_id, drivers_license or proof_of_address
+	DocumentType string `json:"document_type" validate:"required" example:"passport"`
+	// Reference locates the uploaded document in the document store
+	Reference string `json:"reference" validate:"required,max=500" example:"kyc/2024/07/3f1c9a.pdf"`
+}
+
+type setKYCTierRequest struct {
+	Tier string `json:"tier" validate:"required" example:"basic"`
+	Note string `json:"note,omitempty" validate:"max=500" example:"Passport approved"`
+}
+
+type reviewKYCDocumentRequest struct {
+	Status string `json:"status" validate:"required" example:"approved"`
+	Note   string `json:"note,omitempty" validate:"max=500" example:"Photo page legible, not expired"`
+}
+
+// kycDocumentListResponse is one page of KYC documents
+type kycDocumentListResponse struct {
+	Documents []*models.KYCDocument `json:"documents"`
+	Limit     int                   `json:"limit"`
+	Offset    int                   `json:"offset"`
+}
+
+// NewKYCHandler creates a new KYCHandler
+func NewKYCHandler(kycService *service.KYCService) *KYCHandler {
+	return &KYCHandler{
+		KYCService: kycService,
+	}
+}
+
+// kycAppError maps the failures of KYC requests that have their own error code; it
+// returns nil for the rest
+func kycAppError(err error, documentID string) *errors.AppError {
+	switch {
+	case stderrors.Is(err, service.ErrKYCDocumentNotFound):
+		return errors.New(errors.ErrKYCDocumentNotFound, "KYC document not found", http.StatusNotFound).
+			WithDetails("document_id", documentID)
+	case stderrors.Is(err, service.ErrKYCDocumentReviewed):
+		return errors.Conflict(err.Error())
+	case stderrors.Is(err, service.ErrInvalidKYCTier),
+		stderrors.Is(err, service.ErrInvalidKYCDocument),
+		stderrors.Is(err, service.ErrInvalidKYCReview):
+		return errors.InvalidInput(err.Error())
+	default:
+		return nil
+	}
+}
+
+// GetProfile returns a user's verification tier
+// @Summary Get KYC profile
+// @Description Returns the user's verification tier, the daily limits it holds their wallet to, and the documents they submitted, newest first.
+// @Tags users
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} models.KYCProfile
+// @Failure 400 {object} response.Problem "Invalid user ID"
+// @Failure 404 {object} response.Problem "User not found"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v1/users/{id}/kyc [get]
+// @Router /api/v1/admin/users/{id}/kyc [get]
+func (h *KYCHandler) GetProfile(w http.ResponseWriter, r *http.Request) {
+	userIDStr := chi.URLParam(r, "id")
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		response.ErrorMessage(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	profile, err := h.KYCService.GetProfile(r.Context(), userID)
+	if err != nil {
+		respondWithUserError(w, r, err, userIDStr)
+		return
+	}
+
+	response.OK(w, profile)
+}
+
+// SubmitDocument records a document a user submitted for verification
+// @Summary Submit a KYC document
+// @Description Records a document the user uploaded to the document store for an operator to review. Reviewing it does not change the user's tier by itself.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID"
+// @Param document body submitKYCDocumentRequest true "Document type and where it was uploaded"
+// @Success 201 {object} models.KYCDocument
+// @Failure 400 {object} response.Problem "Invalid user ID or document"
+// @Failure 404 {object} response.Problem "User not found"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v1/users/{id}/kyc/documents [post]
+func (h *KYCHandler) SubmitDocument(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+	userIDStr := chi.URLParam(r, "id")
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		response.ErrorMessage(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	var req submitKYCDocumentRequest
+	if appErr := decodeRequest(r, &req); appErr != nil {
+		response.Error(w, appErr)
+		return
+	}
+	if appErr := validateRequest(req); appErr != nil {
+		response.Error(w, appErr)
+		return
+	}
+
+	document, err := h.KYCService.SubmitDocument(r.Context(), userID, req.DocumentType, req.Reference)
+	if err != nil {
+		if appErr := kycAppError(err, ""); appErr != nil {
+			response.Error(w, appErr)
+			return
+		}
+		respondWithUserError(w, r, err, userIDStr)
+		return
+	}
+
+	log.Info("KYC document submitted",
+		zap.String("user_id", userIDStr),
+		zap.String("document_id", document.ID.String()),
+		zap.String("document_type", document.DocumentType))
+
+	response.Created(w, "", document)
+}
+
+// SetTier moves a user to another verification tier
+// @Summary Set a user's KYC tier
+// @Description Moves the user to the unverified, basic or full tier. Their wallet is held to the new tier's daily limits from its next withdrawal or transfer.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param id path string true "User ID"
+// @Param tier body setKYCTierRequest true "New tier and why"
+// @Success 200 {object} models.KYCProfile
+// @Failure 400 {object} response.Problem "Invalid user ID or tier"
+// @Failure 404 {object} response.Problem "User not found"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v1/admin/users/{id}/kyc/tier [put]
+func (h *KYCHandler) SetTier(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+	userIDStr := chi.URLParam(r, "id")
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		response.ErrorMessage(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	var req setKYCTierRequest
+	if appErr := decodeRequest(r, &req); appErr != nil {
+		response.Error(w, appErr)
+		return
+	}
+	if appErr := validateRequest(req); appErr != nil {
+		response.Error(w, appErr)
+		return
+	}
+
+	profile, err := h.KYCService.SetTier(r.Context(), userID, req.Tier, req.Note)
+	if err != nil {
+		if appErr := kycAppError(err, ""); appErr != nil {
+			response.Error(w, appErr)
+			return
+		}
+		respondWithUserError(w, r, err, userIDStr)
+		return
+	}
+
+	log.Info("KYC tier changed", zap.String("user_id", userIDStr), zap.String("tier", profile.Tier))
+
+	response.OK(w, profile)
+}
+
+// ListPendingDocuments pages through the documents awaiting review
+// @Summary List KYC documents awaiting review
+// @Description Returns pending documents, oldest first, at most 200 per page
+// @Tags admin
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param limit query int false "Page size; larger sizes are cut to 200" minimum(0) default(50)
+// @Param offset query int false "Documents to skip" minimum(0) default(0)
+// @Success 200 {object} kycDocumentListResponse
+// @Failure 400 {object} response.Problem "Invalid pagination parameters"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v1/admin/kyc/documents [get]
+func (h *KYCHandler) ListPendingDocuments(w http.ResponseWriter, r *http.Request) {
+	limit, offset, appErr := parsePage(r)
+	if appErr != nil {
+		response.Error(w, appErr)
+		return
+	}
+
+	documents, err := h.KYCService.ListPendingDocuments(r.Context(), limit, offset)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Failed to list KYC documents", zap.Error(err))
+		response.Error(w, errors.InternalError(err))
+		return
+	}
+
+	response.OK(w, kycDocumentListResponse{Documents: documents, Limit: limit, Offset: offset})
+}
+
+// ReviewDocument approves or rejects a submitted document
+// @Summary Review a KYC document
+// @Description Approves or rejects a pending document. Set the user's tier separately once their documents support it.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param id path string true "Document ID"
+// @Param review body reviewKYCDocumentRequest true "approved or rejected, and why"
+// @Success 200 {object} models.KYCDocument
+// @Failure 400 {object} response.Problem "Invalid document ID or status"
+// @Failure 404 {object} response.Problem "Document not found"
+// @Failure 409 {object} response.Problem "Document already reviewed"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v1/admin/kyc/documents/{id}/review [post]
+func (h *KYCHandler) ReviewDocument(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+	documentIDStr := chi.URLParam(r, "id")
+	documentID, err := uuid.Parse(documentIDStr)
+	if err != nil {
+		response.ErrorMessage(w, http.StatusBadRequest, "Invalid document ID")
+		return
+	}
+
+	var req reviewKYCDocumentRequest
+	if appErr := decodeRequest(r, &req); appErr != nil {
+		response.Error(w, appErr)
+		return
+	}
+	if appErr := validateRequest(req); appErr != nil {
+		response.Error(w, appErr)
+		return
+	}
+
+	document, err := h.KYCService.ReviewDocument(r.Context(), documentID, req.Status, req.Note)
+	if err != nil {
+		log.Error("Failed to review KYC document", zap.Error(err), zap.String("document_id", documentIDStr))
+		if appErr := kycAppError(err, documentIDStr); appErr != nil {
+			response.Error(w, appErr)
+			return
+		}
+		response.Error(w, errors.InternalError(err))
+		return
+	}
+
+	log.Info("KYC document reviewed",
+		zap.String("document_id", documentIDStr),
+		zap.String("status", document.Status))
+
+	response.OK(w, document)
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository/sqlite"
+	"github.com/shanwije/wallet-app/internal/service"
+	"github.com/shanwije/wallet-app/pkg/clock"
+	"github.com/shanwije/wallet-app/pkg/money"
+)
+
+func TestKYCTierLimitsWithdrawals(t *testing.T) {
+	conn := newTestDB(t)
+	now := clock.NewFake(time.Date(2024, 7, 20, 9, 0, 0, 0, time.UTC))
+	unverifiedLimit, basicLimit := decimal.NewFromInt(100), decimal.NewFromInt(500)
+	tierLimits := map[string]models.KYCTierLimits{
+		models.KYCTierUnverified: {DailyWithdrawalLimit: &unverifiedLimit},
+		models.KYCTierBasic:      {DailyWithdrawalLimit: &basicLimit},
+	}
+	wallets := walletServiceOn(conn)
+	wallets.KYCRepo = sqlite.NewKYCRepository(conn)
+	wallets.TierLimits = tierLimits
+	wallets.Clock = now
+	kyc := &service.KYCService{Repo: wallets.KYCRepo, UserRepo: wallets.UserRepo, TierLimits: tierLimits, Clock: now}
+
+	wallet := createUserWallet(t, wallets)
+	_, err := wallets.Deposit(context.Background(), wallet.ID, money.New(decimal.NewFromInt(1000), money.DefaultCurrency), "")
+	require.NoError(t, err)
+
+	walletHandler := &WalletHandler{WalletService: wallets}
+	kycHandler := NewKYCHandler(kyc)
+	router := chi.NewRouter()
+	router.Post("/wallets/{id}/withdraw", walletHandler.Withdraw)
+	router.Get("/users/{id}/kyc", kycHandler.GetProfile)
+	router.Post("/users/{id}/kyc/documents", kycHandler.SubmitDocument)
+	router.Put("/admin/users/{id}/kyc/tier", kycHandler.SetTier)
+	router.Get("/admin/kyc/documents", kycHandler.ListPendingDocuments)
+	router.Post("/admin/kyc/documents/{id}/review", kycHandler.ReviewDocument)
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rr
+	}
+	withdraw := func(amount string) *httptest.ResponseRecorder {
+		return send(http.MethodPost, "/wallets/"+wallet.ID.String()+"/withdraw", `{"amount":`+amount+`}`)
+	}
+	userPath := "/users/" + wallet.UserID.String()
+
+	// Users start unverified, held to its daily limit
+	rr := send(http.MethodGet, userPath+"/kyc", "")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.Contains(t, rr.Body.String(), `"tier":"unverified"`)
+	assert.Equal(t, http.StatusOK, withdraw("80").Code)
+	rr = withdraw("30")
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	assert.Contains(t, rr.Body.String(), "LIMIT_EXCEEDED")
+	assert.Contains(t, rr.Body.String(), "for unverified users")
+
+	// A submitted document waits for review without changing the tier
+	assert.Equal(t, http.StatusBadRequest, send(http.MethodPost, userPath+"/kyc/documents", `{"document_type":"selfie","reference":"a.jpg"}`).Code)
+	rr = send(http.MethodPost, userPath+"/kyc/documents", `{"document_type":"passport","reference":"kyc/passport.pdf"}`)
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	var document models.KYCDocument
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &document))
+	assert.Equal(t, models.KYCDocumentStatusPending, document.Status)
+
+	rr = send(http.MethodGet, "/admin/kyc/documents", "")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.Contains(t, rr.Body.String(), document.ID.String())
+
+	reviewPath := "/admin/kyc/documents/" + document.ID.String() + "/review"
+	assert.Equal(t, http.StatusBadRequest, send(http.MethodPost, reviewPath, `{"status":"pending"}`).Code)
+	rr = send(http.MethodPost, reviewPath, `{"status":"approved","note":"Photo page legible"}`)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.Contains(t, rr.Body.String(), `"status":"approved"`)
+	assert.Equal(t, http.StatusConflict, send(http.MethodPost, reviewPath, `{"status":"rejected"}`).Code)
+	assert.NotContains(t, send(http.MethodGet, "/admin/kyc/documents", "").Body.String(), document.ID.String())
+	assert.Equal(t, http.StatusUnprocessableEntity, withdraw("30").Code)
+
+	// Raising the tier lifts the limit to the new tier's, counting today's withdrawals
+	assert.Equal(t, http.StatusBadRequest, send(http.MethodPut, "/admin"+userPath+"/kyc/tier", `{"tier":"gold"}`).Code)
+	rr = send(http.MethodPut, "/admin"+userPath+"/kyc/tier", `{"tier":"basic","note":"Passport approved"}`)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.Contains(t, rr.Body.String(), `"tier":"basic"`)
+	assert.Contains(t, rr.Body.String(), `"daily_withdrawal_limit":"500"`)
+	assert.Equal(t, http.StatusOK, withdraw("400").Code)
+	assert.Equal(t, http.StatusUnprocessableEntity, withdraw("30").Code)
+
+	// Full users are held to their wallet's limits only
+	rr = send(http.MethodPut, "/admin"+userPath+"/kyc/tier", `{"tier":"full"}`)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.Equal(t, http.StatusOK, withdraw("300").Code)
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/shanwije/wallet-app/pkg/errors"
+	"github.com/shanwije/wallet-app/pkg/logger"
+	"github.com/shanwije/wallet-app/pkg/response"
+)
+
+// maxLogLevelDuration caps how long a changed log level lasts before it goes back by
+// itself, so debug logging is not left on by accident
+const maxLogLevelDuration = 24 * time.Hour
+
+type logLevelRequest struct {
+	Level string `json:"level" example:"debug"`
+	// Duration is how long the level lasts, such as "15m". Left out, it lasts until
+	// it is changed or reset.
+	Duration string `json:"duration,omitempty" example:"15m"`
+}
+
+// GetLogLevel returns the current log level
+// @Summary Get the log level
+// @Description Returns the level this instance logs at, the configured one, and when a temporary change ends.
+// @Tags admin
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Success 200 {object} logger.LevelState
+// @Router /api/v1/admin/log-level [get]
+func (h *AdminHandler) GetLogLevel(w http.ResponseWriter, r *http.Request) {
+	response.OK(w, h.LogLevel.State())
+}
+
+// SetLogLevel changes the log level at runtime
+// @Summary Set the log level
+// @Description Changes the level this instance logs at, without a restart. With a duration the configured level comes back once it has passed. Other instances are not changed.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param level body logLevelRequest true "New level: debug, info, warn or error"
+// @Success 200 {object} logger.LevelState
+// @Failure 400 {object} response.Problem "Invalid level or duration"
+// @Router /api/v1/admin/log-level [put]
+func (h *AdminHandler) SetLogLevel(w http.ResponseWriter, r *http.Request) {
+	var req logLevelRequest
+	if appErr := decodeRequest(r, &req); appErr != nil {
+		response.Error(w, appErr)
+		return
+	}
+	if req.Level == "" {
+		response.Error(w, errors.New(errors.ErrMissingField, "level is required", http.StatusBadRequest))
+		return
+	}
+
+	level, err := zapcore.ParseLevel(req.Level)
+	if err != nil || level > zapcore.ErrorLevel {
+		response.Error(w, errors.New(errors.ErrInvalidInput, "level must be debug, info, warn or error", http.StatusBadRequest).
+			WithDetails("level", req.Level))
+		return
+	}
+	var duration time.Duration
+	if req.Duration != "" {
+		duration, err = time.ParseDuration(req.Duration)
+		if err != nil || duration <= 0 || duration > maxLogLevelDuration {
+			response.Error(w, errors.New(errors.ErrInvalidInput, "duration must be positive and at most 24h, such as 15m", http.StatusBadRequest).
+				WithDetails("duration", req.Duration))
+			return
+		}
+	}
+
+	state := h.LogLevel.Set(level, duration)
+	logger.FromContext(r.Context()).Warn("Log level changed",
+		zap.String("level", state.Level),
+		zap.Duration("duration", duration))
+	response.OK(w, state)
+}
+
+// ResetLogLevel goes back to the configured log level
+// @Summary Reset the log level
+// @Description Returns this instance to the level it was configured with, ending any temporary change.
+// @Tags admin
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Success 200 {object} logger.LevelState
+// @Router /api/v1/admin/log-level [delete]
+func (h *AdminHandler) ResetLogLevel(w http.ResponseWriter, r *http.Request) {
+	state := h.LogLevel.Reset()
+	logger.FromContext(r.Context()).Warn("Log level reset", zap.String("level", state.Level))
+	response.OK(w, state)
+}
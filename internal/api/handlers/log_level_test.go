@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/shanwije/wallet-app/pkg/logger"
+)
+
+func TestSetLogLevel(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		want   string
+	}{
+		{"debug", `{"level":"debug"}`, http.StatusOK, `"level":"debug"`},
+		{"for a while", `{"level":"debug","duration":"15m"}`, http.StatusOK, `"until":`},
+		{"unknown level", `{"level":"verbose"}`, http.StatusBadRequest, "INVALID_INPUT"},
+		{"fatal is not allowed", `{"level":"fatal"}`, http.StatusBadRequest, "INVALID_INPUT"},
+		{"invalid duration", `{"level":"debug","duration":"soon"}`, http.StatusBadRequest, "INVALID_INPUT"},
+		{"too long", `{"level":"debug","duration":"48h"}`, http.StatusBadRequest, "INVALID_INPUT"},
+		{"missing level", `{}`, http.StatusBadRequest, "level"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, level, err := logger.New("production")
+			assert.NoError(t, err)
+			handler := &AdminHandler{LogLevel: level}
+			defer level.Reset()
+
+			rr := httptest.NewRecorder()
+			handler.SetLogLevel(rr, httptest.NewRequest(http.MethodPut, "/admin/log-level", strings.NewReader(tt.body)))
+
+			assert.Equal(t, tt.status, rr.Code, rr.Body.String())
+			assert.Contains(t, rr.Body.String(), tt.want)
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/pkg/auth"
+	"github.com/shanwije/wallet-app/pkg/money"
+)
+
+func TestMovementsV2ReturnTheCreatedTransaction(t *testing.T) {
+	wallets := newWalletService(t)
+	wallet := createUserWallet(t, wallets)
+	_, err := wallets.Deposit(context.Background(), wallet.ID, money.New(decimal.NewFromInt(100), money.DefaultCurrency), "")
+	require.NoError(t, err)
+
+	handler := &WalletHandler{WalletService: wallets}
+	router := chi.NewRouter()
+	router.Post("/wallets/{id}/deposit", handler.DepositV2)
+	router.Post("/wallets/{id}/withdraw", handler.WithdrawV2)
+	router.Get("/transactions/{id}", handler.GetTransaction)
+	send := func(operation, key, body string) (*httptest.ResponseRecorder, movementResponse) {
+		req := httptest.NewRequest(http.MethodPost, "/wallets/"+wallet.ID.String()+"/"+operation, strings.NewReader(body))
+		req.Header.Set("Idempotency-Key", key)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		var response movementResponse
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response), rr.Body.String())
+		return rr, response
+	}
+
+	rr, deposit := send("deposit", "deposit-1", `{"amount":25.5}`)
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	assert.Equal(t, "/api/v2/transactions/"+deposit.ID.String(), rr.Header().Get("Location"))
+	assert.Equal(t, models.TransactionTypeDeposit, deposit.Type)
+	assert.Equal(t, wallet.ID, deposit.WalletID)
+	assert.Equal(t, "100", deposit.PreviousBalance.String())
+	assert.Equal(t, "125.5", deposit.NewBalance.String())
+	assert.Equal(t, "125.5", deposit.Wallet.Balance.String())
+
+	rr, withdrawal := send("withdraw", "withdraw-1", `{"amount":20}`)
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	assert.Equal(t, models.TransactionTypeWithdraw, withdrawal.Type)
+	assert.Equal(t, "125.5", withdrawal.PreviousBalance.String())
+	assert.Equal(t, "105.5", withdrawal.NewBalance.String())
+
+	// A retried deposit gets the balances it was posted with and the wallet as it is now
+	rr, replayed := send("deposit", "deposit-1", `{"amount":25.5}`)
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	assert.Equal(t, deposit.ID, replayed.ID)
+	assert.Equal(t, "100", replayed.PreviousBalance.String())
+	assert.Equal(t, "125.5", replayed.NewBalance.String())
+	assert.Equal(t, "105.5", replayed.Wallet.Balance.String())
+
+	// The Location is only readable by the wallet's owner
+	get := func(userID uuid.UUID) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/transactions/"+deposit.ID.String(), nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req.WithContext(auth.WithUserID(req.Context(), userID)))
+		return rr
+	}
+	rr = get(wallet.UserID)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var transaction models.Transaction
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &transaction))
+	assert.Equal(t, deposit.ID, transaction.ID)
+	assert.Equal(t, "25.5", transaction.Amount.String())
+	assert.Equal(t, http.StatusNotFound, get(uuid.New()).Code)
+}
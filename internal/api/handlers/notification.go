@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	stderrors "errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/service"
+	"github.com/shanwije/wallet-app/pkg/errors"
+	"github.com/shanwije/wallet-app/pkg/logger"
+	"github.com/shanwije/wallet-app/pkg/response"
+)
+
+// NotificationHandler serves the transaction alerts users choose to be emailed
+type NotificationHandler struct {
+	NotificationService *service.NotificationService
+}
+
+// notificationPreferencesRequest replaces a user's alert choices; an omitted threshold
+// turns its alert off
+type notificationPreferencesRequest struct {
+	LargeWithdrawalThreshold *decimal.Decimal `json:"large_withdrawal_threshold" swaggertype:"number" example:"1000"`
+	IncomingTransfer         bool             `json:"incoming_transfer" example:"true"`
+}
+
+// NewNotificationHandler creates a new NotificationHandler
+func NewNotificationHandler(notificationService *service.NotificationService) *NotificationHandler {
+	return &NotificationHandler{
+		NotificationService: notificationService,
+	}
+}
+
+// GetPreferences returns the transaction alerts a user is emailed
+// @Summary Get notification preferences
+// @Description Users who never chose their alerts get the server's defaults.
+// @Tags users
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} models.NotificationPreferences
+// @Failure 400 {object} response.Problem "Invalid user ID"
+// @Failure 404 {object} response.Problem "User not found"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v1/users/{id}/notification-preferences [get]
+func (h *NotificationHandler) GetPreferences(w http.ResponseWriter, r *http.Request) {
+	userIDStr := chi.URLParam(r, "id")
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		response.ErrorMessage(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	preferences, err := h.NotificationService.GetPreferences(r.Context(), userID)
+	if err != nil {
+		respondWithUserError(w, r, err, userIDStr)
+		return
+	}
+
+	response.OK(w, preferences)
+}
+
+// SetPreferences replaces the transaction alerts a user is emailed
+// @Summary Set notification preferences
+// @Description Alerts go to the user's email address: withdrawals of at least large_withdrawal_threshold, transfers in when incoming_transfer is true, and the low balance alerts set on their wallet.
+// @Description The threshold is in the wallet's currency; omitting it turns its alert off.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID"
+// @Param preferences body notificationPreferencesRequest true "Alerts to send"
+// @Success 200 {object} models.NotificationPreferences
+// @Failure 400 {object} response.Problem "Invalid user ID or thresholds"
+// @Failure 404 {object} response.Problem "User not found"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v1/users/{id}/notification-preferences [put]
+func (h *NotificationHandler) SetPreferences(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+	userIDStr := chi.URLParam(r, "id")
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		response.ErrorMessage(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	var req notificationPreferencesRequest
+	if appErr := decodeRequest(r, &req); appErr != nil {
+		response.Error(w, appErr)
+		return
+	}
+
+	preferences, err := h.NotificationService.SetPreferences(r.Context(), &models.NotificationPreferences{
+		UserID:                   userID,
+		LargeWithdrawalThreshold: req.LargeWithdrawalThreshold,
+		IncomingTransfer:         req.IncomingTransfer,
+	})
+	if err != nil {
+		if stderrors.Is(err, service.ErrInvalidNotificationThreshold) {
+			response.Error(w, errors.InvalidInput(err.Error()))
+			return
+		}
+		respondWithUserError(w, r, err, userIDStr)
+		return
+	}
+
+	log.Info("Notification preferences changed", zap.String("user_id", userIDStr))
+	response.OK(w, preferences)
+}
@@ -0,0 +1,398 @@
+package handlers
+
+import (
+	stderrors "errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/service"
+	"github.com/shanwije/wallet-app/pkg/auth"
+	"github.com/shanwije/wallet-app/pkg/errors"
+	"github.com/shanwije/wallet-app/pkg/logger"
+	"github.com/shanwije/wallet-app/pkg/money"
+	"github.com/shanwije/wallet-app/pkg/response"
+)
+
+// PaymentHandler serves payments to merchant accounts, their refunds and the
+// merchants' settlement summaries
+type PaymentHandler struct {
+	PaymentService *service.PaymentService
+	// PINs asks for the payer's transaction PIN on payments over its threshold; nil
+	// turns the check off
+	PINs *service.TransactionPINService
+}
+
+type merchantPaymentRequest struct {
+	WalletID         string          `json:"wallet_id" validate:"required"`
+	MerchantWalletID string          `json:"merchant_wallet_id" validate:"required"`
+	Amount           decimal.Decimal `json:"amount" swaggertype:"string" validate:"required" example:"24.99"`
+	Currency         string          `json:"currency,omitempty"`
+	OrderReference   string          `json:"order_reference" validate:"required" example:"ORD-10042"`
+}
+
+type refundRequest struct {
+	// Amount to refund; what is left of the payment when omitted
+	Amount   *decimal.Decimal `json:"amount,omitempty" swaggertype:"string" example:"5.00"`
+	Currency string           `json:"currency,omitempty"`
+	Reason   string           `json:"reason,omitempty" example:"Item out of stock"`
+}
+
+type merchantAccountRequest struct {
+	WalletID string `json:"wallet_id" validate:"required"`
+	Name     string `json:"name" validate:"required" example:"Corner Books"`
+}
+
+// NewPaymentHandler creates a new PaymentHandler
+func NewPaymentHandler(paymentService *service.PaymentService) *PaymentHandler {
+	return &PaymentHandler{
+		PaymentService: paymentService,
+	}
+}
+
+// paymentAppError maps the failures of payments and refunds that have their own error
+// code; it returns nil for the rest
+func paymentAppError(err error, paymentID string) *errors.AppError {
+	switch {
+	case stderrors.Is(err, service.ErrPaymentNotFound):
+		return errors.New(errors.ErrPaymentNotFound, "Payment not found", http.StatusNotFound).
+			WithDetails("payment_id", paymentID)
+	case stderrors.Is(err, service.ErrMerchantNotFound):
+		return errors.New(errors.ErrMerchantNotFound, err.Error(), http.StatusNotFound)
+	case stderrors.Is(err, service.ErrOrderAlreadyPaid):
+		return errors.New(errors.ErrOrderAlreadyPaid, err.Error(), http.StatusConflict)
+	case stderrors.Is(err, service.ErrMerchantExists):
+		return errors.Conflict(err.Error())
+	case stderrors.Is(err, service.ErrInvalidOrderReference),
+		stderrors.Is(err, service.ErrInvalidRefundAmount),
+		stderrors.Is(err, service.ErrInvalidSettlementPeriod),
+		stderrors.Is(err, service.ErrInvalidMerchantName),
+		stderrors.Is(err, money.ErrCurrencyMismatch):
+		return errors.InvalidInput(err.Error())
+	default:
+		return movementAppError(err)
+	}
+}
+
+// Pay pays a merchant for an order
+// @Summary Pay a merchant
+// @Description Sends the amount, in the merchant's currency, from the wallet to the merchant
+// @Description account for the order. Each order is paid once. When auth is enabled the
+// @Description wallet paying must be the caller's.
+// @Tags payments
+// @Accept json
+// @Produce json
+// @Param payment body merchantPaymentRequest true "Payer, merchant, amount and order reference"
+// @Param Idempotency-Key header string false "Makes retries safe: a repeat with the same key and body gets the first response"
+// @Success 201 {object} models.Payment
+// @Param X-Transaction-PIN header string false "Owner's transaction PIN, needed over the PIN threshold once they have set one"
+// @Failure 400 {object} response.Problem "Invalid wallet IDs, amount or order reference, or insufficient funds"
+// @Failure 403 {object} response.Problem "The paying wallet is not the caller's, or transaction PIN required or incorrect"
+// @Failure 404 {object} response.Problem "Wallet or merchant account not found"
+// @Failure 409 {object} response.Problem "Order already paid, or a wallet is frozen or closed"
+// @Failure 423 {object} response.Problem "Transaction PIN locked after too many incorrect attempts"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v1/payments [post]
+func (h *PaymentHandler) Pay(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.FromContext(ctx)
+
+	var req merchantPaymentRequest
+	if appErr := decodeRequest(r, &req); appErr != nil {
+		response.Error(w, appErr)
+		return
+	}
+	if appErr := validateRequest(req); appErr != nil {
+		response.Error(w, appErr)
+		return
+	}
+	payerWalletID, err := uuid.Parse(req.WalletID)
+	if err != nil {
+		response.ErrorMessage(w, http.StatusBadRequest, "Invalid wallet ID")
+		return
+	}
+	merchantWalletID, err := uuid.Parse(req.MerchantWalletID)
+	if err != nil {
+		response.ErrorMessage(w, http.StatusBadRequest, "Invalid merchant wallet ID")
+		return
+	}
+	amount, appErr := parseAmount(req.Amount, req.Currency)
+	if appErr != nil {
+		response.Error(w, appErr)
+		return
+	}
+
+	if userID, ok := auth.UserIDFromContext(ctx); ok {
+		owner, err := h.ownsWallet(r, payerWalletID, userID)
+		if err != nil {
+			response.Error(w, walletAppError(err, req.WalletID))
+			return
+		}
+		if !owner {
+			response.Error(w, errors.Forbidden("You do not have access to this wallet"))
+			return
+		}
+	}
+	if appErr := authorizePIN(r, h.PINs, payerWalletID, amount); appErr != nil {
+		response.Error(w, appErr)
+		return
+	}
+
+	payment, err := h.PaymentService.Pay(ctx, payerWalletID, merchantWalletID, amount, req.OrderReference)
+	if err != nil {
+		log.Error("Payment failed", zap.Error(err),
+			zap.String("wallet_id", req.WalletID),
+			zap.String("merchant_wallet_id", req.MerchantWalletID))
+		if appErr := paymentAppError(err, ""); appErr != nil {
+			response.Error(w, appErr)
+			return
+		}
+		response.Error(w, errors.InternalError(err))
+		return
+	}
+
+	log.Info("Merchant paid",
+		zap.String("payment_id", payment.ID.String()),
+		zap.String("merchant_wallet_id", req.MerchantWalletID),
+		zap.String("order_reference", payment.OrderReference))
+
+	response.Created(w, "/api/v1/payments/"+payment.ID.String(), payment)
+}
+
+// GetPayment returns a payment with its refunds
+// @Summary Get a payment
+// @Description When auth is enabled only the owners of the paying and the merchant wallets can see it.
+// @Tags payments
+// @Produce json
+// @Param id path string true "Payment ID"
+// @Success 200 {object} models.Payment
+// @Failure 400 {object} response.Problem "Invalid payment ID"
+// @Failure 404 {object} response.Problem "Payment not found"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v1/payments/{id} [get]
+func (h *PaymentHandler) GetPayment(w http.ResponseWriter, r *http.Request) {
+	paymentIDStr := chi.URLParam(r, "id")
+	paymentID, err := uuid.Parse(paymentIDStr)
+	if err != nil {
+		response.ErrorMessage(w, http.StatusBadRequest, "Invalid payment ID")
+		return
+	}
+
+	payment, appErr := h.authorizedPayment(r, paymentID, paymentIDStr, false)
+	if appErr != nil {
+		response.Error(w, appErr)
+		return
+	}
+
+	response.OK(w, payment)
+}
+
+// Refund pays part or all of a payment back to the payer
+// @Summary Refund a payment
+// @Description Sends the amount, or all that is left of the payment when it is omitted, from the
+// @Description merchant back to the payer. A payment can be refunded in several parts up to its
+// @Description amount. When auth is enabled only the owner of the merchant wallet can refund it.
+// @Tags payments
+// @Accept json
+// @Produce json
+// @Param id path string true "Payment ID"
+// @Param refund body refundRequest false "Partial amount and reason"
+// @Param Idempotency-Key header string false "Makes retries safe: a repeat with the same key and body gets the first response"
+// @Success 201 {object} models.PaymentRefund
+// @Failure 400 {object} response.Problem "Invalid payment ID or amount, or the merchant cannot afford the refund"
+// @Failure 403 {object} response.Problem "Only the merchant can refund a payment"
+// @Failure 404 {object} response.Problem "Payment not found"
+// @Failure 409 {object} response.Problem "A wallet is frozen or closed"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v1/payments/{id}/refunds [post]
+func (h *PaymentHandler) Refund(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.FromContext(ctx)
+	paymentIDStr := chi.URLParam(r, "id")
+	paymentID, err := uuid.Parse(paymentIDStr)
+	if err != nil {
+		response.ErrorMessage(w, http.StatusBadRequest, "Invalid payment ID")
+		return
+	}
+
+	var req refundRequest
+	if appErr := decodeOptionalRequest(r, &req); appErr != nil {
+		response.Error(w, appErr)
+		return
+	}
+	var amount *money.Money
+	if req.Amount != nil {
+		parsed, appErr := parseAmount(*req.Amount, req.Currency)
+		if appErr != nil {
+			response.Error(w, appErr)
+			return
+		}
+		amount = &parsed
+	}
+
+	if _, appErr := h.authorizedPayment(r, paymentID, paymentIDStr, true); appErr != nil {
+		response.Error(w, appErr)
+		return
+	}
+
+	refund, err := h.PaymentService.Refund(ctx, paymentID, amount, req.Reason)
+	if err != nil {
+		log.Error("Refund failed", zap.Error(err), zap.String("payment_id", paymentIDStr))
+		if appErr := paymentAppError(err, paymentIDStr); appErr != nil {
+			response.Error(w, appErr)
+			return
+		}
+		response.Error(w, errors.InternalError(err))
+		return
+	}
+
+	log.Info("Payment refunded",
+		zap.String("payment_id", paymentIDStr),
+		zap.String("refund_id", refund.ID.String()),
+		zap.String("amount", refund.Amount.String()))
+
+	response.Created(w, "", refund)
+}
+
+// GetSettlement totals what a merchant wallet took over a period
+// @Summary Get a merchant settlement summary
+// @Description Counts and sums the payments the merchant wallet took and the refunds it made
+// @Description in the period, and the net of the two. Refunds count in the period they were made.
+// @Tags payments
+// @Produce json
+// @Param id path string true "Merchant wallet ID"
+// @Param from query string false "Period start as YYYY-MM-DD or RFC3339; defaults to when the wallet became a merchant account"
+// @Param to query string false "Period end as YYYY-MM-DD (inclusive) or RFC3339 (exclusive); defaults to now"
+// @Success 200 {object} models.MerchantSettlement
+// @Failure 400 {object} response.Problem "Invalid wallet ID or period"
+// @Failure 404 {object} response.Problem "Wallet or merchant account not found"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v1/wallets/{id}/settlement [get]
+func (h *PaymentHandler) GetSettlement(w http.ResponseWriter, r *http.Request) {
+	walletIDStr := chi.URLParam(r, "id")
+	walletID, err := uuid.Parse(walletIDStr)
+	if err != nil {
+		response.ErrorMessage(w, http.StatusBadRequest, "Invalid wallet ID")
+		return
+	}
+
+	query := r.URL.Query()
+	from, err := parseStatementTime(query.Get("from"), false)
+	if err != nil {
+		response.Error(w, errors.InvalidInput("Invalid from date").WithDetails("from", query.Get("from")))
+		return
+	}
+	to, err := parseStatementTime(query.Get("to"), true)
+	if err != nil {
+		response.Error(w, errors.InvalidInput("Invalid to date").WithDetails("to", query.Get("to")))
+		return
+	}
+
+	settlement, err := h.PaymentService.Settlement(r.Context(), walletID, from, to)
+	if err != nil {
+		if appErr := paymentAppError(err, ""); appErr != nil {
+			response.Error(w, appErr)
+			return
+		}
+		response.Error(w, walletAppError(err, walletIDStr))
+		return
+	}
+
+	response.OK(w, settlement)
+}
+
+// RegisterMerchant makes a wallet a merchant account
+// @Summary Register a merchant account
+// @Description Lets the wallet take payments for orders; payers see the name in their history.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param merchant body merchantAccountRequest true "Wallet and merchant name"
+// @Success 201 {object} models.MerchantAccount
+// @Failure 400 {object} response.Problem "Invalid wallet ID or name"
+// @Failure 404 {object} response.Problem "Wallet not found"
+// @Failure 409 {object} response.Problem "The wallet already is a merchant account"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v1/admin/merchants [post]
+func (h *PaymentHandler) RegisterMerchant(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+
+	var req merchantAccountRequest
+	if appErr := decodeRequest(r, &req); appErr != nil {
+		response.Error(w, appErr)
+		return
+	}
+	if appErr := validateRequest(req); appErr != nil {
+		response.Error(w, appErr)
+		return
+	}
+	walletID, err := uuid.Parse(req.WalletID)
+	if err != nil {
+		response.ErrorMessage(w, http.StatusBadRequest, "Invalid wallet ID")
+		return
+	}
+
+	account, err := h.PaymentService.RegisterMerchant(r.Context(), walletID, req.Name)
+	if err != nil {
+		if appErr := paymentAppError(err, ""); appErr != nil {
+			response.Error(w, appErr)
+			return
+		}
+		response.Error(w, walletAppError(err, req.WalletID))
+		return
+	}
+
+	log.Info("Merchant account registered", zap.String("wallet_id", req.WalletID), zap.String("name", account.Name))
+	response.Created(w, "", account)
+}
+
+// authorizedPayment loads the payment and, when auth is enabled, checks the user may
+// see it, or refund it when refund is set: only the merchant can. Payments the user has
+// no part in are reported as missing rather than forbidden, so payment IDs cannot be
+// probed.
+func (h *PaymentHandler) authorizedPayment(r *http.Request, paymentID uuid.UUID, paymentIDStr string, refund bool) (*models.Payment, *errors.AppError) {
+	payment, err := h.PaymentService.GetPayment(r.Context(), paymentID)
+	if err != nil {
+		if appErr := paymentAppError(err, paymentIDStr); appErr != nil {
+			return nil, appErr
+		}
+		return nil, errors.InternalError(err)
+	}
+
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		return payment, nil
+	}
+	merchant, err := h.ownsWallet(r, payment.MerchantWalletID, userID)
+	if err != nil && !stderrors.Is(err, service.ErrWalletNotFound) {
+		return nil, errors.InternalError(err)
+	}
+	if merchant {
+		return payment, nil
+	}
+	payer, err := h.ownsWallet(r, payment.PayerWalletID, userID)
+	if err != nil && !stderrors.Is(err, service.ErrWalletNotFound) {
+		return nil, errors.InternalError(err)
+	}
+	if !payer {
+		return nil, paymentAppError(service.ErrPaymentNotFound, paymentIDStr)
+	}
+	if refund {
+		return nil, errors.Forbidden("Only the merchant can refund a payment")
+	}
+	return payment, nil
+}
+
+// ownsWallet reports whether the user owns the wallet
+func (h *PaymentHandler) ownsWallet(r *http.Request, walletID, userID uuid.UUID) (bool, error) {
+	wallet, err := h.PaymentService.Wallets.GetBalance(r.Context(), walletID)
+	if err != nil {
+		return false, err
+	}
+	return wallet.UserID == userID, nil
+}
@@ -0,0 +1,256 @@
+package handlers
+
+import (
+	stderrors "errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+
+	"github.com/shanwije/wallet-app/internal/service"
+	"github.com/shanwije/wallet-app/pkg/errors"
+	"github.com/shanwije/wallet-app/pkg/logger"
+	"github.com/shanwije/wallet-app/pkg/response"
+)
+
+// PaymentRequestHandler serves the payment requests a wallet sends and receives
+type PaymentRequestHandler struct {
+	PaymentRequestService *service.PaymentRequestService
+	// PINs asks for the payer's transaction PIN when accepting requests over its
+	// threshold; nil turns the check off
+	PINs *service.TransactionPINService
+}
+
+type paymentRequestRequest struct {
+	PayerWalletID string          `json:"payer_wallet_id"`
+	Amount        decimal.Decimal `json:"amount" swaggertype:"string" example:"25.00"`
+	Currency      string          `json:"currency,omitempty"`
+	Description   string          `json:"description,omitempty"`
+}
+
+// NewPaymentRequestHandler creates a new PaymentRequestHandler
+func NewPaymentRequestHandler(paymentRequestService *service.PaymentRequestService) *PaymentRequestHandler {
+	return &PaymentRequestHandler{
+		PaymentRequestService: paymentRequestService,
+	}
+}
+
+// paymentRequestAppError maps the failures of answering a payment request that have
+// their own error code; it returns nil for the rest
+func paymentRequestAppError(err error, requestID string) *errors.AppError {
+	switch {
+	case stderrors.Is(err, service.ErrPaymentRequestNotFound):
+		return errors.New(errors.ErrPaymentRequestNotFound, err.Error(), http.StatusNotFound).
+			WithDetails("payment_request_id", requestID)
+	case stderrors.Is(err, service.ErrPaymentRequestNotPending):
+		return errors.Conflict(err.Error())
+	default:
+		return movementAppError(err)
+	}
+}
+
+// Create asks another wallet to pay this one
+// @Summary Request a payment
+// @Description The payer sees the request in its pending list and can accept or decline it
+// @Tags payment-requests
+// @Accept json
+// @Produce json
+// @Param id path string true "Requesting wallet ID"
+// @Param request body paymentRequestRequest true "Payment request details"
+// @Param Idempotency-Key header string false "Makes retries safe: a repeat with the same key and body gets the first response"
+// @Success 201 {object} models.PaymentRequest
+// @Failure 400 {object} response.Problem "Invalid wallet ID or request body"
+// @Failure 404 {object} response.Problem "Wallet not found"
+// @Failure 409 {object} response.Problem "Idempotency-Key reused with a different request body"
+// @Failure 422 {object} response.Problem "Amount over the confirmation threshold"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v1/wallets/{id}/payment-requests [post]
+func (h *PaymentRequestHandler) Create(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+	walletIDStr := chi.URLParam(r, "id")
+	requesterWalletID, err := uuid.Parse(walletIDStr)
+	if err != nil {
+		response.ErrorMessage(w, http.StatusBadRequest, "Invalid wallet ID")
+		return
+	}
+
+	var req paymentRequestRequest
+	if appErr := decodeRequest(r, &req); appErr != nil {
+		response.Error(w, appErr)
+		return
+	}
+
+	payerWalletID, err := uuid.Parse(req.PayerWalletID)
+	if err != nil {
+		response.ErrorMessage(w, http.StatusBadRequest, "Invalid payer wallet ID")
+		return
+	}
+
+	amount, appErr := parseAmount(req.Amount, req.Currency)
+	if appErr != nil {
+		response.Error(w, appErr)
+		return
+	}
+
+	request, err := h.PaymentRequestService.Request(r.Context(), requesterWalletID, payerWalletID, amount, req.Description)
+	if err != nil {
+		log.Error("Failed to request payment", zap.Error(err),
+			zap.String("wallet_id", walletIDStr),
+			zap.String("payer_wallet_id", req.PayerWalletID))
+		if appErr := movementAppError(err); appErr != nil {
+			response.Error(w, appErr)
+			return
+		}
+		response.ErrorMessage(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	log.Info("Payment requested",
+		zap.String("payment_request_id", request.ID.String()),
+		zap.String("wallet_id", walletIDStr),
+		zap.String("payer_wallet_id", req.PayerWalletID))
+
+	response.Created(w, "", request)
+}
+
+// ListPending returns the payment requests waiting for the wallet to answer
+// @Summary List pending payment requests
+// @Tags payment-requests
+// @Produce json
+// @Param id path string true "Paying wallet ID"
+// @Success 200 {array} models.PaymentRequest
+// @Failure 400 {object} response.Problem "Invalid wallet ID"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v1/wallets/{id}/payment-requests [get]
+func (h *PaymentRequestHandler) ListPending(w http.ResponseWriter, r *http.Request) {
+	walletID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.ErrorMessage(w, http.StatusBadRequest, "Invalid wallet ID")
+		return
+	}
+
+	requests, err := h.PaymentRequestService.ListPending(r.Context(), walletID)
+	if err != nil {
+		response.Error(w, errors.InternalError(err))
+		return
+	}
+
+	response.OK(w, requests)
+}
+
+// Accept pays a pending payment request
+// @Summary Accept a payment request
+// @Description Transfers the requested amount to the requesting wallet
+// @Tags payment-requests
+// @Produce json
+// @Param id path string true "Paying wallet ID"
+// @Param requestID path string true "Payment request ID"
+// @Param Idempotency-Key header string false "Makes retries safe: a repeat with the same key and body gets the first response"
+// @Success 200 {object} models.PaymentRequest
+// @Failure 400 {object} response.Problem "Invalid wallet ID or payment request ID, or insufficient funds"
+// @Param X-Transaction-PIN header string false "Owner's transaction PIN, needed over the PIN threshold once they have set one"
+// @Failure 403 {object} response.Problem "Transaction PIN required or incorrect"
+// @Failure 404 {object} response.Problem "Payment request not found"
+// @Failure 409 {object} response.Problem "Payment request is not pending, a wallet is frozen or closed, or Idempotency-Key reused with a different request body"
+// @Failure 422 {object} response.Problem "Wallet limit exceeded, or amount over the confirmation threshold"
+// @Failure 423 {object} response.Problem "Transaction PIN locked after too many incorrect attempts"
+// @Failure 429 {object} response.Problem "Rate limit exceeded"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v1/wallets/{id}/payment-requests/{requestID}/accept [post]
+func (h *PaymentRequestHandler) Accept(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+	walletID, requestID, ok := parsePaymentRequestPath(w, r)
+	if !ok {
+		return
+	}
+
+	// The PIN is asked for by the amount requested. A request that is not pending for
+	// the wallet is left for Accept to report.
+	if h.PINs != nil {
+		pending, err := h.PaymentRequestService.ListPending(r.Context(), walletID)
+		if err != nil {
+			response.Error(w, errors.InternalError(err))
+			return
+		}
+		for _, request := range pending {
+			if request.ID != requestID {
+				continue
+			}
+			if appErr := authorizePIN(r, h.PINs, walletID, request.Funds()); appErr != nil {
+				response.Error(w, appErr)
+				return
+			}
+		}
+	}
+
+	request, err := h.PaymentRequestService.Accept(r.Context(), walletID, requestID)
+	if err != nil {
+		log.Error("Failed to accept payment request", zap.Error(err), zap.String("payment_request_id", requestID.String()))
+		if appErr := paymentRequestAppError(err, requestID.String()); appErr != nil {
+			response.Error(w, appErr)
+			return
+		}
+		response.ErrorMessage(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	log.Info("Payment request accepted",
+		zap.String("payment_request_id", requestID.String()),
+		zap.String("amount", request.Funds().String()))
+
+	response.OK(w, request)
+}
+
+// Decline turns down a pending payment request
+// @Summary Decline a payment request
+// @Tags payment-requests
+// @Produce json
+// @Param id path string true "Paying wallet ID"
+// @Param requestID path string true "Payment request ID"
+// @Param Idempotency-Key header string false "Makes retries safe: a repeat with the same key and body gets the first response"
+// @Success 200 {object} models.PaymentRequest
+// @Failure 400 {object} response.Problem "Invalid wallet ID or payment request ID"
+// @Failure 404 {object} response.Problem "Payment request not found"
+// @Failure 409 {object} response.Problem "Payment request is not pending, or Idempotency-Key reused with a different request body"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v1/wallets/{id}/payment-requests/{requestID}/decline [post]
+func (h *PaymentRequestHandler) Decline(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+	walletID, requestID, ok := parsePaymentRequestPath(w, r)
+	if !ok {
+		return
+	}
+
+	request, err := h.PaymentRequestService.Decline(r.Context(), walletID, requestID)
+	if err != nil {
+		log.Error("Failed to decline payment request", zap.Error(err), zap.String("payment_request_id", requestID.String()))
+		if appErr := paymentRequestAppError(err, requestID.String()); appErr != nil {
+			response.Error(w, appErr)
+			return
+		}
+		response.Error(w, errors.InternalError(err))
+		return
+	}
+
+	log.Info("Payment request declined", zap.String("payment_request_id", requestID.String()))
+
+	response.OK(w, request)
+}
+
+// parsePaymentRequestPath reads the wallet and payment request IDs from the URL,
+// responding with 400 if either is invalid
+func parsePaymentRequestPath(w http.ResponseWriter, r *http.Request) (uuid.UUID, uuid.UUID, bool) {
+	walletID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.ErrorMessage(w, http.StatusBadRequest, "Invalid wallet ID")
+		return uuid.Nil, uuid.Nil, false
+	}
+	requestID, err := uuid.Parse(chi.URLParam(r, "requestID"))
+	if err != nil {
+		response.ErrorMessage(w, http.StatusBadRequest, "Invalid payment request ID")
+		return uuid.Nil, uuid.Nil, false
+	}
+	return walletID, requestID, true
+}
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository/sqlite"
+	"github.com/shanwije/wallet-app/internal/service"
+	"github.com/shanwije/wallet-app/pkg/money"
+)
+
+func TestMerchantPaymentsAreRefundedAndSettled(t *testing.T) {
+	conn := newTestDB(t)
+	wallets := walletServiceOn(conn)
+	payments := &service.PaymentService{Repo: sqlite.NewPaymentRepository(conn), Wallets: wallets}
+	ctx := context.Background()
+	payer, shop, other := createUserWallet(t, wallets), createUserWallet(t, wallets), createUserWallet(t, wallets)
+	_, err := wallets.Deposit(ctx, payer.ID, money.New(decimal.NewFromInt(100), money.DefaultCurrency), "")
+	require.NoError(t, err)
+
+	handler := NewPaymentHandler(payments)
+	router := chi.NewRouter()
+	router.Post("/admin/merchants", handler.RegisterMerchant)
+	router.Post("/payments", handler.Pay)
+	router.Get("/payments/{id}", handler.GetPayment)
+	router.Post("/payments/{id}/refunds", handler.Refund)
+	router.Get("/wallets/{id}/settlement", handler.GetSettlement)
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rr
+	}
+	pay := func(merchant *models.Wallet, amount, order string) *httptest.ResponseRecorder {
+		return send(http.MethodPost, "/payments", `{"wallet_id":"`+payer.ID.String()+`","merchant_wallet_id":"`+merchant.ID.String()+
+			`","amount":`+amount+`,"order_reference":"`+order+`"}`)
+	}
+
+	rr := send(http.MethodPost, "/admin/merchants", `{"wallet_id":"`+shop.ID.String()+`","name":"Corner Books"}`)
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	assert.Equal(t, http.StatusConflict, send(http.MethodPost, "/admin/merchants", `{"wallet_id":"`+shop.ID.String()+`","name":"Corner Books"}`).Code)
+
+	// Only merchant accounts take payments, and each order is paid once
+	assert.Equal(t, http.StatusNotFound, pay(other, "10", "ORD-1").Code)
+	rr = pay(shop, "40", "ORD-1")
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	var payment models.Payment
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &payment))
+	assert.Equal(t, "/api/v1/payments/"+payment.ID.String(), rr.Header().Get("Location"))
+	assert.Equal(t, models.PaymentStatusCompleted, payment.Status)
+	assert.Equal(t, http.StatusConflict, pay(shop, "40", "ORD-1").Code)
+	require.Equal(t, http.StatusCreated, pay(shop, "20", "ORD-2").Code)
+
+	history, err := wallets.GetTransactionHistory(ctx, payer.ID)
+	require.NoError(t, err)
+	require.NotNil(t, history[1].Description)
+	assert.Equal(t, "Payment to Corner Books for order ORD-1", *history[1].Description)
+
+	// Refunds pay the payment back in parts, up to what was paid
+	refund := func(body string) *httptest.ResponseRecorder {
+		return send(http.MethodPost, "/payments/"+payment.ID.String()+"/refunds", body)
+	}
+	require.Equal(t, http.StatusCreated, refund(`{"amount":15,"reason":"Damaged cover"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, refund(`{"amount":30}`).Code)
+	rr = refund("")
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	var rest models.PaymentRefund
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &rest))
+	assert.Equal(t, "25", rest.Amount.String())
+	assert.Equal(t, http.StatusBadRequest, refund("").Code)
+
+	rr = send(http.MethodGet, "/payments/"+payment.ID.String(), "")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &payment))
+	assert.Equal(t, models.PaymentStatusRefunded, payment.Status)
+	assert.Equal(t, "40", payment.RefundedAmount.String())
+	require.Len(t, payment.Refunds, 2)
+	assert.Equal(t, "Damaged cover", *payment.Refunds[0].Reason)
+
+	for wallet, balance := range map[*models.Wallet]string{payer: "80", shop: "20"} {
+		current, err := wallets.GetBalance(ctx, wallet.ID)
+		require.NoError(t, err)
+		assert.Equal(t, balance, current.Balance.String())
+	}
+
+	rr = send(http.MethodGet, "/wallets/"+shop.ID.String()+"/settlement", "")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var settlement models.MerchantSettlement
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &settlement))
+	assert.Equal(t, int64(2), settlement.PaymentCount)
+	assert.Equal(t, "60", settlement.GrossAmount.String())
+	assert.Equal(t, int64(2), settlement.RefundCount)
+	assert.Equal(t, "40", settlement.RefundedAmount.String())
+	assert.Equal(t, "20", settlement.NetAmount.String())
+	assert.Equal(t, http.StatusNotFound, send(http.MethodGet, "/wallets/"+other.ID.String()+"/settlement", "").Code)
+	assert.Equal(t, http.StatusBadRequest, send(http.MethodGet, "/wallets/"+shop.ID.String()+"/settlement?from=2024-07-02&to=2024-07-01", "").Code)
+}
+
+func TestRefundedPaymentsCannotBeReversedOrDisputed(t *testing.T) {
+	conn := newTestDB(t)
+	wallets := walletServiceOn(conn)
+	wallets.HoldRepo = sqlite.NewHoldRepository(conn)
+	wallets.Payments = sqlite.NewPaymentRepository(conn)
+	payments := &service.PaymentService{Repo: wallets.Payments, Wallets: wallets}
+	disputes := &service.DisputeService{Repo: sqlite.NewDisputeRepository(conn), Wallets: wallets}
+	ctx := context.Background()
+	payer, shop := createUserWallet(t, wallets), createUserWallet(t, wallets)
+	_, err := wallets.Deposit(ctx, payer.ID, money.New(decimal.NewFromInt(100), money.DefaultCurrency), "")
+	require.NoError(t, err)
+	_, err = payments.RegisterMerchant(ctx, shop.ID, "Corner Books")
+	require.NoError(t, err)
+	pay := func(amount int64, order string) (*models.Payment, *models.Transaction) {
+		payment, err := payments.Pay(ctx, payer.ID, shop.ID, money.New(decimal.NewFromInt(amount), money.DefaultCurrency), order)
+		require.NoError(t, err)
+		history, err := wallets.GetTransactionHistory(ctx, payer.ID)
+		require.NoError(t, err)
+		return payment, history[0]
+	}
+	refunded, refundedTransaction := pay(40, "ORD-1")
+	_, paidTransaction := pay(20, "ORD-2")
+	partial := money.New(decimal.NewFromInt(5), money.DefaultCurrency)
+	_, err = payments.Refund(ctx, refunded.ID, &partial, "")
+	require.NoError(t, err)
+
+	router := chi.NewRouter()
+	router.Post("/transactions/{id}/reverse", NewWalletHandler(wallets).ReverseTransaction)
+	router.Post("/transactions/{id}/disputes", NewDisputeHandler(disputes).OpenDispute)
+	send := func(path, body string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)))
+		return rr
+	}
+
+	// Reversing or disputing the payment would pay the refunded part back twice
+	rr := send("/transactions/"+refundedTransaction.ID.String()+"/disputes", `{"reason":"Goods never arrived"}`)
+	assert.Equal(t, http.StatusConflict, rr.Code, rr.Body.String())
+	assert.Contains(t, rr.Body.String(), "ALREADY_REFUNDED")
+	rr = send("/transactions/"+refundedTransaction.ID.String()+"/reverse", "")
+	assert.Equal(t, http.StatusConflict, rr.Code, rr.Body.String())
+	assert.Contains(t, rr.Body.String(), "ALREADY_REFUNDED")
+
+	// A payment without refunds is reversed as any other transfer
+	rr = send("/transactions/"+paidTransaction.ID.String()+"/reverse", "")
+	assert.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+
+	current, err := wallets.GetBalance(ctx, payer.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "65", current.Balance.String())
+}
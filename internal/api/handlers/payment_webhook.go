@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"encoding/json"
+	stderrors "errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/service"
+	"github.com/shanwije/wallet-app/pkg/clock"
+	"github.com/shanwije/wallet-app/pkg/errors"
+	"github.com/shanwije/wallet-app/pkg/logger"
+	"github.com/shanwije/wallet-app/pkg/money"
+	"github.com/shanwije/wallet-app/pkg/response"
+	"github.com/shanwije/wallet-app/pkg/webhooksig"
+)
+
+// PaymentSignatureHeader carries the payment provider's signature of a webhook delivery
+const PaymentSignatureHeader = "X-Payment-Signature"
+
+// paymentEventStatuses maps the webhook's event types to the payment status they report;
+// other event types are acknowledged and ignored
+var paymentEventStatuses = map[string]string{
+	"payment.pending":   models.ProviderPaymentStatusPending,
+	"payment.succeeded": models.ProviderPaymentStatusSucceeded,
+	"payment.failed":    models.ProviderPaymentStatusFailed,
+}
+
+// PaymentWebhookHandler receives the payment provider's webhook, depositing the
+// payments it took into wallets
+type PaymentWebhookHandler struct {
+	ProviderPayments *service.ProviderPaymentService
+	// Secret signs every delivery, and Tolerance is how old its signature may be
+	Secret    string
+	Tolerance time.Duration
+	// Clock is optional; the system clock is used when nil
+	Clock clock.Clock
+}
+
+// paymentEvent is one webhook delivery
+type paymentEvent struct {
+	ID   string           `json:"id" example:"evt_1PZ3kQ"`
+	Type string           `json:"type" example:"payment.succeeded"`
+	Data paymentEventData `json:"data"`
+}
+
+// paymentEventData is the payment the event is about
+type paymentEventData struct {
+	PaymentID     string          `json:"payment_id" example:"pay_3MtwBw"`
+	WalletID      uuid.UUID       `json:"wallet_id"`
+	Amount        decimal.Decimal `json:"amount" swaggertype:"string" example:"25.00"`
+	Currency      string          `json:"currency" example:"USD"`
+	FailureReason string          `json:"failure_reason,omitempty" example:"card_declined"`
+}
+
+// NewPaymentWebhookHandler creates a new PaymentWebhookHandler
+func NewPaymentWebhookHandler(providerPayments *service.ProviderPaymentService, secret string, tolerance time.Duration) *PaymentWebhookHandler {
+	return &PaymentWebhookHandler{
+		ProviderPayments: providerPayments,
+		Secret:           secret,
+		Tolerance:        tolerance,
+	}
+}
+
+// Receive applies a webhook event about one of the provider's payments
+// @Summary Receive a payment provider event
+// @Description Called by the payment provider, not by clients. The body must be signed in the X-Payment-Signature header as t=<unix time>,v1=<hex HMAC-SHA256 of "<unix time>.<body>"> with the webhook secret, within the allowed tolerance. payment.succeeded deposits the payment into its wallet once, however often and in whatever order the events arrive, without a fee or the wallet's limits; payment.pending and payment.failed only record its status. Other event types are acknowledged and ignored. A 2xx answer tells the provider to stop delivering the event; any other makes it try again later.
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param X-Payment-Signature header string true "Signature of the body"
+// @Param event body paymentEvent true "Payment event"
+// @Success 200 {object} models.ProviderPayment
+// @Success 204 "Event type ignored"
+// @Failure 400 {object} response.Problem "Malformed event, or invalid payment ID, wallet or amount"
+// @Failure 401 {object} response.Problem "Missing, invalid or expired signature"
+// @Failure 404 {object} response.Problem "Wallet not found"
+// @Failure 409 {object} response.Problem "Wallet frozen or closed, concurrent update, or payment reported with another wallet or amount"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v1/webhooks/payments [post]
+func (h *PaymentWebhookHandler) Receive(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+
+	payload, err := io.ReadAll(r.Body)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if stderrors.As(err, &tooLarge) {
+			response.Error(w, errors.PayloadTooLarge(tooLarge.Limit))
+			return
+		}
+		response.ErrorMessage(w, http.StatusBadRequest, "Failed to read request body")
+		return
+	}
+	// The signature covers the exact bytes received, so it is checked before decoding
+	if err := webhooksig.Verify(h.Secret, r.Header.Get(PaymentSignatureHeader), payload, clock.OrDefault(h.Clock).Now(), h.Tolerance); err != nil {
+		log.Warn("Payment webhook signature rejected", zap.Error(err))
+		response.Error(w, errors.New(errors.ErrInvalidSignature, err.Error(), http.StatusUnauthorized))
+		return
+	}
+
+	var event paymentEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		response.Error(w, errors.InvalidInput("Malformed payment event"))
+		return
+	}
+	status, ok := paymentEventStatuses[event.Type]
+	if !ok {
+		log.Info("Payment event ignored", zap.String("event_id", event.ID), zap.String("type", event.Type))
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	currency, err := money.ParseCurrency(event.Data.Currency)
+	if err != nil {
+		response.Error(w, errors.InvalidInput(err.Error()))
+		return
+	}
+
+	payment, err := h.ProviderPayments.Apply(r.Context(), service.ProviderPaymentReport{
+		PaymentID:     event.Data.PaymentID,
+		Status:        status,
+		WalletID:      event.Data.WalletID,
+		Amount:        money.New(event.Data.Amount, currency),
+		FailureReason: event.Data.FailureReason,
+	})
+	if err != nil {
+		log.Error("Payment event not applied", zap.Error(err),
+			zap.String("event_id", event.ID),
+			zap.String("payment_id", event.Data.PaymentID))
+		switch {
+		case stderrors.Is(err, service.ErrInvalidProviderPayment):
+			response.Error(w, errors.InvalidInput(err.Error()))
+		case stderrors.Is(err, service.ErrProviderPaymentMismatch):
+			response.Error(w, errors.New(errors.ErrProviderPaymentMismatch, err.Error(), http.StatusConflict).
+				WithDetails("payment_id", event.Data.PaymentID))
+		default:
+			if appErr := movementAppError(err); appErr != nil {
+				response.Error(w, appErr)
+				return
+			}
+			response.Error(w, errors.InternalError(err))
+		}
+		return
+	}
+
+	log.Info("Payment event applied",
+		zap.String("event_id", event.ID),
+		zap.String("payment_id", payment.PaymentID),
+		zap.String("status", payment.Status))
+	response.OK(w, payment)
+}
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shanwije/wallet-app/internal/fees"
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository/sqlite"
+	"github.com/shanwije/wallet-app/internal/service"
+	"github.com/shanwije/wallet-app/pkg/clock"
+	"github.com/shanwije/wallet-app/pkg/webhooksig"
+)
+
+func TestPaymentWebhookDepositsEachPaymentOnce(t *testing.T) {
+	ctx := context.Background()
+	conn := newTestDB(t)
+	wallets := walletServiceOn(conn)
+	providerPayments := &service.ProviderPaymentService{Repo: sqlite.NewProviderPaymentRepository(conn), Wallets: wallets}
+	handler := NewPaymentWebhookHandler(providerPayments, "whsec_test", 5*time.Minute)
+	wallet := createUserWallet(t, wallets)
+
+	deliverSignedAt := func(at time.Time, eventType, paymentID, walletID, amount string) *httptest.ResponseRecorder {
+		body := `{"id":"evt_` + uuid.NewString() + `","type":"` + eventType + `","data":{"payment_id":"` + paymentID +
+			`","wallet_id":"` + walletID + `","amount":"` + amount + `","currency":"USD","failure_reason":"card_declined"}}`
+		req := httptest.NewRequest(http.MethodPost, "/webhooks/payments", strings.NewReader(body))
+		req.Header.Set(PaymentSignatureHeader, webhooksig.Sign("whsec_test", []byte(body), at))
+		rr := httptest.NewRecorder()
+		handler.Receive(rr, req)
+		return rr
+	}
+	deliver := func(eventType, paymentID, amount string) *models.ProviderPayment {
+		rr := deliverSignedAt(time.Now(), eventType, paymentID, wallet.ID.String(), amount)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		var payment models.ProviderPayment
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &payment))
+		return &payment
+	}
+	balance := func() string {
+		current, err := wallets.GetBalance(ctx, wallet.ID)
+		require.NoError(t, err)
+		return current.Balance.String()
+	}
+
+	// Deliveries that are not signed with the secret, or were signed too long ago, are refused
+	rr := deliverSignedAt(time.Now().Add(-10*time.Minute), "payment.succeeded", "pay_1", wallet.ID.String(), "25.00")
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	req := httptest.NewRequest(http.MethodPost, "/webhooks/payments", strings.NewReader(`{}`))
+	req.Header.Set(PaymentSignatureHeader, webhooksig.Sign("whsec_other", []byte(`{}`), time.Now()))
+	rr = httptest.NewRecorder()
+	handler.Receive(rr, req)
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	assert.Equal(t, "0", balance())
+
+	// Events about anything but payments are acknowledged without effect
+	assert.Equal(t, http.StatusNoContent, deliverSignedAt(time.Now(), "customer.created", "pay_1", wallet.ID.String(), "25.00").Code)
+
+	// A payment is deposited when it succeeds, and only then, however often that is reported
+	assert.Equal(t, models.ProviderPaymentStatusPending, deliver("payment.pending", "pay_1", "25.00").Status)
+	assert.Equal(t, "0", balance())
+	succeeded := deliver("payment.succeeded", "pay_1", "25.00")
+	assert.Equal(t, models.ProviderPaymentStatusSucceeded, succeeded.Status)
+	require.NotNil(t, succeeded.JournalID)
+	assert.Equal(t, "25", balance())
+	assert.Equal(t, succeeded.JournalID, deliver("payment.succeeded", "pay_1", "25.00").JournalID)
+	assert.Equal(t, models.ProviderPaymentStatusSucceeded, deliver("payment.pending", "pay_1", "25.00").Status, "late events are stale")
+	assert.Equal(t, "25", balance())
+
+	// A failed payment keeps its reason through a late pending, and is deposited if it
+	// succeeds on a retry
+	failed := deliver("payment.failed", "pay_2", "10.00")
+	assert.Equal(t, models.ProviderPaymentStatusFailed, failed.Status)
+	assert.Equal(t, "card_declined", *failed.FailureReason)
+	assert.Equal(t, models.ProviderPaymentStatusFailed, deliver("payment.pending", "pay_2", "10.00").Status)
+	assert.Equal(t, "25", balance())
+	retried := deliver("payment.succeeded", "pay_2", "10.00")
+	assert.Equal(t, models.ProviderPaymentStatusSucceeded, retried.Status)
+	assert.Nil(t, retried.FailureReason)
+	assert.Equal(t, "35", balance())
+
+	// A report that contradicts the recorded payment is refused
+	rr = deliverSignedAt(time.Now(), "payment.succeeded", "pay_1", wallet.ID.String(), "250.00")
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	assert.Contains(t, rr.Body.String(), "PROVIDER_PAYMENT_MISMATCH")
+	rr = deliverSignedAt(time.Now(), "payment.succeeded", "pay_3", uuid.NewString(), "5.00")
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+
+	// A payment into a frozen wallet waits for a delivery after it is unfrozen
+	_, err := wallets.SetWalletStatus(ctx, wallet.ID, models.WalletStatusFrozen)
+	require.NoError(t, err)
+	rr = deliverSignedAt(time.Now(), "payment.succeeded", "pay_4", wallet.ID.String(), "5.00")
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	_, err = wallets.SetWalletStatus(ctx, wallet.ID, models.WalletStatusActive)
+	require.NoError(t, err)
+	assert.Equal(t, models.ProviderPaymentStatusSucceeded, deliver("payment.succeeded", "pay_4", "5.00").Status)
+	assert.Equal(t, "40", balance())
+	assert.NoError(t, wallets.VerifyWalletBalance(ctx, wallet.ID))
+}
+
+func TestPaymentWebhookCreditsPaymentsWithoutFeesOrLimits(t *testing.T) {
+	ctx := context.Background()
+	conn := newTestDB(t)
+	wallets := walletServiceOn(conn)
+	wallets.LimitsRepo = sqlite.NewWalletLimitsRepository(conn)
+	schedule, err := fees.ParseSchedule("deposit=1%")
+	require.NoError(t, err)
+	wallets.Fees = schedule
+	wallets.FeeWalletID = createUserWallet(t, wallets).ID
+	maxAmount := decimal.NewFromInt(10)
+	wallets.MaxAmount = &maxAmount
+	providerPayments := &service.ProviderPaymentService{Repo: sqlite.NewProviderPaymentRepository(conn), Wallets: wallets}
+	handler := NewPaymentWebhookHandler(providerPayments, "whsec_test", 5*time.Minute)
+	signedAt := time.Date(2024, 6, 26, 8, 0, 0, 0, time.UTC)
+	handler.Clock = clock.NewFake(signedAt.Add(time.Minute))
+	wallet := createUserWallet(t, wallets)
+	require.NoError(t, wallets.LimitsRepo.SetWalletLimits(ctx, &models.WalletLimits{WalletID: wallet.ID, MaxTransactionAmount: &maxAmount}))
+
+	// The signature is checked against the handler's clock, and the payment the provider
+	// took is credited in full although it is over every limit and deposits pay a fee
+	body := `{"id":"evt_1","type":"payment.succeeded","data":{"payment_id":"pay_1","wallet_id":"` + wallet.ID.String() +
+		`","amount":"25.00","currency":"USD"}}`
+	req := httptest.NewRequest(http.MethodPost, "/webhooks/payments", strings.NewReader(body))
+	req.Header.Set(PaymentSignatureHeader, webhooksig.Sign("whsec_test", []byte(body), signedAt))
+	rr := httptest.NewRecorder()
+	handler.Receive(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	current, err := wallets.GetBalance(ctx, wallet.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "25", current.Balance.String())
+	feeWallet, err := wallets.GetBalance(ctx, wallets.FeeWalletID)
+	require.NoError(t, err)
+	assert.True(t, feeWallet.Balance.IsZero())
+}
@@ -0,0 +1,258 @@
+package handlers
+
+import (
+	stderrors "errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository"
+	"github.com/shanwije/wallet-app/internal/service"
+	"github.com/shanwije/wallet-app/pkg/errors"
+	"github.com/shanwije/wallet-app/pkg/logger"
+	"github.com/shanwije/wallet-app/pkg/response"
+)
+
+// PayoutHandler serves payouts from wallets to bank accounts
+type PayoutHandler struct {
+	PayoutService *service.PayoutService
+	// PINs asks for the owner's transaction PIN on payouts over its threshold; nil
+	// never asks for it
+	PINs *service.TransactionPINService
+}
+
+type payoutRequest struct {
+	Amount        decimal.Decimal `json:"amount" swaggertype:"string" validate:"required,gt=0" example:"25.00"`
+	Currency      string          `json:"currency,omitempty"`
+	AccountName   string          `json:"account_name" validate:"required,max=255" example:"Alice Smith"`
+	AccountNumber string          `json:"account_number" validate:"required,max=64" example:"GB29NWBK60161331926819"`
+	BankCode      string          `json:"bank_code" validate:"required,max=64" example:"NWBKGB2L"`
+}
+
+// payoutListResponse is one page of payouts
+type payoutListResponse struct {
+	Payouts []*models.Payout `json:"payouts"`
+	Limit   int              `json:"limit"`
+	Offset  int              `json:"offset"`
+}
+
+// NewPayoutHandler creates a new PayoutHandler
+func NewPayoutHandler(payoutService *service.PayoutService) *PayoutHandler {
+	return &PayoutHandler{
+		PayoutService: payoutService,
+	}
+}
+
+// payoutAppError maps the failures of a payout that have their own error code; it
+// returns nil for the rest
+func payoutAppError(err error, payoutID string) *errors.AppError {
+	switch {
+	case stderrors.Is(err, service.ErrPayoutNotFound):
+		return errors.New(errors.ErrPayoutNotFound, "Payout not found", http.StatusNotFound).
+			WithDetails("payout_id", payoutID)
+	case stderrors.Is(err, service.ErrInvalidPayoutDestination):
+		return errors.InvalidInput(err.Error())
+	default:
+		return movementAppError(err)
+	}
+}
+
+// Create pays money out of the wallet to a bank account
+// @Summary Pay out to a bank account
+// @Description Debits the wallet as a withdrawal and sends the money to the bank account through the payout provider. The payout is initiated, then sent once the provider has it, and ends settled, or failed with the amount credited back to the wallet; the withdrawal fee is not refunded. A payout the provider could not be reached about stays initiated and is sent again by the payout worker.
+// @Tags payouts
+// @Accept json
+// @Produce json
+// @Param id path string true "Wallet ID"
+// @Param payout body payoutRequest true "Amount and bank account"
+// @Param Idempotency-Key header string false "Makes retries safe: a repeat with the same key and body gets the first payout"
+// @Param X-Transaction-PIN header string false "Owner's transaction PIN, needed over the PIN threshold once they have set one"
+// @Success 201 {object} models.Payout
+// @Header 201 {string} Location "The created payout"
+// @Failure 400 {object} response.Problem "Invalid wallet ID, request body, amount or bank account, or insufficient funds"
+// @Failure 403 {object} response.Problem "Payouts or withdrawals disabled, blocked by a risk check, or transaction PIN required or incorrect"
+// @Failure 404 {object} response.Problem "Wallet not found"
+// @Failure 409 {object} response.Problem "Wallet frozen or closed, concurrent update, or Idempotency-Key reused"
+// @Failure 422 {object} response.Problem "Wallet limit exceeded"
+// @Failure 423 {object} response.Problem "Transaction PIN locked after too many incorrect attempts"
+// @Failure 429 {object} response.Problem "Rate limit exceeded"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v1/wallets/{id}/payouts [post]
+func (h *PayoutHandler) Create(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+	walletIDStr := chi.URLParam(r, "id")
+	walletID, err := uuid.Parse(walletIDStr)
+	if err != nil {
+		response.ErrorMessage(w, http.StatusBadRequest, "Invalid wallet ID")
+		return
+	}
+
+	var req payoutRequest
+	if appErr := decodeRequest(r, &req); appErr != nil {
+		response.Error(w, appErr)
+		return
+	}
+	if appErr := validateRequest(req); appErr != nil {
+		response.Error(w, appErr)
+		return
+	}
+	amount, appErr := parseAmount(req.Amount, req.Currency)
+	if appErr != nil {
+		response.Error(w, appErr)
+		return
+	}
+	if appErr := authorizePIN(r, h.PINs, walletID, amount); appErr != nil {
+		response.Error(w, appErr)
+		return
+	}
+
+	destination := service.PayoutDestination{
+		AccountName:   req.AccountName,
+		AccountNumber: req.AccountNumber,
+		BankCode:      req.BankCode,
+	}
+	payout, err := h.PayoutService.Create(r.Context(), walletID, amount, destination, r.Header.Get("Idempotency-Key"))
+	if err != nil {
+		log.Error("Payout failed", zap.Error(err),
+			zap.String("wallet_id", walletIDStr),
+			zap.String("amount", amount.String()))
+		if appErr := payoutAppError(err, ""); appErr != nil {
+			response.Error(w, appErr)
+			return
+		}
+		response.ErrorMessage(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	log.Info("Payout initiated",
+		zap.String("payout_id", payout.ID.String()),
+		zap.String("wallet_id", walletIDStr),
+		zap.String("status", payout.Status))
+
+	response.Created(w, "/api/v1/wallets/"+walletIDStr+"/payouts/"+payout.ID.String(), payout)
+}
+
+// List pages through the wallet's payouts
+// @Summary List payouts
+// @Tags payouts
+// @Produce json
+// @Param id path string true "Wallet ID"
+// @Param status query string false "initiated, sent, settled or failed"
+// @Param limit query int false "Page size; larger sizes are cut to 200" minimum(0) default(50)
+// @Param offset query int false "Payouts to skip" minimum(0) default(0)
+// @Success 200 {object} payoutListResponse
+// @Failure 400 {object} response.Problem "Invalid wallet ID, status or pagination parameters"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v1/wallets/{id}/payouts [get]
+func (h *PayoutHandler) List(w http.ResponseWriter, r *http.Request) {
+	walletID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.ErrorMessage(w, http.StatusBadRequest, "Invalid wallet ID")
+		return
+	}
+
+	h.list(w, r, repository.PayoutFilter{WalletID: walletID})
+}
+
+// ListAll pages through every wallet's payouts
+// @Summary List payouts
+// @Description Returns payouts of every wallet, newest first, for following up on the ones still in flight or failed.
+// @Tags admin
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param status query string false "initiated, sent, settled or failed"
+// @Param wallet_id query string false "Only payouts from this wallet"
+// @Param limit query int false "Page size; larger sizes are cut to 200" minimum(0) default(50)
+// @Param offset query int false "Payouts to skip" minimum(0) default(0)
+// @Success 200 {object} payoutListResponse
+// @Failure 400 {object} response.Problem "Invalid wallet ID, status or pagination parameters"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v1/admin/payouts [get]
+func (h *PayoutHandler) ListAll(w http.ResponseWriter, r *http.Request) {
+	var filter repository.PayoutFilter
+	if value := r.URL.Query().Get("wallet_id"); value != "" {
+		walletID, err := uuid.Parse(value)
+		if err != nil {
+			response.ErrorMessage(w, http.StatusBadRequest, "Invalid wallet ID")
+			return
+		}
+		filter.WalletID = walletID
+	}
+
+	h.list(w, r, filter)
+}
+
+// list responds with the page of payouts filter matches, narrowed by the status and
+// pagination query parameters
+func (h *PayoutHandler) list(w http.ResponseWriter, r *http.Request, filter repository.PayoutFilter) {
+	limit, offset, appErr := parsePage(r)
+	if appErr != nil {
+		response.Error(w, appErr)
+		return
+	}
+	filter.Limit, filter.Offset = limit, offset
+
+	filter.Status = r.URL.Query().Get("status")
+	switch filter.Status {
+	case "", models.PayoutStatusInitiated, models.PayoutStatusSent, models.PayoutStatusSettled, models.PayoutStatusFailed:
+	default:
+		response.Error(w, errors.InvalidInput("Status must be initiated, sent, settled or failed").
+			WithDetails("status", filter.Status))
+		return
+	}
+
+	payouts, err := h.PayoutService.List(r.Context(), filter)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Failed to list payouts", zap.Error(err))
+		response.Error(w, errors.InternalError(err))
+		return
+	}
+
+	response.OK(w, payoutListResponse{Payouts: payouts, Limit: limit, Offset: offset})
+}
+
+// Get returns one of the wallet's payouts
+// @Summary Get payout
+// @Tags payouts
+// @Produce json
+// @Param id path string true "Wallet ID"
+// @Param payoutID path string true "Payout ID"
+// @Success 200 {object} models.Payout
+// @Failure 400 {object} response.Problem "Invalid wallet or payout ID"
+// @Failure 404 {object} response.Problem "Payout not found"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v1/wallets/{id}/payouts/{payoutID} [get]
+func (h *PayoutHandler) Get(w http.ResponseWriter, r *http.Request) {
+	walletID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.ErrorMessage(w, http.StatusBadRequest, "Invalid wallet ID")
+		return
+	}
+	payoutIDStr := chi.URLParam(r, "payoutID")
+	payoutID, err := uuid.Parse(payoutIDStr)
+	if err != nil {
+		response.ErrorMessage(w, http.StatusBadRequest, "Invalid payout ID")
+		return
+	}
+
+	payout, err := h.PayoutService.Get(r.Context(), payoutID)
+	// Another wallet's payout is reported as missing rather than forbidden
+	if err == nil && payout.WalletID != walletID {
+		err = service.ErrPayoutNotFound
+	}
+	if err != nil {
+		if appErr := payoutAppError(err, payoutIDStr); appErr != nil {
+			response.Error(w, appErr)
+			return
+		}
+		logger.FromContext(r.Context()).Error("Failed to get payout", zap.Error(err))
+		response.Error(w, errors.InternalError(err))
+		return
+	}
+
+	response.OK(w, payout)
+}
@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/payout"
+	"github.com/shanwije/wallet-app/internal/repository/sqlite"
+	"github.com/shanwije/wallet-app/internal/service"
+	"github.com/shanwije/wallet-app/pkg/money"
+)
+
+// fakePayouts rejects payouts to account REJECTED, fails to answer while down, and
+// reports the status set for each reference, pending until one is set
+type fakePayouts struct {
+	mu       sync.Mutex
+	down     bool
+	statuses map[string]payout.Result
+}
+
+func (f *fakePayouts) Send(_ context.Context, request payout.Request) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.down {
+		return "", errors.New("connection refused")
+	}
+	if request.AccountNumber == "REJECTED" {
+		return "", payout.ErrRejected
+	}
+	return "ref-" + request.ID.String(), nil
+}
+
+func (f *fakePayouts) Status(_ context.Context, reference string) (payout.Result, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if result, ok := f.statuses[reference]; ok {
+		return result, nil
+	}
+	return payout.Result{Status: payout.StatusPending}, nil
+}
+
+func (f *fakePayouts) set(reference string, result payout.Result) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.statuses[reference] = result
+}
+
+func TestPayoutsSettleOrCreditTheWalletBack(t *testing.T) {
+	ctx := context.Background()
+	conn := newTestDB(t)
+	wallets := walletServiceOn(conn)
+	wallets.SystemWallets = &service.SystemWalletService{Repo: sqlite.NewWalletRepository(conn)}
+	provider := &fakePayouts{statuses: make(map[string]payout.Result)}
+	payouts := &service.PayoutService{Repo: sqlite.NewPayoutRepository(conn), Wallets: wallets, Provider: provider}
+	handler := NewPayoutHandler(payouts)
+	router := chi.NewRouter()
+	router.Post("/wallets/{id}/payouts", handler.Create)
+	router.Get("/wallets/{id}/payouts", handler.List)
+	router.Get("/wallets/{id}/payouts/{payoutID}", handler.Get)
+
+	wallet, other := createUserWallet(t, wallets), createUserWallet(t, wallets)
+	_, err := wallets.Deposit(ctx, wallet.ID, money.New(decimal.NewFromInt(100), money.DefaultCurrency), "")
+	require.NoError(t, err)
+
+	send := func(method, path, body, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if key != "" {
+			req.Header.Set("Idempotency-Key", key)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	create := func(amount, account, key string) models.Payout {
+		rr := send(http.MethodPost, "/wallets/"+wallet.ID.String()+"/payouts",
+			`{"amount":`+amount+`,"account_name":"Alice Smith","account_number":"`+account+`","bank_code":"NWBKGB2L"}`, key)
+		require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+		var created models.Payout
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &created))
+		return created
+	}
+	balance := func() string {
+		current, err := wallets.GetBalance(ctx, wallet.ID)
+		require.NoError(t, err)
+		return current.Balance.String()
+	}
+
+	// A payout the provider takes is sent, and a retry with its key does not pay again
+	sent := create("30", "12345678", "rent")
+	assert.Equal(t, models.PayoutStatusSent, sent.Status)
+	assert.Equal(t, "ref-"+sent.ID.String(), *sent.ProviderReference)
+	assert.Equal(t, sent.ID, create("30", "12345678", "rent").ID)
+	assert.Equal(t, "70", balance())
+
+	// One the provider rejects fails at once and is credited back
+	rejected := create("20", "REJECTED", "")
+	assert.Equal(t, models.PayoutStatusFailed, rejected.Status)
+	require.NotNil(t, rejected.RefundJournalID)
+	assert.Equal(t, "70", balance())
+
+	// One the provider could not be reached about stays initiated until the worker sends it
+	provider.down = true
+	initiated := create("10", "87654321", "")
+	assert.Equal(t, models.PayoutStatusInitiated, initiated.Status)
+	assert.Equal(t, "60", balance())
+	provider.down = false
+	processed, err := payouts.ProcessPending(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, processed)
+
+	// The worker settles or fails sent payouts as the provider reports them
+	provider.set("ref-"+sent.ID.String(), payout.Result{Status: payout.StatusSettled})
+	provider.set("ref-"+initiated.ID.String(), payout.Result{Status: payout.StatusFailed, Reason: "account closed"})
+	processed, err = payouts.ProcessPending(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, processed)
+	processed, err = payouts.ProcessPending(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, processed)
+	assert.Equal(t, "70", balance())
+
+	rr := send(http.MethodGet, "/wallets/"+wallet.ID.String()+"/payouts/"+initiated.ID.String(), "", "")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var failed models.Payout
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &failed))
+	assert.Equal(t, models.PayoutStatusFailed, failed.Status)
+	assert.Equal(t, "account closed", *failed.FailureReason)
+	assert.Equal(t, http.StatusNotFound, send(http.MethodGet, "/wallets/"+other.ID.String()+"/payouts/"+initiated.ID.String(), "", "").Code)
+
+	rr = send(http.MethodGet, "/wallets/"+wallet.ID.String()+"/payouts?status=failed", "", "")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var page payoutListResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &page))
+	require.Len(t, page.Payouts, 2)
+	assert.Equal(t, initiated.ID, page.Payouts[0].ID, "newest first")
+	assert.Equal(t, http.StatusBadRequest, send(http.MethodGet, "/wallets/"+wallet.ID.String()+"/payouts?status=lost", "", "").Code)
+
+	// Only the settled payout left the platform
+	treasuryID, err := wallets.SystemWallets.WalletID(ctx, models.WalletKindTreasury, money.DefaultCurrency)
+	require.NoError(t, err)
+	treasury, err := wallets.GetBalance(ctx, treasuryID)
+	require.NoError(t, err)
+	assert.Equal(t, "-70", treasury.Balance.String())
+	for _, id := range []uuid.UUID{wallet.ID, treasuryID} {
+		assert.NoError(t, wallets.VerifyWalletBalance(ctx, id))
+	}
+
+	// Without a provider payouts are switched off
+	payouts.Provider = nil
+	rr = send(http.MethodPost, "/wallets/"+wallet.ID.String()+"/payouts",
+		`{"amount":5,"account_name":"Alice Smith","account_number":"12345678","bank_code":"NWBKGB2L"}`, "")
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+}
@@ -0,0 +1,278 @@
+package handlers
+
+import (
+	"context"
+	stderrors "errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/service"
+	"github.com/shanwije/wallet-app/pkg/errors"
+	"github.com/shanwije/wallet-app/pkg/logger"
+	"github.com/shanwije/wallet-app/pkg/response"
+)
+
+// PendingTransferHandler serves the transfers held for their sender's confirmation
+type PendingTransferHandler struct {
+	PendingTransferService *service.PendingTransferService
+}
+
+// pendingTransferListResponse is one page of pending transfers
+type pendingTransferListResponse struct {
+	Transfers []*models.PendingTransfer `json:"transfers"`
+	Limit     int                       `json:"limit"`
+	Offset    int                       `json:"offset"`
+}
+
+// NewPendingTransferHandler creates a new PendingTransferHandler
+func NewPendingTransferHandler(pendingTransferService *service.PendingTransferService) *PendingTransferHandler {
+	return &PendingTransferHandler{
+		PendingTransferService: pendingTransferService,
+	}
+}
+
+// pendingTransferAppError maps the failures of confirming, approving or cancelling a
+// pending transfer that have their own error code; it returns nil for the rest
+func pendingTransferAppError(err error, transferID string) *errors.AppError {
+	switch {
+	case stderrors.Is(err, service.ErrPendingTransferNotFound):
+		return errors.New(errors.ErrPendingTransferNotFound, err.Error(), http.StatusNotFound).
+			WithDetails("pending_transfer_id", transferID)
+	case stderrors.Is(err, service.ErrPendingTransferNotPending),
+		stderrors.Is(err, service.ErrPendingTransferExpired):
+		return errors.Conflict(err.Error())
+	default:
+		return movementAppError(err)
+	}
+}
+
+// holdForConfirmation answers a transfer over the confirmation threshold by storing it
+// to be confirmed, with 202 Accepted. ctx carries the If-Match precondition and the
+// classification the request set for the transfer.
+func (h *WalletHandler) holdForConfirmation(ctx context.Context, w http.ResponseWriter, r *http.Request, transfer *transferInput) {
+	log := logger.FromContext(ctx)
+
+	pending, err := h.PendingTransfers.Create(ctx, transfer.fromWalletID, transfer.toWalletID, transfer.amount, transfer.description, r.Header.Get("Idempotency-Key"))
+	if err != nil {
+		if appErr := movementAppError(err); appErr != nil {
+			response.Error(w, appErr)
+			return
+		}
+		response.ErrorMessage(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	log.Info("Transfer held for confirmation",
+		zap.String("pending_transfer_id", pending.ID.String()),
+		zap.String("amount", pending.Funds().String()))
+
+	w.Header().Set("Location", "/api/v1/wallets/"+pending.FromWalletID.String()+"/pending-transfers/"+pending.ID.String())
+	response.JSON(w, http.StatusAccepted, pending)
+}
+
+// List returns the transfers a wallet sent for confirmation
+// @Summary List pending transfers
+// @Description Returns the wallet's transfers that were held for confirmation, newest first, in whatever status they are now
+// @Tags pending-transfers
+// @Produce json
+// @Param id path string true "Sending wallet ID"
+// @Success 200 {array} models.PendingTransfer
+// @Failure 400 {object} response.Problem "Invalid wallet ID"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v1/wallets/{id}/pending-transfers [get]
+func (h *PendingTransferHandler) List(w http.ResponseWriter, r *http.Request) {
+	walletID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.ErrorMessage(w, http.StatusBadRequest, "Invalid wallet ID")
+		return
+	}
+
+	transfers, err := h.PendingTransferService.List(r.Context(), walletID)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Failed to list pending transfers", zap.Error(err))
+		response.Error(w, errors.InternalError(err))
+		return
+	}
+
+	response.OK(w, transfers)
+}
+
+// Confirm runs a transfer held for confirmation
+// @Summary Confirm a pending transfer
+// @Description Moves the money of a transfer that was held for being over the confirmation threshold.
+// @Description A transfer that cannot run, such as for want of funds, is failed with the reason; one
+// @Description confirmed after it expired is cancelled.
+// @Tags pending-transfers
+// @Produce json
+// @Param id path string true "Sending wallet ID"
+// @Param transferID path string true "Pending transfer ID"
+// @Param Idempotency-Key header string false "Makes retries safe: a repeat with the same key and body gets the first response"
+// @Success 200 {object} models.PendingTransfer
+// @Failure 400 {object} response.Problem "Invalid wallet ID or pending transfer ID, or insufficient funds"
+// @Failure 404 {object} response.Problem "Pending transfer not found"
+// @Failure 409 {object} response.Problem "Transfer is no longer pending or has expired, a wallet is frozen or closed, or Idempotency-Key reused with a different request body"
+// @Failure 422 {object} response.Problem "Wallet limit exceeded"
+// @Failure 429 {object} response.Problem "Rate limit exceeded"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v1/wallets/{id}/pending-transfers/{transferID}/confirm [post]
+func (h *PendingTransferHandler) Confirm(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+	walletID, transferID, ok := parsePendingTransferPath(w, r)
+	if !ok {
+		return
+	}
+
+	transfer, err := h.PendingTransferService.Confirm(r.Context(), walletID, transferID)
+	if err != nil {
+		log.Error("Failed to confirm transfer", zap.Error(err), zap.String("pending_transfer_id", transferID.String()))
+		if appErr := pendingTransferAppError(err, transferID.String()); appErr != nil {
+			response.Error(w, appErr)
+			return
+		}
+		response.Error(w, errors.InternalError(err))
+		return
+	}
+
+	log.Info("Transfer confirmed",
+		zap.String("pending_transfer_id", transferID.String()),
+		zap.String("amount", transfer.Funds().String()))
+
+	response.OK(w, transfer)
+}
+
+// Cancel drops a transfer held for confirmation
+// @Summary Cancel a pending transfer
+// @Tags pending-transfers
+// @Produce json
+// @Param id path string true "Sending wallet ID"
+// @Param transferID path string true "Pending transfer ID"
+// @Param Idempotency-Key header string false "Makes retries safe: a repeat with the same key and body gets the first response"
+// @Success 200 {object} models.PendingTransfer
+// @Failure 400 {object} response.Problem "Invalid wallet ID or pending transfer ID"
+// @Failure 404 {object} response.Problem "Pending transfer not found"
+// @Failure 409 {object} response.Problem "Transfer is no longer pending, or Idempotency-Key reused with a different request body"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v1/wallets/{id}/pending-transfers/{transferID}/cancel [post]
+func (h *PendingTransferHandler) Cancel(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+	walletID, transferID, ok := parsePendingTransferPath(w, r)
+	if !ok {
+		return
+	}
+
+	transfer, err := h.PendingTransferService.Cancel(r.Context(), walletID, transferID)
+	if err != nil {
+		log.Error("Failed to cancel transfer", zap.Error(err), zap.String("pending_transfer_id", transferID.String()))
+		if appErr := pendingTransferAppError(err, transferID.String()); appErr != nil {
+			response.Error(w, appErr)
+			return
+		}
+		response.Error(w, errors.InternalError(err))
+		return
+	}
+
+	log.Info("Transfer cancelled", zap.String("pending_transfer_id", transferID.String()))
+
+	response.OK(w, transfer)
+}
+
+// ListAll pages through the transfers held for confirmation, for operators
+// @Summary List pending transfers
+// @Description Returns transfers held for confirmation, oldest first, at most 200 per page. Pass status=pending for the ones still waiting.
+// @Tags admin
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param status query string false "pending, completed, failed or cancelled"
+// @Param limit query int false "Page size; larger sizes are cut to 200" minimum(0) default(50)
+// @Param offset query int false "Transfers to skip" minimum(0) default(0)
+// @Success 200 {object} pendingTransferListResponse
+// @Failure 400 {object} response.Problem "Invalid status or pagination parameters"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v1/admin/pending-transfers [get]
+func (h *PendingTransferHandler) ListAll(w http.ResponseWriter, r *http.Request) {
+	limit, offset, appErr := parsePage(r)
+	if appErr != nil {
+		response.Error(w, appErr)
+		return
+	}
+
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", models.PendingTransferStatusPending, models.PendingTransferStatusCompleted,
+		models.PendingTransferStatusFailed, models.PendingTransferStatusCancelled:
+	default:
+		response.Error(w, errors.InvalidInput("Status must be pending, completed, failed or cancelled").
+			WithDetails("status", status))
+		return
+	}
+
+	transfers, err := h.PendingTransferService.ListAll(r.Context(), status, limit, offset)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Failed to list pending transfers", zap.Error(err))
+		response.Error(w, errors.InternalError(err))
+		return
+	}
+
+	response.OK(w, pendingTransferListResponse{Transfers: transfers, Limit: limit, Offset: offset})
+}
+
+// Approve runs a transfer held for confirmation on an operator's say-so
+// @Summary Approve a pending transfer
+// @Description Moves the money of a held transfer in place of its sender's confirmation, on the same terms.
+// @Tags admin
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param id path string true "Pending transfer ID"
+// @Success 200 {object} models.PendingTransfer
+// @Failure 400 {object} response.Problem "Invalid pending transfer ID, or insufficient funds"
+// @Failure 404 {object} response.Problem "Pending transfer not found"
+// @Failure 409 {object} response.Problem "Transfer is no longer pending or has expired, or a wallet is frozen or closed"
+// @Failure 422 {object} response.Problem "Wallet limit exceeded"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v1/admin/pending-transfers/{id}/approve [post]
+func (h *PendingTransferHandler) Approve(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+	transferIDStr := chi.URLParam(r, "id")
+	transferID, err := uuid.Parse(transferIDStr)
+	if err != nil {
+		response.ErrorMessage(w, http.StatusBadRequest, "Invalid pending transfer ID")
+		return
+	}
+
+	transfer, err := h.PendingTransferService.Approve(r.Context(), transferID)
+	if err != nil {
+		log.Error("Failed to approve transfer", zap.Error(err), zap.String("pending_transfer_id", transferIDStr))
+		if appErr := pendingTransferAppError(err, transferIDStr); appErr != nil {
+			response.Error(w, appErr)
+			return
+		}
+		response.Error(w, errors.InternalError(err))
+		return
+	}
+
+	log.Info("Transfer approved",
+		zap.String("pending_transfer_id", transferIDStr),
+		zap.String("amount", transfer.Funds().String()))
+
+	response.OK(w, transfer)
+}
+
+// parsePendingTransferPath reads the wallet and pending transfer IDs from the URL,
+// responding with 400 if either is invalid
+func parsePendingTransferPath(w http.ResponseWriter, r *http.Request) (uuid.UUID, uuid.UUID, bool) {
+	walletID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.ErrorMessage(w, http.StatusBadRequest, "Invalid wallet ID")
+		return uuid.Nil, uuid.Nil, false
+	}
+	transferID, err := uuid.Parse(chi.URLParam(r, "transferID"))
+	if err != nil {
+		response.ErrorMessage(w, http.StatusBadRequest, "Invalid pending transfer ID")
+		return uuid.Nil, uuid.Nil, false
+	}
+	return walletID, transferID, true
+}
@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository/sqlite"
+	"github.com/shanwije/wallet-app/internal/service"
+	"github.com/shanwije/wallet-app/pkg/clock"
+	"github.com/shanwije/wallet-app/pkg/money"
+)
+
+func TestTransfersOverTheThresholdWaitForConfirmation(t *testing.T) {
+	conn := newTestDB(t)
+	wallets := walletServiceOn(conn)
+	threshold := decimal.NewFromInt(100)
+	now := clock.NewFake(time.Date(2024, 7, 14, 9, 0, 0, 0, time.UTC))
+	pending := &service.PendingTransferService{
+		Repo:               sqlite.NewPendingTransferRepository(conn),
+		Wallets:            wallets,
+		Threshold:          &threshold,
+		ConfirmationWindow: 15 * time.Minute,
+		Clock:              now,
+	}
+	ctx := context.Background()
+	sender, recipient := createUserWallet(t, wallets), createUserWallet(t, wallets)
+	_, err := wallets.Deposit(ctx, sender.ID, money.New(decimal.NewFromInt(500), money.DefaultCurrency), "")
+	require.NoError(t, err)
+
+	walletHandler := &WalletHandler{WalletService: wallets, PendingTransfers: pending}
+	handler := NewPendingTransferHandler(pending)
+	router := chi.NewRouter()
+	router.Post("/wallets/{id}/transfer", walletHandler.TransferV2)
+	router.Get("/wallets/{id}/pending-transfers", handler.List)
+	router.Post("/wallets/{id}/pending-transfers/{transferID}/confirm", handler.Confirm)
+	router.Post("/wallets/{id}/pending-transfers/{transferID}/cancel", handler.Cancel)
+	router.Get("/admin/pending-transfers", handler.ListAll)
+	router.Post("/admin/pending-transfers/{id}/approve", handler.Approve)
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rr
+	}
+	walletPath := "/wallets/" + sender.ID.String()
+	hold := func(amount string) *models.PendingTransfer {
+		rr := send(http.MethodPost, walletPath+"/transfer", `{"to_wallet_id":"`+recipient.ID.String()+`","amount":`+amount+`}`)
+		require.Equal(t, http.StatusAccepted, rr.Code, rr.Body.String())
+		var transfer models.PendingTransfer
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &transfer))
+		assert.Equal(t, walletPath+"/pending-transfers/"+transfer.ID.String(), strings.TrimPrefix(rr.Header().Get("Location"), "/api/v1"))
+		return &transfer
+	}
+	available := func(wallet *models.Wallet) string {
+		current, err := wallets.GetBalance(ctx, wallet.ID)
+		require.NoError(t, err)
+		return current.Available().Amount().String()
+	}
+	act := func(transfer *models.PendingTransfer, action string) *httptest.ResponseRecorder {
+		return send(http.MethodPost, walletPath+"/pending-transfers/"+transfer.ID.String()+"/"+action, "")
+	}
+
+	// Transfers up to the threshold move at once
+	rr := send(http.MethodPost, walletPath+"/transfer", `{"to_wallet_id":"`+recipient.ID.String()+`","amount":100}`)
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	assert.Equal(t, "400", available(sender))
+
+	// Larger ones move nothing until confirmed
+	confirmed := hold("150")
+	assert.Equal(t, models.PendingTransferStatusPending, confirmed.Status)
+	assert.Equal(t, "400", available(sender))
+	assert.Equal(t, http.StatusNotFound, send(http.MethodPost, "/wallets/"+recipient.ID.String()+"/pending-transfers/"+confirmed.ID.String()+"/confirm", "").Code)
+	rr = act(confirmed, "confirm")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), confirmed))
+	assert.Equal(t, models.PendingTransferStatusCompleted, confirmed.Status)
+	assert.NotNil(t, confirmed.TransferJournalID)
+	assert.Equal(t, "250", available(sender))
+	assert.Equal(t, "250", available(recipient))
+	assert.Equal(t, http.StatusConflict, act(confirmed, "confirm").Code)
+
+	// A quote would lapse before a held transfer runs, so one cannot pass a quote
+	rr = send(http.MethodPost, walletPath+"/transfer", `{"to_wallet_id":"`+recipient.ID.String()+`","amount":150,"quote_id":"`+uuid.NewString()+`"}`)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), "cannot run on a quote")
+
+	cancelled := hold("200")
+	require.Equal(t, http.StatusOK, act(cancelled, "cancel").Code)
+	assert.Equal(t, http.StatusConflict, act(cancelled, "confirm").Code)
+
+	// A transfer the sender can no longer afford fails with the reason
+	failed := hold("240")
+	_, err = wallets.Withdraw(ctx, sender.ID, money.New(decimal.NewFromInt(50), money.DefaultCurrency), "")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, act(failed, "confirm").Code)
+
+	// Unconfirmed transfers expire, whether or not the worker got to them first
+	late := hold("120")
+	expired := hold("110")
+	now.Advance(16 * time.Minute)
+	assert.Equal(t, http.StatusConflict, act(late, "confirm").Code)
+	count, err := pending.ExpireDue(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	// Operators can approve in the sender's place
+	approved := hold("180")
+	rr = send(http.MethodPost, "/admin/pending-transfers/"+approved.ID.String()+"/approve", "")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.Equal(t, "20", available(sender))
+
+	rr = send(http.MethodGet, walletPath+"/pending-transfers", "")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var listed []*models.PendingTransfer
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &listed))
+	statuses := map[string]string{}
+	for _, transfer := range listed {
+		statuses[transfer.ID.String()] = transfer.Status
+	}
+	assert.Equal(t, map[string]string{
+		confirmed.ID.String(): models.PendingTransferStatusCompleted,
+		cancelled.ID.String(): models.PendingTransferStatusCancelled,
+		failed.ID.String():    models.PendingTransferStatusFailed,
+		late.ID.String():      models.PendingTransferStatusCancelled,
+		expired.ID.String():   models.PendingTransferStatusCancelled,
+		approved.ID.String():  models.PendingTransferStatusCompleted,
+	}, statuses)
+
+	rr = send(http.MethodGet, "/admin/pending-transfers?status=failed", "")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var page pendingTransferListResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &page))
+	require.Len(t, page.Transfers, 1)
+	assert.Equal(t, failed.ID, page.Transfers[0].ID)
+	assert.NotNil(t, page.Transfers[0].Reason)
+	assert.Equal(t, http.StatusBadRequest, send(http.MethodGet, "/admin/pending-transfers?status=held", "").Code)
+
+	// A held transfer keeps the request's classification and Idempotency-Key, and
+	// checks If-Match when it is held
+	_, err = wallets.Deposit(ctx, sender.ID, money.New(decimal.NewFromInt(300), money.DefaultCurrency), "")
+	require.NoError(t, err)
+	current, err := wallets.GetBalance(ctx, sender.ID)
+	require.NoError(t, err)
+	request := func(ifMatch, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, walletPath+"/transfer",
+			strings.NewReader(`{"to_wallet_id":"`+recipient.ID.String()+`","amount":250,"category":" Rent ","tags":["home","July"]}`))
+		req.Header.Set("If-Match", ifMatch)
+		req.Header.Set("Idempotency-Key", key)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	assert.Equal(t, http.StatusPreconditionFailed, request(`"`+strconv.FormatInt(current.Version-1, 10)+`"`, "rent-july").Code)
+	rr = request(walletETag(current), "rent-july")
+	require.Equal(t, http.StatusAccepted, rr.Code, rr.Body.String())
+	var filed models.PendingTransfer
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &filed))
+	require.NotNil(t, filed.Category)
+	assert.Equal(t, "rent", *filed.Category)
+	assert.Equal(t, models.TagList{"home", "july"}, filed.Tags)
+	rr = request("", "rent-july")
+	require.Equal(t, http.StatusAccepted, rr.Code, rr.Body.String())
+	var retried models.PendingTransfer
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &retried))
+	assert.Equal(t, filed.ID, retried.ID, "a retry gets the transfer already held")
+
+	rr = act(&filed, "confirm")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	history, err := wallets.GetTransactionHistory(ctx, sender.ID)
+	require.NoError(t, err)
+	require.NotEmpty(t, history)
+	require.NotNil(t, history[0].Category)
+	assert.Equal(t, "rent", *history[0].Category)
+	assert.Equal(t, []string{"home", "july"}, history[0].Tags)
+}
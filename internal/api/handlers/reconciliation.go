@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository"
+	"github.com/shanwije/wallet-app/pkg/errors"
+	"github.com/shanwije/wallet-app/pkg/logger"
+	"github.com/shanwije/wallet-app/pkg/response"
+)
+
+// reconciliationRunListResponse is one page of reconciliation runs
+type reconciliationRunListResponse struct {
+	Runs   []*models.ReconciliationRun `json:"runs"`
+	Limit  int                         `json:"limit"`
+	Offset int                         `json:"offset"`
+}
+
+// discrepancyListResponse is one page of balance discrepancies
+type discrepancyListResponse struct {
+	Discrepancies []*models.BalanceDiscrepancy `json:"discrepancies"`
+	Limit         int                          `json:"limit"`
+	Offset        int                          `json:"offset"`
+}
+
+// RunReconciliation checks every wallet's balance against its ledger now
+// @Summary Run a reconciliation
+// @Description Compares every wallet's stored balance with the sum of its ledger entries, records the run and returns it with the wallets that disagree.
+// @Tags admin
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param Idempotency-Key header string false "Makes retries safe: a repeat with the same key and body gets the first response"
+// @Success 201 {object} models.ReconciliationRun
+// @Failure 409 {object} response.Problem "Idempotency-Key reused with a different request body"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v1/admin/reconciliation/runs [post]
+func (h *AdminHandler) RunReconciliation(w http.ResponseWriter, r *http.Request) {
+	run, err := h.Reconciliation.Reconcile(r.Context())
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Reconciliation run failed", zap.Error(err))
+		response.Error(w, errors.InternalError(err))
+		return
+	}
+
+	response.Created(w, "", run)
+}
+
+// ListReconciliationRuns pages through past reconciliation runs
+// @Summary List reconciliation runs
+// @Description Returns past runs with how many wallets each checked and found out of balance, newest first, at most 200 per page.
+// @Tags admin
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param limit query int false "Page size; larger sizes are cut to 200" minimum(0) default(50)
+// @Param offset query int false "Runs to skip" minimum(0) default(0)
+// @Success 200 {object} reconciliationRunListResponse
+// @Failure 400 {object} response.Problem "Invalid pagination parameters"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v1/admin/reconciliation/runs [get]
+func (h *AdminHandler) ListReconciliationRuns(w http.ResponseWriter, r *http.Request) {
+	limit, offset, appErr := parsePage(r)
+	if appErr != nil {
+		response.Error(w, appErr)
+		return
+	}
+
+	runs, err := h.Reconciliation.ListRuns(r.Context(), limit, offset)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Failed to list reconciliation runs", zap.Error(err))
+		response.Error(w, errors.InternalError(err))
+		return
+	}
+
+	response.OK(w, reconciliationRunListResponse{Runs: runs, Limit: limit, Offset: offset})
+}
+
+// ListDiscrepancies pages through the wallets reconciliation runs found out of balance
+// @Summary List balance discrepancies
+// @Description Returns wallets whose stored balance differed from their ledger, with both amounts, newest first, at most 200 per page.
+// @Tags admin
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param run_id query string false "Only discrepancies found by this run"
+// @Param wallet_id query string false "Only discrepancies in this wallet"
+// @Param limit query int false "Page size; larger sizes are cut to 200" minimum(0) default(50)
+// @Param offset query int false "Discrepancies to skip" minimum(0) default(0)
+// @Success 200 {object} discrepancyListResponse
+// @Failure 400 {object} response.Problem "Invalid filter or pagination parameters"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v1/admin/reconciliation/discrepancies [get]
+func (h *AdminHandler) ListDiscrepancies(w http.ResponseWriter, r *http.Request) {
+	limit, offset, appErr := parsePage(r)
+	if appErr != nil {
+		response.Error(w, appErr)
+		return
+	}
+
+	query := r.URL.Query()
+	filter := repository.DiscrepancyFilter{Limit: limit, Offset: offset}
+	for param, target := range map[string]*uuid.UUID{"run_id": &filter.RunID, "wallet_id": &filter.WalletID} {
+		value := query.Get(param)
+		if value == "" {
+			continue
+		}
+		parsed, err := uuid.Parse(value)
+		if err != nil {
+			response.Error(w, errors.InvalidInput("IDs must be UUIDs").WithDetails(param, value))
+			return
+		}
+		*target = parsed
+	}
+
+	discrepancies, err := h.Reconciliation.ListDiscrepancies(r.Context(), filter)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Failed to list discrepancies", zap.Error(err))
+		response.Error(w, errors.InternalError(err))
+		return
+	}
+
+	response.OK(w, discrepancyListResponse{Discrepancies: discrepancies, Limit: limit, Offset: offset})
+}
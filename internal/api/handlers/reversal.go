@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	stderrors "errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/service"
+	"github.com/shanwije/wallet-app/pkg/auth"
+	"github.com/shanwije/wallet-app/pkg/errors"
+	"github.com/shanwije/wallet-app/pkg/logger"
+	"github.com/shanwije/wallet-app/pkg/money"
+	"github.com/shanwije/wallet-app/pkg/response"
+)
+
+type reversalRequest struct {
+	// Amount to reverse, in the currency the transfer was sent in; the whole transaction when omitted
+	Amount   *decimal.Decimal `json:"amount,omitempty" swaggertype:"string" example:"5.00"`
+	Currency string           `json:"currency,omitempty"`
+	Reason   string           `json:"reason,omitempty" example:"Refund for cancelled order"`
+}
+
+// reversalAppError maps the failures of a reversal that have their own error code;
+// it returns nil for the rest
+func reversalAppError(err error, transactionID string) *errors.AppError {
+	switch {
+	case stderrors.Is(err, service.ErrTransactionNotFound):
+		return errors.New(errors.ErrTransactionNotFound, "Transaction not found", http.StatusNotFound).
+			WithDetails("transaction_id", transactionID)
+	case stderrors.Is(err, service.ErrAlreadyReversed):
+		return errors.New(errors.ErrAlreadyReversed, "Transaction has already been reversed or corrected", http.StatusConflict).
+			WithDetails("transaction_id", transactionID)
+	case stderrors.Is(err, service.ErrRefunded):
+		return errors.New(errors.ErrAlreadyRefunded, "Transaction has been refunded and can no longer be reversed", http.StatusConflict).
+			WithDetails("transaction_id", transactionID)
+	case stderrors.Is(err, service.ErrPaidOut):
+		return errors.New(errors.ErrPaidOut, "Transaction was paid out and can no longer be reversed", http.StatusConflict).
+			WithDetails("transaction_id", transactionID)
+	case stderrors.Is(err, service.ErrTransferAwaitingAcceptance):
+		return errors.Conflict(err.Error())
+	case stderrors.Is(err, service.ErrNotReversible),
+		stderrors.Is(err, service.ErrPartialReversal),
+		stderrors.Is(err, service.ErrInvalidReversalAmount),
+		stderrors.Is(err, money.ErrCurrencyMismatch):
+		return errors.InvalidInput(err.Error())
+	default:
+		return movementAppError(err)
+	}
+}
+
+// ReverseTransaction undoes a transaction with compensating ledger entries
+// @Summary Reverse a transaction
+// @Description Posts every leg of the transaction's journal again in the opposite direction,
+// @Description as a reversal that references it. A transfer can be reversed in part by
+// @Description passing an amount in the currency it was sent in. Each transaction can be
+// @Description reversed once. When auth is enabled only the owner of the wallet that
+// @Description received the money can reverse it.
+// @Tags transactions
+// @Accept json
+// @Produce json
+// @Param id path string true "Transaction ID"
+// @Param reversal body reversalRequest false "Partial amount and reason"
+// @Param Idempotency-Key header string false "Makes retries safe: a repeat with the same key and body gets the first response"
+// @Success 201 {object} models.Journal
+// @Failure 400 {object} response.Problem "Invalid transaction ID or amount"
+// @Failure 404 {object} response.Problem "Transaction not found"
+// @Failure 409 {object} response.Problem "Transaction already reversed, refunded or paid out, transfer awaiting acceptance, or Idempotency-Key reused with a different request body"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v1/transactions/{id}/reverse [post]
+// @Router /api/v1/admin/transactions/{id}/reverse [post]
+func (h *WalletHandler) ReverseTransaction(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.FromContext(ctx)
+	transactionIDStr := chi.URLParam(r, "id")
+	transactionID, err := uuid.Parse(transactionIDStr)
+	if err != nil {
+		response.ErrorMessage(w, http.StatusBadRequest, "Invalid transaction ID")
+		return
+	}
+
+	var req reversalRequest
+	if appErr := decodeOptionalRequest(r, &req); appErr != nil {
+		response.Error(w, appErr)
+		return
+	}
+
+	var amount *money.Money
+	if req.Amount != nil {
+		parsed, appErr := parseAmount(*req.Amount, req.Currency)
+		if appErr != nil {
+			response.Error(w, appErr)
+			return
+		}
+		amount = &parsed
+	}
+
+	if userID, ok := auth.UserIDFromContext(ctx); ok {
+		if appErr := h.authorizeReversal(r, transactionID, transactionIDStr, userID); appErr != nil {
+			response.Error(w, appErr)
+			return
+		}
+	}
+
+	reversal, err := h.WalletService.ReverseTransaction(ctx, transactionID, amount, req.Reason)
+	if err != nil {
+		log.Error("Failed to reverse transaction", zap.Error(err), zap.String("transaction_id", transactionIDStr))
+		if appErr := reversalAppError(err, transactionIDStr); appErr != nil {
+			response.Error(w, appErr)
+			return
+		}
+		response.Error(w, errors.InternalError(err))
+		return
+	}
+
+	log.Info("Transaction reversed",
+		zap.String("transaction_id", transactionIDStr),
+		zap.String("reversal_id", reversal.ID.String()))
+
+	response.Created(w, "", reversal)
+}
+
+// authorizeReversal lets the user reverse a transaction when they own a wallet it paid
+// money into. Transactions the user has no part in are reported as missing rather than
+// forbidden, so transaction IDs cannot be probed.
+func (h *WalletHandler) authorizeReversal(r *http.Request, transactionID uuid.UUID, transactionIDStr string, userID uuid.UUID) *errors.AppError {
+	journal, err := h.WalletService.GetTransactionJournal(r.Context(), transactionID)
+	if err != nil {
+		if appErr := reversalAppError(err, transactionIDStr); appErr != nil {
+			return appErr
+		}
+		return errors.InternalError(err)
+	}
+
+	party := false
+	for _, entry := range journal.Entries {
+		if entry.WalletID == nil {
+			continue
+		}
+		wallet, err := h.WalletService.GetBalance(r.Context(), *entry.WalletID)
+		if stderrors.Is(err, service.ErrWalletNotFound) {
+			continue
+		}
+		if err != nil {
+			return errors.InternalError(err)
+		}
+		if wallet.UserID != userID {
+			continue
+		}
+		if entry.Direction == models.EntryDirectionCredit {
+			return nil
+		}
+		party = true
+	}
+
+	if party {
+		return errors.Forbidden("Only the recipient of a transaction can reverse it")
+	}
+	return reversalAppError(service.ErrTransactionNotFound, transactionIDStr)
+}
@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	stderrors "errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+
+	"github.com/shanwije/wallet-app/internal/service"
+	"github.com/shanwije/wallet-app/pkg/errors"
+	"github.com/shanwije/wallet-app/pkg/logger"
+	"github.com/shanwije/wallet-app/pkg/response"
+)
+
+// ScheduledTransferHandler serves the scheduled transfers of a wallet
+type ScheduledTransferHandler struct {
+	ScheduledTransferService *service.ScheduledTransferService
+	// PINs asks for the owner's transaction PIN when scheduling transfers over its
+	// threshold; nil never asks for it
+	PINs *service.TransactionPINService
+}
+
+type scheduledTransferRequest struct {
+	ToWalletID  string          `json:"to_wallet_id"`
+	Amount      decimal.Decimal `json:"amount" swaggertype:"string" example:"25.00"`
+	Currency    string          `json:"currency,omitempty"`
+	Description string          `json:"description,omitempty"`
+	// StartAt is when the first transfer runs (RFC3339); now when omitted
+	StartAt   *time.Time `json:"start_at,omitempty"`
+	Frequency string     `json:"frequency" example:"monthly"` // once, daily, weekly, monthly
+}
+
+// NewScheduledTransferHandler creates a new ScheduledTransferHandler
+func NewScheduledTransferHandler(scheduledTransferService *service.ScheduledTransferService) *ScheduledTransferHandler {
+	return &ScheduledTransferHandler{
+		ScheduledTransferService: scheduledTransferService,
+	}
+}
+
+// Create schedules a one-time or recurring transfer out of the wallet
+// @Summary Schedule a transfer
+// @Description Runs once at start_at, or daily, weekly or monthly from start_at until cancelled
+// @Tags scheduled-transfers
+// @Accept json
+// @Produce json
+// @Param id path string true "Source wallet ID"
+// @Param transfer body scheduledTransferRequest true "Scheduled transfer details"
+// @Param Idempotency-Key header string false "Makes retries safe: a repeat with the same key and body gets the first response"
+// @Param X-Transaction-PIN header string false "Owner's transaction PIN, needed over the PIN threshold once they have set one"
+// @Success 201 {object} models.ScheduledTransfer
+// @Failure 400 {object} response.Problem "Invalid wallet ID or schedule"
+// @Failure 403 {object} response.Problem "Transaction PIN required or incorrect"
+// @Failure 409 {object} response.Problem "Idempotency-Key reused with a different request body"
+// @Failure 422 {object} response.Problem "Amount over the confirmation threshold"
+// @Failure 423 {object} response.Problem "Transaction PIN locked after too many incorrect attempts"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v1/wallets/{id}/scheduled-transfers [post]
+func (h *ScheduledTransferHandler) Create(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+	walletIDStr := chi.URLParam(r, "id")
+	fromWalletID, err := uuid.Parse(walletIDStr)
+	if err != nil {
+		response.ErrorMessage(w, http.StatusBadRequest, "Invalid wallet ID")
+		return
+	}
+
+	var req scheduledTransferRequest
+	if appErr := decodeRequest(r, &req); appErr != nil {
+		response.Error(w, appErr)
+		return
+	}
+
+	toWalletID, err := uuid.Parse(req.ToWalletID)
+	if err != nil {
+		response.ErrorMessage(w, http.StatusBadRequest, "Invalid destination wallet ID")
+		return
+	}
+
+	amount, appErr := parseAmount(req.Amount, req.Currency)
+	if appErr != nil {
+		response.Error(w, appErr)
+		return
+	}
+	// Each run moves the money without the owner there, so the PIN is asked for once,
+	// by the amount of a run, when the transfer is scheduled
+	if appErr := authorizePIN(r, h.PINs, fromWalletID, amount); appErr != nil {
+		response.Error(w, appErr)
+		return
+	}
+
+	var startAt time.Time
+	if req.StartAt != nil {
+		startAt = *req.StartAt
+	}
+
+	transfer, err := h.ScheduledTransferService.Schedule(r.Context(), fromWalletID, toWalletID, amount, req.Description, startAt, req.Frequency)
+	if err != nil {
+		log.Error("Failed to schedule transfer", zap.Error(err),
+			zap.String("wallet_id", walletIDStr),
+			zap.String("to_wallet_id", req.ToWalletID))
+		if appErr := movementAppError(err); appErr != nil {
+			response.Error(w, appErr)
+			return
+		}
+		response.ErrorMessage(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	log.Info("Transfer scheduled",
+		zap.String("scheduled_transfer_id", transfer.ID.String()),
+		zap.String("wallet_id", walletIDStr),
+		zap.String("frequency", transfer.Frequency))
+
+	response.Created(w, "", transfer)
+}
+
+// List returns the wallet's scheduled transfers
+// @Summary List scheduled transfers
+// @Tags scheduled-transfers
+// @Produce json
+// @Param id path string true "Source wallet ID"
+// @Success 200 {array} models.ScheduledTransfer
+// @Failure 400 {object} response.Problem "Invalid wallet ID"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v1/wallets/{id}/scheduled-transfers [get]
+func (h *ScheduledTransferHandler) List(w http.ResponseWriter, r *http.Request) {
+	walletID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.ErrorMessage(w, http.StatusBadRequest, "Invalid wallet ID")
+		return
+	}
+
+	transfers, err := h.ScheduledTransferService.List(r.Context(), walletID)
+	if err != nil {
+		response.Error(w, errors.InternalError(err))
+		return
+	}
+
+	response.OK(w, transfers)
+}
+
+// Cancel stops a scheduled transfer from running again
+// @Summary Cancel a scheduled transfer
+// @Tags scheduled-transfers
+// @Produce json
+// @Param id path string true "Source wallet ID"
+// @Param transferID path string true "Scheduled transfer ID"
+// @Success 200 {object} models.ScheduledTransfer
+// @Failure 400 {object} response.Problem "Invalid wallet ID or scheduled transfer ID"
+// @Failure 404 {object} response.Problem "Scheduled transfer not found"
+// @Failure 409 {object} response.Problem "Scheduled transfer is no longer active"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v1/wallets/{id}/scheduled-transfers/{transferID} [delete]
+func (h *ScheduledTransferHandler) Cancel(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+	walletID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.ErrorMessage(w, http.StatusBadRequest, "Invalid wallet ID")
+		return
+	}
+	transferIDStr := chi.URLParam(r, "transferID")
+	transferID, err := uuid.Parse(transferIDStr)
+	if err != nil {
+		response.ErrorMessage(w, http.StatusBadRequest, "Invalid scheduled transfer ID")
+		return
+	}
+
+	transfer, err := h.ScheduledTransferService.Cancel(r.Context(), walletID, transferID)
+	if err != nil {
+		switch {
+		case stderrors.Is(err, service.ErrScheduledTransferNotFound):
+			response.Error(w, errors.New(errors.ErrScheduledTransferNotFound, err.Error(), http.StatusNotFound).
+				WithDetails("scheduled_transfer_id", transferIDStr))
+		case stderrors.Is(err, service.ErrScheduledTransferNotActive):
+			response.Error(w, errors.Conflict(err.Error()))
+		default:
+			log.Error("Failed to cancel scheduled transfer", zap.Error(err), zap.String("scheduled_transfer_id", transferIDStr))
+			response.Error(w, errors.InternalError(err))
+		}
+		return
+	}
+
+	log.Info("Scheduled transfer cancelled", zap.String("scheduled_transfer_id", transferIDStr))
+
+	response.OK(w, transfer)
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository"
+	"github.com/shanwije/wallet-app/internal/service"
+	"github.com/shanwije/wallet-app/pkg/money"
+)
+
+// WalletServiceInterface is what the handlers use of *service.WalletService, so
+// handler tests can stand in for the service layer and check how its results and
+// errors become responses
+type WalletServiceInterface interface {
+	GetBalance(ctx context.Context, walletID uuid.UUID) (*models.Wallet, error)
+	GetBalanceAt(ctx context.Context, walletID uuid.UUID, at time.Time) (*models.HistoricalBalance, error)
+	SearchWallets(ctx context.Context, filter repository.WalletFilter) ([]*models.Wallet, error)
+	SetWalletStatus(ctx context.Context, walletID uuid.UUID, status string) (*models.Wallet, error)
+	AdjustBalance(ctx context.Context, walletID uuid.UUID, amount money.Money, reason, idempotencyKey string) (*models.Wallet, error)
+
+	CreateDeposit(ctx context.Context, walletID uuid.UUID, amount money.Money, idempotencyKey string) (*service.MovementResult, error)
+	CreateWithdrawal(ctx context.Context, walletID uuid.UUID, amount money.Money, idempotencyKey string) (*service.MovementResult, error)
+	Transfer(ctx context.Context, fromWalletID, toWalletID uuid.UUID, amount money.Money, description, idempotencyKey string) error
+	CreateTransfer(ctx context.Context, fromWalletID, toWalletID uuid.UUID, amount money.Money, description, idempotencyKey string) (*service.TransferResult, error)
+	BatchTransfer(ctx context.Context, fromWalletID uuid.UUID, items []service.BatchTransferItem, idempotencyKey string) ([]*models.Journal, error)
+	Sweep(ctx context.Context, fromWalletID, toWalletID uuid.UUID, description, idempotencyKey string) (*service.TransferResult, error)
+	ResolveRecipient(ctx context.Context, recipient service.Recipient) (uuid.UUID, error)
+	QuoteFee(ctx context.Context, walletID uuid.UUID, journalType string, amount money.Money) (*models.FeeQuote, error)
+	QuoteTransfer(ctx context.Context, fromWalletID, toWalletID uuid.UUID, amount money.Money) (*models.TransferQuote, error)
+
+	GetTransaction(ctx context.Context, transactionID uuid.UUID) (*models.Transaction, error)
+	GetTransactionJournal(ctx context.Context, transactionID uuid.UUID) (*models.Journal, error)
+	GetTransactionHistory(ctx context.Context, walletID uuid.UUID) ([]*models.Transaction, error)
+	GetTransactionHistoryInCategory(ctx context.Context, walletID uuid.UUID, category string) ([]*models.Transaction, error)
+	ListTransactionHistory(ctx context.Context, walletID uuid.UUID, filter repository.TransactionFilter) ([]*models.Transaction, error)
+	GetTransfer(ctx context.Context, referenceID uuid.UUID) (*models.Transfer, error)
+	ListTransfers(ctx context.Context, walletID uuid.UUID, limit, offset int) ([]*models.WalletTransfer, error)
+	WriteStatement(ctx context.Context, walletID uuid.UUID, from, to time.Time, w service.StatementWriter) error
+	GetAnalytics(ctx context.Context, walletID uuid.UUID, from, to time.Time) (*models.WalletAnalytics, error)
+	ReverseTransaction(ctx context.Context, transactionID uuid.UUID, amount *money.Money, reason string) (*models.Journal, error)
+	CorrectTransaction(ctx context.Context, transactionID uuid.UUID, amount *money.Money, reason, note string) (*models.Journal, error)
+
+	PlaceHold(ctx context.Context, walletID uuid.UUID, amount money.Money, description string) (*models.Hold, error)
+	CaptureHold(ctx context.Context, walletID, holdID uuid.UUID, amount *money.Money) (*models.Hold, error)
+	ReleaseHold(ctx context.Context, walletID, holdID uuid.UUID) (*models.Hold, error)
+	ListHolds(ctx context.Context, walletID uuid.UUID) ([]*models.Hold, error)
+
+	GetWalletLimits(ctx context.Context, walletID uuid.UUID) (*models.WalletLimits, error)
+	SetWalletLimits(ctx context.Context, limits *models.WalletLimits) (*models.WalletLimits, error)
+	GetAlertSettings(ctx context.Context, walletID uuid.UUID) (*models.WalletAlertSettings, error)
+	SetAlertSettings(ctx context.Context, settings *models.WalletAlertSettings) (*models.WalletAlertSettings, error)
+	ListAlerts(ctx context.Context, walletID uuid.UUID, limit, offset int) ([]*models.WalletAlert, error)
+	ListRiskDecisions(ctx context.Context, filter repository.RiskDecisionFilter) ([]*models.RiskDecision, error)
+}
+
+// UserServiceInterface is what the handlers use of *service.UserService
+type UserServiceInterface interface {
+	CreateUserWithContact(ctx context.Context, name string, contact models.Contact) (*models.UserWithWallet, error)
+	Register(ctx context.Context, name, username, password string) (*models.UserWithWallet, error)
+	Login(ctx context.Context, username, password string) (*models.User, error)
+	GetUserWithWallet(ctx context.Context, id uuid.UUID) (*models.UserWithWallet, error)
+	GetUserWallet(ctx context.Context, id uuid.UUID) (*models.Wallet, error)
+	UpdateUser(ctx context.Context, id uuid.UUID, update service.UserUpdate, expectedVersions []int64) (*models.User, error)
+	DeleteUser(ctx context.Context, id uuid.UUID, withdrawBalance bool) error
+	ListUsers(ctx context.Context, limit, offset int) ([]*models.User, error)
+	SearchUsers(ctx context.Context, query string, limit, offset int) ([]*models.User, error)
+}
+
+var (
+	_ WalletServiceInterface = (*service.WalletService)(nil)
+	_ UserServiceInterface   = (*service.UserService)(nil)
+)
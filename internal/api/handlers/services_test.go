@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"context"
+	stderrors "errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/service"
+	"github.com/shanwije/wallet-app/internal/testutil"
+	"github.com/shanwije/wallet-app/pkg/money"
+)
+
+// mockWalletService stands in for the wallet service. Methods a test has no use for
+// are left to the embedded interface and panic if called.
+type mockWalletService struct {
+	WalletServiceInterface
+	mock.Mock
+}
+
+func (m *mockWalletService) CreateWithdrawal(ctx context.Context, walletID uuid.UUID, amount money.Money, idempotencyKey string) (*service.MovementResult, error) {
+	args := m.Called(ctx, walletID, amount, idempotencyKey)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.MovementResult), args.Error(1)
+}
+
+// mockUserService stands in for the user service
+type mockUserService struct {
+	UserServiceInterface
+	mock.Mock
+}
+
+func (m *mockUserService) GetUserWithWallet(ctx context.Context, id uuid.UUID) (*models.UserWithWallet, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.UserWithWallet), args.Error(1)
+}
+
+// amountOf matches a money argument of the amount in US dollars
+func amountOf(amount int64) any {
+	return mock.MatchedBy(func(m money.Money) bool {
+		return m.Equal(testutil.USD(decimal.NewFromInt(amount)))
+	})
+}
+
+func TestWithdrawMapsServiceErrorsToStatuses(t *testing.T) {
+	maxAmount := decimal.NewFromInt(5)
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		code   string
+	}{
+		{"wallet not found", service.ErrWalletNotFound, http.StatusNotFound, "WALLET_NOT_FOUND"},
+		{"insufficient balance", service.ErrInsufficientBalance, http.StatusBadRequest, "INSUFFICIENT_FUNDS"},
+		{"limit exceeded", service.ErrLimitExceeded, http.StatusUnprocessableEntity, "LIMIT_EXCEEDED"},
+		{"blocked by risk check", service.ErrBlockedByRiskCheck, http.StatusForbidden, "OPERATION_BLOCKED"},
+		{"concurrent update", service.ErrConcurrentUpdate, http.StatusConflict, "CONFLICT"},
+		{"wallet frozen", models.ErrWalletFrozen, http.StatusConflict, "WALLET_FROZEN"},
+		{"wrapped", stderrors.Join(stderrors.New("withdraw"), service.ErrWalletNotFound), http.StatusNotFound, "WALLET_NOT_FOUND"},
+		{"out of range", &service.AmountRangeError{Amount: testutil.USD(decimal.NewFromInt(10)), Max: &maxAmount}, http.StatusBadRequest, "AMOUNT_OUT_OF_RANGE"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wallets := new(mockWalletService)
+			walletID := uuid.New()
+			wallets.On("CreateWithdrawal", mock.Anything, walletID, amountOf(10), "").Return(nil, tt.err)
+
+			router := chi.NewRouter()
+			router.Post("/wallets/{id}/withdraw", (&WalletHandler{WalletService: wallets}).WithdrawV2)
+			req := httptest.NewRequest(http.MethodPost, "/wallets/"+walletID.String()+"/withdraw", strings.NewReader(`{"amount":10}`))
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.status, rr.Code, rr.Body.String())
+			assert.Contains(t, rr.Body.String(), `"code":"`+tt.code+`"`)
+			if tt.code == "AMOUNT_OUT_OF_RANGE" {
+				assert.Contains(t, rr.Body.String(), `"max_amount":"5"`)
+			}
+			wallets.AssertExpectations(t)
+		})
+	}
+}
+
+func TestWithdrawRespondsWithTheMovement(t *testing.T) {
+	wallets := new(mockWalletService)
+	walletID := uuid.New()
+	wallet := testutil.Wallet(walletID, 90)
+	wallets.On("CreateWithdrawal", mock.Anything, walletID, amountOf(10), "withdraw-1").Return(&service.MovementResult{
+		Transaction:     testutil.Transaction(walletID, models.TransactionTypeWithdraw, 10),
+		PreviousBalance: decimal.NewFromInt(100),
+		NewBalance:      decimal.NewFromInt(90),
+		Wallet:          wallet,
+	}, nil)
+
+	router := chi.NewRouter()
+	router.Post("/wallets/{id}/withdraw", (&WalletHandler{WalletService: wallets}).WithdrawV2)
+	req := httptest.NewRequest(http.MethodPost, "/wallets/"+walletID.String()+"/withdraw", strings.NewReader(`{"amount":10}`))
+	req.Header.Set("Idempotency-Key", "withdraw-1")
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	assert.Contains(t, rr.Body.String(), `"previous_balance":"100"`)
+	assert.Contains(t, rr.Body.String(), `"new_balance":"90"`)
+	wallets.AssertExpectations(t)
+}
+
+func TestGetUserMapsServiceErrorsToStatuses(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+	}{
+		{"user not found", service.ErrUserNotFound, http.StatusNotFound},
+		{"no wallet", service.ErrUserHasNoWallet, http.StatusNotFound},
+		{"unexpected", stderrors.New("connection reset"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := new(mockUserService)
+			userID := uuid.New()
+			users.On("GetUserWithWallet", mock.Anything, userID).Return(nil, tt.err)
+
+			router := chi.NewRouter()
+			router.Get("/users/{id}", (&UserHandler{UserService: users}).GetUser)
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/users/"+userID.String(), nil))
+
+			assert.Equal(t, tt.status, rr.Code, rr.Body.String())
+			assert.NotContains(t, rr.Body.String(), "connection reset", "internal errors are not leaked")
+			users.AssertExpectations(t)
+		})
+	}
+}
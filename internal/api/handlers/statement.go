@@ -0,0 +1,227 @@
+package handlers
+
+import (
+	stderrors "errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jung-kurt/gofpdf"
+	"go.uber.org/zap"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/service"
+	"github.com/shanwije/wallet-app/pkg/errors"
+	"github.com/shanwije/wallet-app/pkg/logger"
+	"github.com/shanwije/wallet-app/pkg/response"
+)
+
+const statementDateLayout = "2006-01-02"
+
+// GetStatement exports a wallet statement
+// @Summary Export wallet statement
+// @Description Opening balance, each transaction with the running balance, and closing balance for a period. CSV is streamed row by row.
+// @Tags wallets
+// @Produce text/csv
+// @Produce application/pdf
+// @Param id path string true "Wallet ID"
+// @Param from query string false "Period start as YYYY-MM-DD or RFC3339; defaults to when the wallet was opened"
+// @Param to query string false "Period end as YYYY-MM-DD (inclusive) or RFC3339 (exclusive); defaults to now"
+// @Param format query string false "Statement format" Enums(csv, pdf) default(csv)
+// @Success 200 {file} file
+// @Failure 400 {object} response.Problem "Invalid wallet ID, period or format"
+// @Failure 404 {object} response.Problem "Wallet not found"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v1/wallets/{id}/statement [get]
+func (h *WalletHandler) GetStatement(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+	ctx := r.Context()
+	walletIDStr := chi.URLParam(r, "id")
+	walletID, err := uuid.Parse(walletIDStr)
+	if err != nil {
+		response.ErrorMessage(w, http.StatusBadRequest, "Invalid wallet ID")
+		return
+	}
+
+	query := r.URL.Query()
+	from, err := parseStatementTime(query.Get("from"), false)
+	if err != nil {
+		response.Error(w, errors.InvalidInput("Invalid from date").WithDetails("from", query.Get("from")))
+		return
+	}
+	to, err := parseStatementTime(query.Get("to"), true)
+	if err != nil {
+		response.Error(w, errors.InvalidInput("Invalid to date").WithDetails("to", query.Get("to")))
+		return
+	}
+
+	resp := &statementResponse{ResponseWriter: w}
+	var writer service.StatementWriter
+	switch format := query.Get("format"); format {
+	case "", "csv":
+		writer = newCSVStatementWriter(resp)
+	case "pdf":
+		writer = newPDFStatementWriter(resp)
+	default:
+		response.Error(w, errors.InvalidInput("Unsupported statement format").WithDetails("format", format))
+		return
+	}
+
+	err = h.WalletService.WriteStatement(ctx, walletID, from, to, writer)
+	if err == nil {
+		return
+	}
+
+	log.Error("Statement export failed", zap.Error(err), zap.String("wallet_id", walletIDStr))
+	if resp.written {
+		// The status line is already out; the missing closing balance marks the statement as incomplete
+		return
+	}
+	w.Header().Del("Content-Disposition")
+	if stderrors.Is(err, service.ErrInvalidStatementPeriod) {
+		response.Error(w, errors.InvalidInput(err.Error()))
+		return
+	}
+	response.Error(w, walletAppError(err, walletIDStr))
+}
+
+// parseStatementTime accepts an RFC3339 timestamp or a calendar date in UTC. A date
+// used as the end of a period covers that whole day. An empty value is the zero time.
+func parseStatementTime(value string, endOfPeriod bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(statementDateLayout, value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if endOfPeriod {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+// statementResponse records whether any of the statement body has been sent, after
+// which an error can no longer be reported with a status code
+type statementResponse struct {
+	http.ResponseWriter
+	written bool
+}
+
+func (r *statementResponse) Write(p []byte) (int, error) {
+	r.written = true
+	return r.ResponseWriter.Write(p)
+}
+
+// statementFilename names the download after the wallet and the first and last days
+// the period covers
+func statementFilename(statement *models.Statement, ext string) string {
+	lastDay := statement.To.Add(-time.Nanosecond)
+	return fmt.Sprintf("statement-%s-%s-%s.%s", statement.WalletID,
+		statement.From.UTC().Format(statementDateLayout), lastDay.UTC().Format(statementDateLayout), ext)
+}
+
+// csvStatementWriter streams a statement as CSV, naming the download once the period
+// is known
+type csvStatementWriter struct {
+	*service.CSVStatementWriter
+	w http.ResponseWriter
+}
+
+func newCSVStatementWriter(w http.ResponseWriter) *csvStatementWriter {
+	return &csvStatementWriter{CSVStatementWriter: service.NewCSVStatementWriter(w), w: w}
+}
+
+func (c *csvStatementWriter) Begin(statement *models.Statement) error {
+	c.w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	c.w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", statementFilename(statement, "csv")))
+	return c.CSVStatementWriter.Begin(statement)
+}
+
+// pdfStatementWriter lays a statement out as a PDF table. The document is
+// assembled in memory and written once the closing balance is known.
+type pdfStatementWriter struct {
+	w         http.ResponseWriter
+	pdf       *gofpdf.Fpdf
+	tr        func(string) string
+	statement *models.Statement
+}
+
+// pdfColumns are the table headings and their widths in millimetres
+var pdfColumns = []struct {
+	title string
+	width float64
+	align string
+}{
+	{"Date", 38, "L"},
+	{"Type", 26, "L"},
+	{"Description", 66, "L"},
+	{"Amount", 30, "R"},
+	{"Balance", 30, "R"},
+}
+
+func newPDFStatementWriter(w http.ResponseWriter) *pdfStatementWriter {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	return &pdfStatementWriter{w: w, pdf: pdf, tr: pdf.UnicodeTranslatorFromDescriptor("")}
+}
+
+func (p *pdfStatementWriter) Begin(statement *models.Statement) error {
+	p.statement = statement
+	p.w.Header().Set("Content-Type", "application/pdf")
+	p.w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", statementFilename(statement, "pdf")))
+
+	p.pdf.AddPage()
+	p.pdf.SetFont("Helvetica", "B", 14)
+	p.pdf.CellFormat(0, 8, "Account statement", "", 1, "L", false, 0, "")
+	p.pdf.SetFont("Helvetica", "", 10)
+	p.pdf.CellFormat(0, 6, fmt.Sprintf("Wallet %s (%s)", statement.WalletID, statement.Currency), "", 1, "L", false, 0, "")
+	p.pdf.CellFormat(0, 6, fmt.Sprintf("Period %s to %s",
+		statement.From.UTC().Format(time.RFC3339), statement.To.UTC().Format(time.RFC3339)), "", 1, "L", false, 0, "")
+	p.pdf.Ln(4)
+
+	p.pdf.SetFont("Helvetica", "B", 10)
+	for _, col := range pdfColumns {
+		p.pdf.CellFormat(col.width, 7, col.title, "B", 0, col.align, false, 0, "")
+	}
+	p.pdf.Ln(-1)
+
+	p.pdf.SetFont("Helvetica", "", 9)
+	p.row(statement.From, "Opening balance", "", "", statement.FormatAmount(statement.OpeningBalance))
+	return p.pdf.Error()
+}
+
+func (p *pdfStatementWriter) Line(line *models.StatementLine) error {
+	transaction := line.Transaction
+	var description string
+	if transaction.Description != nil {
+		description = *transaction.Description
+	}
+
+	p.row(transaction.CreatedAt, transaction.Type, description,
+		p.statement.FormatAmount(transaction.SignedAmount()),
+		p.statement.FormatAmount(line.Balance))
+	return p.pdf.Error()
+}
+
+func (p *pdfStatementWriter) End(statement *models.Statement) error {
+	p.row(statement.To, "Closing balance", "", "", statement.FormatAmount(statement.ClosingBalance))
+	return p.pdf.Output(p.w)
+}
+
+// row writes one table row, truncating descriptions that do not fit their column
+func (p *pdfStatementWriter) row(at time.Time, kind, description, amount, balance string) {
+	cells := []string{at.UTC().Format("2006-01-02 15:04:05"), kind, description, amount, balance}
+	for i, col := range pdfColumns {
+		text := p.tr(cells[i])
+		for len(text) > 0 && p.pdf.GetStringWidth(text) > col.width-2 {
+			text = text[:len(text)-1]
+		}
+		p.pdf.CellFormat(col.width, 6, text, "", 0, col.align, false, 0, "")
+	}
+	p.pdf.Ln(-1)
+}
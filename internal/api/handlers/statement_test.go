@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/pkg/money"
+)
+
+// writeTestStatement drives a writer through a statement with a single withdrawal
+func writeTestStatement(t *testing.T, begin func(*models.Statement) error, line func(*models.StatementLine) error, end func(*models.Statement) error) {
+	description := "ATM, Main St"
+	reference := uuid.MustParse("0190f5d2-0000-7000-8000-000000000001")
+	statement := &models.Statement{
+		WalletID:       uuid.MustParse("0190f5d2-0000-7000-8000-0000000000aa"),
+		Currency:       money.USD,
+		From:           time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC),
+		To:             time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC),
+		OpeningBalance: decimal.NewFromInt(100),
+	}
+
+	require.NoError(t, begin(statement))
+	require.NoError(t, line(&models.StatementLine{
+		Transaction: &models.Transaction{
+			Type:        models.TransactionTypeWithdraw,
+			Amount:      decimal.NewFromFloat(20.5),
+			ReferenceID: &reference,
+			Description: &description,
+			CreatedAt:   time.Date(2024, 6, 3, 10, 0, 0, 0, time.UTC),
+		},
+		Balance: decimal.NewFromFloat(79.5),
+	}))
+	statement.ClosingBalance = decimal.NewFromFloat(79.5)
+	require.NoError(t, end(statement))
+}
+
+func TestCSVStatementWriter(t *testing.T) {
+	rr := httptest.NewRecorder()
+	w := newCSVStatementWriter(rr)
+
+	writeTestStatement(t, w.Begin, w.Line, w.End)
+
+	assert.Equal(t, "text/csv; charset=utf-8", rr.Header().Get("Content-Type"))
+	assert.Equal(t,
+		`attachment; filename="statement-0190f5d2-0000-7000-8000-0000000000aa-2024-06-01-2024-06-30.csv"`,
+		rr.Header().Get("Content-Disposition"))
+	assert.Equal(t, "date,type,description,reference_id,amount,balance\n"+
+		"2024-06-01T00:00:00Z,opening_balance,,,,100.00\n"+
+		"2024-06-03T10:00:00Z,withdraw,\"ATM, Main St\",0190f5d2-0000-7000-8000-000000000001,-20.50,79.50\n"+
+		"2024-07-01T00:00:00Z,closing_balance,,,,79.50\n",
+		rr.Body.String())
+}
+
+func TestPDFStatementWriter(t *testing.T) {
+	rr := httptest.NewRecorder()
+	w := newPDFStatementWriter(rr)
+
+	writeTestStatement(t, w.Begin, w.Line, w.End)
+
+	assert.Equal(t, "application/pdf", rr.Header().Get("Content-Type"))
+	assert.True(t, bytes.HasPrefix(rr.Body.Bytes(), []byte("%PDF-")))
+}
+
+func TestParseStatementTime(t *testing.T) {
+	from, err := parseStatementTime("2024-06-01", false)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), from)
+
+	// A date as the end of a period includes the whole day
+	to, err := parseStatementTime("2024-06-30", true)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC), to)
+
+	exact, err := parseStatementTime("2024-06-30T12:00:00Z", true)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 6, 30, 12, 0, 0, 0, time.UTC), exact)
+
+	empty, err := parseStatementTime("", false)
+	require.NoError(t, err)
+	assert.True(t, empty.IsZero())
+
+	_, err = parseStatementTime("June 1st", false)
+	assert.Error(t, err)
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"github.com/shanwije/wallet-app/pkg/errors"
+	"github.com/shanwije/wallet-app/pkg/response"
+)
+
+// sweepRequest names the recipient the way a transfer does; there is no amount
+type sweepRequest struct {
+	ToWalletID  string `json:"to_wallet_id,omitempty" validate:"omitempty,uuid"`
+	ToUserID    string `json:"to_user_id,omitempty" validate:"omitempty,uuid"`
+	ToUsername  string `json:"to_username,omitempty" example:"@alice"`
+	Description string `json:"description,omitempty"`
+}
+
+// Sweep moves the wallet's whole available balance to another wallet
+// @Summary Sweep a wallet
+// @Description Transfers everything the wallet can spend to the recipient, for instance before closing it. The amount is worked out by the server as the transfer runs, so it cannot race a balance read by the client.
+// @Description Held funds stay in the wallet and an overdraft is not drawn on. The transfer fee is taken out of what is swept. Sweeps cannot be held for confirmation, so one over the confirmation threshold is refused.
+// @Description A wallet with nothing to sweep is answered with 400 and code INSUFFICIENT_FUNDS. A retry with the same Idempotency-Key gets the sweep it first made.
+// @Tags wallets
+// @Accept json
+// @Produce json
+// @Param id path string true "Wallet ID"
+// @Param sweep body sweepRequest true "Recipient: one of to_wallet_id, to_user_id or to_username"
+// @Param Idempotency-Key header string false "Makes retries safe: a repeat with the same key and recipient gets the first response"
+// @Param X-Transaction-PIN header string false "Owner's transaction PIN, needed over the PIN threshold once they have set one"
+// @Param If-Match header string false "ETag of the wallet from its balance; the request fails with 412 if the wallet has changed since"
+// @Success 201 {object} transferResponse
+// @Header 201 {string} Location "The created transfer"
+// @Failure 400 {object} response.Problem "Invalid wallet ID or request body, or nothing to sweep"
+// @Failure 403 {object} response.Problem "Transaction PIN required or incorrect"
+// @Failure 404 {object} response.Problem "Wallet or recipient not found"
+// @Failure 409 {object} response.Problem "Wallet frozen or closed, concurrent update, or Idempotency-Key reused"
+// @Failure 412 {object} response.Problem "Wallet changed since the ETag in If-Match was read"
+// @Failure 422 {object} response.Problem "Wallet limit exceeded, or amount over the confirmation threshold"
+// @Failure 423 {object} response.Problem "Transaction PIN locked after too many incorrect attempts"
+// @Failure 429 {object} response.Problem "Rate limit exceeded"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v1/wallets/{id}/sweep [post]
+func (h *WalletHandler) Sweep(w http.ResponseWriter, r *http.Request) {
+	fromWalletID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.Error(w, errors.InvalidInput("Invalid source wallet ID"))
+		return
+	}
+
+	var req sweepRequest
+	if appErr := decodeRequest(r, &req); appErr != nil {
+		response.Error(w, appErr)
+		return
+	}
+	if appErr := validateRequest(req); appErr != nil {
+		response.Error(w, appErr)
+		return
+	}
+
+	recipient := transferRequest{ToWalletID: req.ToWalletID, ToUserID: req.ToUserID, ToUsername: req.ToUsername}
+	toWalletID, err := h.WalletService.ResolveRecipient(r.Context(), recipientFromRequest(recipient))
+	if err != nil {
+		response.Error(w, recipientAppError(err, recipient))
+		return
+	}
+
+	ctx, appErr := withIfMatch(r, fromWalletID)
+	if appErr != nil {
+		response.Error(w, appErr)
+		return
+	}
+	// The PIN is asked for by what the wallet could spend now; the sweep moves no more
+	// than that unless money arrives in the meantime
+	if h.PINs != nil {
+		wallet, err := h.WalletService.GetBalance(ctx, fromWalletID)
+		if err != nil {
+			response.Error(w, walletAppError(err, fromWalletID.String()))
+			return
+		}
+		if appErr := authorizePIN(r, h.PINs, fromWalletID, wallet.Available()); appErr != nil {
+			response.Error(w, appErr)
+			return
+		}
+	}
+	result, err := h.WalletService.Sweep(ctx, fromWalletID, toWalletID, req.Description, r.Header.Get("Idempotency-Key"))
+	if err != nil {
+		if appErr := movementAppError(err); appErr != nil {
+			response.Error(w, appErr)
+			return
+		}
+		response.ErrorMessage(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response.Created(w, "/api/v2/transfers/"+result.Transfer.ReferenceID.String(), newTransferResponse(result))
+}
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shanwije/wallet-app/internal/fees"
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository/sqlite"
+	"github.com/shanwije/wallet-app/pkg/money"
+	"github.com/shanwije/wallet-app/pkg/response"
+)
+
+func TestSweepMovesTheAvailableBalance(t *testing.T) {
+	conn := newTestDB(t)
+	wallets := walletServiceOn(conn)
+	wallets.HoldRepo = sqlite.NewHoldRepository(conn)
+	wallets.LimitsRepo = sqlite.NewWalletLimitsRepository(conn)
+	ctx := context.Background()
+
+	from, to := createUserWallet(t, wallets), createUserWallet(t, wallets)
+	_, err := wallets.Deposit(ctx, from.ID, money.New(decimal.NewFromInt(100), money.DefaultCurrency), "")
+	require.NoError(t, err)
+	_, err = wallets.PlaceHold(ctx, from.ID, money.New(decimal.NewFromInt(30), money.DefaultCurrency), "Hotel")
+	require.NoError(t, err)
+	// An overdraft is not drawn on
+	overdraft := decimal.NewFromInt(50)
+	_, err = wallets.SetWalletLimits(ctx, &models.WalletLimits{WalletID: from.ID, OverdraftLimit: &overdraft})
+	require.NoError(t, err)
+
+	router := chi.NewRouter()
+	router.Post("/wallets/{id}/sweep", (&WalletHandler{WalletService: wallets}).Sweep)
+	sweep := func(idempotencyKey string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/wallets/"+from.ID.String()+"/sweep",
+			strings.NewReader(`{"to_wallet_id":"`+to.ID.String()+`","description":"Closing"}`))
+		req.Header.Set("Idempotency-Key", idempotencyKey)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := sweep("close")
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	var swept transferResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &swept))
+	assert.Equal(t, "70", swept.Amount.String())
+	assert.Equal(t, "30", swept.FromBalance.String(), "held funds stay in the wallet")
+	assert.Equal(t, "70", swept.ToBalance.String())
+	assert.Nil(t, swept.Fee)
+
+	// A retry gets the same sweep although there is nothing left to sweep
+	rr = sweep("close")
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	var replayed transferResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &replayed))
+	assert.Equal(t, swept.ReferenceID, replayed.ReferenceID)
+	assert.Equal(t, "70", replayed.Amount.String())
+
+	rr = sweep("again")
+	require.Equal(t, http.StatusBadRequest, rr.Code, rr.Body.String())
+	var problem response.Problem
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &problem))
+	assert.Equal(t, "INSUFFICIENT_FUNDS", problem.Code)
+
+	wallet, err := wallets.GetBalance(ctx, to.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "70", wallet.Balance.String())
+}
+
+func TestSweepPaysTheTransferFeeAndNeedsConfirmationOverTheThreshold(t *testing.T) {
+	conn := newTestDB(t)
+	wallets := walletServiceOn(conn)
+	schedule, err := fees.ParseSchedule("transfer=1.5%")
+	require.NoError(t, err)
+	ctx := context.Background()
+	collector, from, to := createUserWallet(t, wallets), createUserWallet(t, wallets), createUserWallet(t, wallets)
+	wallets.Fees, wallets.FeeWalletID = schedule, collector.ID
+	_, err = wallets.Deposit(ctx, from.ID, money.New(decimal.NewFromInt(100), money.DefaultCurrency), "")
+	require.NoError(t, err)
+
+	router := chi.NewRouter()
+	router.Post("/wallets/{id}/sweep", (&WalletHandler{WalletService: wallets}).Sweep)
+	sweep := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/wallets/"+from.ID.String()+"/sweep",
+			strings.NewReader(`{"to_wallet_id":"`+to.ID.String()+`"}`))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// A sweep cannot be held for confirmation, so one over the threshold is refused
+	threshold := decimal.NewFromInt(50)
+	wallets.ConfirmationThreshold = &threshold
+	rr := sweep()
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code, rr.Body.String())
+	assert.Contains(t, rr.Body.String(), "CONFIRMATION_REQUIRED")
+
+	// The fee comes out of what is swept, leaving the wallet empty
+	wallets.ConfirmationThreshold = nil
+	rr = sweep()
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	var swept transferResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &swept))
+	assert.Equal(t, "98.52", swept.Amount.String())
+	require.NotNil(t, swept.Fee)
+	assert.Equal(t, "1.48", swept.Fee.String())
+	assert.True(t, swept.FromBalance.IsZero())
+	collected, err := wallets.GetBalance(ctx, collector.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "1.48", collected.Balance.String())
+}
+
+func TestSweepRejectsTheSameWallet(t *testing.T) {
+	wallets := newWalletService(t)
+	wallet := createUserWallet(t, wallets)
+
+	router := chi.NewRouter()
+	router.Post("/wallets/{id}/sweep", (&WalletHandler{WalletService: wallets}).Sweep)
+	req := httptest.NewRequest(http.MethodPost, "/wallets/"+wallet.ID.String()+"/sweep",
+		strings.NewReader(`{"to_wallet_id":"`+wallet.ID.String()+`"}`))
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), "SAME_WALLET")
+}
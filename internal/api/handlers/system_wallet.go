@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	stderrors "errors"
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/service"
+	"github.com/shanwije/wallet-app/pkg/errors"
+	"github.com/shanwije/wallet-app/pkg/logger"
+	"github.com/shanwije/wallet-app/pkg/money"
+	"github.com/shanwije/wallet-app/pkg/response"
+)
+
+// SystemWalletHandler serves operators the wallets the platform keeps for itself
+type SystemWalletHandler struct {
+	SystemWalletService *service.SystemWalletService
+}
+
+type ensureSystemWalletRequest struct {
+	// Kind is treasury, suspense or fees
+	Kind     string `json:"kind" validate:"required" example:"fees"`
+	Currency string `json:"currency,omitempty" example:"USD"`
+}
+
+// systemWalletListResponse lists the system wallets
+type systemWalletListResponse struct {
+	Wallets []*models.Wallet `json:"wallets"`
+}
+
+// NewSystemWalletHandler creates a new SystemWalletHandler
+func NewSystemWalletHandler(systemWalletService *service.SystemWalletService) *SystemWalletHandler {
+	return &SystemWalletHandler{
+		SystemWalletService: systemWalletService,
+	}
+}
+
+// ListWallets returns every system wallet
+// @Summary List system wallets
+// @Description Returns the treasury, suspense and fees wallets of every currency that has them. A treasury wallet's balance is the negative of the money customers paid in and have not taken out, so with the customer wallets of its currency it sums to zero.
+// @Tags admin
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Success 200 {object} systemWalletListResponse
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v1/admin/system-wallets [get]
+func (h *SystemWalletHandler) ListWallets(w http.ResponseWriter, r *http.Request) {
+	wallets, err := h.SystemWalletService.ListWallets(r.Context())
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Failed to list system wallets", zap.Error(err))
+		response.Error(w, errors.InternalError(err))
+		return
+	}
+
+	response.OK(w, systemWalletListResponse{Wallets: wallets})
+}
+
+// EnsureWallet returns a system wallet, creating it when missing
+// @Summary Create a system wallet
+// @Description Returns the system wallet of the kind in the currency, creating it when there is none yet. Treasury and suspense wallets are created when first needed; create the fees wallet of a currency to point FEE_WALLET_ID at it.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param wallet body ensureSystemWalletRequest true "Kind and currency, USD when omitted"
+// @Success 200 {object} models.Wallet
+// @Failure 400 {object} response.Problem "Invalid kind or currency"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v1/admin/system-wallets [post]
+func (h *SystemWalletHandler) EnsureWallet(w http.ResponseWriter, r *http.Request) {
+	var req ensureSystemWalletRequest
+	if appErr := decodeRequest(r, &req); appErr != nil {
+		response.Error(w, appErr)
+		return
+	}
+	if appErr := validateRequest(req); appErr != nil {
+		response.Error(w, appErr)
+		return
+	}
+	currency := money.DefaultCurrency
+	if req.Currency != "" {
+		parsed, err := money.ParseCurrency(req.Currency)
+		if err != nil {
+			response.Error(w, errors.InvalidInput("Unsupported currency").WithDetails("currency", req.Currency))
+			return
+		}
+		currency = parsed
+	}
+
+	wallet, err := h.SystemWalletService.EnsureWallet(r.Context(), req.Kind, currency)
+	if stderrors.Is(err, service.ErrInvalidSystemWalletKind) {
+		response.Error(w, errors.InvalidInput(err.Error()).WithDetails("kind", req.Kind))
+		return
+	}
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Failed to create system wallet", zap.Error(err), zap.String("kind", req.Kind))
+		response.Error(w, errors.InternalError(err))
+		return
+	}
+
+	response.OK(w, wallet)
+}
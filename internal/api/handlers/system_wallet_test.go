@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository/sqlite"
+	"github.com/shanwije/wallet-app/internal/service"
+	"github.com/shanwije/wallet-app/pkg/money"
+)
+
+func TestSystemWalletsSettleExternalMovements(t *testing.T) {
+	ctx := context.Background()
+	conn := newTestDB(t)
+	wallets := walletServiceOn(conn)
+	wallets.HoldRepo = sqlite.NewHoldRepository(conn)
+	wallets.SystemWallets = &service.SystemWalletService{Repo: sqlite.NewWalletRepository(conn)}
+	handler := NewSystemWalletHandler(wallets.SystemWallets)
+	usd := func(amount int64) money.Money {
+		return money.New(decimal.NewFromInt(amount), money.DefaultCurrency)
+	}
+
+	wallet := createUserWallet(t, wallets)
+	_, err := wallets.Deposit(ctx, wallet.ID, usd(100), "")
+	require.NoError(t, err)
+	_, err = wallets.Withdraw(ctx, wallet.ID, usd(30), "")
+	require.NoError(t, err)
+	hold, err := wallets.PlaceHold(ctx, wallet.ID, usd(20), "")
+	require.NoError(t, err)
+	_, err = wallets.CaptureHold(ctx, wallet.ID, hold.ID, nil)
+	require.NoError(t, err)
+	_, err = wallets.AdjustBalance(ctx, wallet.ID, usd(5), "Goodwill credit", "")
+	require.NoError(t, err)
+
+	list := func() []*models.Wallet {
+		rr := httptest.NewRecorder()
+		handler.ListWallets(rr, httptest.NewRequest(http.MethodGet, "/admin/system-wallets", nil))
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		var page systemWalletListResponse
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &page))
+		return page.Wallets
+	}
+
+	// Every movement in or out of the platform is a transfer against a system wallet,
+	// so the wallets of a currency sum to zero
+	system := list()
+	require.Len(t, system, 2)
+	suspense, treasury := system[0], system[1]
+	assert.Equal(t, models.WalletKindSuspense, suspense.Kind)
+	assert.Equal(t, "-5", suspense.Balance.String())
+	assert.Equal(t, models.WalletKindTreasury, treasury.Kind)
+	assert.Equal(t, "-50", treasury.Balance.String())
+	assert.Equal(t, models.SystemUserID, treasury.UserID)
+	wallet, err = wallets.GetBalance(ctx, wallet.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "55", wallet.Balance.String())
+	for _, id := range []uuid.UUID{treasury.ID, suspense.ID, wallet.ID} {
+		assert.NoError(t, wallets.VerifyWalletBalance(ctx, id))
+	}
+
+	history, err := wallets.GetTransactionHistory(ctx, treasury.ID)
+	require.NoError(t, err)
+	assert.Len(t, history, 3)
+
+	// Treasury and suspense wallets only move as the other side of a movement
+	_, err = wallets.Deposit(ctx, treasury.ID, usd(10), "")
+	assert.ErrorIs(t, err, service.ErrSettlementWallet)
+	_, err = wallets.AdjustBalance(ctx, suspense.ID, usd(10), "Top up", "")
+	assert.ErrorIs(t, err, service.ErrSettlementWallet)
+	assert.ErrorIs(t, wallets.Transfer(ctx, wallet.ID, treasury.ID, usd(10), "", ""), service.ErrSettlementWallet)
+
+	ensure := func(body string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		handler.EnsureWallet(rr, httptest.NewRequest(http.MethodPost, "/admin/system-wallets", strings.NewReader(body)))
+		return rr
+	}
+	rr := ensure(`{"kind":"fees","currency":"eur"}`)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var fees models.Wallet
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &fees))
+	assert.Equal(t, models.WalletKindFees, fees.Kind)
+	assert.Equal(t, money.EUR, fees.Currency)
+	rr = ensure(`{"kind":"fees","currency":"EUR"}`)
+	assert.Contains(t, rr.Body.String(), fees.ID.String(), "one wallet of each kind per currency")
+	assert.Equal(t, http.StatusBadRequest, ensure(`{"kind":"user"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, ensure(`{"kind":"fees","currency":"XYZ"}`).Code)
+	assert.Len(t, list(), 3)
+}
@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	stderrors "errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/shanwije/wallet-app/internal/service"
+	"github.com/shanwije/wallet-app/pkg/errors"
+	"github.com/shanwije/wallet-app/pkg/logger"
+	"github.com/shanwije/wallet-app/pkg/money"
+	"github.com/shanwije/wallet-app/pkg/response"
+)
+
+// transactionPINHeader carries the transaction PIN of withdrawals and transfers over
+// the PIN threshold, and the current PIN when removing it. A header keeps the PIN out
+// of the request bodies that idempotency keys and audit entries record.
+const transactionPINHeader = "X-Transaction-PIN"
+
+// TransactionPINHandler serves the transaction PINs users step up large withdrawals
+// and transfers with
+type TransactionPINHandler struct {
+	TransactionPINService *service.TransactionPINService
+}
+
+// setTransactionPINRequest sets a new PIN; changing one takes the current PIN as well
+type setTransactionPINRequest struct {
+	PIN        string `json:"pin" validate:"required" example:"4821"`
+	CurrentPIN string `json:"current_pin,omitempty" example:"1234"`
+}
+
+// NewTransactionPINHandler creates a new TransactionPINHandler
+func NewTransactionPINHandler(transactionPINService *service.TransactionPINService) *TransactionPINHandler {
+	return &TransactionPINHandler{
+		TransactionPINService: transactionPINService,
+	}
+}
+
+// pinAppError maps a PIN that was missing, wrong, locked, malformed or not set; it
+// returns nil for other errors
+func pinAppError(err error) *errors.AppError {
+	var incorrect *service.IncorrectPINError
+	var locked *service.PINLockedError
+	switch {
+	case stderrors.Is(err, service.ErrPINRequired):
+		return errors.New(errors.ErrPINRequired, err.Error(), http.StatusForbidden).
+			WithDetails("header", transactionPINHeader)
+	case stderrors.As(err, &incorrect):
+		return errors.New(errors.ErrIncorrectPIN, err.Error(), http.StatusForbidden).
+			WithDetails("attempts_left", strconv.Itoa(incorrect.AttemptsLeft))
+	case stderrors.As(err, &locked):
+		return errors.New(errors.ErrPINLocked, err.Error(), http.StatusLocked).
+			WithDetails("locked_until", locked.Until.UTC().Format(time.RFC3339))
+	case stderrors.Is(err, service.ErrInvalidPINFormat):
+		return errors.InvalidInput(err.Error()).WithDetails("field", "pin")
+	case stderrors.Is(err, service.ErrPINNotSet):
+		return errors.New(errors.ErrPINNotSet, err.Error(), http.StatusNotFound)
+	default:
+		return nil
+	}
+}
+
+// authorizePIN checks the X-Transaction-PIN header of a request moving amount out of
+// the wallet, returning the error to answer with when the PIN is needed and not right
+func authorizePIN(r *http.Request, pins *service.TransactionPINService, walletID uuid.UUID, amount money.Money) *errors.AppError {
+	err := pins.Authorize(r.Context(), walletID, amount, r.Header.Get(transactionPINHeader))
+	if err == nil {
+		return nil
+	}
+
+	logger.FromContext(r.Context()).Warn("Transaction PIN check failed", zap.Error(err),
+		zap.String("wallet_id", walletID.String()),
+		zap.String("amount", amount.String()))
+	if appErr := movementAppError(err); appErr != nil {
+		return appErr
+	}
+	return errors.InternalError(err)
+}
+
+// GetStatus reports whether a user has set a transaction PIN
+// @Summary Get transaction PIN status
+// @Tags users
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} models.TransactionPINStatus
+// @Failure 400 {object} response.Problem "Invalid user ID"
+// @Failure 404 {object} response.Problem "User not found"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v1/users/{id}/transaction-pin [get]
+func (h *TransactionPINHandler) GetStatus(w http.ResponseWriter, r *http.Request) {
+	userIDStr := chi.URLParam(r, "id")
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		response.ErrorMessage(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	status, err := h.TransactionPINService.GetStatus(r.Context(), userID)
+	if err != nil {
+		respondWithUserError(w, r, err, userIDStr)
+		return
+	}
+
+	response.OK(w, status)
+}
+
+// SetPIN sets or changes a user's transaction PIN
+// @Summary Set transaction PIN
+// @Description Once set, withdrawals and transfers of more than the PIN threshold out of the user's wallet need the PIN in the X-Transaction-PIN header.
+// @Description Changing a PIN takes the current one as current_pin, which counts towards the lockout like any other attempt.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID"
+// @Param pin body setTransactionPINRequest true "New PIN of 4 to 8 digits"
+// @Success 200 {object} models.TransactionPINStatus
+// @Failure 400 {object} response.Problem "Invalid user ID or PIN"
+// @Failure 403 {object} response.Problem "Current PIN incorrect"
+// @Failure 404 {object} response.Problem "User not found"
+// @Failure 423 {object} response.Problem "PIN locked after too many incorrect attempts"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v1/users/{id}/transaction-pin [put]
+func (h *TransactionPINHandler) SetPIN(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+	userIDStr := chi.URLParam(r, "id")
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		response.ErrorMessage(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	var req setTransactionPINRequest
+	if appErr := decodeRequest(r, &req); appErr != nil {
+		response.Error(w, appErr)
+		return
+	}
+	if appErr := validateRequest(req); appErr != nil {
+		response.Error(w, appErr)
+		return
+	}
+
+	status, err := h.TransactionPINService.SetPIN(r.Context(), userID, req.CurrentPIN, req.PIN)
+	if err != nil {
+		if appErr := pinAppError(err); appErr != nil {
+			log.Warn("Transaction PIN not set", zap.Error(err), zap.String("user_id", userIDStr))
+			response.Error(w, appErr)
+			return
+		}
+		respondWithUserError(w, r, err, userIDStr)
+		return
+	}
+
+	log.Info("Transaction PIN set", zap.String("user_id", userIDStr))
+	response.OK(w, status)
+}
+
+// RemovePIN turns a user's transaction PIN off
+// @Summary Remove transaction PIN
+// @Tags users
+// @Param id path string true "User ID"
+// @Param X-Transaction-PIN header string true "The current PIN"
+// @Success 204
+// @Failure 400 {object} response.Problem "Invalid user ID"
+// @Failure 403 {object} response.Problem "PIN missing or incorrect"
+// @Failure 404 {object} response.Problem "User not found or no PIN set"
+// @Failure 423 {object} response.Problem "PIN locked after too many incorrect attempts"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v1/users/{id}/transaction-pin [delete]
+func (h *TransactionPINHandler) RemovePIN(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+	userIDStr := chi.URLParam(r, "id")
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		response.ErrorMessage(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	pin := r.Header.Get(transactionPINHeader)
+	if pin == "" {
+		response.Error(w, pinAppError(service.ErrPINRequired))
+		return
+	}
+
+	if err := h.TransactionPINService.RemovePIN(r.Context(), userID, pin); err != nil {
+		if appErr := pinAppError(err); appErr != nil {
+			log.Warn("Transaction PIN not removed", zap.Error(err), zap.String("user_id", userIDStr))
+			response.Error(w, appErr)
+			return
+		}
+		respondWithUserError(w, r, err, userIDStr)
+		return
+	}
+
+	log.Info("Transaction PIN removed", zap.String("user_id", userIDStr))
+	w.WriteHeader(http.StatusNoContent)
+}
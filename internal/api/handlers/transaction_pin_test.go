@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shanwije/wallet-app/internal/repository/sqlite"
+	"github.com/shanwije/wallet-app/internal/service"
+	"github.com/shanwije/wallet-app/pkg/clock"
+	"github.com/shanwije/wallet-app/pkg/money"
+)
+
+func TestTransactionPINStepsUpLargeWithdrawals(t *testing.T) {
+	conn := newTestDB(t)
+	now := clock.NewFake(time.Date(2024, 7, 19, 9, 0, 0, 0, time.UTC))
+	wallets := walletServiceOn(conn)
+	threshold := decimal.NewFromInt(100)
+	pins := &service.TransactionPINService{
+		Repo:        sqlite.NewTransactionPINRepository(conn),
+		UserRepo:    wallets.UserRepo,
+		WalletRepo:  wallets.WalletRepo,
+		Threshold:   &threshold,
+		MaxAttempts: 3,
+		Lockout:     time.Minute,
+		Clock:       now,
+	}
+
+	wallet := createUserWallet(t, wallets)
+	_, err := wallets.Deposit(context.Background(), wallet.ID, money.New(decimal.NewFromInt(1000), money.DefaultCurrency), "")
+	require.NoError(t, err)
+
+	walletHandler := &WalletHandler{WalletService: wallets, PINs: pins}
+	pinHandler := NewTransactionPINHandler(pins)
+	router := chi.NewRouter()
+	router.Post("/wallets/{id}/withdraw", walletHandler.Withdraw)
+	router.Put("/users/{id}/transaction-pin", pinHandler.SetPIN)
+	router.Delete("/users/{id}/transaction-pin", pinHandler.RemovePIN)
+	send := func(method, path, pin, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if pin != "" {
+			req.Header.Set(transactionPINHeader, pin)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	withdraw := func(amount, pin string) *httptest.ResponseRecorder {
+		return send(http.MethodPost, "/wallets/"+wallet.ID.String()+"/withdraw", pin, `{"amount":`+amount+`}`)
+	}
+	pinPath := "/users/" + wallet.UserID.String() + "/transaction-pin"
+
+	// Without a PIN nothing is asked for
+	rr := withdraw("150", "")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	rr = send(http.MethodPut, pinPath, "", `{"pin":"12a4"}`)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	rr = send(http.MethodPut, pinPath, "", `{"pin":"1234"}`)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.Contains(t, rr.Body.String(), `"enabled":true`)
+
+	// Once set it is asked for over the threshold only
+	assert.Equal(t, http.StatusOK, withdraw("50", "").Code)
+	rr = withdraw("150", "")
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	assert.Contains(t, rr.Body.String(), "PIN_REQUIRED")
+	rr = withdraw("150", "9999")
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	assert.Contains(t, rr.Body.String(), "INCORRECT_PIN")
+	assert.Contains(t, rr.Body.String(), `"attempts_left":"2"`)
+	rr = withdraw("150", "1234")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	// The right PIN cleared the count, so it takes three wrong ones in a row to lock it
+	assert.Equal(t, http.StatusForbidden, withdraw("150", "9999").Code)
+	assert.Equal(t, http.StatusForbidden, withdraw("150", "9999").Code)
+	rr = withdraw("150", "9999")
+	assert.Equal(t, http.StatusLocked, rr.Code)
+	assert.Contains(t, rr.Body.String(), "PIN_LOCKED")
+	assert.Equal(t, http.StatusLocked, withdraw("150", "1234").Code, "even the right PIN waits out the lock")
+	assert.Equal(t, http.StatusLocked, send(http.MethodPut, pinPath, "", `{"pin":"5678","current_pin":"1234"}`).Code)
+
+	current, err := wallets.GetBalance(context.Background(), wallet.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "650", current.Balance.String(), "refused withdrawals moved nothing")
+
+	// Changing the PIN once it unlocks takes the current one
+	now.Advance(time.Minute)
+	assert.Equal(t, http.StatusForbidden, send(http.MethodPut, pinPath, "", `{"pin":"5678"}`).Code)
+	rr = send(http.MethodPut, pinPath, "", `{"pin":"5678","current_pin":"1234"}`)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.Equal(t, http.StatusForbidden, withdraw("150", "1234").Code)
+	assert.Equal(t, http.StatusOK, withdraw("150", "5678").Code)
+
+	// Removing it takes the PIN too, after which nothing is asked for again
+	assert.Equal(t, http.StatusForbidden, send(http.MethodDelete, pinPath, "", "").Code)
+	rr = send(http.MethodDelete, pinPath, "5678", "")
+	require.Equal(t, http.StatusNoContent, rr.Code, rr.Body.String())
+	assert.Equal(t, http.StatusNotFound, send(http.MethodDelete, pinPath, "5678", "").Code)
+	assert.Equal(t, http.StatusOK, withdraw("150", "").Code)
+}
+
+func TestTransactionPINStepsUpPaymentsAndCaptures(t *testing.T) {
+	ctx := context.Background()
+	conn := newTestDB(t)
+	wallets := walletServiceOn(conn)
+	wallets.HoldRepo = sqlite.NewHoldRepository(conn)
+	threshold := decimal.NewFromInt(100)
+	pins := &service.TransactionPINService{
+		Repo:       sqlite.NewTransactionPINRepository(conn),
+		UserRepo:   wallets.UserRepo,
+		WalletRepo: wallets.WalletRepo,
+		Threshold:  &threshold,
+	}
+	payments := &service.PaymentService{Repo: sqlite.NewPaymentRepository(conn), Wallets: wallets}
+	requests := &service.PaymentRequestService{Repo: sqlite.NewPaymentRequestRepository(conn), Wallets: wallets}
+
+	wallet, shop := createUserWallet(t, wallets), createUserWallet(t, wallets)
+	_, err := wallets.Deposit(ctx, wallet.ID, money.New(decimal.NewFromInt(1000), money.DefaultCurrency), "")
+	require.NoError(t, err)
+	_, err = payments.RegisterMerchant(ctx, shop.ID, "Corner Books")
+	require.NoError(t, err)
+	_, err = pins.SetPIN(ctx, wallet.UserID, "", "1234")
+	require.NoError(t, err)
+	hold, err := wallets.PlaceHold(ctx, wallet.ID, money.New(decimal.NewFromInt(150), money.DefaultCurrency), "Hotel")
+	require.NoError(t, err)
+	request, err := requests.Request(ctx, shop.ID, wallet.ID, money.New(decimal.NewFromInt(120), money.DefaultCurrency), "Invoice")
+	require.NoError(t, err)
+
+	walletHandler := &WalletHandler{WalletService: wallets, PINs: pins}
+	paymentHandler := NewPaymentHandler(payments)
+	paymentHandler.PINs = pins
+	requestHandler := NewPaymentRequestHandler(requests)
+	requestHandler.PINs = pins
+	router := chi.NewRouter()
+	router.Post("/wallets/{id}/holds/{holdID}/capture", walletHandler.CaptureHold)
+	router.Post("/payments", paymentHandler.Pay)
+	router.Post("/wallets/{id}/payment-requests/{requestID}/accept", requestHandler.Accept)
+	send := func(path, pin, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		if pin != "" {
+			req.Header.Set(transactionPINHeader, pin)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	capture := func(pin, body string) *httptest.ResponseRecorder {
+		return send("/wallets/"+wallet.ID.String()+"/holds/"+hold.ID.String()+"/capture", pin, body)
+	}
+	pay := func(amount, pin string) *httptest.ResponseRecorder {
+		return send("/payments", pin, `{"wallet_id":"`+wallet.ID.String()+`","merchant_wallet_id":"`+shop.ID.String()+
+			`","amount":`+amount+`,"order_reference":"ORD-`+amount+`"}`)
+	}
+	accept := func(pin string) *httptest.ResponseRecorder {
+		return send("/wallets/"+wallet.ID.String()+"/payment-requests/"+request.ID.String()+"/accept", pin, "")
+	}
+
+	// Payments are asked for the PIN over the threshold, like withdrawals
+	assert.Equal(t, http.StatusCreated, pay("50", "").Code)
+	rr := pay("150", "")
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	assert.Contains(t, rr.Body.String(), "PIN_REQUIRED")
+	assert.Equal(t, http.StatusCreated, pay("150", "1234").Code)
+
+	// Accepting a request is asked for it by the amount requested
+	assert.Equal(t, http.StatusForbidden, accept("").Code)
+	rr = accept("1234")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	// A capture is asked for it by what it captures, the whole hold without an amount
+	assert.Equal(t, http.StatusForbidden, capture("", "").Code)
+	assert.Equal(t, http.StatusForbidden, capture("9999", `{"amount":120}`).Code)
+	rr = capture("", `{"amount":80}`)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	current, err := wallets.GetBalance(ctx, wallet.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "600", current.Balance.String(), "refused movements moved nothing")
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	stderrors "errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/service"
+	"github.com/shanwije/wallet-app/pkg/auth"
+	"github.com/shanwije/wallet-app/pkg/errors"
+	"github.com/shanwije/wallet-app/pkg/logger"
+	"github.com/shanwije/wallet-app/pkg/response"
+)
+
+// transferListResponse is one page of a wallet's transfers
+type transferListResponse struct {
+	Transfers []*models.WalletTransfer `json:"transfers"`
+	Limit     int                      `json:"limit"`
+	Offset    int                      `json:"offset"`
+}
+
+// GetTransfer returns a transfer with both of its legs
+// @Summary Get a transfer
+// @Description Looks a transfer up by the reference_id its transactions carry. When auth
+// @Description is enabled only the owners of the two wallets can see it.
+// @Tags transfers
+// @Produce json
+// @Param reference_id path string true "Transfer reference ID"
+// @Success 200 {object} models.Transfer
+// @Failure 400 {object} response.Problem "Invalid reference ID"
+// @Failure 404 {object} response.Problem "Transfer not found"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v1/transfers/{reference_id} [get]
+func (h *WalletHandler) GetTransfer(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	referenceIDStr := chi.URLParam(r, "reference_id")
+	referenceID, err := uuid.Parse(referenceIDStr)
+	if err != nil {
+		response.ErrorMessage(w, http.StatusBadRequest, "Invalid reference ID")
+		return
+	}
+
+	notFound := errors.New(errors.ErrTransferNotFound, "Transfer not found", http.StatusNotFound).
+		WithDetails("reference_id", referenceIDStr)
+
+	transfer, err := h.WalletService.GetTransfer(ctx, referenceID)
+	if err != nil {
+		if stderrors.Is(err, service.ErrTransferNotFound) {
+			response.Error(w, notFound)
+			return
+		}
+		logger.FromContext(ctx).Error("Failed to get transfer", zap.Error(err))
+		response.Error(w, errors.InternalError(err))
+		return
+	}
+
+	// Someone else's transfer is reported as missing rather than forbidden, so
+	// reference IDs cannot be probed
+	if userID, ok := auth.UserIDFromContext(ctx); ok {
+		party, err := h.isTransferParty(r, transfer, userID)
+		if err != nil {
+			logger.FromContext(ctx).Error("Failed to check transfer access", zap.Error(err))
+			response.Error(w, errors.InternalError(err))
+			return
+		}
+		if !party {
+			response.Error(w, notFound)
+			return
+		}
+	}
+
+	response.OK(w, transfer)
+}
+
+// isTransferParty reports whether the user owns the sending or the receiving wallet
+func (h *WalletHandler) isTransferParty(r *http.Request, transfer *models.Transfer, userID uuid.UUID) (bool, error) {
+	for _, walletID := range []uuid.UUID{transfer.FromWalletID, transfer.ToWalletID} {
+		wallet, err := h.WalletService.GetBalance(r.Context(), walletID)
+		if stderrors.Is(err, service.ErrWalletNotFound) {
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+		if wallet.UserID == userID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ListTransfers returns the wallet's transfers for an activity feed
+// @Summary List wallet transfers
+// @Description Returns the wallet's transfers, newest first, at most 200 per page. Unlike
+// @Description the transaction history, each transfer names its direction and counterparty.
+// @Tags transfers
+// @Produce json
+// @Param id path string true "Wallet ID"
+// @Param limit query int false "Page size; larger sizes are cut to 200" minimum(0) default(50)
+// @Param offset query int false "Transfers to skip" minimum(0) default(0)
+// @Success 200 {object} transferListResponse
+// @Failure 400 {object} response.Problem "Invalid wallet ID or pagination parameters"
+// @Failure 404 {object} response.Problem "Wallet not found"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v1/wallets/{id}/transfers [get]
+func (h *WalletHandler) ListTransfers(w http.ResponseWriter, r *http.Request) {
+	walletIDStr := chi.URLParam(r, "id")
+	walletID, err := uuid.Parse(walletIDStr)
+	if err != nil {
+		response.ErrorMessage(w, http.StatusBadRequest, "Invalid wallet ID")
+		return
+	}
+
+	limit, offset, appErr := parsePage(r)
+	if appErr != nil {
+		response.Error(w, appErr)
+		return
+	}
+
+	transfers, err := h.WalletService.ListTransfers(r.Context(), walletID, limit, offset)
+	if err != nil {
+		response.Error(w, walletAppError(err, walletIDStr))
+		return
+	}
+
+	response.OK(w, transferListResponse{Transfers: transfers, Limit: limit, Offset: offset})
+}
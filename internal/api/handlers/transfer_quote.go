@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	stderrors "errors"
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"github.com/shanwije/wallet-app/pkg/errors"
+	"github.com/shanwije/wallet-app/pkg/logger"
+	"github.com/shanwije/wallet-app/pkg/money"
+	"github.com/shanwije/wallet-app/pkg/response"
+)
+
+// QuoteTransfer prices a transfer and holds its terms for a short while
+// @Summary Quote a transfer
+// @Description Returns what a transfer would debit the sender, fee included, and credit the recipient, at the current exchange rate when they hold different currencies.
+// @Description Nothing is moved. Passing the quote's id as quote_id to the transfer before expires_at makes it at the quoted fee and rate; each quote can be used once.
+// @Tags wallets
+// @Accept json
+// @Produce json
+// @Param id path string true "Wallet ID"
+// @Param transfer body transferRequest true "Transfer details"
+// @Success 201 {object} models.TransferQuote
+// @Failure 400 {object} response.Problem "Invalid wallet ID, request body or amount, or a currency the wallet does not hold"
+// @Failure 404 {object} response.Problem "Wallet or recipient not found"
+// @Failure 409 {object} response.Problem "Wallet frozen or closed"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Failure 503 {object} response.Problem "No exchange rate is available for the currency pair"
+// @Router /api/v1/wallets/{id}/transfer/quote [post]
+func (h *WalletHandler) QuoteTransfer(w http.ResponseWriter, r *http.Request) {
+	transfer, appErr := h.parseTransfer(r)
+	if appErr != nil {
+		response.Error(w, appErr)
+		return
+	}
+
+	quote, err := h.WalletService.QuoteTransfer(r.Context(), transfer.fromWalletID, transfer.toWalletID, transfer.amount)
+	if err != nil {
+		if appErr := movementAppError(err); appErr != nil {
+			response.Error(w, appErr)
+			return
+		}
+		if stderrors.Is(err, money.ErrCurrencyMismatch) {
+			response.Error(w, errors.InvalidInput("Amount must be in the wallet's currency, and the recipient's currency must be convertible to it").
+				WithDetails("currency", transfer.amount.Currency().String()))
+			return
+		}
+		logger.FromContext(r.Context()).Error("Failed to quote transfer", zap.Error(err))
+		response.Error(w, errors.InternalError(err))
+		return
+	}
+
+	response.JSON(w, http.StatusCreated, quote)
+}
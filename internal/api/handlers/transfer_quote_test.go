@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shanwije/wallet-app/internal/fees"
+	"github.com/shanwije/wallet-app/internal/fx"
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository/sqlite"
+	"github.com/shanwije/wallet-app/pkg/clock"
+	"github.com/shanwije/wallet-app/pkg/money"
+)
+
+func TestQuotedTransferRunsOnTheQuotedTerms(t *testing.T) {
+	conn := newTestDB(t)
+	wallets := walletServiceOn(conn)
+	clk := clock.NewFake(time.Date(2024, 7, 16, 12, 0, 0, 0, time.UTC))
+	wallets.QuoteRepo, wallets.Clock = sqlite.NewTransferQuoteRepository(conn), clk
+	schedule, err := fees.ParseSchedule("transfer=1%")
+	require.NoError(t, err)
+	rates, err := fx.ParseStaticRates("USD/EUR=0.92")
+	require.NoError(t, err)
+	ctx := context.Background()
+	collector, sender, recipient := createUserWallet(t, wallets), createUserWallet(t, wallets), createUserWallet(t, wallets)
+	conn.MustExec(`UPDATE wallets SET currency = 'EUR' WHERE id = ?`, recipient.ID)
+	wallets.Fees, wallets.FeeWalletID, wallets.FX = schedule, collector.ID, rates
+	_, err = wallets.Deposit(ctx, sender.ID, money.New(decimal.NewFromInt(200), money.DefaultCurrency), "")
+	require.NoError(t, err)
+
+	handler := NewWalletHandler(wallets)
+	router := chi.NewRouter()
+	router.Post("/wallets/{id}/transfer/quote", handler.QuoteTransfer)
+	router.Post("/wallets/{id}/transfer", handler.TransferV2)
+	send := func(path, body string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)))
+		return rr
+	}
+	walletPath := "/wallets/" + sender.ID.String()
+	quoteTransfer := func(amount string) models.TransferQuote {
+		rr := send(walletPath+"/transfer/quote", `{"to_wallet_id":"`+recipient.ID.String()+`","amount":`+amount+`}`)
+		require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+		var quote models.TransferQuote
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &quote))
+		return quote
+	}
+	transferWith := func(amount string, quoteID uuid.UUID) *httptest.ResponseRecorder {
+		return send(walletPath+"/transfer", `{"to_wallet_id":"`+recipient.ID.String()+`","amount":`+amount+`,"quote_id":"`+quoteID.String()+`"}`)
+	}
+
+	quote := quoteTransfer("50")
+	assert.Equal(t, "0.5", quote.Fee.String())
+	assert.Equal(t, "50.5", quote.DebitedAmount.String())
+	assert.Equal(t, "46", quote.CreditedAmount.String())
+	assert.Equal(t, money.EUR, quote.CreditedCurrency)
+	require.NotNil(t, quote.ExchangeRate)
+	assert.Equal(t, "0.92", quote.ExchangeRate.String())
+	assert.Equal(t, clk.Now().Add(30*time.Second), quote.ExpiresAt.UTC())
+
+	// Rates and fees that change after the quote do not change the transfer
+	wallets.FX, err = fx.ParseStaticRates("USD/EUR=0.5")
+	require.NoError(t, err)
+	wallets.Fees, err = fees.ParseSchedule("transfer=5%")
+	require.NoError(t, err)
+	rr := transferWith("50", quote.ID)
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	var transfer transferResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &transfer))
+	require.NotNil(t, transfer.Fee)
+	assert.Equal(t, "0.5", transfer.Fee.String())
+	assert.Equal(t, "149.5", transfer.FromBalance.String())
+	assert.Equal(t, "46", transfer.ToBalance.String())
+
+	// A quote is used once, for the transfer it priced, before it expires
+	rr = transferWith("50", quote.ID)
+	assert.Equal(t, http.StatusConflict, rr.Code, rr.Body.String())
+	quote = quoteTransfer("20")
+	assert.Equal(t, "10", quote.CreditedAmount.String())
+	rr = transferWith("25", quote.ID)
+	assert.Equal(t, http.StatusBadRequest, rr.Code, rr.Body.String())
+	clk.Advance(30 * time.Second)
+	rr = transferWith("20", quote.ID)
+	assert.Equal(t, http.StatusConflict, rr.Code, rr.Body.String())
+	rr = transferWith("20", uuid.New())
+	assert.Equal(t, http.StatusNotFound, rr.Code, rr.Body.String())
+	assert.Contains(t, rr.Body.String(), "QUOTE_NOT_FOUND")
+
+	for _, wallet := range []*models.Wallet{sender, recipient, collector} {
+		assert.NoError(t, wallets.VerifyWalletBalance(ctx, wallet.ID))
+	}
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/pkg/money"
+)
+
+func TestTransferV2ReturnsTheCreatedTransfer(t *testing.T) {
+	wallets := newWalletService(t)
+	from, to := createUserWallet(t, wallets), createUserWallet(t, wallets)
+	_, err := wallets.Deposit(context.Background(), from.ID, money.New(decimal.NewFromInt(100), money.DefaultCurrency), "")
+	require.NoError(t, err)
+
+	router := chi.NewRouter()
+	router.Post("/wallets/{id}/transfer", (&WalletHandler{WalletService: wallets}).TransferV2)
+	send := func() (*httptest.ResponseRecorder, transferResponse) {
+		req := httptest.NewRequest(http.MethodPost, "/wallets/"+from.ID.String()+"/transfer",
+			strings.NewReader(`{"to_wallet_id":"`+to.ID.String()+`","amount":40,"description":"Rent"}`))
+		req.Header.Set("Idempotency-Key", "rent-june")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		var response transferResponse
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response), rr.Body.String())
+		return rr, response
+	}
+
+	rr, created := send()
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	assert.Equal(t, "/api/v2/transfers/"+created.ReferenceID.String(), rr.Header().Get("Location"))
+	assert.Equal(t, from.ID, created.FromWalletID)
+	assert.Equal(t, to.ID, created.ToWalletID)
+	assert.Equal(t, "40", created.Amount.String())
+	assert.Equal(t, "60", created.FromBalance.String())
+	assert.Equal(t, "40", created.ToBalance.String())
+
+	// The transaction IDs are those in each wallet's history
+	history, err := wallets.GetTransactionHistory(context.Background(), from.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.TransactionTypeTransferOut, history[0].Type)
+	assert.Equal(t, history[0].ID, created.FromTransactionID)
+	history, err = wallets.GetTransactionHistory(context.Background(), to.ID)
+	require.NoError(t, err)
+	assert.Equal(t, history[0].ID, created.ToTransactionID)
+
+	// A retry gets the same transfer without moving the money again
+	rr, replayed := send()
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	assert.Equal(t, created.ReferenceID, replayed.ReferenceID)
+	assert.Equal(t, created.FromTransactionID, replayed.FromTransactionID)
+	assert.Equal(t, created.ToTransactionID, replayed.ToTransactionID)
+	assert.Equal(t, "60", replayed.FromBalance.String())
+	assert.Equal(t, "40", replayed.ToBalance.String())
+}
@@ -1,26 +1,42 @@
 package handlers
 
 import (
-	"encoding/json"
+	stderrors "errors"
 	"net/http"
+	"strconv"
+	"strings"
 
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
 	"go.uber.org/zap"
 
+	"github.com/shanwije/wallet-app/internal/models"
 	"github.com/shanwije/wallet-app/internal/service"
+	"github.com/shanwije/wallet-app/pkg/auth"
 	"github.com/shanwije/wallet-app/pkg/errors"
 	"github.com/shanwije/wallet-app/pkg/logger"
+	"github.com/shanwije/wallet-app/pkg/response"
 )
 
 type UserHandler struct {
-	UserService *service.UserService
+	UserService UserServiceInterface
 }
 
 type createUserRequest struct {
-	Name string `json:"name"`
+	Name  string  `json:"name" validate:"required"`
+	Email *string `json:"email,omitempty" example:"alice@example.com"`
+	Phone *string `json:"phone,omitempty" example:"+447700900123"`
+}
+
+// updateUserRequest changes the fields it sets; an empty email or phone removes it
+type updateUserRequest struct {
+	Name  *string `json:"name,omitempty" example:"Alice Smith"`
+	Email *string `json:"email,omitempty" example:"alice@example.com"`
+	Phone *string `json:"phone,omitempty" example:"+447700900123"`
 }
 
 // NewUserHandler creates a new UserHandler
-func NewUserHandler(userService *service.UserService) *UserHandler {
+func NewUserHandler(userService UserServiceInterface) *UserHandler {
 	return &UserHandler{
 		UserService: userService,
 	}
@@ -32,33 +48,251 @@ func NewUserHandler(userService *service.UserService) *UserHandler {
 // @Accept json
 // @Produce json
 // @Param user body createUserRequest true "User details"
+// @Param Idempotency-Key header string false "Makes retries safe: a repeat with the same key and body gets the first response"
 // @Success 201 {object} models.UserWithWallet
+// @Failure 400 {object} response.Problem "Invalid user details"
+// @Failure 409 {object} response.Problem "Email or phone already in use, or Idempotency-Key reused with a different request body"
+// @Failure 500 {object} response.Problem "Internal server error"
 // @Router /api/v1/users [post]
 func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 	log := logger.FromContext(r.Context())
 
 	var req createUserRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		log.Error("Failed to decode request", zap.Error(err))
-		errors.RespondWithError(w, http.StatusBadRequest, "Invalid request format")
+	if appErr := decodeRequest(r, &req); appErr != nil {
+		log.Error("Failed to decode request", zap.Error(appErr))
+		response.Error(w, appErr)
 		return
 	}
 
-	if req.Name == "" {
-		log.Warn("User creation failed: empty name provided")
-		errors.RespondWithError(w, http.StatusBadRequest, "Name is required")
+	if appErr := validateRequest(req); appErr != nil {
+		log.Warn("User creation failed: invalid request", zap.Any("fields", appErr.Details))
+		response.Error(w, appErr)
 		return
 	}
 
-	user, err := h.UserService.CreateUser(r.Context(), req.Name)
+	user, err := h.UserService.CreateUserWithContact(r.Context(), req.Name, models.Contact{Email: req.Email, Phone: req.Phone})
 	if err != nil {
+		if appErr := contactAppError(err); appErr != nil {
+			log.Warn("User creation failed", zap.Error(err))
+			response.Error(w, appErr)
+			return
+		}
 		log.Error("Failed to create user", zap.Error(err), zap.String("name", req.Name))
-		errors.RespondWithError(w, http.StatusInternalServerError, "Failed to create user")
+		response.ErrorMessage(w, http.StatusInternalServerError, "Failed to create user")
 		return
 	}
 
 	log.Info("User created successfully", zap.String("user_id", user.ID.String()), zap.String("name", user.Name))
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(user)
+	w.Header().Set("ETag", userETag(user.Version))
+	response.Created(w, "", user)
+}
+
+// UpdateUser changes a user's profile
+// @Summary Update user
+// @Description Changes the name, email or phone of the user, leaving fields the body omits as they are; an empty email or phone removes it. Emails and phone numbers belong to one user at a time. With If-Match, the update is only made while the user is still at a version it names, taken from the ETag of GET /users/{id}.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID"
+// @Param user body updateUserRequest true "Profile fields to change"
+// @Param If-Match header string false "ETag of the user the update was based on"
+// @Success 200 {object} models.User
+// @Header 200 {string} ETag "Version of the updated user"
+// @Failure 400 {object} response.Problem "Invalid user ID, profile fields or If-Match"
+// @Failure 404 {object} response.Problem "User not found"
+// @Failure 409 {object} response.Problem "Email or phone already in use"
+// @Failure 412 {object} response.Problem "User changed since the If-Match version"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v1/users/{id} [patch]
+func (h *UserHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+	userIDStr := chi.URLParam(r, "id")
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		response.ErrorMessage(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	var req updateUserRequest
+	if appErr := decodeRequest(r, &req); appErr != nil {
+		response.Error(w, appErr)
+		return
+	}
+	if req.Name != nil && strings.TrimSpace(*req.Name) == "" {
+		response.Error(w, errors.InvalidInput("name cannot be empty").WithDetails("field", "name"))
+		return
+	}
+	expectedVersions, appErr := ifMatchVersions(r, "user")
+	if appErr != nil {
+		response.Error(w, appErr)
+		return
+	}
+
+	user, err := h.UserService.UpdateUser(r.Context(), userID, service.UserUpdate{Name: req.Name, Email: req.Email, Phone: req.Phone}, expectedVersions)
+	if err != nil {
+		if appErr := contactAppError(err); appErr != nil {
+			response.Error(w, appErr)
+			return
+		}
+		if stderrors.Is(err, service.ErrUserVersionMismatch) {
+			response.Error(w, errors.PreconditionFailed(err.Error()))
+			return
+		}
+		respondWithUserError(w, r, err, userIDStr)
+		return
+	}
+
+	log.Info("User updated", zap.String("user_id", userIDStr), zap.Int64("version", user.Version))
+	w.Header().Set("ETag", userETag(user.Version))
+	response.OK(w, user)
+}
+
+// contactAppError returns the response to an email or phone that was malformed or
+// already in use, or nil for any other error
+func contactAppError(err error) *errors.AppError {
+	switch {
+	case stderrors.Is(err, service.ErrInvalidEmail):
+		return errors.InvalidInput(err.Error()).WithDetails("field", "email")
+	case stderrors.Is(err, service.ErrInvalidPhone):
+		return errors.InvalidInput(err.Error()).WithDetails("field", "phone")
+	case stderrors.Is(err, service.ErrEmailTaken):
+		return errors.New(errors.ErrEmailTaken, err.Error(), http.StatusConflict)
+	case stderrors.Is(err, service.ErrPhoneTaken):
+		return errors.New(errors.ErrPhoneTaken, err.Error(), http.StatusConflict)
+	}
+	return nil
+}
+
+// RequireSelf is route middleware for /users/{id} that only lets users look
+// themselves up. It must run after the auth middleware.
+func (h *UserHandler) RequireSelf(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		callerID, ok := auth.UserIDFromContext(r.Context())
+		if !ok {
+			response.Error(w, errors.Unauthorized("Authentication required"))
+			return
+		}
+
+		if chi.URLParam(r, "id") != callerID.String() {
+			response.Error(w, errors.Forbidden("You do not have access to this user"))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// GetUser returns a user with their wallet
+// @Summary Get user
+// @Tags users
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} models.UserWithWallet
+// @Header 200 {string} ETag "Version of the user, for If-Match on PATCH"
+// @Failure 400 {object} response.Problem "Invalid user ID"
+// @Failure 404 {object} response.Problem "User not found"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v1/users/{id} [get]
+func (h *UserHandler) GetUser(w http.ResponseWriter, r *http.Request) {
+	userIDStr := chi.URLParam(r, "id")
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		response.ErrorMessage(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	user, err := h.UserService.GetUserWithWallet(r.Context(), userID)
+	if err != nil {
+		respondWithUserError(w, r, err, userIDStr)
+		return
+	}
+
+	w.Header().Set("ETag", userETag(user.Version))
+	response.OK(w, user)
+}
+
+// GetUserWallet returns a user's wallet
+// @Summary Get user wallet
+// @Tags users
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} models.Wallet
+// @Failure 400 {object} response.Problem "Invalid user ID"
+// @Failure 404 {object} response.Problem "User or wallet not found"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v1/users/{id}/wallet [get]
+func (h *UserHandler) GetUserWallet(w http.ResponseWriter, r *http.Request) {
+	userIDStr := chi.URLParam(r, "id")
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		response.ErrorMessage(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	wallet, err := h.UserService.GetUserWallet(r.Context(), userID)
+	if err != nil {
+		respondWithUserError(w, r, err, userIDStr)
+		return
+	}
+
+	response.OK(w, wallet)
+}
+
+// DeleteUser soft-deletes a user and closes their wallet
+// @Summary Delete user
+// @Description Hides the user and closes their wallet. A wallet with money in it is only closed with withdraw_balance=true, which pays the balance out first; wallets with active holds cannot be closed.
+// @Tags users
+// @Param id path string true "User ID"
+// @Param withdraw_balance query bool false "Withdraw any remaining balance before closing the wallet"
+// @Success 204
+// @Failure 400 {object} response.Problem "Invalid user ID or withdraw_balance"
+// @Failure 404 {object} response.Problem "User not found"
+// @Failure 409 {object} response.Problem "Wallet frozen or still holds a balance"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v1/users/{id} [delete]
+func (h *UserHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+	userIDStr := chi.URLParam(r, "id")
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		response.ErrorMessage(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	withdrawBalance := false
+	if raw := r.URL.Query().Get("withdraw_balance"); raw != "" {
+		if withdrawBalance, err = strconv.ParseBool(raw); err != nil {
+			response.Error(w, errors.InvalidInput("withdraw_balance must be true or false"))
+			return
+		}
+	}
+
+	if err := h.UserService.DeleteUser(r.Context(), userID, withdrawBalance); err != nil {
+		switch {
+		case stderrors.Is(err, service.ErrWalletNotEmpty), stderrors.Is(err, service.ErrWalletHasActiveHolds):
+			response.Error(w, errors.Conflict(err.Error()))
+		case stderrors.Is(err, models.ErrWalletFrozen):
+			response.Error(w, errors.WalletFrozen(err.Error()))
+		default:
+			respondWithUserError(w, r, err, userIDStr)
+		}
+		return
+	}
+
+	log.Info("User deleted", zap.String("user_id", userIDStr), zap.Bool("withdraw_balance", withdrawBalance))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// respondWithUserError answers a failed user lookup or deletion
+func respondWithUserError(w http.ResponseWriter, r *http.Request, err error, userID string) {
+	switch {
+	case stderrors.Is(err, service.ErrUserNotFound):
+		response.Error(w, errors.UserNotFound(userID))
+	case stderrors.Is(err, service.ErrUserHasNoWallet):
+		response.Error(w, errors.New(errors.ErrWalletNotFound, "User has no wallet", http.StatusNotFound).
+			WithDetails("user_id", userID))
+	default:
+		logger.FromContext(r.Context()).Error("User request failed", zap.Error(err), zap.String("user_id", userID))
+		response.Error(w, errors.InternalError(err))
+	}
 }
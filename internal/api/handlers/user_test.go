@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository"
+	"github.com/shanwije/wallet-app/internal/repository/sqlite"
+	"github.com/shanwije/wallet-app/internal/service"
+	"github.com/shanwije/wallet-app/pkg/errors"
+	"github.com/shanwije/wallet-app/pkg/response"
+)
+
+func TestUpdateUserOnlyChangesTheProfileRead(t *testing.T) {
+	wallets := newWalletService(t)
+	handler := &UserHandler{UserService: &service.UserService{UserRepo: wallets.UserRepo, WalletRepo: wallets.WalletRepo}}
+	router := chi.NewRouter()
+	router.Post("/users", handler.CreateUser)
+	router.Get("/users/{id}", handler.GetUser)
+	router.Patch("/users/{id}", handler.UpdateUser)
+	send := func(method, path, ifMatch, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	problemCode := func(rr *httptest.ResponseRecorder) string {
+		var problem response.Problem
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &problem), rr.Body.String())
+		return problem.Code
+	}
+
+	rr := send(http.MethodPost, "/users", "", `{"name":"Alice","email":" Alice@Example.com ","phone":"+44 7700 900123"}`)
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	var alice models.UserWithWallet
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &alice))
+	assert.Equal(t, "alice@example.com", *alice.Email)
+	assert.Equal(t, "+447700900123", *alice.Phone)
+
+	rr = send(http.MethodPost, "/users", "", `{"name":"Mallory","email":"ALICE@example.com"}`)
+	require.Equal(t, http.StatusConflict, rr.Code, rr.Body.String())
+	assert.Equal(t, errors.ErrEmailTaken, problemCode(rr))
+	rr = send(http.MethodPost, "/users", "", `{"name":"Mallory","phone":"07700 900123"}`)
+	require.Equal(t, http.StatusBadRequest, rr.Code, rr.Body.String())
+
+	path := "/users/" + alice.ID.String()
+	rr = send(http.MethodGet, path, "", "")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	seen := rr.Header().Get("ETag")
+	require.NotEmpty(t, seen)
+
+	rr = send(http.MethodPatch, path, seen, `{"name":"Alice Smith","phone":""}`)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var updated models.User
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &updated))
+	assert.Equal(t, "Alice Smith", updated.Name)
+	assert.Equal(t, "alice@example.com", *updated.Email)
+	assert.Nil(t, updated.Phone)
+	assert.NotEqual(t, seen, rr.Header().Get("ETag"))
+
+	// The update changed the user, so the tag read before it no longer matches
+	rr = send(http.MethodPatch, path, seen, `{"name":"Alice Jones"}`)
+	require.Equal(t, http.StatusPreconditionFailed, rr.Code, rr.Body.String())
+	assert.Equal(t, errors.ErrVersionMismatch, problemCode(rr))
+	rr = send(http.MethodPatch, path, "", `{"email":"not an email"}`)
+	assert.Equal(t, http.StatusBadRequest, rr.Code, rr.Body.String())
+
+	rr = send(http.MethodGet, path, "", "")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.Contains(t, rr.Body.String(), `"name":"Alice Smith"`)
+}
+
+// usernameRace answers every username as free, as a registration that checked before a
+// concurrent one for the same username committed would see it
+type usernameRace struct {
+	repository.CredentialRepository
+}
+
+func (usernameRace) GetCredentialsByUsername(context.Context, string) (*models.Credentials, error) {
+	return nil, repository.ErrNotFound
+}
+
+func TestRegistrationLosingTheUsernameLeavesNoUserBehind(t *testing.T) {
+	conn := newTestDB(t)
+	wallets := walletServiceOn(conn)
+	users := &service.UserService{
+		UserRepo:       wallets.UserRepo,
+		WalletRepo:     wallets.WalletRepo,
+		CredentialRepo: usernameRace{sqlite.NewCredentialRepository(conn)},
+		Wallets:        wallets,
+	}
+	ctx := context.Background()
+
+	_, err := users.Register(ctx, "John Doe", "john", "s3cure-password")
+	require.NoError(t, err)
+	_, err = users.Register(ctx, "John Smith", "john", "an0ther-password")
+	assert.ErrorIs(t, err, service.ErrUsernameTaken)
+
+	// The user and wallet of the losing registration were rolled back with it
+	registered, err := wallets.UserRepo.ListUsers(ctx, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, registered, 1)
+	assert.Equal(t, "John Doe", registered[0].Name)
+}
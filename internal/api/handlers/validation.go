@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/shopspring/decimal"
+
+	"github.com/shanwije/wallet-app/pkg/errors"
+	"github.com/shanwije/wallet-app/pkg/money"
+)
+
+// validate checks request bodies against their validate tags
+var validate = newRequestValidator()
+
+// newRequestValidator returns a validator that names fields by their JSON keys,
+// so errors point at what the client actually sent
+func newRequestValidator() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+	// Amounts are decoded as decimals so no digits are lost to float rounding. Their
+	// tags only compare them with zero, so the validator is given the sign.
+	v.RegisterCustomTypeFunc(func(field reflect.Value) interface{} {
+		if d, ok := field.Interface().(decimal.Decimal); ok {
+			return d.Sign()
+		}
+		return nil
+	}, decimal.Decimal{})
+	return v
+}
+
+// decodeRequest strictly decodes a JSON request body into req. Unknown fields and
+// trailing data are rejected rather than silently dropped, and each failure is
+// reported as a structured error naming what was wrong.
+func decodeRequest(r *http.Request, req interface{}) *errors.AppError {
+	return decodeBody(r, req, false)
+}
+
+// decodeOptionalRequest is decodeRequest for endpoints whose body may be left out
+func decodeOptionalRequest(r *http.Request, req interface{}) *errors.AppError {
+	return decodeBody(r, req, true)
+}
+
+func decodeBody(r *http.Request, req interface{}, optional bool) *errors.AppError {
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(req); err != nil {
+		if optional && err == io.EOF {
+			return nil
+		}
+		return decodeError(err)
+	}
+	var extra json.RawMessage
+	if err := dec.Decode(&extra); err != io.EOF {
+		var tooLarge *http.MaxBytesError
+		if stderrors.As(err, &tooLarge) {
+			return errors.PayloadTooLarge(tooLarge.Limit)
+		}
+		return errors.InvalidInput("Request body must contain a single JSON object")
+	}
+	return nil
+}
+
+// decodeError turns a JSON decoding failure into the error reported to the client
+func decodeError(err error) *errors.AppError {
+	var (
+		tooLarge  *http.MaxBytesError
+		syntaxErr *json.SyntaxError
+		typeErr   *json.UnmarshalTypeError
+	)
+	switch {
+	case stderrors.As(err, &tooLarge):
+		return errors.PayloadTooLarge(tooLarge.Limit)
+	case err == io.EOF:
+		return errors.InvalidInput("Request body is empty")
+	case err == io.ErrUnexpectedEOF:
+		return errors.InvalidInput("Request body is not valid JSON")
+	case stderrors.As(err, &syntaxErr):
+		return errors.InvalidInput("Request body is not valid JSON").
+			WithDetails("offset", strconv.FormatInt(syntaxErr.Offset, 10))
+	case stderrors.As(err, &typeErr):
+		if typeErr.Field == "" {
+			return errors.InvalidInput("Request body must be a JSON object")
+		}
+		return errors.ValidationFailed(map[string]string{
+			typeErr.Field: "type=" + strings.TrimPrefix(typeErr.Type.String(), "*"),
+		})
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		// encoding/json has no typed error for unknown fields
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		return errors.ValidationFailed(map[string]string{field: "unknown"})
+	default:
+		return errors.InvalidInput("Invalid request format")
+	}
+}
+
+// validateRequest checks a decoded request body, returning a VALIDATION_FAILED error
+// whose details map each failing field to the rule it broke
+func validateRequest(req interface{}) *errors.AppError {
+	err := validate.Struct(req)
+	if err == nil {
+		return nil
+	}
+
+	var fieldErrs validator.ValidationErrors
+	if !stderrors.As(err, &fieldErrs) {
+		return errors.InvalidInput(err.Error())
+	}
+
+	fields := make(map[string]string, len(fieldErrs))
+	for _, fieldErr := range fieldErrs {
+		rule := fieldErr.Tag()
+		if fieldErr.Param() != "" {
+			rule += "=" + fieldErr.Param()
+		}
+		// Drop the struct name, keeping paths such as transfers[1].amount
+		_, field, _ := strings.Cut(fieldErr.Namespace(), ".")
+		fields[field] = rule
+	}
+	return errors.ValidationFailed(fields)
+}
+
+// parseAmount converts a request amount into Money, defaulting the currency when omitted.
+// Amounts with more decimal places than the currency allows are rejected rather than rounded.
+func parseAmount(amount decimal.Decimal, currency string) (money.Money, *errors.AppError) {
+	c := money.DefaultCurrency
+	if currency != "" {
+		parsed, err := money.ParseCurrency(currency)
+		if err != nil {
+			return money.Money{}, errors.InvalidInput("Unsupported currency").
+				WithDetails("currency", currency)
+		}
+		c = parsed
+	}
+
+	m := money.New(amount, c)
+
+	if !m.HasValidPrecision() {
+		places := c.MinorUnits()
+		return money.Money{}, errors.InvalidAmount(
+			fmt.Sprintf("Amount must have at most %d decimal places for %s", places, c)).
+			WithDetails("amount", m.Amount().String()).
+			WithDetails("currency", c.String()).
+			WithDetails("max_decimal_places", strconv.Itoa(int(places)))
+	}
+
+	return m, nil
+}
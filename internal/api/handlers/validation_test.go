@@ -4,28 +4,17 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
+	"github.com/go-chi/chi/v5"
+	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
-)
-
-// TestRequestValidation tests various request validation scenarios
-func TestRequestValidation(t *testing.T) {
-	req, err := http.NewRequest("GET", "/health", nil)
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	rr := httptest.NewRecorder()
-	handler := NewHealthHandler()
-
-	handler.GetHealth(rr, req)
+	"github.com/stretchr/testify/require"
 
-	if status := rr.Code; status != http.StatusOK {
-		t.Errorf("GetHealth returned wrong status code: got %v want %v",
-			status, http.StatusOK)
-	}
-}
+	"github.com/shanwije/wallet-app/pkg/errors"
+	"github.com/shanwije/wallet-app/pkg/response"
+)
 
 // TestCreateUserRequestValidation tests request validation
 func TestCreateUserRequestValidation(t *testing.T) {
@@ -253,3 +242,184 @@ func TestErrorHandling(t *testing.T) {
 		}
 	})
 }
+
+// TestParseAmountPrecision tests that amounts are validated against currency minor units
+func TestParseAmountPrecision(t *testing.T) {
+	tests := []struct {
+		name        string
+		amount      string
+		currency    string
+		expectError bool
+	}{
+		{name: "Two decimals default currency", amount: "10.99", expectError: false},
+		{name: "Excessive decimals default currency", amount: "10.999999", expectError: true},
+		{name: "Three decimals KWD", amount: "1.125", currency: "KWD", expectError: false},
+		{name: "Fractional JPY", amount: "100.5", currency: "JPY", expectError: true},
+		{name: "Unknown currency", amount: "10", currency: "XYZ", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, appErr := parseAmount(decimal.RequireFromString(tt.amount), tt.currency)
+
+			if tt.expectError {
+				assert.NotNil(t, appErr)
+				assert.Equal(t, http.StatusBadRequest, appErr.HTTPStatus)
+			} else {
+				assert.Nil(t, appErr)
+			}
+		})
+	}
+
+	_, appErr := parseAmount(decimal.RequireFromString("10.999999"), "")
+	assert.Equal(t, "INVALID_AMOUNT", appErr.Code)
+	assert.Equal(t, "2", appErr.Details["max_decimal_places"])
+}
+
+// TestValidateRequest tests that validation failures name each field by its JSON key
+func TestValidateRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     interface{}
+		details map[string]string
+	}{
+		{
+			name: "Valid deposit",
+			req:  depositRequest{Amount: decimal.NewFromInt(10)},
+		},
+		{
+			name:    "Missing amount",
+			req:     withdrawRequest{},
+			details: map[string]string{"amount": "required"},
+		},
+		{
+			name:    "Negative amount",
+			req:     depositRequest{Amount: decimal.NewFromInt(-5)},
+			details: map[string]string{"amount": "gt=0"},
+		},
+		{
+			name:    "Malformed recipient IDs",
+			req:     transferRequest{ToWalletID: "invalid-uuid", ToUserID: "also-invalid", Amount: decimal.NewFromInt(1)},
+			details: map[string]string{"to_wallet_id": "uuid", "to_user_id": "uuid"},
+		},
+		{
+			name:    "Batch item",
+			req:     batchTransferRequest{Transfers: []transferRequest{{ToWalletID: "123e4567-e89b-12d3-a456-426614174000", Amount: decimal.NewFromInt(1)}, {}}},
+			details: map[string]string{"transfers[1].amount": "required"},
+		},
+		{
+			name:    "Empty name",
+			req:     createUserRequest{},
+			details: map[string]string{"name": "required"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			appErr := validateRequest(tt.req)
+
+			if tt.details == nil {
+				assert.Nil(t, appErr)
+				return
+			}
+			require.NotNil(t, appErr)
+			assert.Equal(t, errors.ErrValidation, appErr.Code)
+			assert.Equal(t, http.StatusBadRequest, appErr.HTTPStatus)
+			assert.Equal(t, tt.details, appErr.Details)
+		})
+	}
+}
+
+// TestDepositRejectsInvalidBody tests that an invalid body is answered before the service is called
+func TestDepositRejectsInvalidBody(t *testing.T) {
+	router := chi.NewRouter()
+	router.Post("/wallets/{id}/deposit", NewWalletHandler(nil).Deposit)
+
+	req := httptest.NewRequest(http.MethodPost, "/wallets/123e4567-e89b-12d3-a456-426614174000/deposit",
+		strings.NewReader(`{"amount": 0}`))
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Equal(t, response.ContentTypeProblem, rr.Header().Get("Content-Type"))
+
+	var body response.Problem
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&body))
+	assert.Equal(t, http.StatusBadRequest, body.Status)
+	assert.Equal(t, errors.ErrValidation, body.Code)
+	assert.Equal(t, map[string]string{"amount": "required"}, body.Details)
+}
+
+// TestDecodeRequest tests that request bodies are decoded strictly
+func TestDecodeRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		code    string
+		details map[string]string
+	}{
+		{name: "Valid body", body: `{"amount": 10, "currency": "USD"}`},
+		{name: "Unknown field", body: `{"amount": 10, "amout": 5}`, code: errors.ErrValidation, details: map[string]string{"amout": "unknown"}},
+		{name: "Amount as string", body: `{"amount": "10.10", "currency": "USD"}`},
+		{name: "Wrong type", body: `{"amount": 10, "currency": 840}`, code: errors.ErrValidation, details: map[string]string{"currency": "type=string"}},
+		{name: "Malformed amount", body: `{"amount": "ten"}`, code: errors.ErrInvalidInput},
+		{name: "Trailing data", body: `{"amount": 10}{"amount": 20}`, code: errors.ErrInvalidInput},
+		{name: "Malformed JSON", body: `{"amount": 10,}`, code: errors.ErrInvalidInput, details: map[string]string{"offset": "15"}},
+		{name: "Truncated JSON", body: `{"amount": 10`, code: errors.ErrInvalidInput},
+		{name: "Empty body", body: ``, code: errors.ErrInvalidInput},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+
+			var dst depositRequest
+			appErr := decodeRequest(req, &dst)
+
+			if tt.code == "" {
+				assert.Nil(t, appErr)
+				return
+			}
+			require.NotNil(t, appErr)
+			assert.Equal(t, tt.code, appErr.Code)
+			assert.Equal(t, http.StatusBadRequest, appErr.HTTPStatus)
+			if tt.details != nil {
+				assert.Equal(t, tt.details, appErr.Details)
+			}
+		})
+	}
+}
+
+// TestDecodeRequestKeepsEveryDigitOfAnAmount tests that amounts are not rounded through a float
+func TestDecodeRequestKeepsEveryDigitOfAnAmount(t *testing.T) {
+	for _, body := range []string{`{"amount": 1234567890123456.78}`, `{"amount": "1234567890123456.78"}`} {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+
+		var dst depositRequest
+		require.Nil(t, decodeRequest(req, &dst))
+		assert.Equal(t, "1234567890123456.78", dst.Amount.String(), body)
+	}
+}
+
+// TestDecodeRequestReportsOversizedBody tests that a body cut off by the size limit is a 413
+func TestDecodeRequestReportsOversizedBody(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name": "`+strings.Repeat("a", 64)+`"}`))
+	req.Body = http.MaxBytesReader(httptest.NewRecorder(), req.Body, 32)
+
+	var dst createUserRequest
+	appErr := decodeRequest(req, &dst)
+
+	require.NotNil(t, appErr)
+	assert.Equal(t, errors.ErrPayloadSize, appErr.Code)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, appErr.HTTPStatus)
+	assert.Equal(t, "32", appErr.Details["max_bytes"])
+}
+
+// TestDecodeOptionalRequestAllowsEmptyBody tests that an optional body may be left out
+func TestDecodeOptionalRequestAllowsEmptyBody(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(""))
+
+	var dst captureRequest
+	assert.Nil(t, decodeOptionalRequest(req, &dst))
+	assert.Nil(t, dst.Amount)
+}
@@ -1,44 +1,219 @@
 package handlers
 
 import (
-	"encoding/json"
+	stderrors "errors"
 	"net/http"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 	"github.com/shopspring/decimal"
 	"go.uber.org/zap"
 
+	"github.com/shanwije/wallet-app/internal/fx"
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository"
 	"github.com/shanwije/wallet-app/internal/service"
+	"github.com/shanwije/wallet-app/pkg/auth"
 	"github.com/shanwije/wallet-app/pkg/errors"
 	"github.com/shanwije/wallet-app/pkg/logger"
+	"github.com/shanwije/wallet-app/pkg/money"
+	"github.com/shanwije/wallet-app/pkg/pagination"
+	"github.com/shanwije/wallet-app/pkg/response"
 )
 
 type WalletHandler struct {
-	WalletService *service.WalletService
+	WalletService WalletServiceInterface
+	// PendingTransfers holds transfers over its threshold for confirmation; nil lets
+	// every transfer through at once
+	PendingTransfers *service.PendingTransferService
+	// PINs asks for the owner's transaction PIN on withdrawals and transfers over its
+	// threshold; nil never asks for it
+	PINs *service.TransactionPINService
+	// DepositRefunds refunds deposits
+	DepositRefunds *service.DepositRefundService
 }
 
 type depositRequest struct {
-	Amount float64 `json:"amount"`
+	Amount   decimal.Decimal `json:"amount" swaggertype:"string" validate:"required,gt=0" example:"25.00"`
+	Currency string          `json:"currency,omitempty"`
+	Category string          `json:"category,omitempty" validate:"max=50" example:"salary"`
+	Tags     []string        `json:"tags,omitempty" validate:"max=10,dive,max=50"`
 }
 
 type withdrawRequest struct {
-	Amount float64 `json:"amount"`
+	Amount   decimal.Decimal `json:"amount" swaggertype:"string" validate:"required,gt=0" example:"25.00"`
+	Currency string          `json:"currency,omitempty"`
+	Category string          `json:"category,omitempty" validate:"max=50" example:"groceries"`
+	Tags     []string        `json:"tags,omitempty" validate:"max=10,dive,max=50"`
 }
 
+// transferRequest names the recipient with exactly one of to_wallet_id, to_user_id or to_username
 type transferRequest struct {
-	ToWalletID  string  `json:"to_wallet_id"`
-	Amount      float64 `json:"amount"`
-	Description string  `json:"description,omitempty"`
+	ToWalletID  string          `json:"to_wallet_id,omitempty" validate:"omitempty,uuid"`
+	ToUserID    string          `json:"to_user_id,omitempty" validate:"omitempty,uuid"`
+	ToUsername  string          `json:"to_username,omitempty" example:"@alice"`
+	Amount      decimal.Decimal `json:"amount" swaggertype:"string" validate:"required,gt=0" example:"25.00"`
+	Currency    string          `json:"currency,omitempty"`
+	Description string          `json:"description,omitempty"`
+	Category    string          `json:"category,omitempty" validate:"max=50" example:"rent"`
+	Tags        []string        `json:"tags,omitempty" validate:"max=10,dive,max=50"`
+	// QuoteID runs the transfer on the fee and exchange rate of a quote for it
+	QuoteID string `json:"quote_id,omitempty" validate:"omitempty,uuid"`
 }
 
 // NewWalletHandler creates a new WalletHandler
-func NewWalletHandler(walletService *service.WalletService) *WalletHandler {
+func NewWalletHandler(walletService WalletServiceInterface) *WalletHandler {
 	return &WalletHandler{
 		WalletService: walletService,
 	}
 }
 
+// RequireOwnership is route middleware for /wallets/{id} that rejects callers
+// who are not the wallet's owner. It must run after the auth middleware.
+func (h *WalletHandler) RequireOwnership(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		log := logger.FromContext(r.Context())
+
+		userID, ok := auth.UserIDFromContext(r.Context())
+		if !ok {
+			response.Error(w, errors.Unauthorized("Authentication required"))
+			return
+		}
+
+		walletIDStr := chi.URLParam(r, "id")
+		walletID, err := uuid.Parse(walletIDStr)
+		if err != nil {
+			response.ErrorMessage(w, http.StatusBadRequest, "Invalid wallet ID")
+			return
+		}
+
+		wallet, err := h.WalletService.GetBalance(r.Context(), walletID)
+		if err != nil {
+			response.Error(w, walletAppError(err, walletIDStr))
+			return
+		}
+
+		if wallet.UserID != userID {
+			log.Warn("Wallet access denied",
+				zap.String("wallet_id", walletIDStr),
+				zap.String("user_id", userID.String()))
+			response.Error(w, errors.Forbidden("You do not have access to this wallet"))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// walletAppError maps a failure to load a wallet: 404 when it does not exist and 500
+// for anything else
+func walletAppError(err error, walletID string) *errors.AppError {
+	if stderrors.Is(err, service.ErrWalletNotFound) {
+		return errors.WalletNotFound(walletID)
+	}
+	return errors.InternalError(err)
+}
+
+// movementAppError maps the failures of a deposit, withdrawal or transfer that have
+// their own error code; it returns nil for the rest
+func movementAppError(err error) *errors.AppError {
+	switch {
+	case stderrors.Is(err, service.ErrWalletNotFound):
+		return errors.New(errors.ErrWalletNotFound, err.Error(), http.StatusNotFound)
+	case stderrors.Is(err, service.ErrInsufficientBalance):
+		return errors.InsufficientFunds()
+	case stderrors.Is(err, service.ErrInvalidAmount):
+		return errors.InvalidInput(err.Error())
+	case stderrors.Is(err, service.ErrAmountOutOfRange):
+		return amountRangeAppError(err)
+	case stderrors.Is(err, service.ErrSameWallet):
+		return errors.New(errors.ErrSameWalletTransfer, err.Error(), http.StatusBadRequest)
+	case stderrors.Is(err, fx.ErrRateUnavailable):
+		return errors.New(errors.ErrExchangeRateUnavailable, "No exchange rate is available for this currency pair", http.StatusServiceUnavailable)
+	case stderrors.Is(err, service.ErrIdempotencyKeyReused):
+		return errors.Conflict(err.Error())
+	case stderrors.Is(err, service.ErrVersionMismatch):
+		return errors.PreconditionFailed("The wallet has changed since its ETag was read")
+	case stderrors.Is(err, service.ErrConcurrentUpdate):
+		return errors.Conflict(service.ErrConcurrentUpdate.Error() + ", please retry")
+	case stderrors.Is(err, models.ErrWalletFrozen):
+		return errors.WalletFrozen(err.Error())
+	case stderrors.Is(err, models.ErrWalletClosed):
+		return errors.WalletClosed(err.Error())
+	case stderrors.Is(err, service.ErrLimitExceeded):
+		return errors.LimitExceeded(err.Error())
+	case stderrors.Is(err, service.ErrBlockedByRiskCheck):
+		return errors.New(errors.ErrOperationBlocked, err.Error(), http.StatusForbidden)
+	case stderrors.Is(err, service.ErrQuoteNotFound):
+		return errors.New(errors.ErrQuoteNotFound, err.Error(), http.StatusNotFound)
+	case stderrors.Is(err, service.ErrQuoteExpired), stderrors.Is(err, service.ErrQuoteUsed):
+		return errors.Conflict(err.Error())
+	case stderrors.Is(err, service.ErrQuoteMismatch),
+		stderrors.Is(err, service.ErrQuoteNeedsConfirmation):
+		return errors.InvalidInput(err.Error())
+	case stderrors.Is(err, service.ErrFeatureDisabled):
+		return errors.New(errors.ErrFeatureDisabled, err.Error(), http.StatusForbidden)
+	case stderrors.Is(err, service.ErrSettlementWallet):
+		return errors.New(errors.ErrSettlementWallet, err.Error(), http.StatusUnprocessableEntity)
+	case stderrors.Is(err, service.ErrConfirmationRequired):
+		return errors.New(errors.ErrConfirmationRequired, err.Error(), http.StatusUnprocessableEntity)
+	case stderrors.Is(err, service.ErrPINRequired),
+		stderrors.Is(err, service.ErrIncorrectPIN),
+		stderrors.Is(err, service.ErrPINLocked):
+		return pinAppError(err)
+	default:
+		return nil
+	}
+}
+
+// amountRangeAppError reports an amount outside the allowed range, with the bounds in
+// its details
+func amountRangeAppError(err error) *errors.AppError {
+	appErr := errors.New(errors.ErrAmountOutOfRange, err.Error(), http.StatusBadRequest)
+	var rangeErr *service.AmountRangeError
+	if stderrors.As(err, &rangeErr) {
+		if rangeErr.Min != nil {
+			appErr.WithDetails("min_amount", rangeErr.Min.String())
+		}
+		if rangeErr.Max != nil {
+			appErr.WithDetails("max_amount", rangeErr.Max.String())
+		}
+	}
+	return appErr
+}
+
+// recipientAppError maps a failure to resolve the recipient of a transfer
+func recipientAppError(err error, req transferRequest) *errors.AppError {
+	switch {
+	case stderrors.Is(err, service.ErrInvalidRecipient):
+		return errors.New(errors.ErrMissingField, "Exactly one of to_wallet_id, to_user_id or to_username is required", http.StatusBadRequest)
+	case stderrors.Is(err, service.ErrRecipientNotFound):
+		appErr := errors.New(errors.ErrUserNotFound, "Recipient not found", http.StatusNotFound)
+		if req.ToUserID != "" {
+			return appErr.WithDetails("user_id", req.ToUserID)
+		}
+		return appErr.WithDetails("username", req.ToUsername)
+	case stderrors.Is(err, service.ErrRecipientHasNoWallet):
+		return errors.New(errors.ErrWalletNotFound, "Recipient has no wallet", http.StatusNotFound)
+	default:
+		return errors.InternalError(err)
+	}
+}
+
+// recipientFromRequest reads the recipient of a validated transfer request, whose IDs
+// are known to parse
+func recipientFromRequest(req transferRequest) service.Recipient {
+	recipient := service.Recipient{Username: req.ToUsername}
+	if req.ToWalletID != "" {
+		recipient.WalletID = uuid.MustParse(req.ToWalletID)
+	}
+	if req.ToUserID != "" {
+		recipient.UserID = uuid.MustParse(req.ToUserID)
+	}
+	return recipient
+}
+
 // Deposit adds money to a wallet
 // @Summary Deposit to wallet
 // @Tags wallets
@@ -46,45 +221,95 @@ func NewWalletHandler(walletService *service.WalletService) *WalletHandler {
 // @Produce json
 // @Param id path string true "Wallet ID"
 // @Param deposit body depositRequest true "Deposit details"
+// @Param Idempotency-Key header string false "Makes retries safe: a repeat with the same key and body gets the first response"
 // @Success 200 {object} models.Wallet
+// @Failure 400 {object} response.Problem "Invalid wallet ID, request body or amount"
+// @Failure 404 {object} response.Problem "Wallet not found"
+// @Failure 409 {object} response.Problem "Wallet frozen or closed, concurrent update, or Idempotency-Key reused"
+// @Failure 422 {object} response.Problem "Wallet limit exceeded"
+// @Failure 429 {object} response.Problem "Rate limit exceeded"
+// @Failure 500 {object} response.Problem "Internal server error"
 // @Router /api/v1/wallets/{id}/deposit [post]
 func (h *WalletHandler) Deposit(w http.ResponseWriter, r *http.Request) {
+	if result := h.deposit(w, r); result != nil {
+		response.OK(w, result.Wallet)
+	}
+}
+
+// DepositV2 adds money to a wallet and returns the transaction it created
+// @Summary Deposit to wallet
+// @Description Unlike v1, responds with the transaction, the wallet's balance before and after it and the wallet, and links to the transaction in the Location header.
+// @Tags wallets
+// @Accept json
+// @Produce json
+// @Param id path string true "Wallet ID"
+// @Param deposit body depositRequest true "Deposit details"
+// @Param Idempotency-Key header string false "Makes retries safe: a repeat with the same key and body gets the first response"
+// @Success 201 {object} movementResponse
+// @Header 201 {string} Location "The created transaction"
+// @Failure 400 {object} response.Problem "Invalid wallet ID, request body or amount"
+// @Failure 404 {object} response.Problem "Wallet not found"
+// @Failure 409 {object} response.Problem "Wallet frozen or closed, concurrent update, or Idempotency-Key reused"
+// @Failure 422 {object} response.Problem "Wallet limit exceeded"
+// @Failure 429 {object} response.Problem "Rate limit exceeded"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v2/wallets/{id}/deposit [post]
+func (h *WalletHandler) DepositV2(w http.ResponseWriter, r *http.Request) {
+	if result := h.deposit(w, r); result != nil {
+		respondWithMovement(w, result)
+	}
+}
+
+// deposit runs a deposit request, responding itself when it fails
+func (h *WalletHandler) deposit(w http.ResponseWriter, r *http.Request) *service.MovementResult {
 	log := logger.FromContext(r.Context())
 	ctx := r.Context()
 	walletIDStr := chi.URLParam(r, "id")
 	walletID, err := uuid.Parse(walletIDStr)
 	if err != nil {
 		log.Error("Invalid wallet ID in deposit request", zap.Error(err), zap.String("id", walletIDStr))
-		errors.RespondWithError(w, http.StatusBadRequest, "Invalid wallet ID")
-		return
+		response.ErrorMessage(w, http.StatusBadRequest, "Invalid wallet ID")
+		return nil
 	}
 
 	var req depositRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		log.Error("Failed to decode deposit request", zap.Error(err))
-		errors.RespondWithError(w, http.StatusBadRequest, "Invalid request format")
-		return
+	if appErr := decodeRequest(r, &req); appErr != nil {
+		log.Error("Failed to decode deposit request", zap.Error(appErr))
+		response.Error(w, appErr)
+		return nil
+	}
+	if appErr := validateRequest(req); appErr != nil {
+		log.Warn("Invalid deposit request", zap.Any("fields", appErr.Details))
+		response.Error(w, appErr)
+		return nil
 	}
 
-	// Convert float64 to decimal for precise calculations
-	amount := decimal.NewFromFloat(req.Amount)
+	amount, appErr := parseAmount(req.Amount, req.Currency)
+	if appErr != nil {
+		log.Warn("Invalid amount in deposit request", zap.Error(appErr))
+		response.Error(w, appErr)
+		return nil
+	}
 
-	wallet, err := h.WalletService.Deposit(ctx, walletID, amount)
+	ctx = service.WithClassification(ctx, req.Category, req.Tags)
+	result, err := h.WalletService.CreateDeposit(ctx, walletID, amount, r.Header.Get("Idempotency-Key"))
 	if err != nil {
 		log.Error("Deposit failed", zap.Error(err),
 			zap.String("wallet_id", walletID.String()),
 			zap.String("amount", amount.String()))
-		errors.RespondWithError(w, http.StatusBadRequest, err.Error())
-		return
+		if appErr := movementAppError(err); appErr != nil {
+			response.Error(w, appErr)
+			return nil
+		}
+		response.ErrorMessage(w, http.StatusBadRequest, err.Error())
+		return nil
 	}
 
 	log.Info("Deposit successful",
 		zap.String("wallet_id", walletID.String()),
 		zap.String("amount", amount.String()),
-		zap.String("new_balance", wallet.Balance.String()))
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(wallet)
+		zap.String("new_balance", result.Wallet.Balance.String()))
+	return result
 }
 
 // Withdraw removes money from a wallet
@@ -94,140 +319,581 @@ func (h *WalletHandler) Deposit(w http.ResponseWriter, r *http.Request) {
 // @Produce json
 // @Param id path string true "Wallet ID"
 // @Param withdraw body withdrawRequest true "Withdraw details"
+// @Param Idempotency-Key header string false "Makes retries safe: a repeat with the same key and body gets the first response"
+// @Param X-Transaction-PIN header string false "Owner's transaction PIN, needed over the PIN threshold once they have set one"
+// @Param If-Match header string false "ETag of the wallet from its balance; the request fails with 412 if the wallet has changed since"
 // @Success 200 {object} models.Wallet
+// @Failure 400 {object} response.Problem "Invalid wallet ID, request body or amount, or insufficient funds"
+// @Failure 403 {object} response.Problem "Transaction PIN required or incorrect"
+// @Failure 404 {object} response.Problem "Wallet not found"
+// @Failure 409 {object} response.Problem "Wallet frozen or closed, concurrent update, or Idempotency-Key reused"
+// @Failure 412 {object} response.Problem "Wallet changed since the ETag in If-Match was read"
+// @Failure 422 {object} response.Problem "Wallet limit exceeded"
+// @Failure 423 {object} response.Problem "Transaction PIN locked after too many incorrect attempts"
+// @Failure 429 {object} response.Problem "Rate limit exceeded"
+// @Failure 500 {object} response.Problem "Internal server error"
 // @Router /api/v1/wallets/{id}/withdraw [post]
 func (h *WalletHandler) Withdraw(w http.ResponseWriter, r *http.Request) {
+	if result := h.withdraw(w, r); result != nil {
+		response.OK(w, result.Wallet)
+	}
+}
+
+// WithdrawV2 removes money from a wallet and returns the transaction it created
+// @Summary Withdraw from wallet
+// @Description Unlike v1, responds with the transaction, the wallet's balance before and after it and the wallet, and links to the transaction in the Location header.
+// @Tags wallets
+// @Accept json
+// @Produce json
+// @Param id path string true "Wallet ID"
+// @Param withdraw body withdrawRequest true "Withdraw details"
+// @Param Idempotency-Key header string false "Makes retries safe: a repeat with the same key and body gets the first response"
+// @Param X-Transaction-PIN header string false "Owner's transaction PIN, needed over the PIN threshold once they have set one"
+// @Param If-Match header string false "ETag of the wallet from its balance; the request fails with 412 if the wallet has changed since"
+// @Success 201 {object} movementResponse
+// @Header 201 {string} Location "The created transaction"
+// @Failure 400 {object} response.Problem "Invalid wallet ID, request body or amount, or insufficient funds"
+// @Failure 403 {object} response.Problem "Transaction PIN required or incorrect"
+// @Failure 404 {object} response.Problem "Wallet not found"
+// @Failure 409 {object} response.Problem "Wallet frozen or closed, concurrent update, or Idempotency-Key reused"
+// @Failure 412 {object} response.Problem "Wallet changed since the ETag in If-Match was read"
+// @Failure 422 {object} response.Problem "Wallet limit exceeded"
+// @Failure 423 {object} response.Problem "Transaction PIN locked after too many incorrect attempts"
+// @Failure 429 {object} response.Problem "Rate limit exceeded"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v2/wallets/{id}/withdraw [post]
+func (h *WalletHandler) WithdrawV2(w http.ResponseWriter, r *http.Request) {
+	if result := h.withdraw(w, r); result != nil {
+		respondWithMovement(w, result)
+	}
+}
+
+// withdraw runs a withdrawal request, responding itself when it fails
+func (h *WalletHandler) withdraw(w http.ResponseWriter, r *http.Request) *service.MovementResult {
 	log := logger.FromContext(r.Context())
 	ctx := r.Context()
 	walletIDStr := chi.URLParam(r, "id")
 	walletID, err := uuid.Parse(walletIDStr)
 	if err != nil {
 		log.Error("Invalid wallet ID in withdraw request", zap.Error(err), zap.String("id", walletIDStr))
-		errors.RespondWithError(w, http.StatusBadRequest, "Invalid wallet ID")
-		return
+		response.ErrorMessage(w, http.StatusBadRequest, "Invalid wallet ID")
+		return nil
 	}
 
 	var req withdrawRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		log.Error("Failed to decode withdraw request", zap.Error(err))
-		errors.RespondWithError(w, http.StatusBadRequest, "Invalid request format")
-		return
+	if appErr := decodeRequest(r, &req); appErr != nil {
+		log.Error("Failed to decode withdraw request", zap.Error(appErr))
+		response.Error(w, appErr)
+		return nil
+	}
+	if appErr := validateRequest(req); appErr != nil {
+		log.Warn("Invalid withdraw request", zap.Any("fields", appErr.Details))
+		response.Error(w, appErr)
+		return nil
 	}
 
-	// Convert float64 to decimal for precise calculations
-	amount := decimal.NewFromFloat(req.Amount)
+	amount, appErr := parseAmount(req.Amount, req.Currency)
+	if appErr != nil {
+		log.Warn("Invalid amount in withdraw request", zap.Error(appErr))
+		response.Error(w, appErr)
+		return nil
+	}
+	ctx, appErr = withIfMatch(r, walletID)
+	if appErr != nil {
+		response.Error(w, appErr)
+		return nil
+	}
+	if appErr := authorizePIN(r, h.PINs, walletID, amount); appErr != nil {
+		response.Error(w, appErr)
+		return nil
+	}
 
-	wallet, err := h.WalletService.Withdraw(ctx, walletID, amount)
+	ctx = service.WithClassification(ctx, req.Category, req.Tags)
+	result, err := h.WalletService.CreateWithdrawal(ctx, walletID, amount, r.Header.Get("Idempotency-Key"))
 	if err != nil {
 		log.Error("Withdraw failed", zap.Error(err),
 			zap.String("wallet_id", walletID.String()),
 			zap.String("amount", amount.String()))
-		errors.RespondWithError(w, http.StatusBadRequest, err.Error())
-		return
+		if appErr := movementAppError(err); appErr != nil {
+			response.Error(w, appErr)
+			return nil
+		}
+		response.ErrorMessage(w, http.StatusBadRequest, err.Error())
+		return nil
 	}
 
 	log.Info("Withdraw successful",
 		zap.String("wallet_id", walletID.String()),
 		zap.String("amount", amount.String()),
-		zap.String("new_balance", wallet.Balance.String()))
+		zap.String("new_balance", result.Wallet.Balance.String()))
+	return result
+}
+
+// movementResponse is the transaction created by a v2 deposit or withdrawal, with the
+// wallet's balance before and after it and the wallet as it was left
+type movementResponse struct {
+	*models.Transaction
+	Currency        money.Currency   `json:"currency"`
+	PreviousBalance decimal.Decimal  `json:"previous_balance"`
+	NewBalance      decimal.Decimal  `json:"new_balance"`
+	Fee             *decimal.Decimal `json:"fee,omitempty"`
+	Wallet          *models.Wallet   `json:"wallet"`
+}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(wallet)
+// respondWithMovement answers a v2 deposit or withdrawal with the transaction it created
+func respondWithMovement(w http.ResponseWriter, result *service.MovementResult) {
+	response.Created(w, "/api/v2/transactions/"+result.Transaction.ID.String(), movementResponse{
+		Transaction:     result.Transaction,
+		Currency:        result.Wallet.Currency,
+		PreviousBalance: result.PreviousBalance,
+		NewBalance:      result.NewBalance,
+		Fee:             chargedFee(result.Fee),
+		Wallet:          result.Wallet,
+	})
 }
 
 // Transfer moves money from one wallet to another
 // @Summary Transfer between wallets
+// @Description The recipient is a wallet ID, or a user ID or username whose wallet is credited.
+// @Description The amount is in the sender's currency; a recipient holding another currency is credited its conversion at the current exchange rate.
+// @Description With quote_id, the transfer runs at the fee and exchange rate of that quote, once and before it expires.
+// @Description A transfer over the confirmation threshold moves no money yet: it is answered with 202 and a pending transfer to confirm, and cannot pass quote_id.
 // @Tags wallets
 // @Accept json
 // @Produce json
 // @Param id path string true "Wallet ID"
 // @Param transfer body transferRequest true "Transfer details"
+// @Param Idempotency-Key header string false "Makes retries safe: a repeat with the same key and body gets the first response"
+// @Param X-Transaction-PIN header string false "Owner's transaction PIN, needed over the PIN threshold once they have set one"
+// @Param If-Match header string false "ETag of the wallet from its balance; the request fails with 412 if the wallet has changed since"
 // @Success 200 {object} models.Wallet
+// @Success 202 {object} models.PendingTransfer
+// @Failure 400 {object} response.Problem "Invalid wallet ID, request body or amount, or insufficient funds"
+// @Failure 403 {object} response.Problem "Transaction PIN required or incorrect"
+// @Failure 404 {object} response.Problem "Wallet, recipient or quote not found"
+// @Failure 409 {object} response.Problem "Wallet frozen or closed, concurrent update, quote expired or already used, or Idempotency-Key reused"
+// @Failure 412 {object} response.Problem "Wallet changed since the ETag in If-Match was read"
+// @Failure 422 {object} response.Problem "Wallet limit exceeded"
+// @Failure 423 {object} response.Problem "Transaction PIN locked after too many incorrect attempts"
+// @Failure 429 {object} response.Problem "Rate limit exceeded"
+// @Failure 500 {object} response.Problem "Internal server error"
 // @Router /api/v1/wallets/{id}/transfer [post]
 func (h *WalletHandler) Transfer(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
-	fromWalletIDStr := chi.URLParam(r, "id")
-	fromWalletID, err := uuid.Parse(fromWalletIDStr)
+	transfer, appErr := h.parseTransfer(r)
+	if appErr != nil {
+		response.Error(w, appErr)
+		return
+	}
+	ctx, appErr := withIfMatch(r, transfer.fromWalletID)
+	if appErr != nil {
+		response.Error(w, appErr)
+		return
+	}
+	ctx = service.WithClassification(ctx, transfer.category, transfer.tags)
+	if transfer.quoteID != uuid.Nil {
+		ctx = service.WithQuote(ctx, transfer.quoteID)
+	}
+	if appErr := authorizePIN(r, h.PINs, transfer.fromWalletID, transfer.amount); appErr != nil {
+		response.Error(w, appErr)
+		return
+	}
+	if h.PendingTransfers.RequiresConfirmation(transfer.amount) {
+		h.holdForConfirmation(ctx, w, r, transfer)
+		return
+	}
+
+	err := h.WalletService.Transfer(ctx, transfer.fromWalletID, transfer.toWalletID, transfer.amount, transfer.description, r.Header.Get("Idempotency-Key"))
 	if err != nil {
-		http.Error(w, "Invalid source wallet ID", http.StatusBadRequest)
+		if appErr := movementAppError(err); appErr != nil {
+			response.Error(w, appErr)
+			return
+		}
+		response.ErrorMessage(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	var req transferRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request", http.StatusBadRequest)
+	response.OK(w, map[string]string{
+		"message": "Transfer completed successfully",
+	})
+}
+
+// transferResponse is the transfer created by a v2 transfer request, with the IDs of
+// the transactions it added to each wallet's history and the balances it left them with
+type transferResponse struct {
+	models.Transfer
+	FromTransactionID uuid.UUID        `json:"from_transaction_id"`
+	ToTransactionID   uuid.UUID        `json:"to_transaction_id"`
+	FromBalance       decimal.Decimal  `json:"from_balance"`
+	ToBalance         decimal.Decimal  `json:"to_balance"`
+	Fee               *decimal.Decimal `json:"fee,omitempty"`
+}
+
+// newTransferResponse describes the result of a transfer
+func newTransferResponse(result *service.TransferResult) transferResponse {
+	response := transferResponse{
+		Transfer:    *result.Transfer,
+		FromBalance: result.FromWallet.Balance,
+		ToBalance:   result.ToWallet.Balance,
+		Fee:         chargedFee(result.Fee),
+	}
+	for _, leg := range result.Transfer.Legs {
+		switch {
+		case leg.WalletID == nil:
+		case *leg.WalletID == result.Transfer.FromWalletID && leg.Direction == models.EntryDirectionDebit:
+			response.FromTransactionID = leg.ID
+		case *leg.WalletID == result.Transfer.ToWalletID && leg.Direction == models.EntryDirectionCredit:
+			response.ToTransactionID = leg.ID
+		}
+	}
+	return response
+}
+
+// TransferV2 moves money from one wallet to another and returns the transfer it created
+// @Summary Transfer between wallets
+// @Description The recipient is a wallet ID, or a user ID or username whose wallet is credited.
+// @Description The amount is in the sender's currency; a recipient holding another currency is credited its conversion at the current exchange rate.
+// @Description With quote_id, the transfer runs at the fee and exchange rate of that quote, once and before it expires.
+// @Description Unlike v1, responds with the transfer: its legs, the IDs of the transactions in each wallet's history and both wallets' balances after it. The Location header links to the transfer.
+// @Description A transfer over the confirmation threshold moves no money yet: it is answered with 202 and a pending transfer to confirm, and cannot pass quote_id.
+// @Tags wallets
+// @Accept json
+// @Produce json
+// @Param id path string true "Wallet ID"
+// @Param transfer body transferRequest true "Transfer details"
+// @Param Idempotency-Key header string false "Makes retries safe: a repeat with the same key and body gets the first response"
+// @Param X-Transaction-PIN header string false "Owner's transaction PIN, needed over the PIN threshold once they have set one"
+// @Param If-Match header string false "ETag of the wallet from its balance; the request fails with 412 if the wallet has changed since"
+// @Success 201 {object} transferResponse
+// @Header 201 {string} Location "The created transfer"
+// @Success 202 {object} models.PendingTransfer
+// @Header 202 {string} Location "The transfer awaiting confirmation"
+// @Failure 400 {object} response.Problem "Invalid wallet ID, request body or amount, or insufficient funds"
+// @Failure 403 {object} response.Problem "Transaction PIN required or incorrect"
+// @Failure 404 {object} response.Problem "Wallet, recipient or quote not found"
+// @Failure 409 {object} response.Problem "Wallet frozen or closed, concurrent update, quote expired or already used, or Idempotency-Key reused"
+// @Failure 412 {object} response.Problem "Wallet changed since the ETag in If-Match was read"
+// @Failure 422 {object} response.Problem "Wallet limit exceeded"
+// @Failure 423 {object} response.Problem "Transaction PIN locked after too many incorrect attempts"
+// @Failure 429 {object} response.Problem "Rate limit exceeded"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v2/wallets/{id}/transfer [post]
+func (h *WalletHandler) TransferV2(w http.ResponseWriter, r *http.Request) {
+	transfer, appErr := h.parseTransfer(r)
+	if appErr != nil {
+		response.Error(w, appErr)
+		return
+	}
+	ctx, appErr := withIfMatch(r, transfer.fromWalletID)
+	if appErr != nil {
+		response.Error(w, appErr)
+		return
+	}
+	ctx = service.WithClassification(ctx, transfer.category, transfer.tags)
+	if transfer.quoteID != uuid.Nil {
+		ctx = service.WithQuote(ctx, transfer.quoteID)
+	}
+	if appErr := authorizePIN(r, h.PINs, transfer.fromWalletID, transfer.amount); appErr != nil {
+		response.Error(w, appErr)
+		return
+	}
+	if h.PendingTransfers.RequiresConfirmation(transfer.amount) {
+		h.holdForConfirmation(ctx, w, r, transfer)
 		return
 	}
 
-	toWalletID, err := uuid.Parse(req.ToWalletID)
+	result, err := h.WalletService.CreateTransfer(ctx, transfer.fromWalletID, transfer.toWalletID, transfer.amount, transfer.description, r.Header.Get("Idempotency-Key"))
 	if err != nil {
-		http.Error(w, "Invalid destination wallet ID", http.StatusBadRequest)
+		if appErr := movementAppError(err); appErr != nil {
+			response.Error(w, appErr)
+			return
+		}
+		response.ErrorMessage(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	// Convert float64 to decimal for precise calculations
-	amount := decimal.NewFromFloat(req.Amount)
+	response.Created(w, "/api/v2/transfers/"+result.Transfer.ReferenceID.String(), newTransferResponse(result))
+}
 
-	err = h.WalletService.Transfer(ctx, fromWalletID, toWalletID, amount, req.Description)
+// transferInput is a transfer request with its wallets resolved
+type transferInput struct {
+	fromWalletID uuid.UUID
+	toWalletID   uuid.UUID
+	amount       money.Money
+	description  string
+	category     string
+	tags         []string
+	quoteID      uuid.UUID
+}
+
+// parseTransfer reads a transfer request from the sending wallet's route, resolving
+// its recipient to a wallet
+func (h *WalletHandler) parseTransfer(r *http.Request) (*transferInput, *errors.AppError) {
+	fromWalletID, err := uuid.Parse(chi.URLParam(r, "id"))
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
+		return nil, errors.InvalidInput("Invalid source wallet ID")
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{
-		"message": "Transfer completed successfully",
-	})
+	var req transferRequest
+	if appErr := decodeRequest(r, &req); appErr != nil {
+		return nil, appErr
+	}
+	if appErr := validateRequest(req); appErr != nil {
+		return nil, appErr
+	}
+
+	amount, appErr := parseAmount(req.Amount, req.Currency)
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	toWalletID, err := h.WalletService.ResolveRecipient(r.Context(), recipientFromRequest(req))
+	if err != nil {
+		return nil, recipientAppError(err, req)
+	}
+
+	transfer := &transferInput{
+		fromWalletID: fromWalletID,
+		toWalletID:   toWalletID,
+		amount:       amount,
+		description:  req.Description,
+		category:     req.Category,
+		tags:         req.Tags,
+	}
+	if req.QuoteID != "" {
+		transfer.quoteID = uuid.MustParse(req.QuoteID)
+	}
+	return transfer, nil
 }
 
 // GetBalance gets wallet balance
 // @Summary Get wallet balance
+// @Description Returns the wallet. With at, returns instead its posted balance at that
+// @Description time as a models.HistoricalBalance, counting the transactions made before it.
 // @Tags wallets
 // @Produce json
 // @Param id path string true "Wallet ID"
+// @Param at query string false "RFC3339 time to reconstruct the balance at"
 // @Success 200 {object} models.Wallet
+// @Header 200 {string} ETag "The wallet's version, for If-Match on withdrawals and transfers"
+// @Failure 400 {object} response.Problem "Invalid wallet ID or time"
+// @Failure 404 {object} response.Problem "Wallet not found"
+// @Failure 500 {object} response.Problem "Internal server error"
 // @Router /api/v1/wallets/{id}/balance [get]
 func (h *WalletHandler) GetBalance(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	walletIDStr := chi.URLParam(r, "id")
 	walletID, err := uuid.Parse(walletIDStr)
 	if err != nil {
-		http.Error(w, "Invalid wallet ID", http.StatusBadRequest)
+		response.ErrorMessage(w, http.StatusBadRequest, "Invalid wallet ID")
+		return
+	}
+
+	if at := r.URL.Query().Get("at"); at != "" {
+		h.getBalanceAt(w, r, walletID, at)
 		return
 	}
 
 	wallet, err := h.WalletService.GetBalance(ctx, walletID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusNotFound)
+		response.Error(w, walletAppError(err, walletIDStr))
+		return
+	}
+
+	w.Header().Set("ETag", walletETag(wallet))
+	response.OK(w, wallet)
+}
+
+// getBalanceAt responds with the wallet's balance at the RFC3339 time at
+func (h *WalletHandler) getBalanceAt(w http.ResponseWriter, r *http.Request, walletID uuid.UUID, at string) {
+	parsed, err := time.Parse(time.RFC3339, at)
+	if err != nil {
+		response.Error(w, errors.InvalidInput("at must be an RFC3339 time").WithDetails("at", at))
+		return
+	}
+
+	balance, err := h.WalletService.GetBalanceAt(r.Context(), walletID, parsed)
+	if err != nil {
+		if stderrors.Is(err, service.ErrFutureBalance) {
+			response.Error(w, errors.InvalidInput("at must not be in the future").WithDetails("at", at))
+			return
+		}
+		if !stderrors.Is(err, service.ErrWalletNotFound) {
+			logger.FromContext(r.Context()).Error("Failed to get historical balance", zap.Error(err))
+		}
+		response.Error(w, walletAppError(err, walletID.String()))
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(wallet)
+	response.OK(w, balance)
 }
 
 // GetTransactionHistory gets transaction history for a wallet
 // @Summary Get wallet transaction history
+// @Description Newest first. Carries the latest transaction as an ETag and Last-Modified, so polling clients can send If-None-Match or If-Modified-Since and get 304 Not Modified until a transaction is added.
+// @Description With category, only the transactions filed under it are listed.
+// @Description With limit, sort or cursor the history is paged: a full page comes with a Link header to the next one.
 // @Tags wallets
 // @Produce json
 // @Param id path string true "Wallet ID"
+// @Param category query string false "Only list transactions in this category"
+// @Param limit query int false "Page size; larger sizes are cut to 200" minimum(0) default(50)
+// @Param sort query string false "created_at, or -created_at for newest first" Enums(created_at, -created_at) default(-created_at)
+// @Param cursor query string false "Cursor from the Link header of the previous page"
+// @Param If-None-Match header string false "ETag of a history already held; answered with 304 while it is current"
+// @Param If-Modified-Since header string false "Last-Modified of a history already held; answered with 304 while it is current"
 // @Success 200 {array} models.Transaction
+// @Header 200 {string} ETag "The ID of the latest transaction"
+// @Header 200 {string} Last-Modified "When the latest transaction was posted"
+// @Header 200 {string} Link "The next page of a paged history, as rel=\"next\""
+// @Success 304 "The history has not changed"
+// @Failure 400 {object} response.Problem "Invalid wallet ID, page, sort or cursor"
+// @Failure 404 {object} response.Problem "Wallet not found"
+// @Failure 500 {object} response.Problem "Internal server error"
 // @Router /api/v1/wallets/{id}/transactions [get]
 func (h *WalletHandler) GetTransactionHistory(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	walletIDStr := chi.URLParam(r, "id")
 	walletID, err := uuid.Parse(walletIDStr)
 	if err != nil {
-		http.Error(w, "Invalid wallet ID", http.StatusBadRequest)
+		response.ErrorMessage(w, http.StatusBadRequest, "Invalid wallet ID")
 		return
 	}
 
-	transactions, err := h.WalletService.GetTransactionHistory(ctx, walletID)
+	query := r.URL.Query()
+	if query.Has("limit") || query.Has("sort") || query.Has("cursor") {
+		h.listTransactionHistory(w, r, walletID)
+		return
+	}
+
+	var transactions []*models.Transaction
+	if category := query.Get("category"); category != "" {
+		transactions, err = h.WalletService.GetTransactionHistoryInCategory(ctx, walletID, category)
+	} else {
+		transactions, err = h.WalletService.GetTransactionHistory(ctx, walletID)
+	}
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusNotFound)
+		response.Error(w, walletAppError(err, walletIDStr))
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(transactions)
+	// Clients polling the history revalidate it with the latest transaction, newest first
+	w.Header().Set("Cache-Control", "private, no-cache")
+	if len(transactions) > 0 {
+		latest := transactions[0]
+		etag := historyETag(latest)
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Last-Modified", latest.CreatedAt.UTC().Format(http.TimeFormat))
+		if notModified(r, etag, latest.CreatedAt) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+
+	response.OK(w, transactions)
+}
+
+// listTransactionHistory serves one page of the history. Only the first page newest
+// first starts with the latest transaction, so only it carries the validators.
+func (h *WalletHandler) listTransactionHistory(w http.ResponseWriter, r *http.Request, walletID uuid.UUID) {
+	query := r.URL.Query()
+	page, err := pagination.Parse(query)
+	if err != nil {
+		response.Error(w, paginationAppError(err))
+		return
+	}
+	sort, err := pagination.ParseSort(query.Get("sort"), "-created_at", "created_at")
+	if err != nil {
+		response.Error(w, paginationAppError(err))
+		return
+	}
+	if page.Offset > 0 {
+		response.Error(w, errors.InvalidInput("transaction history is paged by cursor, not offset").WithDetails("offset", query.Get("offset")))
+		return
+	}
+	filter := repository.TransactionFilter{Category: query.Get("category"), Sort: sort, Limit: page.Limit}
+	if page.Cursor != "" {
+		filter.After = &repository.Position{}
+		if err := pagination.DecodeCursor(page.Cursor, sort, filter.After); err != nil {
+			response.Error(w, paginationAppError(err))
+			return
+		}
+	}
+
+	transactions, err := h.WalletService.ListTransactionHistory(r.Context(), walletID, filter)
+	if err != nil {
+		response.Error(w, walletAppError(err, walletID.String()))
+		return
+	}
+
+	w.Header().Set("Cache-Control", "private, no-cache")
+	if page.Full(len(transactions)) {
+		last := transactions[len(transactions)-1]
+		next := pagination.EncodeCursor(sort, repository.Position{ID: last.ID, CreatedAt: last.CreatedAt})
+		w.Header().Set("Link", "<"+pagination.NextURL(r.URL, next)+`>; rel="next"`)
+	}
+	if filter.After == nil && sort.Descending && len(transactions) > 0 {
+		latest := transactions[0]
+		etag := historyETag(latest)
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Last-Modified", latest.CreatedAt.UTC().Format(http.TimeFormat))
+		if notModified(r, etag, latest.CreatedAt) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+
+	response.OK(w, transactions)
+}
+
+// GetTransaction returns one transaction from a wallet's history
+// @Summary Get a transaction
+// @Description Looks a transaction up by the id it has in its wallet's history. When auth
+// @Description is enabled only the wallet's owner can see it.
+// @Tags wallets
+// @Produce json
+// @Param id path string true "Transaction ID"
+// @Success 200 {object} models.Transaction
+// @Failure 400 {object} response.Problem "Invalid transaction ID"
+// @Failure 404 {object} response.Problem "Transaction not found"
+// @Failure 500 {object} response.Problem "Internal server error"
+// @Router /api/v1/transactions/{id} [get]
+func (h *WalletHandler) GetTransaction(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	transactionIDStr := chi.URLParam(r, "id")
+	transactionID, err := uuid.Parse(transactionIDStr)
+	if err != nil {
+		response.ErrorMessage(w, http.StatusBadRequest, "Invalid transaction ID")
+		return
+	}
+
+	notFound := errors.New(errors.ErrTransactionNotFound, "Transaction not found", http.StatusNotFound).
+		WithDetails("transaction_id", transactionIDStr)
+
+	transaction, err := h.WalletService.GetTransaction(ctx, transactionID)
+	if err != nil {
+		if stderrors.Is(err, service.ErrTransactionNotFound) {
+			response.Error(w, notFound)
+			return
+		}
+		logger.FromContext(ctx).Error("Failed to get transaction", zap.Error(err))
+		response.Error(w, errors.InternalError(err))
+		return
+	}
+
+	// Someone else's transaction is reported as missing rather than forbidden, so
+	// transaction IDs cannot be probed
+	if userID, ok := auth.UserIDFromContext(ctx); ok {
+		wallet, err := h.WalletService.GetBalance(ctx, transaction.WalletID)
+		if err != nil && !stderrors.Is(err, service.ErrWalletNotFound) {
+			logger.FromContext(ctx).Error("Failed to check transaction access", zap.Error(err))
+			response.Error(w, errors.InternalError(err))
+			return
+		}
+		if wallet == nil || wallet.UserID != userID {
+			response.Error(w, notFound)
+			return
+		}
+	}
+
+	response.OK(w, transaction)
 }
@@ -0,0 +1,268 @@
+package handlers
+
+import (
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+
+	"github.com/shanwije/wallet-app/internal/realtime"
+	"github.com/shanwije/wallet-app/pkg/auth"
+	"github.com/shanwije/wallet-app/pkg/logger"
+	"github.com/shanwije/wallet-app/pkg/response"
+)
+
+const (
+	// wsWriteWait bounds each write to a client
+	wsWriteWait = 10 * time.Second
+	// wsPongWait is how long a client may stay silent, pongs included, before it is
+	// disconnected; pings go out often enough for a live client to answer in time
+	wsPongWait   = 60 * time.Second
+	wsPingPeriod = wsPongWait * 9 / 10
+	// wsMaxMessageBytes bounds what a client may send in one message
+	wsMaxMessageBytes = 4096
+	// wsMaxSubscriptions bounds the wallets one connection may watch
+	wsMaxSubscriptions = 100
+)
+
+// Message types a client sends and is answered with, besides the balance messages
+// the hub pushes
+const (
+	wsSubscribe    = "subscribe"
+	wsUnsubscribe  = "unsubscribe"
+	wsSubscribed   = "subscribed"
+	wsUnsubscribed = "unsubscribed"
+	wsError        = "error"
+)
+
+// wsUpgrader accepts connections from any origin, like the CORS policy of the rest
+// of the API: clients authenticate with a bearer token rather than a cookie, so
+// another site cannot connect with a user's credentials
+var wsUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	CheckOrigin:     func(*http.Request) bool { return true },
+}
+
+// WebSocketHandler streams balance updates to clients over WebSocket
+type WebSocketHandler struct {
+	Hub           *realtime.Hub
+	WalletService WalletServiceInterface
+}
+
+// NewWebSocketHandler creates a new WebSocketHandler
+func NewWebSocketHandler(hub *realtime.Hub, walletService WalletServiceInterface) *WebSocketHandler {
+	return &WebSocketHandler{
+		Hub:           hub,
+		WalletService: walletService,
+	}
+}
+
+// wsRequest is a message from the client
+type wsRequest struct {
+	Type      string   `json:"type"`
+	WalletIDs []string `json:"wallet_ids"`
+}
+
+// wsReply answers a wsRequest
+type wsReply struct {
+	Type      string      `json:"type"`
+	WalletIDs []uuid.UUID `json:"wallet_ids,omitempty"`
+	Error     string      `json:"error,omitempty"`
+	WalletID  string      `json:"wallet_id,omitempty"`
+}
+
+// wsConn serialises writes to a connection, which the client's reader and writer
+// both make
+type wsConn struct {
+	conn *websocket.Conn
+	mu   sync.Mutex
+}
+
+func (c *wsConn) write(messageType int, data []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+	return c.conn.WriteMessage(messageType, data)
+}
+
+func (c *wsConn) writeJSON(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.write(websocket.TextMessage, data)
+}
+
+// Serve upgrades the request to a WebSocket that pushes balance updates
+// @Summary Stream balance updates
+// @Description Upgrades to a WebSocket. Send {"type":"subscribe","wallet_ids":[...]} to watch your wallets, or "unsubscribe" to stop; each deposit, withdrawal and incoming transfer is then pushed as a "balance" message with the wallet's new balance. A client too slow to keep up is disconnected and should reconnect and read its balances again.
+// @Tags wallets
+// @Success 101 {object} realtime.BalanceMessage
+// @Failure 503 {object} response.Problem "Server is shutting down"
+// @Router /ws [get]
+func (h *WebSocketHandler) Serve(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+
+	client := h.Hub.Register()
+	if client == nil {
+		response.ErrorMessage(w, http.StatusServiceUnavailable, "Server is shutting down")
+		return
+	}
+	defer client.Close()
+
+	// On failure Upgrade has already answered the request
+	ws, err := wsUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Warn("WebSocket upgrade failed", zap.Error(err))
+		return
+	}
+	conn := &wsConn{conn: ws}
+	defer ws.Close()
+
+	writerDone := make(chan struct{})
+	go func() {
+		defer close(writerDone)
+		h.writeMessages(conn, client)
+	}()
+
+	h.readRequests(r, conn, client)
+	client.Close()
+	<-writerDone
+}
+
+// writeMessages sends the client's queued messages and keepalive pings until the
+// client is closed or a write fails, then closes the connection
+func (h *WebSocketHandler) writeMessages(conn *wsConn, client *realtime.Client) {
+	ticker := time.NewTicker(wsPingPeriod)
+	defer ticker.Stop()
+	defer conn.conn.Close()
+
+	for {
+		select {
+		case message := <-client.Send():
+			if err := conn.write(websocket.TextMessage, message); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if err := conn.write(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		case <-client.Done():
+			code, reason := websocket.CloseNormalClosure, ""
+			switch {
+			case stderrors.Is(client.Err(), realtime.ErrSlowClient):
+				code, reason = websocket.CloseTryAgainLater, "too slow to keep up with updates"
+			case stderrors.Is(client.Err(), realtime.ErrHubClosed):
+				code, reason = websocket.CloseGoingAway, "server is shutting down"
+			}
+			conn.write(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason))
+			return
+		}
+	}
+}
+
+// readRequests handles the client's subscribe and unsubscribe requests until the
+// connection fails or the client goes quiet for longer than wsPongWait
+func (h *WebSocketHandler) readRequests(r *http.Request, conn *wsConn, client *realtime.Client) {
+	log := logger.FromContext(r.Context())
+
+	ws := conn.conn
+	ws.SetReadLimit(wsMaxMessageBytes)
+	ws.SetReadDeadline(time.Now().Add(wsPongWait))
+	ws.SetPongHandler(func(string) error {
+		return ws.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+
+	subscribed := make(map[uuid.UUID]struct{})
+	for {
+		_, data, err := ws.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				log.Info("WebSocket closed", zap.Error(err))
+			}
+			return
+		}
+		ws.SetReadDeadline(time.Now().Add(wsPongWait))
+
+		var req wsRequest
+		if err := json.Unmarshal(data, &req); err != nil {
+			conn.writeJSON(wsReply{Type: wsError, Error: "Invalid JSON message"})
+			continue
+		}
+
+		walletIDs, reply := h.parseWalletIDs(r, req)
+		if reply != nil {
+			conn.writeJSON(reply)
+			continue
+		}
+
+		switch req.Type {
+		case wsSubscribe:
+			added := 0
+			for _, id := range walletIDs {
+				if _, ok := subscribed[id]; !ok {
+					added++
+				}
+			}
+			if len(subscribed)+added > wsMaxSubscriptions {
+				conn.writeJSON(wsReply{Type: wsError, Error: fmt.Sprintf("At most %d wallets can be watched at once", wsMaxSubscriptions)})
+				continue
+			}
+			for _, id := range walletIDs {
+				subscribed[id] = struct{}{}
+			}
+			client.Subscribe(walletIDs...)
+			conn.writeJSON(wsReply{Type: wsSubscribed, WalletIDs: walletIDs})
+		case wsUnsubscribe:
+			for _, id := range walletIDs {
+				delete(subscribed, id)
+			}
+			client.Unsubscribe(walletIDs...)
+			conn.writeJSON(wsReply{Type: wsUnsubscribed, WalletIDs: walletIDs})
+		}
+	}
+}
+
+// parseWalletIDs validates a request and the wallets it names. Subscribing to a wallet
+// takes owning it when the connection is authenticated.
+func (h *WebSocketHandler) parseWalletIDs(r *http.Request, req wsRequest) ([]uuid.UUID, *wsReply) {
+	if req.Type != wsSubscribe && req.Type != wsUnsubscribe {
+		return nil, &wsReply{Type: wsError, Error: fmt.Sprintf("Unknown message type %q", req.Type)}
+	}
+	if len(req.WalletIDs) == 0 {
+		return nil, &wsReply{Type: wsError, Error: "wallet_ids is required"}
+	}
+
+	userID, authenticated := auth.UserIDFromContext(r.Context())
+	walletIDs := make([]uuid.UUID, 0, len(req.WalletIDs))
+	for _, idStr := range req.WalletIDs {
+		id, err := uuid.Parse(idStr)
+		if err != nil {
+			return nil, &wsReply{Type: wsError, Error: "Invalid wallet ID", WalletID: idStr}
+		}
+		walletIDs = append(walletIDs, id)
+		if req.Type != wsSubscribe {
+			continue
+		}
+
+		wallet, err := h.WalletService.GetBalance(r.Context(), id)
+		if err != nil {
+			return nil, &wsReply{Type: wsError, Error: walletAppError(err, idStr).Message, WalletID: idStr}
+		}
+		if authenticated && wallet.UserID != userID {
+			logger.FromContext(r.Context()).Warn("Wallet access denied",
+				zap.String("wallet_id", idStr),
+				zap.String("user_id", userID.String()))
+			return nil, &wsReply{Type: wsError, Error: "You do not have access to this wallet", WalletID: idStr}
+		}
+	}
+	return walletIDs, nil
+}
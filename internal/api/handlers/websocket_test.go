@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/jmoiron/sqlx"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shanwije/wallet-app/db/migrations"
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/realtime"
+	"github.com/shanwije/wallet-app/internal/repository/sqlite"
+	"github.com/shanwije/wallet-app/internal/service"
+	"github.com/shanwije/wallet-app/pkg/auth"
+	"github.com/shanwije/wallet-app/pkg/db"
+	"github.com/shanwije/wallet-app/pkg/money"
+)
+
+// newWalletService returns a service on a migrated SQLite database of its own
+func newWalletService(t *testing.T) *service.WalletService {
+	t.Helper()
+
+	return walletServiceOn(newTestDB(t))
+}
+
+// newTestDB returns a migrated SQLite database of the test's own
+func newTestDB(t *testing.T) *sqlx.DB {
+	t.Helper()
+
+	conn, err := db.New(db.Config{Driver: db.DriverSQLite, Name: filepath.Join(t.TempDir(), "wallet.db")})
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	fsys, err := migrations.ForDriver(db.DriverSQLite)
+	require.NoError(t, err)
+	migrator, err := db.NewMigrator(conn.DB, db.DriverSQLite, fsys)
+	require.NoError(t, err)
+	t.Cleanup(func() { migrator.Close() })
+	_, err = migrator.Up(context.Background())
+	require.NoError(t, err)
+
+	return conn.DB
+}
+
+// walletServiceOn returns a wallet service on the SQLite database
+func walletServiceOn(conn *sqlx.DB) *service.WalletService {
+	return &service.WalletService{
+		WalletRepo: sqlite.NewWalletRepository(conn),
+		LedgerRepo: sqlite.NewLedgerRepository(conn),
+		AlertRepo:  sqlite.NewWalletAlertRepository(conn),
+		UserRepo:   sqlite.NewUserRepository(conn),
+	}
+}
+
+func createUserWallet(t *testing.T, wallets *service.WalletService) *models.Wallet {
+	t.Helper()
+
+	ctx := context.Background()
+	user, err := wallets.UserRepo.CreateUser(ctx, "Test User", models.Contact{})
+	require.NoError(t, err)
+	wallet, err := wallets.WalletRepo.CreateWallet(ctx, user.ID)
+	require.NoError(t, err)
+	return wallet
+}
+
+// dialAs connects to the handler as the given user
+func dialAs(t *testing.T, handler *WebSocketHandler, userID uuid.UUID) *websocket.Conn {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler.Serve(w, r.WithContext(auth.WithUserID(r.Context(), userID)))
+	}))
+	t.Cleanup(server.Close)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	return conn
+}
+
+func TestWebSocketPushesDepositsToTheOwner(t *testing.T) {
+	wallets := newWalletService(t)
+	hub := realtime.NewHub(0)
+	wallets.Notifier = hub
+	mine, theirs := createUserWallet(t, wallets), createUserWallet(t, wallets)
+	conn := dialAs(t, NewWebSocketHandler(hub, wallets), mine.UserID)
+
+	var reply map[string]any
+	require.NoError(t, conn.WriteJSON(wsRequest{Type: wsSubscribe, WalletIDs: []string{theirs.ID.String()}}))
+	require.NoError(t, conn.ReadJSON(&reply))
+	assert.Equal(t, wsError, reply["type"])
+	assert.Equal(t, theirs.ID.String(), reply["wallet_id"])
+
+	require.NoError(t, conn.WriteJSON(wsRequest{Type: wsSubscribe, WalletIDs: []string{mine.ID.String()}}))
+	require.NoError(t, conn.ReadJSON(&reply))
+	assert.Equal(t, wsSubscribed, reply["type"])
+
+	// Deposits into the other wallet are not pushed to this client
+	ctx := context.Background()
+	_, err := wallets.Deposit(ctx, theirs.ID, money.New(decimal.NewFromInt(5), money.DefaultCurrency), "")
+	require.NoError(t, err)
+	_, err = wallets.Deposit(ctx, mine.ID, money.New(decimal.RequireFromString("12.50"), money.DefaultCurrency), "")
+	require.NoError(t, err)
+
+	var update realtime.BalanceMessage
+	require.NoError(t, conn.ReadJSON(&update))
+	assert.Equal(t, realtime.MessageTypeBalance, update.Type)
+	assert.Equal(t, mine.ID, update.WalletID)
+	assert.Equal(t, models.EventWalletDeposit, update.Event)
+	assert.Equal(t, "12.5", update.Amount.String())
+	assert.Equal(t, "12.5", update.Balance.String())
+}
+
+func TestWebSocketClosesWhenTheHubCloses(t *testing.T) {
+	wallets := newWalletService(t)
+	hub := realtime.NewHub(0)
+	conn := dialAs(t, NewWebSocketHandler(hub, wallets), uuid.New())
+
+	// A reply shows the connection is registered before the hub closes
+	var reply map[string]any
+	require.NoError(t, conn.WriteJSON(wsRequest{Type: "ping"}))
+	require.NoError(t, conn.ReadJSON(&reply))
+	assert.Equal(t, wsError, reply["type"])
+
+	require.NoError(t, hub.Close())
+	_, _, err := conn.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, websocket.CloseGoingAway), "unexpected error %v", err)
+}
@@ -1,40 +1,46 @@
 package api
 
 import (
-	"fmt"
+	"expvar"
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
-	"github.com/jmoiron/sqlx"
 	httpSwagger "github.com/swaggo/http-swagger"
 	"go.uber.org/zap"
 
 	"github.com/shanwije/wallet-app/internal/api/handlers"
 	"github.com/shanwije/wallet-app/internal/config"
+	"github.com/shanwije/wallet-app/internal/graphqlapi"
 	custommiddleware "github.com/shanwije/wallet-app/internal/middleware"
-	"github.com/shanwije/wallet-app/internal/repository/postgres"
-	"github.com/shanwije/wallet-app/internal/service"
+	"github.com/shanwije/wallet-app/pkg/health"
+	"github.com/shanwije/wallet-app/pkg/response"
 )
 
 // Router sets up the HTTP router with all routes
-func NewRouter(cfg *config.Config, db *sqlx.DB, logger *zap.Logger) *chi.Mux {
+func NewRouter(cfg *config.Config, services *Services, logger *zap.Logger) *chi.Mux {
 	r := chi.NewRouter()
 
 	// Middleware
-	r.Use(custommiddleware.RequestIDMiddleware())
-	r.Use(custommiddleware.LoggingMiddleware())
+	r.Use(custommiddleware.RequestIDMiddleware(logger))
+	r.Use(custommiddleware.LoggingMiddleware(custommiddleware.LoggingOptions{
+		SampleRate:   cfg.LogSampleRate,
+		LogBodies:    cfg.LogBodies,
+		RedactFields: cfg.LogRedactFields,
+	}))
 	r.Use(middleware.Recoverer)
 	r.Use(middleware.RealIP)
 	r.Use(middleware.Compress(5))
-	r.Use(custommiddleware.IdempotencyMiddleware)
+	r.Use(custommiddleware.BodyLimitMiddleware(cfg.MaxBodyBytes))
+	r.Use(custommiddleware.NewPersistentIdempotencyCache(services.repos.idempotencyKeys, services.clock).Middleware)
 
 	// CORS middleware
 	r.Use(func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			w.Header().Set("Access-Control-Allow-Origin", "*")
-			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-			w.Header().Set("Access-Control-Allow-Headers", "Accept, Authorization, Content-Type, X-CSRF-Token")
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Accept, Authorization, Content-Type, X-CSRF-Token, X-Admin-Key, If-Match, If-None-Match, If-Modified-Since, X-Transaction-PIN")
+			w.Header().Set("Access-Control-Expose-Headers", "ETag, Last-Modified")
 
 			if r.Method == "OPTIONS" {
 				w.WriteHeader(http.StatusOK)
@@ -45,38 +51,275 @@ func NewRouter(cfg *config.Config, db *sqlx.DB, logger *zap.Logger) *chi.Mux {
 		})
 	})
 
-	// Create repositories
-	userRepo := postgres.NewUserRepository(db)
-	walletRepo := postgres.NewWalletRepository(db)
-	transactionRepo := postgres.NewTransactionRepository(db)
+	// Create handlers
+	userHandler := &handlers.UserHandler{UserService: services.Users}
+	walletHandler := &handlers.WalletHandler{WalletService: services.Wallets, PendingTransfers: services.PendingTransfers, PINs: services.TransactionPINs, DepositRefunds: services.DepositRefunds}
+	healthHandler := newHealthHandler(cfg, services, logger)
+	authHandler := handlers.NewAuthHandler(services.Users, services.Tokens)
+	adminHandler := handlers.NewAdminHandler(services.Users, services.Wallets, services.Audit, services.Reconciliation, services.FeatureFlags)
+	adminHandler.LogLevel = services.LogLevel
+	scheduledTransferHandler := handlers.NewScheduledTransferHandler(services.ScheduledTransfers)
+	scheduledTransferHandler.PINs = services.TransactionPINs
+	paymentRequestHandler := handlers.NewPaymentRequestHandler(services.PaymentRequests)
+	paymentRequestHandler.PINs = services.TransactionPINs
+	paymentHandler := handlers.NewPaymentHandler(services.Payments)
+	paymentHandler.PINs = services.TransactionPINs
+	payoutHandler := handlers.NewPayoutHandler(services.Payouts)
+	payoutHandler.PINs = services.TransactionPINs
+	exportHandler := handlers.NewExportHandler(services.ExportJobs, services.Wallets)
+	paymentWebhookHandler := handlers.NewPaymentWebhookHandler(services.ProviderPayments, cfg.PaymentWebhookSecret, cfg.PaymentWebhookTolerance)
+	paymentWebhookHandler.Clock = services.clock
+	disputeHandler := handlers.NewDisputeHandler(services.Disputes)
+	pendingTransferHandler := handlers.NewPendingTransferHandler(services.PendingTransfers)
+	incomingTransferHandler := handlers.NewIncomingTransferHandler(services.IncomingTransfers)
+	notificationHandler := handlers.NewNotificationHandler(services.Notifications)
+	transactionPINHandler := handlers.NewTransactionPINHandler(services.TransactionPINs)
+	kycHandler := handlers.NewKYCHandler(services.KYC)
+	complianceHandler := handlers.NewComplianceHandler(services.Compliance)
+	systemWalletHandler := handlers.NewSystemWalletHandler(services.SystemWallets)
+	webSocketHandler := handlers.NewWebSocketHandler(services.Realtime, services.Wallets)
+	graphqlHandler := graphqlapi.NewHandler(services.Users, services.Wallets, cfg.AuthEnabled)
 
-	// Create services
-	userService := &service.UserService{UserRepo: userRepo, WalletRepo: walletRepo}
-	walletService := &service.WalletService{WalletRepo: walletRepo, TransactionRepo: transactionRepo}
+	// Every version up to the configured one is served. Versions share their routes
+	// except where a later one changed an endpoint's behaviour.
+	for _, version := range cfg.ServedAPIVersions() {
+		// v2 responds to money movements with the transaction or transfer they created
+		deposit, withdraw, transfer := walletHandler.DepositV2, walletHandler.WithdrawV2, walletHandler.TransferV2
+		if version == "v1" {
+			deposit, withdraw, transfer = walletHandler.Deposit, walletHandler.Withdraw, walletHandler.Transfer
+		}
 
-	// Create handlers
-	userHandler := &handlers.UserHandler{UserService: userService}
-	walletHandler := &handlers.WalletHandler{WalletService: walletService}
-	healthHandler := handlers.NewHealthHandler()
-
-	// Routes - using configurable API version
-	apiRoute := fmt.Sprintf("/api/%s", cfg.APIVersion)
-	r.Route(apiRoute, func(r chi.Router) {
-		r.Get("/health", healthHandler.GetHealth)
-		r.Post("/users", userHandler.CreateUser)
-
-		// Wallet operations
-		r.Route("/wallets/{id}", func(r chi.Router) {
-			r.Post("/deposit", walletHandler.Deposit)
-			r.Post("/withdraw", walletHandler.Withdraw)
-			r.Post("/transfer", walletHandler.Transfer)
-			r.Get("/balance", walletHandler.GetBalance)
-			r.Get("/transactions", walletHandler.GetTransactionHistory)
+		r.Route("/api/"+version, func(r chi.Router) {
+			r.Use(custommiddleware.TimeoutMiddleware(cfg.RequestTimeout))
+			r.Get("/health", healthHandler.HealthHandler)
+			r.With(custommiddleware.AuditMiddleware(services.Audit, "")).Post("/users", userHandler.CreateUser)
+			// Searching every user by name is for support staff, behind the admin key
+			if cfg.AdminAPIKey != "" {
+				r.With(custommiddleware.AdminKeyMiddleware(cfg.AdminAPIKey)).Get("/users", adminHandler.SearchUsers)
+			}
+			r.Route("/users/{id}", func(r chi.Router) {
+				if cfg.AuthEnabled {
+					r.Use(custommiddleware.AuthMiddleware(services.Tokens))
+					r.Use(userHandler.RequireSelf)
+				}
+				r.Use(custommiddleware.AuditMiddleware(services.Audit, ""))
+
+				r.Get("/", userHandler.GetUser)
+				r.Patch("/", userHandler.UpdateUser)
+				r.Delete("/", userHandler.DeleteUser)
+				r.Get("/wallet", userHandler.GetUserWallet)
+				r.Get("/notification-preferences", notificationHandler.GetPreferences)
+				r.Put("/notification-preferences", notificationHandler.SetPreferences)
+				r.Get("/transaction-pin", transactionPINHandler.GetStatus)
+				r.Put("/transaction-pin", transactionPINHandler.SetPIN)
+				r.Delete("/transaction-pin", transactionPINHandler.RemovePIN)
+				r.Get("/kyc", kycHandler.GetProfile)
+				r.Post("/kyc/documents", kycHandler.SubmitDocument)
+			})
+			r.With(custommiddleware.AuditMiddleware(services.Audit, "")).Post("/auth/register", authHandler.Register)
+			r.Post("/auth/login", authHandler.Login)
+
+			// Wallet operations - restricted to the wallet owner when auth is enabled
+			r.Route("/wallets/{id}", func(r chi.Router) {
+				if cfg.AuthEnabled {
+					r.Use(custommiddleware.AuthMiddleware(services.Tokens))
+					r.Use(walletHandler.RequireOwnership)
+				}
+				// Every mutation is audited with the wallet's balance before and after
+				r.Use(custommiddleware.AuditMiddleware(services.Audit, "id"))
+
+				// Money movements are rate limited per client
+				r.Group(func(r chi.Router) {
+					if services.limiter != nil {
+						r.Use(custommiddleware.RateLimitMiddleware(services.limiter))
+					}
+
+					r.Post("/deposit", deposit)
+					r.Post("/withdraw", withdraw)
+					r.Post("/transfer", transfer)
+					r.Post("/transfers/batch", walletHandler.BatchTransfer)
+					r.Post("/sweep", walletHandler.Sweep)
+					r.Post("/holds", walletHandler.PlaceHold)
+					r.Post("/holds/{holdID}/capture", walletHandler.CaptureHold)
+					r.Post("/payment-requests/{requestID}/accept", paymentRequestHandler.Accept)
+					r.Post("/pending-transfers/{transferID}/confirm", pendingTransferHandler.Confirm)
+					r.Post("/payouts", payoutHandler.Create)
+				})
+
+				r.Get("/balance", walletHandler.GetBalance)
+				r.Get("/transactions", walletHandler.GetTransactionHistory)
+				r.Get("/transfers", walletHandler.ListTransfers)
+				r.Get("/statement", walletHandler.GetStatement)
+				r.Post("/exports", exportHandler.Create)
+				r.Get("/analytics", walletHandler.GetAnalytics)
+				r.Post("/fees/quote", walletHandler.QuoteFee)
+				r.Post("/transfer/quote", walletHandler.QuoteTransfer)
+				r.Get("/holds", walletHandler.ListHolds)
+				r.Post("/holds/{holdID}/release", walletHandler.ReleaseHold)
+				r.Get("/alerts", walletHandler.ListAlerts)
+				r.Get("/alerts/settings", walletHandler.GetAlertSettings)
+				r.Put("/alerts/settings", walletHandler.SetAlertSettings)
+				r.Get("/settlement", paymentHandler.GetSettlement)
+				r.Get("/payouts", payoutHandler.List)
+				r.Get("/payouts/{payoutID}", payoutHandler.Get)
+
+				r.Post("/scheduled-transfers", scheduledTransferHandler.Create)
+				r.Get("/scheduled-transfers", scheduledTransferHandler.List)
+				r.Delete("/scheduled-transfers/{transferID}", scheduledTransferHandler.Cancel)
+
+				r.Post("/payment-requests", paymentRequestHandler.Create)
+				r.Get("/payment-requests", paymentRequestHandler.ListPending)
+				r.Post("/payment-requests/{requestID}/decline", paymentRequestHandler.Decline)
+
+				r.Get("/pending-transfers", pendingTransferHandler.List)
+				r.Post("/pending-transfers/{transferID}/cancel", pendingTransferHandler.Cancel)
+
+				r.Get("/incoming-transfers", incomingTransferHandler.List)
+				r.Get("/incoming-transfers/settings", incomingTransferHandler.GetSettings)
+				r.Put("/incoming-transfers/settings", incomingTransferHandler.SetSettings)
+				r.Post("/incoming-transfers/{transferID}/accept", incomingTransferHandler.Accept)
+				r.Post("/incoming-transfers/{transferID}/reject", incomingTransferHandler.Reject)
+			})
+
+			// A transfer is visible to the owners of both of its wallets, and a
+			// transaction to the owner of its wallet
+			r.Group(func(r chi.Router) {
+				if cfg.AuthEnabled {
+					r.Use(custommiddleware.AuthMiddleware(services.Tokens))
+				}
+				r.Get("/transfers/{reference_id}", walletHandler.GetTransfer)
+				r.Get("/transactions/{id}", walletHandler.GetTransaction)
+			})
+
+			// An export is visible to the owner of the wallet it exports
+			r.Group(func(r chi.Router) {
+				if cfg.AuthEnabled {
+					r.Use(custommiddleware.AuthMiddleware(services.Tokens))
+				}
+				r.Get("/exports/{id}", exportHandler.Get)
+			})
+
+			// A transaction can be reversed, and a deposit refunded, by the owner of the
+			// wallet it paid into
+			r.Group(func(r chi.Router) {
+				if cfg.AuthEnabled {
+					r.Use(custommiddleware.AuthMiddleware(services.Tokens))
+				}
+				r.Use(custommiddleware.AuditMiddleware(services.Audit, ""))
+				r.Post("/transactions/{id}/reverse", walletHandler.ReverseTransaction)
+				r.Post("/transactions/{id}/refund", walletHandler.RefundTransaction)
+			})
+
+			// A transfer can be disputed by its sender, who follows the dispute on the transaction
+			r.Group(func(r chi.Router) {
+				if cfg.AuthEnabled {
+					r.Use(custommiddleware.AuthMiddleware(services.Tokens))
+				}
+				r.Use(custommiddleware.AuditMiddleware(services.Audit, ""))
+				r.Post("/transactions/{id}/disputes", disputeHandler.OpenDispute)
+				r.Get("/transactions/{id}/disputes", disputeHandler.ListTransactionDisputes)
+			})
+
+			// Payments to merchants, seen by the payer and the merchant and refunded by the merchant
+			r.Route("/payments", func(r chi.Router) {
+				if cfg.AuthEnabled {
+					r.Use(custommiddleware.AuthMiddleware(services.Tokens))
+				}
+				r.Use(custommiddleware.AuditMiddleware(services.Audit, ""))
+				r.Group(func(r chi.Router) {
+					if services.limiter != nil {
+						r.Use(custommiddleware.RateLimitMiddleware(services.limiter))
+					}
+					r.Post("/", paymentHandler.Pay)
+					r.Post("/{id}/refunds", paymentHandler.Refund)
+				})
+				r.Get("/{id}", paymentHandler.GetPayment)
+			})
+
+			// The payment provider's webhook, authenticated by its signature - only mounted
+			// when the webhook secret is configured
+			if cfg.PaymentWebhookSecret != "" {
+				r.With(custommiddleware.AuditMiddleware(services.Audit, "")).Post("/webhooks/payments", paymentWebhookHandler.Receive)
+			}
+
+			// Read-only GraphQL view of users, wallets and history
+			r.Group(func(r chi.Router) {
+				if cfg.AuthEnabled {
+					r.Use(custommiddleware.AuthMiddleware(services.Tokens))
+				}
+				r.Method(http.MethodPost, "/graphql", graphqlHandler)
+			})
+
+			// Operator endpoints - only mounted when an admin key is configured
+			if cfg.AdminAPIKey != "" {
+				r.Route("/admin", func(r chi.Router) {
+					r.Use(custommiddleware.AdminKeyMiddleware(cfg.AdminAPIKey))
+					r.Get("/users", adminHandler.ListUsers)
+					r.Get("/wallets", adminHandler.SearchWallets)
+					r.Get("/wallets/{id}", adminHandler.GetWallet)
+					r.Get("/wallets/{id}/limits", adminHandler.GetWalletLimits)
+					r.Get("/system-wallets", systemWalletHandler.ListWallets)
+					r.With(custommiddleware.AuditMiddleware(services.Audit, "")).Post("/system-wallets", systemWalletHandler.EnsureWallet)
+					r.Get("/risk-decisions", adminHandler.ListRiskDecisions)
+					r.Get("/aml-reports", complianceHandler.ListReports)
+					r.Get("/aml-reports/export", complianceHandler.ExportReports)
+					r.Get("/payouts", payoutHandler.ListAll)
+					r.Get("/audit-log", adminHandler.ListAuditEntries)
+					r.Get("/reconciliation/runs", adminHandler.ListReconciliationRuns)
+					r.Get("/reconciliation/discrepancies", adminHandler.ListDiscrepancies)
+					r.With(custommiddleware.AuditMiddleware(services.Audit, "")).Post("/reconciliation/runs", adminHandler.RunReconciliation)
+					r.Get("/feature-flags", adminHandler.ListFeatureFlags)
+					r.With(custommiddleware.AuditMiddleware(services.Audit, "")).Put("/feature-flags/{name}", adminHandler.SetFeatureFlag)
+					r.With(custommiddleware.AuditMiddleware(services.Audit, "")).Delete("/feature-flags/{name}", adminHandler.ResetFeatureFlag)
+					if services.LogLevel != nil {
+						r.Get("/log-level", adminHandler.GetLogLevel)
+						r.With(custommiddleware.AuditMiddleware(services.Audit, "")).Put("/log-level", adminHandler.SetLogLevel)
+						r.With(custommiddleware.AuditMiddleware(services.Audit, "")).Delete("/log-level", adminHandler.ResetLogLevel)
+					}
+					// Operators can reverse any transaction, whoever received the money
+					r.With(custommiddleware.AuditMiddleware(services.Audit, "")).Post("/transactions/{id}/reverse", walletHandler.ReverseTransaction)
+					r.With(custommiddleware.AuditMiddleware(services.Audit, "")).Post("/transactions/{id}/refund", walletHandler.RefundTransaction)
+					r.With(custommiddleware.AuditMiddleware(services.Audit, "")).Post("/transactions/{id}/corrections", adminHandler.CorrectTransaction)
+					r.With(custommiddleware.AuditMiddleware(services.Audit, "")).Post("/merchants", paymentHandler.RegisterMerchant)
+					r.Get("/disputes", disputeHandler.ListDisputes)
+					r.Get("/disputes/{id}", disputeHandler.GetDispute)
+					r.With(custommiddleware.AuditMiddleware(services.Audit, "")).Post("/disputes/{id}/resolve", disputeHandler.ResolveDispute)
+					r.Get("/pending-transfers", pendingTransferHandler.ListAll)
+					r.With(custommiddleware.AuditMiddleware(services.Audit, "")).Post("/pending-transfers/{id}/approve", pendingTransferHandler.Approve)
+					r.Get("/users/{id}/kyc", kycHandler.GetProfile)
+					r.With(custommiddleware.AuditMiddleware(services.Audit, "")).Put("/users/{id}/kyc/tier", kycHandler.SetTier)
+					r.Get("/kyc/documents", kycHandler.ListPendingDocuments)
+					r.With(custommiddleware.AuditMiddleware(services.Audit, "")).Post("/kyc/documents/{id}/review", kycHandler.ReviewDocument)
+
+					// Inline so the wallet ID is routed before the audit reads its balance
+					r.Group(func(r chi.Router) {
+						r.Use(custommiddleware.AuditMiddleware(services.Audit, "id"))
+						r.Put("/wallets/{id}/status", adminHandler.SetWalletStatus)
+						r.Post("/wallets/{id}/adjustments", adminHandler.AdjustBalance)
+						r.Put("/wallets/{id}/limits", adminHandler.SetWalletLimits)
+					})
+				})
+			}
 		})
-	})
+	}
 
-	// Health check at root level for simple monitoring
-	r.Get("/health", healthHandler.GetHealth)
+	// Health checks at root level for monitoring and Kubernetes probes
+	r.Get("/health", healthHandler.HealthHandler)
+	r.Get("/ready", healthHandler.ReadinessHandler)
+	r.Get("/live", healthHandler.LivenessHandler)
+	// Balance updates over WebSocket, outside the API group as the connection outlives
+	// the request timeout
+	r.Group(func(r chi.Router) {
+		if cfg.AuthEnabled {
+			r.Use(custommiddleware.AuthMiddleware(services.Tokens))
+		}
+		r.Get("/ws", webSocketHandler.Serve)
+	})
+	// Runtime and connection pool metrics in expvar's JSON format, which expose the
+	// process's command line and internals, so only to operators
+	if cfg.AdminAPIKey != "" {
+		r.With(custommiddleware.AdminKeyMiddleware(cfg.AdminAPIKey)).Handle("/debug/vars", expvar.Handler())
+	}
 
 	// Swagger documentation
 	r.Get("/swagger/*", httpSwagger.Handler(
@@ -85,11 +328,35 @@ func NewRouter(cfg *config.Config, db *sqlx.DB, logger *zap.Logger) *chi.Mux {
 
 	// Root endpoint
 	r.Get("/", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte(`{"message":"Wallet API is running","swagger":"/swagger/index.html"}`))
+		response.OK(w, map[string]string{"message": "Wallet API is running", "swagger": "/swagger/index.html"})
+	})
+
+	// Unknown routes and methods are problems like any other failure
+	r.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		response.ErrorMessage(w, http.StatusNotFound, "No such endpoint")
+	})
+	r.MethodNotAllowed(func(w http.ResponseWriter, r *http.Request) {
+		response.ErrorMessage(w, http.StatusMethodNotAllowed, "Method not allowed on this endpoint")
 	})
 
 	logger.Info("Router configured with Swagger documentation", zap.String("path", "/swagger/index.html"))
 	return r
 }
+
+// newHealthHandler checks the database, its read replica and Redis, whichever are in use.
+// Redis only shares rate limit buckets and the limiter lets requests through without
+// it, so losing Redis degrades the service rather than taking it out of rotation.
+func newHealthHandler(cfg *config.Config, services *Services, logger *zap.Logger) *health.Handler {
+	h := health.NewHandler(cfg.APIVersion, cfg.Environment, logger)
+	h.CacheTTL = cfg.HealthCacheTTL
+	h.Clock = services.clock
+
+	h.AddCriticalChecker("database", &health.DatabaseChecker{DB: services.db.DB.DB, Name: "database"})
+	if services.db.Replica != nil {
+		h.AddCriticalChecker("database_replica", &health.DatabaseChecker{DB: services.db.Replica.DB, Name: "database_replica"})
+	}
+	if services.redis != nil {
+		h.AddChecker("redis", &health.RedisChecker{Client: services.redis, Name: "redis"})
+	}
+	return h
+}
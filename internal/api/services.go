@@ -0,0 +1,480 @@
+package api
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/shopspring/decimal"
+
+	"github.com/shanwije/wallet-app/internal/cache"
+	"github.com/shanwije/wallet-app/internal/config"
+	"github.com/shanwije/wallet-app/internal/events"
+	"github.com/shanwije/wallet-app/internal/export"
+	"github.com/shanwije/wallet-app/internal/fees"
+	"github.com/shanwije/wallet-app/internal/fx"
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/notify"
+	"github.com/shanwije/wallet-app/internal/payout"
+	"github.com/shanwije/wallet-app/internal/realtime"
+	"github.com/shanwije/wallet-app/internal/repository"
+	"github.com/shanwije/wallet-app/internal/repository/mysql"
+	"github.com/shanwije/wallet-app/internal/repository/postgres"
+	"github.com/shanwije/wallet-app/internal/repository/sqlite"
+	"github.com/shanwije/wallet-app/internal/risk"
+	"github.com/shanwije/wallet-app/internal/service"
+	"github.com/shanwije/wallet-app/pkg/auth"
+	"github.com/shanwije/wallet-app/pkg/awssig"
+	"github.com/shanwije/wallet-app/pkg/clock"
+	dbpkg "github.com/shanwije/wallet-app/pkg/db"
+	"github.com/shanwije/wallet-app/pkg/featureflag"
+	"github.com/shanwije/wallet-app/pkg/logger"
+	"github.com/shanwije/wallet-app/pkg/ratelimit"
+)
+
+// Services holds the business services shared by the HTTP and gRPC APIs
+type Services struct {
+	Users              *service.UserService
+	Wallets            *service.WalletService
+	ScheduledTransfers *service.ScheduledTransferService
+	BalanceSnapshots   *service.BalanceSnapshotService
+	Reconciliation     *service.ReconciliationService
+	PaymentRequests    *service.PaymentRequestService
+	Payments           *service.PaymentService
+	Payouts            *service.PayoutService
+	ProviderPayments   *service.ProviderPaymentService
+	DepositRefunds     *service.DepositRefundService
+	Disputes           *service.DisputeService
+	PendingTransfers   *service.PendingTransferService
+	TransactionPINs    *service.TransactionPINService
+	KYC                *service.KYCService
+	Compliance         *service.ComplianceService
+	SystemWallets      *service.SystemWalletService
+	IncomingTransfers  *service.IncomingTransferService
+	Audit              *service.AuditService
+	Notifications      *service.NotificationService
+	FeatureFlags       *featureflag.Flags
+	Tokens             *auth.TokenManager
+	// Events publishes the outbox and is nil when no publisher is configured
+	Events *events.Dispatcher
+	// Realtime pushes balance updates to WebSocket clients
+	Realtime *realtime.Hub
+	// BalanceRelay shares balance updates between instances and is nil without Redis
+	BalanceRelay *realtime.RedisRelay
+	// Export ships the audit log and the ledger to S3 and is nil unless a bucket is configured
+	Export *export.Exporter
+	// ExportJobs produces the exports users queue of their wallets' transactions
+	ExportJobs *service.ExportJobService
+	// LogLevel lets operators change the log level at runtime. It is nil unless set
+	// to the level of the logger passed to the router.
+	LogLevel *logger.Level
+
+	clock   clock.Clock
+	db      *dbpkg.DB
+	redis   *redis.Client
+	repos   repositories
+	limiter ratelimit.Limiter
+}
+
+// NewServices wires the repositories for the configured database driver into the services.
+// redisClient is optional and shares rate limits, cached balances and balance updates
+// between instances when set. publisher is optional too; without it no wallet events
+// are written to the outbox. mailer is optional too; with it users are emailed alerts
+// about their transactions, read from the outbox after publisher has them. rates is
+// optional as well; without it transfers between currencies are rejected, as are payouts
+// to bank accounts without payouts, the provider sending them. feeSchedule
+// prices the fees paid into the wallet cfg.FeeWalletID; nil charges none. flagDefaults
+// are the feature flags for this environment, before any runtime overrides.
+func NewServices(cfg *config.Config, db *dbpkg.DB, redisClient *redis.Client, publisher events.Publisher, mailer notify.Provider, rates fx.ExchangeRateProvider, payouts payout.Provider, feeSchedule *fees.Schedule, flagDefaults map[string]bool) *Services {
+	clk := clock.New()
+	repos := newRepositories(cfg.DBDriver, db)
+	flags := featureflag.New(flagDefaults, newFeatureFlagStore(cfg, repos, redisClient), cfg.FeatureFlagsCacheTTL, clk)
+
+	notifications := &service.NotificationService{
+		Repo:       repos.notificationPreferences,
+		UserRepo:   repos.users,
+		WalletRepo: repos.wallets,
+		Provider:   mailer,
+		Defaults: models.NotificationPreferences{
+			LargeWithdrawalThreshold: cfg.NotifyLargeWithdrawal,
+			IncomingTransfer:         cfg.NotifyIncomingTransfers,
+		},
+		Clock: clk,
+	}
+	if mailer != nil {
+		if publisher != nil {
+			publisher = events.Fanout{publisher, notifications}
+		} else {
+			publisher = notifications
+		}
+	}
+
+	var outbox repository.OutboxRepository
+	var dispatcher *events.Dispatcher
+	if publisher != nil {
+		outbox = repos.outbox
+		dispatcher = &events.Dispatcher{Repo: repos.outbox, Publisher: publisher, Clock: clk}
+	}
+
+	// FeeWalletID was checked to be a UUID when the configuration was loaded
+	feeWalletID, _ := uuid.Parse(cfg.FeeWalletID)
+	tierLimits := map[string]models.KYCTierLimits{
+		models.KYCTierUnverified: {
+			DailyWithdrawalLimit: cfg.KYCUnverifiedDailyWithdrawalLimit,
+			DailyTransferLimit:   cfg.KYCUnverifiedDailyTransferLimit,
+		},
+		models.KYCTierBasic: {
+			DailyWithdrawalLimit: cfg.KYCBasicDailyWithdrawalLimit,
+			DailyTransferLimit:   cfg.KYCBasicDailyTransferLimit,
+		},
+	}
+	systemWallets := &service.SystemWalletService{Repo: repos.systemWallets}
+	wallets := &service.WalletService{
+		WalletRepo:     repos.wallets,
+		Tx:             repository.NewTxManager(repos.wallets),
+		LedgerRepo:     repos.ledger,
+		HoldRepo:       repos.holds,
+		LimitsRepo:     repos.limits,
+		KYCRepo:        repos.kyc,
+		TierLimits:     tierLimits,
+		AlertRepo:      repos.alerts,
+		Risk:           newRiskEngine(cfg, repos.risk),
+		RiskRepo:       repos.risk,
+		AMLRepo:        repos.aml,
+		AML:            newAMLRules(cfg),
+		Outbox:         outbox,
+		FX:             rates,
+		QuoteRepo:      repos.transferQuotes,
+		QuoteTTL:       cfg.TransferQuoteTTL,
+		Fees:           feeSchedule,
+		FeeWalletID:    feeWalletID,
+		SystemWallets:  systemWallets,
+		Snapshots:      repos.snapshots,
+		UserRepo:       repos.users,
+		CredentialRepo: repos.credentials,
+		Flags:          flags,
+		Clock:          clk,
+
+		IncomingTransfers: repos.incomingTransfers,
+		AcceptanceWindow:  cfg.IncomingTransferAcceptanceWindow,
+		DepositRefunds:    repos.depositRefunds,
+		Payments:          repos.payments,
+		Payouts:           repos.payouts,
+		OptimisticLocking: cfg.WalletLocking == "optimistic",
+		TxTimeout:         cfg.TxTimeout,
+		TxRetries:         cfg.TxMaxRetries,
+		MinAmount:         cfg.MinTransactionAmount,
+		MaxAmount:         cfg.MaxTransactionAmount,
+
+		ConfirmationThreshold: cfg.TransferConfirmationThreshold,
+	}
+	if redisClient != nil && cfg.BalanceCacheTTL > 0 {
+		wallets.Cache = cache.NewWallets(redisClient, cfg.BalanceCacheTTL)
+	}
+
+	// Balance updates reach every instance's WebSocket clients through Redis when it is
+	// configured, and only this instance's otherwise
+	hub := realtime.NewHub(realtime.DefaultSendBuffer)
+	var relay *realtime.RedisRelay
+	wallets.Notifier = hub
+	if redisClient != nil {
+		relay = realtime.NewRedisRelay(redisClient, hub)
+		wallets.Notifier = relay
+	}
+
+	pendingTransfers := &service.PendingTransferService{
+		Repo:               repos.pendingTransfers,
+		Wallets:            wallets,
+		Threshold:          cfg.TransferConfirmationThreshold,
+		ConfirmationWindow: cfg.TransferConfirmationWindow,
+		Clock:              clk,
+	}
+
+	transactionPINs := &service.TransactionPINService{
+		Repo:        repos.transactionPINs,
+		UserRepo:    repos.users,
+		WalletRepo:  repos.wallets,
+		Threshold:   cfg.PINThreshold,
+		MaxAttempts: cfg.PINMaxAttempts,
+		Lockout:     cfg.PINLockout,
+		Clock:       clk,
+	}
+
+	exportStore := newExportStore(cfg)
+	return &Services{
+		Users:              &service.UserService{UserRepo: repos.users, WalletRepo: repos.wallets, CredentialRepo: repos.credentials, Wallets: wallets},
+		Wallets:            wallets,
+		ScheduledTransfers: &service.ScheduledTransferService{Repo: repos.scheduledTransfers, Wallets: wallets, Clock: clk},
+		BalanceSnapshots:   &service.BalanceSnapshotService{Repo: repos.snapshots, Clock: clk},
+		Reconciliation:     &service.ReconciliationService{Repo: repos.reconciliation, Clock: clk},
+		PaymentRequests:    &service.PaymentRequestService{Repo: repos.paymentRequests, Wallets: wallets, Clock: clk},
+		Payments:           &service.PaymentService{Repo: repos.payments, Wallets: wallets, Clock: clk},
+		Payouts:            &service.PayoutService{Repo: repos.payouts, Wallets: wallets, Provider: payouts, Clock: clk},
+		ProviderPayments:   &service.ProviderPaymentService{Repo: repos.providerPayments, Wallets: wallets, Clock: clk},
+		DepositRefunds:     &service.DepositRefundService{Repo: repos.depositRefunds, ProviderPayments: repos.providerPayments, Wallets: wallets},
+		Disputes:           &service.DisputeService{Repo: repos.disputes, Wallets: wallets, Clock: clk},
+		PendingTransfers:   pendingTransfers,
+		TransactionPINs:    transactionPINs,
+		KYC:                &service.KYCService{Repo: repos.kyc, UserRepo: repos.users, TierLimits: tierLimits, Clock: clk},
+		Compliance:         &service.ComplianceService{Repo: repos.aml, Clock: clk},
+		SystemWallets:      systemWallets,
+		IncomingTransfers:  &service.IncomingTransferService{Repo: repos.incomingTransfers, Wallets: wallets, Clock: clk},
+		Audit:              &service.AuditService{Repo: repos.audit, WalletRepo: repos.wallets, Clock: clk},
+		Notifications:      notifications,
+		FeatureFlags:       flags,
+		Tokens:             auth.NewTokenManager(cfg.JWTSecret, cfg.JWTTTL, clk),
+		Events:             dispatcher,
+		Realtime:           hub,
+		BalanceRelay:       relay,
+		Export:             newExporter(cfg, repos, exportStore, clk),
+		ExportJobs:         &service.ExportJobService{Repo: repos.exportJobs, Wallets: wallets, Store: exportStore, Prefix: cfg.ExportPrefix, LinkTTL: cfg.ExportLinkTTL, Clock: clk},
+		clock:              clk,
+		db:                 db,
+		redis:              redisClient,
+		repos:              repos,
+		limiter:            newRateLimiter(cfg, redisClient, clk),
+	}
+}
+
+// newExportStore returns the bucket exports are written to, or nil when
+// EXPORT_S3_BUCKET is not set
+func newExportStore(cfg *config.Config) export.Store {
+	if cfg.ExportS3Bucket == "" {
+		return nil
+	}
+
+	return &export.S3Uploader{
+		Endpoint: cfg.ExportS3Endpoint,
+		Region:   cfg.ExportS3Region,
+		Bucket:   cfg.ExportS3Bucket,
+		Credentials: awssig.Credentials{
+			AccessKeyID:     cfg.AWSAccessKeyID,
+			SecretAccessKey: cfg.AWSSecretAccessKey,
+			SessionToken:    cfg.AWSSessionToken,
+		},
+	}
+}
+
+// newExporter returns what exports the audit log and the ledger to store, or nil
+// without one
+func newExporter(cfg *config.Config, repos repositories, store export.Store, clk clock.Clock) *export.Exporter {
+	if store == nil {
+		return nil
+	}
+
+	return &export.Exporter{
+		Checkpoints: repos.exportCheckpoints,
+		Uploader:    store,
+		Streams:     []export.Stream{export.AuditStream(repos.audit), export.TransactionStream(repos.ledger)},
+		Prefix:      cfg.ExportPrefix,
+		BatchSize:   cfg.ExportBatchSize,
+		Lag:         cfg.ExportLag,
+		Clock:       clk,
+	}
+}
+
+// newRateLimiter returns the limiter for mutating wallet requests, or nil when rate
+// limiting is disabled by RATE_LIMIT_PER_MINUTE=0
+func newRateLimiter(cfg *config.Config, redisClient *redis.Client, clk clock.Clock) ratelimit.Limiter {
+	if cfg.RateLimitPerMinute == 0 {
+		return nil
+	}
+
+	limit := ratelimit.Limit{PerMinute: cfg.RateLimitPerMinute, Burst: cfg.RateLimitBurst}
+	if redisClient != nil {
+		return ratelimit.NewRedis(redisClient, limit)
+	}
+	return ratelimit.NewMemory(limit, clk)
+}
+
+// newFeatureFlagStore returns where runtime flag overrides are kept. Redis and the
+// database share them between instances; memory keeps them to this one until restart.
+func newFeatureFlagStore(cfg *config.Config, repos repositories, redisClient *redis.Client) featureflag.Store {
+	switch {
+	case cfg.FeatureFlagsStore == "redis" && redisClient != nil:
+		return featureflag.NewRedis(redisClient)
+	case cfg.FeatureFlagsStore == "db":
+		return repos.featureFlags
+	default:
+		return featureflag.NewMemory()
+	}
+}
+
+// newRiskEngine returns the rules screening withdrawals and transfers, or nil when
+// risk checks are disabled
+func newRiskEngine(cfg *config.Config, history risk.History) risk.Engine {
+	if !cfg.RiskChecksEnabled {
+		return nil
+	}
+
+	return risk.NewEngine(
+		risk.VelocityRule{History: history, Window: time.Minute, Max: cfg.RiskMaxPerMinute, Action: models.RiskActionBlock},
+		risk.LargeAmountRule{
+			History:    history,
+			Lookback:   30 * 24 * time.Hour,
+			Factor:     decimal.NewFromInt(int64(cfg.RiskLargeAmountFactor)),
+			MinHistory: 3,
+			Action:     models.RiskActionFlag,
+		},
+		risk.NewRecipientRule{History: history, Action: models.RiskActionFlag},
+	)
+}
+
+// newAMLRules returns the AML checks configured, or nil when they are off
+func newAMLRules(cfg *config.Config) *service.AMLRules {
+	if cfg.AMLReportThreshold == nil {
+		return nil
+	}
+
+	return &service.AMLRules{
+		ReportThreshold:   *cfg.AMLReportThreshold,
+		StructuringMargin: decimal.New(int64(cfg.AMLStructuringMargin), -2),
+		StructuringCount:  cfg.AMLStructuringCount,
+		StructuringWindow: cfg.AMLStructuringWindow,
+	}
+}
+
+// repositories groups the data access implementations for one database driver
+type repositories struct {
+	users                   repository.UserRepository
+	wallets                 repository.WalletRepository
+	systemWallets           repository.SystemWalletRepository
+	ledger                  repository.LedgerRepository
+	holds                   repository.HoldRepository
+	limits                  repository.WalletLimitsRepository
+	alerts                  repository.WalletAlertRepository
+	risk                    repository.RiskRepository
+	credentials             repository.CredentialRepository
+	transactionPINs         repository.TransactionPINRepository
+	kyc                     repository.KYCRepository
+	aml                     repository.AMLRepository
+	idempotencyKeys         repository.IdempotencyKeyRepository
+	scheduledTransfers      repository.ScheduledTransferRepository
+	paymentRequests         repository.PaymentRequestRepository
+	payments                repository.PaymentRepository
+	payouts                 repository.PayoutRepository
+	providerPayments        repository.ProviderPaymentRepository
+	depositRefunds          repository.DepositRefundRepository
+	disputes                repository.DisputeRepository
+	pendingTransfers        repository.PendingTransferRepository
+	incomingTransfers       repository.IncomingTransferRepository
+	transferQuotes          repository.TransferQuoteRepository
+	outbox                  repository.OutboxRepository
+	audit                   repository.AuditRepository
+	snapshots               repository.BalanceSnapshotRepository
+	reconciliation          repository.ReconciliationRepository
+	featureFlags            repository.FeatureFlagRepository
+	notificationPreferences repository.NotificationPreferencesRepository
+	exportCheckpoints       repository.ExportCheckpointRepository
+	exportJobs              repository.ExportJobRepository
+}
+
+// newRepositories picks the repository implementations matching the database driver.
+// Wallet lookups, transaction history and reconciliation are read from the replica when
+// there is one.
+func newRepositories(driver string, db *dbpkg.DB) repositories {
+	primary, reader := db.DB, db.Reader()
+	switch driver {
+	case dbpkg.DriverSQLite:
+		return repositories{
+			users:                   sqlite.NewUserRepository(primary),
+			wallets:                 sqlite.NewWalletRepository(primary).WithReadReplica(reader),
+			systemWallets:           sqlite.NewWalletRepository(primary).WithReadReplica(reader),
+			ledger:                  sqlite.NewLedgerRepository(primary).WithReadReplica(reader),
+			holds:                   sqlite.NewHoldRepository(primary),
+			limits:                  sqlite.NewWalletLimitsRepository(primary),
+			alerts:                  sqlite.NewWalletAlertRepository(primary),
+			risk:                    sqlite.NewRiskRepository(primary),
+			credentials:             sqlite.NewCredentialRepository(primary),
+			transactionPINs:         sqlite.NewTransactionPINRepository(primary),
+			kyc:                     sqlite.NewKYCRepository(primary),
+			aml:                     sqlite.NewAMLRepository(primary),
+			idempotencyKeys:         sqlite.NewIdempotencyKeyRepository(primary),
+			scheduledTransfers:      sqlite.NewScheduledTransferRepository(primary),
+			paymentRequests:         sqlite.NewPaymentRequestRepository(primary),
+			payments:                sqlite.NewPaymentRepository(primary),
+			payouts:                 sqlite.NewPayoutRepository(primary),
+			providerPayments:        sqlite.NewProviderPaymentRepository(primary),
+			depositRefunds:          sqlite.NewDepositRefundRepository(primary),
+			disputes:                sqlite.NewDisputeRepository(primary),
+			pendingTransfers:        sqlite.NewPendingTransferRepository(primary),
+			incomingTransfers:       sqlite.NewIncomingTransferRepository(primary),
+			transferQuotes:          sqlite.NewTransferQuoteRepository(primary),
+			outbox:                  sqlite.NewOutboxRepository(primary),
+			audit:                   sqlite.NewAuditRepository(primary),
+			snapshots:               sqlite.NewBalanceSnapshotRepository(primary),
+			reconciliation:          sqlite.NewReconciliationRepository(primary).WithReadReplica(reader),
+			featureFlags:            sqlite.NewFeatureFlagRepository(primary),
+			notificationPreferences: sqlite.NewNotificationPreferencesRepository(primary),
+			exportCheckpoints:       sqlite.NewExportCheckpointRepository(primary),
+			exportJobs:              sqlite.NewExportJobRepository(primary),
+		}
+	case dbpkg.DriverMySQL:
+		return repositories{
+			users:                   mysql.NewUserRepository(primary),
+			wallets:                 mysql.NewWalletRepository(primary).WithReadReplica(reader),
+			systemWallets:           mysql.NewWalletRepository(primary).WithReadReplica(reader),
+			ledger:                  mysql.NewLedgerRepository(primary).WithReadReplica(reader),
+			holds:                   mysql.NewHoldRepository(primary),
+			limits:                  mysql.NewWalletLimitsRepository(primary),
+			alerts:                  mysql.NewWalletAlertRepository(primary),
+			risk:                    mysql.NewRiskRepository(primary),
+			credentials:             mysql.NewCredentialRepository(primary),
+			transactionPINs:         mysql.NewTransactionPINRepository(primary),
+			kyc:                     mysql.NewKYCRepository(primary),
+			aml:                     mysql.NewAMLRepository(primary),
+			idempotencyKeys:         mysql.NewIdempotencyKeyRepository(primary),
+			scheduledTransfers:      mysql.NewScheduledTransferRepository(primary),
+			paymentRequests:         mysql.NewPaymentRequestRepository(primary),
+			payments:                mysql.NewPaymentRepository(primary),
+			payouts:                 mysql.NewPayoutRepository(primary),
+			providerPayments:        mysql.NewProviderPaymentRepository(primary),
+			depositRefunds:          mysql.NewDepositRefundRepository(primary),
+			disputes:                mysql.NewDisputeRepository(primary),
+			pendingTransfers:        mysql.NewPendingTransferRepository(primary),
+			incomingTransfers:       mysql.NewIncomingTransferRepository(primary),
+			transferQuotes:          mysql.NewTransferQuoteRepository(primary),
+			outbox:                  mysql.NewOutboxRepository(primary),
+			audit:                   mysql.NewAuditRepository(primary),
+			snapshots:               mysql.NewBalanceSnapshotRepository(primary),
+			reconciliation:          mysql.NewReconciliationRepository(primary).WithReadReplica(reader),
+			featureFlags:            mysql.NewFeatureFlagRepository(primary),
+			notificationPreferences: mysql.NewNotificationPreferencesRepository(primary),
+			exportCheckpoints:       mysql.NewExportCheckpointRepository(primary),
+			exportJobs:              mysql.NewExportJobRepository(primary),
+		}
+	}
+	return repositories{
+		users:                   postgres.NewUserRepository(primary),
+		wallets:                 postgres.NewWalletRepository(primary).WithReadReplica(reader),
+		systemWallets:           postgres.NewWalletRepository(primary).WithReadReplica(reader),
+		ledger:                  postgres.NewLedgerRepository(primary, db.Pool).WithReadReplica(reader, db.ReaderPool()),
+		holds:                   postgres.NewHoldRepository(primary),
+		limits:                  postgres.NewWalletLimitsRepository(primary),
+		alerts:                  postgres.NewWalletAlertRepository(primary),
+		risk:                    postgres.NewRiskRepository(primary),
+		credentials:             postgres.NewCredentialRepository(primary),
+		transactionPINs:         postgres.NewTransactionPINRepository(primary),
+		kyc:                     postgres.NewKYCRepository(primary),
+		aml:                     postgres.NewAMLRepository(primary),
+		idempotencyKeys:         postgres.NewIdempotencyKeyRepository(primary),
+		scheduledTransfers:      postgres.NewScheduledTransferRepository(primary),
+		paymentRequests:         postgres.NewPaymentRequestRepository(primary),
+		payments:                postgres.NewPaymentRepository(primary),
+		payouts:                 postgres.NewPayoutRepository(primary),
+		providerPayments:        postgres.NewProviderPaymentRepository(primary),
+		depositRefunds:          postgres.NewDepositRefundRepository(primary),
+		disputes:                postgres.NewDisputeRepository(primary),
+		pendingTransfers:        postgres.NewPendingTransferRepository(primary),
+		incomingTransfers:       postgres.NewIncomingTransferRepository(primary),
+		transferQuotes:          postgres.NewTransferQuoteRepository(primary),
+		outbox:                  postgres.NewOutboxRepository(primary),
+		audit:                   postgres.NewAuditRepository(primary),
+		snapshots:               postgres.NewBalanceSnapshotRepository(primary),
+		reconciliation:          postgres.NewReconciliationRepository(primary, db.Pool).WithReadReplica(reader),
+		featureFlags:            postgres.NewFeatureFlagRepository(primary),
+		notificationPreferences: postgres.NewNotificationPreferencesRepository(primary),
+		exportCheckpoints:       postgres.NewExportCheckpointRepository(primary),
+		exportJobs:              postgres.NewExportJobRepository(primary),
+	}
+}
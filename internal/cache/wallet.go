@@ -0,0 +1,96 @@
+// Package cache keeps copies of frequently read rows in Redis
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/shopspring/decimal"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/pkg/money"
+)
+
+// walletKeyPrefix namespaces wallet keys in a shared Redis
+const walletKeyPrefix = "wallet:"
+
+// Wallets caches wallets in Redis, each under its own key expiring after the TTL
+type Wallets struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+// NewWallets creates a wallet cache on client whose entries live for ttl
+func NewWallets(client *redis.Client, ttl time.Duration) *Wallets {
+	return &Wallets{client: client, ttl: ttl}
+}
+
+// cachedWallet is a wallet as stored, with the version its JSON form leaves out
+type cachedWallet struct {
+	ID          uuid.UUID       `json:"id"`
+	UserID      uuid.UUID       `json:"user_id"`
+	Balance     decimal.Decimal `json:"balance"`
+	HeldBalance decimal.Decimal `json:"held_balance"`
+	Currency    money.Currency  `json:"currency"`
+	Status      string          `json:"status"`
+	Version     int64           `json:"version"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+func walletKey(id uuid.UUID) string {
+	return walletKeyPrefix + id.String()
+}
+
+func (c *Wallets) GetWallet(ctx context.Context, id uuid.UUID) (*models.Wallet, error) {
+	value, err := c.client.Get(ctx, walletKey(id)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var cached cachedWallet
+	if err := json.Unmarshal(value, &cached); err != nil {
+		return nil, err
+	}
+	return &models.Wallet{
+		ID:          cached.ID,
+		UserID:      cached.UserID,
+		Balance:     cached.Balance,
+		HeldBalance: cached.HeldBalance,
+		Currency:    cached.Currency,
+		Status:      cached.Status,
+		Version:     cached.Version,
+		CreatedAt:   cached.CreatedAt,
+	}, nil
+}
+
+func (c *Wallets) SetWallet(ctx context.Context, wallet *models.Wallet) error {
+	value, err := json.Marshal(cachedWallet{
+		ID:          wallet.ID,
+		UserID:      wallet.UserID,
+		Balance:     wallet.Balance,
+		HeldBalance: wallet.HeldBalance,
+		Currency:    wallet.Currency,
+		Status:      wallet.Status,
+		Version:     wallet.Version,
+		CreatedAt:   wallet.CreatedAt,
+	})
+	if err != nil {
+		return err
+	}
+	return c.client.Set(ctx, walletKey(wallet.ID), value, c.ttl).Err()
+}
+
+func (c *Wallets) InvalidateWallets(ctx context.Context, ids ...uuid.UUID) error {
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = walletKey(id)
+	}
+	return c.client.Del(ctx, keys...).Err()
+}
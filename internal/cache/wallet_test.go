@@ -0,0 +1,77 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/pkg/money"
+)
+
+func newWallets(t *testing.T) (*Wallets, *miniredis.Miniredis) {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return NewWallets(client, time.Minute), server
+}
+
+func TestWalletsRoundTrip(t *testing.T) {
+	wallets, server := newWallets(t)
+	ctx := context.Background()
+
+	wallet := &models.Wallet{
+		ID:          uuid.New(),
+		UserID:      uuid.New(),
+		Balance:     decimal.RequireFromString("100.10"),
+		HeldBalance: decimal.RequireFromString("0.30"),
+		Currency:    money.DefaultCurrency,
+		Status:      "active",
+		Version:     7,
+		CreatedAt:   time.Date(2024, 7, 1, 9, 0, 0, 0, time.UTC),
+	}
+
+	missing, err := wallets.GetWallet(ctx, wallet.ID)
+	require.NoError(t, err)
+	assert.Nil(t, missing)
+
+	require.NoError(t, wallets.SetWallet(ctx, wallet))
+	cached, err := wallets.GetWallet(ctx, wallet.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "100.1", cached.Balance.String())
+	assert.Equal(t, wallet.Version, cached.Version)
+	assert.True(t, wallet.CreatedAt.Equal(cached.CreatedAt))
+
+	// Entries expire after the TTL
+	server.FastForward(time.Minute)
+	expired, err := wallets.GetWallet(ctx, wallet.ID)
+	require.NoError(t, err)
+	assert.Nil(t, expired)
+}
+
+func TestInvalidateWallets(t *testing.T) {
+	wallets, _ := newWallets(t)
+	ctx := context.Background()
+
+	first := &models.Wallet{ID: uuid.New()}
+	second := &models.Wallet{ID: uuid.New()}
+	require.NoError(t, wallets.SetWallet(ctx, first))
+	require.NoError(t, wallets.SetWallet(ctx, second))
+
+	require.NoError(t, wallets.InvalidateWallets(ctx, first.ID, second.ID))
+
+	for _, id := range []uuid.UUID{first.ID, second.ID} {
+		cached, err := wallets.GetWallet(ctx, id)
+		require.NoError(t, err)
+		assert.Nil(t, cached)
+	}
+}
@@ -1,40 +1,567 @@
 package config
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/joho/godotenv"
+	"github.com/shopspring/decimal"
 )
 
+// APIVersions lists the API versions in the order they were introduced. APIVersion
+// names the newest one served; the versions before it are served alongside it.
+var APIVersions = []string{"v1", "v2"}
+
 type Config struct {
+	DBDriver   string `validate:"required,oneof=postgres mysql sqlite3" env:"DB_DRIVER"`
 	DBHost     string `validate:"required" env:"DB_HOST"`
 	DBPort     string `validate:"required,numeric" env:"DB_PORT"`
 	DBUser     string `validate:"required" env:"DB_USER"`
 	DBPassword string `validate:"required" env:"DB_PASSWORD"`
 	DBName     string `validate:"required" env:"DB_NAME"`
 	DBSSLMode  string `validate:"required,oneof=disable require verify-ca verify-full" env:"DB_SSL_MODE"`
+	// DBReplicaDSN points wallet lookups and transaction history at a read replica
+	DBReplicaDSN string `env:"DB_REPLICA_DSN"`
+	// Connection pool limits, applied to the primary and the replica alike
+	DBMaxOpenConns    int           `validate:"gte=0" env:"DB_MAX_OPEN_CONNS"`
+	DBMaxIdleConns    int           `validate:"gte=0" env:"DB_MAX_IDLE_CONNS"`
+	DBConnMaxLifetime time.Duration `validate:"gte=0" env:"DB_CONN_MAX_LIFETIME"`
+	DBConnMaxIdleTime time.Duration `validate:"gte=0" env:"DB_CONN_MAX_IDLE_TIME"`
+	// MigrateOnStartup applies pending schema migrations before the server starts
+	MigrateOnStartup bool `env:"MIGRATE_ON_STARTUP"`
+	// WalletLocking chooses between row locks and version checks for wallet updates
+	WalletLocking string `validate:"required,oneof=pessimistic optimistic" env:"WALLET_LOCKING"`
 
 	AppPort     string `validate:"required,numeric" env:"APP_PORT"`
-	APIVersion  string `validate:"required" env:"API_VERSION"`
+	GRPCPort    string `validate:"omitempty,numeric" env:"GRPC_PORT"`
+	APIVersion  string `validate:"required,oneof=v1 v2" env:"API_VERSION"`
 	Environment string `validate:"required,oneof=development staging production" env:"ENVIRONMENT"`
+	// MaxBodyBytes caps the size of request bodies
+	MaxBodyBytes int64 `validate:"gt=0" env:"MAX_BODY_BYTES"`
+	// ShutdownTimeout bounds how long in-flight requests and workers get to finish on exit
+	ShutdownTimeout time.Duration `validate:"gt=0" env:"SHUTDOWN_TIMEOUT"`
+	// HealthCacheTTL is how long a dependency check's result is reused by the health endpoints
+	HealthCacheTTL time.Duration `validate:"gte=0" env:"HEALTH_CACHE_TTL"`
+	// LogSampleRate is the share of successful requests logged; failed requests are always logged
+	LogSampleRate float64 `validate:"gte=0,lte=1" env:"LOG_SAMPLE_RATE"`
+	// LogBodies adds redacted JSON request and response bodies to request logs
+	LogBodies bool `env:"LOG_BODIES"`
+	// LogRedactFields are body fields redacted in request logs on top of the built-in ones
+	LogRedactFields []string `env:"LOG_REDACT_FIELDS"`
+	// RequestTimeout is the deadline given to each API request, including its database calls
+	RequestTimeout time.Duration `validate:"gte=0" env:"REQUEST_TIMEOUT"`
+	// TxTimeout bounds each attempt at a money-movement transaction
+	TxTimeout time.Duration `validate:"gte=0" env:"TX_TIMEOUT"`
+	// TxMaxRetries bounds the reruns of a transaction that hit a deadlock, serialization
+	// failure or wallet version conflict
+	TxMaxRetries int `validate:"gte=1" env:"TX_MAX_RETRIES"`
+
+	// TLSCertFile and TLSKeyFile serve the HTTP and gRPC APIs over TLS, with HTTP/2
+	TLSCertFile string `validate:"required_with=TLSKeyFile,excluded_with=TLSAutocertDomains" env:"TLS_CERT_FILE"`
+	TLSKeyFile  string `validate:"required_with=TLSCertFile" env:"TLS_KEY_FILE"`
+	// TLSAutocertDomains gets certificates for these hosts from Let's Encrypt instead
+	TLSAutocertDomains  []string `env:"TLS_AUTOCERT_DOMAINS"`
+	TLSAutocertCacheDir string   `validate:"required_with=TLSAutocertDomains" env:"TLS_AUTOCERT_CACHE_DIR"`
+	// TLSClientCAFile requires clients to present a certificate signed by this CA (mTLS)
+	TLSClientCAFile string `validate:"excluded_without=TLSCertFile" env:"TLS_CLIENT_CA_FILE"`
+
+	AuthEnabled bool          `env:"AUTH_ENABLED"`
+	JWTSecret   string        `validate:"required_if=AuthEnabled true,omitempty,min=32" env:"JWT_SECRET"`
+	JWTTTL      time.Duration `validate:"required_if=AuthEnabled true" env:"JWT_TTL"`
+
+	// AdminAPIKey enables the /admin endpoints when set
+	AdminAPIKey string `validate:"omitempty,min=32" env:"ADMIN_API_KEY"`
+
+	// RedisURL shares rate limit buckets between instances; without it each instance limits on its own
+	RedisURL           string `validate:"required_if=FeatureFlagsStore redis,omitempty,url" env:"REDIS_URL"`
+	RateLimitPerMinute int    `validate:"gte=0" env:"RATE_LIMIT_PER_MINUTE"`
+	RateLimitBurst     int    `validate:"required_unless=RateLimitPerMinute 0,gte=0" env:"RATE_LIMIT_BURST"`
+	// BalanceCacheTTL is how long a wallet read for its balance stays in Redis; 0, or no
+	// Redis, disables the cache
+	BalanceCacheTTL time.Duration `validate:"gte=0" env:"BALANCE_CACHE_TTL"`
+
+	// SchedulerInterval is how often due scheduled transfers are run; 0 disables the worker
+	SchedulerInterval time.Duration `validate:"gte=0" env:"SCHEDULER_INTERVAL"`
+	// BalanceSnapshotInterval is how often the worker checks for ended days to snapshot
+	// wallet balances for; 0 disables it
+	BalanceSnapshotInterval time.Duration `validate:"gte=0" env:"BALANCE_SNAPSHOT_INTERVAL"`
+	// ReconciliationInterval is how often every wallet's balance is checked against its
+	// ledger; 0 disables the worker
+	ReconciliationInterval time.Duration `validate:"gte=0" env:"RECONCILIATION_INTERVAL"`
+
+	// TransferQuoteTTL is how long a transfer quote's fee and exchange rate hold
+	TransferQuoteTTL time.Duration `validate:"gt=0" env:"TRANSFER_QUOTE_TTL"`
+
+	// MinTransactionAmount and MaxTransactionAmount bound the amount of every deposit,
+	// withdrawal and transfer, whatever its currency; empty leaves that side unbounded
+	MinTransactionAmount *decimal.Decimal `env:"MIN_TRANSACTION_AMOUNT"`
+	MaxTransactionAmount *decimal.Decimal `env:"MAX_TRANSACTION_AMOUNT"`
+
+	// TransferConfirmationThreshold holds transfers of more than this, in the transfer's
+	// currency, until the sender confirms them or an operator approves them; empty lets
+	// every transfer through at once
+	TransferConfirmationThreshold *decimal.Decimal `env:"TRANSFER_CONFIRMATION_THRESHOLD"`
+	// TransferConfirmationWindow is how long a held transfer waits to be confirmed
+	TransferConfirmationWindow time.Duration `validate:"gt=0" env:"TRANSFER_CONFIRMATION_WINDOW"`
+	// PendingTransferExpiryInterval is how often held transfers left unconfirmed are
+	// cancelled; 0 disables the worker, leaving them to be cancelled when confirmed late
+	PendingTransferExpiryInterval time.Duration `validate:"gte=0" env:"PENDING_TRANSFER_EXPIRY_INTERVAL"`
+	// IncomingTransferAcceptanceWindow is how long a transfer into a wallet that requires
+	// acceptance waits to be accepted before it is returned to its sender
+	IncomingTransferAcceptanceWindow time.Duration `validate:"gt=0" env:"INCOMING_TRANSFER_ACCEPTANCE_WINDOW"`
+	// IncomingTransferExpiryInterval is how often incoming transfers left unaccepted are
+	// returned; 0 disables the worker, leaving them to be returned when accepted late
+	IncomingTransferExpiryInterval time.Duration `validate:"gte=0" env:"INCOMING_TRANSFER_EXPIRY_INTERVAL"`
+
+	// PINThreshold asks users who set a transaction PIN for it on withdrawals and
+	// transfers of more than this, in their currency; empty never asks for it
+	PINThreshold *decimal.Decimal `env:"PIN_THRESHOLD"`
+	// PINMaxAttempts is how many wrong PINs in a row lock a user's PIN
+	PINMaxAttempts int `validate:"gt=0" env:"PIN_MAX_ATTEMPTS"`
+	// PINLockout is how long a PIN stays locked after too many wrong attempts
+	PINLockout time.Duration `validate:"gt=0" env:"PIN_LOCKOUT"`
+
+	// KYCUnverifiedDailyWithdrawalLimit and KYCUnverifiedDailyTransferLimit cap what the
+	// wallets of unverified users can withdraw and transfer out a day, and the KYCBasic
+	// ones those of basic users; full users are held to their wallet's limits only.
+	// Empty leaves that limit off.
+	KYCUnverifiedDailyWithdrawalLimit *decimal.Decimal `env:"KYC_UNVERIFIED_DAILY_WITHDRAWAL_LIMIT"`
+	KYCUnverifiedDailyTransferLimit   *decimal.Decimal `env:"KYC_UNVERIFIED_DAILY_TRANSFER_LIMIT"`
+	KYCBasicDailyWithdrawalLimit      *decimal.Decimal `env:"KYC_BASIC_DAILY_WITHDRAWAL_LIMIT"`
+	KYCBasicDailyTransferLimit        *decimal.Decimal `env:"KYC_BASIC_DAILY_TRANSFER_LIMIT"`
+
+	// AMLReportThreshold reports deposits, withdrawals and transfers of more than this,
+	// whatever their currency, for anti-money-laundering review; empty turns the AML
+	// checks off
+	AMLReportThreshold *decimal.Decimal `env:"AML_REPORT_THRESHOLD"`
+	// AMLStructuringMargin is how far below the threshold, in percent of it, a movement
+	// counts as just below it
+	AMLStructuringMargin int `validate:"gte=0,lt=100" env:"AML_STRUCTURING_MARGIN"`
+	// AMLStructuringCount movements of one type just below the threshold within
+	// AMLStructuringWindow are reported as structuring; under 2 turns that check off
+	AMLStructuringCount  int           `validate:"gte=0" env:"AML_STRUCTURING_COUNT"`
+	AMLStructuringWindow time.Duration `validate:"gt=0" env:"AML_STRUCTURING_WINDOW"`
+
+	// RiskChecksEnabled screens withdrawals and transfers with the risk rules below
+	RiskChecksEnabled bool `env:"RISK_CHECKS_ENABLED"`
+	// RiskMaxPerMinute blocks a wallet's withdrawals or transfers beyond this many a minute
+	RiskMaxPerMinute int `validate:"required_if=RiskChecksEnabled true,gte=0" env:"RISK_MAX_PER_MINUTE"`
+	// RiskLargeAmountFactor flags amounts this many times over the wallet's 30 day average
+	RiskLargeAmountFactor int `validate:"required_if=RiskChecksEnabled true,gte=0" env:"RISK_LARGE_AMOUNT_FACTOR"`
+
+	// EventsPublisher is where wallet events from the outbox go; none writes no events
+	EventsPublisher string `validate:"required,oneof=none log nats kafka" env:"EVENTS_PUBLISHER"`
+	// EventsNATSURL is the NATS server events are published to, on subjects named after their type
+	EventsNATSURL string `validate:"required_if=EventsPublisher nats,omitempty,url" env:"EVENTS_NATS_URL"`
+	// EventsKafkaRESTURL is a Kafka REST proxy that produces events onto EventsKafkaTopic
+	EventsKafkaRESTURL string `validate:"required_if=EventsPublisher kafka,omitempty,url" env:"EVENTS_KAFKA_REST_URL"`
+	EventsKafkaTopic   string `validate:"required_if=EventsPublisher kafka" env:"EVENTS_KAFKA_TOPIC"`
+	// OutboxPollInterval is how often the dispatcher looks for unpublished events
+	OutboxPollInterval time.Duration `validate:"required_unless=EventsPublisher none,gte=0" env:"OUTBOX_POLL_INTERVAL"`
+
+	// NotificationsProvider emails users alerts about their transactions, read from the
+	// outbox; none sends nothing and dummy writes the emails to the log
+	NotificationsProvider string `validate:"required,oneof=none dummy smtp ses" env:"NOTIFICATIONS_PROVIDER"`
+	// NotificationsFrom is the address alerts are sent from
+	NotificationsFrom string `validate:"required_if=NotificationsProvider smtp,required_if=NotificationsProvider ses,omitempty,email" env:"NOTIFICATIONS_FROM"`
+	// SMTPAddr is the host:port of the server smtp sends through, logging in with
+	// SMTPUsername and SMTPPassword when they are set
+	SMTPAddr     string `validate:"required_if=NotificationsProvider smtp,omitempty,hostname_port" env:"SMTP_ADDR"`
+	SMTPUsername string `env:"SMTP_USERNAME"`
+	SMTPPassword string `env:"SMTP_PASSWORD"`
+	// SESRegion is the AWS region ses sends from, signing with the AWS_* credentials
+	SESRegion string `validate:"required_if=NotificationsProvider ses" env:"SES_REGION"`
+	// SESEndpoint overrides the region's SES endpoint, for testing against a local stand-in
+	SESEndpoint        string `validate:"omitempty,url" env:"SES_ENDPOINT"`
+	AWSAccessKeyID     string `validate:"required_if=NotificationsProvider ses,required_with=ExportS3Bucket" env:"AWS_ACCESS_KEY_ID"`
+	AWSSecretAccessKey string `validate:"required_if=NotificationsProvider ses,required_with=ExportS3Bucket" env:"AWS_SECRET_ACCESS_KEY"`
+	AWSSessionToken    string `env:"AWS_SESSION_TOKEN"`
+	// The alerts users get until they choose their own: withdrawals of at least
+	// NotifyLargeWithdrawal, and incoming transfers. An empty threshold turns its alert off.
+	NotifyLargeWithdrawal   *decimal.Decimal `env:"NOTIFY_LARGE_WITHDRAWAL"`
+	NotifyIncomingTransfers bool             `env:"NOTIFY_INCOMING_TRANSFERS"`
+
+	// ExportS3Bucket is the bucket the audit log, the ledger and wallet exports are
+	// written to as CSV, signing with the AWS_* credentials; empty disables exports
+	ExportS3Bucket string `env:"EXPORT_S3_BUCKET"`
+	ExportS3Region string `validate:"required_with=ExportS3Bucket" env:"EXPORT_S3_REGION"`
+	// ExportS3Endpoint overrides the region's S3 endpoint, for S3-compatible stores
+	ExportS3Endpoint string `validate:"omitempty,url" env:"EXPORT_S3_ENDPOINT"`
+	// ExportPrefix is put in front of the exported files' keys
+	ExportPrefix string `env:"EXPORT_PREFIX"`
+	// ExportInterval is how often new rows are exported
+	ExportInterval time.Duration `validate:"required_with=ExportS3Bucket,gte=0" env:"EXPORT_INTERVAL"`
+	// ExportBatchSize is the most rows one exported file holds
+	ExportBatchSize int `validate:"gt=0" env:"EXPORT_BATCH_SIZE"`
+	// ExportLag leaves rows younger than this to the next export, so rows still being
+	// committed are not skipped
+	ExportLag time.Duration `validate:"gte=0" env:"EXPORT_LAG"`
+	// ExportJobInterval is how often exports of wallets' transactions that users queued
+	// are produced into the same bucket; 0 disables the worker
+	ExportJobInterval time.Duration `validate:"gte=0" env:"EXPORT_JOB_INTERVAL"`
+	// ExportLinkTTL is how long the download links of those exports work, for at most
+	// seven days
+	ExportLinkTTL time.Duration `validate:"gt=0" env:"EXPORT_LINK_TTL"`
+
+	// SettlementKafkaRESTURL is a Kafka REST proxy to consume settlement events through;
+	// empty disables the consumer
+	SettlementKafkaRESTURL string        `validate:"omitempty,url" env:"SETTLEMENT_KAFKA_REST_URL"`
+	SettlementTopic        string        `validate:"required_with=SettlementKafkaRESTURL" env:"SETTLEMENT_TOPIC"`
+	SettlementGroup        string        `validate:"required_with=SettlementKafkaRESTURL" env:"SETTLEMENT_CONSUMER_GROUP"`
+	SettlementPollInterval time.Duration `validate:"required_with=SettlementKafkaRESTURL,gte=0" env:"SETTLEMENT_POLL_INTERVAL"`
+
+	// FXProvider quotes exchange rates for transfers between currencies; none rejects them
+	FXProvider string `validate:"required,oneof=none static http" env:"FX_PROVIDER"`
+	// FXRates is the static rate table, as comma-separated FROM/TO=RATE entries
+	FXRates string `validate:"required_if=FXProvider static" env:"FX_RATES"`
+	// FXAPIURL is a rates API answering GET /latest?from=USD&to=EUR, quoted for FXRateTTL
+	FXAPIURL  string        `validate:"required_if=FXProvider http,omitempty,url" env:"FX_API_URL"`
+	FXRateTTL time.Duration `validate:"gte=0" env:"FX_RATE_TTL"`
+
+	// PayoutProvider sends payouts to bank accounts; none rejects them and dummy logs them
+	// and reports them settled
+	PayoutProvider string `validate:"required,oneof=none dummy http" env:"PAYOUT_PROVIDER"`
+	// PayoutAPIURL is the payout API the http provider sends payouts to and polls
+	PayoutAPIURL string `validate:"required_if=PayoutProvider http,omitempty,url" env:"PAYOUT_API_URL"`
+	// PayoutPollInterval is how often payouts in flight are sent again or checked on;
+	// 0 disables the worker
+	PayoutPollInterval time.Duration `validate:"gte=0" env:"PAYOUT_POLL_INTERVAL"`
+
+	// PaymentWebhookSecret signs the payment provider's webhook deliveries; empty leaves
+	// the webhook unmounted
+	PaymentWebhookSecret string `env:"PAYMENT_WEBHOOK_SECRET"`
+	// PaymentWebhookTolerance is how old a delivery's signature may be before it is
+	// refused as a replay
+	PaymentWebhookTolerance time.Duration `validate:"gt=0" env:"PAYMENT_WEBHOOK_TOLERANCE"`
+	// PaymentProviderAPIURL is the payment provider's API, which refunds the payments
+	// behind refunded deposits; empty refunds them from the wallet only
+	PaymentProviderAPIURL string `validate:"omitempty,url" env:"PAYMENT_PROVIDER_API_URL"`
+	// RefundRetryInterval is how often refunds the payment provider could not be reached
+	// about are sent again; 0 disables the worker
+	RefundRetryInterval time.Duration `validate:"gte=0" env:"REFUND_RETRY_INTERVAL"`
+
+	// Fees charges deposits, withdrawals and transfers, as comma-separated OPERATION=FEE
+	// entries where FEE is a percentage like 1.5% or a flat amount like 0.50
+	Fees string `env:"FEES"`
+	// FeeWalletID is the wallet fees are paid into; fees in other currencies are waived
+	FeeWalletID string `validate:"required_with=Fees,omitempty,uuid" env:"FEE_WALLET_ID"`
+
+	// FeatureFlags sets flag defaults for this environment, as comma-separated name=true|false entries
+	FeatureFlags string `env:"FEATURE_FLAGS"`
+	// FeatureFlagsStore keeps the overrides operators set at runtime
+	FeatureFlagsStore string `validate:"required,oneof=memory db redis" env:"FEATURE_FLAGS_STORE"`
+	// FeatureFlagsCacheTTL is how long a flag's value is reused before the store is read again
+	FeatureFlagsCacheTTL time.Duration `validate:"gte=0" env:"FEATURE_FLAGS_CACHE_TTL"`
 }
 
+// LoadConfig reads the configuration from the environment, with secrets from the
+// provider SECRETS_PROVIDER names
 func LoadConfig() (*Config, error) {
-	_ = godotenv.Load() // Only loads from .env in dev
+	loadEnvFiles()
 
+	provider, err := NewSecretProvider()
+	if err != nil {
+		return nil, err
+	}
+	return Load(context.Background(), provider)
+}
+
+// loadEnvFiles loads .env files in dev. Variables already set are kept, and the
+// profile for the environment, such as .env.staging, wins over .env.
+func loadEnvFiles() {
+	environment := os.Getenv("ENVIRONMENT")
+	if environment == "" {
+		if base, err := godotenv.Read(); err == nil {
+			environment = base["ENVIRONMENT"]
+		}
+	}
+	if environment != "" {
+		_ = godotenv.Load(".env." + environment)
+	}
+	_ = godotenv.Load()
+}
+
+// Load reads the configuration from the environment. The database password, replica
+// DSN, JWT secret, admin key and Redis URL may instead come from <NAME>_FILE or from
+// provider, which may be nil.
+func Load(ctx context.Context, provider SecretProvider) (*Config, error) {
 	config := &Config{
-		DBHost:     getEnv("DB_HOST", "localhost"),
-		DBPort:     getEnv("DB_PORT", "5432"),
-		DBUser:     getEnv("DB_USER", "wallet"),
-		DBPassword: getEnv("DB_PASSWORD", "walletpass"),
-		DBName:     getEnv("DB_NAME", "wallet_db"),
-		DBSSLMode:  getEnv("DB_SSL_MODE", "disable"),
+		DBDriver:  getEnv("DB_DRIVER", "postgres"),
+		DBHost:    getEnv("DB_HOST", "localhost"),
+		DBPort:    getEnv("DB_PORT", "5432"),
+		DBUser:    getEnv("DB_USER", "wallet"),
+		DBName:    getEnv("DB_NAME", "wallet_db"),
+		DBSSLMode: getEnv("DB_SSL_MODE", "disable"),
+
+		MigrateOnStartup: getEnv("MIGRATE_ON_STARTUP", "false") == "true",
+		WalletLocking:    getEnv("WALLET_LOCKING", "pessimistic"),
 
 		AppPort:     getEnv("APP_PORT", "8082"),
-		APIVersion:  getEnv("API_VERSION", "v1"),
+		GRPCPort:    getEnv("GRPC_PORT", "9090"),
+		APIVersion:  getEnv("API_VERSION", "v2"),
 		Environment: getEnv("ENVIRONMENT", "development"),
+
+		AuthEnabled: getEnv("AUTH_ENABLED", "true") == "true",
+	}
+
+	var err error
+	secrets := []struct {
+		target   *string
+		key      string
+		fallback string
+	}{
+		{&config.DBPassword, "DB_PASSWORD", "walletpass"},
+		{&config.DBReplicaDSN, "DB_REPLICA_DSN", ""},
+		{&config.JWTSecret, "JWT_SECRET", ""},
+		{&config.AdminAPIKey, "ADMIN_API_KEY", ""},
+		{&config.RedisURL, "REDIS_URL", ""},
+		{&config.SMTPPassword, "SMTP_PASSWORD", ""},
+		{&config.AWSSecretAccessKey, "AWS_SECRET_ACCESS_KEY", ""},
+	}
+	for _, secret := range secrets {
+		if *secret.target, err = getSecret(ctx, provider, secret.key, secret.fallback); err != nil {
+			return nil, err
+		}
+	}
+
+	jwtTTL, err := time.ParseDuration(getEnv("JWT_TTL", "24h"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid JWT_TTL: %w", err)
+	}
+	config.JWTTTL = jwtTTL
+
+	if config.MaxBodyBytes, err = strconv.ParseInt(getEnv("MAX_BODY_BYTES", "1048576"), 10, 64); err != nil {
+		return nil, fmt.Errorf("invalid MAX_BODY_BYTES: %w", err)
+	}
+
+	if config.ShutdownTimeout, err = time.ParseDuration(getEnv("SHUTDOWN_TIMEOUT", "30s")); err != nil {
+		return nil, fmt.Errorf("invalid SHUTDOWN_TIMEOUT: %w", err)
+	}
+
+	if config.HealthCacheTTL, err = time.ParseDuration(getEnv("HEALTH_CACHE_TTL", "5s")); err != nil {
+		return nil, fmt.Errorf("invalid HEALTH_CACHE_TTL: %w", err)
+	}
+
+	if config.LogSampleRate, err = strconv.ParseFloat(getEnv("LOG_SAMPLE_RATE", "1"), 64); err != nil {
+		return nil, fmt.Errorf("invalid LOG_SAMPLE_RATE: %w", err)
+	}
+	config.LogBodies = getEnv("LOG_BODIES", "false") == "true"
+	if fields := getEnv("LOG_REDACT_FIELDS", ""); fields != "" {
+		config.LogRedactFields = strings.Split(fields, ",")
+	}
+
+	config.TLSCertFile = getEnv("TLS_CERT_FILE", "")
+	config.TLSKeyFile = getEnv("TLS_KEY_FILE", "")
+	if domains := getEnv("TLS_AUTOCERT_DOMAINS", ""); domains != "" {
+		config.TLSAutocertDomains = strings.Split(domains, ",")
+	}
+	config.TLSAutocertCacheDir = getEnv("TLS_AUTOCERT_CACHE_DIR", "certs")
+	config.TLSClientCAFile = getEnv("TLS_CLIENT_CA_FILE", "")
+
+	if config.RequestTimeout, err = time.ParseDuration(getEnv("REQUEST_TIMEOUT", "10s")); err != nil {
+		return nil, fmt.Errorf("invalid REQUEST_TIMEOUT: %w", err)
+	}
+
+	if config.TxTimeout, err = time.ParseDuration(getEnv("TX_TIMEOUT", "10s")); err != nil {
+		return nil, fmt.Errorf("invalid TX_TIMEOUT: %w", err)
+	}
+	if config.TxMaxRetries, err = strconv.Atoi(getEnv("TX_MAX_RETRIES", "3")); err != nil {
+		return nil, fmt.Errorf("invalid TX_MAX_RETRIES: %w", err)
+	}
+
+	if config.DBMaxOpenConns, err = strconv.Atoi(getEnv("DB_MAX_OPEN_CONNS", "25")); err != nil {
+		return nil, fmt.Errorf("invalid DB_MAX_OPEN_CONNS: %w", err)
+	}
+	if config.DBMaxIdleConns, err = strconv.Atoi(getEnv("DB_MAX_IDLE_CONNS", "10")); err != nil {
+		return nil, fmt.Errorf("invalid DB_MAX_IDLE_CONNS: %w", err)
+	}
+	if config.DBConnMaxLifetime, err = time.ParseDuration(getEnv("DB_CONN_MAX_LIFETIME", "30m")); err != nil {
+		return nil, fmt.Errorf("invalid DB_CONN_MAX_LIFETIME: %w", err)
+	}
+	if config.DBConnMaxIdleTime, err = time.ParseDuration(getEnv("DB_CONN_MAX_IDLE_TIME", "5m")); err != nil {
+		return nil, fmt.Errorf("invalid DB_CONN_MAX_IDLE_TIME: %w", err)
+	}
+
+	schedulerInterval, err := time.ParseDuration(getEnv("SCHEDULER_INTERVAL", "30s"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid SCHEDULER_INTERVAL: %w", err)
+	}
+	config.SchedulerInterval = schedulerInterval
+	if config.BalanceSnapshotInterval, err = time.ParseDuration(getEnv("BALANCE_SNAPSHOT_INTERVAL", "1h")); err != nil {
+		return nil, fmt.Errorf("invalid BALANCE_SNAPSHOT_INTERVAL: %w", err)
+	}
+	if config.ReconciliationInterval, err = time.ParseDuration(getEnv("RECONCILIATION_INTERVAL", "1h")); err != nil {
+		return nil, fmt.Errorf("invalid RECONCILIATION_INTERVAL: %w", err)
+	}
+
+	if config.TransferQuoteTTL, err = time.ParseDuration(getEnv("TRANSFER_QUOTE_TTL", "30s")); err != nil {
+		return nil, fmt.Errorf("invalid TRANSFER_QUOTE_TTL: %w", err)
+	}
+
+	if config.MinTransactionAmount, err = parseThreshold("MIN_TRANSACTION_AMOUNT", ""); err != nil {
+		return nil, err
+	}
+	if config.MaxTransactionAmount, err = parseThreshold("MAX_TRANSACTION_AMOUNT", ""); err != nil {
+		return nil, err
+	}
+	if config.MinTransactionAmount != nil && config.MaxTransactionAmount != nil &&
+		config.MinTransactionAmount.GreaterThan(*config.MaxTransactionAmount) {
+		return nil, fmt.Errorf("invalid MIN_TRANSACTION_AMOUNT: must not be above MAX_TRANSACTION_AMOUNT")
+	}
+
+	if config.TransferConfirmationThreshold, err = parseThreshold("TRANSFER_CONFIRMATION_THRESHOLD", "10000"); err != nil {
+		return nil, err
+	}
+	if config.TransferConfirmationWindow, err = time.ParseDuration(getEnv("TRANSFER_CONFIRMATION_WINDOW", "15m")); err != nil {
+		return nil, fmt.Errorf("invalid TRANSFER_CONFIRMATION_WINDOW: %w", err)
+	}
+	if config.PendingTransferExpiryInterval, err = time.ParseDuration(getEnv("PENDING_TRANSFER_EXPIRY_INTERVAL", "1m")); err != nil {
+		return nil, fmt.Errorf("invalid PENDING_TRANSFER_EXPIRY_INTERVAL: %w", err)
+	}
+	if config.IncomingTransferAcceptanceWindow, err = time.ParseDuration(getEnv("INCOMING_TRANSFER_ACCEPTANCE_WINDOW", "72h")); err != nil {
+		return nil, fmt.Errorf("invalid INCOMING_TRANSFER_ACCEPTANCE_WINDOW: %w", err)
+	}
+	if config.IncomingTransferExpiryInterval, err = time.ParseDuration(getEnv("INCOMING_TRANSFER_EXPIRY_INTERVAL", "1m")); err != nil {
+		return nil, fmt.Errorf("invalid INCOMING_TRANSFER_EXPIRY_INTERVAL: %w", err)
+	}
+
+	if config.PINThreshold, err = parseThreshold("PIN_THRESHOLD", "1000"); err != nil {
+		return nil, err
+	}
+	if config.PINMaxAttempts, err = strconv.Atoi(getEnv("PIN_MAX_ATTEMPTS", "5")); err != nil {
+		return nil, fmt.Errorf("invalid PIN_MAX_ATTEMPTS: %w", err)
+	}
+	if config.PINLockout, err = time.ParseDuration(getEnv("PIN_LOCKOUT", "15m")); err != nil {
+		return nil, fmt.Errorf("invalid PIN_LOCKOUT: %w", err)
+	}
+
+	if config.KYCUnverifiedDailyWithdrawalLimit, err = parseThreshold("KYC_UNVERIFIED_DAILY_WITHDRAWAL_LIMIT", ""); err != nil {
+		return nil, err
+	}
+	if config.KYCUnverifiedDailyTransferLimit, err = parseThreshold("KYC_UNVERIFIED_DAILY_TRANSFER_LIMIT", ""); err != nil {
+		return nil, err
+	}
+	if config.KYCBasicDailyWithdrawalLimit, err = parseThreshold("KYC_BASIC_DAILY_WITHDRAWAL_LIMIT", ""); err != nil {
+		return nil, err
+	}
+	if config.KYCBasicDailyTransferLimit, err = parseThreshold("KYC_BASIC_DAILY_TRANSFER_LIMIT", ""); err != nil {
+		return nil, err
+	}
+
+	if config.RateLimitPerMinute, err = strconv.Atoi(getEnv("RATE_LIMIT_PER_MINUTE", "60")); err != nil {
+		return nil, fmt.Errorf("invalid RATE_LIMIT_PER_MINUTE: %w", err)
+	}
+	if config.RateLimitBurst, err = strconv.Atoi(getEnv("RATE_LIMIT_BURST", "10")); err != nil {
+		return nil, fmt.Errorf("invalid RATE_LIMIT_BURST: %w", err)
+	}
+
+	if config.AMLReportThreshold, err = parseThreshold("AML_REPORT_THRESHOLD", "10000"); err != nil {
+		return nil, err
+	}
+	if config.AMLStructuringMargin, err = strconv.Atoi(getEnv("AML_STRUCTURING_MARGIN", "10")); err != nil {
+		return nil, fmt.Errorf("invalid AML_STRUCTURING_MARGIN: %w", err)
+	}
+	if config.AMLStructuringCount, err = strconv.Atoi(getEnv("AML_STRUCTURING_COUNT", "3")); err != nil {
+		return nil, fmt.Errorf("invalid AML_STRUCTURING_COUNT: %w", err)
+	}
+	if config.AMLStructuringWindow, err = time.ParseDuration(getEnv("AML_STRUCTURING_WINDOW", "24h")); err != nil {
+		return nil, fmt.Errorf("invalid AML_STRUCTURING_WINDOW: %w", err)
+	}
+
+	config.RiskChecksEnabled = getEnv("RISK_CHECKS_ENABLED", "true") == "true"
+	if config.RiskMaxPerMinute, err = strconv.Atoi(getEnv("RISK_MAX_PER_MINUTE", "10")); err != nil {
+		return nil, fmt.Errorf("invalid RISK_MAX_PER_MINUTE: %w", err)
+	}
+	if config.RiskLargeAmountFactor, err = strconv.Atoi(getEnv("RISK_LARGE_AMOUNT_FACTOR", "10")); err != nil {
+		return nil, fmt.Errorf("invalid RISK_LARGE_AMOUNT_FACTOR: %w", err)
+	}
+
+	config.EventsPublisher = getEnv("EVENTS_PUBLISHER", "none")
+	config.EventsNATSURL = getEnv("EVENTS_NATS_URL", "")
+	config.EventsKafkaRESTURL = getEnv("EVENTS_KAFKA_REST_URL", "")
+	config.EventsKafkaTopic = getEnv("EVENTS_KAFKA_TOPIC", "wallet-events")
+	if config.OutboxPollInterval, err = time.ParseDuration(getEnv("OUTBOX_POLL_INTERVAL", "1s")); err != nil {
+		return nil, fmt.Errorf("invalid OUTBOX_POLL_INTERVAL: %w", err)
+	}
+
+	config.NotificationsProvider = getEnv("NOTIFICATIONS_PROVIDER", "none")
+	config.NotificationsFrom = getEnv("NOTIFICATIONS_FROM", "")
+	config.SMTPAddr = getEnv("SMTP_ADDR", "")
+	config.SMTPUsername = getEnv("SMTP_USERNAME", "")
+	config.SESRegion = getEnv("SES_REGION", getEnv("AWS_REGION", ""))
+	config.SESEndpoint = getEnv("SES_ENDPOINT", "")
+	config.AWSAccessKeyID = getEnv("AWS_ACCESS_KEY_ID", "")
+	config.AWSSessionToken = getEnv("AWS_SESSION_TOKEN", "")
+	if config.NotifyLargeWithdrawal, err = parseThreshold("NOTIFY_LARGE_WITHDRAWAL", "1000"); err != nil {
+		return nil, err
+	}
+	config.NotifyIncomingTransfers = getEnv("NOTIFY_INCOMING_TRANSFERS", "true") == "true"
+
+	config.ExportS3Bucket = getEnv("EXPORT_S3_BUCKET", "")
+	config.ExportS3Region = getEnv("EXPORT_S3_REGION", getEnv("AWS_REGION", ""))
+	config.ExportS3Endpoint = getEnv("EXPORT_S3_ENDPOINT", "")
+	config.ExportPrefix = getEnv("EXPORT_PREFIX", "wallet-app")
+	if config.ExportInterval, err = time.ParseDuration(getEnv("EXPORT_INTERVAL", "1h")); err != nil {
+		return nil, fmt.Errorf("invalid EXPORT_INTERVAL: %w", err)
+	}
+	if config.ExportBatchSize, err = strconv.Atoi(getEnv("EXPORT_BATCH_SIZE", "10000")); err != nil {
+		return nil, fmt.Errorf("invalid EXPORT_BATCH_SIZE: %w", err)
+	}
+	if config.ExportLag, err = time.ParseDuration(getEnv("EXPORT_LAG", "1m")); err != nil {
+		return nil, fmt.Errorf("invalid EXPORT_LAG: %w", err)
+	}
+	if config.ExportJobInterval, err = time.ParseDuration(getEnv("EXPORT_JOB_INTERVAL", "10s")); err != nil {
+		return nil, fmt.Errorf("invalid EXPORT_JOB_INTERVAL: %w", err)
+	}
+	if config.ExportLinkTTL, err = time.ParseDuration(getEnv("EXPORT_LINK_TTL", "15m")); err != nil {
+		return nil, fmt.Errorf("invalid EXPORT_LINK_TTL: %w", err)
+	}
+
+	config.SettlementKafkaRESTURL = getEnv("SETTLEMENT_KAFKA_REST_URL", "")
+	config.SettlementTopic = getEnv("SETTLEMENT_TOPIC", "settlements")
+	config.SettlementGroup = getEnv("SETTLEMENT_CONSUMER_GROUP", "wallet-app")
+	if config.SettlementPollInterval, err = time.ParseDuration(getEnv("SETTLEMENT_POLL_INTERVAL", "1s")); err != nil {
+		return nil, fmt.Errorf("invalid SETTLEMENT_POLL_INTERVAL: %w", err)
+	}
+
+	config.FXProvider = getEnv("FX_PROVIDER", "none")
+	config.FXRates = getEnv("FX_RATES", "")
+	config.FXAPIURL = getEnv("FX_API_URL", "")
+	if config.FXRateTTL, err = time.ParseDuration(getEnv("FX_RATE_TTL", "1m")); err != nil {
+		return nil, fmt.Errorf("invalid FX_RATE_TTL: %w", err)
+	}
+
+	config.PayoutProvider = getEnv("PAYOUT_PROVIDER", "none")
+	config.PayoutAPIURL = getEnv("PAYOUT_API_URL", "")
+	if config.PayoutPollInterval, err = time.ParseDuration(getEnv("PAYOUT_POLL_INTERVAL", "1m")); err != nil {
+		return nil, fmt.Errorf("invalid PAYOUT_POLL_INTERVAL: %w", err)
+	}
+
+	config.PaymentWebhookSecret = getEnv("PAYMENT_WEBHOOK_SECRET", "")
+	if config.PaymentWebhookTolerance, err = time.ParseDuration(getEnv("PAYMENT_WEBHOOK_TOLERANCE", "5m")); err != nil {
+		return nil, fmt.Errorf("invalid PAYMENT_WEBHOOK_TOLERANCE: %w", err)
+	}
+	config.PaymentProviderAPIURL = getEnv("PAYMENT_PROVIDER_API_URL", "")
+	if config.RefundRetryInterval, err = time.ParseDuration(getEnv("REFUND_RETRY_INTERVAL", "1m")); err != nil {
+		return nil, fmt.Errorf("invalid REFUND_RETRY_INTERVAL: %w", err)
+	}
+
+	config.Fees = getEnv("FEES", "")
+	config.FeeWalletID = getEnv("FEE_WALLET_ID", "")
+
+	config.FeatureFlags = getEnv("FEATURE_FLAGS", "")
+	config.FeatureFlagsStore = getEnv("FEATURE_FLAGS_STORE", "db")
+	if config.BalanceCacheTTL, err = time.ParseDuration(getEnv("BALANCE_CACHE_TTL", "5s")); err != nil {
+		return nil, fmt.Errorf("invalid BALANCE_CACHE_TTL: %w", err)
+	}
+
+	if config.FeatureFlagsCacheTTL, err = time.ParseDuration(getEnv("FEATURE_FLAGS_CACHE_TTL", "5s")); err != nil {
+		return nil, fmt.Errorf("invalid FEATURE_FLAGS_CACHE_TTL: %w", err)
 	}
 
 	// Validate configuration
@@ -46,9 +573,33 @@ func LoadConfig() (*Config, error) {
 	return config, nil
 }
 
+// parseThreshold reads a positive amount from the environment, nil when it is empty
+func parseThreshold(key, fallback string) (*decimal.Decimal, error) {
+	raw := getEnv(key, fallback)
+	if raw == "" {
+		return nil, nil
+	}
+	threshold, err := decimal.NewFromString(raw)
+	if err != nil || !threshold.IsPositive() {
+		return nil, fmt.Errorf("invalid %s: must be a positive amount", key)
+	}
+	return &threshold, nil
+}
+
 func getEnv(key string, fallback string) string {
 	if value, exists := os.LookupEnv(key); exists {
 		return value
 	}
 	return fallback
 }
+
+// ServedAPIVersions returns the API versions to serve: every version up to and
+// including APIVersion
+func (c *Config) ServedAPIVersions() []string {
+	for i, version := range APIVersions {
+		if version == c.APIVersion {
+			return APIVersions[:i+1]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,249 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shanwije/wallet-app/pkg/awssig"
+)
+
+// ErrSecretNotFound is returned by a SecretProvider that has no secret of the given name
+var ErrSecretNotFound = errors.New("secret not found")
+
+// SecretProvider looks up secrets such as the database password by their environment
+// variable name, so they need not be kept in the environment
+type SecretProvider interface {
+	// GetSecret returns the named secret, or ErrSecretNotFound
+	GetSecret(ctx context.Context, name string) (string, error)
+}
+
+// secretTimeout bounds the lookups against a remote secret store at startup
+const secretTimeout = 10 * time.Second
+
+// NewSecretProvider returns the provider named by SECRETS_PROVIDER: env (the default)
+// reads nothing beyond the environment, file reads Docker and Kubernetes secret files
+// from SECRETS_DIR, aws reads a JSON secret from AWS Secrets Manager and vault reads
+// a KV v2 secret from HashiCorp Vault
+func NewSecretProvider() (SecretProvider, error) {
+	switch provider := getEnv("SECRETS_PROVIDER", "env"); provider {
+	case "env":
+		return nil, nil
+	case "file":
+		return FileSecrets{Dir: getEnv("SECRETS_DIR", "/run/secrets")}, nil
+	case "aws":
+		region := getEnv("AWS_REGION", "")
+		secretID := getEnv("SECRETS_AWS_SECRET_ID", "")
+		if region == "" || secretID == "" {
+			return nil, errors.New("SECRETS_PROVIDER=aws requires AWS_REGION and SECRETS_AWS_SECRET_ID")
+		}
+		return &AWSSecrets{
+			Endpoint:        getEnv("SECRETS_AWS_ENDPOINT", "https://secretsmanager."+region+".amazonaws.com"),
+			Region:          region,
+			SecretID:        secretID,
+			AccessKeyID:     getEnv("AWS_ACCESS_KEY_ID", ""),
+			SecretAccessKey: getEnv("AWS_SECRET_ACCESS_KEY", ""),
+			SessionToken:    getEnv("AWS_SESSION_TOKEN", ""),
+		}, nil
+	case "vault":
+		token, err := readEnvOrFile("VAULT_TOKEN")
+		if err != nil {
+			return nil, err
+		}
+		addr := getEnv("VAULT_ADDR", "")
+		path := getEnv("SECRETS_VAULT_PATH", "")
+		if addr == "" || token == "" || path == "" {
+			return nil, errors.New("SECRETS_PROVIDER=vault requires VAULT_ADDR, VAULT_TOKEN and SECRETS_VAULT_PATH")
+		}
+		return &VaultSecrets{Addr: addr, Token: token, Mount: getEnv("SECRETS_VAULT_MOUNT", "secret"), Path: path}, nil
+	default:
+		return nil, fmt.Errorf("unsupported SECRETS_PROVIDER: %q", provider)
+	}
+}
+
+// getSecret reads a secret setting. A value in the environment wins, then a file
+// named by <key>_FILE, then the provider. Otherwise it behaves like getEnv.
+func getSecret(ctx context.Context, provider SecretProvider, key, fallback string) (string, error) {
+	if value, err := readEnvOrFile(key); err != nil || value != "" {
+		return value, err
+	}
+	if provider != nil {
+		ctx, cancel := context.WithTimeout(ctx, secretTimeout)
+		defer cancel()
+		value, err := provider.GetSecret(ctx, key)
+		if err == nil {
+			return value, nil
+		}
+		if !errors.Is(err, ErrSecretNotFound) {
+			return "", fmt.Errorf("failed to read secret %s: %w", key, err)
+		}
+	}
+	return getEnv(key, fallback), nil
+}
+
+// readEnvOrFile returns the environment variable key, or the contents of the file
+// named by <key>_FILE, without the trailing newline
+func readEnvOrFile(key string) (string, error) {
+	if value := os.Getenv(key); value != "" {
+		return value, nil
+	}
+	path := os.Getenv(key + "_FILE")
+	if path == "" {
+		return "", nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s_FILE: %w", key, err)
+	}
+	return strings.TrimRight(string(content), "\r\n"), nil
+}
+
+// FileSecrets reads each secret from the file of the same name in Dir, as Docker and
+// Kubernetes mount them
+type FileSecrets struct {
+	Dir string
+}
+
+func (f FileSecrets) GetSecret(_ context.Context, name string) (string, error) {
+	content, err := os.ReadFile(filepath.Join(f.Dir, name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", ErrSecretNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(content), "\r\n"), nil
+}
+
+// secretMap loads a set of secrets once and serves them by name
+type secretMap struct {
+	once    sync.Once
+	secrets map[string]string
+	err     error
+}
+
+func (m *secretMap) get(ctx context.Context, name string, load func(ctx context.Context) (map[string]string, error)) (string, error) {
+	m.once.Do(func() { m.secrets, m.err = load(ctx) })
+	if m.err != nil {
+		return "", m.err
+	}
+	value, ok := m.secrets[name]
+	if !ok {
+		return "", ErrSecretNotFound
+	}
+	return value, nil
+}
+
+// AWSSecrets reads secrets from one AWS Secrets Manager secret holding a JSON object
+// of names to values. Requests are signed with the static credentials given.
+type AWSSecrets struct {
+	Endpoint        string
+	Region          string
+	SecretID        string
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	// Client is optional; http.DefaultClient is used when nil
+	Client *http.Client
+
+	secrets secretMap
+}
+
+func (a *AWSSecrets) GetSecret(ctx context.Context, name string) (string, error) {
+	return a.secrets.get(ctx, name, a.load)
+}
+
+func (a *AWSSecrets) load(ctx context.Context) (map[string]string, error) {
+	body, err := json.Marshal(map[string]string{"SecretId": a.SecretID})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(a.Endpoint, "/")+"/", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "secretsmanager.GetSecretValue")
+	awssig.Sign(req, body, awssig.Credentials{
+		AccessKeyID:     a.AccessKeyID,
+		SecretAccessKey: a.SecretAccessKey,
+		SessionToken:    a.SessionToken,
+	}, a.Region, "secretsmanager", time.Now())
+
+	var result struct {
+		SecretString string `json:"SecretString"`
+	}
+	if err := doJSON(a.Client, req, &result); err != nil {
+		return nil, fmt.Errorf("AWS Secrets Manager: %w", err)
+	}
+	var secrets map[string]string
+	if err := json.Unmarshal([]byte(result.SecretString), &secrets); err != nil {
+		return nil, fmt.Errorf("AWS secret %s is not a JSON object of strings: %w", a.SecretID, err)
+	}
+	return secrets, nil
+}
+
+// VaultSecrets reads secrets from one HashiCorp Vault KV version 2 secret, whose keys
+// are the secret names
+type VaultSecrets struct {
+	Addr  string
+	Token string
+	// Mount is where the KV engine is mounted, "secret" by default
+	Mount string
+	Path  string
+	// Client is optional; http.DefaultClient is used when nil
+	Client *http.Client
+
+	secrets secretMap
+}
+
+func (v *VaultSecrets) GetSecret(ctx context.Context, name string) (string, error) {
+	return v.secrets.get(ctx, name, v.load)
+}
+
+func (v *VaultSecrets) load(ctx context.Context) (map[string]string, error) {
+	endpoint := strings.TrimRight(v.Addr, "/") + "/v1/" + strings.Trim(v.Mount, "/") + "/data/" + strings.Trim(v.Path, "/")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", v.Token)
+
+	var result struct {
+		Data struct {
+			Data map[string]string `json:"data"`
+		} `json:"data"`
+	}
+	if err := doJSON(v.Client, req, &result); err != nil {
+		return nil, fmt.Errorf("Vault: %w", err)
+	}
+	return result.Data.Data, nil
+}
+
+// doJSON sends the request and decodes a 200 response into out
+func doJSON(client *http.Client, req *http.Request, out any) error {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("returned %d: %s", resp.StatusCode, bytes.TrimSpace(message))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,132 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// staticSecrets is a SecretProvider over a fixed map
+type staticSecrets map[string]string
+
+func (s staticSecrets) GetSecret(_ context.Context, name string) (string, error) {
+	if value, ok := s[name]; ok {
+		return value, nil
+	}
+	return "", ErrSecretNotFound
+}
+
+func TestGetSecretPrecedence(t *testing.T) {
+	provider := staticSecrets{"DB_PASSWORD": "from-provider"}
+
+	t.Run("Environment wins", func(t *testing.T) {
+		t.Setenv("DB_PASSWORD", "from-env")
+		value, err := getSecret(context.Background(), provider, "DB_PASSWORD", "default")
+		require.NoError(t, err)
+		assert.Equal(t, "from-env", value)
+	})
+
+	t.Run("File named by _FILE", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "db_password")
+		require.NoError(t, os.WriteFile(path, []byte("from-file\n"), 0o600))
+		t.Setenv("DB_PASSWORD", "")
+		t.Setenv("DB_PASSWORD_FILE", path)
+
+		value, err := getSecret(context.Background(), provider, "DB_PASSWORD", "default")
+		require.NoError(t, err)
+		assert.Equal(t, "from-file", value)
+	})
+
+	t.Run("Provider", func(t *testing.T) {
+		t.Setenv("DB_PASSWORD", "")
+		value, err := getSecret(context.Background(), provider, "DB_PASSWORD", "default")
+		require.NoError(t, err)
+		assert.Equal(t, "from-provider", value)
+	})
+
+	t.Run("Fallback", func(t *testing.T) {
+		value, err := getSecret(context.Background(), provider, "JWT_SECRET", "default")
+		require.NoError(t, err)
+		assert.Equal(t, "default", value)
+	})
+}
+
+func TestFileSecrets(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "JWT_SECRET"), []byte("s3cret\n"), 0o600))
+	provider := FileSecrets{Dir: dir}
+
+	value, err := provider.GetSecret(context.Background(), "JWT_SECRET")
+	require.NoError(t, err)
+	assert.Equal(t, "s3cret", value)
+
+	_, err = provider.GetSecret(context.Background(), "ADMIN_API_KEY")
+	assert.ErrorIs(t, err, ErrSecretNotFound)
+}
+
+func TestVaultSecrets(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		assert.Equal(t, "/v1/secret/data/wallet-app", r.URL.Path)
+		assert.Equal(t, "vault-token", r.Header.Get("X-Vault-Token"))
+		json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"data": map[string]string{"DB_PASSWORD": "from-vault"}}})
+	}))
+	defer server.Close()
+	provider := &VaultSecrets{Addr: server.URL, Token: "vault-token", Mount: "secret", Path: "wallet-app"}
+
+	value, err := provider.GetSecret(context.Background(), "DB_PASSWORD")
+	require.NoError(t, err)
+	assert.Equal(t, "from-vault", value)
+
+	_, err = provider.GetSecret(context.Background(), "JWT_SECRET")
+	assert.ErrorIs(t, err, ErrSecretNotFound)
+	assert.Equal(t, 1, requests)
+}
+
+func TestAWSSecrets(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "secretsmanager.GetSecretValue", r.Header.Get("X-Amz-Target"))
+		assert.True(t, strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/"))
+		assert.Contains(t, r.Header.Get("Authorization"), "/eu-west-1/secretsmanager/aws4_request")
+		assert.Equal(t, "session", r.Header.Get("X-Amz-Security-Token"))
+
+		var body map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, "wallet-app/prod", body["SecretId"])
+		json.NewEncoder(w).Encode(map[string]string{"SecretString": `{"ADMIN_API_KEY": "from-aws"}`})
+	}))
+	defer server.Close()
+	provider := &AWSSecrets{
+		Endpoint:        server.URL,
+		Region:          "eu-west-1",
+		SecretID:        "wallet-app/prod",
+		AccessKeyID:     "AKID",
+		SecretAccessKey: "secret",
+		SessionToken:    "session",
+	}
+
+	value, err := provider.GetSecret(context.Background(), "ADMIN_API_KEY")
+	require.NoError(t, err)
+	assert.Equal(t, "from-aws", value)
+}
+
+func TestAWSSecretsFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"__type": "AccessDeniedException"}`, http.StatusBadRequest)
+	}))
+	defer server.Close()
+	provider := &AWSSecrets{Endpoint: server.URL, Region: "eu-west-1", SecretID: "wallet-app/prod"}
+
+	_, err := getSecret(context.Background(), provider, "ADMIN_API_KEY", "")
+
+	assert.ErrorContains(t, err, "AccessDeniedException")
+}
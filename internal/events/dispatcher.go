@@ -0,0 +1,90 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/shanwije/wallet-app/internal/repository"
+	"github.com/shanwije/wallet-app/pkg/clock"
+	"github.com/shanwije/wallet-app/pkg/logger"
+)
+
+// defaultBatchSize bounds the events one dispatch claims
+const defaultBatchSize = 100
+
+// Dispatcher moves events from the outbox to a publisher. Several instances can run
+// at once: each claims its batch with row locks the others skip.
+type Dispatcher struct {
+	Repo      repository.OutboxRepository
+	Publisher Publisher
+	// Clock is optional; the system clock is used when nil
+	Clock clock.Clock
+	// BatchSize is optional and defaults to 100
+	BatchSize int
+}
+
+func (d *Dispatcher) batchSize() int {
+	if d.BatchSize <= 0 {
+		return defaultBatchSize
+	}
+	return d.BatchSize
+}
+
+// Run publishes pending events every interval until ctx is cancelled. A full batch
+// is followed straight away by the next, so a backlog drains without waiting.
+func (d *Dispatcher) Run(ctx context.Context, interval time.Duration) {
+	log := logger.FromContext(ctx)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		published, err := d.DispatchPending(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Error("Publishing outbox events failed", zap.Error(err))
+		}
+		if err == nil && published == d.batchSize() {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DispatchPending publishes one batch of unpublished events, oldest first, and returns
+// how many were published. It stops at the first event the publisher rejects so
+// later events are not published ahead of it; that event is retried next time.
+func (d *Dispatcher) DispatchPending(ctx context.Context) (int, error) {
+	published := 0
+	var publishErr error
+	err := repository.NewTxManager(d.Repo).WithinTransaction(ctx, func(ctx context.Context) error {
+		tx := repository.TxFromContext(ctx)
+		pending, err := d.Repo.ClaimUnpublishedWithTx(ctx, tx, d.batchSize())
+		if err != nil {
+			return err
+		}
+
+		for _, event := range pending {
+			if err := d.Publisher.Publish(ctx, event); err != nil {
+				publishErr = fmt.Errorf("failed to publish %s event %s: %w", event.Type, event.ID, err)
+				return d.Repo.RecordFailureWithTx(ctx, tx, event.ID, err.Error())
+			}
+			if err := d.Repo.MarkPublishedWithTx(ctx, tx, event.ID, clock.OrDefault(d.Clock).Now()); err != nil {
+				return err
+			}
+			published++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return published, publishErr
+}
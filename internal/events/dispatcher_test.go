@@ -0,0 +1,73 @@
+package events
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository/mocks"
+	"github.com/shanwije/wallet-app/pkg/clock"
+)
+
+// recordingPublisher keeps what it publishes and fails the events in reject
+type recordingPublisher struct {
+	published []uuid.UUID
+	reject    map[uuid.UUID]bool
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, event *models.OutboxEvent) error {
+	if p.reject[event.ID] {
+		return errors.New("broker unavailable")
+	}
+	p.published = append(p.published, event.ID)
+	return nil
+}
+
+func (p *recordingPublisher) Close() error { return nil }
+
+func TestDispatchPendingMarksPublishedEvents(t *testing.T) {
+	repo := new(mocks.OutboxRepository)
+	publisher := &recordingPublisher{}
+	now := time.Date(2024, 6, 26, 8, 0, 0, 0, time.UTC)
+	dispatcher := &Dispatcher{Repo: repo, Publisher: publisher, Clock: clock.NewFake(now), BatchSize: 10}
+
+	first, second := &models.OutboxEvent{ID: uuid.New()}, &models.OutboxEvent{ID: uuid.New()}
+	repo.On("BeginTx", mock.Anything).Return((*sql.Tx)(nil), nil)
+	repo.On("ClaimUnpublishedWithTx", mock.Anything, (*sql.Tx)(nil), 10).Return([]*models.OutboxEvent{first, second}, nil)
+	repo.On("MarkPublishedWithTx", mock.Anything, (*sql.Tx)(nil), first.ID, now).Return(nil)
+	repo.On("MarkPublishedWithTx", mock.Anything, (*sql.Tx)(nil), second.ID, now).Return(nil)
+
+	published, err := dispatcher.DispatchPending(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, published)
+	assert.Equal(t, []uuid.UUID{first.ID, second.ID}, publisher.published)
+	repo.AssertExpectations(t)
+}
+
+func TestDispatchPendingStopsAtFirstFailure(t *testing.T) {
+	repo := new(mocks.OutboxRepository)
+	first, second, third := &models.OutboxEvent{ID: uuid.New()}, &models.OutboxEvent{ID: uuid.New()}, &models.OutboxEvent{ID: uuid.New()}
+	publisher := &recordingPublisher{reject: map[uuid.UUID]bool{second.ID: true}}
+	dispatcher := &Dispatcher{Repo: repo, Publisher: publisher}
+
+	repo.On("BeginTx", mock.Anything).Return((*sql.Tx)(nil), nil)
+	repo.On("ClaimUnpublishedWithTx", mock.Anything, (*sql.Tx)(nil), defaultBatchSize).Return([]*models.OutboxEvent{first, second, third}, nil)
+	repo.On("MarkPublishedWithTx", mock.Anything, (*sql.Tx)(nil), first.ID, mock.Anything).Return(nil)
+	repo.On("RecordFailureWithTx", mock.Anything, (*sql.Tx)(nil), second.ID, "broker unavailable").Return(nil)
+
+	published, err := dispatcher.DispatchPending(context.Background())
+
+	assert.ErrorContains(t, err, "broker unavailable")
+	assert.Equal(t, 1, published)
+	assert.Equal(t, []uuid.UUID{first.ID}, publisher.published, "later events wait for the failed one")
+	repo.AssertExpectations(t)
+}
@@ -0,0 +1,82 @@
+// Package events publishes wallet events from the transactional outbox. Services
+// write an outbox row in the same transaction as each journal; a Dispatcher later
+// hands unpublished rows to a Publisher, so an event is published if and only if
+// its journal committed. Delivery is at least once: consumers should de-duplicate
+// on the envelope ID.
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/pkg/logger"
+)
+
+// Publisher delivers one event to a broker, returning only once the broker has it
+type Publisher interface {
+	Publish(ctx context.Context, event *models.OutboxEvent) error
+	Close() error
+}
+
+// Envelope is what publishers put on the wire
+type Envelope struct {
+	ID        uuid.UUID       `json:"id"`
+	Type      string          `json:"type"`
+	WalletID  uuid.UUID       `json:"wallet_id"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// NewEnvelope wraps an outbox event for publishing
+func NewEnvelope(event *models.OutboxEvent) Envelope {
+	return Envelope{
+		ID:        event.ID,
+		Type:      event.Type,
+		WalletID:  event.WalletID,
+		CreatedAt: event.CreatedAt,
+		Data:      event.Payload,
+	}
+}
+
+// Fanout publishes each event to every one of its publishers in turn, stopping at the
+// first that fails. The event is retried from the start, so the publishers before the
+// failing one get it again: like every consumer of the outbox they must tolerate
+// duplicates.
+type Fanout []Publisher
+
+func (f Fanout) Publish(ctx context.Context, event *models.OutboxEvent) error {
+	for _, publisher := range f {
+		if err := publisher.Publish(ctx, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f Fanout) Close() error {
+	var errs []error
+	for _, publisher := range f {
+		errs = append(errs, publisher.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// LogPublisher writes events to the log instead of a broker, for development
+type LogPublisher struct{}
+
+func (LogPublisher) Publish(ctx context.Context, event *models.OutboxEvent) error {
+	logger.FromContext(ctx).Info("Wallet event",
+		zap.String("event_id", event.ID.String()),
+		zap.String("type", event.Type),
+		zap.String("wallet_id", event.WalletID.String()),
+		zap.ByteString("data", event.Payload))
+	return nil
+}
+
+func (LogPublisher) Close() error { return nil }
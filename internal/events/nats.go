@@ -0,0 +1,170 @@
+package events
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shanwije/wallet-app/internal/models"
+)
+
+// natsTimeout bounds connecting and each publish round trip
+const natsTimeout = 5 * time.Second
+
+// NATSPublisher publishes each event on the subject named after its type, e.g.
+// wallet.deposit. It speaks the plain-text NATS client protocol over TCP and
+// follows every PUB with a PING, so a publish returns once the server has
+// processed it. The connection is opened on first use and reopened after errors.
+type NATSPublisher struct {
+	addr string
+	// auth holds the user, pass or auth_token fields sent with CONNECT
+	auth map[string]string
+
+	mu     sync.Mutex
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// NewNATSPublisher parses a nats://[user:pass@|token@]host[:port] URL
+func NewNATSPublisher(rawURL string) (*NATSPublisher, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "nats" || u.Hostname() == "" {
+		return nil, fmt.Errorf("invalid NATS URL %q", rawURL)
+	}
+
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), "4222")
+	}
+
+	auth := map[string]string{}
+	if u.User != nil {
+		if pass, ok := u.User.Password(); ok {
+			auth["user"], auth["pass"] = u.User.Username(), pass
+		} else {
+			auth["auth_token"] = u.User.Username()
+		}
+	}
+	return &NATSPublisher{addr: addr, auth: auth}, nil
+}
+
+func (p *NATSPublisher) Publish(ctx context.Context, event *models.OutboxEvent) error {
+	payload, err := json.Marshal(NewEnvelope(event))
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.conn == nil {
+		if err := p.connect(ctx); err != nil {
+			return err
+		}
+	}
+
+	p.conn.SetDeadline(deadline(ctx))
+	message := fmt.Sprintf("PUB %s %d\r\n%s\r\nPING\r\n", event.Type, len(payload), payload)
+	if _, err := p.conn.Write([]byte(message)); err != nil {
+		p.reset()
+		return fmt.Errorf("failed to write to NATS: %w", err)
+	}
+	if err := p.awaitPong(); err != nil {
+		p.reset()
+		return err
+	}
+	return nil
+}
+
+func (p *NATSPublisher) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.conn == nil {
+		return nil
+	}
+	err := p.conn.Close()
+	p.conn = nil
+	return err
+}
+
+// connect dials the server, reads its INFO and authenticates
+func (p *NATSPublisher) connect(ctx context.Context) error {
+	dialer := net.Dialer{Timeout: natsTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", p.addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect to NATS: %w", err)
+	}
+	p.conn, p.reader = conn, bufio.NewReader(conn)
+	p.conn.SetDeadline(deadline(ctx))
+
+	line, err := p.reader.ReadString('\n')
+	if err != nil || !strings.HasPrefix(line, "INFO ") {
+		p.reset()
+		return fmt.Errorf("unexpected NATS greeting %q: %v", strings.TrimSpace(line), err)
+	}
+
+	options := map[string]any{"verbose": false, "pedantic": false, "name": "wallet-app"}
+	for key, value := range p.auth {
+		options[key] = value
+	}
+	connect, err := json.Marshal(options)
+	if err != nil {
+		p.reset()
+		return fmt.Errorf("failed to encode NATS options: %w", err)
+	}
+	if _, err := fmt.Fprintf(p.conn, "CONNECT %s\r\nPING\r\n", connect); err != nil {
+		p.reset()
+		return fmt.Errorf("failed to write to NATS: %w", err)
+	}
+	if err := p.awaitPong(); err != nil {
+		p.reset()
+		return err
+	}
+	return nil
+}
+
+// awaitPong reads until the server answers our PING, answering its own PINGs and
+// failing on -ERR
+func (p *NATSPublisher) awaitPong() error {
+	for {
+		line, err := p.reader.ReadString('\n')
+		if err != nil {
+			return fmt.Errorf("failed to read from NATS: %w", err)
+		}
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "PONG":
+			return nil
+		case line == "PING":
+			if _, err := p.conn.Write([]byte("PONG\r\n")); err != nil {
+				return fmt.Errorf("failed to write to NATS: %w", err)
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			return fmt.Errorf("NATS error: %s", strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+		}
+	}
+}
+
+// reset drops the connection so the next publish reconnects
+func (p *NATSPublisher) reset() {
+	if p.conn != nil {
+		p.conn.Close()
+	}
+	p.conn, p.reader = nil, nil
+}
+
+// deadline is ctx's deadline, or natsTimeout from now if that is sooner
+func deadline(ctx context.Context) time.Time {
+	limit := time.Now().Add(natsTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(limit) {
+		return d
+	}
+	return limit
+}
@@ -0,0 +1,177 @@
+// Package export ships the audit log and the ledger to object storage as CSV files, a
+// batch at a time. Each stream keeps a checkpoint of the last row it shipped, so a
+// restart carries on where it stopped without dropping or repeating rows.
+package export
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/shanwije/wallet-app/internal/repository"
+	"github.com/shanwije/wallet-app/pkg/clock"
+	"github.com/shanwije/wallet-app/pkg/logger"
+)
+
+// defaultBatchSize bounds the rows of one exported file
+const defaultBatchSize = 10000
+
+// Uploader stores an object under key, replacing any already there
+type Uploader interface {
+	Upload(ctx context.Context, key, contentType string, body []byte) error
+}
+
+// Store is an Uploader that also hands out links to download its objects
+type Store interface {
+	Uploader
+	// DownloadURL returns a link to the object under key that needs no credentials
+	// and works until expires has passed
+	DownloadURL(key string, expires time.Duration) (string, error)
+}
+
+// Row is one exported row: its ID, which orders the stream, and its CSV fields
+type Row struct {
+	ID     uuid.UUID
+	Fields []string
+}
+
+// Stream is one kind of row that is exported, such as audit entries
+type Stream struct {
+	// Name names the stream's checkpoint and the folder its files go in
+	Name   string
+	Header []string
+	// Fetch returns up to limit rows in ID order after the row with ID after, leaving
+	// out rows created at or after until
+	Fetch func(ctx context.Context, after uuid.UUID, until time.Time, limit int) ([]Row, error)
+}
+
+// Exporter ships its streams through the uploader. The row IDs are time-ordered, so a
+// stream's checkpoint is the ID of the last row it shipped. A batch is uploaded under
+// a key numbered after the batches before it and only then checkpointed; a batch
+// retried after a crash replaces its own file rather than adding a second one.
+//
+// Only one instance should export at a time, as they would share the checkpoints.
+type Exporter struct {
+	Checkpoints repository.ExportCheckpointRepository
+	Uploader    Uploader
+	Streams     []Stream
+	// Prefix is put in front of every object key
+	Prefix string
+	// BatchSize is optional and defaults to 10000
+	BatchSize int
+	// Lag leaves rows younger than this for a later run, so rows whose transaction is
+	// still committing when a batch is read are not skipped
+	Lag time.Duration
+	// Clock is optional; the system clock is used when nil
+	Clock clock.Clock
+}
+
+func (e *Exporter) batchSize() int {
+	if e.BatchSize <= 0 {
+		return defaultBatchSize
+	}
+	return e.BatchSize
+}
+
+// Run exports every interval until ctx is cancelled
+func (e *Exporter) Run(ctx context.Context, interval time.Duration) {
+	log := logger.FromContext(ctx)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := e.Export(ctx); err != nil && ctx.Err() == nil {
+			log.Error("Export failed", zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Export ships every stream's rows since its checkpoint. A stream that fails does not
+// keep the others from exporting; it is retried from its checkpoint next time.
+func (e *Exporter) Export(ctx context.Context) error {
+	until := clock.OrDefault(e.Clock).Now().Add(-e.Lag)
+
+	var errs []error
+	for _, stream := range e.Streams {
+		if err := e.exportStream(ctx, stream, until); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", stream.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// exportStream ships the stream's rows created before until, a batch at a time
+func (e *Exporter) exportStream(ctx context.Context, stream Stream, until time.Time) error {
+	log := logger.FromContext(ctx).With(zap.String("stream", stream.Name))
+
+	checkpoint, err := e.Checkpoints.GetExportCheckpoint(ctx, stream.Name)
+	if err != nil {
+		return err
+	}
+
+	for {
+		rows, err := stream.Fetch(ctx, checkpoint.LastID, until, e.batchSize())
+		if err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+
+		body, err := encodeCSV(stream.Header, rows)
+		if err != nil {
+			return err
+		}
+		key := ObjectKey(e.Prefix, stream.Name, checkpoint.Batches+1)
+		if err := e.Uploader.Upload(ctx, key, "text/csv", body); err != nil {
+			return fmt.Errorf("failed to upload %s: %w", key, err)
+		}
+
+		checkpoint.LastID = rows[len(rows)-1].ID
+		checkpoint.Batches++
+		checkpoint.Rows += int64(len(rows))
+		if err := e.Checkpoints.SaveExportCheckpoint(ctx, checkpoint); err != nil {
+			return err
+		}
+		log.Info("Exported batch", zap.String("key", key), zap.Int("rows", len(rows)))
+
+		if len(rows) < e.batchSize() {
+			return nil
+		}
+	}
+}
+
+// ObjectKey returns where the stream's numbered batch is stored. Batch numbers are
+// zero-padded so the files list in the order they were written.
+func ObjectKey(prefix, stream string, batch int64) string {
+	return path.Join(prefix, stream, fmt.Sprintf("%010d.csv", batch))
+}
+
+// encodeCSV writes the header and rows as CSV
+func encodeCSV(header []string, rows []Row) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write(header)
+	for _, row := range rows {
+		w.Write(row.Fields)
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, fmt.Errorf("failed to encode CSV: %w", err)
+	}
+	return buf.Bytes(), nil
+}
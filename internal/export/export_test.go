@@ -0,0 +1,205 @@
+package export
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shanwije/wallet-app/db/migrations"
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository/sqlite"
+	"github.com/shanwije/wallet-app/pkg/awssig"
+	"github.com/shanwije/wallet-app/pkg/clock"
+	"github.com/shanwije/wallet-app/pkg/db"
+)
+
+// fakeUploader keeps uploaded objects in memory, failing while err is set
+type fakeUploader struct {
+	objects map[string][]byte
+	puts    []string
+	err     error
+}
+
+func (u *fakeUploader) Upload(ctx context.Context, key, contentType string, body []byte) error {
+	u.puts = append(u.puts, key)
+	if u.err != nil {
+		return u.err
+	}
+	if u.objects == nil {
+		u.objects = map[string][]byte{}
+	}
+	u.objects[key] = body
+	return nil
+}
+
+// rows returns the records of an uploaded file, without its header
+func (u *fakeUploader) rows(t *testing.T, key string) [][]string {
+	t.Helper()
+
+	body, ok := u.objects[key]
+	require.True(t, ok, "no object at %s", key)
+	records, err := csv.NewReader(strings.NewReader(string(body))).ReadAll()
+	require.NoError(t, err)
+	require.NotEmpty(t, records)
+	return records[1:]
+}
+
+// newAuditExporter returns an exporter of the audit log of a migrated SQLite database
+func newAuditExporter(t *testing.T, now time.Time) (*Exporter, *sqlite.AuditRepository, *fakeUploader) {
+	t.Helper()
+
+	conn, err := db.New(db.Config{Driver: db.DriverSQLite, Name: filepath.Join(t.TempDir(), "wallet.db")})
+	require.NoError(t, err)
+	fsys, err := migrations.ForDriver(db.DriverSQLite)
+	require.NoError(t, err)
+	migrator, err := db.NewMigrator(conn.DB, db.DriverSQLite, fsys)
+	require.NoError(t, err)
+	t.Cleanup(func() { migrator.Close() })
+	_, err = migrator.Up(context.Background())
+	require.NoError(t, err)
+
+	audit := sqlite.NewAuditRepository(conn.DB)
+	uploader := &fakeUploader{}
+	exporter := &Exporter{
+		Checkpoints: sqlite.NewExportCheckpointRepository(conn.DB),
+		Uploader:    uploader,
+		Streams:     []Stream{AuditStream(audit)},
+		Prefix:      "exports",
+		BatchSize:   2,
+		Lag:         time.Minute,
+		Clock:       clock.NewFake(now),
+	}
+	return exporter, audit, uploader
+}
+
+func audit(t *testing.T, repo *sqlite.AuditRepository, path string, at time.Time) {
+	t.Helper()
+
+	require.NoError(t, repo.CreateAuditEntry(context.Background(), &models.AuditEntry{
+		ActorType:  "admin",
+		ActorID:    "ops",
+		Endpoint:   "POST /api/v1/admin/test",
+		Path:       path,
+		StatusCode: http.StatusOK,
+		CreatedAt:  at,
+	}))
+}
+
+func TestExportShipsBatchesAndCarriesOnFromTheCheckpoint(t *testing.T) {
+	now := time.Date(2024, 7, 17, 12, 0, 0, 0, time.UTC)
+	exporter, repo, uploader := newAuditExporter(t, now)
+	ctx := context.Background()
+
+	for _, path := range []string{"/a", "/b", "/c"} {
+		audit(t, repo, path, now.Add(-time.Hour))
+	}
+	require.NoError(t, exporter.Export(ctx))
+
+	assert.Equal(t, []string{"exports/audit_log/0000000001.csv", "exports/audit_log/0000000002.csv"}, uploader.puts)
+	first := uploader.rows(t, "exports/audit_log/0000000001.csv")
+	require.Len(t, first, 2)
+	assert.Equal(t, "/a", first[0][4])
+	assert.Equal(t, "/b", first[1][4])
+	second := uploader.rows(t, "exports/audit_log/0000000002.csv")
+	require.Len(t, second, 1)
+	assert.Equal(t, "/c", second[0][4])
+
+	checkpoint, err := exporter.Checkpoints.GetExportCheckpoint(ctx, "audit_log")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), checkpoint.Batches)
+	assert.Equal(t, int64(3), checkpoint.Rows)
+	assert.Equal(t, second[0][0], checkpoint.LastID.String())
+
+	// Only the rows written since are shipped next time
+	audit(t, repo, "/d", now.Add(-time.Hour))
+	require.NoError(t, exporter.Export(ctx))
+	third := uploader.rows(t, "exports/audit_log/0000000003.csv")
+	require.Len(t, third, 1)
+	assert.Equal(t, "/d", third[0][4])
+
+	uploader.puts = nil
+	require.NoError(t, exporter.Export(ctx))
+	assert.Empty(t, uploader.puts, "nothing new to ship")
+}
+
+func TestExportRetriesAFailedBatchUnderTheSameKey(t *testing.T) {
+	now := time.Date(2024, 7, 17, 12, 0, 0, 0, time.UTC)
+	exporter, repo, uploader := newAuditExporter(t, now)
+	ctx := context.Background()
+
+	audit(t, repo, "/a", now.Add(-time.Hour))
+	uploader.err = errors.New("connection reset")
+	err := exporter.Export(ctx)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "audit_log")
+
+	checkpoint, err := exporter.Checkpoints.GetExportCheckpoint(ctx, "audit_log")
+	require.NoError(t, err)
+	assert.Zero(t, checkpoint.Batches, "a failed upload is not checkpointed")
+
+	uploader.err = nil
+	require.NoError(t, exporter.Export(ctx))
+	assert.Equal(t, []string{"exports/audit_log/0000000001.csv", "exports/audit_log/0000000001.csv"}, uploader.puts)
+	assert.Len(t, uploader.rows(t, "exports/audit_log/0000000001.csv"), 1)
+}
+
+func TestExportLeavesRecentRowsForLater(t *testing.T) {
+	now := time.Date(2024, 7, 17, 12, 0, 0, 0, time.UTC)
+	exporter, repo, uploader := newAuditExporter(t, now)
+	ctx := context.Background()
+
+	audit(t, repo, "/old", now.Add(-time.Hour))
+	audit(t, repo, "/recent", now.Add(-time.Second))
+	require.NoError(t, exporter.Export(ctx))
+	rows := uploader.rows(t, "exports/audit_log/0000000001.csv")
+	require.Len(t, rows, 1)
+	assert.Equal(t, "/old", rows[0][4])
+
+	exporter.Clock.(*clock.Fake).Advance(time.Minute)
+	require.NoError(t, exporter.Export(ctx))
+	rows = uploader.rows(t, "exports/audit_log/0000000002.csv")
+	require.Len(t, rows, 1)
+	assert.Equal(t, "/recent", rows[0][4])
+}
+
+func TestS3UploaderPutsSignedObjects(t *testing.T) {
+	var got *http.Request
+	var body []byte
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	uploader := &S3Uploader{
+		Endpoint:    server.URL,
+		Region:      "eu-west-1",
+		Bucket:      "ledger",
+		Credentials: awssig.Credentials{AccessKeyID: "AKID", SecretAccessKey: "secret"},
+	}
+	require.NoError(t, uploader.Upload(context.Background(), "exports/transactions/0000000001.csv", "text/csv", []byte("id\n")))
+
+	assert.Equal(t, http.MethodPut, got.Method)
+	assert.Equal(t, "/ledger/exports/transactions/0000000001.csv", got.URL.Path)
+	assert.Equal(t, "text/csv", got.Header.Get("Content-Type"))
+	assert.NotEmpty(t, got.Header.Get("X-Amz-Content-Sha256"))
+	assert.Contains(t, got.Header.Get("Authorization"), "Credential=AKID/")
+	assert.Equal(t, "id\n", string(body))
+
+	status = http.StatusForbidden
+	err := uploader.Upload(context.Background(), "exports/transactions/0000000002.csv", "text/csv", []byte("id\n"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "403")
+}
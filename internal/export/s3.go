@@ -0,0 +1,87 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/shanwije/wallet-app/pkg/awssig"
+)
+
+// S3Uploader puts objects into a bucket of Amazon S3 or an S3-compatible store, such
+// as MinIO, signing requests with static credentials. It also presigns links to
+// download them.
+type S3Uploader struct {
+	// Endpoint is optional and defaults to the region's S3 endpoint. Objects are
+	// addressed path-style, which S3-compatible stores expect.
+	Endpoint    string
+	Region      string
+	Bucket      string
+	Credentials awssig.Credentials
+	// Client is optional; a client with a 30 second timeout is used when nil
+	Client *http.Client
+}
+
+var s3Client = &http.Client{Timeout: 30 * time.Second}
+
+// maxDownloadExpiry is the longest S3 accepts a presigned URL for
+const maxDownloadExpiry = 7 * 24 * time.Hour
+
+// objectURL addresses the object stored under key
+func (u *S3Uploader) objectURL(key string) string {
+	endpoint := u.Endpoint
+	if endpoint == "" {
+		endpoint = "https://s3." + u.Region + ".amazonaws.com"
+	}
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.TrimRight(endpoint, "/") + "/" + url.PathEscape(u.Bucket) + "/" + strings.Join(segments, "/")
+}
+
+func (u *S3Uploader) Upload(ctx context.Context, key, contentType string, body []byte) error {
+	target := u.objectURL(key)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, target, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build S3 request: %w", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	// S3 requires the payload hash as a header as well as in the signature
+	payloadHash := sha256.Sum256(body)
+	req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(payloadHash[:]))
+	awssig.Sign(req, body, u.Credentials, u.Region, "s3", time.Now())
+
+	client := u.Client
+	if client == nil {
+		client = s3Client
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach S3: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		reason, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("S3 returned %d: %s", resp.StatusCode, bytes.TrimSpace(reason))
+	}
+	return nil
+}
+
+// DownloadURL presigns a link to get the object stored under key, which works until
+// expires has passed, for at most seven days
+func (u *S3Uploader) DownloadURL(key string, expires time.Duration) (string, error) {
+	target, err := url.Parse(u.objectURL(key))
+	if err != nil {
+		return "", fmt.Errorf("failed to build S3 URL: %w", err)
+	}
+	return awssig.Presign(http.MethodGet, target, u.Credentials, u.Region, "s3", time.Now(), min(expires, maxDownloadExpiry)), nil
+}
@@ -0,0 +1,116 @@
+package export
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+
+	"github.com/shanwije/wallet-app/internal/repository"
+	"github.com/shanwije/wallet-app/pkg/money"
+)
+
+// AuditStream exports the audit log
+func AuditStream(repo repository.AuditRepository) Stream {
+	return Stream{
+		Name: "audit_log",
+		Header: []string{"id", "actor_type", "actor_id", "endpoint", "path", "payload_hash", "status_code",
+			"request_id", "wallet_id", "balance_before", "balance_after", "created_at"},
+		Fetch: func(ctx context.Context, after uuid.UUID, until time.Time, limit int) ([]Row, error) {
+			entries, err := repo.ListAuditEntriesAfter(ctx, after, until, limit)
+			if err != nil {
+				return nil, err
+			}
+			rows := make([]Row, len(entries))
+			for i, entry := range entries {
+				rows[i] = Row{ID: entry.ID, Fields: []string{
+					entry.ID.String(),
+					entry.ActorType,
+					entry.ActorID,
+					entry.Endpoint,
+					entry.Path,
+					entry.PayloadHash,
+					strconv.Itoa(entry.StatusCode),
+					entry.RequestID,
+					optionalID(entry.WalletID),
+					optionalDecimal(entry.BalanceBefore),
+					optionalDecimal(entry.BalanceAfter),
+					timestamp(entry.CreatedAt),
+				}}
+			}
+			return rows, nil
+		},
+	}
+}
+
+// TransactionStream exports the ledger entries of every wallet
+func TransactionStream(repo repository.LedgerRepository) Stream {
+	return Stream{
+		Name: "transactions",
+		Header: []string{"id", "wallet_id", "type", "amount", "exchange_rate", "counter_amount", "counter_currency",
+			"reference_id", "reverses_reference_id", "corrected_by_reference_id", "correction_reason",
+			"description", "category", "created_at"},
+		Fetch: func(ctx context.Context, after uuid.UUID, until time.Time, limit int) ([]Row, error) {
+			transactions, err := repo.ListTransactionsAfter(ctx, after, until, limit)
+			if err != nil {
+				return nil, err
+			}
+			rows := make([]Row, len(transactions))
+			for i, t := range transactions {
+				rows[i] = Row{ID: t.ID, Fields: []string{
+					t.ID.String(),
+					t.WalletID.String(),
+					t.Type,
+					t.Amount.String(),
+					optionalDecimal(t.ExchangeRate),
+					optionalDecimal(t.CounterAmount),
+					optionalCurrency(t.CounterCurrency),
+					optionalID(t.ReferenceID),
+					optionalID(t.ReversesReferenceID),
+					optionalID(t.CorrectedByReferenceID),
+					optionalString(t.CorrectionReason),
+					optionalString(t.Description),
+					optionalString(t.Category),
+					timestamp(t.CreatedAt),
+				}}
+			}
+			return rows, nil
+		},
+	}
+}
+
+// The helpers below write a missing value as an empty field
+
+func optionalID(id *uuid.UUID) string {
+	if id == nil {
+		return ""
+	}
+	return id.String()
+}
+
+func optionalDecimal(d *decimal.Decimal) string {
+	if d == nil {
+		return ""
+	}
+	return d.String()
+}
+
+func optionalCurrency(c *money.Currency) string {
+	if c == nil {
+		return ""
+	}
+	return string(*c)
+}
+
+func optionalString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func timestamp(t time.Time) string {
+	return t.UTC().Format(time.RFC3339Nano)
+}
@@ -0,0 +1,135 @@
+// Package fees prices the fee charged on a money movement. A Schedule holds one rule
+// per operation type, either a percentage of the amount or a flat amount, read from
+// configuration.
+package fees
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/pkg/money"
+)
+
+// Rule prices the fee on one operation type: Rate is the fraction of the amount
+// charged, and Flat an amount charged in the movement's currency. A rule has one or
+// the other.
+type Rule struct {
+	Rate decimal.Decimal
+	Flat decimal.Decimal
+}
+
+// Fee returns the fee on amount, rounded to its currency's minor units
+func (r Rule) Fee(amount money.Money) money.Money {
+	if !r.Flat.IsZero() {
+		return money.New(r.Flat, amount.Currency()).Round()
+	}
+	return money.New(amount.Amount().Mul(r.Rate), amount.Currency()).Round()
+}
+
+// Net returns the most of total that can be moved with the fee on it paid out of the
+// rest, so the amount and its fee add up to no more than total
+func (r Rule) Net(total money.Money) money.Money {
+	places := total.Currency().MinorUnits()
+	unit := decimal.New(1, -places)
+	amount := total.Amount().Sub(r.Fee(total).Amount())
+	if r.Flat.IsZero() {
+		// Rounding the fee can leave room for a minor unit more than the exact share,
+		// or take it a minor unit over
+		amount = decimal.Min(total.Amount(), total.Amount().Div(decimal.NewFromInt(1).Add(r.Rate)).Truncate(places).Add(unit))
+	}
+	for amount.IsPositive() && amount.Add(r.Fee(money.New(amount, total.Currency())).Amount()).GreaterThan(total.Amount()) {
+		amount = amount.Sub(unit)
+	}
+	return money.New(amount, total.Currency())
+}
+
+// String writes the rule as it is configured, "1.5%" or "0.50"
+func (r Rule) String() string {
+	if !r.Flat.IsZero() {
+		return r.Flat.String()
+	}
+	return r.Rate.Shift(2).String() + "%"
+}
+
+// Schedule is the fee rule of each operation type that is charged one
+type Schedule struct {
+	rules map[string]Rule
+}
+
+// ParseSchedule reads a schedule written as comma-separated OPERATION=FEE entries,
+// where OPERATION is deposit, withdraw or transfer and FEE a percentage such as 1.5%
+// or a flat amount such as 0.50, for example "transfer=1%,withdraw=0.50"
+func ParseSchedule(spec string) (*Schedule, error) {
+	schedule := &Schedule{rules: make(map[string]Rule)}
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		operation, value, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid fee %q, want OPERATION=FEE", item)
+		}
+		operation = strings.TrimSpace(operation)
+		switch operation {
+		case models.JournalTypeDeposit, models.JournalTypeWithdraw, models.JournalTypeTransfer:
+		default:
+			return nil, fmt.Errorf("invalid fee %q: operation must be deposit, withdraw or transfer", item)
+		}
+		if _, ok := schedule.rules[operation]; ok {
+			return nil, fmt.Errorf("invalid fee %q: %s fee given twice", item, operation)
+		}
+
+		var rule Rule
+		value = strings.TrimSpace(value)
+		percent, isRate := strings.CutSuffix(value, "%")
+		amount, err := decimal.NewFromString(strings.TrimSpace(percent))
+		if err != nil || amount.IsNegative() {
+			return nil, fmt.Errorf("invalid fee %q: must be a percentage or amount of at least zero", item)
+		}
+		if isRate {
+			if amount.GreaterThanOrEqual(decimal.NewFromInt(100)) {
+				return nil, fmt.Errorf("invalid fee %q: percentage must be under 100%%", item)
+			}
+			rule.Rate = amount.Shift(-2)
+		} else {
+			rule.Flat = amount
+		}
+		schedule.rules[operation] = rule
+	}
+	return schedule, nil
+}
+
+// Rule returns the rule of the operation type and whether it is charged a fee. A nil
+// schedule charges none.
+func (s *Schedule) Rule(operation string) (Rule, bool) {
+	if s == nil {
+		return Rule{}, false
+	}
+	rule, ok := s.rules[operation]
+	return rule, ok
+}
+
+// Fee returns the fee on amount moved by the operation type, zero when it is not
+// charged one
+func (s *Schedule) Fee(operation string, amount money.Money) money.Money {
+	rule, ok := s.Rule(operation)
+	if !ok {
+		return money.Zero(amount.Currency())
+	}
+	return rule.Fee(amount)
+}
+
+// Net returns the most of total the operation type can move with its fee paid out of
+// the rest; all of it when it is not charged one
+func (s *Schedule) Net(operation string, total money.Money) money.Money {
+	rule, ok := s.Rule(operation)
+	if !ok {
+		return total
+	}
+	return rule.Net(total)
+}
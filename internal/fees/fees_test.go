@@ -0,0 +1,63 @@
+package fees
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shanwije/wallet-app/pkg/money"
+)
+
+func TestScheduleChargesPercentageOrFlatFees(t *testing.T) {
+	schedule, err := ParseSchedule("transfer=1.5%, withdraw=0.50")
+	require.NoError(t, err)
+
+	fee := schedule.Fee("transfer", money.New(decimal.RequireFromString("123.45"), money.USD))
+	assert.Equal(t, "1.85 USD", fee.String())
+	fee = schedule.Fee("transfer", money.New(decimal.NewFromInt(1000), money.JPY))
+	assert.Equal(t, "15 JPY", fee.String())
+	fee = schedule.Fee("withdraw", money.New(decimal.NewFromInt(20), money.EUR))
+	assert.Equal(t, "0.50 EUR", fee.String())
+	assert.True(t, schedule.Fee("deposit", money.New(decimal.NewFromInt(20), money.EUR)).IsZero())
+
+	rule, ok := schedule.Rule("transfer")
+	require.True(t, ok)
+	assert.Equal(t, "1.5%", rule.String())
+
+	var none *Schedule
+	assert.True(t, none.Fee("transfer", money.New(decimal.NewFromInt(20), money.USD)).IsZero())
+}
+
+func TestNetLeavesRoomForTheFee(t *testing.T) {
+	schedule, err := ParseSchedule("transfer=1.5%, withdraw=0.50")
+	require.NoError(t, err)
+
+	for _, tt := range []struct {
+		operation string
+		total     money.Money
+		want      string
+	}{
+		{"transfer", money.New(decimal.NewFromInt(100), money.USD), "98.52 USD"},
+		{"transfer", money.New(decimal.RequireFromString("0.01"), money.USD), "0.01 USD"},
+		{"transfer", money.New(decimal.NewFromInt(1000), money.JPY), "985 JPY"},
+		{"withdraw", money.New(decimal.NewFromInt(20), money.EUR), "19.50 EUR"},
+		{"withdraw", money.New(decimal.RequireFromString("0.30"), money.EUR), "-0.20 EUR"},
+		{"deposit", money.New(decimal.NewFromInt(20), money.EUR), "20.00 EUR"},
+	} {
+		net := schedule.Net(tt.operation, tt.total)
+		assert.Equal(t, tt.want, net.String(), tt.total.String())
+		if net.IsPositive() {
+			fee := schedule.Fee(tt.operation, net)
+			assert.False(t, net.Amount().Add(fee.Amount()).GreaterThan(tt.total.Amount()), tt.total.String())
+		}
+	}
+}
+
+func TestParseScheduleRejectsBadEntries(t *testing.T) {
+	for _, spec := range []string{"transfer", "refund=1%", "transfer=-1", "transfer=100%", "transfer=abc", "transfer=1%,transfer=2%"} {
+		_, err := ParseSchedule(spec)
+		assert.Error(t, err, spec)
+	}
+}
@@ -0,0 +1,88 @@
+// Package fx quotes exchange rates for transfers between wallets in different
+// currencies. An ExchangeRateProvider is pluggable: a static table from configuration
+// or an external rates API.
+package fx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/shanwije/wallet-app/pkg/money"
+)
+
+// rateScale is the number of decimal places rates derived by division are kept to
+const rateScale = 10
+
+// ErrRateUnavailable is returned when a provider has no rate for a currency pair
+var ErrRateUnavailable = errors.New("exchange rate unavailable")
+
+// ExchangeRateProvider quotes how many units of to one unit of from buys.
+// Implementations must be safe for concurrent use.
+type ExchangeRateProvider interface {
+	Rate(ctx context.Context, from, to money.Currency) (decimal.Decimal, error)
+}
+
+// Convert prices amount in currency to at rate, rounded to that currency's minor units
+func Convert(amount money.Money, rate decimal.Decimal, to money.Currency) money.Money {
+	return money.New(amount.Amount().Mul(rate), to).Round()
+}
+
+type pair struct {
+	from, to money.Currency
+}
+
+// StaticRates is a fixed table of rates. A pair missing from the table is quoted as
+// the inverse of the opposite pair when that one is present.
+type StaticRates struct {
+	rates map[pair]decimal.Decimal
+}
+
+// ParseStaticRates reads a table written as comma-separated FROM/TO=RATE entries,
+// for example "USD/EUR=0.92,GBP/USD=1.27"
+func ParseStaticRates(spec string) (*StaticRates, error) {
+	table := &StaticRates{rates: make(map[pair]decimal.Decimal)}
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		codes, value, ok := strings.Cut(item, "=")
+		fromCode, toCode, okPair := strings.Cut(codes, "/")
+		if !ok || !okPair {
+			return nil, fmt.Errorf("invalid rate %q, want FROM/TO=RATE", item)
+		}
+		from, err := money.ParseCurrency(fromCode)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rate %q: %w", item, err)
+		}
+		to, err := money.ParseCurrency(toCode)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rate %q: %w", item, err)
+		}
+		rate, err := decimal.NewFromString(strings.TrimSpace(value))
+		if err != nil || !rate.IsPositive() {
+			return nil, fmt.Errorf("invalid rate %q: must be a positive number", item)
+		}
+		table.rates[pair{from, to}] = rate
+	}
+	return table, nil
+}
+
+// Rate implements ExchangeRateProvider
+func (s *StaticRates) Rate(_ context.Context, from, to money.Currency) (decimal.Decimal, error) {
+	if from == to {
+		return decimal.NewFromInt(1), nil
+	}
+	if rate, ok := s.rates[pair{from, to}]; ok {
+		return rate, nil
+	}
+	if inverse, ok := s.rates[pair{to, from}]; ok {
+		return decimal.NewFromInt(1).DivRound(inverse, rateScale), nil
+	}
+	return decimal.Zero, fmt.Errorf("%w: %s to %s", ErrRateUnavailable, from, to)
+}
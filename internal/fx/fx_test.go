@@ -0,0 +1,92 @@
+package fx
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shanwije/wallet-app/pkg/clock"
+	"github.com/shanwije/wallet-app/pkg/money"
+)
+
+func TestStaticRates(t *testing.T) {
+	rates, err := ParseStaticRates("USD/EUR=0.92, EUR/GBP=0.85")
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	rate, err := rates.Rate(ctx, money.USD, money.EUR)
+	require.NoError(t, err)
+	assert.True(t, rate.Equal(decimal.RequireFromString("0.92")))
+
+	// The opposite pair is quoted as the inverse
+	rate, err = rates.Rate(ctx, money.GBP, money.EUR)
+	require.NoError(t, err)
+	assert.Equal(t, "1.1764705882", rate.String())
+
+	rate, err = rates.Rate(ctx, money.JPY, money.JPY)
+	require.NoError(t, err)
+	assert.True(t, rate.Equal(decimal.NewFromInt(1)))
+
+	_, err = rates.Rate(ctx, money.USD, money.JPY)
+	assert.ErrorIs(t, err, ErrRateUnavailable)
+}
+
+func TestParseStaticRatesRejectsBadEntries(t *testing.T) {
+	for _, spec := range []string{"USD-EUR=0.92", "USD/EUR", "USD/XYZ=1", "USD/EUR=0", "USD/EUR=abc"} {
+		_, err := ParseStaticRates(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestConvertRoundsToMinorUnits(t *testing.T) {
+	converted := Convert(money.New(decimal.RequireFromString("10.00"), money.USD), decimal.RequireFromString("157.4321"), money.JPY)
+
+	assert.Equal(t, money.JPY, converted.Currency())
+	assert.Equal(t, "1574", converted.Amount().String())
+}
+
+func TestHTTPRatesCachesQuotes(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		assert.Equal(t, "/latest", r.URL.Path)
+		assert.Equal(t, "USD", r.URL.Query().Get("from"))
+		assert.Equal(t, "EUR", r.URL.Query().Get("to"))
+		w.Write([]byte(`{"amount":1.0,"base":"USD","rates":{"EUR":0.9231}}`))
+	}))
+	defer server.Close()
+
+	fakeClock := clock.NewFake(time.Date(2024, 6, 29, 0, 0, 0, 0, time.UTC))
+	rates := NewHTTPRates(server.URL+"/", time.Minute)
+	rates.Clock = fakeClock
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		rate, err := rates.Rate(ctx, money.USD, money.EUR)
+		require.NoError(t, err)
+		assert.True(t, rate.Equal(decimal.RequireFromString("0.9231")))
+	}
+	assert.Equal(t, 1, requests)
+
+	fakeClock.Advance(2 * time.Minute)
+	_, err := rates.Rate(ctx, money.USD, money.EUR)
+	require.NoError(t, err)
+	assert.Equal(t, 2, requests, "an expired quote should be fetched again")
+}
+
+func TestHTTPRatesReportsMissingPair(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"rates":{}}`))
+	}))
+	defer server.Close()
+
+	_, err := NewHTTPRates(server.URL, time.Minute).Rate(context.Background(), money.USD, money.LKR)
+
+	assert.ErrorIs(t, err, ErrRateUnavailable)
+}
@@ -0,0 +1,105 @@
+package fx
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/shanwije/wallet-app/pkg/clock"
+	"github.com/shanwije/wallet-app/pkg/money"
+	"github.com/shanwije/wallet-app/pkg/requestid"
+)
+
+// HTTPRates quotes rates from an external API in the format Frankfurter and similar
+// services use: GET {base}/latest?from=USD&to=EUR answers {"rates":{"EUR":0.92}}.
+// Quotes are cached for TTL so a burst of transfers costs one request per pair.
+type HTTPRates struct {
+	BaseURL string
+	Client  *http.Client
+	TTL     time.Duration
+	// Clock is optional; the system clock is used when nil
+	Clock clock.Clock
+
+	mu    sync.Mutex
+	cache map[pair]quote
+}
+
+type quote struct {
+	rate      decimal.Decimal
+	fetchedAt time.Time
+}
+
+// NewHTTPRates creates a provider for the API at baseURL that reuses quotes for ttl
+func NewHTTPRates(baseURL string, ttl time.Duration) *HTTPRates {
+	return &HTTPRates{
+		BaseURL: strings.TrimRight(baseURL, "/"),
+		Client:  &http.Client{Timeout: 5 * time.Second, Transport: requestid.Transport(nil)},
+		TTL:     ttl,
+	}
+}
+
+// Rate implements ExchangeRateProvider
+func (h *HTTPRates) Rate(ctx context.Context, from, to money.Currency) (decimal.Decimal, error) {
+	if from == to {
+		return decimal.NewFromInt(1), nil
+	}
+
+	now := clock.OrDefault(h.Clock).Now()
+	key := pair{from, to}
+	h.mu.Lock()
+	cached, ok := h.cache[key]
+	h.mu.Unlock()
+	if ok && now.Sub(cached.fetchedAt) < h.TTL {
+		return cached.rate, nil
+	}
+
+	rate, err := h.fetch(ctx, from, to)
+	if err != nil {
+		return decimal.Zero, err
+	}
+
+	h.mu.Lock()
+	if h.cache == nil {
+		h.cache = make(map[pair]quote)
+	}
+	h.cache[key] = quote{rate: rate, fetchedAt: now}
+	h.mu.Unlock()
+	return rate, nil
+}
+
+func (h *HTTPRates) fetch(ctx context.Context, from, to money.Currency) (decimal.Decimal, error) {
+	query := url.Values{"from": {from.String()}, "to": {to.String()}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, h.BaseURL+"/latest?"+query.Encode(), nil)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := h.Client.Do(req)
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("%w: %w", ErrRateUnavailable, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return decimal.Zero, fmt.Errorf("%w: rates API returned %s", ErrRateUnavailable, resp.Status)
+	}
+
+	var body struct {
+		Rates map[string]decimal.Decimal `json:"rates"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return decimal.Zero, fmt.Errorf("%w: invalid rates response: %w", ErrRateUnavailable, err)
+	}
+	rate, ok := body.Rates[to.String()]
+	if !ok || !rate.IsPositive() {
+		return decimal.Zero, fmt.Errorf("%w: %s to %s", ErrRateUnavailable, from, to)
+	}
+	return rate, nil
+}
@@ -0,0 +1,24 @@
+// Package graphqlapi serves a read-only GraphQL view of users, wallets and their
+// transaction history, resolved through the same services as the REST API.
+package graphqlapi
+
+import (
+	_ "embed"
+	"net/http"
+
+	graphql "github.com/graph-gophers/graphql-go"
+	"github.com/graph-gophers/graphql-go/relay"
+
+	"github.com/shanwije/wallet-app/internal/service"
+)
+
+//go:embed schema.graphql
+var schema string
+
+// NewHandler returns an http.Handler answering GraphQL queries posted as JSON.
+// With requireOwner set, callers can only see their own user and wallet, so the
+// handler must sit behind the auth middleware.
+func NewHandler(users *service.UserService, wallets *service.WalletService, requireOwner bool) http.Handler {
+	root := &resolver{users: users, wallets: wallets, requireOwner: requireOwner}
+	return &relay.Handler{Schema: graphql.MustParseSchema(schema, root)}
+}
@@ -0,0 +1,78 @@
+package graphqlapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shanwije/wallet-app/internal/models"
+)
+
+func TestHandlerRejectsAnonymousCallers(t *testing.T) {
+	// Ownership is checked before any service is touched, so none are needed
+	handler := NewHandler(nil, nil, true)
+
+	body := `{"query":"{ user(id: \"` + uuid.NewString() + `\") { name } }"}`
+	req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"user":null`)
+	assert.Contains(t, rec.Body.String(), errForbidden.Error())
+}
+
+func testTransactions(n int) []*models.Transaction {
+	transactions := make([]*models.Transaction, n)
+	for i := range transactions {
+		transactions[i] = &models.Transaction{
+			ID:        uuid.New(),
+			Type:      models.TransactionTypeDeposit,
+			Amount:    decimal.NewFromInt(int64(i + 1)),
+			CreatedAt: time.Now(),
+		}
+	}
+	return transactions
+}
+
+func TestPaginateWalksPages(t *testing.T) {
+	transactions := testTransactions(5)
+
+	first, err := paginate(transactions, 2, nil)
+	require.NoError(t, err)
+	assert.Len(t, first.Edges(), 2)
+	assert.True(t, first.PageInfo().HasNextPage())
+
+	second, err := paginate(transactions, 2, first.PageInfo().EndCursor())
+	require.NoError(t, err)
+	assert.Equal(t, transactions[2].ID, second.page[0].ID)
+
+	last, err := paginate(transactions, 2, second.PageInfo().EndCursor())
+	require.NoError(t, err)
+	assert.Len(t, last.Edges(), 1)
+	assert.False(t, last.PageInfo().HasNextPage())
+}
+
+func TestPaginateRejectsBadArguments(t *testing.T) {
+	transactions := testTransactions(3)
+
+	_, err := paginate(transactions, 0, nil)
+	assert.ErrorIs(t, err, errInvalidPageSize)
+	_, err = paginate(transactions, maxPageSize+1, nil)
+	assert.ErrorIs(t, err, errInvalidPageSize)
+
+	garbage := "not-a-cursor"
+	_, err = paginate(transactions, 2, &garbage)
+	assert.ErrorIs(t, err, errInvalidCursor)
+
+	unknown := encodeCursor(uuid.New())
+	_, err = paginate(transactions, 2, &unknown)
+	assert.ErrorIs(t, err, errInvalidCursor)
+}
@@ -0,0 +1,237 @@
+package graphqlapi
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+
+	"github.com/google/uuid"
+	graphql "github.com/graph-gophers/graphql-go"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/service"
+	"github.com/shanwije/wallet-app/pkg/auth"
+)
+
+// maxPageSize caps the transactions returned by one page of a connection
+const maxPageSize = 100
+
+var (
+	errForbidden       = errors.New("you do not have access to this resource")
+	errWalletNotFound  = errors.New("wallet not found")
+	errInvalidCursor   = errors.New("invalid cursor")
+	errInvalidPageSize = errors.New("first must be between 1 and 100")
+)
+
+// resolver answers the root queries
+type resolver struct {
+	users   *service.UserService
+	wallets *service.WalletService
+	// requireOwner limits callers to their own user and wallet, as the REST routes do
+	requireOwner bool
+}
+
+// checkOwner rejects a caller who is not userID when ownership is enforced
+func (r *resolver) checkOwner(ctx context.Context, userID uuid.UUID) error {
+	if !r.requireOwner {
+		return nil
+	}
+	caller, ok := auth.UserIDFromContext(ctx)
+	if !ok || caller != userID {
+		return errForbidden
+	}
+	return nil
+}
+
+func (r *resolver) User(ctx context.Context, args struct{ ID graphql.ID }) (*userResolver, error) {
+	id, err := uuid.Parse(string(args.ID))
+	if err != nil {
+		return nil, nil
+	}
+	if err := r.checkOwner(ctx, id); err != nil {
+		return nil, err
+	}
+
+	user, err := r.users.GetUserWithWallet(ctx, id)
+	if errors.Is(err, service.ErrUserNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &userResolver{root: r, user: user}, nil
+}
+
+func (r *resolver) Wallet(ctx context.Context, args struct{ ID graphql.ID }) (*walletResolver, error) {
+	id, err := uuid.Parse(string(args.ID))
+	if err != nil {
+		return nil, nil
+	}
+
+	wallet, err := r.wallets.GetBalance(ctx, id)
+	if errors.Is(err, service.ErrWalletNotFound) {
+		return nil, errWalletNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := r.checkOwner(ctx, wallet.UserID); err != nil {
+		return nil, err
+	}
+	return &walletResolver{root: r, wallet: wallet}, nil
+}
+
+type userResolver struct {
+	root *resolver
+	user *models.UserWithWallet
+}
+
+func (u *userResolver) ID() graphql.ID          { return graphql.ID(u.user.ID.String()) }
+func (u *userResolver) Name() string            { return u.user.Name }
+func (u *userResolver) CreatedAt() graphql.Time { return graphql.Time{Time: u.user.CreatedAt} }
+
+// Wallet is null for users without one
+func (u *userResolver) Wallet() *walletResolver {
+	if u.user.Wallet.ID == uuid.Nil {
+		return nil
+	}
+	return &walletResolver{root: u.root, wallet: &u.user.Wallet}
+}
+
+type walletResolver struct {
+	root   *resolver
+	wallet *models.Wallet
+}
+
+func (w *walletResolver) ID() graphql.ID      { return graphql.ID(w.wallet.ID.String()) }
+func (w *walletResolver) UserID() graphql.ID  { return graphql.ID(w.wallet.UserID.String()) }
+func (w *walletResolver) Balance() string     { return w.wallet.Balance.StringFixed(2) }
+func (w *walletResolver) HeldBalance() string { return w.wallet.HeldBalance.StringFixed(2) }
+func (w *walletResolver) AvailableBalance() string {
+	return w.wallet.Available().Amount().StringFixed(2)
+}
+func (w *walletResolver) Currency() string        { return w.wallet.Currency.String() }
+func (w *walletResolver) Status() string          { return w.wallet.Status }
+func (w *walletResolver) CreatedAt() graphql.Time { return graphql.Time{Time: w.wallet.CreatedAt} }
+
+// User resolves the wallet's owner, who may since have been deleted
+func (w *walletResolver) User(ctx context.Context) (*userResolver, error) {
+	return w.root.User(ctx, struct{ ID graphql.ID }{ID: graphql.ID(w.wallet.UserID.String())})
+}
+
+type transactionsArgs struct {
+	First int32
+	After *string
+}
+
+func (w *walletResolver) Transactions(ctx context.Context, args transactionsArgs) (*connectionResolver, error) {
+	transactions, err := w.root.wallets.GetTransactionHistory(ctx, w.wallet.ID)
+	if err != nil {
+		return nil, err
+	}
+	return paginate(transactions, args.First, args.After)
+}
+
+// paginate slices the page of transactions that follows the after cursor
+func paginate(transactions []*models.Transaction, first int32, after *string) (*connectionResolver, error) {
+	if first < 1 || first > maxPageSize {
+		return nil, errInvalidPageSize
+	}
+
+	start := 0
+	if after != nil {
+		id, err := decodeCursor(*after)
+		if err != nil {
+			return nil, err
+		}
+		start = -1
+		for i, transaction := range transactions {
+			if transaction.ID == id {
+				start = i + 1
+				break
+			}
+		}
+		if start < 0 {
+			return nil, errInvalidCursor
+		}
+	}
+
+	end := min(start+int(first), len(transactions))
+	return &connectionResolver{page: transactions[start:end], hasNextPage: end < len(transactions)}, nil
+}
+
+// Cursors are opaque to clients; they wrap the ID of the last transaction seen
+func encodeCursor(id uuid.UUID) string {
+	return base64.RawURLEncoding.EncodeToString(id[:])
+}
+
+func decodeCursor(cursor string) (uuid.UUID, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return uuid.Nil, errInvalidCursor
+	}
+	id, err := uuid.FromBytes(raw)
+	if err != nil {
+		return uuid.Nil, errInvalidCursor
+	}
+	return id, nil
+}
+
+type connectionResolver struct {
+	page        []*models.Transaction
+	hasNextPage bool
+}
+
+func (c *connectionResolver) Edges() []*edgeResolver {
+	edges := make([]*edgeResolver, len(c.page))
+	for i, transaction := range c.page {
+		edges[i] = &edgeResolver{transaction: transaction}
+	}
+	return edges
+}
+
+func (c *connectionResolver) PageInfo() *pageInfoResolver {
+	info := &pageInfoResolver{hasNextPage: c.hasNextPage}
+	if len(c.page) > 0 {
+		cursor := encodeCursor(c.page[len(c.page)-1].ID)
+		info.endCursor = &cursor
+	}
+	return info
+}
+
+type edgeResolver struct {
+	transaction *models.Transaction
+}
+
+func (e *edgeResolver) Cursor() string { return encodeCursor(e.transaction.ID) }
+func (e *edgeResolver) Node() *transactionResolver {
+	return &transactionResolver{transaction: e.transaction}
+}
+
+type pageInfoResolver struct {
+	hasNextPage bool
+	endCursor   *string
+}
+
+func (p *pageInfoResolver) HasNextPage() bool  { return p.hasNextPage }
+func (p *pageInfoResolver) EndCursor() *string { return p.endCursor }
+
+type transactionResolver struct {
+	transaction *models.Transaction
+}
+
+func (t *transactionResolver) ID() graphql.ID       { return graphql.ID(t.transaction.ID.String()) }
+func (t *transactionResolver) Type() string         { return t.transaction.Type }
+func (t *transactionResolver) Amount() string       { return t.transaction.Amount.StringFixed(2) }
+func (t *transactionResolver) Description() *string { return t.transaction.Description }
+func (t *transactionResolver) CreatedAt() graphql.Time {
+	return graphql.Time{Time: t.transaction.CreatedAt}
+}
+
+func (t *transactionResolver) ReferenceID() *graphql.ID {
+	if t.transaction.ReferenceID == nil {
+		return nil
+	}
+	id := graphql.ID(t.transaction.ReferenceID.String())
+	return &id
+}
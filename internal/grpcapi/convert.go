@@ -0,0 +1,87 @@
+package grpcapi
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/pkg/money"
+	walletv1 "github.com/shanwije/wallet-app/pkg/pb/wallet/v1"
+)
+
+func parseWalletID(id string) (uuid.UUID, error) {
+	walletID, err := uuid.Parse(id)
+	if err != nil {
+		return uuid.Nil, status.Error(codes.InvalidArgument, "Invalid wallet ID")
+	}
+	return walletID, nil
+}
+
+// parseAmount converts a decimal string amount into Money, with the same currency
+// default and precision rules as the HTTP API
+func parseAmount(amount, currency string) (money.Money, error) {
+	c := money.DefaultCurrency
+	if currency != "" {
+		parsed, err := money.ParseCurrency(currency)
+		if err != nil {
+			return money.Money{}, status.Errorf(codes.InvalidArgument, "Unsupported currency %q", currency)
+		}
+		c = parsed
+	}
+
+	value, err := decimal.NewFromString(amount)
+	if err != nil {
+		return money.Money{}, status.Error(codes.InvalidArgument, "Invalid amount")
+	}
+
+	m := money.New(value, c)
+	if !m.HasValidPrecision() {
+		return money.Money{}, status.Error(codes.InvalidArgument,
+			fmt.Sprintf("Amount must have at most %d decimal places for %s", c.MinorUnits(), c))
+	}
+
+	return m, nil
+}
+
+func toProtoUser(user *models.UserWithWallet) *walletv1.User {
+	return &walletv1.User{
+		Id:        user.ID.String(),
+		Name:      user.Name,
+		Wallet:    toProtoWallet(&user.Wallet),
+		CreatedAt: timestamppb.New(user.CreatedAt),
+	}
+}
+
+func toProtoWallet(wallet *models.Wallet) *walletv1.Wallet {
+	return &walletv1.Wallet{
+		Id:          wallet.ID.String(),
+		UserId:      wallet.UserID.String(),
+		Balance:     wallet.Balance.String(),
+		Currency:    wallet.Currency.String(),
+		CreatedAt:   timestamppb.New(wallet.CreatedAt),
+		Status:      wallet.Status,
+		HeldBalance: wallet.HeldBalance.String(),
+	}
+}
+
+func toProtoTransaction(transaction *models.Transaction) *walletv1.Transaction {
+	pb := &walletv1.Transaction{
+		Id:        transaction.ID.String(),
+		WalletId:  transaction.WalletID.String(),
+		Type:      transaction.Type,
+		Amount:    transaction.Amount.String(),
+		CreatedAt: timestamppb.New(transaction.CreatedAt),
+	}
+	if transaction.ReferenceID != nil {
+		pb.ReferenceId = transaction.ReferenceID.String()
+	}
+	if transaction.Description != nil {
+		pb.Description = *transaction.Description
+	}
+	return pb
+}
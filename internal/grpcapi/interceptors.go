@@ -0,0 +1,185 @@
+package grpcapi
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/service"
+	"github.com/shanwije/wallet-app/pkg/auth"
+	"github.com/shanwije/wallet-app/pkg/logger"
+	walletv1 "github.com/shanwije/wallet-app/pkg/pb/wallet/v1"
+)
+
+// walletScopedRequest is implemented by every request that operates on one wallet
+type walletScopedRequest interface {
+	GetWalletId() string
+}
+
+// requestIDInterceptor puts log in the context, tagged with the caller's x-request-id
+// or a new one
+func requestIDInterceptor(log *zap.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		requestID := firstMetadata(ctx, "x-request-id")
+		if requestID == "" {
+			requestID = uuid.New().String()
+		}
+		grpc.SetHeader(ctx, metadata.Pairs("x-request-id", requestID))
+
+		return handler(logger.WithRequestID(logger.NewContext(ctx, log), requestID), req)
+	}
+}
+
+// loggingInterceptor logs every call with its outcome and duration
+func loggingInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+
+	logger.FromContext(ctx).Info("gRPC call",
+		zap.String("method", info.FullMethod),
+		zap.String("code", status.Code(err).String()),
+		zap.Duration("duration", time.Since(start)))
+
+	return resp, err
+}
+
+// timeoutInterceptor gives every call a deadline of at most timeout, so a slow query
+// cannot hold a call open longer. A shorter deadline set by the client is kept.
+func timeoutInterceptor(timeout time.Duration) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		return handler(ctx, req)
+	}
+}
+
+// authInterceptor requires a bearer token in the authorization metadata for wallet
+// RPCs and only lets callers operate on wallets they own. CreateUser stays open,
+// matching POST /users on the HTTP API.
+func authInterceptor(tokens *auth.TokenManager, walletService *service.WalletService) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if info.FullMethod == walletv1.WalletService_CreateUser_FullMethodName {
+			return handler(ctx, req)
+		}
+
+		token, found := strings.CutPrefix(firstMetadata(ctx, "authorization"), "Bearer ")
+		if !found || token == "" {
+			return nil, status.Error(codes.Unauthenticated, "Missing bearer token")
+		}
+
+		userID, err := tokens.Verify(token)
+		if err != nil {
+			logger.FromContext(ctx).Warn("Rejected access token", zap.Error(err))
+			return nil, status.Error(codes.Unauthenticated, "Invalid or expired token")
+		}
+
+		if scoped, ok := req.(walletScopedRequest); ok {
+			walletID, err := parseWalletID(scoped.GetWalletId())
+			if err != nil {
+				return nil, err
+			}
+
+			wallet, err := walletService.GetBalance(ctx, walletID)
+			if errors.Is(err, service.ErrWalletNotFound) {
+				return nil, status.Error(codes.NotFound, "Wallet not found")
+			}
+			if err != nil {
+				return nil, internalError(err)
+			}
+			if wallet.UserID != userID {
+				logger.FromContext(ctx).Warn("Wallet access denied",
+					zap.String("wallet_id", walletID.String()),
+					zap.String("user_id", userID.String()))
+				return nil, status.Error(codes.PermissionDenied, "You do not have access to this wallet")
+			}
+		}
+
+		return handler(auth.WithUserID(ctx, userID), req)
+	}
+}
+
+func firstMetadata(ctx context.Context, key string) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// auditedMethods are the RPCs that change state and so are written to the audit log
+var auditedMethods = map[string]bool{
+	walletv1.WalletService_CreateUser_FullMethodName: true,
+	walletv1.WalletService_Deposit_FullMethodName:    true,
+	walletv1.WalletService_Withdraw_FullMethodName:   true,
+	walletv1.WalletService_Transfer_FullMethodName:   true,
+}
+
+// auditInterceptor writes mutating RPCs to the audit log, as AuditMiddleware does for
+// HTTP. The payload hash covers the deterministic protobuf encoding of the request and
+// the status is the gRPC code. It runs after authInterceptor so the caller is known.
+func auditInterceptor(audit *service.AuditService) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if !auditedMethods[info.FullMethod] {
+			return handler(ctx, req)
+		}
+
+		entry := &models.AuditEntry{
+			Endpoint:  info.FullMethod,
+			Path:      info.FullMethod,
+			RequestID: firstMetadata(ctx, "x-request-id"),
+		}
+		if message, ok := req.(proto.Message); ok {
+			encoded, _ := proto.MarshalOptions{Deterministic: true}.Marshal(message)
+			digest := sha256.Sum256(encoded)
+			entry.PayloadHash = hex.EncodeToString(digest[:])
+		}
+		entry.ActorType = models.AuditActorAnonymous
+		if userID, ok := auth.UserIDFromContext(ctx); ok {
+			entry.ActorType, entry.ActorID = models.AuditActorUser, userID.String()
+		} else if client, ok := peer.FromContext(ctx); ok {
+			entry.ActorID = client.Addr.String()
+			if host, _, err := net.SplitHostPort(entry.ActorID); err == nil {
+				entry.ActorID = host
+			}
+		}
+
+		auditCtx := context.WithoutCancel(ctx)
+		if scoped, ok := req.(walletScopedRequest); ok {
+			if walletID, err := uuid.Parse(scoped.GetWalletId()); err == nil {
+				entry.WalletID = &walletID
+				entry.BalanceBefore = audit.WalletBalance(auditCtx, walletID)
+			}
+		}
+
+		resp, err := handler(ctx, req)
+
+		entry.StatusCode = int(status.Code(err))
+		if entry.WalletID != nil {
+			entry.BalanceAfter = audit.WalletBalance(auditCtx, *entry.WalletID)
+		}
+		if recordErr := audit.Record(auditCtx, entry); recordErr != nil {
+			logger.FromContext(ctx).Error("Failed to write audit entry",
+				zap.Error(recordErr),
+				zap.String("endpoint", entry.Endpoint),
+				zap.String("actor_id", entry.ActorID))
+		}
+		return resp, err
+	}
+}
@@ -0,0 +1,245 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/shanwije/wallet-app/internal/fx"
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/service"
+	"github.com/shanwije/wallet-app/pkg/auth"
+	"github.com/shanwije/wallet-app/pkg/logger"
+	"github.com/shanwije/wallet-app/pkg/money"
+	walletv1 "github.com/shanwije/wallet-app/pkg/pb/wallet/v1"
+)
+
+// WalletServer implements the gRPC WalletService on top of the same services as the HTTP API
+type WalletServer struct {
+	walletv1.UnimplementedWalletServiceServer
+
+	UserService   *service.UserService
+	WalletService *service.WalletService
+	// PINs asks for the owner's transaction PIN on withdrawals and transfers over its
+	// threshold; nil asks for none
+	PINs *service.TransactionPINService
+}
+
+// transactionPINMetadata carries the owner's transaction PIN, as the X-Transaction-PIN
+// header does over HTTP
+const transactionPINMetadata = "x-transaction-pin"
+
+// NewServer creates a gRPC server exposing the wallet operations, logging calls to log.
+// When tokens is non-nil, wallet RPCs require a bearer token for the wallet's owner, as
+// over HTTP. When pins is non-nil, withdrawals and transfers over its threshold need the
+// owner's PIN in the x-transaction-pin metadata. When audit is non-nil, mutating RPCs
+// are written to the audit log. A positive timeout caps the deadline of every call.
+// Options, such as TLS credentials, are passed on to the gRPC server.
+func NewServer(log *zap.Logger, userService *service.UserService, walletService *service.WalletService, pins *service.TransactionPINService, tokens *auth.TokenManager, audit *service.AuditService, timeout time.Duration, opts ...grpc.ServerOption) *grpc.Server {
+	interceptors := []grpc.UnaryServerInterceptor{requestIDInterceptor(log), loggingInterceptor}
+	if timeout > 0 {
+		interceptors = append(interceptors, timeoutInterceptor(timeout))
+	}
+	if tokens != nil {
+		interceptors = append(interceptors, authInterceptor(tokens, walletService))
+	}
+	if audit != nil {
+		interceptors = append(interceptors, auditInterceptor(audit))
+	}
+
+	server := grpc.NewServer(append(opts, grpc.ChainUnaryInterceptor(interceptors...))...)
+	walletv1.RegisterWalletServiceServer(server, &WalletServer{
+		UserService:   userService,
+		WalletService: walletService,
+		PINs:          pins,
+	})
+	return server
+}
+
+func (s *WalletServer) CreateUser(ctx context.Context, req *walletv1.CreateUserRequest) (*walletv1.CreateUserResponse, error) {
+	log := logger.FromContext(ctx)
+
+	if req.GetName() == "" {
+		return nil, status.Error(codes.InvalidArgument, "Name is required")
+	}
+
+	user, err := s.UserService.CreateUser(ctx, req.GetName())
+	if err != nil {
+		log.Error("Failed to create user", zap.Error(err), zap.String("name", req.GetName()))
+		return nil, status.Error(codes.Internal, "Failed to create user")
+	}
+
+	return &walletv1.CreateUserResponse{User: toProtoUser(user)}, nil
+}
+
+func (s *WalletServer) Deposit(ctx context.Context, req *walletv1.DepositRequest) (*walletv1.DepositResponse, error) {
+	walletID, err := parseWalletID(req.GetWalletId())
+	if err != nil {
+		return nil, err
+	}
+
+	amount, err := parseAmount(req.GetAmount(), req.GetCurrency())
+	if err != nil {
+		return nil, err
+	}
+
+	wallet, err := s.WalletService.Deposit(ctx, walletID, amount, req.GetIdempotencyKey())
+	if err != nil {
+		logger.FromContext(ctx).Error("Deposit failed", zap.Error(err), zap.String("wallet_id", walletID.String()))
+		return nil, movementError(err)
+	}
+
+	return &walletv1.DepositResponse{Wallet: toProtoWallet(wallet)}, nil
+}
+
+func (s *WalletServer) Withdraw(ctx context.Context, req *walletv1.WithdrawRequest) (*walletv1.WithdrawResponse, error) {
+	walletID, err := parseWalletID(req.GetWalletId())
+	if err != nil {
+		return nil, err
+	}
+
+	amount, err := parseAmount(req.GetAmount(), req.GetCurrency())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.authorizePIN(ctx, walletID, amount); err != nil {
+		return nil, err
+	}
+
+	wallet, err := s.WalletService.Withdraw(ctx, walletID, amount, req.GetIdempotencyKey())
+	if err != nil {
+		logger.FromContext(ctx).Error("Withdrawal failed", zap.Error(err), zap.String("wallet_id", walletID.String()))
+		return nil, movementError(err)
+	}
+
+	return &walletv1.WithdrawResponse{Wallet: toProtoWallet(wallet)}, nil
+}
+
+func (s *WalletServer) Transfer(ctx context.Context, req *walletv1.TransferRequest) (*walletv1.TransferResponse, error) {
+	fromWalletID, err := parseWalletID(req.GetWalletId())
+	if err != nil {
+		return nil, err
+	}
+
+	toWalletID, err := uuid.Parse(req.GetToWalletId())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "Invalid destination wallet ID")
+	}
+
+	amount, err := parseAmount(req.GetAmount(), req.GetCurrency())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.authorizePIN(ctx, fromWalletID, amount); err != nil {
+		return nil, err
+	}
+
+	if err := s.WalletService.Transfer(ctx, fromWalletID, toWalletID, amount, req.GetDescription(), req.GetIdempotencyKey()); err != nil {
+		logger.FromContext(ctx).Error("Transfer failed", zap.Error(err), zap.String("wallet_id", fromWalletID.String()))
+		return nil, movementError(err)
+	}
+
+	return &walletv1.TransferResponse{}, nil
+}
+
+// authorizePIN checks the PIN in the call's metadata before amount leaves the wallet
+func (s *WalletServer) authorizePIN(ctx context.Context, walletID uuid.UUID, amount money.Money) error {
+	err := s.PINs.Authorize(ctx, walletID, amount, firstMetadata(ctx, transactionPINMetadata))
+	if err == nil {
+		return nil
+	}
+
+	logger.FromContext(ctx).Warn("Transaction PIN check failed", zap.Error(err),
+		zap.String("wallet_id", walletID.String()),
+		zap.String("amount", amount.String()))
+	return movementError(err)
+}
+
+func (s *WalletServer) GetBalance(ctx context.Context, req *walletv1.GetBalanceRequest) (*walletv1.GetBalanceResponse, error) {
+	walletID, err := parseWalletID(req.GetWalletId())
+	if err != nil {
+		return nil, err
+	}
+
+	wallet, err := s.WalletService.GetBalance(ctx, walletID)
+	if err != nil {
+		return nil, lookupError(err)
+	}
+
+	return &walletv1.GetBalanceResponse{Wallet: toProtoWallet(wallet)}, nil
+}
+
+func (s *WalletServer) ListTransactions(ctx context.Context, req *walletv1.ListTransactionsRequest) (*walletv1.ListTransactionsResponse, error) {
+	walletID, err := parseWalletID(req.GetWalletId())
+	if err != nil {
+		return nil, err
+	}
+
+	transactions, err := s.WalletService.GetTransactionHistory(ctx, walletID)
+	if err != nil {
+		return nil, lookupError(err)
+	}
+
+	resp := &walletv1.ListTransactionsResponse{Transactions: make([]*walletv1.Transaction, 0, len(transactions))}
+	for _, transaction := range transactions {
+		resp.Transactions = append(resp.Transactions, toProtoTransaction(transaction))
+	}
+	return resp, nil
+}
+
+// lookupError maps a failure to load a wallet to NotFound when it does not exist
+func lookupError(err error) error {
+	if errors.Is(err, service.ErrWalletNotFound) {
+		return status.Error(codes.NotFound, err.Error())
+	}
+	return internalError(err)
+}
+
+// internalError maps an unexpected failure to Internal, or to DeadlineExceeded when the
+// call ran out of time
+func internalError(err error) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
+// movementError maps a failed deposit, withdrawal or transfer to a gRPC status
+func movementError(err error) error {
+	switch {
+	case errors.Is(err, service.ErrWalletNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, service.ErrInsufficientBalance):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, fx.ErrRateUnavailable):
+		return status.Error(codes.Unavailable, err.Error())
+	case errors.Is(err, service.ErrIdempotencyKeyReused):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, service.ErrConcurrentUpdate):
+		return status.Error(codes.Aborted, err.Error())
+	case errors.Is(err, models.ErrWalletFrozen), errors.Is(err, models.ErrWalletClosed):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, service.ErrLimitExceeded):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	case errors.Is(err, service.ErrBlockedByRiskCheck),
+		errors.Is(err, service.ErrPINRequired),
+		errors.Is(err, service.ErrIncorrectPIN):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, service.ErrPINLocked):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	case errors.Is(err, service.ErrFeatureDisabled),
+		errors.Is(err, service.ErrConfirmationRequired):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	}
+	return status.Error(codes.InvalidArgument, err.Error())
+}
@@ -0,0 +1,112 @@
+package grpcapi
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository/mocks"
+	"github.com/shanwije/wallet-app/internal/service"
+	"github.com/shanwije/wallet-app/pkg/auth"
+	"github.com/shanwije/wallet-app/pkg/money"
+	walletv1 "github.com/shanwije/wallet-app/pkg/pb/wallet/v1"
+)
+
+// newTestClient serves a WalletServer over an in-memory connection; the user and wallet
+// services are nil, so only calls rejected before reaching them can be exercised
+func newTestClient(t *testing.T, tokens *auth.TokenManager, pins *service.TransactionPINService) walletv1.WalletServiceClient {
+	listener := bufconn.Listen(1024 * 1024)
+	server := NewServer(zap.NewNop(), nil, nil, pins, tokens, nil, 0)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return walletv1.NewWalletServiceClient(conn)
+}
+
+func TestWalletRPCsRequireToken(t *testing.T) {
+	tokens := auth.NewTokenManager("test-secret-test-secret-test-secret", time.Hour, nil)
+	client := newTestClient(t, tokens, nil)
+
+	_, err := client.GetBalance(context.Background(), &walletv1.GetBalanceRequest{WalletId: uuid.New().String()})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	// CreateUser is open, so it reaches request validation
+	_, err = client.CreateUser(context.Background(), &walletv1.CreateUserRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestDepositRejectsInvalidInput(t *testing.T) {
+	client := newTestClient(t, nil, nil)
+
+	_, err := client.Deposit(context.Background(), &walletv1.DepositRequest{WalletId: "not-a-uuid", Amount: "10"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = client.Deposit(context.Background(), &walletv1.DepositRequest{WalletId: uuid.New().String(), Amount: "10.001"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestLargeMovementsNeedTheTransactionPIN(t *testing.T) {
+	walletRepo := new(mocks.WalletRepository)
+	pinRepo := new(mocks.TransactionPINRepository)
+	threshold := decimal.NewFromInt(100)
+	client := newTestClient(t, nil, &service.TransactionPINService{Repo: pinRepo, WalletRepo: walletRepo, Threshold: &threshold})
+
+	walletID, userID := uuid.New(), uuid.New()
+	hash, err := auth.HashPIN("4821")
+	require.NoError(t, err)
+	walletRepo.On("GetWalletByID", mock.Anything, walletID).Return(&models.Wallet{ID: walletID, UserID: userID}, nil)
+	pinRepo.On("GetTransactionPIN", mock.Anything, userID).Return(&models.TransactionPIN{UserID: userID, PINHash: hash}, nil)
+	pinRepo.On("RecordFailedPINAttempt", mock.Anything, userID, mock.Anything).Return(1, nil)
+
+	_, err = client.Withdraw(context.Background(), &walletv1.WithdrawRequest{WalletId: walletID.String(), Amount: "150"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	assert.ErrorContains(t, err, "transaction PIN required")
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-transaction-pin", "0000")
+	_, err = client.Transfer(ctx, &walletv1.TransferRequest{WalletId: walletID.String(), ToWalletId: uuid.NewString(), Amount: "150"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	assert.ErrorContains(t, err, "incorrect transaction PIN")
+}
+
+func TestParseAmount(t *testing.T) {
+	m, err := parseAmount("10.50", "")
+	assert.NoError(t, err)
+	assert.Equal(t, money.USD, m.Currency())
+	assert.Equal(t, "10.5", m.Amount().String())
+
+	m, err = parseAmount("1.234", "KWD")
+	assert.NoError(t, err)
+	assert.Equal(t, money.KWD, m.Currency())
+
+	_, err = parseAmount("100.5", "JPY")
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = parseAmount("ten", "USD")
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = parseAmount("10", "XYZ")
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"github.com/shanwije/wallet-app/pkg/auth"
+	"github.com/shanwije/wallet-app/pkg/errors"
+	"github.com/shanwije/wallet-app/pkg/logger"
+	"github.com/shanwije/wallet-app/pkg/response"
+)
+
+// AdminKeyHeader carries the shared secret for operator endpoints
+const AdminKeyHeader = "X-Admin-Key"
+
+// AdminKeyMiddleware requires the X-Admin-Key header to match key, and records a
+// fingerprint of the key in the request context to identify the caller
+func AdminKeyMiddleware(key string) func(http.Handler) http.Handler {
+	digest := sha256.Sum256([]byte(key))
+	keyID := "key:" + hex.EncodeToString(digest[:])[:12]
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			given := r.Header.Get(AdminKeyHeader)
+			if given == "" {
+				response.Error(w, errors.Unauthorized("Missing admin key"))
+				return
+			}
+
+			if subtle.ConstantTimeCompare([]byte(given), []byte(key)) != 1 {
+				logger.FromContext(r.Context()).Warn("Rejected admin key", zap.String("path", r.URL.Path))
+				response.Error(w, errors.Forbidden("Invalid admin key"))
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(auth.WithAdminKeyID(r.Context(), keyID)))
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdminKeyMiddleware(t *testing.T) {
+	handler := AdminKeyMiddleware("admin-test-key-0123456789abcdef0123")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name           string
+		key            string
+		expectedStatus int
+	}{
+		{name: "Missing key", key: "", expectedStatus: http.StatusUnauthorized},
+		{name: "Wrong key", key: "not-the-admin-key", expectedStatus: http.StatusForbidden},
+		{name: "Valid key", key: "admin-test-key-0123456789abcdef0123", expectedStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, "/api/v1/admin/wallets/x/status", nil)
+			if tt.key != "" {
+				req.Header.Set(AdminKeyHeader, tt.key)
+			}
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+		})
+	}
+}
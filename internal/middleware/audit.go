@@ -0,0 +1,109 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/pkg/auth"
+	"github.com/shanwije/wallet-app/pkg/logger"
+)
+
+// Auditor reads wallet balances and appends entries to the audit log
+type Auditor interface {
+	WalletBalance(ctx context.Context, walletID uuid.UUID) *decimal.Decimal
+	Record(ctx context.Context, entry *models.AuditEntry) error
+}
+
+// AuditMiddleware records every POST, PUT, PATCH and DELETE in the audit log. When
+// walletParam is set, the URL parameter of that name identifies the wallet whose
+// balance is recorded before and after the call. It must run after the middleware
+// that authenticates the caller, so the actor is known.
+//
+// The entry is written after the response, and a failure to write it is logged
+// rather than failing a call that has already taken effect.
+func AuditMiddleware(auditor Auditor, walletParam string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+			default:
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				respondBodyReadError(w, err, http.StatusBadRequest, "Failed to read request body")
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			digest := sha256.Sum256(body)
+
+			entry := &models.AuditEntry{
+				Path:        r.URL.Path,
+				PayloadHash: hex.EncodeToString(digest[:]),
+				RequestID:   w.Header().Get("X-Request-ID"),
+			}
+			entry.ActorType, entry.ActorID = auditActor(r)
+
+			// Audit the call even if the client goes away while it runs
+			auditCtx := context.WithoutCancel(r.Context())
+			if walletParam != "" {
+				if walletID, err := uuid.Parse(chi.URLParam(r, walletParam)); err == nil {
+					entry.WalletID = &walletID
+					entry.BalanceBefore = auditor.WalletBalance(auditCtx, walletID)
+				}
+			}
+
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r)
+
+			entry.StatusCode = ww.Status()
+			if entry.StatusCode == 0 {
+				// Nothing was written, which net/http sends as 200
+				entry.StatusCode = http.StatusOK
+			}
+			entry.Endpoint = r.Method + " " + r.URL.Path
+			if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+				entry.Endpoint = r.Method + " " + rctx.RoutePattern()
+			}
+			if entry.WalletID != nil {
+				entry.BalanceAfter = auditor.WalletBalance(auditCtx, *entry.WalletID)
+			}
+
+			if err := auditor.Record(auditCtx, entry); err != nil {
+				logger.FromContext(r.Context()).Error("Failed to write audit entry",
+					zap.Error(err),
+					zap.String("endpoint", entry.Endpoint),
+					zap.String("actor_id", entry.ActorID))
+			}
+		})
+	}
+}
+
+// auditActor identifies the caller: the authenticated user, the admin key, or the client IP
+func auditActor(r *http.Request) (actorType, actorID string) {
+	if userID, ok := auth.UserIDFromContext(r.Context()); ok {
+		return models.AuditActorUser, userID.String()
+	}
+	if keyID, ok := auth.AdminKeyIDFromContext(r.Context()); ok {
+		return models.AuditActorAdmin, keyID
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return models.AuditActorAnonymous, host
+}
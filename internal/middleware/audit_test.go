@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/pkg/auth"
+)
+
+// fakeAuditor serves balances from a map and keeps the entries it is given
+type fakeAuditor struct {
+	balances map[uuid.UUID]decimal.Decimal
+	entries  []*models.AuditEntry
+}
+
+func (f *fakeAuditor) WalletBalance(_ context.Context, walletID uuid.UUID) *decimal.Decimal {
+	balance, ok := f.balances[walletID]
+	if !ok {
+		return nil
+	}
+	return &balance
+}
+
+func (f *fakeAuditor) Record(_ context.Context, entry *models.AuditEntry) error {
+	f.entries = append(f.entries, entry)
+	return nil
+}
+
+func TestAuditMiddlewareRecordsMutation(t *testing.T) {
+	walletID := uuid.New()
+	userID := uuid.New()
+	auditor := &fakeAuditor{balances: map[uuid.UUID]decimal.Decimal{walletID: decimal.NewFromInt(100)}}
+
+	router := chi.NewRouter()
+	router.With(AuditMiddleware(auditor, "id")).Post("/wallets/{id}/deposit", func(w http.ResponseWriter, r *http.Request) {
+		auditor.balances[walletID] = decimal.NewFromInt(125)
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	body := `{"amount":"25.00"}`
+	req := httptest.NewRequest(http.MethodPost, "/wallets/"+walletID.String()+"/deposit", strings.NewReader(body))
+	req = req.WithContext(auth.WithUserID(req.Context(), userID))
+	rr := httptest.NewRecorder()
+
+	router.ServeHTTP(rr, req)
+
+	require.Len(t, auditor.entries, 1)
+	entry := auditor.entries[0]
+	digest := sha256.Sum256([]byte(body))
+	assert.Equal(t, models.AuditActorUser, entry.ActorType)
+	assert.Equal(t, userID.String(), entry.ActorID)
+	assert.Equal(t, "POST /wallets/{id}/deposit", entry.Endpoint)
+	assert.Equal(t, hex.EncodeToString(digest[:]), entry.PayloadHash)
+	assert.Equal(t, http.StatusCreated, entry.StatusCode)
+	assert.Equal(t, walletID, *entry.WalletID)
+	assert.True(t, entry.BalanceBefore.Equal(decimal.NewFromInt(100)))
+	assert.True(t, entry.BalanceAfter.Equal(decimal.NewFromInt(125)))
+}
+
+func TestAuditMiddlewareSkipsReads(t *testing.T) {
+	auditor := &fakeAuditor{}
+	handler := AuditMiddleware(auditor, "")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/x", nil))
+
+	assert.Empty(t, auditor.entries)
+}
+
+func TestAuditMiddlewareFallsBackToClientIP(t *testing.T) {
+	auditor := &fakeAuditor{}
+	handler := AuditMiddleware(auditor, "")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader("{}"))
+	req.RemoteAddr = "203.0.113.7:5123"
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	require.Len(t, auditor.entries, 1)
+	assert.Equal(t, models.AuditActorAnonymous, auditor.entries[0].ActorType)
+	assert.Equal(t, "203.0.113.7", auditor.entries[0].ActorID)
+	assert.Equal(t, http.StatusBadRequest, auditor.entries[0].StatusCode)
+	assert.Nil(t, auditor.entries[0].WalletID)
+}
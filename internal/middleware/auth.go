@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/shanwije/wallet-app/pkg/auth"
+	"github.com/shanwije/wallet-app/pkg/errors"
+	"github.com/shanwije/wallet-app/pkg/logger"
+	"github.com/shanwije/wallet-app/pkg/response"
+)
+
+// AuthMiddleware requires a valid "Authorization: Bearer <token>" header and
+// stores the authenticated user ID in the request context
+func AuthMiddleware(tokens *auth.TokenManager) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header := r.Header.Get("Authorization")
+			token, found := strings.CutPrefix(header, "Bearer ")
+			if !found || token == "" {
+				response.Error(w, errors.Unauthorized("Missing bearer token"))
+				return
+			}
+
+			userID, err := tokens.Verify(token)
+			if err != nil {
+				logger.FromContext(r.Context()).Warn("Rejected access token", zap.Error(err))
+				response.Error(w, errors.Unauthorized("Invalid or expired token"))
+				return
+			}
+
+			noteLogUserID(r.Context(), userID)
+			ctx := auth.WithUserID(r.Context(), userID)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
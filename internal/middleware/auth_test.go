@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/shanwije/wallet-app/pkg/auth"
+)
+
+func TestAuthMiddleware(t *testing.T) {
+	tokens := auth.NewTokenManager("middleware-test-secret-0123456789", time.Hour, nil)
+	userID := uuid.New()
+	validToken, _, err := tokens.Issue(userID)
+	assert.NoError(t, err)
+
+	var seenUserID uuid.UUID
+	handler := AuthMiddleware(tokens)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seenUserID, _ = auth.UserIDFromContext(r.Context())
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name           string
+		header         string
+		expectedStatus int
+	}{
+		{name: "Missing header", header: "", expectedStatus: http.StatusUnauthorized},
+		{name: "Wrong scheme", header: "Basic " + validToken, expectedStatus: http.StatusUnauthorized},
+		{name: "Garbage token", header: "Bearer not-a-jwt", expectedStatus: http.StatusUnauthorized},
+		{name: "Valid token", header: "Bearer " + validToken, expectedStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/wallets/x/balance", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+		})
+	}
+
+	assert.Equal(t, userID, seenUserID)
+}
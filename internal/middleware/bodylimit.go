@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	stderrors "errors"
+	"net/http"
+
+	"github.com/shanwije/wallet-app/pkg/errors"
+	"github.com/shanwije/wallet-app/pkg/response"
+)
+
+// BodyLimitMiddleware caps request bodies at maxBytes. Requests that declare a larger
+// Content-Length are rejected up front; for the rest the body stops reading at the
+// limit, so no handler or middleware can buffer more than maxBytes into memory.
+func BodyLimitMiddleware(maxBytes int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > maxBytes {
+				response.Error(w, errors.PayloadTooLarge(maxBytes))
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// respondBodyReadError answers a request whose body could not be read, telling
+// oversized bodies apart from other read failures
+func respondBodyReadError(w http.ResponseWriter, err error, status int, message string) {
+	var tooLarge *http.MaxBytesError
+	if stderrors.As(err, &tooLarge) {
+		response.Error(w, errors.PayloadTooLarge(tooLarge.Limit))
+		return
+	}
+	response.ErrorMessage(w, status, message)
+}
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shanwije/wallet-app/pkg/errors"
+	"github.com/shanwije/wallet-app/pkg/response"
+)
+
+func TestBodyLimitMiddleware(t *testing.T) {
+	handler := BodyLimitMiddleware(16)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := io.ReadAll(r.Body); err != nil {
+			respondBodyReadError(w, err, http.StatusBadRequest, "Failed to read request body")
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name           string
+		body           string
+		chunked        bool
+		expectedStatus int
+	}{
+		{name: "Within limit", body: `{"amount": 10}`, expectedStatus: http.StatusOK},
+		{name: "Declared length over limit", body: `{"amount": 1000000}`, expectedStatus: http.StatusRequestEntityTooLarge},
+		{name: "Unknown length over limit", body: `{"amount": 1000000}`, chunked: true, expectedStatus: http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/wallets/x/deposit", strings.NewReader(tt.body))
+			if tt.chunked {
+				req.ContentLength = -1
+			}
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			if tt.expectedStatus == http.StatusRequestEntityTooLarge {
+				var body response.Problem
+				require.NoError(t, json.NewDecoder(rr.Body).Decode(&body))
+				assert.Equal(t, errors.ErrPayloadSize, body.Code)
+				assert.Equal(t, "16", body.Details["max_bytes"])
+			}
+		})
+	}
+}
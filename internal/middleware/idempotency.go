@@ -1,34 +1,76 @@
 package middleware
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	stderrors "errors"
+	"fmt"
 	"io"
 	"net/http"
 	"strings"
 	"sync"
 	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository"
+	"github.com/shanwije/wallet-app/pkg/clock"
+	"github.com/shanwije/wallet-app/pkg/errors"
+	"github.com/shanwije/wallet-app/pkg/logger"
+	"github.com/shanwije/wallet-app/pkg/response"
 )
 
-// Simple in-memory cache for idempotency (in production, use Redis or any other caching solution)
+// idempotencyTTL is how long a completed response is replayed for its key
+const idempotencyTTL = 24 * time.Hour
+
+// Simple in-memory cache for idempotency, optionally backed by a persistent store
+// so keys survive restarts and are shared between instances
 type IdempotencyCache struct {
 	cache map[string]CacheEntry
 	mutex sync.RWMutex
+	clock clock.Clock
+	store repository.IdempotencyKeyRepository
 }
 
 type CacheEntry struct {
-	Response   []byte
-	StatusCode int
-	Headers    map[string]string
-	Timestamp  time.Time
+	// Fingerprint is the hash of the body of the request that produced the response
+	Fingerprint string
+	Response    []byte
+	StatusCode  int
+	Headers     map[string]string
+	Timestamp   time.Time
+}
+
+var globalCache = NewIdempotencyCache(clock.New())
+
+// NewIdempotencyCache creates an empty cache whose TTLs are evaluated against clk
+func NewIdempotencyCache(clk clock.Clock) *IdempotencyCache {
+	return &IdempotencyCache{
+		cache: make(map[string]CacheEntry),
+		clock: clock.OrDefault(clk),
+	}
 }
 
-var globalCache = &IdempotencyCache{
-	cache: make(map[string]CacheEntry),
+// NewPersistentIdempotencyCache creates a cache that records every key in store
+// before the request runs, in front of an in-memory cache of completed responses
+func NewPersistentIdempotencyCache(store repository.IdempotencyKeyRepository, clk clock.Clock) *IdempotencyCache {
+	c := NewIdempotencyCache(clk)
+	c.store = store
+	return c
 }
 
-// IdempotencyMiddleware provides idempotency for POST requests
+// keyReusedMessage rejects a key replayed with a different request body
+const keyReusedMessage = "This Idempotency-Key was already used with a different request body"
+
+// IdempotencyMiddleware provides idempotency for POST requests using the process-wide cache
 func IdempotencyMiddleware(next http.Handler) http.Handler {
+	return globalCache.Middleware(next)
+}
+
+// Middleware provides idempotency for POST requests backed by this cache
+func (c *IdempotencyCache) Middleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Only apply to POST requests (create operations)
 		if r.Method != http.MethodPost {
@@ -44,15 +86,20 @@ func IdempotencyMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
-		// Create a unique key based on the request
-		requestKey, err := createRequestKey(r, idempotencyKey)
+		// The key identifies the operation; the fingerprint, the body it was sent with
+		requestKey, fingerprint, err := createRequestKey(r, idempotencyKey)
 		if err != nil {
-			http.Error(w, "Failed to process idempotency key", http.StatusInternalServerError)
+			respondBodyReadError(w, err, http.StatusInternalServerError, "Failed to process idempotency key")
 			return
 		}
 
 		// Check if we've seen this request before
-		if cachedResponse, found := getCachedResponse(requestKey); found {
+		if cachedResponse, found := c.getCachedResponse(requestKey); found {
+			if cachedResponse.Fingerprint != fingerprint {
+				response.Error(w, errors.Conflict(keyReusedMessage))
+				return
+			}
+
 			// Return cached response
 			for key, value := range cachedResponse.Headers {
 				w.Header().Set(key, value)
@@ -62,6 +109,11 @@ func IdempotencyMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
+		if c.store != nil {
+			c.servePersistent(w, r, next, requestKey, fingerprint)
+			return
+		}
+
 		// Capture the response
 		responseWriter := &ResponseCapture{
 			ResponseWriter: w,
@@ -73,11 +125,12 @@ func IdempotencyMiddleware(next http.Handler) http.Handler {
 
 		// Cache the response for future requests (only if successful)
 		if responseWriter.statusCode >= 200 && responseWriter.statusCode < 300 {
-			cacheResponse(requestKey, CacheEntry{
-				Response:   responseWriter.body,
-				StatusCode: responseWriter.statusCode,
-				Headers:    responseWriter.headers,
-				Timestamp:  time.Now(),
+			c.cacheResponse(requestKey, CacheEntry{
+				Fingerprint: fingerprint,
+				Response:    responseWriter.body,
+				StatusCode:  responseWriter.statusCode,
+				Headers:     responseWriter.headers,
+				Timestamp:   c.clock.Now(),
 			})
 		}
 	})
@@ -92,97 +145,220 @@ type ResponseCapture struct {
 }
 
 func (rc *ResponseCapture) Write(data []byte) (int, error) {
+	if rc.statusCode == 0 {
+		rc.WriteHeader(http.StatusOK)
+	}
 	rc.body = append(rc.body, data...)
 	return rc.ResponseWriter.Write(data)
 }
 
 func (rc *ResponseCapture) WriteHeader(statusCode int) {
 	rc.statusCode = statusCode
-	rc.ResponseWriter.WriteHeader(statusCode)
-}
-
-func (rc *ResponseCapture) Header() http.Header {
-	headers := rc.ResponseWriter.Header()
-	// Capture important headers
-	for key, values := range headers {
+	// Capture headers as they are sent, after the handler has set them
+	for key, values := range rc.ResponseWriter.Header() {
 		if len(values) > 0 {
 			rc.headers[key] = values[0]
 		}
 	}
-	return headers
+	rc.ResponseWriter.WriteHeader(statusCode)
 }
 
-// createRequestKey creates a unique key for the request
-func createRequestKey(r *http.Request, idempotencyKey string) (string, error) {
+// createRequestKey creates a unique key for the request and a fingerprint of its body.
+// The body is left out of the key, so a key replayed with a different body is found
+// and its fingerprint tells the two apart.
+func createRequestKey(r *http.Request, idempotencyKey string) (requestKey, fingerprint string, err error) {
 	// Read the request body
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		return "", err
+		return "", "", err
 	}
 
 	// Restore the body for the next handler
 	r.Body = io.NopCloser(strings.NewReader(string(body)))
 
-	// Create hash of method + path + body + idempotency key
+	// Create hash of method + path + idempotency key + caller credentials,
+	// so one caller's cached response is never replayed to another
 	hasher := sha256.New()
 	hasher.Write([]byte(r.Method))
 	hasher.Write([]byte(r.URL.Path))
-	hasher.Write(body)
 	hasher.Write([]byte(idempotencyKey))
+	hasher.Write([]byte(r.Header.Get("Authorization")))
 
-	return hex.EncodeToString(hasher.Sum(nil)), nil
+	digest := sha256.Sum256(body)
+	return hex.EncodeToString(hasher.Sum(nil)), hex.EncodeToString(digest[:]), nil
 }
 
 // getCachedResponse retrieves a cached response if it exists and is still valid
-func getCachedResponse(key string) (CacheEntry, bool) {
-	globalCache.mutex.RLock()
-	entry, found := globalCache.cache[key]
+func (c *IdempotencyCache) getCachedResponse(key string) (CacheEntry, bool) {
+	c.mutex.RLock()
+	entry, found := c.cache[key]
 
 	if !found {
-		globalCache.mutex.RUnlock()
+		c.mutex.RUnlock()
 		return CacheEntry{}, false
 	}
 
-	// Check if entry is still valid (24 hours)
-	if time.Since(entry.Timestamp) > 24*time.Hour {
-		globalCache.mutex.RUnlock() // Release read lock before acquiring write lock
+	// Check if entry is still valid
+	if c.expired(entry.Timestamp) {
+		c.mutex.RUnlock() // Release read lock before acquiring write lock
 
 		// Acquire write lock for safe deletion
-		globalCache.mutex.Lock()
-		defer globalCache.mutex.Unlock()
+		c.mutex.Lock()
+		defer c.mutex.Unlock()
 
 		// Double-check the entry still exists and is still expired
 		// (another goroutine might have already deleted it)
-		if entry, exists := globalCache.cache[key]; exists && time.Since(entry.Timestamp) > 24*time.Hour {
-			delete(globalCache.cache, key)
+		if entry, exists := c.cache[key]; exists && c.expired(entry.Timestamp) {
+			delete(c.cache, key)
 		}
 
 		return CacheEntry{}, false
 	}
 
-	globalCache.mutex.RUnlock()
+	c.mutex.RUnlock()
 	return entry, true
 }
 
 // cacheResponse stores a response in the cache
-func cacheResponse(key string, entry CacheEntry) {
-	globalCache.mutex.Lock()
-	defer globalCache.mutex.Unlock()
+func (c *IdempotencyCache) cacheResponse(key string, entry CacheEntry) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
 
-	globalCache.cache[key] = entry
+	c.cache[key] = entry
 
 	// Simple cleanup: if cache gets too large, remove old entries
-	if len(globalCache.cache) > 10000 {
-		cleanupOldEntries()
+	if len(c.cache) > 10000 {
+		c.cleanupOldEntries()
 	}
 }
 
 // cleanupOldEntries removes entries older than 1 hour
-func cleanupOldEntries() {
-	cutoff := time.Now().Add(-1 * time.Hour)
-	for key, entry := range globalCache.cache {
+func (c *IdempotencyCache) cleanupOldEntries() {
+	cutoff := c.clock.Now().Add(-1 * time.Hour)
+	for key, entry := range c.cache {
 		if entry.Timestamp.Before(cutoff) {
-			delete(globalCache.cache, key)
+			delete(c.cache, key)
+		}
+	}
+}
+
+// expired reports whether an entry created at the given time is past the TTL
+func (c *IdempotencyCache) expired(createdAt time.Time) bool {
+	return c.clock.Now().Sub(createdAt) > idempotencyTTL
+}
+
+// servePersistent runs the request under a key reserved in the store. A retry of a
+// completed request is answered with the stored response, even after a restart, and
+// a retry arriving while the original is still running, or with a different body, is
+// rejected with 409
+func (c *IdempotencyCache) servePersistent(w http.ResponseWriter, r *http.Request, next http.Handler, requestKey, fingerprint string) {
+	log := logger.FromContext(r.Context())
+
+	existing, err := c.reserve(r.Context(), requestKey, fingerprint)
+	if err != nil {
+		log.Error("Failed to reserve idempotency key", zap.Error(err))
+		response.ErrorMessage(w, http.StatusInternalServerError, "Failed to process idempotency key")
+		return
+	}
+
+	if existing != nil {
+		if existing.Fingerprint != fingerprint {
+			response.Error(w, errors.Conflict(keyReusedMessage))
+			return
 		}
+		if !existing.Completed() {
+			response.Error(w, errors.Conflict("A request with this Idempotency-Key is still being processed"))
+			return
+		}
+
+		entry := CacheEntry{
+			Fingerprint: existing.Fingerprint,
+			Response:    existing.ResponseBody,
+			StatusCode:  existing.StatusCode,
+			Headers:     existing.Headers,
+			Timestamp:   existing.CreatedAt,
+		}
+		c.cacheResponse(requestKey, entry)
+
+		for key, value := range entry.Headers {
+			w.Header().Set(key, value)
+		}
+		w.WriteHeader(entry.StatusCode)
+		w.Write(entry.Response)
+		return
+	}
+
+	responseWriter := &ResponseCapture{
+		ResponseWriter: w,
+		body:           make([]byte, 0),
+		headers:        make(map[string]string),
+	}
+
+	next.ServeHTTP(responseWriter, r)
+
+	// The outcome is recorded even if the client has gone away, so its retry sees it
+	ctx := context.WithoutCancel(r.Context())
+
+	if responseWriter.statusCode < 200 || responseWriter.statusCode >= 300 {
+		// Failed requests release the key so the client can retry them
+		if err := c.store.DeleteIdempotencyKey(ctx, requestKey); err != nil {
+			log.Error("Failed to release idempotency key", zap.Error(err))
+		}
+		return
+	}
+
+	key := &models.IdempotencyKey{
+		RequestKey:   requestKey,
+		Fingerprint:  fingerprint,
+		StatusCode:   responseWriter.statusCode,
+		Headers:      responseWriter.headers,
+		ResponseBody: responseWriter.body,
+	}
+	if err := c.store.CompleteIdempotencyKey(ctx, key); err != nil {
+		log.Error("Failed to store idempotent response", zap.Error(err))
 	}
+
+	c.cacheResponse(requestKey, CacheEntry{
+		Fingerprint: fingerprint,
+		Response:    responseWriter.body,
+		StatusCode:  responseWriter.statusCode,
+		Headers:     responseWriter.headers,
+		Timestamp:   c.clock.Now(),
+	})
+}
+
+// reserve claims requestKey in the store. It returns nil when the caller now owns
+// the key, or the existing live record when another request already claimed it
+func (c *IdempotencyCache) reserve(ctx context.Context, requestKey, fingerprint string) (*models.IdempotencyKey, error) {
+	// A second attempt is needed when an expired or released key is removed in between
+	for attempt := 0; attempt < 2; attempt++ {
+		err := c.store.ReserveIdempotencyKey(ctx, &models.IdempotencyKey{
+			RequestKey:  requestKey,
+			Fingerprint: fingerprint,
+			CreatedAt:   c.clock.Now(),
+		})
+		if err == nil {
+			return nil, nil
+		}
+		if !stderrors.Is(err, repository.ErrDuplicate) {
+			return nil, err
+		}
+
+		existing, err := c.store.GetIdempotencyKey(ctx, requestKey)
+		if err != nil {
+			if stderrors.Is(err, repository.ErrNotFound) {
+				continue
+			}
+			return nil, err
+		}
+		if !c.expired(existing.CreatedAt) {
+			return existing, nil
+		}
+
+		if err := c.store.DeleteIdempotencyKey(ctx, requestKey); err != nil {
+			return nil, err
+		}
+	}
+
+	return nil, fmt.Errorf("idempotency key could not be reserved: %w", repository.ErrDuplicate)
 }
@@ -0,0 +1,238 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository"
+	"github.com/shanwije/wallet-app/pkg/clock"
+)
+
+// memoryIdempotencyStore is a map-backed IdempotencyKeyRepository standing in for the database
+type memoryIdempotencyStore struct {
+	mu   sync.Mutex
+	keys map[string]models.IdempotencyKey
+}
+
+func newMemoryIdempotencyStore() *memoryIdempotencyStore {
+	return &memoryIdempotencyStore{keys: make(map[string]models.IdempotencyKey)}
+}
+
+func (s *memoryIdempotencyStore) ReserveIdempotencyKey(ctx context.Context, key *models.IdempotencyKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, found := s.keys[key.RequestKey]; found {
+		return fmt.Errorf("idempotency key %w", repository.ErrDuplicate)
+	}
+	s.keys[key.RequestKey] = *key
+	return nil
+}
+
+func (s *memoryIdempotencyStore) CompleteIdempotencyKey(ctx context.Context, key *models.IdempotencyKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	existing, found := s.keys[key.RequestKey]
+	if !found {
+		return fmt.Errorf("idempotency key %w", repository.ErrNotFound)
+	}
+	existing.StatusCode = key.StatusCode
+	existing.Headers = key.Headers
+	existing.ResponseBody = key.ResponseBody
+	s.keys[key.RequestKey] = existing
+	return nil
+}
+
+func (s *memoryIdempotencyStore) GetIdempotencyKey(ctx context.Context, requestKey string) (*models.IdempotencyKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key, found := s.keys[requestKey]
+	if !found {
+		return nil, fmt.Errorf("idempotency key %w", repository.ErrNotFound)
+	}
+	return &key, nil
+}
+
+func (s *memoryIdempotencyStore) DeleteIdempotencyKey(ctx context.Context, requestKey string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.keys, requestKey)
+	return nil
+}
+
+func newTransferRequest(key string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/wallets/abc/transfer", strings.NewReader(`{"amount":10}`))
+	req.Header.Set("Idempotency-Key", key)
+	return req
+}
+
+func TestIdempotencyCacheExpiresWithClock(t *testing.T) {
+	fakeClock := clock.NewFake(time.Date(2024, 6, 11, 0, 0, 0, 0, time.UTC))
+	cache := NewIdempotencyCache(fakeClock)
+
+	calls := 0
+	handler := cache.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	send := func() {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/users", strings.NewReader(`{"name":"John"}`))
+		req.Header.Set("Idempotency-Key", "key-1")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	send()
+	send()
+	assert.Equal(t, 1, calls, "replay within TTL should be served from cache")
+
+	fakeClock.Advance(25 * time.Hour)
+	send()
+	assert.Equal(t, 2, calls, "expired entry should reach the handler again")
+}
+
+func TestPersistentIdempotencySurvivesRestart(t *testing.T) {
+	store := newMemoryIdempotencyStore()
+	fakeClock := clock.NewFake(time.Date(2024, 6, 14, 0, 0, 0, 0, time.UTC))
+
+	calls := 0
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"status":"done"}`))
+	})
+
+	first := httptest.NewRecorder()
+	NewPersistentIdempotencyCache(store, fakeClock).Middleware(handler).ServeHTTP(first, newTransferRequest("transfer-1"))
+	assert.Equal(t, http.StatusOK, first.Code)
+
+	// A fresh cache has an empty memory, as after a restart
+	replay := httptest.NewRecorder()
+	NewPersistentIdempotencyCache(store, fakeClock).Middleware(handler).ServeHTTP(replay, newTransferRequest("transfer-1"))
+
+	assert.Equal(t, 1, calls, "duplicate transfer must not run again after a restart")
+	assert.Equal(t, http.StatusOK, replay.Code)
+	assert.Equal(t, `{"status":"done"}`, replay.Body.String())
+	assert.Equal(t, "application/json", replay.Header().Get("Content-Type"))
+}
+
+func TestPersistentIdempotencyRejectsInFlightDuplicate(t *testing.T) {
+	store := newMemoryIdempotencyStore()
+	fakeClock := clock.NewFake(time.Date(2024, 6, 14, 0, 0, 0, 0, time.UTC))
+	cache := NewPersistentIdempotencyCache(store, fakeClock)
+
+	calls := 0
+	var inner http.Handler
+	inner = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		// The retry arrives while the original is still being handled
+		retry := httptest.NewRecorder()
+		cache.Middleware(inner).ServeHTTP(retry, newTransferRequest("transfer-2"))
+		assert.Equal(t, http.StatusConflict, retry.Code)
+		w.WriteHeader(http.StatusOK)
+	})
+
+	cache.Middleware(inner).ServeHTTP(httptest.NewRecorder(), newTransferRequest("transfer-2"))
+	assert.Equal(t, 1, calls)
+}
+
+func TestPersistentIdempotencyReleasesFailedRequests(t *testing.T) {
+	store := newMemoryIdempotencyStore()
+	fakeClock := clock.NewFake(time.Date(2024, 6, 14, 0, 0, 0, 0, time.UTC))
+	cache := NewPersistentIdempotencyCache(store, fakeClock)
+
+	status := http.StatusBadRequest
+	calls := 0
+	handler := cache.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(status)
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), newTransferRequest("transfer-3"))
+	assert.Empty(t, store.keys, "failed request should release its key")
+
+	status = http.StatusOK
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, newTransferRequest("transfer-3"))
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestPersistentIdempotencyReclaimsExpiredKeys(t *testing.T) {
+	store := newMemoryIdempotencyStore()
+	fakeClock := clock.NewFake(time.Date(2024, 6, 14, 0, 0, 0, 0, time.UTC))
+
+	calls := 0
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusOK)
+	})
+
+	NewPersistentIdempotencyCache(store, fakeClock).Middleware(handler).ServeHTTP(httptest.NewRecorder(), newTransferRequest("transfer-4"))
+
+	fakeClock.Advance(25 * time.Hour)
+	NewPersistentIdempotencyCache(store, fakeClock).Middleware(handler).ServeHTTP(httptest.NewRecorder(), newTransferRequest("transfer-4"))
+
+	assert.Equal(t, 2, calls, "expired key should let the request run again")
+}
+
+func TestIdempotencyRejectsKeyReusedWithDifferentBody(t *testing.T) {
+	fakeClock := clock.NewFake(time.Date(2024, 6, 28, 0, 0, 0, 0, time.UTC))
+	store := newMemoryIdempotencyStore()
+
+	caches := map[string]func() *IdempotencyCache{
+		"memory":     func() *IdempotencyCache { return NewIdempotencyCache(fakeClock) },
+		"persistent": func() *IdempotencyCache { return NewPersistentIdempotencyCache(store, fakeClock) },
+	}
+	for name, newCache := range caches {
+		t.Run(name, func(t *testing.T) {
+			calls := 0
+			handler := newCache().Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				w.Write([]byte(`{"status":"done"}`))
+			}))
+
+			send := func(body string) *httptest.ResponseRecorder {
+				req := httptest.NewRequest(http.MethodPost, "/api/v1/wallets/abc/transfer", strings.NewReader(body))
+				req.Header.Set("Idempotency-Key", "transfer-"+name)
+				rec := httptest.NewRecorder()
+				handler.ServeHTTP(rec, req)
+				return rec
+			}
+
+			assert.Equal(t, http.StatusOK, send(`{"amount":10}`).Code)
+
+			replay := send(`{"amount":10}`)
+			assert.Equal(t, http.StatusOK, replay.Code)
+			assert.Equal(t, `{"status":"done"}`, replay.Body.String())
+
+			conflict := send(`{"amount":99}`)
+			assert.Equal(t, http.StatusConflict, conflict.Code)
+			assert.Contains(t, conflict.Body.String(), "different request body")
+			assert.Equal(t, 1, calls, "a reused key must never run a second operation")
+		})
+	}
+}
+
+func TestPersistentIdempotencyRejectsReusedKeyAfterRestart(t *testing.T) {
+	store := newMemoryIdempotencyStore()
+	fakeClock := clock.NewFake(time.Date(2024, 6, 28, 0, 0, 0, 0, time.UTC))
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+
+	NewPersistentIdempotencyCache(store, fakeClock).Middleware(handler).ServeHTTP(httptest.NewRecorder(), newTransferRequest("transfer-5"))
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/wallets/abc/transfer", strings.NewReader(`{"amount":11}`))
+	req.Header.Set("Idempotency-Key", "transfer-5")
+	rec := httptest.NewRecorder()
+	NewPersistentIdempotencyCache(store, fakeClock).Middleware(handler).ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusConflict, rec.Code)
+}
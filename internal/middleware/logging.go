@@ -1,28 +1,42 @@
 package middleware
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"math/rand/v2"
 	"net/http"
+	"slices"
+	"strings"
+	"time"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/google/uuid"
+	"go.uber.org/zap"
+
 	"github.com/shanwije/wallet-app/pkg/logger"
+	"github.com/shanwije/wallet-app/pkg/requestid"
 )
 
-// RequestIDMiddleware adds request ID to context and response headers
-func RequestIDMiddleware() func(http.Handler) http.Handler {
+// RequestIDMiddleware adds request ID to context and response headers, and puts log in
+// the context tagged with it. From the context it is sent on to outgoing HTTP calls
+// made with requestid.Transport and appended as a comment to database queries.
+func RequestIDMiddleware(log *zap.Logger) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			// Get request ID from header or generate new one
-			requestID := r.Header.Get("X-Request-ID")
+			requestID := r.Header.Get(requestid.Header)
 			if requestID == "" {
 				requestID = uuid.New().String()
 			}
 
 			// Add request ID to response header
-			w.Header().Set("X-Request-ID", requestID)
+			w.Header().Set(requestid.Header, requestID)
 
 			// Add request ID to context with logger
-			ctx := logger.WithRequestID(r.Context(), requestID)
+			ctx := logger.WithRequestID(logger.NewContext(r.Context(), log), requestID)
 			r = r.WithContext(ctx)
 
 			next.ServeHTTP(w, r)
@@ -30,16 +44,184 @@ func RequestIDMiddleware() func(http.Handler) http.Handler {
 	}
 }
 
-// LoggingMiddleware provides structured request logging
-func LoggingMiddleware() func(http.Handler) http.Handler {
-	return middleware.RequestLogger(&middleware.DefaultLogFormatter{
-		Logger: &ChiZapLogger{},
-	})
+// maxLoggedBody is the most of a request or response body kept for logging; larger
+// bodies are left out rather than logged cut off
+const maxLoggedBody = 4096
+
+// redactedValue replaces the value of a sensitive field in a logged body
+const redactedValue = "[REDACTED]"
+
+// defaultRedactFields are the body fields always redacted, matched case-insensitively
+var defaultRedactFields = []string{"password", "token", "access_token", "refresh_token", "secret", "api_key", "authorization"}
+
+// LoggingOptions configures LoggingMiddleware
+type LoggingOptions struct {
+	// SampleRate is the share of successful requests logged, from 0 to 1. Requests
+	// answered with 4xx or 5xx are always logged.
+	SampleRate float64
+	// LogBodies adds JSON request and response bodies to the log line
+	LogBodies bool
+	// RedactFields are body fields redacted in addition to defaultRedactFields
+	RedactFields []string
 }
 
-// ChiZapLogger adapts zap logger for chi middleware
-type ChiZapLogger struct{}
+// requestLogInfo collects details learned further down the chain, such as the
+// authenticated user, for the request's log line
+type requestLogInfo struct {
+	userID string
+}
+
+type requestLogInfoKey struct{}
 
-func (l *ChiZapLogger) Print(v ...interface{}) {
-	logger.Log.Sugar().Info(v...)
+// noteLogUserID records the authenticated user for the request's log line
+func noteLogUserID(ctx context.Context, userID uuid.UUID) {
+	if info, ok := ctx.Value(requestLogInfoKey{}).(*requestLogInfo); ok {
+		info.userID = userID.String()
+	}
+}
+
+// LoggingMiddleware logs one structured line per request with its method, path,
+// status, latency, sizes and the authenticated user. The request ID comes from the
+// context logger, so it must run after RequestIDMiddleware. With LogBodies set, JSON
+// bodies are logged with sensitive fields redacted.
+func LoggingMiddleware(opts LoggingOptions) func(http.Handler) http.Handler {
+	redact := make(map[string]bool, len(defaultRedactFields)+len(opts.RedactFields))
+	for _, field := range slices.Concat(defaultRedactFields, opts.RedactFields) {
+		if field = strings.TrimSpace(field); field != "" {
+			redact[strings.ToLower(field)] = true
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			info := &requestLogInfo{}
+			r = r.WithContext(context.WithValue(r.Context(), requestLogInfoKey{}, info))
+
+			requestBody := &countingBody{ReadCloser: r.Body}
+			if opts.LogBodies {
+				requestBody.capture = &capturedBody{}
+			}
+			if r.Body != nil && r.Body != http.NoBody {
+				r.Body = requestBody
+			}
+
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			var responseBody *capturedBody
+			if opts.LogBodies {
+				responseBody = &capturedBody{}
+				ww.Tee(responseBody)
+			}
+
+			next.ServeHTTP(ww, r)
+
+			status := ww.Status()
+			if status == 0 {
+				// Nothing was written, which net/http sends as 200
+				status = http.StatusOK
+			}
+			if status < http.StatusBadRequest && opts.SampleRate < 1 && rand.Float64() >= opts.SampleRate {
+				return
+			}
+
+			fields := []zap.Field{
+				zap.String("method", r.Method),
+				zap.String("path", r.URL.Path),
+				zap.Int("status", status),
+				zap.Duration("latency", time.Since(start)),
+				zap.Int64("bytes_in", requestBody.read),
+				zap.Int("bytes_out", ww.BytesWritten()),
+			}
+			if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+				fields = append(fields, zap.String("route", rctx.RoutePattern()))
+			}
+			if info.userID != "" {
+				fields = append(fields, zap.String("user_id", info.userID))
+			}
+			if opts.LogBodies {
+				if body, ok := requestBody.capture.redacted(r.Header, redact); ok {
+					fields = append(fields, zap.Any("request_body", body))
+				}
+				if body, ok := responseBody.redacted(ww.Header(), redact); ok {
+					fields = append(fields, zap.Any("response_body", body))
+				}
+			}
+
+			log := logger.FromContext(r.Context())
+			switch {
+			case status >= http.StatusInternalServerError:
+				log.Error("HTTP request", fields...)
+			case status >= http.StatusBadRequest:
+				log.Warn("HTTP request", fields...)
+			default:
+				log.Info("HTTP request", fields...)
+			}
+		})
+	}
+}
+
+// countingBody counts the request body bytes the handler reads and keeps a copy of
+// the first of them when capture is set
+type countingBody struct {
+	io.ReadCloser
+	read    int64
+	capture *capturedBody
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.read += int64(n)
+	if b.capture != nil {
+		b.capture.Write(p[:n])
+	}
+	return n, err
+}
+
+// capturedBody keeps up to maxLoggedBody bytes written to it and notes whether
+// anything beyond that was dropped
+type capturedBody struct {
+	buf       bytes.Buffer
+	truncated bool
+}
+
+func (c *capturedBody) Write(p []byte) (int, error) {
+	if room := maxLoggedBody - c.buf.Len(); len(p) > room {
+		c.buf.Write(p[:max(room, 0)])
+		c.truncated = true
+	} else {
+		c.buf.Write(p)
+	}
+	return len(p), nil
+}
+
+// redacted decodes the captured body and redacts its sensitive fields. It reports
+// false for empty, truncated, compressed or non-JSON bodies, which are not logged.
+func (c *capturedBody) redacted(header http.Header, redact map[string]bool) (any, bool) {
+	if c == nil || c.buf.Len() == 0 || c.truncated || header.Get("Content-Encoding") != "" {
+		return nil, false
+	}
+	var body any
+	if err := json.Unmarshal(c.buf.Bytes(), &body); err != nil {
+		return nil, false
+	}
+	return redactFields(body, redact), true
+}
+
+// redactFields replaces the values of the redacted fields anywhere in a decoded JSON value
+func redactFields(value any, redact map[string]bool) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, field := range v {
+			if redact[strings.ToLower(key)] {
+				v[key] = redactedValue
+			} else {
+				v[key] = redactFields(field, redact)
+			}
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = redactFields(item, redact)
+		}
+	}
+	return value
 }
@@ -0,0 +1,108 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/shanwije/wallet-app/pkg/logger"
+	"github.com/shanwije/wallet-app/pkg/requestid"
+)
+
+// serveLogged runs the request through LoggingMiddleware and returns what it logged
+func serveLogged(t *testing.T, opts LoggingOptions, req *http.Request, handler http.HandlerFunc) []observer.LoggedEntry {
+	core, logs := observer.New(zapcore.DebugLevel)
+	RequestIDMiddleware(zap.New(core))(LoggingMiddleware(opts)(handler)).ServeHTTP(httptest.NewRecorder(), req)
+	return logs.All()
+}
+
+func TestRequestIDMiddlewareInjectsTheLogger(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(requestid.Header, "request-1")
+
+	handler := RequestIDMiddleware(zap.New(core))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logger.FromContext(r.Context()).Info("handled")
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	require.Len(t, logs.All(), 1)
+	assert.Equal(t, "request-1", logs.All()[0].ContextMap()["request_id"])
+}
+
+func TestLoggingMiddlewareLogsRequest(t *testing.T) {
+	userID := uuid.New()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/wallets/x/deposit", strings.NewReader(`{"amount": 10}`))
+
+	entries := serveLogged(t, LoggingOptions{SampleRate: 1}, req, func(w http.ResponseWriter, r *http.Request) {
+		io.ReadAll(r.Body)
+		noteLogUserID(r.Context(), userID)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{}`))
+	})
+
+	require.Len(t, entries, 1)
+	fields := entries[0].ContextMap()
+	assert.Equal(t, zapcore.InfoLevel, entries[0].Level)
+	assert.Equal(t, "POST", fields["method"])
+	assert.Equal(t, "/api/v1/wallets/x/deposit", fields["path"])
+	assert.EqualValues(t, http.StatusCreated, fields["status"])
+	assert.EqualValues(t, 14, fields["bytes_in"])
+	assert.EqualValues(t, 2, fields["bytes_out"])
+	assert.Equal(t, userID.String(), fields["user_id"])
+	assert.NotContains(t, fields, "request_body")
+}
+
+func TestLoggingMiddlewareSampling(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		expected int
+	}{
+		{name: "Successful requests are sampled", status: http.StatusOK, expected: 0},
+		{name: "Client errors are always logged", status: http.StatusNotFound, expected: 1},
+		{name: "Server errors are always logged", status: http.StatusInternalServerError, expected: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/wallets/x", nil)
+
+			entries := serveLogged(t, LoggingOptions{SampleRate: 0}, req, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+			})
+
+			assert.Len(t, entries, tt.expected)
+		})
+	}
+}
+
+func TestLoggingMiddlewareRedactsBodies(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/login",
+		strings.NewReader(`{"name": "alice", "Password": "hunter2", "card": {"pin": "1234"}}`))
+
+	entries := serveLogged(t, LoggingOptions{SampleRate: 1, LogBodies: true, RedactFields: []string{" pin"}}, req,
+		func(w http.ResponseWriter, r *http.Request) {
+			io.ReadAll(r.Body)
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"access_token": "abc", "expires_in": 60}`))
+		})
+
+	require.Len(t, entries, 1)
+	fields := entries[0].ContextMap()
+	assert.Equal(t, map[string]any{
+		"name":     "alice",
+		"Password": redactedValue,
+		"card":     map[string]any{"pin": redactedValue},
+	}, fields["request_body"])
+	assert.Equal(t, map[string]any{"access_token": redactedValue, "expires_in": float64(60)}, fields["response_body"])
+}
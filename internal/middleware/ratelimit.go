@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+
+	"go.uber.org/zap"
+
+	"github.com/shanwije/wallet-app/pkg/auth"
+	"github.com/shanwije/wallet-app/pkg/errors"
+	"github.com/shanwije/wallet-app/pkg/logger"
+	"github.com/shanwije/wallet-app/pkg/ratelimit"
+	"github.com/shanwije/wallet-app/pkg/response"
+)
+
+// RateLimitMiddleware takes a token per request from the caller's bucket and rejects
+// the request with 429 once it is empty. Callers are identified by their
+// authenticated user, or by address when auth is disabled. If the limiter itself
+// fails the request is let through rather than taking the API down with it.
+func RateLimitMiddleware(limiter ratelimit.Limiter) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			result, err := limiter.Allow(r.Context(), rateLimitKey(r))
+			if err != nil {
+				logger.FromContext(r.Context()).Error("Rate limiter unavailable", zap.Error(err))
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("X-RateLimit-Limit", strconv.Itoa(result.Limit))
+			w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+
+			if !result.Allowed {
+				retryAfter := int(math.Ceil(result.RetryAfter.Seconds()))
+				w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+				response.Error(w, errors.RateLimited(retryAfter))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// rateLimitKey identifies the client a request counts against
+func rateLimitKey(r *http.Request) string {
+	if userID, ok := auth.UserIDFromContext(r.Context()); ok {
+		return "user:" + userID.String()
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		// RealIP leaves a bare address without a port
+		host = r.RemoteAddr
+	}
+	return "addr:" + host
+}
@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"context"
+	stderrors "errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/shanwije/wallet-app/pkg/auth"
+	"github.com/shanwije/wallet-app/pkg/clock"
+	"github.com/shanwije/wallet-app/pkg/ratelimit"
+)
+
+func TestRateLimitMiddleware(t *testing.T) {
+	clk := clock.NewFake(time.Date(2024, 6, 11, 9, 0, 0, 0, time.UTC))
+	limiter := ratelimit.NewMemory(ratelimit.Limit{PerMinute: 30, Burst: 1}, clk)
+	handler := RateLimitMiddleware(limiter)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	userID := uuid.New()
+	send := func(userID uuid.UUID) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/wallets/x/deposit", nil)
+		req = req.WithContext(auth.WithUserID(req.Context(), userID))
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	first := send(userID)
+	assert.Equal(t, http.StatusOK, first.Code)
+	assert.Equal(t, "1", first.Header().Get("X-RateLimit-Limit"))
+	assert.Equal(t, "0", first.Header().Get("X-RateLimit-Remaining"))
+
+	limited := send(userID)
+	assert.Equal(t, http.StatusTooManyRequests, limited.Code)
+	assert.Equal(t, "2", limited.Header().Get("Retry-After"))
+	assert.Contains(t, limited.Body.String(), "RATE_LIMITED")
+
+	// Other clients have their own bucket
+	assert.Equal(t, http.StatusOK, send(uuid.New()).Code)
+
+	clk.Advance(2 * time.Second)
+	assert.Equal(t, http.StatusOK, send(userID).Code)
+}
+
+// failingLimiter simulates an unreachable Redis
+type failingLimiter struct{}
+
+func (failingLimiter) Allow(context.Context, string) (ratelimit.Result, error) {
+	return ratelimit.Result{}, stderrors.New("connection refused")
+}
+
+func TestRateLimitMiddlewareFailsOpen(t *testing.T) {
+	handler := RateLimitMiddleware(failingLimiter{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/api/v1/wallets/x/deposit", nil))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+}
+
+func TestRateLimitKeyIgnoresPort(t *testing.T) {
+	first := httptest.NewRequest(http.MethodPost, "/", nil)
+	first.RemoteAddr = "203.0.113.7:50123"
+	second := httptest.NewRequest(http.MethodPost, "/", nil)
+	second.RemoteAddr = "203.0.113.7:50999"
+
+	assert.Equal(t, "addr:203.0.113.7", rateLimitKey(first))
+	assert.Equal(t, rateLimitKey(first), rateLimitKey(second))
+}
@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shanwije/wallet-app/pkg/errors"
+	"github.com/shanwije/wallet-app/pkg/response"
+)
+
+func TestTimeoutMiddlewareAnswersSlowQueriesWith504(t *testing.T) {
+	handler := TimeoutMiddleware(10 * time.Millisecond)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Stands in for a query that runs until the request context gives up on it
+		<-r.Context().Done()
+		response.Error(w, errors.InternalError(r.Context().Err()))
+	}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/wallets/x/balance", nil))
+
+	assert.Equal(t, http.StatusGatewayTimeout, rr.Code)
+	var body response.Problem
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&body))
+	assert.Equal(t, errors.ErrTimeout, body.Code)
+}
+
+func TestTimeoutMiddlewareDisabled(t *testing.T) {
+	handler := TimeoutMiddleware(0)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, hasDeadline := r.Context().Deadline()
+		assert.False(t, hasDeadline)
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/wallets/x/balance", nil))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+
+	"github.com/shanwije/wallet-app/pkg/money"
+)
+
+// WalletAlertSettings choose the alerts raised for a wallet, in the wallet's currency.
+// A nil LowBalanceThreshold raises no low balance alerts.
+type WalletAlertSettings struct {
+	WalletID            uuid.UUID        `db:"wallet_id" json:"wallet_id"`
+	LowBalanceThreshold *decimal.Decimal `db:"low_balance_threshold" json:"low_balance_threshold,omitempty"`
+	UpdatedAt           time.Time        `db:"updated_at" json:"updated_at"`
+}
+
+// WalletAlert records a withdrawal or transfer that took the wallet's balance from at
+// or above Threshold to Balance, below it. JournalID is the movement that did.
+type WalletAlert struct {
+	ID        uuid.UUID       `db:"id" json:"id"`
+	WalletID  uuid.UUID       `db:"wallet_id" json:"wallet_id"`
+	Type      string          `db:"alert_type" json:"type"` // low_balance
+	JournalID uuid.UUID       `db:"journal_id" json:"journal_id"`
+	Threshold decimal.Decimal `db:"threshold" json:"threshold"`
+	Balance   decimal.Decimal `db:"balance" json:"balance"`
+	Currency  money.Currency  `db:"currency" json:"currency"`
+	CreatedAt time.Time       `db:"created_at" json:"created_at"`
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+
+	"github.com/shanwije/wallet-app/pkg/money"
+)
+
+// AML report types. A large transaction is one movement over the reporting threshold;
+// structuring is a run of movements each just below it, as if split to stay under it.
+const (
+	AMLReportLargeTransaction = "large_transaction"
+	AMLReportStructuring      = "structuring"
+)
+
+// AMLReport records a movement the anti-money-laundering checks found reportable.
+// Operation is the journal type it was posted as. For structuring, MovementCount and
+// Total cover every movement in the run, ending with this one.
+type AMLReport struct {
+	ID            uuid.UUID       `db:"id" json:"id"`
+	Type          string          `db:"type" json:"type"` // large_transaction, structuring
+	WalletID      uuid.UUID       `db:"wallet_id" json:"wallet_id"`
+	JournalID     uuid.UUID       `db:"journal_id" json:"journal_id"`
+	Operation     string          `db:"operation" json:"operation"` // deposit, withdraw, transfer
+	Amount        decimal.Decimal `db:"amount" json:"amount"`
+	Currency      money.Currency  `db:"currency" json:"currency"`
+	MovementCount int             `db:"movement_count" json:"movement_count"`
+	Total         decimal.Decimal `db:"total" json:"total"`
+	Reason        string          `db:"reason" json:"reason"`
+	CreatedAt     time.Time       `db:"created_at" json:"created_at"`
+}
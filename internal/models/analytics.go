@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+
+	"github.com/shanwije/wallet-app/pkg/money"
+)
+
+// CategoryTotal totals the transactions a wallet made under one category. A nil
+// Category totals those filed under none.
+type CategoryTotal struct {
+	Category         *string         `db:"category" json:"category"`
+	TransactionCount int64           `db:"transaction_count" json:"transaction_count"`
+	AmountIn         decimal.Decimal `db:"amount_in" json:"amount_in"`
+	AmountOut        decimal.Decimal `db:"amount_out" json:"amount_out"`
+	NetAmount        decimal.Decimal `json:"net_amount"`
+}
+
+// WalletAnalytics breaks down the money that came into and went out of a wallet over
+// the period [From, To) by category, in the wallet's currency
+type WalletAnalytics struct {
+	WalletID   uuid.UUID        `json:"wallet_id"`
+	Currency   money.Currency   `json:"currency"`
+	From       time.Time        `json:"from"`
+	To         time.Time        `json:"to"`
+	AmountIn   decimal.Decimal  `json:"amount_in"`
+	AmountOut  decimal.Decimal  `json:"amount_out"`
+	Categories []*CategoryTotal `json:"categories"`
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// Audit actor types
+const (
+	AuditActorUser      = "user"
+	AuditActorAdmin     = "admin"
+	AuditActorAnonymous = "anonymous"
+)
+
+// AuditEntry records one mutating API call. ActorID is the user ID for users, a
+// fingerprint of the API key for admins and the client IP for anonymous callers.
+// Endpoint is the route pattern, e.g. "POST /api/v1/wallets/{id}/deposit", and
+// PayloadHash the SHA-256 of the request body. The balances are those of WalletID,
+// read before and after the call; they are nil when the call names no wallet.
+type AuditEntry struct {
+	ID            uuid.UUID        `db:"id" json:"id"`
+	ActorType     string           `db:"actor_type" json:"actor_type"`
+	ActorID       string           `db:"actor_id" json:"actor_id"`
+	Endpoint      string           `db:"endpoint" json:"endpoint"`
+	Path          string           `db:"path" json:"path"`
+	PayloadHash   string           `db:"payload_hash" json:"payload_hash"`
+	StatusCode    int              `db:"status_code" json:"status_code"`
+	RequestID     string           `db:"request_id" json:"request_id,omitempty"`
+	WalletID      *uuid.UUID       `db:"wallet_id" json:"wallet_id,omitempty"`
+	BalanceBefore *decimal.Decimal `db:"balance_before" json:"balance_before,omitempty"`
+	BalanceAfter  *decimal.Decimal `db:"balance_after" json:"balance_after,omitempty"`
+	CreatedAt     time.Time        `db:"created_at" json:"created_at"`
+}
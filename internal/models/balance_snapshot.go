@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+
+	"github.com/shanwije/wallet-app/pkg/money"
+)
+
+// BalanceSnapshot is a wallet's balance at the end of a day: the sum of its ledger
+// entries created before AsOf, the following midnight UTC
+type BalanceSnapshot struct {
+	WalletID  uuid.UUID       `db:"wallet_id" json:"wallet_id"`
+	AsOf      time.Time       `db:"as_of" json:"as_of"`
+	Balance   decimal.Decimal `db:"balance" json:"balance"`
+	Currency  money.Currency  `db:"currency" json:"currency"`
+	CreatedAt time.Time       `db:"created_at" json:"created_at"`
+}
+
+// HistoricalBalance is a wallet's posted balance at a point in time, counting the
+// ledger entries created before At
+type HistoricalBalance struct {
+	WalletID uuid.UUID       `json:"wallet_id"`
+	At       time.Time       `json:"at"`
+	Balance  decimal.Decimal `json:"balance"`
+	Currency money.Currency  `json:"currency"`
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shanwije/wallet-app/pkg/money"
+	"github.com/shopspring/decimal"
+)
+
+// Deposit refund statuses. A refund through the payment provider is pending from the
+// wallet's debit until the provider refunds the payment, when it is completed, or
+// refuses to, when it failed and the wallet is credited back. Refunds without the
+// provider are completed straight away.
+const (
+	DepositRefundStatusPending   = "pending"
+	DepositRefundStatusCompleted = "completed"
+	DepositRefundStatusFailed    = "failed"
+)
+
+// DepositRefund pays part or all of a deposit back out of its wallet. JournalID debited
+// the wallet; ProviderRefundID is set when the payment provider that funded the deposit
+// refunded it to the customer, and ReversalJournalID credited the wallet back when the
+// provider refused.
+type DepositRefund struct {
+	ID                uuid.UUID       `db:"id" json:"id"`
+	DepositJournalID  uuid.UUID       `db:"deposit_journal_id" json:"deposit_journal_id"`
+	WalletID          uuid.UUID       `db:"wallet_id" json:"wallet_id"`
+	Amount            decimal.Decimal `db:"amount" json:"amount"`
+	Currency          money.Currency  `db:"currency" json:"currency"`
+	Reason            *string         `db:"reason" json:"reason,omitempty"`
+	Status            string          `db:"status" json:"status"` // pending, completed, failed
+	JournalID         uuid.UUID       `db:"journal_id" json:"journal_id"`
+	ProviderRefundID  *string         `db:"provider_refund_id" json:"provider_refund_id,omitempty"`
+	FailureReason     *string         `db:"failure_reason" json:"failure_reason,omitempty"`
+	ReversalJournalID *uuid.UUID      `db:"reversal_journal_id" json:"reversal_journal_id,omitempty"`
+	CreatedAt         time.Time       `db:"created_at" json:"created_at"`
+}
+
+// Funds returns the amount refunded
+func (r *DepositRefund) Funds() money.Money {
+	return money.New(r.Amount, r.Currency)
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shanwije/wallet-app/pkg/money"
+	"github.com/shopspring/decimal"
+)
+
+// Dispute statuses. A dispute is open until an operator refunds the transfer or
+// rejects the dispute, both of which are final.
+const (
+	DisputeStatusOpen     = "open"
+	DisputeStatusRefunded = "refunded"
+	DisputeStatusRejected = "rejected"
+)
+
+// Dispute is a wallet owner's claim that a transfer they sent, TransactionID being
+// their leg of it, should be paid back. While it is open Amount, what the
+// counterparty was credited, is held in the counterparty's wallet by HoldID. A refund
+// is posted as ReversalJournalID. Events lists every status it entered, oldest first.
+type Dispute struct {
+	ID                   uuid.UUID       `db:"id" json:"id"`
+	TransactionID        uuid.UUID       `db:"transaction_id" json:"transaction_id"`
+	JournalID            uuid.UUID       `db:"journal_id" json:"reference_id"`
+	WalletID             uuid.UUID       `db:"wallet_id" json:"wallet_id"`
+	CounterpartyWalletID uuid.UUID       `db:"counterparty_wallet_id" json:"counterparty_wallet_id"`
+	Amount               decimal.Decimal `db:"amount" json:"amount"`
+	Currency             money.Currency  `db:"currency" json:"currency"`
+	Reason               string          `db:"reason" json:"reason"`
+	Status               string          `db:"status" json:"status"` // open, refunded, rejected
+	HoldID               *uuid.UUID      `db:"hold_id" json:"hold_id,omitempty"`
+	ReversalJournalID    *uuid.UUID      `db:"reversal_journal_id" json:"reversal_journal_id,omitempty"`
+	Events               []*DisputeEvent `db:"-" json:"events,omitempty"`
+	CreatedAt            time.Time       `db:"created_at" json:"created_at"`
+	UpdatedAt            time.Time       `db:"updated_at" json:"updated_at"`
+}
+
+// Funds returns the disputed amount in its currency
+func (d *Dispute) Funds() money.Money {
+	return money.New(d.Amount, d.Currency)
+}
+
+// DisputeEvent records a dispute entering Status, with the note given for it
+type DisputeEvent struct {
+	ID        uuid.UUID `db:"id" json:"id"`
+	DisputeID uuid.UUID `db:"dispute_id" json:"dispute_id"`
+	Status    string    `db:"status" json:"status"`
+	Note      *string   `db:"note" json:"note,omitempty"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
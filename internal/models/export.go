@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ExportCheckpoint is how far an export stream has got: LastID is the last row shipped,
+// or uuid.Nil before the first batch, and Batches and Rows count what was shipped
+type ExportCheckpoint struct {
+	Stream    string    `db:"stream" json:"stream"`
+	LastID    uuid.UUID `db:"last_id" json:"last_id"`
+	Batches   int64     `db:"batches" json:"batches"`
+	Rows      int64     `db:"exported_rows" json:"rows"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// Export job statuses. A job is pending until the export worker has stored its file,
+// then ready, or failed when the file can never be produced.
+const (
+	ExportJobStatusPending = "pending"
+	ExportJobStatusReady   = "ready"
+	ExportJobStatusFailed  = "failed"
+)
+
+// ExportJob is an export of a wallet's transactions over [From, To) as a CSV file,
+// produced in the background. DownloadURL is only filled in on a ready job when it is
+// read, and stops working at DownloadExpiresAt.
+type ExportJob struct {
+	ID                uuid.UUID  `db:"id" json:"id"`
+	WalletID          uuid.UUID  `db:"wallet_id" json:"wallet_id"`
+	From              time.Time  `db:"period_from" json:"from"`
+	To                time.Time  `db:"period_to" json:"to"`
+	Status            string     `db:"status" json:"status"` // pending, ready, failed
+	ObjectKey         *string    `db:"object_key" json:"-"`
+	Rows              int64      `db:"exported_rows" json:"rows"`
+	FailureReason     *string    `db:"failure_reason" json:"failure_reason,omitempty"`
+	CreatedAt         time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt         time.Time  `db:"updated_at" json:"updated_at"`
+	CompletedAt       *time.Time `db:"completed_at" json:"completed_at,omitempty"`
+	DownloadURL       string     `db:"-" json:"download_url,omitempty"`
+	DownloadExpiresAt *time.Time `db:"-" json:"download_expires_at,omitempty"`
+}
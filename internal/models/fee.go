@@ -0,0 +1,18 @@
+package models
+
+import (
+	"github.com/shopspring/decimal"
+
+	"github.com/shanwije/wallet-app/pkg/money"
+)
+
+// FeeQuote is the fee a wallet would be charged on a deposit, withdrawal or transfer of
+// Amount, and BalanceChange what the movement and its fee together would do to its
+// balance: negative for money going out. Fee is zero when none is charged.
+type FeeQuote struct {
+	Operation     string          `json:"operation"` // deposit, withdraw, transfer
+	Amount        decimal.Decimal `json:"amount"`
+	Fee           decimal.Decimal `json:"fee"`
+	BalanceChange decimal.Decimal `json:"balance_change"`
+	Currency      money.Currency  `json:"currency"`
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shanwije/wallet-app/pkg/money"
+	"github.com/shopspring/decimal"
+)
+
+// Hold statuses. Only active holds reserve funds; captured and released are final.
+const (
+	HoldStatusActive   = "active"
+	HoldStatusCaptured = "captured"
+	HoldStatusReleased = "released"
+)
+
+// Hold reserves Amount of a wallet's balance without posting it to the ledger.
+// Capturing posts up to Amount as a withdrawal and frees the rest; releasing frees it all.
+// A hold securing a dispute carries its DisputeID and is freed only by resolving it; one
+// on a transfer awaiting acceptance carries its IncomingTransferID and is freed only by
+// accepting or returning it.
+type Hold struct {
+	ID                 uuid.UUID        `db:"id" json:"id"`
+	WalletID           uuid.UUID        `db:"wallet_id" json:"wallet_id"`
+	Amount             decimal.Decimal  `db:"amount" json:"amount"`
+	Currency           money.Currency   `db:"currency" json:"currency"`
+	Description        *string          `db:"description" json:"description,omitempty"`
+	Status             string           `db:"status" json:"status"` // active, captured, released
+	CapturedAmount     *decimal.Decimal `db:"captured_amount" json:"captured_amount,omitempty"`
+	CaptureJournalID   *uuid.UUID       `db:"capture_journal_id" json:"capture_journal_id,omitempty"`
+	DisputeID          *uuid.UUID       `db:"dispute_id" json:"dispute_id,omitempty"`
+	IncomingTransferID *uuid.UUID       `db:"incoming_transfer_id" json:"incoming_transfer_id,omitempty"`
+	CreatedAt          time.Time        `db:"created_at" json:"created_at"`
+	UpdatedAt          time.Time        `db:"updated_at" json:"updated_at"`
+}
+
+// Funds returns the held amount in its currency
+func (h *Hold) Funds() money.Money {
+	return money.New(h.Amount, h.Currency)
+}
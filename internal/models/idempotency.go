@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// IdempotencyKey records the outcome of a request sent with an Idempotency-Key header,
+// so a retried request is answered with the original response instead of running twice.
+// Fingerprint identifies the request body, so the key cannot be reused for another one.
+type IdempotencyKey struct {
+	RequestKey   string            `db:"request_key" json:"request_key"`
+	Fingerprint  string            `db:"request_fingerprint" json:"request_fingerprint"`
+	StatusCode   int               `db:"status_code" json:"status_code"`
+	Headers      map[string]string `db:"-" json:"headers"`
+	ResponseBody []byte            `db:"response_body" json:"-"`
+	CreatedAt    time.Time         `db:"created_at" json:"created_at"`
+}
+
+// Completed reports whether the original request has finished; a zero status code
+// marks a key reserved by a request that is still in flight
+func (k *IdempotencyKey) Completed() bool {
+	return k.StatusCode != 0
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shanwije/wallet-app/pkg/money"
+	"github.com/shopspring/decimal"
+)
+
+// Incoming transfer statuses. Only pending transfers can be accepted or rejected; the
+// others are final.
+const (
+	IncomingTransferStatusPending  = "pending"
+	IncomingTransferStatusAccepted = "accepted"
+	IncomingTransferStatusRejected = "rejected"
+	IncomingTransferStatusExpired  = "expired"
+)
+
+// IncomingTransferSettings say whether a wallet takes incoming transfers only once its
+// owner accepts them
+type IncomingTransferSettings struct {
+	WalletID          uuid.UUID `db:"wallet_id" json:"wallet_id"`
+	RequireAcceptance bool      `db:"require_acceptance" json:"require_acceptance"`
+	UpdatedAt         time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// IncomingTransfer is a transfer, JournalID, into a wallet that requires acceptance.
+// While it is pending Amount, what the recipient was credited, is held in their wallet
+// by HoldID. Accepting it frees the hold; rejecting it, or leaving it pending until
+// ExpiresAt, returns it to the sender as ReturnJournalID.
+type IncomingTransfer struct {
+	ID              uuid.UUID       `db:"id" json:"id"`
+	JournalID       uuid.UUID       `db:"journal_id" json:"reference_id"`
+	FromWalletID    uuid.UUID       `db:"from_wallet_id" json:"from_wallet_id"`
+	ToWalletID      uuid.UUID       `db:"to_wallet_id" json:"to_wallet_id"`
+	Amount          decimal.Decimal `db:"amount" json:"amount"`
+	Currency        money.Currency  `db:"currency" json:"currency"`
+	Status          string          `db:"status" json:"status"` // pending, accepted, rejected, expired
+	HoldID          *uuid.UUID      `db:"hold_id" json:"hold_id,omitempty"`
+	ReturnJournalID *uuid.UUID      `db:"return_journal_id" json:"return_journal_id,omitempty"`
+	ExpiresAt       time.Time       `db:"expires_at" json:"expires_at"`
+	CreatedAt       time.Time       `db:"created_at" json:"created_at"`
+	UpdatedAt       time.Time       `db:"updated_at" json:"updated_at"`
+}
+
+// Funds returns the credited amount in its currency
+func (t *IncomingTransfer) Funds() money.Money {
+	return money.New(t.Amount, t.Currency)
+}
@@ -0,0 +1,64 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// Verification tiers, from least to most verified
+const (
+	// KYCTierUnverified is the tier of users who were never verified
+	KYCTierUnverified = "unverified"
+	// KYCTierBasic is for users whose identity was checked
+	KYCTierBasic = "basic"
+	// KYCTierFull is for users whose identity and address were checked
+	KYCTierFull = "full"
+)
+
+// Kinds of document users submit for verification
+const (
+	KYCDocumentPassport       = "passport"
+	KYCDocumentNationalID     = "national_id"
+	KYCDocumentDriversLicense = "drivers_license"
+	KYCDocumentProofOfAddress = "proof_of_address"
+)
+
+// Statuses of a submitted document
+const (
+	KYCDocumentStatusPending  = "pending"
+	KYCDocumentStatusApproved = "approved"
+	KYCDocumentStatusRejected = "rejected"
+)
+
+// KYCProfile is a user's verification tier, with the documents they submitted and the
+// limits the tier puts on their wallet. Note is the operator's reason for the tier.
+type KYCProfile struct {
+	UserID    uuid.UUID      `db:"user_id" json:"user_id"`
+	Tier      string         `db:"tier" json:"tier" example:"basic"`
+	Note      *string        `db:"note" json:"note,omitempty"`
+	UpdatedAt *time.Time     `db:"updated_at" json:"updated_at,omitempty"`
+	Limits    KYCTierLimits  `db:"-" json:"limits"`
+	Documents []*KYCDocument `db:"-" json:"documents"`
+}
+
+// KYCTierLimits cap what the wallets of users in a tier pay out over any 24 hours, in
+// the wallet's currency, on top of the wallet's own limits. A nil limit is not enforced.
+type KYCTierLimits struct {
+	DailyWithdrawalLimit *decimal.Decimal `json:"daily_withdrawal_limit,omitempty"`
+	DailyTransferLimit   *decimal.Decimal `json:"daily_transfer_limit,omitempty"`
+}
+
+// KYCDocument is an identity document a user submitted for verification. Reference
+// points at the document where it was uploaded.
+type KYCDocument struct {
+	ID           uuid.UUID  `db:"id" json:"id"`
+	UserID       uuid.UUID  `db:"user_id" json:"user_id"`
+	DocumentType string     `db:"document_type" json:"document_type" example:"passport"`
+	Reference    string     `db:"reference" json:"reference" example:"s3://kyc-uploads/3f2a/passport.pdf"`
+	Status       string     `db:"status" json:"status" example:"pending"`
+	Note         *string    `db:"note" json:"note,omitempty"`
+	SubmittedAt  time.Time  `db:"submitted_at" json:"submitted_at"`
+	ReviewedAt   *time.Time `db:"reviewed_at" json:"reviewed_at,omitempty"`
+}
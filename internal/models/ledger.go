@@ -0,0 +1,172 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+
+	"github.com/shanwije/wallet-app/pkg/money"
+)
+
+// Journal types, one per kind of money movement
+const (
+	JournalTypeDeposit  = "deposit"
+	JournalTypeWithdraw = "withdraw"
+	JournalTypeTransfer = "transfer"
+	// JournalTypeAdjustment is an operator correction, posted against the settlement account
+	JournalTypeAdjustment = "adjustment"
+	// JournalTypeReversal posts the legs of an earlier journal in the opposite direction
+	JournalTypeReversal = "reversal"
+	// JournalTypeCorrection is a reversal an operator posts for an erroneous journal
+	JournalTypeCorrection = "correction"
+	// JournalTypeFee charges a wallet the fee on one of its movements, paid into the
+	// fee-collection wallet
+	JournalTypeFee = "fee"
+)
+
+// Reasons an operator can give for correcting a journal
+const (
+	CorrectionReasonDuplicate      = "duplicate"
+	CorrectionReasonWrongAmount    = "wrong_amount"
+	CorrectionReasonWrongRecipient = "wrong_recipient"
+	CorrectionReasonFraud          = "fraud"
+	CorrectionReasonSystemError    = "system_error"
+)
+
+// IsValidCorrectionReason reports whether reason is one of the correction reasons
+func IsValidCorrectionReason(reason string) bool {
+	switch reason {
+	case CorrectionReasonDuplicate, CorrectionReasonWrongAmount, CorrectionReasonWrongRecipient,
+		CorrectionReasonFraud, CorrectionReasonSystemError:
+		return true
+	default:
+		return false
+	}
+}
+
+// Entry directions. Wallets are liabilities of the platform, so a credit increases
+// a wallet balance and a debit decreases it.
+const (
+	EntryDirectionDebit  = "debit"
+	EntryDirectionCredit = "credit"
+)
+
+// ErrUnbalancedJournal is returned for journals whose debits and credits differ
+var ErrUnbalancedJournal = errors.New("journal debits and credits do not balance")
+
+// Journal is a single money movement, recorded as two or more ledger entries whose
+// debits and credits sum to the same amount in every currency. IdempotencyKey, when
+// set, is unique across journals so a retried request maps to the original journal.
+// ReversesJournalID is set on reversals and corrections to the journal they undo, which
+// is also unique, and CorrectionReason on corrections to why that journal was wrong.
+// Category and Tags are how the client that posted it filed it, both optional.
+// Journals are never changed once recorded.
+type Journal struct {
+	ID                uuid.UUID      `db:"id" json:"id"`
+	Type              string         `db:"type" json:"type"`
+	Description       *string        `db:"description" json:"description,omitempty"`
+	IdempotencyKey    *string        `db:"idempotency_key" json:"-"`
+	ReversesJournalID *uuid.UUID     `db:"reverses_journal_id" json:"reverses_journal_id,omitempty"`
+	CorrectionReason  *string        `db:"correction_reason" json:"correction_reason,omitempty"`
+	Category          *string        `db:"category" json:"category,omitempty"`
+	Tags              []string       `db:"-" json:"tags,omitempty"`
+	Entries           []*LedgerEntry `db:"-" json:"entries"`
+	CreatedAt         time.Time      `db:"created_at" json:"created_at"`
+}
+
+// LedgerEntry is one leg of a journal. A nil WalletID is the external settlement
+// account that funds deposits and receives withdrawals.
+type LedgerEntry struct {
+	ID        uuid.UUID       `db:"id" json:"id"`
+	JournalID uuid.UUID       `db:"journal_id" json:"journal_id"`
+	WalletID  *uuid.UUID      `db:"wallet_id" json:"wallet_id,omitempty"`
+	Direction string          `db:"direction" json:"direction"`
+	Amount    decimal.Decimal `db:"amount" json:"amount"`
+	Currency  money.Currency  `db:"currency" json:"currency"`
+	// ExchangeRate, CounterAmount and CounterCurrency are set on the legs of a transfer
+	// between currencies: the rate from the sender's currency to the recipient's, and
+	// the leg's amount in the other currency
+	ExchangeRate    *decimal.Decimal `db:"exchange_rate" json:"exchange_rate,omitempty"`
+	CounterAmount   *decimal.Decimal `db:"counter_amount" json:"counter_amount,omitempty"`
+	CounterCurrency *money.Currency  `db:"counter_currency" json:"counter_currency,omitempty"`
+	CreatedAt       time.Time        `db:"created_at" json:"created_at"`
+}
+
+// SignedAmount returns the entry's effect on its account: positive for credits, negative for debits
+func (e *LedgerEntry) SignedAmount() decimal.Decimal {
+	if e.Direction == EntryDirectionDebit {
+		return e.Amount.Neg()
+	}
+	return e.Amount
+}
+
+// Validate checks the journal has at least two positive legs that balance to zero per currency
+func (j *Journal) Validate() error {
+	if len(j.Entries) < 2 {
+		return fmt.Errorf("%w: a journal needs at least two entries", ErrUnbalancedJournal)
+	}
+
+	totals := make(map[money.Currency]decimal.Decimal)
+	for _, entry := range j.Entries {
+		if entry.Direction != EntryDirectionDebit && entry.Direction != EntryDirectionCredit {
+			return fmt.Errorf("invalid entry direction %q", entry.Direction)
+		}
+		if !entry.Amount.IsPositive() {
+			return fmt.Errorf("entry amount must be positive")
+		}
+		totals[entry.Currency] = totals[entry.Currency].Add(entry.SignedAmount())
+	}
+
+	for currency, total := range totals {
+		if !total.IsZero() {
+			return fmt.Errorf("%w: %s is off by %s", ErrUnbalancedJournal, currency, total)
+		}
+	}
+	return nil
+}
+
+// WalletEntry returns the journal's first leg for the wallet, or nil when it has none
+func (j *Journal) WalletEntry(walletID uuid.UUID) *LedgerEntry {
+	for _, entry := range j.Entries {
+		if entry.WalletID != nil && *entry.WalletID == walletID {
+			return entry
+		}
+	}
+	return nil
+}
+
+// TransactionType maps a wallet's leg of a journal to the transaction type shown in its history
+func TransactionType(journalType, direction string) string {
+	switch journalType {
+	case JournalTypeTransfer:
+		if direction == EntryDirectionCredit {
+			return TransactionTypeTransferIn
+		}
+		return TransactionTypeTransferOut
+	case JournalTypeAdjustment:
+		if direction == EntryDirectionCredit {
+			return TransactionTypeAdjustmentIn
+		}
+		return TransactionTypeAdjustmentOut
+	case JournalTypeReversal:
+		if direction == EntryDirectionCredit {
+			return TransactionTypeReversalIn
+		}
+		return TransactionTypeReversalOut
+	case JournalTypeCorrection:
+		if direction == EntryDirectionCredit {
+			return TransactionTypeCorrectionIn
+		}
+		return TransactionTypeCorrectionOut
+	case JournalTypeFee:
+		if direction == EntryDirectionCredit {
+			return TransactionTypeFeeIn
+		}
+		return TransactionTypeFeeOut
+	default:
+		return journalType
+	}
+}
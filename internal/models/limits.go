@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// WalletLimits caps how much a wallet may move, in the wallet's currency. A nil
+// limit is not enforced. The daily limits cover a rolling 24 hour window.
+// OverdraftLimit and MinimumBalance set how low withdrawals, transfers and holds may
+// take the balance; at most one of them is set.
+type WalletLimits struct {
+	WalletID             uuid.UUID        `db:"wallet_id" json:"wallet_id"`
+	MaxTransactionAmount *decimal.Decimal `db:"max_transaction_amount" json:"max_transaction_amount,omitempty"`
+	DailyWithdrawalLimit *decimal.Decimal `db:"daily_withdrawal_limit" json:"daily_withdrawal_limit,omitempty"`
+	DailyTransferLimit   *decimal.Decimal `db:"daily_transfer_limit" json:"daily_transfer_limit,omitempty"`
+	OverdraftLimit       *decimal.Decimal `db:"overdraft_limit" json:"overdraft_limit,omitempty"`
+	MinimumBalance       *decimal.Decimal `db:"minimum_balance" json:"minimum_balance,omitempty"`
+	UpdatedAt            time.Time        `db:"updated_at" json:"updated_at"`
+}
+
+// BalanceFloor returns the lowest balance spending may leave: minus the overdraft
+// limit, the minimum balance, or zero when neither is set
+func (l *WalletLimits) BalanceFloor() decimal.Decimal {
+	switch {
+	case l.OverdraftLimit != nil:
+		return l.OverdraftLimit.Neg()
+	case l.MinimumBalance != nil:
+		return *l.MinimumBalance
+	default:
+		return decimal.Zero
+	}
+}
@@ -7,6 +7,9 @@ import (
 	"github.com/google/uuid"
 	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shanwije/wallet-app/pkg/money"
 )
 
 func TestUserModel(t *testing.T) {
@@ -237,3 +240,178 @@ func TestDecimalHandling(t *testing.T) {
 		assert.True(t, result.Equal(expected))
 	})
 }
+
+func TestJournalValidate(t *testing.T) {
+	walletID := uuid.New()
+	leg := func(walletID *uuid.UUID, direction string, amount float64, currency money.Currency) *LedgerEntry {
+		return &LedgerEntry{WalletID: walletID, Direction: direction, Amount: decimal.NewFromFloat(amount), Currency: currency}
+	}
+
+	t.Run("balanced deposit", func(t *testing.T) {
+		journal := &Journal{Type: JournalTypeDeposit, Entries: []*LedgerEntry{
+			leg(nil, EntryDirectionDebit, 10, money.USD),
+			leg(&walletID, EntryDirectionCredit, 10, money.USD),
+		}}
+		assert.NoError(t, journal.Validate())
+	})
+
+	t.Run("unbalanced amounts", func(t *testing.T) {
+		journal := &Journal{Type: JournalTypeDeposit, Entries: []*LedgerEntry{
+			leg(nil, EntryDirectionDebit, 10, money.USD),
+			leg(&walletID, EntryDirectionCredit, 9.99, money.USD),
+		}}
+		assert.ErrorIs(t, journal.Validate(), ErrUnbalancedJournal)
+	})
+
+	t.Run("balanced only across currencies", func(t *testing.T) {
+		journal := &Journal{Type: JournalTypeTransfer, Entries: []*LedgerEntry{
+			leg(&walletID, EntryDirectionDebit, 10, money.USD),
+			leg(nil, EntryDirectionCredit, 10, money.EUR),
+		}}
+		assert.ErrorIs(t, journal.Validate(), ErrUnbalancedJournal)
+	})
+
+	t.Run("single leg", func(t *testing.T) {
+		journal := &Journal{Type: JournalTypeDeposit, Entries: []*LedgerEntry{
+			leg(&walletID, EntryDirectionCredit, 10, money.USD),
+		}}
+		assert.ErrorIs(t, journal.Validate(), ErrUnbalancedJournal)
+	})
+}
+
+func TestTransactionTypeFromLedger(t *testing.T) {
+	assert.Equal(t, TransactionTypeDeposit, TransactionType(JournalTypeDeposit, EntryDirectionCredit))
+	assert.Equal(t, TransactionTypeWithdraw, TransactionType(JournalTypeWithdraw, EntryDirectionDebit))
+	assert.Equal(t, TransactionTypeTransferOut, TransactionType(JournalTypeTransfer, EntryDirectionDebit))
+	assert.Equal(t, TransactionTypeTransferIn, TransactionType(JournalTypeTransfer, EntryDirectionCredit))
+	assert.Equal(t, TransactionTypeAdjustmentIn, TransactionType(JournalTypeAdjustment, EntryDirectionCredit))
+	assert.Equal(t, TransactionTypeAdjustmentOut, TransactionType(JournalTypeAdjustment, EntryDirectionDebit))
+	assert.Equal(t, TransactionTypeReversalIn, TransactionType(JournalTypeReversal, EntryDirectionCredit))
+	assert.Equal(t, TransactionTypeReversalOut, TransactionType(JournalTypeReversal, EntryDirectionDebit))
+	assert.Equal(t, TransactionTypeCorrectionIn, TransactionType(JournalTypeCorrection, EntryDirectionCredit))
+	assert.Equal(t, TransactionTypeCorrectionOut, TransactionType(JournalTypeCorrection, EntryDirectionDebit))
+
+	out := &Transaction{Type: TransactionTypeAdjustmentOut, Amount: decimal.NewFromInt(5)}
+	assert.True(t, out.SignedAmount().Equal(decimal.NewFromInt(-5)))
+	reversed := &Transaction{Type: TransactionTypeReversalOut, Amount: decimal.NewFromInt(5)}
+	assert.True(t, reversed.SignedAmount().Equal(decimal.NewFromInt(-5)))
+	corrected := &Transaction{Type: TransactionTypeCorrectionOut, Amount: decimal.NewFromInt(5)}
+	assert.True(t, corrected.SignedAmount().Equal(decimal.NewFromInt(-5)))
+}
+
+func TestWalletCheckActive(t *testing.T) {
+	assert.NoError(t, (&Wallet{Status: WalletStatusActive}).CheckActive())
+	assert.ErrorIs(t, (&Wallet{Status: WalletStatusFrozen}).CheckActive(), ErrWalletFrozen)
+	assert.ErrorIs(t, (&Wallet{Status: WalletStatusClosed}).CheckActive(), ErrWalletClosed)
+	assert.True(t, IsValidWalletStatus(WalletStatusFrozen))
+	assert.False(t, IsValidWalletStatus("suspended"))
+}
+
+func TestWalletAvailableBalance(t *testing.T) {
+	wallet := &Wallet{
+		Balance:     decimal.NewFromInt(100),
+		HeldBalance: decimal.NewFromInt(30),
+		Currency:    money.USD,
+	}
+
+	assert.True(t, wallet.Funds().Equal(money.New(decimal.NewFromInt(100), money.USD)))
+	assert.True(t, wallet.Held().Equal(money.New(decimal.NewFromInt(30), money.USD)))
+	assert.True(t, wallet.Available().Equal(money.New(decimal.NewFromInt(70), money.USD)))
+}
+
+func TestOccurrenceAt(t *testing.T) {
+	start := time.Date(2024, 1, 31, 9, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, start, OccurrenceAt(start, FrequencyOnce, 3))
+	assert.Equal(t, time.Date(2024, 2, 2, 9, 0, 0, 0, time.UTC), OccurrenceAt(start, FrequencyDaily, 2))
+	assert.Equal(t, time.Date(2024, 2, 14, 9, 0, 0, 0, time.UTC), OccurrenceAt(start, FrequencyWeekly, 2))
+
+	// Monthly runs keep the 31st where the month has one and use the last day otherwise
+	assert.Equal(t, time.Date(2024, 2, 29, 9, 0, 0, 0, time.UTC), OccurrenceAt(start, FrequencyMonthly, 1))
+	assert.Equal(t, time.Date(2024, 3, 31, 9, 0, 0, 0, time.UTC), OccurrenceAt(start, FrequencyMonthly, 2))
+	assert.Equal(t, time.Date(2024, 4, 30, 9, 0, 0, 0, time.UTC), OccurrenceAt(start, FrequencyMonthly, 3))
+	assert.Equal(t, time.Date(2025, 1, 31, 9, 0, 0, 0, time.UTC), OccurrenceAt(start, FrequencyMonthly, 12))
+}
+
+func TestNewWalletEvent(t *testing.T) {
+	walletID := uuid.New()
+	deposit := &Journal{ID: uuid.New(), Type: JournalTypeDeposit, Entries: []*LedgerEntry{
+		{Direction: EntryDirectionDebit, Amount: decimal.NewFromInt(10), Currency: money.USD},
+		{WalletID: &walletID, Direction: EntryDirectionCredit, Amount: decimal.NewFromInt(10), Currency: money.USD},
+	}}
+
+	event := NewWalletEvent(deposit)
+
+	assert.Equal(t, EventWalletDeposit, event.Type)
+	assert.Equal(t, deposit.ID, event.JournalID)
+	assert.Nil(t, event.FromWalletID)
+	assert.Equal(t, walletID, *event.ToWalletID)
+	assert.True(t, event.Amount.Equal(decimal.NewFromInt(10)))
+	assert.Equal(t, walletID, event.KeyWalletID())
+}
+
+func TestNewWalletEventCarriesConversion(t *testing.T) {
+	from, to := uuid.New(), uuid.New()
+	rate, sent, received := decimal.RequireFromString("0.92"), decimal.NewFromInt(25), decimal.NewFromInt(23)
+	usd, eur := money.USD, money.EUR
+	transfer := &Journal{ID: uuid.New(), Type: JournalTypeTransfer, Entries: []*LedgerEntry{
+		{WalletID: &from, Direction: EntryDirectionDebit, Amount: sent, Currency: usd, ExchangeRate: &rate, CounterAmount: &received, CounterCurrency: &eur},
+		{WalletID: &to, Direction: EntryDirectionCredit, Amount: received, Currency: eur, ExchangeRate: &rate, CounterAmount: &sent, CounterCurrency: &usd},
+		{Direction: EntryDirectionCredit, Amount: sent, Currency: usd, ExchangeRate: &rate, CounterAmount: &received, CounterCurrency: &eur},
+		{Direction: EntryDirectionDebit, Amount: received, Currency: eur, ExchangeRate: &rate, CounterAmount: &sent, CounterCurrency: &usd},
+	}}
+	require.NoError(t, transfer.Validate())
+
+	event := NewWalletEvent(transfer)
+
+	assert.Equal(t, from, *event.FromWalletID)
+	assert.Equal(t, to, *event.ToWalletID)
+	assert.True(t, event.Amount.Equal(sent))
+	assert.Equal(t, money.USD, event.Currency)
+	assert.True(t, event.ExchangeRate.Equal(rate))
+	assert.True(t, event.CreditedAmount.Equal(received))
+	assert.Equal(t, money.EUR, *event.CreditedCurrency)
+}
+
+func TestNewTransfer(t *testing.T) {
+	from, to := uuid.New(), uuid.New()
+	rate, sent, received := decimal.RequireFromString("0.92"), decimal.NewFromInt(25), decimal.NewFromInt(23)
+	usd, eur := money.USD, money.EUR
+
+	t.Run("between currencies", func(t *testing.T) {
+		// The settlement legs come first here to show they are never taken for a party
+		journal := &Journal{ID: uuid.New(), Type: JournalTypeTransfer, Entries: []*LedgerEntry{
+			{Direction: EntryDirectionDebit, Amount: received, Currency: eur, ExchangeRate: &rate, CounterAmount: &sent, CounterCurrency: &usd},
+			{Direction: EntryDirectionCredit, Amount: sent, Currency: usd, ExchangeRate: &rate, CounterAmount: &received, CounterCurrency: &eur},
+			{WalletID: &from, Direction: EntryDirectionDebit, Amount: sent, Currency: usd, ExchangeRate: &rate, CounterAmount: &received, CounterCurrency: &eur},
+			{WalletID: &to, Direction: EntryDirectionCredit, Amount: received, Currency: eur, ExchangeRate: &rate, CounterAmount: &sent, CounterCurrency: &usd},
+		}}
+
+		transfer, ok := NewTransfer(journal)
+
+		require.True(t, ok)
+		assert.Equal(t, journal.ID, transfer.ReferenceID)
+		assert.Equal(t, TransferStatusCompleted, transfer.Status)
+		assert.Equal(t, from, transfer.FromWalletID)
+		assert.Equal(t, to, transfer.ToWalletID)
+		assert.True(t, transfer.Amount.Equal(sent))
+		assert.Equal(t, money.USD, transfer.Currency)
+		assert.True(t, transfer.CreditedAmount.Equal(received))
+		assert.Equal(t, money.EUR, *transfer.CreditedCurrency)
+		assert.Len(t, transfer.Legs, 4)
+	})
+
+	t.Run("not a transfer", func(t *testing.T) {
+		deposit := &Journal{ID: uuid.New(), Type: JournalTypeDeposit, Entries: []*LedgerEntry{
+			{Direction: EntryDirectionDebit, Amount: sent, Currency: usd},
+			{WalletID: &to, Direction: EntryDirectionCredit, Amount: sent, Currency: usd},
+		}}
+
+		_, ok := NewTransfer(deposit)
+
+		assert.False(t, ok)
+	})
+
+	assert.Equal(t, TransferDirectionOutgoing, TransferDirection(EntryDirectionDebit))
+	assert.Equal(t, TransferDirectionIncoming, TransferDirection(EntryDirectionCredit))
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// Transaction alerts users can be emailed
+const (
+	// AlertLargeWithdrawal is sent for a withdrawal of at least the user's threshold
+	AlertLargeWithdrawal = "large_withdrawal"
+	// AlertIncomingTransfer is sent when another wallet transfers money to the user
+	AlertIncomingTransfer = "incoming_transfer"
+	// AlertLowBalance is sent when money leaving the wallet takes its balance below
+	// the threshold set for the wallet
+	AlertLowBalance = "low_balance"
+)
+
+// NotificationPreferences choose the transaction alerts a user is emailed. Thresholds
+// are in the wallet's currency, and a nil one turns its alert off. Low balance alerts
+// are chosen per wallet, with WalletAlertSettings.
+type NotificationPreferences struct {
+	UserID                   uuid.UUID        `db:"user_id" json:"user_id"`
+	LargeWithdrawalThreshold *decimal.Decimal `db:"large_withdrawal_threshold" json:"large_withdrawal_threshold,omitempty"`
+	IncomingTransfer         bool             `db:"incoming_transfer" json:"incoming_transfer"`
+	UpdatedAt                time.Time        `db:"updated_at" json:"updated_at"`
+}
@@ -0,0 +1,100 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+
+	"github.com/shanwije/wallet-app/pkg/money"
+)
+
+// Wallet event types, one per journal type
+const (
+	EventWalletDeposit    = "wallet.deposit"
+	EventWalletWithdraw   = "wallet.withdraw"
+	EventWalletTransfer   = "wallet.transfer"
+	EventWalletAdjustment = "wallet.adjustment"
+	EventWalletReversal   = "wallet.reversal"
+	EventWalletCorrection = "wallet.correction"
+)
+
+// EventLowBalanceAlert is published with the WalletAlert raised when a movement took a
+// wallet's balance below its owner's threshold
+const EventLowBalanceAlert = "alert." + AlertLowBalance
+
+// OutboxEvent is an event written in the same transaction as the change it describes
+// and published afterwards. WalletID keys the event so consumers can keep a wallet's
+// events in order; PublishedAt is nil until a publisher has accepted it.
+type OutboxEvent struct {
+	ID          uuid.UUID       `db:"id" json:"id"`
+	Type        string          `db:"event_type" json:"type"`
+	WalletID    uuid.UUID       `db:"wallet_id" json:"wallet_id"`
+	Payload     json.RawMessage `db:"payload" json:"payload"`
+	Attempts    int             `db:"attempts" json:"attempts"`
+	LastError   *string         `db:"last_error" json:"last_error,omitempty"`
+	CreatedAt   time.Time       `db:"created_at" json:"created_at"`
+	PublishedAt *time.Time      `db:"published_at" json:"published_at,omitempty"`
+}
+
+// WalletEvent is the payload of a wallet.* event. A nil wallet ID is the external
+// settlement account, so without system wallets deposits have no FromWalletID and
+// withdrawals no ToWalletID; with them, those are the treasury wallet.
+type WalletEvent struct {
+	Type         string          `json:"type"`
+	JournalID    uuid.UUID       `json:"journal_id"`
+	FromWalletID *uuid.UUID      `json:"from_wallet_id,omitempty"`
+	ToWalletID   *uuid.UUID      `json:"to_wallet_id,omitempty"`
+	Amount       decimal.Decimal `json:"amount"`
+	Currency     money.Currency  `json:"currency"`
+	// A transfer between currencies also carries its rate and what the recipient was credited
+	ExchangeRate     *decimal.Decimal `json:"exchange_rate,omitempty"`
+	CreditedAmount   *decimal.Decimal `json:"credited_amount,omitempty"`
+	CreditedCurrency *money.Currency  `json:"credited_currency,omitempty"`
+	// A reversal carries the journal it undoes
+	ReversesJournalID *uuid.UUID `json:"reverses_journal_id,omitempty"`
+	Description       *string    `json:"description,omitempty"`
+	OccurredAt        time.Time  `json:"occurred_at"`
+}
+
+// NewWalletEvent describes a recorded journal. The first debit and credit legs give
+// the direction of the movement.
+func NewWalletEvent(journal *Journal) WalletEvent {
+	event := WalletEvent{
+		Type:              "wallet." + journal.Type,
+		JournalID:         journal.ID,
+		ReversesJournalID: journal.ReversesJournalID,
+		Description:       journal.Description,
+		OccurredAt:        journal.CreatedAt,
+	}
+	var debited, credited bool
+	for _, entry := range journal.Entries {
+		switch {
+		case entry.Direction == EntryDirectionDebit && !debited:
+			debited = true
+			event.FromWalletID = entry.WalletID
+			event.Amount, event.Currency = entry.Amount, entry.Currency
+		case entry.Direction == EntryDirectionCredit && !credited:
+			credited = true
+			event.ToWalletID = entry.WalletID
+			if entry.ExchangeRate != nil {
+				amount, currency := entry.Amount, entry.Currency
+				event.ExchangeRate = entry.ExchangeRate
+				event.CreditedAmount, event.CreditedCurrency = &amount, &currency
+			}
+		}
+	}
+	return event
+}
+
+// KeyWalletID is the wallet the event is about: the sender, or the recipient of a deposit
+func (e WalletEvent) KeyWalletID() uuid.UUID {
+	if e.FromWalletID != nil {
+		return *e.FromWalletID
+	}
+	if e.ToWalletID != nil {
+		return *e.ToWalletID
+	}
+	return uuid.Nil
+}
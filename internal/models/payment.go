@@ -0,0 +1,73 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shanwije/wallet-app/pkg/money"
+	"github.com/shopspring/decimal"
+)
+
+// Payment statuses. A payment is completed until refunds start paying it back.
+const (
+	PaymentStatusCompleted         = "completed"
+	PaymentStatusPartiallyRefunded = "partially_refunded"
+	PaymentStatusRefunded          = "refunded"
+)
+
+// MerchantAccount is a wallet an operator registered to take payments for orders.
+// Name is what its payers see.
+type MerchantAccount struct {
+	WalletID  uuid.UUID `db:"wallet_id" json:"wallet_id"`
+	Name      string    `db:"name" json:"name"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
+
+// Payment is a wallet paying a merchant for the order OrderReference, which the
+// merchant is paid for once. It was posted as JournalID; RefundedAmount is how much of
+// it the merchant has paid back since.
+type Payment struct {
+	ID               uuid.UUID        `db:"id" json:"id"`
+	PayerWalletID    uuid.UUID        `db:"payer_wallet_id" json:"payer_wallet_id"`
+	MerchantWalletID uuid.UUID        `db:"merchant_wallet_id" json:"merchant_wallet_id"`
+	OrderReference   string           `db:"order_reference" json:"order_reference"`
+	Amount           decimal.Decimal  `db:"amount" json:"amount"`
+	RefundedAmount   decimal.Decimal  `db:"refunded_amount" json:"refunded_amount"`
+	Currency         money.Currency   `db:"currency" json:"currency"`
+	Status           string           `db:"status" json:"status"` // completed, partially_refunded, refunded
+	JournalID        uuid.UUID        `db:"journal_id" json:"journal_id"`
+	Refunds          []*PaymentRefund `db:"-" json:"refunds"`
+	CreatedAt        time.Time        `db:"created_at" json:"created_at"`
+	UpdatedAt        time.Time        `db:"updated_at" json:"updated_at"`
+}
+
+// Refundable returns what is left of the payment to refund
+func (p *Payment) Refundable() money.Money {
+	return money.New(p.Amount.Sub(p.RefundedAmount), p.Currency)
+}
+
+// PaymentRefund is money a merchant paid back against a payment, posted as JournalID
+type PaymentRefund struct {
+	ID        uuid.UUID       `db:"id" json:"id"`
+	PaymentID uuid.UUID       `db:"payment_id" json:"payment_id"`
+	Amount    decimal.Decimal `db:"amount" json:"amount"`
+	Currency  money.Currency  `db:"currency" json:"currency"`
+	Reason    *string         `db:"reason" json:"reason,omitempty"`
+	JournalID uuid.UUID       `db:"journal_id" json:"journal_id"`
+	CreatedAt time.Time       `db:"created_at" json:"created_at"`
+}
+
+// MerchantSettlement totals what a merchant wallet was paid, and paid back in refunds,
+// over the period [From, To). Refunds count in the period they were made, whenever the
+// payment was.
+type MerchantSettlement struct {
+	WalletID       uuid.UUID       `json:"wallet_id"`
+	Currency       money.Currency  `json:"currency"`
+	From           time.Time       `json:"from"`
+	To             time.Time       `json:"to"`
+	PaymentCount   int64           `db:"payment_count" json:"payment_count"`
+	GrossAmount    decimal.Decimal `db:"gross_amount" json:"gross_amount"`
+	RefundCount    int64           `db:"refund_count" json:"refund_count"`
+	RefundedAmount decimal.Decimal `db:"refunded_amount" json:"refunded_amount"`
+	NetAmount      decimal.Decimal `json:"net_amount"`
+}
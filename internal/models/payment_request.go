@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shanwije/wallet-app/pkg/money"
+	"github.com/shopspring/decimal"
+)
+
+// Payment request statuses. Only pending requests can be answered; the others are final.
+const (
+	PaymentRequestStatusPending  = "pending"
+	PaymentRequestStatusAccepted = "accepted"
+	PaymentRequestStatusDeclined = "declined"
+)
+
+// PaymentRequest asks the payer wallet to send Amount to the requester wallet.
+// Accepting it runs the transfer, recorded as TransferJournalID; declining it ends it.
+type PaymentRequest struct {
+	ID                uuid.UUID       `db:"id" json:"id"`
+	RequesterWalletID uuid.UUID       `db:"requester_wallet_id" json:"requester_wallet_id"`
+	PayerWalletID     uuid.UUID       `db:"payer_wallet_id" json:"payer_wallet_id"`
+	Amount            decimal.Decimal `db:"amount" json:"amount"`
+	Currency          money.Currency  `db:"currency" json:"currency"`
+	Description       *string         `db:"description" json:"description,omitempty"`
+	Status            string          `db:"status" json:"status"` // pending, accepted, declined
+	TransferJournalID *uuid.UUID      `db:"transfer_journal_id" json:"transfer_journal_id,omitempty"`
+	CreatedAt         time.Time       `db:"created_at" json:"created_at"`
+	UpdatedAt         time.Time       `db:"updated_at" json:"updated_at"`
+}
+
+// Funds returns the requested amount in its currency
+func (p *PaymentRequest) Funds() money.Money {
+	return money.New(p.Amount, p.Currency)
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shanwije/wallet-app/pkg/money"
+	"github.com/shopspring/decimal"
+)
+
+// Payout statuses. A payout is initiated once its wallet is debited, sent once the
+// provider has it, and ends settled when the bank pays it or failed when it never will,
+// in which case the wallet is credited back.
+const (
+	PayoutStatusInitiated = "initiated"
+	PayoutStatusSent      = "sent"
+	PayoutStatusSettled   = "settled"
+	PayoutStatusFailed    = "failed"
+)
+
+// Payout is money sent from a wallet to a bank account. The wallet was debited by
+// JournalID; RefundJournalID credited it back when the payout failed.
+type Payout struct {
+	ID                uuid.UUID       `db:"id" json:"id"`
+	WalletID          uuid.UUID       `db:"wallet_id" json:"wallet_id"`
+	Amount            decimal.Decimal `db:"amount" json:"amount"`
+	Currency          money.Currency  `db:"currency" json:"currency"`
+	AccountName       string          `db:"account_name" json:"account_name"`
+	AccountNumber     string          `db:"account_number" json:"account_number"`
+	BankCode          string          `db:"bank_code" json:"bank_code"`
+	Status            string          `db:"status" json:"status"` // initiated, sent, settled, failed
+	ProviderReference *string         `db:"provider_reference" json:"provider_reference,omitempty"`
+	FailureReason     *string         `db:"failure_reason" json:"failure_reason,omitempty"`
+	JournalID         uuid.UUID       `db:"journal_id" json:"journal_id"`
+	RefundJournalID   *uuid.UUID      `db:"refund_journal_id" json:"refund_journal_id,omitempty"`
+	CreatedAt         time.Time       `db:"created_at" json:"created_at"`
+	UpdatedAt         time.Time       `db:"updated_at" json:"updated_at"`
+}
+
+// Funds returns the amount paid out
+func (p *Payout) Funds() money.Money {
+	return money.New(p.Amount, p.Currency)
+}
+
+// IsFinal reports whether the payout has settled or failed
+func (p *Payout) IsFinal() bool {
+	return p.Status == PayoutStatusSettled || p.Status == PayoutStatusFailed
+}
@@ -0,0 +1,83 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shanwije/wallet-app/pkg/money"
+	"github.com/shopspring/decimal"
+)
+
+// Pending transfer statuses. Only pending transfers can be confirmed, approved or
+// cancelled; the others are final.
+const (
+	PendingTransferStatusPending   = "pending"
+	PendingTransferStatusCompleted = "completed"
+	PendingTransferStatusFailed    = "failed"
+	PendingTransferStatusCancelled = "cancelled"
+)
+
+// PendingTransfer is a transfer over the confirmation threshold that waits for its
+// sender to confirm it, or an operator to approve it, before any money moves. Running
+// it posts TransferJournalID, filed under the Category and Tags of the request that
+// held it; Reason says why it failed or was cancelled. One still pending at ExpiresAt
+// is cancelled.
+type PendingTransfer struct {
+	ID                uuid.UUID       `db:"id" json:"id"`
+	FromWalletID      uuid.UUID       `db:"from_wallet_id" json:"from_wallet_id"`
+	ToWalletID        uuid.UUID       `db:"to_wallet_id" json:"to_wallet_id"`
+	Amount            decimal.Decimal `db:"amount" json:"amount"`
+	Currency          money.Currency  `db:"currency" json:"currency"`
+	Description       *string         `db:"description" json:"description,omitempty"`
+	Category          *string         `db:"category" json:"category,omitempty"`
+	Tags              TagList         `db:"tags" json:"tags,omitempty"`
+	Status            string          `db:"status" json:"status"` // pending, completed, failed, cancelled
+	Reason            *string         `db:"reason" json:"reason,omitempty"`
+	TransferJournalID *uuid.UUID      `db:"transfer_journal_id" json:"transfer_journal_id,omitempty"`
+	ExpiresAt         time.Time       `db:"expires_at" json:"expires_at"`
+	CreatedAt         time.Time       `db:"created_at" json:"created_at"`
+	UpdatedAt         time.Time       `db:"updated_at" json:"updated_at"`
+	// IdempotencyKey is the sender's scoped Idempotency-Key, which a retry of the
+	// request that held the transfer finds it by
+	IdempotencyKey *string `db:"idempotency_key" json:"-"`
+}
+
+// Funds returns the transfer amount in its currency
+func (t *PendingTransfer) Funds() money.Money {
+	return money.New(t.Amount, t.Currency)
+}
+
+// TagList is a list of tags stored in one column as a JSON array
+type TagList []string
+
+// Value stores the tags as a JSON array, or NULL when there are none
+func (t TagList) Value() (driver.Value, error) {
+	if len(t) == 0 {
+		return nil, nil
+	}
+	encoded, err := json.Marshal([]string(t))
+	if err != nil {
+		return nil, err
+	}
+	return string(encoded), nil
+}
+
+// Scan reads tags stored by Value
+func (t *TagList) Scan(src any) error {
+	var encoded []byte
+	switch v := src.(type) {
+	case nil:
+		*t = nil
+		return nil
+	case string:
+		encoded = []byte(v)
+	case []byte:
+		encoded = v
+	default:
+		return fmt.Errorf("cannot scan %T into tags", src)
+	}
+	return json.Unmarshal(encoded, (*[]string)(t))
+}
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shanwije/wallet-app/pkg/money"
+	"github.com/shopspring/decimal"
+)
+
+// Provider payment statuses, as the payment provider reports them. A payment can fail
+// and later succeed when the customer retries it, but never the other way round.
+const (
+	ProviderPaymentStatusPending   = "pending"
+	ProviderPaymentStatusSucceeded = "succeeded"
+	ProviderPaymentStatusFailed    = "failed"
+)
+
+// ProviderPayment is a payment the payment provider took to fund a wallet, identified
+// by the provider's PaymentID. JournalID deposited it once it succeeded.
+type ProviderPayment struct {
+	ID            uuid.UUID       `db:"id" json:"id"`
+	PaymentID     string          `db:"payment_id" json:"payment_id"`
+	WalletID      uuid.UUID       `db:"wallet_id" json:"wallet_id"`
+	Amount        decimal.Decimal `db:"amount" json:"amount"`
+	Currency      money.Currency  `db:"currency" json:"currency"`
+	Status        string          `db:"status" json:"status"` // pending, succeeded, failed
+	FailureReason *string         `db:"failure_reason" json:"failure_reason,omitempty"`
+	JournalID     *uuid.UUID      `db:"journal_id" json:"journal_id,omitempty"`
+	CreatedAt     time.Time       `db:"created_at" json:"created_at"`
+	UpdatedAt     time.Time       `db:"updated_at" json:"updated_at"`
+}
+
+// Funds returns the amount paid
+func (p *ProviderPayment) Funds() money.Money {
+	return money.New(p.Amount, p.Currency)
+}
+
+// CanBecome reports whether a report of status may replace the payment's current one.
+// Reports can arrive in any order, so one that would move the payment backwards, such
+// as a late pending after it succeeded, is stale.
+func (p *ProviderPayment) CanBecome(status string) bool {
+	rank := map[string]int{
+		ProviderPaymentStatusPending:   0,
+		ProviderPaymentStatusFailed:    1,
+		ProviderPaymentStatusSucceeded: 2,
+	}
+	return rank[status] > rank[p.Status]
+}
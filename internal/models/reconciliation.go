@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+
+	"github.com/shanwije/wallet-app/pkg/money"
+)
+
+// ReconciliationRun is one comparison of every wallet's stored balance with its ledger
+type ReconciliationRun struct {
+	ID               uuid.UUID `db:"id" json:"id"`
+	StartedAt        time.Time `db:"started_at" json:"started_at"`
+	FinishedAt       time.Time `db:"finished_at" json:"finished_at"`
+	WalletsChecked   int64     `db:"wallets_checked" json:"wallets_checked"`
+	DiscrepancyCount int       `db:"discrepancy_count" json:"discrepancy_count"`
+	// Discrepancies is only filled in for a run that has just finished
+	Discrepancies []*BalanceDiscrepancy `db:"-" json:"discrepancies,omitempty"`
+}
+
+// BalanceDiscrepancy is a wallet whose stored balance a reconciliation run found out of
+// line with the sum of its ledger entries. Difference is Balance - LedgerBalance.
+type BalanceDiscrepancy struct {
+	ID            uuid.UUID       `db:"id" json:"id"`
+	RunID         uuid.UUID       `db:"run_id" json:"run_id"`
+	WalletID      uuid.UUID       `db:"wallet_id" json:"wallet_id"`
+	Balance       decimal.Decimal `db:"balance" json:"balance"`
+	LedgerBalance decimal.Decimal `db:"ledger_balance" json:"ledger_balance"`
+	Difference    decimal.Decimal `db:"difference" json:"difference"`
+	Currency      money.Currency  `db:"currency" json:"currency"`
+	CreatedAt     time.Time       `db:"created_at" json:"created_at"`
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+
+	"github.com/shanwije/wallet-app/pkg/money"
+)
+
+// Risk actions, from least to most severe. Flagged operations go ahead but are kept
+// for review; blocked ones are refused.
+const (
+	RiskActionAllow = "allow"
+	RiskActionFlag  = "flag"
+	RiskActionBlock = "block"
+)
+
+// RiskDecision records a withdrawal or transfer that the risk checks flagged or blocked.
+// Operation is the journal type the movement would have been posted as.
+type RiskDecision struct {
+	ID                   uuid.UUID       `db:"id" json:"id"`
+	WalletID             uuid.UUID       `db:"wallet_id" json:"wallet_id"`
+	Operation            string          `db:"operation" json:"operation"` // withdraw, transfer
+	CounterpartyWalletID *uuid.UUID      `db:"counterparty_wallet_id" json:"counterparty_wallet_id,omitempty"`
+	Amount               decimal.Decimal `db:"amount" json:"amount"`
+	Currency             money.Currency  `db:"currency" json:"currency"`
+	Action               string          `db:"action" json:"action"` // flag, block
+	Reasons              string          `db:"reasons" json:"reasons"`
+	CreatedAt            time.Time       `db:"created_at" json:"created_at"`
+}
+
+// RiskSeverity orders risk actions so the most severe of several can be picked
+func RiskSeverity(action string) int {
+	switch action {
+	case RiskActionBlock:
+		return 2
+	case RiskActionFlag:
+		return 1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,84 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shanwije/wallet-app/pkg/money"
+	"github.com/shopspring/decimal"
+)
+
+// Scheduled transfer frequencies
+const (
+	FrequencyOnce    = "once"
+	FrequencyDaily   = "daily"
+	FrequencyWeekly  = "weekly"
+	FrequencyMonthly = "monthly"
+)
+
+// Scheduled transfer statuses. Only active transfers run; the others are final.
+const (
+	ScheduleStatusActive    = "active"
+	ScheduleStatusCompleted = "completed"
+	ScheduleStatusFailed    = "failed"
+	ScheduleStatusCancelled = "cancelled"
+)
+
+// ScheduledTransfer moves Amount from one wallet to another at StartAt and, for
+// recurring frequencies, again at every following occurrence until cancelled.
+// Occurrence counts the runs already processed, successful or not.
+type ScheduledTransfer struct {
+	ID           uuid.UUID       `db:"id" json:"id"`
+	FromWalletID uuid.UUID       `db:"from_wallet_id" json:"from_wallet_id"`
+	ToWalletID   uuid.UUID       `db:"to_wallet_id" json:"to_wallet_id"`
+	Amount       decimal.Decimal `db:"amount" json:"amount"`
+	Currency     money.Currency  `db:"currency" json:"currency"`
+	Description  *string         `db:"description" json:"description,omitempty"`
+	Frequency    string          `db:"frequency" json:"frequency"` // once, daily, weekly, monthly
+	StartAt      time.Time       `db:"start_at" json:"start_at"`
+	NextRunAt    time.Time       `db:"next_run_at" json:"next_run_at"`
+	Occurrence   int             `db:"occurrence" json:"occurrence"`
+	Status       string          `db:"status" json:"status"` // active, completed, failed, cancelled
+	LastError    *string         `db:"last_error" json:"last_error,omitempty"`
+	CreatedAt    time.Time       `db:"created_at" json:"created_at"`
+	UpdatedAt    time.Time       `db:"updated_at" json:"updated_at"`
+}
+
+// Funds returns the transfer amount in its currency
+func (t *ScheduledTransfer) Funds() money.Money {
+	return money.New(t.Amount, t.Currency)
+}
+
+// IsRecurring reports whether the transfer runs more than once
+func (t *ScheduledTransfer) IsRecurring() bool {
+	return t.Frequency != FrequencyOnce
+}
+
+// OccurrenceAt returns when the n-th run (counting from zero) of a schedule
+// starting at start is due. Monthly runs keep the day of the month of start,
+// falling back to the last day of shorter months.
+func OccurrenceAt(start time.Time, frequency string, n int) time.Time {
+	switch frequency {
+	case FrequencyDaily:
+		return start.AddDate(0, 0, n)
+	case FrequencyWeekly:
+		return start.AddDate(0, 0, 7*n)
+	case FrequencyMonthly:
+		firstOfMonth := time.Date(start.Year(), start.Month()+time.Month(n), 1,
+			start.Hour(), start.Minute(), start.Second(), start.Nanosecond(), start.Location())
+		lastDay := firstOfMonth.AddDate(0, 1, -1).Day()
+		return firstOfMonth.AddDate(0, 0, min(start.Day(), lastDay)-1)
+	default:
+		return start
+	}
+}
+
+// IsValidFrequency validates scheduled transfer frequency
+func IsValidFrequency(frequency string) bool {
+	switch frequency {
+	case FrequencyOnce, FrequencyDaily, FrequencyWeekly, FrequencyMonthly:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shanwije/wallet-app/pkg/money"
+	"github.com/shopspring/decimal"
+)
+
+// Statement summarises a wallet's ledger over the period [From, To).
+// ClosingBalance is only set once every line has been produced.
+type Statement struct {
+	WalletID       uuid.UUID
+	Currency       money.Currency
+	From           time.Time
+	To             time.Time
+	OpeningBalance decimal.Decimal
+	ClosingBalance decimal.Decimal
+}
+
+// StatementLine is one transaction on a statement with the wallet balance after it
+type StatementLine struct {
+	Transaction *Transaction
+	Balance     decimal.Decimal
+}
+
+// FormatAmount renders an amount to the minor units of the statement's currency
+func (s *Statement) FormatAmount(amount decimal.Decimal) string {
+	return amount.StringFixed(s.Currency.MinorUnits())
+}
@@ -5,6 +5,8 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/shopspring/decimal"
+
+	"github.com/shanwije/wallet-app/pkg/money"
 )
 
 // Transaction types as constants
@@ -13,22 +15,84 @@ const (
 	TransactionTypeWithdraw    = "withdraw"
 	TransactionTypeTransferIn  = "transfer_in"
 	TransactionTypeTransferOut = "transfer_out"
+	// Adjustments are operator corrections that add to or take from the wallet
+	TransactionTypeAdjustmentIn  = "adjustment_in"
+	TransactionTypeAdjustmentOut = "adjustment_out"
+	// Reversals undo an earlier transaction, returning or taking back its money
+	TransactionTypeReversalIn  = "reversal_in"
+	TransactionTypeReversalOut = "reversal_out"
+	// Corrections undo an erroneous transaction on an operator's behalf
+	TransactionTypeCorrectionIn  = "correction_in"
+	TransactionTypeCorrectionOut = "correction_out"
+	// Fees are charged to the paying wallet and collected by the fee-collection wallet
+	TransactionTypeFeeIn  = "fee_in"
+	TransactionTypeFeeOut = "fee_out"
 )
 
+// Transaction is a wallet's view of one ledger entry, as returned in its history.
+// ReferenceID is the journal the entry belongs to, shared by both legs of a transfer.
+// A transfer between currencies also carries the rate and the counterparty's amount, and
+// a reversal or correction the reference ID of the journal it undoes. A transaction an
+// operator found erroneous carries the reference ID of the correction that undid it.
+// Category and Tags are what the client filed the journal under.
 type Transaction struct {
-	ID          uuid.UUID       `db:"id" json:"id"`
-	WalletID    uuid.UUID       `db:"wallet_id" json:"wallet_id"`
-	Type        string          `db:"type" json:"type"` // deposit, withdraw, transfer_in, transfer_out
-	Amount      decimal.Decimal `db:"amount" json:"amount"`
-	ReferenceID *uuid.UUID      `db:"reference_id" json:"reference_id,omitempty"`
-	Description *string         `db:"description" json:"description,omitempty"`
-	CreatedAt   time.Time       `db:"created_at" json:"created_at"`
+	ID                     uuid.UUID        `db:"id" json:"id"`
+	WalletID               uuid.UUID        `db:"wallet_id" json:"wallet_id"`
+	Type                   string           `db:"type" json:"type"` // deposit, withdraw, transfer_in, transfer_out, adjustment_in, adjustment_out, reversal_in, reversal_out, correction_in, correction_out, fee_in, fee_out
+	Amount                 decimal.Decimal  `db:"amount" json:"amount"`
+	ExchangeRate           *decimal.Decimal `db:"exchange_rate" json:"exchange_rate,omitempty"`
+	CounterAmount          *decimal.Decimal `db:"counter_amount" json:"counter_amount,omitempty"`
+	CounterCurrency        *money.Currency  `db:"counter_currency" json:"counter_currency,omitempty"`
+	ReferenceID            *uuid.UUID       `db:"reference_id" json:"reference_id,omitempty"`
+	ReversesReferenceID    *uuid.UUID       `db:"reverses_reference_id" json:"reverses_reference_id,omitempty"`
+	CorrectionReason       *string          `db:"correction_reason" json:"correction_reason,omitempty"`
+	CorrectedByReferenceID *uuid.UUID       `db:"corrected_by_reference_id" json:"corrected_by_reference_id,omitempty"`
+	Description            *string          `db:"description" json:"description,omitempty"`
+	Category               *string          `db:"category" json:"category,omitempty"`
+	Tags                   []string         `db:"-" json:"tags,omitempty"`
+	CreatedAt              time.Time        `db:"created_at" json:"created_at"`
+}
+
+// SignedAmount returns the amount as it affects the wallet balance: positive for money
+// coming in, negative for money going out
+func (t *Transaction) SignedAmount() decimal.Decimal {
+	switch t.Type {
+	case TransactionTypeWithdraw, TransactionTypeTransferOut, TransactionTypeAdjustmentOut, TransactionTypeReversalOut, TransactionTypeCorrectionOut,
+		TransactionTypeFeeOut:
+		return t.Amount.Neg()
+	default:
+		return t.Amount
+	}
+}
+
+// NewTransaction is the wallet's view of its leg of a journal. entry must be one of the
+// journal's entries and belong to a wallet.
+func NewTransaction(journal *Journal, entry *LedgerEntry) *Transaction {
+	referenceID := journal.ID
+	return &Transaction{
+		ID:                  entry.ID,
+		WalletID:            *entry.WalletID,
+		Type:                TransactionType(journal.Type, entry.Direction),
+		Amount:              entry.Amount,
+		ExchangeRate:        entry.ExchangeRate,
+		CounterAmount:       entry.CounterAmount,
+		CounterCurrency:     entry.CounterCurrency,
+		ReferenceID:         &referenceID,
+		ReversesReferenceID: journal.ReversesJournalID,
+		CorrectionReason:    journal.CorrectionReason,
+		Description:         journal.Description,
+		Category:            journal.Category,
+		Tags:                journal.Tags,
+		CreatedAt:           entry.CreatedAt,
+	}
 }
 
 // IsValidTransactionType validates transaction type
 func IsValidTransactionType(txType string) bool {
 	switch txType {
-	case TransactionTypeDeposit, TransactionTypeWithdraw, TransactionTypeTransferIn, TransactionTypeTransferOut:
+	case TransactionTypeDeposit, TransactionTypeWithdraw, TransactionTypeTransferIn, TransactionTypeTransferOut,
+		TransactionTypeAdjustmentIn, TransactionTypeAdjustmentOut, TransactionTypeReversalIn, TransactionTypeReversalOut,
+		TransactionTypeCorrectionIn, TransactionTypeCorrectionOut, TransactionTypeFeeIn, TransactionTypeFeeOut:
 		return true
 	default:
 		return false
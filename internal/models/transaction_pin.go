@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TransactionPIN is the PIN a user gives to step up a withdrawal or transfer over the
+// configured threshold. FailedAttempts counts wrong PINs since the last right one.
+type TransactionPIN struct {
+	UserID         uuid.UUID  `db:"user_id" json:"user_id"`
+	PINHash        string     `db:"pin_hash" json:"-"`
+	FailedAttempts int        `db:"failed_attempts" json:"failed_attempts"`
+	LockedUntil    *time.Time `db:"locked_until" json:"locked_until,omitempty"`
+	CreatedAt      time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt      time.Time  `db:"updated_at" json:"updated_at"`
+}
+
+// TransactionPINStatus tells a user whether they have set a transaction PIN, and until
+// when it is locked after too many wrong attempts
+type TransactionPINStatus struct {
+	UserID      uuid.UUID  `json:"user_id"`
+	Enabled     bool       `json:"enabled"`
+	LockedUntil *time.Time `json:"locked_until,omitempty"`
+}
@@ -0,0 +1,108 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+
+	"github.com/shanwije/wallet-app/pkg/money"
+)
+
+// TransferStatusCompleted is the status of every recorded transfer: journals are
+// written only once the money has moved
+const TransferStatusCompleted = "completed"
+
+// Transfer directions, relative to the wallet whose activity is listed
+const (
+	TransferDirectionIncoming = "incoming"
+	TransferDirectionOutgoing = "outgoing"
+)
+
+// Transfer is a movement between two wallets with every leg of its journal.
+// ReferenceID is the journal's ID, the reference_id its transactions carry.
+type Transfer struct {
+	ReferenceID  uuid.UUID       `json:"reference_id"`
+	Status       string          `json:"status" example:"completed"`
+	FromWalletID uuid.UUID       `json:"from_wallet_id"`
+	ToWalletID   uuid.UUID       `json:"to_wallet_id"`
+	Amount       decimal.Decimal `json:"amount"`
+	Currency     money.Currency  `json:"currency"`
+	// ExchangeRate, CreditedAmount and CreditedCurrency are set for a transfer between
+	// currencies: the rate used and what the recipient received
+	ExchangeRate     *decimal.Decimal `json:"exchange_rate,omitempty"`
+	CreditedAmount   *decimal.Decimal `json:"credited_amount,omitempty"`
+	CreditedCurrency *money.Currency  `json:"credited_currency,omitempty"`
+	Description      *string          `json:"description,omitempty"`
+	Legs             []*LedgerEntry   `json:"legs"`
+	CreatedAt        time.Time        `json:"created_at"`
+}
+
+// NewTransfer assembles a transfer from its journal. It reports false for journals
+// that are not transfers between two wallets.
+func NewTransfer(journal *Journal) (*Transfer, bool) {
+	if journal.Type != JournalTypeTransfer {
+		return nil, false
+	}
+
+	transfer := &Transfer{
+		ReferenceID: journal.ID,
+		Status:      TransferStatusCompleted,
+		Description: journal.Description,
+		Legs:        journal.Entries,
+		CreatedAt:   journal.CreatedAt,
+	}
+	var from, to *LedgerEntry
+	for _, entry := range journal.Entries {
+		if entry.WalletID == nil {
+			// Settlement legs that carry a conversion between currencies
+			continue
+		}
+		switch {
+		case entry.Direction == EntryDirectionDebit && from == nil:
+			from = entry
+		case entry.Direction == EntryDirectionCredit && to == nil:
+			to = entry
+		}
+	}
+	if from == nil || to == nil {
+		return nil, false
+	}
+
+	transfer.FromWalletID, transfer.ToWalletID = *from.WalletID, *to.WalletID
+	transfer.Amount, transfer.Currency = from.Amount, from.Currency
+	if to.ExchangeRate != nil {
+		amount, currency := to.Amount, to.Currency
+		transfer.ExchangeRate = to.ExchangeRate
+		transfer.CreditedAmount, transfer.CreditedCurrency = &amount, &currency
+	}
+	return transfer, true
+}
+
+// WalletTransfer is a transfer as it appears in one wallet's activity feed. Amount is
+// what left or reached this wallet; for a transfer between currencies, CounterAmount
+// is the counterparty's side.
+type WalletTransfer struct {
+	ReferenceID          uuid.UUID        `json:"reference_id"`
+	Status               string           `json:"status" example:"completed"`
+	Direction            string           `json:"direction" example:"outgoing"` // incoming or outgoing
+	Amount               decimal.Decimal  `json:"amount"`
+	Currency             money.Currency   `json:"currency"`
+	ExchangeRate         *decimal.Decimal `json:"exchange_rate,omitempty"`
+	CounterAmount        *decimal.Decimal `json:"counter_amount,omitempty"`
+	CounterCurrency      *money.Currency  `json:"counter_currency,omitempty"`
+	CounterpartyWalletID uuid.UUID        `json:"counterparty_wallet_id"`
+	// CounterpartyName is the name of the counterparty wallet's owner
+	CounterpartyName *string   `json:"counterparty_name,omitempty"`
+	Description      *string   `json:"description,omitempty"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// TransferDirection is the direction of a transfer for the wallet with a leg in the
+// given direction
+func TransferDirection(entryDirection string) string {
+	if entryDirection == EntryDirectionDebit {
+		return TransferDirectionOutgoing
+	}
+	return TransferDirectionIncoming
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shanwije/wallet-app/pkg/money"
+	"github.com/shopspring/decimal"
+)
+
+// TransferQuote fixes the terms of a transfer for a short while: the fee, what the
+// sender is debited and what the recipient is credited, at ExchangeRate when they hold
+// different currencies. A transfer that passes the quote's ID runs on those terms; it
+// can do so once, before ExpiresAt, and is then TransferJournalID.
+type TransferQuote struct {
+	ID                uuid.UUID        `db:"id" json:"id"`
+	FromWalletID      uuid.UUID        `db:"from_wallet_id" json:"from_wallet_id"`
+	ToWalletID        uuid.UUID        `db:"to_wallet_id" json:"to_wallet_id"`
+	Amount            decimal.Decimal  `db:"amount" json:"amount"`
+	Currency          money.Currency   `db:"currency" json:"currency"`
+	Fee               decimal.Decimal  `db:"fee" json:"fee"`
+	DebitedAmount     decimal.Decimal  `db:"debited_amount" json:"debited_amount"` // amount plus fee
+	CreditedAmount    decimal.Decimal  `db:"credited_amount" json:"credited_amount"`
+	CreditedCurrency  money.Currency   `db:"credited_currency" json:"credited_currency"`
+	ExchangeRate      *decimal.Decimal `db:"exchange_rate" json:"exchange_rate,omitempty"`
+	TransferJournalID *uuid.UUID       `db:"transfer_journal_id" json:"transfer_journal_id,omitempty"`
+	ExpiresAt         time.Time        `db:"expires_at" json:"expires_at"`
+	CreatedAt         time.Time        `db:"created_at" json:"created_at"`
+}
+
+// Funds returns the quoted amount in the sender's currency
+func (q *TransferQuote) Funds() money.Money {
+	return money.New(q.Amount, q.Currency)
+}
+
+// FeeFunds returns the quoted fee in the sender's currency
+func (q *TransferQuote) FeeFunds() money.Money {
+	return money.New(q.Fee, q.Currency)
+}
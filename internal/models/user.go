@@ -6,15 +6,26 @@ import (
 	"github.com/google/uuid"
 )
 
+// Contact is how a user can be reached. Either detail may be missing, and each is
+// unique among users: an email address in lower case, a phone number in E.164 form.
+type Contact struct {
+	Email *string `db:"email" json:"email,omitempty" example:"alice@example.com"`
+	Phone *string `db:"phone" json:"phone,omitempty" example:"+447700900123"`
+}
+
 type User struct {
-	ID        uuid.UUID `db:"id" json:"id"`
-	Name      string    `db:"name" json:"name"`
+	ID   uuid.UUID `db:"id" json:"id"`
+	Name string    `db:"name" json:"name"`
+	Contact
+	Version   int64     `db:"version" json:"-"` // bumped by every profile update
 	CreatedAt time.Time `db:"created_at" json:"created_at"`
 }
 
 type UserWithWallet struct {
-	ID        uuid.UUID `json:"id"`
-	Name      string    `json:"name"`
+	ID   uuid.UUID `json:"id"`
+	Name string    `json:"name"`
+	Contact
+	Version   int64     `json:"-"`
 	Wallet    Wallet    `json:"wallet"`
 	CreatedAt time.Time `json:"created_at"`
 }
@@ -1,15 +1,111 @@
 package models
 
 import (
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/shanwije/wallet-app/pkg/money"
 	"github.com/shopspring/decimal"
 )
 
+// Wallet statuses. Only active wallets can move money; closed is final.
+const (
+	WalletStatusActive = "active"
+	WalletStatusFrozen = "frozen"
+	WalletStatusClosed = "closed"
+)
+
+// Wallet kinds. Customers hold user wallets; the rest are system wallets the platform
+// keeps for itself, at most one of each kind per currency.
+const (
+	WalletKindUser = "user"
+	// WalletKindTreasury mirrors the money held outside the platform: deposits are
+	// transfers from it and withdrawals transfers to it, so its balance is the negative
+	// of what customers were paid in and have not taken out
+	WalletKindTreasury = "treasury"
+	// WalletKindSuspense takes the other side of balance adjustments until operators
+	// account for them
+	WalletKindSuspense = "suspense"
+	// WalletKindFees collects fees when the fee wallet is set to it
+	WalletKindFees = "fees"
+)
+
+// SystemUserID owns every system wallet. It is a reserved user that is marked deleted,
+// so it cannot be looked up, listed or signed in as.
+var SystemUserID = uuid.MustParse("00000000-0000-0000-0000-000000000001")
+
+var (
+	// ErrWalletFrozen is returned when money is moved into or out of a frozen wallet
+	ErrWalletFrozen = errors.New("wallet is frozen")
+	// ErrWalletClosed is returned when money is moved into or out of a closed wallet
+	ErrWalletClosed = errors.New("wallet is closed")
+)
+
+// Wallet balances follow posted-vs-available semantics: Balance is the posted balance
+// proven by the ledger, and HeldBalance is the part of it reserved by active holds.
 type Wallet struct {
-	ID        uuid.UUID       `db:"id" json:"id"`
-	UserID    uuid.UUID       `db:"user_id" json:"user_id"`
-	Balance   decimal.Decimal `db:"balance" json:"balance"`
-	CreatedAt time.Time       `db:"created_at" json:"created_at"`
+	ID          uuid.UUID       `db:"id" json:"id"`
+	UserID      uuid.UUID       `db:"user_id" json:"user_id"`
+	Balance     decimal.Decimal `db:"balance" json:"balance"`
+	HeldBalance decimal.Decimal `db:"held_balance" json:"held_balance"`
+	Currency    money.Currency  `db:"currency" json:"currency"`
+	Status      string          `db:"status" json:"status"` // active, frozen, closed
+	Kind        string          `db:"kind" json:"kind"`     // user, treasury, suspense, fees
+	Version     int64           `db:"version" json:"-"`     // bumped by every update
+	CreatedAt   time.Time       `db:"created_at" json:"created_at"`
+}
+
+// Funds returns the posted wallet balance as a currency-aware amount
+func (w *Wallet) Funds() money.Money {
+	return money.New(w.Balance, w.Currency)
+}
+
+// Held returns the amount reserved by active holds
+func (w *Wallet) Held() money.Money {
+	return money.New(w.HeldBalance, w.Currency)
+}
+
+// Available returns the balance that withdrawals, transfers and new holds may spend
+func (w *Wallet) Available() money.Money {
+	return money.New(w.Balance.Sub(w.HeldBalance), w.Currency)
+}
+
+// CheckActive returns ErrWalletFrozen or ErrWalletClosed unless the wallet can move money
+func (w *Wallet) CheckActive() error {
+	switch w.Status {
+	case WalletStatusFrozen:
+		return ErrWalletFrozen
+	case WalletStatusClosed:
+		return ErrWalletClosed
+	default:
+		return nil
+	}
+}
+
+// IsSettlement reports whether the wallet is a treasury or suspense wallet. Their
+// balances mirror money outside the platform's customer wallets and may go below zero;
+// money only reaches them as the other side of the movements they settle.
+func (w *Wallet) IsSettlement() bool {
+	return w.Kind == WalletKindTreasury || w.Kind == WalletKindSuspense
+}
+
+// IsValidSystemWalletKind reports whether kind is the kind of a system wallet
+func IsValidSystemWalletKind(kind string) bool {
+	switch kind {
+	case WalletKindTreasury, WalletKindSuspense, WalletKindFees:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsValidWalletStatus validates wallet status
+func IsValidWalletStatus(status string) bool {
+	switch status {
+	case WalletStatusActive, WalletStatusFrozen, WalletStatusClosed:
+		return true
+	default:
+		return false
+	}
 }
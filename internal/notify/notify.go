@@ -0,0 +1,34 @@
+// Package notify delivers messages to users by email. A Provider sends one message
+// through a mail service; what to send, and when, is up to the notification service.
+package notify
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+
+	"github.com/shanwije/wallet-app/pkg/logger"
+)
+
+// Message is a plain-text email to one recipient
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Provider sends a message, returning only once the mail service has accepted it
+type Provider interface {
+	Send(ctx context.Context, message Message) error
+}
+
+// LogProvider writes messages to the log instead of sending them, for development
+type LogProvider struct{}
+
+func (LogProvider) Send(ctx context.Context, message Message) error {
+	logger.FromContext(ctx).Info("Notification",
+		zap.String("to", message.To),
+		zap.String("subject", message.Subject),
+		zap.String("body", message.Body))
+	return nil
+}
@@ -0,0 +1,113 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shanwije/wallet-app/pkg/awssig"
+)
+
+var testMessage = Message{To: "alice@example.com", Subject: "You received a transfer", Body: "20.00 USD was transferred\ninto your wallet."}
+
+// fakeSMTP accepts one client without STARTTLS or auth and sends the envelope and
+// message it receives on the returned channel
+func fakeSMTP(t *testing.T) (string, <-chan string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	received := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		reader := bufio.NewReader(conn)
+		var transcript strings.Builder
+		fmt.Fprint(conn, "220 localhost ESMTP\r\n")
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			switch command := strings.ToUpper(strings.Fields(line)[0]); command {
+			case "EHLO", "HELO":
+				fmt.Fprint(conn, "250 localhost\r\n")
+			case "MAIL", "RCPT":
+				transcript.WriteString(line)
+				fmt.Fprint(conn, "250 OK\r\n")
+			case "DATA":
+				fmt.Fprint(conn, "354 Go ahead\r\n")
+				for {
+					line, err := reader.ReadString('\n')
+					if err != nil || line == ".\r\n" {
+						break
+					}
+					transcript.WriteString(line)
+				}
+				fmt.Fprint(conn, "250 Queued\r\n")
+			case "QUIT":
+				fmt.Fprint(conn, "221 Bye\r\n")
+				received <- transcript.String()
+				return
+			default:
+				fmt.Fprint(conn, "502 Not implemented\r\n")
+			}
+		}
+	}()
+	return listener.Addr().String(), received
+}
+
+func TestSMTPProviderSendsThePlainTextMessage(t *testing.T) {
+	addr, received := fakeSMTP(t)
+	provider := &SMTPProvider{Addr: addr, From: "alerts@wallet.example"}
+
+	require.NoError(t, provider.Send(context.Background(), testMessage))
+	transcript := <-received
+	assert.Contains(t, transcript, "MAIL FROM:<alerts@wallet.example>")
+	assert.Contains(t, transcript, "RCPT TO:<alice@example.com>")
+	assert.Contains(t, transcript, "Subject: You received a transfer\r\n")
+	assert.Contains(t, transcript, "Content-Type: text/plain; charset=UTF-8\r\n")
+	assert.Contains(t, transcript, "\r\n\r\n20.00 USD was transferred\r\ninto your wallet.\r\n")
+}
+
+func TestSESProviderSignsTheSendEmailRequest(t *testing.T) {
+	var request sesSendEmailRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v2/email/outbound-emails", r.URL.Path)
+		assert.True(t, strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/"), r.Header.Get("Authorization"))
+		assert.Contains(t, r.Header.Get("Authorization"), "/eu-west-1/ses/aws4_request")
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		w.Write([]byte(`{"MessageId":"1"}`))
+	}))
+	t.Cleanup(server.Close)
+
+	provider := &SESProvider{
+		Endpoint:    server.URL,
+		Region:      "eu-west-1",
+		From:        "alerts@wallet.example",
+		Credentials: awssig.Credentials{AccessKeyID: "AKID", SecretAccessKey: "secret"},
+	}
+	require.NoError(t, provider.Send(context.Background(), testMessage))
+	assert.Equal(t, "alerts@wallet.example", request.FromEmailAddress)
+	assert.Equal(t, []string{"alice@example.com"}, request.Destination.ToAddresses)
+	assert.Equal(t, testMessage.Subject, request.Content.Simple.Subject.Data)
+	assert.Equal(t, testMessage.Body, request.Content.Simple.Body.Text.Data)
+
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"message":"The security token included in the request is invalid"}`, http.StatusForbidden)
+	})
+	assert.ErrorContains(t, provider.Send(context.Background(), testMessage), "SES returned 403")
+}
@@ -0,0 +1,87 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/shanwije/wallet-app/pkg/awssig"
+)
+
+// SESProvider sends messages through the Amazon SES v2 API, signing requests with
+// static credentials
+type SESProvider struct {
+	// Endpoint is optional and defaults to the region's SES endpoint
+	Endpoint    string
+	Region      string
+	From        string
+	Credentials awssig.Credentials
+	// Client is optional; a client with a 10 second timeout is used when nil
+	Client *http.Client
+}
+
+type sesContent struct {
+	Data    string `json:"Data"`
+	Charset string `json:"Charset"`
+}
+
+type sesSendEmailRequest struct {
+	FromEmailAddress string `json:"FromEmailAddress"`
+	Destination      struct {
+		ToAddresses []string `json:"ToAddresses"`
+	} `json:"Destination"`
+	Content struct {
+		Simple struct {
+			Subject sesContent `json:"Subject"`
+			Body    struct {
+				Text sesContent `json:"Text"`
+			} `json:"Body"`
+		} `json:"Simple"`
+	} `json:"Content"`
+}
+
+var sesClient = &http.Client{Timeout: 10 * time.Second}
+
+func (p *SESProvider) Send(ctx context.Context, message Message) error {
+	var request sesSendEmailRequest
+	request.FromEmailAddress = p.From
+	request.Destination.ToAddresses = []string{message.To}
+	request.Content.Simple.Subject = sesContent{Data: message.Subject, Charset: "UTF-8"}
+	request.Content.Simple.Body.Text = sesContent{Data: message.Body, Charset: "UTF-8"}
+	body, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("failed to encode message: %w", err)
+	}
+
+	endpoint := p.Endpoint
+	if endpoint == "" {
+		endpoint = "https://email." + p.Region + ".amazonaws.com"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(endpoint, "/")+"/v2/email/outbound-emails", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build SES request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	awssig.Sign(req, body, p.Credentials, p.Region, "ses", time.Now())
+
+	client := p.Client
+	if client == nil {
+		client = sesClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach SES: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		reason, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("SES returned %d: %s", resp.StatusCode, bytes.TrimSpace(reason))
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package notify
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// smtpTimeout bounds a whole conversation with the server when ctx has no deadline
+const smtpTimeout = 30 * time.Second
+
+// SMTPProvider sends messages through an SMTP server, upgrading the connection with
+// STARTTLS when the server offers it. Username and Password are optional; when set
+// they authenticate with PLAIN, which net/smtp only allows over TLS or to localhost.
+type SMTPProvider struct {
+	Addr     string
+	From     string
+	Username string
+	Password string
+}
+
+func (p *SMTPProvider) Send(ctx context.Context, message Message) error {
+	host, _, err := net.SplitHostPort(p.Addr)
+	if err != nil {
+		return fmt.Errorf("invalid SMTP address %q: %w", p.Addr, err)
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", p.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to reach SMTP server: %w", err)
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(smtpTimeout)
+	}
+	conn.SetDeadline(deadline)
+
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to greet SMTP server: %w", err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return fmt.Errorf("failed to start TLS with SMTP server: %w", err)
+		}
+	}
+	if p.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", p.Username, p.Password, host)); err != nil {
+			return fmt.Errorf("failed to authenticate with SMTP server: %w", err)
+		}
+	}
+	if err := client.Mail(p.From); err != nil {
+		return fmt.Errorf("SMTP server refused sender: %w", err)
+	}
+	if err := client.Rcpt(message.To); err != nil {
+		return fmt.Errorf("SMTP server refused recipient: %w", err)
+	}
+
+	data, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("SMTP server refused message: %w", err)
+	}
+	if _, err := data.Write(p.compose(message)); err != nil {
+		data.Close()
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	if err := data.Close(); err != nil {
+		return fmt.Errorf("SMTP server refused message: %w", err)
+	}
+	return client.Quit()
+}
+
+// compose renders message with its headers, its lines ending in CRLF as SMTP requires
+func (p *SMTPProvider) compose(message Message) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", p.From)
+	fmt.Fprintf(&b, "To: %s\r\n", message.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", message.Subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	body := strings.ReplaceAll(strings.ReplaceAll(message.Body, "\r\n", "\n"), "\n", "\r\n")
+	b.WriteString(body)
+	if !strings.HasSuffix(body, "\r\n") {
+		b.WriteString("\r\n")
+	}
+	return []byte(b.String())
+}
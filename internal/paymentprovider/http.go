@@ -0,0 +1,83 @@
+package paymentprovider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/shanwije/wallet-app/pkg/requestid"
+)
+
+// HTTPRefunder refunds through the payment provider's API:
+//
+//	POST {base}/payments/{payment_id}/refunds {"amount","currency","reason"}
+//	answers {"id":"..."}, or a 4xx other than 429 when the refund is rejected
+//
+// The refund ID is sent as the Idempotency-Key header, so a refund sent again after a
+// lost response is made once.
+type HTTPRefunder struct {
+	BaseURL string
+	Client  *http.Client
+}
+
+// NewHTTPRefunder creates a refunder for the API at baseURL
+func NewHTTPRefunder(baseURL string) *HTTPRefunder {
+	return &HTTPRefunder{
+		BaseURL: strings.TrimRight(baseURL, "/"),
+		Client:  &http.Client{Timeout: 10 * time.Second, Transport: requestid.Transport(nil)},
+	}
+}
+
+type httpRefundRequest struct {
+	Amount   string `json:"amount"`
+	Currency string `json:"currency"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// Refund implements Refunder
+func (h *HTTPRefunder) Refund(ctx context.Context, request RefundRequest) (string, error) {
+	body, err := json.Marshal(httpRefundRequest{
+		Amount:   request.Amount.Amount().StringFixed(request.Amount.Currency().MinorUnits()),
+		Currency: request.Amount.Currency().String(),
+		Reason:   request.Reason,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode refund: %w", err)
+	}
+
+	endpoint := h.BaseURL + "/payments/" + url.PathEscape(request.PaymentID) + "/refunds"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Idempotency-Key", request.ID.String())
+
+	resp, err := h.Client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to reach payment provider: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return "", fmt.Errorf("%w: %s: %s", ErrRejected, resp.Status, strings.TrimSpace(string(detail)))
+	}
+	if resp.StatusCode/100 != 2 {
+		return "", fmt.Errorf("payment provider returned %s", resp.Status)
+	}
+
+	var answer struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&answer); err != nil || answer.ID == "" {
+		return "", fmt.Errorf("invalid refund response: %v", err)
+	}
+	return answer.ID, nil
+}
@@ -0,0 +1,56 @@
+package paymentprovider
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shanwije/wallet-app/pkg/money"
+)
+
+func TestHTTPRefunderRefundsPayments(t *testing.T) {
+	refundID := uuid.New()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/payments/pay_1/refunds":
+			var body httpRefundRequest
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			assert.Equal(t, refundID.String(), r.Header.Get("Idempotency-Key"))
+			assert.Equal(t, httpRefundRequest{Amount: "7.50", Currency: "USD", Reason: "duplicate"}, body)
+			w.Write([]byte(`{"id":"re_1"}`))
+		case "/payments/pay_2/refunds":
+			http.Error(w, "charge already refunded", http.StatusBadRequest)
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	refunder := NewHTTPRefunder(server.URL + "/")
+	ctx := context.Background()
+	request := func(paymentID string) RefundRequest {
+		return RefundRequest{
+			ID:        refundID,
+			PaymentID: paymentID,
+			Amount:    money.New(decimal.RequireFromString("7.5"), money.USD),
+			Reason:    "duplicate",
+		}
+	}
+
+	id, err := refunder.Refund(ctx, request("pay_1"))
+	require.NoError(t, err)
+	assert.Equal(t, "re_1", id)
+
+	_, err = refunder.Refund(ctx, request("pay_2"))
+	assert.ErrorIs(t, err, ErrRejected)
+	_, err = refunder.Refund(ctx, request("pay_3"))
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, ErrRejected)
+}
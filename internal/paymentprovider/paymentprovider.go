@@ -0,0 +1,34 @@
+// Package paymentprovider calls back to the payment provider whose webhook funds
+// wallets. So far that is only to refund a payment to the customer; debiting the wallet
+// for it is up to the deposit refund service.
+package paymentprovider
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+
+	"github.com/shanwije/wallet-app/pkg/money"
+)
+
+// ErrRejected is returned when the provider refuses a refund for good, for example
+// because the payment was already refunded on its side. Other errors may be retried.
+var ErrRejected = errors.New("refund rejected")
+
+// RefundRequest is amount of the provider's payment PaymentID to give back. ID
+// identifies the refund, so a provider given the same request twice refunds it once.
+type RefundRequest struct {
+	ID        uuid.UUID
+	PaymentID string
+	Amount    money.Money
+	Reason    string
+}
+
+// Refunder refunds the provider's payments. Implementations must be safe for
+// concurrent use.
+type Refunder interface {
+	// Refund gives the amount back to the customer and returns the provider's ID for
+	// the refund, wrapping ErrRejected when the provider will never make it
+	Refund(ctx context.Context, request RefundRequest) (string, error)
+}
@@ -0,0 +1,121 @@
+package payout
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/shanwije/wallet-app/pkg/requestid"
+)
+
+// HTTPProvider pays out through a payment rail's API:
+//
+//	POST {base}/payouts {"id","amount","currency","account_name","account_number","bank_code"}
+//	answers {"reference":"..."}, or a 4xx other than 429 when the payout is rejected
+//	GET {base}/payouts/{reference} answers {"status":"pending|settled|failed","reason":"..."}
+//
+// The payout ID is also sent as the Idempotency-Key header, so a payout sent again
+// after a lost response is paid once.
+type HTTPProvider struct {
+	BaseURL string
+	Client  *http.Client
+}
+
+// NewHTTPProvider creates a provider for the API at baseURL
+func NewHTTPProvider(baseURL string) *HTTPProvider {
+	return &HTTPProvider{
+		BaseURL: strings.TrimRight(baseURL, "/"),
+		Client:  &http.Client{Timeout: 10 * time.Second, Transport: requestid.Transport(nil)},
+	}
+}
+
+type httpPayoutRequest struct {
+	ID            string `json:"id"`
+	Amount        string `json:"amount"`
+	Currency      string `json:"currency"`
+	AccountName   string `json:"account_name"`
+	AccountNumber string `json:"account_number"`
+	BankCode      string `json:"bank_code"`
+}
+
+// Send implements Provider
+func (h *HTTPProvider) Send(ctx context.Context, request Request) (string, error) {
+	body, err := json.Marshal(httpPayoutRequest{
+		ID:            request.ID.String(),
+		Amount:        request.Amount.Amount().StringFixed(request.Amount.Currency().MinorUnits()),
+		Currency:      request.Amount.Currency().String(),
+		AccountName:   request.AccountName,
+		AccountNumber: request.AccountNumber,
+		BankCode:      request.BankCode,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode payout: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.BaseURL+"/payouts", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Idempotency-Key", request.ID.String())
+
+	resp, err := h.Client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to reach payout API: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return "", fmt.Errorf("%w: %s: %s", ErrRejected, resp.Status, strings.TrimSpace(string(detail)))
+	}
+	if resp.StatusCode/100 != 2 {
+		return "", fmt.Errorf("payout API returned %s", resp.Status)
+	}
+
+	var answer struct {
+		Reference string `json:"reference"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&answer); err != nil || answer.Reference == "" {
+		return "", fmt.Errorf("invalid payout response: %v", err)
+	}
+	return answer.Reference, nil
+}
+
+// Status implements Provider
+func (h *HTTPProvider) Status(ctx context.Context, reference string) (Result, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, h.BaseURL+"/payouts/"+url.PathEscape(reference), nil)
+	if err != nil {
+		return Result{}, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := h.Client.Do(req)
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to reach payout API: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Result{}, fmt.Errorf("payout API returned %s", resp.Status)
+	}
+
+	var answer struct {
+		Status string `json:"status"`
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&answer); err != nil {
+		return Result{}, fmt.Errorf("invalid payout status response: %w", err)
+	}
+	switch answer.Status {
+	case StatusPending, StatusSettled, StatusFailed:
+	default:
+		return Result{}, fmt.Errorf("invalid payout status %q", answer.Status)
+	}
+	return Result{Status: answer.Status, Reason: answer.Reason}, nil
+}
@@ -0,0 +1,71 @@
+package payout
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shanwije/wallet-app/pkg/money"
+)
+
+func TestHTTPProviderSendsAndPolls(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/payouts":
+			var body httpPayoutRequest
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			assert.Equal(t, body.ID, r.Header.Get("Idempotency-Key"))
+			assert.Equal(t, "12.50", body.Amount)
+			if body.AccountNumber == "closed" {
+				http.Error(w, "account closed", http.StatusUnprocessableEntity)
+				return
+			}
+			if body.AccountNumber == "busy" {
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			w.Write([]byte(`{"reference":"pay_1"}`))
+		case r.URL.Path == "/payouts/pay_1":
+			w.Write([]byte(`{"status":"failed","reason":"account closed"}`))
+		default:
+			w.Write([]byte(`{"status":"lost"}`))
+		}
+	}))
+	defer server.Close()
+
+	provider := NewHTTPProvider(server.URL + "/")
+	ctx := context.Background()
+	request := func(account string) Request {
+		return Request{
+			ID:            uuid.New(),
+			Amount:        money.New(decimal.RequireFromString("12.5"), money.USD),
+			AccountName:   "Alice Smith",
+			AccountNumber: account,
+			BankCode:      "NWBKGB2L",
+		}
+	}
+
+	reference, err := provider.Send(ctx, request("12345678"))
+	require.NoError(t, err)
+	assert.Equal(t, "pay_1", reference)
+
+	// A 4xx rejects the payout for good, but a 429 is only worth retrying
+	_, err = provider.Send(ctx, request("closed"))
+	assert.ErrorIs(t, err, ErrRejected)
+	_, err = provider.Send(ctx, request("busy"))
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, ErrRejected)
+
+	result, err := provider.Status(ctx, "pay_1")
+	require.NoError(t, err)
+	assert.Equal(t, Result{Status: StatusFailed, Reason: "account closed"}, result)
+	_, err = provider.Status(ctx, "pay_2")
+	assert.Error(t, err)
+}
@@ -0,0 +1,68 @@
+// Package payout sends money out of wallets to bank accounts. A Provider hands a payout
+// to a payment rail and reports how it ended; debiting the wallet, and crediting it
+// back when the payout fails, is up to the payout service.
+package payout
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/shanwije/wallet-app/pkg/logger"
+	"github.com/shanwije/wallet-app/pkg/money"
+)
+
+// ErrRejected is returned when the provider refuses a payout for good, for example
+// because the bank account does not exist. Other errors may be retried.
+var ErrRejected = errors.New("payout rejected")
+
+// Payout statuses a provider reports once it has a payout
+const (
+	StatusPending = "pending"
+	StatusSettled = "settled"
+	StatusFailed  = "failed"
+)
+
+// Request is money to pay into a bank account. ID identifies the payout, so a provider
+// given the same request twice pays it once.
+type Request struct {
+	ID            uuid.UUID
+	Amount        money.Money
+	AccountName   string
+	AccountNumber string
+	BankCode      string
+}
+
+// Result is how a payout stands with the provider. Reason says why a failed payout failed.
+type Result struct {
+	Status string
+	Reason string
+}
+
+// Provider pays money out to bank accounts. Implementations must be safe for
+// concurrent use.
+type Provider interface {
+	// Send hands the payout to the payment rail and returns the provider's reference
+	// for it, wrapping ErrRejected when the provider will never pay it
+	Send(ctx context.Context, request Request) (string, error)
+	// Status reports how the payout the provider knows as reference stands
+	Status(ctx context.Context, reference string) (Result, error)
+}
+
+// LogProvider writes payouts to the log and reports them settled, for development
+type LogProvider struct{}
+
+func (LogProvider) Send(ctx context.Context, request Request) (string, error) {
+	logger.FromContext(ctx).Info("Payout",
+		zap.String("payout_id", request.ID.String()),
+		zap.String("amount", request.Amount.String()),
+		zap.String("account_name", request.AccountName),
+		zap.String("bank_code", request.BankCode))
+	return "log-" + request.ID.String(), nil
+}
+
+func (LogProvider) Status(context.Context, string) (Result, error) {
+	return Result{Status: StatusSettled}, nil
+}
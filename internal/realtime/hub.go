@@ -0,0 +1,218 @@
+// Package realtime pushes balance updates to connected clients. A Hub keeps each
+// client's wallet subscriptions and a bounded queue of messages for it; a client that
+// falls so far behind that its queue fills is disconnected rather than allowed to hold
+// up the others, and can reconnect and read its balance afresh.
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/shanwije/wallet-app/internal/service"
+	"github.com/shanwije/wallet-app/pkg/logger"
+)
+
+// DefaultSendBuffer is how many messages a client may have queued before it is
+// disconnected, when the hub is given no other size
+const DefaultSendBuffer = 32
+
+// Why a client was disconnected by the hub rather than closed by its owner
+var (
+	ErrSlowClient = errors.New("client fell behind on its updates")
+	ErrHubClosed  = errors.New("hub closed")
+)
+
+// MessageTypeBalance is the type of the message carrying a balance update
+const MessageTypeBalance = "balance"
+
+// BalanceMessage is what subscribers receive for each balance update
+type BalanceMessage struct {
+	Type string `json:"type"`
+	service.BalanceUpdate
+}
+
+// Hub delivers balance updates to the clients subscribed to their wallets. It is safe
+// for concurrent use.
+type Hub struct {
+	sendBuffer int
+
+	mu          sync.RWMutex
+	subscribers map[uuid.UUID]map[*Client]struct{}
+	clients     map[*Client]struct{}
+	closed      bool
+}
+
+// NewHub creates a hub whose clients may queue sendBuffer messages each;
+// DefaultSendBuffer is used when it is not positive
+func NewHub(sendBuffer int) *Hub {
+	if sendBuffer <= 0 {
+		sendBuffer = DefaultSendBuffer
+	}
+	return &Hub{
+		sendBuffer:  sendBuffer,
+		subscribers: make(map[uuid.UUID]map[*Client]struct{}),
+		clients:     make(map[*Client]struct{}),
+	}
+}
+
+// Client is one connection's view of the hub. Messages for it are read from Send
+// until Done is closed.
+type Client struct {
+	hub     *Hub
+	send    chan []byte
+	done    chan struct{}
+	wallets map[uuid.UUID]struct{}
+	err     error
+}
+
+// Register adds a client with no subscriptions. It returns nil once the hub is closed.
+func (h *Hub) Register() *Client {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return nil
+	}
+	c := &Client{
+		hub:     h,
+		send:    make(chan []byte, h.sendBuffer),
+		done:    make(chan struct{}),
+		wallets: make(map[uuid.UUID]struct{}),
+	}
+	h.clients[c] = struct{}{}
+	return c
+}
+
+// Send returns the client's queued messages
+func (c *Client) Send() <-chan []byte {
+	return c.send
+}
+
+// Done is closed when the client is unregistered, either by Close or because it fell
+// behind
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+// Err returns why the hub disconnected the client: ErrSlowClient or ErrHubClosed. It
+// is nil while the client is connected and after Close.
+func (c *Client) Err() error {
+	c.hub.mu.RLock()
+	defer c.hub.mu.RUnlock()
+	return c.err
+}
+
+// Subscribe starts delivering the wallets' updates to the client
+func (c *Client) Subscribe(walletIDs ...uuid.UUID) {
+	h := c.hub
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.clients[c]; !ok {
+		return
+	}
+	for _, id := range walletIDs {
+		c.wallets[id] = struct{}{}
+		if h.subscribers[id] == nil {
+			h.subscribers[id] = make(map[*Client]struct{})
+		}
+		h.subscribers[id][c] = struct{}{}
+	}
+}
+
+// Unsubscribe stops delivering the wallets' updates to the client
+func (c *Client) Unsubscribe(walletIDs ...uuid.UUID) {
+	h := c.hub
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, id := range walletIDs {
+		h.unsubscribe(c, id)
+	}
+}
+
+// Close unregisters the client and closes Done. It may be called more than once.
+func (c *Client) Close() {
+	h := c.hub
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.remove(c, nil)
+}
+
+// Publish queues message for every client subscribed to the wallet without waiting.
+// Clients whose queue is full are disconnected.
+func (h *Hub) Publish(walletID uuid.UUID, message []byte) {
+	var slow []*Client
+
+	h.mu.RLock()
+	for c := range h.subscribers[walletID] {
+		select {
+		case c.send <- message:
+		default:
+			slow = append(slow, c)
+		}
+	}
+	h.mu.RUnlock()
+
+	if len(slow) == 0 {
+		return
+	}
+	h.mu.Lock()
+	for _, c := range slow {
+		h.remove(c, ErrSlowClient)
+	}
+	h.mu.Unlock()
+}
+
+// NotifyBalance publishes the update to the wallet's subscribers, making the hub a
+// service.BalanceNotifier
+func (h *Hub) NotifyBalance(ctx context.Context, update service.BalanceUpdate) {
+	message, err := json.Marshal(BalanceMessage{Type: MessageTypeBalance, BalanceUpdate: update})
+	if err != nil {
+		logger.FromContext(ctx).Error("Encoding balance update failed", zap.Error(err))
+		return
+	}
+	h.Publish(update.WalletID, message)
+}
+
+// Close disconnects every client and refuses new ones
+func (h *Hub) Close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for c := range h.clients {
+		h.remove(c, ErrHubClosed)
+	}
+	return nil
+}
+
+// remove unregisters c for the given reason; h.mu must be held for writing
+func (h *Hub) remove(c *Client, reason error) {
+	if _, ok := h.clients[c]; !ok {
+		return
+	}
+	c.err = reason
+	for id := range c.wallets {
+		h.unsubscribe(c, id)
+	}
+	delete(h.clients, c)
+	close(c.done)
+}
+
+// unsubscribe drops c's subscription to the wallet; h.mu must be held for writing
+func (h *Hub) unsubscribe(c *Client, walletID uuid.UUID) {
+	delete(c.wallets, walletID)
+	if subscribers, ok := h.subscribers[walletID]; ok {
+		delete(subscribers, c)
+		if len(subscribers) == 0 {
+			delete(h.subscribers, walletID)
+		}
+	}
+}
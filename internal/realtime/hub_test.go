@@ -0,0 +1,112 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shanwije/wallet-app/internal/service"
+)
+
+// received drains the messages queued for c
+func received(c *Client) []string {
+	var messages []string
+	for {
+		select {
+		case message := <-c.Send():
+			messages = append(messages, string(message))
+		default:
+			return messages
+		}
+	}
+}
+
+func TestHubDeliversToSubscribersOnly(t *testing.T) {
+	hub := NewHub(4)
+	walletID, otherID := uuid.New(), uuid.New()
+
+	watching := hub.Register()
+	watching.Subscribe(walletID)
+	other := hub.Register()
+	other.Subscribe(otherID)
+	left := hub.Register()
+	left.Subscribe(walletID)
+	left.Unsubscribe(walletID)
+
+	hub.Publish(walletID, []byte("update"))
+
+	assert.Equal(t, []string{"update"}, received(watching))
+	assert.Empty(t, received(other))
+	assert.Empty(t, received(left))
+}
+
+func TestHubDisconnectsClientsThatFallBehind(t *testing.T) {
+	hub := NewHub(2)
+	walletID := uuid.New()
+
+	slow := hub.Register()
+	slow.Subscribe(walletID)
+	fast := hub.Register()
+	fast.Subscribe(walletID)
+
+	for i := 0; i < 3; i++ {
+		hub.Publish(walletID, []byte("update"))
+		received(fast)
+	}
+
+	select {
+	case <-slow.Done():
+	default:
+		t.Fatal("slow client is still connected")
+	}
+	assert.ErrorIs(t, slow.Err(), ErrSlowClient)
+	assert.NoError(t, fast.Err())
+
+	// A disconnected client gets no more updates, and the others carry on
+	hub.Publish(walletID, []byte("later"))
+	assert.Len(t, received(slow), 2)
+	assert.Equal(t, []string{"later"}, received(fast))
+}
+
+func TestHubCloseDisconnectsEveryone(t *testing.T) {
+	hub := NewHub(0)
+	client := hub.Register()
+	client.Subscribe(uuid.New())
+
+	require.NoError(t, hub.Close())
+
+	<-client.Done()
+	assert.ErrorIs(t, client.Err(), ErrHubClosed)
+	assert.Nil(t, hub.Register())
+
+	// Closing the client afterwards is harmless
+	client.Close()
+}
+
+func TestNotifyBalanceSendsABalanceMessage(t *testing.T) {
+	hub := NewHub(0)
+	walletID := uuid.New()
+	client := hub.Register()
+	client.Subscribe(walletID)
+
+	hub.NotifyBalance(context.Background(), service.BalanceUpdate{
+		WalletID: walletID,
+		Event:    "wallet.deposit",
+		Amount:   decimal.RequireFromString("25.50"),
+		Balance:  decimal.RequireFromString("125.50"),
+	})
+
+	messages := received(client)
+	require.Len(t, messages, 1)
+	var message map[string]any
+	require.NoError(t, json.Unmarshal([]byte(messages[0]), &message))
+	assert.Equal(t, "balance", message["type"])
+	assert.Equal(t, walletID.String(), message["wallet_id"])
+	assert.Equal(t, "wallet.deposit", message["event"])
+	assert.Equal(t, "125.5", message["balance"])
+}
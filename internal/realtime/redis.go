@@ -0,0 +1,71 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+
+	"github.com/shanwije/wallet-app/internal/service"
+	"github.com/shanwije/wallet-app/pkg/logger"
+)
+
+// balanceChannel is the Redis channel instances share balance updates on
+const balanceChannel = "wallet:balance-updates"
+
+// RedisRelay shares balance updates between instances over Redis pub/sub, so a
+// client hears about a wallet whichever instance it is connected to and whichever
+// moved the money. Updates published while an instance is not subscribed, such as
+// during a Redis outage, are lost to its clients.
+type RedisRelay struct {
+	client *redis.Client
+	hub    *Hub
+}
+
+// NewRedisRelay creates a relay delivering the updates every instance publishes to hub
+func NewRedisRelay(client *redis.Client, hub *Hub) *RedisRelay {
+	return &RedisRelay{client: client, hub: hub}
+}
+
+// NotifyBalance publishes the update to every instance, this one included, making the
+// relay a service.BalanceNotifier
+func (r *RedisRelay) NotifyBalance(ctx context.Context, update service.BalanceUpdate) {
+	log := logger.FromContext(ctx)
+
+	payload, err := json.Marshal(update)
+	if err != nil {
+		log.Error("Encoding balance update failed", zap.Error(err))
+		return
+	}
+	if err := r.client.Publish(ctx, balanceChannel, payload).Err(); err != nil {
+		log.Error("Publishing balance update failed", zap.Error(err))
+	}
+}
+
+// Run hands the updates published on Redis to the hub until ctx is cancelled. The
+// client reconnects by itself after Redis errors.
+func (r *RedisRelay) Run(ctx context.Context) {
+	log := logger.FromContext(ctx)
+
+	sub := r.client.Subscribe(ctx, balanceChannel)
+	defer sub.Close()
+
+	messages := sub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-messages:
+			if !ok {
+				return
+			}
+			var update service.BalanceUpdate
+			if err := json.Unmarshal([]byte(msg.Payload), &update); err != nil {
+				log.Warn("Ignoring malformed balance update", zap.Error(err))
+				continue
+			}
+			r.hub.NotifyBalance(ctx, update)
+		}
+	}
+}
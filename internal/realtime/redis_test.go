@@ -0,0 +1,45 @@
+package realtime
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shanwije/wallet-app/internal/service"
+)
+
+func TestRedisRelaySharesUpdatesBetweenInstances(t *testing.T) {
+	server := miniredis.RunT(t)
+	newRelay := func() (*RedisRelay, *Hub) {
+		client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+		t.Cleanup(func() { client.Close() })
+		hub := NewHub(0)
+		return NewRedisRelay(client, hub), hub
+	}
+	sender, _ := newRelay()
+	receiver, hub := newRelay()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go receiver.Run(ctx)
+	require.Eventually(t, func() bool { return server.PubSubNumSub(balanceChannel)[balanceChannel] == 1 }, time.Second, 10*time.Millisecond)
+
+	walletID := uuid.New()
+	client := hub.Register()
+	client.Subscribe(walletID)
+	sender.NotifyBalance(ctx, service.BalanceUpdate{WalletID: walletID, Event: "wallet.transfer", Balance: decimal.NewFromInt(10)})
+
+	select {
+	case message := <-client.Send():
+		assert.Contains(t, string(message), `"event":"wallet.transfer"`)
+	case <-time.After(time.Second):
+		t.Fatal("update was not relayed")
+	}
+}
@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrNotFound is wrapped by repositories when the requested row does not exist
+	ErrNotFound = errors.New("not found")
+	// ErrDuplicate is wrapped by repositories when a unique constraint is violated
+	ErrDuplicate = errors.New("already exists")
+	// ErrVersionConflict is wrapped by wallet updates when the wallet's version no longer
+	// matches the one it was read at, meaning another transaction changed it first
+	ErrVersionConflict = errors.New("version conflict")
+	// ErrDuplicateEmail and ErrDuplicatePhone are wrapped by user writes when another
+	// user has the email address or phone number; both wrap ErrDuplicate
+	ErrDuplicateEmail = fmt.Errorf("email %w", ErrDuplicate)
+	ErrDuplicatePhone = fmt.Errorf("phone %w", ErrDuplicate)
+)
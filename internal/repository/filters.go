@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/pkg/pagination"
+	"github.com/shopspring/decimal"
+)
+
+// WalletFilter narrows a wallet search. Zero-valued fields match every wallet.
+type WalletFilter struct {
+	Status     string
+	Currency   string
+	MinBalance *decimal.Decimal
+	MaxBalance *decimal.Decimal
+	// CreatedFrom and CreatedTo bound created_at, CreatedFrom inclusive and CreatedTo exclusive
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+	// Sort orders the matches by WalletSortCreatedAt or WalletSortBalance, oldest wallet
+	// first when zero
+	Sort pagination.Sort
+	// After continues the listing from the last wallet of the previous page, which
+	// must have been listed in the same order; Offset is then ignored
+	After *WalletCursor
+	// Limit and Offset page through the matches
+	Limit  int
+	Offset int
+}
+
+// Columns wallets can be sorted by
+const (
+	WalletSortCreatedAt = "created_at"
+	WalletSortBalance   = "balance"
+)
+
+// WalletCursor is the position of a wallet in a sorted search: the value of the sort
+// column and the wallet ID
+type WalletCursor struct {
+	ID        uuid.UUID       `json:"id"`
+	CreatedAt time.Time       `json:"created_at"`
+	Balance   decimal.Decimal `json:"balance"`
+}
+
+// NewWalletCursor returns the position of wallet
+func NewWalletCursor(wallet *models.Wallet) *WalletCursor {
+	return &WalletCursor{ID: wallet.ID, CreatedAt: wallet.CreatedAt, Balance: wallet.Balance}
+}
+
+// RiskDecisionFilter narrows a listing of risk decisions. Zero-valued fields match every decision.
+type RiskDecisionFilter struct {
+	WalletID uuid.UUID
+	Action   string
+	// Limit and Offset page through the matches, newest decision first
+	Limit  int
+	Offset int
+}
+
+// AMLReportFilter narrows a listing of AML reports. Zero-valued fields match every report.
+type AMLReportFilter struct {
+	Type     string
+	WalletID uuid.UUID
+	// From and To bound created_at, From inclusive and To exclusive
+	From time.Time
+	To   time.Time
+	// Limit and Offset page through the matches, newest report first
+	Limit  int
+	Offset int
+}
+
+// PayoutFilter narrows a listing of payouts. Zero-valued fields match every payout.
+type PayoutFilter struct {
+	WalletID uuid.UUID
+	Status   string
+	// Limit and Offset page through the matches, newest payout first
+	Limit  int
+	Offset int
+}
+
+// AuditFilter narrows a listing of the audit log. Zero-valued fields match every entry.
+type AuditFilter struct {
+	ActorID  string
+	WalletID uuid.UUID
+	// From and To bound created_at, From inclusive and To exclusive
+	From time.Time
+	To   time.Time
+	// After continues the listing from the last entry of the previous page; Offset is
+	// then ignored
+	After *Position
+	// Limit and Offset page through the matches, newest entry first
+	Limit  int
+	Offset int
+}
+
+// TransactionFilter narrows a wallet's transaction history. Zero-valued fields match
+// every transaction.
+type TransactionFilter struct {
+	// Category only matches the transactions filed under it
+	Category string
+	// Sort orders the history by created_at, newest first when Descending
+	Sort pagination.Sort
+	// After continues the history from the last transaction of the previous page,
+	// which must have been listed in the same order
+	After *Position
+	Limit int
+}
+
+// Position is the place of a row in a listing ordered by creation: its creation time
+// and ID, which breaks ties
+type Position struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// DiscrepancyFilter narrows a listing of balance discrepancies. Zero-valued fields match every discrepancy.
+type DiscrepancyFilter struct {
+	RunID    uuid.UUID
+	WalletID uuid.UUID
+	// Limit and Offset page through the matches, newest discrepancy first
+	Limit  int
+	Offset int
+}
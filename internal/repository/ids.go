@@ -0,0 +1,10 @@
+package repository
+
+import "github.com/google/uuid"
+
+// NewTimeOrderedID generates a UUIDv7. Its leading bits encode the creation
+// time, so new rows land at the tail of the primary key index and IDs sort
+// roughly by insertion order, which makes them usable as pagination cursors.
+func NewTimeOrderedID() (uuid.UUID, error) {
+	return uuid.NewV7()
+}
@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewTimeOrderedIDsAreUUIDv7InCreationOrder(t *testing.T) {
+	before := time.Now()
+	ids := make([]uuid.UUID, 1000)
+	for i := range ids {
+		id, err := NewTimeOrderedID()
+		require.NoError(t, err)
+		ids[i] = id
+	}
+
+	for i, id := range ids {
+		assert.Equal(t, uuid.Version(7), id.Version())
+		assert.Equal(t, uuid.RFC4122, id.Variant())
+		// Many are made in the same millisecond, and still sort after the one before
+		if i > 0 {
+			assert.Equal(t, 1, bytes.Compare(id[:], ids[i-1][:]), "ID %d sorts before the one made before it", i)
+			assert.Greater(t, id.String(), ids[i-1].String())
+		}
+	}
+
+	// The leading 48 bits are the creation time in Unix milliseconds
+	sec, nsec := ids[0].Time().UnixTime()
+	assert.WithinDuration(t, before, time.Unix(sec, nsec), time.Second)
+}
@@ -1,33 +1,522 @@
 package repository
 
+//go:generate go run github.com/vektra/mockery/v2@v2.53.3 --name Repository$ --output mocks --outpkg mocks --case underscore --disable-version-string
+
 import (
 	"context"
 	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/pkg/money"
 	"github.com/shopspring/decimal"
 )
 
 type UserRepository interface {
-	CreateUser(ctx context.Context, name string) (*models.User, error)
+	// CreateUser wraps ErrDuplicateEmail or ErrDuplicatePhone when another user has
+	// the contact details
+	CreateUser(ctx context.Context, name string, contact models.Contact) (*models.User, error)
+	// CreateUserWithTx creates the user as part of tx, wrapping the same errors as CreateUser
+	CreateUserWithTx(ctx context.Context, tx *sql.Tx, name string, contact models.Contact) (*models.User, error)
 	GetUserByID(ctx context.Context, id uuid.UUID) (*models.User, error)
 	GetUserWithWallet(ctx context.Context, id uuid.UUID) (*models.UserWithWallet, error)
+	// UpdateUser writes the user's name and contact details if they are still at the
+	// version they were read at, and advances it. It wraps ErrVersionConflict if the
+	// user has changed or been deleted since, and ErrDuplicateEmail or ErrDuplicatePhone
+	// when another user has the contact details.
+	UpdateUser(ctx context.Context, user *models.User) error
+	// SoftDeleteUserWithTx hides the user from lookups, wrapping ErrNotFound if they are
+	// missing or already deleted
+	SoftDeleteUserWithTx(ctx context.Context, tx *sql.Tx, id uuid.UUID, deletedAt time.Time) error
+	// ListUsers pages through the users that are not deleted, oldest first
+	ListUsers(ctx context.Context, limit, offset int) ([]*models.User, error)
+	// SearchUsers pages through the users that are not deleted whose name matches
+	// query, best matches first
+	SearchUsers(ctx context.Context, query string, limit, offset int) ([]*models.User, error)
+}
+
+type CredentialRepository interface {
+	CreateCredentials(ctx context.Context, credentials *models.Credentials) error
+	// CreateCredentialsWithTx creates the credentials as part of tx, wrapping ErrDuplicate
+	// when the username is taken
+	CreateCredentialsWithTx(ctx context.Context, tx *sql.Tx, credentials *models.Credentials) error
+	GetCredentialsByUsername(ctx context.Context, username string) (*models.Credentials, error)
+}
+
+// TransactionPINRepository stores the PINs users step up withdrawals and transfers with
+type TransactionPINRepository interface {
+	// GetTransactionPIN returns the user's PIN, wrapping ErrNotFound when they set none
+	GetTransactionPIN(ctx context.Context, userID uuid.UUID) (*models.TransactionPIN, error)
+	// SetTransactionPIN creates or replaces the user's PIN, clearing its failed attempts
+	// and any lock
+	SetTransactionPIN(ctx context.Context, pin *models.TransactionPIN) error
+	// DeleteTransactionPIN removes the user's PIN, wrapping ErrNotFound when they set none
+	DeleteTransactionPIN(ctx context.Context, userID uuid.UUID) error
+	// RecordFailedPINAttempt counts a wrong PIN and returns the failed attempts since
+	// the last right one
+	RecordFailedPINAttempt(ctx context.Context, userID uuid.UUID, at time.Time) (int, error)
+	// ResetPINAttempts clears the failed attempts, locking the PIN until lockedUntil
+	// unless it is nil
+	ResetPINAttempts(ctx context.Context, userID uuid.UUID, lockedUntil *time.Time, at time.Time) error
+}
+
+type IdempotencyKeyRepository interface {
+	// ReserveIdempotencyKey inserts an in-flight key, wrapping ErrDuplicate if it already exists
+	ReserveIdempotencyKey(ctx context.Context, key *models.IdempotencyKey) error
+	CompleteIdempotencyKey(ctx context.Context, key *models.IdempotencyKey) error
+	GetIdempotencyKey(ctx context.Context, requestKey string) (*models.IdempotencyKey, error)
+	DeleteIdempotencyKey(ctx context.Context, requestKey string) error
 }
 
 type WalletRepository interface {
 	CreateWallet(ctx context.Context, userID uuid.UUID) (*models.Wallet, error)
+	CreateWalletWithTx(ctx context.Context, tx *sql.Tx, userID uuid.UUID) (*models.Wallet, error)
 	GetWalletByUserID(ctx context.Context, userID uuid.UUID) (*models.Wallet, error)
 	GetWalletByID(ctx context.Context, id uuid.UUID) (*models.Wallet, error)
-	UpdateBalance(ctx context.Context, id uuid.UUID, balance decimal.Decimal) error
+	// The updates below compare and swap on the wallet's version: they only apply if the
+	// wallet is still at version, which they then increment, and wrap ErrVersionConflict otherwise
+	UpdateBalance(ctx context.Context, id uuid.UUID, balance decimal.Decimal, version int64) error
 	// Transaction support for atomic operations
 	BeginTx(ctx context.Context) (*sql.Tx, error)
-	UpdateBalanceWithTx(ctx context.Context, tx *sql.Tx, id uuid.UUID, balance decimal.Decimal) error
+	UpdateBalanceWithTx(ctx context.Context, tx *sql.Tx, id uuid.UUID, balance decimal.Decimal, version int64) error
+	// GetWalletByIDWithTx reads the wallet and locks its row until tx ends
 	GetWalletByIDWithTx(ctx context.Context, tx *sql.Tx, id uuid.UUID) (*models.Wallet, error)
+	// GetWalletSnapshotWithTx reads the wallet without locking it, for optimistic updates
+	GetWalletSnapshotWithTx(ctx context.Context, tx *sql.Tx, id uuid.UUID) (*models.Wallet, error)
+	UpdateStatusWithTx(ctx context.Context, tx *sql.Tx, id uuid.UUID, status string, version int64) error
+	// UpdateHeldBalanceWithTx sets the part of the balance reserved by active holds
+	UpdateHeldBalanceWithTx(ctx context.Context, tx *sql.Tx, id uuid.UUID, heldBalance decimal.Decimal, version int64) error
+	// SearchWallets returns the wallets matching filter
+	SearchWallets(ctx context.Context, filter WalletFilter) ([]*models.Wallet, error)
 }
 
-type TransactionRepository interface {
-	CreateTransaction(ctx context.Context, transaction *models.Transaction) error
-	CreateTransactionWithTx(ctx context.Context, tx *sql.Tx, transaction *models.Transaction) error
+// SystemWalletRepository stores the wallets the platform keeps for itself, at most one
+// of each kind per currency, owned by models.SystemUserID
+type SystemWalletRepository interface {
+	// EnsureSystemWallet returns the system wallet of kind in currency, creating it when
+	// there is none yet. Concurrent calls get the same wallet.
+	EnsureSystemWallet(ctx context.Context, kind string, currency money.Currency) (*models.Wallet, error)
+	// ListSystemWallets returns every system wallet by kind and currency
+	ListSystemWallets(ctx context.Context) ([]*models.Wallet, error)
+}
+
+// LedgerRepository stores money movements as balanced double-entry journals
+type LedgerRepository interface {
+	// CreateJournalWithTx validates and inserts a journal with all of its entries
+	CreateJournalWithTx(ctx context.Context, tx *sql.Tx, journal *models.Journal) error
+	// GetJournalByIdempotencyKey returns the journal and entries recorded under key, wrapping ErrNotFound
+	GetJournalByIdempotencyKey(ctx context.Context, key string) (*models.Journal, error)
+	// GetJournalByID returns the journal and its entries, wrapping ErrNotFound
+	GetJournalByID(ctx context.Context, id uuid.UUID) (*models.Journal, error)
+	// GetJournalByEntryID returns the journal one of whose ledger entries has the ID,
+	// wrapping ErrNotFound
+	GetJournalByEntryID(ctx context.Context, entryID uuid.UUID) (*models.Journal, error)
+	// GetTransactionsByWalletID returns the wallet's ledger entries as transactions with
+	// their tags, newest first
 	GetTransactionsByWalletID(ctx context.Context, walletID uuid.UUID) ([]*models.Transaction, error)
+	// ListTransactionsByWalletID returns a page of the wallet's transactions matching
+	// filter, with their tags
+	ListTransactionsByWalletID(ctx context.Context, walletID uuid.UUID, filter TransactionFilter) ([]*models.Transaction, error)
+	// ListTransactionsAfter returns up to limit ledger entries of every wallet as
+	// transactions, without their tags, in ID order after the entry with ID after and
+	// created before until
+	ListTransactionsAfter(ctx context.Context, after uuid.UUID, until time.Time, limit int) ([]*models.Transaction, error)
+	// GetTransfersByWalletID returns a page of the wallet's transfers with their
+	// counterparties, newest first
+	GetTransfersByWalletID(ctx context.Context, walletID uuid.UUID, limit, offset int) ([]*models.WalletTransfer, error)
+	// StreamTransactionsByWalletID calls fn for each of the wallet's transactions created in
+	// [from, to), oldest first, without loading them all into memory
+	StreamTransactionsByWalletID(ctx context.Context, walletID uuid.UUID, from, to time.Time, fn func(*models.Transaction) error) error
+	// GetWalletLedgerBalance sums the wallet's credits minus its debits
+	GetWalletLedgerBalance(ctx context.Context, walletID uuid.UUID) (decimal.Decimal, error)
+	// GetWalletLedgerBalanceBefore sums the wallet's entries created before the given time
+	GetWalletLedgerBalanceBefore(ctx context.Context, walletID uuid.UUID, before time.Time) (decimal.Decimal, error)
+	// GetWalletLedgerBalanceBetween sums the wallet's entries created in [from, to)
+	GetWalletLedgerBalanceBetween(ctx context.Context, walletID uuid.UUID, from, to time.Time) (decimal.Decimal, error)
+	// GetCategoryTotals totals the wallet's entries created in [from, to) by the category
+	// of their journal, in no particular order
+	GetCategoryTotals(ctx context.Context, walletID uuid.UUID, from, to time.Time) ([]*models.CategoryTotal, error)
+	// IsJournalReversedWithTx reports whether a reversal, partial or whole, or a
+	// correction has undone the journal
+	IsJournalReversedWithTx(ctx context.Context, tx *sql.Tx, journalID uuid.UUID) (bool, error)
+	// SumWalletDebitsSinceWithTx sums the money that left the wallet in journals of the
+	// given type created at or after since, including what tx itself has recorded
+	SumWalletDebitsSinceWithTx(ctx context.Context, tx *sql.Tx, walletID uuid.UUID, journalType string, since time.Time) (decimal.Decimal, error)
+}
+
+// HoldRepository stores reservations of wallet funds awaiting capture or release
+type HoldRepository interface {
+	CreateHoldWithTx(ctx context.Context, tx *sql.Tx, hold *models.Hold) error
+	// GetHoldWithTx locks the hold until tx ends, wrapping ErrNotFound when there is none
+	GetHoldWithTx(ctx context.Context, tx *sql.Tx, id uuid.UUID) (*models.Hold, error)
+	// ListHoldsByWalletID returns the wallet's holds, newest first
+	ListHoldsByWalletID(ctx context.Context, walletID uuid.UUID) ([]*models.Hold, error)
+	// UpdateHoldWithTx stores the hold's status and capture details
+	UpdateHoldWithTx(ctx context.Context, tx *sql.Tx, hold *models.Hold) error
+}
+
+// ScheduledTransferRepository stores one-time and recurring transfers waiting to run
+type ScheduledTransferRepository interface {
+	CreateScheduledTransfer(ctx context.Context, transfer *models.ScheduledTransfer) error
+	// GetScheduledTransfer wraps ErrNotFound when there is no transfer with the ID
+	GetScheduledTransfer(ctx context.Context, id uuid.UUID) (*models.ScheduledTransfer, error)
+	// ListScheduledTransfersByWalletID returns transfers paying out of the wallet, newest first
+	ListScheduledTransfersByWalletID(ctx context.Context, walletID uuid.UUID) ([]*models.ScheduledTransfer, error)
+	// ListDueScheduledTransfers returns up to limit active transfers due at or before now, oldest first
+	ListDueScheduledTransfers(ctx context.Context, now time.Time, limit int) ([]*models.ScheduledTransfer, error)
+	// RecordScheduledTransferRun stores the outcome of a run if the transfer is still
+	// active at previousOccurrence, and reports whether it was
+	RecordScheduledTransferRun(ctx context.Context, transfer *models.ScheduledTransfer, previousOccurrence int) (bool, error)
+	// CancelScheduledTransfer cancels an active transfer, wrapping ErrNotFound otherwise
+	CancelScheduledTransfer(ctx context.Context, id uuid.UUID, cancelledAt time.Time) error
+}
+
+// PaymentRequestRepository stores requests for one wallet to pay another
+type PaymentRequestRepository interface {
+	CreatePaymentRequest(ctx context.Context, request *models.PaymentRequest) error
+	// GetPaymentRequestWithTx locks the request until tx ends, wrapping ErrNotFound when there is none
+	GetPaymentRequestWithTx(ctx context.Context, tx *sql.Tx, id uuid.UUID) (*models.PaymentRequest, error)
+	// ListPendingPaymentRequestsByPayer returns the requests awaiting the payer wallet's answer, newest first
+	ListPendingPaymentRequestsByPayer(ctx context.Context, payerWalletID uuid.UUID) ([]*models.PaymentRequest, error)
+	// UpdatePaymentRequestWithTx stores the request's status and transfer journal
+	UpdatePaymentRequestWithTx(ctx context.Context, tx *sql.Tx, request *models.PaymentRequest) error
+}
+
+// PendingTransferRepository stores transfers waiting to be confirmed or approved
+type PendingTransferRepository interface {
+	// CreatePendingTransfer wraps ErrDuplicate when another transfer has its idempotency key
+	CreatePendingTransfer(ctx context.Context, transfer *models.PendingTransfer) error
+	// GetPendingTransfer wraps ErrNotFound when there is none
+	GetPendingTransfer(ctx context.Context, id uuid.UUID) (*models.PendingTransfer, error)
+	// GetPendingTransferByIdempotencyKey returns the transfer held under the scoped key,
+	// wrapping ErrNotFound when there is none
+	GetPendingTransferByIdempotencyKey(ctx context.Context, key string) (*models.PendingTransfer, error)
+	// GetPendingTransferWithTx locks the transfer until tx ends, wrapping ErrNotFound when there is none
+	GetPendingTransferWithTx(ctx context.Context, tx *sql.Tx, id uuid.UUID) (*models.PendingTransfer, error)
+	// ListPendingTransfersByWalletID returns the transfers sent from the wallet, newest first
+	ListPendingTransfersByWalletID(ctx context.Context, walletID uuid.UUID) ([]*models.PendingTransfer, error)
+	// ListPendingTransfers returns a page of transfers in the status, or in any status
+	// when it is empty, oldest first
+	ListPendingTransfers(ctx context.Context, status string, limit, offset int) ([]*models.PendingTransfer, error)
+	// ListExpiredPendingTransfers returns up to limit transfers still pending at now
+	// whose confirmation window has ended, oldest expiry first
+	ListExpiredPendingTransfers(ctx context.Context, now time.Time, limit int) ([]*models.PendingTransfer, error)
+	// UpdatePendingTransferWithTx stores the transfer's status, reason and journal
+	UpdatePendingTransferWithTx(ctx context.Context, tx *sql.Tx, transfer *models.PendingTransfer) error
+}
+
+// IncomingTransferRepository stores which wallets require incoming transfers to be
+// accepted and the transfers awaiting it
+type IncomingTransferRepository interface {
+	// GetIncomingTransferSettings returns the wallet's settings, not requiring acceptance
+	// when none were stored
+	GetIncomingTransferSettings(ctx context.Context, walletID uuid.UUID) (*models.IncomingTransferSettings, error)
+	// GetIncomingTransferSettingsWithTx is GetIncomingTransferSettings inside tx
+	GetIncomingTransferSettingsWithTx(ctx context.Context, tx *sql.Tx, walletID uuid.UUID) (*models.IncomingTransferSettings, error)
+	// SetIncomingTransferSettings creates or replaces the wallet's settings
+	SetIncomingTransferSettings(ctx context.Context, settings *models.IncomingTransferSettings) error
+	CreateIncomingTransferWithTx(ctx context.Context, tx *sql.Tx, transfer *models.IncomingTransfer) error
+	// GetIncomingTransfer wraps ErrNotFound when there is none
+	GetIncomingTransfer(ctx context.Context, id uuid.UUID) (*models.IncomingTransfer, error)
+	// GetIncomingTransferWithTx locks the transfer until tx ends, wrapping ErrNotFound when there is none
+	GetIncomingTransferWithTx(ctx context.Context, tx *sql.Tx, id uuid.UUID) (*models.IncomingTransfer, error)
+	// ListIncomingTransfersByWalletID returns the transfers into the wallet that needed
+	// accepting, newest first
+	ListIncomingTransfersByWalletID(ctx context.Context, walletID uuid.UUID) ([]*models.IncomingTransfer, error)
+	// ListExpiredIncomingTransfers returns up to limit transfers still pending at now
+	// whose acceptance window has ended, in ID order after the transfer with ID after
+	ListExpiredIncomingTransfers(ctx context.Context, now time.Time, after uuid.UUID, limit int) ([]*models.IncomingTransfer, error)
+	// IsAwaitingAcceptanceWithTx reports whether the transfer journal posted an incoming
+	// transfer that is still pending, without locking it
+	IsAwaitingAcceptanceWithTx(ctx context.Context, tx *sql.Tx, journalID uuid.UUID) (bool, error)
+	// UpdateIncomingTransferWithTx stores the transfer's status and return journal
+	UpdateIncomingTransferWithTx(ctx context.Context, tx *sql.Tx, transfer *models.IncomingTransfer) error
+}
+
+// TransferQuoteRepository stores the terms transfers were quoted at
+type TransferQuoteRepository interface {
+	CreateTransferQuote(ctx context.Context, quote *models.TransferQuote) error
+	// GetTransferQuote wraps ErrNotFound when there is none
+	GetTransferQuote(ctx context.Context, id uuid.UUID) (*models.TransferQuote, error)
+	// UseTransferQuoteWithTx records the transfer journal that used the quote, wrapping
+	// ErrNotFound when there is no quote or another transfer already used it
+	UseTransferQuoteWithTx(ctx context.Context, tx *sql.Tx, id, journalID uuid.UUID) error
+}
+
+// PaymentRepository stores merchant accounts, the payments made to them and their refunds
+type PaymentRepository interface {
+	// CreateMerchantAccount wraps ErrDuplicate when the wallet already is one
+	CreateMerchantAccount(ctx context.Context, account *models.MerchantAccount) error
+	// GetMerchantAccount wraps ErrNotFound when the wallet is not a merchant account
+	GetMerchantAccount(ctx context.Context, walletID uuid.UUID) (*models.MerchantAccount, error)
+	// CreatePaymentWithTx wraps ErrDuplicate when the merchant was already paid for the order
+	CreatePaymentWithTx(ctx context.Context, tx *sql.Tx, payment *models.Payment) error
+	// GetPayment returns the payment with its refunds, oldest first, wrapping ErrNotFound
+	// when there is none
+	GetPayment(ctx context.Context, id uuid.UUID) (*models.Payment, error)
+	// GetPaymentWithTx locks the payment until tx ends, wrapping ErrNotFound when there is none
+	GetPaymentWithTx(ctx context.Context, tx *sql.Tx, id uuid.UUID) (*models.Payment, error)
+	// GetPaymentByJournalIDWithTx returns the payment that journal paid, without its
+	// refunds and without locking it, wrapping ErrNotFound when there is none
+	GetPaymentByJournalIDWithTx(ctx context.Context, tx *sql.Tx, journalID uuid.UUID) (*models.Payment, error)
+	// UpdatePaymentWithTx stores the payment's refunded amount and status
+	UpdatePaymentWithTx(ctx context.Context, tx *sql.Tx, payment *models.Payment) error
+	CreatePaymentRefundWithTx(ctx context.Context, tx *sql.Tx, refund *models.PaymentRefund) error
+	// GetMerchantSettlement counts and sums the payments to the merchant wallet and the
+	// refunds it made in [from, to)
+	GetMerchantSettlement(ctx context.Context, merchantWalletID uuid.UUID, from, to time.Time) (*models.MerchantSettlement, error)
+}
+
+// PayoutRepository stores payouts from wallets to bank accounts
+type PayoutRepository interface {
+	CreatePayoutWithTx(ctx context.Context, tx *sql.Tx, payout *models.Payout) error
+	// GetPayout wraps ErrNotFound when there is none
+	GetPayout(ctx context.Context, id uuid.UUID) (*models.Payout, error)
+	// GetPayoutByJournalID returns the payout that journal debited the wallet for,
+	// wrapping ErrNotFound when there is none
+	GetPayoutByJournalID(ctx context.Context, journalID uuid.UUID) (*models.Payout, error)
+	// GetPayoutByJournalIDWithTx is GetPayoutByJournalID inside tx, without locking the payout
+	GetPayoutByJournalIDWithTx(ctx context.Context, tx *sql.Tx, journalID uuid.UUID) (*models.Payout, error)
+	// GetPayoutWithTx locks the payout until tx ends, wrapping ErrNotFound when there is none
+	GetPayoutWithTx(ctx context.Context, tx *sql.Tx, id uuid.UUID) (*models.Payout, error)
+	// UpdatePayoutWithTx stores the payout's status, provider reference, failure reason
+	// and refund journal
+	UpdatePayoutWithTx(ctx context.Context, tx *sql.Tx, payout *models.Payout) error
+	// ListPayouts returns a page of the payouts filter matches, newest first
+	ListPayouts(ctx context.Context, filter PayoutFilter) ([]*models.Payout, error)
+	// ListPayoutsInStatus returns up to limit payouts in the status, in ID order after
+	// the payout with ID after
+	ListPayoutsInStatus(ctx context.Context, status string, after uuid.UUID, limit int) ([]*models.Payout, error)
+}
+
+// ProviderPaymentRepository stores the payments the payment provider took to fund wallets
+type ProviderPaymentRepository interface {
+	// CreateProviderPaymentWithTx wraps ErrDuplicate when the provider's payment ID is
+	// already recorded
+	CreateProviderPaymentWithTx(ctx context.Context, tx *sql.Tx, payment *models.ProviderPayment) error
+	// GetProviderPayment wraps ErrNotFound when the provider's payment ID is not recorded
+	GetProviderPayment(ctx context.Context, paymentID string) (*models.ProviderPayment, error)
+	// GetProviderPaymentByJournalID returns the payment that journal deposited, wrapping
+	// ErrNotFound when there is none
+	GetProviderPaymentByJournalID(ctx context.Context, journalID uuid.UUID) (*models.ProviderPayment, error)
+	// GetProviderPaymentWithTx locks the payment until tx ends, wrapping ErrNotFound when
+	// the provider's payment ID is not recorded
+	GetProviderPaymentWithTx(ctx context.Context, tx *sql.Tx, paymentID string) (*models.ProviderPayment, error)
+	// UpdateProviderPaymentWithTx stores the payment's status, failure reason and deposit
+	// journal
+	UpdateProviderPaymentWithTx(ctx context.Context, tx *sql.Tx, payment *models.ProviderPayment) error
+}
+
+// DepositRefundRepository stores the refunds of deposits
+type DepositRefundRepository interface {
+	CreateDepositRefundWithTx(ctx context.Context, tx *sql.Tx, refund *models.DepositRefund) error
+	// GetDepositRefundWithTx locks the refund until tx ends, wrapping ErrNotFound when there is none
+	GetDepositRefundWithTx(ctx context.Context, tx *sql.Tx, id uuid.UUID) (*models.DepositRefund, error)
+	// GetDepositRefundByJournalIDWithTx returns the refund that journal debited the
+	// wallet for, without locking it, wrapping ErrNotFound when there is none
+	GetDepositRefundByJournalIDWithTx(ctx context.Context, tx *sql.Tx, journalID uuid.UUID) (*models.DepositRefund, error)
+	// UpdateDepositRefundWithTx stores the refund's status, provider refund ID, failure
+	// reason and reversal journal
+	UpdateDepositRefundWithTx(ctx context.Context, tx *sql.Tx, refund *models.DepositRefund) error
+	// ListDepositRefundsWithTx returns the refunds of the deposit journal, oldest first
+	ListDepositRefundsWithTx(ctx context.Context, tx *sql.Tx, depositJournalID uuid.UUID) ([]*models.DepositRefund, error)
+	// ListDepositRefundsInStatus returns up to limit refunds in the status, in ID order
+	// after the refund with ID after
+	ListDepositRefundsInStatus(ctx context.Context, status string, after uuid.UUID, limit int) ([]*models.DepositRefund, error)
+	// IsDepositReversedWithTx reports whether a reversal or correction has undone the
+	// deposit journal
+	IsDepositReversedWithTx(ctx context.Context, tx *sql.Tx, depositJournalID uuid.UUID) (bool, error)
+}
+
+// DisputeRepository stores disputes of transfers and every status they entered
+type DisputeRepository interface {
+	// CreateDisputeWithTx wraps ErrDuplicate when the transaction was already disputed
+	CreateDisputeWithTx(ctx context.Context, tx *sql.Tx, dispute *models.Dispute) error
+	// GetDispute returns the dispute with its events, oldest first, wrapping ErrNotFound
+	// when there is none
+	GetDispute(ctx context.Context, id uuid.UUID) (*models.Dispute, error)
+	// GetDisputeWithTx locks the dispute until tx ends, wrapping ErrNotFound when there is none
+	GetDisputeWithTx(ctx context.Context, tx *sql.Tx, id uuid.UUID) (*models.Dispute, error)
+	// ListDisputesByTransactionID returns the disputes of the transaction with their events
+	ListDisputesByTransactionID(ctx context.Context, transactionID uuid.UUID) ([]*models.Dispute, error)
+	// ListDisputes returns a page of disputes in the status, or in any status when it is
+	// empty, oldest first and without their events
+	ListDisputes(ctx context.Context, status string, limit, offset int) ([]*models.Dispute, error)
+	// UpdateDisputeWithTx stores the dispute's status and refund
+	UpdateDisputeWithTx(ctx context.Context, tx *sql.Tx, dispute *models.Dispute) error
+	CreateDisputeEventWithTx(ctx context.Context, tx *sql.Tx, event *models.DisputeEvent) error
+}
+
+// NotificationPreferencesRepository stores the transaction alerts each user chose
+type NotificationPreferencesRepository interface {
+	// GetNotificationPreferences returns the user's preferences, or ErrNotFound when
+	// they never set any
+	GetNotificationPreferences(ctx context.Context, userID uuid.UUID) (*models.NotificationPreferences, error)
+	// SetNotificationPreferences creates or replaces the user's preferences
+	SetNotificationPreferences(ctx context.Context, preferences *models.NotificationPreferences) error
+}
+
+// WalletLimitsRepository stores the per-wallet transaction limits
+type WalletLimitsRepository interface {
+	// GetWalletLimits returns the wallet's limits, all unset when none were stored
+	GetWalletLimits(ctx context.Context, walletID uuid.UUID) (*models.WalletLimits, error)
+	// GetWalletLimitsWithTx is GetWalletLimits inside tx
+	GetWalletLimitsWithTx(ctx context.Context, tx *sql.Tx, walletID uuid.UUID) (*models.WalletLimits, error)
+	// SetWalletLimits creates or replaces the wallet's limits
+	SetWalletLimits(ctx context.Context, limits *models.WalletLimits) error
+}
+
+// KYCRepository stores users' verification tiers and the documents they submitted
+type KYCRepository interface {
+	// GetKYCProfile returns the user's tier, unverified when none was set, without documents
+	GetKYCProfile(ctx context.Context, userID uuid.UUID) (*models.KYCProfile, error)
+	// GetKYCTierWithTx is the user's tier inside tx
+	GetKYCTierWithTx(ctx context.Context, tx *sql.Tx, userID uuid.UUID) (string, error)
+	// SetKYCProfile creates or replaces the user's tier and its note
+	SetKYCProfile(ctx context.Context, profile *models.KYCProfile) error
+	CreateKYCDocument(ctx context.Context, document *models.KYCDocument) error
+	// GetKYCDocument wraps ErrNotFound when there is no document with the ID
+	GetKYCDocument(ctx context.Context, id uuid.UUID) (*models.KYCDocument, error)
+	// ListKYCDocumentsByUserID returns the user's documents, newest first
+	ListKYCDocumentsByUserID(ctx context.Context, userID uuid.UUID) ([]*models.KYCDocument, error)
+	// ListKYCDocumentsByStatus returns a page of documents in the status, oldest first
+	ListKYCDocumentsByStatus(ctx context.Context, status string, limit, offset int) ([]*models.KYCDocument, error)
+	// UpdateKYCDocument stores the review of a pending document, wrapping ErrNotFound
+	// when it is no longer pending
+	UpdateKYCDocument(ctx context.Context, document *models.KYCDocument) error
+}
+
+// WalletAlertRepository stores the alerts each wallet's owner chose and those raised
+type WalletAlertRepository interface {
+	// GetWalletAlertSettings returns the wallet's alert settings, all unset when none were stored
+	GetWalletAlertSettings(ctx context.Context, walletID uuid.UUID) (*models.WalletAlertSettings, error)
+	// GetWalletAlertSettingsWithTx is GetWalletAlertSettings inside tx
+	GetWalletAlertSettingsWithTx(ctx context.Context, tx *sql.Tx, walletID uuid.UUID) (*models.WalletAlertSettings, error)
+	// SetWalletAlertSettings creates or replaces the wallet's alert settings
+	SetWalletAlertSettings(ctx context.Context, settings *models.WalletAlertSettings) error
+	// CreateWalletAlertWithTx records the alert as part of tx, giving it an ID
+	CreateWalletAlertWithTx(ctx context.Context, tx *sql.Tx, alert *models.WalletAlert) error
+	// ListWalletAlerts returns the wallet's alerts, newest first
+	ListWalletAlerts(ctx context.Context, walletID uuid.UUID, limit, offset int) ([]*models.WalletAlert, error)
+}
+
+// RiskRepository reads the wallet activity risk rules look at and stores their decisions
+type RiskRepository interface {
+	// CountWalletDebitsSince counts the wallet's outgoing journals of the type since the time
+	CountWalletDebitsSince(ctx context.Context, walletID uuid.UUID, journalType string, since time.Time) (int, error)
+	// AverageWalletDebitSince averages the wallet's outgoing journals of the type since
+	// the time, and reports how many there were
+	AverageWalletDebitSince(ctx context.Context, walletID uuid.UUID, journalType string, since time.Time) (decimal.Decimal, int, error)
+	// HasTransferredTo reports whether the wallet has ever transferred to the recipient
+	HasTransferredTo(ctx context.Context, walletID, toWalletID uuid.UUID) (bool, error)
+	CreateRiskDecision(ctx context.Context, decision *models.RiskDecision) error
+	// ListRiskDecisions returns the decisions matching filter, newest first
+	ListRiskDecisions(ctx context.Context, filter RiskDecisionFilter) ([]*models.RiskDecision, error)
+}
+
+// AMLRepository reads the wallet activity the anti-money-laundering checks look at and
+// stores the reports they raise
+type AMLRepository interface {
+	// SumWalletMovementsBetweenWithTx counts and sums, inside tx, the wallet's entries in
+	// direction from journals of the type since the time whose amount is from min to
+	// max inclusive
+	SumWalletMovementsBetweenWithTx(ctx context.Context, tx *sql.Tx, walletID uuid.UUID, journalType, direction string, min, max decimal.Decimal, since time.Time) (int, decimal.Decimal, error)
+	// HasAMLReportSinceWithTx reports whether the wallet has a report of the type for
+	// journals of the operation since the time
+	HasAMLReportSinceWithTx(ctx context.Context, tx *sql.Tx, walletID uuid.UUID, reportType, operation string, since time.Time) (bool, error)
+	// CreateAMLReportWithTx records the report as part of tx, giving it an ID
+	CreateAMLReportWithTx(ctx context.Context, tx *sql.Tx, report *models.AMLReport) error
+	// ListAMLReports returns the reports matching filter, newest first
+	ListAMLReports(ctx context.Context, filter AMLReportFilter) ([]*models.AMLReport, error)
+	// ListAMLReportsAfter returns up to limit reports in ID order after the report with
+	// ID after, created from from and before until
+	ListAMLReportsAfter(ctx context.Context, after uuid.UUID, from, until time.Time, limit int) ([]*models.AMLReport, error)
+}
+
+// OutboxRepository stores wallet events until they are published
+type OutboxRepository interface {
+	// CreateOutboxEventWithTx inserts the event as part of tx, so it exists only if tx commits
+	CreateOutboxEventWithTx(ctx context.Context, tx *sql.Tx, event *models.OutboxEvent) error
+	BeginTx(ctx context.Context) (*sql.Tx, error)
+	// ClaimUnpublishedWithTx locks up to limit unpublished events, oldest first, skipping
+	// those another dispatcher has already locked
+	ClaimUnpublishedWithTx(ctx context.Context, tx *sql.Tx, limit int) ([]*models.OutboxEvent, error)
+	MarkPublishedWithTx(ctx context.Context, tx *sql.Tx, id uuid.UUID, publishedAt time.Time) error
+	// RecordFailureWithTx counts a failed attempt to publish the event and keeps the error
+	RecordFailureWithTx(ctx context.Context, tx *sql.Tx, id uuid.UUID, message string) error
+}
+
+// AuditRepository appends to the audit log and reads it back; entries are never changed
+type AuditRepository interface {
+	CreateAuditEntry(ctx context.Context, entry *models.AuditEntry) error
+	// ListAuditEntries returns the entries matching filter, newest first
+	ListAuditEntries(ctx context.Context, filter AuditFilter) ([]*models.AuditEntry, error)
+	// ListAuditEntriesAfter returns up to limit entries in ID order after the entry with
+	// ID after and created before until
+	ListAuditEntriesAfter(ctx context.Context, after uuid.UUID, until time.Time, limit int) ([]*models.AuditEntry, error)
+}
+
+// ExportCheckpointRepository remembers how far each export stream has got
+type ExportCheckpointRepository interface {
+	// GetExportCheckpoint returns the stream's checkpoint, or one at the start when the
+	// stream has never exported
+	GetExportCheckpoint(ctx context.Context, stream string) (*models.ExportCheckpoint, error)
+	SaveExportCheckpoint(ctx context.Context, checkpoint *models.ExportCheckpoint) error
+}
+
+// ExportJobRepository stores the exports of wallets' transactions made in the background
+type ExportJobRepository interface {
+	CreateExportJob(ctx context.Context, job *models.ExportJob) error
+	// GetExportJob wraps ErrNotFound when there is none
+	GetExportJob(ctx context.Context, id uuid.UUID) (*models.ExportJob, error)
+	// UpdateExportJob stores the job's status, object key, rows, failure reason and
+	// completion time
+	UpdateExportJob(ctx context.Context, job *models.ExportJob) error
+	// ListExportJobsInStatus returns up to limit jobs in the status, in ID order after
+	// the job with ID after
+	ListExportJobsInStatus(ctx context.Context, status string, after uuid.UUID, limit int) ([]*models.ExportJob, error)
+}
+
+// BalanceSnapshotRepository stores end-of-day wallet balances
+type BalanceSnapshotRepository interface {
+	// CreateSnapshots snapshots every wallet created before asOf that has no snapshot at
+	// asOf yet, from its previous snapshot plus its ledger entries since, and returns
+	// how many snapshots it wrote
+	CreateSnapshots(ctx context.Context, asOf time.Time) (int64, error)
+	// GetLatestSnapshotTime returns the most recent as_of of any snapshot, or the zero
+	// time when there are none
+	GetLatestSnapshotTime(ctx context.Context) (time.Time, error)
+	// GetLatestSnapshot returns the wallet's most recent snapshot as of at or earlier,
+	// wrapping ErrNotFound when there is none
+	GetLatestSnapshot(ctx context.Context, walletID uuid.UUID, at time.Time) (*models.BalanceSnapshot, error)
+}
+
+// ReconciliationRepository checks stored wallet balances against the ledger and keeps
+// the results
+type ReconciliationRepository interface {
+	// CountWallets returns how many wallets there are
+	CountWallets(ctx context.Context) (int64, error)
+	// FindBalanceDiscrepancies returns every wallet whose stored balance differs from the
+	// sum of its ledger entries, compared in a single consistent read
+	FindBalanceDiscrepancies(ctx context.Context) ([]*models.BalanceDiscrepancy, error)
+	// CreateRun records the run and its discrepancies together, assigning their IDs
+	CreateRun(ctx context.Context, run *models.ReconciliationRun) error
+	// ListRuns returns runs newest first
+	ListRuns(ctx context.Context, limit, offset int) ([]*models.ReconciliationRun, error)
+	// ListDiscrepancies returns the discrepancies matching filter, newest first
+	ListDiscrepancies(ctx context.Context, filter DiscrepancyFilter) ([]*models.BalanceDiscrepancy, error)
+}
+
+// FeatureFlagRepository stores the feature flag values operators set at runtime,
+// satisfying featureflag.Store
+type FeatureFlagRepository interface {
+	// GetFlag returns the flag's stored value; ok is false when it has none
+	GetFlag(ctx context.Context, name string) (enabled, ok bool, err error)
+	// SetFlag creates or replaces the flag's value
+	SetFlag(ctx context.Context, name string, enabled bool) error
+	// DeleteFlag removes the flag's value, if any
+	DeleteFlag(ctx context.Context, name string) error
+	// ListFlags returns every stored value by flag name
+	ListFlags(ctx context.Context) (map[string]bool, error)
 }
@@ -0,0 +1,108 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository"
+)
+
+const walletAlertSettingsQuery = `
+		SELECT wallet_id, low_balance_threshold, updated_at
+		FROM wallet_alert_settings
+		WHERE wallet_id = ?`
+
+type WalletAlertRepository struct {
+	db *sqlx.DB
+}
+
+func NewWalletAlertRepository(db *sqlx.DB) *WalletAlertRepository {
+	return &WalletAlertRepository{db: db}
+}
+
+func (r *WalletAlertRepository) GetWalletAlertSettings(ctx context.Context, walletID uuid.UUID) (*models.WalletAlertSettings, error) {
+	return scanWalletAlertSettings(r.db.QueryRowContext(ctx, walletAlertSettingsQuery, walletID), walletID)
+}
+
+func (r *WalletAlertRepository) GetWalletAlertSettingsWithTx(ctx context.Context, tx *sql.Tx, walletID uuid.UUID) (*models.WalletAlertSettings, error) {
+	return scanWalletAlertSettings(tx.QueryRowContext(ctx, walletAlertSettingsQuery, walletID), walletID)
+}
+
+func (r *WalletAlertRepository) SetWalletAlertSettings(ctx context.Context, settings *models.WalletAlertSettings) error {
+	query := `
+		INSERT INTO wallet_alert_settings (wallet_id, low_balance_threshold, updated_at)
+		VALUES (?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+			low_balance_threshold = VALUES(low_balance_threshold),
+			updated_at = VALUES(updated_at)`
+
+	_, err := r.db.ExecContext(ctx, query, settings.WalletID, settings.LowBalanceThreshold, settings.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to set wallet alert settings: %w", err)
+	}
+
+	return nil
+}
+
+func (r *WalletAlertRepository) CreateWalletAlertWithTx(ctx context.Context, tx *sql.Tx, alert *models.WalletAlert) error {
+	id, err := repository.NewTimeOrderedID()
+	if err != nil {
+		return fmt.Errorf("failed to generate wallet alert ID: %w", err)
+	}
+	alert.ID = id
+
+	query := `
+		INSERT INTO wallet_alerts (id, wallet_id, alert_type, journal_id, threshold, balance, currency, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+
+	_, err = tx.ExecContext(ctx, query,
+		alert.ID,
+		alert.WalletID,
+		alert.Type,
+		alert.JournalID,
+		alert.Threshold,
+		alert.Balance,
+		alert.Currency,
+		alert.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create wallet alert: %w", err)
+	}
+
+	return nil
+}
+
+func (r *WalletAlertRepository) ListWalletAlerts(ctx context.Context, walletID uuid.UUID, limit, offset int) ([]*models.WalletAlert, error) {
+	query := `
+		SELECT id, wallet_id, alert_type, journal_id, threshold, balance, currency, created_at
+		FROM wallet_alerts
+		WHERE wallet_id = ?
+		ORDER BY created_at DESC, id DESC
+		LIMIT ? OFFSET ?`
+
+	alerts := []*models.WalletAlert{}
+	if err := r.db.SelectContext(ctx, &alerts, query, walletID, limit, offset); err != nil {
+		return nil, fmt.Errorf("failed to list wallet alerts: %w", err)
+	}
+	return alerts, nil
+}
+
+// scanWalletAlertSettings reads a wallet_alert_settings row, returning unset settings
+// when there is none
+func scanWalletAlertSettings(row *sql.Row, walletID uuid.UUID) (*models.WalletAlertSettings, error) {
+	settings := &models.WalletAlertSettings{}
+	err := row.Scan(&settings.WalletID, &settings.LowBalanceThreshold, &settings.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return &models.WalletAlertSettings{WalletID: walletID}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get wallet alert settings: %w", err)
+	}
+
+	return settings, nil
+}
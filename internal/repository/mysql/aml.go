@@ -0,0 +1,139 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/shopspring/decimal"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository"
+)
+
+type AMLRepository struct {
+	db *sqlx.DB
+}
+
+func NewAMLRepository(db *sqlx.DB) *AMLRepository {
+	return &AMLRepository{db: db}
+}
+
+func (r *AMLRepository) SumWalletMovementsBetweenWithTx(ctx context.Context, tx *sql.Tx, walletID uuid.UUID, journalType, direction string, min, max decimal.Decimal, since time.Time) (int, decimal.Decimal, error) {
+	var count int
+	var total decimal.Decimal
+
+	query := `
+		SELECT COUNT(*), COALESCE(SUM(e.amount), 0)
+		FROM ledger_entries e
+		JOIN journals j ON j.id = e.journal_id
+		WHERE e.wallet_id = ? AND e.direction = ? AND j.type = ? AND e.created_at >= ?
+			AND e.amount >= ? AND e.amount <= ?`
+
+	err := tx.QueryRowContext(ctx, query, walletID, direction, journalType, since, min, max).Scan(&count, &total)
+	if err != nil {
+		return 0, decimal.Zero, fmt.Errorf("failed to sum ledger entries: %w", err)
+	}
+
+	return count, total, nil
+}
+
+func (r *AMLRepository) HasAMLReportSinceWithTx(ctx context.Context, tx *sql.Tx, walletID uuid.UUID, reportType, operation string, since time.Time) (bool, error) {
+	var exists bool
+
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM aml_reports
+			WHERE wallet_id = ? AND type = ? AND operation = ? AND created_at >= ?
+		)`
+
+	if err := tx.QueryRowContext(ctx, query, walletID, reportType, operation, since).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to look up AML reports: %w", err)
+	}
+
+	return exists, nil
+}
+
+func (r *AMLRepository) CreateAMLReportWithTx(ctx context.Context, tx *sql.Tx, report *models.AMLReport) error {
+	id, err := repository.NewTimeOrderedID()
+	if err != nil {
+		return fmt.Errorf("failed to generate AML report ID: %w", err)
+	}
+	report.ID = id
+
+	query := `
+		INSERT INTO aml_reports (id, type, wallet_id, journal_id, operation, amount, currency, movement_count, total, reason, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	_, err = tx.ExecContext(ctx, query,
+		report.ID,
+		report.Type,
+		report.WalletID,
+		report.JournalID,
+		report.Operation,
+		report.Amount,
+		report.Currency,
+		report.MovementCount,
+		report.Total,
+		report.Reason,
+		report.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create AML report: %w", err)
+	}
+
+	return nil
+}
+
+func (r *AMLRepository) ListAMLReports(ctx context.Context, filter repository.AMLReportFilter) ([]*models.AMLReport, error) {
+	var conditions []string
+	var args []interface{}
+	where := func(condition string, arg interface{}) {
+		conditions = append(conditions, condition)
+		args = append(args, arg)
+	}
+	if filter.Type != "" {
+		where("type = ?", filter.Type)
+	}
+	if filter.WalletID != uuid.Nil {
+		where("wallet_id = ?", filter.WalletID)
+	}
+	if !filter.From.IsZero() {
+		where("created_at >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		where("created_at < ?", filter.To)
+	}
+
+	query := `SELECT id, type, wallet_id, journal_id, operation, amount, currency, movement_count, total, reason, created_at
+		FROM aml_reports`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY created_at DESC, id DESC LIMIT ? OFFSET ?"
+	args = append(args, filter.Limit, filter.Offset)
+
+	reports := []*models.AMLReport{}
+	if err := r.db.SelectContext(ctx, &reports, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to list AML reports: %w", err)
+	}
+	return reports, nil
+}
+
+func (r *AMLRepository) ListAMLReportsAfter(ctx context.Context, after uuid.UUID, from, until time.Time, limit int) ([]*models.AMLReport, error) {
+	query := `SELECT id, type, wallet_id, journal_id, operation, amount, currency, movement_count, total, reason, created_at
+		FROM aml_reports
+		WHERE id > ? AND created_at >= ? AND created_at < ?
+		ORDER BY id
+		LIMIT ?`
+
+	reports := []*models.AMLReport{}
+	if err := r.db.SelectContext(ctx, &reports, query, after, from, until, limit); err != nil {
+		return nil, fmt.Errorf("failed to list AML reports: %w", err)
+	}
+	return reports, nil
+}
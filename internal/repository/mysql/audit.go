@@ -0,0 +1,79 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository"
+)
+
+type AuditRepository struct {
+	db *sqlx.DB
+}
+
+func NewAuditRepository(db *sqlx.DB) *AuditRepository {
+	return &AuditRepository{db: db}
+}
+
+func (r *AuditRepository) CreateAuditEntry(ctx context.Context, entry *models.AuditEntry) error {
+	id, err := repository.NewTimeOrderedID()
+	if err != nil {
+		return fmt.Errorf("failed to generate audit entry ID: %w", err)
+	}
+	entry.ID = id
+
+	query := `
+		INSERT INTO audit_log (id, actor_type, actor_id, endpoint, path, payload_hash, status_code,
+			request_id, wallet_id, balance_before, balance_after, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	_, err = r.db.ExecContext(ctx, query,
+		entry.ID,
+		entry.ActorType,
+		entry.ActorID,
+		entry.Endpoint,
+		entry.Path,
+		entry.PayloadHash,
+		entry.StatusCode,
+		entry.RequestID,
+		entry.WalletID,
+		entry.BalanceBefore,
+		entry.BalanceAfter,
+		entry.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create audit entry: %w", err)
+	}
+
+	return nil
+}
+
+func (r *AuditRepository) ListAuditEntries(ctx context.Context, filter repository.AuditFilter) ([]*models.AuditEntry, error) {
+	query, args := repository.AuditListQuery(filter, repository.QuestionPlaceholders)
+
+	entries := []*models.AuditEntry{}
+	if err := r.db.SelectContext(ctx, &entries, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to list audit entries: %w", err)
+	}
+	return entries, nil
+}
+
+func (r *AuditRepository) ListAuditEntriesAfter(ctx context.Context, after uuid.UUID, until time.Time, limit int) ([]*models.AuditEntry, error) {
+	query := `SELECT id, actor_type, actor_id, endpoint, path, payload_hash, status_code,
+			request_id, wallet_id, balance_before, balance_after, created_at
+		FROM audit_log
+		WHERE id > ? AND created_at < ?
+		ORDER BY id
+		LIMIT ?`
+
+	entries := []*models.AuditEntry{}
+	if err := r.db.SelectContext(ctx, &entries, query, after, until, limit); err != nil {
+		return nil, fmt.Errorf("failed to list audit entries: %w", err)
+	}
+	return entries, nil
+}
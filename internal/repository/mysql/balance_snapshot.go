@@ -0,0 +1,81 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository"
+)
+
+type BalanceSnapshotRepository struct {
+	db *sqlx.DB
+}
+
+func NewBalanceSnapshotRepository(db *sqlx.DB) *BalanceSnapshotRepository {
+	return &BalanceSnapshotRepository{db: db}
+}
+
+// CreateSnapshots carries each wallet's previous snapshot forward by the entries
+// created since it, so a day's run reads only that day's entries. The no-op update
+// skips wallets already snapshotted at asOf without counting them as written.
+func (r *BalanceSnapshotRepository) CreateSnapshots(ctx context.Context, asOf time.Time) (int64, error) {
+	query := `
+		INSERT INTO balance_snapshots (wallet_id, as_of, balance, currency) 
+		SELECT w.id, ?, COALESCE(prev.balance, 0) + COALESCE((
+				SELECT SUM(CASE WHEN e.direction = 'credit' THEN e.amount ELSE -e.amount END) 
+				FROM ledger_entries e 
+				WHERE e.wallet_id = w.id AND e.created_at < ? 
+					AND (prev.as_of IS NULL OR e.created_at >= prev.as_of)
+			), 0), w.currency 
+		FROM wallets w 
+		LEFT JOIN LATERAL (
+			SELECT s.balance, s.as_of 
+			FROM balance_snapshots s 
+			WHERE s.wallet_id = w.id AND s.as_of < ? 
+			ORDER BY s.as_of DESC 
+			LIMIT 1
+		) prev ON TRUE 
+		WHERE w.created_at < ? 
+		ON DUPLICATE KEY UPDATE wallet_id = balance_snapshots.wallet_id`
+
+	result, err := r.db.ExecContext(ctx, query, asOf, asOf, asOf, asOf)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create balance snapshots: %w", err)
+	}
+	created, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count balance snapshots: %w", err)
+	}
+	return created, nil
+}
+
+func (r *BalanceSnapshotRepository) GetLatestSnapshotTime(ctx context.Context) (time.Time, error) {
+	var latest sql.NullTime
+	if err := r.db.GetContext(ctx, &latest, `SELECT MAX(as_of) FROM balance_snapshots`); err != nil {
+		return time.Time{}, fmt.Errorf("failed to get latest balance snapshot: %w", err)
+	}
+	return latest.Time, nil
+}
+
+func (r *BalanceSnapshotRepository) GetLatestSnapshot(ctx context.Context, walletID uuid.UUID, at time.Time) (*models.BalanceSnapshot, error) {
+	snapshot := &models.BalanceSnapshot{}
+	query := `
+		SELECT wallet_id, as_of, balance, currency, created_at 
+		FROM balance_snapshots 
+		WHERE wallet_id = ? AND as_of <= ? 
+		ORDER BY as_of DESC 
+		LIMIT 1`
+
+	if err := r.db.GetContext(ctx, snapshot, query, walletID, at); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("balance snapshot %w", repository.ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to get balance snapshot: %w", err)
+	}
+	return snapshot, nil
+}
@@ -0,0 +1,177 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository"
+)
+
+const depositRefundColumns = `id, deposit_journal_id, wallet_id, amount, currency, reason, status, journal_id,
+		provider_refund_id, failure_reason, reversal_journal_id, created_at`
+
+type DepositRefundRepository struct {
+	db *sqlx.DB
+}
+
+func NewDepositRefundRepository(db *sqlx.DB) *DepositRefundRepository {
+	return &DepositRefundRepository{db: db}
+}
+
+func (r *DepositRefundRepository) CreateDepositRefundWithTx(ctx context.Context, tx *sql.Tx, refund *models.DepositRefund) error {
+	id, err := repository.NewTimeOrderedID()
+	if err != nil {
+		return fmt.Errorf("failed to generate deposit refund ID: %w", err)
+	}
+	refund.ID = id
+
+	query := `
+		INSERT INTO deposit_refunds (` + depositRefundColumns + `)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	_, err = tx.ExecContext(ctx, query,
+		refund.ID,
+		refund.DepositJournalID,
+		refund.WalletID,
+		refund.Amount,
+		refund.Currency,
+		refund.Reason,
+		refund.Status,
+		refund.JournalID,
+		refund.ProviderRefundID,
+		refund.FailureReason,
+		refund.ReversalJournalID,
+		refund.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create deposit refund: %w", err)
+	}
+
+	return nil
+}
+
+func (r *DepositRefundRepository) GetDepositRefundWithTx(ctx context.Context, tx *sql.Tx, id uuid.UUID) (*models.DepositRefund, error) {
+	return scanDepositRefund(tx.QueryRowContext(ctx, `SELECT `+depositRefundColumns+` FROM deposit_refunds WHERE id = ? FOR UPDATE`, id))
+}
+
+func (r *DepositRefundRepository) GetDepositRefundByJournalIDWithTx(ctx context.Context, tx *sql.Tx, journalID uuid.UUID) (*models.DepositRefund, error) {
+	return scanDepositRefund(tx.QueryRowContext(ctx, `SELECT `+depositRefundColumns+` FROM deposit_refunds WHERE journal_id = ?`, journalID))
+}
+
+func (r *DepositRefundRepository) UpdateDepositRefundWithTx(ctx context.Context, tx *sql.Tx, refund *models.DepositRefund) error {
+	query := `
+		UPDATE deposit_refunds
+		SET status = ?, provider_refund_id = ?, failure_reason = ?, reversal_journal_id = ?
+		WHERE id = ?`
+
+	result, err := tx.ExecContext(ctx, query,
+		refund.Status, refund.ProviderRefundID, refund.FailureReason, refund.ReversalJournalID, refund.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update deposit refund: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("deposit refund %w", repository.ErrNotFound)
+	}
+
+	return nil
+}
+
+func (r *DepositRefundRepository) ListDepositRefundsWithTx(ctx context.Context, tx *sql.Tx, depositJournalID uuid.UUID) ([]*models.DepositRefund, error) {
+	query := `SELECT ` + depositRefundColumns + `
+		FROM deposit_refunds
+		WHERE deposit_journal_id = ?
+		ORDER BY created_at, id`
+
+	rows, err := tx.QueryContext(ctx, query, depositJournalID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deposit refunds: %w", err)
+	}
+	defer rows.Close()
+
+	refunds := []*models.DepositRefund{}
+	for rows.Next() {
+		refund := &models.DepositRefund{}
+		err := rows.Scan(
+			&refund.ID,
+			&refund.DepositJournalID,
+			&refund.WalletID,
+			&refund.Amount,
+			&refund.Currency,
+			&refund.Reason,
+			&refund.Status,
+			&refund.JournalID,
+			&refund.ProviderRefundID,
+			&refund.FailureReason,
+			&refund.ReversalJournalID,
+			&refund.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan deposit refund: %w", err)
+		}
+		refunds = append(refunds, refund)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list deposit refunds: %w", err)
+	}
+	return refunds, nil
+}
+
+func (r *DepositRefundRepository) ListDepositRefundsInStatus(ctx context.Context, status string, after uuid.UUID, limit int) ([]*models.DepositRefund, error) {
+	query := `SELECT ` + depositRefundColumns + `
+		FROM deposit_refunds
+		WHERE status = ? AND id > ?
+		ORDER BY id
+		LIMIT ?`
+
+	refunds := []*models.DepositRefund{}
+	if err := r.db.SelectContext(ctx, &refunds, query, status, after, limit); err != nil {
+		return nil, fmt.Errorf("failed to list deposit refunds: %w", err)
+	}
+	return refunds, nil
+}
+
+func (r *DepositRefundRepository) IsDepositReversedWithTx(ctx context.Context, tx *sql.Tx, depositJournalID uuid.UUID) (bool, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM journals WHERE reverses_journal_id = ?`
+	if err := tx.QueryRowContext(ctx, query, depositJournalID).Scan(&count); err != nil {
+		return false, fmt.Errorf("failed to check deposit reversal: %w", err)
+	}
+	return count > 0, nil
+}
+
+func scanDepositRefund(row *sql.Row) (*models.DepositRefund, error) {
+	refund := &models.DepositRefund{}
+	err := row.Scan(
+		&refund.ID,
+		&refund.DepositJournalID,
+		&refund.WalletID,
+		&refund.Amount,
+		&refund.Currency,
+		&refund.Reason,
+		&refund.Status,
+		&refund.JournalID,
+		&refund.ProviderRefundID,
+		&refund.FailureReason,
+		&refund.ReversalJournalID,
+		&refund.CreatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("deposit refund %w", repository.ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to get deposit refund: %w", err)
+	}
+
+	return refund, nil
+}
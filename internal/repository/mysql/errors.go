@@ -0,0 +1,36 @@
+package mysql
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+
+	"github.com/shanwije/wallet-app/internal/repository"
+)
+
+// duplicateEntry is the MySQL error number for unique key violations
+const duplicateEntry = 1062
+
+func isUniqueViolation(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == duplicateEntry
+}
+
+// contactError returns err wrapped with the repository error for the user contact
+// detail it found taken, or err itself. MySQL names the violated key only in the
+// message, as "for key 'users.uq_users_email'".
+func contactError(err error) error {
+	var mysqlErr *mysql.MySQLError
+	if !errors.As(err, &mysqlErr) || mysqlErr.Number != duplicateEntry {
+		return err
+	}
+	switch {
+	case strings.HasSuffix(mysqlErr.Message, "uq_users_email'"):
+		return fmt.Errorf("%w: %v", repository.ErrDuplicateEmail, err)
+	case strings.HasSuffix(mysqlErr.Message, "uq_users_phone'"):
+		return fmt.Errorf("%w: %v", repository.ErrDuplicatePhone, err)
+	}
+	return err
+}
@@ -0,0 +1,122 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository"
+)
+
+const exportJobColumns = `id, wallet_id, period_from, period_to, status, object_key, exported_rows, failure_reason,
+		created_at, updated_at, completed_at`
+
+type ExportJobRepository struct {
+	db *sqlx.DB
+}
+
+func NewExportJobRepository(db *sqlx.DB) *ExportJobRepository {
+	return &ExportJobRepository{db: db}
+}
+
+func (r *ExportJobRepository) CreateExportJob(ctx context.Context, job *models.ExportJob) error {
+	id, err := repository.NewTimeOrderedID()
+	if err != nil {
+		return fmt.Errorf("failed to generate export job ID: %w", err)
+	}
+	job.ID = id
+
+	query := `
+		INSERT INTO export_jobs (` + exportJobColumns + `)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	_, err = r.db.ExecContext(ctx, query,
+		job.ID,
+		job.WalletID,
+		job.From,
+		job.To,
+		job.Status,
+		job.ObjectKey,
+		job.Rows,
+		job.FailureReason,
+		job.CreatedAt,
+		job.UpdatedAt,
+		job.CompletedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create export job: %w", err)
+	}
+
+	return nil
+}
+
+func (r *ExportJobRepository) GetExportJob(ctx context.Context, id uuid.UUID) (*models.ExportJob, error) {
+	return scanExportJob(r.db.QueryRowContext(ctx, `SELECT `+exportJobColumns+` FROM export_jobs WHERE id = ?`, id))
+}
+
+func (r *ExportJobRepository) UpdateExportJob(ctx context.Context, job *models.ExportJob) error {
+	query := `
+		UPDATE export_jobs
+		SET status = ?, object_key = ?, exported_rows = ?, failure_reason = ?, updated_at = ?, completed_at = ?
+		WHERE id = ?`
+
+	result, err := r.db.ExecContext(ctx, query,
+		job.Status, job.ObjectKey, job.Rows, job.FailureReason, job.UpdatedAt, job.CompletedAt, job.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update export job: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("export job %w", repository.ErrNotFound)
+	}
+
+	return nil
+}
+
+func (r *ExportJobRepository) ListExportJobsInStatus(ctx context.Context, status string, after uuid.UUID, limit int) ([]*models.ExportJob, error) {
+	query := `SELECT ` + exportJobColumns + `
+		FROM export_jobs
+		WHERE status = ? AND id > ?
+		ORDER BY id
+		LIMIT ?`
+
+	jobs := []*models.ExportJob{}
+	if err := r.db.SelectContext(ctx, &jobs, query, status, after, limit); err != nil {
+		return nil, fmt.Errorf("failed to list export jobs: %w", err)
+	}
+	return jobs, nil
+}
+
+func scanExportJob(row *sql.Row) (*models.ExportJob, error) {
+	job := &models.ExportJob{}
+	err := row.Scan(
+		&job.ID,
+		&job.WalletID,
+		&job.From,
+		&job.To,
+		&job.Status,
+		&job.ObjectKey,
+		&job.Rows,
+		&job.FailureReason,
+		&job.CreatedAt,
+		&job.UpdatedAt,
+		&job.CompletedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("export job %w", repository.ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to get export job: %w", err)
+	}
+
+	return job, nil
+}
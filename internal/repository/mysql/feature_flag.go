@@ -0,0 +1,68 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// FeatureFlagRepository stores feature flag overrides, implementing featureflag.Store
+type FeatureFlagRepository struct {
+	db *sqlx.DB
+}
+
+func NewFeatureFlagRepository(db *sqlx.DB) *FeatureFlagRepository {
+	return &FeatureFlagRepository{db: db}
+}
+
+func (r *FeatureFlagRepository) GetFlag(ctx context.Context, name string) (bool, bool, error) {
+	var enabled bool
+	err := r.db.GetContext(ctx, &enabled, `SELECT enabled FROM feature_flags WHERE name = ?`, name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, false, nil
+	}
+	if err != nil {
+		return false, false, fmt.Errorf("failed to get feature flag: %w", err)
+	}
+	return enabled, true, nil
+}
+
+func (r *FeatureFlagRepository) SetFlag(ctx context.Context, name string, enabled bool) error {
+	query := `
+		INSERT INTO feature_flags (name, enabled, updated_at)
+		VALUES (?, ?, CURRENT_TIMESTAMP(6))
+		ON DUPLICATE KEY UPDATE
+			enabled = VALUES(enabled),
+			updated_at = VALUES(updated_at)`
+
+	if _, err := r.db.ExecContext(ctx, query, name, enabled); err != nil {
+		return fmt.Errorf("failed to set feature flag: %w", err)
+	}
+	return nil
+}
+
+func (r *FeatureFlagRepository) DeleteFlag(ctx context.Context, name string) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM feature_flags WHERE name = ?`, name); err != nil {
+		return fmt.Errorf("failed to delete feature flag: %w", err)
+	}
+	return nil
+}
+
+func (r *FeatureFlagRepository) ListFlags(ctx context.Context) (map[string]bool, error) {
+	var rows []struct {
+		Name    string `db:"name"`
+		Enabled bool   `db:"enabled"`
+	}
+	if err := r.db.SelectContext(ctx, &rows, `SELECT name, enabled FROM feature_flags`); err != nil {
+		return nil, fmt.Errorf("failed to list feature flags: %w", err)
+	}
+
+	flags := make(map[string]bool, len(rows))
+	for _, row := range rows {
+		flags[row.Name] = row.Enabled
+	}
+	return flags, nil
+}
@@ -0,0 +1,125 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository"
+)
+
+const holdColumns = `id, wallet_id, amount, currency, description, status, captured_amount, capture_journal_id,
+		dispute_id, incoming_transfer_id, created_at, updated_at`
+
+type HoldRepository struct {
+	db *sqlx.DB
+}
+
+func NewHoldRepository(db *sqlx.DB) *HoldRepository {
+	return &HoldRepository{db: db}
+}
+
+func (r *HoldRepository) CreateHoldWithTx(ctx context.Context, tx *sql.Tx, hold *models.Hold) error {
+	id, err := repository.NewTimeOrderedID()
+	if err != nil {
+		return fmt.Errorf("failed to generate hold ID: %w", err)
+	}
+	hold.ID = id
+
+	query := `
+		INSERT INTO holds (id, wallet_id, amount, currency, description, status, dispute_id, incoming_transfer_id,
+			created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	_, err = tx.ExecContext(ctx, query,
+		hold.ID,
+		hold.WalletID,
+		hold.Amount,
+		hold.Currency,
+		hold.Description,
+		hold.Status,
+		hold.DisputeID,
+		hold.IncomingTransferID,
+		hold.CreatedAt,
+		hold.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create hold: %w", err)
+	}
+	hold.UpdatedAt = hold.CreatedAt
+
+	return nil
+}
+
+func (r *HoldRepository) GetHoldWithTx(ctx context.Context, tx *sql.Tx, id uuid.UUID) (*models.Hold, error) {
+	hold := &models.Hold{}
+	query := `SELECT ` + holdColumns + ` FROM holds WHERE id = ? FOR UPDATE`
+
+	err := tx.QueryRowContext(ctx, query, id).Scan(
+		&hold.ID,
+		&hold.WalletID,
+		&hold.Amount,
+		&hold.Currency,
+		&hold.Description,
+		&hold.Status,
+		&hold.CapturedAmount,
+		&hold.CaptureJournalID,
+		&hold.DisputeID,
+		&hold.IncomingTransferID,
+		&hold.CreatedAt,
+		&hold.UpdatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("hold %w", repository.ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to get hold: %w", err)
+	}
+
+	return hold, nil
+}
+
+func (r *HoldRepository) ListHoldsByWalletID(ctx context.Context, walletID uuid.UUID) ([]*models.Hold, error) {
+	var holds []*models.Hold
+	query := `SELECT ` + holdColumns + ` FROM holds
+		WHERE wallet_id = ?
+		ORDER BY created_at DESC, id DESC`
+
+	if err := r.db.SelectContext(ctx, &holds, query, walletID); err != nil {
+		return nil, fmt.Errorf("failed to list holds: %w", err)
+	}
+
+	return holds, nil
+}
+
+func (r *HoldRepository) UpdateHoldWithTx(ctx context.Context, tx *sql.Tx, hold *models.Hold) error {
+	query := `
+		UPDATE holds
+		SET status = ?, captured_amount = ?, capture_journal_id = ?, updated_at = ?
+		WHERE id = ?`
+
+	result, err := tx.ExecContext(ctx, query,
+		hold.Status,
+		hold.CapturedAmount,
+		hold.CaptureJournalID,
+		hold.UpdatedAt,
+		hold.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update hold: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("hold %w", repository.ErrNotFound)
+	}
+
+	return nil
+}
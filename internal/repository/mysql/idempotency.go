@@ -0,0 +1,95 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository"
+)
+
+type IdempotencyKeyRepository struct {
+	db *sqlx.DB
+}
+
+func NewIdempotencyKeyRepository(db *sqlx.DB) *IdempotencyKeyRepository {
+	return &IdempotencyKeyRepository{db: db}
+}
+
+func (r *IdempotencyKeyRepository) ReserveIdempotencyKey(ctx context.Context, key *models.IdempotencyKey) error {
+	query := `INSERT INTO idempotency_keys (request_key, request_fingerprint, headers, created_at) VALUES (?, ?, '{}', ?)`
+
+	_, err := r.db.ExecContext(ctx, query, key.RequestKey, key.Fingerprint, key.CreatedAt)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("idempotency key %w", repository.ErrDuplicate)
+		}
+		return fmt.Errorf("failed to reserve idempotency key: %w", err)
+	}
+
+	return nil
+}
+
+func (r *IdempotencyKeyRepository) CompleteIdempotencyKey(ctx context.Context, key *models.IdempotencyKey) error {
+	headers, err := json.Marshal(key.Headers)
+	if err != nil {
+		return fmt.Errorf("failed to encode idempotency headers: %w", err)
+	}
+
+	query := `UPDATE idempotency_keys SET status_code = ?, headers = ?, response_body = ? WHERE request_key = ?`
+
+	result, err := r.db.ExecContext(ctx, query, key.StatusCode, headers, key.ResponseBody, key.RequestKey)
+	if err != nil {
+		return fmt.Errorf("failed to complete idempotency key: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("idempotency key %w", repository.ErrNotFound)
+	}
+
+	return nil
+}
+
+func (r *IdempotencyKeyRepository) GetIdempotencyKey(ctx context.Context, requestKey string) (*models.IdempotencyKey, error) {
+	key := &models.IdempotencyKey{}
+	var headers []byte
+	query := `SELECT request_key, request_fingerprint, status_code, headers, response_body, created_at FROM idempotency_keys WHERE request_key = ?`
+
+	err := r.db.QueryRowContext(ctx, query, requestKey).Scan(
+		&key.RequestKey,
+		&key.Fingerprint,
+		&key.StatusCode,
+		&headers,
+		&key.ResponseBody,
+		&key.CreatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("idempotency key %w", repository.ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to get idempotency key: %w", err)
+	}
+
+	if err := json.Unmarshal(headers, &key.Headers); err != nil {
+		return nil, fmt.Errorf("failed to decode idempotency headers: %w", err)
+	}
+
+	return key, nil
+}
+
+func (r *IdempotencyKeyRepository) DeleteIdempotencyKey(ctx context.Context, requestKey string) error {
+	query := `DELETE FROM idempotency_keys WHERE request_key = ?`
+
+	if _, err := r.db.ExecContext(ctx, query, requestKey); err != nil {
+		return fmt.Errorf("failed to delete idempotency key: %w", err)
+	}
+
+	return nil
+}
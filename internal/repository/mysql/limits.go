@@ -0,0 +1,82 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/shanwije/wallet-app/internal/models"
+)
+
+const walletLimitsQuery = `
+		SELECT wallet_id, max_transaction_amount, daily_withdrawal_limit, daily_transfer_limit, overdraft_limit, minimum_balance, updated_at
+		FROM wallet_limits
+		WHERE wallet_id = ?`
+
+type WalletLimitsRepository struct {
+	db *sqlx.DB
+}
+
+func NewWalletLimitsRepository(db *sqlx.DB) *WalletLimitsRepository {
+	return &WalletLimitsRepository{db: db}
+}
+
+func (r *WalletLimitsRepository) GetWalletLimits(ctx context.Context, walletID uuid.UUID) (*models.WalletLimits, error) {
+	return scanWalletLimits(r.db.QueryRowContext(ctx, walletLimitsQuery, walletID), walletID)
+}
+
+func (r *WalletLimitsRepository) GetWalletLimitsWithTx(ctx context.Context, tx *sql.Tx, walletID uuid.UUID) (*models.WalletLimits, error) {
+	return scanWalletLimits(tx.QueryRowContext(ctx, walletLimitsQuery, walletID), walletID)
+}
+
+func (r *WalletLimitsRepository) SetWalletLimits(ctx context.Context, limits *models.WalletLimits) error {
+	query := `
+		INSERT INTO wallet_limits (wallet_id, max_transaction_amount, daily_withdrawal_limit, daily_transfer_limit, overdraft_limit, minimum_balance, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+			max_transaction_amount = VALUES(max_transaction_amount),
+			daily_withdrawal_limit = VALUES(daily_withdrawal_limit),
+			daily_transfer_limit = VALUES(daily_transfer_limit),
+			overdraft_limit = VALUES(overdraft_limit),
+			minimum_balance = VALUES(minimum_balance),
+			updated_at = VALUES(updated_at)`
+
+	_, err := r.db.ExecContext(ctx, query,
+		limits.WalletID,
+		limits.MaxTransactionAmount,
+		limits.DailyWithdrawalLimit,
+		limits.DailyTransferLimit,
+		limits.OverdraftLimit,
+		limits.MinimumBalance,
+		limits.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to set wallet limits: %w", err)
+	}
+
+	return nil
+}
+
+// scanWalletLimits reads a wallet_limits row, returning unset limits when there is none
+func scanWalletLimits(row *sql.Row, walletID uuid.UUID) (*models.WalletLimits, error) {
+	limits := &models.WalletLimits{}
+	err := row.Scan(
+		&limits.WalletID,
+		&limits.MaxTransactionAmount,
+		&limits.DailyWithdrawalLimit,
+		&limits.DailyTransferLimit,
+		&limits.OverdraftLimit,
+		&limits.MinimumBalance,
+		&limits.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return &models.WalletLimits{WalletID: walletID}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get wallet limits: %w", err)
+	}
+
+	return limits, nil
+}
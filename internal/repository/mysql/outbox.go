@@ -0,0 +1,94 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository"
+)
+
+type OutboxRepository struct {
+	db *sqlx.DB
+}
+
+func NewOutboxRepository(db *sqlx.DB) *OutboxRepository {
+	return &OutboxRepository{db: db}
+}
+
+func (r *OutboxRepository) CreateOutboxEventWithTx(ctx context.Context, tx *sql.Tx, event *models.OutboxEvent) error {
+	id, err := repository.NewTimeOrderedID()
+	if err != nil {
+		return fmt.Errorf("failed to generate outbox event ID: %w", err)
+	}
+	event.ID = id
+
+	query := `
+		INSERT INTO outbox_events (id, event_type, wallet_id, payload, created_at)
+		VALUES (?, ?, ?, ?, ?)`
+
+	// Sent as text: MySQL refuses JSON from a binary string
+	_, err = tx.ExecContext(ctx, query, event.ID, event.Type, event.WalletID, string(event.Payload), event.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create outbox event: %w", err)
+	}
+
+	return nil
+}
+
+func (r *OutboxRepository) BeginTx(ctx context.Context) (*sql.Tx, error) {
+	return r.db.BeginTx(ctx, nil)
+}
+
+func (r *OutboxRepository) ClaimUnpublishedWithTx(ctx context.Context, tx *sql.Tx, limit int) ([]*models.OutboxEvent, error) {
+	query := `
+		SELECT id, event_type, wallet_id, payload, attempts, last_error, created_at
+		FROM outbox_events
+		WHERE published_at IS NULL
+		ORDER BY created_at, id
+		LIMIT ?
+		FOR UPDATE SKIP LOCKED`
+
+	rows, err := tx.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim outbox events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []*models.OutboxEvent
+	for rows.Next() {
+		event := &models.OutboxEvent{}
+		var payload []byte
+		if err := rows.Scan(&event.ID, &event.Type, &event.WalletID, &payload, &event.Attempts, &event.LastError, &event.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan outbox event: %w", err)
+		}
+		event.Payload = payload
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
+
+func (r *OutboxRepository) MarkPublishedWithTx(ctx context.Context, tx *sql.Tx, id uuid.UUID, publishedAt time.Time) error {
+	query := `UPDATE outbox_events SET published_at = ?, attempts = attempts + 1, last_error = NULL WHERE id = ?`
+
+	if _, err := tx.ExecContext(ctx, query, publishedAt, id); err != nil {
+		return fmt.Errorf("failed to mark outbox event published: %w", err)
+	}
+
+	return nil
+}
+
+func (r *OutboxRepository) RecordFailureWithTx(ctx context.Context, tx *sql.Tx, id uuid.UUID, message string) error {
+	query := `UPDATE outbox_events SET attempts = attempts + 1, last_error = ? WHERE id = ?`
+
+	if _, err := tx.ExecContext(ctx, query, message, id); err != nil {
+		return fmt.Errorf("failed to record outbox event failure: %w", err)
+	}
+
+	return nil
+}
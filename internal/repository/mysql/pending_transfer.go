@@ -0,0 +1,191 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository"
+)
+
+const pendingTransferColumns = `id, from_wallet_id, to_wallet_id, amount, currency, description, category, tags,
+		status, reason, transfer_journal_id, expires_at, created_at, updated_at, idempotency_key`
+
+type PendingTransferRepository struct {
+	db *sqlx.DB
+}
+
+func NewPendingTransferRepository(db *sqlx.DB) *PendingTransferRepository {
+	return &PendingTransferRepository{db: db}
+}
+
+func (r *PendingTransferRepository) CreatePendingTransfer(ctx context.Context, transfer *models.PendingTransfer) error {
+	id, err := repository.NewTimeOrderedID()
+	if err != nil {
+		return fmt.Errorf("failed to generate pending transfer ID: %w", err)
+	}
+	transfer.ID = id
+
+	query := `
+		INSERT INTO pending_transfers (id, from_wallet_id, to_wallet_id, amount, currency, description, category,
+			tags, status, expires_at, created_at, updated_at, idempotency_key)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	_, err = r.db.ExecContext(ctx, query,
+		transfer.ID,
+		transfer.FromWalletID,
+		transfer.ToWalletID,
+		transfer.Amount,
+		transfer.Currency,
+		transfer.Description,
+		transfer.Category,
+		transfer.Tags,
+		transfer.Status,
+		transfer.ExpiresAt,
+		transfer.CreatedAt,
+		transfer.CreatedAt,
+		transfer.IdempotencyKey,
+	)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("pending transfer idempotency key %w", repository.ErrDuplicate)
+		}
+		return fmt.Errorf("failed to create pending transfer: %w", err)
+	}
+	transfer.UpdatedAt = transfer.CreatedAt
+
+	return nil
+}
+
+func (r *PendingTransferRepository) GetPendingTransfer(ctx context.Context, id uuid.UUID) (*models.PendingTransfer, error) {
+	transfer := &models.PendingTransfer{}
+	query := `SELECT ` + pendingTransferColumns + ` FROM pending_transfers WHERE id = ?`
+
+	if err := r.db.GetContext(ctx, transfer, query, id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("pending transfer %w", repository.ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to get pending transfer: %w", err)
+	}
+
+	return transfer, nil
+}
+
+func (r *PendingTransferRepository) GetPendingTransferByIdempotencyKey(ctx context.Context, key string) (*models.PendingTransfer, error) {
+	transfer := &models.PendingTransfer{}
+	query := `SELECT ` + pendingTransferColumns + ` FROM pending_transfers WHERE idempotency_key = ?`
+
+	if err := r.db.GetContext(ctx, transfer, query, key); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("pending transfer %w", repository.ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to get pending transfer: %w", err)
+	}
+
+	return transfer, nil
+}
+
+func (r *PendingTransferRepository) GetPendingTransferWithTx(ctx context.Context, tx *sql.Tx, id uuid.UUID) (*models.PendingTransfer, error) {
+	transfer := &models.PendingTransfer{}
+	query := `SELECT ` + pendingTransferColumns + ` FROM pending_transfers WHERE id = ? FOR UPDATE`
+
+	err := tx.QueryRowContext(ctx, query, id).Scan(
+		&transfer.ID,
+		&transfer.FromWalletID,
+		&transfer.ToWalletID,
+		&transfer.Amount,
+		&transfer.Currency,
+		&transfer.Description,
+		&transfer.Category,
+		&transfer.Tags,
+		&transfer.Status,
+		&transfer.Reason,
+		&transfer.TransferJournalID,
+		&transfer.ExpiresAt,
+		&transfer.CreatedAt,
+		&transfer.UpdatedAt,
+		&transfer.IdempotencyKey,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("pending transfer %w", repository.ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to get pending transfer: %w", err)
+	}
+
+	return transfer, nil
+}
+
+func (r *PendingTransferRepository) ListPendingTransfersByWalletID(ctx context.Context, walletID uuid.UUID) ([]*models.PendingTransfer, error) {
+	transfers := []*models.PendingTransfer{}
+	query := `SELECT ` + pendingTransferColumns + ` FROM pending_transfers
+		WHERE from_wallet_id = ?
+		ORDER BY created_at DESC, id DESC`
+
+	if err := r.db.SelectContext(ctx, &transfers, query, walletID); err != nil {
+		return nil, fmt.Errorf("failed to list pending transfers: %w", err)
+	}
+
+	return transfers, nil
+}
+
+func (r *PendingTransferRepository) ListPendingTransfers(ctx context.Context, status string, limit, offset int) ([]*models.PendingTransfer, error) {
+	transfers := []*models.PendingTransfer{}
+	query := `SELECT ` + pendingTransferColumns + ` FROM pending_transfers
+		WHERE ? = '' OR status = ?
+		ORDER BY created_at, id
+		LIMIT ? OFFSET ?`
+
+	if err := r.db.SelectContext(ctx, &transfers, query, status, status, limit, offset); err != nil {
+		return nil, fmt.Errorf("failed to list pending transfers: %w", err)
+	}
+
+	return transfers, nil
+}
+
+func (r *PendingTransferRepository) ListExpiredPendingTransfers(ctx context.Context, now time.Time, limit int) ([]*models.PendingTransfer, error) {
+	transfers := []*models.PendingTransfer{}
+	query := `SELECT ` + pendingTransferColumns + ` FROM pending_transfers
+		WHERE status = ? AND expires_at <= ?
+		ORDER BY expires_at, id
+		LIMIT ?`
+
+	if err := r.db.SelectContext(ctx, &transfers, query, models.PendingTransferStatusPending, now, limit); err != nil {
+		return nil, fmt.Errorf("failed to list expired pending transfers: %w", err)
+	}
+
+	return transfers, nil
+}
+
+func (r *PendingTransferRepository) UpdatePendingTransferWithTx(ctx context.Context, tx *sql.Tx, transfer *models.PendingTransfer) error {
+	query := `
+		UPDATE pending_transfers
+		SET status = ?, reason = ?, transfer_journal_id = ?, updated_at = ?
+		WHERE id = ?`
+
+	result, err := tx.ExecContext(ctx, query,
+		transfer.Status,
+		transfer.Reason,
+		transfer.TransferJournalID,
+		transfer.UpdatedAt,
+		transfer.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update pending transfer: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("pending transfer %w", repository.ErrNotFound)
+	}
+
+	return nil
+}
@@ -0,0 +1,158 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository"
+)
+
+type ReconciliationRepository struct {
+	db     *sqlx.DB
+	reader *sqlx.DB
+}
+
+func NewReconciliationRepository(db *sqlx.DB) *ReconciliationRepository {
+	return &ReconciliationRepository{db: db, reader: db}
+}
+
+// WithReadReplica moves the balance comparison, a scan of the whole ledger, onto replica.
+// A replica applies each transaction at once, so its balances and entries still agree.
+func (r *ReconciliationRepository) WithReadReplica(replica *sqlx.DB) *ReconciliationRepository {
+	r.reader = replica
+	return r
+}
+
+func (r *ReconciliationRepository) CountWallets(ctx context.Context) (int64, error) {
+	var count int64
+	if err := r.reader.GetContext(ctx, &count, `SELECT COUNT(*) FROM wallets`); err != nil {
+		return 0, fmt.Errorf("failed to count wallets: %w", err)
+	}
+	return count, nil
+}
+
+func (r *ReconciliationRepository) FindBalanceDiscrepancies(ctx context.Context) ([]*models.BalanceDiscrepancy, error) {
+	// One statement sees one snapshot, and a wallet's balance is updated in the same
+	// transaction as its entries are written, so movements in flight never show up here
+	query := `
+		SELECT w.id AS wallet_id, w.balance, w.currency,
+			COALESCE(SUM(CASE WHEN e.direction = 'credit' THEN e.amount ELSE -e.amount END), 0) AS ledger_balance
+		FROM wallets w
+		LEFT JOIN ledger_entries e ON e.wallet_id = w.id
+		GROUP BY w.id, w.balance, w.currency
+		HAVING w.balance <> COALESCE(SUM(CASE WHEN e.direction = 'credit' THEN e.amount ELSE -e.amount END), 0)
+		ORDER BY w.id`
+
+	discrepancies := []*models.BalanceDiscrepancy{}
+	if err := r.reader.SelectContext(ctx, &discrepancies, query); err != nil {
+		return nil, fmt.Errorf("failed to compare balances with the ledger: %w", err)
+	}
+	for _, discrepancy := range discrepancies {
+		discrepancy.Difference = discrepancy.Balance.Sub(discrepancy.LedgerBalance)
+	}
+	return discrepancies, nil
+}
+
+func (r *ReconciliationRepository) CreateRun(ctx context.Context, run *models.ReconciliationRun) error {
+	id, err := repository.NewTimeOrderedID()
+	if err != nil {
+		return fmt.Errorf("failed to generate reconciliation run ID: %w", err)
+	}
+	run.ID = id
+	run.DiscrepancyCount = len(run.Discrepancies)
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO reconciliation_runs (id, started_at, finished_at, wallets_checked, discrepancy_count)
+		VALUES (?, ?, ?, ?, ?)`
+
+	if _, err := tx.ExecContext(ctx, query, run.ID, run.StartedAt, run.FinishedAt, run.WalletsChecked, run.DiscrepancyCount); err != nil {
+		return fmt.Errorf("failed to create reconciliation run: %w", err)
+	}
+
+	discrepancyQuery := `
+		INSERT INTO reconciliation_discrepancies (id, run_id, wallet_id, balance, ledger_balance, difference, currency, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+
+	for _, discrepancy := range run.Discrepancies {
+		discrepancyID, err := repository.NewTimeOrderedID()
+		if err != nil {
+			return fmt.Errorf("failed to generate discrepancy ID: %w", err)
+		}
+		discrepancy.ID = discrepancyID
+		discrepancy.RunID = run.ID
+		discrepancy.CreatedAt = run.FinishedAt
+
+		_, err = tx.ExecContext(ctx, discrepancyQuery,
+			discrepancy.ID,
+			discrepancy.RunID,
+			discrepancy.WalletID,
+			discrepancy.Balance,
+			discrepancy.LedgerBalance,
+			discrepancy.Difference,
+			discrepancy.Currency,
+			discrepancy.CreatedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to create discrepancy: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit reconciliation run: %w", err)
+	}
+	return nil
+}
+
+func (r *ReconciliationRepository) ListRuns(ctx context.Context, limit, offset int) ([]*models.ReconciliationRun, error) {
+	query := `
+		SELECT id, started_at, finished_at, wallets_checked, discrepancy_count
+		FROM reconciliation_runs
+		ORDER BY started_at DESC, id DESC
+		LIMIT ? OFFSET ?`
+
+	runs := []*models.ReconciliationRun{}
+	if err := r.db.SelectContext(ctx, &runs, query, limit, offset); err != nil {
+		return nil, fmt.Errorf("failed to list reconciliation runs: %w", err)
+	}
+	return runs, nil
+}
+
+func (r *ReconciliationRepository) ListDiscrepancies(ctx context.Context, filter repository.DiscrepancyFilter) ([]*models.BalanceDiscrepancy, error) {
+	var conditions []string
+	var args []interface{}
+	where := func(condition string, arg interface{}) {
+		conditions = append(conditions, condition)
+		args = append(args, arg)
+	}
+	if filter.RunID != uuid.Nil {
+		where("run_id = ?", filter.RunID)
+	}
+	if filter.WalletID != uuid.Nil {
+		where("wallet_id = ?", filter.WalletID)
+	}
+
+	query := `SELECT id, run_id, wallet_id, balance, ledger_balance, difference, currency, created_at
+		FROM reconciliation_discrepancies`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY created_at DESC, id DESC LIMIT ? OFFSET ?"
+	args = append(args, filter.Limit, filter.Offset)
+
+	discrepancies := []*models.BalanceDiscrepancy{}
+	if err := r.db.SelectContext(ctx, &discrepancies, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to list discrepancies: %w", err)
+	}
+	return discrepancies, nil
+}
@@ -0,0 +1,115 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository"
+)
+
+type TransactionPINRepository struct {
+	db *sqlx.DB
+}
+
+func NewTransactionPINRepository(db *sqlx.DB) *TransactionPINRepository {
+	return &TransactionPINRepository{db: db}
+}
+
+func (r *TransactionPINRepository) GetTransactionPIN(ctx context.Context, userID uuid.UUID) (*models.TransactionPIN, error) {
+	query := `
+		SELECT user_id, pin_hash, failed_attempts, locked_until, created_at, updated_at
+		FROM transaction_pins
+		WHERE user_id = ?`
+
+	var pin models.TransactionPIN
+	err := r.db.GetContext(ctx, &pin, query, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("transaction PIN %w", repository.ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction PIN: %w", err)
+	}
+
+	return &pin, nil
+}
+
+func (r *TransactionPINRepository) SetTransactionPIN(ctx context.Context, pin *models.TransactionPIN) error {
+	query := `
+		INSERT INTO transaction_pins (user_id, pin_hash, failed_attempts, locked_until, created_at, updated_at)
+		VALUES (?, ?, 0, NULL, ?, ?)
+		ON DUPLICATE KEY UPDATE
+			pin_hash = VALUES(pin_hash),
+			failed_attempts = 0,
+			locked_until = NULL,
+			updated_at = VALUES(updated_at)`
+
+	_, err := r.db.ExecContext(ctx, query, pin.UserID, pin.PINHash, pin.UpdatedAt, pin.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to set transaction PIN: %w", err)
+	}
+
+	return nil
+}
+
+func (r *TransactionPINRepository) DeleteTransactionPIN(ctx context.Context, userID uuid.UUID) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM transaction_pins WHERE user_id = ?`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete transaction PIN: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("transaction PIN %w", repository.ErrNotFound)
+	}
+
+	return nil
+}
+
+func (r *TransactionPINRepository) RecordFailedPINAttempt(ctx context.Context, userID uuid.UUID, at time.Time) (int, error) {
+	// MySQL has no RETURNING clause, so the count is read back in the same transaction,
+	// which the update left holding the row
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE transaction_pins SET failed_attempts = failed_attempts + 1, updated_at = ? WHERE user_id = ?`
+	if _, err := tx.ExecContext(ctx, query, at, userID); err != nil {
+		return 0, fmt.Errorf("failed to record failed PIN attempt: %w", err)
+	}
+
+	var attempts int
+	err = tx.QueryRowContext(ctx, `SELECT failed_attempts FROM transaction_pins WHERE user_id = ?`, userID).Scan(&attempts)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("transaction PIN %w", repository.ErrNotFound)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to record failed PIN attempt: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return attempts, nil
+}
+
+func (r *TransactionPINRepository) ResetPINAttempts(ctx context.Context, userID uuid.UUID, lockedUntil *time.Time, at time.Time) error {
+	query := `UPDATE transaction_pins SET failed_attempts = 0, locked_until = ?, updated_at = ? WHERE user_id = ?`
+
+	if _, err := r.db.ExecContext(ctx, query, lockedUntil, at, userID); err != nil {
+		return fmt.Errorf("failed to reset PIN attempts: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,257 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository"
+	"github.com/shanwije/wallet-app/pkg/money"
+	"github.com/shopspring/decimal"
+)
+
+type WalletRepository struct {
+	db *sqlx.DB
+	// reader serves GetWalletByID and SearchWallets, which can tolerate replication lag
+	reader *sqlx.DB
+}
+
+func NewWalletRepository(db *sqlx.DB) *WalletRepository {
+	return &WalletRepository{db: db, reader: db}
+}
+
+// WithReadReplica moves the repository's lag-tolerant reads onto replica
+func (r *WalletRepository) WithReadReplica(replica *sqlx.DB) *WalletRepository {
+	r.reader = replica
+	return r
+}
+
+func (r *WalletRepository) CreateWallet(ctx context.Context, userID uuid.UUID) (*models.Wallet, error) {
+	id, err := repository.NewTimeOrderedID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate wallet ID: %w", err)
+	}
+
+	wallet := &models.Wallet{
+		ID:        id,
+		UserID:    userID,
+		Balance:   decimal.Zero,
+		Currency:  money.DefaultCurrency,
+		Status:    models.WalletStatusActive,
+		Kind:      models.WalletKindUser,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	query := `INSERT INTO wallets (id, user_id, balance, currency, status, created_at) VALUES (?, ?, ?, ?, ?, ?)`
+
+	_, err = r.db.ExecContext(ctx, query, wallet.ID, wallet.UserID, wallet.Balance, wallet.Currency, wallet.Status, wallet.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create wallet: %w", err)
+	}
+
+	return wallet, nil
+}
+
+func (r *WalletRepository) CreateWalletWithTx(ctx context.Context, tx *sql.Tx, userID uuid.UUID) (*models.Wallet, error) {
+	id, err := repository.NewTimeOrderedID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate wallet ID: %w", err)
+	}
+
+	wallet := &models.Wallet{
+		ID:        id,
+		UserID:    userID,
+		Balance:   decimal.Zero,
+		Currency:  money.DefaultCurrency,
+		Status:    models.WalletStatusActive,
+		Kind:      models.WalletKindUser,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	query := `INSERT INTO wallets (id, user_id, balance, currency, status, created_at) VALUES (?, ?, ?, ?, ?, ?)`
+
+	_, err = tx.ExecContext(ctx, query, wallet.ID, wallet.UserID, wallet.Balance, wallet.Currency, wallet.Status, wallet.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create wallet: %w", err)
+	}
+
+	return wallet, nil
+}
+
+func (r *WalletRepository) GetWalletByUserID(ctx context.Context, userID uuid.UUID) (*models.Wallet, error) {
+	wallet := &models.Wallet{}
+	query := `SELECT id, user_id, balance, held_balance, currency, status, kind, version, created_at FROM wallets WHERE user_id = ?`
+
+	err := r.db.GetContext(ctx, wallet, query, userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("wallet %w for user ID: %s", repository.ErrNotFound, userID)
+		}
+		return nil, fmt.Errorf("failed to get wallet by user ID: %w", err)
+	}
+
+	return wallet, nil
+}
+
+func (r *WalletRepository) GetWalletByID(ctx context.Context, id uuid.UUID) (*models.Wallet, error) {
+	wallet := &models.Wallet{}
+	query := `SELECT id, user_id, balance, held_balance, currency, status, kind, version, created_at FROM wallets WHERE id = ?`
+
+	err := r.reader.GetContext(ctx, wallet, query, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("wallet %w", repository.ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to get wallet: %w", err)
+	}
+
+	return wallet, nil
+}
+
+func (r *WalletRepository) UpdateBalance(ctx context.Context, id uuid.UUID, balance decimal.Decimal, version int64) error {
+	query := `UPDATE wallets SET balance = ?, version = version + 1 WHERE id = ? AND version = ?`
+
+	result, err := r.db.ExecContext(ctx, query, balance, id, version)
+	if err != nil {
+		return fmt.Errorf("failed to update wallet balance: %w", err)
+	}
+
+	return checkVersionedUpdate(result, id)
+}
+
+// Transaction support methods
+func (r *WalletRepository) BeginTx(ctx context.Context) (*sql.Tx, error) {
+	return r.db.BeginTx(ctx, nil)
+}
+
+func (r *WalletRepository) UpdateBalanceWithTx(ctx context.Context, tx *sql.Tx, id uuid.UUID, balance decimal.Decimal, version int64) error {
+	query := `UPDATE wallets SET balance = ?, version = version + 1 WHERE id = ? AND version = ?`
+
+	result, err := tx.ExecContext(ctx, query, balance, id, version)
+	if err != nil {
+		return fmt.Errorf("failed to update wallet balance: %w", err)
+	}
+
+	return checkVersionedUpdate(result, id)
+}
+
+func (r *WalletRepository) UpdateStatusWithTx(ctx context.Context, tx *sql.Tx, id uuid.UUID, status string, version int64) error {
+	query := `UPDATE wallets SET status = ?, version = version + 1 WHERE id = ? AND version = ?`
+
+	result, err := tx.ExecContext(ctx, query, status, id, version)
+	if err != nil {
+		return fmt.Errorf("failed to update wallet status: %w", err)
+	}
+
+	return checkVersionedUpdate(result, id)
+}
+
+func (r *WalletRepository) UpdateHeldBalanceWithTx(ctx context.Context, tx *sql.Tx, id uuid.UUID, heldBalance decimal.Decimal, version int64) error {
+	query := `UPDATE wallets SET held_balance = ?, version = version + 1 WHERE id = ? AND version = ?`
+
+	result, err := tx.ExecContext(ctx, query, heldBalance, id, version)
+	if err != nil {
+		return fmt.Errorf("failed to update wallet held balance: %w", err)
+	}
+
+	return checkVersionedUpdate(result, id)
+}
+
+// GetWalletByIDWithTx reads the wallet with an exclusive InnoDB row lock held until the transaction ends
+func (r *WalletRepository) GetWalletByIDWithTx(ctx context.Context, tx *sql.Tx, id uuid.UUID) (*models.Wallet, error) {
+	wallet := &models.Wallet{}
+	query := `SELECT id, user_id, balance, held_balance, currency, status, kind, version, created_at FROM wallets WHERE id = ? FOR UPDATE`
+
+	err := tx.QueryRowContext(ctx, query, id).Scan(&wallet.ID, &wallet.UserID, &wallet.Balance, &wallet.HeldBalance, &wallet.Currency, &wallet.Status, &wallet.Kind, &wallet.Version, &wallet.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("wallet %w", repository.ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to get wallet: %w", err)
+	}
+
+	return wallet, nil
+}
+
+// GetWalletSnapshotWithTx reads the wallet inside tx without locking it. Concurrent
+// writers are caught by the version check when the wallet is updated.
+func (r *WalletRepository) GetWalletSnapshotWithTx(ctx context.Context, tx *sql.Tx, id uuid.UUID) (*models.Wallet, error) {
+	wallet := &models.Wallet{}
+	query := `SELECT id, user_id, balance, held_balance, currency, status, kind, version, created_at FROM wallets WHERE id = ?`
+
+	err := tx.QueryRowContext(ctx, query, id).Scan(&wallet.ID, &wallet.UserID, &wallet.Balance, &wallet.HeldBalance, &wallet.Currency, &wallet.Status, &wallet.Kind, &wallet.Version, &wallet.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("wallet %w", repository.ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to get wallet: %w", err)
+	}
+
+	return wallet, nil
+}
+
+// SearchWallets returns a page of the wallets matching filter
+func (r *WalletRepository) SearchWallets(ctx context.Context, filter repository.WalletFilter) ([]*models.Wallet, error) {
+	query, args := repository.WalletSearchQuery(filter, repository.QuestionPlaceholders)
+
+	wallets := []*models.Wallet{}
+	if err := r.reader.SelectContext(ctx, &wallets, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to search wallets: %w", err)
+	}
+	return wallets, nil
+}
+
+// EnsureSystemWallet inserts the system wallet unless the unique index on kind and
+// currency already holds one, then reads whichever wallet it holds
+func (r *WalletRepository) EnsureSystemWallet(ctx context.Context, kind string, currency money.Currency) (*models.Wallet, error) {
+	id, err := repository.NewTimeOrderedID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate wallet ID: %w", err)
+	}
+
+	query := `
+		INSERT INTO wallets (id, user_id, balance, currency, status, kind, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE id = id`
+	if _, err := r.db.ExecContext(ctx, query, id, models.SystemUserID, decimal.Zero, currency, models.WalletStatusActive, kind, time.Now().UTC()); err != nil {
+		return nil, fmt.Errorf("failed to create %s wallet: %w", kind, err)
+	}
+
+	wallet := &models.Wallet{}
+	query = `SELECT id, user_id, balance, held_balance, currency, status, kind, version, created_at FROM wallets WHERE kind = ? AND currency = ?`
+	if err := r.db.GetContext(ctx, wallet, query, kind, currency); err != nil {
+		return nil, fmt.Errorf("failed to get %s wallet: %w", kind, err)
+	}
+	return wallet, nil
+}
+
+// ListSystemWallets returns every system wallet by kind and currency
+func (r *WalletRepository) ListSystemWallets(ctx context.Context) ([]*models.Wallet, error) {
+	wallets := []*models.Wallet{}
+	query := `SELECT id, user_id, balance, held_balance, currency, status, kind, version, created_at FROM wallets
+		WHERE kind <> 'user' ORDER BY kind, currency`
+	if err := r.reader.SelectContext(ctx, &wallets, query); err != nil {
+		return nil, fmt.Errorf("failed to list system wallets: %w", err)
+	}
+	return wallets, nil
+}
+
+// checkVersionedUpdate maps a zero-row compare-and-swap UPDATE to ErrVersionConflict:
+// the wallet changed, or disappeared, after it was read at the expected version.
+// The DSN sets clientFoundRows so a matched row always counts as affected.
+func checkVersionedUpdate(result sql.Result, id uuid.UUID) error {
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("wallet %s: %w", id, repository.ErrVersionConflict)
+	}
+
+	return nil
+}
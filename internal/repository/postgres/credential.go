@@ -0,0 +1,76 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository"
+)
+
+type CredentialRepository struct {
+	db *sqlx.DB
+}
+
+func NewCredentialRepository(db *sqlx.DB) *CredentialRepository {
+	return &CredentialRepository{db: db}
+}
+
+func (r *CredentialRepository) CreateCredentials(ctx context.Context, credentials *models.Credentials) error {
+	query := `
+		INSERT INTO user_credentials (user_id, username, password_hash) 
+		VALUES ($1, $2, $3) 
+		RETURNING created_at`
+
+	err := r.db.QueryRowContext(ctx, query,
+		credentials.UserID,
+		credentials.Username,
+		credentials.PasswordHash,
+	).Scan(&credentials.CreatedAt)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("username %w", repository.ErrDuplicate)
+		}
+		return fmt.Errorf("failed to create credentials: %w", err)
+	}
+
+	return nil
+}
+
+func (r *CredentialRepository) CreateCredentialsWithTx(ctx context.Context, tx *sql.Tx, credentials *models.Credentials) error {
+	query := `
+		INSERT INTO user_credentials (user_id, username, password_hash) 
+		VALUES ($1, $2, $3) 
+		RETURNING created_at`
+
+	err := tx.QueryRowContext(ctx, query,
+		credentials.UserID,
+		credentials.Username,
+		credentials.PasswordHash,
+	).Scan(&credentials.CreatedAt)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("username %w", repository.ErrDuplicate)
+		}
+		return fmt.Errorf("failed to create credentials: %w", err)
+	}
+
+	return nil
+}
+
+func (r *CredentialRepository) GetCredentialsByUsername(ctx context.Context, username string) (*models.Credentials, error) {
+	credentials := &models.Credentials{}
+	query := `SELECT user_id, username, password_hash, created_at FROM user_credentials WHERE username = $1`
+
+	err := r.db.GetContext(ctx, credentials, query, username)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("credentials %w", repository.ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to get credentials: %w", err)
+	}
+
+	return credentials, nil
+}
@@ -0,0 +1,187 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository"
+)
+
+// disputeSelect reads disputes with the hold securing them
+const disputeSelect = `
+		SELECT d.id, d.transaction_id, d.journal_id, d.wallet_id, d.counterparty_wallet_id, d.amount, d.currency,
+			d.reason, d.status, h.id AS hold_id, d.reversal_journal_id, d.created_at, d.updated_at
+		FROM disputes d
+		LEFT JOIN holds h ON h.dispute_id = d.id`
+
+type DisputeRepository struct {
+	db *sqlx.DB
+}
+
+func NewDisputeRepository(db *sqlx.DB) *DisputeRepository {
+	return &DisputeRepository{db: db}
+}
+
+func (r *DisputeRepository) CreateDisputeWithTx(ctx context.Context, tx *sql.Tx, dispute *models.Dispute) error {
+	id, err := repository.NewTimeOrderedID()
+	if err != nil {
+		return fmt.Errorf("failed to generate dispute ID: %w", err)
+	}
+	dispute.ID = id
+
+	query := `
+		INSERT INTO disputes (id, transaction_id, journal_id, wallet_id, counterparty_wallet_id, amount, currency,
+			reason, status, reversal_journal_id, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`
+
+	_, err = tx.ExecContext(ctx, query,
+		dispute.ID,
+		dispute.TransactionID,
+		dispute.JournalID,
+		dispute.WalletID,
+		dispute.CounterpartyWalletID,
+		dispute.Amount,
+		dispute.Currency,
+		dispute.Reason,
+		dispute.Status,
+		dispute.ReversalJournalID,
+		dispute.CreatedAt,
+		dispute.UpdatedAt,
+	)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("dispute of transaction %w", repository.ErrDuplicate)
+		}
+		return fmt.Errorf("failed to create dispute: %w", err)
+	}
+
+	return nil
+}
+
+func (r *DisputeRepository) GetDispute(ctx context.Context, id uuid.UUID) (*models.Dispute, error) {
+	dispute := &models.Dispute{}
+	if err := r.db.GetContext(ctx, dispute, disputeSelect+` WHERE d.id = $1`, id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("dispute %w", repository.ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to get dispute: %w", err)
+	}
+
+	if err := r.loadEvents(ctx, dispute); err != nil {
+		return nil, err
+	}
+	return dispute, nil
+}
+
+func (r *DisputeRepository) GetDisputeWithTx(ctx context.Context, tx *sql.Tx, id uuid.UUID) (*models.Dispute, error) {
+	dispute := &models.Dispute{}
+	err := tx.QueryRowContext(ctx, disputeSelect+` WHERE d.id = $1 FOR UPDATE OF d`, id).Scan(
+		&dispute.ID,
+		&dispute.TransactionID,
+		&dispute.JournalID,
+		&dispute.WalletID,
+		&dispute.CounterpartyWalletID,
+		&dispute.Amount,
+		&dispute.Currency,
+		&dispute.Reason,
+		&dispute.Status,
+		&dispute.HoldID,
+		&dispute.ReversalJournalID,
+		&dispute.CreatedAt,
+		&dispute.UpdatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("dispute %w", repository.ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to get dispute: %w", err)
+	}
+
+	return dispute, nil
+}
+
+func (r *DisputeRepository) ListDisputesByTransactionID(ctx context.Context, transactionID uuid.UUID) ([]*models.Dispute, error) {
+	disputes := []*models.Dispute{}
+	query := disputeSelect + ` WHERE d.transaction_id = $1 ORDER BY d.created_at, d.id`
+
+	if err := r.db.SelectContext(ctx, &disputes, query, transactionID); err != nil {
+		return nil, fmt.Errorf("failed to list disputes: %w", err)
+	}
+
+	for _, dispute := range disputes {
+		if err := r.loadEvents(ctx, dispute); err != nil {
+			return nil, err
+		}
+	}
+	return disputes, nil
+}
+
+func (r *DisputeRepository) ListDisputes(ctx context.Context, status string, limit, offset int) ([]*models.Dispute, error) {
+	disputes := []*models.Dispute{}
+	query := disputeSelect + `
+		WHERE $1::text = '' OR d.status = $1
+		ORDER BY d.created_at, d.id
+		LIMIT $2 OFFSET $3`
+
+	if err := r.db.SelectContext(ctx, &disputes, query, status, limit, offset); err != nil {
+		return nil, fmt.Errorf("failed to list disputes: %w", err)
+	}
+
+	return disputes, nil
+}
+
+func (r *DisputeRepository) UpdateDisputeWithTx(ctx context.Context, tx *sql.Tx, dispute *models.Dispute) error {
+	query := `UPDATE disputes SET status = $1, reversal_journal_id = $2, updated_at = $3 WHERE id = $4`
+
+	result, err := tx.ExecContext(ctx, query, dispute.Status, dispute.ReversalJournalID, dispute.UpdatedAt, dispute.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update dispute: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("dispute %w", repository.ErrNotFound)
+	}
+
+	return nil
+}
+
+func (r *DisputeRepository) CreateDisputeEventWithTx(ctx context.Context, tx *sql.Tx, event *models.DisputeEvent) error {
+	id, err := repository.NewTimeOrderedID()
+	if err != nil {
+		return fmt.Errorf("failed to generate dispute event ID: %w", err)
+	}
+	event.ID = id
+
+	query := `INSERT INTO dispute_events (id, dispute_id, status, note, created_at) VALUES ($1, $2, $3, $4, $5)`
+
+	if _, err := tx.ExecContext(ctx, query, event.ID, event.DisputeID, event.Status, event.Note, event.CreatedAt); err != nil {
+		return fmt.Errorf("failed to create dispute event: %w", err)
+	}
+
+	return nil
+}
+
+// loadEvents reads the dispute's events, oldest first
+func (r *DisputeRepository) loadEvents(ctx context.Context, dispute *models.Dispute) error {
+	dispute.Events = []*models.DisputeEvent{}
+	query := `
+		SELECT id, dispute_id, status, note, created_at
+		FROM dispute_events
+		WHERE dispute_id = $1
+		ORDER BY created_at, id`
+
+	if err := r.db.SelectContext(ctx, &dispute.Events, query, dispute.ID); err != nil {
+		return fmt.Errorf("failed to get dispute events: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package postgres
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/shanwije/wallet-app/internal/repository"
+)
+
+// uniqueViolation is the SQLSTATE Postgres reports for unique constraint failures
+const uniqueViolation = "23505"
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolation
+}
+
+// contactError returns err wrapped with the repository error for the user contact
+// detail it found taken, or err itself
+func contactError(err error) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || pgErr.Code != uniqueViolation {
+		return err
+	}
+	switch pgErr.ConstraintName {
+	case "uq_users_email":
+		return fmt.Errorf("%w: %v", repository.ErrDuplicateEmail, err)
+	case "uq_users_phone":
+		return fmt.Errorf("%w: %v", repository.ErrDuplicatePhone, err)
+	}
+	return err
+}
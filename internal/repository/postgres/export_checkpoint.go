@@ -0,0 +1,52 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/shanwije/wallet-app/internal/models"
+)
+
+type ExportCheckpointRepository struct {
+	db *sqlx.DB
+}
+
+func NewExportCheckpointRepository(db *sqlx.DB) *ExportCheckpointRepository {
+	return &ExportCheckpointRepository{db: db}
+}
+
+func (r *ExportCheckpointRepository) GetExportCheckpoint(ctx context.Context, stream string) (*models.ExportCheckpoint, error) {
+	checkpoint := &models.ExportCheckpoint{}
+	err := r.db.GetContext(ctx, checkpoint,
+		`SELECT stream, last_id, batches, exported_rows, updated_at FROM export_checkpoints WHERE stream = $1`, stream)
+	if errors.Is(err, sql.ErrNoRows) {
+		return &models.ExportCheckpoint{Stream: stream}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get export checkpoint: %w", err)
+	}
+	return checkpoint, nil
+}
+
+func (r *ExportCheckpointRepository) SaveExportCheckpoint(ctx context.Context, checkpoint *models.ExportCheckpoint) error {
+	checkpoint.UpdatedAt = time.Now().UTC()
+	query := `
+		INSERT INTO export_checkpoints (stream, last_id, batches, exported_rows, updated_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (stream) DO UPDATE SET
+			last_id = EXCLUDED.last_id,
+			batches = EXCLUDED.batches,
+			exported_rows = EXCLUDED.exported_rows,
+			updated_at = EXCLUDED.updated_at`
+
+	_, err := r.db.ExecContext(ctx, query, checkpoint.Stream, checkpoint.LastID, checkpoint.Batches, checkpoint.Rows, checkpoint.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save export checkpoint: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository"
+)
+
+const kycDocumentSelect = `
+		SELECT id, user_id, document_type, reference, status, note, submitted_at, reviewed_at
+		FROM kyc_documents`
+
+type KYCRepository struct {
+	db *sqlx.DB
+}
+
+func NewKYCRepository(db *sqlx.DB) *KYCRepository {
+	return &KYCRepository{db: db}
+}
+
+func (r *KYCRepository) GetKYCProfile(ctx context.Context, userID uuid.UUID) (*models.KYCProfile, error) {
+	query := `SELECT user_id, tier, note, updated_at FROM kyc_profiles WHERE user_id = $1`
+
+	var profile models.KYCProfile
+	err := r.db.GetContext(ctx, &profile, query, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return &models.KYCProfile{UserID: userID, Tier: models.KYCTierUnverified}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get KYC profile: %w", err)
+	}
+
+	return &profile, nil
+}
+
+func (r *KYCRepository) GetKYCTierWithTx(ctx context.Context, tx *sql.Tx, userID uuid.UUID) (string, error) {
+	var tier string
+	err := tx.QueryRowContext(ctx, `SELECT tier FROM kyc_profiles WHERE user_id = $1`, userID).Scan(&tier)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.KYCTierUnverified, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get KYC tier: %w", err)
+	}
+
+	return tier, nil
+}
+
+func (r *KYCRepository) SetKYCProfile(ctx context.Context, profile *models.KYCProfile) error {
+	query := `
+		INSERT INTO kyc_profiles (user_id, tier, note, updated_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id) DO UPDATE SET
+			tier = EXCLUDED.tier,
+			note = EXCLUDED.note,
+			updated_at = EXCLUDED.updated_at`
+
+	if _, err := r.db.ExecContext(ctx, query, profile.UserID, profile.Tier, profile.Note, profile.UpdatedAt); err != nil {
+		return fmt.Errorf("failed to set KYC profile: %w", err)
+	}
+
+	return nil
+}
+
+func (r *KYCRepository) CreateKYCDocument(ctx context.Context, document *models.KYCDocument) error {
+	id, err := repository.NewTimeOrderedID()
+	if err != nil {
+		return fmt.Errorf("failed to generate KYC document ID: %w", err)
+	}
+	document.ID = id
+
+	query := `
+		INSERT INTO kyc_documents (id, user_id, document_type, reference, status, submitted_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`
+
+	_, err = r.db.ExecContext(ctx, query,
+		document.ID,
+		document.UserID,
+		document.DocumentType,
+		document.Reference,
+		document.Status,
+		document.SubmittedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create KYC document: %w", err)
+	}
+
+	return nil
+}
+
+func (r *KYCRepository) GetKYCDocument(ctx context.Context, id uuid.UUID) (*models.KYCDocument, error) {
+	var document models.KYCDocument
+	err := r.db.GetContext(ctx, &document, kycDocumentSelect+` WHERE id = $1`, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("KYC document %w", repository.ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get KYC document: %w", err)
+	}
+
+	return &document, nil
+}
+
+func (r *KYCRepository) ListKYCDocumentsByUserID(ctx context.Context, userID uuid.UUID) ([]*models.KYCDocument, error) {
+	documents := []*models.KYCDocument{}
+	query := kycDocumentSelect + `
+		WHERE user_id = $1
+		ORDER BY submitted_at DESC, id DESC`
+
+	if err := r.db.SelectContext(ctx, &documents, query, userID); err != nil {
+		return nil, fmt.Errorf("failed to list KYC documents: %w", err)
+	}
+
+	return documents, nil
+}
+
+func (r *KYCRepository) ListKYCDocumentsByStatus(ctx context.Context, status string, limit, offset int) ([]*models.KYCDocument, error) {
+	documents := []*models.KYCDocument{}
+	query := kycDocumentSelect + `
+		WHERE status = $1
+		ORDER BY submitted_at, id
+		LIMIT $2 OFFSET $3`
+
+	if err := r.db.SelectContext(ctx, &documents, query, status, limit, offset); err != nil {
+		return nil, fmt.Errorf("failed to list KYC documents: %w", err)
+	}
+
+	return documents, nil
+}
+
+func (r *KYCRepository) UpdateKYCDocument(ctx context.Context, document *models.KYCDocument) error {
+	query := `
+		UPDATE kyc_documents SET status = $1, note = $2, reviewed_at = $3
+		WHERE id = $4 AND status = $5`
+
+	result, err := r.db.ExecContext(ctx, query,
+		document.Status, document.Note, document.ReviewedAt, document.ID, models.KYCDocumentStatusPending)
+	if err != nil {
+		return fmt.Errorf("failed to update KYC document: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("pending KYC document %w", repository.ErrNotFound)
+	}
+
+	return nil
+}
@@ -0,0 +1,205 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository"
+)
+
+const paymentColumns = `id, payer_wallet_id, merchant_wallet_id, order_reference, amount, refunded_amount, currency,
+		status, journal_id, created_at, updated_at`
+
+type PaymentRepository struct {
+	db *sqlx.DB
+}
+
+func NewPaymentRepository(db *sqlx.DB) *PaymentRepository {
+	return &PaymentRepository{db: db}
+}
+
+func (r *PaymentRepository) CreateMerchantAccount(ctx context.Context, account *models.MerchantAccount) error {
+	query := `INSERT INTO merchant_accounts (wallet_id, name, created_at) VALUES ($1, $2, $3)`
+
+	if _, err := r.db.ExecContext(ctx, query, account.WalletID, account.Name, account.CreatedAt); err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("merchant account %w", repository.ErrDuplicate)
+		}
+		return fmt.Errorf("failed to create merchant account: %w", err)
+	}
+
+	return nil
+}
+
+func (r *PaymentRepository) GetMerchantAccount(ctx context.Context, walletID uuid.UUID) (*models.MerchantAccount, error) {
+	account := &models.MerchantAccount{}
+	query := `SELECT wallet_id, name, created_at FROM merchant_accounts WHERE wallet_id = $1`
+
+	if err := r.db.GetContext(ctx, account, query, walletID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("merchant account %w", repository.ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to get merchant account: %w", err)
+	}
+
+	return account, nil
+}
+
+func (r *PaymentRepository) CreatePaymentWithTx(ctx context.Context, tx *sql.Tx, payment *models.Payment) error {
+	id, err := repository.NewTimeOrderedID()
+	if err != nil {
+		return fmt.Errorf("failed to generate payment ID: %w", err)
+	}
+	payment.ID = id
+
+	query := `
+		INSERT INTO payments (` + paymentColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`
+
+	_, err = tx.ExecContext(ctx, query,
+		payment.ID,
+		payment.PayerWalletID,
+		payment.MerchantWalletID,
+		payment.OrderReference,
+		payment.Amount,
+		payment.RefundedAmount,
+		payment.Currency,
+		payment.Status,
+		payment.JournalID,
+		payment.CreatedAt,
+		payment.UpdatedAt,
+	)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("payment order reference %w", repository.ErrDuplicate)
+		}
+		return fmt.Errorf("failed to create payment: %w", err)
+	}
+
+	return nil
+}
+
+func (r *PaymentRepository) GetPayment(ctx context.Context, id uuid.UUID) (*models.Payment, error) {
+	payment, err := scanPayment(r.db.QueryRowContext(ctx, `SELECT `+paymentColumns+` FROM payments WHERE id = $1`, id))
+	if err != nil {
+		return nil, err
+	}
+
+	payment.Refunds = []*models.PaymentRefund{}
+	query := `
+		SELECT id, payment_id, amount, currency, reason, journal_id, created_at
+		FROM payment_refunds
+		WHERE payment_id = $1
+		ORDER BY created_at, id`
+
+	if err := r.db.SelectContext(ctx, &payment.Refunds, query, id); err != nil {
+		return nil, fmt.Errorf("failed to get payment refunds: %w", err)
+	}
+
+	return payment, nil
+}
+
+func (r *PaymentRepository) GetPaymentWithTx(ctx context.Context, tx *sql.Tx, id uuid.UUID) (*models.Payment, error) {
+	return scanPayment(tx.QueryRowContext(ctx, `SELECT `+paymentColumns+` FROM payments WHERE id = $1 FOR UPDATE`, id))
+}
+
+func (r *PaymentRepository) GetPaymentByJournalIDWithTx(ctx context.Context, tx *sql.Tx, journalID uuid.UUID) (*models.Payment, error) {
+	return scanPayment(tx.QueryRowContext(ctx, `SELECT `+paymentColumns+` FROM payments WHERE journal_id = $1`, journalID))
+}
+
+func (r *PaymentRepository) UpdatePaymentWithTx(ctx context.Context, tx *sql.Tx, payment *models.Payment) error {
+	query := `UPDATE payments SET refunded_amount = $1, status = $2, updated_at = $3 WHERE id = $4`
+
+	result, err := tx.ExecContext(ctx, query, payment.RefundedAmount, payment.Status, payment.UpdatedAt, payment.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update payment: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("payment %w", repository.ErrNotFound)
+	}
+
+	return nil
+}
+
+func (r *PaymentRepository) CreatePaymentRefundWithTx(ctx context.Context, tx *sql.Tx, refund *models.PaymentRefund) error {
+	id, err := repository.NewTimeOrderedID()
+	if err != nil {
+		return fmt.Errorf("failed to generate payment refund ID: %w", err)
+	}
+	refund.ID = id
+
+	query := `
+		INSERT INTO payment_refunds (id, payment_id, amount, currency, reason, journal_id, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`
+
+	_, err = tx.ExecContext(ctx, query, refund.ID, refund.PaymentID, refund.Amount, refund.Currency, refund.Reason, refund.JournalID, refund.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create payment refund: %w", err)
+	}
+
+	return nil
+}
+
+func (r *PaymentRepository) GetMerchantSettlement(ctx context.Context, merchantWalletID uuid.UUID, from, to time.Time) (*models.MerchantSettlement, error) {
+	settlement := &models.MerchantSettlement{WalletID: merchantWalletID, From: from, To: to}
+
+	query := `
+		SELECT COUNT(*), COALESCE(SUM(amount), 0)
+		FROM payments
+		WHERE merchant_wallet_id = $1 AND created_at >= $2 AND created_at < $3`
+
+	err := r.db.QueryRowContext(ctx, query, merchantWalletID, from, to).Scan(&settlement.PaymentCount, &settlement.GrossAmount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sum payments: %w", err)
+	}
+
+	refundQuery := `
+		SELECT COUNT(*), COALESCE(SUM(f.amount), 0)
+		FROM payment_refunds f
+		JOIN payments p ON p.id = f.payment_id
+		WHERE p.merchant_wallet_id = $1 AND f.created_at >= $2 AND f.created_at < $3`
+
+	err = r.db.QueryRowContext(ctx, refundQuery, merchantWalletID, from, to).Scan(&settlement.RefundCount, &settlement.RefundedAmount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sum payment refunds: %w", err)
+	}
+
+	return settlement, nil
+}
+
+func scanPayment(row *sql.Row) (*models.Payment, error) {
+	payment := &models.Payment{}
+	err := row.Scan(
+		&payment.ID,
+		&payment.PayerWalletID,
+		&payment.MerchantWalletID,
+		&payment.OrderReference,
+		&payment.Amount,
+		&payment.RefundedAmount,
+		&payment.Currency,
+		&payment.Status,
+		&payment.JournalID,
+		&payment.CreatedAt,
+		&payment.UpdatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("payment %w", repository.ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to get payment: %w", err)
+	}
+
+	return payment, nil
+}
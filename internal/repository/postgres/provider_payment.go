@@ -0,0 +1,120 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository"
+)
+
+const providerPaymentColumns = `id, payment_id, wallet_id, amount, currency, status, failure_reason, journal_id,
+		created_at, updated_at`
+
+type ProviderPaymentRepository struct {
+	db *sqlx.DB
+}
+
+func NewProviderPaymentRepository(db *sqlx.DB) *ProviderPaymentRepository {
+	return &ProviderPaymentRepository{db: db}
+}
+
+func (r *ProviderPaymentRepository) CreateProviderPaymentWithTx(ctx context.Context, tx *sql.Tx, payment *models.ProviderPayment) error {
+	id, err := repository.NewTimeOrderedID()
+	if err != nil {
+		return fmt.Errorf("failed to generate provider payment ID: %w", err)
+	}
+	payment.ID = id
+
+	query := `
+		INSERT INTO provider_payments (` + providerPaymentColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+
+	_, err = tx.ExecContext(ctx, query,
+		payment.ID,
+		payment.PaymentID,
+		payment.WalletID,
+		payment.Amount,
+		payment.Currency,
+		payment.Status,
+		payment.FailureReason,
+		payment.JournalID,
+		payment.CreatedAt,
+		payment.UpdatedAt,
+	)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("provider payment %w", repository.ErrDuplicate)
+		}
+		return fmt.Errorf("failed to create provider payment: %w", err)
+	}
+
+	return nil
+}
+
+func (r *ProviderPaymentRepository) GetProviderPayment(ctx context.Context, paymentID string) (*models.ProviderPayment, error) {
+	return scanProviderPayment(r.db.QueryRowContext(ctx,
+		`SELECT `+providerPaymentColumns+` FROM provider_payments WHERE payment_id = $1`, paymentID))
+}
+
+func (r *ProviderPaymentRepository) GetProviderPaymentByJournalID(ctx context.Context, journalID uuid.UUID) (*models.ProviderPayment, error) {
+	return scanProviderPayment(r.db.QueryRowContext(ctx,
+		`SELECT `+providerPaymentColumns+` FROM provider_payments WHERE journal_id = $1`, journalID))
+}
+
+func (r *ProviderPaymentRepository) GetProviderPaymentWithTx(ctx context.Context, tx *sql.Tx, paymentID string) (*models.ProviderPayment, error) {
+	return scanProviderPayment(tx.QueryRowContext(ctx,
+		`SELECT `+providerPaymentColumns+` FROM provider_payments WHERE payment_id = $1 FOR UPDATE`, paymentID))
+}
+
+func (r *ProviderPaymentRepository) UpdateProviderPaymentWithTx(ctx context.Context, tx *sql.Tx, payment *models.ProviderPayment) error {
+	query := `
+		UPDATE provider_payments
+		SET status = $1, failure_reason = $2, journal_id = $3, updated_at = $4
+		WHERE id = $5`
+
+	result, err := tx.ExecContext(ctx, query,
+		payment.Status, payment.FailureReason, payment.JournalID, payment.UpdatedAt, payment.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update provider payment: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("provider payment %w", repository.ErrNotFound)
+	}
+
+	return nil
+}
+
+func scanProviderPayment(row *sql.Row) (*models.ProviderPayment, error) {
+	payment := &models.ProviderPayment{}
+	err := row.Scan(
+		&payment.ID,
+		&payment.PaymentID,
+		&payment.WalletID,
+		&payment.Amount,
+		&payment.Currency,
+		&payment.Status,
+		&payment.FailureReason,
+		&payment.JournalID,
+		&payment.CreatedAt,
+		&payment.UpdatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("provider payment %w", repository.ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to get provider payment: %w", err)
+	}
+
+	return payment, nil
+}
@@ -0,0 +1,164 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository"
+)
+
+type ReconciliationRepository struct {
+	db     *sqlx.DB
+	reader *sqlx.DB
+	// pool is the pgx pool beneath db, through which a run and its discrepancies are
+	// written in a single batch
+	pool *pgxpool.Pool
+}
+
+func NewReconciliationRepository(db *sqlx.DB, pool *pgxpool.Pool) *ReconciliationRepository {
+	return &ReconciliationRepository{db: db, reader: db, pool: pool}
+}
+
+// WithReadReplica moves the balance comparison, a scan of the whole ledger, onto replica.
+// A replica applies each transaction at once, so its balances and entries still agree.
+func (r *ReconciliationRepository) WithReadReplica(replica *sqlx.DB) *ReconciliationRepository {
+	r.reader = replica
+	return r
+}
+
+func (r *ReconciliationRepository) CountWallets(ctx context.Context) (int64, error) {
+	var count int64
+	if err := r.reader.GetContext(ctx, &count, `SELECT COUNT(*) FROM wallets`); err != nil {
+		return 0, fmt.Errorf("failed to count wallets: %w", err)
+	}
+	return count, nil
+}
+
+func (r *ReconciliationRepository) FindBalanceDiscrepancies(ctx context.Context) ([]*models.BalanceDiscrepancy, error) {
+	// One statement sees one snapshot, and a wallet's balance is updated in the same
+	// transaction as its entries are written, so movements in flight never show up here
+	query := `
+		SELECT w.id AS wallet_id, w.balance, w.currency,
+			COALESCE(SUM(CASE WHEN e.direction = 'credit' THEN e.amount ELSE -e.amount END), 0) AS ledger_balance
+		FROM wallets w
+		LEFT JOIN ledger_entries e ON e.wallet_id = w.id
+		GROUP BY w.id, w.balance, w.currency
+		HAVING w.balance <> COALESCE(SUM(CASE WHEN e.direction = 'credit' THEN e.amount ELSE -e.amount END), 0)
+		ORDER BY w.id`
+
+	discrepancies := []*models.BalanceDiscrepancy{}
+	if err := r.reader.SelectContext(ctx, &discrepancies, query); err != nil {
+		return nil, fmt.Errorf("failed to compare balances with the ledger: %w", err)
+	}
+	for _, discrepancy := range discrepancies {
+		discrepancy.Difference = discrepancy.Balance.Sub(discrepancy.LedgerBalance)
+	}
+	return discrepancies, nil
+}
+
+func (r *ReconciliationRepository) CreateRun(ctx context.Context, run *models.ReconciliationRun) error {
+	id, err := repository.NewTimeOrderedID()
+	if err != nil {
+		return fmt.Errorf("failed to generate reconciliation run ID: %w", err)
+	}
+	run.ID = id
+	run.DiscrepancyCount = len(run.Discrepancies)
+
+	batch := &pgx.Batch{}
+	batch.Queue(`
+		INSERT INTO reconciliation_runs (id, started_at, finished_at, wallets_checked, discrepancy_count)
+		VALUES ($1, $2, $3, $4, $5)`,
+		run.ID, run.StartedAt, run.FinishedAt, run.WalletsChecked, run.DiscrepancyCount,
+	)
+
+	discrepancyQuery := `
+		INSERT INTO reconciliation_discrepancies (id, run_id, wallet_id, balance, ledger_balance, difference, currency, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+
+	for _, discrepancy := range run.Discrepancies {
+		discrepancyID, err := repository.NewTimeOrderedID()
+		if err != nil {
+			return fmt.Errorf("failed to generate discrepancy ID: %w", err)
+		}
+		discrepancy.ID = discrepancyID
+		discrepancy.RunID = run.ID
+		discrepancy.CreatedAt = run.FinishedAt
+
+		batch.Queue(discrepancyQuery,
+			discrepancy.ID,
+			discrepancy.RunID,
+			discrepancy.WalletID,
+			discrepancy.Balance,
+			discrepancy.LedgerBalance,
+			discrepancy.Difference,
+			discrepancy.Currency,
+			discrepancy.CreatedAt,
+		)
+	}
+
+	// The run and all its discrepancies go to the database in one round trip
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("failed to create reconciliation run: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit reconciliation run: %w", err)
+	}
+	return nil
+}
+
+func (r *ReconciliationRepository) ListRuns(ctx context.Context, limit, offset int) ([]*models.ReconciliationRun, error) {
+	query := `
+		SELECT id, started_at, finished_at, wallets_checked, discrepancy_count
+		FROM reconciliation_runs
+		ORDER BY started_at DESC, id DESC
+		LIMIT $1 OFFSET $2`
+
+	runs := []*models.ReconciliationRun{}
+	if err := r.db.SelectContext(ctx, &runs, query, limit, offset); err != nil {
+		return nil, fmt.Errorf("failed to list reconciliation runs: %w", err)
+	}
+	return runs, nil
+}
+
+func (r *ReconciliationRepository) ListDiscrepancies(ctx context.Context, filter repository.DiscrepancyFilter) ([]*models.BalanceDiscrepancy, error) {
+	var conditions []string
+	var args []interface{}
+	where := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+	if filter.RunID != uuid.Nil {
+		where("run_id = $%d", filter.RunID)
+	}
+	if filter.WalletID != uuid.Nil {
+		where("wallet_id = $%d", filter.WalletID)
+	}
+
+	query := `SELECT id, run_id, wallet_id, balance, ledger_balance, difference, currency, created_at
+		FROM reconciliation_discrepancies`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	args = append(args, filter.Limit, filter.Offset)
+	query += fmt.Sprintf(" ORDER BY created_at DESC, id DESC LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+
+	discrepancies := []*models.BalanceDiscrepancy{}
+	if err := r.db.SelectContext(ctx, &discrepancies, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to list discrepancies: %w", err)
+	}
+	return discrepancies, nil
+}
@@ -0,0 +1,137 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/shopspring/decimal"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository"
+)
+
+// RiskRepository reads recent activity from the primary: velocity checks cannot
+// tolerate replication lag
+type RiskRepository struct {
+	db *sqlx.DB
+}
+
+func NewRiskRepository(db *sqlx.DB) *RiskRepository {
+	return &RiskRepository{db: db}
+}
+
+func (r *RiskRepository) CountWalletDebitsSince(ctx context.Context, walletID uuid.UUID, journalType string, since time.Time) (int, error) {
+	var count int
+
+	query := `
+		SELECT COUNT(*)
+		FROM ledger_entries e
+		JOIN journals j ON j.id = e.journal_id
+		WHERE e.wallet_id = $1 AND e.direction = 'debit' AND j.type = $2 AND e.created_at >= $3`
+
+	if err := r.db.QueryRowContext(ctx, query, walletID, journalType, since).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count ledger entries: %w", err)
+	}
+
+	return count, nil
+}
+
+func (r *RiskRepository) AverageWalletDebitSince(ctx context.Context, walletID uuid.UUID, journalType string, since time.Time) (decimal.Decimal, int, error) {
+	var average decimal.Decimal
+	var count int
+
+	query := `
+		SELECT COALESCE(AVG(e.amount), 0), COUNT(*)
+		FROM ledger_entries e
+		JOIN journals j ON j.id = e.journal_id
+		WHERE e.wallet_id = $1 AND e.direction = 'debit' AND j.type = $2 AND e.created_at >= $3`
+
+	if err := r.db.QueryRowContext(ctx, query, walletID, journalType, since).Scan(&average, &count); err != nil {
+		return decimal.Zero, 0, fmt.Errorf("failed to average ledger entries: %w", err)
+	}
+
+	return average, count, nil
+}
+
+func (r *RiskRepository) HasTransferredTo(ctx context.Context, walletID, toWalletID uuid.UUID) (bool, error) {
+	var exists bool
+
+	query := `
+		SELECT EXISTS (
+			SELECT 1
+			FROM ledger_entries d
+			JOIN ledger_entries c ON c.journal_id = d.journal_id
+			JOIN journals j ON j.id = d.journal_id
+			WHERE d.wallet_id = $1 AND d.direction = 'debit'
+				AND c.wallet_id = $2 AND c.direction = 'credit'
+				AND j.type = 'transfer'
+		)`
+
+	if err := r.db.QueryRowContext(ctx, query, walletID, toWalletID).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to look up transfers: %w", err)
+	}
+
+	return exists, nil
+}
+
+func (r *RiskRepository) CreateRiskDecision(ctx context.Context, decision *models.RiskDecision) error {
+	id, err := repository.NewTimeOrderedID()
+	if err != nil {
+		return fmt.Errorf("failed to generate risk decision ID: %w", err)
+	}
+	decision.ID = id
+
+	query := `
+		INSERT INTO risk_decisions (id, wallet_id, operation, counterparty_wallet_id, amount, currency, action, reasons, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+
+	_, err = r.db.ExecContext(ctx, query,
+		decision.ID,
+		decision.WalletID,
+		decision.Operation,
+		decision.CounterpartyWalletID,
+		decision.Amount,
+		decision.Currency,
+		decision.Action,
+		decision.Reasons,
+		decision.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create risk decision: %w", err)
+	}
+
+	return nil
+}
+
+func (r *RiskRepository) ListRiskDecisions(ctx context.Context, filter repository.RiskDecisionFilter) ([]*models.RiskDecision, error) {
+	var conditions []string
+	var args []interface{}
+	where := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+	if filter.WalletID != uuid.Nil {
+		where("wallet_id = $%d", filter.WalletID)
+	}
+	if filter.Action != "" {
+		where("action = $%d", filter.Action)
+	}
+
+	query := `SELECT id, wallet_id, operation, counterparty_wallet_id, amount, currency, action, reasons, created_at
+		FROM risk_decisions`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	args = append(args, filter.Limit, filter.Offset)
+	query += fmt.Sprintf(" ORDER BY created_at DESC, id DESC LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+
+	decisions := []*models.RiskDecision{}
+	if err := r.db.SelectContext(ctx, &decisions, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to list risk decisions: %w", err)
+	}
+	return decisions, nil
+}
@@ -0,0 +1,148 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository"
+)
+
+const scheduledTransferColumns = `id, from_wallet_id, to_wallet_id, amount, currency, description, frequency,
+		start_at, next_run_at, occurrence, status, last_error, created_at, updated_at`
+
+type ScheduledTransferRepository struct {
+	db *sqlx.DB
+}
+
+func NewScheduledTransferRepository(db *sqlx.DB) *ScheduledTransferRepository {
+	return &ScheduledTransferRepository{db: db}
+}
+
+func (r *ScheduledTransferRepository) CreateScheduledTransfer(ctx context.Context, transfer *models.ScheduledTransfer) error {
+	id, err := repository.NewTimeOrderedID()
+	if err != nil {
+		return fmt.Errorf("failed to generate scheduled transfer ID: %w", err)
+	}
+	transfer.ID = id
+
+	query := `
+		INSERT INTO scheduled_transfers (id, from_wallet_id, to_wallet_id, amount, currency, description,
+			frequency, start_at, next_run_at, occurrence, status, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $12)`
+
+	_, err = r.db.ExecContext(ctx, query,
+		transfer.ID,
+		transfer.FromWalletID,
+		transfer.ToWalletID,
+		transfer.Amount,
+		transfer.Currency,
+		transfer.Description,
+		transfer.Frequency,
+		transfer.StartAt,
+		transfer.NextRunAt,
+		transfer.Occurrence,
+		transfer.Status,
+		transfer.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create scheduled transfer: %w", err)
+	}
+	transfer.UpdatedAt = transfer.CreatedAt
+
+	return nil
+}
+
+func (r *ScheduledTransferRepository) GetScheduledTransfer(ctx context.Context, id uuid.UUID) (*models.ScheduledTransfer, error) {
+	transfer := &models.ScheduledTransfer{}
+	query := `SELECT ` + scheduledTransferColumns + ` FROM scheduled_transfers WHERE id = $1`
+
+	err := r.db.GetContext(ctx, transfer, query, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("scheduled transfer %w", repository.ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to get scheduled transfer: %w", err)
+	}
+
+	return transfer, nil
+}
+
+func (r *ScheduledTransferRepository) ListScheduledTransfersByWalletID(ctx context.Context, walletID uuid.UUID) ([]*models.ScheduledTransfer, error) {
+	var transfers []*models.ScheduledTransfer
+	query := `SELECT ` + scheduledTransferColumns + ` FROM scheduled_transfers
+		WHERE from_wallet_id = $1
+		ORDER BY created_at DESC, id DESC`
+
+	if err := r.db.SelectContext(ctx, &transfers, query, walletID); err != nil {
+		return nil, fmt.Errorf("failed to list scheduled transfers: %w", err)
+	}
+
+	return transfers, nil
+}
+
+func (r *ScheduledTransferRepository) ListDueScheduledTransfers(ctx context.Context, now time.Time, limit int) ([]*models.ScheduledTransfer, error) {
+	var transfers []*models.ScheduledTransfer
+	query := `SELECT ` + scheduledTransferColumns + ` FROM scheduled_transfers
+		WHERE status = $1 AND next_run_at <= $2
+		ORDER BY next_run_at, id
+		LIMIT $3`
+
+	if err := r.db.SelectContext(ctx, &transfers, query, models.ScheduleStatusActive, now, limit); err != nil {
+		return nil, fmt.Errorf("failed to list due scheduled transfers: %w", err)
+	}
+
+	return transfers, nil
+}
+
+func (r *ScheduledTransferRepository) RecordScheduledTransferRun(ctx context.Context, transfer *models.ScheduledTransfer, previousOccurrence int) (bool, error) {
+	query := `
+		UPDATE scheduled_transfers
+		SET next_run_at = $1, occurrence = $2, status = $3, last_error = $4, updated_at = $5
+		WHERE id = $6 AND occurrence = $7 AND status = $8`
+
+	result, err := r.db.ExecContext(ctx, query,
+		transfer.NextRunAt,
+		transfer.Occurrence,
+		transfer.Status,
+		transfer.LastError,
+		transfer.UpdatedAt,
+		transfer.ID,
+		previousOccurrence,
+		models.ScheduleStatusActive,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to record scheduled transfer run: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected == 1, nil
+}
+
+func (r *ScheduledTransferRepository) CancelScheduledTransfer(ctx context.Context, id uuid.UUID, cancelledAt time.Time) error {
+	query := `UPDATE scheduled_transfers SET status = $1, updated_at = $2 WHERE id = $3 AND status = $4`
+
+	result, err := r.db.ExecContext(ctx, query, models.ScheduleStatusCancelled, cancelledAt, id, models.ScheduleStatusActive)
+	if err != nil {
+		return fmt.Errorf("failed to cancel scheduled transfer: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("active scheduled transfer %w", repository.ErrNotFound)
+	}
+
+	return nil
+}
@@ -4,10 +4,13 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
 	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository"
+	"github.com/shanwije/wallet-app/pkg/money"
 	"github.com/shopspring/decimal"
 )
 
@@ -19,20 +22,41 @@ func NewUserRepository(db *sqlx.DB) *UserRepository {
 	return &UserRepository{db: db}
 }
 
-func (r *UserRepository) CreateUser(ctx context.Context, name string) (*models.User, error) {
+func (r *UserRepository) CreateUser(ctx context.Context, name string, contact models.Contact) (*models.User, error) {
 	user := &models.User{
-		ID:   uuid.New(),
-		Name: name,
+		ID:      uuid.New(),
+		Name:    name,
+		Contact: contact,
 	}
 
 	query := `
-		INSERT INTO users (id, name) 
-		VALUES ($1, $2) 
+		INSERT INTO users (id, name, email, phone) 
+		VALUES ($1, $2, $3, $4) 
 		RETURNING created_at`
 
-	err := r.db.QueryRowContext(ctx, query, user.ID, user.Name).Scan(&user.CreatedAt)
+	err := r.db.QueryRowContext(ctx, query, user.ID, user.Name, user.Email, user.Phone).Scan(&user.CreatedAt)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create user: %w", err)
+		return nil, fmt.Errorf("failed to create user: %w", contactError(err))
+	}
+
+	return user, nil
+}
+
+func (r *UserRepository) CreateUserWithTx(ctx context.Context, tx *sql.Tx, name string, contact models.Contact) (*models.User, error) {
+	user := &models.User{
+		ID:      uuid.New(),
+		Name:    name,
+		Contact: contact,
+	}
+
+	query := `
+		INSERT INTO users (id, name, email, phone) 
+		VALUES ($1, $2, $3, $4) 
+		RETURNING created_at`
+
+	err := tx.QueryRowContext(ctx, query, user.ID, user.Name, user.Email, user.Phone).Scan(&user.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create user: %w", contactError(err))
 	}
 
 	return user, nil
@@ -40,12 +64,12 @@ func (r *UserRepository) CreateUser(ctx context.Context, name string) (*models.U
 
 func (r *UserRepository) GetUserByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
 	user := &models.User{}
-	query := `SELECT id, name, created_at FROM users WHERE id = $1`
+	query := `SELECT id, name, email, phone, version, created_at FROM users WHERE id = $1 AND deleted_at IS NULL`
 
 	err := r.db.GetContext(ctx, user, query, id)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("user not found")
+			return nil, fmt.Errorf("user %w", repository.ErrNotFound)
 		}
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
@@ -57,42 +81,112 @@ func (r *UserRepository) GetUserWithWallet(ctx context.Context, id uuid.UUID) (*
 	var userWithWallet models.UserWithWallet
 	query := `
 		SELECT 
-			u.id, u.name, u.created_at,
-			w.id as wallet_id, w.user_id as wallet_user_id, w.balance, w.created_at as wallet_created_at
+			u.id, u.name, u.email, u.phone, u.version, u.created_at,
+			w.id as wallet_id, w.user_id as wallet_user_id, w.balance, w.held_balance, w.currency, w.status, w.created_at as wallet_created_at
 		FROM users u
 		LEFT JOIN wallets w ON u.id = w.user_id
-		WHERE u.id = $1`
+		WHERE u.id = $1 AND u.deleted_at IS NULL`
 
 	row := r.db.QueryRowContext(ctx, query, id)
 
-	var walletID sql.NullString
-	var walletUserID sql.NullString
-	var balance sql.NullFloat64
+	var walletID uuid.NullUUID
+	var walletUserID uuid.NullUUID
+	var balance decimal.NullDecimal
+	var heldBalance decimal.NullDecimal
+	var currency sql.NullString
+	var status sql.NullString
 	var walletCreatedAt sql.NullTime
 
 	err := row.Scan(
-		&userWithWallet.ID, &userWithWallet.Name, &userWithWallet.CreatedAt,
-		&walletID, &walletUserID, &balance, &walletCreatedAt,
+		&userWithWallet.ID, &userWithWallet.Name, &userWithWallet.Email, &userWithWallet.Phone, &userWithWallet.Version, &userWithWallet.CreatedAt,
+		&walletID, &walletUserID, &balance, &heldBalance, &currency, &status, &walletCreatedAt,
 	)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("user not found")
+			return nil, fmt.Errorf("user %w", repository.ErrNotFound)
 		}
 		return nil, fmt.Errorf("failed to get user with wallet: %w", err)
 	}
 
 	// If wallet exists, populate it
 	if walletID.Valid {
-		walletUUID, _ := uuid.Parse(walletID.String)
-		userUUID, _ := uuid.Parse(walletUserID.String)
 		userWithWallet.Wallet = models.Wallet{
-			ID:        walletUUID,
-			UserID:    userUUID,
-			Balance:   decimal.NewFromFloat(balance.Float64),
-			CreatedAt: walletCreatedAt.Time,
+			ID:          walletID.UUID,
+			UserID:      walletUserID.UUID,
+			Balance:     balance.Decimal,
+			HeldBalance: heldBalance.Decimal,
+			Currency:    money.Currency(currency.String),
+			Status:      status.String,
+			CreatedAt:   walletCreatedAt.Time,
 		}
 	}
 
 	return &userWithWallet, nil
 }
+
+// UpdateUser writes the user's name and contact details at the version they were read
+// at, advancing it
+func (r *UserRepository) UpdateUser(ctx context.Context, user *models.User) error {
+	query := `UPDATE users SET name = $1, email = $2, phone = $3, version = version + 1
+		WHERE id = $4 AND version = $5 AND deleted_at IS NULL`
+
+	result, err := r.db.ExecContext(ctx, query, user.Name, user.Email, user.Phone, user.ID, user.Version)
+	if err != nil {
+		return fmt.Errorf("failed to update user: %w", contactError(err))
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("user %s: %w", user.ID, repository.ErrVersionConflict)
+	}
+
+	user.Version++
+	return nil
+}
+
+// SoftDeleteUserWithTx marks the user deleted, wrapping ErrNotFound if they are
+// missing or already deleted
+func (r *UserRepository) SoftDeleteUserWithTx(ctx context.Context, tx *sql.Tx, id uuid.UUID, deletedAt time.Time) error {
+	result, err := tx.ExecContext(ctx, `UPDATE users SET deleted_at = $1, email = NULL, phone = NULL WHERE id = $2 AND deleted_at IS NULL`, deletedAt, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("user %w", repository.ErrNotFound)
+	}
+
+	return nil
+}
+
+// ListUsers pages through the users that are not deleted, oldest first
+func (r *UserRepository) ListUsers(ctx context.Context, limit, offset int) ([]*models.User, error) {
+	users := []*models.User{}
+	if err := r.db.SelectContext(ctx, &users, `SELECT id, name, email, phone, version, created_at FROM users WHERE deleted_at IS NULL ORDER BY created_at, id LIMIT $1 OFFSET $2`, limit, offset); err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+	return users, nil
+}
+
+// SearchUsers pages through the users that are not deleted whose name contains query,
+// ignoring case, or is similar enough to it by pg_trgm's trigram similarity to survive
+// a typo. The most similar names come first.
+func (r *UserRepository) SearchUsers(ctx context.Context, query string, limit, offset int) ([]*models.User, error) {
+	users := []*models.User{}
+	err := r.db.SelectContext(ctx, &users, `SELECT id, name, email, phone, version, created_at FROM users
+		WHERE deleted_at IS NULL AND (name ILIKE $1 OR name % $2)
+		ORDER BY similarity(name, $2) DESC, created_at, id
+		LIMIT $3 OFFSET $4`, repository.ContainsPattern(query), query, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search users: %w", err)
+	}
+	return users, nil
+}
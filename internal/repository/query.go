@@ -0,0 +1,192 @@
+package repository
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Placeholders writes the nth bind parameter of a query, counting from 1, in the
+// syntax of a database driver
+type Placeholders func(n int) string
+
+var (
+	// QuestionPlaceholders are MySQL's and SQLite's ?
+	QuestionPlaceholders Placeholders = func(int) string { return "?" }
+	// DollarPlaceholders are PostgreSQL's $1, $2, ...
+	DollarPlaceholders Placeholders = func(n int) string { return "$" + strconv.Itoa(n) }
+)
+
+// QueryBuilder collects the conditions of a WHERE clause and the arguments they bind,
+// so that optional filters can be written once for every database
+type QueryBuilder struct {
+	placeholders Placeholders
+	conditions   []string
+	args         []interface{}
+}
+
+// NewQueryBuilder returns an empty builder binding arguments with placeholders
+func NewQueryBuilder(placeholders Placeholders) *QueryBuilder {
+	return &QueryBuilder{placeholders: placeholders}
+}
+
+// Arg binds value and returns its placeholder
+func (b *QueryBuilder) Arg(value interface{}) string {
+	b.args = append(b.args, value)
+	return b.placeholders(len(b.args))
+}
+
+// Where adds a condition, which has a %s verb for each of values in turn
+func (b *QueryBuilder) Where(condition string, values ...interface{}) {
+	placeholders := make([]interface{}, len(values))
+	for i, value := range values {
+		placeholders[i] = b.Arg(value)
+	}
+	b.conditions = append(b.conditions, fmt.Sprintf(condition, placeholders...))
+}
+
+// WhereClause returns " WHERE " and the conditions joined by AND, or nothing when
+// there are none
+func (b *QueryBuilder) WhereClause() string {
+	if len(b.conditions) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(b.conditions, " AND ")
+}
+
+// After adds the keyset condition for the rows that follow value and id in a listing
+// ordered by column and then idColumn, both descending or both ascending
+func (b *QueryBuilder) After(column, idColumn string, descending bool, value, id interface{}) {
+	after := ">"
+	if descending {
+		after = "<"
+	}
+	// Spelled out rather than as a row comparison, which MySQL does not plan as a range
+	b.Where(fmt.Sprintf("(%[1]s %[3]s %%s OR (%[1]s = %%s AND %[2]s %[3]s %%s))", column, idColumn, after), value, value, id)
+}
+
+// Args returns the arguments bound so far, in placeholder order
+func (b *QueryBuilder) Args() []interface{} {
+	return b.args
+}
+
+// likeEscaper escapes the wildcards of a LIKE pattern with backslashes
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// ContainsPattern returns a LIKE pattern, escaped with backslashes, matching values
+// that contain s
+func ContainsPattern(s string) string {
+	return "%" + likeEscaper.Replace(s) + "%"
+}
+
+// walletColumns are the columns a wallet is scanned from
+const walletColumns = `id, user_id, balance, held_balance, currency, status, kind, version, created_at`
+
+// WalletSearchQuery builds the query for a page of the wallets matching filter, and its
+// arguments. It pages by keyset when filter.After is set: the next wallets in sort
+// order after it, which stays cheap however deep the listing goes, as long as an index
+// leads with the filtered columns and ends with the sort column and id.
+func WalletSearchQuery(filter WalletFilter, placeholders Placeholders) (string, []interface{}) {
+	b := NewQueryBuilder(placeholders)
+	if filter.Status != "" {
+		b.Where("status = %s", filter.Status)
+	}
+	if filter.Currency != "" {
+		b.Where("currency = %s", filter.Currency)
+	}
+	if filter.MinBalance != nil {
+		b.Where("balance >= %s", *filter.MinBalance)
+	}
+	if filter.MaxBalance != nil {
+		b.Where("balance <= %s", *filter.MaxBalance)
+	}
+	if !filter.CreatedFrom.IsZero() {
+		b.Where("created_at >= %s", filter.CreatedFrom)
+	}
+	if !filter.CreatedTo.IsZero() {
+		b.Where("created_at < %s", filter.CreatedTo)
+	}
+
+	column := filter.Sort.Field
+	if column != WalletSortBalance {
+		column = WalletSortCreatedAt
+	}
+	direction := "ASC"
+	if filter.Sort.Descending {
+		direction = "DESC"
+	}
+	if filter.After != nil {
+		var value interface{} = filter.After.CreatedAt
+		if column == WalletSortBalance {
+			value = filter.After.Balance
+		}
+		b.After(column, "id", filter.Sort.Descending, value, filter.After.ID)
+	}
+
+	query := `SELECT ` + walletColumns + ` FROM wallets` + b.WhereClause() +
+		fmt.Sprintf(" ORDER BY %[1]s %[2]s, id %[2]s LIMIT %[3]s", column, direction, b.Arg(filter.Limit))
+	if filter.After == nil {
+		query += " OFFSET " + b.Arg(filter.Offset)
+	}
+	return query, b.Args()
+}
+
+// auditColumns are the columns an audit entry is scanned from
+const auditColumns = `id, actor_type, actor_id, endpoint, path, payload_hash, status_code,
+		request_id, wallet_id, balance_before, balance_after, created_at`
+
+// AuditListQuery builds the query for a page of the audit entries matching filter,
+// newest first, and its arguments. It pages by keyset when filter.After is set.
+func AuditListQuery(filter AuditFilter, placeholders Placeholders) (string, []interface{}) {
+	b := NewQueryBuilder(placeholders)
+	if filter.ActorID != "" {
+		b.Where("actor_id = %s", filter.ActorID)
+	}
+	if filter.WalletID != uuid.Nil {
+		b.Where("wallet_id = %s", filter.WalletID)
+	}
+	if !filter.From.IsZero() {
+		b.Where("created_at >= %s", filter.From)
+	}
+	if !filter.To.IsZero() {
+		b.Where("created_at < %s", filter.To)
+	}
+	if filter.After != nil {
+		b.After("created_at", "id", true, filter.After.CreatedAt, filter.After.ID)
+	}
+
+	query := `SELECT ` + auditColumns + ` FROM audit_log` + b.WhereClause() +
+		" ORDER BY created_at DESC, id DESC LIMIT " + b.Arg(filter.Limit)
+	if filter.After == nil {
+		query += " OFFSET " + b.Arg(filter.Offset)
+	}
+	return query, b.Args()
+}
+
+// TransactionHistoryQuery builds the query for a page of the wallet's transactions
+// matching filter, and its arguments. Its columns are those every driver scans a
+// transaction from.
+func TransactionHistoryQuery(walletID uuid.UUID, filter TransactionFilter, placeholders Placeholders) (string, []interface{}) {
+	b := NewQueryBuilder(placeholders)
+	b.Where("e.wallet_id = %s", walletID)
+	if filter.Category != "" {
+		b.Where("j.category = %s", filter.Category)
+	}
+	direction := "ASC"
+	if filter.Sort.Descending {
+		direction = "DESC"
+	}
+	if filter.After != nil {
+		b.After("e.created_at", "e.id", filter.Sort.Descending, filter.After.CreatedAt, filter.After.ID)
+	}
+
+	query := `
+		SELECT e.id, e.wallet_id, j.type, e.direction, e.amount, e.exchange_rate, e.counter_amount, e.counter_currency, j.id, j.reverses_journal_id, j.correction_reason, c.id, j.description, j.category, e.created_at
+		FROM ledger_entries e
+		JOIN journals j ON j.id = e.journal_id
+		LEFT JOIN journals c ON c.reverses_journal_id = j.id AND c.type = 'correction'` + b.WhereClause() +
+		fmt.Sprintf(" ORDER BY e.created_at %[1]s, e.id %[1]s LIMIT %[2]s", direction, b.Arg(filter.Limit))
+	return query, b.Args()
+}
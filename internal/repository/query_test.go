@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/shanwije/wallet-app/pkg/pagination"
+)
+
+func TestWalletSearchQueryNumbersDollarPlaceholders(t *testing.T) {
+	after := &WalletCursor{ID: uuid.New(), Balance: decimal.NewFromInt(10)}
+	query, args := WalletSearchQuery(WalletFilter{
+		Status:      "active",
+		CreatedFrom: time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC),
+		Sort:        pagination.Sort{Field: WalletSortBalance, Descending: true},
+		After:       after,
+		Limit:       20,
+		Offset:      40,
+	}, DollarPlaceholders)
+
+	assert.Equal(t, `SELECT id, user_id, balance, held_balance, currency, status, kind, version, created_at FROM wallets`+
+		` WHERE status = $1 AND created_at >= $2 AND (balance < $3 OR (balance = $4 AND id < $5))`+
+		` ORDER BY balance DESC, id DESC LIMIT $6`, query)
+	// The cursor replaces the offset
+	assert.Equal(t, []interface{}{"active", time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC), after.Balance, after.Balance, after.ID, 20}, args)
+}
+
+func TestTransactionHistoryQueryPagesByKeyset(t *testing.T) {
+	walletID := uuid.New()
+	after := &Position{ID: uuid.New(), CreatedAt: time.Date(2024, 7, 26, 0, 0, 0, 0, time.UTC)}
+	query, args := TransactionHistoryQuery(walletID, TransactionFilter{
+		Category: "rent",
+		Sort:     pagination.Sort{Field: "created_at", Descending: true},
+		After:    after,
+		Limit:    10,
+	}, QuestionPlaceholders)
+
+	assert.Contains(t, query, ` WHERE e.wallet_id = ? AND j.category = ? AND (e.created_at < ? OR (e.created_at = ? AND e.id < ?))`+
+		` ORDER BY e.created_at DESC, e.id DESC LIMIT ?`)
+	assert.Equal(t, []interface{}{walletID, "rent", after.CreatedAt, after.CreatedAt, after.ID, 10}, args)
+}
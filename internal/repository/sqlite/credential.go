@@ -0,0 +1,77 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository"
+)
+
+type CredentialRepository struct {
+	db *sqlx.DB
+}
+
+func NewCredentialRepository(db *sqlx.DB) *CredentialRepository {
+	return &CredentialRepository{db: db}
+}
+
+func (r *CredentialRepository) CreateCredentials(ctx context.Context, credentials *models.Credentials) error {
+	credentials.CreatedAt = time.Now().UTC()
+
+	query := `INSERT INTO user_credentials (user_id, username, password_hash, created_at) VALUES (?, ?, ?, ?)`
+
+	_, err := r.db.ExecContext(ctx, query,
+		credentials.UserID,
+		credentials.Username,
+		credentials.PasswordHash,
+		credentials.CreatedAt,
+	)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("username %w", repository.ErrDuplicate)
+		}
+		return fmt.Errorf("failed to create credentials: %w", err)
+	}
+
+	return nil
+}
+
+func (r *CredentialRepository) CreateCredentialsWithTx(ctx context.Context, tx *sql.Tx, credentials *models.Credentials) error {
+	credentials.CreatedAt = time.Now().UTC()
+
+	query := `INSERT INTO user_credentials (user_id, username, password_hash, created_at) VALUES (?, ?, ?, ?)`
+
+	_, err := tx.ExecContext(ctx, query,
+		credentials.UserID,
+		credentials.Username,
+		credentials.PasswordHash,
+		credentials.CreatedAt,
+	)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("username %w", repository.ErrDuplicate)
+		}
+		return fmt.Errorf("failed to create credentials: %w", err)
+	}
+
+	return nil
+}
+
+func (r *CredentialRepository) GetCredentialsByUsername(ctx context.Context, username string) (*models.Credentials, error) {
+	credentials := &models.Credentials{}
+	query := `SELECT user_id, username, password_hash, created_at FROM user_credentials WHERE username = ?`
+
+	err := r.db.GetContext(ctx, credentials, query, username)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("credentials %w", repository.ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to get credentials: %w", err)
+	}
+
+	return credentials, nil
+}
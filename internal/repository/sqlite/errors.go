@@ -0,0 +1,34 @@
+package sqlite
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/mattn/go-sqlite3"
+
+	"github.com/shanwije/wallet-app/internal/repository"
+)
+
+func isUniqueViolation(err error) bool {
+	var sqliteErr sqlite3.Error
+	return errors.As(err, &sqliteErr) &&
+		(sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique || sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey)
+}
+
+// contactError returns err wrapped with the repository error for the user contact
+// detail it found taken, or err itself. SQLite names the column rather than the
+// index, as "UNIQUE constraint failed: users.email".
+func contactError(err error) error {
+	var sqliteErr sqlite3.Error
+	if !errors.As(err, &sqliteErr) || sqliteErr.ExtendedCode != sqlite3.ErrConstraintUnique {
+		return err
+	}
+	switch {
+	case strings.HasSuffix(err.Error(), "users.email"):
+		return fmt.Errorf("%w: %v", repository.ErrDuplicateEmail, err)
+	case strings.HasSuffix(err.Error(), "users.phone"):
+		return fmt.Errorf("%w: %v", repository.ErrDuplicatePhone, err)
+	}
+	return err
+}
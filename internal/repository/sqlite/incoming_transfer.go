@@ -0,0 +1,199 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository"
+)
+
+const incomingTransferSettingsQuery = `
+		SELECT wallet_id, require_acceptance, updated_at
+		FROM incoming_transfer_settings
+		WHERE wallet_id = ?`
+
+// incomingTransferSelect reads incoming transfers with the hold on them
+const incomingTransferSelect = `
+		SELECT t.id, t.journal_id, t.from_wallet_id, t.to_wallet_id, t.amount, t.currency, t.status,
+			h.id AS hold_id, t.return_journal_id, t.expires_at, t.created_at, t.updated_at
+		FROM incoming_transfers t
+		LEFT JOIN holds h ON h.incoming_transfer_id = t.id`
+
+type IncomingTransferRepository struct {
+	db *sqlx.DB
+}
+
+func NewIncomingTransferRepository(db *sqlx.DB) *IncomingTransferRepository {
+	return &IncomingTransferRepository{db: db}
+}
+
+func (r *IncomingTransferRepository) GetIncomingTransferSettings(ctx context.Context, walletID uuid.UUID) (*models.IncomingTransferSettings, error) {
+	return scanIncomingTransferSettings(r.db.QueryRowContext(ctx, incomingTransferSettingsQuery, walletID), walletID)
+}
+
+func (r *IncomingTransferRepository) GetIncomingTransferSettingsWithTx(ctx context.Context, tx *sql.Tx, walletID uuid.UUID) (*models.IncomingTransferSettings, error) {
+	return scanIncomingTransferSettings(tx.QueryRowContext(ctx, incomingTransferSettingsQuery, walletID), walletID)
+}
+
+func (r *IncomingTransferRepository) SetIncomingTransferSettings(ctx context.Context, settings *models.IncomingTransferSettings) error {
+	query := `
+		INSERT INTO incoming_transfer_settings (wallet_id, require_acceptance, updated_at)
+		VALUES (?, ?, ?)
+		ON CONFLICT (wallet_id) DO UPDATE SET
+			require_acceptance = excluded.require_acceptance,
+			updated_at = excluded.updated_at`
+
+	_, err := r.db.ExecContext(ctx, query, settings.WalletID, settings.RequireAcceptance, settings.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to set incoming transfer settings: %w", err)
+	}
+
+	return nil
+}
+
+func (r *IncomingTransferRepository) CreateIncomingTransferWithTx(ctx context.Context, tx *sql.Tx, transfer *models.IncomingTransfer) error {
+	id, err := repository.NewTimeOrderedID()
+	if err != nil {
+		return fmt.Errorf("failed to generate incoming transfer ID: %w", err)
+	}
+	transfer.ID = id
+
+	query := `
+		INSERT INTO incoming_transfers (id, journal_id, from_wallet_id, to_wallet_id, amount, currency, status,
+			expires_at, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	_, err = tx.ExecContext(ctx, query,
+		transfer.ID,
+		transfer.JournalID,
+		transfer.FromWalletID,
+		transfer.ToWalletID,
+		transfer.Amount,
+		transfer.Currency,
+		transfer.Status,
+		transfer.ExpiresAt,
+		transfer.CreatedAt,
+		transfer.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create incoming transfer: %w", err)
+	}
+	transfer.UpdatedAt = transfer.CreatedAt
+
+	return nil
+}
+
+func (r *IncomingTransferRepository) GetIncomingTransfer(ctx context.Context, id uuid.UUID) (*models.IncomingTransfer, error) {
+	transfer := &models.IncomingTransfer{}
+	if err := r.db.GetContext(ctx, transfer, incomingTransferSelect+` WHERE t.id = ?`, id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("incoming transfer %w", repository.ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to get incoming transfer: %w", err)
+	}
+
+	return transfer, nil
+}
+
+func (r *IncomingTransferRepository) GetIncomingTransferWithTx(ctx context.Context, tx *sql.Tx, id uuid.UUID) (*models.IncomingTransfer, error) {
+	transfer := &models.IncomingTransfer{}
+	err := tx.QueryRowContext(ctx, incomingTransferSelect+` WHERE t.id = ?`, id).Scan(
+		&transfer.ID,
+		&transfer.JournalID,
+		&transfer.FromWalletID,
+		&transfer.ToWalletID,
+		&transfer.Amount,
+		&transfer.Currency,
+		&transfer.Status,
+		&transfer.HoldID,
+		&transfer.ReturnJournalID,
+		&transfer.ExpiresAt,
+		&transfer.CreatedAt,
+		&transfer.UpdatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("incoming transfer %w", repository.ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to get incoming transfer: %w", err)
+	}
+
+	return transfer, nil
+}
+
+func (r *IncomingTransferRepository) ListIncomingTransfersByWalletID(ctx context.Context, walletID uuid.UUID) ([]*models.IncomingTransfer, error) {
+	transfers := []*models.IncomingTransfer{}
+	query := incomingTransferSelect + `
+		WHERE t.to_wallet_id = ?
+		ORDER BY t.created_at DESC, t.id DESC`
+
+	if err := r.db.SelectContext(ctx, &transfers, query, walletID); err != nil {
+		return nil, fmt.Errorf("failed to list incoming transfers: %w", err)
+	}
+
+	return transfers, nil
+}
+
+func (r *IncomingTransferRepository) ListExpiredIncomingTransfers(ctx context.Context, now time.Time, after uuid.UUID, limit int) ([]*models.IncomingTransfer, error) {
+	transfers := []*models.IncomingTransfer{}
+	query := incomingTransferSelect + `
+		WHERE t.status = ? AND t.expires_at <= ? AND t.id > ?
+		ORDER BY t.id
+		LIMIT ?`
+
+	if err := r.db.SelectContext(ctx, &transfers, query, models.IncomingTransferStatusPending, now, after, limit); err != nil {
+		return nil, fmt.Errorf("failed to list expired incoming transfers: %w", err)
+	}
+
+	return transfers, nil
+}
+
+func (r *IncomingTransferRepository) IsAwaitingAcceptanceWithTx(ctx context.Context, tx *sql.Tx, journalID uuid.UUID) (bool, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM incoming_transfers WHERE journal_id = ? AND status = ?`
+	if err := tx.QueryRowContext(ctx, query, journalID, models.IncomingTransferStatusPending).Scan(&count); err != nil {
+		return false, fmt.Errorf("failed to check incoming transfer: %w", err)
+	}
+	return count > 0, nil
+}
+
+func (r *IncomingTransferRepository) UpdateIncomingTransferWithTx(ctx context.Context, tx *sql.Tx, transfer *models.IncomingTransfer) error {
+	query := `UPDATE incoming_transfers SET status = ?, return_journal_id = ?, updated_at = ? WHERE id = ?`
+
+	result, err := tx.ExecContext(ctx, query, transfer.Status, transfer.ReturnJournalID, transfer.UpdatedAt, transfer.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update incoming transfer: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("incoming transfer %w", repository.ErrNotFound)
+	}
+
+	return nil
+}
+
+// scanIncomingTransferSettings reads an incoming_transfer_settings row, returning
+// settings that do not require acceptance when there is none
+func scanIncomingTransferSettings(row *sql.Row, walletID uuid.UUID) (*models.IncomingTransferSettings, error) {
+	settings := &models.IncomingTransferSettings{}
+	err := row.Scan(&settings.WalletID, &settings.RequireAcceptance, &settings.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return &models.IncomingTransferSettings{WalletID: walletID}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get incoming transfer settings: %w", err)
+	}
+
+	return settings, nil
+}
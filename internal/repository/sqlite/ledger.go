@@ -0,0 +1,481 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository"
+	"github.com/shopspring/decimal"
+)
+
+type LedgerRepository struct {
+	db *sqlx.DB
+	// reader serves transaction history and ledger balances, which can tolerate replication
+	// lag. Idempotency lookups always go to the primary.
+	reader *sqlx.DB
+}
+
+func NewLedgerRepository(db *sqlx.DB) *LedgerRepository {
+	return &LedgerRepository{db: db, reader: db}
+}
+
+// WithReadReplica moves the repository's lag-tolerant reads onto replica
+func (r *LedgerRepository) WithReadReplica(replica *sqlx.DB) *LedgerRepository {
+	r.reader = replica
+	return r
+}
+
+// CreateJournalWithTx inserts the journal and its entries. IDs and the creation time
+// are generated client-side, as in the MySQL repository.
+func (r *LedgerRepository) CreateJournalWithTx(ctx context.Context, tx *sql.Tx, journal *models.Journal) error {
+	if err := journal.Validate(); err != nil {
+		return err
+	}
+
+	id, err := repository.NewTimeOrderedID()
+	if err != nil {
+		return fmt.Errorf("failed to generate journal ID: %w", err)
+	}
+	journal.ID = id
+
+	if journal.CreatedAt.IsZero() {
+		journal.CreatedAt = time.Now().UTC()
+	}
+
+	query := `INSERT INTO journals (id, type, description, idempotency_key, reverses_journal_id, correction_reason, category, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+
+	_, err = tx.ExecContext(ctx, query, journal.ID, journal.Type, journal.Description, journal.IdempotencyKey, journal.ReversesJournalID, journal.CorrectionReason, journal.Category, journal.CreatedAt)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return duplicateJournalError(journal)
+		}
+		return fmt.Errorf("failed to create journal: %w", err)
+	}
+
+	entryQuery := `
+		INSERT INTO ledger_entries (id, journal_id, wallet_id, direction, amount, currency, exchange_rate, counter_amount, counter_currency, created_at) 
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	for _, entry := range journal.Entries {
+		entryID, err := repository.NewTimeOrderedID()
+		if err != nil {
+			return fmt.Errorf("failed to generate ledger entry ID: %w", err)
+		}
+		entry.ID = entryID
+		entry.JournalID = journal.ID
+		entry.CreatedAt = journal.CreatedAt
+
+		_, err = tx.ExecContext(ctx, entryQuery,
+			entry.ID,
+			entry.JournalID,
+			entry.WalletID,
+			entry.Direction,
+			entry.Amount,
+			entry.Currency,
+			entry.ExchangeRate,
+			entry.CounterAmount,
+			entry.CounterCurrency,
+			entry.CreatedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to create ledger entry: %w", err)
+		}
+	}
+
+	for _, tag := range journal.Tags {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO journal_tags (journal_id, tag) VALUES (?, ?)`, journal.ID, tag); err != nil {
+			return fmt.Errorf("failed to tag journal: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (r *LedgerRepository) GetJournalByIdempotencyKey(ctx context.Context, key string) (*models.Journal, error) {
+	return getJournal(ctx, r.db, "idempotency_key", key)
+}
+
+func (r *LedgerRepository) GetJournalByID(ctx context.Context, id uuid.UUID) (*models.Journal, error) {
+	return getJournal(ctx, r.reader, "id", id)
+}
+
+// GetJournalByEntryID reads from the primary, as callers go on to act on the journal
+func (r *LedgerRepository) GetJournalByEntryID(ctx context.Context, entryID uuid.UUID) (*models.Journal, error) {
+	var journalID uuid.UUID
+	err := r.db.GetContext(ctx, &journalID, `SELECT journal_id FROM ledger_entries WHERE id = ?`, entryID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("ledger entry %w", repository.ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to get ledger entry: %w", err)
+	}
+	return getJournal(ctx, r.db, "id", journalID)
+}
+
+// duplicateJournalError names the unique key a journal collided with: a reversal
+// can only clash on the journal it reverses, anything else on its idempotency key
+func duplicateJournalError(journal *models.Journal) error {
+	if journal.ReversesJournalID != nil {
+		return fmt.Errorf("journal reversal %w", repository.ErrDuplicate)
+	}
+	return fmt.Errorf("journal idempotency key %w", repository.ErrDuplicate)
+}
+
+// getJournal loads the journal whose column matches value, with its entries
+func getJournal(ctx context.Context, db *sqlx.DB, column string, value interface{}) (*models.Journal, error) {
+	journal := &models.Journal{}
+	query := `SELECT id, type, description, idempotency_key, reverses_journal_id, correction_reason, category, created_at FROM journals WHERE ` + column + ` = ?`
+
+	err := db.GetContext(ctx, journal, query, value)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("journal %w", repository.ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to get journal: %w", err)
+	}
+
+	entriesQuery := `
+		SELECT id, journal_id, wallet_id, direction, amount, currency, exchange_rate, counter_amount, counter_currency, created_at 
+		FROM ledger_entries 
+		WHERE journal_id = ? 
+		ORDER BY id`
+
+	if err := db.SelectContext(ctx, &journal.Entries, entriesQuery, journal.ID); err != nil {
+		return nil, fmt.Errorf("failed to get ledger entries: %w", err)
+	}
+
+	tagsQuery := `SELECT tag FROM journal_tags WHERE journal_id = ? ORDER BY tag`
+	if err := db.SelectContext(ctx, &journal.Tags, tagsQuery, journal.ID); err != nil {
+		return nil, fmt.Errorf("failed to get journal tags: %w", err)
+	}
+
+	return journal, nil
+}
+
+func (r *LedgerRepository) GetTransactionsByWalletID(ctx context.Context, walletID uuid.UUID) ([]*models.Transaction, error) {
+	var transactions []*models.Transaction
+
+	query := `
+		SELECT e.id, e.wallet_id, j.type, e.direction, e.amount, e.exchange_rate, e.counter_amount, e.counter_currency, j.id, j.reverses_journal_id, j.correction_reason, c.id, j.description, j.category, e.created_at 
+		FROM ledger_entries e 
+		JOIN journals j ON j.id = e.journal_id 
+		LEFT JOIN journals c ON c.reverses_journal_id = j.id AND c.type = 'correction' 
+		WHERE e.wallet_id = ? 
+		ORDER BY e.created_at DESC, e.id DESC`
+
+	rows, err := r.reader.QueryContext(ctx, query, walletID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transactions: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		transaction, err := scanTransaction(rows)
+		if err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, transaction)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("transaction rows error: %w", err)
+	}
+
+	if err := r.attachTags(ctx, walletID, transactions); err != nil {
+		return nil, err
+	}
+
+	return transactions, nil
+}
+
+func (r *LedgerRepository) ListTransactionsByWalletID(ctx context.Context, walletID uuid.UUID, filter repository.TransactionFilter) ([]*models.Transaction, error) {
+	query, args := repository.TransactionHistoryQuery(walletID, filter, repository.QuestionPlaceholders)
+
+	rows, err := r.reader.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list transactions: %w", err)
+	}
+	defer rows.Close()
+
+	transactions := []*models.Transaction{}
+	for rows.Next() {
+		transaction, err := scanTransaction(rows)
+		if err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, transaction)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("transaction rows error: %w", err)
+	}
+
+	if err := r.attachTags(ctx, walletID, transactions); err != nil {
+		return nil, err
+	}
+	return transactions, nil
+}
+
+func (r *LedgerRepository) ListTransactionsAfter(ctx context.Context, after uuid.UUID, until time.Time, limit int) ([]*models.Transaction, error) {
+	query := `
+		SELECT e.id, e.wallet_id, j.type, e.direction, e.amount, e.exchange_rate, e.counter_amount, e.counter_currency, j.id, j.reverses_journal_id, j.correction_reason, c.id, j.description, j.category, e.created_at
+		FROM ledger_entries e
+		JOIN journals j ON j.id = e.journal_id
+		LEFT JOIN journals c ON c.reverses_journal_id = j.id AND c.type = 'correction'
+		WHERE e.wallet_id IS NOT NULL AND e.id > ? AND e.created_at < ?
+		ORDER BY e.id
+		LIMIT ?`
+
+	rows, err := r.reader.QueryContext(ctx, query, after, until, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list transactions: %w", err)
+	}
+	defer rows.Close()
+
+	transactions := []*models.Transaction{}
+	for rows.Next() {
+		transaction, err := scanTransaction(rows)
+		if err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, transaction)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("transaction rows error: %w", err)
+	}
+	return transactions, nil
+}
+
+// attachTags fills in the tags of the wallet's transactions from their journals
+func (r *LedgerRepository) attachTags(ctx context.Context, walletID uuid.UUID, transactions []*models.Transaction) error {
+	var tags []struct {
+		JournalID uuid.UUID `db:"journal_id"`
+		Tag       string    `db:"tag"`
+	}
+	query := `
+		SELECT DISTINCT t.journal_id, t.tag 
+		FROM journal_tags t 
+		JOIN ledger_entries e ON e.journal_id = t.journal_id 
+		WHERE e.wallet_id = ? 
+		ORDER BY t.tag`
+	if err := r.reader.SelectContext(ctx, &tags, query, walletID); err != nil {
+		return fmt.Errorf("failed to get journal tags: %w", err)
+	}
+
+	byJournal := make(map[uuid.UUID][]string)
+	for _, tag := range tags {
+		byJournal[tag.JournalID] = append(byJournal[tag.JournalID], tag.Tag)
+	}
+	for _, transaction := range transactions {
+		transaction.Tags = byJournal[*transaction.ReferenceID]
+	}
+	return nil
+}
+
+// GetTransfersByWalletID pairs each of the wallet's transfer legs with the
+// counterparty's leg, which is the journal's other wallet leg
+func (r *LedgerRepository) GetTransfersByWalletID(ctx context.Context, walletID uuid.UUID, limit, offset int) ([]*models.WalletTransfer, error) {
+	transfers := []*models.WalletTransfer{}
+
+	query := `
+		SELECT j.id, e.direction, e.amount, e.currency, e.exchange_rate, e.counter_amount, e.counter_currency, 
+			c.wallet_id, u.name, j.description, j.created_at 
+		FROM ledger_entries e 
+		JOIN journals j ON j.id = e.journal_id 
+		JOIN ledger_entries c ON c.journal_id = e.journal_id AND c.wallet_id IS NOT NULL AND c.direction <> e.direction 
+		LEFT JOIN wallets w ON w.id = c.wallet_id 
+		LEFT JOIN users u ON u.id = w.user_id 
+		WHERE e.wallet_id = ? AND j.type = ? 
+		ORDER BY j.created_at DESC, j.id DESC 
+		LIMIT ? OFFSET ?`
+
+	rows, err := r.reader.QueryContext(ctx, query, walletID, models.JournalTypeTransfer, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transfers: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		transfer := &models.WalletTransfer{Status: models.TransferStatusCompleted}
+		var direction string
+		err := rows.Scan(
+			&transfer.ReferenceID,
+			&direction,
+			&transfer.Amount,
+			&transfer.Currency,
+			&transfer.ExchangeRate,
+			&transfer.CounterAmount,
+			&transfer.CounterCurrency,
+			&transfer.CounterpartyWalletID,
+			&transfer.CounterpartyName,
+			&transfer.Description,
+			&transfer.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan transfer: %w", err)
+		}
+		transfer.Direction = models.TransferDirection(direction)
+		transfers = append(transfers, transfer)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("transfer rows error: %w", err)
+	}
+
+	return transfers, nil
+}
+
+func (r *LedgerRepository) StreamTransactionsByWalletID(ctx context.Context, walletID uuid.UUID, from, to time.Time, fn func(*models.Transaction) error) error {
+	query := `
+		SELECT e.id, e.wallet_id, j.type, e.direction, e.amount, e.exchange_rate, e.counter_amount, e.counter_currency, j.id, j.reverses_journal_id, j.correction_reason, c.id, j.description, j.category, e.created_at 
+		FROM ledger_entries e 
+		JOIN journals j ON j.id = e.journal_id 
+		LEFT JOIN journals c ON c.reverses_journal_id = j.id AND c.type = 'correction' 
+		WHERE e.wallet_id = ? AND e.created_at >= ? AND e.created_at < ? 
+		ORDER BY e.created_at, e.id`
+
+	rows, err := r.reader.QueryContext(ctx, query, walletID, from, to)
+	if err != nil {
+		return fmt.Errorf("failed to get transactions: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		transaction, err := scanTransaction(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(transaction); err != nil {
+			return err
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("transaction rows error: %w", err)
+	}
+
+	return nil
+}
+
+func (r *LedgerRepository) GetWalletLedgerBalance(ctx context.Context, walletID uuid.UUID) (decimal.Decimal, error) {
+	var balance decimal.Decimal
+
+	query := `
+		SELECT decimal_sum(CASE WHEN direction = 'credit' THEN amount ELSE -amount END) 
+		FROM ledger_entries 
+		WHERE wallet_id = ?`
+
+	if err := r.reader.QueryRowContext(ctx, query, walletID).Scan(&balance); err != nil {
+		return decimal.Zero, fmt.Errorf("failed to sum ledger entries: %w", err)
+	}
+
+	return balance, nil
+}
+
+func (r *LedgerRepository) GetWalletLedgerBalanceBefore(ctx context.Context, walletID uuid.UUID, before time.Time) (decimal.Decimal, error) {
+	var balance decimal.Decimal
+
+	query := `
+		SELECT decimal_sum(CASE WHEN direction = 'credit' THEN amount ELSE -amount END) 
+		FROM ledger_entries 
+		WHERE wallet_id = ? AND created_at < ?`
+
+	if err := r.reader.QueryRowContext(ctx, query, walletID, before).Scan(&balance); err != nil {
+		return decimal.Zero, fmt.Errorf("failed to sum ledger entries: %w", err)
+	}
+
+	return balance, nil
+}
+
+func (r *LedgerRepository) GetWalletLedgerBalanceBetween(ctx context.Context, walletID uuid.UUID, from, to time.Time) (decimal.Decimal, error) {
+	var balance decimal.Decimal
+
+	query := `
+		SELECT decimal_sum(CASE WHEN direction = 'credit' THEN amount ELSE -amount END) 
+		FROM ledger_entries 
+		WHERE wallet_id = ? AND created_at >= ? AND created_at < ?`
+
+	if err := r.reader.QueryRowContext(ctx, query, walletID, from, to).Scan(&balance); err != nil {
+		return decimal.Zero, fmt.Errorf("failed to sum ledger entries: %w", err)
+	}
+
+	return balance, nil
+}
+
+func (r *LedgerRepository) GetCategoryTotals(ctx context.Context, walletID uuid.UUID, from, to time.Time) ([]*models.CategoryTotal, error) {
+	totals := []*models.CategoryTotal{}
+
+	query := `
+		SELECT j.category, COUNT(*) AS transaction_count, 
+			decimal_sum(CASE WHEN e.direction = 'credit' THEN e.amount ELSE 0 END) AS amount_in, 
+			decimal_sum(CASE WHEN e.direction = 'debit' THEN e.amount ELSE 0 END) AS amount_out 
+		FROM ledger_entries e 
+		JOIN journals j ON j.id = e.journal_id 
+		WHERE e.wallet_id = ? AND e.created_at >= ? AND e.created_at < ? 
+		GROUP BY j.category`
+
+	if err := r.reader.SelectContext(ctx, &totals, query, walletID, from, to); err != nil {
+		return nil, fmt.Errorf("failed to total transactions by category: %w", err)
+	}
+
+	return totals, nil
+}
+
+func (r *LedgerRepository) IsJournalReversedWithTx(ctx context.Context, tx *sql.Tx, journalID uuid.UUID) (bool, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM journals WHERE reverses_journal_id = ?`
+	if err := tx.QueryRowContext(ctx, query, journalID).Scan(&count); err != nil {
+		return false, fmt.Errorf("failed to check journal reversal: %w", err)
+	}
+	return count > 0, nil
+}
+
+func (r *LedgerRepository) SumWalletDebitsSinceWithTx(ctx context.Context, tx *sql.Tx, walletID uuid.UUID, journalType string, since time.Time) (decimal.Decimal, error) {
+	var total decimal.Decimal
+
+	query := `
+		SELECT decimal_sum(e.amount)
+		FROM ledger_entries e
+		JOIN journals j ON j.id = e.journal_id
+		WHERE e.wallet_id = ? AND e.direction = 'debit' AND j.type = ? AND e.created_at >= ?`
+
+	if err := tx.QueryRowContext(ctx, query, walletID, journalType, since).Scan(&total); err != nil {
+		return decimal.Zero, fmt.Errorf("failed to sum ledger entries: %w", err)
+	}
+
+	return total, nil
+}
+
+// scanTransaction reads one ledger entry joined with its journal as a wallet transaction
+func scanTransaction(rows *sql.Rows) (*models.Transaction, error) {
+	transaction := &models.Transaction{}
+	var journalType, direction string
+	var journalID uuid.UUID
+	err := rows.Scan(
+		&transaction.ID,
+		&transaction.WalletID,
+		&journalType,
+		&direction,
+		&transaction.Amount,
+		&transaction.ExchangeRate,
+		&transaction.CounterAmount,
+		&transaction.CounterCurrency,
+		&journalID,
+		&transaction.ReversesReferenceID,
+		&transaction.CorrectionReason,
+		&transaction.CorrectedByReferenceID,
+		&transaction.Description,
+		&transaction.Category,
+		&transaction.CreatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan transaction: %w", err)
+	}
+	transaction.Type = models.TransactionType(journalType, direction)
+	transaction.ReferenceID = &journalID
+	return transaction, nil
+}
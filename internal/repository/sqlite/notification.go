@@ -0,0 +1,61 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository"
+)
+
+type NotificationPreferencesRepository struct {
+	db *sqlx.DB
+}
+
+func NewNotificationPreferencesRepository(db *sqlx.DB) *NotificationPreferencesRepository {
+	return &NotificationPreferencesRepository{db: db}
+}
+
+func (r *NotificationPreferencesRepository) GetNotificationPreferences(ctx context.Context, userID uuid.UUID) (*models.NotificationPreferences, error) {
+	query := `
+		SELECT user_id, large_withdrawal_threshold, incoming_transfer, updated_at
+		FROM notification_preferences
+		WHERE user_id = ?`
+
+	var preferences models.NotificationPreferences
+	err := r.db.GetContext(ctx, &preferences, query, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, repository.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notification preferences: %w", err)
+	}
+
+	return &preferences, nil
+}
+
+func (r *NotificationPreferencesRepository) SetNotificationPreferences(ctx context.Context, preferences *models.NotificationPreferences) error {
+	query := `
+		INSERT INTO notification_preferences (user_id, large_withdrawal_threshold, incoming_transfer, updated_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT (user_id) DO UPDATE SET
+			large_withdrawal_threshold = excluded.large_withdrawal_threshold,
+			incoming_transfer = excluded.incoming_transfer,
+			updated_at = excluded.updated_at`
+
+	_, err := r.db.ExecContext(ctx, query,
+		preferences.UserID,
+		preferences.LargeWithdrawalThreshold,
+		preferences.IncomingTransfer,
+		preferences.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to set notification preferences: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,121 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository"
+)
+
+const paymentRequestColumns = `id, requester_wallet_id, payer_wallet_id, amount, currency, description, status,
+		transfer_journal_id, created_at, updated_at`
+
+type PaymentRequestRepository struct {
+	db *sqlx.DB
+}
+
+func NewPaymentRequestRepository(db *sqlx.DB) *PaymentRequestRepository {
+	return &PaymentRequestRepository{db: db}
+}
+
+func (r *PaymentRequestRepository) CreatePaymentRequest(ctx context.Context, request *models.PaymentRequest) error {
+	id, err := repository.NewTimeOrderedID()
+	if err != nil {
+		return fmt.Errorf("failed to generate payment request ID: %w", err)
+	}
+	request.ID = id
+
+	query := `
+		INSERT INTO payment_requests (id, requester_wallet_id, payer_wallet_id, amount, currency, description,
+			status, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	_, err = r.db.ExecContext(ctx, query,
+		request.ID,
+		request.RequesterWalletID,
+		request.PayerWalletID,
+		request.Amount,
+		request.Currency,
+		request.Description,
+		request.Status,
+		request.CreatedAt,
+		request.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create payment request: %w", err)
+	}
+	request.UpdatedAt = request.CreatedAt
+
+	return nil
+}
+
+func (r *PaymentRequestRepository) GetPaymentRequestWithTx(ctx context.Context, tx *sql.Tx, id uuid.UUID) (*models.PaymentRequest, error) {
+	request := &models.PaymentRequest{}
+	query := `SELECT ` + paymentRequestColumns + ` FROM payment_requests WHERE id = ?`
+
+	err := tx.QueryRowContext(ctx, query, id).Scan(
+		&request.ID,
+		&request.RequesterWalletID,
+		&request.PayerWalletID,
+		&request.Amount,
+		&request.Currency,
+		&request.Description,
+		&request.Status,
+		&request.TransferJournalID,
+		&request.CreatedAt,
+		&request.UpdatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("payment request %w", repository.ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to get payment request: %w", err)
+	}
+
+	return request, nil
+}
+
+func (r *PaymentRequestRepository) ListPendingPaymentRequestsByPayer(ctx context.Context, payerWalletID uuid.UUID) ([]*models.PaymentRequest, error) {
+	var requests []*models.PaymentRequest
+	query := `SELECT ` + paymentRequestColumns + ` FROM payment_requests
+		WHERE payer_wallet_id = ? AND status = ?
+		ORDER BY created_at DESC, id DESC`
+
+	if err := r.db.SelectContext(ctx, &requests, query, payerWalletID, models.PaymentRequestStatusPending); err != nil {
+		return nil, fmt.Errorf("failed to list payment requests: %w", err)
+	}
+
+	return requests, nil
+}
+
+func (r *PaymentRequestRepository) UpdatePaymentRequestWithTx(ctx context.Context, tx *sql.Tx, request *models.PaymentRequest) error {
+	query := `
+		UPDATE payment_requests
+		SET status = ?, transfer_journal_id = ?, updated_at = ?
+		WHERE id = ?`
+
+	result, err := tx.ExecContext(ctx, query,
+		request.Status,
+		request.TransferJournalID,
+		request.UpdatedAt,
+		request.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update payment request: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("payment request %w", repository.ErrNotFound)
+	}
+
+	return nil
+}
@@ -0,0 +1,169 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository"
+)
+
+const payoutColumns = `id, wallet_id, amount, currency, account_name, account_number, bank_code, status,
+		provider_reference, failure_reason, journal_id, refund_journal_id, created_at, updated_at`
+
+type PayoutRepository struct {
+	db *sqlx.DB
+}
+
+func NewPayoutRepository(db *sqlx.DB) *PayoutRepository {
+	return &PayoutRepository{db: db}
+}
+
+func (r *PayoutRepository) CreatePayoutWithTx(ctx context.Context, tx *sql.Tx, payout *models.Payout) error {
+	id, err := repository.NewTimeOrderedID()
+	if err != nil {
+		return fmt.Errorf("failed to generate payout ID: %w", err)
+	}
+	payout.ID = id
+
+	query := `
+		INSERT INTO payouts (` + payoutColumns + `)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	_, err = tx.ExecContext(ctx, query,
+		payout.ID,
+		payout.WalletID,
+		payout.Amount,
+		payout.Currency,
+		payout.AccountName,
+		payout.AccountNumber,
+		payout.BankCode,
+		payout.Status,
+		payout.ProviderReference,
+		payout.FailureReason,
+		payout.JournalID,
+		payout.RefundJournalID,
+		payout.CreatedAt,
+		payout.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create payout: %w", err)
+	}
+
+	return nil
+}
+
+func (r *PayoutRepository) GetPayout(ctx context.Context, id uuid.UUID) (*models.Payout, error) {
+	return scanPayout(r.db.QueryRowContext(ctx, `SELECT `+payoutColumns+` FROM payouts WHERE id = ?`, id))
+}
+
+func (r *PayoutRepository) GetPayoutByJournalID(ctx context.Context, journalID uuid.UUID) (*models.Payout, error) {
+	return scanPayout(r.db.QueryRowContext(ctx, `SELECT `+payoutColumns+` FROM payouts WHERE journal_id = ?`, journalID))
+}
+
+func (r *PayoutRepository) GetPayoutByJournalIDWithTx(ctx context.Context, tx *sql.Tx, journalID uuid.UUID) (*models.Payout, error) {
+	return scanPayout(tx.QueryRowContext(ctx, `SELECT `+payoutColumns+` FROM payouts WHERE journal_id = ?`, journalID))
+}
+
+func (r *PayoutRepository) GetPayoutWithTx(ctx context.Context, tx *sql.Tx, id uuid.UUID) (*models.Payout, error) {
+	return scanPayout(tx.QueryRowContext(ctx, `SELECT `+payoutColumns+` FROM payouts WHERE id = ?`, id))
+}
+
+func (r *PayoutRepository) UpdatePayoutWithTx(ctx context.Context, tx *sql.Tx, payout *models.Payout) error {
+	query := `
+		UPDATE payouts
+		SET status = ?, provider_reference = ?, failure_reason = ?, refund_journal_id = ?, updated_at = ?
+		WHERE id = ?`
+
+	result, err := tx.ExecContext(ctx, query,
+		payout.Status, payout.ProviderReference, payout.FailureReason, payout.RefundJournalID, payout.UpdatedAt, payout.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update payout: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("payout %w", repository.ErrNotFound)
+	}
+
+	return nil
+}
+
+func (r *PayoutRepository) ListPayouts(ctx context.Context, filter repository.PayoutFilter) ([]*models.Payout, error) {
+	var conditions []string
+	var args []interface{}
+	where := func(condition string, arg interface{}) {
+		conditions = append(conditions, condition)
+		args = append(args, arg)
+	}
+	if filter.WalletID != uuid.Nil {
+		where("wallet_id = ?", filter.WalletID)
+	}
+	if filter.Status != "" {
+		where("status = ?", filter.Status)
+	}
+
+	query := `SELECT ` + payoutColumns + ` FROM payouts`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY created_at DESC, id DESC LIMIT ? OFFSET ?"
+	args = append(args, filter.Limit, filter.Offset)
+
+	payouts := []*models.Payout{}
+	if err := r.db.SelectContext(ctx, &payouts, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to list payouts: %w", err)
+	}
+	return payouts, nil
+}
+
+func (r *PayoutRepository) ListPayoutsInStatus(ctx context.Context, status string, after uuid.UUID, limit int) ([]*models.Payout, error) {
+	query := `SELECT ` + payoutColumns + `
+		FROM payouts
+		WHERE status = ? AND id > ?
+		ORDER BY id
+		LIMIT ?`
+
+	payouts := []*models.Payout{}
+	if err := r.db.SelectContext(ctx, &payouts, query, status, after, limit); err != nil {
+		return nil, fmt.Errorf("failed to list payouts: %w", err)
+	}
+	return payouts, nil
+}
+
+func scanPayout(row *sql.Row) (*models.Payout, error) {
+	payout := &models.Payout{}
+	err := row.Scan(
+		&payout.ID,
+		&payout.WalletID,
+		&payout.Amount,
+		&payout.Currency,
+		&payout.AccountName,
+		&payout.AccountNumber,
+		&payout.BankCode,
+		&payout.Status,
+		&payout.ProviderReference,
+		&payout.FailureReason,
+		&payout.JournalID,
+		&payout.RefundJournalID,
+		&payout.CreatedAt,
+		&payout.UpdatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("payout %w", repository.ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to get payout: %w", err)
+	}
+
+	return payout, nil
+}
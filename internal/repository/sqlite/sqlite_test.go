@@ -0,0 +1,388 @@
+package sqlite
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shanwije/wallet-app/db/migrations"
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository"
+	"github.com/shanwije/wallet-app/pkg/db"
+	"github.com/shanwije/wallet-app/pkg/money"
+	"github.com/shanwije/wallet-app/pkg/pagination"
+)
+
+// openDB returns a migrated database in a file of its own
+func openDB(t *testing.T) *sqlx.DB {
+	t.Helper()
+
+	conn, err := db.New(db.Config{Driver: db.DriverSQLite, Name: filepath.Join(t.TempDir(), "wallet.db")})
+	require.NoError(t, err)
+	fsys, err := migrations.ForDriver(db.DriverSQLite)
+	require.NoError(t, err)
+	migrator, err := db.NewMigrator(conn.DB, db.DriverSQLite, fsys)
+	require.NoError(t, err)
+	t.Cleanup(func() { migrator.Close() })
+
+	_, err = migrator.Up(context.Background())
+	require.NoError(t, err)
+	return conn.DB
+}
+
+func createWallet(t *testing.T, conn *sqlx.DB) *models.Wallet {
+	t.Helper()
+
+	ctx := context.Background()
+	user, err := NewUserRepository(conn).CreateUser(ctx, "Test User", models.Contact{})
+	require.NoError(t, err)
+	wallet, err := NewWalletRepository(conn).CreateWallet(ctx, user.ID)
+	require.NoError(t, err)
+	return wallet
+}
+
+// deposit posts a deposit journal of amount into the wallet at the given time
+func deposit(t *testing.T, conn *sqlx.DB, walletID uuid.UUID, amount string, at time.Time) {
+	t.Helper()
+
+	journal := &models.Journal{
+		Type: models.JournalTypeDeposit,
+		Entries: []*models.LedgerEntry{
+			{Direction: models.EntryDirectionDebit, Amount: decimal.RequireFromString(amount), Currency: money.DefaultCurrency},
+			{WalletID: &walletID, Direction: models.EntryDirectionCredit, Amount: decimal.RequireFromString(amount), Currency: money.DefaultCurrency},
+		},
+		CreatedAt: at,
+	}
+
+	ctx := context.Background()
+	tx, err := conn.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	require.NoError(t, NewLedgerRepository(conn).CreateJournalWithTx(ctx, tx, journal))
+	require.NoError(t, tx.Commit())
+}
+
+func TestLedgerBalancesAreSummedExactly(t *testing.T) {
+	conn := openDB(t)
+	wallet := createWallet(t, conn)
+	ledger := NewLedgerRepository(conn)
+	ctx := context.Background()
+
+	start := time.Date(2024, 7, 1, 9, 0, 0, 0, time.UTC)
+	for i := 0; i < 10; i++ {
+		deposit(t, conn, wallet.ID, "0.10", start.Add(time.Duration(i)*time.Minute))
+	}
+	deposit(t, conn, wallet.ID, "0.20", start.Add(time.Hour))
+
+	balance, err := ledger.GetWalletLedgerBalance(ctx, wallet.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "1.2", balance.String())
+
+	// Times in other zones are compared as the same instants
+	before, err := ledger.GetWalletLedgerBalanceBefore(ctx, wallet.ID, start.Add(5*time.Minute).In(time.FixedZone("UTC+5", 5*60*60)))
+	require.NoError(t, err)
+	assert.Equal(t, "0.5", before.String())
+
+	between, err := ledger.GetWalletLedgerBalanceBetween(ctx, wallet.ID, start.Add(30*time.Minute), start.Add(2*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, "0.2", between.String())
+}
+
+func TestListTransactionsAfterPagesByID(t *testing.T) {
+	conn := openDB(t)
+	wallet := createWallet(t, conn)
+	ledger := NewLedgerRepository(conn)
+	ctx := context.Background()
+
+	start := time.Date(2024, 7, 1, 9, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		deposit(t, conn, wallet.ID, "1.00", start.Add(time.Duration(i)*time.Minute))
+	}
+
+	// Only the wallets' side of each journal is listed
+	first, err := ledger.ListTransactionsAfter(ctx, uuid.Nil, start.Add(time.Hour), 2)
+	require.NoError(t, err)
+	require.Len(t, first, 2)
+	assert.Equal(t, wallet.ID, first[0].WalletID)
+	rest, err := ledger.ListTransactionsAfter(ctx, first[1].ID, start.Add(time.Hour), 2)
+	require.NoError(t, err)
+	require.Len(t, rest, 1)
+	assert.True(t, start.Add(2*time.Minute).Equal(rest[0].CreatedAt))
+
+	// Entries created at or after until are left out
+	early, err := ledger.ListTransactionsAfter(ctx, uuid.Nil, start.Add(time.Minute), 10)
+	require.NoError(t, err)
+	assert.Len(t, early, 1)
+}
+
+func TestSnapshotsCarryTheLastSnapshotForward(t *testing.T) {
+	conn := openDB(t)
+	wallet := createWallet(t, conn)
+	snapshots := NewBalanceSnapshotRepository(conn)
+	ctx := context.Background()
+
+	latest, err := snapshots.GetLatestSnapshotTime(ctx)
+	require.NoError(t, err)
+	assert.True(t, latest.IsZero())
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	deposit(t, conn, wallet.ID, "10.10", today.Add(time.Hour))
+	firstDay := today.Add(24 * time.Hour)
+	created, err := snapshots.CreateSnapshots(ctx, firstDay)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), created)
+
+	// A second run at the same time writes nothing
+	created, err = snapshots.CreateSnapshots(ctx, firstDay)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), created)
+
+	deposit(t, conn, wallet.ID, "0.20", firstDay.Add(time.Hour))
+	secondDay := firstDay.Add(24 * time.Hour)
+	_, err = snapshots.CreateSnapshots(ctx, secondDay)
+	require.NoError(t, err)
+
+	latest, err = snapshots.GetLatestSnapshotTime(ctx)
+	require.NoError(t, err)
+	assert.True(t, secondDay.Equal(latest))
+
+	snapshot, err := snapshots.GetLatestSnapshot(ctx, wallet.ID, secondDay)
+	require.NoError(t, err)
+	assert.Equal(t, "10.3", snapshot.Balance.String())
+}
+
+func TestReconciliationFindsWalletsOutOfBalance(t *testing.T) {
+	conn := openDB(t)
+	balanced := createWallet(t, conn)
+	drifted := createWallet(t, conn)
+	wallets := NewWalletRepository(conn)
+	ctx := context.Background()
+
+	now := time.Now().UTC()
+	deposit(t, conn, balanced.ID, "0.30", now)
+	deposit(t, conn, drifted.ID, "0.30", now)
+	require.NoError(t, wallets.UpdateBalance(ctx, balanced.ID, decimal.RequireFromString("0.30"), 0))
+	require.NoError(t, wallets.UpdateBalance(ctx, drifted.ID, decimal.RequireFromString("0.40"), 0))
+
+	discrepancies, err := NewReconciliationRepository(conn).FindBalanceDiscrepancies(ctx)
+	require.NoError(t, err)
+	require.Len(t, discrepancies, 1)
+	assert.Equal(t, drifted.ID, discrepancies[0].WalletID)
+	assert.Equal(t, "0.1", discrepancies[0].Difference.String())
+}
+
+func TestWalletVersionConflict(t *testing.T) {
+	conn := openDB(t)
+	wallet := createWallet(t, conn)
+	wallets := NewWalletRepository(conn)
+	ctx := context.Background()
+
+	require.NoError(t, wallets.UpdateBalance(ctx, wallet.ID, decimal.NewFromInt(5), 0))
+	// Writing the same balance again at a stale version is still a conflict
+	err := wallets.UpdateBalance(ctx, wallet.ID, decimal.NewFromInt(5), 0)
+	assert.ErrorIs(t, err, repository.ErrVersionConflict)
+}
+
+func TestSearchWalletsPagesByKeyset(t *testing.T) {
+	conn := openDB(t)
+	wallets := NewWalletRepository(conn)
+	ctx := context.Background()
+
+	// Five wallets whose balances tie in pairs, so pages break ties by ID
+	var created []*models.Wallet
+	for _, balance := range []int64{30, 10, 20, 10, 30} {
+		wallet := createWallet(t, conn)
+		require.NoError(t, wallets.UpdateBalance(ctx, wallet.ID, decimal.NewFromInt(balance), 0))
+		created = append(created, wallet)
+	}
+
+	search := func(filter repository.WalletFilter) []*models.Wallet {
+		page, err := wallets.SearchWallets(ctx, filter)
+		require.NoError(t, err)
+		return page
+	}
+	sort := pagination.Sort{Field: repository.WalletSortBalance, Descending: true}
+	var balances []string
+	var cursor *repository.WalletCursor
+	for {
+		page := search(repository.WalletFilter{Sort: sort, After: cursor, Limit: 2})
+		if len(page) == 0 {
+			break
+		}
+		for _, wallet := range page {
+			balances = append(balances, wallet.Balance.String())
+		}
+		cursor = repository.NewWalletCursor(page[len(page)-1])
+	}
+	assert.Equal(t, []string{"30", "30", "20", "10", "10"}, balances)
+
+	// Creation time pages the same way, and bounds the search
+	page := search(repository.WalletFilter{After: repository.NewWalletCursor(created[2]), Limit: 10})
+	require.Len(t, page, 2)
+	assert.Equal(t, created[3].ID, page[0].ID)
+	page = search(repository.WalletFilter{CreatedFrom: created[1].CreatedAt, CreatedTo: created[3].CreatedAt, Limit: 10})
+	require.Len(t, page, 2)
+	assert.Equal(t, created[1].ID, page[0].ID)
+	assert.Equal(t, created[2].ID, page[1].ID)
+}
+
+func TestSearchUsersMatchesPartOfTheName(t *testing.T) {
+	conn := openDB(t)
+	users := NewUserRepository(conn)
+	ctx := context.Background()
+
+	for _, name := range []string{"Malice Cooper", "Alice", "Bob", "50% Alice"} {
+		_, err := users.CreateUser(ctx, name, models.Contact{})
+		require.NoError(t, err)
+	}
+
+	names := func(query string) []string {
+		found, err := users.SearchUsers(ctx, query, 10, 0)
+		require.NoError(t, err)
+		var names []string
+		for _, user := range found {
+			names = append(names, user.Name)
+		}
+		return names
+	}
+	// Earlier matches, then shorter names, come first
+	assert.Equal(t, []string{"Alice", "Malice Cooper", "50% Alice"}, names("alice"))
+	// Wildcards in the query match themselves
+	assert.Equal(t, []string{"50% Alice"}, names("0%"))
+	assert.Empty(t, names("_"))
+}
+
+func TestUserContactBelongsToOneUser(t *testing.T) {
+	conn := openDB(t)
+	users := NewUserRepository(conn)
+	ctx := context.Background()
+	email, phone := "alice@example.com", "+447700900123"
+
+	alice, err := users.CreateUser(ctx, "Alice", models.Contact{Email: &email, Phone: &phone})
+	require.NoError(t, err)
+	_, err = users.CreateUser(ctx, "Mallory", models.Contact{Email: &email})
+	assert.ErrorIs(t, err, repository.ErrDuplicateEmail)
+	bob, err := users.CreateUser(ctx, "Bob", models.Contact{})
+	require.NoError(t, err)
+
+	bob.Phone = &phone
+	assert.ErrorIs(t, users.UpdateUser(ctx, bob), repository.ErrDuplicatePhone)
+	assert.ErrorIs(t, users.UpdateUser(ctx, bob), repository.ErrDuplicate)
+
+	// An update from a stale read is refused
+	stale := *alice
+	alice.Name = "Alice Smith"
+	require.NoError(t, users.UpdateUser(ctx, alice))
+	assert.Equal(t, int64(1), alice.Version)
+	assert.ErrorIs(t, users.UpdateUser(ctx, &stale), repository.ErrVersionConflict)
+
+	// Deleting a user frees their contact details
+	tx, err := conn.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	require.NoError(t, users.SoftDeleteUserWithTx(ctx, tx, alice.ID, time.Now()))
+	require.NoError(t, tx.Commit())
+	require.NoError(t, users.UpdateUser(ctx, bob))
+	found, err := users.GetUserByID(ctx, bob.ID)
+	require.NoError(t, err)
+	assert.Equal(t, phone, *found.Phone)
+	assert.Nil(t, found.Email)
+}
+
+func TestNotificationPreferencesAreReplaced(t *testing.T) {
+	conn := openDB(t)
+	preferences := NewNotificationPreferencesRepository(conn)
+	ctx := context.Background()
+	user, err := NewUserRepository(conn).CreateUser(ctx, "Alice", models.Contact{})
+	require.NoError(t, err)
+
+	_, err = preferences.GetNotificationPreferences(ctx, user.ID)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+
+	threshold := decimal.RequireFromString("250.50")
+	require.NoError(t, preferences.SetNotificationPreferences(ctx, &models.NotificationPreferences{
+		UserID: user.ID, LargeWithdrawalThreshold: &threshold, IncomingTransfer: true, UpdatedAt: time.Now(),
+	}))
+	stored, err := preferences.GetNotificationPreferences(ctx, user.ID)
+	require.NoError(t, err)
+	require.NotNil(t, stored.LargeWithdrawalThreshold)
+	assert.True(t, threshold.Equal(*stored.LargeWithdrawalThreshold))
+
+	require.NoError(t, preferences.SetNotificationPreferences(ctx, &models.NotificationPreferences{
+		UserID: user.ID, UpdatedAt: time.Now(),
+	}))
+	stored, err = preferences.GetNotificationPreferences(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Nil(t, stored.LargeWithdrawalThreshold)
+	assert.False(t, stored.IncomingTransfer)
+}
+
+func TestDuplicateIdempotencyKey(t *testing.T) {
+	conn := openDB(t)
+	keys := NewIdempotencyKeyRepository(conn)
+	ctx := context.Background()
+
+	key := &models.IdempotencyKey{RequestKey: "key", Fingerprint: "fingerprint", CreatedAt: time.Now()}
+	require.NoError(t, keys.ReserveIdempotencyKey(ctx, key))
+	err := keys.ReserveIdempotencyKey(ctx, key)
+	assert.ErrorIs(t, err, repository.ErrDuplicate)
+}
+
+func TestUpsertsReplaceExistingRows(t *testing.T) {
+	conn := openDB(t)
+	wallet := createWallet(t, conn)
+	ctx := context.Background()
+
+	flags := NewFeatureFlagRepository(conn)
+	require.NoError(t, flags.SetFlag(ctx, "transfers", false))
+	require.NoError(t, flags.SetFlag(ctx, "transfers", true))
+	enabled, found, err := flags.GetFlag(ctx, "transfers")
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.True(t, enabled)
+
+	limits := NewWalletLimitsRepository(conn)
+	overdraft := decimal.NewFromInt(50)
+	minimum := decimal.NewFromInt(10)
+	require.NoError(t, limits.SetWalletLimits(ctx, &models.WalletLimits{WalletID: wallet.ID, OverdraftLimit: &overdraft, UpdatedAt: time.Now()}))
+	require.NoError(t, limits.SetWalletLimits(ctx, &models.WalletLimits{WalletID: wallet.ID, MinimumBalance: &minimum, UpdatedAt: time.Now()}))
+
+	stored, err := limits.GetWalletLimits(ctx, wallet.ID)
+	require.NoError(t, err)
+	assert.Nil(t, stored.OverdraftLimit)
+	require.NotNil(t, stored.MinimumBalance)
+	assert.True(t, minimum.Equal(*stored.MinimumBalance))
+}
+
+func TestAuditLogIsAppendOnly(t *testing.T) {
+	conn := openDB(t)
+	ctx := context.Background()
+
+	entry := &models.AuditEntry{ActorType: "admin", ActorID: "ops", Endpoint: "POST /admin", Path: "/admin", PayloadHash: "hash", StatusCode: 200, CreatedAt: time.Now()}
+	require.NoError(t, NewAuditRepository(conn).CreateAuditEntry(ctx, entry))
+
+	_, err := conn.ExecContext(ctx, `DELETE FROM audit_log`)
+	assert.ErrorContains(t, err, "append-only")
+}
+
+func TestLedgerIsAppendOnly(t *testing.T) {
+	conn := openDB(t)
+	ctx := context.Background()
+	wallet := createWallet(t, conn)
+	deposit(t, conn, wallet.ID, "10.00", time.Now())
+
+	for _, statement := range []string{
+		`UPDATE journals SET description = 'edited'`,
+		`DELETE FROM journals`,
+		`UPDATE ledger_entries SET amount = '1.00'`,
+		`DELETE FROM ledger_entries`,
+	} {
+		_, err := conn.ExecContext(ctx, statement)
+		assert.ErrorContains(t, err, "append-only", statement)
+	}
+}
@@ -0,0 +1,90 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository"
+)
+
+type TransferQuoteRepository struct {
+	db *sqlx.DB
+}
+
+func NewTransferQuoteRepository(db *sqlx.DB) *TransferQuoteRepository {
+	return &TransferQuoteRepository{db: db}
+}
+
+func (r *TransferQuoteRepository) CreateTransferQuote(ctx context.Context, quote *models.TransferQuote) error {
+	id, err := repository.NewTimeOrderedID()
+	if err != nil {
+		return fmt.Errorf("failed to generate transfer quote ID: %w", err)
+	}
+	quote.ID = id
+
+	query := `
+		INSERT INTO transfer_quotes (id, from_wallet_id, to_wallet_id, amount, currency, fee, debited_amount,
+			credited_amount, credited_currency, exchange_rate, expires_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	_, err = r.db.ExecContext(ctx, query,
+		quote.ID,
+		quote.FromWalletID,
+		quote.ToWalletID,
+		quote.Amount,
+		quote.Currency,
+		quote.Fee,
+		quote.DebitedAmount,
+		quote.CreditedAmount,
+		quote.CreditedCurrency,
+		quote.ExchangeRate,
+		quote.ExpiresAt,
+		quote.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create transfer quote: %w", err)
+	}
+
+	return nil
+}
+
+func (r *TransferQuoteRepository) GetTransferQuote(ctx context.Context, id uuid.UUID) (*models.TransferQuote, error) {
+	quote := &models.TransferQuote{}
+	query := `
+		SELECT id, from_wallet_id, to_wallet_id, amount, currency, fee, debited_amount, credited_amount,
+			credited_currency, exchange_rate, transfer_journal_id, expires_at, created_at
+		FROM transfer_quotes WHERE id = ?`
+
+	if err := r.db.GetContext(ctx, quote, query, id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("transfer quote %w", repository.ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to get transfer quote: %w", err)
+	}
+
+	return quote, nil
+}
+
+func (r *TransferQuoteRepository) UseTransferQuoteWithTx(ctx context.Context, tx *sql.Tx, id, journalID uuid.UUID) error {
+	query := `UPDATE transfer_quotes SET transfer_journal_id = ? WHERE id = ? AND transfer_journal_id IS NULL`
+
+	result, err := tx.ExecContext(ctx, query, journalID, id)
+	if err != nil {
+		return fmt.Errorf("failed to use transfer quote: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("unused transfer quote %w", repository.ErrNotFound)
+	}
+
+	return nil
+}
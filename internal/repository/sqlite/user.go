@@ -0,0 +1,189 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository"
+	"github.com/shanwije/wallet-app/pkg/money"
+	"github.com/shopspring/decimal"
+)
+
+type UserRepository struct {
+	db *sqlx.DB
+}
+
+func NewUserRepository(db *sqlx.DB) *UserRepository {
+	return &UserRepository{db: db}
+}
+
+func (r *UserRepository) CreateUser(ctx context.Context, name string, contact models.Contact) (*models.User, error) {
+	// Timestamps are assigned here in UTC, which keeps SQLite's text times in order
+	user := &models.User{
+		ID:        uuid.New(),
+		Name:      name,
+		Contact:   contact,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	query := `INSERT INTO users (id, name, email, phone, created_at) VALUES (?, ?, ?, ?, ?)`
+
+	_, err := r.db.ExecContext(ctx, query, user.ID, user.Name, user.Email, user.Phone, user.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create user: %w", contactError(err))
+	}
+
+	return user, nil
+}
+
+func (r *UserRepository) CreateUserWithTx(ctx context.Context, tx *sql.Tx, name string, contact models.Contact) (*models.User, error) {
+	// Timestamps are assigned here in UTC, which keeps SQLite's text times in order
+	user := &models.User{
+		ID:        uuid.New(),
+		Name:      name,
+		Contact:   contact,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	query := `INSERT INTO users (id, name, email, phone, created_at) VALUES (?, ?, ?, ?, ?)`
+
+	_, err := tx.ExecContext(ctx, query, user.ID, user.Name, user.Email, user.Phone, user.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create user: %w", contactError(err))
+	}
+
+	return user, nil
+}
+
+func (r *UserRepository) GetUserByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	user := &models.User{}
+	query := `SELECT id, name, email, phone, version, created_at FROM users WHERE id = ? AND deleted_at IS NULL`
+
+	err := r.db.GetContext(ctx, user, query, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("user %w", repository.ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	return user, nil
+}
+
+func (r *UserRepository) GetUserWithWallet(ctx context.Context, id uuid.UUID) (*models.UserWithWallet, error) {
+	var userWithWallet models.UserWithWallet
+	query := `
+		SELECT 
+			u.id, u.name, u.email, u.phone, u.version, u.created_at,
+			w.id AS wallet_id, w.user_id AS wallet_user_id, w.balance, w.held_balance, w.currency, w.status, w.created_at AS wallet_created_at
+		FROM users u
+		LEFT JOIN wallets w ON u.id = w.user_id
+		WHERE u.id = ? AND u.deleted_at IS NULL`
+
+	row := r.db.QueryRowContext(ctx, query, id)
+
+	var walletID uuid.NullUUID
+	var walletUserID uuid.NullUUID
+	var balance decimal.NullDecimal
+	var heldBalance decimal.NullDecimal
+	var currency sql.NullString
+	var status sql.NullString
+	var walletCreatedAt sql.NullTime
+
+	err := row.Scan(
+		&userWithWallet.ID, &userWithWallet.Name, &userWithWallet.Email, &userWithWallet.Phone, &userWithWallet.Version, &userWithWallet.CreatedAt,
+		&walletID, &walletUserID, &balance, &heldBalance, &currency, &status, &walletCreatedAt,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("user %w", repository.ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to get user with wallet: %w", err)
+	}
+
+	// If wallet exists, populate it
+	if walletID.Valid {
+		userWithWallet.Wallet = models.Wallet{
+			ID:          walletID.UUID,
+			UserID:      walletUserID.UUID,
+			Balance:     balance.Decimal,
+			HeldBalance: heldBalance.Decimal,
+			Currency:    money.Currency(currency.String),
+			Status:      status.String,
+			CreatedAt:   walletCreatedAt.Time,
+		}
+	}
+
+	return &userWithWallet, nil
+}
+
+// UpdateUser writes the user's name and contact details at the version they were read
+// at, advancing it
+func (r *UserRepository) UpdateUser(ctx context.Context, user *models.User) error {
+	query := `UPDATE users SET name = ?, email = ?, phone = ?, version = version + 1
+		WHERE id = ? AND version = ? AND deleted_at IS NULL`
+
+	result, err := r.db.ExecContext(ctx, query, user.Name, user.Email, user.Phone, user.ID, user.Version)
+	if err != nil {
+		return fmt.Errorf("failed to update user: %w", contactError(err))
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("user %s: %w", user.ID, repository.ErrVersionConflict)
+	}
+
+	user.Version++
+	return nil
+}
+
+// SoftDeleteUserWithTx marks the user deleted, wrapping ErrNotFound if they are
+// missing or already deleted
+func (r *UserRepository) SoftDeleteUserWithTx(ctx context.Context, tx *sql.Tx, id uuid.UUID, deletedAt time.Time) error {
+	result, err := tx.ExecContext(ctx, `UPDATE users SET deleted_at = ?, email = NULL, phone = NULL WHERE id = ? AND deleted_at IS NULL`, deletedAt, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("user %w", repository.ErrNotFound)
+	}
+
+	return nil
+}
+
+// ListUsers pages through the users that are not deleted, oldest first
+func (r *UserRepository) ListUsers(ctx context.Context, limit, offset int) ([]*models.User, error) {
+	users := []*models.User{}
+	if err := r.db.SelectContext(ctx, &users, `SELECT id, name, email, phone, version, created_at FROM users WHERE deleted_at IS NULL ORDER BY created_at, id LIMIT ? OFFSET ?`, limit, offset); err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+	return users, nil
+}
+
+// SearchUsers pages through the users that are not deleted whose name contains query,
+// ignoring case. Names where it appears earlier, then shorter names, come first.
+func (r *UserRepository) SearchUsers(ctx context.Context, query string, limit, offset int) ([]*models.User, error) {
+	users := []*models.User{}
+	err := r.db.SelectContext(ctx, &users, `SELECT id, name, email, phone, version, created_at FROM users
+		WHERE deleted_at IS NULL AND name LIKE ? ESCAPE '\'
+		ORDER BY INSTR(LOWER(name), LOWER(?)), LENGTH(name), created_at, id
+		LIMIT ? OFFSET ?`, repository.ContainsPattern(query), query, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search users: %w", err)
+	}
+	return users, nil
+}
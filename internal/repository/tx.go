@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"github.com/shanwije/wallet-app/pkg/logger"
+)
+
+// TxManager runs units of work in a database transaction
+type TxManager interface {
+	// WithinTransaction runs fn in a transaction, which it commits if fn succeeds and
+	// rolls back otherwise. The transaction travels in the context fn is given, and
+	// TxFromContext recovers it for the repositories' *WithTx methods. Called with a
+	// context that already carries a transaction, fn joins that transaction instead,
+	// and the outermost call commits or rolls back.
+	WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// TxBeginner starts database transactions. The wallet and outbox repositories are
+// TxBeginners.
+type TxBeginner interface {
+	BeginTx(ctx context.Context) (*sql.Tx, error)
+}
+
+type txKey struct{}
+
+// WithTx returns a copy of ctx carrying tx
+func WithTx(ctx context.Context, tx *sql.Tx) context.Context {
+	return context.WithValue(ctx, txKey{}, tx)
+}
+
+// TxFromContext returns the transaction ctx carries, or nil when it carries none
+func TxFromContext(ctx context.Context) *sql.Tx {
+	tx, _ := ctx.Value(txKey{}).(*sql.Tx)
+	return tx
+}
+
+type txManager struct {
+	db TxBeginner
+}
+
+// NewTxManager returns a TxManager whose transactions db begins
+func NewTxManager(db TxBeginner) TxManager {
+	return txManager{db: db}
+}
+
+func (m txManager) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return fn(ctx)
+	}
+
+	tx, err := m.db.BeginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	// Test doubles begin no real transaction, and there is nothing to end
+	if tx == nil {
+		return fn(WithTx(ctx, tx))
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	if err := fn(WithTx(ctx, tx)); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			logger.FromContext(ctx).Error("Transaction rollback failed", zap.Error(rbErr), zap.NamedError("cause", err))
+		}
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shanwije/wallet-app/pkg/db"
+)
+
+// sqlBeginner begins transactions on a database the way the repositories do
+type sqlBeginner struct {
+	db *sql.DB
+}
+
+func (b sqlBeginner) BeginTx(ctx context.Context) (*sql.Tx, error) {
+	return b.db.BeginTx(ctx, nil)
+}
+
+func openCounter(t *testing.T) (*sql.DB, TxManager) {
+	t.Helper()
+
+	conn, err := db.New(db.Config{Driver: db.DriverSQLite, Name: filepath.Join(t.TempDir(), "tx.db")})
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	_, err = conn.Exec(`CREATE TABLE counter (n INTEGER NOT NULL); INSERT INTO counter (n) VALUES (0)`)
+	require.NoError(t, err)
+	return conn.DB.DB, NewTxManager(sqlBeginner{db: conn.DB.DB})
+}
+
+func increment(ctx context.Context) error {
+	_, err := TxFromContext(ctx).ExecContext(ctx, `UPDATE counter SET n = n + 1`)
+	return err
+}
+
+func count(t *testing.T, conn *sql.DB) int {
+	t.Helper()
+
+	var n int
+	require.NoError(t, conn.QueryRow(`SELECT n FROM counter`).Scan(&n))
+	return n
+}
+
+func TestWithinTransactionCommitsOnSuccess(t *testing.T) {
+	conn, tx := openCounter(t)
+
+	require.NoError(t, tx.WithinTransaction(context.Background(), increment))
+	assert.Equal(t, 1, count(t, conn))
+}
+
+func TestWithinTransactionRollsBackOnError(t *testing.T) {
+	conn, tx := openCounter(t)
+
+	err := tx.WithinTransaction(context.Background(), func(ctx context.Context) error {
+		require.NoError(t, increment(ctx))
+		return assert.AnError
+	})
+	assert.ErrorIs(t, err, assert.AnError)
+	assert.Equal(t, 0, count(t, conn))
+
+	assert.Panics(t, func() {
+		tx.WithinTransaction(context.Background(), func(ctx context.Context) error {
+			require.NoError(t, increment(ctx))
+			panic("boom")
+		})
+	})
+	assert.Equal(t, 0, count(t, conn))
+}
+
+func TestNestedTransactionsJoinTheOutermost(t *testing.T) {
+	conn, tx := openCounter(t)
+
+	err := tx.WithinTransaction(context.Background(), func(ctx context.Context) error {
+		outer := TxFromContext(ctx)
+		require.NoError(t, tx.WithinTransaction(ctx, func(ctx context.Context) error {
+			assert.Same(t, outer, TxFromContext(ctx))
+			return increment(ctx)
+		}))
+		return assert.AnError
+	})
+	assert.ErrorIs(t, err, assert.AnError)
+	// The inner success is undone with the outer transaction
+	assert.Equal(t, 0, count(t, conn))
+}
+
+func TestTxFromContextWithoutTransaction(t *testing.T) {
+	assert.Nil(t, TxFromContext(context.Background()))
+}
@@ -0,0 +1,82 @@
+// Package risk screens withdrawals and transfers before they run. An Engine decides
+// whether to allow an operation, let it through flagged for review, or block it.
+package risk
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/pkg/money"
+)
+
+// Operation is a withdrawal or transfer about to run
+type Operation struct {
+	// Type is the journal type the operation will be posted as: withdraw or transfer
+	Type     string
+	WalletID uuid.UUID
+	// ToWalletID is the recipient of a transfer and nil for a withdrawal
+	ToWalletID *uuid.UUID
+	Amount     money.Money
+	At         time.Time
+}
+
+// Decision is an engine's verdict on an operation, with the reasons of every rule
+// that did not simply allow it
+type Decision struct {
+	Action  string // allow, flag, block
+	Reasons []string
+}
+
+// Engine assesses operations. Implementations must be safe for concurrent use.
+type Engine interface {
+	Assess(ctx context.Context, op Operation) (Decision, error)
+}
+
+// Rule is one check an engine runs. It returns the action it calls for and, unless
+// that is allow, a reason a reviewer can read.
+type Rule interface {
+	Evaluate(ctx context.Context, op Operation) (action, reason string, err error)
+}
+
+// History is the wallet activity the built-in rules look at
+type History interface {
+	// CountWalletDebitsSince counts the wallet's outgoing journals of the type since the time
+	CountWalletDebitsSince(ctx context.Context, walletID uuid.UUID, journalType string, since time.Time) (int, error)
+	// AverageWalletDebitSince averages the wallet's outgoing journals of the type since
+	// the time, and reports how many there were
+	AverageWalletDebitSince(ctx context.Context, walletID uuid.UUID, journalType string, since time.Time) (decimal.Decimal, int, error)
+	// HasTransferredTo reports whether the wallet has ever transferred to the recipient
+	HasTransferredTo(ctx context.Context, walletID, toWalletID uuid.UUID) (bool, error)
+}
+
+// RuleEngine runs every rule and takes the most severe action any of them calls for
+type RuleEngine struct {
+	rules []Rule
+}
+
+// NewEngine returns an engine running the rules in order
+func NewEngine(rules ...Rule) *RuleEngine {
+	return &RuleEngine{rules: rules}
+}
+
+func (e *RuleEngine) Assess(ctx context.Context, op Operation) (Decision, error) {
+	decision := Decision{Action: models.RiskActionAllow}
+	for _, rule := range e.rules {
+		action, reason, err := rule.Evaluate(ctx, op)
+		if err != nil {
+			return Decision{}, err
+		}
+		if action == models.RiskActionAllow {
+			continue
+		}
+		decision.Reasons = append(decision.Reasons, reason)
+		if models.RiskSeverity(action) > models.RiskSeverity(decision.Action) {
+			decision.Action = action
+		}
+	}
+	return decision, nil
+}
@@ -0,0 +1,117 @@
+package risk
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/pkg/money"
+)
+
+// fakeHistory answers every wallet with the same activity
+type fakeHistory struct {
+	count       int
+	average     decimal.Decimal
+	averageOver int
+	known       bool
+}
+
+func (h fakeHistory) CountWalletDebitsSince(ctx context.Context, walletID uuid.UUID, journalType string, since time.Time) (int, error) {
+	return h.count, nil
+}
+
+func (h fakeHistory) AverageWalletDebitSince(ctx context.Context, walletID uuid.UUID, journalType string, since time.Time) (decimal.Decimal, int, error) {
+	return h.average, h.averageOver, nil
+}
+
+func (h fakeHistory) HasTransferredTo(ctx context.Context, walletID, toWalletID uuid.UUID) (bool, error) {
+	return h.known, nil
+}
+
+func transfer(amount int64) Operation {
+	to := uuid.New()
+	return Operation{
+		Type:       models.JournalTypeTransfer,
+		WalletID:   uuid.New(),
+		ToWalletID: &to,
+		Amount:     money.New(decimal.NewFromInt(amount), money.USD),
+		At:         time.Date(2024, 6, 25, 12, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestVelocityRuleBlocksAtMax(t *testing.T) {
+	rule := VelocityRule{History: fakeHistory{count: 4}, Window: time.Minute, Max: 5, Action: models.RiskActionBlock}
+
+	action, _, err := rule.Evaluate(context.Background(), transfer(10))
+	require.NoError(t, err)
+	assert.Equal(t, models.RiskActionAllow, action)
+
+	rule.History = fakeHistory{count: 5}
+	action, reason, err := rule.Evaluate(context.Background(), transfer(10))
+	require.NoError(t, err)
+	assert.Equal(t, models.RiskActionBlock, action)
+	assert.Equal(t, "6 transfer operations in the last 1m0s", reason)
+}
+
+func TestLargeAmountRuleNeedsHistory(t *testing.T) {
+	rule := LargeAmountRule{
+		History:    fakeHistory{average: decimal.NewFromInt(20), averageOver: 2},
+		Lookback:   30 * 24 * time.Hour,
+		Factor:     decimal.NewFromInt(10),
+		MinHistory: 3,
+		Action:     models.RiskActionFlag,
+	}
+
+	action, _, err := rule.Evaluate(context.Background(), transfer(1000))
+	require.NoError(t, err)
+	assert.Equal(t, models.RiskActionAllow, action, "too little history to judge")
+
+	rule.History = fakeHistory{average: decimal.NewFromInt(20), averageOver: 3}
+	action, _, err = rule.Evaluate(context.Background(), transfer(200))
+	require.NoError(t, err)
+	assert.Equal(t, models.RiskActionAllow, action, "exactly the factor is allowed")
+
+	action, reason, err := rule.Evaluate(context.Background(), transfer(201))
+	require.NoError(t, err)
+	assert.Equal(t, models.RiskActionFlag, action)
+	assert.Contains(t, reason, "average transfer of 20.00")
+}
+
+func TestNewRecipientRuleIgnoresWithdrawals(t *testing.T) {
+	rule := NewRecipientRule{History: fakeHistory{known: false}, Action: models.RiskActionFlag}
+
+	withdrawal := transfer(10)
+	withdrawal.Type, withdrawal.ToWalletID = models.JournalTypeWithdraw, nil
+	action, _, err := rule.Evaluate(context.Background(), withdrawal)
+	require.NoError(t, err)
+	assert.Equal(t, models.RiskActionAllow, action)
+
+	action, _, err = rule.Evaluate(context.Background(), transfer(10))
+	require.NoError(t, err)
+	assert.Equal(t, models.RiskActionFlag, action)
+}
+
+func TestEngineTakesMostSevereAction(t *testing.T) {
+	history := fakeHistory{count: 10, known: false}
+	engine := NewEngine(
+		NewRecipientRule{History: history, Action: models.RiskActionFlag},
+		VelocityRule{History: history, Window: time.Minute, Max: 10, Action: models.RiskActionBlock},
+	)
+
+	decision, err := engine.Assess(context.Background(), transfer(10))
+
+	require.NoError(t, err)
+	assert.Equal(t, models.RiskActionBlock, decision.Action)
+	assert.Len(t, decision.Reasons, 2)
+
+	decision, err = NewEngine().Assess(context.Background(), transfer(10))
+	require.NoError(t, err)
+	assert.Equal(t, models.RiskActionAllow, decision.Action)
+	assert.Empty(t, decision.Reasons)
+}
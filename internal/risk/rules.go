@@ -0,0 +1,74 @@
+package risk
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/shanwije/wallet-app/internal/models"
+)
+
+// VelocityRule fires when the wallet already made Max operations of the same type
+// within Window, e.g. too many transfers in a minute
+type VelocityRule struct {
+	History History
+	Window  time.Duration
+	Max     int
+	Action  string
+}
+
+func (r VelocityRule) Evaluate(ctx context.Context, op Operation) (string, string, error) {
+	count, err := r.History.CountWalletDebitsSince(ctx, op.WalletID, op.Type, op.At.Add(-r.Window))
+	if err != nil {
+		return "", "", fmt.Errorf("failed to count recent %s operations: %w", op.Type, err)
+	}
+	if count < r.Max {
+		return models.RiskActionAllow, "", nil
+	}
+	return r.Action, fmt.Sprintf("%d %s operations in the last %s", count+1, op.Type, r.Window), nil
+}
+
+// LargeAmountRule fires when the amount is more than Factor times the wallet's average
+// operation of the same type over Lookback. Wallets with fewer than MinHistory such
+// operations have no meaningful average and are let through.
+type LargeAmountRule struct {
+	History    History
+	Lookback   time.Duration
+	Factor     decimal.Decimal
+	MinHistory int
+	Action     string
+}
+
+func (r LargeAmountRule) Evaluate(ctx context.Context, op Operation) (string, string, error) {
+	average, count, err := r.History.AverageWalletDebitSince(ctx, op.WalletID, op.Type, op.At.Add(-r.Lookback))
+	if err != nil {
+		return "", "", fmt.Errorf("failed to average recent %s operations: %w", op.Type, err)
+	}
+	if count < r.MinHistory || !op.Amount.Amount().GreaterThan(average.Mul(r.Factor)) {
+		return models.RiskActionAllow, "", nil
+	}
+	return r.Action, fmt.Sprintf("%s is over %s times the average %s of %s",
+		op.Amount, r.Factor, op.Type, average.StringFixed(2)), nil
+}
+
+// NewRecipientRule fires on a transfer to a wallet the sender has never paid before
+type NewRecipientRule struct {
+	History History
+	Action  string
+}
+
+func (r NewRecipientRule) Evaluate(ctx context.Context, op Operation) (string, string, error) {
+	if op.ToWalletID == nil {
+		return models.RiskActionAllow, "", nil
+	}
+	known, err := r.History.HasTransferredTo(ctx, op.WalletID, *op.ToWalletID)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to look up earlier transfers: %w", err)
+	}
+	if known {
+		return models.RiskActionAllow, "", nil
+	}
+	return r.Action, "first transfer to this recipient", nil
+}
@@ -0,0 +1,189 @@
+// Package seed fills a database with demo users, each with a wallet and a transaction
+// history of deposits, withdrawals and transfers between them. Everything goes through
+// the service layer, so the ledger, limits and events are exactly as if clients had
+// made the same requests.
+package seed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/service"
+	"github.com/shanwije/wallet-app/pkg/money"
+)
+
+// Config sizes the demo data
+type Config struct {
+	// Users is how many users are created, each with one wallet
+	Users int
+	// Transactions is how many deposits, withdrawals and transfers each wallet makes
+	// after its opening deposit
+	Transactions int
+	// Password is optional; when set every user is registered with it as
+	// UsernamePrefix followed by their number, so they can log in
+	Password       string
+	UsernamePrefix string
+	// Seed makes the generated data repeatable; the same seed on an empty database
+	// gives the same users and amounts
+	Seed uint64
+}
+
+// Summary counts what a run created. Skipped movements were refused by the service,
+// for example for want of funds or by a wallet limit, and left out of the history.
+type Summary struct {
+	Users       int `json:"users"`
+	Deposits    int `json:"deposits"`
+	Withdrawals int `json:"withdrawals"`
+	Transfers   int `json:"transfers"`
+	Skipped     int `json:"skipped"`
+}
+
+// Generator creates demo data through the user and wallet services
+type Generator struct {
+	Users   *service.UserService
+	Wallets *service.WalletService
+	Config  Config
+}
+
+var (
+	firstNames = []string{"Alice", "Bruno", "Chen", "Dilani", "Emma", "Farah", "Gustavo", "Hana", "Ivan", "Jaya", "Kofi", "Lena", "Mateo", "Nadia", "Oscar", "Priya"}
+	lastNames  = []string{"Silva", "Perera", "Nakamura", "Okafor", "Schmidt", "Haddad", "Novak", "Fernando", "Kowalski", "Mensah", "Rossi", "Larsen"}
+
+	// spending is the categories withdrawals and transfers are filed under, with a
+	// description typical of each
+	spending = []struct{ category, description string }{
+		{"groceries", "Weekly groceries"},
+		{"dining", "Dinner out"},
+		{"transport", "Train pass"},
+		{"utilities", "Electricity bill"},
+		{"rent", "Monthly rent"},
+		{"entertainment", "Concert tickets"},
+		{"gifts", "Birthday present"},
+	}
+)
+
+// Run creates the configured users and their histories, stopping at the first error
+// other than a movement the service refused
+func (g *Generator) Run(ctx context.Context) (*Summary, error) {
+	if g.Config.Users <= 0 {
+		return nil, errors.New("at least one user is required")
+	}
+	if g.Config.Transactions < 0 {
+		return nil, errors.New("transactions cannot be negative")
+	}
+	rng := rand.New(rand.NewPCG(g.Config.Seed, g.Config.Seed^0x5eed))
+	summary := &Summary{}
+
+	wallets := make([]uuid.UUID, 0, g.Config.Users)
+	for i := 1; i <= g.Config.Users; i++ {
+		user, err := g.createUser(ctx, i, rng)
+		if err != nil {
+			return summary, err
+		}
+		summary.Users++
+		wallets = append(wallets, user.Wallet.ID)
+
+		// Every wallet opens with a salary so it has something to spend
+		if err := g.deposit(ctx, summary, user.Wallet.ID, amount(rng, 1000, 5000), "salary", "Monthly salary"); err != nil {
+			return summary, err
+		}
+	}
+
+	for _, walletID := range wallets {
+		for range g.Config.Transactions {
+			var err error
+			spend := spending[rng.IntN(len(spending))]
+			switch roll := rng.IntN(10); {
+			case roll < 2:
+				err = g.deposit(ctx, summary, walletID, amount(rng, 20, 800), "income", "Freelance payment")
+			case roll < 5 || len(wallets) < 2:
+				err = g.withdraw(ctx, summary, walletID, amount(rng, 5, 250), spend.category, spend.description)
+			default:
+				to := wallets[rng.IntN(len(wallets))]
+				for to == walletID {
+					to = wallets[rng.IntN(len(wallets))]
+				}
+				err = g.transfer(ctx, summary, walletID, to, amount(rng, 5, 300), spend.category, spend.description)
+			}
+			if err != nil {
+				return summary, err
+			}
+		}
+	}
+	return summary, nil
+}
+
+// createUser creates the nth user, registering them when a password is configured
+func (g *Generator) createUser(ctx context.Context, n int, rng *rand.Rand) (*models.UserWithWallet, error) {
+	name := firstNames[rng.IntN(len(firstNames))] + " " + lastNames[rng.IntN(len(lastNames))]
+	if g.Config.Password == "" {
+		user, err := g.Users.CreateUser(ctx, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create user %d: %w", n, err)
+		}
+		return user, nil
+	}
+
+	username := fmt.Sprintf("%s%04d", g.Config.UsernamePrefix, n)
+	user, err := g.Users.Register(ctx, name, username, g.Config.Password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to register user %s: %w", username, err)
+	}
+	return user, nil
+}
+
+// deposit, withdraw and transfer make one movement filed under category, tallying it
+// in summary
+func (g *Generator) deposit(ctx context.Context, summary *Summary, walletID uuid.UUID, amount money.Money, category, description string) error {
+	ctx = service.WithClassification(ctx, category, nil)
+	_, err := g.Wallets.Deposit(ctx, walletID, amount, "")
+	return tally(err, &summary.Deposits, &summary.Skipped, description)
+}
+
+func (g *Generator) withdraw(ctx context.Context, summary *Summary, walletID uuid.UUID, amount money.Money, category, description string) error {
+	ctx = service.WithClassification(ctx, category, nil)
+	_, err := g.Wallets.Withdraw(ctx, walletID, amount, "")
+	return tally(err, &summary.Withdrawals, &summary.Skipped, description)
+}
+
+func (g *Generator) transfer(ctx context.Context, summary *Summary, from, to uuid.UUID, amount money.Money, category, description string) error {
+	ctx = service.WithClassification(ctx, category, nil)
+	err := g.Wallets.Transfer(ctx, from, to, amount, description, "")
+	return tally(err, &summary.Transfers, &summary.Skipped, description)
+}
+
+// tally counts a movement as made or, when the service refused it, skipped. Any
+// other failure is returned.
+func tally(err error, made, skipped *int, description string) error {
+	switch {
+	case err == nil:
+		*made++
+	case refused(err):
+		*skipped++
+	default:
+		return fmt.Errorf("failed to seed %q: %w", description, err)
+	}
+	return nil
+}
+
+// refused reports whether the service turned a movement down on its merits, which
+// random demo data runs into now and then
+func refused(err error) bool {
+	return errors.Is(err, service.ErrInsufficientBalance) ||
+		errors.Is(err, service.ErrLimitExceeded) ||
+		errors.Is(err, service.ErrAmountOutOfRange) ||
+		errors.Is(err, service.ErrBlockedByRiskCheck) ||
+		errors.Is(err, service.ErrFeatureDisabled)
+}
+
+// amount returns a random amount in the default currency between low and high
+func amount(rng *rand.Rand, low, high int64) money.Money {
+	cents := low*100 + rng.Int64N((high-low)*100+1)
+	return money.New(decimal.New(cents, -2), money.DefaultCurrency)
+}
@@ -0,0 +1,83 @@
+package seed
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shanwije/wallet-app/db/migrations"
+	"github.com/shanwije/wallet-app/internal/repository/sqlite"
+	"github.com/shanwije/wallet-app/internal/service"
+	"github.com/shanwije/wallet-app/pkg/db"
+)
+
+// newServices returns user and wallet services on a migrated SQLite database
+func newServices(t *testing.T) (*service.UserService, *service.WalletService) {
+	t.Helper()
+
+	conn, err := db.New(db.Config{Driver: db.DriverSQLite, Name: filepath.Join(t.TempDir(), "wallet.db")})
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	fsys, err := migrations.ForDriver(db.DriverSQLite)
+	require.NoError(t, err)
+	migrator, err := db.NewMigrator(conn.DB, db.DriverSQLite, fsys)
+	require.NoError(t, err)
+	t.Cleanup(func() { migrator.Close() })
+	_, err = migrator.Up(context.Background())
+	require.NoError(t, err)
+
+	wallets := &service.WalletService{
+		WalletRepo: sqlite.NewWalletRepository(conn.DB),
+		LedgerRepo: sqlite.NewLedgerRepository(conn.DB),
+		UserRepo:   sqlite.NewUserRepository(conn.DB),
+	}
+	users := &service.UserService{
+		UserRepo:       wallets.UserRepo,
+		WalletRepo:     wallets.WalletRepo,
+		CredentialRepo: sqlite.NewCredentialRepository(conn.DB),
+		Wallets:        wallets,
+	}
+	return users, wallets
+}
+
+func TestGeneratorSeedsBalancedHistories(t *testing.T) {
+	users, wallets := newServices(t)
+	ctx := context.Background()
+
+	generator := &Generator{Users: users, Wallets: wallets, Config: Config{
+		Users:          3,
+		Transactions:   20,
+		Password:       "demo-password-1",
+		UsernamePrefix: "demo",
+		Seed:           42,
+	}}
+	summary, err := generator.Run(ctx)
+	require.NoError(t, err)
+
+	assert.Equal(t, 3, summary.Users)
+	assert.Equal(t, 3+3*20, summary.Deposits+summary.Withdrawals+summary.Transfers+summary.Skipped)
+	assert.Positive(t, summary.Transfers)
+	assert.Positive(t, summary.Withdrawals)
+
+	// Registered users can log in, and every wallet's balance matches its ledger
+	for _, username := range []string{"demo0001", "demo0002", "demo0003"} {
+		user, err := users.Login(ctx, username, "demo-password-1")
+		require.NoError(t, err, username)
+		wallet, err := users.GetUserWallet(ctx, user.ID)
+		require.NoError(t, err)
+		assert.NoError(t, wallets.VerifyWalletBalance(ctx, wallet.ID))
+		history, err := wallets.GetTransactionHistory(ctx, wallet.ID)
+		require.NoError(t, err)
+		assert.NotEmpty(t, history)
+	}
+}
+
+func TestGeneratorRejectsEmptyConfig(t *testing.T) {
+	users, wallets := newServices(t)
+
+	_, err := (&Generator{Users: users, Wallets: wallets}).Run(context.Background())
+	assert.Error(t, err)
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository"
+	"github.com/shopspring/decimal"
+)
+
+var (
+	// ErrWalletNotEmpty is returned when deleting a user whose wallet still holds money
+	// without asking for it to be withdrawn
+	ErrWalletNotEmpty = errors.New("wallet balance must be zero to close it")
+	// ErrWalletHasActiveHolds is returned when deleting a user whose wallet has funds on hold
+	ErrWalletHasActiveHolds = errors.New("wallet has active holds")
+)
+
+// closingWithdrawalDescription describes the withdrawal that empties a deleted user's wallet
+const closingWithdrawalDescription = "Closing balance withdrawal"
+
+// DeleteUser soft-deletes the user and closes their wallet, after which the user can
+// no longer be looked up, log in or receive transfers. A wallet that still holds money
+// is only closed when withdrawBalance is set, in which case the balance is paid out
+// as a final withdrawal. Wallets with funds on hold are never closed.
+func (s *UserService) DeleteUser(ctx context.Context, id uuid.UUID, withdrawBalance bool) error {
+	return s.Wallets.closeUserAccount(ctx, id, withdrawBalance)
+}
+
+// closeUserAccount deletes the user and closes their wallet in one transaction
+func (s *WalletService) closeUserAccount(ctx context.Context, userID uuid.UUID, withdrawBalance bool) error {
+	// The treasury wallet a closing withdrawal settles against is found before the
+	// transaction, which could not create it. A failed lookup is reported by the
+	// transaction, which reads the wallet again.
+	var treasuryID *uuid.UUID
+	if withdrawBalance && s.SystemWallets != nil {
+		if found, err := s.WalletRepo.GetWalletByUserID(ctx, userID); err == nil {
+			if treasuryID, err = s.settlementWallet(ctx, models.WalletKindTreasury, found.Currency); err != nil {
+				return err
+			}
+		}
+	}
+
+	return s.withTx(ctx, "delete user", func(ctx context.Context, tx *sql.Tx) error {
+		// Marking the user first also locks their row against a concurrent delete
+		if err := s.UserRepo.SoftDeleteUserWithTx(ctx, tx, userID, s.now()); err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				return ErrUserNotFound
+			}
+			return err
+		}
+
+		found, err := s.WalletRepo.GetWalletByUserID(ctx, userID)
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get wallet: %w", err)
+		}
+
+		wallet, err := s.getWalletForUpdate(ctx, tx, found.ID)
+		if err != nil {
+			return fmt.Errorf("failed to get wallet: %w", err)
+		}
+
+		if wallet.HeldBalance.IsPositive() {
+			return ErrWalletHasActiveHolds
+		}
+		// An overdrawn wallet has to be paid back before it can be closed
+		if wallet.Balance.IsNegative() {
+			return ErrWalletNotEmpty
+		}
+		if wallet.Balance.IsPositive() {
+			if !withdrawBalance {
+				return ErrWalletNotEmpty
+			}
+			// A frozen wallet's money stays put until it is unfrozen
+			if err := wallet.CheckActive(); err != nil {
+				return err
+			}
+			if err := s.withdrawClosingBalance(ctx, tx, wallet, treasuryID); err != nil {
+				return err
+			}
+		}
+
+		if wallet.Status == models.WalletStatusClosed {
+			return nil
+		}
+		return s.setStatus(ctx, tx, wallet, models.WalletStatusClosed)
+	})
+}
+
+// withdrawClosingBalance pays the wallet's whole balance out to the treasury wallet
+// treasuryID, or the external settlement account when it is nil
+func (s *WalletService) withdrawClosingBalance(ctx context.Context, tx *sql.Tx, wallet *models.Wallet, treasuryID *uuid.UUID) error {
+	balance := wallet.Funds()
+	description := closingWithdrawalDescription
+	journal := newJournal(models.JournalTypeWithdraw, &description, scopedIdempotencyKey(wallet.ID, "close"),
+		debit(&wallet.ID, balance),
+		credit(treasuryID, balance),
+	)
+
+	if err := s.setBalance(ctx, tx, wallet, decimal.Zero); err != nil {
+		return fmt.Errorf("failed to update wallet balance: %w", err)
+	}
+	if err := s.settle(ctx, tx, wallet, journal, treasuryID); err != nil {
+		return err
+	}
+	return s.recordJournal(ctx, tx, journal)
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository"
+	"github.com/shanwije/wallet-app/internal/repository/mocks"
+	"github.com/shanwije/wallet-app/internal/testutil"
+)
+
+// setupAccountService creates a user service whose wallet service has mocked repositories
+func setupAccountService() (*UserService, *mocks.WalletRepository, *mocks.LedgerRepository, *mocks.UserRepository) {
+	wallets, walletRepo, ledgerRepo := setupWalletService()
+	userRepo := new(mocks.UserRepository)
+	wallets.UserRepo = userRepo
+	walletRepo.On("BeginTx", mock.Anything).Return((*sql.Tx)(nil), nil)
+	return &UserService{UserRepo: userRepo, WalletRepo: walletRepo, Wallets: wallets}, walletRepo, ledgerRepo, userRepo
+}
+
+func TestDeleteUserClosesEmptyWallet(t *testing.T) {
+	service, walletRepo, ledgerRepo, userRepo := setupAccountService()
+
+	userID := uuid.New()
+	walletID := uuid.New()
+	wallet := testutil.Wallet(walletID, 0)
+	userRepo.On("SoftDeleteUserWithTx", mock.Anything, (*sql.Tx)(nil), userID, mock.Anything).Return(nil)
+	walletRepo.On("GetWalletByUserID", mock.Anything, userID).Return(wallet, nil)
+	walletRepo.On("GetWalletByIDWithTx", mock.Anything, (*sql.Tx)(nil), walletID).Return(wallet, nil)
+	walletRepo.On("UpdateStatusWithTx", mock.Anything, (*sql.Tx)(nil), walletID, models.WalletStatusClosed, mock.Anything).Return(nil)
+
+	err := service.DeleteUser(context.Background(), userID, false)
+
+	require.NoError(t, err)
+	walletRepo.AssertExpectations(t)
+	ledgerRepo.AssertNotCalled(t, "CreateJournalWithTx", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestDeleteUserWithdrawsRemainingBalance(t *testing.T) {
+	service, walletRepo, ledgerRepo, userRepo := setupAccountService()
+
+	userID := uuid.New()
+	walletID := uuid.New()
+	wallet := testutil.Wallet(walletID, 42.5)
+	userRepo.On("SoftDeleteUserWithTx", mock.Anything, (*sql.Tx)(nil), userID, mock.Anything).Return(nil)
+	walletRepo.On("GetWalletByUserID", mock.Anything, userID).Return(wallet, nil)
+	walletRepo.On("GetWalletByIDWithTx", mock.Anything, (*sql.Tx)(nil), walletID).Return(wallet, nil)
+	walletRepo.On("UpdateBalanceWithTx", mock.Anything, (*sql.Tx)(nil), walletID, decimal.Zero, mock.Anything).Return(nil)
+	walletRepo.On("UpdateStatusWithTx", mock.Anything, (*sql.Tx)(nil), walletID, models.WalletStatusClosed, mock.Anything).Return(nil)
+	ledgerRepo.On("CreateJournalWithTx", mock.Anything, (*sql.Tx)(nil), mock.AnythingOfType("*models.Journal")).Return(nil)
+
+	err := service.DeleteUser(context.Background(), userID, true)
+
+	require.NoError(t, err)
+	walletRepo.AssertExpectations(t)
+
+	journal := ledgerRepo.Calls[0].Arguments.Get(2).(*models.Journal)
+	assert.Equal(t, models.JournalTypeWithdraw, journal.Type)
+	assert.True(t, journal.Entries[0].Amount.Equal(decimal.NewFromFloat(42.5)))
+	assert.NoError(t, journal.Validate())
+}
+
+func TestDeleteUserErrors(t *testing.T) {
+	tests := []struct {
+		name            string
+		balance, held   float64
+		status          string
+		withdrawBalance bool
+		wantErr         error
+	}{
+		{name: "money left", balance: 10, wantErr: ErrWalletNotEmpty},
+		{name: "funds on hold", balance: 10, held: 5, withdrawBalance: true, wantErr: ErrWalletHasActiveHolds},
+		{name: "frozen wallet", balance: 10, status: models.WalletStatusFrozen, withdrawBalance: true, wantErr: models.ErrWalletFrozen},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, walletRepo, _, userRepo := setupAccountService()
+
+			userID := uuid.New()
+			walletID := uuid.New()
+			wallet := createHeldWallet(walletID, tt.balance, tt.held)
+			if tt.status != "" {
+				wallet.Status = tt.status
+			}
+			userRepo.On("SoftDeleteUserWithTx", mock.Anything, (*sql.Tx)(nil), userID, mock.Anything).Return(nil)
+			walletRepo.On("GetWalletByUserID", mock.Anything, userID).Return(wallet, nil)
+			walletRepo.On("GetWalletByIDWithTx", mock.Anything, (*sql.Tx)(nil), walletID).Return(wallet, nil)
+
+			err := service.DeleteUser(context.Background(), userID, tt.withdrawBalance)
+
+			assert.ErrorIs(t, err, tt.wantErr)
+			walletRepo.AssertNotCalled(t, "UpdateStatusWithTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+
+	t.Run("unknown user", func(t *testing.T) {
+		service, _, _, userRepo := setupAccountService()
+
+		userID := uuid.New()
+		userRepo.On("SoftDeleteUserWithTx", mock.Anything, (*sql.Tx)(nil), userID, mock.Anything).Return(fmt.Errorf("user %w", repository.ErrNotFound))
+
+		assert.ErrorIs(t, service.DeleteUser(context.Background(), userID, false), ErrUserNotFound)
+	})
+}
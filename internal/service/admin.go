@@ -0,0 +1,148 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository"
+	"github.com/shanwije/wallet-app/pkg/money"
+	"github.com/shanwije/wallet-app/pkg/pagination"
+)
+
+// ErrInvalidAdjustment is returned for a zero adjustment or one without a reason
+var ErrInvalidAdjustment = errors.New("an adjustment needs a non-zero amount and a reason")
+
+var (
+	// ErrInvalidWalletSort is returned for a wallet search sorted by an unknown column
+	ErrInvalidWalletSort = errors.New("wallets can be sorted by created_at or balance")
+	// ErrInvalidCreationPeriod is returned when a wallet search's creation period ends
+	// before it starts
+	ErrInvalidCreationPeriod = errors.New("creation period must end after it starts")
+	// ErrEmptySearch is returned for a user search without a query
+	ErrEmptySearch = errors.New("search query must not be empty")
+)
+
+// ListUsers pages through the users that have not been deleted, oldest first
+func (s *UserService) ListUsers(ctx context.Context, limit, offset int) ([]*models.User, error) {
+	users, err := s.UserRepo.ListUsers(ctx, pagination.ClampLimit(limit), max(offset, 0))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+	return users, nil
+}
+
+// SearchUsers pages through the users that are not deleted whose name matches query,
+// best matches first
+func (s *UserService) SearchUsers(ctx context.Context, query string, limit, offset int) ([]*models.User, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, ErrEmptySearch
+	}
+
+	users, err := s.UserRepo.SearchUsers(ctx, query, pagination.ClampLimit(limit), max(offset, 0))
+	if err != nil {
+		return nil, fmt.Errorf("failed to search users: %w", err)
+	}
+	return users, nil
+}
+
+// SearchWallets pages through the wallets matching filter in its sort order, oldest
+// first by default
+func (s *WalletService) SearchWallets(ctx context.Context, filter repository.WalletFilter) ([]*models.Wallet, error) {
+	filter.Limit = pagination.ClampLimit(filter.Limit)
+	filter.Offset = max(filter.Offset, 0)
+	switch filter.Sort.Field {
+	case "", repository.WalletSortCreatedAt, repository.WalletSortBalance:
+	default:
+		return nil, ErrInvalidWalletSort
+	}
+	if !filter.CreatedFrom.IsZero() && !filter.CreatedTo.IsZero() && !filter.CreatedTo.After(filter.CreatedFrom) {
+		return nil, ErrInvalidCreationPeriod
+	}
+
+	wallets, err := s.WalletRepo.SearchWallets(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search wallets: %w", err)
+	}
+	return wallets, nil
+}
+
+// AdjustBalance corrects a wallet's balance by amount, which is added when positive
+// and taken away when negative. The adjustment is posted to the ledger against the
+// suspense wallet, or the settlement account without system wallets, with reason as
+// its description. Frozen wallets can be adjusted,
+// closed ones cannot, and an adjustment may not take more than the available balance.
+// A non-empty idempotencyKey makes retries return the wallet without adjusting again.
+func (s *WalletService) AdjustBalance(ctx context.Context, walletID uuid.UUID, amount money.Money, reason, idempotencyKey string) (*models.Wallet, error) {
+	if amount.IsZero() || reason == "" {
+		return nil, ErrInvalidAdjustment
+	}
+
+	// Money moves between the wallet and the suspense wallet, in either direction
+	suspenseID, err := s.settlementWallet(ctx, models.WalletKindSuspense, amount.Currency())
+	if err != nil {
+		return nil, err
+	}
+	from, to, size := suspenseID, &walletID, amount
+	if amount.IsNegative() {
+		from, to, size = &walletID, suspenseID, amount.Neg()
+	}
+	journal := newJournal(models.JournalTypeAdjustment, &reason, scopedIdempotencyKey(walletID, idempotencyKey),
+		debit(from, size),
+		credit(to, size),
+	)
+
+	var wallet *models.Wallet
+	replayed, err := s.idempotent(ctx, journal, func() error {
+		return s.withTx(ctx, "adjust balance", func(ctx context.Context, tx *sql.Tx) error {
+			current, err := s.getWalletForUpdate(ctx, tx, walletID)
+			if err != nil {
+				return fmt.Errorf("failed to get wallet: %w", err)
+			}
+			if current.Status == models.WalletStatusClosed {
+				return models.ErrWalletClosed
+			}
+
+			if amount.IsNegative() {
+				cmp, err := current.Available().Cmp(size)
+				if err != nil {
+					return fmt.Errorf("invalid adjustment: %w", err)
+				}
+				if cmp < 0 {
+					return ErrInsufficientAvailableBalance
+				}
+			}
+
+			newBalance, err := current.Funds().Add(amount)
+			if err != nil {
+				return fmt.Errorf("invalid adjustment: %w", err)
+			}
+			if err := s.setBalance(ctx, tx, current, newBalance.Amount()); err != nil {
+				return fmt.Errorf("failed to update wallet balance: %w", err)
+			}
+			if err := s.settle(ctx, tx, current, journal, suspenseID); err != nil {
+				return err
+			}
+
+			if err := s.recordJournal(ctx, tx, journal); err != nil {
+				return err
+			}
+
+			wallet = current
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	if replayed {
+		return s.GetBalance(ctx, walletID)
+	}
+
+	return wallet, nil
+}
@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository"
+	"github.com/shanwije/wallet-app/internal/repository/mocks"
+	"github.com/shanwije/wallet-app/internal/testutil"
+	"github.com/shanwije/wallet-app/pkg/pagination"
+)
+
+func TestAdjustBalanceCreditsFrozenWallet(t *testing.T) {
+	service, walletRepo, ledgerRepo := setupWalletService()
+
+	walletID := uuid.New()
+	wallet := testutil.Wallet(walletID, 100.0)
+	wallet.Status = models.WalletStatusFrozen
+
+	var journal *models.Journal
+	walletRepo.On("BeginTx", mock.Anything).Return((*sql.Tx)(nil), nil)
+	walletRepo.On("GetWalletByIDWithTx", mock.Anything, (*sql.Tx)(nil), walletID).Return(wallet, nil)
+	walletRepo.On("UpdateBalanceWithTx", mock.Anything, (*sql.Tx)(nil), walletID, mock.MatchedBy(decimal.NewFromInt(125).Equal), mock.Anything).Return(nil)
+	ledgerRepo.On("CreateJournalWithTx", mock.Anything, (*sql.Tx)(nil), mock.AnythingOfType("*models.Journal")).
+		Run(func(args mock.Arguments) { journal = args.Get(2).(*models.Journal) }).
+		Return(nil)
+
+	result, err := service.AdjustBalance(context.Background(), walletID, testutil.USD(decimal.NewFromInt(25)), "Goodwill credit", "")
+
+	require.NoError(t, err)
+	assert.True(t, result.Balance.Equal(decimal.NewFromInt(125)))
+	require.NotNil(t, journal)
+	assert.Equal(t, models.JournalTypeAdjustment, journal.Type)
+	assert.Equal(t, "Goodwill credit", *journal.Description)
+	assert.Equal(t, walletID, *journal.Entries[1].WalletID)
+	assert.Equal(t, models.EntryDirectionCredit, journal.Entries[1].Direction)
+	assert.NoError(t, journal.Validate())
+}
+
+func TestAdjustBalanceDebitCannotExceedAvailable(t *testing.T) {
+	service, walletRepo, ledgerRepo := setupWalletService()
+
+	walletID := uuid.New()
+	walletRepo.On("BeginTx", mock.Anything).Return((*sql.Tx)(nil), nil)
+	walletRepo.On("GetWalletByIDWithTx", mock.Anything, (*sql.Tx)(nil), walletID).Return(createHeldWallet(walletID, 100.0, 80.0), nil)
+
+	_, err := service.AdjustBalance(context.Background(), walletID, testutil.USD(decimal.NewFromInt(-30)), "Chargeback", "")
+
+	assert.ErrorIs(t, err, ErrInsufficientAvailableBalance)
+	walletRepo.AssertNotCalled(t, "UpdateBalanceWithTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	ledgerRepo.AssertNotCalled(t, "CreateJournalWithTx", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestAdjustBalanceRejections(t *testing.T) {
+	service, walletRepo, _ := setupWalletService()
+	walletID := uuid.New()
+
+	_, err := service.AdjustBalance(context.Background(), walletID, testutil.USD(decimal.Zero), "Nothing", "")
+	assert.ErrorIs(t, err, ErrInvalidAdjustment)
+	_, err = service.AdjustBalance(context.Background(), walletID, testutil.USD(decimal.NewFromInt(5)), "", "")
+	assert.ErrorIs(t, err, ErrInvalidAdjustment)
+	walletRepo.AssertNotCalled(t, "BeginTx", mock.Anything)
+
+	closed := testutil.Wallet(walletID, 10.0)
+	closed.Status = models.WalletStatusClosed
+	walletRepo.On("BeginTx", mock.Anything).Return((*sql.Tx)(nil), nil)
+	walletRepo.On("GetWalletByIDWithTx", mock.Anything, (*sql.Tx)(nil), walletID).Return(closed, nil)
+
+	_, err = service.AdjustBalance(context.Background(), walletID, testutil.USD(decimal.NewFromInt(5)), "Late refund", "")
+	assert.ErrorIs(t, err, models.ErrWalletClosed)
+}
+
+func TestSearchWalletsClampsPage(t *testing.T) {
+	service, walletRepo, _ := setupWalletService()
+
+	walletRepo.On("SearchWallets", mock.Anything, repository.WalletFilter{Status: models.WalletStatusFrozen, Limit: pagination.MaxLimit}).
+		Return([]*models.Wallet{}, nil)
+
+	_, err := service.SearchWallets(context.Background(), repository.WalletFilter{Status: models.WalletStatusFrozen, Limit: 10000, Offset: -3})
+
+	require.NoError(t, err)
+	walletRepo.AssertExpectations(t)
+}
+
+func TestSearchWalletsRejectsUnknownSortsAndEmptyPeriods(t *testing.T) {
+	service, walletRepo, _ := setupWalletService()
+
+	_, err := service.SearchWallets(context.Background(), repository.WalletFilter{Sort: pagination.Sort{Field: "user_id"}})
+	assert.ErrorIs(t, err, ErrInvalidWalletSort)
+
+	at := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	_, err = service.SearchWallets(context.Background(), repository.WalletFilter{CreatedFrom: at, CreatedTo: at})
+	assert.ErrorIs(t, err, ErrInvalidCreationPeriod)
+	walletRepo.AssertNotCalled(t, "SearchWallets", mock.Anything, mock.Anything)
+}
+
+func TestSearchUsersTrimsTheQuery(t *testing.T) {
+	userRepo := new(mocks.UserRepository)
+	service := &UserService{UserRepo: userRepo}
+	userRepo.On("SearchUsers", mock.Anything, "alice", 50, 0).Return([]*models.User{}, nil)
+
+	_, err := service.SearchUsers(context.Background(), "  alice ", 0, -1)
+	require.NoError(t, err)
+	_, err = service.SearchUsers(context.Background(), "   ", 0, 0)
+	assert.ErrorIs(t, err, ErrEmptySearch)
+	userRepo.AssertExpectations(t)
+}
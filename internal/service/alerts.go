@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/pkg/pagination"
+)
+
+// GetAlertSettings returns the wallet's alert settings; unset thresholds are nil
+func (s *WalletService) GetAlertSettings(ctx context.Context, walletID uuid.UUID) (*models.WalletAlertSettings, error) {
+	if _, err := s.WalletRepo.GetWalletByID(ctx, walletID); err != nil {
+		return nil, fmt.Errorf("failed to get wallet: %w", walletLookupError(err))
+	}
+
+	settings, err := s.AlertRepo.GetWalletAlertSettings(ctx, walletID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get wallet alert settings: %w", err)
+	}
+	return settings, nil
+}
+
+// SetAlertSettings replaces all of the wallet's alert settings; nil thresholds turn
+// their alerts off
+func (s *WalletService) SetAlertSettings(ctx context.Context, settings *models.WalletAlertSettings) (*models.WalletAlertSettings, error) {
+	if threshold := settings.LowBalanceThreshold; threshold != nil && !threshold.IsPositive() {
+		return nil, ErrInvalidNotificationThreshold
+	}
+	if _, err := s.WalletRepo.GetWalletByID(ctx, settings.WalletID); err != nil {
+		return nil, fmt.Errorf("failed to get wallet: %w", walletLookupError(err))
+	}
+
+	settings.UpdatedAt = s.now()
+	if err := s.AlertRepo.SetWalletAlertSettings(ctx, settings); err != nil {
+		return nil, fmt.Errorf("failed to set wallet alert settings: %w", err)
+	}
+	return settings, nil
+}
+
+// ListAlerts returns a page of the alerts raised for the wallet, newest first
+func (s *WalletService) ListAlerts(ctx context.Context, walletID uuid.UUID, limit, offset int) ([]*models.WalletAlert, error) {
+	if _, err := s.WalletRepo.GetWalletByID(ctx, walletID); err != nil {
+		return nil, fmt.Errorf("failed to get wallet: %w", walletLookupError(err))
+	}
+
+	alerts, err := s.AlertRepo.ListWalletAlerts(ctx, walletID, pagination.ClampLimit(limit), max(offset, 0))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list wallet alerts: %w", err)
+	}
+	return alerts, nil
+}
+
+// alertLowBalance raises a low balance alert when the recorded journal took the
+// locked wallet from previous, at or above its threshold, to below it. The alert is
+// written inside tx, along with the event that has the owner emailed when the service
+// has an outbox, so both exist only if the movement commits. A balance already below
+// the threshold raises nothing more until it has recovered.
+func (s *WalletService) alertLowBalance(ctx context.Context, tx *sql.Tx, wallet *models.Wallet, previous decimal.Decimal, journal *models.Journal) error {
+	if s.AlertRepo == nil {
+		return nil
+	}
+	settings, err := s.AlertRepo.GetWalletAlertSettingsWithTx(ctx, tx, wallet.ID)
+	if err != nil {
+		return err
+	}
+	threshold := settings.LowBalanceThreshold
+	if threshold == nil || previous.LessThan(*threshold) || !wallet.Balance.LessThan(*threshold) {
+		return nil
+	}
+
+	alert := &models.WalletAlert{
+		WalletID:  wallet.ID,
+		Type:      models.AlertLowBalance,
+		JournalID: journal.ID,
+		Threshold: *threshold,
+		Balance:   wallet.Balance,
+		Currency:  wallet.Currency,
+		CreatedAt: journal.CreatedAt,
+	}
+	if err := s.AlertRepo.CreateWalletAlertWithTx(ctx, tx, alert); err != nil {
+		return err
+	}
+	if s.Outbox == nil {
+		return nil
+	}
+
+	payload, err := json.Marshal(alert)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s event: %w", models.EventLowBalanceAlert, err)
+	}
+	event := &models.OutboxEvent{
+		Type:      models.EventLowBalanceAlert,
+		WalletID:  wallet.ID,
+		Payload:   payload,
+		CreatedAt: alert.CreatedAt,
+	}
+	if err := s.Outbox.CreateOutboxEventWithTx(ctx, tx, event); err != nil {
+		return fmt.Errorf("failed to record %s event: %w", event.Type, err)
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository"
+	"github.com/shanwije/wallet-app/pkg/clock"
+	"github.com/shanwije/wallet-app/pkg/logger"
+	"github.com/shanwije/wallet-app/pkg/money"
+	"github.com/shanwije/wallet-app/pkg/pagination"
+)
+
+// ErrInvalidAMLReportPeriod is returned for an export period that ends before it starts
+var ErrInvalidAMLReportPeriod = errors.New("AML report period must end after it starts")
+
+// amlExportBatch is how many reports an export reads at a time
+const amlExportBatch = 500
+
+// AMLRules are the anti-money-laundering checks deposits, withdrawals and transfers out
+// are screened with. Amounts are compared whatever their currency.
+type AMLRules struct {
+	// ReportThreshold reports every movement of more than this as a large transaction
+	ReportThreshold decimal.Decimal
+	// StructuringMargin is how far below ReportThreshold, as a fraction of it, a movement
+	// counts as just below it
+	StructuringMargin decimal.Decimal
+	// StructuringCount movements of one type just below the threshold within
+	// StructuringWindow are reported as structuring; fewer than 2 turns the check off
+	StructuringCount  int
+	StructuringWindow time.Duration
+}
+
+// screenAML reports the journal, which moved amount in or out of the locked wallet,
+// when it is over the reporting threshold, or when it ends a run of movements just
+// below it. Reports are written inside tx, so they exist only if the movement commits.
+// A run is reported once per window: further movements in it raise no new report.
+// Nothing is screened when the service has no AML repository or rules.
+func (s *WalletService) screenAML(ctx context.Context, tx *sql.Tx, wallet *models.Wallet, journal *models.Journal, amount money.Money) error {
+	if s.AMLRepo == nil || s.AML == nil {
+		return nil
+	}
+	rules := s.AML
+	report := &models.AMLReport{
+		WalletID:  wallet.ID,
+		JournalID: journal.ID,
+		Operation: journal.Type,
+		Amount:    amount.Amount(),
+		Currency:  amount.Currency(),
+		CreatedAt: journal.CreatedAt,
+	}
+
+	if amount.Amount().GreaterThan(rules.ReportThreshold) {
+		report.Type = models.AMLReportLargeTransaction
+		report.MovementCount = 1
+		report.Total = amount.Amount()
+		report.Reason = fmt.Sprintf("%s is over the %s reporting threshold", amount, rules.ReportThreshold)
+		return s.recordAMLReport(ctx, tx, report)
+	}
+
+	floor := rules.ReportThreshold.Mul(decimal.NewFromInt(1).Sub(rules.StructuringMargin))
+	if rules.StructuringCount < 2 || amount.Amount().LessThan(floor) {
+		return nil
+	}
+
+	direction := models.EntryDirectionDebit
+	if journal.Type == models.JournalTypeDeposit {
+		direction = models.EntryDirectionCredit
+	}
+	since := journal.CreatedAt.Add(-rules.StructuringWindow)
+	// The journal is already recorded in tx, so it is among those counted
+	count, total, err := s.AMLRepo.SumWalletMovementsBetweenWithTx(ctx, tx, wallet.ID, journal.Type, direction, floor, rules.ReportThreshold, since)
+	if err != nil {
+		return fmt.Errorf("failed to count movements near the reporting threshold: %w", err)
+	}
+	if count < rules.StructuringCount {
+		return nil
+	}
+	reported, err := s.AMLRepo.HasAMLReportSinceWithTx(ctx, tx, wallet.ID, models.AMLReportStructuring, journal.Type, since)
+	if err != nil {
+		return err
+	}
+	if reported {
+		return nil
+	}
+
+	report.Type = models.AMLReportStructuring
+	report.MovementCount = count
+	report.Total = total
+	report.Reason = fmt.Sprintf("%d %s movements of %s to %s within %s, %s altogether",
+		count, journal.Type, floor, rules.ReportThreshold, rules.StructuringWindow, total)
+	return s.recordAMLReport(ctx, tx, report)
+}
+
+// recordAMLReport writes report inside tx
+func (s *WalletService) recordAMLReport(ctx context.Context, tx *sql.Tx, report *models.AMLReport) error {
+	if err := s.AMLRepo.CreateAMLReportWithTx(ctx, tx, report); err != nil {
+		return err
+	}
+
+	logger.FromContext(ctx).Warn("AML report raised",
+		zap.String("wallet_id", report.WalletID.String()),
+		zap.String("type", report.Type),
+		zap.String("reason", report.Reason))
+	return nil
+}
+
+// ComplianceService gives operators the AML reports movements raised
+type ComplianceService struct {
+	Repo repository.AMLRepository
+	// Clock is optional; the system clock is used when nil
+	Clock clock.Clock
+}
+
+// ListReports pages through AML reports, newest first
+func (s *ComplianceService) ListReports(ctx context.Context, filter repository.AMLReportFilter) ([]*models.AMLReport, error) {
+	if !filter.From.IsZero() && !filter.To.IsZero() && !filter.To.After(filter.From) {
+		return nil, ErrInvalidAMLReportPeriod
+	}
+	filter.Limit = pagination.ClampLimit(filter.Limit)
+	filter.Offset = max(filter.Offset, 0)
+
+	reports, err := s.Repo.ListAMLReports(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list AML reports: %w", err)
+	}
+	return reports, nil
+}
+
+// ExportReports hands write every AML report raised from from until to, oldest first.
+// A zero to exports up to now. Reports are read in batches, so write sees the first
+// ones before the last are read.
+func (s *ComplianceService) ExportReports(ctx context.Context, from, to time.Time, write func(*models.AMLReport) error) error {
+	if to.IsZero() {
+		to = clock.OrDefault(s.Clock).Now()
+	}
+	if !to.After(from) {
+		return ErrInvalidAMLReportPeriod
+	}
+
+	after := uuid.Nil
+	for {
+		reports, err := s.Repo.ListAMLReportsAfter(ctx, after, from, to, amlExportBatch)
+		if err != nil {
+			return fmt.Errorf("failed to list AML reports: %w", err)
+		}
+		for _, report := range reports {
+			if err := write(report); err != nil {
+				return err
+			}
+		}
+		if len(reports) < amlExportBatch {
+			return nil
+		}
+		after = reports[len(reports)-1].ID
+	}
+}
@@ -0,0 +1,47 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/shanwije/wallet-app/pkg/money"
+)
+
+// ErrAmountOutOfRange is returned for a deposit, withdrawal or transfer below the
+// service's MinAmount or above its MaxAmount, wrapped in an *AmountRangeError
+var ErrAmountOutOfRange = errors.New("amount out of range")
+
+// AmountRangeError reports an amount outside the allowed range. Min or Max is nil when
+// that side is unbounded.
+type AmountRangeError struct {
+	Amount money.Money
+	Min    *decimal.Decimal
+	Max    *decimal.Decimal
+}
+
+func (e *AmountRangeError) Error() string {
+	switch {
+	case e.Min != nil && e.Max != nil:
+		return fmt.Sprintf("%s: %s is not between %s and %s", ErrAmountOutOfRange, e.Amount, e.Min, e.Max)
+	case e.Min != nil:
+		return fmt.Sprintf("%s: %s is below the minimum of %s", ErrAmountOutOfRange, e.Amount, e.Min)
+	default:
+		return fmt.Sprintf("%s: %s is above the maximum of %s", ErrAmountOutOfRange, e.Amount, e.Max)
+	}
+}
+
+func (e *AmountRangeError) Unwrap() error {
+	return ErrAmountOutOfRange
+}
+
+// checkAmountRange rejects an amount below MinAmount or above MaxAmount. The bounds
+// apply to the amount as given, whatever its currency.
+func (s *WalletService) checkAmountRange(amount money.Money) error {
+	if (s.MinAmount != nil && amount.Amount().LessThan(*s.MinAmount)) ||
+		(s.MaxAmount != nil && amount.Amount().GreaterThan(*s.MaxAmount)) {
+		return &AmountRangeError{Amount: amount, Min: s.MinAmount, Max: s.MaxAmount}
+	}
+	return nil
+}
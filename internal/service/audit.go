@@ -0,0 +1,66 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository"
+	"github.com/shanwije/wallet-app/pkg/clock"
+	"github.com/shanwije/wallet-app/pkg/pagination"
+)
+
+// ErrInvalidAuditPeriod is returned when an audit listing's period ends before it starts
+var ErrInvalidAuditPeriod = errors.New("audit period must end after it starts")
+
+// AuditService keeps the audit log of mutating API calls
+type AuditService struct {
+	Repo       repository.AuditRepository
+	WalletRepo repository.WalletRepository
+	// Clock is optional; the system clock is used when nil
+	Clock clock.Clock
+}
+
+// WalletBalance reads the wallet's balance for an audit entry, or nil when there is no
+// such wallet. It reads the primary inside a short transaction, because a replica may
+// not yet show the balance a call has just written.
+func (s *AuditService) WalletBalance(ctx context.Context, walletID uuid.UUID) *decimal.Decimal {
+	var balance *decimal.Decimal
+	repository.NewTxManager(s.WalletRepo).WithinTransaction(ctx, func(ctx context.Context) error {
+		wallet, err := s.WalletRepo.GetWalletSnapshotWithTx(ctx, repository.TxFromContext(ctx), walletID)
+		if err != nil {
+			return err
+		}
+		balance = &wallet.Balance
+		return nil
+	})
+	return balance
+}
+
+// Record stamps the entry and appends it to the audit log
+func (s *AuditService) Record(ctx context.Context, entry *models.AuditEntry) error {
+	entry.CreatedAt = clock.OrDefault(s.Clock).Now()
+	if err := s.Repo.CreateAuditEntry(ctx, entry); err != nil {
+		return fmt.Errorf("failed to record audit entry: %w", err)
+	}
+	return nil
+}
+
+// ListAuditEntries pages through the audit log, newest first
+func (s *AuditService) ListAuditEntries(ctx context.Context, filter repository.AuditFilter) ([]*models.AuditEntry, error) {
+	filter.Limit = pagination.ClampLimit(filter.Limit)
+	filter.Offset = max(filter.Offset, 0)
+	if !filter.From.IsZero() && !filter.To.IsZero() && !filter.To.After(filter.From) {
+		return nil, ErrInvalidAuditPeriod
+	}
+
+	entries, err := s.Repo.ListAuditEntries(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit entries: %w", err)
+	}
+	return entries, nil
+}
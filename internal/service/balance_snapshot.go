@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository"
+	"github.com/shanwije/wallet-app/pkg/clock"
+	"github.com/shanwije/wallet-app/pkg/logger"
+)
+
+// ErrFutureBalance is returned when asking for a balance at a time that has not come yet
+var ErrFutureBalance = errors.New("balance time is in the future")
+
+// snapshotSettleDelay is how long after midnight a day is snapshotted, leaving journals
+// stamped just before midnight time to commit
+const snapshotSettleDelay = 10 * time.Minute
+
+// BalanceSnapshotService records every wallet's end-of-day balance
+type BalanceSnapshotService struct {
+	Repo repository.BalanceSnapshotRepository
+	// Clock is optional; the system clock is used when nil
+	Clock clock.Clock
+}
+
+// Run snapshots any days that have ended every interval until ctx is cancelled
+func (s *BalanceSnapshotService) Run(ctx context.Context, interval time.Duration) {
+	log := logger.FromContext(ctx)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		created, err := s.SnapshotDue(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Error("Balance snapshot run failed", zap.Error(err))
+		} else if created > 0 {
+			log.Info("Recorded balance snapshots", zap.Int64("count", created))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// SnapshotDue snapshots every day that has ended since the latest snapshot, oldest
+// first, so days missed while no worker was running are filled in. With no snapshots
+// yet it starts at the most recent midnight. It returns how many snapshots it wrote.
+// Snapshots already taken are skipped, so several workers can run at once.
+func (s *BalanceSnapshotService) SnapshotDue(ctx context.Context) (int64, error) {
+	lastMidnight := clock.OrDefault(s.Clock).Now().Add(-snapshotSettleDelay).UTC().Truncate(24 * time.Hour)
+
+	latest, err := s.Repo.GetLatestSnapshotTime(ctx)
+	if err != nil {
+		return 0, err
+	}
+	day := lastMidnight
+	if !latest.IsZero() {
+		day = latest.UTC().Add(24 * time.Hour)
+	}
+
+	var total int64
+	for ; !day.After(lastMidnight); day = day.Add(24 * time.Hour) {
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+		created, err := s.Repo.CreateSnapshots(ctx, day)
+		if err != nil {
+			return total, err
+		}
+		total += created
+	}
+	return total, nil
+}
+
+// GetBalanceAt reconstructs the wallet's posted balance at a past time from its latest
+// snapshot before then plus the ledger entries since. Without a snapshot, or without
+// a snapshot repository, the entries are summed from the start of the ledger.
+func (s *WalletService) GetBalanceAt(ctx context.Context, walletID uuid.UUID, at time.Time) (*models.HistoricalBalance, error) {
+	if at.After(s.now()) {
+		return nil, ErrFutureBalance
+	}
+
+	wallet, err := s.WalletRepo.GetWalletByID(ctx, walletID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get wallet: %w", walletLookupError(err))
+	}
+
+	balance := &models.HistoricalBalance{WalletID: walletID, At: at, Currency: wallet.Currency}
+
+	var snapshot *models.BalanceSnapshot
+	if s.Snapshots != nil {
+		snapshot, err = s.Snapshots.GetLatestSnapshot(ctx, walletID, at)
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return nil, fmt.Errorf("failed to get balance snapshot: %w", err)
+		}
+	}
+	if snapshot == nil {
+		balance.Balance, err = s.LedgerRepo.GetWalletLedgerBalanceBefore(ctx, walletID, at)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get balance: %w", err)
+		}
+		return balance, nil
+	}
+
+	delta, err := s.LedgerRepo.GetWalletLedgerBalanceBetween(ctx, walletID, snapshot.AsOf, at)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get balance: %w", err)
+	}
+	balance.Balance = snapshot.Balance.Add(delta)
+	return balance, nil
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository"
+	"github.com/shanwije/wallet-app/internal/repository/mocks"
+	"github.com/shanwije/wallet-app/internal/testutil"
+	"github.com/shanwije/wallet-app/pkg/clock"
+)
+
+func TestSnapshotDueBackfillsMissedDays(t *testing.T) {
+	repo := new(mocks.BalanceSnapshotRepository)
+	service := &BalanceSnapshotService{Repo: repo, Clock: clock.NewFake(time.Date(2024, 6, 12, 3, 0, 0, 0, time.UTC))}
+
+	repo.On("GetLatestSnapshotTime", mock.Anything).Return(time.Date(2024, 6, 10, 0, 0, 0, 0, time.UTC), nil)
+	repo.On("CreateSnapshots", mock.Anything, time.Date(2024, 6, 11, 0, 0, 0, 0, time.UTC)).Return(int64(3), nil).Once()
+	repo.On("CreateSnapshots", mock.Anything, time.Date(2024, 6, 12, 0, 0, 0, 0, time.UTC)).Return(int64(4), nil).Once()
+
+	created, err := service.SnapshotDue(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), created)
+	repo.AssertExpectations(t)
+}
+
+func TestSnapshotDueWaitsForTheDayToSettle(t *testing.T) {
+	repo := new(mocks.BalanceSnapshotRepository)
+	service := &BalanceSnapshotService{Repo: repo, Clock: clock.NewFake(time.Date(2024, 6, 12, 0, 5, 0, 0, time.UTC))}
+
+	repo.On("GetLatestSnapshotTime", mock.Anything).Return(time.Date(2024, 6, 11, 0, 0, 0, 0, time.UTC), nil)
+
+	created, err := service.SnapshotDue(context.Background())
+
+	require.NoError(t, err)
+	assert.Zero(t, created)
+	repo.AssertNotCalled(t, "CreateSnapshots", mock.Anything, mock.Anything)
+}
+
+func TestSnapshotDueStartsAtLastMidnight(t *testing.T) {
+	repo := new(mocks.BalanceSnapshotRepository)
+	service := &BalanceSnapshotService{Repo: repo, Clock: clock.NewFake(time.Date(2024, 6, 12, 3, 0, 0, 0, time.UTC))}
+
+	repo.On("GetLatestSnapshotTime", mock.Anything).Return(time.Time{}, nil)
+	repo.On("CreateSnapshots", mock.Anything, time.Date(2024, 6, 12, 0, 0, 0, 0, time.UTC)).Return(int64(2), nil).Once()
+
+	created, err := service.SnapshotDue(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), created)
+	repo.AssertExpectations(t)
+}
+
+func TestGetBalanceAt(t *testing.T) {
+	walletID := uuid.New()
+	now := time.Date(2024, 6, 12, 9, 0, 0, 0, time.UTC)
+	at := time.Date(2024, 6, 11, 15, 30, 0, 0, time.UTC)
+	snapshotAt := time.Date(2024, 6, 11, 0, 0, 0, 0, time.UTC)
+
+	setup := func() (*WalletService, *mocks.LedgerRepository, *mocks.BalanceSnapshotRepository) {
+		service, walletRepo, ledgerRepo := setupWalletService()
+		service.Clock = clock.NewFake(now)
+		snapshots := new(mocks.BalanceSnapshotRepository)
+		service.Snapshots = snapshots
+		walletRepo.On("GetWalletByID", mock.Anything, walletID).Return(testutil.Wallet(walletID, 500.0), nil)
+		return service, ledgerRepo, snapshots
+	}
+
+	t.Run("From a snapshot", func(t *testing.T) {
+		service, ledgerRepo, snapshots := setup()
+		snapshots.On("GetLatestSnapshot", mock.Anything, walletID, at).
+			Return(&models.BalanceSnapshot{WalletID: walletID, AsOf: snapshotAt, Balance: decimal.NewFromInt(120), Currency: "USD"}, nil)
+		ledgerRepo.On("GetWalletLedgerBalanceBetween", mock.Anything, walletID, snapshotAt, at).Return(decimal.NewFromInt(-20), nil)
+
+		balance, err := service.GetBalanceAt(context.Background(), walletID, at)
+
+		require.NoError(t, err)
+		assert.True(t, balance.Balance.Equal(decimal.NewFromInt(100)))
+		assert.EqualValues(t, "USD", balance.Currency)
+		assert.Equal(t, at, balance.At)
+		ledgerRepo.AssertNotCalled(t, "GetWalletLedgerBalanceBefore", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Without a snapshot", func(t *testing.T) {
+		service, ledgerRepo, snapshots := setup()
+		snapshots.On("GetLatestSnapshot", mock.Anything, walletID, at).Return(nil, repository.ErrNotFound)
+		ledgerRepo.On("GetWalletLedgerBalanceBefore", mock.Anything, walletID, at).Return(decimal.NewFromInt(75), nil)
+
+		balance, err := service.GetBalanceAt(context.Background(), walletID, at)
+
+		require.NoError(t, err)
+		assert.True(t, balance.Balance.Equal(decimal.NewFromInt(75)))
+	})
+
+	t.Run("In the future", func(t *testing.T) {
+		service, _, snapshots := setup()
+
+		_, err := service.GetBalanceAt(context.Background(), walletID, now.Add(time.Minute))
+
+		assert.ErrorIs(t, err, ErrFutureBalance)
+		snapshots.AssertNotCalled(t, "GetLatestSnapshot", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/pkg/money"
+)
+
+// BalanceNotifier pushes balance changes to whoever is watching the wallets, such as
+// WebSocket clients. It is called once the transaction making the changes has
+// committed, on the request's goroutine, so it must not wait on slow subscribers.
+type BalanceNotifier interface {
+	NotifyBalance(ctx context.Context, update BalanceUpdate)
+}
+
+// BalanceUpdate tells a wallet's owner that money moved in or out of the wallet.
+// Amount is what moved, in the wallet's currency, and Balance is the wallet's balance
+// once the transaction that moved it committed.
+type BalanceUpdate struct {
+	WalletID   uuid.UUID       `json:"wallet_id"`
+	Event      string          `json:"event"`
+	JournalID  uuid.UUID       `json:"journal_id"`
+	Amount     decimal.Decimal `json:"amount"`
+	Currency   money.Currency  `json:"currency"`
+	Balance    decimal.Decimal `json:"balance"`
+	OccurredAt time.Time       `json:"occurred_at"`
+}
+
+// balanceUpdates lists the updates for the journals a committed transaction recorded:
+// deposits and withdrawals for their wallet, and transfers for the wallet paid into.
+// Other journals, such as adjustments and reversals, are left out.
+func balanceUpdates(changes *txChanges) []BalanceUpdate {
+	var updates []BalanceUpdate
+	for _, journal := range changes.journals {
+		var direction string
+		switch journal.Type {
+		case models.JournalTypeDeposit, models.JournalTypeTransfer:
+			direction = models.EntryDirectionCredit
+		case models.JournalTypeWithdraw:
+			direction = models.EntryDirectionDebit
+		default:
+			continue
+		}
+
+		event := models.NewWalletEvent(journal).Type
+		for _, entry := range journal.Entries {
+			if entry.Direction != direction || entry.WalletID == nil {
+				continue
+			}
+			wallet, ok := changes.wallets[*entry.WalletID]
+			if !ok {
+				continue
+			}
+			updates = append(updates, BalanceUpdate{
+				WalletID:   wallet.ID,
+				Event:      event,
+				JournalID:  journal.ID,
+				Amount:     entry.Amount,
+				Currency:   entry.Currency,
+				Balance:    wallet.Balance,
+				OccurredAt: journal.CreatedAt,
+			})
+		}
+	}
+	return updates
+}
+
+// notifyBalances tells Notifier about the money a committed transaction moved
+func (s *WalletService) notifyBalances(ctx context.Context, changes *txChanges) {
+	if s.Notifier == nil {
+		return
+	}
+	for _, update := range balanceUpdates(changes) {
+		s.Notifier.NotifyBalance(ctx, update)
+	}
+}
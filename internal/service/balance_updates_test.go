@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/testutil"
+)
+
+// recordingNotifier is a BalanceNotifier that keeps what it is told
+type recordingNotifier struct {
+	updates []BalanceUpdate
+}
+
+func (n *recordingNotifier) NotifyBalance(_ context.Context, update BalanceUpdate) {
+	n.updates = append(n.updates, update)
+}
+
+func TestCommittedDepositNotifiesItsWallet(t *testing.T) {
+	service, walletRepo, ledgerRepo := setupWalletService()
+	notifier := &recordingNotifier{}
+	service.Notifier = notifier
+
+	walletID := uuid.New()
+	walletRepo.On("BeginTx", mock.Anything).Return((*sql.Tx)(nil), nil)
+	walletRepo.On("GetWalletByIDWithTx", mock.Anything, (*sql.Tx)(nil), walletID).Return(testutil.Wallet(walletID, 100.0), nil).Once()
+	walletRepo.On("GetWalletByIDWithTx", mock.Anything, (*sql.Tx)(nil), walletID).Return(testutil.Wallet(walletID, 100.0), nil).Once()
+	walletRepo.On("UpdateBalanceWithTx", mock.Anything, (*sql.Tx)(nil), walletID, mock.Anything, mock.Anything).Return(nil)
+	ledgerRepo.On("CreateJournalWithTx", mock.Anything, (*sql.Tx)(nil), mock.AnythingOfType("*models.Journal")).Return(assert.AnError).Once()
+	ledgerRepo.On("CreateJournalWithTx", mock.Anything, (*sql.Tx)(nil), mock.AnythingOfType("*models.Journal")).Return(nil).Once()
+
+	// A rolled back deposit tells no one
+	_, err := service.Deposit(context.Background(), walletID, testutil.USD(decimal.NewFromInt(50)), "")
+	require.Error(t, err)
+	assert.Empty(t, notifier.updates)
+
+	_, err = service.Deposit(context.Background(), walletID, testutil.USD(decimal.NewFromInt(50)), "")
+	require.NoError(t, err)
+	require.Len(t, notifier.updates, 1)
+	update := notifier.updates[0]
+	assert.Equal(t, walletID, update.WalletID)
+	assert.Equal(t, models.EventWalletDeposit, update.Event)
+	assert.True(t, update.Amount.Equal(decimal.NewFromInt(50)))
+	assert.True(t, update.Balance.Equal(decimal.NewFromInt(150)))
+}
+
+func TestTransfersUpdateTheWalletPaidInto(t *testing.T) {
+	from := testutil.Wallet(uuid.New(), 70)
+	to := testutil.Wallet(uuid.New(), 30)
+	amount := testutil.USD(decimal.NewFromInt(30))
+
+	changes := &txChanges{
+		wallets: map[uuid.UUID]*models.Wallet{from.ID: from, to.ID: to},
+		journals: []*models.Journal{
+			newJournal(models.JournalTypeTransfer, nil, nil, debit(&from.ID, amount), credit(&to.ID, amount)),
+			newJournal(models.JournalTypeAdjustment, nil, nil, debit(nil, amount), credit(&to.ID, amount)),
+		},
+	}
+
+	updates := balanceUpdates(changes)
+	require.Len(t, updates, 1)
+	assert.Equal(t, to.ID, updates[0].WalletID)
+	assert.Equal(t, models.EventWalletTransfer, updates[0].Event)
+	assert.True(t, updates[0].Balance.Equal(decimal.NewFromInt(30)))
+}
@@ -0,0 +1,187 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository"
+	"github.com/shanwije/wallet-app/pkg/money"
+)
+
+// MaxBatchTransfers caps the transfers in one batch so a single request cannot hold
+// its wallet locks for longer than the transaction timeout allows
+const MaxBatchTransfers = 100
+
+// ErrInvalidBatchSize is returned for an empty batch or one over MaxBatchTransfers
+var ErrInvalidBatchSize = fmt.Errorf("a batch must contain between 1 and %d transfers", MaxBatchTransfers)
+
+// BatchTransferItem is one payment of a batch
+type BatchTransferItem struct {
+	ToWalletID  uuid.UUID
+	Amount      money.Money
+	Description string
+}
+
+// BatchItemError reports the item that made a batch fail. Nothing in the batch was posted.
+type BatchItemError struct {
+	Index int
+	Err   error
+}
+
+func (e *BatchItemError) Error() string {
+	return fmt.Sprintf("transfer %d: %v", e.Index, e.Err)
+}
+
+func (e *BatchItemError) Unwrap() error {
+	return e.Err
+}
+
+// BatchTransfer pays every item out of the source wallet in a single transaction, so
+// either all of the transfers are posted or none are. It returns one journal per item,
+// in order. When an item fails the error is a *BatchItemError naming it.
+//
+// A non-empty idempotencyKey is suffixed with each item's position, giving every
+// journal its own key; retrying the same batch returns the journals already recorded.
+func (s *WalletService) BatchTransfer(ctx context.Context, fromWalletID uuid.UUID, items []BatchTransferItem, idempotencyKey string) ([]*models.Journal, error) {
+	if len(items) == 0 || len(items) > MaxBatchTransfers {
+		return nil, ErrInvalidBatchSize
+	}
+
+	journals := make([]*models.Journal, len(items))
+	fees := make([]money.Money, len(items))
+	total := money.Zero(items[0].Amount.Currency())
+	for i, item := range items {
+		if err := s.validateTransferAmount(item.Amount, fromWalletID, item.ToWalletID); err != nil {
+			return nil, &BatchItemError{Index: i, Err: err}
+		}
+		total = money.New(total.Amount().Add(item.Amount.Amount()), total.Currency())
+		fee, err := s.movementFee(ctx, models.JournalTypeTransfer, fromWalletID, item.Amount)
+		if err != nil {
+			return nil, err
+		}
+		fees[i] = fee
+
+		description := item.Description
+		toWalletID := item.ToWalletID
+		journals[i] = newJournal(models.JournalTypeTransfer, &description, batchItemKey(fromWalletID, idempotencyKey, i),
+			debit(&fromWalletID, item.Amount),
+			credit(&toWalletID, item.Amount),
+		)
+	}
+	// A batch cannot be held for confirmation, so one whose items add up to more than
+	// the threshold is refused
+	if err := s.checkConfirmation(total); err != nil {
+		return nil, err
+	}
+
+	// The batch commits as a whole, so the first journal stands in for all of them
+	replayed, err := s.idempotent(ctx, journals[0], func() error {
+		for i, item := range items {
+			if err := s.assessRisk(ctx, models.JournalTypeTransfer, fromWalletID, &item.ToWalletID, item.Amount); err != nil {
+				return &BatchItemError{Index: i, Err: err}
+			}
+		}
+		return s.withTx(ctx, "batch transfer", func(ctx context.Context, tx *sql.Tx) error {
+			if err := s.lockBatchWallets(ctx, tx, fromWalletID, items, fees); err != nil {
+				return err
+			}
+			for i, item := range items {
+				_, to, err := s.transferExecution(ctx, tx, fromWalletID, item.ToWalletID, item.Amount, fees[i], journals[i])
+				if i > 0 && errors.Is(err, repository.ErrDuplicate) {
+					// Only a replay of the whole batch is recognised, which the
+					// first journal's key detects
+					err = ErrIdempotencyKeyReused
+				}
+				if err == nil {
+					err = s.awaitAcceptance(ctx, tx, to, journals[i])
+				}
+				if err != nil {
+					return &BatchItemError{Index: i, Err: err}
+				}
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	if replayed {
+		return s.recordedBatch(ctx, fromWalletID, idempotencyKey, journals)
+	}
+
+	return journals, nil
+}
+
+// batchItemKey is the idempotency key of the journal for the batch's index-th item
+func batchItemKey(fromWalletID uuid.UUID, idempotencyKey string, index int) *string {
+	if idempotencyKey == "" {
+		return nil
+	}
+	return scopedIdempotencyKey(fromWalletID, idempotencyKey+":"+strconv.Itoa(index))
+}
+
+// recordedBatch loads the journals a replayed batch recorded, checking that the
+// retry asks for the same transfers as the original
+func (s *WalletService) recordedBatch(ctx context.Context, fromWalletID uuid.UUID, idempotencyKey string, expected []*models.Journal) ([]*models.Journal, error) {
+	recorded := make([]*models.Journal, len(expected))
+	for i, journal := range expected {
+		found, err := s.LedgerRepo.GetJournalByIdempotencyKey(ctx, *journal.IdempotencyKey)
+		if errors.Is(err, repository.ErrNotFound) {
+			// The original batch had fewer transfers
+			return nil, ErrIdempotencyKeyReused
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up idempotency key: %w", err)
+		}
+		if !sameMovement(found, journal) {
+			return nil, ErrIdempotencyKeyReused
+		}
+		recorded[i] = found
+	}
+
+	// Nor may the original batch have had more transfers
+	if _, err := s.LedgerRepo.GetJournalByIdempotencyKey(ctx, *batchItemKey(fromWalletID, idempotencyKey, len(expected))); err == nil {
+		return nil, ErrIdempotencyKeyReused
+	}
+
+	return recorded, nil
+}
+
+// lockBatchWallets locks the source and every recipient of a batch up front, with the
+// fee wallet when any item is charged a fee, in lockOrder, so the batch cannot
+// deadlock against transfers between the same wallets. Optimistic locking takes no
+// row locks and skips this.
+func (s *WalletService) lockBatchWallets(ctx context.Context, tx *sql.Tx, fromWalletID uuid.UUID, items []BatchTransferItem, fees []money.Money) error {
+	if s.OptimisticLocking {
+		return nil
+	}
+
+	ids := make([]uuid.UUID, 0, len(items)+2)
+	ids = append(ids, fromWalletID)
+	for _, item := range items {
+		ids = append(ids, item.ToWalletID)
+	}
+	if slices.ContainsFunc(fees, money.Money.IsPositive) {
+		ids = append(ids, s.FeeWalletID)
+	}
+
+	for _, id := range lockOrder(ids...) {
+		if _, err := s.getWalletForUpdate(ctx, tx, id); err != nil {
+			if id == fromWalletID {
+				return fmt.Errorf("failed to get source wallet: %w", err)
+			}
+			if !slices.ContainsFunc(items, func(item BatchTransferItem) bool { return item.ToWalletID == id }) {
+				return fmt.Errorf("failed to get fee wallet: %w", err)
+			}
+			index := slices.IndexFunc(items, func(item BatchTransferItem) bool { return item.ToWalletID == id })
+			return &BatchItemError{Index: index, Err: fmt.Errorf("failed to get destination wallet: %w", err)}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository"
+	"github.com/shanwije/wallet-app/internal/testutil"
+)
+
+func TestBatchTransferPostsEveryTransfer(t *testing.T) {
+	service, walletRepo, ledgerRepo := setupWalletService()
+
+	fromWalletID := uuid.New()
+	toWalletIDs := []uuid.UUID{uuid.New(), uuid.New()}
+	walletRepo.On("BeginTx", mock.Anything).Return((*sql.Tx)(nil), nil).Once()
+	walletRepo.On("GetWalletByIDWithTx", mock.Anything, (*sql.Tx)(nil), fromWalletID).Return(testutil.Wallet(fromWalletID, 100.0), nil)
+	for _, id := range toWalletIDs {
+		walletRepo.On("GetWalletByIDWithTx", mock.Anything, (*sql.Tx)(nil), id).Return(testutil.Wallet(id, 0), nil)
+	}
+	walletRepo.On("UpdateBalanceWithTx", mock.Anything, (*sql.Tx)(nil), mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	ledgerRepo.On("CreateJournalWithTx", mock.Anything, (*sql.Tx)(nil), mock.AnythingOfType("*models.Journal")).Return(nil).Twice()
+
+	journals, err := service.BatchTransfer(context.Background(), fromWalletID, []BatchTransferItem{
+		{ToWalletID: toWalletIDs[0], Amount: testutil.USD(decimal.NewFromInt(30)), Description: "Salary"},
+		{ToWalletID: toWalletIDs[1], Amount: testutil.USD(decimal.NewFromInt(20)), Description: "Salary"},
+	}, "")
+
+	require.NoError(t, err)
+	require.Len(t, journals, 2)
+	for i, journal := range journals {
+		assert.Equal(t, models.JournalTypeTransfer, journal.Type)
+		assert.Equal(t, toWalletIDs[i], *journal.Entries[1].WalletID)
+		assert.NoError(t, journal.Validate())
+	}
+	walletRepo.AssertExpectations(t)
+	ledgerRepo.AssertExpectations(t)
+}
+
+func TestBatchTransferFailsAsAWhole(t *testing.T) {
+	service, walletRepo, ledgerRepo := setupWalletService()
+
+	fromWalletID := uuid.New()
+	toWalletID := uuid.New()
+	closedWalletID := uuid.New()
+	closedWallet := testutil.Wallet(closedWalletID, 0)
+	closedWallet.Status = models.WalletStatusClosed
+
+	walletRepo.On("BeginTx", mock.Anything).Return((*sql.Tx)(nil), nil).Once()
+	walletRepo.On("GetWalletByIDWithTx", mock.Anything, (*sql.Tx)(nil), fromWalletID).Return(testutil.Wallet(fromWalletID, 100.0), nil)
+	walletRepo.On("GetWalletByIDWithTx", mock.Anything, (*sql.Tx)(nil), toWalletID).Return(testutil.Wallet(toWalletID, 0), nil)
+	walletRepo.On("GetWalletByIDWithTx", mock.Anything, (*sql.Tx)(nil), closedWalletID).Return(closedWallet, nil)
+	walletRepo.On("UpdateBalanceWithTx", mock.Anything, (*sql.Tx)(nil), mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	ledgerRepo.On("CreateJournalWithTx", mock.Anything, (*sql.Tx)(nil), mock.AnythingOfType("*models.Journal")).Return(nil).Once()
+
+	journals, err := service.BatchTransfer(context.Background(), fromWalletID, []BatchTransferItem{
+		{ToWalletID: toWalletID, Amount: testutil.USD(decimal.NewFromInt(30))},
+		{ToWalletID: closedWalletID, Amount: testutil.USD(decimal.NewFromInt(20))},
+		{ToWalletID: toWalletID, Amount: testutil.USD(decimal.NewFromInt(10))},
+	}, "")
+
+	assert.Nil(t, journals)
+	var itemErr *BatchItemError
+	require.True(t, errors.As(err, &itemErr))
+	assert.Equal(t, 1, itemErr.Index)
+	assert.ErrorIs(t, err, models.ErrWalletClosed)
+	ledgerRepo.AssertExpectations(t)
+}
+
+func TestBatchTransferValidation(t *testing.T) {
+	service, walletRepo, _ := setupWalletService()
+	fromWalletID := uuid.New()
+
+	_, err := service.BatchTransfer(context.Background(), fromWalletID, nil, "")
+	assert.ErrorIs(t, err, ErrInvalidBatchSize)
+
+	_, err = service.BatchTransfer(context.Background(), fromWalletID, make([]BatchTransferItem, MaxBatchTransfers+1), "")
+	assert.ErrorIs(t, err, ErrInvalidBatchSize)
+
+	_, err = service.BatchTransfer(context.Background(), fromWalletID, []BatchTransferItem{
+		{ToWalletID: uuid.New(), Amount: testutil.USD(decimal.NewFromInt(10))},
+		{ToWalletID: fromWalletID, Amount: testutil.USD(decimal.NewFromInt(10))},
+	}, "")
+	var itemErr *BatchItemError
+	require.True(t, errors.As(err, &itemErr))
+	assert.Equal(t, 1, itemErr.Index)
+
+	walletRepo.AssertNotCalled(t, "BeginTx", mock.Anything)
+}
+
+func TestBatchTransferReplaysIdempotencyKey(t *testing.T) {
+	service, walletRepo, ledgerRepo := setupWalletService()
+
+	fromWalletID := uuid.New()
+	items := []BatchTransferItem{
+		{ToWalletID: uuid.New(), Amount: testutil.USD(decimal.NewFromInt(30))},
+		{ToWalletID: uuid.New(), Amount: testutil.USD(decimal.NewFromInt(20))},
+	}
+	for i, item := range items {
+		key := batchItemKey(fromWalletID, "payroll-06", i)
+		recorded := newJournal(models.JournalTypeTransfer, nil, key,
+			debit(&fromWalletID, item.Amount),
+			credit(&item.ToWalletID, item.Amount),
+		)
+		recorded.ID = uuid.New()
+		ledgerRepo.On("GetJournalByIdempotencyKey", mock.Anything, *key).Return(recorded, nil)
+	}
+	ledgerRepo.On("GetJournalByIdempotencyKey", mock.Anything, *batchItemKey(fromWalletID, "payroll-06", 2)).Return(nil, repository.ErrNotFound)
+
+	journals, err := service.BatchTransfer(context.Background(), fromWalletID, items, "payroll-06")
+
+	require.NoError(t, err)
+	require.Len(t, journals, 2)
+	assert.NotEqual(t, uuid.Nil, journals[0].ID)
+	walletRepo.AssertNotCalled(t, "BeginTx", mock.Anything)
+
+	// The same key with a different batch is refused
+	items[1].Amount = testutil.USD(decimal.NewFromInt(25))
+	_, err = service.BatchTransfer(context.Background(), fromWalletID, items, "payroll-06")
+	assert.ErrorIs(t, err, ErrIdempotencyKeyReused)
+}
@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+
+	"github.com/shanwije/wallet-app/internal/models"
+)
+
+// ErrInvalidAnalyticsPeriod is returned when an analytics period does not end after it starts
+var ErrInvalidAnalyticsPeriod = errors.New("analytics period must end after it starts")
+
+// classificationKey is the context key of a classification
+type classificationKey struct{}
+
+// classification is the category and tags set by WithClassification
+type classification struct {
+	category *string
+	tags     []string
+}
+
+// WithClassification returns a context in which the deposit, withdrawal or transfer
+// made is filed under category and tags. Both are trimmed and lowercased so they group
+// however the client spelled them; blank ones are ignored and repeated tags dropped.
+func WithClassification(ctx context.Context, category string, tags []string) context.Context {
+	var filed classification
+	if category = normalizeCategory(category); category != "" {
+		filed.category = &category
+	}
+	for _, tag := range tags {
+		if tag = normalizeCategory(tag); tag != "" && !slices.Contains(filed.tags, tag) {
+			filed.tags = append(filed.tags, tag)
+		}
+	}
+	return context.WithValue(ctx, classificationKey{}, filed)
+}
+
+// normalizeCategory is the form categories and tags are stored and looked up in
+func normalizeCategory(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// classify files journal under the category and tags ctx carries, if any
+func classify(ctx context.Context, journal *models.Journal) {
+	journal.Category, journal.Tags = classificationOf(ctx)
+}
+
+// classificationOf returns the category and tags ctx carries, if any
+func classificationOf(ctx context.Context) (*string, []string) {
+	filed, _ := ctx.Value(classificationKey{}).(classification)
+	return filed.category, filed.tags
+}
+
+// GetTransactionHistoryInCategory returns the wallet's transactions filed under
+// category, newest first
+func (s *WalletService) GetTransactionHistoryInCategory(ctx context.Context, walletID uuid.UUID, category string) ([]*models.Transaction, error) {
+	transactions, err := s.GetTransactionHistory(ctx, walletID)
+	if err != nil {
+		return nil, err
+	}
+
+	category = normalizeCategory(category)
+	return slices.DeleteFunc(transactions, func(transaction *models.Transaction) bool {
+		return transaction.Category == nil || *transaction.Category != category
+	}), nil
+}
+
+// GetAnalytics totals the money that came into and went out of the wallet over
+// [from, to) by category, named categories first in alphabetical order. A zero from
+// starts when the wallet was created and a zero to ends now.
+func (s *WalletService) GetAnalytics(ctx context.Context, walletID uuid.UUID, from, to time.Time) (*models.WalletAnalytics, error) {
+	wallet, err := s.GetBalance(ctx, walletID)
+	if err != nil {
+		return nil, err
+	}
+
+	if from.IsZero() {
+		from = wallet.CreatedAt
+	}
+	if to.IsZero() {
+		to = s.now()
+	}
+	if !to.After(from) {
+		return nil, ErrInvalidAnalyticsPeriod
+	}
+
+	totals, err := s.LedgerRepo.GetCategoryTotals(ctx, walletID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get category totals: %w", err)
+	}
+	slices.SortFunc(totals, func(a, b *models.CategoryTotal) int {
+		switch {
+		case a.Category == nil:
+			return 1
+		case b.Category == nil:
+			return -1
+		default:
+			return strings.Compare(*a.Category, *b.Category)
+		}
+	})
+
+	analytics := &models.WalletAnalytics{
+		WalletID:   walletID,
+		Currency:   wallet.Currency,
+		From:       from,
+		To:         to,
+		AmountIn:   decimal.Zero,
+		AmountOut:  decimal.Zero,
+		Categories: totals,
+	}
+	for _, total := range totals {
+		total.NetAmount = total.AmountIn.Sub(total.AmountOut)
+		analytics.AmountIn = analytics.AmountIn.Add(total.AmountIn)
+		analytics.AmountOut = analytics.AmountOut.Add(total.AmountOut)
+	}
+	return analytics, nil
+}
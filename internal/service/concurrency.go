@@ -0,0 +1,121 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository"
+	"github.com/shopspring/decimal"
+)
+
+// ErrConcurrentUpdate is returned when a transaction kept conflicting with other
+// transactions updating the same wallets and gave up
+var ErrConcurrentUpdate = errors.New("wallet was updated concurrently")
+
+// ErrVersionMismatch is returned when a wallet is not at the version the caller
+// expected it to be at, because it changed after the caller read it
+var ErrVersionMismatch = errors.New("wallet has changed since it was read")
+
+// expectedVersionKey is the context key of an expectedVersion
+type expectedVersionKey struct{}
+
+// expectedVersion is the precondition set by WithExpectedVersion
+type expectedVersion struct {
+	walletID uuid.UUID
+	versions []int64
+}
+
+// WithExpectedVersion returns a context in which money is only moved out of the
+// wallet while it is at one of versions, as a client that decided to act on the
+// balance it read requires. Any other version fails with ErrVersionMismatch.
+func WithExpectedVersion(ctx context.Context, walletID uuid.UUID, versions ...int64) context.Context {
+	return context.WithValue(ctx, expectedVersionKey{}, expectedVersion{walletID: walletID, versions: versions})
+}
+
+// checkExpectedVersion returns ErrVersionMismatch when ctx expects the wallet at
+// another version
+func checkExpectedVersion(ctx context.Context, wallet *models.Wallet) error {
+	expected, ok := ctx.Value(expectedVersionKey{}).(expectedVersion)
+	if !ok || expected.walletID != wallet.ID || slices.Contains(expected.versions, wallet.Version) {
+		return nil
+	}
+	return ErrVersionMismatch
+}
+
+// getWalletForUpdate reads a wallet that tx is about to change. By default its row is
+// locked until tx ends; with OptimisticLocking it is read without a lock and the
+// version check on the update detects any concurrent writer instead. A wallet not at
+// the version set by WithExpectedVersion fails with ErrVersionMismatch.
+func (s *WalletService) getWalletForUpdate(ctx context.Context, tx *sql.Tx, id uuid.UUID) (*models.Wallet, error) {
+	var wallet *models.Wallet
+	var err error
+	if s.OptimisticLocking {
+		wallet, err = s.WalletRepo.GetWalletSnapshotWithTx(ctx, tx, id)
+	} else {
+		wallet, err = s.WalletRepo.GetWalletByIDWithTx(ctx, tx, id)
+	}
+	if err != nil {
+		return nil, walletLookupError(err)
+	}
+	if err := checkExpectedVersion(ctx, wallet); err != nil {
+		return nil, err
+	}
+	return wallet, nil
+}
+
+// lockOrder returns the distinct wallet IDs in the order their rows must be locked.
+// Every transaction that locks more than one wallet takes them in this order, so two
+// of them can never wait on each other.
+func lockOrder(ids ...uuid.UUID) []uuid.UUID {
+	ordered := slices.Clone(ids)
+	slices.SortFunc(ordered, func(a, b uuid.UUID) int { return bytes.Compare(a[:], b[:]) })
+	return slices.Compact(ordered)
+}
+
+// walletLookupError reports a wallet lookup that found nothing as ErrWalletNotFound
+func walletLookupError(err error) error {
+	if errors.Is(err, repository.ErrNotFound) {
+		return ErrWalletNotFound
+	}
+	return err
+}
+
+// The setters below write one field of a wallet read by getWalletForUpdate. Each write
+// is a compare-and-swap on the version the wallet was read at and advances it, so a
+// transaction may update the same wallet more than once. Once the transaction commits
+// the wallet is dropped from the cache and its new balance announced to subscribers.
+
+func (s *WalletService) setBalance(ctx context.Context, tx *sql.Tx, wallet *models.Wallet, balance decimal.Decimal) error {
+	if err := s.WalletRepo.UpdateBalanceWithTx(ctx, tx, wallet.ID, balance, wallet.Version); err != nil {
+		return err
+	}
+	wallet.Balance = balance
+	wallet.Version++
+	walletChanged(ctx, wallet)
+	return nil
+}
+
+func (s *WalletService) setHeldBalance(ctx context.Context, tx *sql.Tx, wallet *models.Wallet, heldBalance decimal.Decimal) error {
+	if err := s.WalletRepo.UpdateHeldBalanceWithTx(ctx, tx, wallet.ID, heldBalance, wallet.Version); err != nil {
+		return err
+	}
+	wallet.HeldBalance = heldBalance
+	wallet.Version++
+	walletChanged(ctx, wallet)
+	return nil
+}
+
+func (s *WalletService) setStatus(ctx context.Context, tx *sql.Tx, wallet *models.Wallet, status string) error {
+	if err := s.WalletRepo.UpdateStatusWithTx(ctx, tx, wallet.ID, status, wallet.Version); err != nil {
+		return err
+	}
+	wallet.Status = status
+	wallet.Version++
+	walletChanged(ctx, wallet)
+	return nil
+}
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository"
+	"github.com/shanwije/wallet-app/internal/repository/mocks"
+	"github.com/shanwije/wallet-app/internal/testutil"
+)
+
+func TestOptimisticDepositRetriesAfterVersionConflict(t *testing.T) {
+	service, walletRepo, ledgerRepo := setupWalletService()
+	service.OptimisticLocking = true
+
+	walletID := uuid.New()
+	stale := testutil.Wallet(walletID, 100.0)
+	stale.Version = 4
+	// Another deposit of 10 committed between the first read and write
+	fresh := testutil.Wallet(walletID, 110.0)
+	fresh.Version = 5
+	conflict := fmt.Errorf("wallet %s: %w", walletID, repository.ErrVersionConflict)
+
+	walletRepo.On("BeginTx", mock.Anything).Return((*sql.Tx)(nil), nil).Twice()
+	walletRepo.On("GetWalletSnapshotWithTx", mock.Anything, (*sql.Tx)(nil), walletID).Return(stale, nil).Once()
+	walletRepo.On("GetWalletSnapshotWithTx", mock.Anything, (*sql.Tx)(nil), walletID).Return(fresh, nil).Once()
+	walletRepo.On("UpdateBalanceWithTx", mock.Anything, (*sql.Tx)(nil), walletID, mock.Anything, int64(4)).Return(conflict).Once()
+	walletRepo.On("UpdateBalanceWithTx", mock.Anything, (*sql.Tx)(nil), walletID, mock.MatchedBy(decimal.NewFromInt(160).Equal), int64(5)).Return(nil).Once()
+	ledgerRepo.On("CreateJournalWithTx", mock.Anything, (*sql.Tx)(nil), mock.AnythingOfType("*models.Journal")).Return(nil).Once()
+
+	wallet, err := service.Deposit(context.Background(), walletID, testutil.USD(decimal.NewFromInt(50)), "")
+
+	require.NoError(t, err)
+	assert.True(t, wallet.Balance.Equal(decimal.NewFromInt(160)))
+	assert.Equal(t, int64(6), wallet.Version)
+	walletRepo.AssertNotCalled(t, "GetWalletByIDWithTx", mock.Anything, mock.Anything, mock.Anything)
+	walletRepo.AssertExpectations(t)
+	ledgerRepo.AssertExpectations(t)
+}
+
+func TestOptimisticDepositGivesUpAfterRepeatedConflicts(t *testing.T) {
+	service, walletRepo, ledgerRepo := setupWalletService()
+	service.OptimisticLocking = true
+
+	walletID := uuid.New()
+	conflict := fmt.Errorf("wallet %s: %w", walletID, repository.ErrVersionConflict)
+
+	walletRepo.On("BeginTx", mock.Anything).Return((*sql.Tx)(nil), nil)
+	walletRepo.On("GetWalletSnapshotWithTx", mock.Anything, (*sql.Tx)(nil), walletID).Return(testutil.Wallet(walletID, 100.0), nil)
+	walletRepo.On("UpdateBalanceWithTx", mock.Anything, (*sql.Tx)(nil), walletID, mock.Anything, mock.Anything).Return(conflict)
+
+	_, err := service.Deposit(context.Background(), walletID, testutil.USD(decimal.NewFromInt(50)), "")
+
+	assert.ErrorIs(t, err, ErrConcurrentUpdate)
+	assert.ErrorIs(t, err, repository.ErrVersionConflict)
+	walletRepo.AssertNumberOfCalls(t, "BeginTx", defaultTxRetries+1)
+	ledgerRepo.AssertNotCalled(t, "CreateJournalWithTx", mock.Anything, mock.Anything, mock.Anything)
+}
+
+// rowLocks stands in for the database's row locks: reading a wallet for update takes
+// its lock, and writing the transfer's journal, the last step before commit, releases it
+type rowLocks struct {
+	rows map[uuid.UUID]*sync.Mutex
+}
+
+func (l *rowLocks) release(journal *models.Journal) {
+	for _, entry := range journal.Entries {
+		if entry.WalletID != nil {
+			l.rows[*entry.WalletID].Unlock()
+		}
+	}
+}
+
+type lockingWalletRepository struct {
+	*mocks.WalletRepository
+	locks *rowLocks
+}
+
+func (r *lockingWalletRepository) GetWalletByIDWithTx(ctx context.Context, tx *sql.Tx, id uuid.UUID) (*models.Wallet, error) {
+	r.locks.rows[id].Lock()
+	// Give the opposite transfer time to take its first lock
+	time.Sleep(time.Millisecond)
+	return testutil.Wallet(id, 1000.0), nil
+}
+
+type lockingLedgerRepository struct {
+	*mocks.LedgerRepository
+	locks *rowLocks
+}
+
+func (r *lockingLedgerRepository) CreateJournalWithTx(ctx context.Context, tx *sql.Tx, journal *models.Journal) error {
+	r.locks.release(journal)
+	return nil
+}
+
+func TestOppositeTransfersDoNotDeadlock(t *testing.T) {
+	service, walletRepo, ledgerRepo := setupWalletService()
+	walletA, walletB := uuid.New(), uuid.New()
+	locks := &rowLocks{rows: map[uuid.UUID]*sync.Mutex{walletA: {}, walletB: {}}}
+	service.WalletRepo = &lockingWalletRepository{WalletRepository: walletRepo, locks: locks}
+	service.LedgerRepo = &lockingLedgerRepository{LedgerRepository: ledgerRepo, locks: locks}
+
+	walletRepo.On("BeginTx", mock.Anything).Return((*sql.Tx)(nil), nil)
+	walletRepo.On("UpdateBalanceWithTx", mock.Anything, (*sql.Tx)(nil), mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	const rounds = 20
+	done := make(chan error, 2*rounds)
+	for i := 0; i < rounds; i++ {
+		go func() {
+			done <- service.Transfer(context.Background(), walletA, walletB, testutil.USD(decimal.NewFromInt(1)), "A to B", "")
+		}()
+		go func() {
+			done <- service.Transfer(context.Background(), walletB, walletA, testutil.USD(decimal.NewFromInt(1)), "B to A", "")
+		}()
+	}
+
+	timeout := time.After(10 * time.Second)
+	for i := 0; i < 2*rounds; i++ {
+		select {
+		case err := <-done:
+			require.NoError(t, err)
+		case <-timeout:
+			t.Fatal("transfers in opposite directions deadlocked")
+		}
+	}
+}
+
+func TestLockOrderIsDeterministic(t *testing.T) {
+	low := uuid.MustParse("00000000-0000-0000-0000-000000000001")
+	high := uuid.MustParse("ffffffff-0000-0000-0000-000000000000")
+
+	assert.Equal(t, []uuid.UUID{low, high}, lockOrder(high, low))
+	assert.Equal(t, []uuid.UUID{low, high}, lockOrder(low, high, low))
+}
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository"
+	"github.com/shanwije/wallet-app/pkg/money"
+)
+
+// ErrInvalidCorrectionReason is returned for a correction without one of the known reason codes
+var ErrInvalidCorrectionReason = errors.New("correction reason must be one of duplicate, wrong_amount, wrong_recipient, fraud or system_error")
+
+// CorrectTransaction undoes the journal that transactionID belongs to on an operator's
+// behalf, because it should never have been posted. Like a reversal it posts the legs
+// again in the opposite direction, in part when amount is set, but as a correction
+// journal that records reason and references the original, which is left as it was.
+// A journal is corrected or reversed once, and neither a refunded deposit nor the debit
+// of a payout or deposit refund at all. Frozen wallets can be corrected; closed ones cannot, and the wallets
+// paying the money back need it available.
+func (s *WalletService) CorrectTransaction(ctx context.Context, transactionID uuid.UUID, amount *money.Money, reason, note string) (*models.Journal, error) {
+	if !models.IsValidCorrectionReason(reason) {
+		return nil, ErrInvalidCorrectionReason
+	}
+	original, err := s.GetTransactionJournal(ctx, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	if original.Type == models.JournalTypeReversal || original.Type == models.JournalTypeCorrection {
+		return nil, ErrNotReversible
+	}
+
+	entries, err := reversalEntries(original, amount)
+	if err != nil {
+		return nil, err
+	}
+	var description *string
+	if note != "" {
+		description = &note
+	}
+	correction := newJournal(models.JournalTypeCorrection, description, nil, entries...)
+	correction.ReversesJournalID = &original.ID
+	correction.CorrectionReason = &reason
+
+	err = s.withTx(ctx, "correct transaction", func(ctx context.Context, tx *sql.Tx) error {
+		if err := s.applyReversal(ctx, tx, correction); err != nil {
+			return err
+		}
+		if err := s.checkReversible(ctx, tx, original); err != nil {
+			return err
+		}
+		return s.recordJournal(ctx, tx, correction)
+	})
+	if errors.Is(err, repository.ErrDuplicate) {
+		return nil, ErrAlreadyReversed
+	}
+	if err != nil {
+		return nil, err
+	}
+	return correction, nil
+}
@@ -0,0 +1,357 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/paymentprovider"
+	"github.com/shanwije/wallet-app/internal/repository"
+	"github.com/shanwije/wallet-app/pkg/logger"
+	"github.com/shanwije/wallet-app/pkg/money"
+)
+
+var (
+	// ErrNotRefundable is returned for a refund of a transaction that is not a deposit
+	ErrNotRefundable = errors.New("only deposits can be refunded")
+	// ErrInvalidDepositRefundAmount is returned for a refund that is not positive, has
+	// too many decimal places or exceeds what is left of the deposit
+	ErrInvalidDepositRefundAmount = errors.New("refund amount must be positive and no more than what is left of the deposit")
+)
+
+// depositRefundBatchSize is how many pending refunds the worker reads at a time
+const depositRefundBatchSize = 100
+
+// DepositRefundService pays deposits back out of the wallets they funded, in part or
+// in whole. Each refund is a withdrawal from the wallet, linked to the deposit, so a
+// deposit is never refunded for more than it brought in. A deposit funded by the
+// payment provider is refunded to the customer through it as well, once the wallet's
+// debit has committed.
+type DepositRefundService struct {
+	Repo             repository.DepositRefundRepository
+	ProviderPayments repository.ProviderPaymentRepository
+	Wallets          *WalletService
+	// Refunder is optional; without it refunds only debit the wallet, and the money
+	// goes back to the customer some other way
+	Refunder paymentprovider.Refunder
+}
+
+// Refund pays amount of the deposit that transactionID, one of its ledger entries,
+// belongs to back out of its wallet; what is left of the deposit when amount is nil.
+// The refund obeys the checks of a withdrawal: the wallet must be active and have the
+// money available. A deposit that was reversed or corrected cannot be refunded. When
+// the provider funded the deposit the refund is recorded as pending along with the
+// debit, then handed to the provider: it comes back completed, failed and credited
+// back when the provider rejects it, or still pending when the provider could not be
+// reached, for the worker to send again.
+func (s *DepositRefundService) Refund(ctx context.Context, transactionID uuid.UUID, amount *money.Money, reason string) (*models.DepositRefund, error) {
+	deposit, err := s.Wallets.GetTransactionJournal(ctx, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	if deposit.Type != models.JournalTypeDeposit {
+		return nil, ErrNotRefundable
+	}
+	var credited *models.LedgerEntry
+	for _, entry := range deposit.Entries {
+		if entry.WalletID != nil && entry.Direction == models.EntryDirectionCredit {
+			credited = entry
+			break
+		}
+	}
+	if credited == nil {
+		return nil, fmt.Errorf("deposit %s credits no wallet", deposit.ID)
+	}
+	walletID := *credited.WalletID
+	deposited := money.New(credited.Amount, credited.Currency)
+
+	payment, err := s.ProviderPayments.GetProviderPaymentByJournalID(ctx, deposit.ID)
+	if errors.Is(err, repository.ErrNotFound) {
+		payment = nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get provider payment: %w", err)
+	}
+	treasuryID, err := s.Wallets.settlementWallet(ctx, models.WalletKindTreasury, deposited.Currency())
+	if err != nil {
+		return nil, err
+	}
+
+	var refund *models.DepositRefund
+	err = s.Wallets.withTx(ctx, "refund deposit", func(ctx context.Context, tx *sql.Tx) error {
+		// Locking the wallet first keeps concurrent refunds of the deposit from both
+		// seeing the same amount left
+		if _, err := s.Wallets.getWalletForUpdate(ctx, tx, walletID); err != nil {
+			return err
+		}
+		reversed, err := s.Repo.IsDepositReversedWithTx(ctx, tx, deposit.ID)
+		if err != nil {
+			return err
+		}
+		if reversed {
+			return ErrAlreadyReversed
+		}
+
+		previous, err := s.Repo.ListDepositRefundsWithTx(ctx, tx, deposit.ID)
+		if err != nil {
+			return err
+		}
+		// A refund that failed was credited back, so it no longer counts
+		refunded := decimal.Zero
+		for _, p := range previous {
+			if p.Status != models.DepositRefundStatusFailed {
+				refunded = refunded.Add(p.Amount)
+			}
+		}
+		refundable := money.New(deposited.Amount().Sub(refunded), deposited.Currency())
+		requested := refundable
+		if amount != nil {
+			requested = *amount
+		}
+		if !requested.SameCurrency(refundable) {
+			return fmt.Errorf("invalid refund: %w", money.ErrCurrencyMismatch)
+		}
+		if cmp, _ := requested.Cmp(refundable); !requested.IsPositive() || !requested.HasValidPrecision() || cmp > 0 {
+			return ErrInvalidDepositRefundAmount
+		}
+
+		description := "Refund of deposit"
+		if reason != "" {
+			description += ": " + reason
+		}
+		journal := newJournal(models.JournalTypeWithdraw, &description, nil,
+			debit(&walletID, requested),
+			credit(treasuryID, requested),
+		)
+		classify(ctx, journal)
+		if _, err := s.Wallets.withdrawExecution(ctx, tx, walletID, requested, noFee, journal, treasuryID); err != nil {
+			return err
+		}
+
+		current := &models.DepositRefund{
+			DepositJournalID: deposit.ID,
+			WalletID:         walletID,
+			Amount:           requested.Amount(),
+			Currency:         requested.Currency(),
+			Status:           models.DepositRefundStatusCompleted,
+			JournalID:        journal.ID,
+			CreatedAt:        journal.CreatedAt,
+		}
+		if reason != "" {
+			current.Reason = &reason
+		}
+		if payment != nil && s.Refunder != nil {
+			current.Status = models.DepositRefundStatusPending
+		}
+		if err := s.Repo.CreateDepositRefundWithTx(ctx, tx, current); err != nil {
+			return err
+		}
+
+		refund = current
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logger.FromContext(ctx).Info("Deposit refunded",
+		zap.String("deposit_id", deposit.ID.String()),
+		zap.String("wallet_id", walletID.String()),
+		zap.String("amount", refund.Funds().String()),
+		zap.String("status", refund.Status))
+	if refund.Status != models.DepositRefundStatusPending {
+		return refund, nil
+	}
+	return s.send(ctx, refund)
+}
+
+// Run sends pending refunds to the payment provider again every interval until ctx is
+// cancelled
+func (s *DepositRefundService) Run(ctx context.Context, interval time.Duration) {
+	log := logger.FromContext(ctx)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		processed, err := s.ProcessPending(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Error("Deposit refund run failed", zap.Error(err))
+		} else if processed > 0 {
+			log.Info("Processed deposit refunds", zap.Int("count", processed))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProcessPending sends every pending refund to the payment provider again and returns
+// how many were completed or failed. A refund the provider cannot be reached about, or
+// that fails to update, is left for the next run.
+func (s *DepositRefundService) ProcessPending(ctx context.Context) (int, error) {
+	if s.Refunder == nil {
+		return 0, nil
+	}
+
+	processed := 0
+	after := uuid.Nil
+	for {
+		batch, err := s.Repo.ListDepositRefundsInStatus(ctx, models.DepositRefundStatusPending, after, depositRefundBatchSize)
+		if err != nil {
+			return processed, fmt.Errorf("failed to list pending deposit refunds: %w", err)
+		}
+		for _, pending := range batch {
+			current, err := s.send(ctx, pending)
+			if err != nil {
+				if ctx.Err() != nil {
+					return processed, err
+				}
+				// One refund that cannot be moved on does not hold up the rest
+				logger.FromContext(ctx).Error("Failed to process deposit refund",
+					zap.Error(err), zap.String("refund_id", pending.ID.String()))
+				continue
+			}
+			if current.Status != models.DepositRefundStatusPending {
+				processed++
+			}
+		}
+		if len(batch) < depositRefundBatchSize {
+			return processed, nil
+		}
+		after = batch[len(batch)-1].ID
+	}
+}
+
+// send asks the payment provider to refund the pending refund's payment. The provider
+// gets the refund's ID to refund it once, however often it is sent.
+func (s *DepositRefundService) send(ctx context.Context, pending *models.DepositRefund) (*models.DepositRefund, error) {
+	payment, err := s.ProviderPayments.GetProviderPaymentByJournalID(ctx, pending.DepositJournalID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get provider payment: %w", err)
+	}
+	reason := ""
+	if pending.Reason != nil {
+		reason = *pending.Reason
+	}
+
+	providerRefundID, err := s.Refunder.Refund(ctx, paymentprovider.RefundRequest{
+		ID:        pending.ID,
+		PaymentID: payment.PaymentID,
+		Amount:    pending.Funds(),
+		Reason:    reason,
+	})
+	if errors.Is(err, paymentprovider.ErrRejected) {
+		return s.fail(ctx, pending.ID, err.Error())
+	}
+	if err != nil {
+		logger.FromContext(ctx).Warn("Deposit refund not sent, will retry",
+			zap.Error(err), zap.String("refund_id", pending.ID.String()))
+		return pending, nil
+	}
+
+	var completed *models.DepositRefund
+	err = s.Wallets.withTx(ctx, "complete deposit refund", func(ctx context.Context, tx *sql.Tx) error {
+		current, err := s.Repo.GetDepositRefundWithTx(ctx, tx, pending.ID)
+		if err != nil {
+			return err
+		}
+		completed = current
+		if current.Status != models.DepositRefundStatusPending {
+			return nil
+		}
+		current.Status = models.DepositRefundStatusCompleted
+		current.ProviderRefundID = &providerRefundID
+		return s.Repo.UpdateDepositRefundWithTx(ctx, tx, current)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return completed, nil
+}
+
+// fail marks the refund failed for reason and credits its amount back to the wallet,
+// as a reversal of the refund's debit, in one transaction. The money never reached the
+// customer, so it goes back whatever the wallet's status.
+func (s *DepositRefundService) fail(ctx context.Context, refundID uuid.UUID, reason string) (*models.DepositRefund, error) {
+	var failed *models.DepositRefund
+	creditedBack := false
+	err := s.Wallets.withTx(ctx, "fail deposit refund", func(ctx context.Context, tx *sql.Tx) error {
+		current, err := s.Repo.GetDepositRefundWithTx(ctx, tx, refundID)
+		if err != nil {
+			return err
+		}
+		failed, creditedBack = current, false
+		if current.Status != models.DepositRefundStatusPending {
+			return nil
+		}
+
+		original, err := s.Wallets.LedgerRepo.GetJournalByID(ctx, current.JournalID)
+		if err != nil {
+			return fmt.Errorf("failed to get deposit refund journal: %w", err)
+		}
+		entries, err := reversalEntries(original, nil)
+		if err != nil {
+			return err
+		}
+		// The treasury wallet the refund was paid to, if any, pays it back
+		var treasuryID *uuid.UUID
+		for _, entry := range original.Entries {
+			if entry.Direction == models.EntryDirectionCredit {
+				treasuryID = entry.WalletID
+			}
+		}
+		description := "Failed refund of deposit"
+		reversal := newJournal(models.JournalTypeReversal, &description, nil, entries...)
+		reversal.ReversesJournalID = &original.ID
+
+		wallet, err := s.Wallets.getWalletForUpdate(ctx, tx, current.WalletID)
+		if err != nil {
+			return fmt.Errorf("failed to get wallet: %w", err)
+		}
+		newBalance, err := wallet.Funds().Add(current.Funds())
+		if err != nil {
+			return fmt.Errorf("invalid deposit refund reversal: %w", err)
+		}
+		if err := s.Wallets.setBalance(ctx, tx, wallet, newBalance.Amount()); err != nil {
+			return fmt.Errorf("failed to update wallet balance: %w", err)
+		}
+		if err := s.Wallets.settle(ctx, tx, wallet, reversal, treasuryID); err != nil {
+			return err
+		}
+		if err := s.Wallets.recordJournal(ctx, tx, reversal); err != nil {
+			return err
+		}
+
+		current.Status = models.DepositRefundStatusFailed
+		current.FailureReason = &reason
+		current.ReversalJournalID = &reversal.ID
+		creditedBack = true
+		return s.Repo.UpdateDepositRefundWithTx(ctx, tx, current)
+	})
+	if errors.Is(err, repository.ErrDuplicate) {
+		// The refund's debit was reversed by hand, which already paid it back
+		return nil, fmt.Errorf("failed deposit refund %s: %w", refundID, ErrAlreadyReversed)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !creditedBack {
+		return failed, nil
+	}
+
+	logger.FromContext(ctx).Warn("Deposit refund failed",
+		zap.String("refund_id", refundID.String()),
+		zap.String("wallet_id", failed.WalletID.String()),
+		zap.String("reason", reason))
+	return failed, nil
+}
@@ -0,0 +1,290 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository"
+	"github.com/shanwije/wallet-app/pkg/clock"
+)
+
+// Outcomes an operator can resolve a dispute with
+const (
+	DisputeOutcomeRefund = "refund"
+	DisputeOutcomeReject = "reject"
+)
+
+var (
+	// ErrDisputeNotFound is returned when no dispute has the ID
+	ErrDisputeNotFound = errors.New("dispute not found")
+	// ErrInvalidDisputeReason is returned for a dispute without a reason
+	ErrInvalidDisputeReason = errors.New("dispute reason cannot be empty")
+	// ErrNotDisputable is returned for a transaction that is not the sender's side of a transfer
+	ErrNotDisputable = errors.New("only transfers can be disputed, by their sender")
+	// ErrAlreadyDisputed is returned when the transaction was disputed before
+	ErrAlreadyDisputed = errors.New("transaction has already been disputed")
+	// ErrDisputedFundsUnavailable is returned when the recipient of a disputed transfer
+	// no longer has what they were credited available to hold
+	ErrDisputedFundsUnavailable = errors.New("recipient no longer has the disputed funds available")
+	// ErrDisputeResolved is returned when resolving a dispute that is no longer open
+	ErrDisputeResolved = errors.New("dispute has already been resolved")
+	// ErrInvalidDisputeOutcome is returned for an outcome other than refund or reject
+	ErrInvalidDisputeOutcome = errors.New("dispute outcome must be refund or reject")
+)
+
+// DisputeService lets the sender of a transfer dispute it, holding what the recipient
+// was credited until an operator refunds the transfer or rejects the dispute. Every
+// status a dispute enters is recorded as an event.
+type DisputeService struct {
+	Repo    repository.DisputeRepository
+	Wallets *WalletService
+	// Clock is optional; the system clock is used when nil
+	Clock clock.Clock
+}
+
+// now returns the current time from the injected clock
+func (s *DisputeService) now() time.Time {
+	return clock.OrDefault(s.Clock).Now()
+}
+
+// Open disputes the transfer whose sending leg is transactionID, holding what the
+// recipient was credited in their wallet until the dispute is resolved. The hold is
+// placed even on a frozen wallet, but fails when the funds are no longer available.
+func (s *DisputeService) Open(ctx context.Context, transactionID uuid.UUID, reason string) (*models.Dispute, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, ErrInvalidDisputeReason
+	}
+
+	journal, err := s.Wallets.GetTransactionJournal(ctx, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	transfer, ok := models.NewTransfer(journal)
+	if !ok {
+		return nil, ErrNotDisputable
+	}
+	sent := journal.WalletEntry(transfer.FromWalletID)
+	if sent == nil || sent.ID != transactionID {
+		return nil, ErrNotDisputable
+	}
+	credited := journal.WalletEntry(transfer.ToWalletID)
+
+	now := s.now()
+	dispute := &models.Dispute{
+		TransactionID:        transactionID,
+		JournalID:            journal.ID,
+		WalletID:             transfer.FromWalletID,
+		CounterpartyWalletID: transfer.ToWalletID,
+		Amount:               credited.Amount,
+		Currency:             credited.Currency,
+		Reason:               reason,
+		Status:               models.DisputeStatusOpen,
+		CreatedAt:            now,
+		UpdatedAt:            now,
+	}
+
+	err = s.Wallets.withTx(ctx, "open dispute", func(ctx context.Context, tx *sql.Tx) error {
+		counterparty, err := s.Wallets.getWalletForUpdate(ctx, tx, dispute.CounterpartyWalletID)
+		if err != nil {
+			return fmt.Errorf("failed to get wallet: %w", err)
+		}
+		// A transfer the recipient already paid back in part or in whole cannot be refunded
+		// again. A reversal locks the recipient's wallet too, so none can commit in between.
+		reversed, err := s.Wallets.LedgerRepo.IsJournalReversedWithTx(ctx, tx, dispute.JournalID)
+		if err != nil {
+			return err
+		}
+		if reversed {
+			return ErrAlreadyReversed
+		}
+		// Refunding the dispute would pay back what the merchant already refunded. A
+		// payment refund locks the merchant's wallet too, so none can commit in between.
+		if err := s.Wallets.checkPaymentNotRefunded(ctx, tx, dispute.JournalID); err != nil {
+			return err
+		}
+
+		if err := s.Repo.CreateDisputeWithTx(ctx, tx, dispute); err != nil {
+			return err
+		}
+		if err := s.recordEvent(ctx, tx, dispute, &reason); err != nil {
+			return err
+		}
+
+		description := "Dispute " + dispute.ID.String()
+		hold := &models.Hold{
+			WalletID:    counterparty.ID,
+			Amount:      dispute.Amount,
+			Currency:    dispute.Currency,
+			Description: &description,
+			Status:      models.HoldStatusActive,
+			DisputeID:   &dispute.ID,
+		}
+		if err := s.Wallets.holdFunds(ctx, tx, counterparty, hold); err != nil {
+			if errors.Is(err, ErrInsufficientAvailableBalance) {
+				return ErrDisputedFundsUnavailable
+			}
+			return err
+		}
+		dispute.HoldID = &hold.ID
+		return nil
+	})
+	if errors.Is(err, repository.ErrDuplicate) {
+		// The unique key on the transaction catches concurrent disputes too
+		return nil, ErrAlreadyDisputed
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetDispute(ctx, dispute.ID)
+}
+
+// Resolve settles an open dispute. A refund reverses the whole transfer, paying the
+// recipient's held funds back to the sender; a rejection leaves the transfer as it was.
+// Either way the hold is released, and note is recorded with the new status.
+func (s *DisputeService) Resolve(ctx context.Context, disputeID uuid.UUID, outcome, note string) (*models.Dispute, error) {
+	if outcome != DisputeOutcomeRefund && outcome != DisputeOutcomeReject {
+		return nil, ErrInvalidDisputeOutcome
+	}
+	var notePtr *string
+	if note = strings.TrimSpace(note); note != "" {
+		notePtr = &note
+	}
+
+	existing, err := s.GetDispute(ctx, disputeID)
+	if err != nil {
+		return nil, err
+	}
+	original, err := s.Wallets.GetTransactionJournal(ctx, existing.TransactionID)
+	if err != nil {
+		return nil, err
+	}
+
+	var dispute *models.Dispute
+	err = s.Wallets.withTx(ctx, "resolve dispute", func(ctx context.Context, tx *sql.Tx) error {
+		current, err := s.Repo.GetDisputeWithTx(ctx, tx, disputeID)
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrDisputeNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if current.Status != models.DisputeStatusOpen {
+			return ErrDisputeResolved
+		}
+
+		// Both wallets are locked up front, in the order a reversal locks them
+		wallets := make(map[uuid.UUID]*models.Wallet)
+		for _, id := range lockOrder(current.WalletID, current.CounterpartyWalletID) {
+			if wallets[id], err = s.Wallets.getWalletForUpdate(ctx, tx, id); err != nil {
+				return fmt.Errorf("failed to get wallet: %w", err)
+			}
+		}
+		if current.HoldID != nil {
+			hold, err := s.Wallets.HoldRepo.GetHoldWithTx(ctx, tx, *current.HoldID)
+			if err != nil {
+				return err
+			}
+			if err := s.Wallets.releaseHold(ctx, tx, wallets[current.CounterpartyWalletID], hold); err != nil {
+				return err
+			}
+		}
+
+		current.Status = models.DisputeStatusRejected
+		if outcome == DisputeOutcomeRefund {
+			reversal, err := s.refund(ctx, tx, current, original)
+			if err != nil {
+				return err
+			}
+			current.Status = models.DisputeStatusRefunded
+			current.ReversalJournalID = &reversal.ID
+		}
+
+		current.UpdatedAt = s.now()
+		if err := s.Repo.UpdateDisputeWithTx(ctx, tx, current); err != nil {
+			return err
+		}
+		if err := s.recordEvent(ctx, tx, current, notePtr); err != nil {
+			return err
+		}
+
+		dispute = current
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetDispute(ctx, dispute.ID)
+}
+
+// refund posts the reversal of original, the disputed transfer
+func (s *DisputeService) refund(ctx context.Context, tx *sql.Tx, dispute *models.Dispute, original *models.Journal) (*models.Journal, error) {
+	entries, err := reversalEntries(original, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	description := "Refund of dispute: " + dispute.Reason
+	reversal := newJournal(models.JournalTypeReversal, &description, nil, entries...)
+	reversal.ReversesJournalID = &original.ID
+	if err := s.Wallets.applyReversal(ctx, tx, reversal); err != nil {
+		return nil, err
+	}
+	if err := s.Wallets.recordJournal(ctx, tx, reversal); err != nil {
+		if errors.Is(err, repository.ErrDuplicate) {
+			return nil, ErrAlreadyReversed
+		}
+		return nil, err
+	}
+	return reversal, nil
+}
+
+// recordEvent records the dispute entering its current status
+func (s *DisputeService) recordEvent(ctx context.Context, tx *sql.Tx, dispute *models.Dispute, note *string) error {
+	return s.Repo.CreateDisputeEventWithTx(ctx, tx, &models.DisputeEvent{
+		DisputeID: dispute.ID,
+		Status:    dispute.Status,
+		Note:      note,
+		CreatedAt: dispute.UpdatedAt,
+	})
+}
+
+// GetDispute returns the dispute with its events
+func (s *DisputeService) GetDispute(ctx context.Context, disputeID uuid.UUID) (*models.Dispute, error) {
+	dispute, err := s.Repo.GetDispute(ctx, disputeID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrDisputeNotFound
+		}
+		return nil, fmt.Errorf("failed to get dispute: %w", err)
+	}
+	return dispute, nil
+}
+
+// ListTransactionDisputes returns the disputes of the transaction with their events
+func (s *DisputeService) ListTransactionDisputes(ctx context.Context, transactionID uuid.UUID) ([]*models.Dispute, error) {
+	disputes, err := s.Repo.ListDisputesByTransactionID(ctx, transactionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list disputes: %w", err)
+	}
+	return disputes, nil
+}
+
+// ListDisputes returns a page of disputes in the status, or in any status when it is
+// empty, oldest first
+func (s *DisputeService) ListDisputes(ctx context.Context, status string, limit, offset int) ([]*models.Dispute, error) {
+	disputes, err := s.Repo.ListDisputes(ctx, status, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list disputes: %w", err)
+	}
+	return disputes, nil
+}
@@ -0,0 +1,229 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/shanwije/wallet-app/internal/export"
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository"
+	"github.com/shanwije/wallet-app/pkg/clock"
+	"github.com/shanwije/wallet-app/pkg/logger"
+)
+
+// ErrExportJobNotFound is returned when no export job has the ID
+var ErrExportJobNotFound = errors.New("export job not found")
+
+const (
+	// exportJobBatchSize is how many export jobs the worker reads at a time
+	exportJobBatchSize = 100
+	// defaultExportLinkTTL is how long a download link works when none is configured
+	defaultExportLinkTTL = 15 * time.Minute
+)
+
+// ExportJobService exports wallets' transactions in the background, for histories too
+// long to stream within a request. A job is created pending; the worker writes the
+// wallet's statement for the job's period as CSV to the store, and a ready job is read
+// with a link to download it that expires.
+//
+// Each job's file has its own key, so a job produced twice, after a crash or by two
+// workers at once, only replaces its file.
+type ExportJobService struct {
+	Repo    repository.ExportJobRepository
+	Wallets *WalletService
+	// Store is optional; exports are rejected as a disabled feature when nil
+	Store export.Store
+	// Prefix is put in front of the keys of the exported files
+	Prefix string
+	// LinkTTL is how long download links work; 15 minutes when zero
+	LinkTTL time.Duration
+	// Clock is optional; the system clock is used when nil
+	Clock clock.Clock
+}
+
+// now returns the current time from the injected clock
+func (s *ExportJobService) now() time.Time {
+	return clock.OrDefault(s.Clock).Now()
+}
+
+func (s *ExportJobService) linkTTL() time.Duration {
+	if s.LinkTTL <= 0 {
+		return defaultExportLinkTTL
+	}
+	return s.LinkTTL
+}
+
+// Create queues an export of the wallet's transactions created in [from, to). A zero
+// from starts it when the wallet was opened and a zero to ends it now, so the file
+// holds the same rows however late it is produced.
+func (s *ExportJobService) Create(ctx context.Context, walletID uuid.UUID, from, to time.Time) (*models.ExportJob, error) {
+	if s.Store == nil {
+		return nil, fmt.Errorf("%w: exports", ErrFeatureDisabled)
+	}
+
+	wallet, err := s.Wallets.WalletRepo.GetWalletByID(ctx, walletID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get wallet: %w", walletLookupError(err))
+	}
+	now := s.now()
+	if from.IsZero() {
+		from = wallet.CreatedAt
+	}
+	if to.IsZero() {
+		to = now
+	}
+	if !to.After(from) {
+		return nil, ErrInvalidStatementPeriod
+	}
+
+	job := &models.ExportJob{
+		WalletID:  walletID,
+		From:      from,
+		To:        to,
+		Status:    models.ExportJobStatusPending,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := s.Repo.CreateExportJob(ctx, job); err != nil {
+		return nil, err
+	}
+
+	logger.FromContext(ctx).Info("Export queued",
+		zap.String("export_id", job.ID.String()),
+		zap.String("wallet_id", walletID.String()))
+	return job, nil
+}
+
+// Get returns the export job, with a fresh download link when it is ready
+func (s *ExportJobService) Get(ctx context.Context, jobID uuid.UUID) (*models.ExportJob, error) {
+	job, err := s.Repo.GetExportJob(ctx, jobID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrExportJobNotFound
+		}
+		return nil, fmt.Errorf("failed to get export job: %w", err)
+	}
+
+	if job.Status == models.ExportJobStatusReady && job.ObjectKey != nil && s.Store != nil {
+		ttl := s.linkTTL()
+		expiresAt := s.now().Add(ttl)
+		link, err := s.Store.DownloadURL(*job.ObjectKey, ttl)
+		if err != nil {
+			return nil, fmt.Errorf("failed to sign download link: %w", err)
+		}
+		job.DownloadURL = link
+		job.DownloadExpiresAt = &expiresAt
+	}
+	return job, nil
+}
+
+// Run produces pending exports every interval until ctx is cancelled
+func (s *ExportJobService) Run(ctx context.Context, interval time.Duration) {
+	log := logger.FromContext(ctx)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		processed, err := s.ProcessPending(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Error("Export job run failed", zap.Error(err))
+		} else if processed > 0 {
+			log.Info("Processed export jobs", zap.Int("count", processed))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProcessPending produces every pending export and returns how many are done. A job
+// that fails for a reason that may pass, such as the store being unreachable, stays
+// pending for the next run.
+func (s *ExportJobService) ProcessPending(ctx context.Context) (int, error) {
+	if s.Store == nil {
+		return 0, nil
+	}
+
+	processed := 0
+	after := uuid.Nil
+	for {
+		batch, err := s.Repo.ListExportJobsInStatus(ctx, models.ExportJobStatusPending, after, exportJobBatchSize)
+		if err != nil {
+			return processed, fmt.Errorf("failed to list pending export jobs: %w", err)
+		}
+		for _, pending := range batch {
+			if err := s.produce(ctx, pending); err != nil {
+				if ctx.Err() != nil {
+					return processed, err
+				}
+				// One export that cannot be produced does not hold up the rest
+				logger.FromContext(ctx).Error("Failed to produce export",
+					zap.Error(err), zap.String("export_id", pending.ID.String()))
+				continue
+			}
+			processed++
+		}
+		if len(batch) < exportJobBatchSize {
+			return processed, nil
+		}
+		after = batch[len(batch)-1].ID
+	}
+}
+
+// produce writes the job's statement to the store and marks the job ready, or failed
+// when its wallet is gone
+func (s *ExportJobService) produce(ctx context.Context, job *models.ExportJob) error {
+	var body bytes.Buffer
+	writer := NewCSVStatementWriter(&body)
+	err := s.Wallets.WriteStatement(ctx, job.WalletID, job.From, job.To, writer)
+	if errors.Is(err, ErrWalletNotFound) || errors.Is(err, ErrInvalidStatementPeriod) {
+		reason := err.Error()
+		job.FailureReason = &reason
+		return s.complete(ctx, job, models.ExportJobStatusFailed)
+	}
+	if err != nil {
+		return err
+	}
+
+	key := exportObjectKey(s.Prefix, job)
+	if err := s.Store.Upload(ctx, key, "text/csv", body.Bytes()); err != nil {
+		return fmt.Errorf("failed to upload %s: %w", key, err)
+	}
+	job.ObjectKey = &key
+	job.Rows = writer.Lines
+	return s.complete(ctx, job, models.ExportJobStatusReady)
+}
+
+// complete stores the job in its final status
+func (s *ExportJobService) complete(ctx context.Context, job *models.ExportJob, status string) error {
+	now := s.now()
+	job.Status = status
+	job.UpdatedAt = now
+	job.CompletedAt = &now
+	if err := s.Repo.UpdateExportJob(ctx, job); err != nil {
+		return err
+	}
+
+	logger.FromContext(ctx).Info("Export produced",
+		zap.String("export_id", job.ID.String()),
+		zap.String("status", status),
+		zap.Int64("rows", job.Rows))
+	return nil
+}
+
+// exportObjectKey returns where the job's file is stored, in a folder of its wallet's
+// exports
+func exportObjectKey(prefix string, job *models.ExportJob) string {
+	return path.Join(prefix, "wallet-exports", job.WalletID.String(), job.ID.String()+".csv")
+}
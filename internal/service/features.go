@@ -0,0 +1,43 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrFeatureDisabled is returned for an operation whose feature flag is switched off
+var ErrFeatureDisabled = errors.New("feature is disabled")
+
+// Feature flags consulted by the wallet service
+const (
+	// FlagTransfers allows transfers between wallets, including batches, scheduled
+	// transfers and paid payment requests
+	FlagTransfers = "transfers"
+	// FlagWithdrawals allows withdrawals
+	FlagWithdrawals = "withdrawals"
+	// FlagOverdraft lets wallets with an overdraft limit go below zero; when off their
+	// balance stops at zero
+	FlagOverdraft = "overdraft"
+)
+
+// DefaultFeatureFlags are the known flags and their values when not configured
+var DefaultFeatureFlags = map[string]bool{
+	FlagTransfers:   true,
+	FlagWithdrawals: true,
+	FlagOverdraft:   true,
+}
+
+// featureEnabled reports whether the flag is on; every feature is on when the service
+// has no flags
+func (s *WalletService) featureEnabled(ctx context.Context, flag string) bool {
+	return s.Flags == nil || s.Flags.Enabled(ctx, flag)
+}
+
+// requireFeature returns ErrFeatureDisabled when the flag is off
+func (s *WalletService) requireFeature(ctx context.Context, flag string) error {
+	if !s.featureEnabled(ctx, flag) {
+		return fmt.Errorf("%w: %s", ErrFeatureDisabled, flag)
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/pkg/money"
+)
+
+// noFee is passed for movements that are never charged a fee, such as merchant payments
+var noFee money.Money
+
+// movementFee returns the fee on amount moved by payerID as a journal of journalType.
+// Nothing is charged without a fee schedule or fee wallet, to the fee wallet itself, or
+// in a currency the fee wallet does not hold.
+func (s *WalletService) movementFee(ctx context.Context, journalType string, payerID uuid.UUID, amount money.Money) (money.Money, error) {
+	fee := s.Fees.Fee(journalType, amount)
+	if !fee.IsPositive() || s.FeeWalletID == uuid.Nil || payerID == s.FeeWalletID {
+		return money.Zero(amount.Currency()), nil
+	}
+
+	feeWallet, err := s.WalletRepo.GetWalletByID(ctx, s.FeeWalletID)
+	if err != nil {
+		return money.Money{}, fmt.Errorf("failed to get fee wallet: %w", err)
+	}
+	if !feeWallet.Funds().SameCurrency(fee) {
+		return money.Zero(amount.Currency()), nil
+	}
+	return fee, nil
+}
+
+// lockWithFeeWallet locks the wallet and, when fee is charged, the fee wallet, in lock
+// order. The fee wallet is nil when no fee is charged.
+func (s *WalletService) lockWithFeeWallet(ctx context.Context, tx *sql.Tx, walletID uuid.UUID, fee money.Money) (*models.Wallet, *models.Wallet, error) {
+	if !fee.IsPositive() {
+		wallet, err := s.getWalletForUpdate(ctx, tx, walletID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get wallet: %w", err)
+		}
+		return wallet, nil, nil
+	}
+
+	wallets := make(map[uuid.UUID]*models.Wallet, 2)
+	for _, id := range lockOrder(walletID, s.FeeWalletID) {
+		wallet, err := s.getWalletForUpdate(ctx, tx, id)
+		if err != nil {
+			if id == walletID {
+				return nil, nil, fmt.Errorf("failed to get wallet: %w", err)
+			}
+			return nil, nil, fmt.Errorf("failed to get fee wallet: %w", err)
+		}
+		wallets[id] = wallet
+	}
+	return wallets[walletID], wallets[s.FeeWalletID], nil
+}
+
+// chargeFee posts the fee journal moving fee from payer into feeWallet, both locked by
+// tx, after the movement of journalType it is charged on. The payer must still be able
+// to spend the fee.
+func (s *WalletService) chargeFee(ctx context.Context, tx *sql.Tx, payer, feeWallet *models.Wallet, journalType string, fee money.Money) error {
+	if feeWallet == nil || !fee.IsPositive() {
+		return nil
+	}
+
+	spendable, err := s.spendable(ctx, tx, payer)
+	if err != nil {
+		return err
+	}
+	cmp, err := spendable.Cmp(fee)
+	if err != nil {
+		return fmt.Errorf("invalid fee: %w", err)
+	}
+	if cmp < 0 {
+		return fmt.Errorf("%w to pay the %s %s fee", ErrInsufficientBalance, fee, journalType)
+	}
+
+	payerBalance, err := payer.Funds().Sub(fee)
+	if err != nil {
+		return fmt.Errorf("invalid fee: %w", err)
+	}
+	if err := s.setBalance(ctx, tx, payer, payerBalance.Amount()); err != nil {
+		return fmt.Errorf("failed to update wallet balance: %w", err)
+	}
+	feeBalance, err := feeWallet.Funds().Add(fee)
+	if err != nil {
+		return fmt.Errorf("invalid fee: %w", err)
+	}
+	if err := s.setBalance(ctx, tx, feeWallet, feeBalance.Amount()); err != nil {
+		return fmt.Errorf("failed to update fee wallet balance: %w", err)
+	}
+
+	description := journalType + " fee"
+	journal := newJournal(models.JournalTypeFee, &description, nil,
+		debit(&payer.ID, fee),
+		credit(&feeWallet.ID, fee),
+	)
+	return s.recordJournal(ctx, tx, journal)
+}
+
+// QuoteFee previews the fee the wallet would be charged on a journal of journalType
+// moving amount, without moving any money
+func (s *WalletService) QuoteFee(ctx context.Context, walletID uuid.UUID, journalType string, amount money.Money) (*models.FeeQuote, error) {
+	if !amount.IsPositive() {
+		return nil, fmt.Errorf("%s %w", journalType, ErrInvalidAmount)
+	}
+	wallet, err := s.WalletRepo.GetWalletByID(ctx, walletID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get wallet: %w", walletLookupError(err))
+	}
+	if !wallet.Funds().SameCurrency(amount) {
+		return nil, fmt.Errorf("invalid %s: %w", journalType, money.ErrCurrencyMismatch)
+	}
+
+	fee, err := s.movementFee(ctx, journalType, walletID, amount)
+	if err != nil {
+		return nil, err
+	}
+	change := amount.Amount().Neg()
+	if journalType == models.JournalTypeDeposit {
+		change = amount.Amount()
+	}
+	return &models.FeeQuote{
+		Operation:     journalType,
+		Amount:        amount.Amount(),
+		Fee:           fee.Amount(),
+		BalanceChange: change.Sub(fee.Amount()),
+		Currency:      amount.Currency(),
+	}, nil
+}
@@ -0,0 +1,278 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository"
+	"github.com/shanwije/wallet-app/pkg/money"
+)
+
+var (
+	// ErrHoldNotFound is returned when the wallet has no hold with the ID
+	ErrHoldNotFound = errors.New("hold not found")
+	// ErrHoldNotActive is returned when capturing or releasing a hold that was already finalized
+	ErrHoldNotActive = errors.New("hold is no longer active")
+	// ErrInsufficientAvailableBalance is returned when a hold exceeds the wallet's spendable balance
+	ErrInsufficientAvailableBalance = errors.New("insufficient available balance")
+	// ErrInvalidCaptureAmount is returned when a capture is not a positive amount within the hold
+	ErrInvalidCaptureAmount = errors.New("capture amount must be positive and no more than the held amount")
+	// ErrHoldDisputed is returned when capturing or releasing a hold that secures a dispute,
+	// which only resolving the dispute frees
+	ErrHoldDisputed = errors.New("hold secures a dispute and is freed when the dispute is resolved")
+	// ErrHoldAwaitingAcceptance is returned when capturing or releasing the hold on an
+	// incoming transfer, which only accepting or returning the transfer frees
+	ErrHoldAwaitingAcceptance = errors.New("hold secures an incoming transfer and is freed when it is accepted or returned")
+)
+
+// PlaceHold reserves amount of the wallet's available balance, which may reach into its
+// overdraft. Nothing is posted to the ledger until the hold is captured.
+func (s *WalletService) PlaceHold(ctx context.Context, walletID uuid.UUID, amount money.Money, description string) (*models.Hold, error) {
+	if !amount.IsPositive() {
+		return nil, fmt.Errorf("hold %w", ErrInvalidAmount)
+	}
+
+	hold := &models.Hold{
+		WalletID: walletID,
+		Amount:   amount.Amount(),
+		Currency: amount.Currency(),
+		Status:   models.HoldStatusActive,
+	}
+	if description != "" {
+		hold.Description = &description
+	}
+
+	err := s.withTx(ctx, "place hold", func(ctx context.Context, tx *sql.Tx) error {
+		wallet, err := s.getWalletForUpdate(ctx, tx, walletID)
+		if err != nil {
+			return fmt.Errorf("failed to get wallet: %w", err)
+		}
+		if err := wallet.CheckActive(); err != nil {
+			return err
+		}
+		return s.holdFunds(ctx, tx, wallet, hold)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return hold, nil
+}
+
+// holdFunds records hold against the wallet locked by tx, reserving its amount out of
+// what the wallet can spend
+func (s *WalletService) holdFunds(ctx context.Context, tx *sql.Tx, wallet *models.Wallet, hold *models.Hold) error {
+	amount := hold.Funds()
+	spendable, err := s.spendable(ctx, tx, wallet)
+	if err != nil {
+		return err
+	}
+	cmp, err := spendable.Cmp(amount)
+	if err != nil {
+		return fmt.Errorf("invalid hold: %w", err)
+	}
+	if cmp < 0 {
+		return ErrInsufficientAvailableBalance
+	}
+	return s.reserveFunds(ctx, tx, wallet, hold)
+}
+
+// reserveFunds records hold against the wallet locked by tx without checking the wallet
+// can spend its amount
+func (s *WalletService) reserveFunds(ctx context.Context, tx *sql.Tx, wallet *models.Wallet, hold *models.Hold) error {
+	newHeld, err := wallet.Held().Add(hold.Funds())
+	if err != nil {
+		return fmt.Errorf("invalid hold: %w", err)
+	}
+	if err := s.setHeldBalance(ctx, tx, wallet, newHeld.Amount()); err != nil {
+		return err
+	}
+
+	hold.CreatedAt = s.now()
+	return s.HoldRepo.CreateHoldWithTx(ctx, tx, hold)
+}
+
+// CaptureHold posts an active hold to the ledger as a withdrawal. A nil amount
+// captures the whole hold; a smaller amount captures that much and frees the rest.
+func (s *WalletService) CaptureHold(ctx context.Context, walletID, holdID uuid.UUID, amount *money.Money) (*models.Hold, error) {
+	treasuryID, err := s.walletSettlement(ctx, walletID, models.WalletKindTreasury)
+	if err != nil {
+		return nil, err
+	}
+
+	var hold *models.Hold
+	err = s.withTx(ctx, "capture hold", func(ctx context.Context, tx *sql.Tx) error {
+		wallet, current, err := s.lockWalletAndHold(ctx, tx, walletID, holdID)
+		if err != nil {
+			return err
+		}
+		if err := wallet.CheckActive(); err != nil {
+			return err
+		}
+
+		captured := current.Funds()
+		if amount != nil {
+			if !amount.IsPositive() {
+				return ErrInvalidCaptureAmount
+			}
+			cmp, err := captured.Cmp(*amount)
+			if err != nil {
+				return fmt.Errorf("invalid capture: %w", err)
+			}
+			if cmp < 0 {
+				return ErrInvalidCaptureAmount
+			}
+			captured = *amount
+		}
+		// A capture pays out like a withdrawal, so it counts against the same limits and
+		// is screened for AML the same way
+		if err := s.checkLimits(ctx, tx, wallet, models.JournalTypeWithdraw, captured); err != nil {
+			return err
+		}
+
+		// The whole hold stops counting against the available balance
+		newHeld, err := wallet.Held().Sub(current.Funds())
+		if err != nil {
+			return fmt.Errorf("invalid capture: %w", err)
+		}
+		newBalance, err := wallet.Funds().Sub(captured)
+		if err != nil {
+			return fmt.Errorf("invalid capture: %w", err)
+		}
+		previous := wallet.Balance
+		if err := s.setHeldBalance(ctx, tx, wallet, newHeld.Amount()); err != nil {
+			return err
+		}
+		if err := s.setBalance(ctx, tx, wallet, newBalance.Amount()); err != nil {
+			return fmt.Errorf("failed to update wallet balance: %w", err)
+		}
+
+		// Captured funds leave to the treasury wallet or the external settlement account,
+		// like a withdrawal. The key is unique per hold, so the ledger itself refuses a
+		// second capture.
+		journal := newJournal(models.JournalTypeWithdraw, current.Description,
+			scopedIdempotencyKey(walletID, "hold:"+holdID.String()),
+			debit(&walletID, captured),
+			credit(treasuryID, captured),
+		)
+		if err := s.settle(ctx, tx, wallet, journal, treasuryID); err != nil {
+			return err
+		}
+		if err := s.recordJournal(ctx, tx, journal); err != nil {
+			return err
+		}
+		if err := s.alertLowBalance(ctx, tx, wallet, previous, journal); err != nil {
+			return err
+		}
+		if err := s.screenAML(ctx, tx, wallet, journal, captured); err != nil {
+			return err
+		}
+
+		capturedAmount := captured.Amount()
+		current.Status = models.HoldStatusCaptured
+		current.CapturedAmount = &capturedAmount
+		current.CaptureJournalID = &journal.ID
+		current.UpdatedAt = journal.CreatedAt
+		if err := s.HoldRepo.UpdateHoldWithTx(ctx, tx, current); err != nil {
+			return err
+		}
+
+		hold = current
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return hold, nil
+}
+
+// ReleaseHold returns an active hold's funds to the available balance without
+// posting anything. Holds on frozen and closed wallets can still be released.
+func (s *WalletService) ReleaseHold(ctx context.Context, walletID, holdID uuid.UUID) (*models.Hold, error) {
+	var hold *models.Hold
+	err := s.withTx(ctx, "release hold", func(ctx context.Context, tx *sql.Tx) error {
+		wallet, current, err := s.lockWalletAndHold(ctx, tx, walletID, holdID)
+		if err != nil {
+			return err
+		}
+		if err := s.releaseHold(ctx, tx, wallet, current); err != nil {
+			return err
+		}
+
+		hold = current
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return hold, nil
+}
+
+// releaseHold frees the active hold on the wallet, both locked by tx
+func (s *WalletService) releaseHold(ctx context.Context, tx *sql.Tx, wallet *models.Wallet, hold *models.Hold) error {
+	newHeld, err := wallet.Held().Sub(hold.Funds())
+	if err != nil {
+		return fmt.Errorf("invalid release: %w", err)
+	}
+	if err := s.setHeldBalance(ctx, tx, wallet, newHeld.Amount()); err != nil {
+		return err
+	}
+
+	hold.Status = models.HoldStatusReleased
+	hold.UpdatedAt = s.now()
+	return s.HoldRepo.UpdateHoldWithTx(ctx, tx, hold)
+}
+
+// ListHolds returns the wallet's holds, newest first
+func (s *WalletService) ListHolds(ctx context.Context, walletID uuid.UUID) ([]*models.Hold, error) {
+	if _, err := s.WalletRepo.GetWalletByID(ctx, walletID); err != nil {
+		return nil, fmt.Errorf("failed to get wallet: %w", walletLookupError(err))
+	}
+
+	holds, err := s.HoldRepo.ListHoldsByWalletID(ctx, walletID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list holds: %w", err)
+	}
+
+	return holds, nil
+}
+
+// lockWalletAndHold locks the wallet and then one of its active holds that no dispute
+// or incoming transfer depends on. Every hold operation takes the locks in this order, the same order
+// PlaceHold uses.
+func (s *WalletService) lockWalletAndHold(ctx context.Context, tx *sql.Tx, walletID, holdID uuid.UUID) (*models.Wallet, *models.Hold, error) {
+	wallet, err := s.getWalletForUpdate(ctx, tx, walletID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get wallet: %w", err)
+	}
+
+	hold, err := s.HoldRepo.GetHoldWithTx(ctx, tx, holdID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, nil, ErrHoldNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Other wallets' holds are reported as missing rather than forbidden
+	if hold.WalletID != walletID {
+		return nil, nil, ErrHoldNotFound
+	}
+	if hold.Status != models.HoldStatusActive {
+		return nil, nil, ErrHoldNotActive
+	}
+	if hold.DisputeID != nil {
+		return nil, nil, ErrHoldDisputed
+	}
+	if hold.IncomingTransferID != nil {
+		return nil, nil, ErrHoldAwaitingAcceptance
+	}
+
+	return wallet, hold, nil
+}
@@ -0,0 +1,261 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository"
+	"github.com/shanwije/wallet-app/internal/repository/mocks"
+	"github.com/shanwije/wallet-app/internal/testutil"
+	"github.com/shanwije/wallet-app/pkg/clock"
+	"github.com/shanwije/wallet-app/pkg/money"
+)
+
+// setupHoldService creates a wallet service with a mocked hold repository
+func setupHoldService() (*WalletService, *mocks.WalletRepository, *mocks.LedgerRepository, *mocks.HoldRepository) {
+	service, walletRepo, ledgerRepo := setupWalletService()
+	holdRepo := new(mocks.HoldRepository)
+	service.HoldRepo = holdRepo
+	walletRepo.On("BeginTx", mock.Anything).Return((*sql.Tx)(nil), nil)
+	return service, walletRepo, ledgerRepo, holdRepo
+}
+
+// createHeldWallet creates a test wallet with part of its balance on hold
+func createHeldWallet(id uuid.UUID, balance, held float64) *models.Wallet {
+	wallet := testutil.Wallet(id, balance)
+	wallet.HeldBalance = decimal.NewFromFloat(held)
+	return wallet
+}
+
+// createActiveHold creates an active test hold on the wallet
+func createActiveHold(walletID uuid.UUID, amount float64) *models.Hold {
+	return &models.Hold{
+		ID:       uuid.New(),
+		WalletID: walletID,
+		Amount:   decimal.NewFromFloat(amount),
+		Currency: money.USD,
+		Status:   models.HoldStatusActive,
+	}
+}
+
+func TestPlaceHoldReservesAvailableBalance(t *testing.T) {
+	service, walletRepo, _, holdRepo := setupHoldService()
+
+	walletID := uuid.New()
+	walletRepo.On("GetWalletByIDWithTx", mock.Anything, (*sql.Tx)(nil), walletID).Return(createHeldWallet(walletID, 100.0, 50.0), nil)
+	walletRepo.On("UpdateHeldBalanceWithTx", mock.Anything, (*sql.Tx)(nil), walletID, mock.Anything, mock.Anything).Return(nil)
+	holdRepo.On("CreateHoldWithTx", mock.Anything, (*sql.Tx)(nil), mock.AnythingOfType("*models.Hold")).Return(nil)
+
+	hold, err := service.PlaceHold(context.Background(), walletID, testutil.USD(decimal.NewFromInt(30)), "Hotel")
+
+	require.NoError(t, err)
+	assert.Equal(t, models.HoldStatusActive, hold.Status)
+	assert.Equal(t, "Hotel", *hold.Description)
+
+	held := walletRepo.Calls[len(walletRepo.Calls)-1].Arguments.Get(3).(decimal.Decimal)
+	assert.True(t, held.Equal(decimal.NewFromInt(80)), "held balance should be 80, got %s", held)
+}
+
+func TestPlaceHoldInsufficientAvailableBalance(t *testing.T) {
+	service, walletRepo, _, holdRepo := setupHoldService()
+
+	walletID := uuid.New()
+	walletRepo.On("GetWalletByIDWithTx", mock.Anything, (*sql.Tx)(nil), walletID).Return(createHeldWallet(walletID, 100.0, 80.0), nil)
+
+	_, err := service.PlaceHold(context.Background(), walletID, testutil.USD(decimal.NewFromInt(30)), "")
+
+	assert.ErrorIs(t, err, ErrInsufficientAvailableBalance)
+	holdRepo.AssertNotCalled(t, "CreateHoldWithTx", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestWithdrawCannotSpendHeldFunds(t *testing.T) {
+	service, walletRepo, _, _ := setupHoldService()
+
+	walletID := uuid.New()
+	walletRepo.On("GetWalletByIDWithTx", mock.Anything, (*sql.Tx)(nil), walletID).Return(createHeldWallet(walletID, 100.0, 80.0), nil)
+
+	_, err := service.Withdraw(context.Background(), walletID, testutil.USD(decimal.NewFromInt(50)), "")
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "insufficient balance")
+	walletRepo.AssertNotCalled(t, "UpdateBalanceWithTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestCaptureHoldPartially(t *testing.T) {
+	service, walletRepo, ledgerRepo, holdRepo := setupHoldService()
+
+	walletID := uuid.New()
+	hold := createActiveHold(walletID, 30.0)
+	walletRepo.On("GetWalletByIDWithTx", mock.Anything, (*sql.Tx)(nil), walletID).Return(createHeldWallet(walletID, 100.0, 30.0), nil)
+	holdRepo.On("GetHoldWithTx", mock.Anything, (*sql.Tx)(nil), hold.ID).Return(hold, nil)
+	// The second write to the wallet checks the version the first one advanced it to
+	walletRepo.On("UpdateHeldBalanceWithTx", mock.Anything, (*sql.Tx)(nil), walletID, mock.MatchedBy(decimal.Zero.Equal), int64(0)).Return(nil)
+	walletRepo.On("UpdateBalanceWithTx", mock.Anything, (*sql.Tx)(nil), walletID, mock.MatchedBy(decimal.NewFromInt(80).Equal), int64(1)).Return(nil)
+	ledgerRepo.On("CreateJournalWithTx", mock.Anything, (*sql.Tx)(nil), mock.AnythingOfType("*models.Journal")).Return(nil)
+	holdRepo.On("UpdateHoldWithTx", mock.Anything, (*sql.Tx)(nil), hold).Return(nil)
+
+	amount := testutil.USD(decimal.NewFromInt(20))
+	captured, err := service.CaptureHold(context.Background(), walletID, hold.ID, &amount)
+
+	require.NoError(t, err)
+	assert.Equal(t, models.HoldStatusCaptured, captured.Status)
+	assert.True(t, captured.CapturedAmount.Equal(decimal.NewFromInt(20)))
+	walletRepo.AssertExpectations(t)
+
+	journal := ledgerRepo.Calls[0].Arguments.Get(2).(*models.Journal)
+	assert.Equal(t, models.JournalTypeWithdraw, journal.Type)
+	assert.Equal(t, walletID.String()+":hold:"+hold.ID.String(), *journal.IdempotencyKey)
+	assert.NoError(t, journal.Validate())
+}
+
+func TestCaptureHoldRejectsAmountAboveHold(t *testing.T) {
+	service, walletRepo, _, holdRepo := setupHoldService()
+
+	walletID := uuid.New()
+	hold := createActiveHold(walletID, 30.0)
+	walletRepo.On("GetWalletByIDWithTx", mock.Anything, (*sql.Tx)(nil), walletID).Return(createHeldWallet(walletID, 100.0, 30.0), nil)
+	holdRepo.On("GetHoldWithTx", mock.Anything, (*sql.Tx)(nil), hold.ID).Return(hold, nil)
+
+	amount := testutil.USD(decimal.NewFromInt(31))
+	_, err := service.CaptureHold(context.Background(), walletID, hold.ID, &amount)
+
+	assert.ErrorIs(t, err, ErrInvalidCaptureAmount)
+}
+
+func TestCaptureHoldCountsAgainstTierWithdrawalLimit(t *testing.T) {
+	service, walletRepo, ledgerRepo, holdRepo := setupHoldService()
+	now := time.Date(2024, 7, 20, 9, 0, 0, 0, time.UTC)
+	service.Clock = clock.NewFake(now)
+	kycRepo := new(mocks.KYCRepository)
+	service.KYCRepo = kycRepo
+	service.TierLimits = map[string]models.KYCTierLimits{
+		models.KYCTierUnverified: {DailyWithdrawalLimit: decimalPtr(50)},
+	}
+
+	walletID := uuid.New()
+	wallet := createHeldWallet(walletID, 100.0, 30.0)
+	hold := createActiveHold(walletID, 30.0)
+	walletRepo.On("GetWalletByIDWithTx", mock.Anything, (*sql.Tx)(nil), walletID).Return(wallet, nil)
+	holdRepo.On("GetHoldWithTx", mock.Anything, (*sql.Tx)(nil), hold.ID).Return(hold, nil)
+	kycRepo.On("GetKYCTierWithTx", mock.Anything, (*sql.Tx)(nil), wallet.UserID).Return(models.KYCTierUnverified, nil)
+	ledgerRepo.On("SumWalletDebitsSinceWithTx", mock.Anything, (*sql.Tx)(nil), walletID, models.JournalTypeWithdraw, now.Add(-24*time.Hour)).
+		Return(decimal.NewFromInt(40), nil)
+
+	_, err := service.CaptureHold(context.Background(), walletID, hold.ID, nil)
+
+	assert.ErrorIs(t, err, ErrLimitExceeded)
+	assert.ErrorContains(t, err, "for unverified users")
+	walletRepo.AssertNotCalled(t, "UpdateBalanceWithTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	ledgerRepo.AssertNotCalled(t, "CreateJournalWithTx", mock.Anything, mock.Anything, mock.Anything)
+	holdRepo.AssertNotCalled(t, "UpdateHoldWithTx", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestCaptureHoldIsScreenedForAML(t *testing.T) {
+	service, walletRepo, ledgerRepo, holdRepo := setupHoldService()
+	amlRepo := new(mocks.AMLRepository)
+	service.AMLRepo = amlRepo
+	service.AML = &AMLRules{ReportThreshold: decimal.NewFromInt(25)}
+
+	walletID := uuid.New()
+	hold := createActiveHold(walletID, 30.0)
+	walletRepo.On("GetWalletByIDWithTx", mock.Anything, (*sql.Tx)(nil), walletID).Return(createHeldWallet(walletID, 100.0, 30.0), nil)
+	holdRepo.On("GetHoldWithTx", mock.Anything, (*sql.Tx)(nil), hold.ID).Return(hold, nil)
+	walletRepo.On("UpdateHeldBalanceWithTx", mock.Anything, (*sql.Tx)(nil), walletID, mock.Anything, mock.Anything).Return(nil)
+	walletRepo.On("UpdateBalanceWithTx", mock.Anything, (*sql.Tx)(nil), walletID, mock.Anything, mock.Anything).Return(nil)
+	ledgerRepo.On("CreateJournalWithTx", mock.Anything, (*sql.Tx)(nil), mock.AnythingOfType("*models.Journal")).Return(nil)
+	holdRepo.On("UpdateHoldWithTx", mock.Anything, (*sql.Tx)(nil), hold).Return(nil)
+	var report *models.AMLReport
+	amlRepo.On("CreateAMLReportWithTx", mock.Anything, (*sql.Tx)(nil), mock.AnythingOfType("*models.AMLReport")).
+		Run(func(args mock.Arguments) { report = args.Get(2).(*models.AMLReport) }).
+		Return(nil)
+
+	captured, err := service.CaptureHold(context.Background(), walletID, hold.ID, nil)
+
+	require.NoError(t, err)
+	require.NotNil(t, report)
+	assert.Equal(t, models.AMLReportLargeTransaction, report.Type)
+	assert.Equal(t, models.JournalTypeWithdraw, report.Operation)
+	assert.Equal(t, *captured.CaptureJournalID, report.JournalID)
+	assert.True(t, report.Amount.Equal(decimal.NewFromInt(30)))
+}
+
+func TestReleaseHold(t *testing.T) {
+	service, walletRepo, ledgerRepo, holdRepo := setupHoldService()
+
+	walletID := uuid.New()
+	hold := createActiveHold(walletID, 30.0)
+	walletRepo.On("GetWalletByIDWithTx", mock.Anything, (*sql.Tx)(nil), walletID).Return(createHeldWallet(walletID, 100.0, 45.0), nil)
+	holdRepo.On("GetHoldWithTx", mock.Anything, (*sql.Tx)(nil), hold.ID).Return(hold, nil)
+	walletRepo.On("UpdateHeldBalanceWithTx", mock.Anything, (*sql.Tx)(nil), walletID, mock.MatchedBy(decimal.NewFromInt(15).Equal), mock.Anything).Return(nil)
+	holdRepo.On("UpdateHoldWithTx", mock.Anything, (*sql.Tx)(nil), hold).Return(nil)
+
+	released, err := service.ReleaseHold(context.Background(), walletID, hold.ID)
+
+	require.NoError(t, err)
+	assert.Equal(t, models.HoldStatusReleased, released.Status)
+	walletRepo.AssertExpectations(t)
+	walletRepo.AssertNotCalled(t, "UpdateBalanceWithTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	ledgerRepo.AssertNotCalled(t, "CreateJournalWithTx", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestFinalizeHoldErrors(t *testing.T) {
+	walletID := uuid.New()
+
+	tests := []struct {
+		name    string
+		hold    func() *models.Hold
+		repoErr error
+		wantErr error
+	}{
+		{
+			name:    "unknown hold",
+			hold:    func() *models.Hold { return nil },
+			repoErr: repository.ErrNotFound,
+			wantErr: ErrHoldNotFound,
+		},
+		{
+			name:    "another wallet's hold",
+			hold:    func() *models.Hold { return createActiveHold(uuid.New(), 30.0) },
+			wantErr: ErrHoldNotFound,
+		},
+		{
+			name: "already captured",
+			hold: func() *models.Hold {
+				hold := createActiveHold(walletID, 30.0)
+				hold.Status = models.HoldStatusCaptured
+				return hold
+			},
+			wantErr: ErrHoldNotActive,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, walletRepo, _, holdRepo := setupHoldService()
+			holdID := uuid.New()
+			walletRepo.On("GetWalletByIDWithTx", mock.Anything, (*sql.Tx)(nil), walletID).Return(createHeldWallet(walletID, 100.0, 30.0), nil)
+			if hold := tt.hold(); hold != nil {
+				holdRepo.On("GetHoldWithTx", mock.Anything, (*sql.Tx)(nil), holdID).Return(hold, nil)
+			} else {
+				holdRepo.On("GetHoldWithTx", mock.Anything, (*sql.Tx)(nil), holdID).Return(nil, tt.repoErr)
+			}
+
+			_, err := service.CaptureHold(context.Background(), walletID, holdID, nil)
+			assert.ErrorIs(t, err, tt.wantErr)
+
+			_, err = service.ReleaseHold(context.Background(), walletID, holdID)
+			assert.ErrorIs(t, err, tt.wantErr)
+
+			walletRepo.AssertNotCalled(t, "UpdateHeldBalanceWithTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
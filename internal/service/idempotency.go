@@ -0,0 +1,122 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository"
+)
+
+// ErrIdempotencyKeyReused is returned when a key is retried with a different operation
+var ErrIdempotencyKeyReused = errors.New("idempotency key was already used for a different request")
+
+// scopedIdempotencyKey namespaces a client's key by the wallet the money leaves or
+// enters through, so keys chosen by different wallet owners cannot collide
+func scopedIdempotencyKey(walletID uuid.UUID, key string) *string {
+	if key == "" {
+		return nil
+	}
+	scoped := walletID.String() + ":" + key
+	return &scoped
+}
+
+// idempotent runs op unless a journal with the same idempotency key has already been
+// recorded, and reports whether op was skipped because the call is a replay. The unique
+// key on journals catches a concurrent retry that commits between the lookup and op.
+func (s *WalletService) idempotent(ctx context.Context, journal *models.Journal, op func() error) (bool, error) {
+	if journal.IdempotencyKey == nil {
+		return false, op()
+	}
+
+	if replayed, err := s.findRecordedJournal(ctx, journal); err != nil || replayed {
+		return replayed, err
+	}
+
+	err := op()
+	if errors.Is(err, repository.ErrDuplicate) {
+		return s.findRecordedJournal(ctx, journal)
+	}
+	return false, err
+}
+
+// findRecordedJournal looks up the journal stored under the key of expected and
+// checks that it records the same movement of money
+func (s *WalletService) findRecordedJournal(ctx context.Context, expected *models.Journal) (bool, error) {
+	recorded, err := s.LedgerRepo.GetJournalByIdempotencyKey(ctx, *expected.IdempotencyKey)
+	if errors.Is(err, repository.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to look up idempotency key: %w", err)
+	}
+
+	if !sameMovement(recorded, expected) {
+		return false, ErrIdempotencyKeyReused
+	}
+	return true, nil
+}
+
+// sameMovement compares the type and legs of two journals, ignoring IDs, timestamps
+// and descriptions, so a retry whose request body differs only cosmetically still matches
+func sameMovement(a, b *models.Journal) bool {
+	aLegs, bLegs := requestedLegs(a), requestedLegs(b)
+	if a.Type != b.Type || len(aLegs) != len(bLegs) {
+		return false
+	}
+
+	type leg struct {
+		wallet    uuid.UUID
+		direction string
+		amount    string
+		currency  string
+	}
+	key := func(e *models.LedgerEntry) leg {
+		l := leg{direction: e.Direction, amount: e.Amount.String(), currency: e.Currency.String()}
+		if e.WalletID != nil {
+			l.wallet = *e.WalletID
+		}
+		return l
+	}
+
+	legs := make(map[leg]int)
+	for _, e := range aLegs {
+		legs[key(e)]++
+	}
+	for _, e := range bLegs {
+		legs[key(e)]--
+	}
+	for _, n := range legs {
+		if n != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// requestedLegs returns a journal's legs as requested, before any currency conversion:
+// the settlement legs a conversion adds are dropped and the recipient's leg is counted
+// in the sender's currency, so a retry still matches after the rate has moved
+func requestedLegs(j *models.Journal) []*models.LedgerEntry {
+	legs := make([]*models.LedgerEntry, 0, len(j.Entries))
+	for _, e := range j.Entries {
+		switch {
+		case e.ExchangeRate == nil:
+			legs = append(legs, e)
+		case e.WalletID == nil:
+			// A settlement leg added by the conversion
+		case e.Direction == models.EntryDirectionCredit:
+			legs = append(legs, &models.LedgerEntry{
+				WalletID:  e.WalletID,
+				Direction: e.Direction,
+				Amount:    *e.CounterAmount,
+				Currency:  *e.CounterCurrency,
+			})
+		default:
+			legs = append(legs, e)
+		}
+	}
+	return legs
+}
@@ -0,0 +1,350 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository"
+	"github.com/shanwije/wallet-app/pkg/clock"
+	"github.com/shanwije/wallet-app/pkg/logger"
+)
+
+var (
+	// ErrIncomingTransferNotFound is returned when the wallet has no incoming transfer with the ID
+	ErrIncomingTransferNotFound = errors.New("incoming transfer not found")
+	// ErrIncomingTransferNotPending is returned when accepting or rejecting a transfer
+	// that was already accepted, rejected or expired
+	ErrIncomingTransferNotPending = errors.New("incoming transfer is no longer pending")
+	// ErrIncomingTransferExpired is returned when accepting a transfer after its
+	// acceptance window ended; the transfer is returned to its sender
+	ErrIncomingTransferExpired = errors.New("incoming transfer was not accepted in time")
+	// ErrTransferAwaitingAcceptance is returned when reversing or correcting a transfer
+	// its recipient has yet to accept; rejecting it returns the money instead
+	ErrTransferAwaitingAcceptance = errors.New("transfer is awaiting acceptance and is returned if rejected or not accepted in time")
+)
+
+// defaultAcceptanceWindow is how long an incoming transfer waits to be accepted when
+// AcceptanceWindow is zero
+const defaultAcceptanceWindow = 72 * time.Hour
+
+// awaitAcceptance holds what journal, a transfer just posted, credited the recipient
+// until they accept it, when their wallet requires incoming transfers to be accepted.
+// The recipient is locked by tx. The hold is placed whatever else the wallet holds,
+// since it only reserves the money the transfer brought in.
+func (s *WalletService) awaitAcceptance(ctx context.Context, tx *sql.Tx, recipient *models.Wallet, journal *models.Journal) error {
+	if s.IncomingTransfers == nil {
+		return nil
+	}
+	settings, err := s.IncomingTransfers.GetIncomingTransferSettingsWithTx(ctx, tx, recipient.ID)
+	if err != nil {
+		return err
+	}
+	if !settings.RequireAcceptance {
+		return nil
+	}
+
+	transfer, _ := models.NewTransfer(journal)
+	credited := journal.WalletEntry(recipient.ID)
+	window := s.AcceptanceWindow
+	if window <= 0 {
+		window = defaultAcceptanceWindow
+	}
+	now := s.now()
+	incoming := &models.IncomingTransfer{
+		JournalID:    journal.ID,
+		FromWalletID: transfer.FromWalletID,
+		ToWalletID:   recipient.ID,
+		Amount:       credited.Amount,
+		Currency:     credited.Currency,
+		Status:       models.IncomingTransferStatusPending,
+		ExpiresAt:    now.Add(window),
+		CreatedAt:    now,
+	}
+	if err := s.IncomingTransfers.CreateIncomingTransferWithTx(ctx, tx, incoming); err != nil {
+		return err
+	}
+
+	description := "Incoming transfer " + incoming.ID.String()
+	return s.reserveFunds(ctx, tx, recipient, &models.Hold{
+		WalletID:           recipient.ID,
+		Amount:             incoming.Amount,
+		Currency:           incoming.Currency,
+		Description:        &description,
+		Status:             models.HoldStatusActive,
+		IncomingTransferID: &incoming.ID,
+	})
+}
+
+// IncomingTransferService lets a wallet's owner require incoming transfers to be
+// accepted, and accepts, rejects and expires the transfers waiting for it. A rejected
+// or expired transfer is reversed, returning the money to its sender; any fee the
+// sender paid is kept.
+type IncomingTransferService struct {
+	Repo    repository.IncomingTransferRepository
+	Wallets *WalletService
+	// Clock is optional; the system clock is used when nil
+	Clock clock.Clock
+}
+
+// now returns the current time from the injected clock
+func (s *IncomingTransferService) now() time.Time {
+	return clock.OrDefault(s.Clock).Now()
+}
+
+// GetSettings returns whether the wallet requires incoming transfers to be accepted
+func (s *IncomingTransferService) GetSettings(ctx context.Context, walletID uuid.UUID) (*models.IncomingTransferSettings, error) {
+	if _, err := s.Wallets.WalletRepo.GetWalletByID(ctx, walletID); err != nil {
+		return nil, fmt.Errorf("failed to get wallet: %w", walletLookupError(err))
+	}
+
+	settings, err := s.Repo.GetIncomingTransferSettings(ctx, walletID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get incoming transfer settings: %w", err)
+	}
+	return settings, nil
+}
+
+// SetSettings replaces the wallet's incoming transfer settings. Turning acceptance off
+// leaves transfers already waiting to be accepted or rejected as before.
+func (s *IncomingTransferService) SetSettings(ctx context.Context, settings *models.IncomingTransferSettings) (*models.IncomingTransferSettings, error) {
+	if _, err := s.Wallets.WalletRepo.GetWalletByID(ctx, settings.WalletID); err != nil {
+		return nil, fmt.Errorf("failed to get wallet: %w", walletLookupError(err))
+	}
+
+	settings.UpdatedAt = s.now()
+	if err := s.Repo.SetIncomingTransferSettings(ctx, settings); err != nil {
+		return nil, fmt.Errorf("failed to set incoming transfer settings: %w", err)
+	}
+	return settings, nil
+}
+
+// List returns the transfers into the wallet that needed accepting, newest first
+func (s *IncomingTransferService) List(ctx context.Context, walletID uuid.UUID) ([]*models.IncomingTransfer, error) {
+	transfers, err := s.Repo.ListIncomingTransfersByWalletID(ctx, walletID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list incoming transfers: %w", err)
+	}
+
+	return transfers, nil
+}
+
+// Accept frees the money of a pending transfer into the wallet. One accepted after it
+// expired is returned to its sender instead.
+func (s *IncomingTransferService) Accept(ctx context.Context, walletID, transferID uuid.UUID) (*models.IncomingTransfer, error) {
+	transfer, err := s.get(ctx, walletID, transferID)
+	if err != nil {
+		return nil, err
+	}
+	if transfer.Status != models.IncomingTransferStatusPending {
+		return nil, ErrIncomingTransferNotPending
+	}
+	if !s.now().Before(transfer.ExpiresAt) {
+		if _, err := s.finish(ctx, transferID, models.IncomingTransferStatusExpired); err != nil {
+			return nil, err
+		}
+		return nil, ErrIncomingTransferExpired
+	}
+
+	return s.finish(ctx, transferID, models.IncomingTransferStatusAccepted)
+}
+
+// Reject returns a pending transfer into the wallet to its sender
+func (s *IncomingTransferService) Reject(ctx context.Context, walletID, transferID uuid.UUID) (*models.IncomingTransfer, error) {
+	if _, err := s.get(ctx, walletID, transferID); err != nil {
+		return nil, err
+	}
+	return s.finish(ctx, transferID, models.IncomingTransferStatusRejected)
+}
+
+// finish moves a transfer that is still pending to status, freeing its hold. Unless it
+// is accepted the transfer is reversed in the same transaction, which needs both
+// wallets active. A transfer whose journal was already reversed some other way has had
+// its money returned, so it is only moved to status.
+func (s *IncomingTransferService) finish(ctx context.Context, transferID uuid.UUID, status string) (*models.IncomingTransfer, error) {
+	existing, err := s.Repo.GetIncomingTransfer(ctx, transferID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get incoming transfer: %w", err)
+	}
+	var original *models.Journal
+	if status != models.IncomingTransferStatusAccepted {
+		if original, err = s.Wallets.LedgerRepo.GetJournalByID(ctx, existing.JournalID); err != nil {
+			return nil, fmt.Errorf("failed to get transfer journal: %w", err)
+		}
+	}
+
+	transfer, err := s.complete(ctx, transferID, status, original)
+	if errors.Is(err, ErrAlreadyReversed) {
+		logger.FromContext(ctx).Warn("Incoming transfer was already reversed, closing it without returning it",
+			zap.String("incoming_transfer_id", transferID.String()),
+			zap.String("status", status))
+		return s.complete(ctx, transferID, status, nil)
+	}
+	return transfer, err
+}
+
+// complete moves the transfer to status and frees its hold in one transaction, posting
+// the return of original when it is set
+func (s *IncomingTransferService) complete(ctx context.Context, transferID uuid.UUID, status string, original *models.Journal) (*models.IncomingTransfer, error) {
+	var transfer *models.IncomingTransfer
+	err := s.Wallets.withTx(ctx, "finish incoming transfer", func(ctx context.Context, tx *sql.Tx) error {
+		current, err := s.Repo.GetIncomingTransferWithTx(ctx, tx, transferID)
+		if err != nil {
+			return err
+		}
+		if current.Status != models.IncomingTransferStatusPending {
+			return ErrIncomingTransferNotPending
+		}
+
+		// Both wallets are locked up front, in the order a reversal locks them
+		wallets := make(map[uuid.UUID]*models.Wallet)
+		for _, id := range lockOrder(current.FromWalletID, current.ToWalletID) {
+			if wallets[id], err = s.Wallets.getWalletForUpdate(ctx, tx, id); err != nil {
+				return fmt.Errorf("failed to get wallet: %w", err)
+			}
+		}
+		if current.HoldID != nil {
+			hold, err := s.Wallets.HoldRepo.GetHoldWithTx(ctx, tx, *current.HoldID)
+			if err != nil {
+				return err
+			}
+			if err := s.Wallets.releaseHold(ctx, tx, wallets[current.ToWalletID], hold); err != nil {
+				return err
+			}
+		}
+
+		if original != nil {
+			returned, err := s.returnTransfer(ctx, tx, status, original)
+			if err != nil {
+				return err
+			}
+			current.ReturnJournalID = &returned.ID
+		}
+
+		current.Status = status
+		current.UpdatedAt = s.now()
+		if err := s.Repo.UpdateIncomingTransferWithTx(ctx, tx, current); err != nil {
+			return err
+		}
+
+		transfer = current
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return transfer, nil
+}
+
+// returnTransfer posts the reversal of original, the transfer that was rejected or expired
+func (s *IncomingTransferService) returnTransfer(ctx context.Context, tx *sql.Tx, status string, original *models.Journal) (*models.Journal, error) {
+	entries, err := reversalEntries(original, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	description := "Return of " + status + " incoming transfer"
+	reversal := newJournal(models.JournalTypeReversal, &description, nil, entries...)
+	reversal.ReversesJournalID = &original.ID
+	if err := s.Wallets.applyReversal(ctx, tx, reversal); err != nil {
+		return nil, err
+	}
+	if err := s.Wallets.recordJournal(ctx, tx, reversal); err != nil {
+		if errors.Is(err, repository.ErrDuplicate) {
+			return nil, ErrAlreadyReversed
+		}
+		return nil, err
+	}
+	return reversal, nil
+}
+
+// get returns the transfer with the ID into the wallet
+func (s *IncomingTransferService) get(ctx context.Context, walletID, transferID uuid.UUID) (*models.IncomingTransfer, error) {
+	transfer, err := s.Repo.GetIncomingTransfer(ctx, transferID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrIncomingTransferNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get incoming transfer: %w", err)
+	}
+
+	// Other wallets' transfers are reported as missing rather than forbidden
+	if transfer.ToWalletID != walletID {
+		return nil, ErrIncomingTransferNotFound
+	}
+	return transfer, nil
+}
+
+// Run returns expired transfers to their senders every interval until ctx is cancelled
+func (s *IncomingTransferService) Run(ctx context.Context, interval time.Duration) {
+	log := logger.FromContext(ctx)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		expired, err := s.ExpireDue(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Error("Incoming transfer expiry failed", zap.Error(err))
+		} else if expired > 0 {
+			log.Info("Returned expired incoming transfers", zap.Int("count", expired))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ExpireDue returns the transfers whose acceptance window has ended to their senders
+// and reports how many it returned. A transfer that cannot be returned, such as while
+// either wallet is frozen, is logged and tried again on a later run without holding up
+// the rest; one accepted or rejected while this runs is left to whichever gets it first.
+func (s *IncomingTransferService) ExpireDue(ctx context.Context) (int, error) {
+	now := s.now()
+	expired := 0
+	after := uuid.Nil
+	for {
+		due, err := s.Repo.ListExpiredIncomingTransfers(ctx, now, after, expiryBatchSize)
+		if err != nil {
+			return expired, fmt.Errorf("failed to list expired incoming transfers: %w", err)
+		}
+		for _, transfer := range due {
+			_, err := s.finish(ctx, transfer.ID, models.IncomingTransferStatusExpired)
+			if errors.Is(err, ErrIncomingTransferNotPending) {
+				continue
+			}
+			if declinesTransfer(err) {
+				logger.FromContext(ctx).Warn("Expired incoming transfer cannot be returned yet", zap.Error(err),
+					zap.String("incoming_transfer_id", transfer.ID.String()))
+				continue
+			}
+			if err != nil {
+				if ctx.Err() != nil {
+					return expired, err
+				}
+				logger.FromContext(ctx).Error("Failed to return expired incoming transfer", zap.Error(err),
+					zap.String("incoming_transfer_id", transfer.ID.String()))
+				continue
+			}
+			expired++
+		}
+		// Transfers left pending stay behind the cursor, so a page of them does not
+		// stop the run from reaching the ones after
+		if len(due) < expiryBatchSize {
+			break
+		}
+		after = due[len(due)-1].ID
+	}
+
+	return expired, nil
+}
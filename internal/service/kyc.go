@@ -0,0 +1,169 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository"
+	"github.com/shanwije/wallet-app/pkg/clock"
+	"github.com/shanwije/wallet-app/pkg/pagination"
+)
+
+var (
+	// ErrInvalidKYCTier is returned for a tier other than unverified, basic or full
+	ErrInvalidKYCTier = errors.New("tier must be unverified, basic or full")
+	// ErrInvalidKYCDocument is returned for a document of an unknown type or without a reference
+	ErrInvalidKYCDocument = errors.New("document_type must be passport, national_id, drivers_license or proof_of_address, with a reference")
+	// ErrInvalidKYCReview is returned for a review that neither approves nor rejects
+	ErrInvalidKYCReview = errors.New("status must be approved or rejected")
+	// ErrKYCDocumentNotFound is returned when no document has the ID
+	ErrKYCDocumentNotFound = errors.New("KYC document not found")
+	// ErrKYCDocumentReviewed is returned when reviewing a document that was already reviewed
+	ErrKYCDocumentReviewed = errors.New("KYC document was already reviewed")
+)
+
+// KYCService keeps users' verification tiers and the documents they submit for
+// operators to review. The tier sets the daily limits WalletService holds the user's
+// wallet to; reviewing documents does not change it by itself, operators set it.
+type KYCService struct {
+	Repo     repository.KYCRepository
+	UserRepo repository.UserRepository
+	// TierLimits are the limits of each tier, reported with a user's profile
+	TierLimits map[string]models.KYCTierLimits
+	// Clock is optional; the system clock is used when nil
+	Clock clock.Clock
+}
+
+// now returns the current time from the injected clock
+func (s *KYCService) now() time.Time {
+	return clock.OrDefault(s.Clock).Now()
+}
+
+// GetProfile returns the user's tier and its limits, with the documents they submitted
+func (s *KYCService) GetProfile(ctx context.Context, userID uuid.UUID) (*models.KYCProfile, error) {
+	if err := s.checkUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	profile, err := s.Repo.GetKYCProfile(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get KYC profile: %w", err)
+	}
+	if profile.Documents, err = s.Repo.ListKYCDocumentsByUserID(ctx, userID); err != nil {
+		return nil, fmt.Errorf("failed to list KYC documents: %w", err)
+	}
+	profile.Limits = s.TierLimits[profile.Tier]
+	return profile, nil
+}
+
+// SetTier moves the user to tier, recording why as note
+func (s *KYCService) SetTier(ctx context.Context, userID uuid.UUID, tier, note string) (*models.KYCProfile, error) {
+	switch tier {
+	case models.KYCTierUnverified, models.KYCTierBasic, models.KYCTierFull:
+	default:
+		return nil, ErrInvalidKYCTier
+	}
+	if err := s.checkUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	profile := &models.KYCProfile{UserID: userID, Tier: tier, UpdatedAt: &now}
+	if note = strings.TrimSpace(note); note != "" {
+		profile.Note = &note
+	}
+	if err := s.Repo.SetKYCProfile(ctx, profile); err != nil {
+		return nil, fmt.Errorf("failed to set KYC tier: %w", err)
+	}
+	return s.GetProfile(ctx, userID)
+}
+
+// SubmitDocument records a document the user uploaded elsewhere, found at reference,
+// for review
+func (s *KYCService) SubmitDocument(ctx context.Context, userID uuid.UUID, documentType, reference string) (*models.KYCDocument, error) {
+	reference = strings.TrimSpace(reference)
+	switch documentType {
+	case models.KYCDocumentPassport, models.KYCDocumentNationalID, models.KYCDocumentDriversLicense, models.KYCDocumentProofOfAddress:
+	default:
+		return nil, ErrInvalidKYCDocument
+	}
+	if reference == "" {
+		return nil, ErrInvalidKYCDocument
+	}
+	if err := s.checkUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	document := &models.KYCDocument{
+		UserID:       userID,
+		DocumentType: documentType,
+		Reference:    reference,
+		Status:       models.KYCDocumentStatusPending,
+		SubmittedAt:  s.now(),
+	}
+	if err := s.Repo.CreateKYCDocument(ctx, document); err != nil {
+		return nil, fmt.Errorf("failed to create KYC document: %w", err)
+	}
+	return document, nil
+}
+
+// ListPendingDocuments returns a page of the documents waiting for review, oldest first
+func (s *KYCService) ListPendingDocuments(ctx context.Context, limit, offset int) ([]*models.KYCDocument, error) {
+	documents, err := s.Repo.ListKYCDocumentsByStatus(ctx, models.KYCDocumentStatusPending, pagination.ClampLimit(limit), max(offset, 0))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list KYC documents: %w", err)
+	}
+	return documents, nil
+}
+
+// ReviewDocument approves or rejects a pending document, recording why as note
+func (s *KYCService) ReviewDocument(ctx context.Context, documentID uuid.UUID, status, note string) (*models.KYCDocument, error) {
+	if status != models.KYCDocumentStatusApproved && status != models.KYCDocumentStatusRejected {
+		return nil, ErrInvalidKYCReview
+	}
+
+	document, err := s.Repo.GetKYCDocument(ctx, documentID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrKYCDocumentNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get KYC document: %w", err)
+	}
+	if document.Status != models.KYCDocumentStatusPending {
+		return nil, ErrKYCDocumentReviewed
+	}
+
+	now := s.now()
+	document.Status = status
+	document.ReviewedAt = &now
+	document.Note = nil
+	if note = strings.TrimSpace(note); note != "" {
+		document.Note = &note
+	}
+	err = s.Repo.UpdateKYCDocument(ctx, document)
+	if errors.Is(err, repository.ErrNotFound) {
+		// Another operator reviewed it first
+		return nil, ErrKYCDocumentReviewed
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to review KYC document: %w", err)
+	}
+	return document, nil
+}
+
+// checkUser returns ErrUserNotFound unless the user exists
+func (s *KYCService) checkUser(ctx context.Context, userID uuid.UUID) error {
+	if _, err := s.UserRepo.GetUserByID(ctx, userID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrUserNotFound
+		}
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	return nil
+}
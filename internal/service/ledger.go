@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/pkg/money"
+	"github.com/shopspring/decimal"
+)
+
+// ErrLedgerMismatch is returned when a wallet's stored balance differs from its ledger
+var ErrLedgerMismatch = errors.New("wallet balance does not match ledger")
+
+// debit builds a leg taking amount out of the wallet, or out of the external
+// settlement account when walletID is nil
+func debit(walletID *uuid.UUID, amount money.Money) *models.LedgerEntry {
+	return ledgerEntry(walletID, models.EntryDirectionDebit, amount)
+}
+
+// credit builds a leg paying amount into the wallet, or into the external
+// settlement account when walletID is nil
+func credit(walletID *uuid.UUID, amount money.Money) *models.LedgerEntry {
+	return ledgerEntry(walletID, models.EntryDirectionCredit, amount)
+}
+
+func ledgerEntry(walletID *uuid.UUID, direction string, amount money.Money) *models.LedgerEntry {
+	return &models.LedgerEntry{
+		WalletID:  walletID,
+		Direction: direction,
+		Amount:    amount.Amount(),
+		Currency:  amount.Currency(),
+	}
+}
+
+// converting records on a leg of a transfer between currencies the rate it used and
+// counter, the leg's amount in the other currency
+func converting(entry *models.LedgerEntry, rate decimal.Decimal, counter money.Money) *models.LedgerEntry {
+	counterAmount, counterCurrency := counter.Amount(), counter.Currency()
+	entry.ExchangeRate = &rate
+	entry.CounterAmount = &counterAmount
+	entry.CounterCurrency = &counterCurrency
+	return entry
+}
+
+// newJournal assembles a journal from its legs; idempotencyKey may be nil
+func newJournal(journalType string, description, idempotencyKey *string, entries ...*models.LedgerEntry) *models.Journal {
+	return &models.Journal{
+		Type:           journalType,
+		Description:    description,
+		IdempotencyKey: idempotencyKey,
+		Entries:        entries,
+	}
+}
+
+// recordJournal stamps and writes one balanced journal inside tx, along with the
+// wallet event announcing it when the service has an outbox
+func (s *WalletService) recordJournal(ctx context.Context, tx *sql.Tx, journal *models.Journal) error {
+	journal.CreatedAt = s.now()
+
+	if err := s.LedgerRepo.CreateJournalWithTx(ctx, tx, journal); err != nil {
+		return fmt.Errorf("failed to record %s journal: %w", journal.Type, err)
+	}
+	journalRecorded(ctx, journal)
+	if s.Outbox == nil {
+		return nil
+	}
+
+	event := models.NewWalletEvent(journal)
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s event: %w", event.Type, err)
+	}
+	// Money paid in from a system wallet is keyed by the customer wallet it reached,
+	// as it was when it came from the settlement account
+	key := event.KeyWalletID()
+	if event.ToWalletID != nil && s.SystemWallets.isSystemWallet(key) {
+		key = *event.ToWalletID
+	}
+	outboxEvent := &models.OutboxEvent{
+		Type:      event.Type,
+		WalletID:  key,
+		Payload:   payload,
+		CreatedAt: journal.CreatedAt,
+	}
+	if err := s.Outbox.CreateOutboxEventWithTx(ctx, tx, outboxEvent); err != nil {
+		return fmt.Errorf("failed to record %s event: %w", event.Type, err)
+	}
+	return nil
+}
+
+// VerifyWalletBalance proves a wallet's stored balance from its ledger entries alone,
+// returning ErrLedgerMismatch when the two disagree
+func (s *WalletService) VerifyWalletBalance(ctx context.Context, walletID uuid.UUID) error {
+	wallet, err := s.WalletRepo.GetWalletByID(ctx, walletID)
+	if err != nil {
+		return fmt.Errorf("failed to get wallet: %w", walletLookupError(err))
+	}
+
+	ledgerBalance, err := s.LedgerRepo.GetWalletLedgerBalance(ctx, walletID)
+	if err != nil {
+		return fmt.Errorf("failed to get ledger balance: %w", err)
+	}
+
+	if !wallet.Balance.Equal(ledgerBalance) {
+		return fmt.Errorf("%w: stored %s, ledger %s", ErrLedgerMismatch, wallet.Balance, ledgerBalance)
+	}
+	return nil
+}
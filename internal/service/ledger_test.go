@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository/mocks"
+	"github.com/shanwije/wallet-app/internal/testutil"
+	"github.com/shanwije/wallet-app/pkg/clock"
+)
+
+func TestTransferWritesOutboxEventInSameTransaction(t *testing.T) {
+	service, walletRepo, ledgerRepo := setupWalletService()
+	outbox := new(mocks.OutboxRepository)
+	service.Outbox = outbox
+	now := time.Date(2024, 6, 26, 8, 0, 0, 0, time.UTC)
+	service.Clock = clock.NewFake(now)
+
+	fromID, toID := uuid.New(), uuid.New()
+	journalID := uuid.New()
+	walletRepo.On("BeginTx", mock.Anything).Return((*sql.Tx)(nil), nil)
+	walletRepo.On("GetWalletByIDWithTx", mock.Anything, (*sql.Tx)(nil), fromID).Return(testutil.Wallet(fromID, 100.0), nil)
+	walletRepo.On("GetWalletByIDWithTx", mock.Anything, (*sql.Tx)(nil), toID).Return(testutil.Wallet(toID, 0), nil)
+	walletRepo.On("UpdateBalanceWithTx", mock.Anything, (*sql.Tx)(nil), mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	ledgerRepo.On("CreateJournalWithTx", mock.Anything, (*sql.Tx)(nil), mock.AnythingOfType("*models.Journal")).
+		Run(func(args mock.Arguments) { args.Get(2).(*models.Journal).ID = journalID }).
+		Return(nil)
+
+	var written *models.OutboxEvent
+	outbox.On("CreateOutboxEventWithTx", mock.Anything, (*sql.Tx)(nil), mock.AnythingOfType("*models.OutboxEvent")).
+		Run(func(args mock.Arguments) { written = args.Get(2).(*models.OutboxEvent) }).
+		Return(nil)
+
+	err := service.Transfer(context.Background(), fromID, toID, testutil.USD(decimal.NewFromInt(30)), "Rent", "")
+
+	require.NoError(t, err)
+	require.NotNil(t, written)
+	assert.Equal(t, models.EventWalletTransfer, written.Type)
+	assert.Equal(t, fromID, written.WalletID)
+	assert.Equal(t, now, written.CreatedAt)
+
+	var event models.WalletEvent
+	require.NoError(t, json.Unmarshal(written.Payload, &event))
+	assert.Equal(t, journalID, event.JournalID)
+	assert.Equal(t, fromID, *event.FromWalletID)
+	assert.Equal(t, toID, *event.ToWalletID)
+	assert.True(t, event.Amount.Equal(decimal.NewFromInt(30)))
+	assert.Equal(t, "Rent", *event.Description)
+}
+
+func TestJournalFailsWhenOutboxWriteFails(t *testing.T) {
+	service, walletRepo, ledgerRepo := setupWalletService()
+	outbox := new(mocks.OutboxRepository)
+	service.Outbox = outbox
+
+	walletID := uuid.New()
+	walletRepo.On("BeginTx", mock.Anything).Return((*sql.Tx)(nil), nil)
+	walletRepo.On("GetWalletByIDWithTx", mock.Anything, (*sql.Tx)(nil), walletID).Return(testutil.Wallet(walletID, 10.0), nil)
+	walletRepo.On("UpdateBalanceWithTx", mock.Anything, (*sql.Tx)(nil), walletID, mock.Anything, mock.Anything).Return(nil)
+	ledgerRepo.On("CreateJournalWithTx", mock.Anything, (*sql.Tx)(nil), mock.AnythingOfType("*models.Journal")).Return(nil)
+	outbox.On("CreateOutboxEventWithTx", mock.Anything, (*sql.Tx)(nil), mock.AnythingOfType("*models.OutboxEvent")).Return(assert.AnError)
+
+	_, err := service.Deposit(context.Background(), walletID, testutil.USD(decimal.NewFromInt(5)), "")
+
+	assert.ErrorIs(t, err, assert.AnError)
+	assert.ErrorContains(t, err, "failed to record wallet.deposit event")
+}
@@ -0,0 +1,163 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/pkg/money"
+)
+
+var (
+	// ErrLimitExceeded is returned when a movement would break one of the wallet's limits
+	ErrLimitExceeded = errors.New("wallet limit exceeded")
+	// ErrInvalidLimits is returned for a limit that is set but not positive
+	ErrInvalidLimits = errors.New("limits must be positive amounts")
+	// ErrConflictingBalanceFloors is returned for limits with both an overdraft and a minimum balance
+	ErrConflictingBalanceFloors = errors.New("a wallet can have an overdraft limit or a minimum balance, not both")
+)
+
+// limitWindow is the rolling window the daily limits cover
+const limitWindow = 24 * time.Hour
+
+// GetWalletLimits returns the wallet's limits; unset limits are nil
+func (s *WalletService) GetWalletLimits(ctx context.Context, walletID uuid.UUID) (*models.WalletLimits, error) {
+	if _, err := s.WalletRepo.GetWalletByID(ctx, walletID); err != nil {
+		return nil, fmt.Errorf("failed to get wallet: %w", walletLookupError(err))
+	}
+
+	limits, err := s.LimitsRepo.GetWalletLimits(ctx, walletID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get wallet limits: %w", err)
+	}
+	return limits, nil
+}
+
+// SetWalletLimits replaces all of the wallet's limits; nil ones are lifted
+func (s *WalletService) SetWalletLimits(ctx context.Context, limits *models.WalletLimits) (*models.WalletLimits, error) {
+	for _, limit := range []*decimal.Decimal{limits.MaxTransactionAmount, limits.DailyWithdrawalLimit, limits.DailyTransferLimit, limits.OverdraftLimit, limits.MinimumBalance} {
+		if limit != nil && !limit.IsPositive() {
+			return nil, ErrInvalidLimits
+		}
+	}
+	if limits.OverdraftLimit != nil && limits.MinimumBalance != nil {
+		return nil, ErrConflictingBalanceFloors
+	}
+
+	if _, err := s.WalletRepo.GetWalletByID(ctx, limits.WalletID); err != nil {
+		return nil, fmt.Errorf("failed to get wallet: %w", walletLookupError(err))
+	}
+
+	limits.UpdatedAt = s.now()
+	if err := s.LimitsRepo.SetWalletLimits(ctx, limits); err != nil {
+		return nil, fmt.Errorf("failed to set wallet limits: %w", err)
+	}
+	return limits, nil
+}
+
+// spendable returns what the locked wallet can pay out or put on hold: its available
+// balance down to the wallet's overdraft limit, while the overdraft flag is on, or its
+// minimum balance. Without a limits repository the available balance is spendable.
+func (s *WalletService) spendable(ctx context.Context, tx *sql.Tx, wallet *models.Wallet) (money.Money, error) {
+	available := wallet.Available()
+	if s.LimitsRepo == nil {
+		return available, nil
+	}
+
+	limits, err := s.LimitsRepo.GetWalletLimitsWithTx(ctx, tx, wallet.ID)
+	if err != nil {
+		return money.Money{}, fmt.Errorf("failed to get wallet limits: %w", err)
+	}
+	floor := limits.BalanceFloor()
+	if floor.IsNegative() && !s.featureEnabled(ctx, FlagOverdraft) {
+		floor = decimal.Zero
+	}
+	return money.New(available.Amount().Sub(floor), wallet.Currency), nil
+}
+
+// checkLimits rejects amount leaving or entering the locked wallet as a journal of
+// journalType if it breaks the wallet's limits or those of its owner's KYC tier
+func (s *WalletService) checkLimits(ctx context.Context, tx *sql.Tx, wallet *models.Wallet, journalType string, amount money.Money) error {
+	if err := s.checkWalletLimits(ctx, tx, wallet, journalType, amount); err != nil {
+		return err
+	}
+	return s.checkTierLimits(ctx, tx, wallet, journalType, amount)
+}
+
+// checkWalletLimits holds every movement to the wallet's per-transaction limit;
+// withdrawals and transfers out also count against their daily limit, together with
+// what tx has already recorded. No limits are enforced when the service has no limits
+// repository.
+func (s *WalletService) checkWalletLimits(ctx context.Context, tx *sql.Tx, wallet *models.Wallet, journalType string, amount money.Money) error {
+	if s.LimitsRepo == nil {
+		return nil
+	}
+
+	limits, err := s.LimitsRepo.GetWalletLimitsWithTx(ctx, tx, wallet.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get wallet limits: %w", err)
+	}
+
+	if perTransaction := limits.MaxTransactionAmount; perTransaction != nil && amount.Amount().GreaterThan(*perTransaction) {
+		return fmt.Errorf("%w: %s is over the %s per transaction limit", ErrLimitExceeded, amount, money.New(*perTransaction, wallet.Currency))
+	}
+
+	var daily *decimal.Decimal
+	switch journalType {
+	case models.JournalTypeWithdraw:
+		daily = limits.DailyWithdrawalLimit
+	case models.JournalTypeTransfer:
+		daily = limits.DailyTransferLimit
+	}
+	if daily == nil {
+		return nil
+	}
+	return s.checkDailyLimit(ctx, tx, wallet, journalType, amount, *daily, "")
+}
+
+// checkTierLimits holds withdrawals and transfers out to the daily limits of the KYC
+// tier of the wallet's owner. None are enforced without a KYC repository.
+func (s *WalletService) checkTierLimits(ctx context.Context, tx *sql.Tx, wallet *models.Wallet, journalType string, amount money.Money) error {
+	if s.KYCRepo == nil || len(s.TierLimits) == 0 {
+		return nil
+	}
+	if journalType != models.JournalTypeWithdraw && journalType != models.JournalTypeTransfer {
+		return nil
+	}
+
+	tier, err := s.KYCRepo.GetKYCTierWithTx(ctx, tx, wallet.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to get KYC tier: %w", err)
+	}
+	limits := s.TierLimits[tier]
+	daily := limits.DailyTransferLimit
+	if journalType == models.JournalTypeWithdraw {
+		daily = limits.DailyWithdrawalLimit
+	}
+	if daily == nil {
+		return nil
+	}
+	return s.checkDailyLimit(ctx, tx, wallet, journalType, amount, *daily, " for "+tier+" users")
+}
+
+// checkDailyLimit rejects amount if, with what left the wallet in journals of
+// journalType over the last 24 hours, it comes to more than limit. scope says whose
+// limit it is in the error.
+func (s *WalletService) checkDailyLimit(ctx context.Context, tx *sql.Tx, wallet *models.Wallet, journalType string, amount money.Money, limit decimal.Decimal, scope string) error {
+	spent, err := s.LedgerRepo.SumWalletDebitsSinceWithTx(ctx, tx, wallet.ID, journalType, s.now().Add(-limitWindow))
+	if err != nil {
+		return fmt.Errorf("failed to sum recent %s movements: %w", journalType, err)
+	}
+	if spent.Add(amount.Amount()).GreaterThan(limit) {
+		remaining := decimal.Max(limit.Sub(spent), decimal.Zero)
+		return fmt.Errorf("%w: only %s of the daily %s limit%s is left", ErrLimitExceeded,
+			money.New(remaining, wallet.Currency), journalType, scope)
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository/mocks"
+	"github.com/shanwije/wallet-app/internal/testutil"
+	"github.com/shanwije/wallet-app/pkg/clock"
+	"github.com/shanwije/wallet-app/pkg/featureflag"
+)
+
+// setupLimitedWalletService creates a wallet service whose wallet has the given limits
+func setupLimitedWalletService(walletID uuid.UUID, limits *models.WalletLimits) (*WalletService, *mocks.WalletRepository, *mocks.LedgerRepository) {
+	service, walletRepo, ledgerRepo := setupWalletService()
+	limitsRepo := new(mocks.WalletLimitsRepository)
+	limitsRepo.On("GetWalletLimitsWithTx", mock.Anything, (*sql.Tx)(nil), walletID).Return(limits, nil)
+	service.LimitsRepo = limitsRepo
+	walletRepo.On("BeginTx", mock.Anything).Return((*sql.Tx)(nil), nil)
+	return service, walletRepo, ledgerRepo
+}
+
+func decimalPtr(value int64) *decimal.Decimal {
+	d := decimal.NewFromInt(value)
+	return &d
+}
+
+func TestWithdrawRejectsAmountOverPerTransactionLimit(t *testing.T) {
+	walletID := uuid.New()
+	service, walletRepo, _ := setupLimitedWalletService(walletID, &models.WalletLimits{WalletID: walletID, MaxTransactionAmount: decimalPtr(50)})
+	walletRepo.On("GetWalletByIDWithTx", mock.Anything, (*sql.Tx)(nil), walletID).Return(testutil.Wallet(walletID, 100.0), nil)
+
+	_, err := service.Withdraw(context.Background(), walletID, testutil.USD(decimal.NewFromInt(60)), "")
+
+	assert.ErrorIs(t, err, ErrLimitExceeded)
+	walletRepo.AssertNotCalled(t, "UpdateBalanceWithTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestWithdrawCountsAgainstRollingDailyLimit(t *testing.T) {
+	walletID := uuid.New()
+	now := time.Date(2024, 6, 24, 9, 0, 0, 0, time.UTC)
+	service, walletRepo, ledgerRepo := setupLimitedWalletService(walletID, &models.WalletLimits{WalletID: walletID, DailyWithdrawalLimit: decimalPtr(100)})
+	service.Clock = clock.NewFake(now)
+	walletRepo.On("GetWalletByIDWithTx", mock.Anything, (*sql.Tx)(nil), walletID).Return(testutil.Wallet(walletID, 500.0), nil)
+	ledgerRepo.On("SumWalletDebitsSinceWithTx", mock.Anything, (*sql.Tx)(nil), walletID, models.JournalTypeWithdraw, now.Add(-24*time.Hour)).
+		Return(decimal.NewFromInt(70), nil)
+
+	_, err := service.Withdraw(context.Background(), walletID, testutil.USD(decimal.NewFromInt(40)), "")
+
+	assert.ErrorIs(t, err, ErrLimitExceeded)
+	assert.ErrorContains(t, err, "only 30.00 USD")
+	ledgerRepo.AssertExpectations(t)
+}
+
+func TestTransferWithinDailyLimit(t *testing.T) {
+	fromWalletID, toWalletID := uuid.New(), uuid.New()
+	service, walletRepo, ledgerRepo := setupLimitedWalletService(fromWalletID, &models.WalletLimits{WalletID: fromWalletID, DailyTransferLimit: decimalPtr(100)})
+	walletRepo.On("GetWalletByIDWithTx", mock.Anything, (*sql.Tx)(nil), fromWalletID).Return(testutil.Wallet(fromWalletID, 500.0), nil)
+	walletRepo.On("GetWalletByIDWithTx", mock.Anything, (*sql.Tx)(nil), toWalletID).Return(testutil.Wallet(toWalletID, 0), nil)
+	walletRepo.On("UpdateBalanceWithTx", mock.Anything, (*sql.Tx)(nil), mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	ledgerRepo.On("SumWalletDebitsSinceWithTx", mock.Anything, (*sql.Tx)(nil), fromWalletID, models.JournalTypeTransfer, mock.Anything).
+		Return(decimal.NewFromInt(60), nil)
+	ledgerRepo.On("CreateJournalWithTx", mock.Anything, (*sql.Tx)(nil), mock.Anything).Return(nil)
+
+	err := service.Transfer(context.Background(), fromWalletID, toWalletID, testutil.USD(decimal.NewFromInt(40)), "Rent", "")
+
+	require.NoError(t, err)
+	ledgerRepo.AssertExpectations(t)
+}
+
+func TestSetWalletLimitsRejectsNonPositiveLimit(t *testing.T) {
+	service, walletRepo, _ := setupWalletService()
+
+	_, err := service.SetWalletLimits(context.Background(), &models.WalletLimits{WalletID: uuid.New(), DailyTransferLimit: decimalPtr(0)})
+
+	assert.ErrorIs(t, err, ErrInvalidLimits)
+	walletRepo.AssertNotCalled(t, "GetWalletByID", mock.Anything, mock.Anything)
+}
+
+func TestWithdrawIntoOverdraft(t *testing.T) {
+	walletID := uuid.New()
+	service, walletRepo, ledgerRepo := setupLimitedWalletService(walletID, &models.WalletLimits{WalletID: walletID, OverdraftLimit: decimalPtr(50)})
+	walletRepo.On("GetWalletByIDWithTx", mock.Anything, (*sql.Tx)(nil), walletID).Return(testutil.Wallet(walletID, 20.0), nil)
+	walletRepo.On("UpdateBalanceWithTx", mock.Anything, (*sql.Tx)(nil), walletID, mock.MatchedBy(decimal.NewFromInt(-30).Equal), mock.Anything).Return(nil)
+	ledgerRepo.On("CreateJournalWithTx", mock.Anything, (*sql.Tx)(nil), mock.Anything).Return(nil)
+
+	wallet, err := service.Withdraw(context.Background(), walletID, testutil.USD(decimal.NewFromInt(50)), "")
+
+	require.NoError(t, err)
+	assert.True(t, wallet.Balance.Equal(decimal.NewFromInt(-30)))
+	walletRepo.AssertExpectations(t)
+}
+
+func TestWithdrawBeyondOverdraft(t *testing.T) {
+	walletID := uuid.New()
+	service, walletRepo, _ := setupLimitedWalletService(walletID, &models.WalletLimits{WalletID: walletID, OverdraftLimit: decimalPtr(50)})
+	walletRepo.On("GetWalletByIDWithTx", mock.Anything, (*sql.Tx)(nil), walletID).Return(testutil.Wallet(walletID, 20.0), nil)
+
+	_, err := service.Withdraw(context.Background(), walletID, testutil.USD(decimal.NewFromInt(71)), "")
+
+	assert.ErrorIs(t, err, ErrInsufficientBalance)
+	walletRepo.AssertNotCalled(t, "UpdateBalanceWithTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestTransferKeepsMinimumBalance(t *testing.T) {
+	fromWalletID, toWalletID := uuid.New(), uuid.New()
+	service, walletRepo, _ := setupLimitedWalletService(fromWalletID, &models.WalletLimits{WalletID: fromWalletID, MinimumBalance: decimalPtr(25)})
+	walletRepo.On("GetWalletByIDWithTx", mock.Anything, (*sql.Tx)(nil), fromWalletID).Return(testutil.Wallet(fromWalletID, 100.0), nil)
+	walletRepo.On("GetWalletByIDWithTx", mock.Anything, (*sql.Tx)(nil), toWalletID).Return(testutil.Wallet(toWalletID, 0), nil)
+
+	err := service.Transfer(context.Background(), fromWalletID, toWalletID, testutil.USD(decimal.NewFromInt(80)), "", "")
+
+	assert.ErrorIs(t, err, ErrInsufficientBalance)
+	walletRepo.AssertNotCalled(t, "UpdateBalanceWithTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSetWalletLimitsRejectsOverdraftWithMinimumBalance(t *testing.T) {
+	service, walletRepo, _ := setupWalletService()
+
+	_, err := service.SetWalletLimits(context.Background(), &models.WalletLimits{
+		WalletID:       uuid.New(),
+		OverdraftLimit: decimalPtr(100),
+		MinimumBalance: decimalPtr(10),
+	})
+
+	assert.ErrorIs(t, err, ErrConflictingBalanceFloors)
+	walletRepo.AssertNotCalled(t, "GetWalletByID", mock.Anything, mock.Anything)
+}
+
+func TestWithdrawStopsAtZeroWithOverdraftSwitchedOff(t *testing.T) {
+	walletID := uuid.New()
+	service, walletRepo, _ := setupLimitedWalletService(walletID, &models.WalletLimits{WalletID: walletID, OverdraftLimit: decimalPtr(50)})
+	service.Flags = featureflag.New(map[string]bool{FlagOverdraft: false, FlagWithdrawals: true}, featureflag.NewMemory(), 0, nil)
+	walletRepo.On("GetWalletByIDWithTx", mock.Anything, (*sql.Tx)(nil), walletID).Return(testutil.Wallet(walletID, 20.0), nil)
+
+	_, err := service.Withdraw(context.Background(), walletID, testutil.USD(decimal.NewFromInt(30)), "")
+
+	assert.ErrorIs(t, err, ErrInsufficientBalance)
+	walletRepo.AssertNotCalled(t, "UpdateBalanceWithTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestCaptureHoldHeldToWalletLimits(t *testing.T) {
+	now := time.Date(2024, 7, 20, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		limits  models.WalletLimits
+		spent   int64
+		wantErr string
+	}{
+		{
+			name:    "over the per transaction limit",
+			limits:  models.WalletLimits{MaxTransactionAmount: decimalPtr(25)},
+			wantErr: "over the 25.00 USD per transaction limit",
+		},
+		{
+			name:    "over the daily withdrawal limit",
+			limits:  models.WalletLimits{DailyWithdrawalLimit: decimalPtr(100)},
+			spent:   80,
+			wantErr: "only 20.00 USD of the daily withdraw limit",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			walletID := uuid.New()
+			limits := tt.limits
+			limits.WalletID = walletID
+			service, walletRepo, ledgerRepo := setupLimitedWalletService(walletID, &limits)
+			service.Clock = clock.NewFake(now)
+			holdRepo := new(mocks.HoldRepository)
+			service.HoldRepo = holdRepo
+			hold := createActiveHold(walletID, 30.0)
+			walletRepo.On("GetWalletByIDWithTx", mock.Anything, (*sql.Tx)(nil), walletID).Return(createHeldWallet(walletID, 100.0, 30.0), nil)
+			holdRepo.On("GetHoldWithTx", mock.Anything, (*sql.Tx)(nil), hold.ID).Return(hold, nil)
+			ledgerRepo.On("SumWalletDebitsSinceWithTx", mock.Anything, (*sql.Tx)(nil), walletID, models.JournalTypeWithdraw, now.Add(-24*time.Hour)).
+				Return(decimal.NewFromInt(tt.spent), nil)
+
+			_, err := service.CaptureHold(context.Background(), walletID, hold.ID, nil)
+
+			assert.ErrorIs(t, err, ErrLimitExceeded)
+			assert.ErrorContains(t, err, tt.wantErr)
+			walletRepo.AssertNotCalled(t, "UpdateHeldBalanceWithTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			ledgerRepo.AssertNotCalled(t, "CreateJournalWithTx", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
@@ -0,0 +1,193 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/notify"
+	"github.com/shanwije/wallet-app/internal/repository"
+	"github.com/shanwije/wallet-app/pkg/clock"
+	"github.com/shanwije/wallet-app/pkg/money"
+)
+
+// ErrInvalidNotificationThreshold is returned for an alert threshold that is set but not positive
+var ErrInvalidNotificationThreshold = errors.New("alert thresholds must be positive amounts")
+
+// NotificationService emails users alerts about their transactions. It reads wallet
+// events as a publisher of the outbox, so an alert is only sent for a movement that
+// committed, and is retried until the provider accepts it. Delivery is at least once:
+// an event that fails part way through is retried from the start.
+type NotificationService struct {
+	Repo       repository.NotificationPreferencesRepository
+	UserRepo   repository.UserRepository
+	WalletRepo repository.WalletRepository
+	Provider   notify.Provider
+	// Defaults are the preferences of users who never set their own
+	Defaults models.NotificationPreferences
+	// Clock is optional; the system clock is used when nil
+	Clock clock.Clock
+}
+
+// GetPreferences returns the user's notification preferences, the defaults when they
+// never set any
+func (s *NotificationService) GetPreferences(ctx context.Context, userID uuid.UUID) (*models.NotificationPreferences, error) {
+	if _, err := s.UserRepo.GetUserByID(ctx, userID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	return s.preferences(ctx, userID)
+}
+
+// SetPreferences replaces all of the user's notification preferences
+func (s *NotificationService) SetPreferences(ctx context.Context, preferences *models.NotificationPreferences) (*models.NotificationPreferences, error) {
+	if threshold := preferences.LargeWithdrawalThreshold; threshold != nil && !threshold.IsPositive() {
+		return nil, ErrInvalidNotificationThreshold
+	}
+	if _, err := s.UserRepo.GetUserByID(ctx, preferences.UserID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	preferences.UpdatedAt = clock.OrDefault(s.Clock).Now()
+	if err := s.Repo.SetNotificationPreferences(ctx, preferences); err != nil {
+		return nil, fmt.Errorf("failed to set notification preferences: %w", err)
+	}
+	return preferences, nil
+}
+
+// preferences returns the stored preferences of the user, or the defaults
+func (s *NotificationService) preferences(ctx context.Context, userID uuid.UUID) (*models.NotificationPreferences, error) {
+	preferences, err := s.Repo.GetNotificationPreferences(ctx, userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		defaults := s.Defaults
+		defaults.UserID = userID
+		return &defaults, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notification preferences: %w", err)
+	}
+	return preferences, nil
+}
+
+// Publish sends the alerts a wallet event calls for to the owners of the wallets it
+// moved money between, and emails the owner of a wallet a low balance alert raised for
+// it. Owners without an email address, and deleted ones, get none.
+func (s *NotificationService) Publish(ctx context.Context, event *models.OutboxEvent) error {
+	if event.Type == models.EventLowBalanceAlert {
+		var alert models.WalletAlert
+		if err := json.Unmarshal(event.Payload, &alert); err != nil {
+			return fmt.Errorf("failed to decode %s event: %w", event.Type, err)
+		}
+		return s.alertLowBalance(ctx, alert)
+	}
+	if !strings.HasPrefix(event.Type, "wallet.") {
+		return nil
+	}
+	var movement models.WalletEvent
+	if err := json.Unmarshal(event.Payload, &movement); err != nil {
+		return fmt.Errorf("failed to decode %s event: %w", event.Type, err)
+	}
+
+	if movement.Type == models.EventWalletWithdraw && movement.FromWalletID != nil {
+		if err := s.alertWithdrawal(ctx, *movement.FromWalletID, movement); err != nil {
+			return err
+		}
+	}
+	if movement.Type == models.EventWalletTransfer && movement.ToWalletID != nil {
+		if err := s.alertRecipient(ctx, *movement.ToWalletID, movement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *NotificationService) Close() error { return nil }
+
+// alertWithdrawal tells the wallet's owner about a withdrawal of at least their threshold
+func (s *NotificationService) alertWithdrawal(ctx context.Context, walletID uuid.UUID, movement models.WalletEvent) error {
+	email, preferences, err := s.recipient(ctx, walletID)
+	if err != nil || email == "" {
+		return err
+	}
+	threshold := preferences.LargeWithdrawalThreshold
+	if threshold == nil || movement.Amount.LessThan(*threshold) {
+		return nil
+	}
+
+	return s.send(ctx, email, "Large withdrawal from your wallet",
+		fmt.Sprintf("%s was withdrawn from your wallet at %s. If this was not you, contact support straight away.",
+			money.New(movement.Amount, movement.Currency), movement.OccurredAt.UTC().Format("2 Jan 2006 15:04 MST")))
+}
+
+// alertLowBalance tells the wallet's owner that its balance fell below their threshold
+func (s *NotificationService) alertLowBalance(ctx context.Context, alert models.WalletAlert) error {
+	email, _, err := s.recipient(ctx, alert.WalletID)
+	if err != nil || email == "" {
+		return err
+	}
+
+	return s.send(ctx, email, "Your wallet balance is low",
+		fmt.Sprintf("A payment at %s left your wallet balance at %s, below the %s you asked to be told about.",
+			alert.CreatedAt.UTC().Format("2 Jan 2006 15:04 MST"),
+			money.New(alert.Balance, alert.Currency), money.New(alert.Threshold, alert.Currency)))
+}
+
+// alertRecipient tells the wallet's owner about a transfer into it
+func (s *NotificationService) alertRecipient(ctx context.Context, walletID uuid.UUID, movement models.WalletEvent) error {
+	email, preferences, err := s.recipient(ctx, walletID)
+	if err != nil || email == "" || !preferences.IncomingTransfer {
+		return err
+	}
+
+	received := money.New(movement.Amount, movement.Currency)
+	if movement.CreditedAmount != nil && movement.CreditedCurrency != nil {
+		received = money.New(*movement.CreditedAmount, *movement.CreditedCurrency)
+	}
+	body := fmt.Sprintf("%s was transferred into your wallet.", received)
+	if movement.Description != nil && *movement.Description != "" {
+		body += fmt.Sprintf(" The sender's note: %q.", *movement.Description)
+	}
+	return s.send(ctx, email, "You received a transfer", body)
+}
+
+// recipient returns the email address of the wallet's owner and their preferences.
+// The email is empty when there is nobody to alert.
+func (s *NotificationService) recipient(ctx context.Context, walletID uuid.UUID) (string, *models.NotificationPreferences, error) {
+	wallet, err := s.WalletRepo.GetWalletByID(ctx, walletID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return "", nil, nil
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to get wallet: %w", err)
+	}
+	user, err := s.UserRepo.GetUserByID(ctx, wallet.UserID)
+	if errors.Is(err, repository.ErrNotFound) || (err == nil && user.Email == nil) {
+		return "", nil, nil
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	preferences, err := s.preferences(ctx, user.ID)
+	if err != nil {
+		return "", nil, err
+	}
+	return *user.Email, preferences, nil
+}
+
+func (s *NotificationService) send(ctx context.Context, to, subject, body string) error {
+	if err := s.Provider.Send(ctx, notify.Message{To: to, Subject: subject, Body: body}); err != nil {
+		return fmt.Errorf("failed to send %q to %s: %w", subject, to, err)
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/notify"
+	"github.com/shanwije/wallet-app/internal/repository"
+	"github.com/shanwije/wallet-app/internal/repository/mocks"
+	"github.com/shanwije/wallet-app/internal/testutil"
+	"github.com/shanwije/wallet-app/pkg/money"
+)
+
+// recordingProvider keeps the messages it is asked to send
+type recordingProvider struct {
+	sent []notify.Message
+}
+
+func (p *recordingProvider) Send(_ context.Context, message notify.Message) error {
+	p.sent = append(p.sent, message)
+	return nil
+}
+
+func walletEvent(t *testing.T, movement models.WalletEvent) *models.OutboxEvent {
+	t.Helper()
+
+	payload, err := json.Marshal(movement)
+	require.NoError(t, err)
+	return &models.OutboxEvent{ID: uuid.New(), Type: movement.Type, Payload: payload}
+}
+
+func TestNotificationsFollowEachUsersPreferences(t *testing.T) {
+	walletRepo := new(mocks.WalletRepository)
+	userRepo := new(mocks.UserRepository)
+	preferencesRepo := new(mocks.NotificationPreferencesRepository)
+	provider := &recordingProvider{}
+	service := &NotificationService{
+		Repo:       preferencesRepo,
+		UserRepo:   userRepo,
+		WalletRepo: walletRepo,
+		Provider:   provider,
+		Defaults:   models.NotificationPreferences{LargeWithdrawalThreshold: decimalPtr(1000), IncomingTransfer: true},
+	}
+
+	// Alice kept the defaults; Bob turned his alerts off; Carol has no email address
+	aliceEmail, bobEmail := "alice@example.com", "bob@example.com"
+	alice := &models.User{ID: uuid.New(), Contact: models.Contact{Email: &aliceEmail}}
+	bob := &models.User{ID: uuid.New(), Contact: models.Contact{Email: &bobEmail}}
+	carol := &models.User{ID: uuid.New()}
+	wallets := map[*models.User]*models.Wallet{}
+	for _, user := range []*models.User{alice, bob, carol} {
+		wallet := testutil.Wallet(uuid.New(), 40)
+		wallet.UserID = user.ID
+		wallets[user] = wallet
+		walletRepo.On("GetWalletByID", mock.Anything, wallet.ID).Return(wallet, nil)
+		userRepo.On("GetUserByID", mock.Anything, user.ID).Return(user, nil)
+	}
+	preferencesRepo.On("GetNotificationPreferences", mock.Anything, alice.ID).Return(nil, repository.ErrNotFound)
+	preferencesRepo.On("GetNotificationPreferences", mock.Anything, bob.ID).Return(&models.NotificationPreferences{UserID: bob.ID}, nil)
+
+	publish := func(movement models.WalletEvent) []notify.Message {
+		provider.sent = nil
+		require.NoError(t, service.Publish(context.Background(), walletEvent(t, movement)))
+		return provider.sent
+	}
+	withdrawal := func(user *models.User, amount int64) models.WalletEvent {
+		return models.WalletEvent{Type: models.EventWalletWithdraw, FromWalletID: &wallets[user].ID,
+			Amount: decimal.NewFromInt(amount), Currency: money.USD, OccurredAt: time.Now()}
+	}
+
+	// 1500 is over the default threshold
+	sent := publish(withdrawal(alice, 1500))
+	require.Len(t, sent, 1)
+	assert.Equal(t, aliceEmail, sent[0].To)
+	assert.Equal(t, "Large withdrawal from your wallet", sent[0].Subject)
+	assert.Contains(t, sent[0].Body, "1500.00 USD")
+	assert.Empty(t, publish(withdrawal(alice, 5)))
+	assert.Empty(t, publish(withdrawal(bob, 1500)))
+
+	// A low balance alert raised for the wallet is sent whatever the preferences
+	lowBalance := func(user *models.User) []notify.Message {
+		payload, err := json.Marshal(models.WalletAlert{ID: uuid.New(), WalletID: wallets[user].ID, Type: models.AlertLowBalance,
+			JournalID: uuid.New(), Threshold: decimal.NewFromInt(50), Balance: decimal.NewFromInt(40), Currency: money.USD, CreatedAt: time.Now()})
+		require.NoError(t, err)
+		provider.sent = nil
+		require.NoError(t, service.Publish(context.Background(), &models.OutboxEvent{ID: uuid.New(), Type: models.EventLowBalanceAlert, Payload: payload}))
+		return provider.sent
+	}
+	sent = lowBalance(bob)
+	require.Len(t, sent, 1)
+	assert.Equal(t, bobEmail, sent[0].To)
+	assert.Equal(t, "Your wallet balance is low", sent[0].Subject)
+	assert.Contains(t, sent[0].Body, "40.00 USD, below the 50.00 USD")
+	assert.Empty(t, lowBalance(carol))
+
+	transfer := func(from, to *models.User) models.WalletEvent {
+		return models.WalletEvent{Type: models.EventWalletTransfer, FromWalletID: &wallets[from].ID, ToWalletID: &wallets[to].ID,
+			Amount: decimal.NewFromInt(5), Currency: money.USD, OccurredAt: time.Now()}
+	}
+	sent = publish(transfer(bob, alice))
+	require.Len(t, sent, 1)
+	assert.Equal(t, aliceEmail, sent[0].To)
+	assert.Equal(t, "You received a transfer", sent[0].Subject)
+	assert.Empty(t, publish(transfer(alice, bob)))
+	assert.Empty(t, publish(transfer(alice, carol)))
+}
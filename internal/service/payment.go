@@ -0,0 +1,269 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository"
+	"github.com/shanwije/wallet-app/pkg/clock"
+	"github.com/shanwije/wallet-app/pkg/money"
+)
+
+var (
+	// ErrMerchantNotFound is returned when the wallet is not a merchant account
+	ErrMerchantNotFound = errors.New("merchant account not found")
+	// ErrMerchantExists is returned when registering a wallet that already is a merchant account
+	ErrMerchantExists = errors.New("wallet is already a merchant account")
+	// ErrInvalidMerchantName is returned for a merchant account without a name
+	ErrInvalidMerchantName = errors.New("merchant name cannot be empty")
+	// ErrPaymentNotFound is returned when no payment has the ID
+	ErrPaymentNotFound = errors.New("payment not found")
+	// ErrInvalidOrderReference is returned for a payment without an order reference
+	ErrInvalidOrderReference = errors.New("order reference cannot be empty")
+	// ErrOrderAlreadyPaid is returned when the merchant was already paid for the order
+	ErrOrderAlreadyPaid = errors.New("order has already been paid")
+	// ErrInvalidRefundAmount is returned for a refund that is not positive, has too many
+	// decimal places or exceeds what is left of the payment
+	ErrInvalidRefundAmount = errors.New("refund amount must be positive and no more than what is left of the payment")
+	// ErrInvalidSettlementPeriod is returned when a settlement period does not end after it starts
+	ErrInvalidSettlementPeriod = errors.New("settlement period must end after it starts")
+)
+
+// PaymentService lets wallets pay merchant accounts for their orders, and merchants
+// refund those payments and total what they took. Payments and refunds are posted as
+// transfers, so they obey the same checks, limits and feature flags.
+type PaymentService struct {
+	Repo    repository.PaymentRepository
+	Wallets *WalletService
+	// Clock is optional; the system clock is used when nil
+	Clock clock.Clock
+}
+
+// now returns the current time from the injected clock
+func (s *PaymentService) now() time.Time {
+	return clock.OrDefault(s.Clock).Now()
+}
+
+// RegisterMerchant makes the wallet a merchant account that payers see as name
+func (s *PaymentService) RegisterMerchant(ctx context.Context, walletID uuid.UUID, name string) (*models.MerchantAccount, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, ErrInvalidMerchantName
+	}
+	if _, err := s.Wallets.GetBalance(ctx, walletID); err != nil {
+		return nil, err
+	}
+
+	account := &models.MerchantAccount{WalletID: walletID, Name: name, CreatedAt: s.now()}
+	if err := s.Repo.CreateMerchantAccount(ctx, account); err != nil {
+		if errors.Is(err, repository.ErrDuplicate) {
+			return nil, ErrMerchantExists
+		}
+		return nil, fmt.Errorf("failed to create merchant account: %w", err)
+	}
+
+	return account, nil
+}
+
+// GetMerchant returns the merchant account of the wallet
+func (s *PaymentService) GetMerchant(ctx context.Context, walletID uuid.UUID) (*models.MerchantAccount, error) {
+	account, err := s.Repo.GetMerchantAccount(ctx, walletID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrMerchantNotFound
+		}
+		return nil, fmt.Errorf("failed to get merchant account: %w", err)
+	}
+	return account, nil
+}
+
+// Pay sends amount, in the merchant's currency, from the payer wallet to the merchant
+// for the order orderReference. The transfer and the payment commit together, and a
+// merchant is paid for each of its orders once.
+func (s *PaymentService) Pay(ctx context.Context, payerWalletID, merchantWalletID uuid.UUID, amount money.Money, orderReference string) (*models.Payment, error) {
+	orderReference = strings.TrimSpace(orderReference)
+	if orderReference == "" {
+		return nil, ErrInvalidOrderReference
+	}
+	if err := s.Wallets.validateTransferAmount(amount, payerWalletID, merchantWalletID); err != nil {
+		return nil, err
+	}
+
+	merchant, err := s.GetMerchant(ctx, merchantWalletID)
+	if err != nil {
+		return nil, err
+	}
+	// Merchants are paid the price of the order as it is, never a conversion of it
+	merchantWallet, err := s.Wallets.GetBalance(ctx, merchantWalletID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get merchant wallet: %w", err)
+	}
+	if !merchantWallet.Funds().SameCurrency(amount) {
+		return nil, fmt.Errorf("invalid payment: %w", money.ErrCurrencyMismatch)
+	}
+
+	description := fmt.Sprintf("Payment to %s for order %s", merchant.Name, orderReference)
+	journal := newJournal(models.JournalTypeTransfer, &description, nil,
+		debit(&payerWalletID, amount),
+		credit(&merchantWalletID, amount),
+	)
+
+	if err := s.Wallets.assessRisk(ctx, models.JournalTypeTransfer, payerWalletID, &merchantWalletID, amount); err != nil {
+		return nil, err
+	}
+
+	var payment *models.Payment
+	err = s.Wallets.withTx(ctx, "pay merchant", func(ctx context.Context, tx *sql.Tx) error {
+		if _, _, err := s.Wallets.transferExecution(ctx, tx, payerWalletID, merchantWalletID, amount, noFee, journal); err != nil {
+			return err
+		}
+
+		current := &models.Payment{
+			PayerWalletID:    payerWalletID,
+			MerchantWalletID: merchantWalletID,
+			OrderReference:   orderReference,
+			Amount:           amount.Amount(),
+			Currency:         amount.Currency(),
+			Status:           models.PaymentStatusCompleted,
+			JournalID:        journal.ID,
+			Refunds:          []*models.PaymentRefund{},
+			CreatedAt:        journal.CreatedAt,
+			UpdatedAt:        journal.CreatedAt,
+		}
+		if err := s.Repo.CreatePaymentWithTx(ctx, tx, current); err != nil {
+			return err
+		}
+
+		payment = current
+		return nil
+	})
+	if errors.Is(err, repository.ErrDuplicate) {
+		// The unique key on the order catches concurrent payments too
+		return nil, ErrOrderAlreadyPaid
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return payment, nil
+}
+
+// GetPayment returns the payment with its refunds
+func (s *PaymentService) GetPayment(ctx context.Context, paymentID uuid.UUID) (*models.Payment, error) {
+	payment, err := s.Repo.GetPayment(ctx, paymentID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrPaymentNotFound
+		}
+		return nil, fmt.Errorf("failed to get payment: %w", err)
+	}
+	return payment, nil
+}
+
+// Refund pays amount of the payment back from the merchant to the payer, or all that
+// is left of it when amount is nil. A payment can be refunded in several parts until
+// the whole of it has been paid back.
+func (s *PaymentService) Refund(ctx context.Context, paymentID uuid.UUID, amount *money.Money, reason string) (*models.PaymentRefund, error) {
+	var refund *models.PaymentRefund
+	err := s.Wallets.withTx(ctx, "refund payment", func(ctx context.Context, tx *sql.Tx) error {
+		payment, err := s.Repo.GetPaymentWithTx(ctx, tx, paymentID)
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrPaymentNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		refundable := payment.Refundable()
+		requested := refundable
+		if amount != nil {
+			requested = *amount
+		}
+		if !requested.SameCurrency(refundable) {
+			return fmt.Errorf("invalid refund: %w", money.ErrCurrencyMismatch)
+		}
+		if cmp, _ := requested.Cmp(refundable); !requested.IsPositive() || !requested.HasValidPrecision() || cmp > 0 {
+			return ErrInvalidRefundAmount
+		}
+
+		description := fmt.Sprintf("Refund for order %s", payment.OrderReference)
+		journal := newJournal(models.JournalTypeTransfer, &description, nil,
+			debit(&payment.MerchantWalletID, requested),
+			credit(&payment.PayerWalletID, requested),
+		)
+		if _, _, err := s.Wallets.transferExecution(ctx, tx, payment.MerchantWalletID, payment.PayerWalletID, requested, noFee, journal); err != nil {
+			return err
+		}
+
+		current := &models.PaymentRefund{
+			PaymentID: payment.ID,
+			Amount:    requested.Amount(),
+			Currency:  requested.Currency(),
+			JournalID: journal.ID,
+			CreatedAt: journal.CreatedAt,
+		}
+		if reason != "" {
+			current.Reason = &reason
+		}
+		if err := s.Repo.CreatePaymentRefundWithTx(ctx, tx, current); err != nil {
+			return err
+		}
+
+		payment.RefundedAmount = payment.RefundedAmount.Add(current.Amount)
+		payment.Status = models.PaymentStatusPartiallyRefunded
+		if payment.RefundedAmount.Equal(payment.Amount) {
+			payment.Status = models.PaymentStatusRefunded
+		}
+		payment.UpdatedAt = journal.CreatedAt
+		if err := s.Repo.UpdatePaymentWithTx(ctx, tx, payment); err != nil {
+			return err
+		}
+
+		refund = current
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return refund, nil
+}
+
+// Settlement totals the payments the merchant wallet took and the refunds it made over
+// [from, to). A zero from starts when the wallet became a merchant account and a zero
+// to ends now.
+func (s *PaymentService) Settlement(ctx context.Context, merchantWalletID uuid.UUID, from, to time.Time) (*models.MerchantSettlement, error) {
+	merchant, err := s.GetMerchant(ctx, merchantWalletID)
+	if err != nil {
+		return nil, err
+	}
+	wallet, err := s.Wallets.GetBalance(ctx, merchantWalletID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get merchant wallet: %w", err)
+	}
+
+	if from.IsZero() {
+		from = merchant.CreatedAt
+	}
+	if to.IsZero() {
+		to = s.now()
+	}
+	if !to.After(from) {
+		return nil, ErrInvalidSettlementPeriod
+	}
+
+	settlement, err := s.Repo.GetMerchantSettlement(ctx, merchantWalletID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get merchant settlement: %w", err)
+	}
+	settlement.Currency = wallet.Currency
+	settlement.NetAmount = settlement.GrossAmount.Sub(settlement.RefundedAmount)
+	return settlement, nil
+}
@@ -0,0 +1,180 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository"
+	"github.com/shanwije/wallet-app/pkg/clock"
+	"github.com/shanwije/wallet-app/pkg/money"
+)
+
+var (
+	// ErrPaymentRequestNotFound is returned when no request with the ID awaits the wallet's answer
+	ErrPaymentRequestNotFound = errors.New("payment request not found")
+	// ErrPaymentRequestNotPending is returned when answering a request that was already accepted or declined
+	ErrPaymentRequestNotPending = errors.New("payment request is no longer pending")
+)
+
+// PaymentRequestService lets a wallet ask another to pay it, and the payer accept or decline
+type PaymentRequestService struct {
+	Repo    repository.PaymentRequestRepository
+	Wallets *WalletService
+	// Clock is optional; the system clock is used when nil
+	Clock clock.Clock
+}
+
+// now returns the current time from the injected clock
+func (s *PaymentRequestService) now() time.Time {
+	return clock.OrDefault(s.Clock).Now()
+}
+
+// Request asks the payer wallet to send amount to the requester wallet
+func (s *PaymentRequestService) Request(ctx context.Context, requesterWalletID, payerWalletID uuid.UUID, amount money.Money, description string) (*models.PaymentRequest, error) {
+	if err := s.Wallets.validateTransferAmount(amount, payerWalletID, requesterWalletID); err != nil {
+		return nil, err
+	}
+	if err := s.Wallets.checkConfirmation(amount); err != nil {
+		return nil, err
+	}
+
+	requester, err := s.Wallets.GetBalance(ctx, requesterWalletID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get requesting wallet: %w", err)
+	}
+	if err := requester.CheckActive(); err != nil {
+		return nil, err
+	}
+
+	// Catch a wrong payer now rather than when the request is accepted
+	payer, err := s.Wallets.GetBalance(ctx, payerWalletID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get paying wallet: %w", err)
+	}
+	if !requester.Funds().SameCurrency(amount) || !payer.Funds().SameCurrency(amount) {
+		return nil, fmt.Errorf("invalid payment request: %w", money.ErrCurrencyMismatch)
+	}
+
+	request := &models.PaymentRequest{
+		RequesterWalletID: requesterWalletID,
+		PayerWalletID:     payerWalletID,
+		Amount:            amount.Amount(),
+		Currency:          amount.Currency(),
+		Status:            models.PaymentRequestStatusPending,
+		CreatedAt:         s.now(),
+	}
+	if description != "" {
+		request.Description = &description
+	}
+
+	if err := s.Repo.CreatePaymentRequest(ctx, request); err != nil {
+		return nil, fmt.Errorf("failed to create payment request: %w", err)
+	}
+
+	return request, nil
+}
+
+// ListPending returns the requests waiting for the payer wallet to accept or decline them
+func (s *PaymentRequestService) ListPending(ctx context.Context, payerWalletID uuid.UUID) ([]*models.PaymentRequest, error) {
+	requests, err := s.Repo.ListPendingPaymentRequestsByPayer(ctx, payerWalletID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list payment requests: %w", err)
+	}
+
+	return requests, nil
+}
+
+// Accept pays a pending request out of the payer wallet. The transfer and the change
+// of status commit together, so a request is never paid twice or paid and left pending.
+func (s *PaymentRequestService) Accept(ctx context.Context, payerWalletID, requestID uuid.UUID) (*models.PaymentRequest, error) {
+	var request *models.PaymentRequest
+	err := s.Wallets.withTx(ctx, "accept payment request", func(ctx context.Context, tx *sql.Tx) error {
+		current, err := s.lockPendingRequest(ctx, tx, payerWalletID, requestID)
+		if err != nil {
+			return err
+		}
+
+		// A request made before the threshold was lowered
+		amount := current.Funds()
+		if err := s.Wallets.checkConfirmation(amount); err != nil {
+			return err
+		}
+
+		// The key is unique per request, so the ledger itself refuses a second payment
+		journal := newJournal(models.JournalTypeTransfer, current.Description,
+			scopedIdempotencyKey(payerWalletID, "payment-request:"+requestID.String()),
+			debit(&current.PayerWalletID, amount),
+			credit(&current.RequesterWalletID, amount),
+		)
+		if _, _, err := s.Wallets.transferExecution(ctx, tx, current.PayerWalletID, current.RequesterWalletID, amount, noFee, journal); err != nil {
+			return err
+		}
+
+		current.Status = models.PaymentRequestStatusAccepted
+		current.TransferJournalID = &journal.ID
+		current.UpdatedAt = journal.CreatedAt
+		if err := s.Repo.UpdatePaymentRequestWithTx(ctx, tx, current); err != nil {
+			return err
+		}
+
+		request = current
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return request, nil
+}
+
+// Decline turns down a pending request without moving any money
+func (s *PaymentRequestService) Decline(ctx context.Context, payerWalletID, requestID uuid.UUID) (*models.PaymentRequest, error) {
+	var request *models.PaymentRequest
+	err := s.Wallets.withTx(ctx, "decline payment request", func(ctx context.Context, tx *sql.Tx) error {
+		current, err := s.lockPendingRequest(ctx, tx, payerWalletID, requestID)
+		if err != nil {
+			return err
+		}
+
+		current.Status = models.PaymentRequestStatusDeclined
+		current.UpdatedAt = s.now()
+		if err := s.Repo.UpdatePaymentRequestWithTx(ctx, tx, current); err != nil {
+			return err
+		}
+
+		request = current
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return request, nil
+}
+
+// lockPendingRequest locks a pending request addressed to the payer wallet until tx ends
+func (s *PaymentRequestService) lockPendingRequest(ctx context.Context, tx *sql.Tx, payerWalletID, requestID uuid.UUID) (*models.PaymentRequest, error) {
+	request, err := s.Repo.GetPaymentRequestWithTx(ctx, tx, requestID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrPaymentRequestNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Requests addressed to other wallets are reported as missing rather than forbidden
+	if request.PayerWalletID != payerWalletID {
+		return nil, ErrPaymentRequestNotFound
+	}
+	if request.Status != models.PaymentRequestStatusPending {
+		return nil, ErrPaymentRequestNotPending
+	}
+
+	return request, nil
+}
@@ -0,0 +1,181 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository"
+	"github.com/shanwije/wallet-app/internal/repository/mocks"
+	"github.com/shanwije/wallet-app/internal/testutil"
+	"github.com/shanwije/wallet-app/pkg/money"
+)
+
+// setupPaymentRequestService creates a payment request service over mocked repositories
+func setupPaymentRequestService() (*PaymentRequestService, *mocks.WalletRepository, *mocks.LedgerRepository, *mocks.PaymentRequestRepository) {
+	wallets, walletRepo, ledgerRepo := setupWalletService()
+	repo := new(mocks.PaymentRequestRepository)
+	walletRepo.On("BeginTx", mock.Anything).Return((*sql.Tx)(nil), nil)
+	return &PaymentRequestService{Repo: repo, Wallets: wallets}, walletRepo, ledgerRepo, repo
+}
+
+// createPendingRequest creates a pending test request for payer to pay requester
+func createPendingRequest(requesterWalletID, payerWalletID uuid.UUID, amount float64) *models.PaymentRequest {
+	return &models.PaymentRequest{
+		ID:                uuid.New(),
+		RequesterWalletID: requesterWalletID,
+		PayerWalletID:     payerWalletID,
+		Amount:            decimal.NewFromFloat(amount),
+		Currency:          money.USD,
+		Status:            models.PaymentRequestStatusPending,
+	}
+}
+
+func TestRequestPayment(t *testing.T) {
+	service, walletRepo, _, repo := setupPaymentRequestService()
+
+	requesterID, payerID := uuid.New(), uuid.New()
+	walletRepo.On("GetWalletByID", mock.Anything, requesterID).Return(testutil.Wallet(requesterID, 0), nil)
+	walletRepo.On("GetWalletByID", mock.Anything, payerID).Return(testutil.Wallet(payerID, 50.0), nil)
+	repo.On("CreatePaymentRequest", mock.Anything, mock.AnythingOfType("*models.PaymentRequest")).Return(nil)
+
+	request, err := service.Request(context.Background(), requesterID, payerID, testutil.USD(decimal.NewFromInt(20)), "Dinner")
+
+	require.NoError(t, err)
+	assert.Equal(t, models.PaymentRequestStatusPending, request.Status)
+	assert.Equal(t, payerID, request.PayerWalletID)
+	assert.Equal(t, "Dinner", *request.Description)
+}
+
+func TestRequestPaymentValidation(t *testing.T) {
+	service, walletRepo, _, repo := setupPaymentRequestService()
+
+	walletID := uuid.New()
+	_, err := service.Request(context.Background(), walletID, walletID, testutil.USD(decimal.NewFromInt(20)), "")
+	assert.Error(t, err)
+
+	requesterID, payerID := uuid.New(), uuid.New()
+	euroWallet := testutil.Wallet(payerID, 50.0)
+	euroWallet.Currency = money.EUR
+	walletRepo.On("GetWalletByID", mock.Anything, requesterID).Return(testutil.Wallet(requesterID, 0), nil)
+	walletRepo.On("GetWalletByID", mock.Anything, payerID).Return(euroWallet, nil)
+
+	_, err = service.Request(context.Background(), requesterID, payerID, testutil.USD(decimal.NewFromInt(20)), "")
+	assert.ErrorIs(t, err, money.ErrCurrencyMismatch)
+	repo.AssertNotCalled(t, "CreatePaymentRequest", mock.Anything, mock.Anything)
+}
+
+func TestAcceptPaymentRequestTransfersFunds(t *testing.T) {
+	service, walletRepo, ledgerRepo, repo := setupPaymentRequestService()
+
+	requesterID, payerID := uuid.New(), uuid.New()
+	request := createPendingRequest(requesterID, payerID, 40.0)
+	repo.On("GetPaymentRequestWithTx", mock.Anything, (*sql.Tx)(nil), request.ID).Return(request, nil)
+	walletRepo.On("GetWalletByIDWithTx", mock.Anything, (*sql.Tx)(nil), payerID).Return(testutil.Wallet(payerID, 100.0), nil)
+	walletRepo.On("GetWalletByIDWithTx", mock.Anything, (*sql.Tx)(nil), requesterID).Return(testutil.Wallet(requesterID, 10.0), nil)
+	walletRepo.On("UpdateBalanceWithTx", mock.Anything, (*sql.Tx)(nil), payerID, mock.MatchedBy(decimal.NewFromInt(60).Equal), mock.Anything).Return(nil)
+	walletRepo.On("UpdateBalanceWithTx", mock.Anything, (*sql.Tx)(nil), requesterID, mock.MatchedBy(decimal.NewFromInt(50).Equal), mock.Anything).Return(nil)
+	ledgerRepo.On("CreateJournalWithTx", mock.Anything, (*sql.Tx)(nil), mock.MatchedBy(func(journal *models.Journal) bool {
+		return journal.Type == models.JournalTypeTransfer &&
+			journal.Validate() == nil &&
+			*journal.Entries[0].WalletID == payerID &&
+			*journal.Entries[1].WalletID == requesterID
+	})).Return(nil).Once()
+	repo.On("UpdatePaymentRequestWithTx", mock.Anything, (*sql.Tx)(nil), request).Return(nil)
+
+	accepted, err := service.Accept(context.Background(), payerID, request.ID)
+
+	require.NoError(t, err)
+	assert.Equal(t, models.PaymentRequestStatusAccepted, accepted.Status)
+	assert.NotNil(t, accepted.TransferJournalID)
+	walletRepo.AssertExpectations(t)
+	ledgerRepo.AssertExpectations(t)
+}
+
+func TestAcceptPaymentRequestInsufficientBalance(t *testing.T) {
+	service, walletRepo, ledgerRepo, repo := setupPaymentRequestService()
+
+	requesterID, payerID := uuid.New(), uuid.New()
+	request := createPendingRequest(requesterID, payerID, 40.0)
+	repo.On("GetPaymentRequestWithTx", mock.Anything, (*sql.Tx)(nil), request.ID).Return(request, nil)
+	walletRepo.On("GetWalletByIDWithTx", mock.Anything, (*sql.Tx)(nil), payerID).Return(testutil.Wallet(payerID, 30.0), nil)
+	walletRepo.On("GetWalletByIDWithTx", mock.Anything, (*sql.Tx)(nil), requesterID).Return(testutil.Wallet(requesterID, 10.0), nil)
+
+	_, err := service.Accept(context.Background(), payerID, request.ID)
+
+	assert.ErrorContains(t, err, "insufficient balance")
+	assert.Equal(t, models.PaymentRequestStatusPending, request.Status)
+	ledgerRepo.AssertNotCalled(t, "CreateJournalWithTx", mock.Anything, mock.Anything, mock.Anything)
+	repo.AssertNotCalled(t, "UpdatePaymentRequestWithTx", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestDeclinePaymentRequest(t *testing.T) {
+	service, walletRepo, _, repo := setupPaymentRequestService()
+
+	payerID := uuid.New()
+	request := createPendingRequest(uuid.New(), payerID, 40.0)
+	repo.On("GetPaymentRequestWithTx", mock.Anything, (*sql.Tx)(nil), request.ID).Return(request, nil)
+	repo.On("UpdatePaymentRequestWithTx", mock.Anything, (*sql.Tx)(nil), request).Return(nil)
+
+	declined, err := service.Decline(context.Background(), payerID, request.ID)
+
+	require.NoError(t, err)
+	assert.Equal(t, models.PaymentRequestStatusDeclined, declined.Status)
+	walletRepo.AssertNotCalled(t, "UpdateBalanceWithTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestAnswerPaymentRequestErrors(t *testing.T) {
+	payerID := uuid.New()
+
+	tests := []struct {
+		name    string
+		request func() *models.PaymentRequest
+		repoErr error
+		wantErr error
+	}{
+		{
+			name:    "unknown request",
+			request: func() *models.PaymentRequest { return nil },
+			repoErr: repository.ErrNotFound,
+			wantErr: ErrPaymentRequestNotFound,
+		},
+		{
+			name:    "addressed to another wallet",
+			request: func() *models.PaymentRequest { return createPendingRequest(uuid.New(), uuid.New(), 10.0) },
+			wantErr: ErrPaymentRequestNotFound,
+		},
+		{
+			name: "already declined",
+			request: func() *models.PaymentRequest {
+				request := createPendingRequest(uuid.New(), payerID, 10.0)
+				request.Status = models.PaymentRequestStatusDeclined
+				return request
+			},
+			wantErr: ErrPaymentRequestNotPending,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, _, _, repo := setupPaymentRequestService()
+			requestID := uuid.New()
+			if request := tt.request(); request != nil {
+				repo.On("GetPaymentRequestWithTx", mock.Anything, (*sql.Tx)(nil), requestID).Return(request, tt.repoErr)
+			} else {
+				repo.On("GetPaymentRequestWithTx", mock.Anything, (*sql.Tx)(nil), requestID).Return(nil, tt.repoErr)
+			}
+
+			_, err := service.Accept(context.Background(), payerID, requestID)
+			assert.ErrorIs(t, err, tt.wantErr)
+			_, err = service.Decline(context.Background(), payerID, requestID)
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
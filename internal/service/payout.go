@@ -0,0 +1,407 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/payout"
+	"github.com/shanwije/wallet-app/internal/repository"
+	"github.com/shanwije/wallet-app/pkg/clock"
+	"github.com/shanwije/wallet-app/pkg/logger"
+	"github.com/shanwije/wallet-app/pkg/money"
+	"github.com/shanwije/wallet-app/pkg/pagination"
+)
+
+var (
+	// ErrPayoutNotFound is returned when no payout has the ID
+	ErrPayoutNotFound = errors.New("payout not found")
+	// ErrInvalidPayoutDestination is returned for a payout without an account name,
+	// account number or bank code
+	ErrInvalidPayoutDestination = errors.New("payout needs an account name, account number and bank code")
+)
+
+// payoutBatchSize is how many payouts the worker reads at a time
+const payoutBatchSize = 100
+
+// PayoutDestination is the bank account a payout is paid into
+type PayoutDestination struct {
+	AccountName   string
+	AccountNumber string
+	BankCode      string
+}
+
+// PayoutService pays money out of wallets to bank accounts through a Provider. The
+// wallet is debited as a withdrawal when the payout is initiated, so it obeys the same
+// checks, limits and feature flags, and is credited back if the payout fails.
+type PayoutService struct {
+	Repo    repository.PayoutRepository
+	Wallets *WalletService
+	// Provider is optional; payouts are rejected as a disabled feature when nil
+	Provider payout.Provider
+	// Clock is optional; the system clock is used when nil
+	Clock clock.Clock
+}
+
+// now returns the current time from the injected clock
+func (s *PayoutService) now() time.Time {
+	return clock.OrDefault(s.Clock).Now()
+}
+
+// Create debits amount from the wallet and sends it to the bank account. The payout is
+// recorded as initiated along with the debit, then handed to the provider: it comes
+// back sent, failed and credited back when the provider rejects it, or still initiated
+// when the provider could not be reached, for the worker to send again. A non-empty
+// idempotencyKey makes retries of the same payout return it without paying again.
+func (s *PayoutService) Create(ctx context.Context, walletID uuid.UUID, amount money.Money, destination PayoutDestination, idempotencyKey string) (*models.Payout, error) {
+	if s.Provider == nil {
+		return nil, fmt.Errorf("%w: payouts", ErrFeatureDisabled)
+	}
+	destination.AccountName = strings.TrimSpace(destination.AccountName)
+	destination.AccountNumber = strings.TrimSpace(destination.AccountNumber)
+	destination.BankCode = strings.TrimSpace(destination.BankCode)
+	if destination.AccountName == "" || destination.AccountNumber == "" || destination.BankCode == "" {
+		return nil, ErrInvalidPayoutDestination
+	}
+
+	// Keys are namespaced apart from withdrawals, which post the same legs
+	var key *string
+	if idempotencyKey != "" {
+		key = scopedIdempotencyKey(walletID, "payout:"+idempotencyKey)
+	}
+	treasuryID, err := s.Wallets.settlementWallet(ctx, models.WalletKindTreasury, amount.Currency())
+	if err != nil {
+		return nil, err
+	}
+	description := "Payout to " + destination.AccountName
+	journal := newJournal(models.JournalTypeWithdraw, &description, key,
+		debit(&walletID, amount),
+		credit(treasuryID, amount),
+	)
+	classify(ctx, journal)
+	fee, err := s.Wallets.movementFee(ctx, models.JournalTypeWithdraw, walletID, amount)
+	if err != nil {
+		return nil, err
+	}
+
+	var created *models.Payout
+	replayed, err := s.Wallets.idempotent(ctx, journal, func() error {
+		if err := s.Wallets.requireFeature(ctx, FlagWithdrawals); err != nil {
+			return err
+		}
+		if err := s.Wallets.assessRisk(ctx, models.JournalTypeWithdraw, walletID, nil, amount); err != nil {
+			return err
+		}
+		return s.Wallets.withTx(ctx, "initiate payout", func(ctx context.Context, tx *sql.Tx) error {
+			if _, err := s.Wallets.withdrawExecution(ctx, tx, walletID, amount, fee, journal, treasuryID); err != nil {
+				return err
+			}
+
+			current := &models.Payout{
+				WalletID:      walletID,
+				Amount:        amount.Amount(),
+				Currency:      amount.Currency(),
+				AccountName:   destination.AccountName,
+				AccountNumber: destination.AccountNumber,
+				BankCode:      destination.BankCode,
+				Status:        models.PayoutStatusInitiated,
+				JournalID:     journal.ID,
+				CreatedAt:     journal.CreatedAt,
+				UpdatedAt:     journal.CreatedAt,
+			}
+			if err := s.Repo.CreatePayoutWithTx(ctx, tx, current); err != nil {
+				return err
+			}
+
+			created = current
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	if replayed {
+		return s.recordedPayout(ctx, *key)
+	}
+
+	return s.send(ctx, created)
+}
+
+// recordedPayout returns the payout whose debit was recorded under the idempotency key
+func (s *PayoutService) recordedPayout(ctx context.Context, key string) (*models.Payout, error) {
+	journal, err := s.Wallets.LedgerRepo.GetJournalByIdempotencyKey(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recorded payout: %w", err)
+	}
+	recorded, err := s.Repo.GetPayoutByJournalID(ctx, journal.ID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrIdempotencyKeyReused
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recorded payout: %w", err)
+	}
+	return recorded, nil
+}
+
+// Get returns the payout
+func (s *PayoutService) Get(ctx context.Context, payoutID uuid.UUID) (*models.Payout, error) {
+	found, err := s.Repo.GetPayout(ctx, payoutID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrPayoutNotFound
+		}
+		return nil, fmt.Errorf("failed to get payout: %w", err)
+	}
+	return found, nil
+}
+
+// List pages through payouts, newest first
+func (s *PayoutService) List(ctx context.Context, filter repository.PayoutFilter) ([]*models.Payout, error) {
+	filter.Limit = pagination.ClampLimit(filter.Limit)
+	filter.Offset = max(filter.Offset, 0)
+
+	payouts, err := s.Repo.ListPayouts(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list payouts: %w", err)
+	}
+	return payouts, nil
+}
+
+// Run sends initiated payouts and follows sent ones until they settle or fail, every
+// interval until ctx is cancelled
+func (s *PayoutService) Run(ctx context.Context, interval time.Duration) {
+	log := logger.FromContext(ctx)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		processed, err := s.ProcessPending(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Error("Payout run failed", zap.Error(err))
+		} else if processed > 0 {
+			log.Info("Processed payouts", zap.Int("count", processed))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProcessPending sends every initiated payout to the provider again and asks it how
+// every sent payout stands, and returns how many payouts changed status. A payout the
+// provider cannot be reached about, or that fails to update, is left for the next run.
+func (s *PayoutService) ProcessPending(ctx context.Context) (int, error) {
+	if s.Provider == nil {
+		return 0, nil
+	}
+
+	processed := 0
+	for _, status := range []string{models.PayoutStatusInitiated, models.PayoutStatusSent} {
+		after := uuid.Nil
+		for {
+			batch, err := s.Repo.ListPayoutsInStatus(ctx, status, after, payoutBatchSize)
+			if err != nil {
+				return processed, fmt.Errorf("failed to list %s payouts: %w", status, err)
+			}
+			for _, pending := range batch {
+				var current *models.Payout
+				if status == models.PayoutStatusInitiated {
+					current, err = s.send(ctx, pending)
+				} else {
+					current, err = s.poll(ctx, pending)
+				}
+				if err != nil {
+					if ctx.Err() != nil {
+						return processed, err
+					}
+					// One payout that cannot be moved on does not hold up the rest
+					logger.FromContext(ctx).Error("Failed to process payout",
+						zap.Error(err), zap.String("payout_id", pending.ID.String()))
+					continue
+				}
+				if current.Status != status {
+					processed++
+				}
+			}
+			if len(batch) < payoutBatchSize {
+				break
+			}
+			after = batch[len(batch)-1].ID
+		}
+	}
+	return processed, nil
+}
+
+// send hands the initiated payout to the provider. The provider gets the payout's ID
+// to pay it once, however often it is sent.
+func (s *PayoutService) send(ctx context.Context, initiated *models.Payout) (*models.Payout, error) {
+	reference, err := s.Provider.Send(ctx, payout.Request{
+		ID:            initiated.ID,
+		Amount:        initiated.Funds(),
+		AccountName:   initiated.AccountName,
+		AccountNumber: initiated.AccountNumber,
+		BankCode:      initiated.BankCode,
+	})
+	if errors.Is(err, payout.ErrRejected) {
+		return s.fail(ctx, initiated.ID, err.Error())
+	}
+	if err != nil {
+		logger.FromContext(ctx).Warn("Payout not sent, will retry",
+			zap.Error(err), zap.String("payout_id", initiated.ID.String()))
+		return initiated, nil
+	}
+
+	return s.advance(ctx, initiated.ID, "mark payout sent", func(current *models.Payout) bool {
+		if current.Status != models.PayoutStatusInitiated {
+			return false
+		}
+		current.Status = models.PayoutStatusSent
+		current.ProviderReference = &reference
+		return true
+	})
+}
+
+// poll asks the provider how the sent payout stands and records it once it has settled
+// or failed
+func (s *PayoutService) poll(ctx context.Context, sent *models.Payout) (*models.Payout, error) {
+	result, err := s.Provider.Status(ctx, *sent.ProviderReference)
+	if err != nil {
+		logger.FromContext(ctx).Warn("Payout status unavailable, will retry",
+			zap.Error(err), zap.String("payout_id", sent.ID.String()))
+		return sent, nil
+	}
+
+	switch result.Status {
+	case payout.StatusSettled:
+		return s.advance(ctx, sent.ID, "settle payout", func(current *models.Payout) bool {
+			if current.Status != models.PayoutStatusSent {
+				return false
+			}
+			current.Status = models.PayoutStatusSettled
+			return true
+		})
+	case payout.StatusFailed:
+		reason := result.Reason
+		if reason == "" {
+			reason = "payout failed"
+		}
+		return s.fail(ctx, sent.ID, reason)
+	default:
+		return sent, nil
+	}
+}
+
+// advance locks the payout and stores it once update has changed it, leaving it as it
+// is when update reports that it has already moved on
+func (s *PayoutService) advance(ctx context.Context, payoutID uuid.UUID, operation string, update func(*models.Payout) bool) (*models.Payout, error) {
+	var updated *models.Payout
+	err := s.Wallets.withTx(ctx, operation, func(ctx context.Context, tx *sql.Tx) error {
+		current, err := s.Repo.GetPayoutWithTx(ctx, tx, payoutID)
+		if err != nil {
+			return err
+		}
+		updated = current
+		if !update(current) {
+			return nil
+		}
+		current.UpdatedAt = s.now()
+		return s.Repo.UpdatePayoutWithTx(ctx, tx, current)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// fail marks the payout failed for reason and credits its amount back to the wallet,
+// as a reversal of the payout's debit, in one transaction. The money never left, so it
+// goes back whatever the wallet's status; the withdrawal fee is not refunded.
+func (s *PayoutService) fail(ctx context.Context, payoutID uuid.UUID, reason string) (*models.Payout, error) {
+	found, err := s.Get(ctx, payoutID)
+	if err != nil {
+		return nil, err
+	}
+	original, err := s.Wallets.LedgerRepo.GetJournalByID(ctx, found.JournalID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payout journal: %w", err)
+	}
+	entries, err := reversalEntries(original, nil)
+	if err != nil {
+		return nil, err
+	}
+	// The treasury wallet the payout was paid to, if any, pays it back
+	var treasuryID *uuid.UUID
+	for _, entry := range original.Entries {
+		if entry.Direction == models.EntryDirectionCredit {
+			treasuryID = entry.WalletID
+		}
+	}
+	description := "Failed payout to " + found.AccountName
+	refund := newJournal(models.JournalTypeReversal, &description, nil, entries...)
+	refund.ReversesJournalID = &original.ID
+
+	var failed *models.Payout
+	refunded := false
+	err = s.Wallets.withTx(ctx, "fail payout", func(ctx context.Context, tx *sql.Tx) error {
+		current, err := s.Repo.GetPayoutWithTx(ctx, tx, payoutID)
+		if err != nil {
+			return err
+		}
+		failed, refunded = current, false
+		if current.IsFinal() {
+			return nil
+		}
+
+		wallet, err := s.Wallets.getWalletForUpdate(ctx, tx, current.WalletID)
+		if err != nil {
+			return fmt.Errorf("failed to get wallet: %w", err)
+		}
+		newBalance, err := wallet.Funds().Add(current.Funds())
+		if err != nil {
+			return fmt.Errorf("invalid payout refund: %w", err)
+		}
+		if err := s.Wallets.setBalance(ctx, tx, wallet, newBalance.Amount()); err != nil {
+			return fmt.Errorf("failed to update wallet balance: %w", err)
+		}
+		if err := s.Wallets.settle(ctx, tx, wallet, refund, treasuryID); err != nil {
+			return err
+		}
+		if err := s.Wallets.recordJournal(ctx, tx, refund); err != nil {
+			return err
+		}
+
+		current.Status = models.PayoutStatusFailed
+		current.FailureReason = &reason
+		current.RefundJournalID = &refund.ID
+		current.UpdatedAt = refund.CreatedAt
+		refunded = true
+		return s.Repo.UpdatePayoutWithTx(ctx, tx, current)
+	})
+	if errors.Is(err, repository.ErrDuplicate) {
+		// The payout's debit was reversed by hand, which already paid it back
+		return nil, fmt.Errorf("failed payout %s: %w", payoutID, ErrAlreadyReversed)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !refunded {
+		return failed, nil
+	}
+
+	logger.FromContext(ctx).Warn("Payout failed",
+		zap.String("payout_id", payoutID.String()),
+		zap.String("wallet_id", failed.WalletID.String()),
+		zap.String("reason", reason))
+	return failed, nil
+}
@@ -0,0 +1,399 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository"
+	"github.com/shanwije/wallet-app/pkg/clock"
+	"github.com/shanwije/wallet-app/pkg/logger"
+	"github.com/shanwije/wallet-app/pkg/money"
+)
+
+var (
+	// ErrPendingTransferNotFound is returned when the wallet has no pending transfer with the ID
+	ErrPendingTransferNotFound = errors.New("pending transfer not found")
+	// ErrPendingTransferNotPending is returned when confirming, approving or cancelling a
+	// transfer that already completed, failed or was cancelled
+	ErrPendingTransferNotPending = errors.New("transfer is no longer pending")
+	// ErrPendingTransferExpired is returned when confirming or approving a transfer after
+	// its confirmation window ended; the transfer is cancelled
+	ErrPendingTransferExpired = errors.New("transfer was not confirmed in time")
+	// ErrConfirmationRequired is returned for a transfer over the confirmation threshold
+	// made any way other than as a pending transfer its sender confirms
+	ErrConfirmationRequired = errors.New("transfer over the confirmation threshold must be confirmed")
+)
+
+const (
+	// defaultConfirmationWindow is how long a transfer waits for confirmation when
+	// ConfirmationWindow is zero
+	defaultConfirmationWindow = 15 * time.Minute
+	// expiryBatchSize bounds how many transfers one ExpireDue call cancels
+	expiryBatchSize = 100
+	// expiredReason is recorded on transfers cancelled for not being confirmed in time
+	expiredReason = "not confirmed before it expired"
+)
+
+// PendingTransferService holds transfers over a threshold until their sender confirms
+// them or an operator approves them, and cancels those left unconfirmed
+type PendingTransferService struct {
+	Repo    repository.PendingTransferRepository
+	Wallets *WalletService
+	// Threshold is the amount, in the transfer's currency, over which a transfer needs
+	// confirming; nil lets every transfer through at once
+	Threshold *decimal.Decimal
+	// ConfirmationWindow is how long a transfer waits to be confirmed
+	ConfirmationWindow time.Duration
+	// Clock is optional; the system clock is used when nil
+	Clock clock.Clock
+}
+
+// now returns the current time from the injected clock
+func (s *PendingTransferService) now() time.Time {
+	return clock.OrDefault(s.Clock).Now()
+}
+
+// RequiresConfirmation reports whether a transfer of amount has to be confirmed
+// before it runs. It is false on a nil service.
+func (s *PendingTransferService) RequiresConfirmation(amount money.Money) bool {
+	return s != nil && s.Threshold != nil && amount.Amount().GreaterThan(*s.Threshold)
+}
+
+// Create stores a transfer of amount to wait for confirmation. No money moves, so the
+// sender's balance is only checked when it runs, but a version the sender must be at,
+// set with WithExpectedVersion, is checked now. The classification set with
+// WithClassification is kept for the transfer to be filed under when it runs. A quote
+// set with WithQuote is refused with ErrQuoteNeedsConfirmation rather than dropped,
+// since the transfer runs on the terms in force when it is confirmed. A non-empty
+// idempotencyKey makes retries return the transfer already held under it.
+func (s *PendingTransferService) Create(ctx context.Context, fromWalletID, toWalletID uuid.UUID, amount money.Money, description, idempotencyKey string) (*models.PendingTransfer, error) {
+	if err := s.Wallets.validateTransferAmount(amount, fromWalletID, toWalletID); err != nil {
+		return nil, err
+	}
+	if _, quoted := ctx.Value(quoteIDKey{}).(uuid.UUID); quoted {
+		return nil, ErrQuoteNeedsConfirmation
+	}
+
+	var key *string
+	if idempotencyKey != "" {
+		key = scopedIdempotencyKey(fromWalletID, idempotencyKey)
+		if held, err := s.heldTransfer(ctx, *key, toWalletID, amount); !errors.Is(err, repository.ErrNotFound) {
+			return held, err
+		}
+	}
+
+	// Catch a wallet that could not send or receive the transfer now rather than when
+	// it is confirmed
+	sender, err := s.Wallets.GetBalance(ctx, fromWalletID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get source wallet: %w", err)
+	}
+	if err := checkExpectedVersion(ctx, sender); err != nil {
+		return nil, err
+	}
+	if err := sender.CheckActive(); err != nil {
+		return nil, err
+	}
+	if !sender.Funds().SameCurrency(amount) {
+		return nil, fmt.Errorf("invalid transfer: %w", money.ErrCurrencyMismatch)
+	}
+	if _, err := s.Wallets.GetBalance(ctx, toWalletID); err != nil {
+		return nil, fmt.Errorf("failed to get destination wallet: %w", err)
+	}
+
+	window := s.ConfirmationWindow
+	if window <= 0 {
+		window = defaultConfirmationWindow
+	}
+	now := s.now()
+	category, tags := classificationOf(ctx)
+	transfer := &models.PendingTransfer{
+		FromWalletID:   fromWalletID,
+		ToWalletID:     toWalletID,
+		Amount:         amount.Amount(),
+		Currency:       amount.Currency(),
+		Category:       category,
+		Tags:           tags,
+		Status:         models.PendingTransferStatusPending,
+		ExpiresAt:      now.Add(window),
+		CreatedAt:      now,
+		IdempotencyKey: key,
+	}
+	if description != "" {
+		transfer.Description = &description
+	}
+
+	if err := s.Repo.CreatePendingTransfer(ctx, transfer); err != nil {
+		if errors.Is(err, repository.ErrDuplicate) && key != nil {
+			// A concurrent retry held it first
+			return s.heldTransfer(ctx, *key, toWalletID, amount)
+		}
+		return nil, fmt.Errorf("failed to create pending transfer: %w", err)
+	}
+
+	return transfer, nil
+}
+
+// heldTransfer returns the transfer already held under the scoped idempotency key,
+// wrapping repository.ErrNotFound when there is none, and ErrIdempotencyKeyReused when
+// it is not a transfer of amount to the same wallet
+func (s *PendingTransferService) heldTransfer(ctx context.Context, key string, toWalletID uuid.UUID, amount money.Money) (*models.PendingTransfer, error) {
+	held, err := s.Repo.GetPendingTransferByIdempotencyKey(ctx, key)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up idempotency key: %w", err)
+	}
+	if held.ToWalletID != toWalletID || !held.Funds().Equal(amount) {
+		return nil, ErrIdempotencyKeyReused
+	}
+	return held, nil
+}
+
+// List returns the transfers the wallet sent for confirmation, newest first
+func (s *PendingTransferService) List(ctx context.Context, walletID uuid.UUID) ([]*models.PendingTransfer, error) {
+	transfers, err := s.Repo.ListPendingTransfersByWalletID(ctx, walletID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pending transfers: %w", err)
+	}
+
+	return transfers, nil
+}
+
+// ListAll returns a page of transfers in the status, or in any status when it is
+// empty, oldest first
+func (s *PendingTransferService) ListAll(ctx context.Context, status string, limit, offset int) ([]*models.PendingTransfer, error) {
+	transfers, err := s.Repo.ListPendingTransfers(ctx, status, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pending transfers: %w", err)
+	}
+
+	return transfers, nil
+}
+
+// Confirm runs a pending transfer the wallet sent
+func (s *PendingTransferService) Confirm(ctx context.Context, walletID, transferID uuid.UUID) (*models.PendingTransfer, error) {
+	transfer, err := s.get(ctx, transferID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Other wallets' transfers are reported as missing rather than forbidden
+	if transfer.FromWalletID != walletID {
+		return nil, ErrPendingTransferNotFound
+	}
+	return s.run(ctx, transfer)
+}
+
+// Approve runs a pending transfer on an operator's say-so, in place of its sender's
+// confirmation
+func (s *PendingTransferService) Approve(ctx context.Context, transferID uuid.UUID) (*models.PendingTransfer, error) {
+	transfer, err := s.get(ctx, transferID)
+	if err != nil {
+		return nil, err
+	}
+	return s.run(ctx, transfer)
+}
+
+// Cancel drops a pending transfer the wallet sent without moving any money
+func (s *PendingTransferService) Cancel(ctx context.Context, walletID, transferID uuid.UUID) (*models.PendingTransfer, error) {
+	transfer, err := s.get(ctx, transferID)
+	if err != nil {
+		return nil, err
+	}
+	if transfer.FromWalletID != walletID {
+		return nil, ErrPendingTransferNotFound
+	}
+	return s.finish(ctx, transferID, models.PendingTransferStatusCancelled, nil)
+}
+
+// run moves the money of a pending transfer. The transfer and the change of status
+// commit together, so a transfer never runs twice or runs and is left pending. A
+// transfer that cannot run, for want of funds or a wallet that can no longer take part,
+// is failed with the reason; one confirmed too late is cancelled.
+func (s *PendingTransferService) run(ctx context.Context, transfer *models.PendingTransfer) (*models.PendingTransfer, error) {
+	if transfer.Status != models.PendingTransferStatusPending {
+		return nil, ErrPendingTransferNotPending
+	}
+	if !s.now().Before(transfer.ExpiresAt) {
+		reason := expiredReason
+		if _, err := s.finish(ctx, transfer.ID, models.PendingTransferStatusCancelled, &reason); err != nil {
+			return nil, err
+		}
+		return nil, ErrPendingTransferExpired
+	}
+
+	amount := transfer.Funds()
+	fee, err := s.Wallets.movementFee(ctx, models.JournalTypeTransfer, transfer.FromWalletID, amount)
+	if err != nil {
+		return nil, err
+	}
+	err = s.Wallets.assessRisk(ctx, models.JournalTypeTransfer, transfer.FromWalletID, &transfer.ToWalletID, amount)
+	if err == nil {
+		err = s.Wallets.withTx(ctx, "confirm transfer", func(ctx context.Context, tx *sql.Tx) error {
+			current, err := s.Repo.GetPendingTransferWithTx(ctx, tx, transfer.ID)
+			if err != nil {
+				return err
+			}
+			if current.Status != models.PendingTransferStatusPending {
+				return ErrPendingTransferNotPending
+			}
+
+			// The key is unique per transfer, so the ledger itself refuses a second run
+			journal := newJournal(models.JournalTypeTransfer, current.Description,
+				scopedIdempotencyKey(current.FromWalletID, "pending-transfer:"+current.ID.String()),
+				debit(&current.FromWalletID, amount),
+				credit(&current.ToWalletID, amount),
+			)
+			journal.Category, journal.Tags = current.Category, current.Tags
+			_, to, err := s.Wallets.transferExecution(ctx, tx, current.FromWalletID, current.ToWalletID, amount, fee, journal)
+			if err != nil {
+				return err
+			}
+			if err := s.Wallets.awaitAcceptance(ctx, tx, to, journal); err != nil {
+				return err
+			}
+
+			current.Status = models.PendingTransferStatusCompleted
+			current.TransferJournalID = &journal.ID
+			current.UpdatedAt = journal.CreatedAt
+			if err := s.Repo.UpdatePendingTransferWithTx(ctx, tx, current); err != nil {
+				return err
+			}
+
+			transfer = current
+			return nil
+		})
+	}
+	if err != nil {
+		if declinesTransfer(err) {
+			reason := err.Error()
+			if _, finishErr := s.finish(ctx, transfer.ID, models.PendingTransferStatusFailed, &reason); finishErr != nil {
+				logger.FromContext(ctx).Error("Failed to record failed transfer", zap.Error(finishErr),
+					zap.String("pending_transfer_id", transfer.ID.String()))
+			}
+		}
+		return nil, err
+	}
+
+	return transfer, nil
+}
+
+// declinesTransfer reports whether err means the transfer cannot run as it stands,
+// rather than that running it failed and may succeed when retried
+func declinesTransfer(err error) bool {
+	for _, declined := range []error{
+		ErrInsufficientBalance,
+		ErrLimitExceeded,
+		ErrBlockedByRiskCheck,
+		ErrWalletNotFound,
+		models.ErrWalletFrozen,
+		models.ErrWalletClosed,
+		money.ErrCurrencyMismatch,
+	} {
+		if errors.Is(err, declined) {
+			return true
+		}
+	}
+	return false
+}
+
+// finish moves a transfer that is still pending to a final status without running it
+func (s *PendingTransferService) finish(ctx context.Context, transferID uuid.UUID, status string, reason *string) (*models.PendingTransfer, error) {
+	var transfer *models.PendingTransfer
+	err := s.Wallets.withTx(ctx, "finish pending transfer", func(ctx context.Context, tx *sql.Tx) error {
+		current, err := s.Repo.GetPendingTransferWithTx(ctx, tx, transferID)
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrPendingTransferNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if current.Status != models.PendingTransferStatusPending {
+			return ErrPendingTransferNotPending
+		}
+
+		current.Status = status
+		current.Reason = reason
+		current.UpdatedAt = s.now()
+		if err := s.Repo.UpdatePendingTransferWithTx(ctx, tx, current); err != nil {
+			return err
+		}
+
+		transfer = current
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return transfer, nil
+}
+
+// get returns the pending transfer with the ID
+func (s *PendingTransferService) get(ctx context.Context, transferID uuid.UUID) (*models.PendingTransfer, error) {
+	transfer, err := s.Repo.GetPendingTransfer(ctx, transferID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrPendingTransferNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pending transfer: %w", err)
+	}
+	return transfer, nil
+}
+
+// Run cancels expired transfers every interval until ctx is cancelled
+func (s *PendingTransferService) Run(ctx context.Context, interval time.Duration) {
+	log := logger.FromContext(ctx)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		expired, err := s.ExpireDue(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Error("Pending transfer expiry failed", zap.Error(err))
+		} else if expired > 0 {
+			log.Info("Cancelled expired pending transfers", zap.Int("count", expired))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ExpireDue cancels the transfers whose confirmation window has ended and returns how
+// many it cancelled. One confirmed while this runs is left to whichever gets it first.
+func (s *PendingTransferService) ExpireDue(ctx context.Context) (int, error) {
+	due, err := s.Repo.ListExpiredPendingTransfers(ctx, s.now(), expiryBatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list expired pending transfers: %w", err)
+	}
+
+	expired := 0
+	reason := expiredReason
+	for _, transfer := range due {
+		_, err := s.finish(ctx, transfer.ID, models.PendingTransferStatusCancelled, &reason)
+		if errors.Is(err, ErrPendingTransferNotPending) {
+			continue
+		}
+		if err != nil {
+			return expired, err
+		}
+		expired++
+	}
+
+	return expired, nil
+}
@@ -0,0 +1,215 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository"
+	"github.com/shanwije/wallet-app/pkg/clock"
+	"github.com/shanwije/wallet-app/pkg/logger"
+	"github.com/shanwije/wallet-app/pkg/money"
+)
+
+var (
+	// ErrProviderPaymentNotFound is returned when the provider's payment ID is not recorded
+	ErrProviderPaymentNotFound = errors.New("provider payment not found")
+	// ErrInvalidProviderPayment is returned for a report without a payment ID or wallet,
+	// or with a status other than pending, succeeded or failed
+	ErrInvalidProviderPayment = errors.New("invalid provider payment")
+	// ErrProviderPaymentMismatch is returned when a report names another wallet or
+	// amount than the payment was first reported with
+	ErrProviderPaymentMismatch = errors.New("provider payment does not match the one recorded")
+)
+
+// ProviderPaymentReport is what the payment provider reported about one of its payments
+type ProviderPaymentReport struct {
+	// PaymentID is the provider's ID for the payment, the same in every report about it
+	PaymentID     string
+	Status        string // pending, succeeded or failed
+	WalletID      uuid.UUID
+	Amount        money.Money
+	FailureReason string
+}
+
+// ProviderPaymentService deposits the payments the payment provider takes to fund
+// wallets. The provider reports each payment as it progresses, possibly more than once
+// and out of order; the payment is deposited once, when it is first reported succeeded.
+type ProviderPaymentService struct {
+	Repo    repository.ProviderPaymentRepository
+	Wallets *WalletService
+	// Clock is optional; the system clock is used when nil
+	Clock clock.Clock
+}
+
+// now returns the current time from the injected clock
+func (s *ProviderPaymentService) now() time.Time {
+	return clock.OrDefault(s.Clock).Now()
+}
+
+// Apply records the report and returns the payment as it now stands. A payment reported
+// succeeded is deposited into its wallet, under an idempotency key of its payment ID,
+// along with its new status; a report the payment has already moved past is ignored.
+// A deposit that fails, say because the wallet is frozen, leaves the payment as it was
+// for the provider's next delivery of the report. The amount only has to be positive.
+func (s *ProviderPaymentService) Apply(ctx context.Context, report ProviderPaymentReport) (*models.ProviderPayment, error) {
+	report.PaymentID = strings.TrimSpace(report.PaymentID)
+	if report.PaymentID == "" || report.WalletID == uuid.Nil {
+		return nil, ErrInvalidProviderPayment
+	}
+	switch report.Status {
+	case models.ProviderPaymentStatusPending, models.ProviderPaymentStatusFailed, models.ProviderPaymentStatusSucceeded:
+	default:
+		return nil, fmt.Errorf("%w: status %q", ErrInvalidProviderPayment, report.Status)
+	}
+	// The provider has already taken the money, so the transaction amount range is not
+	// applied: refusing it would only make the provider deliver the report again
+	if !report.Amount.IsPositive() {
+		return nil, fmt.Errorf("deposit %w", ErrInvalidAmount)
+	}
+
+	recorded, err := s.record(ctx, report)
+	if err != nil {
+		return nil, err
+	}
+	if !recorded.CanBecome(report.Status) {
+		return recorded, nil
+	}
+	if report.Status == models.ProviderPaymentStatusSucceeded {
+		return s.deposit(ctx, recorded)
+	}
+
+	var updated *models.ProviderPayment
+	err = s.Wallets.withTx(ctx, "update provider payment", func(ctx context.Context, tx *sql.Tx) error {
+		current, err := s.Repo.GetProviderPaymentWithTx(ctx, tx, report.PaymentID)
+		if err != nil {
+			return err
+		}
+		updated = current
+		if !current.CanBecome(report.Status) {
+			return nil
+		}
+		current.Status = report.Status
+		current.FailureReason = nil
+		if report.Status == models.ProviderPaymentStatusFailed && report.FailureReason != "" {
+			current.FailureReason = &report.FailureReason
+		}
+		current.UpdatedAt = s.now()
+		return s.Repo.UpdateProviderPaymentWithTx(ctx, tx, current)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// record returns the payment the report is about, recording it as pending the first time
+// it is reported, and checks that the report describes the same payment
+func (s *ProviderPaymentService) record(ctx context.Context, report ProviderPaymentReport) (*models.ProviderPayment, error) {
+	recorded, err := s.Repo.GetProviderPayment(ctx, report.PaymentID)
+	if errors.Is(err, repository.ErrNotFound) {
+		if _, err := s.Wallets.GetBalance(ctx, report.WalletID); err != nil {
+			return nil, err
+		}
+		now := s.now()
+		recorded = &models.ProviderPayment{
+			PaymentID: report.PaymentID,
+			WalletID:  report.WalletID,
+			Amount:    report.Amount.Amount(),
+			Currency:  report.Amount.Currency(),
+			Status:    models.ProviderPaymentStatusPending,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+		err = s.Wallets.withTx(ctx, "record provider payment", func(ctx context.Context, tx *sql.Tx) error {
+			return s.Repo.CreateProviderPaymentWithTx(ctx, tx, recorded)
+		})
+		if errors.Is(err, repository.ErrDuplicate) {
+			// Another delivery about the same payment recorded it first
+			recorded, err = s.Repo.GetProviderPayment(ctx, report.PaymentID)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to record provider payment: %w", err)
+	}
+
+	if recorded.WalletID != report.WalletID || !recorded.Funds().Equal(report.Amount) {
+		return nil, ErrProviderPaymentMismatch
+	}
+	return recorded, nil
+}
+
+// deposit credits the payment to its wallet and marks it succeeded, in one transaction.
+// The payment is not charged a fee or held to the wallet's limits.
+func (s *ProviderPaymentService) deposit(ctx context.Context, payment *models.ProviderPayment) (*models.ProviderPayment, error) {
+	amount := payment.Funds()
+	treasuryID, err := s.Wallets.settlementWallet(ctx, models.WalletKindTreasury, amount.Currency())
+	if err != nil {
+		return nil, err
+	}
+	description := "Payment " + payment.PaymentID
+	journal := newJournal(models.JournalTypeDeposit, &description, scopedIdempotencyKey(payment.WalletID, "payment:"+payment.PaymentID),
+		debit(treasuryID, amount),
+		credit(&payment.WalletID, amount),
+	)
+	classify(ctx, journal)
+
+	var deposited *models.ProviderPayment
+	credited := false
+	replayed, err := s.Wallets.idempotent(ctx, journal, func() error {
+		return s.Wallets.withTx(ctx, "deposit provider payment", func(ctx context.Context, tx *sql.Tx) error {
+			current, err := s.Repo.GetProviderPaymentWithTx(ctx, tx, payment.PaymentID)
+			if err != nil {
+				return err
+			}
+			deposited, credited = current, false
+			if !current.CanBecome(models.ProviderPaymentStatusSucceeded) {
+				return nil
+			}
+
+			if _, err := s.Wallets.capturedDepositExecution(ctx, tx, current.WalletID, amount, journal, treasuryID); err != nil {
+				return err
+			}
+			current.Status = models.ProviderPaymentStatusSucceeded
+			current.FailureReason = nil
+			current.JournalID = &journal.ID
+			current.UpdatedAt = journal.CreatedAt
+			credited = true
+			return s.Repo.UpdateProviderPaymentWithTx(ctx, tx, current)
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	if replayed || deposited == nil {
+		return s.Get(ctx, payment.PaymentID)
+	}
+	if !credited {
+		return deposited, nil
+	}
+
+	logger.FromContext(ctx).Info("Provider payment deposited",
+		zap.String("payment_id", payment.PaymentID),
+		zap.String("wallet_id", payment.WalletID.String()),
+		zap.String("amount", amount.String()))
+	return deposited, nil
+}
+
+// Get returns the payment the provider knows by paymentID
+func (s *ProviderPaymentService) Get(ctx context.Context, paymentID string) (*models.ProviderPayment, error) {
+	found, err := s.Repo.GetProviderPayment(ctx, paymentID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrProviderPaymentNotFound
+		}
+		return nil, fmt.Errorf("failed to get provider payment: %w", err)
+	}
+	return found, nil
+}
@@ -0,0 +1,188 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+
+	"github.com/shanwije/wallet-app/internal/fx"
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository"
+	"github.com/shanwije/wallet-app/pkg/money"
+)
+
+var (
+	// ErrQuoteNotFound is returned when a transfer passes a quote ID that does not exist
+	ErrQuoteNotFound = errors.New("transfer quote not found")
+	// ErrQuoteExpired is returned when a transfer passes a quote after it expired
+	ErrQuoteExpired = errors.New("transfer quote has expired")
+	// ErrQuoteUsed is returned when a transfer passes a quote another transfer already used
+	ErrQuoteUsed = errors.New("transfer quote has already been used")
+	// ErrQuoteMismatch is returned when a transfer passes a quote for other wallets or
+	// another amount
+	ErrQuoteMismatch = errors.New("transfer does not match its quote")
+	// ErrQuoteNeedsConfirmation is returned when a transfer that is held for confirmation
+	// passes a quote, which would expire before the transfer runs
+	ErrQuoteNeedsConfirmation = errors.New("a transfer that needs confirmation cannot run on a quote")
+)
+
+// defaultQuoteTTL is how long a transfer quote holds when QuoteTTL is zero
+const defaultQuoteTTL = 30 * time.Second
+
+// quoteIDKey is the context key of the quote ID set by WithQuote
+type quoteIDKey struct{}
+
+// quotedTermsKey is the context key of the quote a running transfer was priced at
+type quotedTermsKey struct{}
+
+// WithQuote returns a context in which the transfer made runs on the terms of the
+// quote: its fee and exchange rate rather than the current ones. The transfer must be
+// the one quoted, before the quote expires, and fails with ErrQuoteNotFound,
+// ErrQuoteExpired, ErrQuoteUsed or ErrQuoteMismatch otherwise.
+func WithQuote(ctx context.Context, quoteID uuid.UUID) context.Context {
+	return context.WithValue(ctx, quoteIDKey{}, quoteID)
+}
+
+// QuoteTransfer prices a transfer of amount from one wallet to another without moving
+// any money: the fee, what the sender is debited, and what the recipient is credited at
+// the current exchange rate. The quote holds for QuoteTTL, and a transfer passing its
+// ID with WithQuote in that time is made on its terms.
+func (s *WalletService) QuoteTransfer(ctx context.Context, fromWalletID, toWalletID uuid.UUID, amount money.Money) (*models.TransferQuote, error) {
+	if s.QuoteRepo == nil {
+		return nil, fmt.Errorf("transfer quotes: %w", ErrFeatureDisabled)
+	}
+	if err := s.validateTransferAmount(amount, fromWalletID, toWalletID); err != nil {
+		return nil, err
+	}
+
+	fromWallet, err := s.WalletRepo.GetWalletByID(ctx, fromWalletID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get source wallet: %w", walletLookupError(err))
+	}
+	toWallet, err := s.WalletRepo.GetWalletByID(ctx, toWalletID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get destination wallet: %w", walletLookupError(err))
+	}
+	if err := fromWallet.CheckActive(); err != nil {
+		return nil, fmt.Errorf("source %w", err)
+	}
+	if err := toWallet.CheckActive(); err != nil {
+		return nil, fmt.Errorf("destination %w", err)
+	}
+	if !fromWallet.Funds().SameCurrency(amount) {
+		return nil, fmt.Errorf("invalid transfer: %w", money.ErrCurrencyMismatch)
+	}
+
+	fee, err := s.movementFee(ctx, models.JournalTypeTransfer, fromWalletID, amount)
+	if err != nil {
+		return nil, err
+	}
+	debited, err := amount.Add(fee)
+	if err != nil {
+		return nil, fmt.Errorf("invalid fee: %w", err)
+	}
+
+	credited := amount
+	var exchangeRate *decimal.Decimal
+	if !toWallet.Funds().SameCurrency(amount) {
+		rate, err := s.exchangeRate(ctx, amount.Currency(), toWallet.Currency)
+		if err != nil {
+			return nil, err
+		}
+		if credited = fx.Convert(amount, rate, toWallet.Currency); !credited.IsPositive() {
+			return nil, fmt.Errorf("converted %w", ErrInvalidAmount)
+		}
+		exchangeRate = &rate
+	}
+
+	ttl := s.QuoteTTL
+	if ttl <= 0 {
+		ttl = defaultQuoteTTL
+	}
+	now := s.now()
+	quote := &models.TransferQuote{
+		FromWalletID:     fromWalletID,
+		ToWalletID:       toWalletID,
+		Amount:           amount.Amount(),
+		Currency:         amount.Currency(),
+		Fee:              fee.Amount(),
+		DebitedAmount:    debited.Amount(),
+		CreditedAmount:   credited.Amount(),
+		CreditedCurrency: credited.Currency(),
+		ExchangeRate:     exchangeRate,
+		ExpiresAt:        now.Add(ttl),
+		CreatedAt:        now,
+	}
+	if err := s.QuoteRepo.CreateTransferQuote(ctx, quote); err != nil {
+		return nil, fmt.Errorf("failed to create transfer quote: %w", err)
+	}
+	return quote, nil
+}
+
+// quotedTransfer returns the quote ctx asks a transfer of amount between the wallets
+// to run on, or nil when it asks for none. The quote must be for that transfer, unused
+// and unexpired.
+func (s *WalletService) quotedTransfer(ctx context.Context, fromWalletID, toWalletID uuid.UUID, amount money.Money) (*models.TransferQuote, error) {
+	quoteID, ok := ctx.Value(quoteIDKey{}).(uuid.UUID)
+	if !ok {
+		return nil, nil
+	}
+	if s.QuoteRepo == nil {
+		return nil, ErrQuoteNotFound
+	}
+
+	quote, err := s.QuoteRepo.GetTransferQuote(ctx, quoteID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrQuoteNotFound
+		}
+		return nil, fmt.Errorf("failed to get transfer quote: %w", err)
+	}
+	if quote.FromWalletID != fromWalletID || quote.ToWalletID != toWalletID ||
+		quote.Currency != amount.Currency() || !quote.Amount.Equal(amount.Amount()) {
+		return nil, ErrQuoteMismatch
+	}
+	if quote.TransferJournalID != nil {
+		return nil, ErrQuoteUsed
+	}
+	if !s.now().Before(quote.ExpiresAt) {
+		return nil, ErrQuoteExpired
+	}
+	return quote, nil
+}
+
+// useQuote marks quote as used by the transfer journal tx records. It fails with
+// ErrQuoteUsed when another transfer used it first.
+func (s *WalletService) useQuote(ctx context.Context, tx *sql.Tx, quote *models.TransferQuote, journalID uuid.UUID) error {
+	if err := s.QuoteRepo.UseTransferQuoteWithTx(ctx, tx, quote.ID, journalID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrQuoteUsed
+		}
+		return fmt.Errorf("failed to use transfer quote: %w", err)
+	}
+	quote.TransferJournalID = &journalID
+	return nil
+}
+
+// exchangeRate returns the rate converting from into to: the one quoted when ctx
+// carries a quote between those currencies, and the current rate otherwise
+func (s *WalletService) exchangeRate(ctx context.Context, from, to money.Currency) (decimal.Decimal, error) {
+	if quote, ok := ctx.Value(quotedTermsKey{}).(*models.TransferQuote); ok && quote.ExchangeRate != nil &&
+		quote.Currency == from && quote.CreditedCurrency == to {
+		return *quote.ExchangeRate, nil
+	}
+
+	if s.FX == nil {
+		return decimal.Decimal{}, fmt.Errorf("invalid transfer: %w", money.ErrCurrencyMismatch)
+	}
+	rate, err := s.FX.Rate(ctx, from, to)
+	if err != nil {
+		return decimal.Decimal{}, fmt.Errorf("failed to get exchange rate: %w", err)
+	}
+	return rate, nil
+}
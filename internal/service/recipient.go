@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/shanwije/wallet-app/internal/repository"
+)
+
+var (
+	// ErrInvalidRecipient is returned unless exactly one way of naming the recipient is given
+	ErrInvalidRecipient = errors.New("exactly one of wallet ID, user ID or username must identify the recipient")
+	// ErrRecipientNotFound is returned when no user has the recipient's user ID or username
+	ErrRecipientNotFound = errors.New("recipient not found")
+	// ErrRecipientHasNoWallet is returned when the recipient user exists but has no wallet to pay into
+	ErrRecipientHasNoWallet = errors.New("recipient has no wallet")
+)
+
+// Recipient names who a transfer pays: a wallet directly, or a user by ID or by
+// the username they registered with, which doubles as their public handle
+type Recipient struct {
+	WalletID uuid.UUID
+	UserID   uuid.UUID
+	Username string
+}
+
+// ResolveRecipient returns the wallet a transfer to the recipient should credit
+func (s *WalletService) ResolveRecipient(ctx context.Context, recipient Recipient) (uuid.UUID, error) {
+	// A leading @ is accepted so handles can be pasted as they are displayed
+	username := strings.TrimPrefix(strings.TrimSpace(recipient.Username), "@")
+
+	given := 0
+	for _, set := range []bool{recipient.WalletID != uuid.Nil, recipient.UserID != uuid.Nil, username != ""} {
+		if set {
+			given++
+		}
+	}
+	if given != 1 {
+		return uuid.Nil, ErrInvalidRecipient
+	}
+
+	if recipient.WalletID != uuid.Nil {
+		return recipient.WalletID, nil
+	}
+
+	userID := recipient.UserID
+	if username != "" {
+		credentials, err := s.CredentialRepo.GetCredentialsByUsername(ctx, username)
+		if errors.Is(err, repository.ErrNotFound) {
+			return uuid.Nil, fmt.Errorf("%w: no user named %q", ErrRecipientNotFound, username)
+		}
+		if err != nil {
+			return uuid.Nil, fmt.Errorf("failed to look up recipient: %w", err)
+		}
+		userID = credentials.UserID
+	}
+
+	// Deleted users keep their credentials, so a username is checked here too
+	_, err := s.UserRepo.GetUserByID(ctx, userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return uuid.Nil, fmt.Errorf("%w: no user with ID %s", ErrRecipientNotFound, userID)
+	}
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to look up recipient: %w", err)
+	}
+
+	wallet, err := s.WalletRepo.GetWalletByUserID(ctx, userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return uuid.Nil, ErrRecipientHasNoWallet
+	}
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to get recipient wallet: %w", err)
+	}
+
+	return wallet.ID, nil
+}
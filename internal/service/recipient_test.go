@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository"
+	"github.com/shanwije/wallet-app/internal/repository/mocks"
+)
+
+// setupRecipientService creates a wallet service that can look up users
+func setupRecipientService() (*WalletService, *mocks.WalletRepository, *mocks.UserRepository, *mocks.CredentialRepository) {
+	service, walletRepo, _ := setupWalletService()
+	userRepo := new(mocks.UserRepository)
+	credentialRepo := new(mocks.CredentialRepository)
+	service.UserRepo = userRepo
+	service.CredentialRepo = credentialRepo
+	return service, walletRepo, userRepo, credentialRepo
+}
+
+func TestResolveRecipientByWalletID(t *testing.T) {
+	service, walletRepo, userRepo, _ := setupRecipientService()
+	walletID := uuid.New()
+
+	resolved, err := service.ResolveRecipient(context.Background(), Recipient{WalletID: walletID})
+
+	assert.NoError(t, err)
+	assert.Equal(t, walletID, resolved)
+	walletRepo.AssertNotCalled(t, "GetWalletByUserID", mock.Anything, mock.Anything)
+	userRepo.AssertNotCalled(t, "GetUserByID", mock.Anything, mock.Anything)
+}
+
+func TestResolveRecipientByUserID(t *testing.T) {
+	service, walletRepo, userRepo, _ := setupRecipientService()
+	userID := uuid.New()
+	walletID := uuid.New()
+
+	userRepo.On("GetUserByID", mock.Anything, userID).Return(&models.User{ID: userID}, nil)
+	walletRepo.On("GetWalletByUserID", mock.Anything, userID).Return(&models.Wallet{ID: walletID, UserID: userID}, nil)
+
+	resolved, err := service.ResolveRecipient(context.Background(), Recipient{UserID: userID})
+
+	assert.NoError(t, err)
+	assert.Equal(t, walletID, resolved)
+}
+
+func TestResolveRecipientByUsername(t *testing.T) {
+	service, walletRepo, userRepo, credentialRepo := setupRecipientService()
+	userID := uuid.New()
+	walletID := uuid.New()
+
+	credentialRepo.On("GetCredentialsByUsername", mock.Anything, "alice").Return(&models.Credentials{UserID: userID, Username: "alice"}, nil)
+	userRepo.On("GetUserByID", mock.Anything, userID).Return(&models.User{ID: userID}, nil)
+	walletRepo.On("GetWalletByUserID", mock.Anything, userID).Return(&models.Wallet{ID: walletID, UserID: userID}, nil)
+
+	resolved, err := service.ResolveRecipient(context.Background(), Recipient{Username: " @alice"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, walletID, resolved)
+}
+
+func TestResolveRecipientErrors(t *testing.T) {
+	t.Run("no recipient", func(t *testing.T) {
+		service, _, _, _ := setupRecipientService()
+		_, err := service.ResolveRecipient(context.Background(), Recipient{})
+		assert.ErrorIs(t, err, ErrInvalidRecipient)
+	})
+
+	t.Run("more than one recipient", func(t *testing.T) {
+		service, _, _, _ := setupRecipientService()
+		_, err := service.ResolveRecipient(context.Background(), Recipient{WalletID: uuid.New(), Username: "alice"})
+		assert.ErrorIs(t, err, ErrInvalidRecipient)
+	})
+
+	t.Run("unknown username", func(t *testing.T) {
+		service, _, _, credentialRepo := setupRecipientService()
+		credentialRepo.On("GetCredentialsByUsername", mock.Anything, "nobody").Return(nil, fmt.Errorf("credentials %w", repository.ErrNotFound))
+
+		_, err := service.ResolveRecipient(context.Background(), Recipient{Username: "nobody"})
+		assert.ErrorIs(t, err, ErrRecipientNotFound)
+	})
+
+	t.Run("deleted user's username", func(t *testing.T) {
+		service, _, userRepo, credentialRepo := setupRecipientService()
+		userID := uuid.New()
+		credentialRepo.On("GetCredentialsByUsername", mock.Anything, "gone").Return(&models.Credentials{UserID: userID, Username: "gone"}, nil)
+		userRepo.On("GetUserByID", mock.Anything, userID).Return(nil, fmt.Errorf("user %w", repository.ErrNotFound))
+
+		_, err := service.ResolveRecipient(context.Background(), Recipient{Username: "gone"})
+		assert.ErrorIs(t, err, ErrRecipientNotFound)
+	})
+
+	t.Run("unknown user ID", func(t *testing.T) {
+		service, _, userRepo, _ := setupRecipientService()
+		userID := uuid.New()
+		userRepo.On("GetUserByID", mock.Anything, userID).Return(nil, fmt.Errorf("user %w", repository.ErrNotFound))
+
+		_, err := service.ResolveRecipient(context.Background(), Recipient{UserID: userID})
+		assert.ErrorIs(t, err, ErrRecipientNotFound)
+	})
+
+	t.Run("user without a wallet", func(t *testing.T) {
+		service, walletRepo, userRepo, _ := setupRecipientService()
+		userID := uuid.New()
+		userRepo.On("GetUserByID", mock.Anything, userID).Return(&models.User{ID: userID}, nil)
+		walletRepo.On("GetWalletByUserID", mock.Anything, userID).Return(nil, fmt.Errorf("wallet %w for user ID: %s", repository.ErrNotFound, userID))
+
+		_, err := service.ResolveRecipient(context.Background(), Recipient{UserID: userID})
+		assert.ErrorIs(t, err, ErrRecipientHasNoWallet)
+	})
+}
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository"
+	"github.com/shanwije/wallet-app/pkg/clock"
+	"github.com/shanwije/wallet-app/pkg/logger"
+	"github.com/shanwije/wallet-app/pkg/pagination"
+)
+
+// ReconciliationService checks every wallet's stored balance against the sum of its
+// ledger entries and records the wallets that disagree
+type ReconciliationService struct {
+	Repo repository.ReconciliationRepository
+	// Clock is optional; the system clock is used when nil
+	Clock clock.Clock
+
+	mu    sync.Mutex
+	stats ReconciliationStats
+}
+
+// ReconciliationStats summarises the runs since the process started
+type ReconciliationStats struct {
+	Runs     int64 `json:"runs"`
+	Failures int64 `json:"failures"`
+	// DiscrepanciesFound adds up every run's discrepancies, so a wallet that stays out of
+	// balance is counted once per run
+	DiscrepanciesFound int64                     `json:"discrepancies_found"`
+	LastRun            *models.ReconciliationRun `json:"last_run,omitempty"`
+	LastError          string                    `json:"last_error,omitempty"`
+}
+
+// Run reconciles every interval until ctx is cancelled
+func (s *ReconciliationService) Run(ctx context.Context, interval time.Duration) {
+	log := logger.FromContext(ctx)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.Reconcile(ctx); err != nil && ctx.Err() == nil {
+			log.Error("Reconciliation run failed", zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Reconcile compares every wallet's balance with its ledger and records the run with
+// the discrepancies it found, each of which is logged as an error to alert on
+func (s *ReconciliationService) Reconcile(ctx context.Context) (*models.ReconciliationRun, error) {
+	run, err := s.reconcile(ctx)
+	s.record(run, err)
+	if err != nil {
+		return nil, err
+	}
+
+	log := logger.FromContext(ctx)
+	for _, discrepancy := range run.Discrepancies {
+		log.Error("Wallet balance does not match its ledger",
+			zap.String("run_id", run.ID.String()),
+			zap.String("wallet_id", discrepancy.WalletID.String()),
+			zap.String("balance", discrepancy.Balance.String()),
+			zap.String("ledger_balance", discrepancy.LedgerBalance.String()),
+			zap.String("currency", string(discrepancy.Currency)))
+	}
+	log.Info("Reconciliation run finished",
+		zap.String("run_id", run.ID.String()),
+		zap.Int64("wallets_checked", run.WalletsChecked),
+		zap.Int("discrepancies", run.DiscrepancyCount))
+
+	return run, nil
+}
+
+func (s *ReconciliationService) reconcile(ctx context.Context) (*models.ReconciliationRun, error) {
+	clk := clock.OrDefault(s.Clock)
+	run := &models.ReconciliationRun{StartedAt: clk.Now()}
+
+	checked, err := s.Repo.CountWallets(ctx)
+	if err != nil {
+		return nil, err
+	}
+	run.WalletsChecked = checked
+
+	run.Discrepancies, err = s.Repo.FindBalanceDiscrepancies(ctx)
+	if err != nil {
+		return nil, err
+	}
+	run.FinishedAt = clk.Now()
+
+	if err := s.Repo.CreateRun(ctx, run); err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+// record adds the outcome of a run to the stats
+func (s *ReconciliationService) record(run *models.ReconciliationRun, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err != nil {
+		s.stats.Failures++
+		s.stats.LastError = err.Error()
+		return
+	}
+	s.stats.Runs++
+	s.stats.DiscrepanciesFound += int64(run.DiscrepancyCount)
+	s.stats.LastError = ""
+	summary := *run
+	summary.Discrepancies = nil
+	s.stats.LastRun = &summary
+}
+
+// Stats returns the totals since the process started, for publishing as metrics
+func (s *ReconciliationService) Stats() ReconciliationStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stats
+}
+
+// ListRuns pages through past runs, newest first
+func (s *ReconciliationService) ListRuns(ctx context.Context, limit, offset int) ([]*models.ReconciliationRun, error) {
+	runs, err := s.Repo.ListRuns(ctx, pagination.ClampLimit(limit), max(offset, 0))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list reconciliation runs: %w", err)
+	}
+	return runs, nil
+}
+
+// ListDiscrepancies pages through the wallets runs found out of balance, newest first
+func (s *ReconciliationService) ListDiscrepancies(ctx context.Context, filter repository.DiscrepancyFilter) ([]*models.BalanceDiscrepancy, error) {
+	filter.Limit = pagination.ClampLimit(filter.Limit)
+	filter.Offset = max(filter.Offset, 0)
+
+	discrepancies, err := s.Repo.ListDiscrepancies(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list discrepancies: %w", err)
+	}
+	return discrepancies, nil
+}
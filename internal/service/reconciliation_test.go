@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository"
+	"github.com/shanwije/wallet-app/internal/repository/mocks"
+	"github.com/shanwije/wallet-app/pkg/clock"
+)
+
+var reconcileNow = time.Date(2024, 7, 2, 3, 0, 0, 0, time.UTC)
+
+func TestReconcileRecordsDiscrepancies(t *testing.T) {
+	repo := new(mocks.ReconciliationRepository)
+	service := &ReconciliationService{Repo: repo, Clock: clock.NewFake(reconcileNow)}
+
+	walletID := uuid.New()
+	discrepancy := &models.BalanceDiscrepancy{
+		WalletID:      walletID,
+		Balance:       decimal.NewFromInt(100),
+		LedgerBalance: decimal.NewFromInt(90),
+		Difference:    decimal.NewFromInt(10),
+		Currency:      "USD",
+	}
+	repo.On("CountWallets", mock.Anything).Return(int64(12), nil)
+	repo.On("FindBalanceDiscrepancies", mock.Anything).Return([]*models.BalanceDiscrepancy{discrepancy}, nil)
+	repo.On("CreateRun", mock.Anything, mock.AnythingOfType("*models.ReconciliationRun")).
+		Run(func(args mock.Arguments) {
+			run := args.Get(1).(*models.ReconciliationRun)
+			run.ID = uuid.New()
+			run.DiscrepancyCount = len(run.Discrepancies)
+		}).
+		Return(nil)
+
+	run, err := service.Reconcile(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(12), run.WalletsChecked)
+	assert.Equal(t, reconcileNow, run.StartedAt)
+	assert.Equal(t, []*models.BalanceDiscrepancy{discrepancy}, run.Discrepancies)
+
+	stats := service.Stats()
+	assert.Equal(t, int64(1), stats.Runs)
+	assert.Equal(t, int64(1), stats.DiscrepanciesFound)
+	require.NotNil(t, stats.LastRun)
+	assert.Equal(t, run.ID, stats.LastRun.ID)
+	assert.Nil(t, stats.LastRun.Discrepancies)
+}
+
+func TestReconcileCountsFailures(t *testing.T) {
+	repo := new(mocks.ReconciliationRepository)
+	service := &ReconciliationService{Repo: repo, Clock: clock.NewFake(reconcileNow)}
+
+	repo.On("CountWallets", mock.Anything).Return(int64(12), nil)
+	repo.On("FindBalanceDiscrepancies", mock.Anything).Return(nil, errors.New("connection reset"))
+
+	_, err := service.Reconcile(context.Background())
+
+	assert.Error(t, err)
+	repo.AssertNotCalled(t, "CreateRun", mock.Anything, mock.Anything)
+	stats := service.Stats()
+	assert.Equal(t, int64(0), stats.Runs)
+	assert.Equal(t, int64(1), stats.Failures)
+	assert.Equal(t, "connection reset", stats.LastError)
+}
+
+func TestListDiscrepanciesClampsPage(t *testing.T) {
+	repo := new(mocks.ReconciliationRepository)
+	service := &ReconciliationService{Repo: repo}
+
+	runID := uuid.New()
+	repo.On("ListDiscrepancies", mock.Anything, repository.DiscrepancyFilter{RunID: runID, Limit: 200, Offset: 0}).
+		Return([]*models.BalanceDiscrepancy{}, nil)
+
+	_, err := service.ListDiscrepancies(context.Background(), repository.DiscrepancyFilter{RunID: runID, Limit: 1000, Offset: -5})
+
+	assert.NoError(t, err)
+	repo.AssertExpectations(t)
+}
@@ -0,0 +1,297 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/shanwije/wallet-app/internal/fx"
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository"
+	"github.com/shanwije/wallet-app/pkg/money"
+)
+
+var (
+	// ErrTransactionNotFound is returned when no ledger entry has the transaction ID
+	ErrTransactionNotFound = errors.New("transaction not found")
+	// ErrAlreadyReversed is returned for a transaction whose journal was reversed or
+	// corrected before
+	ErrAlreadyReversed = errors.New("transaction has already been reversed or corrected")
+	// ErrNotReversible is returned for a reversal or correction, which cannot itself be reversed
+	ErrNotReversible = errors.New("reversals and corrections cannot be reversed")
+	// ErrPartialReversal is returned for a partial reversal of anything but a transfer
+	ErrPartialReversal = errors.New("only transfers can be partially reversed")
+	// ErrInvalidReversalAmount is returned for a partial reversal that is not positive,
+	// has too many decimal places or exceeds the transfer
+	ErrInvalidReversalAmount = errors.New("reversal amount must be positive and no more than the transfer")
+	// ErrRefunded is returned for a reversal or correction of a transaction that was
+	// refunded, in part or in whole, which would pay the refunded money back twice
+	ErrRefunded = errors.New("transaction has been refunded and can no longer be reversed")
+	// ErrPaidOut is returned for a reversal or correction of a withdrawal that paid out to
+	// a bank account or refunded a deposit; only the payout or refund failing credits it
+	// back, and it would be credited twice
+	ErrPaidOut = errors.New("transaction was paid out and can no longer be reversed")
+)
+
+// GetTransactionJournal returns the journal that transactionID, one of its ledger
+// entries, belongs to
+func (s *WalletService) GetTransactionJournal(ctx context.Context, transactionID uuid.UUID) (*models.Journal, error) {
+	journal, err := s.LedgerRepo.GetJournalByEntryID(ctx, transactionID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrTransactionNotFound
+		}
+		return nil, fmt.Errorf("failed to get transaction: %w", err)
+	}
+	return journal, nil
+}
+
+// ReverseTransaction undoes the journal that transactionID belongs to by posting each of
+// its legs again in the opposite direction, as a reversal journal that references it.
+// A transfer can be reversed in part by passing amount, in the currency it was sent in;
+// legs in the recipient's currency are scaled at the transfer's rate. Without amount
+// the whole journal is reversed. A journal can be reversed once, in full or in part,
+// and neither a deposit that was refunded, the debit of a payout or deposit refund nor
+// a transfer awaiting acceptance at all. Every wallet involved must be active, and the wallets paying the
+// money back need it available.
+func (s *WalletService) ReverseTransaction(ctx context.Context, transactionID uuid.UUID, amount *money.Money, reason string) (*models.Journal, error) {
+	original, err := s.GetTransactionJournal(ctx, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	if original.Type == models.JournalTypeReversal || original.Type == models.JournalTypeCorrection {
+		return nil, ErrNotReversible
+	}
+
+	entries, err := reversalEntries(original, amount)
+	if err != nil {
+		return nil, err
+	}
+	var description *string
+	if reason != "" {
+		description = &reason
+	}
+	reversal := newJournal(models.JournalTypeReversal, description, nil, entries...)
+	reversal.ReversesJournalID = &original.ID
+
+	err = s.withTx(ctx, "reverse transaction", func(ctx context.Context, tx *sql.Tx) error {
+		if err := s.applyReversal(ctx, tx, reversal); err != nil {
+			return err
+		}
+		if err := s.checkReversible(ctx, tx, original); err != nil {
+			return err
+		}
+		return s.recordJournal(ctx, tx, reversal)
+	})
+	if errors.Is(err, repository.ErrDuplicate) {
+		// The unique key on the reversed journal catches concurrent reversals too
+		return nil, ErrAlreadyReversed
+	}
+	if err != nil {
+		return nil, err
+	}
+	return reversal, nil
+}
+
+// checkReversible refuses to undo original when a deposit refund already paid part of
+// it back, a refund that failed and was credited back aside, when it is a merchant
+// payment the merchant refunded any of, when it debited a payout or a deposit refund,
+// which failing credits back, or when it is a transfer still waiting for its recipient
+// to accept it, which rejecting or expiring returns. It runs once applyReversal has
+// locked the wallets the reversal touches, the wallets a refund or an incoming transfer
+// locks among them, so neither can commit in between.
+func (s *WalletService) checkReversible(ctx context.Context, tx *sql.Tx, original *models.Journal) error {
+	if original.Type == models.JournalTypeTransfer && s.IncomingTransfers != nil {
+		awaiting, err := s.IncomingTransfers.IsAwaitingAcceptanceWithTx(ctx, tx, original.ID)
+		if err != nil {
+			return err
+		}
+		if awaiting {
+			return ErrTransferAwaitingAcceptance
+		}
+	}
+	if original.Type == models.JournalTypeTransfer {
+		return s.checkPaymentNotRefunded(ctx, tx, original.ID)
+	}
+	if original.Type == models.JournalTypeWithdraw {
+		return s.checkNotPaidOut(ctx, tx, original.ID)
+	}
+	if original.Type != models.JournalTypeDeposit || s.DepositRefunds == nil {
+		return nil
+	}
+	refunds, err := s.DepositRefunds.ListDepositRefundsWithTx(ctx, tx, original.ID)
+	if err != nil {
+		return fmt.Errorf("failed to list deposit refunds: %w", err)
+	}
+	for _, refund := range refunds {
+		if refund.Status != models.DepositRefundStatusFailed {
+			return ErrRefunded
+		}
+	}
+	return nil
+}
+
+// checkPaymentNotRefunded returns ErrRefunded when journalID paid a merchant that has
+// refunded any of the payment since. Journals that are not payments pass.
+func (s *WalletService) checkPaymentNotRefunded(ctx context.Context, tx *sql.Tx, journalID uuid.UUID) error {
+	if s.Payments == nil {
+		return nil
+	}
+	payment, err := s.Payments.GetPaymentByJournalIDWithTx(ctx, tx, journalID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if payment.RefundedAmount.IsPositive() {
+		return ErrRefunded
+	}
+	return nil
+}
+
+// checkNotPaidOut returns ErrPaidOut when journalID debited the wallet for a payout or
+// a deposit refund. Other withdrawals pass.
+func (s *WalletService) checkNotPaidOut(ctx context.Context, tx *sql.Tx, journalID uuid.UUID) error {
+	if s.Payouts != nil {
+		_, err := s.Payouts.GetPayoutByJournalIDWithTx(ctx, tx, journalID)
+		if err == nil {
+			return ErrPaidOut
+		}
+		if !errors.Is(err, repository.ErrNotFound) {
+			return fmt.Errorf("failed to get payout: %w", err)
+		}
+	}
+	if s.DepositRefunds != nil {
+		_, err := s.DepositRefunds.GetDepositRefundByJournalIDWithTx(ctx, tx, journalID)
+		if err == nil {
+			return ErrPaidOut
+		}
+		if !errors.Is(err, repository.ErrNotFound) {
+			return fmt.Errorf("failed to get deposit refund: %w", err)
+		}
+	}
+	return nil
+}
+
+// reversalEntries flips the legs of original. With amount set, original must be a
+// transfer, and its legs are scaled down to amount of what was sent or its conversion.
+func reversalEntries(original *models.Journal, amount *money.Money) ([]*models.LedgerEntry, error) {
+	var part, converted *money.Money
+	if amount != nil {
+		transfer, ok := models.NewTransfer(original)
+		if !ok {
+			return nil, ErrPartialReversal
+		}
+		sent := money.New(transfer.Amount, transfer.Currency)
+		requested := *amount
+		if !requested.SameCurrency(sent) {
+			return nil, fmt.Errorf("invalid reversal: %w", money.ErrCurrencyMismatch)
+		}
+		if cmp, _ := requested.Cmp(sent); !requested.IsPositive() || !requested.HasValidPrecision() || cmp > 0 {
+			return nil, ErrInvalidReversalAmount
+		}
+		if !requested.Equal(sent) {
+			part = &requested
+			if transfer.ExchangeRate != nil {
+				c := fx.Convert(requested, *transfer.ExchangeRate, *transfer.CreditedCurrency)
+				if !c.IsPositive() {
+					return nil, ErrInvalidReversalAmount
+				}
+				converted = &c
+			}
+		}
+	}
+
+	entries := make([]*models.LedgerEntry, 0, len(original.Entries))
+	for _, leg := range original.Entries {
+		entry := &models.LedgerEntry{
+			WalletID:        leg.WalletID,
+			Direction:       models.EntryDirectionCredit,
+			Amount:          leg.Amount,
+			Currency:        leg.Currency,
+			ExchangeRate:    leg.ExchangeRate,
+			CounterAmount:   leg.CounterAmount,
+			CounterCurrency: leg.CounterCurrency,
+		}
+		if leg.Direction == models.EntryDirectionCredit {
+			entry.Direction = models.EntryDirectionDebit
+		}
+
+		// Every leg of a transfer moves either what was sent or its conversion
+		if part != nil {
+			own, counter := part, converted
+			if leg.Currency != part.Currency() {
+				own, counter = converted, part
+			}
+			entry.Amount = own.Amount()
+			if counter != nil {
+				counterAmount := counter.Amount()
+				entry.CounterAmount = &counterAmount
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// applyReversal locks every wallet the reversal touches and applies its net effect on
+// each, checking the wallets it takes money from can afford it. Corrections, like
+// adjustments, may move money on frozen wallets but not on closed ones.
+func (s *WalletService) applyReversal(ctx context.Context, tx *sql.Tx, reversal *models.Journal) error {
+	changes := make(map[uuid.UUID]money.Money)
+	var walletIDs []uuid.UUID
+	for _, entry := range reversal.Entries {
+		if entry.WalletID == nil {
+			continue
+		}
+		change := money.New(entry.SignedAmount(), entry.Currency)
+		if total, ok := changes[*entry.WalletID]; ok {
+			sum, err := total.Add(change)
+			if err != nil {
+				return fmt.Errorf("invalid reversal: %w", err)
+			}
+			change = sum
+		} else {
+			walletIDs = append(walletIDs, *entry.WalletID)
+		}
+		changes[*entry.WalletID] = change
+	}
+
+	for _, id := range lockOrder(walletIDs...) {
+		wallet, err := s.getWalletForUpdate(ctx, tx, id)
+		if err != nil {
+			return fmt.Errorf("failed to get wallet: %w", err)
+		}
+		if reversal.Type == models.JournalTypeCorrection {
+			if wallet.Status == models.WalletStatusClosed {
+				return models.ErrWalletClosed
+			}
+		} else if err := wallet.CheckActive(); err != nil {
+			return err
+		}
+
+		// Treasury and suspense wallets may go below zero
+		change := changes[id]
+		if change.IsNegative() && !wallet.IsSettlement() {
+			cmp, err := wallet.Available().Cmp(change.Neg())
+			if err != nil {
+				return fmt.Errorf("invalid reversal: %w", err)
+			}
+			if cmp < 0 {
+				return ErrInsufficientBalance
+			}
+		}
+
+		newBalance, err := wallet.Funds().Add(change)
+		if err != nil {
+			return fmt.Errorf("invalid reversal: %w", err)
+		}
+		if err := s.setBalance(ctx, tx, wallet, newBalance.Amount()); err != nil {
+			return fmt.Errorf("failed to update wallet balance: %w", err)
+		}
+	}
+	return nil
+}
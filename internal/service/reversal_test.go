@@ -0,0 +1,240 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository"
+	"github.com/shanwije/wallet-app/internal/repository/mocks"
+	"github.com/shanwije/wallet-app/internal/testutil"
+	"github.com/shanwije/wallet-app/pkg/money"
+)
+
+// recordedJournal builds a journal as the ledger returns it, with IDs on every leg
+func recordedJournal(journalType string, entries ...*models.LedgerEntry) *models.Journal {
+	journal := newJournal(journalType, nil, nil, entries...)
+	journal.ID = uuid.New()
+	for _, entry := range entries {
+		entry.ID = uuid.New()
+		entry.JournalID = journal.ID
+	}
+	return journal
+}
+
+// expectReversal stubs the transaction a reversal runs in and captures its journal
+func expectReversal(walletRepo *mocks.WalletRepository, ledgerRepo *mocks.LedgerRepository, journal **models.Journal) {
+	walletRepo.On("BeginTx", mock.Anything).Return((*sql.Tx)(nil), nil)
+	ledgerRepo.On("CreateJournalWithTx", mock.Anything, (*sql.Tx)(nil), mock.AnythingOfType("*models.Journal")).
+		Run(func(args mock.Arguments) { *journal = args.Get(2).(*models.Journal) }).
+		Return(nil)
+}
+
+func TestReverseDepositInFull(t *testing.T) {
+	service, walletRepo, ledgerRepo := setupWalletService()
+
+	walletID := uuid.New()
+	deposit := recordedJournal(models.JournalTypeDeposit,
+		debit(nil, testutil.USD(decimal.NewFromInt(40))),
+		credit(&walletID, testutil.USD(decimal.NewFromInt(40))),
+	)
+	ledgerRepo.On("GetJournalByEntryID", mock.Anything, deposit.Entries[1].ID).Return(deposit, nil)
+	walletRepo.On("GetWalletByIDWithTx", mock.Anything, (*sql.Tx)(nil), walletID).Return(testutil.Wallet(walletID, 100.0), nil)
+	walletRepo.On("UpdateBalanceWithTx", mock.Anything, (*sql.Tx)(nil), walletID, mock.MatchedBy(decimal.NewFromInt(60).Equal), mock.Anything).Return(nil)
+	var journal *models.Journal
+	expectReversal(walletRepo, ledgerRepo, &journal)
+
+	reversal, err := service.ReverseTransaction(context.Background(), deposit.Entries[1].ID, nil, "Card payment charged back")
+
+	require.NoError(t, err)
+	assert.Same(t, journal, reversal)
+	assert.Equal(t, models.JournalTypeReversal, reversal.Type)
+	assert.Equal(t, deposit.ID, *reversal.ReversesJournalID)
+	assert.Equal(t, "Card payment charged back", *reversal.Description)
+	require.Len(t, reversal.Entries, 2)
+	assert.Equal(t, models.EntryDirectionCredit, reversal.Entries[0].Direction)
+	assert.Nil(t, reversal.Entries[0].WalletID)
+	assert.Equal(t, models.EntryDirectionDebit, reversal.Entries[1].Direction)
+	assert.True(t, reversal.Entries[1].Amount.Equal(decimal.NewFromInt(40)))
+	assert.NoError(t, reversal.Validate())
+}
+
+func TestReverseTransferInPart(t *testing.T) {
+	service, walletRepo, ledgerRepo := setupWalletService()
+
+	fromWalletID, toWalletID := uuid.New(), uuid.New()
+	transfer := recordedJournal(models.JournalTypeTransfer,
+		debit(&fromWalletID, testutil.USD(decimal.NewFromInt(30))),
+		credit(&toWalletID, testutil.USD(decimal.NewFromInt(30))),
+	)
+	ledgerRepo.On("GetJournalByEntryID", mock.Anything, transfer.Entries[0].ID).Return(transfer, nil)
+	walletRepo.On("GetWalletByIDWithTx", mock.Anything, (*sql.Tx)(nil), fromWalletID).Return(testutil.Wallet(fromWalletID, 70.0), nil)
+	walletRepo.On("GetWalletByIDWithTx", mock.Anything, (*sql.Tx)(nil), toWalletID).Return(testutil.Wallet(toWalletID, 30.0), nil)
+	walletRepo.On("UpdateBalanceWithTx", mock.Anything, (*sql.Tx)(nil), fromWalletID, mock.MatchedBy(decimal.NewFromInt(80).Equal), mock.Anything).Return(nil)
+	walletRepo.On("UpdateBalanceWithTx", mock.Anything, (*sql.Tx)(nil), toWalletID, mock.MatchedBy(decimal.NewFromInt(20).Equal), mock.Anything).Return(nil)
+	var journal *models.Journal
+	expectReversal(walletRepo, ledgerRepo, &journal)
+
+	part := testutil.USD(decimal.NewFromInt(10))
+	reversal, err := service.ReverseTransaction(context.Background(), transfer.Entries[0].ID, &part, "")
+
+	require.NoError(t, err)
+	assert.Nil(t, reversal.Description)
+	assert.Equal(t, transfer.ID, *reversal.ReversesJournalID)
+	for _, entry := range reversal.Entries {
+		assert.True(t, entry.Amount.Equal(decimal.NewFromInt(10)))
+	}
+	walletRepo.AssertExpectations(t)
+}
+
+func TestReverseConvertedTransferInPart(t *testing.T) {
+	fromWalletID, toWalletID := uuid.New(), uuid.New()
+	rate := decimal.RequireFromString("0.9")
+	sent, credited := testutil.USD(decimal.NewFromInt(100)), money.New(decimal.NewFromInt(90), money.EUR)
+	transfer := recordedJournal(models.JournalTypeTransfer,
+		converting(debit(&fromWalletID, sent), rate, credited),
+		converting(credit(&toWalletID, credited), rate, sent),
+		converting(credit(nil, sent), rate, credited),
+		converting(debit(nil, credited), rate, sent),
+	)
+
+	part := testutil.USD(decimal.NewFromInt(50))
+	entries, err := reversalEntries(transfer, &part)
+
+	require.NoError(t, err)
+	require.Len(t, entries, 4)
+	// The recipient returns the conversion of the reversed part at the transfer's rate
+	assert.Equal(t, toWalletID, *entries[1].WalletID)
+	assert.Equal(t, models.EntryDirectionDebit, entries[1].Direction)
+	assert.True(t, entries[1].Amount.Equal(decimal.NewFromInt(45)))
+	assert.True(t, entries[1].CounterAmount.Equal(decimal.NewFromInt(50)))
+	assert.True(t, entries[0].Amount.Equal(decimal.NewFromInt(50)))
+	assert.True(t, entries[0].CounterAmount.Equal(decimal.NewFromInt(45)))
+	assert.NoError(t, (&models.Journal{Entries: entries}).Validate())
+}
+
+func TestReverseTransactionRejections(t *testing.T) {
+	walletID, otherWalletID := uuid.New(), uuid.New()
+	deposit := recordedJournal(models.JournalTypeDeposit,
+		debit(nil, testutil.USD(decimal.NewFromInt(40))),
+		credit(&walletID, testutil.USD(decimal.NewFromInt(40))),
+	)
+	transfer := recordedJournal(models.JournalTypeTransfer,
+		debit(&otherWalletID, testutil.USD(decimal.NewFromInt(30))),
+		credit(&walletID, testutil.USD(decimal.NewFromInt(30))),
+	)
+	reversal := recordedJournal(models.JournalTypeReversal,
+		debit(&walletID, testutil.USD(decimal.NewFromInt(40))),
+		credit(nil, testutil.USD(decimal.NewFromInt(40))),
+	)
+	missingID := uuid.New()
+
+	tests := []struct {
+		name    string
+		journal *models.Journal
+		amount  *money.Money
+		want    error
+	}{
+		{name: "Reversal", journal: reversal, want: ErrNotReversible},
+		{name: "Partial deposit", journal: deposit, amount: ptr(testutil.USD(decimal.NewFromInt(10))), want: ErrPartialReversal},
+		{name: "More than the transfer", journal: transfer, amount: ptr(testutil.USD(decimal.NewFromInt(31))), want: ErrInvalidReversalAmount},
+		{name: "Negative amount", journal: transfer, amount: ptr(testutil.USD(decimal.NewFromInt(-5))), want: ErrInvalidReversalAmount},
+		{name: "Other currency", journal: transfer, amount: ptr(money.New(decimal.NewFromInt(5), money.EUR)), want: money.ErrCurrencyMismatch},
+		{name: "Unknown transaction", want: ErrTransactionNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, walletRepo, ledgerRepo := setupWalletService()
+			transactionID := missingID
+			if tt.journal != nil {
+				transactionID = tt.journal.Entries[0].ID
+				ledgerRepo.On("GetJournalByEntryID", mock.Anything, transactionID).Return(tt.journal, nil)
+			} else {
+				ledgerRepo.On("GetJournalByEntryID", mock.Anything, transactionID).Return(nil, fmt.Errorf("ledger entry %w", repository.ErrNotFound))
+			}
+
+			_, err := service.ReverseTransaction(context.Background(), transactionID, tt.amount, "")
+
+			assert.ErrorIs(t, err, tt.want)
+			walletRepo.AssertNotCalled(t, "BeginTx", mock.Anything)
+		})
+	}
+}
+
+func TestReverseTransactionOnlyOnce(t *testing.T) {
+	service, walletRepo, ledgerRepo := setupWalletService()
+
+	walletID := uuid.New()
+	withdrawal := recordedJournal(models.JournalTypeWithdraw,
+		debit(&walletID, testutil.USD(decimal.NewFromInt(25))),
+		credit(nil, testutil.USD(decimal.NewFromInt(25))),
+	)
+	ledgerRepo.On("GetJournalByEntryID", mock.Anything, withdrawal.Entries[0].ID).Return(withdrawal, nil)
+	walletRepo.On("BeginTx", mock.Anything).Return((*sql.Tx)(nil), nil)
+	walletRepo.On("GetWalletByIDWithTx", mock.Anything, (*sql.Tx)(nil), walletID).Return(testutil.Wallet(walletID, 0), nil)
+	walletRepo.On("UpdateBalanceWithTx", mock.Anything, (*sql.Tx)(nil), walletID, mock.Anything, mock.Anything).Return(nil)
+	ledgerRepo.On("CreateJournalWithTx", mock.Anything, (*sql.Tx)(nil), mock.AnythingOfType("*models.Journal")).
+		Return(fmt.Errorf("journal reversal %w", repository.ErrDuplicate))
+
+	_, err := service.ReverseTransaction(context.Background(), withdrawal.Entries[0].ID, nil, "")
+
+	assert.ErrorIs(t, err, ErrAlreadyReversed)
+}
+
+func TestReverseTransactionRefusesAPayoutsDebit(t *testing.T) {
+	service, walletRepo, ledgerRepo := setupWalletService()
+	payoutRepo := new(mocks.PayoutRepository)
+	service.Payouts = payoutRepo
+
+	walletID := uuid.New()
+	withdrawal := recordedJournal(models.JournalTypeWithdraw,
+		debit(&walletID, testutil.USD(decimal.NewFromInt(25))),
+		credit(nil, testutil.USD(decimal.NewFromInt(25))),
+	)
+	ledgerRepo.On("GetJournalByEntryID", mock.Anything, withdrawal.Entries[0].ID).Return(withdrawal, nil)
+	walletRepo.On("BeginTx", mock.Anything).Return((*sql.Tx)(nil), nil)
+	walletRepo.On("GetWalletByIDWithTx", mock.Anything, (*sql.Tx)(nil), walletID).Return(testutil.Wallet(walletID, 0), nil)
+	walletRepo.On("UpdateBalanceWithTx", mock.Anything, (*sql.Tx)(nil), walletID, mock.Anything, mock.Anything).Return(nil)
+	payoutRepo.On("GetPayoutByJournalIDWithTx", mock.Anything, (*sql.Tx)(nil), withdrawal.ID).
+		Return(&models.Payout{ID: uuid.New(), JournalID: withdrawal.ID, Status: models.PayoutStatusSent}, nil)
+
+	_, err := service.ReverseTransaction(context.Background(), withdrawal.Entries[0].ID, nil, "")
+	assert.ErrorIs(t, err, ErrPaidOut)
+
+	_, err = service.CorrectTransaction(context.Background(), withdrawal.Entries[0].ID, nil, models.CorrectionReasonDuplicate, "")
+	assert.ErrorIs(t, err, ErrPaidOut)
+	ledgerRepo.AssertNotCalled(t, "CreateJournalWithTx", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestReverseTransactionNeedsAvailableBalance(t *testing.T) {
+	service, walletRepo, ledgerRepo := setupWalletService()
+
+	fromWalletID, toWalletID := uuid.New(), uuid.New()
+	transfer := recordedJournal(models.JournalTypeTransfer,
+		debit(&fromWalletID, testutil.USD(decimal.NewFromInt(30))),
+		credit(&toWalletID, testutil.USD(decimal.NewFromInt(30))),
+	)
+	ledgerRepo.On("GetJournalByEntryID", mock.Anything, transfer.Entries[1].ID).Return(transfer, nil)
+	walletRepo.On("BeginTx", mock.Anything).Return((*sql.Tx)(nil), nil)
+	walletRepo.On("GetWalletByIDWithTx", mock.Anything, (*sql.Tx)(nil), fromWalletID).Return(testutil.Wallet(fromWalletID, 70.0), nil)
+	walletRepo.On("GetWalletByIDWithTx", mock.Anything, (*sql.Tx)(nil), toWalletID).Return(createHeldWallet(toWalletID, 30.0, 20.0), nil)
+	walletRepo.On("UpdateBalanceWithTx", mock.Anything, (*sql.Tx)(nil), mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	_, err := service.ReverseTransaction(context.Background(), transfer.Entries[1].ID, nil, "")
+
+	assert.ErrorIs(t, err, ErrInsufficientBalance)
+	ledgerRepo.AssertNotCalled(t, "CreateJournalWithTx", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
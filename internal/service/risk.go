@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository"
+	"github.com/shanwije/wallet-app/internal/risk"
+	"github.com/shanwije/wallet-app/pkg/logger"
+	"github.com/shanwije/wallet-app/pkg/money"
+	"github.com/shanwije/wallet-app/pkg/pagination"
+)
+
+// ErrBlockedByRiskCheck is returned when the risk engine refuses a withdrawal or transfer
+var ErrBlockedByRiskCheck = errors.New("operation blocked by risk checks")
+
+// assessRisk runs an outgoing movement past the risk engine before it executes.
+// Flagged and blocked movements are recorded for review; blocked ones fail with
+// ErrBlockedByRiskCheck. Nothing is checked when the service has no engine.
+func (s *WalletService) assessRisk(ctx context.Context, journalType string, walletID uuid.UUID, toWalletID *uuid.UUID, amount money.Money) error {
+	if s.Risk == nil {
+		return nil
+	}
+
+	decision, err := s.Risk.Assess(ctx, risk.Operation{
+		Type:       journalType,
+		WalletID:   walletID,
+		ToWalletID: toWalletID,
+		Amount:     amount,
+		At:         s.now(),
+	})
+	if err != nil {
+		return fmt.Errorf("risk assessment failed: %w", err)
+	}
+	if decision.Action == models.RiskActionAllow {
+		return nil
+	}
+
+	reasons := strings.Join(decision.Reasons, "; ")
+	record := &models.RiskDecision{
+		WalletID:             walletID,
+		Operation:            journalType,
+		CounterpartyWalletID: toWalletID,
+		Amount:               amount.Amount(),
+		Currency:             amount.Currency(),
+		Action:               decision.Action,
+		Reasons:              reasons,
+		CreatedAt:            s.now(),
+	}
+	if err := s.RiskRepo.CreateRiskDecision(ctx, record); err != nil {
+		return fmt.Errorf("failed to record risk decision: %w", err)
+	}
+
+	logger.FromContext(ctx).Warn("Risk checks fired",
+		zap.String("wallet_id", walletID.String()),
+		zap.String("operation", journalType),
+		zap.String("action", decision.Action),
+		zap.String("reasons", reasons))
+
+	if decision.Action == models.RiskActionBlock {
+		return fmt.Errorf("%w: %s", ErrBlockedByRiskCheck, reasons)
+	}
+	return nil
+}
+
+// ListRiskDecisions pages through flagged and blocked operations, newest first
+func (s *WalletService) ListRiskDecisions(ctx context.Context, filter repository.RiskDecisionFilter) ([]*models.RiskDecision, error) {
+	filter.Limit = pagination.ClampLimit(filter.Limit)
+	filter.Offset = max(filter.Offset, 0)
+
+	decisions, err := s.RiskRepo.ListRiskDecisions(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list risk decisions: %w", err)
+	}
+	return decisions, nil
+}
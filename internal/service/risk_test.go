@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository/mocks"
+	"github.com/shanwije/wallet-app/internal/risk"
+	"github.com/shanwije/wallet-app/internal/testutil"
+)
+
+// stubEngine returns the same decision for every operation
+type stubEngine risk.Decision
+
+func (e stubEngine) Assess(ctx context.Context, op risk.Operation) (risk.Decision, error) {
+	return risk.Decision(e), nil
+}
+
+func TestWithdrawBlockedByRiskCheckIsRecorded(t *testing.T) {
+	service, walletRepo, _ := setupWalletService()
+	riskRepo := new(mocks.RiskRepository)
+	service.Risk = stubEngine{Action: models.RiskActionBlock, Reasons: []string{"11 withdraw operations in the last 1m0s"}}
+	service.RiskRepo = riskRepo
+
+	walletID := uuid.New()
+	var recorded *models.RiskDecision
+	riskRepo.On("CreateRiskDecision", mock.Anything, mock.AnythingOfType("*models.RiskDecision")).
+		Run(func(args mock.Arguments) { recorded = args.Get(1).(*models.RiskDecision) }).
+		Return(nil)
+
+	_, err := service.Withdraw(context.Background(), walletID, testutil.USD(decimal.NewFromInt(40)), "")
+
+	assert.ErrorIs(t, err, ErrBlockedByRiskCheck)
+	require.NotNil(t, recorded)
+	assert.Equal(t, walletID, recorded.WalletID)
+	assert.Equal(t, models.JournalTypeWithdraw, recorded.Operation)
+	assert.Equal(t, models.RiskActionBlock, recorded.Action)
+	assert.Equal(t, "11 withdraw operations in the last 1m0s", recorded.Reasons)
+	walletRepo.AssertNotCalled(t, "BeginTx", mock.Anything)
+}
+
+func TestTransferFlaggedByRiskCheckStillRuns(t *testing.T) {
+	service, walletRepo, _ := setupWalletService()
+	riskRepo := new(mocks.RiskRepository)
+	service.Risk = stubEngine{Action: models.RiskActionFlag, Reasons: []string{"first transfer to this recipient"}}
+	service.RiskRepo = riskRepo
+
+	fromID, toID := uuid.New(), uuid.New()
+	riskRepo.On("CreateRiskDecision", mock.Anything, mock.MatchedBy(func(d *models.RiskDecision) bool {
+		return d.Action == models.RiskActionFlag && d.CounterpartyWalletID != nil && *d.CounterpartyWalletID == toID
+	})).Return(nil)
+	walletRepo.On("BeginTx", mock.Anything).Return((*sql.Tx)(nil), assert.AnError)
+
+	err := service.Transfer(context.Background(), fromID, toID, testutil.USD(decimal.NewFromInt(5)), "", "")
+
+	assert.NotErrorIs(t, err, ErrBlockedByRiskCheck)
+	riskRepo.AssertExpectations(t)
+	walletRepo.AssertCalled(t, "BeginTx", mock.Anything)
+}
+
+func TestPaymentBlockedByRiskCheckIsRecorded(t *testing.T) {
+	wallets, walletRepo, _ := setupWalletService()
+	riskRepo := new(mocks.RiskRepository)
+	paymentRepo := new(mocks.PaymentRepository)
+	wallets.Risk = stubEngine{Action: models.RiskActionBlock, Reasons: []string{"first transfer to this recipient"}}
+	wallets.RiskRepo = riskRepo
+	service := &PaymentService{Repo: paymentRepo, Wallets: wallets}
+
+	payerID, merchantID := uuid.New(), uuid.New()
+	paymentRepo.On("GetMerchantAccount", mock.Anything, merchantID).
+		Return(&models.MerchantAccount{WalletID: merchantID, Name: "Corner Books"}, nil)
+	walletRepo.On("GetWalletByID", mock.Anything, merchantID).Return(testutil.Wallet(merchantID, 0), nil)
+	riskRepo.On("CreateRiskDecision", mock.Anything, mock.MatchedBy(func(d *models.RiskDecision) bool {
+		return d.Operation == models.JournalTypeTransfer && d.CounterpartyWalletID != nil && *d.CounterpartyWalletID == merchantID
+	})).Return(nil)
+
+	_, err := service.Pay(context.Background(), payerID, merchantID, testutil.USD(decimal.NewFromInt(40)), "ORD-1")
+
+	assert.ErrorIs(t, err, ErrBlockedByRiskCheck)
+	riskRepo.AssertExpectations(t)
+	walletRepo.AssertNotCalled(t, "BeginTx", mock.Anything)
+}
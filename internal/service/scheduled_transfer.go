@@ -0,0 +1,244 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository"
+	"github.com/shanwije/wallet-app/pkg/clock"
+	"github.com/shanwije/wallet-app/pkg/logger"
+	"github.com/shanwije/wallet-app/pkg/money"
+)
+
+var (
+	// ErrInvalidSchedule is returned for a schedule that can never run as requested
+	ErrInvalidSchedule = errors.New("invalid schedule")
+	// ErrScheduledTransferNotFound is returned when the wallet has no scheduled transfer with the ID
+	ErrScheduledTransferNotFound = errors.New("scheduled transfer not found")
+	// ErrScheduledTransferNotActive is returned when cancelling a transfer that has already finished
+	ErrScheduledTransferNotActive = errors.New("scheduled transfer is no longer active")
+)
+
+const (
+	// scheduleClockSkew lets a start time slightly in the past through, for clients whose clocks run behind
+	scheduleClockSkew = time.Minute
+	// scheduledBatchSize bounds how many due transfers one RunDue call processes
+	scheduledBatchSize = 100
+)
+
+// ScheduledTransferService stores future and recurring transfers and runs them when due
+type ScheduledTransferService struct {
+	Repo    repository.ScheduledTransferRepository
+	Wallets *WalletService
+	// Clock is optional; the system clock is used when nil
+	Clock clock.Clock
+}
+
+// now returns the current time from the injected clock
+func (s *ScheduledTransferService) now() time.Time {
+	return clock.OrDefault(s.Clock).Now()
+}
+
+// Schedule stores a transfer to run at startAt, or now when startAt is zero, and then
+// at every following occurrence for recurring frequencies
+func (s *ScheduledTransferService) Schedule(ctx context.Context, fromWalletID, toWalletID uuid.UUID, amount money.Money, description string, startAt time.Time, frequency string) (*models.ScheduledTransfer, error) {
+	now := s.now()
+	if startAt.IsZero() {
+		startAt = now
+	}
+
+	if err := s.Wallets.validateTransferAmount(amount, fromWalletID, toWalletID); err != nil {
+		return nil, err
+	}
+	// Runs nobody is there to confirm would all be refused
+	if err := s.Wallets.checkConfirmation(amount); err != nil {
+		return nil, err
+	}
+	if !models.IsValidFrequency(frequency) {
+		return nil, fmt.Errorf("%w: frequency must be once, daily, weekly or monthly", ErrInvalidSchedule)
+	}
+	if startAt.Before(now.Add(-scheduleClockSkew)) {
+		return nil, fmt.Errorf("%w: start time is in the past", ErrInvalidSchedule)
+	}
+
+	// Catch a wrong destination now rather than at the first run
+	destination, err := s.Wallets.GetBalance(ctx, toWalletID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get destination wallet: %w", err)
+	}
+	if !destination.Funds().SameCurrency(amount) {
+		return nil, fmt.Errorf("invalid transfer: %w", money.ErrCurrencyMismatch)
+	}
+
+	transfer := &models.ScheduledTransfer{
+		FromWalletID: fromWalletID,
+		ToWalletID:   toWalletID,
+		Amount:       amount.Amount(),
+		Currency:     amount.Currency(),
+		Frequency:    frequency,
+		StartAt:      startAt,
+		NextRunAt:    startAt,
+		Status:       models.ScheduleStatusActive,
+		CreatedAt:    now,
+	}
+	if description != "" {
+		transfer.Description = &description
+	}
+
+	if err := s.Repo.CreateScheduledTransfer(ctx, transfer); err != nil {
+		return nil, fmt.Errorf("failed to schedule transfer: %w", err)
+	}
+
+	return transfer, nil
+}
+
+// List returns the scheduled transfers paying out of the wallet
+func (s *ScheduledTransferService) List(ctx context.Context, walletID uuid.UUID) ([]*models.ScheduledTransfer, error) {
+	transfers, err := s.Repo.ListScheduledTransfersByWalletID(ctx, walletID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list scheduled transfers: %w", err)
+	}
+
+	return transfers, nil
+}
+
+// Cancel stops an active scheduled transfer of the wallet from running again
+func (s *ScheduledTransferService) Cancel(ctx context.Context, walletID, transferID uuid.UUID) (*models.ScheduledTransfer, error) {
+	transfer, err := s.Repo.GetScheduledTransfer(ctx, transferID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrScheduledTransferNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Other wallets' transfers are reported as missing rather than forbidden
+	if transfer.FromWalletID != walletID {
+		return nil, ErrScheduledTransferNotFound
+	}
+	if transfer.Status != models.ScheduleStatusActive {
+		return nil, ErrScheduledTransferNotActive
+	}
+
+	now := s.now()
+	if err := s.Repo.CancelScheduledTransfer(ctx, transferID, now); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			// It finished or was cancelled since we read it
+			return nil, ErrScheduledTransferNotActive
+		}
+		return nil, err
+	}
+
+	transfer.Status = models.ScheduleStatusCancelled
+	transfer.UpdatedAt = now
+	return transfer, nil
+}
+
+// Run processes due transfers every interval until ctx is cancelled
+func (s *ScheduledTransferService) Run(ctx context.Context, interval time.Duration) {
+	log := logger.FromContext(ctx)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		processed, err := s.RunDue(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Error("Scheduled transfer run failed", zap.Error(err))
+		} else if processed > 0 {
+			log.Info("Processed scheduled transfers", zap.Int("count", processed))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunDue runs every transfer that is due and returns how many were processed.
+//
+// Each run goes through Transfer with an idempotency key naming the transfer and
+// occurrence, and the outcome is recorded only if no other worker got there first.
+// A worker that crashes between the two steps therefore leaves the run to be
+// replayed, not repeated, on the next pass.
+func (s *ScheduledTransferService) RunDue(ctx context.Context) (int, error) {
+	now := s.now()
+
+	due, err := s.Repo.ListDueScheduledTransfers(ctx, now, scheduledBatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list due transfers: %w", err)
+	}
+
+	processed := 0
+	for _, transfer := range due {
+		if err := ctx.Err(); err != nil {
+			return processed, err
+		}
+		if err := s.runOnce(ctx, transfer, now); err != nil {
+			return processed, err
+		}
+		processed++
+	}
+
+	return processed, nil
+}
+
+// runOnce executes the transfer's current occurrence and schedules the next one.
+// Occurrences missed while no worker was running are skipped rather than run in a burst.
+func (s *ScheduledTransferService) runOnce(ctx context.Context, transfer *models.ScheduledTransfer, now time.Time) error {
+	log := logger.FromContext(ctx).With(
+		zap.String("scheduled_transfer_id", transfer.ID.String()),
+		zap.Int("occurrence", transfer.Occurrence))
+
+	var description string
+	if transfer.Description != nil {
+		description = *transfer.Description
+	}
+	previous := transfer.Occurrence
+	key := fmt.Sprintf("scheduled:%s:%d", transfer.ID, previous)
+
+	runErr := s.Wallets.Transfer(ctx, transfer.FromWalletID, transfer.ToWalletID, transfer.Funds(), description, key)
+	if runErr != nil && ctx.Err() != nil {
+		// Shutting down; leave the occurrence for the next pass
+		return ctx.Err()
+	}
+
+	transfer.LastError = nil
+	if runErr != nil {
+		message := runErr.Error()
+		transfer.LastError = &message
+		log.Warn("Scheduled transfer failed", zap.Error(runErr))
+	}
+
+	transfer.Occurrence++
+	transfer.UpdatedAt = now
+	switch {
+	case !transfer.IsRecurring() && runErr != nil:
+		transfer.Status = models.ScheduleStatusFailed
+	case !transfer.IsRecurring():
+		transfer.Status = models.ScheduleStatusCompleted
+	default:
+		transfer.NextRunAt = models.OccurrenceAt(transfer.StartAt, transfer.Frequency, transfer.Occurrence)
+		for !transfer.NextRunAt.After(now) {
+			transfer.Occurrence++
+			transfer.NextRunAt = models.OccurrenceAt(transfer.StartAt, transfer.Frequency, transfer.Occurrence)
+		}
+	}
+
+	recorded, err := s.Repo.RecordScheduledTransferRun(ctx, transfer, previous)
+	if err != nil {
+		return err
+	}
+	if !recorded {
+		log.Info("Scheduled transfer run already recorded by another worker")
+	}
+	return nil
+}
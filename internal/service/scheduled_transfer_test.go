@@ -0,0 +1,200 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository"
+	"github.com/shanwije/wallet-app/internal/repository/mocks"
+	"github.com/shanwije/wallet-app/internal/testutil"
+	"github.com/shanwije/wallet-app/pkg/clock"
+	"github.com/shanwije/wallet-app/pkg/money"
+)
+
+var scheduleNow = time.Date(2024, 6, 11, 9, 0, 0, 0, time.UTC)
+
+// setupScheduledTransferService creates a scheduler over a mocked wallet service
+func setupScheduledTransferService() (*ScheduledTransferService, *mocks.ScheduledTransferRepository, *mocks.WalletRepository, *mocks.LedgerRepository) {
+	walletService, walletRepo, ledgerRepo := setupWalletService()
+	clk := clock.NewFake(scheduleNow)
+	walletService.Clock = clk
+
+	repo := new(mocks.ScheduledTransferRepository)
+	service := &ScheduledTransferService{Repo: repo, Wallets: walletService, Clock: clk}
+	return service, repo, walletRepo, ledgerRepo
+}
+
+// expectTransfer sets up the wallet mocks for one successful transfer of 40 USD
+func expectTransfer(walletRepo *mocks.WalletRepository, ledgerRepo *mocks.LedgerRepository, fromWalletID, toWalletID uuid.UUID) {
+	ledgerRepo.On("GetJournalByIdempotencyKey", mock.Anything, mock.Anything).Return(nil, repository.ErrNotFound)
+	walletRepo.On("BeginTx", mock.Anything).Return((*sql.Tx)(nil), nil)
+	walletRepo.On("GetWalletByIDWithTx", mock.Anything, (*sql.Tx)(nil), fromWalletID).Return(testutil.Wallet(fromWalletID, 100.0), nil)
+	walletRepo.On("GetWalletByIDWithTx", mock.Anything, (*sql.Tx)(nil), toWalletID).Return(testutil.Wallet(toWalletID, 0), nil)
+	walletRepo.On("UpdateBalanceWithTx", mock.Anything, (*sql.Tx)(nil), mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	ledgerRepo.On("CreateJournalWithTx", mock.Anything, (*sql.Tx)(nil), mock.AnythingOfType("*models.Journal")).Return(nil)
+}
+
+func TestScheduleTransfer(t *testing.T) {
+	service, repo, walletRepo, _ := setupScheduledTransferService()
+
+	fromWalletID := uuid.New()
+	toWalletID := uuid.New()
+	startAt := scheduleNow.Add(24 * time.Hour)
+
+	walletRepo.On("GetWalletByID", mock.Anything, toWalletID).Return(testutil.Wallet(toWalletID, 0), nil)
+	repo.On("CreateScheduledTransfer", mock.Anything, mock.AnythingOfType("*models.ScheduledTransfer")).Return(nil)
+
+	transfer, err := service.Schedule(context.Background(), fromWalletID, toWalletID,
+		testutil.USD(decimal.NewFromInt(40)), "Rent", startAt, models.FrequencyMonthly)
+
+	assert.NoError(t, err)
+	assert.Equal(t, startAt, transfer.NextRunAt)
+	assert.Equal(t, models.ScheduleStatusActive, transfer.Status)
+	assert.Equal(t, "Rent", *transfer.Description)
+	repo.AssertExpectations(t)
+}
+
+func TestScheduleTransferValidation(t *testing.T) {
+	fromWalletID := uuid.New()
+	toWalletID := uuid.New()
+
+	tests := []struct {
+		name      string
+		to        uuid.UUID
+		amount    money.Money
+		startAt   time.Time
+		frequency string
+	}{
+		{name: "same wallet", to: fromWalletID, amount: testutil.USD(decimal.NewFromInt(10)), frequency: models.FrequencyOnce},
+		{name: "non-positive amount", to: toWalletID, amount: testutil.USD(decimal.Zero), frequency: models.FrequencyOnce},
+		{name: "unknown frequency", to: toWalletID, amount: testutil.USD(decimal.NewFromInt(10)), frequency: "hourly"},
+		{name: "start in the past", to: toWalletID, amount: testutil.USD(decimal.NewFromInt(10)), startAt: scheduleNow.Add(-time.Hour), frequency: models.FrequencyOnce},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, repo, _, _ := setupScheduledTransferService()
+
+			_, err := service.Schedule(context.Background(), fromWalletID, tt.to, tt.amount, "", tt.startAt, tt.frequency)
+
+			assert.Error(t, err)
+			repo.AssertNotCalled(t, "CreateScheduledTransfer", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestRunDueCompletesOneTimeTransfer(t *testing.T) {
+	service, repo, walletRepo, ledgerRepo := setupScheduledTransferService()
+
+	fromWalletID := uuid.New()
+	toWalletID := uuid.New()
+	transfer := &models.ScheduledTransfer{
+		ID:           uuid.New(),
+		FromWalletID: fromWalletID,
+		ToWalletID:   toWalletID,
+		Amount:       decimal.NewFromInt(40),
+		Currency:     money.USD,
+		Frequency:    models.FrequencyOnce,
+		StartAt:      scheduleNow.Add(-time.Minute),
+		NextRunAt:    scheduleNow.Add(-time.Minute),
+		Status:       models.ScheduleStatusActive,
+	}
+
+	repo.On("ListDueScheduledTransfers", mock.Anything, scheduleNow, scheduledBatchSize).Return([]*models.ScheduledTransfer{transfer}, nil)
+	expectTransfer(walletRepo, ledgerRepo, fromWalletID, toWalletID)
+	repo.On("RecordScheduledTransferRun", mock.Anything, transfer, 0).Return(true, nil)
+
+	processed, err := service.RunDue(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, processed)
+	assert.Equal(t, models.ScheduleStatusCompleted, transfer.Status)
+	assert.Equal(t, 1, transfer.Occurrence)
+	assert.Nil(t, transfer.LastError)
+	ledgerRepo.AssertCalled(t, "GetJournalByIdempotencyKey", mock.Anything,
+		fromWalletID.String()+":scheduled:"+transfer.ID.String()+":0")
+}
+
+func TestRunDueKeepsRecurringTransferAfterFailure(t *testing.T) {
+	service, repo, walletRepo, ledgerRepo := setupScheduledTransferService()
+
+	fromWalletID := uuid.New()
+	toWalletID := uuid.New()
+	startAt := scheduleNow.AddDate(0, 0, -7)
+	transfer := &models.ScheduledTransfer{
+		ID:           uuid.New(),
+		FromWalletID: fromWalletID,
+		ToWalletID:   toWalletID,
+		Amount:       decimal.NewFromInt(500),
+		Currency:     money.USD,
+		Frequency:    models.FrequencyDaily,
+		StartAt:      startAt,
+		NextRunAt:    startAt,
+		Status:       models.ScheduleStatusActive,
+	}
+
+	repo.On("ListDueScheduledTransfers", mock.Anything, scheduleNow, scheduledBatchSize).Return([]*models.ScheduledTransfer{transfer}, nil)
+	ledgerRepo.On("GetJournalByIdempotencyKey", mock.Anything, mock.Anything).Return(nil, repository.ErrNotFound)
+	walletRepo.On("BeginTx", mock.Anything).Return((*sql.Tx)(nil), nil)
+	walletRepo.On("GetWalletByIDWithTx", mock.Anything, (*sql.Tx)(nil), fromWalletID).Return(testutil.Wallet(fromWalletID, 100.0), nil)
+	walletRepo.On("GetWalletByIDWithTx", mock.Anything, (*sql.Tx)(nil), toWalletID).Return(testutil.Wallet(toWalletID, 0), nil)
+	repo.On("RecordScheduledTransferRun", mock.Anything, transfer, 0).Return(true, nil)
+
+	_, err := service.RunDue(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.ScheduleStatusActive, transfer.Status)
+	assert.Contains(t, *transfer.LastError, "insufficient balance")
+	// The missed week is skipped: the next run is tomorrow, not yesterday
+	assert.Equal(t, startAt.AddDate(0, 0, 8), transfer.NextRunAt)
+	assert.Equal(t, 8, transfer.Occurrence)
+}
+
+func TestCancelScheduledTransfer(t *testing.T) {
+	walletID := uuid.New()
+	active := func() *models.ScheduledTransfer {
+		return &models.ScheduledTransfer{ID: uuid.New(), FromWalletID: walletID, Status: models.ScheduleStatusActive}
+	}
+
+	t.Run("cancels an active transfer", func(t *testing.T) {
+		service, repo, _, _ := setupScheduledTransferService()
+		transfer := active()
+		repo.On("GetScheduledTransfer", mock.Anything, transfer.ID).Return(transfer, nil)
+		repo.On("CancelScheduledTransfer", mock.Anything, transfer.ID, scheduleNow).Return(nil)
+
+		cancelled, err := service.Cancel(context.Background(), walletID, transfer.ID)
+
+		assert.NoError(t, err)
+		assert.Equal(t, models.ScheduleStatusCancelled, cancelled.Status)
+	})
+
+	t.Run("another wallet's transfer", func(t *testing.T) {
+		service, repo, _, _ := setupScheduledTransferService()
+		transfer := active()
+		repo.On("GetScheduledTransfer", mock.Anything, transfer.ID).Return(transfer, nil)
+
+		_, err := service.Cancel(context.Background(), uuid.New(), transfer.ID)
+
+		assert.ErrorIs(t, err, ErrScheduledTransferNotFound)
+		repo.AssertNotCalled(t, "CancelScheduledTransfer", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("already completed", func(t *testing.T) {
+		service, repo, _, _ := setupScheduledTransferService()
+		transfer := active()
+		transfer.Status = models.ScheduleStatusCompleted
+		repo.On("GetScheduledTransfer", mock.Anything, transfer.ID).Return(transfer, nil)
+
+		_, err := service.Cancel(context.Background(), walletID, transfer.ID)
+
+		assert.ErrorIs(t, err, ErrScheduledTransferNotActive)
+	})
+}
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shanwije/wallet-app/internal/models"
+)
+
+// ErrInvalidStatementPeriod is returned when a statement period ends before it starts
+var ErrInvalidStatementPeriod = errors.New("statement period must end after it starts")
+
+// StatementWriter renders a statement as it is produced, so long statements never
+// have to be held in memory
+type StatementWriter interface {
+	// Begin is called once the opening balance is known, before any line
+	Begin(statement *models.Statement) error
+	// Line is called for each transaction in the period, oldest first
+	Line(line *models.StatementLine) error
+	// End is called after the last line with the closing balance filled in
+	End(statement *models.Statement) error
+}
+
+// WriteStatement renders the wallet's transactions created in [from, to) to w, with
+// the balance before the first and after the last. A zero from starts the statement
+// when the wallet was opened; a zero to ends it now.
+func (s *WalletService) WriteStatement(ctx context.Context, walletID uuid.UUID, from, to time.Time, w StatementWriter) error {
+	wallet, err := s.WalletRepo.GetWalletByID(ctx, walletID)
+	if err != nil {
+		return fmt.Errorf("failed to get wallet: %w", walletLookupError(err))
+	}
+
+	if from.IsZero() {
+		from = wallet.CreatedAt
+	}
+	if to.IsZero() {
+		to = s.now()
+	}
+	if !to.After(from) {
+		return ErrInvalidStatementPeriod
+	}
+
+	opening, err := s.LedgerRepo.GetWalletLedgerBalanceBefore(ctx, walletID, from)
+	if err != nil {
+		return fmt.Errorf("failed to get opening balance: %w", err)
+	}
+
+	statement := &models.Statement{
+		WalletID:       walletID,
+		Currency:       wallet.Currency,
+		From:           from,
+		To:             to,
+		OpeningBalance: opening,
+	}
+	if err := w.Begin(statement); err != nil {
+		return err
+	}
+
+	balance := opening
+	err = s.LedgerRepo.StreamTransactionsByWalletID(ctx, walletID, from, to, func(transaction *models.Transaction) error {
+		balance = balance.Add(transaction.SignedAmount())
+		return w.Line(&models.StatementLine{Transaction: transaction, Balance: balance})
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write statement: %w", err)
+	}
+
+	statement.ClosingBalance = balance
+	return w.End(statement)
+}
+
+// CSVStatementWriter writes a statement as CSV: a row for the opening balance, one for
+// each transaction with the balance after it, and one for the closing balance. Rows go
+// out through a small buffer as they are produced.
+type CSVStatementWriter struct {
+	csv       *csv.Writer
+	statement *models.Statement
+	// Lines counts the transactions written
+	Lines int64
+}
+
+// NewCSVStatementWriter returns a writer of statements to w
+func NewCSVStatementWriter(w io.Writer) *CSVStatementWriter {
+	return &CSVStatementWriter{csv: csv.NewWriter(w)}
+}
+
+func (c *CSVStatementWriter) Begin(statement *models.Statement) error {
+	c.statement = statement
+	c.csv.Write([]string{"date", "type", "description", "reference_id", "amount", "balance"})
+	c.csv.Write([]string{statement.From.UTC().Format(time.RFC3339), "opening_balance", "", "", "",
+		statement.FormatAmount(statement.OpeningBalance)})
+	return c.csv.Error()
+}
+
+func (c *CSVStatementWriter) Line(line *models.StatementLine) error {
+	transaction := line.Transaction
+	var description, reference string
+	if transaction.Description != nil {
+		description = *transaction.Description
+	}
+	if transaction.ReferenceID != nil {
+		reference = transaction.ReferenceID.String()
+	}
+
+	c.csv.Write([]string{
+		transaction.CreatedAt.UTC().Format(time.RFC3339),
+		transaction.Type,
+		description,
+		reference,
+		c.statement.FormatAmount(transaction.SignedAmount()),
+		c.statement.FormatAmount(line.Balance),
+	})
+	c.Lines++
+	return c.csv.Error()
+}
+
+func (c *CSVStatementWriter) End(statement *models.Statement) error {
+	c.csv.Write([]string{statement.To.UTC().Format(time.RFC3339), "closing_balance", "", "", "",
+		statement.FormatAmount(statement.ClosingBalance)})
+	c.csv.Flush()
+	return c.csv.Error()
+}
@@ -0,0 +1,161 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository"
+	"github.com/shanwije/wallet-app/pkg/money"
+)
+
+// Sweep transfers everything the wallet can spend to another wallet, for instance
+// before it is closed. The amount is worked out once both wallets are locked, so a
+// movement landing between the client reading the balance and the sweep running is
+// swept too rather than left behind or failing the sweep. Held funds stay in the wallet
+// and a sweep never takes it into its overdraft. The transfer fee comes out of what is
+// swept, and like any other transfer a sweep over the confirmation threshold is refused.
+//
+// A non-empty idempotencyKey makes retries return the sweep first recorded under it,
+// even though the wallet no longer has anything to sweep.
+func (s *WalletService) Sweep(ctx context.Context, fromWalletID, toWalletID uuid.UUID, description, idempotencyKey string) (*TransferResult, error) {
+	if fromWalletID == toWalletID {
+		return nil, ErrSameWallet
+	}
+	key := scopedIdempotencyKey(fromWalletID, idempotencyKey)
+	if key != nil {
+		if result, err := s.recordedSweep(ctx, fromWalletID, toWalletID, *key); err != nil || result != nil {
+			return result, err
+		}
+	}
+
+	// The risk rules see the available balance, which is at least what is swept
+	wallet, err := s.GetBalance(ctx, fromWalletID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.assessRisk(ctx, models.JournalTypeTransfer, fromWalletID, &toWalletID, wallet.Available()); err != nil {
+		return nil, err
+	}
+	// Whether the fee wallet takes a fee does not depend on the amount, which is only
+	// known once it is locked too
+	fee, err := s.movementFee(ctx, models.JournalTypeTransfer, fromWalletID, wallet.Available())
+	if err != nil {
+		return nil, err
+	}
+	charged := fee.IsPositive()
+
+	var result *TransferResult
+	err = s.withTx(ctx, "sweep", func(ctx context.Context, tx *sql.Tx) error {
+		amount, fee, err := s.sweepAmount(ctx, tx, fromWalletID, toWalletID, charged)
+		if err != nil {
+			return err
+		}
+
+		journal := newJournal(models.JournalTypeTransfer, &description, key,
+			debit(&fromWalletID, amount),
+			credit(&toWalletID, amount),
+		)
+		from, to, err := s.transferExecution(ctx, tx, fromWalletID, toWalletID, amount, fee, journal)
+		if err != nil {
+			return err
+		}
+		if err := s.awaitAcceptance(ctx, tx, to, journal); err != nil {
+			return err
+		}
+		transfer, _ := models.NewTransfer(journal)
+		result = &TransferResult{Transfer: transfer, FromWallet: from, ToWallet: to, Fee: fee}
+		return nil
+	})
+	if errors.Is(err, repository.ErrDuplicate) && key != nil {
+		// A retry with the same key committed first
+		if result, lookupErr := s.recordedSweep(ctx, fromWalletID, toWalletID, *key); lookupErr != nil || result != nil {
+			return result, lookupErr
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// sweepAmount locks both wallets, and the fee wallet when charged, in the order
+// transfers lock them. It returns what the source wallet can spend without going below
+// zero, less the fee on it when charged, and that fee. Like any transfer the amount
+// must be within the allowed range and the confirmation threshold.
+func (s *WalletService) sweepAmount(ctx context.Context, tx *sql.Tx, fromWalletID, toWalletID uuid.UUID, charged bool) (money.Money, money.Money, error) {
+	ids := []uuid.UUID{fromWalletID, toWalletID}
+	if charged {
+		ids = append(ids, s.FeeWalletID)
+	}
+	var from *models.Wallet
+	for _, id := range lockOrder(ids...) {
+		wallet, err := s.getWalletForUpdate(ctx, tx, id)
+		if err != nil {
+			switch id {
+			case fromWalletID:
+				return money.Money{}, money.Money{}, fmt.Errorf("failed to get source wallet: %w", err)
+			case toWalletID:
+				return money.Money{}, money.Money{}, fmt.Errorf("failed to get destination wallet: %w", err)
+			default:
+				return money.Money{}, money.Money{}, fmt.Errorf("failed to get fee wallet: %w", err)
+			}
+		}
+		if id == fromWalletID {
+			from = wallet
+		}
+	}
+
+	amount, err := s.spendable(ctx, tx, from)
+	if err != nil {
+		return money.Money{}, money.Money{}, err
+	}
+	if available := from.Available(); amount.Amount().GreaterThan(available.Amount()) {
+		amount = available
+	}
+	fee := noFee
+	if charged {
+		amount = s.Fees.Net(models.JournalTypeTransfer, amount)
+		fee = s.Fees.Fee(models.JournalTypeTransfer, amount)
+	}
+	if !amount.IsPositive() {
+		return money.Money{}, money.Money{}, fmt.Errorf("%w: nothing to sweep", ErrInsufficientBalance)
+	}
+	if err := s.checkAmountRange(amount); err != nil {
+		return money.Money{}, money.Money{}, err
+	}
+	if err := s.checkConfirmation(amount); err != nil {
+		return money.Money{}, money.Money{}, err
+	}
+	return amount, fee, nil
+}
+
+// recordedSweep returns the transfer recorded under the idempotency key, with the
+// wallets as they are now, or nil when there is none. A key used for anything but a
+// transfer between the same wallets is ErrIdempotencyKeyReused.
+func (s *WalletService) recordedSweep(ctx context.Context, fromWalletID, toWalletID uuid.UUID, key string) (*TransferResult, error) {
+	journal, err := s.LedgerRepo.GetJournalByIdempotencyKey(ctx, key)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up idempotency key: %w", err)
+	}
+
+	transfer, ok := models.NewTransfer(journal)
+	if !ok || transfer.FromWalletID != fromWalletID || transfer.ToWalletID != toWalletID {
+		return nil, ErrIdempotencyKeyReused
+	}
+	result := &TransferResult{Transfer: transfer}
+	if result.FromWallet, err = s.GetBalance(ctx, fromWalletID); err != nil {
+		return nil, err
+	}
+	if result.ToWallet, err = s.GetBalance(ctx, toWalletID); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
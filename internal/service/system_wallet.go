@@ -0,0 +1,148 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository"
+	"github.com/shanwije/wallet-app/pkg/money"
+)
+
+var (
+	// ErrInvalidSystemWalletKind is returned for a system wallet kind other than
+	// treasury, suspense or fees
+	ErrInvalidSystemWalletKind = errors.New("system wallet kind must be treasury, suspense or fees")
+	// ErrSettlementWallet is returned when a deposit, withdrawal, transfer or adjustment
+	// names a treasury or suspense wallet, which only move money as the other side of
+	// other wallets' movements
+	ErrSettlementWallet = errors.New("treasury and suspense wallets cannot be moved directly")
+)
+
+// SystemWalletService keeps the wallets the platform holds for itself: one treasury,
+// suspense and fees wallet per currency at most, each created on first use
+type SystemWalletService struct {
+	Repo repository.SystemWalletRepository
+
+	// ids caches the ID of every system wallet looked up, by systemWalletKey; a system
+	// wallet is never replaced, so entries never go stale
+	ids sync.Map
+}
+
+type systemWalletKey struct {
+	kind     string
+	currency money.Currency
+}
+
+// EnsureWallet returns the system wallet of kind in currency, creating it when there is
+// none yet
+func (s *SystemWalletService) EnsureWallet(ctx context.Context, kind string, currency money.Currency) (*models.Wallet, error) {
+	if !models.IsValidSystemWalletKind(kind) {
+		return nil, ErrInvalidSystemWalletKind
+	}
+	if !currency.IsValid() {
+		return nil, fmt.Errorf("%w: %q", money.ErrUnknownCurrency, currency)
+	}
+
+	wallet, err := s.Repo.EnsureSystemWallet(ctx, kind, currency)
+	if err != nil {
+		return nil, err
+	}
+	s.ids.Store(systemWalletKey{kind: kind, currency: currency}, wallet.ID)
+	return wallet, nil
+}
+
+// WalletID returns the ID of the system wallet of kind in currency, creating the wallet
+// when there is none yet. Only the first call for each goes to the database.
+func (s *SystemWalletService) WalletID(ctx context.Context, kind string, currency money.Currency) (uuid.UUID, error) {
+	if id, ok := s.ids.Load(systemWalletKey{kind: kind, currency: currency}); ok {
+		return id.(uuid.UUID), nil
+	}
+	wallet, err := s.EnsureWallet(ctx, kind, currency)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return wallet.ID, nil
+}
+
+// isSystemWallet reports whether id is a system wallet the service has looked up. It is
+// false for every wallet on a nil service.
+func (s *SystemWalletService) isSystemWallet(id uuid.UUID) bool {
+	if s == nil {
+		return false
+	}
+	found := false
+	s.ids.Range(func(_, value any) bool {
+		found = value.(uuid.UUID) == id
+		return !found
+	})
+	return found
+}
+
+// ListWallets returns every system wallet by kind and currency
+func (s *SystemWalletService) ListWallets(ctx context.Context) ([]*models.Wallet, error) {
+	wallets, err := s.Repo.ListSystemWallets(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list system wallets: %w", err)
+	}
+	return wallets, nil
+}
+
+// settlementWallet returns the ID of the system wallet of kind in currency a movement
+// settles against, or nil when the service keeps no system wallets and the movement
+// settles against the external settlement account. It may create the wallet, so it is
+// called before the movement's transaction begins.
+func (s *WalletService) settlementWallet(ctx context.Context, kind string, currency money.Currency) (*uuid.UUID, error) {
+	if s.SystemWallets == nil {
+		return nil, nil
+	}
+	id, err := s.SystemWallets.WalletID(ctx, kind, currency)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s wallet: %w", kind, err)
+	}
+	return &id, nil
+}
+
+// walletSettlement is settlementWallet in the currency of the wallet
+func (s *WalletService) walletSettlement(ctx context.Context, walletID uuid.UUID, kind string) (*uuid.UUID, error) {
+	if s.SystemWallets == nil {
+		return nil, nil
+	}
+	wallet, err := s.WalletRepo.GetWalletByID(ctx, walletID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get wallet: %w", walletLookupError(err))
+	}
+	return s.settlementWallet(ctx, kind, wallet.Currency)
+}
+
+// settle applies the legs journal posts to the system wallet systemID to its balance,
+// locking it in tx. System wallets are locked after the wallet the movement is for,
+// which must not be a treasury or suspense wallet itself. Nothing is settled when
+// systemID is nil.
+func (s *WalletService) settle(ctx context.Context, tx *sql.Tx, wallet *models.Wallet, journal *models.Journal, systemID *uuid.UUID) error {
+	if wallet.IsSettlement() {
+		return ErrSettlementWallet
+	}
+	if systemID == nil {
+		return nil
+	}
+
+	system, err := s.getWalletForUpdate(ctx, tx, *systemID)
+	if err != nil {
+		return fmt.Errorf("failed to get system wallet: %w", err)
+	}
+	balance := system.Balance
+	for _, entry := range journal.Entries {
+		if entry.WalletID != nil && *entry.WalletID == system.ID {
+			balance = balance.Add(entry.SignedAmount())
+		}
+	}
+	if err := s.setBalance(ctx, tx, system, balance); err != nil {
+		return fmt.Errorf("failed to update %s wallet balance: %w", system.Kind, err)
+	}
+	return nil
+}
@@ -0,0 +1,267 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository"
+	"github.com/shanwije/wallet-app/pkg/auth"
+	"github.com/shanwije/wallet-app/pkg/clock"
+	"github.com/shanwije/wallet-app/pkg/money"
+)
+
+var (
+	// ErrInvalidPINFormat is returned when setting a PIN that is not 4 to 8 digits
+	ErrInvalidPINFormat = errors.New("PIN must be 4 to 8 digits")
+	// ErrPINRequired is returned for a withdrawal or transfer over the threshold by a
+	// user with a PIN who did not give it
+	ErrPINRequired = errors.New("transaction PIN required for this amount")
+	// ErrIncorrectPIN is returned for a wrong PIN, wrapped in an *IncorrectPINError
+	ErrIncorrectPIN = errors.New("incorrect transaction PIN")
+	// ErrPINLocked is returned while a PIN is locked after too many wrong attempts,
+	// wrapped in a *PINLockedError
+	ErrPINLocked = errors.New("transaction PIN locked after too many incorrect attempts")
+	// ErrPINNotSet is returned when removing the PIN of a user who has none
+	ErrPINNotSet = errors.New("no transaction PIN is set")
+)
+
+const (
+	// defaultMaxPINAttempts is how many wrong PINs in a row lock it when MaxAttempts is zero
+	defaultMaxPINAttempts = 5
+	// defaultPINLockout is how long a PIN stays locked when Lockout is zero
+	defaultPINLockout = 15 * time.Minute
+	// minPINLength and maxPINLength bound the digits of a PIN
+	minPINLength = 4
+	maxPINLength = 8
+)
+
+// IncorrectPINError reports a wrong PIN with the attempts left before it is locked
+type IncorrectPINError struct {
+	AttemptsLeft int
+}
+
+func (e *IncorrectPINError) Error() string {
+	return fmt.Sprintf("%s, %d attempts left", ErrIncorrectPIN, e.AttemptsLeft)
+}
+
+func (e *IncorrectPINError) Unwrap() error {
+	return ErrIncorrectPIN
+}
+
+// PINLockedError reports a locked PIN with when it unlocks
+type PINLockedError struct {
+	Until time.Time
+}
+
+func (e *PINLockedError) Error() string {
+	return fmt.Sprintf("%s until %s", ErrPINLocked, e.Until.UTC().Format(time.RFC3339))
+}
+
+func (e *PINLockedError) Unwrap() error {
+	return ErrPINLocked
+}
+
+// TransactionPINService keeps the PINs users can set to step up withdrawals and
+// transfers over a threshold. The PIN is optional: money leaves the wallet of a user
+// who set none without one. Too many wrong PINs in a row lock it for a while, during
+// which even the right one is refused.
+type TransactionPINService struct {
+	Repo       repository.TransactionPINRepository
+	UserRepo   repository.UserRepository
+	WalletRepo repository.WalletRepository
+	// Threshold is the amount, in the movement's currency, over which the PIN is asked
+	// for; nil never asks for it
+	Threshold *decimal.Decimal
+	// MaxAttempts is how many wrong PINs in a row lock it
+	MaxAttempts int
+	// Lockout is how long a PIN stays locked
+	Lockout time.Duration
+	// Clock is optional; the system clock is used when nil
+	Clock clock.Clock
+}
+
+// now returns the current time from the injected clock
+func (s *TransactionPINService) now() time.Time {
+	return clock.OrDefault(s.Clock).Now()
+}
+
+// GetStatus reports whether the user has set a PIN and until when it is locked
+func (s *TransactionPINService) GetStatus(ctx context.Context, userID uuid.UUID) (*models.TransactionPINStatus, error) {
+	if err := s.checkUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	pin, err := s.Repo.GetTransactionPIN(ctx, userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return &models.TransactionPINStatus{UserID: userID}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction PIN: %w", err)
+	}
+	return s.status(pin), nil
+}
+
+// SetPIN sets the user's PIN. Changing one already set takes the current PIN, checked
+// like any other, so a locked PIN cannot be changed until it unlocks.
+func (s *TransactionPINService) SetPIN(ctx context.Context, userID uuid.UUID, currentPIN, pin string) (*models.TransactionPINStatus, error) {
+	if !validPIN(pin) {
+		return nil, ErrInvalidPINFormat
+	}
+	if err := s.checkUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	existing, err := s.Repo.GetTransactionPIN(ctx, userID)
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+	case err != nil:
+		return nil, fmt.Errorf("failed to get transaction PIN: %w", err)
+	default:
+		if err := s.verify(ctx, existing, currentPIN); err != nil {
+			return nil, err
+		}
+	}
+
+	hash, err := auth.HashPIN(pin)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash PIN: %w", err)
+	}
+	stored := &models.TransactionPIN{UserID: userID, PINHash: hash, UpdatedAt: s.now()}
+	if err := s.Repo.SetTransactionPIN(ctx, stored); err != nil {
+		return nil, fmt.Errorf("failed to set transaction PIN: %w", err)
+	}
+	return s.status(stored), nil
+}
+
+// RemovePIN turns the user's PIN off, which takes the current PIN
+func (s *TransactionPINService) RemovePIN(ctx context.Context, userID uuid.UUID, currentPIN string) error {
+	if err := s.checkUser(ctx, userID); err != nil {
+		return err
+	}
+
+	existing, err := s.Repo.GetTransactionPIN(ctx, userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return ErrPINNotSet
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get transaction PIN: %w", err)
+	}
+	if err := s.verify(ctx, existing, currentPIN); err != nil {
+		return err
+	}
+
+	if err := s.Repo.DeleteTransactionPIN(ctx, userID); err != nil {
+		return fmt.Errorf("failed to delete transaction PIN: %w", err)
+	}
+	return nil
+}
+
+// RequiresPIN reports whether moving amount out of a wallet asks for its owner's PIN,
+// when they set one. It is false on a nil service.
+func (s *TransactionPINService) RequiresPIN(amount money.Money) bool {
+	return s != nil && s.Threshold != nil && amount.Amount().GreaterThan(*s.Threshold)
+}
+
+// Authorize checks pin before amount leaves the wallet. Amounts at or under the
+// threshold, and wallets whose owner set no PIN, need none. It does nothing on a nil
+// service.
+func (s *TransactionPINService) Authorize(ctx context.Context, walletID uuid.UUID, amount money.Money, pin string) error {
+	if !s.RequiresPIN(amount) {
+		return nil
+	}
+
+	wallet, err := s.WalletRepo.GetWalletByID(ctx, walletID)
+	if err != nil {
+		return fmt.Errorf("failed to get wallet: %w", walletLookupError(err))
+	}
+	stored, err := s.Repo.GetTransactionPIN(ctx, wallet.UserID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get transaction PIN: %w", err)
+	}
+	if pin == "" {
+		return ErrPINRequired
+	}
+	return s.verify(ctx, stored, pin)
+}
+
+// verify checks pin against the stored one. A wrong PIN is counted and the PIN locked
+// once MaxAttempts are counted in a row; the right one clears the count.
+func (s *TransactionPINService) verify(ctx context.Context, stored *models.TransactionPIN, pin string) error {
+	now := s.now()
+	if stored.LockedUntil != nil && now.Before(*stored.LockedUntil) {
+		return &PINLockedError{Until: *stored.LockedUntil}
+	}
+
+	if auth.CheckPassword(stored.PINHash, pin) {
+		if stored.FailedAttempts > 0 || stored.LockedUntil != nil {
+			if err := s.Repo.ResetPINAttempts(ctx, stored.UserID, nil, now); err != nil {
+				return fmt.Errorf("failed to reset PIN attempts: %w", err)
+			}
+		}
+		return nil
+	}
+
+	attempts, err := s.Repo.RecordFailedPINAttempt(ctx, stored.UserID, now)
+	if err != nil {
+		return fmt.Errorf("failed to record PIN attempt: %w", err)
+	}
+	maxAttempts := s.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = defaultMaxPINAttempts
+	}
+	if attempts < maxAttempts {
+		return &IncorrectPINError{AttemptsLeft: maxAttempts - attempts}
+	}
+
+	lockout := s.Lockout
+	if lockout <= 0 {
+		lockout = defaultPINLockout
+	}
+	until := now.Add(lockout)
+	if err := s.Repo.ResetPINAttempts(ctx, stored.UserID, &until, now); err != nil {
+		return fmt.Errorf("failed to lock PIN: %w", err)
+	}
+	return &PINLockedError{Until: until}
+}
+
+// status describes the stored PIN, leaving out a lock that has ended
+func (s *TransactionPINService) status(pin *models.TransactionPIN) *models.TransactionPINStatus {
+	status := &models.TransactionPINStatus{UserID: pin.UserID, Enabled: true}
+	if pin.LockedUntil != nil && s.now().Before(*pin.LockedUntil) {
+		status.LockedUntil = pin.LockedUntil
+	}
+	return status
+}
+
+// checkUser returns ErrUserNotFound unless the user exists
+func (s *TransactionPINService) checkUser(ctx context.Context, userID uuid.UUID) error {
+	if _, err := s.UserRepo.GetUserByID(ctx, userID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrUserNotFound
+		}
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	return nil
+}
+
+// validPIN reports whether pin is 4 to 8 digits
+func validPIN(pin string) bool {
+	if len(pin) < minPINLength || len(pin) > maxPINLength {
+		return false
+	}
+	for _, c := range pin {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,180 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository"
+	"github.com/shanwije/wallet-app/pkg/db"
+	"github.com/shanwije/wallet-app/pkg/logger"
+)
+
+// defaultTxTimeout bounds how long a money-movement transaction may run once it has
+// been detached from the caller's context, when the service sets no TxTimeout
+const defaultTxTimeout = 10 * time.Second
+
+// defaultTxRetries bounds how often withTx reruns a conflicting transaction when the
+// service sets no TxRetries
+const defaultTxRetries = 3
+
+// The wait before a rerun is drawn at random from zero up to txRetryBaseDelay, doubled
+// for every earlier rerun and capped at txRetryMaxDelay, so transactions that
+// conflicted with each other do not collide again in step
+const (
+	txRetryBaseDelay = 20 * time.Millisecond
+	txRetryMaxDelay  = time.Second
+)
+
+// withTx runs fn inside a database transaction and commits it if fn succeeds.
+//
+// A client disconnect cancels the request context, and database/sql rolls back
+// any transaction bound to a cancelled context - possibly between the balance
+// UPDATE and the COMMIT. To keep the outcome deterministic the transaction runs
+// on a context that ignores the caller's cancellation but is still bounded by
+// TxTimeout and by the request's own deadline, so a slow query cannot outlive
+// the request. Requests that are already cancelled are rejected before any work starts.
+//
+// A transaction that conflicts with a concurrent one is rolled back and run again from
+// the start after a jittered, exponentially growing wait, up to TxRetries times, so fn
+// must not depend on state left behind by an earlier attempt. Conflicts are lost
+// wallet version checks, and the serialization failures and deadlocks the database
+// aborts transactions with. Once the retries run out ErrConcurrentUpdate is returned.
+func (s *WalletService) withTx(ctx context.Context, operation string, fn func(ctx context.Context, tx *sql.Tx) error) error {
+	log := logger.FromContext(ctx).With(zap.String("operation", operation))
+
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("%s aborted before start: %w", operation, err)
+	}
+
+	retries := s.txRetries()
+	for attempt := 0; ; attempt++ {
+		err := s.runTx(ctx, log, fn)
+		if !errors.Is(err, repository.ErrVersionConflict) && !db.IsRetryable(err) {
+			return err
+		}
+		if attempt == retries {
+			return fmt.Errorf("%w after %d attempts: %w", ErrConcurrentUpdate, attempt+1, err)
+		}
+
+		delay := txRetryDelay(attempt)
+		log.Warn("Retrying conflicting transaction", zap.Error(err), zap.Int("attempt", attempt+1), zap.Duration("delay", delay))
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%s aborted while retrying: %w", operation, ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+// txRetries returns TxRetries, or defaultTxRetries when it is zero
+func (s *WalletService) txRetries() int {
+	if s.TxRetries <= 0 {
+		return defaultTxRetries
+	}
+	return s.TxRetries
+}
+
+// txRetryDelay returns the wait before rerunning a transaction that has already been
+// rerun attempt times
+func txRetryDelay(attempt int) time.Duration {
+	ceiling := txRetryMaxDelay
+	if attempt < 10 {
+		ceiling = min(txRetryBaseDelay<<attempt, txRetryMaxDelay)
+	}
+	return rand.N(ceiling) + 1
+}
+
+// runTx makes a single attempt at the transaction withTx describes
+func (s *WalletService) runTx(ctx context.Context, log *zap.Logger, fn func(ctx context.Context, tx *sql.Tx) error) error {
+	txCtx, cancel := s.txContext(ctx)
+	defer cancel()
+	txCtx, changes := withTxChanges(txCtx)
+
+	err := s.txManager().WithinTransaction(txCtx, func(ctx context.Context) error {
+		return fn(ctx, repository.TxFromContext(ctx))
+	})
+	if err != nil {
+		log.Info("Transaction rolled back", zap.Error(err))
+		return err
+	}
+
+	log.Info("Transaction committed")
+	if changes != nil {
+		s.invalidateWallets(txCtx, changes)
+		s.notifyBalances(txCtx, changes)
+	}
+	return nil
+}
+
+// txManager returns Tx, or a manager beginning transactions on WalletRepo when it is nil
+func (s *WalletService) txManager() repository.TxManager {
+	if s.Tx != nil {
+		return s.Tx
+	}
+	return repository.NewTxManager(s.WalletRepo)
+}
+
+// txContext detaches ctx from the caller's cancellation and gives it a deadline of
+// TxTimeout from now, or ctx's own deadline when that comes first
+func (s *WalletService) txContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	timeout := s.TxTimeout
+	if timeout <= 0 {
+		timeout = defaultTxTimeout
+	}
+	deadline := time.Now().Add(timeout)
+	if requestDeadline, ok := ctx.Deadline(); ok && requestDeadline.Before(deadline) {
+		deadline = requestDeadline
+	}
+	return context.WithDeadline(context.WithoutCancel(ctx), deadline)
+}
+
+type txChangesKey struct{}
+
+// txChanges collects the wallets a transaction updates and the journals it records,
+// for the work that waits until it commits: dropping the wallets from the cache and
+// telling subscribers about their new balances
+type txChanges struct {
+	mu       sync.Mutex
+	wallets  map[uuid.UUID]*models.Wallet
+	journals []*models.Journal
+}
+
+// withTxChanges returns ctx with a place to collect the transaction's changes. Inside
+// an enclosing transaction it returns ctx unchanged and nil, as the changes are only
+// final once the enclosing transaction commits.
+func withTxChanges(ctx context.Context) (context.Context, *txChanges) {
+	if _, ok := ctx.Value(txChangesKey{}).(*txChanges); ok {
+		return ctx, nil
+	}
+	changes := &txChanges{wallets: make(map[uuid.UUID]*models.Wallet)}
+	return context.WithValue(ctx, txChangesKey{}, changes), changes
+}
+
+// walletChanged records that the transaction running on ctx updated the wallet
+func walletChanged(ctx context.Context, wallet *models.Wallet) {
+	if changes, ok := ctx.Value(txChangesKey{}).(*txChanges); ok {
+		changes.mu.Lock()
+		changes.wallets[wallet.ID] = wallet
+		changes.mu.Unlock()
+	}
+}
+
+// journalRecorded records that the transaction running on ctx wrote the journal
+func journalRecorded(ctx context.Context, journal *models.Journal) {
+	if changes, ok := ctx.Value(txChangesKey{}).(*txChanges); ok {
+		changes.mu.Lock()
+		changes.journals = append(changes.journals, journal)
+		changes.mu.Unlock()
+	}
+}
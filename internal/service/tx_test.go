@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shanwije/wallet-app/internal/repository/mocks"
+)
+
+// passThroughTx runs each unit of work without a database transaction
+type passThroughTx struct {
+	calls int
+}
+
+func (m *passThroughTx) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	m.calls++
+	return fn(ctx)
+}
+
+func TestWithTxRunsInTheTxManager(t *testing.T) {
+	walletRepo := new(mocks.WalletRepository)
+	manager := &passThroughTx{}
+	service := &WalletService{WalletRepo: walletRepo, Tx: manager}
+
+	err := service.withTx(context.Background(), "test", func(ctx context.Context, tx *sql.Tx) error {
+		assert.Nil(t, tx)
+		return nil
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, manager.calls)
+	walletRepo.AssertNotCalled(t, "BeginTx", mock.Anything)
+}
+
+func TestTxContext(t *testing.T) {
+	t.Run("Ignores the caller's cancellation", func(t *testing.T) {
+		service := &WalletService{TxTimeout: time.Minute}
+		ctx, cancel := context.WithCancel(context.Background())
+
+		txCtx, txCancel := service.txContext(ctx)
+		defer txCancel()
+		cancel()
+
+		assert.NoError(t, txCtx.Err())
+		deadline, ok := txCtx.Deadline()
+		require.True(t, ok)
+		assert.WithinDuration(t, time.Now().Add(time.Minute), deadline, time.Second)
+	})
+
+	t.Run("Keeps an earlier request deadline", func(t *testing.T) {
+		service := &WalletService{TxTimeout: time.Minute}
+		requestDeadline := time.Now().Add(time.Second)
+		ctx, cancel := context.WithDeadline(context.Background(), requestDeadline)
+		defer cancel()
+
+		txCtx, txCancel := service.txContext(ctx)
+		defer txCancel()
+
+		deadline, ok := txCtx.Deadline()
+		require.True(t, ok)
+		assert.Equal(t, requestDeadline, deadline)
+	})
+
+	t.Run("Defaults when TxTimeout is zero", func(t *testing.T) {
+		service := &WalletService{}
+
+		txCtx, txCancel := service.txContext(context.Background())
+		defer txCancel()
+
+		deadline, ok := txCtx.Deadline()
+		require.True(t, ok)
+		assert.WithinDuration(t, time.Now().Add(defaultTxTimeout), deadline, time.Second)
+	})
+}
+
+func TestWithTxRetriesDeadlocks(t *testing.T) {
+	deadlock := fmt.Errorf("failed to update balance: %w", &pgconn.PgError{Code: "40P01"})
+
+	t.Run("Succeeds once the conflict clears", func(t *testing.T) {
+		manager := &passThroughTx{}
+		service := &WalletService{Tx: manager}
+
+		attempts := 0
+		err := service.withTx(context.Background(), "test", func(ctx context.Context, tx *sql.Tx) error {
+			attempts++
+			if attempts < 3 {
+				return deadlock
+			}
+			return nil
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, 3, manager.calls)
+	})
+
+	t.Run("Gives up after TxRetries reruns", func(t *testing.T) {
+		manager := &passThroughTx{}
+		service := &WalletService{Tx: manager, TxRetries: 2}
+
+		err := service.withTx(context.Background(), "test", func(ctx context.Context, tx *sql.Tx) error {
+			return deadlock
+		})
+
+		assert.ErrorIs(t, err, ErrConcurrentUpdate)
+		assert.ErrorContains(t, err, "after 3 attempts")
+		assert.Equal(t, 3, manager.calls)
+	})
+
+	t.Run("Does not rerun other failures", func(t *testing.T) {
+		manager := &passThroughTx{}
+		service := &WalletService{Tx: manager}
+
+		err := service.withTx(context.Background(), "test", func(ctx context.Context, tx *sql.Tx) error {
+			return &pgconn.PgError{Code: "23505"}
+		})
+
+		assert.Error(t, err)
+		assert.NotErrorIs(t, err, ErrConcurrentUpdate)
+		assert.Equal(t, 1, manager.calls)
+	})
+}
+
+func TestTxRetryDelay(t *testing.T) {
+	for attempt, ceiling := range []time.Duration{txRetryBaseDelay, 2 * txRetryBaseDelay, 4 * txRetryBaseDelay} {
+		for i := 0; i < 100; i++ {
+			delay := txRetryDelay(attempt)
+			assert.Positive(t, delay)
+			assert.LessOrEqual(t, delay, ceiling)
+		}
+	}
+	assert.LessOrEqual(t, txRetryDelay(62), txRetryMaxDelay)
+}
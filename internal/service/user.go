@@ -2,28 +2,83 @@ package service
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"net/mail"
+	"regexp"
+	"slices"
+	"strings"
 
 	"github.com/google/uuid"
 	"github.com/shanwije/wallet-app/internal/models"
 	"github.com/shanwije/wallet-app/internal/repository"
+	"github.com/shanwije/wallet-app/pkg/auth"
 )
 
+var (
+	// ErrInvalidCredentials is returned by Login for an unknown username or wrong password
+	ErrInvalidCredentials = errors.New("invalid username or password")
+	// ErrUsernameTaken is returned by Register when the username is already registered
+	ErrUsernameTaken = errors.New("username is already taken")
+	// ErrUserNotFound is returned when no user has the ID
+	ErrUserNotFound = errors.New("user not found")
+	// ErrUserHasNoWallet is returned by GetUserWallet for a user without a wallet
+	ErrUserHasNoWallet = errors.New("user has no wallet")
+	// ErrInvalidEmail is returned for an email that is not a bare address
+	ErrInvalidEmail = errors.New("email must be an address such as alice@example.com")
+	// ErrInvalidPhone is returned for a phone number not in E.164 format
+	ErrInvalidPhone = errors.New("phone must be in E.164 format, such as +447700900123")
+	// ErrEmailTaken is returned when another user has the email
+	ErrEmailTaken = errors.New("email is already in use")
+	// ErrPhoneTaken is returned when another user has the phone number
+	ErrPhoneTaken = errors.New("phone is already in use")
+	// ErrUserVersionMismatch is returned by UpdateUser when the user is not at a version
+	// the caller expected, because it changed after the caller read it
+	ErrUserVersionMismatch = errors.New("user has changed since it was read")
+)
+
+// e164 matches a phone number in E.164 format: a + and up to 15 digits, the first of
+// them the country code
+var e164 = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+
+// UserUpdate holds the profile fields to change; nil fields are left as they are and
+// an empty email or phone removes it
+type UserUpdate struct {
+	Name  *string
+	Email *string
+	Phone *string
+}
+
 type UserService struct {
-	UserRepo   repository.UserRepository
-	WalletRepo repository.WalletRepository
+	UserRepo       repository.UserRepository
+	WalletRepo     repository.WalletRepository
+	CredentialRepo repository.CredentialRepository
+	// Wallets closes a deleted user's wallet
+	Wallets *WalletService
 }
 
 func (s *UserService) CreateUser(ctx context.Context, name string) (*models.UserWithWallet, error) {
+	return s.CreateUserWithContact(ctx, name, models.Contact{})
+}
+
+// CreateUserWithContact creates a user with a wallet, reachable at the email and phone
+// of contact when they are set. It fails with ErrInvalidEmail or ErrInvalidPhone for
+// malformed ones and ErrEmailTaken or ErrPhoneTaken for ones another user has.
+func (s *UserService) CreateUserWithContact(ctx context.Context, name string, contact models.Contact) (*models.UserWithWallet, error) {
 	// Validate input
 	if name == "" {
 		return nil, fmt.Errorf("name cannot be empty")
 	}
+	contact, err := normalizeContact(contact)
+	if err != nil {
+		return nil, err
+	}
 
 	// Create user
-	user, err := s.UserRepo.CreateUser(ctx, name)
+	user, err := s.UserRepo.CreateUser(ctx, name, contact)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create user: %w", err)
+		return nil, contactError(err, "failed to create user")
 	}
 
 	// Create wallet for the user
@@ -32,21 +87,221 @@ func (s *UserService) CreateUser(ctx context.Context, name string) (*models.User
 		return nil, fmt.Errorf("failed to create wallet for user: %w", err)
 	}
 
-	// Return user with wallet
+	return newUserWithWallet(user, wallet), nil
+}
+
+// newUserWithWallet returns the user together with their wallet
+func newUserWithWallet(user *models.User, wallet *models.Wallet) *models.UserWithWallet {
 	return &models.UserWithWallet{
-		ID:   user.ID,
-		Name: user.Name,
+		ID:      user.ID,
+		Name:    user.Name,
+		Contact: user.Contact,
+		Version: user.Version,
 
 		Wallet:    *wallet,
 		CreatedAt: user.CreatedAt,
-	}, nil
+	}
 }
 
+// GetUserWithWallet returns the user and their wallet, which is zero-valued if they have none
 func (s *UserService) GetUserWithWallet(ctx context.Context, id uuid.UUID) (*models.UserWithWallet, error) {
 	userWithWallet, err := s.UserRepo.GetUserWithWallet(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrUserNotFound
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user with wallet: %w", err)
 	}
 
 	return userWithWallet, nil
 }
+
+// UpdateUser applies update to the user's profile and returns the updated user. With
+// expectedVersions the user must still be at one of them, as a client that edited the
+// profile it read requires, or ErrUserVersionMismatch is returned; without them the
+// update is applied to whatever the profile holds by then.
+func (s *UserService) UpdateUser(ctx context.Context, id uuid.UUID, update UserUpdate, expectedVersions []int64) (*models.User, error) {
+	for attempt := 0; ; attempt++ {
+		user, err := s.UserRepo.GetUserByID(ctx, id)
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrUserNotFound
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get user: %w", err)
+		}
+		if expectedVersions != nil && !slices.Contains(expectedVersions, user.Version) {
+			return nil, ErrUserVersionMismatch
+		}
+
+		if update.Name != nil {
+			if user.Name = strings.TrimSpace(*update.Name); user.Name == "" {
+				return nil, fmt.Errorf("name cannot be empty")
+			}
+		}
+		if update.Email != nil {
+			user.Email = update.Email
+		}
+		if update.Phone != nil {
+			user.Phone = update.Phone
+		}
+		if user.Contact, err = normalizeContact(user.Contact); err != nil {
+			return nil, err
+		}
+
+		err = s.UserRepo.UpdateUser(ctx, user)
+		if err == nil {
+			return user, nil
+		}
+		if !errors.Is(err, repository.ErrVersionConflict) {
+			return nil, contactError(err, "failed to update user")
+		}
+		// The user changed between the read and the write. A client holding an older
+		// version has lost; otherwise the update is applied again on top of the change.
+		if expectedVersions != nil {
+			return nil, ErrUserVersionMismatch
+		}
+		if attempt == defaultTxRetries {
+			return nil, fmt.Errorf("failed to update user %s: %w", id, err)
+		}
+	}
+}
+
+// normalizeContact returns contact with its email lowercased and its phone number
+// stripped of spaces, both nil when empty, or ErrInvalidEmail or ErrInvalidPhone when
+// either is malformed. Stored this way, the uniqueness of each is checked as users
+// would expect.
+func normalizeContact(contact models.Contact) (models.Contact, error) {
+	if contact.Email != nil {
+		email := strings.ToLower(strings.TrimSpace(*contact.Email))
+		contact.Email = nil
+		if email != "" {
+			address, err := mail.ParseAddress(email)
+			if err != nil || address.Address != email {
+				return contact, ErrInvalidEmail
+			}
+			contact.Email = &email
+		}
+	}
+	if contact.Phone != nil {
+		phone := strings.Join(strings.Fields(*contact.Phone), "")
+		contact.Phone = nil
+		if phone != "" {
+			if !e164.MatchString(phone) {
+				return contact, ErrInvalidPhone
+			}
+			contact.Phone = &phone
+		}
+	}
+	return contact, nil
+}
+
+// contactError returns ErrEmailTaken or ErrPhoneTaken for a write that failed because
+// another user has the contact details, or err wrapped with message
+func contactError(err error, message string) error {
+	switch {
+	case errors.Is(err, repository.ErrDuplicateEmail):
+		return ErrEmailTaken
+	case errors.Is(err, repository.ErrDuplicatePhone):
+		return ErrPhoneTaken
+	}
+	return fmt.Errorf("%s: %w", message, err)
+}
+
+// GetUserWallet returns the user's wallet
+func (s *UserService) GetUserWallet(ctx context.Context, id uuid.UUID) (*models.Wallet, error) {
+	userWithWallet, err := s.GetUserWithWallet(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if userWithWallet.Wallet.ID == uuid.Nil {
+		return nil, ErrUserHasNoWallet
+	}
+
+	return &userWithWallet.Wallet, nil
+}
+
+// Register creates a user with a wallet and login credentials. All three commit
+// together, so a registration that fails, even on losing the username to a concurrent
+// one, leaves no user or wallet behind.
+func (s *UserService) Register(ctx context.Context, name, username, password string) (*models.UserWithWallet, error) {
+	if name == "" {
+		return nil, fmt.Errorf("name cannot be empty")
+	}
+	username = strings.TrimSpace(username)
+	if username == "" {
+		return nil, fmt.Errorf("username cannot be empty")
+	}
+
+	// Hash first so a weak password is rejected before anything is written
+	passwordHash, err := auth.HashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.CredentialRepo.GetCredentialsByUsername(ctx, username)
+	if err == nil {
+		return nil, ErrUsernameTaken
+	}
+	if !errors.Is(err, repository.ErrNotFound) {
+		return nil, fmt.Errorf("failed to check username: %w", err)
+	}
+
+	var userWithWallet *models.UserWithWallet
+	err = s.Wallets.withTx(ctx, "register", func(ctx context.Context, tx *sql.Tx) error {
+		user, err := s.UserRepo.CreateUserWithTx(ctx, tx, name, models.Contact{})
+		if err != nil {
+			return contactError(err, "failed to create user")
+		}
+		wallet, err := s.WalletRepo.CreateWalletWithTx(ctx, tx, user.ID)
+		if err != nil {
+			return fmt.Errorf("failed to create wallet for user: %w", err)
+		}
+
+		credentials := &models.Credentials{
+			UserID:       user.ID,
+			Username:     username,
+			PasswordHash: passwordHash,
+		}
+		if err := s.CredentialRepo.CreateCredentialsWithTx(ctx, tx, credentials); err != nil {
+			// Lost a race with a concurrent registration for the same username
+			if errors.Is(err, repository.ErrDuplicate) {
+				return ErrUsernameTaken
+			}
+			return fmt.Errorf("failed to create credentials: %w", err)
+		}
+
+		userWithWallet = newUserWithWallet(user, wallet)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return userWithWallet, nil
+}
+
+// Login verifies the username and password and returns the matching user
+func (s *UserService) Login(ctx context.Context, username, password string) (*models.User, error) {
+	credentials, err := s.CredentialRepo.GetCredentialsByUsername(ctx, strings.TrimSpace(username))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("failed to get credentials: %w", err)
+	}
+
+	if !auth.CheckPassword(credentials.PasswordHash, password) {
+		return nil, ErrInvalidCredentials
+	}
+
+	user, err := s.UserRepo.GetUserByID(ctx, credentials.UserID)
+	if err != nil {
+		// Deleted users keep their credentials but can no longer log in
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	return user, nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"testing"
 	"time"
 
@@ -13,88 +14,15 @@ import (
 	"github.com/stretchr/testify/mock"
 
 	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository"
+	"github.com/shanwije/wallet-app/internal/repository/mocks"
+	"github.com/shanwije/wallet-app/pkg/auth"
 )
 
-// MockUserRepository is a mock implementation of UserRepository
-type MockUserRepository struct {
-	mock.Mock
-}
-
-func (m *MockUserRepository) CreateUser(ctx context.Context, name string) (*models.User, error) {
-	args := m.Called(ctx, name)
-	if args.Get(0) == nil {
-		return nil, args.Error(1)
-	}
-	return args.Get(0).(*models.User), args.Error(1)
-}
-
-func (m *MockUserRepository) GetUserByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
-	args := m.Called(ctx, id)
-	if args.Get(0) == nil {
-		return nil, args.Error(1)
-	}
-	return args.Get(0).(*models.User), args.Error(1)
-}
-
-func (m *MockUserRepository) GetUserWithWallet(ctx context.Context, id uuid.UUID) (*models.UserWithWallet, error) {
-	args := m.Called(ctx, id)
-	if args.Get(0) == nil {
-		return nil, args.Error(1)
-	}
-	return args.Get(0).(*models.UserWithWallet), args.Error(1)
-}
-
-// MockWalletRepository is a mock implementation of WalletRepository
-type MockWalletRepository struct {
-	mock.Mock
-}
-
-func (m *MockWalletRepository) CreateWallet(ctx context.Context, userID uuid.UUID) (*models.Wallet, error) {
-	args := m.Called(ctx, userID)
-	if args.Get(0) == nil {
-		return nil, args.Error(1)
-	}
-	return args.Get(0).(*models.Wallet), args.Error(1)
-}
-
-func (m *MockWalletRepository) GetWalletByUserID(ctx context.Context, userID uuid.UUID) (*models.Wallet, error) {
-	args := m.Called(ctx, userID)
-	return args.Get(0).(*models.Wallet), args.Error(1)
-}
-
-func (m *MockWalletRepository) GetWalletByID(ctx context.Context, id uuid.UUID) (*models.Wallet, error) {
-	args := m.Called(ctx, id)
-	return args.Get(0).(*models.Wallet), args.Error(1)
-}
-
-func (m *MockWalletRepository) UpdateBalance(ctx context.Context, id uuid.UUID, balance decimal.Decimal) error {
-	args := m.Called(ctx, id, balance)
-	return args.Error(0)
-}
-
-// Transaction support methods (not used in user tests, but required by interface)
-func (m *MockWalletRepository) BeginTx(ctx context.Context) (*sql.Tx, error) {
-	args := m.Called(ctx)
-	return nil, args.Error(1) // Return nil for Tx as it's not used in user tests
-}
-
-func (m *MockWalletRepository) UpdateBalanceWithTx(ctx context.Context, tx *sql.Tx, id uuid.UUID, balance decimal.Decimal) error {
-	args := m.Called(ctx, tx, id, balance)
-	return args.Error(0)
-}
-
-func (m *MockWalletRepository) GetWalletByIDWithTx(ctx context.Context, tx *sql.Tx, id uuid.UUID) (*models.Wallet, error) {
-	args := m.Called(ctx, tx, id)
-	if args.Get(0) == nil {
-		return nil, args.Error(1)
-	}
-	return args.Get(0).(*models.Wallet), args.Error(1)
-}
-
 // Core functionality test: Successful user creation with wallet
 func TestCreateUser(t *testing.T) {
-	userRepo := new(MockUserRepository)
-	walletRepo := new(MockWalletRepository)
+	userRepo := new(mocks.UserRepository)
+	walletRepo := new(mocks.WalletRepository)
 	service := &UserService{
 		UserRepo:   userRepo,
 		WalletRepo: walletRepo,
@@ -117,7 +45,7 @@ func TestCreateUser(t *testing.T) {
 		CreatedAt: now,
 	}
 
-	userRepo.On("CreateUser", mock.Anything, "John Doe").Return(expectedUser, nil)
+	userRepo.On("CreateUser", mock.Anything, "John Doe", models.Contact{}).Return(expectedUser, nil)
 	walletRepo.On("CreateWallet", mock.Anything, userID).Return(expectedWallet, nil)
 
 	result, err := service.CreateUser(context.Background(), "John Doe")
@@ -135,14 +63,14 @@ func TestCreateUser(t *testing.T) {
 
 // Core functionality test: User creation failure
 func TestCreateUserError(t *testing.T) {
-	userRepo := new(MockUserRepository)
-	walletRepo := new(MockWalletRepository)
+	userRepo := new(mocks.UserRepository)
+	walletRepo := new(mocks.WalletRepository)
 	service := &UserService{
 		UserRepo:   userRepo,
 		WalletRepo: walletRepo,
 	}
 
-	userRepo.On("CreateUser", mock.Anything, "John Doe").Return(nil, errors.New("database error"))
+	userRepo.On("CreateUser", mock.Anything, "John Doe", models.Contact{}).Return(nil, errors.New("database error"))
 
 	result, err := service.CreateUser(context.Background(), "John Doe")
 
@@ -155,8 +83,8 @@ func TestCreateUserError(t *testing.T) {
 
 // Core functionality test: Get user with wallet
 func TestGetUserWithWallet(t *testing.T) {
-	userRepo := new(MockUserRepository)
-	walletRepo := new(MockWalletRepository)
+	userRepo := new(mocks.UserRepository)
+	walletRepo := new(mocks.WalletRepository)
 	service := &UserService{
 		UserRepo:   userRepo,
 		WalletRepo: walletRepo,
@@ -192,11 +120,46 @@ func TestGetUserWithWallet(t *testing.T) {
 	userRepo.AssertExpectations(t)
 }
 
+func TestGetUserWithWalletNotFound(t *testing.T) {
+	userRepo := new(mocks.UserRepository)
+	service := &UserService{UserRepo: userRepo}
+
+	userID := uuid.New()
+	userRepo.On("GetUserWithWallet", mock.Anything, userID).Return(nil, fmt.Errorf("user %w", repository.ErrNotFound))
+
+	_, err := service.GetUserWithWallet(context.Background(), userID)
+	assert.ErrorIs(t, err, ErrUserNotFound)
+
+	_, err = service.GetUserWallet(context.Background(), userID)
+	assert.ErrorIs(t, err, ErrUserNotFound)
+}
+
+func TestGetUserWallet(t *testing.T) {
+	userRepo := new(mocks.UserRepository)
+	service := &UserService{UserRepo: userRepo}
+
+	userID := uuid.New()
+	walletID := uuid.New()
+	withoutWalletID := uuid.New()
+	userRepo.On("GetUserWithWallet", mock.Anything, userID).Return(&models.UserWithWallet{
+		ID:     userID,
+		Wallet: models.Wallet{ID: walletID, UserID: userID},
+	}, nil)
+	userRepo.On("GetUserWithWallet", mock.Anything, withoutWalletID).Return(&models.UserWithWallet{ID: withoutWalletID}, nil)
+
+	wallet, err := service.GetUserWallet(context.Background(), userID)
+	assert.NoError(t, err)
+	assert.Equal(t, walletID, wallet.ID)
+
+	_, err = service.GetUserWallet(context.Background(), withoutWalletID)
+	assert.ErrorIs(t, err, ErrUserHasNoWallet)
+}
+
 // Tests for assignment requirements - user validation
 
 func TestCreateUserEmptyName(t *testing.T) {
-	userRepo := new(MockUserRepository)
-	walletRepo := new(MockWalletRepository)
+	userRepo := new(mocks.UserRepository)
+	walletRepo := new(mocks.WalletRepository)
 	service := &UserService{
 		UserRepo:   userRepo,
 		WalletRepo: walletRepo,
@@ -228,3 +191,63 @@ func TestUUIDValidation(t *testing.T) {
 	_, err = uuid.Parse("invalid-uuid")
 	assert.Error(t, err, "Invalid UUID should cause parsing error")
 }
+
+func TestRegisterUser(t *testing.T) {
+	userRepo := new(mocks.UserRepository)
+	walletRepo := new(mocks.WalletRepository)
+	credentialRepo := new(mocks.CredentialRepository)
+	wallets := &WalletService{WalletRepo: walletRepo}
+	service := &UserService{UserRepo: userRepo, WalletRepo: walletRepo, CredentialRepo: credentialRepo, Wallets: wallets}
+
+	userID := uuid.New()
+	credentialRepo.On("GetCredentialsByUsername", mock.Anything, "john").Return(nil, fmt.Errorf("credentials %w", repository.ErrNotFound))
+	walletRepo.On("BeginTx", mock.Anything).Return((*sql.Tx)(nil), nil)
+	userRepo.On("CreateUserWithTx", mock.Anything, (*sql.Tx)(nil), "John Doe", models.Contact{}).Return(&models.User{ID: userID, Name: "John Doe"}, nil)
+	walletRepo.On("CreateWalletWithTx", mock.Anything, (*sql.Tx)(nil), userID).Return(&models.Wallet{ID: uuid.New(), UserID: userID}, nil)
+	credentialRepo.On("CreateCredentialsWithTx", mock.Anything, (*sql.Tx)(nil), mock.MatchedBy(func(c *models.Credentials) bool {
+		// The password must never be stored in plain text
+		return c.UserID == userID && c.Username == "john" && c.PasswordHash != "s3cure-password"
+	})).Return(nil)
+
+	result, err := service.Register(context.Background(), "John Doe", "john", "s3cure-password")
+
+	assert.NoError(t, err)
+	assert.Equal(t, userID, result.ID)
+	credentialRepo.AssertExpectations(t)
+}
+
+func TestRegisterUsernameTaken(t *testing.T) {
+	credentialRepo := new(mocks.CredentialRepository)
+	service := &UserService{CredentialRepo: credentialRepo}
+
+	credentialRepo.On("GetCredentialsByUsername", mock.Anything, "john").Return(&models.Credentials{Username: "john"}, nil)
+
+	result, err := service.Register(context.Background(), "John Doe", "john", "s3cure-password")
+
+	assert.Nil(t, result)
+	assert.ErrorIs(t, err, ErrUsernameTaken)
+}
+
+func TestLogin(t *testing.T) {
+	userRepo := new(mocks.UserRepository)
+	credentialRepo := new(mocks.CredentialRepository)
+	service := &UserService{UserRepo: userRepo, CredentialRepo: credentialRepo}
+
+	userID := uuid.New()
+	hash, err := auth.HashPassword("s3cure-password")
+	assert.NoError(t, err)
+
+	credentialRepo.On("GetCredentialsByUsername", mock.Anything, "john").Return(&models.Credentials{UserID: userID, Username: "john", PasswordHash: hash}, nil)
+	credentialRepo.On("GetCredentialsByUsername", mock.Anything, "ghost").Return(nil, fmt.Errorf("credentials %w", repository.ErrNotFound))
+	userRepo.On("GetUserByID", mock.Anything, userID).Return(&models.User{ID: userID, Name: "John Doe"}, nil)
+
+	user, err := service.Login(context.Background(), "john", "s3cure-password")
+	assert.NoError(t, err)
+	assert.Equal(t, userID, user.ID)
+
+	_, err = service.Login(context.Background(), "john", "wrong-password")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+
+	_, err = service.Login(context.Background(), "ghost", "s3cure-password")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+}
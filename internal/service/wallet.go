@@ -3,177 +3,482 @@ package service
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/shanwije/wallet-app/internal/fees"
+	"github.com/shanwije/wallet-app/internal/fx"
 	"github.com/shanwije/wallet-app/internal/models"
 	"github.com/shanwije/wallet-app/internal/repository"
+	"github.com/shanwije/wallet-app/internal/risk"
+	"github.com/shanwije/wallet-app/pkg/clock"
+	"github.com/shanwije/wallet-app/pkg/featureflag"
+	"github.com/shanwije/wallet-app/pkg/money"
+	"github.com/shanwije/wallet-app/pkg/pagination"
 	"github.com/shopspring/decimal"
 )
 
-// Transaction type constants for better readability
-const (
-	TransactionTypeDeposit     = "deposit"
-	TransactionTypeWithdraw    = "withdraw"
-	TransactionTypeTransferOut = "transfer_out"
-	TransactionTypeTransferIn  = "transfer_in"
+var (
+	// ErrInvalidWalletStatus is returned for a status other than active, frozen or closed
+	ErrInvalidWalletStatus = errors.New("invalid wallet status")
+	// ErrWalletNotFound is returned when no wallet has the ID, or the user has no wallet
+	ErrWalletNotFound = errors.New("wallet not found")
+	// ErrInvalidAmount is returned for a deposit, withdrawal or transfer that is not positive
+	ErrInvalidAmount = errors.New("amount must be positive")
+	// ErrInsufficientBalance is returned when a withdrawal or transfer exceeds the spendable
+	// balance: what is available down to the wallet's overdraft limit or minimum balance
+	ErrInsufficientBalance = errors.New("insufficient balance")
+	// ErrSameWallet is returned for a transfer whose source and destination are the same wallet
+	ErrSameWallet = errors.New("cannot transfer to the same wallet")
+	// ErrTransferNotFound is returned when no transfer has the reference ID
+	ErrTransferNotFound = errors.New("transfer not found")
 )
 
 type WalletService struct {
-	WalletRepo      repository.WalletRepository
-	TransactionRepo repository.TransactionRepository
+	WalletRepo repository.WalletRepository
+	// Tx is optional; when nil transactions are begun on WalletRepo
+	Tx         repository.TxManager
+	LedgerRepo repository.LedgerRepository
+	HoldRepo   repository.HoldRepository
+	// LimitsRepo is optional; no wallet limits are enforced when nil
+	LimitsRepo repository.WalletLimitsRepository
+	// KYCRepo is optional; when set the wallets of users in a tier with TierLimits
+	// pay out no more than those allow, on top of their own limits
+	KYCRepo    repository.KYCRepository
+	TierLimits map[string]models.KYCTierLimits
+	// AlertRepo is optional; when set withdrawals, transfers and hold captures that
+	// take a wallet below its low balance threshold raise an alert
+	AlertRepo repository.WalletAlertRepository
+	// Risk is optional; when set it screens withdrawals and transfers before they run,
+	// and RiskRepo stores the decisions it flags or blocks
+	Risk     risk.Engine
+	RiskRepo repository.RiskRepository
+	// AMLRepo is optional; when set with AML, deposits, withdrawals and transfers out
+	// are screened inside their transaction and the reportable ones recorded for
+	// compliance
+	AMLRepo repository.AMLRepository
+	AML     *AMLRules
+	// Outbox is optional; when set every journal also writes a wallet event for the
+	// dispatcher to publish
+	Outbox repository.OutboxRepository
+	// FX is optional; when set transfers to a wallet in another currency are converted
+	// at its rate, and without it they are rejected as a currency mismatch
+	FX fx.ExchangeRateProvider
+	// QuoteRepo is optional; when set transfers can be quoted, and a transfer passing
+	// the quote runs on its terms for QuoteTTL, 30 seconds when zero
+	QuoteRepo repository.TransferQuoteRepository
+	QuoteTTL  time.Duration
+	// Fees is optional; when set with FeeWalletID, deposits, withdrawals and transfers
+	// are charged the fee of their operation type, paid into that wallet as a separate
+	// journal in the same transaction. Movements in a currency the fee wallet does not
+	// hold are not charged.
+	Fees        *fees.Schedule
+	FeeWalletID uuid.UUID
+	// SystemWallets is optional; when set deposits, withdrawals, hold captures and
+	// closing withdrawals are transfers against the treasury wallet of their currency,
+	// and adjustments against the suspense wallet, instead of postings to the external
+	// settlement account, so every journal balances between wallets
+	SystemWallets *SystemWalletService
+	// Snapshots is optional; when set historical balances start from the latest
+	// end-of-day snapshot instead of summing the wallet's whole ledger
+	Snapshots repository.BalanceSnapshotRepository
+	// UserRepo and CredentialRepo resolve transfer recipients named by user
+	UserRepo       repository.UserRepository
+	CredentialRepo repository.CredentialRepository
+	// Flags is optional; every feature is enabled when nil
+	Flags *featureflag.Flags
+	// Cache is optional; when set GetBalance reads through it
+	Cache WalletCache
+	// Notifier is optional; when set it hears about deposits, withdrawals and incoming
+	// transfers once they commit
+	Notifier BalanceNotifier
+	// IncomingTransfers is optional; when set a transfer into a wallet that requires
+	// acceptance is held there until its owner accepts it, for AcceptanceWindow, 72 hours
+	// when zero
+	IncomingTransfers repository.IncomingTransferRepository
+	AcceptanceWindow  time.Duration
+	// DepositRefunds is optional; when set deposits that were refunded, and the
+	// withdrawals that refunded them, cannot be reversed or corrected
+	DepositRefunds repository.DepositRefundRepository
+	// Payments is optional; when set merchant payments that were refunded cannot be
+	// reversed, corrected or disputed
+	Payments repository.PaymentRepository
+	// Payouts is optional; when set withdrawals that paid out to a bank account cannot
+	// be reversed or corrected
+	Payouts repository.PayoutRepository
+	// Clock is optional; the system clock is used when nil
+	Clock clock.Clock
+	// TxTimeout bounds each attempt at a money-movement transaction; 10 seconds when zero
+	TxTimeout time.Duration
+	// TxRetries bounds how often a transaction that conflicted with another is rerun;
+	// 3 when zero
+	TxRetries int
+	// OptimisticLocking reads wallets without row locks and retries transactions whose
+	// versioned updates conflict, instead of locking every wallet a transaction touches
+	OptimisticLocking bool
+	// MinAmount and MaxAmount are optional bounds on the amount of every deposit,
+	// withdrawal and transfer, whatever its currency
+	MinAmount *decimal.Decimal
+	MaxAmount *decimal.Decimal
+	// ConfirmationThreshold is optional; transfers over it, in their currency, are
+	// refused with ErrConfirmationRequired unless they run as a confirmed pending transfer
+	ConfirmationThreshold *decimal.Decimal
 }
 
-// validateDepositAmount validates that the deposit amount is positive
-func (s *WalletService) validateDepositAmount(amount decimal.Decimal) error {
-	if amount.LessThanOrEqual(decimal.Zero) {
-		return fmt.Errorf("deposit amount must be positive")
+// now returns the current time from the injected clock
+func (s *WalletService) now() time.Time {
+	return clock.OrDefault(s.Clock).Now()
+}
+
+// validateDepositAmount validates that the deposit amount is positive and within range
+func (s *WalletService) validateDepositAmount(amount money.Money) error {
+	if !amount.IsPositive() {
+		return fmt.Errorf("deposit %w", ErrInvalidAmount)
 	}
-	return nil
+	return s.checkAmountRange(amount)
 }
 
-// validateWithdrawAmount validates that the withdraw amount is positive and sufficient
-func (s *WalletService) validateWithdrawAmount(amount decimal.Decimal, currentBalance decimal.Decimal) error {
-	if amount.LessThanOrEqual(decimal.Zero) {
-		return fmt.Errorf("withdraw amount must be positive")
+// validateWithdrawAmount validates that the withdraw amount is positive, within range
+// and within the spendable balance
+func (s *WalletService) validateWithdrawAmount(amount money.Money, spendable money.Money) error {
+	if !amount.IsPositive() {
+		return fmt.Errorf("withdraw %w", ErrInvalidAmount)
 	}
-	if currentBalance.LessThan(amount) {
-		return fmt.Errorf("insufficient balance for withdrawal")
+	if err := s.checkAmountRange(amount); err != nil {
+		return err
+	}
+	cmp, err := spendable.Cmp(amount)
+	if err != nil {
+		return err
+	}
+	if cmp < 0 {
+		return fmt.Errorf("%w for withdrawal", ErrInsufficientBalance)
 	}
 	return nil
 }
 
 // validateTransferAmount validates transfer amount and wallets
-func (s *WalletService) validateTransferAmount(amount decimal.Decimal, fromWalletID, toWalletID uuid.UUID) error {
-	if amount.LessThanOrEqual(decimal.Zero) {
-		return fmt.Errorf("transfer amount must be positive")
+func (s *WalletService) validateTransferAmount(amount money.Money, fromWalletID, toWalletID uuid.UUID) error {
+	if !amount.IsPositive() {
+		return fmt.Errorf("transfer %w", ErrInvalidAmount)
 	}
 	if fromWalletID == toWalletID {
-		return fmt.Errorf("cannot transfer to the same wallet")
+		return ErrSameWallet
+	}
+	return s.checkAmountRange(amount)
+}
+
+// checkConfirmation refuses a transfer of amount that has to be held for its sender's
+// confirmation
+func (s *WalletService) checkConfirmation(amount money.Money) error {
+	if s.ConfirmationThreshold != nil && amount.Amount().GreaterThan(*s.ConfirmationThreshold) {
+		return ErrConfirmationRequired
 	}
 	return nil
 }
 
-func (s *WalletService) Deposit(ctx context.Context, walletID uuid.UUID, amount decimal.Decimal) (*models.Wallet, error) {
+// Deposit credits amount to the wallet. A non-empty idempotencyKey makes retries of
+// the same deposit return the wallet without depositing again.
+func (s *WalletService) Deposit(ctx context.Context, walletID uuid.UUID, amount money.Money, idempotencyKey string) (*models.Wallet, error) {
+	result, replayed, err := s.deposit(ctx, walletID, amount, idempotencyKey)
+	if err != nil {
+		return nil, err
+	}
+	if replayed {
+		return s.GetBalance(ctx, walletID)
+	}
+
+	// Return updated wallet
+	return result.Wallet, nil
+}
+
+// MovementResult is a deposit or withdrawal as it was recorded: the wallet's
+// transaction, its balance either side of it, the fee it was charged and the wallet
+// as it was left. Fee is zero when none was charged or the result is a replay.
+type MovementResult struct {
+	Transaction     *models.Transaction
+	PreviousBalance decimal.Decimal
+	NewBalance      decimal.Decimal
+	Fee             money.Money
+	Wallet          *models.Wallet
+}
+
+// newMovementResult describes journal's movement of the wallet's balance from previous,
+// and the fee charged on it
+func newMovementResult(journal *models.Journal, wallet *models.Wallet, previous decimal.Decimal, fee money.Money) *MovementResult {
+	return &MovementResult{
+		Transaction:     models.NewTransaction(journal, journal.WalletEntry(wallet.ID)),
+		PreviousBalance: previous,
+		NewBalance:      wallet.Balance,
+		Fee:             fee,
+		Wallet:          wallet,
+	}
+}
+
+// CreateDeposit is Deposit returning the transaction it recorded and the wallet's
+// balance either side of it. A replayed call returns the transaction first recorded
+// under the idempotency key, with its balances at the time and the wallet as it is now.
+func (s *WalletService) CreateDeposit(ctx context.Context, walletID uuid.UUID, amount money.Money, idempotencyKey string) (*MovementResult, error) {
+	result, replayed, err := s.deposit(ctx, walletID, amount, idempotencyKey)
+	if err != nil || !replayed {
+		return result, err
+	}
+	return s.recordedMovement(ctx, walletID, idempotencyKey)
+}
+
+// deposit runs a deposit and reports whether it was a replay, in which case the
+// result is nil
+func (s *WalletService) deposit(ctx context.Context, walletID uuid.UUID, amount money.Money, idempotencyKey string) (*MovementResult, bool, error) {
 	// Validate input
 	if err := s.validateDepositAmount(amount); err != nil {
-		return nil, err
+		return nil, false, err
 	}
 
-	// Begin database transaction for atomicity
-	tx, err := s.WalletRepo.BeginTx(ctx)
+	// Funds come in from the treasury wallet, or the external settlement account
+	treasuryID, err := s.settlementWallet(ctx, models.WalletKindTreasury, amount.Currency())
 	if err != nil {
-		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+		return nil, false, err
+	}
+	journal := newJournal(models.JournalTypeDeposit, nil, scopedIdempotencyKey(walletID, idempotencyKey),
+		debit(treasuryID, amount),
+		credit(&walletID, amount),
+	)
+	classify(ctx, journal)
+	fee, err := s.movementFee(ctx, models.JournalTypeDeposit, walletID, amount)
+	if err != nil {
+		return nil, false, err
 	}
-	defer func() {
-		if err != nil {
-			if tx != nil {
-				tx.Rollback()
-			}
-		}
-	}()
 
+	var result *MovementResult
+	replayed, err := s.idempotent(ctx, journal, func() error {
+		return s.withTx(ctx, "deposit", func(ctx context.Context, tx *sql.Tx) error {
+			var err error
+			result, err = s.depositExecution(ctx, tx, walletID, amount, fee, journal, treasuryID)
+			return err
+		})
+	})
+	if err != nil || replayed {
+		return nil, replayed, err
+	}
+	return result, false, nil
+}
+
+// depositExecution credits amount to the wallet inside tx, charging its fee, and
+// records journal, which pays amount in from treasuryID, or the external settlement
+// account when that is nil
+func (s *WalletService) depositExecution(ctx context.Context, tx *sql.Tx, walletID uuid.UUID, amount, fee money.Money, journal *models.Journal, treasuryID *uuid.UUID) (*MovementResult, error) {
 	// Get current wallet
-	wallet, err := s.WalletRepo.GetWalletByIDWithTx(ctx, tx, walletID)
+	current, feeWallet, err := s.lockWithFeeWallet(ctx, tx, walletID, fee)
+	if err != nil {
+		return nil, err
+	}
+	if err := current.CheckActive(); err != nil {
+		return nil, err
+	}
+	if err := s.checkLimits(ctx, tx, current, models.JournalTypeDeposit, amount); err != nil {
+		return nil, err
+	}
+	return s.postDeposit(ctx, tx, current, feeWallet, amount, fee, journal, treasuryID)
+}
+
+// capturedDepositExecution is depositExecution for money a payment provider has already
+// taken from the customer. It has to be credited, so neither the wallet's limits nor a
+// fee stand in its way; only a frozen or closed wallet does.
+func (s *WalletService) capturedDepositExecution(ctx context.Context, tx *sql.Tx, walletID uuid.UUID, amount money.Money, journal *models.Journal, treasuryID *uuid.UUID) (*MovementResult, error) {
+	current, err := s.getWalletForUpdate(ctx, tx, walletID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get wallet: %w", err)
 	}
+	if err := current.CheckActive(); err != nil {
+		return nil, err
+	}
+	return s.postDeposit(ctx, tx, current, nil, amount, noFee, journal, treasuryID)
+}
 
+// postDeposit credits amount to current, locked by tx, records journal and charges fee
+// into feeWallet
+func (s *WalletService) postDeposit(ctx context.Context, tx *sql.Tx, current, feeWallet *models.Wallet, amount, fee money.Money, journal *models.Journal, treasuryID *uuid.UUID) (*MovementResult, error) {
 	// Update balance
-	newBalance := wallet.Balance.Add(amount)
-	err = s.WalletRepo.UpdateBalanceWithTx(ctx, tx, walletID, newBalance)
+	previous := current.Balance
+	newBalance, err := current.Funds().Add(amount)
 	if err != nil {
+		return nil, fmt.Errorf("invalid deposit: %w", err)
+	}
+	if err := s.setBalance(ctx, tx, current, newBalance.Amount()); err != nil {
 		return nil, fmt.Errorf("failed to update wallet balance: %w", err)
 	}
+	if err := s.settle(ctx, tx, current, journal, treasuryID); err != nil {
+		return nil, err
+	}
 
-	// Record transaction
-	transaction := &models.Transaction{
-		WalletID:    walletID,
-		Type:        TransactionTypeDeposit,
-		Amount:      amount,
-		Description: nil, // Optional description can be added later
+	if err := s.recordJournal(ctx, tx, journal); err != nil {
+		return nil, err
+	}
+	if err := s.chargeFee(ctx, tx, current, feeWallet, models.JournalTypeDeposit, fee); err != nil {
+		return nil, err
+	}
+	if err := s.screenAML(ctx, tx, current, journal, amount); err != nil {
+		return nil, err
 	}
 
-	err = s.TransactionRepo.CreateTransactionWithTx(ctx, tx, transaction)
+	return newMovementResult(journal, current, previous, fee), nil
+}
+
+// Withdraw debits amount from the wallet. A non-empty idempotencyKey makes retries of
+// the same withdrawal return the wallet without withdrawing again.
+func (s *WalletService) Withdraw(ctx context.Context, walletID uuid.UUID, amount money.Money, idempotencyKey string) (*models.Wallet, error) {
+	result, replayed, err := s.withdraw(ctx, walletID, amount, idempotencyKey)
 	if err != nil {
-		return nil, fmt.Errorf("failed to record transaction: %w", err)
+		return nil, err
 	}
-
-	// Commit transaction
-	if tx != nil {
-		err = tx.Commit()
-		if err != nil {
-			return nil, fmt.Errorf("failed to commit transaction: %w", err)
-		}
+	if replayed {
+		return s.GetBalance(ctx, walletID)
 	}
 
 	// Return updated wallet
-	wallet.Balance = newBalance
-	return wallet, nil
+	return result.Wallet, nil
+}
+
+// CreateWithdrawal is Withdraw returning the transaction it recorded and the wallet's
+// balance either side of it, as CreateDeposit does for deposits
+func (s *WalletService) CreateWithdrawal(ctx context.Context, walletID uuid.UUID, amount money.Money, idempotencyKey string) (*MovementResult, error) {
+	result, replayed, err := s.withdraw(ctx, walletID, amount, idempotencyKey)
+	if err != nil || !replayed {
+		return result, err
+	}
+	return s.recordedMovement(ctx, walletID, idempotencyKey)
 }
 
-func (s *WalletService) Withdraw(ctx context.Context, walletID uuid.UUID, amount decimal.Decimal) (*models.Wallet, error) {
-	// Begin database transaction for atomicity
-	tx, err := s.WalletRepo.BeginTx(ctx)
+// withdraw runs a withdrawal and reports whether it was a replay, in which case the
+// result is nil
+func (s *WalletService) withdraw(ctx context.Context, walletID uuid.UUID, amount money.Money, idempotencyKey string) (*MovementResult, bool, error) {
+	// Funds leave to the treasury wallet, or the external settlement account
+	treasuryID, err := s.settlementWallet(ctx, models.WalletKindTreasury, amount.Currency())
 	if err != nil {
-		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+		return nil, false, err
+	}
+	journal := newJournal(models.JournalTypeWithdraw, nil, scopedIdempotencyKey(walletID, idempotencyKey),
+		debit(&walletID, amount),
+		credit(treasuryID, amount),
+	)
+	classify(ctx, journal)
+	fee, err := s.movementFee(ctx, models.JournalTypeWithdraw, walletID, amount)
+	if err != nil {
+		return nil, false, err
 	}
-	defer func() {
-		if err != nil {
-			if tx != nil {
-				tx.Rollback()
-			}
+
+	var result *MovementResult
+	replayed, err := s.idempotent(ctx, journal, func() error {
+		if err := s.requireFeature(ctx, FlagWithdrawals); err != nil {
+			return err
 		}
-	}()
+		if err := s.assessRisk(ctx, models.JournalTypeWithdraw, walletID, nil, amount); err != nil {
+			return err
+		}
+		return s.withTx(ctx, "withdraw", func(ctx context.Context, tx *sql.Tx) error {
+			var err error
+			result, err = s.withdrawExecution(ctx, tx, walletID, amount, fee, journal, treasuryID)
+			return err
+		})
+	})
+	if err != nil || replayed {
+		return nil, replayed, err
+	}
+	return result, false, nil
+}
 
+// withdrawExecution debits amount and its fee from the wallet inside tx and records
+// journal, which pays amount out to treasuryID, or the external settlement account
+// when that is nil
+func (s *WalletService) withdrawExecution(ctx context.Context, tx *sql.Tx, walletID uuid.UUID, amount, fee money.Money, journal *models.Journal, treasuryID *uuid.UUID) (*MovementResult, error) {
 	// Get current wallet
-	wallet, err := s.WalletRepo.GetWalletByIDWithTx(ctx, tx, walletID)
+	current, feeWallet, err := s.lockWithFeeWallet(ctx, tx, walletID, fee)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get wallet: %w", err)
+		return nil, err
+	}
+	if err := current.CheckActive(); err != nil {
+		return nil, err
 	}
 
-	// Validate input amount and sufficient balance
-	if err := s.validateWithdrawAmount(amount, wallet.Balance); err != nil {
+	// Validate input amount and sufficient balance; held funds cannot be withdrawn
+	spendable, err := s.spendable(ctx, tx, current)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.validateWithdrawAmount(amount, spendable); err != nil {
+		return nil, err
+	}
+	if err := s.checkLimits(ctx, tx, current, models.JournalTypeWithdraw, amount); err != nil {
 		return nil, err
 	}
 
 	// Update balance
-	newBalance := wallet.Balance.Sub(amount)
-	err = s.WalletRepo.UpdateBalanceWithTx(ctx, tx, walletID, newBalance)
+	previous := current.Balance
+	newBalance, err := current.Funds().Sub(amount)
 	if err != nil {
+		return nil, fmt.Errorf("invalid withdrawal: %w", err)
+	}
+	if err := s.setBalance(ctx, tx, current, newBalance.Amount()); err != nil {
 		return nil, fmt.Errorf("failed to update wallet balance: %w", err)
 	}
+	if err := s.settle(ctx, tx, current, journal, treasuryID); err != nil {
+		return nil, err
+	}
 
-	// Record transaction
-	transaction := &models.Transaction{
-		WalletID:    walletID,
-		Type:        TransactionTypeWithdraw,
-		Amount:      amount,
-		Description: nil, // Optional description can be added later
+	if err := s.recordJournal(ctx, tx, journal); err != nil {
+		return nil, err
 	}
+	if err := s.chargeFee(ctx, tx, current, feeWallet, models.JournalTypeWithdraw, fee); err != nil {
+		return nil, err
+	}
+	if err := s.alertLowBalance(ctx, tx, current, previous, journal); err != nil {
+		return nil, err
+	}
+	if err := s.screenAML(ctx, tx, current, journal, amount); err != nil {
+		return nil, err
+	}
+
+	return newMovementResult(journal, current, previous, fee), nil
+}
 
-	err = s.TransactionRepo.CreateTransactionWithTx(ctx, tx, transaction)
+// recordedMovement describes the deposit or withdrawal recorded under the wallet's
+// idempotency key. Its balances are rebuilt from the ledger as they stood when it was
+// posted.
+func (s *WalletService) recordedMovement(ctx context.Context, walletID uuid.UUID, idempotencyKey string) (*MovementResult, error) {
+	journal, err := s.LedgerRepo.GetJournalByIdempotencyKey(ctx, *scopedIdempotencyKey(walletID, idempotencyKey))
 	if err != nil {
-		return nil, fmt.Errorf("failed to record transaction: %w", err)
+		return nil, fmt.Errorf("failed to get recorded transaction: %w", err)
+	}
+	entry := journal.WalletEntry(walletID)
+	if entry == nil {
+		return nil, ErrIdempotencyKeyReused
 	}
 
-	// Commit transaction
-	if tx != nil {
-		err = tx.Commit()
-		if err != nil {
-			return nil, fmt.Errorf("failed to commit transaction: %w", err)
-		}
+	before, err := s.GetBalanceAt(ctx, walletID, journal.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	wallet, err := s.GetBalance(ctx, walletID)
+	if err != nil {
+		return nil, err
 	}
 
-	// Return updated wallet
-	wallet.Balance = newBalance
-	return wallet, nil
+	transaction := models.NewTransaction(journal, entry)
+	return &MovementResult{
+		Transaction:     transaction,
+		PreviousBalance: before.Balance,
+		NewBalance:      before.Balance.Add(transaction.SignedAmount()),
+		Wallet:          wallet,
+	}, nil
 }
 
+// GetBalance reads the wallet, from Cache when it is set
 func (s *WalletService) GetBalance(ctx context.Context, walletID uuid.UUID) (*models.Wallet, error) {
-	wallet, err := s.WalletRepo.GetWalletByID(ctx, walletID)
+	wallet, err := s.cachedWallet(ctx, walletID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get wallet: %w", err)
+		return nil, fmt.Errorf("failed to get wallet: %w", walletLookupError(err))
 	}
 
 	return wallet, nil
@@ -182,123 +487,300 @@ func (s *WalletService) GetBalance(ctx context.Context, walletID uuid.UUID) (*mo
 func (s *WalletService) GetWalletByUserID(ctx context.Context, userID uuid.UUID) (*models.Wallet, error) {
 	wallet, err := s.WalletRepo.GetWalletByUserID(ctx, userID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get wallet for user: %w", err)
+		return nil, fmt.Errorf("failed to get wallet for user: %w", walletLookupError(err))
 	}
 
 	return wallet, nil
 }
 
-// transferExecution handles the actual transfer logic within a transaction
-func (s *WalletService) transferExecution(ctx context.Context, tx *sql.Tx, fromWalletID, toWalletID uuid.UUID, amount decimal.Decimal, description string) error {
-	// Lock and get both wallets
-	fromWallet, toWallet, err := s.lockAndGetWallets(ctx, tx, fromWalletID, toWalletID)
+// transferExecution handles the actual transfer logic within a transaction, charging
+// the sender fee, and returns both wallets as it left them. Transfers that are never
+// charged pass noFee.
+func (s *WalletService) transferExecution(ctx context.Context, tx *sql.Tx, fromWalletID, toWalletID uuid.UUID, amount, fee money.Money, journal *models.Journal) (*models.Wallet, *models.Wallet, error) {
+	if err := s.requireFeature(ctx, FlagTransfers); err != nil {
+		return nil, nil, err
+	}
+
+	// Lock and get both wallets, and the fee wallet when a fee is charged
+	fromWallet, toWallet, feeWallet, err := s.lockAndGetWallets(ctx, tx, fromWalletID, toWalletID, fee)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 
-	// Validate sufficient balance
-	if fromWallet.Balance.LessThan(amount) {
-		return fmt.Errorf("insufficient balance")
+	// Neither side of a transfer may be frozen or closed
+	if err := fromWallet.CheckActive(); err != nil {
+		return nil, nil, fmt.Errorf("source %w", err)
+	}
+	if err := toWallet.CheckActive(); err != nil {
+		return nil, nil, fmt.Errorf("destination %w", err)
+	}
+	if fromWallet.IsSettlement() || toWallet.IsSettlement() {
+		return nil, nil, ErrSettlementWallet
+	}
+
+	// The sender must hold the transfer currency; the recipient is credited in theirs
+	if !fromWallet.Funds().SameCurrency(amount) {
+		return nil, nil, fmt.Errorf("invalid transfer: %w", money.ErrCurrencyMismatch)
+	}
+	credited := amount
+	if !toWallet.Funds().SameCurrency(amount) {
+		if credited, err = s.convertTransfer(ctx, journal, fromWalletID, toWalletID, amount, toWallet.Currency); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// Validate sufficient balance; held funds cannot be transferred
+	spendable, err := s.spendable(ctx, tx, fromWallet)
+	if err != nil {
+		return nil, nil, err
+	}
+	cmp, err := spendable.Cmp(amount)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid transfer: %w", err)
+	}
+	if cmp < 0 {
+		return nil, nil, ErrInsufficientBalance
+	}
+	if err := s.checkLimits(ctx, tx, fromWallet, models.JournalTypeTransfer, amount); err != nil {
+		return nil, nil, err
 	}
 
 	// Update balances
-	if err := s.updateTransferBalances(ctx, tx, fromWalletID, toWalletID, fromWallet.Balance, toWallet.Balance, amount); err != nil {
-		return err
+	previous := fromWallet.Balance
+	if err := s.updateTransferBalances(ctx, tx, fromWallet, toWallet, amount, credited); err != nil {
+		return nil, nil, err
 	}
 
-	// Create transaction records
-	return s.createTransferRecords(ctx, tx, fromWalletID, toWalletID, amount, description)
+	// Record the transfer as a single journal with a leg per wallet
+	if err := s.recordJournal(ctx, tx, journal); err != nil {
+		return nil, nil, err
+	}
+	if err := s.chargeFee(ctx, tx, fromWallet, feeWallet, models.JournalTypeTransfer, fee); err != nil {
+		return nil, nil, err
+	}
+	if err := s.alertLowBalance(ctx, tx, fromWallet, previous, journal); err != nil {
+		return nil, nil, err
+	}
+	if err := s.screenAML(ctx, tx, fromWallet, journal, amount); err != nil {
+		return nil, nil, err
+	}
+	return fromWallet, toWallet, nil
 }
 
-// lockAndGetWallets locks and retrieves both wallets for transfer
-func (s *WalletService) lockAndGetWallets(ctx context.Context, tx *sql.Tx, fromWalletID, toWalletID uuid.UUID) (*models.Wallet, *models.Wallet, error) {
-	fromWallet, err := s.WalletRepo.GetWalletByIDWithTx(ctx, tx, fromWalletID)
+// convertTransfer prices a transfer of amount in the recipient's currency, at the rate
+// it was quoted at if any, and rewrites its journal as two pairs of legs through the
+// settlement account, one per currency, so it still balances in each. Every leg records
+// the rate and its amount in the other currency. It returns the amount to credit the
+// recipient.
+func (s *WalletService) convertTransfer(ctx context.Context, journal *models.Journal, fromWalletID, toWalletID uuid.UUID, amount money.Money, to money.Currency) (money.Money, error) {
+	rate, err := s.exchangeRate(ctx, amount.Currency(), to)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to get source wallet: %w", err)
+		return money.Money{}, err
+	}
+	converted := fx.Convert(amount, rate, to)
+	if !converted.IsPositive() {
+		return money.Money{}, fmt.Errorf("converted %w", ErrInvalidAmount)
 	}
 
-	toWallet, err := s.WalletRepo.GetWalletByIDWithTx(ctx, tx, toWalletID)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to get destination wallet: %w", err)
+	journal.Entries = []*models.LedgerEntry{
+		converting(debit(&fromWalletID, amount), rate, converted),
+		converting(credit(&toWalletID, converted), rate, amount),
+		converting(credit(nil, amount), rate, converted),
+		converting(debit(nil, converted), rate, amount),
 	}
+	return converted, nil
+}
 
-	return fromWallet, toWallet, nil
+// lockAndGetWallets locks and retrieves both wallets for transfer, and the fee wallet
+// when fee is charged. They are locked in ID order rather than source first, so
+// transfers A→B and B→A running at the same time queue on the same wallet instead of
+// each holding the lock the other needs. The fee wallet is nil when no fee is charged.
+func (s *WalletService) lockAndGetWallets(ctx context.Context, tx *sql.Tx, fromWalletID, toWalletID uuid.UUID, fee money.Money) (*models.Wallet, *models.Wallet, *models.Wallet, error) {
+	ids := []uuid.UUID{fromWalletID, toWalletID}
+	if fee.IsPositive() {
+		ids = append(ids, s.FeeWalletID)
+	}
+
+	wallets := make(map[uuid.UUID]*models.Wallet, len(ids))
+	for _, id := range lockOrder(ids...) {
+		wallet, err := s.getWalletForUpdate(ctx, tx, id)
+		if err != nil {
+			switch id {
+			case fromWalletID:
+				return nil, nil, nil, fmt.Errorf("failed to get source wallet: %w", err)
+			case toWalletID:
+				return nil, nil, nil, fmt.Errorf("failed to get destination wallet: %w", err)
+			default:
+				return nil, nil, nil, fmt.Errorf("failed to get fee wallet: %w", err)
+			}
+		}
+		wallets[id] = wallet
+	}
+
+	if !fee.IsPositive() {
+		return wallets[fromWalletID], wallets[toWalletID], nil, nil
+	}
+	return wallets[fromWalletID], wallets[toWalletID], wallets[s.FeeWalletID], nil
 }
 
-// updateTransferBalances updates both wallet balances
-func (s *WalletService) updateTransferBalances(ctx context.Context, tx *sql.Tx, fromWalletID, toWalletID uuid.UUID, fromBalance, toBalance, amount decimal.Decimal) error {
-	newFromBalance := fromBalance.Sub(amount)
-	newToBalance := toBalance.Add(amount)
+// updateTransferBalances debits the sender and credits the recipient, who receives
+// credited: the same amount, or its conversion into their currency
+func (s *WalletService) updateTransferBalances(ctx context.Context, tx *sql.Tx, fromWallet, toWallet *models.Wallet, debited, credited money.Money) error {
+	newFromBalance, err := fromWallet.Funds().Sub(debited)
+	if err != nil {
+		return fmt.Errorf("invalid transfer: %w", err)
+	}
+	newToBalance, err := toWallet.Funds().Add(credited)
+	if err != nil {
+		return fmt.Errorf("invalid transfer: %w", err)
+	}
 
-	if err := s.WalletRepo.UpdateBalanceWithTx(ctx, tx, fromWalletID, newFromBalance); err != nil {
+	if err := s.setBalance(ctx, tx, fromWallet, newFromBalance.Amount()); err != nil {
 		return fmt.Errorf("failed to update source wallet balance: %w", err)
 	}
 
-	if err := s.WalletRepo.UpdateBalanceWithTx(ctx, tx, toWalletID, newToBalance); err != nil {
+	if err := s.setBalance(ctx, tx, toWallet, newToBalance.Amount()); err != nil {
 		return fmt.Errorf("failed to update destination wallet balance: %w", err)
 	}
 
 	return nil
 }
 
-// createTransferRecords creates both transaction records for the transfer
-func (s *WalletService) createTransferRecords(ctx context.Context, tx *sql.Tx, fromWalletID, toWalletID uuid.UUID, amount decimal.Decimal, description string) error {
-	referenceID := uuid.New()
+// Transfer money between wallets atomically. A non-empty idempotencyKey makes
+// retries of the same transfer succeed without moving the money again.
+func (s *WalletService) Transfer(ctx context.Context, fromWalletID, toWalletID uuid.UUID, amount money.Money, description, idempotencyKey string) error {
+	_, _, err := s.transfer(ctx, fromWalletID, toWalletID, amount, description, idempotencyKey)
+	return err
+}
 
-	outTransaction := &models.Transaction{
-		WalletID:    fromWalletID,
-		Type:        TransactionTypeTransferOut,
-		Amount:      amount,
-		ReferenceID: &referenceID,
-		Description: &description,
-	}
+// TransferResult is a transfer as it was recorded, with both wallets' balances and the
+// fee the sender was charged. Fee is zero when none was charged or the result is a replay.
+type TransferResult struct {
+	Transfer   *models.Transfer
+	FromWallet *models.Wallet
+	ToWallet   *models.Wallet
+	Fee        money.Money
+}
 
-	if err := s.TransactionRepo.CreateTransactionWithTx(ctx, tx, outTransaction); err != nil {
-		return fmt.Errorf("failed to create outbound transaction: %w", err)
+// CreateTransfer is Transfer returning the transfer it recorded and both wallets as
+// the transfer left them. A replayed call returns the transfer first recorded under
+// the idempotency key, with the wallets as they are now.
+func (s *WalletService) CreateTransfer(ctx context.Context, fromWalletID, toWalletID uuid.UUID, amount money.Money, description, idempotencyKey string) (*TransferResult, error) {
+	result, replayed, err := s.transfer(ctx, fromWalletID, toWalletID, amount, description, idempotencyKey)
+	if err != nil {
+		return nil, err
 	}
-
-	inTransaction := &models.Transaction{
-		WalletID:    toWalletID,
-		Type:        TransactionTypeTransferIn,
-		Amount:      amount,
-		ReferenceID: &referenceID,
-		Description: &description,
+	if !replayed {
+		return result, nil
 	}
 
-	if err := s.TransactionRepo.CreateTransactionWithTx(ctx, tx, inTransaction); err != nil {
-		return fmt.Errorf("failed to create inbound transaction: %w", err)
+	journal, err := s.LedgerRepo.GetJournalByIdempotencyKey(ctx, *scopedIdempotencyKey(fromWalletID, idempotencyKey))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recorded transfer: %w", err)
 	}
-
-	return nil
+	result = &TransferResult{}
+	var ok bool
+	if result.Transfer, ok = models.NewTransfer(journal); !ok {
+		return nil, ErrIdempotencyKeyReused
+	}
+	if result.FromWallet, err = s.GetBalance(ctx, result.Transfer.FromWalletID); err != nil {
+		return nil, err
+	}
+	if result.ToWallet, err = s.GetBalance(ctx, result.Transfer.ToWalletID); err != nil {
+		return nil, err
+	}
+	return result, nil
 }
 
-// Transfer money between wallets atomically
-func (s *WalletService) Transfer(ctx context.Context, fromWalletID, toWalletID uuid.UUID, amount decimal.Decimal, description string) error {
+// transfer runs a transfer and reports whether it was a replay, in which case the
+// result is nil
+func (s *WalletService) transfer(ctx context.Context, fromWalletID, toWalletID uuid.UUID, amount money.Money, description, idempotencyKey string) (*TransferResult, bool, error) {
 	if err := s.validateTransferAmount(amount, fromWalletID, toWalletID); err != nil {
-		return err
+		return nil, false, err
+	}
+	if err := s.checkConfirmation(amount); err != nil {
+		return nil, false, err
 	}
 
-	tx, err := s.WalletRepo.BeginTx(ctx)
+	journal := newJournal(models.JournalTypeTransfer, &description, scopedIdempotencyKey(fromWalletID, idempotencyKey),
+		debit(&fromWalletID, amount),
+		credit(&toWalletID, amount),
+	)
+	classify(ctx, journal)
+	fee, err := s.movementFee(ctx, models.JournalTypeTransfer, fromWalletID, amount)
 	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
+		return nil, false, err
 	}
-	defer func() {
-		if err != nil && tx != nil {
-			tx.Rollback()
+
+	var result *TransferResult
+	replayed, err := s.idempotent(ctx, journal, func() error {
+		// A quoted transfer runs at the quote's fee and exchange rate
+		quote, err := s.quotedTransfer(ctx, fromWalletID, toWalletID, amount)
+		if err != nil {
+			return err
+		}
+		ctx := ctx
+		if quote != nil {
+			fee = quote.FeeFunds()
+			ctx = context.WithValue(ctx, quotedTermsKey{}, quote)
 		}
-	}()
 
-	if err := s.transferExecution(ctx, tx, fromWalletID, toWalletID, amount, description); err != nil {
-		return err
+		if err := s.assessRisk(ctx, models.JournalTypeTransfer, fromWalletID, &toWalletID, amount); err != nil {
+			return err
+		}
+		return s.withTx(ctx, "transfer", func(ctx context.Context, tx *sql.Tx) error {
+			from, to, err := s.transferExecution(ctx, tx, fromWalletID, toWalletID, amount, fee, journal)
+			if err != nil {
+				return err
+			}
+			if err := s.awaitAcceptance(ctx, tx, to, journal); err != nil {
+				return err
+			}
+			if quote != nil {
+				if err := s.useQuote(ctx, tx, quote, journal.ID); err != nil {
+					return err
+				}
+			}
+			transfer, _ := models.NewTransfer(journal)
+			result = &TransferResult{Transfer: transfer, FromWallet: from, ToWallet: to, Fee: fee}
+			return nil
+		})
+	})
+	if err != nil || replayed {
+		return nil, replayed, err
+	}
+	return result, false, nil
+}
+
+// SetWalletStatus freezes, unfreezes or closes a wallet. Closing is permanent.
+func (s *WalletService) SetWalletStatus(ctx context.Context, walletID uuid.UUID, status string) (*models.Wallet, error) {
+	if !models.IsValidWalletStatus(status) {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidWalletStatus, status)
 	}
 
-	if tx != nil {
-		if err := tx.Commit(); err != nil {
-			return fmt.Errorf("failed to commit transaction: %w", err)
+	var wallet *models.Wallet
+	err := s.withTx(ctx, "set wallet status", func(ctx context.Context, tx *sql.Tx) error {
+		current, err := s.getWalletForUpdate(ctx, tx, walletID)
+		if err != nil {
+			return fmt.Errorf("failed to get wallet: %w", err)
+		}
+
+		if current.Status == models.WalletStatusClosed && status != models.WalletStatusClosed {
+			return models.ErrWalletClosed
+		}
+
+		if err := s.setStatus(ctx, tx, current, status); err != nil {
+			return err
 		}
+
+		wallet = current
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return nil
+	return wallet, nil
 }
 
 // GetTransactionHistory gets transaction history for a wallet
@@ -306,14 +788,78 @@ func (s *WalletService) GetTransactionHistory(ctx context.Context, walletID uuid
 	// First verify the wallet exists
 	_, err := s.WalletRepo.GetWalletByID(ctx, walletID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get wallet: %w", err)
+		return nil, fmt.Errorf("failed to get wallet: %w", walletLookupError(err))
 	}
 
 	// Get transaction history
-	transactions, err := s.TransactionRepo.GetTransactionsByWalletID(ctx, walletID)
+	transactions, err := s.LedgerRepo.GetTransactionsByWalletID(ctx, walletID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get transaction history: %w", err)
 	}
 
 	return transactions, nil
 }
+
+// ListTransactionHistory pages through the wallet's transactions matching filter,
+// ordered by when they were posted
+func (s *WalletService) ListTransactionHistory(ctx context.Context, walletID uuid.UUID, filter repository.TransactionFilter) ([]*models.Transaction, error) {
+	if _, err := s.WalletRepo.GetWalletByID(ctx, walletID); err != nil {
+		return nil, fmt.Errorf("failed to get wallet: %w", walletLookupError(err))
+	}
+
+	filter.Limit = pagination.ClampLimit(filter.Limit)
+	filter.Category = normalizeCategory(filter.Category)
+	transactions, err := s.LedgerRepo.ListTransactionsByWalletID(ctx, walletID, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction history: %w", err)
+	}
+	return transactions, nil
+}
+
+// GetTransaction returns a transaction from a wallet's history by its ID
+func (s *WalletService) GetTransaction(ctx context.Context, transactionID uuid.UUID) (*models.Transaction, error) {
+	journal, err := s.GetTransactionJournal(ctx, transactionID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range journal.Entries {
+		if entry.ID == transactionID && entry.WalletID != nil {
+			return models.NewTransaction(journal, entry), nil
+		}
+	}
+	// The settlement account's legs are not in any wallet's history
+	return nil, ErrTransactionNotFound
+}
+
+// GetTransfer returns the transfer recorded under referenceID with both of its legs
+func (s *WalletService) GetTransfer(ctx context.Context, referenceID uuid.UUID) (*models.Transfer, error) {
+	journal, err := s.LedgerRepo.GetJournalByID(ctx, referenceID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrTransferNotFound
+		}
+		return nil, fmt.Errorf("failed to get transfer: %w", err)
+	}
+
+	transfer, ok := models.NewTransfer(journal)
+	if !ok {
+		// Deposits, withdrawals and adjustments share the reference IDs but are not transfers
+		return nil, ErrTransferNotFound
+	}
+	return transfer, nil
+}
+
+// ListTransfers returns a page of the wallet's transfers, newest first, each with its
+// direction and counterparty
+func (s *WalletService) ListTransfers(ctx context.Context, walletID uuid.UUID, limit, offset int) ([]*models.WalletTransfer, error) {
+	if _, err := s.WalletRepo.GetWalletByID(ctx, walletID); err != nil {
+		return nil, fmt.Errorf("failed to get wallet: %w", walletLookupError(err))
+	}
+
+	transfers, err := s.LedgerRepo.GetTransfersByWalletID(ctx, walletID, pagination.ClampLimit(limit), max(offset, 0))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list transfers: %w", err)
+	}
+	return transfers, nil
+}
@@ -0,0 +1,144 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+
+	"github.com/shanwije/wallet-app/db/migrations"
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository/sqlite"
+	"github.com/shanwije/wallet-app/internal/testutil"
+	"github.com/shanwije/wallet-app/pkg/db"
+)
+
+// The benchmarks run money movements end to end against a SQLite database, so they
+// measure the service and repositories together: locking, the ledger writes and
+// commit. Run them with
+//
+//	go test ./internal/service -run '^$' -bench . -benchmem
+
+// benchWalletService returns a wallet service on a fresh SQLite database, and wallets
+// funded to move money out of
+func benchWalletService(b *testing.B, optimistic bool, wallets int) (*WalletService, []uuid.UUID) {
+	b.Helper()
+
+	ctx := context.Background()
+	conn, err := db.New(db.Config{Driver: db.DriverSQLite, Name: filepath.Join(b.TempDir(), "wallet.db")})
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { conn.Close() })
+	fsys, err := migrations.ForDriver(db.DriverSQLite)
+	if err != nil {
+		b.Fatal(err)
+	}
+	migrator, err := db.NewMigrator(conn.DB, db.DriverSQLite, fsys)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { migrator.Close() })
+	if _, err := migrator.Up(ctx); err != nil {
+		b.Fatal(err)
+	}
+
+	service := &WalletService{
+		WalletRepo:        sqlite.NewWalletRepository(conn.DB),
+		LedgerRepo:        sqlite.NewLedgerRepository(conn.DB),
+		HoldRepo:          sqlite.NewHoldRepository(conn.DB),
+		OptimisticLocking: optimistic,
+	}
+	users := sqlite.NewUserRepository(conn.DB)
+	ids := make([]uuid.UUID, wallets)
+	for i := range ids {
+		user, err := users.CreateUser(ctx, "Bench User", models.Contact{})
+		if err != nil {
+			b.Fatal(err)
+		}
+		wallet, err := service.WalletRepo.CreateWallet(ctx, user.ID)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err := service.Deposit(ctx, wallet.ID, testutil.USD(decimal.NewFromInt(1_000_000)), ""); err != nil {
+			b.Fatal(err)
+		}
+		ids[i] = wallet.ID
+	}
+	return service, ids
+}
+
+// lockingModes runs a benchmark with row locks and with optimistic locking
+func lockingModes(b *testing.B, bench func(b *testing.B, optimistic bool)) {
+	b.Run("pessimistic", func(b *testing.B) { bench(b, false) })
+	b.Run("optimistic", func(b *testing.B) { bench(b, true) })
+}
+
+func BenchmarkDeposit(b *testing.B) {
+	lockingModes(b, func(b *testing.B, optimistic bool) {
+		service, wallets := benchWalletService(b, optimistic, 1)
+		ctx, amount := context.Background(), testutil.USD(decimal.NewFromInt(10))
+
+		b.ResetTimer()
+		for range b.N {
+			if _, err := service.Deposit(ctx, wallets[0], amount, ""); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkWithdraw(b *testing.B) {
+	lockingModes(b, func(b *testing.B, optimistic bool) {
+		service, wallets := benchWalletService(b, optimistic, 1)
+		ctx, amount := context.Background(), testutil.USD(decimal.NewFromInt(1))
+
+		b.ResetTimer()
+		for range b.N {
+			if _, err := service.Withdraw(ctx, wallets[0], amount, ""); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkTransfer(b *testing.B) {
+	lockingModes(b, func(b *testing.B, optimistic bool) {
+		service, wallets := benchWalletService(b, optimistic, 2)
+		ctx, amount := context.Background(), testutil.USD(decimal.NewFromInt(1))
+
+		b.ResetTimer()
+		for i := range b.N {
+			from, to := wallets[i%2], wallets[(i+1)%2]
+			if err := service.Transfer(ctx, from, to, amount, "", ""); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// BenchmarkTransferContended runs transfers in parallel between a handful of wallets,
+// so most of them wait on a lock another holds, or retry after a conflict
+func BenchmarkTransferContended(b *testing.B) {
+	lockingModes(b, func(b *testing.B, optimistic bool) {
+		service, wallets := benchWalletService(b, optimistic, 4)
+		ctx, amount := context.Background(), testutil.USD(decimal.NewFromInt(1))
+
+		b.ResetTimer()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				from := rand.IntN(len(wallets))
+				to := (from + 1 + rand.IntN(len(wallets)-1)) % len(wallets)
+				// Conflicts that outlast the retries are part of what is measured
+				if err := service.Transfer(ctx, wallets[from], wallets[to], amount, "", ""); err != nil && !errors.Is(err, ErrConcurrentUpdate) {
+					b.Error(err)
+					return
+				}
+			}
+		})
+	})
+}
@@ -0,0 +1,63 @@
+package service
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/pkg/logger"
+)
+
+// WalletCache keeps recently read wallets so that balance reads can skip the database.
+// Entries expire after a short TTL, and every transaction that changes a wallet
+// removes its entry once committed. A read that races a commit can still put the old
+// wallet back, so a cached balance may be stale by up to the TTL; the checks inside
+// money movements always read the database.
+type WalletCache interface {
+	// GetWallet returns the cached wallet, or nil when it is not cached
+	GetWallet(ctx context.Context, id uuid.UUID) (*models.Wallet, error)
+	SetWallet(ctx context.Context, wallet *models.Wallet) error
+	InvalidateWallets(ctx context.Context, ids ...uuid.UUID) error
+}
+
+// cachedWallet reads the wallet through Cache, falling back to the database when there
+// is no cache or it fails
+func (s *WalletService) cachedWallet(ctx context.Context, walletID uuid.UUID) (*models.Wallet, error) {
+	if s.Cache == nil {
+		return s.WalletRepo.GetWalletByID(ctx, walletID)
+	}
+
+	log := logger.FromContext(ctx)
+	wallet, err := s.Cache.GetWallet(ctx, walletID)
+	if err != nil {
+		log.Warn("Reading the wallet cache failed", zap.Error(err))
+	}
+	if wallet != nil {
+		return wallet, nil
+	}
+
+	wallet, err = s.WalletRepo.GetWalletByID(ctx, walletID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Cache.SetWallet(ctx, wallet); err != nil {
+		log.Warn("Writing the wallet cache failed", zap.Error(err))
+	}
+	return wallet, nil
+}
+
+// invalidateWallets removes the wallets a committed transaction changed from Cache
+func (s *WalletService) invalidateWallets(ctx context.Context, changes *txChanges) {
+	if s.Cache == nil || len(changes.wallets) == 0 {
+		return
+	}
+	ids := make([]uuid.UUID, 0, len(changes.wallets))
+	for id := range changes.wallets {
+		ids = append(ids, id)
+	}
+	if err := s.Cache.InvalidateWallets(ctx, ids...); err != nil {
+		logger.FromContext(ctx).Error("Invalidating cached wallets failed", zap.Error(err))
+	}
+}
@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/testutil"
+)
+
+// memoryWalletCache is a WalletCache in a map
+type memoryWalletCache struct {
+	wallets map[uuid.UUID]*models.Wallet
+}
+
+func newMemoryWalletCache() *memoryWalletCache {
+	return &memoryWalletCache{wallets: make(map[uuid.UUID]*models.Wallet)}
+}
+
+func (c *memoryWalletCache) GetWallet(_ context.Context, id uuid.UUID) (*models.Wallet, error) {
+	return c.wallets[id], nil
+}
+
+func (c *memoryWalletCache) SetWallet(_ context.Context, wallet *models.Wallet) error {
+	c.wallets[wallet.ID] = wallet
+	return nil
+}
+
+func (c *memoryWalletCache) InvalidateWallets(_ context.Context, ids ...uuid.UUID) error {
+	for _, id := range ids {
+		delete(c.wallets, id)
+	}
+	return nil
+}
+
+func TestGetBalanceReadsThroughTheCache(t *testing.T) {
+	service, walletRepo, _ := setupWalletService()
+	cache := newMemoryWalletCache()
+	service.Cache = cache
+
+	walletID := uuid.New()
+	walletRepo.On("GetWalletByID", mock.Anything, walletID).Return(testutil.Wallet(walletID, 100.0), nil).Once()
+
+	for i := 0; i < 2; i++ {
+		wallet, err := service.GetBalance(context.Background(), walletID)
+		require.NoError(t, err)
+		assert.True(t, wallet.Balance.Equal(decimal.NewFromInt(100)))
+	}
+	walletRepo.AssertNumberOfCalls(t, "GetWalletByID", 1)
+	assert.Contains(t, cache.wallets, walletID)
+}
+
+func TestCommittedDepositInvalidatesTheCachedWallet(t *testing.T) {
+	service, walletRepo, ledgerRepo := setupWalletService()
+	cache := newMemoryWalletCache()
+	service.Cache = cache
+
+	walletID := uuid.New()
+	require.NoError(t, cache.SetWallet(context.Background(), testutil.Wallet(walletID, 100.0)))
+
+	walletRepo.On("BeginTx", mock.Anything).Return((*sql.Tx)(nil), nil)
+	walletRepo.On("GetWalletByIDWithTx", mock.Anything, (*sql.Tx)(nil), walletID).Return(testutil.Wallet(walletID, 100.0), nil)
+	walletRepo.On("UpdateBalanceWithTx", mock.Anything, (*sql.Tx)(nil), walletID, mock.Anything, mock.Anything).Return(nil)
+	ledgerRepo.On("CreateJournalWithTx", mock.Anything, (*sql.Tx)(nil), mock.AnythingOfType("*models.Journal")).Return(assert.AnError).Once()
+	ledgerRepo.On("CreateJournalWithTx", mock.Anything, (*sql.Tx)(nil), mock.AnythingOfType("*models.Journal")).Return(nil).Once()
+
+	// A rolled back deposit leaves the cache alone
+	_, err := service.Deposit(context.Background(), walletID, testutil.USD(decimal.NewFromInt(50)), "")
+	require.Error(t, err)
+	assert.Contains(t, cache.wallets, walletID)
+
+	_, err = service.Deposit(context.Background(), walletID, testutil.USD(decimal.NewFromInt(50)), "")
+	require.NoError(t, err)
+	assert.NotContains(t, cache.wallets, walletID)
+}
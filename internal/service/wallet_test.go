@@ -4,14 +4,26 @@ package service
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 
+	"github.com/shanwije/wallet-app/internal/fx"
 	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository"
+	"github.com/shanwije/wallet-app/internal/repository/mocks"
+	"github.com/shanwije/wallet-app/internal/testutil"
+	"github.com/shanwije/wallet-app/pkg/clock"
+	"github.com/shanwije/wallet-app/pkg/featureflag"
+	"github.com/shanwije/wallet-app/pkg/money"
+	"github.com/shanwije/wallet-app/pkg/pagination"
 )
 
 // Test fixtures and helper functions
@@ -22,154 +34,96 @@ const (
 )
 
 // setupWalletService creates a test wallet service with mocked dependencies
-func setupWalletService() (*WalletService, *MockWalletRepositoryTest, *MockTransactionRepositoryTest) {
-	walletRepo := new(MockWalletRepositoryTest)
-	transactionRepo := new(MockTransactionRepositoryTest)
+func setupWalletService() (*WalletService, *mocks.WalletRepository, *mocks.LedgerRepository) {
+	walletRepo := new(mocks.WalletRepository)
+	ledgerRepo := new(mocks.LedgerRepository)
 	service := &WalletService{
-		WalletRepo:      walletRepo,
-		TransactionRepo: transactionRepo,
+		WalletRepo: walletRepo,
+		LedgerRepo: ledgerRepo,
 	}
-	return service, walletRepo, transactionRepo
+	return service, walletRepo, ledgerRepo
 }
 
-// createTestWallet creates a wallet for testing
-func createTestWallet(id uuid.UUID, balance float64) *models.Wallet {
-	return &models.Wallet{
-		ID:      id,
-		Balance: decimal.NewFromFloat(balance),
-	}
-}
-
-// MockWalletRepository for testing
-type MockWalletRepositoryTest struct {
-	mock.Mock
-}
-
-func (m *MockWalletRepositoryTest) CreateWallet(ctx context.Context, userID uuid.UUID) (*models.Wallet, error) {
-	args := m.Called(ctx, userID)
-	if args.Get(0) == nil {
-		return nil, args.Error(1)
-	}
-	return args.Get(0).(*models.Wallet), args.Error(1)
-}
-
-func (m *MockWalletRepositoryTest) GetWalletByUserID(ctx context.Context, userID uuid.UUID) (*models.Wallet, error) {
-	args := m.Called(ctx, userID)
-	if args.Get(0) == nil {
-		return nil, args.Error(1)
-	}
-	return args.Get(0).(*models.Wallet), args.Error(1)
-}
-
-func (m *MockWalletRepositoryTest) GetWalletByID(ctx context.Context, id uuid.UUID) (*models.Wallet, error) {
-	args := m.Called(ctx, id)
-	if args.Get(0) == nil {
-		return nil, args.Error(1)
-	}
-	return args.Get(0).(*models.Wallet), args.Error(1)
-}
-
-func (m *MockWalletRepositoryTest) UpdateBalance(ctx context.Context, id uuid.UUID, balance decimal.Decimal) error {
-	args := m.Called(ctx, id, balance)
-	return args.Error(0)
-}
-
-// Mock transaction methods
-func (m *MockWalletRepositoryTest) BeginTx(ctx context.Context) (*sql.Tx, error) {
-	args := m.Called(ctx)
-	return args.Get(0).(*sql.Tx), args.Error(1)
-}
-
-func (m *MockWalletRepositoryTest) UpdateBalanceWithTx(ctx context.Context, tx *sql.Tx, id uuid.UUID, balance decimal.Decimal) error {
-	args := m.Called(ctx, tx, id, balance)
-	return args.Error(0)
-}
-
-func (m *MockWalletRepositoryTest) GetWalletByIDWithTx(ctx context.Context, tx *sql.Tx, id uuid.UUID) (*models.Wallet, error) {
-	args := m.Called(ctx, tx, id)
-	if args.Get(0) == nil {
-		return nil, args.Error(1)
-	}
-	return args.Get(0).(*models.Wallet), args.Error(1)
-}
+func TestWalletDepositValidAmount(t *testing.T) {
+	service, walletRepo, ledgerRepo := setupWalletService()
 
-// MockTransactionRepository for testing
-type MockTransactionRepositoryTest struct {
-	mock.Mock
-}
+	walletID := uuid.New()
+	wallet := testutil.Wallet(walletID, testWalletBalance)
+	depositAmount := decimal.NewFromFloat(testDepositAmount)
+	expectedBalance := decimal.NewFromFloat(testWalletBalance + testDepositAmount)
 
-func (m *MockTransactionRepositoryTest) CreateTransaction(ctx context.Context, transaction *models.Transaction) error {
-	args := m.Called(ctx, transaction)
-	return args.Error(0)
-}
+	walletRepo.On("BeginTx", mock.Anything).Return((*sql.Tx)(nil), nil)
+	walletRepo.On("GetWalletByIDWithTx", mock.Anything, (*sql.Tx)(nil), walletID).Return(wallet, nil)
+	walletRepo.On("UpdateBalanceWithTx", mock.Anything, (*sql.Tx)(nil), walletID, expectedBalance, mock.Anything).Return(nil)
+	ledgerRepo.On("CreateJournalWithTx", mock.Anything, (*sql.Tx)(nil), mock.AnythingOfType("*models.Journal")).Return(nil)
 
-func (m *MockTransactionRepositoryTest) CreateTransactionWithTx(ctx context.Context, tx *sql.Tx, transaction *models.Transaction) error {
-	args := m.Called(ctx, tx, transaction)
-	return args.Error(0)
-}
+	result, err := service.Deposit(context.Background(), walletID, testutil.USD(depositAmount), "")
 
-func (m *MockTransactionRepositoryTest) GetTransactionsByWalletID(ctx context.Context, walletID uuid.UUID) ([]*models.Transaction, error) {
-	args := m.Called(ctx, walletID)
-	return args.Get(0).([]*models.Transaction), args.Error(1)
+	assert.NoError(t, err)
+	assert.NotNil(t, result)
+	assert.True(t, result.Balance.Equal(expectedBalance))
+	walletRepo.AssertExpectations(t)
+	ledgerRepo.AssertExpectations(t)
 }
 
-func TestWalletDepositValidAmount(t *testing.T) {
-	service, walletRepo, transactionRepo := setupWalletService()
+func TestWalletDepositUsesInjectedClock(t *testing.T) {
+	service, walletRepo, ledgerRepo := setupWalletService()
+	frozen := time.Date(2024, 6, 11, 9, 30, 0, 0, time.UTC)
+	service.Clock = clock.NewFake(frozen)
 
 	walletID := uuid.New()
-	wallet := createTestWallet(walletID, testWalletBalance)
-	depositAmount := decimal.NewFromFloat(testDepositAmount)
+	wallet := testutil.Wallet(walletID, testWalletBalance)
 	expectedBalance := decimal.NewFromFloat(testWalletBalance + testDepositAmount)
 
 	walletRepo.On("BeginTx", mock.Anything).Return((*sql.Tx)(nil), nil)
 	walletRepo.On("GetWalletByIDWithTx", mock.Anything, (*sql.Tx)(nil), walletID).Return(wallet, nil)
-	walletRepo.On("UpdateBalanceWithTx", mock.Anything, (*sql.Tx)(nil), walletID, expectedBalance).Return(nil)
-	transactionRepo.On("CreateTransactionWithTx", mock.Anything, (*sql.Tx)(nil), mock.AnythingOfType("*models.Transaction")).Return(nil)
+	walletRepo.On("UpdateBalanceWithTx", mock.Anything, (*sql.Tx)(nil), walletID, expectedBalance, mock.Anything).Return(nil)
+	ledgerRepo.On("CreateJournalWithTx", mock.Anything, (*sql.Tx)(nil), mock.MatchedBy(func(journal *models.Journal) bool {
+		return journal.CreatedAt.Equal(frozen)
+	})).Return(nil)
 
-	result, err := service.Deposit(context.Background(), walletID, depositAmount)
+	_, err := service.Deposit(context.Background(), walletID, testutil.USD(decimal.NewFromFloat(testDepositAmount)), "")
 
 	assert.NoError(t, err)
-	assert.NotNil(t, result)
-	assert.True(t, result.Balance.Equal(expectedBalance))
-	walletRepo.AssertExpectations(t)
-	transactionRepo.AssertExpectations(t)
+	ledgerRepo.AssertExpectations(t)
 }
 
 func TestWalletWithdrawValidAmount(t *testing.T) {
-	service, walletRepo, transactionRepo := setupWalletService()
+	service, walletRepo, ledgerRepo := setupWalletService()
 
 	walletID := uuid.New()
-	wallet := createTestWallet(walletID, testWalletBalance)
+	wallet := testutil.Wallet(walletID, testWalletBalance)
 	withdrawAmount := decimal.NewFromFloat(testWithdrawAmount)
 	expectedBalance := decimal.NewFromFloat(testWalletBalance - testWithdrawAmount)
 
 	walletRepo.On("BeginTx", mock.Anything).Return((*sql.Tx)(nil), nil)
 	walletRepo.On("GetWalletByIDWithTx", mock.Anything, (*sql.Tx)(nil), walletID).Return(wallet, nil)
-	walletRepo.On("UpdateBalanceWithTx", mock.Anything, (*sql.Tx)(nil), walletID, expectedBalance).Return(nil)
-	transactionRepo.On("CreateTransactionWithTx", mock.Anything, (*sql.Tx)(nil), mock.AnythingOfType("*models.Transaction")).Return(nil)
+	walletRepo.On("UpdateBalanceWithTx", mock.Anything, (*sql.Tx)(nil), walletID, expectedBalance, mock.Anything).Return(nil)
+	ledgerRepo.On("CreateJournalWithTx", mock.Anything, (*sql.Tx)(nil), mock.AnythingOfType("*models.Journal")).Return(nil)
 
-	result, err := service.Withdraw(context.Background(), walletID, withdrawAmount)
+	result, err := service.Withdraw(context.Background(), walletID, testutil.USD(withdrawAmount), "")
 
 	assert.NoError(t, err)
 	assert.NotNil(t, result)
 	assert.True(t, result.Balance.Equal(expectedBalance))
 	walletRepo.AssertExpectations(t)
-	transactionRepo.AssertExpectations(t)
+	ledgerRepo.AssertExpectations(t)
 }
 
 func TestWalletWithdrawInsufficientBalance(t *testing.T) {
-	walletRepo := new(MockWalletRepositoryTest)
-	transactionRepo := new(MockTransactionRepositoryTest)
+	walletRepo := new(mocks.WalletRepository)
+	ledgerRepo := new(mocks.LedgerRepository)
 
 	service := &WalletService{
-		WalletRepo:      walletRepo,
-		TransactionRepo: transactionRepo,
+		WalletRepo: walletRepo,
+		LedgerRepo: ledgerRepo,
 	}
 
 	walletID := uuid.New()
 	wallet := &models.Wallet{
-		ID:      walletID,
-		Balance: decimal.NewFromFloat(30.0),
+		ID:       walletID,
+		Balance:  decimal.NewFromFloat(30.0),
+		Currency: money.USD,
 	}
 
 	withdrawAmount := decimal.NewFromFloat(50.0)
@@ -177,22 +131,23 @@ func TestWalletWithdrawInsufficientBalance(t *testing.T) {
 	walletRepo.On("BeginTx", mock.Anything).Return((*sql.Tx)(nil), nil)
 	walletRepo.On("GetWalletByIDWithTx", mock.Anything, (*sql.Tx)(nil), walletID).Return(wallet, nil)
 
-	result, err := service.Withdraw(context.Background(), walletID, withdrawAmount)
+	result, err := service.Withdraw(context.Background(), walletID, testutil.USD(withdrawAmount), "")
 
 	assert.Error(t, err)
 	assert.Nil(t, result)
-	assert.Contains(t, err.Error(), "insufficient balance")
+	assert.ErrorIs(t, err, ErrInsufficientBalance)
 	walletRepo.AssertExpectations(t)
 }
 
 func TestWalletGetBalance(t *testing.T) {
-	walletRepo := new(MockWalletRepositoryTest)
+	walletRepo := new(mocks.WalletRepository)
 	service := &WalletService{WalletRepo: walletRepo}
 
 	walletID := uuid.New()
 	wallet := &models.Wallet{
-		ID:      walletID,
-		Balance: decimal.NewFromFloat(100.0),
+		ID:       walletID,
+		Balance:  decimal.NewFromFloat(100.0),
+		Currency: money.USD,
 	}
 
 	walletRepo.On("GetWalletByID", mock.Anything, walletID).Return(wallet, nil)
@@ -205,53 +160,130 @@ func TestWalletGetBalance(t *testing.T) {
 	walletRepo.AssertExpectations(t)
 }
 
+func TestWalletLookupsReportMissingWallet(t *testing.T) {
+	service, walletRepo, _ := setupWalletService()
+
+	missingID := uuid.New()
+	toWalletID := uuid.New()
+	walletRepo.On("GetWalletByID", mock.Anything, missingID).Return(nil, fmt.Errorf("wallet %w", repository.ErrNotFound))
+	walletRepo.On("BeginTx", mock.Anything).Return((*sql.Tx)(nil), nil)
+	walletRepo.On("GetWalletByIDWithTx", mock.Anything, (*sql.Tx)(nil), missingID).Return(nil, fmt.Errorf("wallet %w", repository.ErrNotFound))
+	walletRepo.On("GetWalletByIDWithTx", mock.Anything, (*sql.Tx)(nil), toWalletID).Return(testutil.Wallet(toWalletID, 0), nil)
+
+	_, err := service.GetBalance(context.Background(), missingID)
+	assert.ErrorIs(t, err, ErrWalletNotFound)
+
+	err = service.Transfer(context.Background(), toWalletID, missingID, testutil.USD(decimal.NewFromInt(5)), "Test", "")
+	assert.ErrorIs(t, err, ErrWalletNotFound)
+	assert.Contains(t, err.Error(), "destination")
+
+	// Other repository failures are not mistaken for a missing wallet
+	otherID := uuid.New()
+	walletRepo.On("GetWalletByID", mock.Anything, otherID).Return(nil, errors.New("connection refused"))
+	_, err = service.GetBalance(context.Background(), otherID)
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrWalletNotFound)
+}
+
 func TestWalletDepositNegativeAmount(t *testing.T) {
-	walletRepo := new(MockWalletRepositoryTest)
-	transactionRepo := new(MockTransactionRepositoryTest)
+	walletRepo := new(mocks.WalletRepository)
+	ledgerRepo := new(mocks.LedgerRepository)
 	service := &WalletService{
-		WalletRepo:      walletRepo,
-		TransactionRepo: transactionRepo,
+		WalletRepo: walletRepo,
+		LedgerRepo: ledgerRepo,
 	}
 
 	walletID := uuid.New()
 	negativeAmount := decimal.NewFromFloat(-10.0)
 
-	result, err := service.Deposit(context.Background(), walletID, negativeAmount)
+	result, err := service.Deposit(context.Background(), walletID, testutil.USD(negativeAmount), "")
 
 	assert.Error(t, err)
 	assert.Nil(t, result)
-	assert.Contains(t, err.Error(), "deposit amount must be positive")
+	assert.ErrorIs(t, err, ErrInvalidAmount)
 }
 
 func TestWalletTransferValidation(t *testing.T) {
-	walletRepo := new(MockWalletRepositoryTest)
-	transactionRepo := new(MockTransactionRepositoryTest)
+	walletRepo := new(mocks.WalletRepository)
+	ledgerRepo := new(mocks.LedgerRepository)
 	service := &WalletService{
-		WalletRepo:      walletRepo,
-		TransactionRepo: transactionRepo,
+		WalletRepo: walletRepo,
+		LedgerRepo: ledgerRepo,
 	}
 
 	fromWalletID := uuid.New()
 	toWalletID := uuid.New()
 
 	// Test negative amount
-	err := service.Transfer(context.Background(), fromWalletID, toWalletID, decimal.NewFromFloat(-10.0), "Test")
+	err := service.Transfer(context.Background(), fromWalletID, toWalletID, testutil.USD(decimal.NewFromFloat(-10.0)), "Test", "")
 	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "transfer amount must be positive")
+	assert.ErrorIs(t, err, ErrInvalidAmount)
 
 	// Test same wallet transfer
-	err = service.Transfer(context.Background(), fromWalletID, fromWalletID, decimal.NewFromFloat(10.0), "Test")
+	err = service.Transfer(context.Background(), fromWalletID, fromWalletID, testutil.USD(decimal.NewFromFloat(10.0)), "Test", "")
 	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "cannot transfer to the same wallet")
+	assert.ErrorIs(t, err, ErrSameWallet)
+}
+
+func TestWalletAmountsMustBeInRange(t *testing.T) {
+	minimum, maximum := decimal.NewFromInt(1), decimal.NewFromInt(500)
+	service := &WalletService{
+		WalletRepo: new(mocks.WalletRepository),
+		LedgerRepo: new(mocks.LedgerRepository),
+		MinAmount:  &minimum,
+		MaxAmount:  &maximum,
+	}
+	ctx := context.Background()
+
+	_, err := service.Deposit(ctx, uuid.New(), testutil.USD(decimal.RequireFromString("0.50")), "")
+	assert.ErrorIs(t, err, ErrAmountOutOfRange)
+	var rangeErr *AmountRangeError
+	require.ErrorAs(t, err, &rangeErr)
+	assert.Equal(t, "1", rangeErr.Min.String())
+	assert.Equal(t, "500", rangeErr.Max.String())
+
+	err = service.Transfer(ctx, uuid.New(), uuid.New(), testutil.USD(decimal.NewFromInt(501)), "Test", "")
+	assert.ErrorIs(t, err, ErrAmountOutOfRange)
+	assert.EqualError(t, err, "amount out of range: 501.00 USD is not between 1 and 500")
+
+	// Only one side may be bounded
+	service.MinAmount = nil
+	err = service.Transfer(ctx, uuid.New(), uuid.New(), testutil.USD(decimal.NewFromInt(501)), "Test", "")
+	assert.EqualError(t, err, "amount out of range: 501.00 USD is above the maximum of 500")
+}
+
+func TestTransfersOverTheConfirmationThresholdAreRefused(t *testing.T) {
+	threshold := decimal.NewFromInt(100)
+	service := &WalletService{
+		WalletRepo:            new(mocks.WalletRepository),
+		LedgerRepo:            new(mocks.LedgerRepository),
+		ConfirmationThreshold: &threshold,
+	}
+	scheduler := &ScheduledTransferService{Repo: new(mocks.ScheduledTransferRepository), Wallets: service}
+	ctx := context.Background()
+	fromWalletID, toWalletID := uuid.New(), uuid.New()
+
+	err := service.Transfer(ctx, fromWalletID, toWalletID, testutil.USD(decimal.NewFromInt(101)), "Test", "")
+	assert.ErrorIs(t, err, ErrConfirmationRequired)
+
+	// Batch items are counted together
+	_, err = service.BatchTransfer(ctx, fromWalletID, []BatchTransferItem{
+		{ToWalletID: toWalletID, Amount: testutil.USD(decimal.NewFromInt(60))},
+		{ToWalletID: uuid.New(), Amount: testutil.USD(decimal.NewFromInt(60))},
+	}, "")
+	assert.ErrorIs(t, err, ErrConfirmationRequired)
+
+	_, err = scheduler.Schedule(ctx, fromWalletID, toWalletID, testutil.USD(decimal.NewFromInt(101)), "Rent", time.Time{}, models.FrequencyMonthly)
+	assert.ErrorIs(t, err, ErrConfirmationRequired)
 }
 
 func TestWalletTransferInsufficientBalance(t *testing.T) {
-	walletRepo := new(MockWalletRepositoryTest)
-	transactionRepo := new(MockTransactionRepositoryTest)
+	walletRepo := new(mocks.WalletRepository)
+	ledgerRepo := new(mocks.LedgerRepository)
 
 	service := &WalletService{
-		WalletRepo:      walletRepo,
-		TransactionRepo: transactionRepo,
+		WalletRepo: walletRepo,
+		LedgerRepo: ledgerRepo,
 	}
 
 	fromWalletID := uuid.New()
@@ -259,58 +291,45 @@ func TestWalletTransferInsufficientBalance(t *testing.T) {
 	transferAmount := decimal.NewFromFloat(150.0)
 
 	fromWallet := &models.Wallet{
-		ID:      fromWalletID,
-		Balance: decimal.NewFromFloat(100.0),
+		ID:       fromWalletID,
+		Balance:  decimal.NewFromFloat(100.0),
+		Currency: money.USD,
 	}
 
 	toWallet := &models.Wallet{
-		ID:      toWalletID,
-		Balance: decimal.NewFromFloat(25.0),
+		ID:       toWalletID,
+		Balance:  decimal.NewFromFloat(25.0),
+		Currency: money.USD,
 	}
 
 	walletRepo.On("BeginTx", mock.Anything).Return((*sql.Tx)(nil), nil)
 	walletRepo.On("GetWalletByIDWithTx", mock.Anything, (*sql.Tx)(nil), fromWalletID).Return(fromWallet, nil)
 	walletRepo.On("GetWalletByIDWithTx", mock.Anything, (*sql.Tx)(nil), toWalletID).Return(toWallet, nil)
 
-	err := service.Transfer(context.Background(), fromWalletID, toWalletID, transferAmount, "Test transfer")
+	err := service.Transfer(context.Background(), fromWalletID, toWalletID, testutil.USD(transferAmount), "Test transfer", "")
 
 	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "insufficient balance")
+	assert.ErrorIs(t, err, ErrInsufficientBalance)
 	walletRepo.AssertExpectations(t)
 }
 
 func TestWalletGetTransactionHistory(t *testing.T) {
-	walletRepo := new(MockWalletRepositoryTest)
-	transactionRepo := new(MockTransactionRepositoryTest)
+	walletRepo := new(mocks.WalletRepository)
+	ledgerRepo := new(mocks.LedgerRepository)
 	service := &WalletService{
-		WalletRepo:      walletRepo,
-		TransactionRepo: transactionRepo,
+		WalletRepo: walletRepo,
+		LedgerRepo: ledgerRepo,
 	}
 
 	walletID := uuid.New()
-	wallet := &models.Wallet{
-		ID:      walletID,
-		Balance: decimal.NewFromFloat(100.0),
-	}
-
-	// Mock transactions
+	wallet := testutil.Wallet(walletID, 100.0)
 	transactions := []*models.Transaction{
-		{
-			ID:       uuid.New(),
-			WalletID: walletID,
-			Type:     "deposit",
-			Amount:   decimal.NewFromFloat(50.0),
-		},
-		{
-			ID:       uuid.New(),
-			WalletID: walletID,
-			Type:     "withdraw",
-			Amount:   decimal.NewFromFloat(25.0),
-		},
+		testutil.Transaction(walletID, models.TransactionTypeDeposit, 50.0),
+		testutil.Transaction(walletID, models.TransactionTypeWithdraw, 25.0),
 	}
 
 	walletRepo.On("GetWalletByID", mock.Anything, walletID).Return(wallet, nil)
-	transactionRepo.On("GetTransactionsByWalletID", mock.Anything, walletID).Return(transactions, nil)
+	ledgerRepo.On("GetTransactionsByWalletID", mock.Anything, walletID).Return(transactions, nil)
 
 	result, err := service.GetTransactionHistory(context.Background(), walletID)
 
@@ -320,68 +339,68 @@ func TestWalletGetTransactionHistory(t *testing.T) {
 	assert.Equal(t, "deposit", result[0].Type)
 	assert.Equal(t, "withdraw", result[1].Type)
 	walletRepo.AssertExpectations(t)
-	transactionRepo.AssertExpectations(t)
+	ledgerRepo.AssertExpectations(t)
 }
 
 // Tests for assignment requirements - edge cases and validation
 
 func TestWalletDepositZeroAmount(t *testing.T) {
-	walletRepo := new(MockWalletRepositoryTest)
-	transactionRepo := new(MockTransactionRepositoryTest)
+	walletRepo := new(mocks.WalletRepository)
+	ledgerRepo := new(mocks.LedgerRepository)
 	service := &WalletService{
-		WalletRepo:      walletRepo,
-		TransactionRepo: transactionRepo,
+		WalletRepo: walletRepo,
+		LedgerRepo: ledgerRepo,
 	}
 
 	walletID := uuid.New()
 	zeroAmount := decimal.Zero
 
-	result, err := service.Deposit(context.Background(), walletID, zeroAmount)
+	result, err := service.Deposit(context.Background(), walletID, testutil.USD(zeroAmount), "")
 
 	assert.Error(t, err)
 	assert.Nil(t, result)
-	assert.Contains(t, err.Error(), "deposit amount must be positive")
+	assert.ErrorIs(t, err, ErrInvalidAmount)
 }
 
 func TestWalletWithdrawZeroAmount(t *testing.T) {
-	walletRepo := new(MockWalletRepositoryTest)
-	transactionRepo := new(MockTransactionRepositoryTest)
+	walletRepo := new(mocks.WalletRepository)
+	ledgerRepo := new(mocks.LedgerRepository)
 	service := &WalletService{
-		WalletRepo:      walletRepo,
-		TransactionRepo: transactionRepo,
+		WalletRepo: walletRepo,
+		LedgerRepo: ledgerRepo,
 	}
 
 	walletID := uuid.New()
-	wallet := createTestWallet(walletID, testWalletBalance)
+	wallet := testutil.Wallet(walletID, testWalletBalance)
 	zeroAmount := decimal.Zero
 
 	walletRepo.On("BeginTx", mock.Anything).Return((*sql.Tx)(nil), nil)
 	walletRepo.On("GetWalletByIDWithTx", mock.Anything, (*sql.Tx)(nil), walletID).Return(wallet, nil)
 
-	result, err := service.Withdraw(context.Background(), walletID, zeroAmount)
+	result, err := service.Withdraw(context.Background(), walletID, testutil.USD(zeroAmount), "")
 
 	assert.Error(t, err)
 	assert.Nil(t, result)
-	assert.Contains(t, err.Error(), "withdraw amount must be positive")
+	assert.ErrorIs(t, err, ErrInvalidAmount)
 	walletRepo.AssertExpectations(t)
 }
 
 func TestWalletTransferZeroAmount(t *testing.T) {
-	walletRepo := new(MockWalletRepositoryTest)
-	transactionRepo := new(MockTransactionRepositoryTest)
+	walletRepo := new(mocks.WalletRepository)
+	ledgerRepo := new(mocks.LedgerRepository)
 	service := &WalletService{
-		WalletRepo:      walletRepo,
-		TransactionRepo: transactionRepo,
+		WalletRepo: walletRepo,
+		LedgerRepo: ledgerRepo,
 	}
 
 	fromWalletID := uuid.New()
 	toWalletID := uuid.New()
 	zeroAmount := decimal.Zero
 
-	err := service.Transfer(context.Background(), fromWalletID, toWalletID, zeroAmount, "Test")
+	err := service.Transfer(context.Background(), fromWalletID, toWalletID, testutil.USD(zeroAmount), "Test", "")
 
 	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "transfer amount must be positive")
+	assert.ErrorIs(t, err, ErrInvalidAmount)
 }
 
 func TestWalletDecimalPrecision(t *testing.T) {
@@ -414,3 +433,502 @@ func TestMoneyFormatting(t *testing.T) {
 	assert.True(t, zero.IsZero())
 	assert.Equal(t, "0", zero.String())
 }
+
+func TestWalletDepositCurrencyMismatch(t *testing.T) {
+	service, walletRepo, _ := setupWalletService()
+
+	walletID := uuid.New()
+	wallet := testutil.Wallet(walletID, testWalletBalance)
+
+	walletRepo.On("BeginTx", mock.Anything).Return((*sql.Tx)(nil), nil)
+	walletRepo.On("GetWalletByIDWithTx", mock.Anything, (*sql.Tx)(nil), walletID).Return(wallet, nil)
+
+	result, err := service.Deposit(context.Background(), walletID, money.New(decimal.NewFromFloat(testDepositAmount), money.EUR), "")
+
+	assert.Error(t, err)
+	assert.Nil(t, result)
+	assert.ErrorIs(t, err, money.ErrCurrencyMismatch)
+	walletRepo.AssertNotCalled(t, "UpdateBalanceWithTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestWalletDepositRejectsCancelledContext(t *testing.T) {
+	service, walletRepo, _ := setupWalletService()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	result, err := service.Deposit(ctx, uuid.New(), testutil.USD(decimal.NewFromFloat(testDepositAmount)), "")
+
+	assert.Nil(t, result)
+	assert.ErrorIs(t, err, context.Canceled)
+	walletRepo.AssertNotCalled(t, "BeginTx", mock.Anything)
+}
+
+func TestWalletDepositSurvivesClientDisconnect(t *testing.T) {
+	service, walletRepo, ledgerRepo := setupWalletService()
+
+	walletID := uuid.New()
+	wallet := testutil.Wallet(walletID, testWalletBalance)
+	expectedBalance := decimal.NewFromFloat(testWalletBalance + testDepositAmount)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	walletRepo.On("BeginTx", mock.Anything).Return((*sql.Tx)(nil), nil)
+	// The client disconnects after the wallet row has been locked
+	walletRepo.On("GetWalletByIDWithTx", mock.Anything, (*sql.Tx)(nil), walletID).
+		Run(func(mock.Arguments) { cancel() }).
+		Return(wallet, nil)
+	walletRepo.On("UpdateBalanceWithTx", mock.MatchedBy(func(txCtx context.Context) bool {
+		return txCtx.Err() == nil
+	}), (*sql.Tx)(nil), walletID, expectedBalance, mock.Anything).Return(nil)
+	ledgerRepo.On("CreateJournalWithTx", mock.Anything, (*sql.Tx)(nil), mock.AnythingOfType("*models.Journal")).Return(nil)
+
+	result, err := service.Deposit(ctx, walletID, testutil.USD(decimal.NewFromFloat(testDepositAmount)), "")
+
+	assert.NoError(t, err)
+	assert.True(t, result.Balance.Equal(expectedBalance))
+	walletRepo.AssertExpectations(t)
+	ledgerRepo.AssertExpectations(t)
+}
+
+func TestWalletDepositRecordsBalancedJournal(t *testing.T) {
+	service, walletRepo, ledgerRepo := setupWalletService()
+
+	walletID := uuid.New()
+	wallet := testutil.Wallet(walletID, testWalletBalance)
+	expectedBalance := decimal.NewFromFloat(testWalletBalance + testDepositAmount)
+
+	var recorded *models.Journal
+	walletRepo.On("BeginTx", mock.Anything).Return((*sql.Tx)(nil), nil)
+	walletRepo.On("GetWalletByIDWithTx", mock.Anything, (*sql.Tx)(nil), walletID).Return(wallet, nil)
+	walletRepo.On("UpdateBalanceWithTx", mock.Anything, (*sql.Tx)(nil), walletID, expectedBalance, mock.Anything).Return(nil)
+	ledgerRepo.On("CreateJournalWithTx", mock.Anything, (*sql.Tx)(nil), mock.AnythingOfType("*models.Journal")).
+		Run(func(args mock.Arguments) { recorded = args.Get(2).(*models.Journal) }).
+		Return(nil)
+
+	_, err := service.Deposit(context.Background(), walletID, testutil.USD(decimal.NewFromFloat(testDepositAmount)), "")
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.JournalTypeDeposit, recorded.Type)
+	assert.NoError(t, recorded.Validate())
+	assert.Len(t, recorded.Entries, 2)
+	assert.Nil(t, recorded.Entries[0].WalletID, "deposit is funded by the external account")
+	assert.Equal(t, models.EntryDirectionDebit, recorded.Entries[0].Direction)
+	assert.Equal(t, walletID, *recorded.Entries[1].WalletID)
+	assert.Equal(t, models.EntryDirectionCredit, recorded.Entries[1].Direction)
+}
+
+func TestWalletTransferRecordsSingleJournal(t *testing.T) {
+	service, walletRepo, ledgerRepo := setupWalletService()
+
+	fromWalletID := uuid.New()
+	toWalletID := uuid.New()
+	transferAmount := decimal.NewFromFloat(40.0)
+
+	walletRepo.On("BeginTx", mock.Anything).Return((*sql.Tx)(nil), nil)
+	walletRepo.On("GetWalletByIDWithTx", mock.Anything, (*sql.Tx)(nil), fromWalletID).Return(testutil.Wallet(fromWalletID, 100.0), nil)
+	walletRepo.On("GetWalletByIDWithTx", mock.Anything, (*sql.Tx)(nil), toWalletID).Return(testutil.Wallet(toWalletID, 10.0), nil)
+	walletRepo.On("UpdateBalanceWithTx", mock.Anything, (*sql.Tx)(nil), fromWalletID, decimal.NewFromFloat(60.0), mock.Anything).Return(nil)
+	walletRepo.On("UpdateBalanceWithTx", mock.Anything, (*sql.Tx)(nil), toWalletID, decimal.NewFromFloat(50.0), mock.Anything).Return(nil)
+	ledgerRepo.On("CreateJournalWithTx", mock.Anything, (*sql.Tx)(nil), mock.MatchedBy(func(journal *models.Journal) bool {
+		return journal.Type == models.JournalTypeTransfer &&
+			journal.Validate() == nil &&
+			len(journal.Entries) == 2 &&
+			*journal.Entries[0].WalletID == fromWalletID &&
+			*journal.Entries[1].WalletID == toWalletID
+	})).Return(nil).Once()
+
+	err := service.Transfer(context.Background(), fromWalletID, toWalletID, testutil.USD(transferAmount), "Rent", "")
+
+	assert.NoError(t, err)
+	ledgerRepo.AssertExpectations(t)
+}
+
+func TestWalletTransferConvertsBetweenCurrencies(t *testing.T) {
+	service, walletRepo, ledgerRepo := setupWalletService()
+	rates, err := fx.ParseStaticRates("USD/EUR=0.92")
+	require.NoError(t, err)
+	service.FX = rates
+
+	fromWalletID := uuid.New()
+	toWalletID := uuid.New()
+	toWallet := testutil.Wallet(toWalletID, 5.0)
+	toWallet.Currency = money.EUR
+
+	var journal *models.Journal
+	walletRepo.On("BeginTx", mock.Anything).Return((*sql.Tx)(nil), nil)
+	walletRepo.On("GetWalletByIDWithTx", mock.Anything, (*sql.Tx)(nil), fromWalletID).Return(testutil.Wallet(fromWalletID, 100.0), nil)
+	walletRepo.On("GetWalletByIDWithTx", mock.Anything, (*sql.Tx)(nil), toWalletID).Return(toWallet, nil)
+	walletRepo.On("UpdateBalanceWithTx", mock.Anything, (*sql.Tx)(nil), fromWalletID, mock.MatchedBy(decimal.NewFromInt(75).Equal), mock.Anything).Return(nil)
+	walletRepo.On("UpdateBalanceWithTx", mock.Anything, (*sql.Tx)(nil), toWalletID, mock.MatchedBy(decimal.NewFromInt(28).Equal), mock.Anything).Return(nil)
+	ledgerRepo.On("CreateJournalWithTx", mock.Anything, (*sql.Tx)(nil), mock.AnythingOfType("*models.Journal")).
+		Run(func(args mock.Arguments) { journal = args.Get(2).(*models.Journal) }).
+		Return(nil)
+
+	err = service.Transfer(context.Background(), fromWalletID, toWalletID, testutil.USD(decimal.NewFromInt(25)), "Rent", "")
+
+	require.NoError(t, err)
+	require.NotNil(t, journal)
+	require.NoError(t, journal.Validate())
+	require.Len(t, journal.Entries, 4)
+
+	sent, received := journal.Entries[0], journal.Entries[1]
+	assert.Equal(t, fromWalletID, *sent.WalletID)
+	assert.Equal(t, money.USD, sent.Currency)
+	assert.True(t, sent.CounterAmount.Equal(decimal.NewFromInt(23)))
+	assert.Equal(t, money.EUR, *sent.CounterCurrency)
+	assert.Equal(t, toWalletID, *received.WalletID)
+	assert.Equal(t, money.EUR, received.Currency)
+	assert.True(t, received.Amount.Equal(decimal.NewFromInt(23)))
+	assert.True(t, received.CounterAmount.Equal(decimal.NewFromInt(25)))
+	assert.True(t, received.ExchangeRate.Equal(decimal.RequireFromString("0.92")))
+
+	// A retry is recognised as the same transfer even once the rate has moved
+	requested := newJournal(models.JournalTypeTransfer, nil, nil,
+		debit(&fromWalletID, testutil.USD(decimal.NewFromInt(25))),
+		credit(&toWalletID, testutil.USD(decimal.NewFromInt(25))),
+	)
+	assert.True(t, sameMovement(journal, requested))
+}
+
+func TestWalletTransferBetweenCurrenciesNeedsRates(t *testing.T) {
+	service, walletRepo, _ := setupWalletService()
+
+	fromWalletID := uuid.New()
+	toWalletID := uuid.New()
+	toWallet := testutil.Wallet(toWalletID, 0)
+	toWallet.Currency = money.EUR
+
+	walletRepo.On("BeginTx", mock.Anything).Return((*sql.Tx)(nil), nil)
+	walletRepo.On("GetWalletByIDWithTx", mock.Anything, (*sql.Tx)(nil), fromWalletID).Return(testutil.Wallet(fromWalletID, 100.0), nil)
+	walletRepo.On("GetWalletByIDWithTx", mock.Anything, (*sql.Tx)(nil), toWalletID).Return(toWallet, nil)
+
+	err := service.Transfer(context.Background(), fromWalletID, toWalletID, testutil.USD(decimal.NewFromInt(25)), "Rent", "")
+	assert.ErrorIs(t, err, money.ErrCurrencyMismatch)
+
+	rates, err := fx.ParseStaticRates("")
+	require.NoError(t, err)
+	service.FX = rates
+	err = service.Transfer(context.Background(), fromWalletID, toWalletID, testutil.USD(decimal.NewFromInt(25)), "Rent", "")
+	assert.ErrorIs(t, err, fx.ErrRateUnavailable)
+	walletRepo.AssertNotCalled(t, "UpdateBalanceWithTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestVerifyWalletBalance(t *testing.T) {
+	service, walletRepo, ledgerRepo := setupWalletService()
+
+	walletID := uuid.New()
+	walletRepo.On("GetWalletByID", mock.Anything, walletID).Return(testutil.Wallet(walletID, 100.0), nil)
+
+	ledgerRepo.On("GetWalletLedgerBalance", mock.Anything, walletID).Return(decimal.NewFromInt(100), nil).Once()
+	assert.NoError(t, service.VerifyWalletBalance(context.Background(), walletID))
+
+	ledgerRepo.On("GetWalletLedgerBalance", mock.Anything, walletID).Return(decimal.NewFromInt(90), nil).Once()
+	assert.ErrorIs(t, service.VerifyWalletBalance(context.Background(), walletID), ErrLedgerMismatch)
+}
+
+func TestWalletDepositReplaysIdempotencyKey(t *testing.T) {
+	service, walletRepo, ledgerRepo := setupWalletService()
+
+	walletID := uuid.New()
+	amount := testutil.USD(decimal.NewFromFloat(testDepositAmount))
+	recorded := newJournal(models.JournalTypeDeposit, nil, scopedIdempotencyKey(walletID, "key-1"),
+		debit(nil, amount),
+		credit(&walletID, amount),
+	)
+
+	ledgerRepo.On("GetJournalByIdempotencyKey", mock.Anything, walletID.String()+":key-1").Return(recorded, nil)
+	walletRepo.On("GetWalletByID", mock.Anything, walletID).Return(testutil.Wallet(walletID, testWalletBalance), nil)
+
+	result, err := service.Deposit(context.Background(), walletID, amount, "key-1")
+
+	assert.NoError(t, err)
+	assert.True(t, result.Balance.Equal(decimal.NewFromFloat(testWalletBalance)))
+	walletRepo.AssertNotCalled(t, "BeginTx", mock.Anything)
+	ledgerRepo.AssertNotCalled(t, "CreateJournalWithTx", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestWalletDepositRejectsReusedIdempotencyKey(t *testing.T) {
+	service, walletRepo, ledgerRepo := setupWalletService()
+
+	walletID := uuid.New()
+	earlier := testutil.USD(decimal.NewFromFloat(10.0))
+	recorded := newJournal(models.JournalTypeDeposit, nil, scopedIdempotencyKey(walletID, "key-1"),
+		debit(nil, earlier),
+		credit(&walletID, earlier),
+	)
+
+	ledgerRepo.On("GetJournalByIdempotencyKey", mock.Anything, walletID.String()+":key-1").Return(recorded, nil)
+
+	result, err := service.Deposit(context.Background(), walletID, testutil.USD(decimal.NewFromFloat(testDepositAmount)), "key-1")
+
+	assert.Nil(t, result)
+	assert.ErrorIs(t, err, ErrIdempotencyKeyReused)
+	walletRepo.AssertNotCalled(t, "BeginTx", mock.Anything)
+}
+
+func TestWalletTransferConcurrentRetryReplays(t *testing.T) {
+	service, walletRepo, ledgerRepo := setupWalletService()
+
+	fromWalletID := uuid.New()
+	toWalletID := uuid.New()
+	amount := testutil.USD(decimal.NewFromFloat(40.0))
+	key := fromWalletID.String() + ":key-1"
+	committed := newJournal(models.JournalTypeTransfer, nil, &key,
+		debit(&fromWalletID, amount),
+		credit(&toWalletID, amount),
+	)
+
+	// The first lookup misses, then a concurrent request with the same key commits first
+	ledgerRepo.On("GetJournalByIdempotencyKey", mock.Anything, key).Return(nil, repository.ErrNotFound).Once()
+	walletRepo.On("BeginTx", mock.Anything).Return((*sql.Tx)(nil), nil)
+	walletRepo.On("GetWalletByIDWithTx", mock.Anything, (*sql.Tx)(nil), fromWalletID).Return(testutil.Wallet(fromWalletID, 100.0), nil)
+	walletRepo.On("GetWalletByIDWithTx", mock.Anything, (*sql.Tx)(nil), toWalletID).Return(testutil.Wallet(toWalletID, 10.0), nil)
+	walletRepo.On("UpdateBalanceWithTx", mock.Anything, (*sql.Tx)(nil), mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	ledgerRepo.On("CreateJournalWithTx", mock.Anything, (*sql.Tx)(nil), mock.AnythingOfType("*models.Journal")).Return(repository.ErrDuplicate)
+	ledgerRepo.On("GetJournalByIdempotencyKey", mock.Anything, key).Return(committed, nil).Once()
+
+	err := service.Transfer(context.Background(), fromWalletID, toWalletID, amount, "Rent", "key-1")
+
+	assert.NoError(t, err)
+	ledgerRepo.AssertExpectations(t)
+}
+
+// recordingStatementWriter keeps everything a statement writer is handed
+type recordingStatementWriter struct {
+	begun *models.Statement
+	lines []*models.StatementLine
+	ended *models.Statement
+}
+
+func (w *recordingStatementWriter) Begin(statement *models.Statement) error {
+	w.begun = statement
+	return nil
+}
+
+func (w *recordingStatementWriter) Line(line *models.StatementLine) error {
+	w.lines = append(w.lines, line)
+	return nil
+}
+
+func (w *recordingStatementWriter) End(statement *models.Statement) error {
+	w.ended = statement
+	return nil
+}
+
+func TestWriteStatementRunningBalance(t *testing.T) {
+	service, walletRepo, ledgerRepo := setupWalletService()
+
+	walletID := uuid.New()
+	from := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	transactions := []*models.Transaction{
+		{Type: models.TransactionTypeDeposit, Amount: decimal.NewFromInt(50)},
+		{Type: models.TransactionTypeTransferOut, Amount: decimal.NewFromInt(30)},
+		{Type: models.TransactionTypeTransferIn, Amount: decimal.NewFromInt(5)},
+	}
+
+	walletRepo.On("GetWalletByID", mock.Anything, walletID).Return(testutil.Wallet(walletID, testWalletBalance), nil)
+	ledgerRepo.On("GetWalletLedgerBalanceBefore", mock.Anything, walletID, from).Return(decimal.NewFromInt(100), nil)
+	ledgerRepo.On("StreamTransactionsByWalletID", mock.Anything, walletID, from, to, mock.Anything).
+		Run(func(args mock.Arguments) {
+			fn := args.Get(4).(func(*models.Transaction) error)
+			for _, transaction := range transactions {
+				require.NoError(t, fn(transaction))
+			}
+		}).
+		Return(nil)
+
+	w := &recordingStatementWriter{}
+	err := service.WriteStatement(context.Background(), walletID, from, to, w)
+
+	assert.NoError(t, err)
+	assert.True(t, w.begun.OpeningBalance.Equal(decimal.NewFromInt(100)))
+	assert.Len(t, w.lines, 3)
+	assert.True(t, w.lines[0].Balance.Equal(decimal.NewFromInt(150)))
+	assert.True(t, w.lines[1].Balance.Equal(decimal.NewFromInt(120)))
+	assert.True(t, w.lines[2].Balance.Equal(decimal.NewFromInt(125)))
+	assert.True(t, w.ended.ClosingBalance.Equal(decimal.NewFromInt(125)))
+}
+
+func TestWriteStatementDefaultsPeriod(t *testing.T) {
+	service, walletRepo, ledgerRepo := setupWalletService()
+	now := time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+	service.Clock = clock.NewFake(now)
+
+	walletID := uuid.New()
+	wallet := testutil.Wallet(walletID, 0)
+	wallet.CreatedAt = time.Date(2024, 6, 1, 8, 0, 0, 0, time.UTC)
+
+	walletRepo.On("GetWalletByID", mock.Anything, walletID).Return(wallet, nil)
+	ledgerRepo.On("GetWalletLedgerBalanceBefore", mock.Anything, walletID, wallet.CreatedAt).Return(decimal.Zero, nil)
+	ledgerRepo.On("StreamTransactionsByWalletID", mock.Anything, walletID, wallet.CreatedAt, now, mock.Anything).Return(nil)
+
+	w := &recordingStatementWriter{}
+	err := service.WriteStatement(context.Background(), walletID, time.Time{}, time.Time{}, w)
+
+	assert.NoError(t, err)
+	assert.Equal(t, wallet.CreatedAt, w.ended.From)
+	assert.Equal(t, now, w.ended.To)
+	ledgerRepo.AssertExpectations(t)
+}
+
+func TestWriteStatementRejectsInvertedPeriod(t *testing.T) {
+	service, walletRepo, _ := setupWalletService()
+
+	walletID := uuid.New()
+	walletRepo.On("GetWalletByID", mock.Anything, walletID).Return(testutil.Wallet(walletID, 0), nil)
+
+	day := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	err := service.WriteStatement(context.Background(), walletID, day, day, &recordingStatementWriter{})
+
+	assert.ErrorIs(t, err, ErrInvalidStatementPeriod)
+}
+
+func TestWalletDepositRejectsFrozenWallet(t *testing.T) {
+	service, walletRepo, ledgerRepo := setupWalletService()
+
+	walletID := uuid.New()
+	wallet := testutil.Wallet(walletID, testWalletBalance)
+	wallet.Status = models.WalletStatusFrozen
+
+	walletRepo.On("BeginTx", mock.Anything).Return((*sql.Tx)(nil), nil)
+	walletRepo.On("GetWalletByIDWithTx", mock.Anything, (*sql.Tx)(nil), walletID).Return(wallet, nil)
+
+	result, err := service.Deposit(context.Background(), walletID, testutil.USD(decimal.NewFromFloat(testDepositAmount)), "")
+
+	assert.Nil(t, result)
+	assert.ErrorIs(t, err, models.ErrWalletFrozen)
+	walletRepo.AssertNotCalled(t, "UpdateBalanceWithTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	ledgerRepo.AssertNotCalled(t, "CreateJournalWithTx", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestWalletTransferRejectsClosedDestination(t *testing.T) {
+	service, walletRepo, _ := setupWalletService()
+
+	fromWalletID := uuid.New()
+	toWalletID := uuid.New()
+	toWallet := testutil.Wallet(toWalletID, 0)
+	toWallet.Status = models.WalletStatusClosed
+
+	walletRepo.On("BeginTx", mock.Anything).Return((*sql.Tx)(nil), nil)
+	walletRepo.On("GetWalletByIDWithTx", mock.Anything, (*sql.Tx)(nil), fromWalletID).Return(testutil.Wallet(fromWalletID, 100.0), nil)
+	walletRepo.On("GetWalletByIDWithTx", mock.Anything, (*sql.Tx)(nil), toWalletID).Return(toWallet, nil)
+
+	err := service.Transfer(context.Background(), fromWalletID, toWalletID, testutil.USD(decimal.NewFromFloat(10.0)), "Test", "")
+
+	assert.ErrorIs(t, err, models.ErrWalletClosed)
+	assert.Contains(t, err.Error(), "destination")
+	walletRepo.AssertNotCalled(t, "UpdateBalanceWithTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSetWalletStatus(t *testing.T) {
+	t.Run("freeze", func(t *testing.T) {
+		service, walletRepo, _ := setupWalletService()
+		walletID := uuid.New()
+
+		walletRepo.On("BeginTx", mock.Anything).Return((*sql.Tx)(nil), nil)
+		walletRepo.On("GetWalletByIDWithTx", mock.Anything, (*sql.Tx)(nil), walletID).Return(testutil.Wallet(walletID, 0), nil)
+		walletRepo.On("UpdateStatusWithTx", mock.Anything, (*sql.Tx)(nil), walletID, models.WalletStatusFrozen, mock.Anything).Return(nil)
+
+		wallet, err := service.SetWalletStatus(context.Background(), walletID, models.WalletStatusFrozen)
+
+		assert.NoError(t, err)
+		assert.Equal(t, models.WalletStatusFrozen, wallet.Status)
+		walletRepo.AssertExpectations(t)
+	})
+
+	t.Run("unknown status", func(t *testing.T) {
+		service, walletRepo, _ := setupWalletService()
+
+		_, err := service.SetWalletStatus(context.Background(), uuid.New(), "suspended")
+
+		assert.ErrorIs(t, err, ErrInvalidWalletStatus)
+		walletRepo.AssertNotCalled(t, "BeginTx", mock.Anything)
+	})
+
+	t.Run("closed wallets stay closed", func(t *testing.T) {
+		service, walletRepo, _ := setupWalletService()
+		walletID := uuid.New()
+		closed := testutil.Wallet(walletID, 0)
+		closed.Status = models.WalletStatusClosed
+
+		walletRepo.On("BeginTx", mock.Anything).Return((*sql.Tx)(nil), nil)
+		walletRepo.On("GetWalletByIDWithTx", mock.Anything, (*sql.Tx)(nil), walletID).Return(closed, nil)
+
+		_, err := service.SetWalletStatus(context.Background(), walletID, models.WalletStatusActive)
+
+		assert.ErrorIs(t, err, models.ErrWalletClosed)
+		walletRepo.AssertNotCalled(t, "UpdateStatusWithTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestGetTransfer(t *testing.T) {
+	service, _, ledgerRepo := setupWalletService()
+	from, to := uuid.New(), uuid.New()
+	amount := decimal.NewFromInt(40)
+
+	transferID, depositID, missingID := uuid.New(), uuid.New(), uuid.New()
+	ledgerRepo.On("GetJournalByID", mock.Anything, transferID).Return(&models.Journal{
+		ID: transferID, Type: models.JournalTypeTransfer, Entries: []*models.LedgerEntry{
+			debit(&from, testutil.USD(amount)), credit(&to, testutil.USD(amount)),
+		},
+	}, nil)
+	ledgerRepo.On("GetJournalByID", mock.Anything, depositID).Return(&models.Journal{
+		ID: depositID, Type: models.JournalTypeDeposit, Entries: []*models.LedgerEntry{
+			debit(nil, testutil.USD(amount)), credit(&to, testutil.USD(amount)),
+		},
+	}, nil)
+	ledgerRepo.On("GetJournalByID", mock.Anything, missingID).Return(nil, fmt.Errorf("journal %w", repository.ErrNotFound))
+
+	transfer, err := service.GetTransfer(context.Background(), transferID)
+	require.NoError(t, err)
+	assert.Equal(t, from, transfer.FromWalletID)
+	assert.Equal(t, to, transfer.ToWalletID)
+	assert.Len(t, transfer.Legs, 2)
+
+	_, err = service.GetTransfer(context.Background(), depositID)
+	assert.ErrorIs(t, err, ErrTransferNotFound)
+
+	_, err = service.GetTransfer(context.Background(), missingID)
+	assert.ErrorIs(t, err, ErrTransferNotFound)
+}
+
+func TestListTransfers(t *testing.T) {
+	service, walletRepo, ledgerRepo := setupWalletService()
+	walletID := uuid.New()
+	transfers := []*models.WalletTransfer{{ReferenceID: uuid.New(), Direction: models.TransferDirectionOutgoing}}
+
+	walletRepo.On("GetWalletByID", mock.Anything, walletID).Return(testutil.Wallet(walletID, testWalletBalance), nil)
+	ledgerRepo.On("GetTransfersByWalletID", mock.Anything, walletID, pagination.MaxLimit, 0).Return(transfers, nil)
+
+	// Page sizes are clamped and negative offsets start from the beginning
+	result, err := service.ListTransfers(context.Background(), walletID, 10000, -5)
+
+	require.NoError(t, err)
+	assert.Equal(t, transfers, result)
+
+	missing := uuid.New()
+	walletRepo.On("GetWalletByID", mock.Anything, missing).Return(nil, fmt.Errorf("wallet %w", repository.ErrNotFound))
+	_, err = service.ListTransfers(context.Background(), missing, 0, 0)
+	assert.ErrorIs(t, err, ErrWalletNotFound)
+}
+
+func TestWalletMovementsSwitchedOffByFeatureFlags(t *testing.T) {
+	service, walletRepo, _ := setupWalletService()
+	service.Flags = featureflag.New(DefaultFeatureFlags, featureflag.NewMemory(), 0, nil)
+	_, err := service.Flags.Set(context.Background(), FlagWithdrawals, false)
+	require.NoError(t, err)
+	_, err = service.Flags.Set(context.Background(), FlagTransfers, false)
+	require.NoError(t, err)
+
+	walletRepo.On("BeginTx", mock.Anything).Return((*sql.Tx)(nil), nil)
+
+	_, err = service.Withdraw(context.Background(), uuid.New(), testutil.USD(decimal.NewFromInt(10)), "")
+	assert.ErrorIs(t, err, ErrFeatureDisabled)
+
+	err = service.Transfer(context.Background(), uuid.New(), uuid.New(), testutil.USD(decimal.NewFromInt(10)), "Rent", "")
+	assert.ErrorIs(t, err, ErrFeatureDisabled)
+	walletRepo.AssertNotCalled(t, "GetWalletByIDWithTx", mock.Anything, mock.Anything, mock.Anything)
+}
@@ -0,0 +1,101 @@
+package settlement
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/shanwije/wallet-app/pkg/logger"
+)
+
+// Source is a stream of settlement records with explicit offset commits
+type Source interface {
+	Poll(ctx context.Context) ([]Record, error)
+	Commit(ctx context.Context, records []Record) error
+	Close() error
+}
+
+// Handler applies one record's payload; Processor is the production implementation
+type Handler interface {
+	Process(ctx context.Context, payload []byte) error
+}
+
+// Consumer feeds records from a source to a handler in order
+type Consumer struct {
+	Source  Source
+	Handler Handler
+	// RetryDelay is how long to wait before retrying a record that failed transiently
+	RetryDelay time.Duration
+}
+
+// Run consumes until ctx is cancelled, waiting interval whenever there is nothing to do
+func (c *Consumer) Run(ctx context.Context, interval time.Duration) {
+	log := logger.FromContext(ctx)
+
+	for {
+		consumed, err := c.ConsumeOnce(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Error("Consuming settlement events failed", zap.Error(err))
+		}
+		if err == nil && consumed > 0 {
+			continue
+		}
+		if !sleep(ctx, interval) {
+			return
+		}
+	}
+}
+
+// ConsumeOnce processes one poll's worth of records and commits them, returning how
+// many there were.
+//
+// A record that fails transiently is retried until it succeeds, holding back the
+// records after it, so credits are applied in order and none is skipped. Rejected
+// records are logged for someone to handle and then passed over.
+func (c *Consumer) ConsumeOnce(ctx context.Context) (int, error) {
+	log := logger.FromContext(ctx)
+
+	records, err := c.Source.Poll(ctx)
+	if err != nil || len(records) == 0 {
+		return 0, err
+	}
+
+	for _, record := range records {
+		for {
+			err := c.Handler.Process(ctx, record.Value)
+			if err == nil {
+				break
+			}
+			fields := []zap.Field{zap.Error(err), zap.Int32("partition", record.Partition), zap.Int64("offset", record.Offset)}
+			if errors.Is(err, ErrRejected) {
+				log.Error("Settlement event rejected; it needs manual handling",
+					append(fields, zap.ByteString("event", record.Value))...)
+				break
+			}
+			log.Warn("Settlement event failed; retrying", fields...)
+			if !sleep(ctx, c.RetryDelay) {
+				return 0, ctx.Err()
+			}
+		}
+	}
+
+	if err := c.Source.Commit(ctx, records); err != nil {
+		return 0, err
+	}
+	return len(records), nil
+}
+
+// sleep waits for d and reports false if ctx was cancelled first
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
@@ -0,0 +1,202 @@
+package settlement
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	kafkaV2      = "application/vnd.kafka.v2+json"
+	kafkaJSONV2  = "application/vnd.kafka.json.v2+json"
+	kafkaTimeout = 30 * time.Second
+)
+
+// errConsumerGone is returned when the proxy has dropped our consumer instance,
+// which it does after a period without polls
+var errConsumerGone = errors.New("consumer instance no longer exists")
+
+// Record is one message read from the topic
+type Record struct {
+	Topic     string          `json:"topic"`
+	Partition int32           `json:"partition"`
+	Offset    int64           `json:"offset"`
+	Value     json.RawMessage `json:"value"`
+}
+
+// KafkaRESTSource reads a topic as a member of a consumer group through a Kafka REST
+// proxy speaking the Confluent v2 API. Offsets are committed explicitly, only after
+// the records before them were processed. The consumer instance is created on first
+// use and recreated if the proxy expires it.
+type KafkaRESTSource struct {
+	baseURL string
+	group   string
+	topic   string
+	client  *http.Client
+
+	mu sync.Mutex
+	// instance is the consumer's base URI, empty until it has been created
+	instance string
+}
+
+// NewKafkaRESTSource consumes topic in group through the proxy at baseURL
+func NewKafkaRESTSource(baseURL, group, topic string) *KafkaRESTSource {
+	return &KafkaRESTSource{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		group:   group,
+		topic:   topic,
+		client:  &http.Client{Timeout: kafkaTimeout},
+	}
+}
+
+// Poll returns the next records, possibly none
+func (s *KafkaRESTSource) Poll(ctx context.Context) ([]Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.ensureInstance(ctx); err != nil {
+		return nil, err
+	}
+
+	var records []Record
+	err := s.do(ctx, http.MethodGet, s.instance+"/records", kafkaJSONV2, nil, &records)
+	if errors.Is(err, errConsumerGone) {
+		s.instance = ""
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to poll %s: %w", s.topic, err)
+	}
+	return records, nil
+}
+
+// Commit marks everything up to and including the records as consumed
+func (s *KafkaRESTSource) Commit(ctx context.Context, records []Record) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	type offset struct {
+		Topic     string `json:"topic"`
+		Partition int32  `json:"partition"`
+		Offset    int64  `json:"offset"`
+	}
+	latest := map[int32]int64{}
+	for _, record := range records {
+		if current, ok := latest[record.Partition]; !ok || record.Offset > current {
+			latest[record.Partition] = record.Offset
+		}
+	}
+	// The proxy commits the position after each offset given, as the Kafka client would
+	offsets := make([]offset, 0, len(latest))
+	for partition, position := range latest {
+		offsets = append(offsets, offset{Topic: s.topic, Partition: partition, Offset: position})
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.instance == "" {
+		return fmt.Errorf("failed to commit offsets: %w", errConsumerGone)
+	}
+	err := s.do(ctx, http.MethodPost, s.instance+"/offsets", kafkaV2, map[string]any{"offsets": offsets}, nil)
+	if errors.Is(err, errConsumerGone) {
+		s.instance = ""
+	}
+	if err != nil {
+		return fmt.Errorf("failed to commit offsets: %w", err)
+	}
+	return nil
+}
+
+// Close removes the consumer instance so the group rebalances straight away
+func (s *KafkaRESTSource) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.instance == "" {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), kafkaTimeout)
+	defer cancel()
+
+	err := s.do(ctx, http.MethodDelete, s.instance, kafkaV2, nil, nil)
+	s.instance = ""
+	if err != nil && !errors.Is(err, errConsumerGone) {
+		return fmt.Errorf("failed to delete consumer: %w", err)
+	}
+	return nil
+}
+
+// ensureInstance creates the consumer and subscribes it to the topic
+func (s *KafkaRESTSource) ensureInstance(ctx context.Context) error {
+	if s.instance != "" {
+		return nil
+	}
+
+	var created struct {
+		BaseURI string `json:"base_uri"`
+	}
+	config := map[string]string{
+		"format":             "json",
+		"auto.offset.reset":  "earliest",
+		"auto.commit.enable": "false",
+	}
+	if err := s.do(ctx, http.MethodPost, s.baseURL+"/consumers/"+url.PathEscape(s.group), kafkaV2, config, &created); err != nil {
+		return fmt.Errorf("failed to create consumer: %w", err)
+	}
+
+	subscription := map[string][]string{"topics": {s.topic}}
+	if err := s.do(ctx, http.MethodPost, created.BaseURI+"/subscription", kafkaV2, subscription, nil); err != nil {
+		return fmt.Errorf("failed to subscribe to %s: %w", s.topic, err)
+	}
+
+	s.instance = created.BaseURI
+	return nil
+}
+
+// do sends one request to the proxy, decoding the response into out when it is not nil
+func (s *KafkaRESTSource) do(ctx context.Context, method, endpoint, accept string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", accept)
+	if body != nil {
+		req.Header.Set("Content-Type", kafkaV2)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return errConsumerGone
+	case resp.StatusCode >= 300:
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("Kafka REST proxy returned %d: %s", resp.StatusCode, bytes.TrimSpace(message))
+	case out != nil:
+		return json.NewDecoder(resp.Body).Decode(out)
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,88 @@
+// Package settlement credits wallets from settlement events published by external
+// payment rails, such as a bank webhook processor. Each event is applied as a deposit
+// keyed by the event ID, so redelivered events never credit a wallet twice.
+package settlement
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/service"
+	"github.com/shanwije/wallet-app/pkg/money"
+)
+
+// ErrRejected marks events that can never be applied, such as malformed ones or those
+// for a closed wallet. Retrying them is pointless; they need someone to look at them.
+var ErrRejected = errors.New("settlement event rejected")
+
+// Event is an external payment that has settled into a wallet
+type Event struct {
+	// ID is unique per payment and makes redelivery safe
+	ID       string          `json:"id"`
+	WalletID uuid.UUID       `json:"wallet_id"`
+	Amount   decimal.Decimal `json:"amount"`
+	Currency string          `json:"currency"`
+}
+
+// Processor applies settlement events to wallets
+type Processor struct {
+	Wallets *service.WalletService
+}
+
+// Process credits the wallet named in payload. Errors wrapping ErrRejected are
+// permanent; any other error is transient and the event should be retried.
+func (p *Processor) Process(ctx context.Context, payload []byte) error {
+	var event Event
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return fmt.Errorf("%w: malformed event: %v", ErrRejected, err)
+	}
+	amount, err := event.funds()
+	if err != nil {
+		return fmt.Errorf("%w: event %q: %v", ErrRejected, event.ID, err)
+	}
+
+	_, err = p.Wallets.Deposit(ctx, event.WalletID, amount, "settlement:"+event.ID)
+	if err != nil && permanent(err) {
+		return fmt.Errorf("%w: event %q: %w", ErrRejected, event.ID, err)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to apply settlement event %q: %w", event.ID, err)
+	}
+	return nil
+}
+
+// funds validates the event and returns the amount to credit
+func (e Event) funds() (money.Money, error) {
+	if e.ID == "" {
+		return money.Money{}, errors.New("id is required")
+	}
+	if e.WalletID == uuid.Nil {
+		return money.Money{}, errors.New("wallet_id is required")
+	}
+	currency, err := money.ParseCurrency(e.Currency)
+	if err != nil {
+		return money.Money{}, err
+	}
+	amount := money.New(e.Amount, currency)
+	if !amount.IsPositive() || !amount.HasValidPrecision() {
+		return money.Money{}, fmt.Errorf("invalid amount %s", e.Amount)
+	}
+	return amount, nil
+}
+
+// permanent reports whether a deposit failed for a reason retrying cannot fix
+func permanent(err error) bool {
+	return errors.Is(err, service.ErrWalletNotFound) ||
+		errors.Is(err, models.ErrWalletFrozen) ||
+		errors.Is(err, models.ErrWalletClosed) ||
+		errors.Is(err, money.ErrCurrencyMismatch) ||
+		errors.Is(err, service.ErrLimitExceeded) ||
+		errors.Is(err, service.ErrAmountOutOfRange) ||
+		errors.Is(err, service.ErrIdempotencyKeyReused)
+}
@@ -0,0 +1,164 @@
+package settlement
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/service"
+)
+
+func TestProcessRejectsInvalidEvents(t *testing.T) {
+	// Invalid events are rejected before any wallet is touched
+	processor := &Processor{}
+
+	for name, payload := range map[string]string{
+		"malformed":         `{"id":`,
+		"missing id":        `{"wallet_id":"0190a1c2-7c1e-7d2a-9a4b-1f2e3d4c5b6a","amount":"10","currency":"USD"}`,
+		"missing wallet":    `{"id":"evt-1","amount":"10","currency":"USD"}`,
+		"unknown currency":  `{"id":"evt-1","wallet_id":"0190a1c2-7c1e-7d2a-9a4b-1f2e3d4c5b6a","amount":"10","currency":"XYZ"}`,
+		"negative amount":   `{"id":"evt-1","wallet_id":"0190a1c2-7c1e-7d2a-9a4b-1f2e3d4c5b6a","amount":"-10","currency":"USD"}`,
+		"too many decimals": `{"id":"evt-1","wallet_id":"0190a1c2-7c1e-7d2a-9a4b-1f2e3d4c5b6a","amount":"10.001","currency":"USD"}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.ErrorIs(t, processor.Process(context.Background(), []byte(payload)), ErrRejected)
+		})
+	}
+}
+
+func TestPermanentDepositErrors(t *testing.T) {
+	assert.True(t, permanent(fmt.Errorf("failed to get wallet: %w", service.ErrWalletNotFound)))
+	assert.True(t, permanent(models.ErrWalletClosed))
+	assert.True(t, permanent(service.ErrLimitExceeded))
+	assert.False(t, permanent(errors.New("connection refused")))
+}
+
+// fakeSource serves one batch of records and remembers what was committed
+type fakeSource struct {
+	records   []Record
+	committed []Record
+}
+
+func (s *fakeSource) Poll(ctx context.Context) ([]Record, error) {
+	records := s.records
+	s.records = nil
+	return records, nil
+}
+
+func (s *fakeSource) Commit(ctx context.Context, records []Record) error {
+	s.committed = append(s.committed, records...)
+	return nil
+}
+
+func (s *fakeSource) Close() error { return nil }
+
+// scriptedHandler returns the queued errors for each payload in turn
+type scriptedHandler struct {
+	results map[string][]error
+	calls   []string
+}
+
+func (h *scriptedHandler) Process(ctx context.Context, payload []byte) error {
+	key := string(payload)
+	h.calls = append(h.calls, key)
+	if len(h.results[key]) == 0 {
+		return nil
+	}
+	err := h.results[key][0]
+	h.results[key] = h.results[key][1:]
+	return err
+}
+
+func TestConsumeOnceRetriesTransientFailuresAndSkipsRejected(t *testing.T) {
+	source := &fakeSource{records: []Record{
+		{Partition: 0, Offset: 1, Value: json.RawMessage(`"a"`)},
+		{Partition: 0, Offset: 2, Value: json.RawMessage(`"b"`)},
+		{Partition: 0, Offset: 3, Value: json.RawMessage(`"c"`)},
+	}}
+	handler := &scriptedHandler{results: map[string][]error{
+		`"a"`: {errors.New("database unavailable")},
+		`"b"`: {fmt.Errorf("%w: wallet is closed", ErrRejected)},
+	}}
+	consumer := &Consumer{Source: source, Handler: handler, RetryDelay: time.Millisecond}
+
+	consumed, err := consumer.ConsumeOnce(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, 3, consumed)
+	assert.Equal(t, []string{`"a"`, `"a"`, `"b"`, `"c"`}, handler.calls)
+	assert.Len(t, source.committed, 3)
+}
+
+func TestKafkaRESTSourcePollsAndCommitsHighestOffsets(t *testing.T) {
+	var server *httptest.Server
+	var committed map[string][]map[string]any
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "POST /consumers/wallet-app":
+			fmt.Fprintf(w, `{"instance_id":"c1","base_uri":"%s/consumers/wallet-app/instances/c1"}`, server.URL)
+		case "POST /consumers/wallet-app/instances/c1/subscription":
+			w.WriteHeader(http.StatusNoContent)
+		case "GET /consumers/wallet-app/instances/c1/records":
+			assert.Equal(t, kafkaJSONV2, r.Header.Get("Accept"))
+			fmt.Fprint(w, `[{"topic":"settlements","partition":0,"offset":4,"value":{"id":"evt-4"}},
+				{"topic":"settlements","partition":1,"offset":9,"value":{"id":"evt-9"}},
+				{"topic":"settlements","partition":0,"offset":5,"value":{"id":"evt-5"}}]`)
+		case "POST /consumers/wallet-app/instances/c1/offsets":
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&committed))
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	source := NewKafkaRESTSource(server.URL, "wallet-app", "settlements")
+	records, err := source.Poll(context.Background())
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	assert.JSONEq(t, `{"id":"evt-4"}`, string(records[0].Value))
+
+	require.NoError(t, source.Commit(context.Background(), records))
+	assert.ElementsMatch(t, []map[string]any{
+		{"topic": "settlements", "partition": float64(0), "offset": float64(5)},
+		{"topic": "settlements", "partition": float64(1), "offset": float64(9)},
+	}, committed["offsets"])
+}
+
+func TestKafkaRESTSourceRecreatesExpiredConsumer(t *testing.T) {
+	created := 0
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/consumers/wallet-app":
+			created++
+			fmt.Fprintf(w, `{"base_uri":"%s/consumers/wallet-app/instances/c%d"}`, server.URL, created)
+		case r.URL.Path == "/consumers/wallet-app/instances/c1/records":
+			w.WriteHeader(http.StatusNotFound)
+		case r.URL.Path == "/consumers/wallet-app/instances/c2/records":
+			fmt.Fprint(w, `[]`)
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+
+	source := NewKafkaRESTSource(server.URL, "wallet-app", "settlements")
+	_, err := source.Poll(context.Background())
+	assert.ErrorIs(t, err, errConsumerGone)
+
+	records, err := source.Poll(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, records)
+	assert.Equal(t, 2, created)
+}
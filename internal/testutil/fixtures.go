@@ -0,0 +1,35 @@
+// Package testutil holds fixtures shared by the tests of several packages
+package testutil
+
+import (
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/pkg/money"
+)
+
+// USD wraps a decimal amount as US dollars
+func USD(amount decimal.Decimal) money.Money {
+	return money.New(amount, money.USD)
+}
+
+// Wallet returns an active US dollar wallet with the balance
+func Wallet(id uuid.UUID, balance float64) *models.Wallet {
+	return &models.Wallet{
+		ID:       id,
+		Balance:  decimal.NewFromFloat(balance),
+		Currency: money.USD,
+		Status:   models.WalletStatusActive,
+	}
+}
+
+// Transaction returns a transaction of the type and amount on the wallet
+func Transaction(walletID uuid.UUID, transactionType string, amount float64) *models.Transaction {
+	return &models.Transaction{
+		ID:       uuid.New(),
+		WalletID: walletID,
+		Type:     transactionType,
+		Amount:   decimal.NewFromFloat(amount),
+	}
+}
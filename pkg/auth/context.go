@@ -0,0 +1,37 @@
+package auth
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+type contextKey string
+
+const (
+	userIDKey contextKey = "auth_user_id"
+	adminKey  contextKey = "auth_admin_key"
+)
+
+// WithUserID stores the authenticated user ID in the context
+func WithUserID(ctx context.Context, userID uuid.UUID) context.Context {
+	return context.WithValue(ctx, userIDKey, userID)
+}
+
+// UserIDFromContext returns the authenticated user ID, if any
+func UserIDFromContext(ctx context.Context) (uuid.UUID, bool) {
+	userID, ok := ctx.Value(userIDKey).(uuid.UUID)
+	return userID, ok
+}
+
+// WithAdminKeyID marks the context as authenticated by the admin key with the given
+// identifier, which must not reveal the key itself
+func WithAdminKeyID(ctx context.Context, keyID string) context.Context {
+	return context.WithValue(ctx, adminKey, keyID)
+}
+
+// AdminKeyIDFromContext returns the identifier of the admin key used, if any
+func AdminKeyIDFromContext(ctx context.Context) (string, bool) {
+	keyID, ok := ctx.Value(adminKey).(string)
+	return keyID, ok
+}
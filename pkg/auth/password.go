@@ -0,0 +1,42 @@
+package auth
+
+import (
+	"errors"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// MinPasswordLength is the shortest password accepted at registration
+const MinPasswordLength = 8
+
+// ErrPasswordTooShort is returned by HashPassword for passwords under MinPasswordLength
+var ErrPasswordTooShort = errors.New("password must be at least 8 characters")
+
+// HashPassword returns a bcrypt hash of the password
+func HashPassword(password string) (string, error) {
+	if len(password) < MinPasswordLength {
+		return "", ErrPasswordTooShort
+	}
+
+	return hash(password)
+}
+
+// HashPIN returns a bcrypt hash of a transaction PIN. Unlike HashPassword it takes a
+// PIN of any length; its format is for the caller to check.
+func HashPIN(pin string) (string, error) {
+	return hash(pin)
+}
+
+// hash returns a bcrypt hash of secret
+func hash(secret string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(secret), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// CheckPassword reports whether password, or a PIN, matches the bcrypt hash
+func CheckPassword(hash, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
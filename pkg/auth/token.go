@@ -0,0 +1,78 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+
+	"github.com/shanwije/wallet-app/pkg/clock"
+)
+
+// Issuer is written to and required in the iss claim
+const Issuer = "wallet-api"
+
+// ErrInvalidToken is returned for tokens that are malformed, expired or wrongly signed
+var ErrInvalidToken = errors.New("invalid token")
+
+// TokenManager issues and verifies HS256-signed JWT access tokens
+type TokenManager struct {
+	secret []byte
+	ttl    time.Duration
+	clock  clock.Clock
+}
+
+// NewTokenManager creates a TokenManager; clk may be nil to use the system clock
+func NewTokenManager(secret string, ttl time.Duration, clk clock.Clock) *TokenManager {
+	return &TokenManager{
+		secret: []byte(secret),
+		ttl:    ttl,
+		clock:  clock.OrDefault(clk),
+	}
+}
+
+// Issue creates a signed access token for the user
+func (m *TokenManager) Issue(userID uuid.UUID) (string, time.Time, error) {
+	now := m.clock.Now()
+	expiresAt := now.Add(m.ttl)
+
+	claims := jwt.RegisteredClaims{
+		Issuer:    Issuer,
+		Subject:   userID.String(),
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(expiresAt),
+	}
+
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(m.secret)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to sign token: %w", err)
+	}
+
+	return signed, expiresAt, nil
+}
+
+// Verify validates the token and returns the user ID it was issued for
+func (m *TokenManager) Verify(token string) (uuid.UUID, error) {
+	claims := &jwt.RegisteredClaims{}
+
+	_, err := jwt.ParseWithClaims(token, claims, func(*jwt.Token) (interface{}, error) {
+		return m.secret, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(Issuer),
+		jwt.WithExpirationRequired(),
+		jwt.WithTimeFunc(m.clock.Now),
+	)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%w: bad subject", ErrInvalidToken)
+	}
+
+	return userID, nil
+}
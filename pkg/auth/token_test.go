@@ -0,0 +1,59 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/shanwije/wallet-app/pkg/clock"
+)
+
+func TestTokenRoundTrip(t *testing.T) {
+	fakeClock := clock.NewFake(time.Date(2024, 6, 11, 12, 0, 0, 0, time.UTC))
+	tokens := NewTokenManager("test-secret-test-secret-test-secret", time.Hour, fakeClock)
+	userID := uuid.New()
+
+	token, expiresAt, err := tokens.Issue(userID)
+	assert.NoError(t, err)
+	assert.Equal(t, fakeClock.Now().Add(time.Hour), expiresAt)
+
+	verified, err := tokens.Verify(token)
+	assert.NoError(t, err)
+	assert.Equal(t, userID, verified)
+}
+
+func TestTokenExpiry(t *testing.T) {
+	fakeClock := clock.NewFake(time.Date(2024, 6, 11, 12, 0, 0, 0, time.UTC))
+	tokens := NewTokenManager("test-secret-test-secret-test-secret", time.Hour, fakeClock)
+
+	token, _, err := tokens.Issue(uuid.New())
+	assert.NoError(t, err)
+
+	fakeClock.Advance(2 * time.Hour)
+	_, err = tokens.Verify(token)
+	assert.True(t, errors.Is(err, ErrInvalidToken))
+}
+
+func TestTokenWrongSecret(t *testing.T) {
+	issuer := NewTokenManager("secret-one-secret-one-secret-one", time.Hour, nil)
+	verifier := NewTokenManager("secret-two-secret-two-secret-two", time.Hour, nil)
+
+	token, _, err := issuer.Issue(uuid.New())
+	assert.NoError(t, err)
+
+	_, err = verifier.Verify(token)
+	assert.True(t, errors.Is(err, ErrInvalidToken))
+}
+
+func TestPasswordHashing(t *testing.T) {
+	_, err := HashPassword("short")
+	assert.ErrorIs(t, err, ErrPasswordTooShort)
+
+	hash, err := HashPassword("correct horse battery")
+	assert.NoError(t, err)
+	assert.True(t, CheckPassword(hash, "correct horse battery"))
+	assert.False(t, CheckPassword(hash, "wrong password"))
+}
@@ -0,0 +1,64 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock abstracts the current time so that time-dependent logic
+// (idempotency TTLs, scheduling, statement periods) can be tested deterministically
+type Clock interface {
+	Now() time.Time
+}
+
+// systemClock reads the wall clock
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now().UTC()
+}
+
+// New returns a Clock backed by the system time (UTC)
+func New() Clock {
+	return systemClock{}
+}
+
+// OrDefault returns c, or the system clock when c is nil
+func OrDefault(c Clock) Clock {
+	if c == nil {
+		return New()
+	}
+	return c
+}
+
+// Fake is a manually controlled Clock for tests
+type Fake struct {
+	mutex sync.RWMutex
+	now   time.Time
+}
+
+// NewFake creates a Fake clock frozen at the given time
+func NewFake(now time.Time) *Fake {
+	return &Fake{now: now}
+}
+
+// Now returns the frozen time
+func (f *Fake) Now() time.Time {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+	return f.now
+}
+
+// Set moves the clock to the given time
+func (f *Fake) Set(now time.Time) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.now = now
+}
+
+// Advance moves the clock forward by d
+func (f *Fake) Advance(d time.Duration) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.now = f.now.Add(d)
+}
@@ -0,0 +1,154 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/shanwije/wallet-app/pkg/requestid"
+)
+
+// drivers are the database drivers opened from a DSN, by name, opened directly so their
+// connections can be wrapped. PostgreSQL connections come from a pgx pool instead.
+var drivers = map[string]driver.Driver{
+	DriverMySQL:  &mysql.MySQLDriver{},
+	DriverSQLite: sqliteDriver,
+}
+
+// connect opens a pool on dsn with the named driver
+func connect(driverName, dsn string) (*sqlx.DB, error) {
+	return open(dsnConnector{driver: drivers[driverName], dsn: dsn}, driverName)
+}
+
+// open opens a pool whose queries carry the request ID of their context as a trailing
+// SQL comment, so they can be matched to the request in the database's logs, and
+// checks that the database is reachable
+func open(connector driver.Connector, driverName string) (*sqlx.DB, error) {
+	db := sqlx.NewDb(sql.OpenDB(commentConnector{Connector: connector}), driverName)
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// withComment appends the context's request ID comment to query, if it has one
+func withComment(ctx context.Context, query string) string {
+	if comment := requestid.SQLComment(ctx); comment != "" {
+		return query + " " + comment
+	}
+	return query
+}
+
+// dsnConnector opens connections on a DSN, as sql.Open would
+type dsnConnector struct {
+	driver driver.Driver
+	dsn    string
+}
+
+func (c dsnConnector) Connect(context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.dsn)
+}
+
+func (c dsnConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+type commentConnector struct {
+	driver.Connector
+}
+
+func (c commentConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	_, isPgx := conn.(*stdlib.Conn)
+	return commentConn{Conn: conn, uncached: isPgx}, nil
+}
+
+// commentConn annotates the queries run on a driver connection. It passes every
+// optional driver interface through, answering with the database/sql fallback where
+// the underlying connection does not implement one.
+type commentConn struct {
+	driver.Conn
+	// uncached runs annotated queries without preparing them, for pgx connections.
+	// Every annotated query has new text, and would otherwise push the statements
+	// worth keeping out of the connection's cache.
+	uncached bool
+}
+
+// annotate adds the context's request ID comment to query and, when the query is
+// annotated on an uncached connection, tells pgx to run it in a single round trip
+// without preparing it
+func (c commentConn) annotate(ctx context.Context, query string, args []driver.NamedValue) (string, []driver.NamedValue) {
+	annotated := withComment(ctx, query)
+	if annotated == query || !c.uncached {
+		return annotated, args
+	}
+	return annotated, append([]driver.NamedValue{{Value: pgx.QueryExecModeExec}}, args...)
+}
+
+func (c commentConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	query = withComment(ctx, query)
+	if preparer, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		return preparer.PrepareContext(ctx, query)
+	}
+	return c.Conn.Prepare(query)
+}
+
+func (c commentConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if execer, ok := c.Conn.(driver.ExecerContext); ok {
+		query, args = c.annotate(ctx, query, args)
+		return execer.ExecContext(ctx, query, args)
+	}
+	return nil, driver.ErrSkip
+}
+
+func (c commentConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if queryer, ok := c.Conn.(driver.QueryerContext); ok {
+		query, args = c.annotate(ctx, query, args)
+		return queryer.QueryContext(ctx, query, args)
+	}
+	return nil, driver.ErrSkip
+}
+
+func (c commentConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if beginner, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return beginner.BeginTx(ctx, opts)
+	}
+	return c.Conn.Begin()
+}
+
+func (c commentConn) Ping(ctx context.Context) error {
+	if pinger, ok := c.Conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+func (c commentConn) ResetSession(ctx context.Context) error {
+	if resetter, ok := c.Conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c commentConn) IsValid() bool {
+	if validator, ok := c.Conn.(driver.Validator); ok {
+		return validator.IsValid()
+	}
+	return true
+}
+
+func (c commentConn) CheckNamedValue(value *driver.NamedValue) error {
+	if checker, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(value)
+	}
+	return driver.ErrSkip
+}
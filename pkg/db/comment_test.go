@@ -0,0 +1,65 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shanwije/wallet-app/pkg/requestid"
+)
+
+// recordingDriver opens connections that remember the last statement they were given
+type recordingDriver struct {
+	conn *recordingConn
+}
+
+func (d recordingDriver) Open(string) (driver.Conn, error) {
+	return d.conn, nil
+}
+
+type recordingConn struct {
+	query string
+}
+
+func (c *recordingConn) Prepare(query string) (driver.Stmt, error) { return nil, driver.ErrSkip }
+func (c *recordingConn) Close() error                              { return nil }
+func (c *recordingConn) Begin() (driver.Tx, error)                 { return nil, driver.ErrSkip }
+
+func (c *recordingConn) ExecContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Result, error) {
+	c.query = query
+	return driver.RowsAffected(1), nil
+}
+
+func TestConnectionAppendsRequestIDComment(t *testing.T) {
+	conn := &recordingConn{}
+	pool := sql.OpenDB(commentConnector{Connector: dsnConnector{driver: recordingDriver{conn: conn}}})
+	defer pool.Close()
+
+	_, err := pool.ExecContext(requestid.With(context.Background(), "abc-123"), "DELETE FROM holds WHERE id = $1", 1)
+	require.NoError(t, err)
+	assert.Equal(t, "DELETE FROM holds WHERE id = $1 /*request_id='abc-123'*/", conn.query)
+
+	_, err = pool.ExecContext(context.Background(), "DELETE FROM holds WHERE id = $1", 1)
+	require.NoError(t, err)
+	assert.Equal(t, "DELETE FROM holds WHERE id = $1", conn.query)
+}
+
+func TestAnnotatedPgxQueriesSkipTheStatementCache(t *testing.T) {
+	conn := commentConn{uncached: true}
+	args := []driver.NamedValue{{Ordinal: 1, Value: int64(1)}}
+
+	query, annotated := conn.annotate(requestid.With(context.Background(), "abc-123"), "SELECT $1", args)
+	assert.Equal(t, "SELECT $1 /*request_id='abc-123'*/", query)
+	require.Len(t, annotated, 2)
+	assert.Equal(t, pgx.QueryExecModeExec, annotated[0].Value)
+
+	// Queries outside a request keep their text and are prepared and cached
+	query, plain := conn.annotate(context.Background(), "SELECT $1", args)
+	assert.Equal(t, "SELECT $1", query)
+	assert.Equal(t, args, plain)
+}
@@ -0,0 +1,41 @@
+package db
+
+import (
+	"fmt"
+	"io/fs"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pressly/goose/v3"
+	"github.com/pressly/goose/v3/lock"
+)
+
+// NewMigrator returns a goose provider that applies the migrations in fsys to db.
+// It records versions in the same goose_db_version table as the goose CLI, so the
+// two can be used interchangeably. Closing the provider closes db.
+func NewMigrator(db *sqlx.DB, driver string, fsys fs.FS) (*goose.Provider, error) {
+	var opts []goose.ProviderOption
+
+	dialect := goose.DialectPostgres
+	switch driver {
+	case "", DriverPostgres:
+		// An advisory lock stops instances starting together from migrating at the same time
+		locker, err := lock.NewPostgresSessionLocker()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create migration lock: %w", err)
+		}
+		opts = append(opts, goose.WithSessionLocker(locker))
+	case DriverMySQL:
+		dialect = goose.DialectMySQL
+	case DriverSQLite:
+		dialect = goose.DialectSQLite3
+	default:
+		return nil, fmt.Errorf("unsupported database driver: %q", driver)
+	}
+
+	provider, err := goose.NewProvider(dialect, db.DB, fsys, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load migrations: %w", err)
+	}
+
+	return provider, nil
+}
@@ -0,0 +1,55 @@
+package db
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jmoiron/sqlx"
+)
+
+func newMySQL(cfg Config) (*sqlx.DB, error) {
+	db, err := connect(DriverMySQL, mysqlDSN(cfg))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to MySQL: %w", err)
+	}
+
+	return db, nil
+}
+
+// mysqlDSN builds a go-sql-driver DSN, translating the Postgres-style SSL modes
+// used in config into the driver's tls setting
+func mysqlDSN(cfg Config) string {
+	mysqlCfg := mysql.NewConfig()
+	mysqlCfg.User = cfg.User
+	mysqlCfg.Passwd = cfg.Password
+	mysqlCfg.Net = "tcp"
+	mysqlCfg.Addr = net.JoinHostPort(cfg.Host, cfg.Port)
+	mysqlCfg.DBName = cfg.Name
+	// Scan DATETIME columns into time.Time, always in UTC
+	mysqlCfg.ParseTime = true
+	// Report matched rather than changed rows so no-op balance updates are not mistaken for missing wallets
+	mysqlCfg.ClientFoundRows = true
+
+	switch cfg.SSLMode {
+	case "require":
+		mysqlCfg.TLSConfig = "skip-verify"
+	case "verify-ca", "verify-full":
+		mysqlCfg.TLSConfig = "true"
+	default:
+		mysqlCfg.TLSConfig = "false"
+	}
+
+	return mysqlCfg.FormatDSN()
+}
+
+// mysqlReplicaDSN applies the driver settings the repositories rely on to a replica DSN
+func mysqlReplicaDSN(dsn string) (string, error) {
+	mysqlCfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return "", fmt.Errorf("invalid replica DSN: %w", err)
+	}
+	mysqlCfg.ParseTime = true
+	mysqlCfg.ClientFoundRows = true
+	return mysqlCfg.FormatDSN(), nil
+}
@@ -0,0 +1,47 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMySQLDSN(t *testing.T) {
+	dsn := mysqlDSN(Config{
+		Driver:   DriverMySQL,
+		Host:     "localhost",
+		Port:     "3306",
+		User:     "wallet",
+		Password: "secret",
+		Name:     "wallet_db",
+		SSLMode:  "disable",
+	})
+
+	parsed, err := mysql.ParseDSN(dsn)
+	assert.NoError(t, err)
+	assert.Equal(t, "localhost:3306", parsed.Addr)
+	assert.Equal(t, "wallet_db", parsed.DBName)
+	assert.True(t, parsed.ParseTime)
+	assert.True(t, parsed.ClientFoundRows)
+	assert.Equal(t, "false", parsed.TLSConfig)
+}
+
+func TestMySQLReplicaDSN(t *testing.T) {
+	dsn, err := mysqlReplicaDSN("wallet:secret@tcp(replica:3306)/wallet_db")
+	assert.NoError(t, err)
+
+	parsed, err := mysql.ParseDSN(dsn)
+	assert.NoError(t, err)
+	assert.Equal(t, "replica:3306", parsed.Addr)
+	assert.True(t, parsed.ParseTime)
+	assert.True(t, parsed.ClientFoundRows)
+
+	_, err = mysqlReplicaDSN("not a dsn")
+	assert.Error(t, err)
+}
+
+func TestNewRejectsUnknownDriver(t *testing.T) {
+	_, err := New(Config{Driver: "oracle"})
+	assert.Error(t, err)
+}
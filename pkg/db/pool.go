@@ -0,0 +1,109 @@
+package db
+
+import (
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jmoiron/sqlx"
+)
+
+// PoolConfig sizes a connection pool. Zero limits follow database/sql: unlimited
+// open connections, no idle connections kept, and connections reused forever. With
+// PostgreSQL they keep pgxpool's defaults instead, and idle connections are kept
+// until ConnMaxIdleTime, so MaxIdleConns does not apply.
+type PoolConfig struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+}
+
+// apply sets the pool limits on db
+func (p PoolConfig) apply(db *sqlx.DB) {
+	db.SetMaxOpenConns(p.MaxOpenConns)
+	db.SetMaxIdleConns(p.MaxIdleConns)
+	db.SetConnMaxLifetime(p.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(p.ConnMaxIdleTime)
+}
+
+// applyPgx sets the limits that are not zero on a pgx pool's config
+func (p PoolConfig) applyPgx(config *pgxpool.Config) {
+	if p.MaxOpenConns > 0 {
+		config.MaxConns = int32(p.MaxOpenConns)
+	}
+	if p.ConnMaxLifetime > 0 {
+		config.MaxConnLifetime = p.ConnMaxLifetime
+	}
+	if p.ConnMaxIdleTime > 0 {
+		config.MaxConnIdleTime = p.ConnMaxIdleTime
+	}
+}
+
+// PoolStats is a snapshot of a connection pool's usage
+type PoolStats struct {
+	MaxOpenConns      int   `json:"max_open_connections"`
+	OpenConns         int   `json:"open_connections"`
+	InUse             int   `json:"in_use"`
+	Idle              int   `json:"idle"`
+	WaitCount         int64 `json:"wait_count"`
+	WaitDurationMs    int64 `json:"wait_duration_ms"`
+	MaxIdleClosed     int64 `json:"max_idle_closed"`
+	MaxIdleTimeClosed int64 `json:"max_idle_time_closed"`
+	MaxLifetimeClosed int64 `json:"max_lifetime_closed"`
+}
+
+// Stats reports the usage of the primary pool and, when there is one, the replica's
+type Stats struct {
+	Primary PoolStats  `json:"primary"`
+	Replica *PoolStats `json:"replica,omitempty"`
+}
+
+// PoolStats snapshots the usage of the database's connection pools
+func (db *DB) PoolStats() Stats {
+	if db.Pool != nil {
+		stats := Stats{Primary: pgxPoolStats(db.Pool)}
+		if db.ReplicaPool != nil {
+			replica := pgxPoolStats(db.ReplicaPool)
+			stats.Replica = &replica
+		}
+		return stats
+	}
+
+	stats := Stats{Primary: poolStats(db.DB)}
+	if db.Replica != nil {
+		replica := poolStats(db.Replica)
+		stats.Replica = &replica
+	}
+	return stats
+}
+
+func poolStats(db *sqlx.DB) PoolStats {
+	s := db.Stats()
+	return PoolStats{
+		MaxOpenConns:      s.MaxOpenConnections,
+		OpenConns:         s.OpenConnections,
+		InUse:             s.InUse,
+		Idle:              s.Idle,
+		WaitCount:         s.WaitCount,
+		WaitDurationMs:    s.WaitDuration.Milliseconds(),
+		MaxIdleClosed:     s.MaxIdleClosed,
+		MaxIdleTimeClosed: s.MaxIdleTimeClosed,
+		MaxLifetimeClosed: s.MaxLifetimeClosed,
+	}
+}
+
+// pgxPoolStats reports a pgx pool in the same terms. It has no cap on idle
+// connections, so MaxIdleClosed stays zero.
+func pgxPoolStats(pool *pgxpool.Pool) PoolStats {
+	s := pool.Stat()
+	return PoolStats{
+		MaxOpenConns:      int(s.MaxConns()),
+		OpenConns:         int(s.TotalConns()),
+		InUse:             int(s.AcquiredConns()),
+		Idle:              int(s.IdleConns()),
+		WaitCount:         s.EmptyAcquireCount(),
+		WaitDurationMs:    s.EmptyAcquireWaitTime().Milliseconds(),
+		MaxIdleTimeClosed: s.MaxIdleDestroyCount(),
+		MaxLifetimeClosed: s.MaxLifetimeDestroyCount(),
+	}
+}
@@ -0,0 +1,50 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPoolConfigAppliesLimits(t *testing.T) {
+	// Opening does not connect, so no server is needed
+	primary, err := sqlx.Open(DriverMySQL, "user@tcp(localhost:3306)/wallet")
+	require.NoError(t, err)
+	database := &DB{DB: primary}
+	defer database.Close()
+
+	PoolConfig{MaxOpenConns: 25, MaxIdleConns: 10, ConnMaxLifetime: time.Minute}.apply(primary)
+
+	stats := database.PoolStats()
+	assert.Equal(t, 25, stats.Primary.MaxOpenConns)
+	assert.Zero(t, stats.Primary.OpenConns)
+	assert.Nil(t, stats.Replica)
+}
+
+func TestPoolConfigAppliesPgxLimits(t *testing.T) {
+	config, err := pgxpool.ParseConfig("host=localhost sslmode=disable")
+	require.NoError(t, err)
+	defaultLifetime := config.MaxConnLifetime
+
+	PoolConfig{MaxOpenConns: 25, ConnMaxIdleTime: time.Minute}.applyPgx(config)
+	assert.Equal(t, int32(25), config.MaxConns)
+	assert.Equal(t, time.Minute, config.MaxConnIdleTime)
+	// Zero limits keep the pool's defaults
+	assert.Equal(t, defaultLifetime, config.MaxConnLifetime)
+
+	// A pgx pool connects lazily too
+	pool, err := pgxpool.NewWithConfig(context.Background(), config)
+	require.NoError(t, err)
+	database := &DB{Pool: pool}
+	defer pool.Close()
+
+	stats := database.PoolStats()
+	assert.Equal(t, 25, stats.Primary.MaxOpenConns)
+	assert.Zero(t, stats.Primary.OpenConns)
+	assert.Nil(t, stats.Replica)
+}
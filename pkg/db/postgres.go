@@ -1,32 +1,179 @@
 package db
 
 import (
+	"context"
 	"fmt"
 
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
 	"github.com/jmoiron/sqlx"
-	_ "github.com/lib/pq"
+)
+
+// Supported database drivers
+const (
+	DriverPostgres = "postgres"
+	DriverMySQL    = "mysql"
+	DriverSQLite   = "sqlite3"
 )
 
 type Config struct {
+	Driver   string
 	Host     string
 	Port     string
 	User     string
 	Password string
-	Name     string
-	SSLMode  string
+	// Name is the database, or with SQLite the path of the database file
+	Name    string
+	SSLMode string
+	// ReplicaDSN optionally names a read replica, in the driver's own DSN format
+	ReplicaDSN string
+	// Pool sizes the primary's connection pool and the replica's
+	Pool PoolConfig
+}
+
+// DB is the primary connection pool, through which all writes go, plus an optional
+// pool on a read replica
+type DB struct {
+	*sqlx.DB
+	// Replica serves reads that can tolerate replication lag; nil without a replica
+	Replica *sqlx.DB
+	// Pool is the pgx pool beneath the primary with PostgreSQL, for batches and other
+	// features database/sql lacks; nil with the other drivers
+	Pool *pgxpool.Pool
+	// ReplicaPool is the pgx pool beneath the replica, nil unless both are set
+	ReplicaPool *pgxpool.Pool
+}
+
+// Reader returns the pool for lag-tolerant reads: the replica when there is one,
+// otherwise the primary
+func (db *DB) Reader() *sqlx.DB {
+	if db.Replica != nil {
+		return db.Replica
+	}
+	return db.DB
+}
+
+// ReaderPool is Reader's pgx pool: the replica's when there is one, otherwise the
+// primary's. It is nil with drivers other than PostgreSQL.
+func (db *DB) ReaderPool() *pgxpool.Pool {
+	if db.ReplicaPool != nil {
+		return db.ReplicaPool
+	}
+	return db.Pool
 }
 
-func New(cfg Config) (*sqlx.DB, error) {
+// Close closes the primary pool and the replica's
+func (db *DB) Close() error {
+	// The pgx pools outlive the database/sql handles on top of them
+	defer func() {
+		if db.ReplicaPool != nil {
+			db.ReplicaPool.Close()
+		}
+		if db.Pool != nil {
+			db.Pool.Close()
+		}
+	}()
+	if db.Replica != nil {
+		if err := db.Replica.Close(); err != nil {
+			db.DB.Close()
+			return err
+		}
+	}
+	return db.DB.Close()
+}
+
+// New opens a connection pool for the configured driver (PostgreSQL when unset), and
+// one on the read replica when cfg.ReplicaDSN is set
+func New(cfg Config) (*DB, error) {
+	var primary *sqlx.DB
+	var err error
+	switch cfg.Driver {
+	case "", DriverPostgres:
+		return newPostgres(cfg)
+	case DriverMySQL:
+		primary, err = newMySQL(cfg)
+	case DriverSQLite:
+		if cfg.ReplicaDSN != "" {
+			return nil, fmt.Errorf("read replicas are not supported with SQLite")
+		}
+		primary, err = newSQLite(cfg)
+	default:
+		return nil, fmt.Errorf("unsupported database driver: %q", cfg.Driver)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.Pool.apply(primary)
+
+	db := &DB{DB: primary}
+	if cfg.ReplicaDSN != "" {
+		dsn, err := mysqlReplicaDSN(cfg.ReplicaDSN)
+		if err == nil {
+			db.Replica, err = connect(DriverMySQL, dsn)
+		}
+		if err != nil {
+			primary.Close()
+			return nil, fmt.Errorf("failed to connect to read replica: %w", err)
+		}
+		cfg.Pool.apply(db.Replica)
+	}
+
+	return db, nil
+}
+
+// newPostgres opens pgx pools on the primary and the replica. Each pool connection
+// prepares the statements it runs and keeps them cached, so a repeated query is sent
+// as just its arguments, and database/sql borrows its connections from the pool.
+func newPostgres(cfg Config) (*DB, error) {
 	dsn := fmt.Sprintf(
 		"user=%s password=%s dbname=%s host=%s port=%s sslmode=%s",
 		cfg.User, cfg.Password, cfg.Name, cfg.Host, cfg.Port, cfg.SSLMode,
 	)
 
-	db, err := sqlx.Connect("postgres", dsn)
+	pool, primary, err := connectPostgres(dsn, cfg.Pool)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to PostgreSQL: %w", err)
 	}
 
-	// Connection successful - caller can log this if needed
+	db := &DB{DB: primary, Pool: pool}
+	if cfg.ReplicaDSN != "" {
+		if db.ReplicaPool, db.Replica, err = connectPostgres(cfg.ReplicaDSN, cfg.Pool); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to connect to read replica: %w", err)
+		}
+	}
+
 	return db, nil
 }
+
+// NewPostgresDSN connects to the PostgreSQL database at dsn, a postgres:// URL or a
+// key=value connection string, with the pool sized as New would
+func NewPostgresDSN(dsn string, poolCfg PoolConfig) (*DB, error) {
+	pool, primary, err := connectPostgres(dsn, poolCfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to PostgreSQL: %w", err)
+	}
+	return &DB{DB: primary, Pool: pool}, nil
+}
+
+func connectPostgres(dsn string, poolCfg PoolConfig) (*pgxpool.Pool, *sqlx.DB, error) {
+	config, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		return nil, nil, err
+	}
+	poolCfg.applyPgx(config)
+
+	pool, err := pgxpool.NewWithConfig(context.Background(), config)
+	if err != nil {
+		return nil, nil, err
+	}
+	// The pool does the pooling, so database/sql hands each connection straight back
+	db, err := open(stdlib.GetPoolConnector(pool), DriverPostgres)
+	if err != nil {
+		pool.Close()
+		return nil, nil, err
+	}
+	db.SetMaxIdleConns(0)
+	return pool, db, nil
+}
@@ -0,0 +1,37 @@
+package db
+
+import (
+	"errors"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/mattn/go-sqlite3"
+)
+
+// SQLSTATEs PostgreSQL aborts a transaction with when it conflicts with another
+const (
+	serializationFailure = "40001"
+	deadlockDetected     = "40P01"
+)
+
+// mysqlDeadlock is the MySQL error number for a transaction rolled back as a deadlock victim
+const mysqlDeadlock = 1213
+
+// IsRetryable reports whether err aborted a transaction only because of a concurrent
+// one: a serialization failure or deadlock, or with SQLite a database that stayed
+// locked. Running the whole transaction again may then succeed.
+func IsRetryable(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == serializationFailure || pgErr.Code == deadlockDetected
+	}
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return mysqlErr.Number == mysqlDeadlock
+	}
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.Code == sqlite3.ErrBusy || sqliteErr.Code == sqlite3.ErrLocked
+	}
+	return false
+}
@@ -0,0 +1,35 @@
+package db
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"PostgreSQL serialization failure", &pgconn.PgError{Code: "40001"}, true},
+		{"PostgreSQL deadlock", &pgconn.PgError{Code: "40P01"}, true},
+		{"PostgreSQL unique violation", &pgconn.PgError{Code: "23505"}, false},
+		{"MySQL deadlock", &mysql.MySQLError{Number: 1213}, true},
+		{"MySQL duplicate entry", &mysql.MySQLError{Number: 1062}, false},
+		{"SQLite busy", sqlite3.Error{Code: sqlite3.ErrBusy}, true},
+		{"SQLite constraint", sqlite3.Error{Code: sqlite3.ErrConstraint}, false},
+		{"wrapped", fmt.Errorf("failed to update balance: %w", &pgconn.PgError{Code: "40P01"}), true},
+		{"other", assert.AnError, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, IsRetryable(tt.err))
+		})
+	}
+}
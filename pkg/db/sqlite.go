@@ -0,0 +1,148 @@
+package db
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/mattn/go-sqlite3"
+	"github.com/shopspring/decimal"
+)
+
+// sqliteDriver opens SQLite connections with the decimal functions the repositories
+// rely on registered
+var sqliteDriver = utcDriver{Driver: &sqlite3.SQLiteDriver{ConnectHook: registerDecimalFunctions}}
+
+// newSQLite opens the database file cfg.Name, creating it if needed. It is meant for
+// local development and tests: there is one writer at a time, and host, port and
+// credentials are ignored.
+func newSQLite(cfg Config) (*sqlx.DB, error) {
+	db, err := connect(DriverSQLite, sqliteDSN(cfg.Name))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open SQLite database: %w", err)
+	}
+
+	return db, nil
+}
+
+// sqliteDSN enables foreign keys, lets readers work alongside the writer (WAL), waits
+// for the write lock rather than failing at once, and takes that lock when each
+// transaction begins, which stands in for the row locks of the other databases
+func sqliteDSN(path string) string {
+	params := url.Values{}
+	params.Set("_foreign_keys", "on")
+	params.Set("_journal_mode", "WAL")
+	params.Set("_busy_timeout", "5000")
+	params.Set("_txlock", "immediate")
+	return "file:" + path + "?" + params.Encode()
+}
+
+// registerDecimalFunctions adds decimal_sum and decimal_add, which add amounts exactly.
+// SQLite stores DECIMAL columns as integers or doubles, so its own SUM and + can be
+// off by a fraction of a cent.
+func registerDecimalFunctions(conn *sqlite3.SQLiteConn) error {
+	if err := conn.RegisterAggregator("decimal_sum", newDecimalSum, true); err != nil {
+		return err
+	}
+	return conn.RegisterFunc("decimal_add", decimalAdd, true)
+}
+
+// decimalSum is the decimal_sum aggregate. Like COALESCE(SUM(x), 0), it skips NULLs
+// and is 0 over no rows.
+type decimalSum struct {
+	total decimal.Decimal
+}
+
+func newDecimalSum() *decimalSum {
+	return &decimalSum{}
+}
+
+func (s *decimalSum) Step(value any) error {
+	amount, err := sqliteDecimal(value)
+	if err != nil {
+		return err
+	}
+	s.total = s.total.Add(amount)
+	return nil
+}
+
+func (s *decimalSum) Done() string {
+	return s.total.String()
+}
+
+// decimalAdd is the decimal_add function, which treats NULL as 0
+func decimalAdd(a, b any) (string, error) {
+	x, err := sqliteDecimal(a)
+	if err != nil {
+		return "", err
+	}
+	y, err := sqliteDecimal(b)
+	if err != nil {
+		return "", err
+	}
+	return x.Add(y).String(), nil
+}
+
+// sqliteDecimal reads a value as SQLite hands it to a function, with NULL arriving as
+// a nil byte slice. Doubles are taken at their shortest representation, which is the
+// decimal that was stored.
+func sqliteDecimal(value any) (decimal.Decimal, error) {
+	switch v := value.(type) {
+	case int64:
+		return decimal.NewFromInt(v), nil
+	case float64:
+		return decimal.NewFromFloat(v), nil
+	case string:
+		return decimal.NewFromString(v)
+	case []byte:
+		if v == nil {
+			return decimal.Zero, nil
+		}
+		return decimal.NewFromString(string(v))
+	default:
+		return decimal.Zero, fmt.Errorf("not a decimal: %T", value)
+	}
+}
+
+// utcDriver writes every time in UTC. SQLite stores times as text, which only sorts
+// and compares correctly when all of them have the same offset.
+type utcDriver struct {
+	driver.Driver
+}
+
+func (d utcDriver) Open(dsn string) (driver.Conn, error) {
+	conn, err := d.Driver.Open(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return utcConn{sqliteConn: conn.(sqliteConn)}, nil
+}
+
+// sqliteConn lists the driver interfaces a go-sqlite3 connection implements, so that
+// utcConn passes them on
+type sqliteConn interface {
+	driver.Conn
+	driver.ConnBeginTx
+	driver.ConnPrepareContext
+	driver.ExecerContext
+	driver.QueryerContext
+	driver.Pinger
+}
+
+type utcConn struct {
+	sqliteConn
+}
+
+func (utcConn) CheckNamedValue(value *driver.NamedValue) error {
+	converted, err := driver.DefaultParameterConverter.ConvertValue(value.Value)
+	if err != nil {
+		return err
+	}
+	if t, ok := converted.(time.Time); ok {
+		converted = t.UTC()
+	}
+	value.Value = converted
+	return nil
+}
@@ -0,0 +1,64 @@
+package db
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func openSQLite(t *testing.T) *DB {
+	t.Helper()
+
+	db, err := New(Config{Driver: DriverSQLite, Name: filepath.Join(t.TempDir(), "test.db")})
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestSQLiteWritesTimesInUTC(t *testing.T) {
+	db := openSQLite(t)
+
+	_, err := db.Exec(`CREATE TABLE events (at DATETIME NOT NULL)`)
+	require.NoError(t, err)
+	at := time.Date(2024, 7, 1, 23, 30, 0, 0, time.FixedZone("UTC-5", -5*60*60))
+	_, err = db.Exec(`INSERT INTO events (at) VALUES (?)`, at)
+	require.NoError(t, err)
+
+	var stored string
+	require.NoError(t, db.Get(&stored, `SELECT CAST(at AS TEXT) FROM events`))
+	assert.Equal(t, "2024-07-02 04:30:00+00:00", stored)
+
+	var read time.Time
+	require.NoError(t, db.Get(&read, `SELECT at FROM events`))
+	assert.True(t, at.Equal(read))
+}
+
+func TestSQLiteDecimalFunctions(t *testing.T) {
+	db := openSQLite(t)
+
+	_, err := db.Exec(`CREATE TABLE amounts (amount DECIMAL(20, 2))`)
+	require.NoError(t, err)
+
+	var empty string
+	require.NoError(t, db.Get(&empty, `SELECT decimal_sum(amount) FROM amounts`))
+	assert.Equal(t, "0", empty)
+
+	for _, amount := range []any{"0.10", "0.20", nil, 3} {
+		_, err = db.Exec(`INSERT INTO amounts (amount) VALUES (?)`, amount)
+		require.NoError(t, err)
+	}
+
+	var sum, added string
+	require.NoError(t, db.Get(&sum, `SELECT decimal_sum(amount) FROM amounts`))
+	assert.Equal(t, "3.3", sum)
+	require.NoError(t, db.Get(&added, `SELECT decimal_add(0.1, NULL)`))
+	assert.Equal(t, "0.1", added)
+}
+
+func TestSQLiteRejectsReplica(t *testing.T) {
+	_, err := New(Config{Driver: DriverSQLite, Name: filepath.Join(t.TempDir(), "test.db"), ReplicaDSN: "replica.db"})
+	assert.Error(t, err)
+}
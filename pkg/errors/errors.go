@@ -1,28 +1,77 @@
 package errors
 
 import (
-	"encoding/json"
+	"context"
+	stderrors "errors"
 	"fmt"
 	"net/http"
+	"strconv"
 )
 
 // Error codes for the application
 const (
 	// Validation errors
-	ErrInvalidInput  = "INVALID_INPUT"
-	ErrMissingField  = "MISSING_FIELD"
-	ErrInvalidUUID   = "INVALID_UUID"
-	ErrInvalidAmount = "INVALID_AMOUNT"
+	ErrInvalidInput     = "INVALID_INPUT"
+	ErrMissingField     = "MISSING_FIELD"
+	ErrInvalidUUID      = "INVALID_UUID"
+	ErrInvalidAmount    = "INVALID_AMOUNT"
+	ErrAmountOutOfRange = "AMOUNT_OUT_OF_RANGE"
+	ErrValidation       = "VALIDATION_FAILED"
+	ErrPayloadSize      = "PAYLOAD_TOO_LARGE"
 
 	// Business logic errors
-	ErrInsufficientFunds  = "INSUFFICIENT_FUNDS"
-	ErrWalletNotFound     = "WALLET_NOT_FOUND"
-	ErrUserNotFound       = "USER_NOT_FOUND"
-	ErrSameWalletTransfer = "SAME_WALLET_TRANSFER"
+	ErrInsufficientFunds         = "INSUFFICIENT_FUNDS"
+	ErrWalletNotFound            = "WALLET_NOT_FOUND"
+	ErrUserNotFound              = "USER_NOT_FOUND"
+	ErrScheduledTransferNotFound = "SCHEDULED_TRANSFER_NOT_FOUND"
+	ErrHoldNotFound              = "HOLD_NOT_FOUND"
+	ErrPaymentRequestNotFound    = "PAYMENT_REQUEST_NOT_FOUND"
+	ErrPendingTransferNotFound   = "PENDING_TRANSFER_NOT_FOUND"
+	ErrIncomingTransferNotFound  = "INCOMING_TRANSFER_NOT_FOUND"
+	ErrQuoteNotFound             = "QUOTE_NOT_FOUND"
+	ErrMerchantNotFound          = "MERCHANT_NOT_FOUND"
+	ErrPaymentNotFound           = "PAYMENT_NOT_FOUND"
+	ErrPayoutNotFound            = "PAYOUT_NOT_FOUND"
+	ErrExportNotFound            = "EXPORT_NOT_FOUND"
+	ErrProviderPaymentMismatch   = "PROVIDER_PAYMENT_MISMATCH"
+	ErrOrderAlreadyPaid          = "ORDER_ALREADY_PAID"
+	ErrTransferNotFound          = "TRANSFER_NOT_FOUND"
+	ErrTransactionNotFound       = "TRANSACTION_NOT_FOUND"
+	ErrAlreadyReversed           = "ALREADY_REVERSED"
+	ErrAlreadyRefunded           = "ALREADY_REFUNDED"
+	ErrPaidOut                   = "PAID_OUT"
+	ErrDisputeNotFound           = "DISPUTE_NOT_FOUND"
+	ErrAlreadyDisputed           = "ALREADY_DISPUTED"
+	ErrSameWalletTransfer        = "SAME_WALLET_TRANSFER"
+	ErrWalletFrozen              = "WALLET_FROZEN"
+	ErrWalletClosed              = "WALLET_CLOSED"
+	ErrLimitExceeded             = "LIMIT_EXCEEDED"
+	ErrOperationBlocked          = "OPERATION_BLOCKED"
+	ErrExchangeRateUnavailable   = "EXCHANGE_RATE_UNAVAILABLE"
+	ErrFeatureDisabled           = "FEATURE_DISABLED"
+	ErrFeatureFlagNotFound       = "FEATURE_FLAG_NOT_FOUND"
+	ErrVersionMismatch           = "VERSION_MISMATCH"
+	ErrEmailTaken                = "EMAIL_TAKEN"
+	ErrPhoneTaken                = "PHONE_TAKEN"
+	ErrPINRequired               = "PIN_REQUIRED"
+	ErrIncorrectPIN              = "INCORRECT_PIN"
+	ErrPINLocked                 = "PIN_LOCKED"
+	ErrPINNotSet                 = "PIN_NOT_SET"
+	ErrKYCDocumentNotFound       = "KYC_DOCUMENT_NOT_FOUND"
+	ErrSettlementWallet          = "SETTLEMENT_WALLET"
+	ErrConfirmationRequired      = "CONFIRMATION_REQUIRED"
+
+	// Authentication errors
+	ErrUnauthorized     = "UNAUTHORIZED"
+	ErrInvalidSignature = "INVALID_SIGNATURE"
+	ErrForbidden        = "FORBIDDEN"
+	ErrConflict         = "CONFLICT"
+	ErrRateLimited      = "RATE_LIMITED"
 
 	// System errors
 	ErrDatabaseConnection = "DATABASE_CONNECTION"
 	ErrTransactionFailed  = "TRANSACTION_FAILED"
+	ErrTimeout            = "TIMEOUT"
 	ErrInternal           = "INTERNAL_ERROR"
 )
 
@@ -79,6 +128,26 @@ func InvalidInput(message string) *AppError {
 	return New(ErrInvalidInput, message, http.StatusBadRequest)
 }
 
+func InvalidAmount(message string) *AppError {
+	return New(ErrInvalidAmount, message, http.StatusBadRequest)
+}
+
+// ValidationFailed reports a request body that broke its validation rules. Details
+// maps each failing field to the rule it broke, e.g. "amount": "gt=0".
+func ValidationFailed(fields map[string]string) *AppError {
+	appErr := New(ErrValidation, "Request validation failed", http.StatusBadRequest)
+	for field, rule := range fields {
+		appErr.WithDetails(field, rule)
+	}
+	return appErr
+}
+
+// PayloadTooLarge reports a request body over the server's size limit
+func PayloadTooLarge(limit int64) *AppError {
+	return New(ErrPayloadSize, "Request body is too large", http.StatusRequestEntityTooLarge).
+		WithDetails("max_bytes", strconv.FormatInt(limit, 10))
+}
+
 func InsufficientFunds() *AppError {
 	return New(ErrInsufficientFunds, "Insufficient funds for this operation", http.StatusBadRequest)
 }
@@ -88,48 +157,60 @@ func WalletNotFound(walletID string) *AppError {
 		WithDetails("wallet_id", walletID)
 }
 
+func WalletFrozen(message string) *AppError {
+	return New(ErrWalletFrozen, message, http.StatusConflict)
+}
+
+func WalletClosed(message string) *AppError {
+	return New(ErrWalletClosed, message, http.StatusConflict)
+}
+
+func LimitExceeded(message string) *AppError {
+	return New(ErrLimitExceeded, message, http.StatusUnprocessableEntity)
+}
+
 func UserNotFound(userID string) *AppError {
 	return New(ErrUserNotFound, "User not found", http.StatusNotFound).
 		WithDetails("user_id", userID)
 }
 
-func DatabaseError(err error) *AppError {
-	return Wrap(err, ErrDatabaseConnection, "Database operation failed", http.StatusInternalServerError)
+func Unauthorized(message string) *AppError {
+	return New(ErrUnauthorized, message, http.StatusUnauthorized)
 }
 
-func InternalError(err error) *AppError {
-	return Wrap(err, ErrInternal, "Internal server error", http.StatusInternalServerError)
+func Forbidden(message string) *AppError {
+	return New(ErrForbidden, message, http.StatusForbidden)
 }
 
-// HTTP response utilities
-
-// ErrorResponse represents a JSON error response
-type ErrorResponse struct {
-	Error string `json:"error"`
-	Code  string `json:"code,omitempty"`
+func Conflict(message string) *AppError {
+	return New(ErrConflict, message, http.StatusConflict)
 }
 
-// RespondWithError sends a JSON error response
-func RespondWithError(w http.ResponseWriter, httpStatus int, message string) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(httpStatus)
+// PreconditionFailed is returned when the resource no longer matches the If-Match header
+func PreconditionFailed(message string) *AppError {
+	return New(ErrVersionMismatch, message, http.StatusPreconditionFailed)
+}
 
-	response := ErrorResponse{
-		Error: message,
-	}
+func RateLimited(retryAfterSeconds int) *AppError {
+	return New(ErrRateLimited, "Too many requests", http.StatusTooManyRequests).
+		WithDetails("retry_after_seconds", strconv.Itoa(retryAfterSeconds))
+}
 
-	json.NewEncoder(w).Encode(response)
+func DatabaseError(err error) *AppError {
+	return Wrap(err, ErrDatabaseConnection, "Database operation failed", http.StatusInternalServerError)
 }
 
-// RespondWithAppError sends a JSON error response using an AppError
-func RespondWithAppError(w http.ResponseWriter, appErr *AppError) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(appErr.HTTPStatus)
+// Timeout reports an operation that ran out of time, such as a query cancelled at the
+// request's deadline
+func Timeout(err error) *AppError {
+	return Wrap(err, ErrTimeout, "The operation timed out", http.StatusGatewayTimeout)
+}
 
-	response := ErrorResponse{
-		Error: appErr.Message,
-		Code:  appErr.Code,
+// InternalError wraps an unexpected failure, or a Timeout when err is a deadline
+// running out
+func InternalError(err error) *AppError {
+	if stderrors.Is(err, context.DeadlineExceeded) {
+		return Timeout(err)
 	}
-
-	json.NewEncoder(w).Encode(response)
+	return Wrap(err, ErrInternal, "Internal server error", http.StatusInternalServerError)
 }
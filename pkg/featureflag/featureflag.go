@@ -0,0 +1,187 @@
+// Package featureflag switches features on and off per environment, with overrides
+// that operators can set at runtime in memory, the database or Redis
+package featureflag
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/shanwije/wallet-app/pkg/clock"
+	"github.com/shanwije/wallet-app/pkg/logger"
+)
+
+// ErrUnknownFlag is returned for a flag that has no default, which catches typos
+var ErrUnknownFlag = errors.New("unknown feature flag")
+
+// Store keeps the overrides set at runtime
+type Store interface {
+	// GetFlag returns the flag's override; ok is false when it has none
+	GetFlag(ctx context.Context, name string) (enabled, ok bool, err error)
+	// SetFlag creates or replaces the flag's override
+	SetFlag(ctx context.Context, name string, enabled bool) error
+	// DeleteFlag removes the flag's override, if any
+	DeleteFlag(ctx context.Context, name string) error
+	// ListFlags returns every override
+	ListFlags(ctx context.Context) (map[string]bool, error)
+}
+
+// Flag is a flag's current value and where it comes from
+type Flag struct {
+	Name    string `json:"name"`
+	Enabled bool   `json:"enabled"`
+	Default bool   `json:"default"`
+	// Overridden is true when Enabled was set at runtime rather than by the default
+	Overridden bool `json:"overridden"`
+}
+
+type cachedFlag struct {
+	enabled   bool
+	expiresAt time.Time
+}
+
+// Flags answers whether features are enabled. A flag's value is its override in the
+// store when it has one, otherwise its default. Values are cached for the TTL, so an
+// override set on another instance takes up to that long to apply here.
+type Flags struct {
+	defaults map[string]bool
+	store    Store
+	ttl      time.Duration
+	clock    clock.Clock
+
+	mu    sync.Mutex
+	cache map[string]cachedFlag
+}
+
+// New creates the flags with the given defaults, which also define the known flags.
+// clk may be nil to use the system clock.
+func New(defaults map[string]bool, store Store, ttl time.Duration, clk clock.Clock) *Flags {
+	return &Flags{
+		defaults: defaults,
+		store:    store,
+		ttl:      ttl,
+		clock:    clock.OrDefault(clk),
+		cache:    make(map[string]cachedFlag),
+	}
+}
+
+// Enabled reports whether the flag is on. Unknown flags are off. When the store
+// cannot be read the default is used, so an outage does not switch features off.
+func (f *Flags) Enabled(ctx context.Context, name string) bool {
+	def, known := f.defaults[name]
+	if !known {
+		return false
+	}
+
+	now := f.clock.Now()
+	f.mu.Lock()
+	cached, ok := f.cache[name]
+	f.mu.Unlock()
+	if ok && now.Before(cached.expiresAt) {
+		return cached.enabled
+	}
+
+	enabled, overridden, err := f.store.GetFlag(ctx, name)
+	if err != nil {
+		logger.FromContext(ctx).Warn("Failed to read feature flag, using its default",
+			zap.String("flag", name), zap.Error(err))
+		return def
+	}
+	if !overridden {
+		enabled = def
+	}
+
+	f.mu.Lock()
+	f.cache[name] = cachedFlag{enabled: enabled, expiresAt: now.Add(f.ttl)}
+	f.mu.Unlock()
+	return enabled
+}
+
+// List returns every known flag, by name
+func (f *Flags) List(ctx context.Context) ([]Flag, error) {
+	overrides, err := f.store.ListFlags(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list feature flags: %w", err)
+	}
+
+	flags := make([]Flag, 0, len(f.defaults))
+	for name, def := range f.defaults {
+		flag := Flag{Name: name, Enabled: def, Default: def}
+		if enabled, ok := overrides[name]; ok {
+			flag.Enabled, flag.Overridden = enabled, true
+		}
+		flags = append(flags, flag)
+	}
+	sort.Slice(flags, func(i, j int) bool { return flags[i].Name < flags[j].Name })
+	return flags, nil
+}
+
+// Set overrides the flag
+func (f *Flags) Set(ctx context.Context, name string, enabled bool) (*Flag, error) {
+	def, known := f.defaults[name]
+	if !known {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownFlag, name)
+	}
+	if err := f.store.SetFlag(ctx, name, enabled); err != nil {
+		return nil, fmt.Errorf("failed to set feature flag: %w", err)
+	}
+	f.forget(name)
+	return &Flag{Name: name, Enabled: enabled, Default: def, Overridden: true}, nil
+}
+
+// Reset removes the flag's override, returning it to its default
+func (f *Flags) Reset(ctx context.Context, name string) (*Flag, error) {
+	def, known := f.defaults[name]
+	if !known {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownFlag, name)
+	}
+	if err := f.store.DeleteFlag(ctx, name); err != nil {
+		return nil, fmt.Errorf("failed to reset feature flag: %w", err)
+	}
+	f.forget(name)
+	return &Flag{Name: name, Enabled: def, Default: def}, nil
+}
+
+// forget drops the cached value so this instance sees a change at once
+func (f *Flags) forget(name string) {
+	f.mu.Lock()
+	delete(f.cache, name)
+	f.mu.Unlock()
+}
+
+// ParseDefaults applies comma-separated name=true|false entries, such as
+// "transfers=false,overdraft=true", over defaults. Every name must already be known.
+func ParseDefaults(defaults map[string]bool, entries string) (map[string]bool, error) {
+	parsed := make(map[string]bool, len(defaults))
+	for name, enabled := range defaults {
+		parsed[name] = enabled
+	}
+
+	for _, entry := range strings.Split(entries, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, value, found := strings.Cut(entry, "=")
+		if !found {
+			return nil, fmt.Errorf("invalid feature flag %q, expected name=true|false", entry)
+		}
+		name = strings.TrimSpace(name)
+		if _, known := parsed[name]; !known {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownFlag, name)
+		}
+		enabled, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for feature flag %s: %w", name, err)
+		}
+		parsed[name] = enabled
+	}
+	return parsed, nil
+}
@@ -0,0 +1,124 @@
+package featureflag
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shanwije/wallet-app/pkg/clock"
+)
+
+// failingStore cannot be read, like a database that is down
+type failingStore struct{ *Memory }
+
+func (failingStore) GetFlag(context.Context, string) (bool, bool, error) {
+	return false, false, errors.New("connection refused")
+}
+
+func TestEnabledUsesOverrideOverDefault(t *testing.T) {
+	store := NewMemory()
+	flags := New(map[string]bool{"transfers": true, "overdraft": false}, store, 0, nil)
+	ctx := context.Background()
+
+	require.NoError(t, store.SetFlag(ctx, "overdraft", true))
+
+	assert.True(t, flags.Enabled(ctx, "transfers"))
+	assert.True(t, flags.Enabled(ctx, "overdraft"))
+	assert.False(t, flags.Enabled(ctx, "webhooks"))
+}
+
+func TestEnabledCachesForTTL(t *testing.T) {
+	clk := clock.NewFake(time.Date(2024, 7, 4, 9, 0, 0, 0, time.UTC))
+	store := NewMemory()
+	flags := New(map[string]bool{"transfers": true}, store, 5*time.Second, clk)
+	ctx := context.Background()
+
+	assert.True(t, flags.Enabled(ctx, "transfers"))
+	// Another instance switches transfers off
+	require.NoError(t, store.SetFlag(ctx, "transfers", false))
+	assert.True(t, flags.Enabled(ctx, "transfers"))
+
+	clk.Advance(5 * time.Second)
+	assert.False(t, flags.Enabled(ctx, "transfers"))
+}
+
+func TestEnabledFallsBackToDefaultWhenStoreFails(t *testing.T) {
+	flags := New(map[string]bool{"transfers": true}, failingStore{NewMemory()}, time.Minute, nil)
+
+	assert.True(t, flags.Enabled(context.Background(), "transfers"))
+}
+
+func TestSetAndReset(t *testing.T) {
+	clk := clock.NewFake(time.Date(2024, 7, 4, 9, 0, 0, 0, time.UTC))
+	flags := New(map[string]bool{"transfers": true, "overdraft": true}, NewMemory(), time.Minute, clk)
+	ctx := context.Background()
+	assert.True(t, flags.Enabled(ctx, "transfers"))
+
+	flag, err := flags.Set(ctx, "transfers", false)
+	require.NoError(t, err)
+	assert.Equal(t, &Flag{Name: "transfers", Enabled: false, Default: true, Overridden: true}, flag)
+	assert.False(t, flags.Enabled(ctx, "transfers"))
+
+	list, err := flags.List(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []Flag{
+		{Name: "overdraft", Enabled: true, Default: true},
+		{Name: "transfers", Enabled: false, Default: true, Overridden: true},
+	}, list)
+
+	flag, err = flags.Reset(ctx, "transfers")
+	require.NoError(t, err)
+	assert.Equal(t, &Flag{Name: "transfers", Enabled: true, Default: true}, flag)
+	assert.True(t, flags.Enabled(ctx, "transfers"))
+
+	_, err = flags.Set(ctx, "webhooks", true)
+	assert.ErrorIs(t, err, ErrUnknownFlag)
+	_, err = flags.Reset(ctx, "webhooks")
+	assert.ErrorIs(t, err, ErrUnknownFlag)
+}
+
+func TestParseDefaults(t *testing.T) {
+	defaults := map[string]bool{"transfers": true, "overdraft": true}
+
+	parsed, err := ParseDefaults(defaults, " overdraft=false , transfers=1")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{"transfers": true, "overdraft": false}, parsed)
+	assert.True(t, defaults["overdraft"], "defaults must not be changed")
+
+	_, err = ParseDefaults(defaults, "webhooks=true")
+	assert.ErrorIs(t, err, ErrUnknownFlag)
+	_, err = ParseDefaults(defaults, "overdraft")
+	assert.Error(t, err)
+	_, err = ParseDefaults(defaults, "overdraft=maybe")
+	assert.Error(t, err)
+}
+
+func TestRedisStore(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	store := NewRedis(client)
+	ctx := context.Background()
+
+	_, ok, err := store.GetFlag(ctx, "transfers")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	require.NoError(t, store.SetFlag(ctx, "transfers", false))
+	require.NoError(t, store.SetFlag(ctx, "overdraft", true))
+	enabled, ok, err := store.GetFlag(ctx, "transfers")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.False(t, enabled)
+
+	require.NoError(t, store.DeleteFlag(ctx, "overdraft"))
+	all, err := store.ListFlags(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{"transfers": false}, all)
+}
@@ -0,0 +1,104 @@
+package featureflag
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Memory keeps overrides in process memory, so they are lost on restart and apply to
+// this instance only
+type Memory struct {
+	mu    sync.RWMutex
+	flags map[string]bool
+}
+
+// NewMemory creates an empty in-memory store
+func NewMemory() *Memory {
+	return &Memory{flags: make(map[string]bool)}
+}
+
+func (m *Memory) GetFlag(_ context.Context, name string) (bool, bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	enabled, ok := m.flags[name]
+	return enabled, ok, nil
+}
+
+func (m *Memory) SetFlag(_ context.Context, name string, enabled bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.flags[name] = enabled
+	return nil
+}
+
+func (m *Memory) DeleteFlag(_ context.Context, name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.flags, name)
+	return nil
+}
+
+func (m *Memory) ListFlags(context.Context) (map[string]bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	flags := make(map[string]bool, len(m.flags))
+	for name, enabled := range m.flags {
+		flags[name] = enabled
+	}
+	return flags, nil
+}
+
+// redisKey is the hash holding every override in a shared Redis
+const redisKey = "featureflags"
+
+// Redis keeps overrides in a Redis hash shared by every instance
+type Redis struct {
+	client *redis.Client
+}
+
+// NewRedis creates a store backed by client
+func NewRedis(client *redis.Client) *Redis {
+	return &Redis{client: client}
+}
+
+func (r *Redis) GetFlag(ctx context.Context, name string) (bool, bool, error) {
+	value, err := r.client.HGet(ctx, redisKey, name).Result()
+	if errors.Is(err, redis.Nil) {
+		return false, false, nil
+	}
+	if err != nil {
+		return false, false, err
+	}
+	enabled, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, false, fmt.Errorf("invalid value %q for feature flag %s", value, name)
+	}
+	return enabled, true, nil
+}
+
+func (r *Redis) SetFlag(ctx context.Context, name string, enabled bool) error {
+	return r.client.HSet(ctx, redisKey, name, strconv.FormatBool(enabled)).Err()
+}
+
+func (r *Redis) DeleteFlag(ctx context.Context, name string) error {
+	return r.client.HDel(ctx, redisKey, name).Err()
+}
+
+func (r *Redis) ListFlags(ctx context.Context) (map[string]bool, error) {
+	values, err := r.client.HGetAll(ctx, redisKey).Result()
+	if err != nil {
+		return nil, err
+	}
+	flags := make(map[string]bool, len(values))
+	for name, value := range values {
+		if enabled, err := strconv.ParseBool(value); err == nil {
+			flags[name] = enabled
+		}
+	}
+	return flags, nil
+}
@@ -5,9 +5,14 @@ import (
 	"database/sql"
 	"encoding/json"
 	"net/http"
+	"strconv"
+	"sync"
 	"time"
 
+	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
+
+	"github.com/shanwije/wallet-app/pkg/clock"
 )
 
 // Status represents the health status
@@ -24,9 +29,15 @@ type HealthCheck struct {
 	Name        string            `json:"name"`
 	Status      Status            `json:"status"`
 	Message     string            `json:"message,omitempty"`
-	Duration    time.Duration     `json:"duration"`
+	Duration    time.Duration     `json:"duration" swaggertype:"integer"`
 	LastChecked time.Time         `json:"last_checked"`
 	Details     map[string]string `json:"details,omitempty"`
+	// Critical checks make the service unhealthy when they fail; the others only degrade it
+	Critical bool `json:"critical"`
+	// Cached is set when the result was reused rather than checked for this request
+	Cached bool `json:"cached"`
+	// LatencyHistory holds the durations of the most recent checks, oldest first
+	LatencyHistory []time.Duration `json:"latency_history,omitempty" swaggertype:"array,integer"`
 }
 
 // HealthReport represents the overall health status
@@ -43,7 +54,8 @@ type Checker interface {
 	Check(ctx context.Context) HealthCheck
 }
 
-// DatabaseChecker checks database connectivity
+// DatabaseChecker checks database connectivity and reports connection pool usage,
+// so operators can size the pools
 type DatabaseChecker struct {
 	DB   *sql.DB
 	Name string
@@ -67,15 +79,100 @@ func (d *DatabaseChecker) Check(ctx context.Context) HealthCheck {
 	} else {
 		check.Status = StatusHealthy
 		check.Message = "Database connection successful"
+
+		stats := d.DB.Stats()
+		check.Details = map[string]string{
+			"max_open_connections": strconv.Itoa(stats.MaxOpenConnections),
+			"open_connections":     strconv.Itoa(stats.OpenConnections),
+			"in_use":               strconv.Itoa(stats.InUse),
+			"idle":                 strconv.Itoa(stats.Idle),
+			"wait_count":           strconv.FormatInt(stats.WaitCount, 10),
+		}
 	}
 
 	check.Duration = time.Since(start)
 	return check
 }
 
-// Handler provides HTTP health check endpoints
+// RedisChecker checks Redis connectivity
+type RedisChecker struct {
+	Client *redis.Client
+	Name   string
+}
+
+func (c *RedisChecker) Check(ctx context.Context) HealthCheck {
+	start := time.Now()
+
+	check := HealthCheck{
+		Name:        c.Name,
+		LastChecked: start,
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	if err := c.Client.Ping(timeoutCtx).Err(); err != nil {
+		check.Status = StatusUnhealthy
+		check.Message = "Redis connection failed"
+		check.Details = map[string]string{"error": err.Error()}
+	} else {
+		check.Status = StatusHealthy
+		check.Message = "Redis connection successful"
+	}
+
+	check.Duration = time.Since(start)
+	return check
+}
+
+// historySize is how many recent latencies are reported for each check
+const historySize = 10
+
+// registration is a checker in the registry with its latest result and latencies
+type registration struct {
+	checker  Checker
+	critical bool
+
+	mutex   sync.Mutex
+	last    HealthCheck
+	expires time.Time
+	history []time.Duration
+}
+
+// result returns the checker's latest result, running the check again once the
+// cached one is older than ttl. Callers arriving while a check runs wait for it
+// rather than starting their own, so probes never pile up on a slow dependency.
+func (r *registration) result(ctx context.Context, ttl time.Duration, now time.Time) HealthCheck {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	cached := now.Before(r.expires)
+	if !cached {
+		// The result is shared, so one caller going away must not fail it for the rest
+		r.last = r.checker.Check(context.WithoutCancel(ctx))
+		r.expires = now.Add(ttl)
+		r.history = append(r.history, r.last.Duration)
+		if len(r.history) > historySize {
+			r.history = r.history[len(r.history)-historySize:]
+		}
+	}
+
+	check := r.last
+	check.Critical = r.critical
+	check.Cached = cached
+	check.LatencyHistory = append([]time.Duration(nil), r.history...)
+	return check
+}
+
+// Handler provides HTTP health check endpoints over a registry of checkers.
+// Critical checkers are dependencies the service cannot work without: when one
+// fails the service is unhealthy and not ready. Failures of the others only
+// degrade it.
 type Handler struct {
-	checkers    map[string]Checker
+	// CacheTTL is how long a check's result is reused before it runs again
+	CacheTTL time.Duration
+	Clock    clock.Clock
+
+	checkers    map[string]*registration
 	version     string
 	environment string
 	logger      *zap.Logger
@@ -84,54 +181,86 @@ type Handler struct {
 // NewHandler creates a new health check handler
 func NewHandler(version, environment string, logger *zap.Logger) *Handler {
 	return &Handler{
-		checkers:    make(map[string]Checker),
+		checkers:    make(map[string]*registration),
 		version:     version,
 		environment: environment,
 		logger:      logger,
 	}
 }
 
-// AddChecker adds a health checker
+// AddChecker adds a non-critical health checker
 func (h *Handler) AddChecker(name string, checker Checker) {
-	h.checkers[name] = checker
+	h.checkers[name] = &registration{checker: checker}
+}
+
+// AddCriticalChecker adds a health checker for a dependency the service cannot
+// work without
+func (h *Handler) AddCriticalChecker(name string, checker Checker) {
+	h.checkers[name] = &registration{checker: checker, critical: true}
+}
+
+// runChecks runs the registered checks concurrently, reusing cached results
+func (h *Handler) runChecks(ctx context.Context, criticalOnly bool) map[string]HealthCheck {
+	now := clock.OrDefault(h.Clock).Now()
+
+	var (
+		wg      sync.WaitGroup
+		mutex   sync.Mutex
+		results = make(map[string]HealthCheck, len(h.checkers))
+	)
+	for name, reg := range h.checkers {
+		if criticalOnly && !reg.critical {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			check := reg.result(ctx, h.CacheTTL, now)
+			mutex.Lock()
+			results[name] = check
+			mutex.Unlock()
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// overallStatus is unhealthy when a critical check fails and degraded when any
+// other check is not healthy
+func overallStatus(checks map[string]HealthCheck) Status {
+	status := StatusHealthy
+	for _, check := range checks {
+		switch {
+		case check.Status == StatusUnhealthy && check.Critical:
+			return StatusUnhealthy
+		case check.Status != StatusHealthy:
+			status = StatusDegraded
+		}
+	}
+	return status
 }
 
 // HealthHandler returns the overall health status
+// @Summary Health check
+// @Description Reports every dependency check with its recent latencies. Returns 503
+// @Description when a dependency the service cannot work without is down.
+// @Tags health
+// @Produce json
+// @Success 200 {object} health.HealthReport
+// @Failure 503 {object} health.HealthReport
+// @Router /health [get]
 func (h *Handler) HealthHandler(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
-
 	report := HealthReport{
 		Version:     h.version,
 		Timestamp:   time.Now(),
 		Environment: h.environment,
-		Checks:      make(map[string]HealthCheck),
-	}
-
-	overallStatus := StatusHealthy
-
-	// Run all health checks
-	for name, checker := range h.checkers {
-		check := checker.Check(ctx)
-		report.Checks[name] = check
-
-		// Determine overall status
-		if check.Status == StatusUnhealthy {
-			overallStatus = StatusUnhealthy
-		} else if check.Status == StatusDegraded && overallStatus == StatusHealthy {
-			overallStatus = StatusDegraded
-		}
+		Checks:      h.runChecks(r.Context(), false),
 	}
+	report.Status = overallStatus(report.Checks)
 
-	report.Status = overallStatus
-
-	// Set appropriate HTTP status code
-	var httpStatus int
-	switch overallStatus {
-	case StatusHealthy:
-		httpStatus = http.StatusOK
-	case StatusDegraded:
-		httpStatus = http.StatusOK // Still return 200 for degraded
-	case StatusUnhealthy:
+	// Degraded still returns 200
+	httpStatus := http.StatusOK
+	if report.Status == StatusUnhealthy {
 		httpStatus = http.StatusServiceUnavailable
 	}
 
@@ -143,22 +272,34 @@ func (h *Handler) HealthHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// ReadinessHandler returns readiness status (simpler check for k8s)
+// ReadinessHandler reports whether the service can take traffic. Only critical
+// checks are run, so an outage of an optional dependency such as Redis does not
+// take the service out of rotation.
+// @Summary Readiness probe
+// @Tags health
+// @Produce plain
+// @Success 200 {string} string "Ready"
+// @Failure 503 {string} string "Not Ready"
+// @Router /ready [get]
 func (h *Handler) ReadinessHandler(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
-
-	// Only check critical services for readiness
-	for name, checker := range h.checkers {
-		if name == "database" { // Only database is critical for readiness
-			check := checker.Check(ctx)
-			if check.Status == StatusUnhealthy {
-				w.WriteHeader(http.StatusServiceUnavailable)
-				w.Write([]byte("Not Ready"))
-				return
-			}
-		}
+	if overallStatus(h.runChecks(r.Context(), true)) == StatusUnhealthy {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte("Not Ready"))
+		return
 	}
 
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("Ready"))
 }
+
+// LivenessHandler reports that the process is up and serving. It checks no
+// dependencies, so an outage elsewhere never gets healthy instances restarted.
+// @Summary Liveness probe
+// @Tags health
+// @Produce plain
+// @Success 200 {string} string "Alive"
+// @Router /live [get]
+func (h *Handler) LivenessHandler(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Alive"))
+}
@@ -0,0 +1,184 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/shanwije/wallet-app/pkg/clock"
+)
+
+// staticChecker always reports the same status
+type staticChecker Status
+
+func (c staticChecker) Check(context.Context) HealthCheck {
+	return HealthCheck{Status: Status(c)}
+}
+
+// countingChecker counts how often it is run and reports the next latency in turn
+type countingChecker struct {
+	runs      int
+	latencies []time.Duration
+}
+
+func (c *countingChecker) Check(context.Context) HealthCheck {
+	latency := c.latencies[c.runs%len(c.latencies)]
+	c.runs++
+	return HealthCheck{Status: StatusHealthy, Duration: latency}
+}
+
+func TestHandlerEndpoints(t *testing.T) {
+	tests := []struct {
+		name         string
+		critical     map[string]Status
+		optional     map[string]Status
+		healthStatus int
+		overall      Status
+		readyStatus  int
+	}{
+		{
+			name:         "All healthy",
+			critical:     map[string]Status{"database": StatusHealthy},
+			optional:     map[string]Status{"redis": StatusHealthy},
+			healthStatus: http.StatusOK,
+			overall:      StatusHealthy,
+			readyStatus:  http.StatusOK,
+		},
+		{
+			name:         "Optional dependency down",
+			critical:     map[string]Status{"database": StatusHealthy},
+			optional:     map[string]Status{"redis": StatusUnhealthy},
+			healthStatus: http.StatusOK,
+			overall:      StatusDegraded,
+			readyStatus:  http.StatusOK,
+		},
+		{
+			name:         "Critical dependency degraded",
+			critical:     map[string]Status{"database": StatusDegraded},
+			healthStatus: http.StatusOK,
+			overall:      StatusDegraded,
+			readyStatus:  http.StatusOK,
+		},
+		{
+			name:         "Critical dependency down",
+			critical:     map[string]Status{"database": StatusHealthy, "database_replica": StatusUnhealthy},
+			optional:     map[string]Status{"redis": StatusHealthy},
+			healthStatus: http.StatusServiceUnavailable,
+			overall:      StatusUnhealthy,
+			readyStatus:  http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHandler("v1", "test", zap.NewNop())
+			for name, status := range tt.critical {
+				h.AddCriticalChecker(name, staticChecker(status))
+			}
+			for name, status := range tt.optional {
+				h.AddChecker(name, staticChecker(status))
+			}
+
+			rr := httptest.NewRecorder()
+			h.HealthHandler(rr, httptest.NewRequest(http.MethodGet, "/health", nil))
+			assert.Equal(t, tt.healthStatus, rr.Code)
+
+			var report HealthReport
+			require.NoError(t, json.NewDecoder(rr.Body).Decode(&report))
+			assert.Equal(t, tt.overall, report.Status)
+			assert.Len(t, report.Checks, len(tt.critical)+len(tt.optional))
+			for name := range tt.critical {
+				assert.True(t, report.Checks[name].Critical, name)
+			}
+			for name := range tt.optional {
+				assert.False(t, report.Checks[name].Critical, name)
+			}
+
+			rr = httptest.NewRecorder()
+			h.ReadinessHandler(rr, httptest.NewRequest(http.MethodGet, "/ready", nil))
+			assert.Equal(t, tt.readyStatus, rr.Code)
+
+			// Liveness never depends on other services
+			rr = httptest.NewRecorder()
+			h.LivenessHandler(rr, httptest.NewRequest(http.MethodGet, "/live", nil))
+			assert.Equal(t, http.StatusOK, rr.Code)
+		})
+	}
+}
+
+func TestHandlerCachesResultsAndKeepsLatencyHistory(t *testing.T) {
+	clk := clock.NewFake(time.Date(2024, 6, 30, 9, 0, 0, 0, time.UTC))
+	checker := &countingChecker{latencies: []time.Duration{time.Millisecond, 3 * time.Millisecond}}
+
+	h := NewHandler("v1", "test", zap.NewNop())
+	h.CacheTTL = 5 * time.Second
+	h.Clock = clk
+	h.AddCriticalChecker("database", checker)
+
+	report := func() HealthCheck {
+		rr := httptest.NewRecorder()
+		h.HealthHandler(rr, httptest.NewRequest(http.MethodGet, "/health", nil))
+		var report HealthReport
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&report))
+		return report.Checks["database"]
+	}
+
+	first := report()
+	assert.False(t, first.Cached)
+	assert.Equal(t, []time.Duration{time.Millisecond}, first.LatencyHistory)
+
+	// Within the TTL the health and readiness endpoints reuse the result
+	clk.Advance(4 * time.Second)
+	assert.True(t, report().Cached)
+	h.ReadinessHandler(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/ready", nil))
+	assert.Equal(t, 1, checker.runs)
+
+	clk.Advance(2 * time.Second)
+	second := report()
+	assert.False(t, second.Cached)
+	assert.Equal(t, 2, checker.runs)
+	assert.Equal(t, []time.Duration{time.Millisecond, 3 * time.Millisecond}, second.LatencyHistory)
+
+	// Only the most recent latencies are kept
+	for i := 0; i < historySize; i++ {
+		clk.Advance(6 * time.Second)
+		report()
+	}
+	assert.Len(t, report().LatencyHistory, historySize)
+}
+
+func TestDatabaseCheckerReportsUnreachableDatabase(t *testing.T) {
+	db, err := sql.Open("pgx", "host=127.0.0.1 port=1 user=wallet dbname=wallet_db sslmode=disable connect_timeout=1")
+	require.NoError(t, err)
+	defer db.Close()
+
+	check := (&DatabaseChecker{DB: db, Name: "database"}).Check(context.Background())
+
+	assert.Equal(t, StatusUnhealthy, check.Status)
+	assert.NotEmpty(t, check.Details["error"])
+}
+
+func TestRedisChecker(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+	checker := &RedisChecker{Client: client, Name: "redis"}
+
+	assert.Equal(t, StatusHealthy, checker.Check(context.Background()).Status)
+
+	server.Close()
+	check := checker.Check(context.Background())
+	assert.Equal(t, StatusUnhealthy, check.Status)
+	assert.NotEmpty(t, check.Details["error"])
+}
@@ -0,0 +1,130 @@
+// Package lifecycle runs the long-lived parts of the process together and stops
+// them in order when it is asked to exit.
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/shanwije/wallet-app/pkg/logger"
+)
+
+// Component is one part of the process with a lifetime of its own
+type Component struct {
+	Name string
+	// Run does the component's work until its context is cancelled or Stop is called.
+	// It is nil for resources that only need releasing, such as a connection pool.
+	Run func(ctx context.Context) error
+	// Stop releases the component, waiting for in-flight work until ctx expires.
+	// Components without one are stopped by cancelling Run's context.
+	Stop func(ctx context.Context) error
+}
+
+// Closer is a component that only needs closing when the process exits
+func Closer(name string, c io.Closer) Component {
+	return Component{
+		Name: name,
+		Stop: func(context.Context) error { return c.Close() },
+	}
+}
+
+// Manager starts components in the order they were added and stops them in reverse,
+// so servers stop taking requests and drain before the workers and connections they
+// depend on go away
+type Manager struct {
+	// StopTimeout bounds the whole shutdown; components still busy after it are abandoned
+	StopTimeout time.Duration
+
+	components []Component
+}
+
+// New creates a Manager whose shutdown takes at most stopTimeout
+func New(stopTimeout time.Duration) *Manager {
+	return &Manager{StopTimeout: stopTimeout}
+}
+
+// Add registers a component; it is started after and stopped before those added earlier
+func (m *Manager) Add(c Component) {
+	m.components = append(m.components, c)
+}
+
+// running tracks a started component's Run
+type running struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Run starts every component and blocks until ctx is cancelled or one of them fails,
+// then stops them all. It returns the failure that ended the run, if any, joined
+// with the errors from stopping.
+func (m *Manager) Run(ctx context.Context) error {
+	log := logger.FromContext(ctx)
+
+	// Components get their own contexts so they can be stopped one at a time
+	// rather than all at once when ctx is cancelled
+	group, failed := errgroup.WithContext(context.WithoutCancel(ctx))
+	started := make([]*running, len(m.components))
+	for i, c := range m.components {
+		if c.Run == nil {
+			continue
+		}
+		runCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		r := &running{cancel: cancel, done: make(chan struct{})}
+		started[i] = r
+
+		group.Go(func() error {
+			defer close(r.done)
+			if err := c.Run(runCtx); err != nil && runCtx.Err() == nil {
+				return fmt.Errorf("%s: %w", c.Name, err)
+			}
+			return nil
+		})
+	}
+
+	select {
+	case <-ctx.Done():
+		log.Info("Shutting down")
+	case <-failed.Done():
+		log.Error("Component failed; shutting down", zap.Error(context.Cause(failed)))
+	}
+
+	stopCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), m.StopTimeout)
+	defer cancel()
+
+	var errs []error
+	abandoned := false
+	for i := len(m.components) - 1; i >= 0; i-- {
+		c, r := m.components[i], started[i]
+		if r != nil {
+			r.cancel()
+		}
+		if c.Stop != nil {
+			if err := c.Stop(stopCtx); err != nil {
+				errs = append(errs, fmt.Errorf("stopping %s: %w", c.Name, err))
+			}
+		}
+		if r != nil {
+			select {
+			case <-r.done:
+			case <-stopCtx.Done():
+				abandoned = true
+				errs = append(errs, fmt.Errorf("stopping %s: %w", c.Name, stopCtx.Err()))
+				continue
+			}
+		}
+		log.Info("Stopped", zap.String("component", c.Name))
+	}
+
+	// An abandoned component would keep Wait blocked; the failure is known anyway
+	runErr := context.Cause(failed)
+	if !abandoned {
+		runErr = group.Wait()
+	}
+	return errors.Join(append([]error{runErr}, errs...)...)
+}
@@ -0,0 +1,127 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recorder notes the order components stop in
+type recorder struct {
+	mu      sync.Mutex
+	stopped []string
+}
+
+func (r *recorder) record(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stopped = append(r.stopped, name)
+}
+
+// worker runs until its context is cancelled
+func (r *recorder) worker(name string) Component {
+	return Component{
+		Name: name,
+		Run: func(ctx context.Context) error {
+			<-ctx.Done()
+			r.record(name)
+			return nil
+		},
+	}
+}
+
+// server runs until Stop is called, like http.Server
+func (r *recorder) server(name string) Component {
+	stop := make(chan struct{})
+	return Component{
+		Name: name,
+		Run: func(context.Context) error {
+			<-stop
+			return errors.New("server closed")
+		},
+		Stop: func(context.Context) error {
+			close(stop)
+			r.record(name)
+			return nil
+		},
+	}
+}
+
+func (r *recorder) closer(name string) Component {
+	return Component{
+		Name: name,
+		Stop: func(context.Context) error {
+			r.record(name)
+			return nil
+		},
+	}
+}
+
+func TestManagerStopsComponentsInReverseOrder(t *testing.T) {
+	rec := &recorder{}
+	app := New(time.Second)
+	app.Add(rec.closer("database"))
+	app.Add(rec.worker("scheduler"))
+	app.Add(rec.worker("dispatcher"))
+	app.Add(rec.server("http"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- app.Run(ctx) }()
+
+	cancel()
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("manager did not stop")
+	}
+	assert.Equal(t, []string{"http", "dispatcher", "scheduler", "database"}, rec.stopped)
+}
+
+func TestManagerShutsDownWhenAComponentFails(t *testing.T) {
+	rec := &recorder{}
+	app := New(time.Second)
+	app.Add(rec.closer("database"))
+	app.Add(rec.worker("scheduler"))
+	app.Add(Component{
+		Name: "http",
+		Run:  func(context.Context) error { return errors.New("address already in use") },
+	})
+
+	err := app.Run(context.Background())
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "http: address already in use")
+	assert.Equal(t, []string{"scheduler", "database"}, rec.stopped)
+}
+
+func TestManagerAbandonsComponentsAfterStopTimeout(t *testing.T) {
+	rec := &recorder{}
+	release := make(chan struct{})
+	defer close(release)
+
+	app := New(50 * time.Millisecond)
+	app.Add(rec.closer("database"))
+	app.Add(Component{
+		Name: "stuck",
+		Run: func(context.Context) error {
+			<-release
+			return nil
+		},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := app.Run(ctx)
+
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Contains(t, err.Error(), "stopping stuck")
+	// Connections are still released so the process can exit
+	assert.Equal(t, []string{"database"}, rec.stopped)
+}
@@ -0,0 +1,95 @@
+package logger
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Level is the minimum level of a logger built by New. Operators change it at runtime,
+// for instance to debug an incident, optionally only for a while.
+type Level struct {
+	atomic  zap.AtomicLevel
+	initial zapcore.Level
+
+	mu     sync.Mutex
+	until  time.Time
+	revert *time.Timer
+	// changes counts the changes, so a timer knows whether the level changed after it
+	// was started
+	changes uint64
+}
+
+// LevelState is the current level, and when it goes back to the configured one if it
+// was changed only for a while
+type LevelState struct {
+	Level   string     `json:"level" example:"debug"`
+	Initial string     `json:"initial" example:"info"`
+	Until   *time.Time `json:"until,omitempty"`
+}
+
+func newLevel(atomic zap.AtomicLevel) *Level {
+	return &Level{atomic: atomic, initial: atomic.Level()}
+}
+
+// State returns the current level
+func (l *Level) State() LevelState {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	state := LevelState{Level: l.atomic.Level().String(), Initial: l.initial.String()}
+	if !l.until.IsZero() {
+		until := l.until
+		state.Until = &until
+	}
+	return state
+}
+
+// Set changes the level. With a positive duration the configured level comes back
+// once it has passed; otherwise the change lasts until the next Set or Reset.
+func (l *Level) Set(level zapcore.Level, duration time.Duration) LevelState {
+	l.mu.Lock()
+	l.stopRevert()
+	l.atomic.SetLevel(level)
+	if duration > 0 {
+		l.until = time.Now().Add(duration).UTC()
+		change := l.changes
+		l.revert = time.AfterFunc(duration, func() { l.expire(change) })
+	}
+	l.mu.Unlock()
+	return l.State()
+}
+
+// Reset goes back to the configured level
+func (l *Level) Reset() LevelState {
+	l.mu.Lock()
+	l.stopRevert()
+	l.atomic.SetLevel(l.initial)
+	l.mu.Unlock()
+	return l.State()
+}
+
+// expire goes back to the configured level, unless the level was changed again since
+// the timer was started
+func (l *Level) expire(change uint64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.changes != change {
+		return
+	}
+	l.revert = nil
+	l.until = time.Time{}
+	l.atomic.SetLevel(l.initial)
+}
+
+// stopRevert cancels a pending return to the configured level. l.mu must be held.
+func (l *Level) stopRevert() {
+	if l.revert != nil {
+		l.revert.Stop()
+		l.revert = nil
+	}
+	l.until = time.Time{}
+	l.changes++
+}
@@ -0,0 +1,46 @@
+package logger
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestLevelSetAndReset(t *testing.T) {
+	atomic := zap.NewAtomicLevelAt(zapcore.InfoLevel)
+	level := newLevel(atomic)
+
+	state := level.Set(zapcore.DebugLevel, 0)
+	assert.Equal(t, "debug", state.Level)
+	assert.Equal(t, "info", state.Initial)
+	assert.Nil(t, state.Until)
+	assert.True(t, atomic.Enabled(zapcore.DebugLevel))
+
+	state = level.Reset()
+	assert.Equal(t, "info", state.Level)
+	assert.False(t, atomic.Enabled(zapcore.DebugLevel))
+}
+
+func TestLevelGoesBackAfterTheDuration(t *testing.T) {
+	atomic := zap.NewAtomicLevelAt(zapcore.InfoLevel)
+	level := newLevel(atomic)
+
+	state := level.Set(zapcore.DebugLevel, 20*time.Millisecond)
+	assert.NotNil(t, state.Until)
+	assert.Eventually(t, func() bool { return level.State().Level == "info" }, time.Second, 5*time.Millisecond)
+	assert.Nil(t, level.State().Until)
+}
+
+func TestLevelChangeCancelsThePendingReturn(t *testing.T) {
+	atomic := zap.NewAtomicLevelAt(zapcore.InfoLevel)
+	level := newLevel(atomic)
+
+	level.Set(zapcore.DebugLevel, 10*time.Millisecond)
+	level.Set(zapcore.WarnLevel, 0)
+	time.Sleep(30 * time.Millisecond)
+
+	assert.Equal(t, "warn", level.State().Level)
+}
@@ -5,6 +5,8 @@ import (
 	"os"
 
 	"go.uber.org/zap"
+
+	"github.com/shanwije/wallet-app/pkg/requestid"
 )
 
 // ContextKey is a custom type for context keys to avoid collisions
@@ -14,13 +16,10 @@ const (
 	LoggerKey ContextKey = "logger"
 )
 
-var (
-	// Global logger instance
-	Log *zap.Logger
-)
-
-// Initialize sets up the global logger
-func Initialize(env string) error {
+// New builds the logger for the environment: JSON at info level in production,
+// readable console output at debug level everywhere else. The returned Level changes
+// the logger's level at runtime.
+func New(env string) (*zap.Logger, *Level, error) {
 	var config zap.Config
 
 	if env == "production" {
@@ -29,35 +28,33 @@ func Initialize(env string) error {
 		config = zap.NewDevelopmentConfig()
 	}
 
-	logger, err := config.Build()
+	log, err := config.Build()
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
+	return log, newLevel(config.Level), nil
+}
 
-	Log = logger
-	return nil
+// NewContext returns a copy of ctx carrying log, which FromContext returns from it and
+// every context derived from it
+func NewContext(ctx context.Context, log *zap.Logger) context.Context {
+	return context.WithValue(ctx, LoggerKey, log)
 }
 
-// FromContext extracts request-scoped logger from context
+// FromContext extracts request-scoped logger from context. A context no logger was
+// put into, as in unit tests, logs nothing.
 func FromContext(ctx context.Context) *zap.Logger {
 	if logger, ok := ctx.Value(LoggerKey).(*zap.Logger); ok {
 		return logger
 	}
-	return Log
+	return zap.NewNop()
 }
 
-// WithRequestID adds request ID to logger
+// WithRequestID adds request ID to logger, and stores it in the context so it is
+// passed on to outgoing calls and database queries
 func WithRequestID(ctx context.Context, requestID string) context.Context {
-	logger := Log.With(zap.String("request_id", requestID))
-	return context.WithValue(ctx, LoggerKey, logger)
-}
-
-// Close gracefully shuts down the logger
-func Close() error {
-	if Log != nil {
-		return Log.Sync()
-	}
-	return nil
+	logger := FromContext(ctx).With(zap.String("request_id", requestID))
+	return context.WithValue(requestid.With(ctx, requestID), LoggerKey, logger)
 }
 
 // GetEnvironment returns the current environment
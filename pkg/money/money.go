@@ -0,0 +1,207 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// Currency is an ISO 4217 currency code
+type Currency string
+
+// Supported currencies
+const (
+	USD Currency = "USD"
+	EUR Currency = "EUR"
+	GBP Currency = "GBP"
+	JPY Currency = "JPY"
+	LKR Currency = "LKR"
+	KWD Currency = "KWD"
+)
+
+// DefaultCurrency is used for wallets and requests that do not specify one
+const DefaultCurrency = USD
+
+// minorUnits holds the number of decimal places each currency allows
+var minorUnits = map[Currency]int32{
+	USD: 2,
+	EUR: 2,
+	GBP: 2,
+	JPY: 0,
+	LKR: 2,
+	KWD: 3,
+}
+
+var (
+	// ErrCurrencyMismatch is returned when combining amounts in different currencies
+	ErrCurrencyMismatch = errors.New("currency mismatch")
+	// ErrUnknownCurrency is returned for currency codes that are not supported
+	ErrUnknownCurrency = errors.New("unknown currency")
+)
+
+// ParseCurrency normalizes and validates a currency code
+func ParseCurrency(code string) (Currency, error) {
+	c := Currency(strings.ToUpper(strings.TrimSpace(code)))
+	if !c.IsValid() {
+		return "", fmt.Errorf("%w: %q", ErrUnknownCurrency, code)
+	}
+	return c, nil
+}
+
+// IsValid reports whether the currency is supported
+func (c Currency) IsValid() bool {
+	_, ok := minorUnits[c]
+	return ok
+}
+
+// MinorUnits returns the number of decimal places allowed for the currency
+func (c Currency) MinorUnits() int32 {
+	if units, ok := minorUnits[c]; ok {
+		return units
+	}
+	return minorUnits[DefaultCurrency]
+}
+
+func (c Currency) String() string {
+	return string(c)
+}
+
+// Money is an amount in a specific currency. Arithmetic between different
+// currencies fails with ErrCurrencyMismatch instead of silently mixing them.
+type Money struct {
+	amount   decimal.Decimal
+	currency Currency
+}
+
+// New creates a Money value
+func New(amount decimal.Decimal, currency Currency) Money {
+	return Money{amount: amount, currency: currency}
+}
+
+// Zero returns a zero amount in the given currency
+func Zero(currency Currency) Money {
+	return Money{amount: decimal.Zero, currency: currency}
+}
+
+// Amount returns the decimal amount
+func (m Money) Amount() decimal.Decimal {
+	return m.amount
+}
+
+// Currency returns the currency
+func (m Money) Currency() Currency {
+	return m.currency
+}
+
+// SameCurrency reports whether both values share a currency
+func (m Money) SameCurrency(other Money) bool {
+	return m.currency == other.currency
+}
+
+func (m Money) assertSameCurrency(other Money) error {
+	if !m.SameCurrency(other) {
+		return fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.currency, other.currency)
+	}
+	return nil
+}
+
+// Add returns m + other
+func (m Money) Add(other Money) (Money, error) {
+	if err := m.assertSameCurrency(other); err != nil {
+		return Money{}, err
+	}
+	return Money{amount: m.amount.Add(other.amount), currency: m.currency}, nil
+}
+
+// Sub returns m - other
+func (m Money) Sub(other Money) (Money, error) {
+	if err := m.assertSameCurrency(other); err != nil {
+		return Money{}, err
+	}
+	return Money{amount: m.amount.Sub(other.amount), currency: m.currency}, nil
+}
+
+// Neg returns -m
+func (m Money) Neg() Money {
+	return Money{amount: m.amount.Neg(), currency: m.currency}
+}
+
+// Cmp compares m and other, returning -1, 0 or +1
+func (m Money) Cmp(other Money) (int, error) {
+	if err := m.assertSameCurrency(other); err != nil {
+		return 0, err
+	}
+	return m.amount.Cmp(other.amount), nil
+}
+
+// Equal reports whether both the currency and amount match
+func (m Money) Equal(other Money) bool {
+	return m.SameCurrency(other) && m.amount.Equal(other.amount)
+}
+
+// IsZero reports whether the amount is zero
+func (m Money) IsZero() bool {
+	return m.amount.IsZero()
+}
+
+// IsPositive reports whether the amount is greater than zero
+func (m Money) IsPositive() bool {
+	return m.amount.IsPositive()
+}
+
+// IsNegative reports whether the amount is less than zero
+func (m Money) IsNegative() bool {
+	return m.amount.IsNegative()
+}
+
+// HasValidPrecision reports whether the amount has no more decimal places than the currency allows
+func (m Money) HasValidPrecision() bool {
+	return m.amount.Equal(m.amount.Truncate(m.currency.MinorUnits()))
+}
+
+// Round rounds the amount to the currency's minor units
+func (m Money) Round() Money {
+	return Money{amount: m.amount.Round(m.currency.MinorUnits()), currency: m.currency}
+}
+
+// String formats the amount with the currency's minor units, e.g. "10.50 USD"
+func (m Money) String() string {
+	return m.amount.StringFixed(m.currency.MinorUnits()) + " " + string(m.currency)
+}
+
+// moneyJSON is the wire representation; the amount is a string to avoid float rounding
+type moneyJSON struct {
+	Amount   decimal.Decimal `json:"amount"`
+	Currency Currency        `json:"currency"`
+}
+
+// MarshalJSON encodes Money as {"amount":"10.50","currency":"USD"}
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Amount   string   `json:"amount"`
+		Currency Currency `json:"currency"`
+	}{
+		Amount:   m.amount.StringFixed(m.currency.MinorUnits()),
+		Currency: m.currency,
+	})
+}
+
+// UnmarshalJSON accepts the amount as either a JSON string or number
+func (m *Money) UnmarshalJSON(data []byte) error {
+	var raw moneyJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	currency, err := ParseCurrency(string(raw.Currency))
+	if err != nil {
+		return err
+	}
+
+	m.amount = raw.Amount
+	m.currency = currency
+	return nil
+}
@@ -0,0 +1,85 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMoneyArithmetic(t *testing.T) {
+	a := New(decimal.RequireFromString("10.25"), USD)
+	b := New(decimal.RequireFromString("0.75"), USD)
+
+	sum, err := a.Add(b)
+	assert.NoError(t, err)
+	assert.True(t, sum.Equal(New(decimal.RequireFromString("11"), USD)))
+
+	diff, err := a.Sub(b)
+	assert.NoError(t, err)
+	assert.Equal(t, "9.50 USD", diff.String())
+
+	cmp, err := a.Cmp(b)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, cmp)
+}
+
+func TestMoneyCurrencyMismatch(t *testing.T) {
+	usd := New(decimal.NewFromInt(10), USD)
+	eur := New(decimal.NewFromInt(10), EUR)
+
+	_, err := usd.Add(eur)
+	assert.True(t, errors.Is(err, ErrCurrencyMismatch))
+
+	_, err = usd.Sub(eur)
+	assert.True(t, errors.Is(err, ErrCurrencyMismatch))
+
+	_, err = usd.Cmp(eur)
+	assert.True(t, errors.Is(err, ErrCurrencyMismatch))
+
+	assert.False(t, usd.Equal(eur))
+}
+
+func TestMoneyRoundUsesMinorUnits(t *testing.T) {
+	assert.Equal(t, "10.46 USD", New(decimal.RequireFromString("10.456"), USD).Round().String())
+	assert.Equal(t, "10 JPY", New(decimal.RequireFromString("10.4"), JPY).Round().String())
+	assert.Equal(t, "1.235 KWD", New(decimal.RequireFromString("1.2345"), KWD).Round().String())
+}
+
+func TestMoneyJSON(t *testing.T) {
+	m := New(decimal.RequireFromString("10.5"), USD)
+
+	data, err := json.Marshal(m)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"amount":"10.50","currency":"USD"}`, string(data))
+
+	var decoded Money
+	assert.NoError(t, json.Unmarshal([]byte(`{"amount":"10.50","currency":"usd"}`), &decoded))
+	assert.True(t, decoded.Equal(m))
+
+	assert.NoError(t, json.Unmarshal([]byte(`{"amount":3.25,"currency":"EUR"}`), &decoded))
+	assert.Equal(t, "3.25 EUR", decoded.String())
+
+	err = json.Unmarshal([]byte(`{"amount":"1","currency":"XYZ"}`), &decoded)
+	assert.True(t, errors.Is(err, ErrUnknownCurrency))
+}
+
+func TestParseCurrency(t *testing.T) {
+	c, err := ParseCurrency(" lkr ")
+	assert.NoError(t, err)
+	assert.Equal(t, LKR, c)
+	assert.Equal(t, int32(2), c.MinorUnits())
+
+	_, err = ParseCurrency("ABC")
+	assert.Error(t, err)
+}
+
+func TestMoneyHasValidPrecision(t *testing.T) {
+	assert.True(t, New(decimal.RequireFromString("10.99"), USD).HasValidPrecision())
+	assert.True(t, New(decimal.RequireFromString("10.9"), USD).HasValidPrecision())
+	assert.False(t, New(decimal.RequireFromString("10.999999"), USD).HasValidPrecision())
+	assert.False(t, New(decimal.RequireFromString("10.5"), JPY).HasValidPrecision())
+	assert.True(t, New(decimal.RequireFromString("1.125"), KWD).HasValidPrecision())
+}
@@ -0,0 +1,186 @@
+// Package pagination reads and writes the paging parameters shared by every list API,
+// so they all behave alike:
+//
+//	limit   page size, 50 when unset and cut to 200
+//	offset  rows to skip, for listings paged by offset
+//	sort    a column from the listing's whitelist, descending with a leading -
+//	cursor  the next_cursor of the previous page, for listings paged by keyset
+//
+// A cursor is opaque to clients. It holds the position of the last row of a page and
+// the sort it was listed in, so it is refused for a listing in another order.
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	// DefaultLimit is the page size when none is asked for
+	DefaultLimit = 50
+	// MaxLimit caps the rows returned by one page of a listing
+	MaxLimit = 200
+)
+
+var (
+	// ErrInvalidPage is returned for a limit or offset that is not a non-negative
+	// integer, or a cursor combined with an offset
+	ErrInvalidPage = errors.New("invalid page")
+	// ErrInvalidSort is returned for a sort by a column the listing does not allow
+	ErrInvalidSort = errors.New("invalid sort")
+	// ErrInvalidCursor is returned for a cursor that was not issued for a listing in
+	// the same order
+	ErrInvalidCursor = errors.New("invalid cursor")
+)
+
+// ParamError reports the query parameter that failed to parse, and why in words fit
+// for the client that sent it. It wraps ErrInvalidPage, ErrInvalidSort or
+// ErrInvalidCursor.
+type ParamError struct {
+	Param  string
+	Value  string
+	Reason string
+	Err    error
+}
+
+func (e *ParamError) Error() string {
+	return e.Reason
+}
+
+func (e *ParamError) Unwrap() error {
+	return e.Err
+}
+
+// ClampLimit bounds a requested page size to 1..MaxLimit, defaulting to DefaultLimit
+// when unset
+func ClampLimit(limit int) int {
+	switch {
+	case limit <= 0:
+		return DefaultLimit
+	case limit > MaxLimit:
+		return MaxLimit
+	default:
+		return limit
+	}
+}
+
+// Page is the page of a listing a request asked for
+type Page struct {
+	Limit  int
+	Offset int
+	// Cursor is the raw cursor, to be read with DecodeCursor once the sort is known
+	Cursor string
+}
+
+// Parse reads limit, offset and cursor from query. The limit is clamped; a cursor
+// cannot be combined with an offset, as it already says where the page starts.
+func Parse(query url.Values) (Page, error) {
+	var page Page
+	for param, target := range map[string]*int{"limit": &page.Limit, "offset": &page.Offset} {
+		value := query.Get(param)
+		if value == "" {
+			continue
+		}
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			return Page{}, &ParamError{Param: param, Value: value, Err: ErrInvalidPage,
+				Reason: "Pagination parameters must be non-negative integers"}
+		}
+		*target = parsed
+	}
+	page.Limit = ClampLimit(page.Limit)
+
+	page.Cursor = query.Get("cursor")
+	if page.Cursor != "" && page.Offset > 0 {
+		return Page{}, &ParamError{Param: "cursor", Value: page.Cursor, Err: ErrInvalidPage,
+			Reason: "cursor cannot be combined with offset"}
+	}
+	return page, nil
+}
+
+// Full reports whether n rows fill the page, so that there may be more after it
+func (p Page) Full(n int) bool {
+	return n > 0 && n == p.Limit
+}
+
+// Sort orders a listing by Field, ties broken by ID in the same direction
+type Sort struct {
+	Field      string
+	Descending bool
+}
+
+// String returns the sort as it is written in a query: the field, with a leading - when
+// descending
+func (s Sort) String() string {
+	if s.Descending {
+		return "-" + s.Field
+	}
+	return s.Field
+}
+
+// ParseSort reads a sort by one of fields, the listing's whitelist. An empty value
+// sorts by fallback, which is written the same way.
+func ParseSort(value, fallback string, fields ...string) (Sort, error) {
+	if value == "" {
+		value = fallback
+	}
+	sort := Sort{Field: strings.TrimPrefix(value, "-"), Descending: strings.HasPrefix(value, "-")}
+	if !slices.Contains(fields, sort.Field) {
+		return Sort{}, &ParamError{Param: "sort", Value: value, Err: ErrInvalidSort,
+			Reason: "sort must be " + strings.Join(fields, " or ") + ", with a leading - for descending"}
+	}
+	return sort, nil
+}
+
+// cursor is what an encoded cursor holds
+type cursor struct {
+	Sort     string          `json:"sort"`
+	Position json.RawMessage `json:"position"`
+}
+
+// EncodeCursor returns the cursor continuing a listing in sort order after position,
+// the last row of a page. position is encoded as JSON.
+func EncodeCursor(sort Sort, position any) string {
+	encoded, err := json.Marshal(position)
+	if err != nil {
+		panic(fmt.Sprintf("pagination: cursor position cannot be encoded: %v", err))
+	}
+	raw, _ := json.Marshal(cursor{Sort: sort.String(), Position: encoded})
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// DecodeCursor reads the position of a cursor issued for a listing in sort order into
+// position
+func DecodeCursor(encoded string, sort Sort, position any) error {
+	invalid := &ParamError{Param: "cursor", Value: encoded, Err: ErrInvalidCursor,
+		Reason: "cursor is not one returned for this sort"}
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return invalid
+	}
+	var decoded cursor
+	if err := json.Unmarshal(raw, &decoded); err != nil || decoded.Sort != sort.String() {
+		return invalid
+	}
+	if err := json.Unmarshal(decoded.Position, position); err != nil {
+		return invalid
+	}
+	return nil
+}
+
+// NextURL returns u with its cursor set to next and its offset dropped, the link to the
+// page after the one u asked for
+func NextURL(u *url.URL, next string) string {
+	query := u.Query()
+	query.Set("cursor", next)
+	query.Del("offset")
+	link := *u
+	link.RawQuery = query.Encode()
+	return link.RequestURI()
+}
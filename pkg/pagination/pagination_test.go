@@ -0,0 +1,79 @@
+package pagination
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClampLimit(t *testing.T) {
+	assert.Equal(t, DefaultLimit, ClampLimit(0))
+	assert.Equal(t, 20, ClampLimit(20))
+	assert.Equal(t, MaxLimit, ClampLimit(MaxLimit+1))
+}
+
+func TestParse(t *testing.T) {
+	page, err := Parse(url.Values{"limit": {"500"}, "offset": {"40"}})
+	require.NoError(t, err)
+	assert.Equal(t, Page{Limit: MaxLimit, Offset: 40}, page)
+	assert.True(t, page.Full(MaxLimit))
+	assert.False(t, page.Full(MaxLimit-1))
+
+	page, err = Parse(url.Values{"cursor": {"abc"}})
+	require.NoError(t, err)
+	assert.Equal(t, Page{Limit: DefaultLimit, Cursor: "abc"}, page)
+
+	for _, query := range []url.Values{
+		{"limit": {"-1"}},
+		{"offset": {"ten"}},
+		{"cursor": {"abc"}, "offset": {"10"}},
+	} {
+		_, err := Parse(query)
+		assert.ErrorIs(t, err, ErrInvalidPage, query.Encode())
+	}
+}
+
+func TestParseSort(t *testing.T) {
+	sort, err := ParseSort("", "-created_at", "created_at", "balance")
+	require.NoError(t, err)
+	assert.Equal(t, Sort{Field: "created_at", Descending: true}, sort)
+	assert.Equal(t, "-created_at", sort.String())
+
+	sort, err = ParseSort("balance", "-created_at", "created_at", "balance")
+	require.NoError(t, err)
+	assert.Equal(t, Sort{Field: "balance"}, sort)
+
+	_, err = ParseSort("-user_id", "created_at", "created_at", "balance")
+	assert.ErrorIs(t, err, ErrInvalidSort)
+	var paramErr *ParamError
+	require.ErrorAs(t, err, &paramErr)
+	assert.Equal(t, "sort", paramErr.Param)
+}
+
+func TestCursor(t *testing.T) {
+	type position struct {
+		ID        uuid.UUID `json:"id"`
+		CreatedAt time.Time `json:"created_at"`
+	}
+	last := position{ID: uuid.New(), CreatedAt: time.Date(2024, 7, 26, 9, 30, 0, 0, time.UTC)}
+	newestFirst := Sort{Field: "created_at", Descending: true}
+	encoded := EncodeCursor(newestFirst, last)
+
+	var decoded position
+	require.NoError(t, DecodeCursor(encoded, newestFirst, &decoded))
+	assert.Equal(t, last, decoded)
+
+	// A cursor only continues a listing in the order it was issued for
+	assert.ErrorIs(t, DecodeCursor(encoded, Sort{Field: "created_at"}, &decoded), ErrInvalidCursor)
+	assert.ErrorIs(t, DecodeCursor("not a cursor", newestFirst, &decoded), ErrInvalidCursor)
+}
+
+func TestNextURL(t *testing.T) {
+	u, err := url.Parse("/api/v1/admin/audit-log?actor_id=42&limit=10&offset=20")
+	require.NoError(t, err)
+	assert.Equal(t, "/api/v1/admin/audit-log?actor_id=42&cursor=abc&limit=10", NextURL(u, "abc"))
+}
@@ -0,0 +1,113 @@
+// Package ratelimit implements token bucket rate limiting, in memory for a single
+// instance or in Redis so that every instance shares the same buckets
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/shanwije/wallet-app/pkg/clock"
+)
+
+// Limit configures a token bucket: Burst requests may be made at once, and the
+// bucket refills at PerMinute tokens a minute
+type Limit struct {
+	PerMinute int
+	Burst     int
+}
+
+// refillInterval is how long one token takes to come back
+func (l Limit) refillInterval() time.Duration {
+	return time.Minute / time.Duration(l.PerMinute)
+}
+
+// Result is the outcome of taking a token from a bucket
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// RetryAfter is how long until a token is available; zero when Allowed
+	RetryAfter time.Duration
+}
+
+// Limiter takes one token from the bucket identified by key
+type Limiter interface {
+	Allow(ctx context.Context, key string) (Result, error)
+}
+
+// maxIdleBuckets bounds the memory limiter before it sweeps full buckets
+const maxIdleBuckets = 10000
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Memory is a Limiter that keeps buckets in process memory. Limits are not
+// shared between instances; use Redis when running more than one.
+type Memory struct {
+	limit   Limit
+	clock   clock.Clock
+	mutex   sync.Mutex
+	buckets map[string]*bucket
+}
+
+// NewMemory creates an in-memory Limiter; clk may be nil to use the system clock
+func NewMemory(limit Limit, clk clock.Clock) *Memory {
+	return &Memory{
+		limit:   limit,
+		clock:   clock.OrDefault(clk),
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// Allow refills the key's bucket for the time since it was last used and takes a token
+func (m *Memory) Allow(_ context.Context, key string) (Result, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	now := m.clock.Now()
+	if len(m.buckets) >= maxIdleBuckets {
+		m.sweep(now)
+	}
+
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(m.limit.Burst), last: now}
+		m.buckets[key] = b
+	}
+
+	b.tokens = m.refill(b, now)
+	b.last = now
+
+	result := Result{Limit: m.limit.Burst}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		missing := 1 - b.tokens
+		result.RetryAfter = time.Duration(math.Ceil(missing * float64(m.limit.refillInterval())))
+	}
+	result.Remaining = int(b.tokens)
+	return result, nil
+}
+
+// refill returns the tokens in b at now, capped at the burst size
+func (m *Memory) refill(b *bucket, now time.Time) float64 {
+	elapsed := now.Sub(b.last)
+	if elapsed <= 0 {
+		return b.tokens
+	}
+	return math.Min(float64(m.limit.Burst), b.tokens+float64(elapsed)/float64(m.limit.refillInterval()))
+}
+
+// sweep drops buckets that have refilled completely, since a new bucket is identical
+func (m *Memory) sweep(now time.Time) {
+	for key, b := range m.buckets {
+		if m.refill(b, now) >= float64(m.limit.Burst) {
+			delete(m.buckets, key)
+		}
+	}
+}
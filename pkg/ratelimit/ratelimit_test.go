@@ -0,0 +1,76 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shanwije/wallet-app/pkg/clock"
+)
+
+func TestMemoryLimiter(t *testing.T) {
+	clk := clock.NewFake(time.Date(2024, 6, 11, 9, 0, 0, 0, time.UTC))
+	limiter := NewMemory(Limit{PerMinute: 60, Burst: 2}, clk)
+	ctx := context.Background()
+
+	first, _ := limiter.Allow(ctx, "client-a")
+	second, _ := limiter.Allow(ctx, "client-a")
+	third, _ := limiter.Allow(ctx, "client-a")
+
+	assert.True(t, first.Allowed)
+	assert.Equal(t, 1, first.Remaining)
+	assert.True(t, second.Allowed)
+	assert.Equal(t, 0, second.Remaining)
+	assert.False(t, third.Allowed)
+	assert.Equal(t, time.Second, third.RetryAfter)
+
+	// Buckets are per key
+	other, _ := limiter.Allow(ctx, "client-b")
+	assert.True(t, other.Allowed)
+
+	// One token a second comes back
+	clk.Advance(time.Second)
+	refilled, _ := limiter.Allow(ctx, "client-a")
+	assert.True(t, refilled.Allowed)
+	assert.Equal(t, 0, refilled.Remaining)
+}
+
+func TestMemoryLimiterCapsAtBurst(t *testing.T) {
+	clk := clock.NewFake(time.Date(2024, 6, 11, 9, 0, 0, 0, time.UTC))
+	limiter := NewMemory(Limit{PerMinute: 60, Burst: 3}, clk)
+
+	limiter.Allow(context.Background(), "client-a")
+	clk.Advance(time.Hour)
+
+	result, _ := limiter.Allow(context.Background(), "client-a")
+	assert.Equal(t, 2, result.Remaining)
+}
+
+func TestRedisLimiter(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	limiter := NewRedis(client, Limit{PerMinute: 60, Burst: 2})
+	ctx := context.Background()
+
+	first, err := limiter.Allow(ctx, "client-a")
+	require.NoError(t, err)
+	second, err := limiter.Allow(ctx, "client-a")
+	require.NoError(t, err)
+	third, err := limiter.Allow(ctx, "client-a")
+	require.NoError(t, err)
+
+	assert.True(t, first.Allowed)
+	assert.Equal(t, 1, first.Remaining)
+	assert.True(t, second.Allowed)
+	assert.False(t, third.Allowed)
+	assert.Greater(t, third.RetryAfter, time.Duration(0))
+	assert.LessOrEqual(t, third.RetryAfter, time.Second)
+	assert.True(t, server.Exists(keyPrefix+"client-a"))
+}
@@ -0,0 +1,79 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// keyPrefix namespaces bucket keys in a shared Redis
+const keyPrefix = "ratelimit:"
+
+// tokenBucketScript refills and takes from a bucket stored as a hash of
+// {tokens, ts}. It reads the time from Redis so that instances with skewed clocks
+// agree, and expires the key once the bucket would be full again anyway.
+//
+// KEYS[1] bucket key; ARGV[1] refill interval per token in ms; ARGV[2] burst.
+// Returns {allowed, remaining, retry after in ms}.
+var tokenBucketScript = redis.NewScript(`
+local interval = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1]) or burst
+local ts = tonumber(state[2]) or now
+if now > ts then
+  tokens = math.min(burst, tokens + (now - ts) / interval)
+end
+
+local allowed = 0
+local retry = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+else
+  retry = math.ceil((1 - tokens) * interval)
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', now)
+redis.call('PEXPIRE', KEYS[1], math.ceil(burst * interval) + 1000)
+return {allowed, math.floor(tokens), retry}
+`)
+
+// Redis is a Limiter whose buckets live in Redis and are shared by every instance
+type Redis struct {
+	client *redis.Client
+	limit  Limit
+}
+
+// NewRedis creates a Limiter backed by client
+func NewRedis(client *redis.Client, limit Limit) *Redis {
+	return &Redis{client: client, limit: limit}
+}
+
+// Allow atomically refills the key's bucket and takes a token
+func (r *Redis) Allow(ctx context.Context, key string) (Result, error) {
+	interval := r.limit.refillInterval().Milliseconds()
+	if interval < 1 {
+		interval = 1
+	}
+
+	values, err := tokenBucketScript.Run(ctx, r.client, []string{keyPrefix + key}, interval, r.limit.Burst).Int64Slice()
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to take rate limit token: %w", err)
+	}
+	if len(values) != 3 {
+		return Result{}, fmt.Errorf("unexpected rate limit script reply: %v", values)
+	}
+
+	return Result{
+		Allowed:    values[0] == 1,
+		Limit:      r.limit.Burst,
+		Remaining:  int(values[1]),
+		RetryAfter: time.Duration(values[2]) * time.Millisecond,
+	}, nil
+}
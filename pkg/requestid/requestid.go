@@ -0,0 +1,63 @@
+// Package requestid carries the ID of the request being served, so it can be passed on
+// to the services and databases the request calls and correlated across their logs
+package requestid
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// Header is the HTTP header the request ID is read from and sent in
+const Header = "X-Request-ID"
+
+type contextKey string
+
+const requestIDKey contextKey = "request_id"
+
+// With stores the request ID in the context
+func With(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey, requestID)
+}
+
+// FromContext returns the request ID, if any
+func FromContext(ctx context.Context) (string, bool) {
+	requestID, ok := ctx.Value(requestIDKey).(string)
+	return requestID, ok && requestID != ""
+}
+
+// SQLComment returns a comment naming the request ID, in the sqlcommenter format, for
+// appending to a query. The ID is URL-encoded, so a client-supplied ID cannot close
+// the comment. It returns "" when the context has no request ID.
+func SQLComment(ctx context.Context) string {
+	requestID, ok := FromContext(ctx)
+	if !ok {
+		return ""
+	}
+	return "/*request_id='" + url.QueryEscape(requestID) + "'*/"
+}
+
+// Transport sends the request ID of each outgoing request's context in the
+// X-Request-ID header, unless the request already sets one. A nil base uses
+// http.DefaultTransport.
+func Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return roundTripper{base: base}
+}
+
+type roundTripper struct {
+	base http.RoundTripper
+}
+
+func (t roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	requestID, ok := FromContext(req.Context())
+	if !ok || req.Header.Get(Header) != "" {
+		return t.base.RoundTrip(req)
+	}
+	// A RoundTripper must not modify the request it is given
+	req = req.Clone(req.Context())
+	req.Header.Set(Header, requestID)
+	return t.base.RoundTrip(req)
+}
@@ -0,0 +1,53 @@
+package requestid
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLComment(t *testing.T) {
+	assert.Empty(t, SQLComment(context.Background()))
+	assert.Equal(t, "/*request_id='abc-123'*/", SQLComment(With(context.Background(), "abc-123")))
+	assert.Equal(t, "/*request_id='x%2A%2F+DROP+TABLE+wallets'*/", SQLComment(With(context.Background(), "x*/ DROP TABLE wallets")))
+}
+
+func TestTransport(t *testing.T) {
+	var received string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Get(Header)
+	}))
+	defer server.Close()
+	client := &http.Client{Transport: Transport(nil)}
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		header   string
+		expected string
+	}{
+		{name: "Sends the request ID", ctx: With(context.Background(), "abc-123"), expected: "abc-123"},
+		{name: "Keeps an explicit header", ctx: With(context.Background(), "abc-123"), header: "upstream", expected: "upstream"},
+		{name: "Without a request ID", ctx: context.Background(), expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequestWithContext(tt.ctx, http.MethodGet, server.URL, nil)
+			require.NoError(t, err)
+			if tt.header != "" {
+				req.Header.Set(Header, tt.header)
+			}
+
+			resp, err := client.Do(req)
+			require.NoError(t, err)
+			resp.Body.Close()
+
+			assert.Equal(t, tt.expected, received)
+		})
+	}
+}
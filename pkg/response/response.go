@@ -0,0 +1,82 @@
+// Package response writes HTTP responses the same way for every handler: successful
+// payloads as JSON and failures as RFC 7807 problem details, served as
+// application/problem+json.
+package response
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/shanwije/wallet-app/pkg/errors"
+)
+
+// Content types of the responses written here
+const (
+	ContentTypeJSON    = "application/json"
+	ContentTypeProblem = "application/problem+json"
+)
+
+// Problem is an RFC 7807 problem details document. Type is always about:blank, so
+// Title is the status's standard text and clients tell problems apart by Code, the
+// application's error code. Details carries the fields or values the problem is about.
+type Problem struct {
+	Type     string            `json:"type" example:"about:blank"`
+	Title    string            `json:"title" example:"Not Found"`
+	Status   int               `json:"status" example:"404"`
+	Detail   string            `json:"detail,omitempty" example:"Wallet not found"`
+	Instance string            `json:"instance,omitempty"`
+	Code     string            `json:"code,omitempty" example:"WALLET_NOT_FOUND"`
+	Details  map[string]string `json:"details,omitempty"`
+}
+
+// JSON writes payload as JSON with the given status
+func JSON(w http.ResponseWriter, status int, payload any) {
+	w.Header().Set("Content-Type", ContentTypeJSON)
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(payload)
+}
+
+// OK writes payload as JSON with 200 OK
+func OK(w http.ResponseWriter, payload any) {
+	JSON(w, http.StatusOK, payload)
+}
+
+// Created writes payload as JSON with 201 Created, with a Location header when
+// location is not empty
+func Created(w http.ResponseWriter, location string, payload any) {
+	if location != "" {
+		w.Header().Set("Location", location)
+	}
+	JSON(w, http.StatusCreated, payload)
+}
+
+// Error writes appErr as a problem
+func Error(w http.ResponseWriter, appErr *errors.AppError) {
+	WriteProblem(w, Problem{
+		Status:  appErr.HTTPStatus,
+		Detail:  appErr.Message,
+		Code:    appErr.Code,
+		Details: appErr.Details,
+	})
+}
+
+// ErrorMessage writes a problem with the given status and detail, for failures that
+// have no error code of their own
+func ErrorMessage(w http.ResponseWriter, status int, detail string) {
+	WriteProblem(w, Problem{Status: status, Detail: detail})
+}
+
+// WriteProblem writes problem with its status, filling in the type and title it
+// leaves empty
+func WriteProblem(w http.ResponseWriter, problem Problem) {
+	if problem.Type == "" {
+		problem.Type = "about:blank"
+	}
+	if problem.Title == "" {
+		problem.Title = http.StatusText(problem.Status)
+	}
+
+	w.Header().Set("Content-Type", ContentTypeProblem)
+	w.WriteHeader(problem.Status)
+	json.NewEncoder(w).Encode(problem)
+}
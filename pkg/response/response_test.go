@@ -0,0 +1,48 @@
+package response
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shanwije/wallet-app/pkg/errors"
+)
+
+func TestErrorWritesAProblem(t *testing.T) {
+	rr := httptest.NewRecorder()
+	Error(rr, errors.WalletNotFound("wallet-1"))
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.Equal(t, ContentTypeProblem, rr.Header().Get("Content-Type"))
+	var problem map[string]any
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &problem))
+	assert.Equal(t, map[string]any{
+		"type":    "about:blank",
+		"title":   "Not Found",
+		"status":  float64(404),
+		"detail":  "Wallet not found",
+		"code":    errors.ErrWalletNotFound,
+		"details": map[string]any{"wallet_id": "wallet-1"},
+	}, problem)
+}
+
+func TestErrorMessageHasNoCode(t *testing.T) {
+	rr := httptest.NewRecorder()
+	ErrorMessage(rr, http.StatusBadRequest, "Invalid wallet ID")
+
+	assert.JSONEq(t, `{"type":"about:blank","title":"Bad Request","status":400,"detail":"Invalid wallet ID"}`, rr.Body.String())
+}
+
+func TestCreatedSetsTheLocation(t *testing.T) {
+	rr := httptest.NewRecorder()
+	Created(rr, "/api/v2/transfers/1", map[string]string{"id": "1"})
+
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	assert.Equal(t, ContentTypeJSON, rr.Header().Get("Content-Type"))
+	assert.Equal(t, "/api/v2/transfers/1", rr.Header().Get("Location"))
+	assert.JSONEq(t, `{"id":"1"}`, rr.Body.String())
+}
@@ -0,0 +1,79 @@
+// Package tlsconfig builds the TLS settings the HTTP and gRPC servers are served with
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// Config chooses where the server certificate comes from: a certificate and key on
+// disk, or Let's Encrypt for AutocertDomains. Leaving both unset serves plain text.
+type Config struct {
+	CertFile string
+	KeyFile  string
+	// AutocertDomains are the hosts certificates are requested for from Let's Encrypt,
+	// using the TLS-ALPN challenge, so the server must be reachable on port 443
+	AutocertDomains []string
+	// AutocertCacheDir keeps issued certificates across restarts
+	AutocertCacheDir string
+	// ClientCAFile requires every client to present a certificate signed by one of
+	// these CAs (mutual TLS); it needs CertFile and KeyFile
+	ClientCAFile string
+}
+
+// Enabled reports whether a certificate source is configured
+func (c Config) Enabled() bool {
+	return c.CertFile != "" || len(c.AutocertDomains) > 0
+}
+
+// New returns the server TLS settings for c, or nil when TLS is not enabled. HTTP/2
+// is offered through ALPN.
+func New(c Config) (*tls.Config, error) {
+	if !c.Enabled() {
+		return nil, nil
+	}
+
+	if len(c.AutocertDomains) > 0 {
+		if c.ClientCAFile != "" {
+			return nil, errors.New("client certificates cannot be required with Let's Encrypt certificates")
+		}
+		manager := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(c.AutocertDomains...),
+			Cache:      autocert.DirCache(c.AutocertCacheDir),
+		}
+		config := manager.TLSConfig()
+		config.MinVersion = tls.VersionTLS12
+		return config, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+		NextProtos:   []string{"h2", "http/1.1"},
+	}
+
+	if c.ClientCAFile != "" {
+		pem, err := os.ReadFile(c.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client CA: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in client CA file %s", c.ClientCAFile)
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return config, nil
+}
@@ -0,0 +1,84 @@
+package tlsconfig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeSelfSigned writes a self-signed certificate and its key to dir
+func writeSelfSigned(t *testing.T, dir string) (certFile, keyFile string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "wallet-app"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile, keyFile = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+	return certFile, keyFile
+}
+
+func TestNewDisabled(t *testing.T) {
+	config, err := New(Config{})
+
+	require.NoError(t, err)
+	assert.Nil(t, config)
+}
+
+func TestNewFromFiles(t *testing.T) {
+	certFile, keyFile := writeSelfSigned(t, t.TempDir())
+
+	config, err := New(Config{CertFile: certFile, KeyFile: keyFile})
+
+	require.NoError(t, err)
+	assert.Len(t, config.Certificates, 1)
+	assert.Contains(t, config.NextProtos, "h2")
+	assert.Equal(t, tls.NoClientCert, config.ClientAuth)
+}
+
+func TestNewWithClientCA(t *testing.T) {
+	certFile, keyFile := writeSelfSigned(t, t.TempDir())
+
+	config, err := New(Config{CertFile: certFile, KeyFile: keyFile, ClientCAFile: certFile})
+
+	require.NoError(t, err)
+	assert.Equal(t, tls.RequireAndVerifyClientCert, config.ClientAuth)
+	assert.NotNil(t, config.ClientCAs)
+
+	_, err = New(Config{CertFile: certFile, KeyFile: keyFile, ClientCAFile: keyFile})
+	assert.Error(t, err)
+}
+
+func TestNewWithAutocert(t *testing.T) {
+	config, err := New(Config{AutocertDomains: []string{"wallet.example.com"}, AutocertCacheDir: t.TempDir()})
+
+	require.NoError(t, err)
+	assert.NotNil(t, config.GetCertificate)
+	assert.Contains(t, config.NextProtos, "acme-tls/1")
+
+	_, err = New(Config{AutocertDomains: []string{"wallet.example.com"}, ClientCAFile: "ca.pem"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,31 @@
+package webhooksig
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerify(t *testing.T) {
+	signedAt := time.Date(2024, 7, 24, 12, 0, 0, 0, time.UTC)
+	payload := []byte(`{"id":"evt_1"}`)
+	header := Sign("whsec_current", payload, signedAt)
+
+	assert.NoError(t, Verify("whsec_current", header, payload, signedAt.Add(time.Minute), 5*time.Minute))
+
+	// While the secret is rotated, deliveries carry a signature per secret
+	rotated := header + ",v1=" + strings.Split(Sign("whsec_old", payload, signedAt), "v1=")[1]
+	assert.NoError(t, Verify("whsec_old", rotated, payload, signedAt, 5*time.Minute))
+
+	assert.ErrorIs(t, Verify("whsec_current", header, []byte(`{"id":"evt_2"}`), signedAt, 5*time.Minute), ErrInvalidSignature)
+	assert.ErrorIs(t, Verify("whsec_other", header, payload, signedAt, 5*time.Minute), ErrInvalidSignature)
+	for _, malformed := range []string{"", "v1=abc", "t=1720000000", "t=now," + strings.Split(header, ",")[1]} {
+		assert.ErrorIs(t, Verify("whsec_current", malformed, payload, signedAt, 5*time.Minute), ErrInvalidSignature, malformed)
+	}
+
+	// A replayed delivery is refused once it is older than the tolerance
+	assert.ErrorIs(t, Verify("whsec_current", header, payload, signedAt.Add(6*time.Minute), 5*time.Minute), ErrExpired)
+	assert.ErrorIs(t, Verify("whsec_current", header, payload, signedAt.Add(-6*time.Minute), 5*time.Minute), ErrExpired)
+}
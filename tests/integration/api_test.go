@@ -4,16 +4,21 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
 	"testing"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/shanwije/wallet-app/internal/models"
 )
 
 // getTestURL returns the base URL for integration tests
 func getTestURL() string {
+	if inProcessURL != "" {
+		return inProcessURL
+	}
 	port := os.Getenv("APP_PORT")
 	if port == "" {
 		port = "8082" // Default port
@@ -21,6 +26,60 @@ func getTestURL() string {
 	return fmt.Sprintf("http://localhost:%s", port)
 }
 
+// registerUser creates a user with wallet through the auth API and returns it
+// together with an access token for the user's wallet operations
+func registerUser(t *testing.T, baseURL, name string) (models.UserWithWallet, string) {
+	t.Helper()
+
+	payload := map[string]string{
+		"name":     name,
+		"username": "it-" + uuid.New().String(),
+		"password": "integration-test-password",
+	}
+	payloadJSON, _ := json.Marshal(payload)
+
+	resp, err := http.Post(baseURL+"/api/v1/auth/register", "application/json", bytes.NewBuffer(payloadJSON))
+	if err != nil {
+		t.Fatalf("Failed to register user: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d", http.StatusCreated, resp.StatusCode)
+	}
+
+	var registered struct {
+		AccessToken string                `json:"access_token"`
+		User        models.UserWithWallet `json:"user"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&registered); err != nil {
+		t.Fatalf("Failed to decode register response: %v", err)
+	}
+
+	return registered.User, registered.AccessToken
+}
+
+// postWithToken sends an authenticated JSON POST
+func postWithToken(url, token string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodPost, url, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	return http.DefaultClient.Do(req)
+}
+
+// getWithToken sends an authenticated GET
+func getWithToken(url, token string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return http.DefaultClient.Do(req)
+}
+
 // TestSwaggerEndpoint tests that swagger documentation is accessible
 func TestSwaggerEndpoint(t *testing.T) {
 	// Test the swagger endpoint
@@ -58,27 +117,8 @@ func TestWalletTransactionHistory(t *testing.T) {
 
 	baseURL := getTestURL()
 
-	// 1. Create a user
-	userPayload := map[string]string{
-		"name": "Transaction Test User",
-	}
-	userJSON, _ := json.Marshal(userPayload)
-
-	resp, err := http.Post(baseURL+"/api/v1/users", "application/json", bytes.NewBuffer(userJSON))
-	if err != nil {
-		t.Fatalf("Failed to create user: %v", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusCreated {
-		t.Fatalf("Expected status %d, got %d", http.StatusCreated, resp.StatusCode)
-	}
-
-	var userWithWallet models.UserWithWallet
-	err = json.NewDecoder(resp.Body).Decode(&userWithWallet)
-	if err != nil {
-		t.Fatalf("Failed to decode user response: %v", err)
-	}
+	// 1. Register a user
+	userWithWallet, token := registerUser(t, baseURL, "Transaction Test User")
 
 	walletID := userWithWallet.Wallet.ID.String()
 
@@ -88,7 +128,7 @@ func TestWalletTransactionHistory(t *testing.T) {
 	}
 	depositJSON, _ := json.Marshal(depositPayload)
 
-	resp, err = http.Post(fmt.Sprintf("%s/api/v1/wallets/%s/deposit", baseURL, walletID), "application/json", bytes.NewBuffer(depositJSON))
+	resp, err := postWithToken(fmt.Sprintf("%s/api/v1/wallets/%s/deposit", baseURL, walletID), token, bytes.NewBuffer(depositJSON))
 	if err != nil {
 		t.Fatalf("Failed to make deposit: %v", err)
 	}
@@ -104,7 +144,7 @@ func TestWalletTransactionHistory(t *testing.T) {
 	}
 	withdrawJSON, _ := json.Marshal(withdrawPayload)
 
-	resp, err = http.Post(fmt.Sprintf("%s/api/v1/wallets/%s/withdraw", baseURL, walletID), "application/json", bytes.NewBuffer(withdrawJSON))
+	resp, err = postWithToken(fmt.Sprintf("%s/api/v1/wallets/%s/withdraw", baseURL, walletID), token, bytes.NewBuffer(withdrawJSON))
 	if err != nil {
 		t.Fatalf("Failed to make withdrawal: %v", err)
 	}
@@ -118,7 +158,7 @@ func TestWalletTransactionHistory(t *testing.T) {
 	time.Sleep(100 * time.Millisecond)
 
 	// 5. Get transaction history
-	resp, err = http.Get(fmt.Sprintf("%s/api/v1/wallets/%s/transactions", baseURL, walletID))
+	resp, err = getWithToken(fmt.Sprintf("%s/api/v1/wallets/%s/transactions", baseURL, walletID), token)
 	if err != nil {
 		t.Fatalf("Failed to get transaction history: %v", err)
 	}
@@ -237,45 +277,18 @@ func TestWalletTransfer(t *testing.T) {
 
 	baseURL := getTestURL()
 
-	// Create sender user
-	senderPayload := map[string]string{"name": "Transfer Sender"}
-	senderJSON, _ := json.Marshal(senderPayload)
-
-	resp, err := http.Post(baseURL+"/api/v1/users", "application/json", bytes.NewBuffer(senderJSON))
-	if err != nil {
-		t.Fatalf("Failed to create sender user: %v", err)
-	}
-	defer resp.Body.Close()
-
-	var senderUser models.UserWithWallet
-	err = json.NewDecoder(resp.Body).Decode(&senderUser)
-	if err != nil {
-		t.Fatalf("Failed to decode sender response: %v", err)
-	}
+	// Register sender and receiver
+	senderUser, senderToken := registerUser(t, baseURL, "Transfer Sender")
 	senderWalletID := senderUser.Wallet.ID.String()
 
-	// Create receiver user
-	receiverPayload := map[string]string{"name": "Transfer Receiver"}
-	receiverJSON, _ := json.Marshal(receiverPayload)
-
-	resp, err = http.Post(baseURL+"/api/v1/users", "application/json", bytes.NewBuffer(receiverJSON))
-	if err != nil {
-		t.Fatalf("Failed to create receiver user: %v", err)
-	}
-	defer resp.Body.Close()
-
-	var receiverUser models.UserWithWallet
-	err = json.NewDecoder(resp.Body).Decode(&receiverUser)
-	if err != nil {
-		t.Fatalf("Failed to decode receiver response: %v", err)
-	}
+	receiverUser, receiverToken := registerUser(t, baseURL, "Transfer Receiver")
 	receiverWalletID := receiverUser.Wallet.ID.String()
 
 	// Deposit money to sender wallet
 	depositPayload := map[string]float64{"amount": 200.00}
 	depositJSON, _ := json.Marshal(depositPayload)
 
-	resp, err = http.Post(fmt.Sprintf("%s/api/v1/wallets/%s/deposit", baseURL, senderWalletID), "application/json", bytes.NewBuffer(depositJSON))
+	resp, err := postWithToken(fmt.Sprintf("%s/api/v1/wallets/%s/deposit", baseURL, senderWalletID), senderToken, bytes.NewBuffer(depositJSON))
 	if err != nil {
 		t.Fatalf("Failed to make deposit: %v", err)
 	}
@@ -288,7 +301,7 @@ func TestWalletTransfer(t *testing.T) {
 	}
 	transferJSON, _ := json.Marshal(transferPayload)
 
-	resp, err = http.Post(fmt.Sprintf("%s/api/v1/wallets/%s/transfer", baseURL, senderWalletID), "application/json", bytes.NewBuffer(transferJSON))
+	resp, err = postWithToken(fmt.Sprintf("%s/api/v1/wallets/%s/transfer", baseURL, senderWalletID), senderToken, bytes.NewBuffer(transferJSON))
 	if err != nil {
 		t.Fatalf("Failed to make transfer: %v", err)
 	}
@@ -299,7 +312,7 @@ func TestWalletTransfer(t *testing.T) {
 	}
 
 	// Verify sender balance (should be 149.25)
-	resp, err = http.Get(fmt.Sprintf("%s/api/v1/wallets/%s/balance", baseURL, senderWalletID))
+	resp, err = getWithToken(fmt.Sprintf("%s/api/v1/wallets/%s/balance", baseURL, senderWalletID), senderToken)
 	if err != nil {
 		t.Fatalf("Failed to get sender balance: %v", err)
 	}
@@ -317,7 +330,7 @@ func TestWalletTransfer(t *testing.T) {
 	}
 
 	// Verify receiver balance (should be 50.75)
-	resp, err = http.Get(fmt.Sprintf("%s/api/v1/wallets/%s/balance", baseURL, receiverWalletID))
+	resp, err = getWithToken(fmt.Sprintf("%s/api/v1/wallets/%s/balance", baseURL, receiverWalletID), receiverToken)
 	if err != nil {
 		t.Fatalf("Failed to get receiver balance: %v", err)
 	}
@@ -343,28 +356,15 @@ func TestErrorScenarios(t *testing.T) {
 
 	baseURL := getTestURL()
 
-	// Create a user with minimal balance
-	userPayload := map[string]string{"name": "Error Test User"}
-	userJSON, _ := json.Marshal(userPayload)
-
-	resp, err := http.Post(baseURL+"/api/v1/users", "application/json", bytes.NewBuffer(userJSON))
-	if err != nil {
-		t.Fatalf("Failed to create user: %v", err)
-	}
-	defer resp.Body.Close()
-
-	var user models.UserWithWallet
-	err = json.NewDecoder(resp.Body).Decode(&user)
-	if err != nil {
-		t.Fatalf("Failed to decode user response: %v", err)
-	}
+	// Register a user with minimal balance
+	user, token := registerUser(t, baseURL, "Error Test User")
 	walletID := user.Wallet.ID.String()
 
 	// Test insufficient funds withdrawal
 	withdrawPayload := map[string]float64{"amount": 100.00} // More than available balance (0)
 	withdrawJSON, _ := json.Marshal(withdrawPayload)
 
-	resp, err = http.Post(fmt.Sprintf("%s/api/v1/wallets/%s/withdraw", baseURL, walletID), "application/json", bytes.NewBuffer(withdrawJSON))
+	resp, err := postWithToken(fmt.Sprintf("%s/api/v1/wallets/%s/withdraw", baseURL, walletID), token, bytes.NewBuffer(withdrawJSON))
 	if err != nil {
 		t.Fatalf("Failed to make withdrawal request: %v", err)
 	}
@@ -378,7 +378,7 @@ func TestErrorScenarios(t *testing.T) {
 	negativeDepositPayload := map[string]float64{"amount": -50.00}
 	negativeDepositJSON, _ := json.Marshal(negativeDepositPayload)
 
-	resp, err = http.Post(fmt.Sprintf("%s/api/v1/wallets/%s/deposit", baseURL, walletID), "application/json", bytes.NewBuffer(negativeDepositJSON))
+	resp, err = postWithToken(fmt.Sprintf("%s/api/v1/wallets/%s/deposit", baseURL, walletID), token, bytes.NewBuffer(negativeDepositJSON))
 	if err != nil {
 		t.Fatalf("Failed to make negative deposit request: %v", err)
 	}
@@ -390,7 +390,7 @@ func TestErrorScenarios(t *testing.T) {
 
 	// Test invalid wallet ID
 	invalidWalletID := "invalid-uuid"
-	resp, err = http.Get(fmt.Sprintf("%s/api/v1/wallets/%s/balance", baseURL, invalidWalletID))
+	resp, err = getWithToken(fmt.Sprintf("%s/api/v1/wallets/%s/balance", baseURL, invalidWalletID), token)
 	if err != nil {
 		t.Fatalf("Failed to make invalid wallet request: %v", err)
 	}
@@ -411,21 +411,8 @@ func TestIdempotencyWithWalletOperations(t *testing.T) {
 
 	baseURL := getTestURL()
 
-	// Create a user
-	userPayload := map[string]string{"name": "Idempotency Wallet Test User"}
-	userJSON, _ := json.Marshal(userPayload)
-
-	resp, err := http.Post(baseURL+"/api/v1/users", "application/json", bytes.NewBuffer(userJSON))
-	if err != nil {
-		t.Fatalf("Failed to create user: %v", err)
-	}
-	defer resp.Body.Close()
-
-	var user models.UserWithWallet
-	err = json.NewDecoder(resp.Body).Decode(&user)
-	if err != nil {
-		t.Fatalf("Failed to decode user response: %v", err)
-	}
+	// Register a user
+	user, token := registerUser(t, baseURL, "Idempotency Wallet Test User")
 	walletID := user.Wallet.ID.String()
 
 	// Test idempotent deposit
@@ -437,6 +424,7 @@ func TestIdempotencyWithWalletOperations(t *testing.T) {
 	// First deposit with idempotency key
 	req1, _ := http.NewRequest("POST", fmt.Sprintf("%s/api/v1/wallets/%s/deposit", baseURL, walletID), bytes.NewBuffer(depositJSON))
 	req1.Header.Set("Content-Type", "application/json")
+	req1.Header.Set("Authorization", "Bearer "+token)
 	req1.Header.Set("Idempotency-Key", "deposit-test-key-456")
 
 	resp1, err := client.Do(req1)
@@ -453,7 +441,7 @@ func TestIdempotencyWithWalletOperations(t *testing.T) {
 	time.Sleep(200 * time.Millisecond)
 
 	// Check balance after first deposit
-	resp, err = http.Get(fmt.Sprintf("%s/api/v1/wallets/%s/balance", baseURL, walletID))
+	resp, err := getWithToken(fmt.Sprintf("%s/api/v1/wallets/%s/balance", baseURL, walletID), token)
 	if err != nil {
 		t.Fatalf("Failed to get balance: %v", err)
 	}
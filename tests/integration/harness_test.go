@@ -0,0 +1,138 @@
+package integration
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"go.uber.org/zap"
+
+	"github.com/shanwije/wallet-app/db/migrations"
+	_ "github.com/shanwije/wallet-app/docs"
+	"github.com/shanwije/wallet-app/internal/api"
+	"github.com/shanwije/wallet-app/internal/config"
+	"github.com/shanwije/wallet-app/internal/fees"
+	"github.com/shanwije/wallet-app/internal/service"
+	"github.com/shanwije/wallet-app/pkg/db"
+	"github.com/shanwije/wallet-app/pkg/featureflag"
+)
+
+// inProcessURL is where the in-process server listens, when the tests started one
+var inProcessURL string
+
+// TestMain runs the tests against a server it starts itself when
+// INTEGRATION_IN_PROCESS is true, instead of one already running at APP_PORT. The
+// server is the real router on a freshly migrated database, PostgreSQL at
+// POSTGRES_TEST_DSN or else a temporary SQLite file, with an in-memory Redis.
+func TestMain(m *testing.M) {
+	if os.Getenv("INTEGRATION_IN_PROCESS") != "true" {
+		os.Exit(m.Run())
+	}
+
+	stop, err := startInProcess()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to start in-process server:", err)
+		os.Exit(1)
+	}
+	code := m.Run()
+	stop()
+	os.Exit(code)
+}
+
+// startInProcess starts the server and points getTestURL at it, returning what stops it
+func startInProcess() (func(), error) {
+	ctx := context.Background()
+
+	dir, err := os.MkdirTemp("", "wallet-integration")
+	if err != nil {
+		return nil, err
+	}
+	cleanup := []func(){func() { os.RemoveAll(dir) }}
+	stop := func() {
+		for i := len(cleanup) - 1; i >= 0; i-- {
+			cleanup[i]()
+		}
+	}
+
+	redisServer, err := miniredis.Run()
+	if err != nil {
+		stop()
+		return nil, fmt.Errorf("failed to start Redis: %w", err)
+	}
+	cleanup = append(cleanup, redisServer.Close)
+
+	env := map[string]string{
+		"DB_DRIVER":  db.DriverSQLite,
+		"DB_NAME":    filepath.Join(dir, "wallet.db"),
+		"JWT_SECRET": "integration-test-secret-not-for-production",
+		"REDIS_URL":  "redis://" + redisServer.Addr(),
+	}
+	dsn := os.Getenv("POSTGRES_TEST_DSN")
+	if dsn != "" {
+		env["DB_DRIVER"] = db.DriverPostgres
+	}
+	for key, value := range env {
+		os.Setenv(key, value)
+	}
+	cfg, err := config.Load(ctx, nil)
+	if err != nil {
+		stop()
+		return nil, err
+	}
+
+	var conn *db.DB
+	if dsn != "" {
+		conn, err = db.NewPostgresDSN(dsn, db.PoolConfig{})
+	} else {
+		conn, err = db.New(db.Config{Driver: db.DriverSQLite, Name: cfg.DBName})
+	}
+	if err != nil {
+		stop()
+		return nil, err
+	}
+	cleanup = append(cleanup, func() { conn.Close() })
+
+	fsys, err := migrations.ForDriver(cfg.DBDriver)
+	if err != nil {
+		stop()
+		return nil, err
+	}
+	migrator, err := db.NewMigrator(conn.DB, cfg.DBDriver, fsys)
+	if err != nil {
+		stop()
+		return nil, err
+	}
+	cleanup = append(cleanup, func() { migrator.Close() })
+	if _, err := migrator.Up(ctx); err != nil {
+		stop()
+		return nil, fmt.Errorf("failed to migrate: %w", err)
+	}
+
+	redisClient, err := db.NewRedis(cfg.RedisURL)
+	if err != nil {
+		stop()
+		return nil, err
+	}
+	cleanup = append(cleanup, func() { redisClient.Close() })
+
+	flagDefaults, err := featureflag.ParseDefaults(service.DefaultFeatureFlags, cfg.FeatureFlags)
+	if err != nil {
+		stop()
+		return nil, err
+	}
+	feeSchedule, err := fees.ParseSchedule(cfg.Fees)
+	if err != nil {
+		stop()
+		return nil, err
+	}
+
+	services := api.NewServices(cfg, conn, redisClient, nil, nil, nil, nil, feeSchedule, flagDefaults)
+	server := httptest.NewServer(api.NewRouter(cfg, services, zap.NewNop()))
+	cleanup = append(cleanup, server.Close)
+	inProcessURL = server.URL
+	return stop, nil
+}
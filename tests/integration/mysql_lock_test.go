@@ -0,0 +1,67 @@
+package integration
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/shanwije/wallet-app/internal/models"
+	"github.com/shanwije/wallet-app/internal/repository/mysql"
+)
+
+// TestMySQLWalletRowLock verifies that GetWalletByIDWithTx takes an exclusive
+// row lock on MySQL, i.e. a second locking read blocks until the first
+// transaction ends. Requires MYSQL_TEST_DSN pointing at a migrated database.
+func TestMySQLWalletRowLock(t *testing.T) {
+	dsn := os.Getenv("MYSQL_TEST_DSN")
+	if dsn == "" {
+		t.Skip("MYSQL_TEST_DSN not set")
+	}
+
+	db, err := sqlx.Connect("mysql", dsn)
+	if err != nil {
+		t.Fatalf("Failed to connect to MySQL: %v", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	userRepo := mysql.NewUserRepository(db)
+	walletRepo := mysql.NewWalletRepository(db)
+
+	user, err := userRepo.CreateUser(ctx, "Lock Test User", models.Contact{})
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	wallet, err := walletRepo.CreateWallet(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("Failed to create wallet: %v", err)
+	}
+
+	holder, err := walletRepo.BeginTx(ctx)
+	if err != nil {
+		t.Fatalf("Failed to begin first transaction: %v", err)
+	}
+	defer holder.Rollback()
+
+	if _, err := walletRepo.GetWalletByIDWithTx(ctx, holder, wallet.ID); err != nil {
+		t.Fatalf("Failed to lock wallet: %v", err)
+	}
+
+	// A competing locking read must not complete while the lock is held
+	waitCtx, cancel := context.WithTimeout(ctx, 500*time.Millisecond)
+	defer cancel()
+
+	contender, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatalf("Failed to begin second transaction: %v", err)
+	}
+	defer contender.Rollback()
+
+	if _, err := walletRepo.GetWalletByIDWithTx(waitCtx, contender, wallet.ID); err == nil {
+		t.Fatal("Expected second FOR UPDATE to block while the row is locked")
+	}
+}